@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// MinTimeoutHeartbeatRatio is how many heartbeats must fit in the shortest
+// election timeout. Raft needs broadcastTime << electionTimeout: with fewer
+// heartbeats per timeout, one late or lost heartbeat starts an election
+// against a healthy leader.
+const MinTimeoutHeartbeatRatio = 3
+
+// Config holds a node's timing parameters. Every node may have its own, and
+// SetConfig changes them while the node runs, so timings can be explored
+// without a rebuild.
+type Config struct {
+	HeartbeatInterval  time.Duration // Leader heartbeat (and replication) period
+	ElectionTimeoutMin time.Duration // Election timeouts are random in [Min, Max)
+	ElectionTimeoutMax time.Duration
+}
+
+// DefaultConfig returns the timings the demo runs with.
+func DefaultConfig() Config {
+	return Config{
+		HeartbeatInterval:  100 * time.Millisecond,
+		ElectionTimeoutMin: 300 * time.Millisecond,
+		ElectionTimeoutMax: 600 * time.Millisecond,
+	}
+}
+
+// Validate checks the timings are positive, the election timeout range is
+// not empty, and the shortest election timeout spans at least
+// MinTimeoutHeartbeatRatio heartbeats.
+func (c Config) Validate() error {
+	switch {
+	case c.HeartbeatInterval <= 0:
+		return errors.New("heartbeat interval must be positive")
+	case c.ElectionTimeoutMin <= 0:
+		return errors.New("election timeout min must be positive")
+	case c.ElectionTimeoutMax <= c.ElectionTimeoutMin:
+		return fmt.Errorf("election timeout max (%v) must exceed min (%v)", c.ElectionTimeoutMax, c.ElectionTimeoutMin)
+	case c.ElectionTimeoutMin < MinTimeoutHeartbeatRatio*c.HeartbeatInterval:
+		return fmt.Errorf("election timeout min (%v) must be at least %d heartbeat intervals (%v)",
+			c.ElectionTimeoutMin, MinTimeoutHeartbeatRatio, MinTimeoutHeartbeatRatio*c.HeartbeatInterval)
+	}
+	return nil
+}
+
+// configJSON is Config with durations as strings such as "150ms", the form
+// used by config files and the admin API. Fields left empty keep their
+// current value.
+type configJSON struct {
+	HeartbeatInterval  string `json:"heartbeat_interval,omitempty"`
+	ElectionTimeoutMin string `json:"election_timeout_min,omitempty"`
+	ElectionTimeoutMax string `json:"election_timeout_max,omitempty"`
+}
+
+// MarshalJSON writes the durations as strings.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{
+		HeartbeatInterval:  c.HeartbeatInterval.String(),
+		ElectionTimeoutMin: c.ElectionTimeoutMin.String(),
+		ElectionTimeoutMax: c.ElectionTimeoutMax.String(),
+	})
+}
+
+// UnmarshalJSON overlays the fields present onto c.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var raw configJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"heartbeat_interval", raw.HeartbeatInterval, &c.HeartbeatInterval},
+		{"election_timeout_min", raw.ElectionTimeoutMin, &c.ElectionTimeoutMin},
+		{"election_timeout_max", raw.ElectionTimeoutMax, &c.ElectionTimeoutMax},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.dst = d
+	}
+	return nil
+}
+
+// LoadConfig reads a JSON config file over base, e.g.
+//
+//	{"heartbeat_interval": "50ms", "election_timeout_min": "200ms"}
+func LoadConfig(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+	config := base
+	if err := json.Unmarshal(data, &config); err != nil {
+		return base, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// TimingHandler serves the admin API for node timings:
+//
+//	GET  /admin/timing?node=2   the node's current timings
+//	POST /admin/timing?node=2   change them; the body holds the fields to change
+//
+// A change that fails Validate is rejected with 400 and nothing changes.
+func TimingHandler(rafts []*Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		node, err := strconv.Atoi(r.URL.Query().Get("node"))
+		if err != nil || node < 0 || node >= len(rafts) {
+			http.Error(w, fmt.Sprintf("node must be between 0 and %d", len(rafts)-1), http.StatusBadRequest)
+			return
+		}
+		rf := rafts[node]
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			config := rf.Config()
+			if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := rf.SetConfig(config); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rf.Config())
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DiskStore is an append-only key-value store on disk (Bitcask-style).
+//
+// Every Put and Delete appends a record to a single data file and is synced
+// before it returns. Only keys and file offsets are kept in memory, so
+// values never have to fit in RAM, and snapshots stream values straight from
+// the file. Records are only ever appended, except that a full Restore or
+// Seed empties the file and writes it again from offset 0, so values are
+// read under the lock, and a snapshot that copied its offsets before a reset
+// fails rather than read whatever was written over them.
+//
+// Deleted keys keep a tombstone in memory so incremental snapshots can carry
+// the delete. There is no compaction; the file grows with every write.
+type DiskStore struct {
+	mu        sync.RWMutex
+	file      *os.File
+	size      int64
+	keydir    map[string]keyEntry
+	lastIndex int
+	resets    int // Number of resets, so offsets copied before one can be told stale
+}
+
+// errReset fails a snapshot whose offsets a full restore made stale
+var errReset = errors.New("store was reset by a full restore during the snapshot")
+
+// keyEntry locates the latest record for a key
+type keyEntry struct {
+	offset  int64 // Offset of the value in the data file
+	size    uint32
+	index   int
+	deleted bool
+}
+
+// Record layout: crc32 | index uint64 | op uint8 | keyLen uint32 | valueLen uint32 | key | value
+// The checksum covers everything after it.
+const recordHeaderSize = 4 + 8 + 1 + 4 + 4
+
+const (
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+// OpenDiskStore opens or creates the store at path, rebuilding the key
+// index from the data file. A torn record at the end of the file (from a
+// crash mid-write) is truncated away.
+func OpenDiskStore(path string) (*DiskStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DiskStore{file: file, keydir: make(map[string]keyEntry)}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replays the data file into the key index
+func (s *DiskStore) load() error {
+	header := make([]byte, recordHeaderSize)
+	var offset int64
+
+	for {
+		if _, err := s.file.ReadAt(header, offset); err != nil {
+			break // EOF or torn header
+		}
+		index := int(binary.BigEndian.Uint64(header[4:]))
+		op := header[12]
+		keyLen := binary.BigEndian.Uint32(header[13:])
+		valueLen := binary.BigEndian.Uint32(header[17:])
+
+		body := make([]byte, keyLen+valueLen)
+		if _, err := s.file.ReadAt(body, offset+recordHeaderSize); err != nil {
+			break // Torn body
+		}
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(body)
+		if crc.Sum32() != binary.BigEndian.Uint32(header) {
+			break // Torn or corrupt record
+		}
+
+		s.index(string(body[:keyLen]), keyEntry{
+			offset:  offset + recordHeaderSize + int64(keyLen),
+			size:    valueLen,
+			index:   index,
+			deleted: op == opDelete,
+		})
+		offset += recordHeaderSize + int64(keyLen+valueLen)
+	}
+
+	s.size = offset
+	return s.file.Truncate(offset)
+}
+
+// index records the latest entry for key. Callers must hold s.mu.
+func (s *DiskStore) index(key string, entry keyEntry) {
+	s.keydir[key] = entry
+	if entry.index > s.lastIndex {
+		s.lastIndex = entry.index
+	}
+}
+
+// Put stores value under key, as of log index
+func (s *DiskStore) Put(index int, key, value string) error {
+	return s.append(index, opPut, key, value, true)
+}
+
+// Delete removes key, as of log index
+func (s *DiskStore) Delete(index int, key string) error {
+	return s.append(index, opDelete, key, "", true)
+}
+
+// append writes one record, syncing it if asked, then updates the key index
+func (s *DiskStore) append(index int, op byte, key, value string, sync bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := make([]byte, recordHeaderSize+len(key)+len(value))
+	binary.BigEndian.PutUint64(record[4:], uint64(index))
+	record[12] = op
+	binary.BigEndian.PutUint32(record[13:], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[17:], uint32(len(value)))
+	copy(record[recordHeaderSize:], key)
+	copy(record[recordHeaderSize+len(key):], value)
+	binary.BigEndian.PutUint32(record, crc32.ChecksumIEEE(record[4:]))
+
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return err
+	}
+	if sync {
+		if err := s.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	s.index(key, keyEntry{
+		offset:  s.size + recordHeaderSize + int64(len(key)),
+		size:    uint32(len(value)),
+		index:   index,
+		deleted: op == opDelete,
+	})
+	s.size += int64(len(record))
+	return nil
+}
+
+// Get returns the value stored under key
+func (s *DiskStore) Get(key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.keydir[key]
+	if !ok || entry.deleted {
+		return "", false, nil
+	}
+	value, err := s.readValue(entry)
+	return value, err == nil, err
+}
+
+// readValue reads a value from the data file. Callers must hold s.mu, since
+// a reset rewrites the file from offset 0.
+func (s *DiskStore) readValue(entry keyEntry) (string, error) {
+	buf := make([]byte, entry.size)
+	if _, err := s.file.ReadAt(buf, entry.offset); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readSince reads a value whose entry was copied under the lock before,
+// unless the store was reset since: resets is the count seen then.
+func (s *DiskStore) readSince(entry keyEntry, resets int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.resets != resets {
+		return "", errReset
+	}
+	return s.readValue(entry)
+}
+
+// LastIndex returns the highest log index written to the store
+func (s *DiskStore) LastIndex() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastIndex
+}
+
+// Snapshot streams every key changed after index since, in key order, as a
+// gob-encoded SnapshotHeader followed by SnapshotRecords.
+//
+// Only the matching keys and offsets are copied under the lock; values are
+// read from disk one at a time while writes continue. A full Restore or Seed
+// meanwhile fails the snapshot. A full snapshot (since == 0) leaves out
+// tombstones.
+func (s *DiskStore) Snapshot(w io.Writer, since int) (int, error) {
+	type snapshotKey struct {
+		key   string
+		entry keyEntry
+	}
+
+	s.mu.RLock()
+	index, resets := s.lastIndex, s.resets
+	keys := make([]snapshotKey, 0)
+	for key, entry := range s.keydir {
+		if entry.index <= since || (since == 0 && entry.deleted) {
+			continue
+		}
+		keys = append(keys, snapshotKey{key, entry})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key < keys[j].key })
+
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(SnapshotHeader{Since: since, Index: index}); err != nil {
+		return 0, err
+	}
+	for _, k := range keys {
+		record := SnapshotRecord{Key: k.key, Deleted: k.entry.deleted, Index: k.entry.index}
+		if !k.entry.deleted {
+			value, err := s.readSince(k.entry, resets)
+			if err != nil {
+				return 0, err
+			}
+			record.Value = value
+		}
+		if err := enc.Encode(record); err != nil {
+			return 0, err
+		}
+	}
+	return index, nil
+}
+
+// Restore loads a snapshot written by Snapshot. A full snapshot replaces
+// the data file, failing any Snapshot in progress; an incremental one is
+// appended and must start at LastIndex.
+func (s *DiskStore) Restore(r io.Reader) error {
+	return s.restore(r, false)
+}
+
+// Seed replaces the store with a full snapshot taken from another cluster.
+// Log indices are meaningless in a fresh cluster, whose log starts again at
+// 1, so every key is written as of index 0 and the store starts at 0.
+func (s *DiskStore) Seed(r io.Reader) error {
+	return s.restore(r, true)
+}
+
+func (s *DiskStore) restore(r io.Reader, seed bool) error {
+	dec := gob.NewDecoder(r)
+	var header SnapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("read snapshot header: %w", err)
+	}
+
+	if seed && header.Since != 0 {
+		return fmt.Errorf("can only seed from a full snapshot, got one since index %d", header.Since)
+	}
+	if header.Since == 0 {
+		if err := s.reset(); err != nil {
+			return err
+		}
+	} else if last := s.LastIndex(); header.Since != last {
+		return fmt.Errorf("incremental snapshot starts at index %d, store is at %d", header.Since, last)
+	}
+
+	for {
+		var record SnapshotRecord
+		if err := dec.Decode(&record); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read snapshot record: %w", err)
+		}
+
+		op := opPut
+		if record.Deleted {
+			op = opDelete
+		}
+		if seed {
+			record.Index = 0
+		}
+		// Synced once at the end rather than per record
+		if err := s.append(record.Index, op, record.Key, record.Value, false); err != nil {
+			return err
+		}
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	// A full snapshot leaves out tombstones, which may include the last change
+	s.mu.Lock()
+	if !seed && header.Index > s.lastIndex {
+		s.lastIndex = header.Index
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// reset empties the store
+func (s *DiskStore) reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	s.size = 0
+	s.keydir = make(map[string]keyEntry)
+	s.lastIndex = 0
+	s.resets++
+	return nil
+}
+
+// Close closes the data file
+func (s *DiskStore) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openStore opens a store at path, closed when the test ends.
+func openStore(t *testing.T, path string) *DiskStore {
+	t.Helper()
+	store, err := OpenDiskStore(path)
+	if err != nil {
+		t.Fatalf("OpenDiskStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newStore opens an empty store in a temporary directory.
+func newStore(t *testing.T) *DiskStore {
+	t.Helper()
+	return openStore(t, filepath.Join(t.TempDir(), "kv.db"))
+}
+
+// snapshotOf takes a snapshot of store since index since.
+func snapshotOf(t *testing.T, store *DiskStore, since int) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := store.Snapshot(&buf, since); err != nil {
+		t.Fatalf("Snapshot since %d failed: %v", since, err)
+	}
+	return &buf
+}
+
+// decodeSnapshot reads a snapshot stream's header and records.
+func decodeSnapshot(t *testing.T, stream []byte) (SnapshotHeader, []SnapshotRecord) {
+	t.Helper()
+	dec := gob.NewDecoder(bytes.NewReader(stream))
+	var header SnapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		t.Fatalf("Snapshot header can't be read: %v", err)
+	}
+	var records []SnapshotRecord
+	for {
+		var record SnapshotRecord
+		if err := dec.Decode(&record); errors.Is(err, io.EOF) {
+			return header, records
+		} else if err != nil {
+			t.Fatalf("Snapshot record can't be read: %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+// expectValues checks store holds exactly want among the keys named, a
+// key missing from want being absent.
+func expectValues(t *testing.T, store *DiskStore, want map[string]string, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		value, ok, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("Get %q failed: %v", key, err)
+		}
+		expected, present := want[key]
+		if ok != present || value != expected {
+			t.Errorf("Key %q: expected %q (present %v), got %q (present %v)", key, expected, present, value, ok)
+		}
+	}
+}
+
+// TestDiskStore_PutDelete verifies puts and deletes are read back, the last
+// write to a key wins, and LastIndex follows the highest index written.
+func TestDiskStore_PutDelete(t *testing.T) {
+	store := newStore(t)
+	for _, write := range []struct {
+		index      int
+		key, value string
+		delete     bool
+	}{
+		{1, "name", "Alice", false},
+		{2, "city", "Paris", false},
+		{3, "name", "Bob", false},
+		{4, "city", "", true},
+	} {
+		var err error
+		if write.delete {
+			err = store.Delete(write.index, write.key)
+		} else {
+			err = store.Put(write.index, write.key, write.value)
+		}
+		if err != nil {
+			t.Fatalf("Write at index %d failed: %v", write.index, err)
+		}
+	}
+
+	expectValues(t, store, map[string]string{"name": "Bob"}, "name", "city", "missing")
+	if last := store.LastIndex(); last != 4 {
+		t.Errorf("Expected LastIndex 4, got %d", last)
+	}
+
+	// A deleted key can be written again
+	if err := store.Put(5, "city", "Rome"); err != nil {
+		t.Fatal(err)
+	}
+	expectValues(t, store, map[string]string{"name": "Bob", "city": "Rome"}, "name", "city")
+}
+
+// TestDiskStore_SnapshotRestore verifies a full snapshot restored into a
+// fresh store gives the same values and index, without tombstones.
+func TestDiskStore_SnapshotRestore(t *testing.T) {
+	source := newStore(t)
+	source.Put(1, "a", "1")
+	source.Put(2, "b", "2")
+	source.Put(3, "c", "3")
+	source.Delete(4, "b")
+
+	snapshot := snapshotOf(t, source, 0)
+	header, records := decodeSnapshot(t, snapshot.Bytes())
+	if header != (SnapshotHeader{Since: 0, Index: 4}) || len(records) != 2 || records[0].Key != "a" || records[1].Key != "c" {
+		t.Fatalf("Expected a and c as of index 4, without b's tombstone, got %+v %+v", header, records)
+	}
+
+	target := newStore(t)
+	target.Put(7, "stale", "x") // Replaced by the full restore
+	if err := target.Restore(snapshot); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	expectValues(t, target, map[string]string{"a": "1", "c": "3"}, "a", "b", "c", "stale")
+	if last := target.LastIndex(); last != 4 {
+		// The delete at 4 is left out, but the snapshot was taken at 4
+		t.Errorf("Expected LastIndex 4 after restore, got %d", last)
+	}
+}
+
+// TestDiskStore_IncrementalAfterFullRestore verifies a store restored from
+// a full snapshot catches up from an incremental one, deletes included,
+// and refuses one that doesn't start at its index.
+func TestDiskStore_IncrementalAfterFullRestore(t *testing.T) {
+	source := newStore(t)
+	source.Put(1, "a", "1")
+	source.Put(2, "b", "2")
+
+	target := newStore(t)
+	if err := target.Restore(snapshotOf(t, source, 0)); err != nil {
+		t.Fatalf("Full restore failed: %v", err)
+	}
+
+	source.Put(3, "c", "3")
+	source.Delete(4, "a")
+	source.Put(5, "b", "22")
+
+	// Starting anywhere but the target's index would skip or repeat writes
+	if err := target.Restore(snapshotOf(t, source, 3)); err == nil || !strings.Contains(err.Error(), "store is at 2") {
+		t.Fatalf("Expected an incremental snapshot from 3 refused at index 2, got %v", err)
+	}
+
+	if err := target.Restore(snapshotOf(t, source, target.LastIndex())); err != nil {
+		t.Fatalf("Incremental restore failed: %v", err)
+	}
+	expectValues(t, target, map[string]string{"b": "22", "c": "3"}, "a", "b", "c")
+	if last := target.LastIndex(); last != 5 {
+		t.Errorf("Expected LastIndex 5, got %d", last)
+	}
+
+	// Nothing changed since: an empty incremental leaves the store as is
+	if err := target.Restore(snapshotOf(t, source, 5)); err != nil {
+		t.Fatalf("Empty incremental restore failed: %v", err)
+	}
+	expectValues(t, target, map[string]string{"b": "22", "c": "3"}, "a", "b", "c")
+}
+
+// TestDiskStore_SnapshotFailsOnReset verifies a snapshot that copied its
+// offsets before a full restore fails instead of reading rewritten data.
+func TestDiskStore_SnapshotFailsOnReset(t *testing.T) {
+	store := newStore(t)
+	store.Put(1, "a", "1")
+
+	store.mu.RLock()
+	entry, resets := store.keydir["a"], store.resets
+	store.mu.RUnlock()
+
+	other := newStore(t)
+	other.Put(1, "z", "26")
+	if err := store.Restore(snapshotOf(t, other, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.readSince(entry, resets); !errors.Is(err, errReset) {
+		t.Errorf("Expected errReset reading an offset from before the restore, got %v", err)
+	}
+}
+
+// TestDiskStore_Reopen verifies a store reopened after close has the same
+// values and index, and that a record torn by a crash mid-write is dropped.
+func TestDiskStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	store, err := OpenDiskStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put(1, "a", "1")
+	store.Put(2, "b", "2")
+	store.Delete(3, "a")
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Half a record at the end, as a crash mid-append leaves it
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0})
+	file.Close()
+
+	reopened := openStore(t, path)
+	expectValues(t, reopened, map[string]string{"b": "2"}, "a", "b")
+	if last := reopened.LastIndex(); last != 3 {
+		t.Errorf("Expected LastIndex 3 after reopening, got %d", last)
+	}
+
+	// Writes go where the torn record was
+	if err := reopened.Put(4, "c", "3"); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+	again := openStore(t, path)
+	expectValues(t, again, map[string]string{"b": "2", "c": "3"}, "a", "b", "c")
+}
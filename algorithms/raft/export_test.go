@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExportStore returns a KV store holding a=1 and c=3 as of index 4.
+func newExportStore(t *testing.T) *KVStore {
+	t.Helper()
+	kv := NewKVStore(&Raft{}, newStore(t))
+	for index, cmd := range []KVCommand{
+		{Op: "put", Key: "a", Value: "1"},
+		{Op: "put", Key: "b", Value: "2"},
+		{Op: "delete", Key: "b"},
+		{Op: "put", Key: "c", Value: "3"},
+	} {
+		kv.Apply(ApplyMsg{CommandValid: true, Command: cmd, CommandIndex: index + 1})
+	}
+	return kv
+}
+
+// TestExport_ChecksumRoundTrip verifies an export carries the checksum of
+// its snapshot stream, seeds a fresh store once verified, and is refused
+// if truncated or corrupted.
+func TestExport_ChecksumRoundTrip(t *testing.T) {
+	kv := newExportStore(t)
+	var export bytes.Buffer
+	index, sum, err := ExportSnapshot(&export, kv, 0)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	stream := export.Bytes()[:export.Len()-sha256.Size]
+	if want := sha256.Sum256(stream); index != 4 || !bytes.Equal(sum, want[:]) || !bytes.HasSuffix(export.Bytes(), sum) {
+		t.Fatalf("Expected index 4 and the stream's checksum appended, got index %d sum %x", index, sum)
+	}
+
+	snapshot, err := VerifyExport(bytes.NewReader(export.Bytes()))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	spooled := snapshot.(*spooledSnapshot).file.Name()
+	seeded := newStore(t)
+	if err := seeded.Seed(snapshot); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	snapshot.Close()
+	if _, err := os.Stat(spooled); !os.IsNotExist(err) {
+		t.Errorf("Expected the spooled export removed on close, got %v", err)
+	}
+	expectValues(t, seeded, map[string]string{"a": "1", "c": "3"}, "a", "b", "c")
+	if last := seeded.LastIndex(); last != 0 {
+		t.Errorf("Expected a seeded store to start at index 0 for a fresh log, got %d", last)
+	}
+
+	corrupt := append([]byte(nil), export.Bytes()...)
+	corrupt[len(stream)/2] ^= 0xff
+	for name, data := range map[string][]byte{
+		"corrupt":            corrupt,
+		"truncated":          export.Bytes()[:export.Len()-1],
+		"shorter than a sum": export.Bytes()[:sha256.Size-1],
+		"empty":              nil,
+	} {
+		if _, err := VerifyExport(bytes.NewReader(data)); !errors.Is(err, ErrSnapshotChecksum) {
+			t.Errorf("%s: expected ErrSnapshotChecksum, got %v", name, err)
+		}
+	}
+
+	// Only a full export can seed a cluster
+	export.Reset()
+	if _, _, err := ExportSnapshot(&export, kv, 2); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err = VerifyExport(&export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	if err := newStore(t).Seed(snapshot); err == nil || !strings.Contains(err.Error(), "full snapshot") {
+		t.Errorf("Expected seeding from an incremental export refused, got %v", err)
+	}
+}
+
+// TestExport_Handler verifies GET /snapshot streams a verifiable export
+// with its index and checksum in trailers, and waits for min_index.
+func TestExport_Handler(t *testing.T) {
+	kv := newExportStore(t)
+	server := httptest.NewServer(SnapshotHandler(kv))
+	defer server.Close()
+
+	// The write at index 5 lands while the export waits for it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		kv.Apply(ApplyMsg{CommandValid: true, Command: KVCommand{Op: "put", Key: "d", Value: "4"}, CommandIndex: 5})
+	}()
+	resp, err := http.Get(server.URL + "/snapshot?min_index=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body) // Trailers arrive after the body
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected an export, got %d (%v)", resp.StatusCode, err)
+	}
+	sum := sha256.Sum256(body[:len(body)-sha256.Size])
+	if index, checksum := resp.Trailer.Get("X-Snapshot-Index"), resp.Trailer.Get("X-Snapshot-Sha256"); index != "5" || checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected trailers for index 5 and the body's checksum, got %q and %q", index, checksum)
+	}
+
+	snapshot, err := VerifyExport(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	defer snapshot.Close()
+	restored := newStore(t)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	expectValues(t, restored, map[string]string{"a": "1", "c": "3", "d": "4"}, "a", "b", "c", "d")
+
+	for _, c := range []struct {
+		method, query string
+		status        int
+	}{
+		{http.MethodPost, "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "?since=-1", http.StatusBadRequest},
+		{http.MethodGet, "?min_index=x", http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest(c.method, server.URL+"/snapshot"+c.query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.query, c.status, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Leader Leases and Fencing Tokens
+//
+// Being elected doesn't stop a node from acting as leader after it has been
+// replaced: a paused or partitioned leader keeps believing it leads until it
+// hears of a higher term. Anything outside Raft that follows the leader, such
+// as a matching engine primary writing to shared storage, needs two things:
+//
+//   - A lease: a window in which no other node can have been elected. A
+//     follower that heard from the leader refuses to vote, or even adopt a
+//     higher term, for the leader's ElectionTimeoutMin after the heartbeat
+//     arrived. The leader counts the lease from when it sent the latest
+//     heartbeat a majority answered, shortened by MaxClockDrift, so it ends
+//     before that majority will vote for anyone else.
+//
+//   - A fencing token: the term the lease was granted in. Terms only grow,
+//     so a resource that remembers the highest token it has seen (see Fence)
+//     rejects a stale leader even if its lease check raced with expiry.
+
+// MaxClockDrift is the fraction by which one node's clock may run faster than
+// another's while measuring a lease. The leader's lease is cut by it.
+const MaxClockDrift = 0.1
+
+// Lease is a leader's lease as of when it was read.
+type Lease struct {
+	Node         int           `json:"node"`
+	FencingToken int           `json:"fencing_token"` // The leader's term
+	Expires      time.Time     `json:"expires"`
+	Remaining    time.Duration `json:"remaining_ns"`
+}
+
+// ErrStaleToken is returned by Fence.Check for a token lower than one it has
+// already admitted.
+type ErrStaleToken struct {
+	Token, Highest int
+}
+
+func (e *ErrStaleToken) Error() string {
+	return fmt.Sprintf("stale fencing token %d (already admitted %d)", e.Token, e.Highest)
+}
+
+// Lease returns this node's lease, or false if it is not a leader holding
+// one: a follower, a leader not yet heard from by a majority in its term,
+// or one that has lost contact with the majority for too long.
+func (rf *Raft) Lease() (Lease, bool) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	now := time.Now()
+	if rf.dead || rf.state != Leader {
+		return Lease{}, false
+	}
+	expires := rf.leaseExpiry(now)
+	if !now.Before(expires) {
+		return Lease{}, false
+	}
+	return Lease{
+		Node:         rf.id,
+		FencingToken: rf.currentTerm,
+		Expires:      expires,
+		Remaining:    expires.Sub(now),
+	}, true
+}
+
+// leaseExpiry is when the leader's lease ends: the latest send time a
+// majority has acknowledged, counting this node as acknowledging now, plus
+// the lease. Callers must hold rf.mu and be the leader.
+func (rf *Raft) leaseExpiry(now time.Time) time.Time {
+	acked := make([]time.Time, 0, len(rf.peers))
+	for i := range rf.peers {
+		if i == rf.id {
+			acked = append(acked, now)
+			continue
+		}
+		acked = append(acked, rf.progress[i].ackedSent)
+	}
+	sort.Slice(acked, func(i, j int) bool { return acked[i].After(acked[j]) })
+	majority := acked[len(acked)/2]
+	if majority.IsZero() {
+		return time.Time{}
+	}
+
+	lease := time.Duration(float64(rf.config.ElectionTimeoutMin) * (1 - MaxClockDrift))
+	return majority.Add(lease)
+}
+
+// leaseHeld reports whether a leader's lease may still hold, so this node
+// must not help elect another. Callers must hold rf.mu.
+func (rf *Raft) leaseHeld(now time.Time) bool {
+	if rf.state == Leader {
+		return now.Before(rf.leaseExpiry(now))
+	}
+	return now.Before(rf.leaseUntil)
+}
+
+// Fence admits writes from leaders in order of their fencing tokens. It is
+// what the protected resource runs, not Raft: once it has seen a token it
+// refuses any lower one.
+type Fence struct {
+	mu      sync.Mutex
+	highest int
+}
+
+// Check admits token if no higher one has been admitted.
+func (f *Fence) Check(token int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if token < f.highest {
+		return &ErrStaleToken{Token: token, Highest: f.highest}
+	}
+	f.highest = token
+	return nil
+}
+
+// LeaseHandler serves the lease API:
+//
+//	GET /lease?node=2   node 2's lease; 409 if it holds none
+//	GET /lease          the lease of whichever node holds one; 503 if none does
+//
+// A writer holds the lease only until expires, and must present
+// fencing_token with every write.
+func LeaseHandler(rafts []*Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		candidates := rafts
+		status := http.StatusServiceUnavailable
+		if s := r.URL.Query().Get("node"); s != "" {
+			node, err := strconv.Atoi(s)
+			if err != nil || node < 0 || node >= len(rafts) {
+				http.Error(w, fmt.Sprintf("node must be between 0 and %d", len(rafts)-1), http.StatusBadRequest)
+				return
+			}
+			candidates = rafts[node : node+1]
+			status = http.StatusConflict
+		}
+
+		for _, rf := range candidates {
+			if lease, ok := rf.Lease(); ok {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(lease)
+				return
+			}
+		}
+		http.Error(w, "no leader holds a lease", status)
+	}
+}
@@ -1,39 +1,62 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 )
 
 // KVCommand represents a key-value operation
 type KVCommand struct {
-	Op    string // "put" or "get"
+	Op    string // "put" or "delete"
 	Key   string
 	Value string
 }
 
-// KVStore is a simple key-value store backed by Raft
+// KVStore is a simple key-value store backed by Raft, persisted in a DiskStore
 type KVStore struct {
-	raft *Raft
-	data map[string]string
+	raft  *Raft
+	store *DiskStore
 }
 
-func NewKVStore(raft *Raft) *KVStore {
+// KVStore is the state machine Raft applies to
+var _ StateMachine = (*KVStore)(nil)
+
+func NewKVStore(raft *Raft, store *DiskStore) *KVStore {
 	return &KVStore{
-		raft: raft,
-		data: make(map[string]string),
+		raft:  raft,
+		store: store,
 	}
 }
 
-func (kv *KVStore) Put(key, value string) bool {
+// Put submits a write on any node; followers forward it to the leader
+func (kv *KVStore) Put(key, value string) error {
 	cmd := KVCommand{Op: "put", Key: key, Value: value}
-	_, _, isLeader := kv.raft.Start(cmd)
-	return isLeader
+	_, _, err := kv.raft.Propose(cmd)
+	return err
+}
+
+// Delete submits a delete on any node; followers forward it to the leader
+func (kv *KVStore) Delete(key string) error {
+	cmd := KVCommand{Op: "delete", Key: key}
+	_, _, err := kv.raft.Propose(cmd)
+	return err
 }
 
 func (kv *KVStore) Get(key string) (string, bool) {
-	val, ok := kv.data[key]
+	val, ok, err := kv.store.Get(key)
+	if err != nil {
+		fmt.Printf("[KVStore %d] Read failed: %v\n", kv.raft.id, err)
+	}
 	return val, ok
 }
 
@@ -47,23 +70,64 @@ func (kv *KVStore) Apply(msg ApplyMsg) {
 		return
 	}
 
+	// Entries at or below the store's index were applied before a restart
+	// or arrived in a restored snapshot
+	if msg.CommandIndex <= kv.store.LastIndex() {
+		return
+	}
+
+	var err error
+	switch cmd.Op {
+	case "put":
+		err = kv.store.Put(msg.CommandIndex, cmd.Key, cmd.Value)
+	case "delete":
+		err = kv.store.Delete(msg.CommandIndex, cmd.Key)
+	default:
+		return
+	}
+	if err != nil {
+		fmt.Printf("[KVStore %d] Failed to apply %s %s (index %d): %v\n",
+			kv.raft.id, cmd.Op, cmd.Key, msg.CommandIndex, err)
+		return
+	}
+
 	if cmd.Op == "put" {
-		kv.data[cmd.Key] = cmd.Value
 		fmt.Printf("[KVStore %d] Applied: PUT %s=%s (index %d)\n",
 			kv.raft.id, cmd.Key, cmd.Value, msg.CommandIndex)
+	} else {
+		fmt.Printf("[KVStore %d] Applied: DELETE %s (index %d)\n",
+			kv.raft.id, cmd.Key, msg.CommandIndex)
 	}
 }
 
+func (kv *KVStore) LastApplied() int {
+	return kv.store.LastIndex()
+}
+
+func (kv *KVStore) Snapshot(w io.Writer, since int) (int, error) {
+	return kv.store.Snapshot(w, since)
+}
+
+func (kv *KVStore) Restore(r io.Reader) error {
+	return kv.store.Restore(r)
+}
+
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
+	config, opts, err := parseFlags()
+	if err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║         RAFT CONSENSUS ALGORITHM - LIVE DEMO             ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	// Create cluster of 5 nodes
-	numNodes := 5
+	// Create cluster of 5 nodes, or -nodes when serving
+	numNodes := opts.nodes
 	applyChs := make([]chan ApplyMsg, numNodes)
 	rafts := make([]*Raft, numNodes)
 	kvStores := make([]*KVStore, numNodes)
@@ -73,10 +137,25 @@ func main() {
 		applyChs[i] = make(chan ApplyMsg, 100)
 	}
 
+	// Each node keeps its KV data in its own file
+	dataDir, err := os.MkdirTemp("", "raft-demo-")
+	if err != nil {
+		fmt.Printf("Failed to create data directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dataDir)
+
 	// Create Raft nodes
 	for i := 0; i < numNodes; i++ {
-		rafts[i] = NewRaft(i, rafts, applyChs[i])
-		kvStores[i] = NewKVStore(rafts[i])
+		store, err := OpenDiskStore(filepath.Join(dataDir, fmt.Sprintf("node-%d.db", i)))
+		if err != nil {
+			fmt.Printf("Failed to open store for node %d: %v\n", i, err)
+			return
+		}
+		defer store.Close()
+
+		rafts[i] = NewRaft(i, rafts, applyChs[i], config)
+		kvStores[i] = NewKVStore(rafts[i], store)
 	}
 
 	// Set peer references
@@ -93,9 +172,36 @@ func main() {
 		}(i)
 	}
 
-	fmt.Println("✓ Created 5-node Raft cluster")
+	fmt.Printf("✓ Created %d-node Raft cluster\n", numNodes)
+	fmt.Printf("✓ Timing: heartbeat=%v, election timeout=%v-%v\n",
+		config.HeartbeatInterval, config.ElectionTimeoutMin, config.ElectionTimeoutMax)
+
+	// Timings can be changed per node while the demo runs, and the leader's
+	// lease read
+	if adminAddr := opts.adminAddr; adminAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/admin/timing", TimingHandler(rafts))
+		mux.Handle("/lease", LeaseHandler(rafts))
+		admin := &http.Server{Addr: adminAddr, Handler: mux}
+		go func() {
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("Admin API failed: %v\n", err)
+			}
+		}()
+		defer admin.Close()
+		fmt.Printf("✓ Admin API: curl -d '{\"heartbeat_interval\":\"50ms\"}' 'http://%s/admin/timing?node=0'\n", adminAddr)
+		fmt.Printf("✓ Lease API: curl 'http://%s/lease'\n", adminAddr)
+	}
 	fmt.Println()
 
+	if opts.serve {
+		serve(rafts)
+		for i := 0; i < numNodes; i++ {
+			rafts[i].Kill()
+		}
+		return
+	}
+
 	// Demo 1: Leader Election
 	fmt.Println("═══════════════════════════════════════════════════════════")
 	fmt.Println("DEMO 1: LEADER ELECTION")
@@ -106,6 +212,9 @@ func main() {
 	leaderID := findLeader(rafts)
 	if leaderID != -1 {
 		fmt.Printf("✓ Node %d elected as leader\n", leaderID)
+		if readIndex, ok := rafts[leaderID].ReadIndex(); ok {
+			fmt.Printf("✓ Leader committed its no-op, reads are up to date from index %d\n", readIndex)
+		}
 	}
 	fmt.Println()
 
@@ -121,7 +230,12 @@ func main() {
 	kvStores[leaderID].Put("age", "30")
 	time.Sleep(500 * time.Millisecond)
 
-	kvStores[leaderID].Put("city", "Seattle")
+	// Clients don't need to find the leader: a follower forwards the write
+	forwarderID := (leaderID + 1) % numNodes
+	fmt.Printf("Submitting to Node %d (follower), which forwards to the leader...\n", forwarderID)
+	if err := kvStores[forwarderID].Put("city", "Seattle"); err != nil {
+		fmt.Printf("✗ Forwarding failed: %v\n", err)
+	}
 	time.Sleep(500 * time.Millisecond)
 
 	fmt.Println("\nVerifying replication across all nodes:")
@@ -166,12 +280,25 @@ func main() {
 		fmt.Printf("  Node %d: status=%s\n", i, status)
 	}
 	fmt.Println("✓ Cluster continues operating with 4/5 nodes!")
+
+	fmt.Printf("\nReplication stats on leader Node %d:\n", leaderID)
+	printReplicationStats(rafts[leaderID])
+	fmt.Println("✓ Dead follower is backed off instead of retried every heartbeat")
 	fmt.Println()
 
 	// Demo 4: Leader Failure and Re-election
 	fmt.Println("═══════════════════════════════════════════════════════════")
 	fmt.Println("DEMO 4: LEADER FAILURE - Triggering Re-election")
 	fmt.Println("═══════════════════════════════════════════════════════════")
+	// An external resource fenced by Raft terms, written by the leader
+	var fence Fence
+	oldLease, ok := rafts[leaderID].Lease()
+	if ok {
+		fence.Check(oldLease.FencingToken)
+		fmt.Printf("Node %d holds the lease for %v, fencing token %d\n",
+			leaderID, oldLease.Remaining.Round(time.Millisecond), oldLease.FencingToken)
+	}
+
 	fmt.Printf("Killing Node %d (Current Leader)...\n", leaderID)
 	rafts[leaderID].Kill()
 
@@ -182,6 +309,14 @@ func main() {
 	if newLeaderID != -1 && newLeaderID != leaderID {
 		fmt.Printf("✓ Node %d elected as new leader!\n", newLeaderID)
 	}
+	if newLeaderID != -1 {
+		if newLease, ok := rafts[newLeaderID].Lease(); ok && fence.Check(newLease.FencingToken) == nil {
+			fmt.Printf("✓ Node %d writes with fencing token %d\n", newLeaderID, newLease.FencingToken)
+		}
+		if err := fence.Check(oldLease.FencingToken); err != nil {
+			fmt.Printf("✓ A write from the old leader is refused: %v\n", err)
+		}
+	}
 	fmt.Println()
 
 	// Demo 5: Continue Operations
@@ -209,19 +344,154 @@ func main() {
 	fmt.Println("✓ System fully operational with majority quorum!")
 	fmt.Println()
 
+	// Demo 6: Snapshots
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Println("DEMO 6: INCREMENTAL SNAPSHOTS")
+	fmt.Println("═══════════════════════════════════════════════════════════")
+
+	var full bytes.Buffer
+	fullIndex, err := kvStores[newLeaderID].Snapshot(&full, 0)
+	if err != nil {
+		fmt.Printf("Full snapshot failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Full snapshot of Node %d at index %d: %d bytes\n", newLeaderID, fullIndex, full.Len())
+
+	fmt.Println("Submitting more commands...")
+	kvStores[newLeaderID].Delete("status")
+	kvStores[newLeaderID].Put("city", "Portland")
+	time.Sleep(1 * time.Second)
+
+	var incremental bytes.Buffer
+	incrementalIndex, err := kvStores[newLeaderID].Snapshot(&incremental, fullIndex)
+	if err != nil {
+		fmt.Printf("Incremental snapshot failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Incremental snapshot from index %d to %d: %d bytes\n", fullIndex, incrementalIndex, incremental.Len())
+
+	restored, err := OpenDiskStore(filepath.Join(dataDir, "restored.db"))
+	if err != nil {
+		fmt.Printf("Failed to open restore target: %v\n", err)
+		return
+	}
+	defer restored.Close()
+	if err := restored.Restore(&full); err != nil {
+		fmt.Printf("Restoring full snapshot failed: %v\n", err)
+		return
+	}
+	if err := restored.Restore(&incremental); err != nil {
+		fmt.Printf("Restoring incremental snapshot failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nFresh store restored to index %d:\n", restored.LastIndex())
+	for _, key := range []string{"name", "city", "status", "leader"} {
+		if value, ok, _ := restored.Get(key); ok {
+			fmt.Printf("  %s=%s\n", key, value)
+		} else {
+			fmt.Printf("  %s: (deleted)\n", key)
+		}
+	}
+	fmt.Println("✓ Full + incremental snapshot rebuilds the same state!")
+	fmt.Println()
+
+	// Demo 7: Snapshot export into a fresh cluster
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Println("DEMO 7: SNAPSHOT EXPORT & SEEDING A FRESH CLUSTER")
+	fmt.Println("═══════════════════════════════════════════════════════════")
+
+	// Exports are read-only, so any node can serve them
+	exportID := -1
+	for i := 0; i < numNodes; i++ {
+		if i != followerID && i != leaderID && i != newLeaderID {
+			exportID = i
+			break
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/snapshot", SnapshotHandler(kvStores[exportID]))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("Failed to listen: %v\n", err)
+		return
+	}
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	url := fmt.Sprintf("http://%s/snapshot?min_index=%d", listener.Addr(), incrementalIndex)
+	fmt.Printf("GET %s (served by Node %d, a follower)\n", url, exportID)
+	backupPath := filepath.Join(dataDir, "backup.snap")
+	if err := downloadSnapshot(url, backupPath); err != nil {
+		fmt.Printf("Export failed: %v\n", err)
+		return
+	}
+
+	fmt.Println("\nStarting a fresh 3-node cluster seeded from the export...")
+	freshNodes := 3
+	freshApplyChs := make([]chan ApplyMsg, freshNodes)
+	freshRafts := make([]*Raft, freshNodes)
+	freshStores := make([]*KVStore, freshNodes)
+	for i := 0; i < freshNodes; i++ {
+		store, err := seedStore(filepath.Join(dataDir, fmt.Sprintf("fresh-%d.db", i)), backupPath)
+		if err != nil {
+			fmt.Printf("Failed to seed fresh node %d: %v\n", i, err)
+			return
+		}
+		defer store.Close()
+
+		freshApplyChs[i] = make(chan ApplyMsg, 100)
+		freshRafts[i] = NewRaft(i, freshRafts, freshApplyChs[i], config)
+		freshStores[i] = NewKVStore(freshRafts[i], store)
+	}
+	for i := 0; i < freshNodes; i++ {
+		freshRafts[i].peers = freshRafts
+		go func(nodeID int) {
+			for msg := range freshApplyChs[nodeID] {
+				freshStores[nodeID].Apply(msg)
+			}
+		}(i)
+	}
+	time.Sleep(2 * time.Second)
+
+	if err := freshStores[0].Put("seeded", "true"); err != nil {
+		fmt.Printf("✗ Write to fresh cluster failed: %v\n", err)
+	}
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\nFresh cluster state:")
+	for i := 0; i < freshNodes; i++ {
+		name, _ := freshStores[i].Get("name")
+		city, _ := freshStores[i].Get("city")
+		seeded, _ := freshStores[i].Get("seeded")
+		fmt.Printf("  Node %d: name=%s, city=%s, seeded=%s\n", i, name, city, seeded)
+	}
+	fmt.Println("✓ Exported state carries over and the new cluster keeps accepting writes!")
+	fmt.Println()
+
+	for i := 0; i < freshNodes; i++ {
+		freshRafts[i].Kill()
+		close(freshApplyChs[i])
+	}
+
 	// Summary
 	fmt.Println("═══════════════════════════════════════════════════════════")
 	fmt.Println("DEMONSTRATION SUMMARY")
 	fmt.Println("═══════════════════════════════════════════════════════════")
 	fmt.Println("✓ Leader Election: Automatic election of a leader")
 	fmt.Println("✓ Log Replication: Commands replicated to all nodes")
+	fmt.Println("✓ Forwarding: Followers forward writes to the leader")
 	fmt.Println("✓ Fault Tolerance: Survived follower failure")
 	fmt.Println("✓ Leader Failure: Automatic failover and re-election")
 	fmt.Println("✓ Continued Operation: System works with 3/5 nodes (majority)")
+	fmt.Println("✓ Snapshots: State exported in full, then only what changed")
+	fmt.Println("✓ Export: Checksummed snapshot seeds a fresh cluster")
 	fmt.Println()
 	fmt.Println("Key Insights:")
 	fmt.Println("  • Raft requires (N/2 + 1) nodes for quorum (3/5 in this case)")
-	fmt.Println("  • Leader handles all writes, followers replicate")
+	fmt.Println("  • Leader handles all writes, followers replicate (and forward client writes)")
 	fmt.Println("  • Automatic failover when leader dies")
 	fmt.Println("  • Strong consistency: all alive nodes have same data")
 	fmt.Println()
@@ -235,8 +505,148 @@ func main() {
 	}
 }
 
+// options are the flags other than node timings
+type options struct {
+	adminAddr string
+	nodes     int
+	serve     bool
+}
+
+// parseFlags returns the node timings, from -config and then the timing
+// flags, and the other options
+func parseFlags() (Config, options, error) {
+	configPath := flag.String("config", "", "JSON file of node timings, e.g. {\"heartbeat_interval\": \"50ms\"}")
+	heartbeat := flag.Duration("heartbeat", 0, "Leader heartbeat interval (default 100ms)")
+	electionMin := flag.Duration("election-timeout-min", 0, "Shortest election timeout (default 300ms)")
+	electionMax := flag.Duration("election-timeout-max", 0, "Longest election timeout (default 600ms)")
+	adminAddr := flag.String("admin", "", "Address to serve the timing admin API on, e.g. 127.0.0.1:8090 (empty = off)")
+	nodes := flag.Int("nodes", 5, "Cluster size; the scripted demo needs 5")
+	serveFlag := flag.Bool("serve", false, "Skip the demo and keep the cluster running until interrupted")
+	flag.Parse()
+
+	opts := options{adminAddr: *adminAddr, nodes: *nodes, serve: *serveFlag}
+	if opts.nodes < 1 || (!opts.serve && opts.nodes != 5) {
+		return Config{}, opts, fmt.Errorf("-nodes %d: the demo runs 5 nodes; other sizes need -serve", opts.nodes)
+	}
+
+	config := DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if config, err = LoadConfig(*configPath, config); err != nil {
+			return config, opts, err
+		}
+	}
+	for _, override := range []struct {
+		value time.Duration
+		dst   *time.Duration
+	}{
+		{*heartbeat, &config.HeartbeatInterval},
+		{*electionMin, &config.ElectionTimeoutMin},
+		{*electionMax, &config.ElectionTimeoutMax},
+	} {
+		if override.value != 0 {
+			*override.dst = override.value
+		}
+	}
+	return config, opts, config.Validate()
+}
+
+// serve keeps the cluster running, for the lab or to explore it through the
+// admin API, and reports leader changes until interrupted
+func serve(rafts []*Raft) {
+	fmt.Printf("Serving %d-node cluster, Ctrl+C to stop\n", len(rafts))
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	leaderID := -1
+	for {
+		select {
+		case <-stop:
+			fmt.Println("Stopping cluster")
+			return
+		case <-ticker.C:
+			if id := findLeader(rafts); id != leaderID && id != -1 {
+				term, _ := rafts[id].GetState()
+				fmt.Printf("✓ Node %d leads in term %d\n", id, term)
+				leaderID = id
+			}
+		}
+	}
+}
+
+// downloadSnapshot saves an exported snapshot to path
+func downloadSnapshot(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("export returned %s", resp.Status)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	size, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Trailers are only populated once the body has been read
+	sum := resp.Trailer.Get("X-Snapshot-Sha256")
+	if sum == "" {
+		return fmt.Errorf("export ended without a checksum")
+	}
+	fmt.Printf("✓ Exported %d bytes at index %s, sha256 %s...\n",
+		size, resp.Trailer.Get("X-Snapshot-Index"), sum[:16])
+	return nil
+}
+
+// seedStore creates a store at path from a verified export
+func seedStore(path, backupPath string) (*DiskStore, error) {
+	backup, err := os.Open(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	snapshot, err := VerifyExport(backup)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+
+	store, err := OpenDiskStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Seed(snapshot); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func printReplicationStats(rf *Raft) {
+	for _, st := range rf.ReplicationStats() {
+		fmt.Printf("  Node %d: match=%d lag=%d inflight=%d backoff=%v last_contact=%v\n",
+			st.ID, st.MatchIndex, st.Lag, st.Inflight, st.Backoff, st.LastContact.Round(time.Millisecond))
+	}
+}
+
 func findLeader(rafts []*Raft) int {
 	for i, rf := range rafts {
+		// A killed leader still thinks it leads
+		if rf.dead {
+			continue
+		}
 		_, isLeader := rf.GetState()
 		if isLeader {
 			return i
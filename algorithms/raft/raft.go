@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -22,30 +23,39 @@ type Raft struct {
 
 	// Volatile state
 	state       ServerState
+	leaderID    int // Leader of currentTerm as far as we know, -1 if unknown
 	commitIndex int
 	lastApplied int
 
 	// Leader state (reinitialized after election)
 	nextIndex  []int
 	matchIndex []int
+	progress   []followerProgress
 
 	// Timing
+	config           Config
+	configChanged    chan struct{} // Wakes the heartbeat daemon to reset its ticker
 	electionTimeout  time.Duration
 	lastHeartbeat    time.Time
+	leaseUntil       time.Time // No votes before this: a leader's lease may still hold (see lease.go)
 	heartbeatTicker  *time.Ticker
 	electionTimer    *time.Timer
 }
 
-// NewRaft creates a new Raft instance
-func NewRaft(id int, peers []*Raft, applyCh chan ApplyMsg) *Raft {
+// NewRaft creates a new Raft instance with the given timings, which must
+// be valid (see Config.Validate)
+func NewRaft(id int, peers []*Raft, applyCh chan ApplyMsg, config Config) *Raft {
 	rf := &Raft{
 		id:           id,
+		config:       config,
+		configChanged: make(chan struct{}, 1),
 		peers:        peers,
 		applyCh:      applyCh,
 		currentTerm:  0,
 		votedFor:     -1,
 		log:          []LogEntry{{Term: 0, Index: 0}}, // Dummy entry at index 0
 		state:        Follower,
+		leaderID:     -1,
 		commitIndex:  0,
 		lastApplied:  0,
 		lastHeartbeat: time.Now(),
@@ -86,7 +96,7 @@ func (rf *Raft) Start(command interface{}) (int, int, bool) {
 	rf.mu.Lock()
 	defer rf.mu.Unlock()
 
-	if rf.state != Leader {
+	if rf.state != Leader || rf.dead {
 		return -1, rf.currentTerm, false
 	}
 
@@ -107,11 +117,96 @@ func (rf *Raft) Start(command interface{}) (int, int, bool) {
 	return index, term, true
 }
 
+// NotLeaderError is returned for a proposal that reached a node which is
+// not the leader and could not be forwarded to one
+type NotLeaderError struct {
+	LeaderHint int // Node believed to be leader, -1 if unknown
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.LeaderHint == -1 {
+		return "not leader: leader unknown"
+	}
+	return fmt.Sprintf("not leader: try node %d", e.LeaderHint)
+}
+
+// ErrProposalTimeout is returned when the leader does not answer a
+// forwarded proposal within ProposalTimeout
+var ErrProposalTimeout = errors.New("proposal forwarding timed out")
+
+// Propose submits a command on any node. The leader appends it directly;
+// a follower forwards it to the leader it last heard from. Forwarding is a
+// single hop, so a stale leader hint fails with a NotLeaderError instead of
+// bouncing between nodes.
+func (rf *Raft) Propose(command interface{}) (int, int, error) {
+	index, term, isLeader := rf.Start(command)
+	if isLeader {
+		return index, term, nil
+	}
+
+	rf.mu.Lock()
+	leader := rf.leaderID
+	rf.mu.Unlock()
+	if leader == -1 || leader == rf.id {
+		return -1, term, &NotLeaderError{LeaderHint: -1}
+	}
+
+	args := ProposeArgs{Command: command}
+	reply := ProposeReply{}
+	done := make(chan bool, 1)
+	go func() {
+		done <- rf.peers[leader].HandlePropose(&args, &reply)
+	}()
+
+	select {
+	case ok := <-done:
+		if !ok {
+			return -1, term, &NotLeaderError{LeaderHint: -1}
+		}
+		if !reply.IsLeader {
+			return -1, reply.Term, &NotLeaderError{LeaderHint: reply.LeaderHint}
+		}
+		fmt.Printf("[Node %d] Forwarded command: %v to leader Node %d (index %d)\n", rf.id, command, leader, reply.Index)
+		return reply.Index, reply.Term, nil
+	case <-time.After(ProposalTimeout):
+		return -1, term, ErrProposalTimeout
+	}
+}
+
+// Config returns the node's current timings
+func (rf *Raft) Config() Config {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.config
+}
+
+// SetConfig changes the node's timings while it runs. A new election
+// timeout is drawn from the new range straight away, and a leader's next
+// heartbeat follows the new interval.
+func (rf *Raft) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	rf.mu.Lock()
+	rf.config = config
+	rf.resetElectionTimeout()
+	rf.mu.Unlock()
+
+	select {
+	case rf.configChanged <- struct{}{}:
+	default: // A reset is already pending
+	}
+	fmt.Printf("[Node %d] Timing changed: heartbeat=%v election timeout=%v-%v\n",
+		rf.id, config.HeartbeatInterval, config.ElectionTimeoutMin, config.ElectionTimeoutMax)
+	return nil
+}
+
 // resetElectionTimeout resets the election timeout to a random value
 func (rf *Raft) resetElectionTimeout() {
-	min := int(ElectionTimeoutMin.Milliseconds())
-	max := int(ElectionTimeoutMax.Milliseconds())
-	timeout := time.Duration(min + rand.Intn(max-min)) * time.Millisecond
+	min := rf.config.ElectionTimeoutMin
+	max := rf.config.ElectionTimeoutMax
+	timeout := min + time.Duration(rand.Int63n(int64(max-min)))
 	rf.electionTimeout = timeout
 	rf.lastHeartbeat = time.Now()
 }
@@ -143,6 +238,7 @@ func (rf *Raft) startElection() {
 	rf.state = Candidate
 	rf.currentTerm++
 	rf.votedFor = rf.id
+	rf.leaderID = -1
 	rf.resetElectionTimeout()
 
 	currentTerm := rf.currentTerm
@@ -189,6 +285,7 @@ func (rf *Raft) startElection() {
 				rf.currentTerm = reply.Term
 				rf.state = Follower
 				rf.votedFor = -1
+				rf.leaderID = -1
 				return
 			}
 
@@ -212,27 +309,47 @@ func (rf *Raft) startElection() {
 // becomeLeader transitions the node to leader state
 func (rf *Raft) becomeLeader() {
 	rf.state = Leader
+	rf.leaderID = rf.id
 	fmt.Printf("[Node %d] Became LEADER for term %d\n", rf.id, rf.currentTerm)
 
 	// Initialize leader state
 	rf.nextIndex = make([]int, len(rf.peers))
 	rf.matchIndex = make([]int, len(rf.peers))
+	rf.progress = make([]followerProgress, len(rf.peers))
 	for i := range rf.peers {
 		rf.nextIndex[i] = len(rf.log)
 		rf.matchIndex[i] = 0
+		rf.progress[i].lastContact = time.Now()
 	}
 
+	// Append a no-op so entries from earlier terms commit without waiting
+	// for a client write, and the election shows up in the log
+	rf.log = append(rf.log, LogEntry{
+		Term:    rf.currentTerm,
+		Index:   len(rf.log),
+		Command: NoOp{},
+	})
+
 	// Send immediate heartbeat
 	go rf.replicateToAll()
 }
 
 // heartbeatDaemon sends periodic heartbeats when leader
 func (rf *Raft) heartbeatDaemon() {
-	ticker := time.NewTicker(HeartbeatInterval)
+	rf.mu.Lock()
+	ticker := time.NewTicker(rf.config.HeartbeatInterval)
+	rf.mu.Unlock()
 	defer ticker.Stop()
 
 	for {
-		<-ticker.C
+		select {
+		case <-ticker.C:
+		case <-rf.configChanged:
+			rf.mu.Lock()
+			ticker.Reset(rf.config.HeartbeatInterval)
+			rf.mu.Unlock()
+			continue
+		}
 
 		rf.mu.Lock()
 		if rf.dead {
@@ -249,7 +366,19 @@ func (rf *Raft) heartbeatDaemon() {
 	}
 }
 
-// replicateToAll sends AppendEntries to all peers
+// followerProgress is the leader's flow control state for one follower
+type followerProgress struct {
+	inflight    int           // AppendEntries calls awaiting a reply
+	backoff     time.Duration // 0 while the follower is reachable
+	retryAt     time.Time     // Nothing is sent before this while backing off
+	lastContact time.Time
+	ackedSent   time.Time // When the latest AppendEntries it answered was sent
+}
+
+// replicateToAll sends AppendEntries to all peers, skipping followers that
+// already have MaxInflightAppends calls outstanding or are backing off.
+// Without the limits every heartbeat and every Start would add another
+// goroutine blocked on a slow or dead follower.
 func (rf *Raft) replicateToAll() {
 	rf.mu.Lock()
 	defer rf.mu.Unlock()
@@ -258,19 +387,25 @@ func (rf *Raft) replicateToAll() {
 		return
 	}
 
+	now := time.Now()
 	for i := range rf.peers {
 		if i == rf.id {
 			continue
 		}
 
-		go rf.replicateToPeer(i)
+		progress := &rf.progress[i]
+		if progress.inflight >= MaxInflightAppends || now.Before(progress.retryAt) {
+			continue
+		}
+		progress.inflight++
+		go rf.replicateToPeer(i, rf.currentTerm)
 	}
 }
 
 // replicateToPeer sends AppendEntries to a specific peer
-func (rf *Raft) replicateToPeer(serverID int) {
+func (rf *Raft) replicateToPeer(serverID int, term int) {
 	rf.mu.Lock()
-	if rf.state != Leader || rf.dead {
+	if rf.state != Leader || rf.dead || rf.currentTerm != term {
 		rf.mu.Unlock()
 		return
 	}
@@ -291,14 +426,13 @@ func (rf *Raft) replicateToPeer(serverID int) {
 		PrevLogTerm:  prevLogTerm,
 		Entries:      entries,
 		LeaderCommit: rf.commitIndex,
+		LeaseDuration: rf.config.ElectionTimeoutMin,
 	}
 	rf.mu.Unlock()
 
 	reply := AppendEntriesReply{}
+	sent := time.Now()
 	ok := rf.peers[serverID].AppendEntries(&args, &reply)
-	if !ok {
-		return
-	}
 
 	rf.mu.Lock()
 	defer rf.mu.Unlock()
@@ -308,17 +442,48 @@ func (rf *Raft) replicateToPeer(serverID int) {
 		return
 	}
 
+	progress := &rf.progress[serverID]
+	progress.inflight--
+	if !ok {
+		// Unreachable: back off exponentially instead of retrying every heartbeat
+		if progress.backoff == 0 {
+			progress.backoff = rf.config.HeartbeatInterval
+			fmt.Printf("[Node %d] Node %d unreachable, backing off replication\n", rf.id, serverID)
+		} else {
+			progress.backoff *= 2
+			if progress.backoff > ReplicationBackoffMax {
+				progress.backoff = ReplicationBackoffMax
+			}
+		}
+		progress.retryAt = time.Now().Add(progress.backoff)
+		return
+	}
+	if progress.backoff != 0 {
+		fmt.Printf("[Node %d] Node %d reachable again, resuming replication\n", rf.id, serverID)
+		progress.backoff = 0
+		progress.retryAt = time.Time{}
+	}
+	progress.lastContact = time.Now()
+
 	// Update term if we're behind
 	if reply.Term > rf.currentTerm {
 		rf.currentTerm = reply.Term
 		rf.state = Follower
 		rf.votedFor = -1
+		rf.leaderID = -1
 		return
 	}
 
+	// The follower accepted this term's leader as of sent, whether or not
+	// its log matched
+	if sent.After(progress.ackedSent) {
+		progress.ackedSent = sent
+	}
+
 	if reply.Success {
-		// Update match and next indices
-		rf.matchIndex[serverID] = prevLogIndex + len(entries)
+		// Update match and next indices. With several calls in flight a
+		// reply may be older than one already handled.
+		rf.matchIndex[serverID] = max(rf.matchIndex[serverID], prevLogIndex+len(entries))
 		rf.nextIndex[serverID] = rf.matchIndex[serverID] + 1
 
 		// Check if we can commit more entries
@@ -344,12 +509,63 @@ func (rf *Raft) updateCommitIndex() {
 		}
 
 		if count > len(rf.peers)/2 {
+			if !rf.committedCurrentTerm() {
+				fmt.Printf("[Node %d] Committed first entry of term %d at index %d - leadership established\n", rf.id, rf.currentTerm, n)
+			}
 			rf.commitIndex = n
 			fmt.Printf("[Node %d] Committed entry at index %d: %v\n", rf.id, n, rf.log[n].Command)
 		}
 	}
 }
 
+// committedCurrentTerm reports whether an entry from the current term has
+// committed. Until it has, a new leader's commitIndex may lag entries that
+// an earlier leader already committed. Callers must hold rf.mu.
+func (rf *Raft) committedCurrentTerm() bool {
+	return rf.log[rf.commitIndex].Term == rf.currentTerm
+}
+
+// ReadIndex returns the commit index a linearizable read must wait to be
+// applied before it is served. It fails if this node is not the leader, or
+// is a leader that has not yet committed an entry from its own term.
+func (rf *Raft) ReadIndex() (int, bool) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.state != Leader || !rf.committedCurrentTerm() {
+		return -1, false
+	}
+	return rf.commitIndex, true
+}
+
+// ReplicationStats returns the leader's view of each follower, or nil if
+// this node is not the leader
+func (rf *Raft) ReplicationStats() []FollowerStats {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.state != Leader {
+		return nil
+	}
+
+	lastIndex := len(rf.log) - 1
+	stats := make([]FollowerStats, 0, len(rf.peers)-1)
+	for i := range rf.peers {
+		if i == rf.id {
+			continue
+		}
+		stats = append(stats, FollowerStats{
+			ID:          i,
+			MatchIndex:  rf.matchIndex[i],
+			Lag:         lastIndex - rf.matchIndex[i],
+			Inflight:    rf.progress[i].inflight,
+			Backoff:     rf.progress[i].backoff,
+			LastContact: time.Since(rf.progress[i].lastContact),
+		})
+	}
+	return stats
+}
+
 // applyDaemon applies committed entries to the state machine
 func (rf *Raft) applyDaemon() {
 	for {
@@ -366,8 +582,10 @@ func (rf *Raft) applyDaemon() {
 			rf.lastApplied++
 			entry := rf.log[rf.lastApplied]
 
+			// No-ops still advance CommandIndex, but carry nothing to apply
+			_, isNoOp := entry.Command.(NoOp)
 			msg := ApplyMsg{
-				CommandValid: true,
+				CommandValid: !isNoOp,
 				Command:      entry.Command,
 				CommandIndex: entry.Index,
 			}
@@ -389,11 +607,20 @@ func (rf *Raft) RequestVote(args *RequestVoteArgs, reply *RequestVoteReply) bool
 		return false
 	}
 
+	// A leader's lease may still hold: don't help elect another, or even
+	// adopt the candidate's term (see lease.go)
+	if args.Term > rf.currentTerm && rf.leaseHeld(time.Now()) {
+		reply.Term = rf.currentTerm
+		reply.VoteGranted = false
+		return true
+	}
+
 	// Update term if we're behind
 	if args.Term > rf.currentTerm {
 		rf.currentTerm = args.Term
 		rf.state = Follower
 		rf.votedFor = -1
+		rf.leaderID = -1
 	}
 
 	reply.Term = rf.currentTerm
@@ -426,6 +653,24 @@ func (rf *Raft) RequestVote(args *RequestVoteArgs, reply *RequestVoteReply) bool
 	return true
 }
 
+// HandlePropose handles a proposal forwarded by a follower
+func (rf *Raft) HandlePropose(args *ProposeArgs, reply *ProposeReply) bool {
+	rf.mu.Lock()
+	if rf.dead {
+		rf.mu.Unlock()
+		return false
+	}
+	rf.mu.Unlock()
+
+	reply.Index, reply.Term, reply.IsLeader = rf.Start(args.Command)
+	if !reply.IsLeader {
+		rf.mu.Lock()
+		reply.LeaderHint = rf.leaderID
+		rf.mu.Unlock()
+	}
+	return true
+}
+
 // AppendEntries handles AppendEntries RPC (heartbeat and log replication)
 func (rf *Raft) AppendEntries(args *AppendEntriesArgs, reply *AppendEntriesReply) bool {
 	rf.mu.Lock()
@@ -440,6 +685,7 @@ func (rf *Raft) AppendEntries(args *AppendEntriesArgs, reply *AppendEntriesReply
 		rf.currentTerm = args.Term
 		rf.state = Follower
 		rf.votedFor = -1
+		rf.leaderID = -1
 	}
 
 	reply.Term = rf.currentTerm
@@ -453,6 +699,8 @@ func (rf *Raft) AppendEntries(args *AppendEntriesArgs, reply *AppendEntriesReply
 	// Reset election timeout (we heard from leader)
 	rf.resetElectionTimeout()
 	rf.state = Follower
+	rf.leaderID = args.LeaderID
+	rf.leaseUntil = time.Now().Add(args.LeaseDuration)
 
 	// Check if log contains entry at prevLogIndex with matching term
 	if args.PrevLogIndex >= len(rf.log) || rf.log[args.PrevLogIndex].Term != args.PrevLogTerm {
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCluster is a cluster of in-process nodes, each applying committed
+// entries to a KV store of its own, as the demo runs them.
+type testCluster struct {
+	t      *testing.T
+	rafts  []*Raft
+	stores []*KVStore
+}
+
+func newTestCluster(t *testing.T, n int, config Config) *testCluster {
+	t.Helper()
+	dir := t.TempDir()
+	c := &testCluster{t: t, rafts: make([]*Raft, n), stores: make([]*KVStore, n)}
+	for i := range c.rafts {
+		applyCh := make(chan ApplyMsg, 100)
+		store := openStore(t, filepath.Join(dir, fmt.Sprintf("node-%d.db", i)))
+		c.rafts[i] = NewRaft(i, c.rafts, applyCh, config)
+		c.stores[i] = NewKVStore(c.rafts[i], store)
+		go func(kv *KVStore) {
+			for msg := range applyCh {
+				kv.Apply(msg)
+			}
+		}(c.stores[i])
+	}
+	t.Cleanup(func() {
+		for _, rf := range c.rafts {
+			rf.Kill()
+		}
+	})
+	return c
+}
+
+// leader waits for a leader whose followers all know it, and returns it.
+func (c *testCluster) leader() int {
+	c.t.Helper()
+	leader := -1
+	waitFor(c.t, "a leader known to every node", 5*time.Second, func() bool {
+		leader = findLeader(c.rafts)
+		if leader == -1 {
+			return false
+		}
+		for _, rf := range c.rafts {
+			if rf.knownLeader() != leader {
+				return false
+			}
+		}
+		return true
+	})
+	return leader
+}
+
+// knownLeader returns the leader the node last heard from.
+func (rf *Raft) knownLeader() int {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.leaderID
+}
+
+// setKnownLeader makes the node believe node leads, until it next hears
+// from the actual leader.
+func (rf *Raft) setKnownLeader(node int) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.leaderID = node
+}
+
+// waitFor polls cond until it holds or timeout passes.
+func waitFor(t *testing.T, what string, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestPropose_FollowerForwards verifies a write submitted to a follower is
+// forwarded to the leader and applied by every node.
+func TestPropose_FollowerForwards(t *testing.T) {
+	c := newTestCluster(t, 3, DefaultConfig())
+	leader := c.leader()
+	follower := (leader + 1) % 3
+
+	index, term, err := c.rafts[follower].Propose(KVCommand{Op: "put", Key: "city", Value: "Seattle"})
+	if err != nil {
+		t.Fatalf("Forwarded proposal failed: %v", err)
+	}
+	if leaderTerm, _ := c.rafts[leader].GetState(); index < 1 || term != leaderTerm {
+		t.Errorf("Expected an index in the leader's term %d, got index %d term %d", leaderTerm, index, term)
+	}
+	for i, kv := range c.stores {
+		waitFor(t, fmt.Sprintf("node %d to apply the write", i), 2*time.Second, func() bool {
+			value, ok := kv.Get("city")
+			return ok && value == "Seattle"
+		})
+	}
+
+	// The leader takes writes itself
+	if _, _, err := c.rafts[leader].Propose(KVCommand{Op: "delete", Key: "city"}); err != nil {
+		t.Fatalf("Proposal to the leader failed: %v", err)
+	}
+	waitFor(t, "the delete", 2*time.Second, func() bool {
+		_, ok := c.stores[follower].Get("city")
+		return !ok
+	})
+}
+
+// TestPropose_NotLeaderHints verifies a proposal that can't reach the
+// leader in one hop fails with a NotLeaderError naming the leader, if the
+// node it reached knows one.
+func TestPropose_NotLeaderHints(t *testing.T) {
+	c := newTestCluster(t, 3, DefaultConfig())
+	leader := c.leader()
+	follower, other := (leader+1)%3, (leader+2)%3
+	cmd := KVCommand{Op: "put", Key: "k", Value: "v"}
+
+	// A follower asked directly names the leader
+	var reply ProposeReply
+	if ok := c.rafts[follower].HandlePropose(&ProposeArgs{Command: cmd}, &reply); !ok || reply.IsLeader || reply.LeaderHint != leader {
+		t.Fatalf("Expected follower %d to refuse naming leader %d, got %+v", follower, leader, reply)
+	}
+
+	// A stale hint is not followed further: the error names the leader
+	c.rafts[follower].setKnownLeader(other)
+	_, _, err := c.rafts[follower].Propose(cmd)
+	var notLeader *NotLeaderError
+	if !errors.As(err, &notLeader) || notLeader.LeaderHint != leader {
+		t.Fatalf("Expected a NotLeaderError naming node %d, got %v", leader, err)
+	}
+	if want := fmt.Sprintf("not leader: try node %d", leader); err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	// Without a leader to forward to there is no hint
+	c.rafts[follower].setKnownLeader(-1)
+	if _, _, err := c.rafts[follower].Propose(cmd); !errors.As(err, &notLeader) || notLeader.LeaderHint != -1 || err.Error() != "not leader: leader unknown" {
+		t.Errorf("Expected a NotLeaderError without a hint, got %v", err)
+	}
+
+	// A dead leader can't be forwarded to either
+	c.rafts[leader].Kill()
+	c.rafts[follower].setKnownLeader(leader)
+	if _, _, err := c.rafts[follower].Propose(cmd); !errors.As(err, &notLeader) || notLeader.LeaderHint != -1 {
+		t.Errorf("Expected a NotLeaderError forwarding to a dead leader, got %v", err)
+	}
+}
+
+// TestPropose_ForwardTimeout verifies a forwarded proposal the leader
+// doesn't answer within ProposalTimeout fails with ErrProposalTimeout.
+func TestPropose_ForwardTimeout(t *testing.T) {
+	c := newTestCluster(t, 3, DefaultConfig())
+	leader := c.leader()
+	follower := (leader + 1) % 3
+
+	// A leader stuck holding its lock answers nothing
+	c.rafts[leader].mu.Lock()
+	start := time.Now()
+	_, _, err := c.rafts[follower].Propose(KVCommand{Op: "put", Key: "k", Value: "v"})
+	elapsed := time.Since(start)
+	c.rafts[leader].mu.Unlock()
+
+	if !errors.Is(err, ErrProposalTimeout) {
+		t.Fatalf("Expected ErrProposalTimeout, got %v", err)
+	}
+	if elapsed < ProposalTimeout {
+		t.Errorf("Gave up after %v, before ProposalTimeout", elapsed)
+	}
+}
+
+// fastConfig replicates every 20ms, so flow control shows up quickly.
+func fastConfig() Config {
+	return Config{HeartbeatInterval: 20 * time.Millisecond, ElectionTimeoutMin: 100 * time.Millisecond, ElectionTimeoutMax: 200 * time.Millisecond}
+}
+
+// statsOf returns the leader's view of one follower.
+func statsOf(leader *Raft, follower int) FollowerStats {
+	for _, stats := range leader.ReplicationStats() {
+		if stats.ID == follower {
+			return stats
+		}
+	}
+	return FollowerStats{ID: follower, Inflight: -1}
+}
+
+// TestReplication_InflightLimit verifies a follower that stops answering
+// never has more than MaxInflightAppends calls outstanding, while the rest
+// of the cluster commits without it, and catches up once it answers.
+func TestReplication_InflightLimit(t *testing.T) {
+	c := newTestCluster(t, 3, fastConfig())
+	leader := c.leader()
+	slow := (leader + 1) % 3
+
+	// A follower stuck holding its lock answers nothing
+	c.rafts[slow].mu.Lock()
+	unlock := sync.OnceFunc(c.rafts[slow].mu.Unlock)
+	t.Cleanup(unlock) // Before the nodes are killed
+	for i := 0; i < 10; i++ {
+		c.rafts[leader].Start(KVCommand{Op: "put", Key: fmt.Sprintf("k%d", i), Value: "v"})
+		time.Sleep(10 * time.Millisecond) // Heartbeats keep coming too
+		if stats := statsOf(c.rafts[leader], slow); stats.Inflight > MaxInflightAppends {
+			t.Errorf("Expected at most %d calls in flight to the slow follower, got %d", MaxInflightAppends, stats.Inflight)
+		}
+	}
+	stats := statsOf(c.rafts[leader], slow)
+	if stats.Inflight != MaxInflightAppends || stats.Lag == 0 {
+		t.Errorf("Expected %d calls in flight and a lag, got %+v", MaxInflightAppends, stats)
+	}
+	waitFor(t, "a majority to apply without the slow follower", 2*time.Second, func() bool {
+		_, ok := c.stores[(leader+2)%3].Get("k9")
+		return ok
+	})
+	unlock()
+
+	waitFor(t, "the slow follower to catch up", 2*time.Second, func() bool {
+		stats := statsOf(c.rafts[leader], slow)
+		return stats.Lag == 0 && stats.Inflight == 0
+	})
+}
+
+// TestReplication_Backoff verifies an unreachable follower is retried with
+// a growing backoff, capped at ReplicationBackoffMax, which ends as soon as
+// it answers again.
+func TestReplication_Backoff(t *testing.T) {
+	config := fastConfig()
+	c := newTestCluster(t, 3, config)
+	leader := c.leader()
+	down := (leader + 1) % 3
+
+	c.rafts[down].Kill()
+	waitFor(t, "the backoff to double twice", 2*time.Second, func() bool {
+		return statsOf(c.rafts[leader], down).Backoff >= 4*config.HeartbeatInterval
+	})
+	if stats := statsOf(c.rafts[leader], down); stats.Backoff > ReplicationBackoffMax || stats.Inflight > MaxInflightAppends {
+		t.Errorf("Expected the backoff capped at %v, got %+v", ReplicationBackoffMax, stats)
+	}
+	c.rafts[leader].Start(KVCommand{Op: "put", Key: "k", Value: "v"})
+
+	// Back up (its own timers stay stopped): the next retry gets through
+	c.rafts[down].mu.Lock()
+	c.rafts[down].dead = false
+	c.rafts[down].mu.Unlock()
+	waitFor(t, "replication to resume", ReplicationBackoffMax+time.Second, func() bool {
+		stats := statsOf(c.rafts[leader], down)
+		return stats.Backoff == 0 && stats.Lag == 0
+	})
+}
@@ -31,6 +31,15 @@ type LogEntry struct {
 	Command interface{}
 }
 
+// NoOp is the command a new leader appends as soon as it is elected.
+//
+// A leader may only count replicas for entries from its own term (Figure 8
+// in the Raft paper), so entries left over from earlier terms stay
+// uncommitted until something new is appended. The no-op commits them
+// without waiting for a client write, and its commit marks the point from
+// which the leader's commitIndex is known to be current.
+type NoOp struct{}
+
 // RequestVoteArgs is the RPC request for voting
 type RequestVoteArgs struct {
 	Term         int
@@ -53,6 +62,10 @@ type AppendEntriesArgs struct {
 	PrevLogTerm  int
 	Entries      []LogEntry
 	LeaderCommit int
+
+	// How long the follower must refuse votes after receiving this: the
+	// leader's ElectionTimeoutMin (see lease.go)
+	LeaseDuration time.Duration
 }
 
 // AppendEntriesReply is the RPC response for log replication
@@ -61,6 +74,29 @@ type AppendEntriesReply struct {
 	Success bool
 }
 
+// ProposeArgs is the RPC request a follower forwards a client command with
+type ProposeArgs struct {
+	Command interface{}
+}
+
+// ProposeReply is the RPC response to a forwarded command
+type ProposeReply struct {
+	Index      int
+	Term       int
+	IsLeader   bool
+	LeaderHint int // Set when IsLeader is false; -1 if unknown
+}
+
+// FollowerStats is the leader's view of one follower's replication
+type FollowerStats struct {
+	ID          int
+	MatchIndex  int
+	Lag         int           // Entries the follower is known to be missing
+	Inflight    int           // AppendEntries calls awaiting a reply
+	Backoff     time.Duration // 0 while reachable
+	LastContact time.Duration // Time since the last reply
+}
+
 // ApplyMsg represents a message to apply to the state machine
 type ApplyMsg struct {
 	CommandValid bool
@@ -68,9 +104,11 @@ type ApplyMsg struct {
 	CommandIndex int
 }
 
-// Config for timing (in milliseconds)
+// Fixed timing; heartbeat and election timeouts are per node (see Config)
 const (
-	HeartbeatInterval = 100 * time.Millisecond
-	ElectionTimeoutMin = 300 * time.Millisecond
-	ElectionTimeoutMax = 600 * time.Millisecond
+	ProposalTimeout = 500 * time.Millisecond
+
+	// Flow control per follower; backoff starts at one heartbeat interval
+	MaxInflightAppends    = 2
+	ReplicationBackoffMax = 2 * time.Second
 )
@@ -0,0 +1,35 @@
+package main
+
+import "io"
+
+// StateMachine is the application Raft replicates commands into
+type StateMachine interface {
+	// Apply applies a committed entry
+	Apply(msg ApplyMsg)
+
+	// LastApplied returns the index of the last entry that changed state
+	LastApplied() int
+
+	// Snapshot streams the state changed after index since (0 for a full
+	// snapshot) up to LastApplied, and returns the index it was taken at.
+	// It must not need a copy of the whole state in memory.
+	Snapshot(w io.Writer, since int) (int, error)
+
+	// Restore loads a snapshot written by Snapshot. A full snapshot replaces
+	// the state; an incremental one must start at LastApplied.
+	Restore(r io.Reader) error
+}
+
+// SnapshotHeader starts every snapshot stream
+type SnapshotHeader struct {
+	Since int // 0 for a full snapshot
+	Index int // Last index included
+}
+
+// SnapshotRecord is one key in a snapshot stream, in key order
+type SnapshotRecord struct {
+	Key     string
+	Value   string
+	Deleted bool // Only in incremental snapshots
+	Index   int  // Index of the entry that last changed the key
+}
@@ -0,0 +1,199 @@
+// Command lab runs the whole system-design lab with one command:
+//
+//	go run ./cmd/lab
+//
+// It builds each project from its own module and runs it as a child
+// process, configured to work with the others:
+//
+//	redis    embedded (miniredis) unless -redis names a real one
+//	backend  rate-limiter/backend on :8081
+//	gateway  rate-limiter/gateway on :8080, limiting in Redis, proxying to the
+//	         backend, or with -front engine to the matching engine
+//	engine   order-matching-engine on -engine-port, sharing reference prices in Redis
+//	raft     algorithms/raft, a -raft-nodes cluster with its admin API on -raft-admin
+//
+// Every line a service prints is prefixed with its name. Ctrl+C stops them
+// all, the last started first; so does any one of them exiting.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// The rate limiter's ports are fixed in its programs.
+const (
+	gatewayAddr = "localhost:8080"
+	backendAddr = "localhost:8081"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("lab: ")
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	root := flag.String("root", ".", "Repository root, holding order-matching-engine, rate-limiter and algorithms")
+	redisAddr := flag.String("redis", "embedded", "Redis address, or embedded to run miniredis in the lab")
+	enginePort := flag.Int("engine-port", 8090, "Matching engine HTTP port")
+	raftAdmin := flag.String("raft-admin", "localhost:8093", "Raft cluster admin API address (timings and leases)")
+	raftNodes := flag.Int("raft-nodes", 3, "Raft cluster size")
+	dataDir := flag.String("data", "", "Directory for the engine's logs (default: a temporary one, removed on exit)")
+	front := flag.String("front", "backend", "What the gateway rate limits and proxies to: backend, or engine")
+	flag.Parse()
+
+	if *front != "backend" && *front != "engine" {
+		return fmt.Errorf("invalid -front %q: must be backend or engine", *front)
+	}
+
+	for _, dir := range []string{"order-matching-engine", "rate-limiter", "algorithms/raft"} {
+		if _, err := os.Stat(filepath.Join(*root, dir)); err != nil {
+			return fmt.Errorf("%s not found under -root %s; run from the repository root", dir, *root)
+		}
+	}
+
+	workDir := *dataDir
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "system-design-lab-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		workDir = dir
+	} else if err := os.MkdirAll(workDir, 0o755); err != nil {
+		return err
+	}
+	binDir := filepath.Join(workDir, "bin")
+
+	if *redisAddr == "embedded" {
+		mr, err := miniredis.Run()
+		if err != nil {
+			return fmt.Errorf("starting embedded Redis: %w", err)
+		}
+		defer mr.Close()
+		*redisAddr = mr.Addr()
+		log.Printf("embedded Redis on %s", *redisAddr)
+	}
+
+	engineAddr := net.JoinHostPort("localhost", strconv.Itoa(*enginePort))
+	upstream := backendAddr
+	if *front == "engine" {
+		upstream = engineAddr
+	}
+	services := []*service{
+		{
+			name: "backend",
+			dir:  "rate-limiter/backend",
+			pkg:  ".",
+			addr: backendAddr,
+		},
+		{
+			name: "gateway",
+			dir:  "rate-limiter/gateway",
+			pkg:  ".",
+			env: []string{
+				"REDIS_MODE=standalone",
+				"REDIS_ADDR=" + *redisAddr,
+				"BACKEND_URL=http://" + upstream,
+				"GATEWAY_ID=lab-gateway",
+			},
+			addr: gatewayAddr,
+		},
+		{
+			name: "engine",
+			dir:  "order-matching-engine",
+			pkg:  "./cmd/server",
+			args: []string{
+				"-port", strconv.Itoa(*enginePort),
+				"-refshare-redis", *redisAddr,
+				"-shard-id", "lab-engine",
+			},
+			addr: engineAddr,
+		},
+		{
+			name: "raft",
+			dir:  "algorithms/raft",
+			pkg:  ".",
+			args: []string{"-serve", "-nodes", strconv.Itoa(*raftNodes), "-admin", *raftAdmin},
+			addr: *raftAdmin,
+		},
+	}
+
+	out := &output{}
+	for _, s := range services {
+		if len(s.name) > out.width {
+			out.width = len(s.name)
+		}
+	}
+
+	for _, s := range services {
+		log.Printf("building %s", s.name)
+		if err := s.build(*root, binDir); err != nil {
+			return err
+		}
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	// exited fires when any service exits on its own
+	exited := make(chan *service, len(services))
+	var started []*service
+	shutdown := func() {
+		for i := len(started) - 1; i >= 0; i-- {
+			started[i].stop()
+		}
+	}
+
+	for _, s := range services {
+		if err := s.start(binDir, workDir, out); err != nil {
+			s.stop()
+			shutdown()
+			return err
+		}
+		started = append(started, s)
+		go func(s *service) {
+			<-s.done
+			exited <- s
+		}(s)
+	}
+
+	out.printf("\n%s\n", banner(*front, *redisAddr, engineAddr, *raftAdmin, workDir))
+
+	select {
+	case <-stop:
+		log.Printf("stopping")
+	case s := <-exited:
+		log.Printf("%s exited (%v), stopping the rest", s.name, s.err)
+	}
+	shutdown()
+	return nil
+}
+
+// banner lists where everything runs once it is up.
+func banner(front, redisAddr, engineAddr, raftAdmin, workDir string) string {
+	try := "curl http://" + gatewayAddr + "/api/resource"
+	if front == "engine" {
+		try = "curl http://" + gatewayAddr + "/latency-report (after POST /order through it)"
+	}
+	return fmt.Sprintf(`System design lab running (Ctrl+C to stop)
+  Gateway:  http://%s     %s
+  Backend:  http://%s
+  Engine:   http://%s     curl http://%s/health
+  Raft:     http://%s     curl http://%s/lease
+  Redis:    %s
+  Data:     %s
+`, gatewayAddr, try, backendAddr, engineAddr, engineAddr, raftAdmin, raftAdmin, redisAddr, workDir)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// service is one program of the lab, built from its own module and run as
+// a child process.
+type service struct {
+	name string
+	dir  string // Module directory, relative to the repository root
+	pkg  string // Package to build, relative to dir
+	args []string
+	env  []string
+
+	// addr accepts connections once the service is up
+	addr string
+
+	cmd  *exec.Cmd
+	done chan struct{} // Closed when the process has exited
+	err  error         // Exit status, set before done closes
+}
+
+// ReadyTimeout is how long a started service has to accept connections.
+const ReadyTimeout = 30 * time.Second
+
+// StopTimeout is how long a service has to exit after SIGTERM before it is
+// killed.
+const StopTimeout = 5 * time.Second
+
+// build compiles the service into binDir.
+func (s *service) build(root, binDir string) error {
+	cmd := exec.Command("go", "build", "-o", filepath.Join(binDir, s.name), s.pkg)
+	cmd.Dir = filepath.Join(root, s.dir)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("building %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// start runs the built service in workDir, copying its output to out with
+// every line prefixed by its name, and waits until it accepts connections.
+func (s *service) start(binDir, workDir string, out *output) error {
+	s.cmd = exec.Command(filepath.Join(binDir, s.name), s.args...)
+	s.cmd.Dir = workDir
+	s.cmd.Env = append(os.Environ(), s.env...)
+
+	pipe, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	s.cmd.Stderr = s.cmd.Stdout
+	if err := s.cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", s.name, err)
+	}
+
+	s.done = make(chan struct{})
+	go func() {
+		out.copy(s.name, pipe)
+		s.err = s.cmd.Wait()
+		close(s.done)
+	}()
+
+	deadline := time.Now().Add(ReadyTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", s.addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-s.done:
+			return fmt.Errorf("%s exited during startup: %v", s.name, s.err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not accepting connections on %s after %v", s.name, s.addr, ReadyTimeout)
+		}
+	}
+}
+
+// stop asks a running service to exit and kills it if it doesn't in time.
+func (s *service) stop() {
+	if s.cmd == nil || s.done == nil {
+		return
+	}
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		s.cmd.Process.Kill()
+	}
+	select {
+	case <-s.done:
+	case <-time.After(StopTimeout):
+		s.cmd.Process.Kill()
+		<-s.done
+	}
+}
+
+// output interleaves the lines of all services on stdout.
+type output struct {
+	mu    sync.Mutex
+	width int // Longest service name, to align the prefixes
+}
+
+func (o *output) copy(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		o.printf("%-*s | %s\n", o.width, name, scanner.Text())
+	}
+}
+
+func (o *output) printf(format string, args ...interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Printf(format, args...)
+}
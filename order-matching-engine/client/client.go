@@ -0,0 +1,453 @@
+// Package client is a Go SDK for the order matching engine HTTP API.
+//
+// Backpressure Handling:
+//
+// When the engine's ring buffer is full, the sequencer rejects the claim and
+// the server answers 503 "server busy". The order never entered the ring
+// buffer, so it is always safe to resubmit. The SDK does this automatically:
+//
+//  1. Exponential backoff with full jitter between attempts, so a fleet of
+//     clients doesn't retry in lockstep and re-overload the engine
+//  2. A retry budget shared by all calls on a Client, so retries stay a
+//     bounded fraction of traffic instead of multiplying load during an outage
+//  3. A backpressure hook invoked on every 503, so callers can shed load,
+//     alert, or slow their own submission rate
+//
+// Example:
+//
+//	c := client.New("http://localhost:8080",
+//	    client.WithBackpressureHook(func(ev client.BackpressureEvent) {
+//	        if ev.Consecutive >= 10 {
+//	            log.Printf("engine saturated: %d consecutive 503s", ev.Consecutive)
+//	        }
+//	    }))
+//	resp, err := c.SubmitOrder(ctx, client.OrderRequest{...})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerBusy is returned when the engine kept answering 503 until the
+// retry policy gave up.
+var ErrServerBusy = errors.New("server busy")
+
+// ErrRetryBudgetExhausted is returned when a 503 could not be retried
+// because the client's retry budget is empty.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// OrderRequest is an order submission.
+type OrderRequest struct {
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`  // "buy" or "sell"
+	Type          string `json:"type"`  // "market", "limit", "ioc", "fok"
+	Price         string `json:"price"` // Dollar amount as string
+	Quantity      int64  `json:"quantity"`
+	AccountID     string `json:"account_id"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	DisplayQty    int64  `json:"display_qty,omitempty"` // Iceberg: shares shown at a time
+	Peg           string `json:"peg,omitempty"`         // "midpoint" or "primary"; Price is then an optional cap
+}
+
+// ReplaceRequest changes the price and/or total quantity of a resting order.
+// Side and AccountID must match the resting order.
+type ReplaceRequest struct {
+	Symbol    string `json:"symbol"`
+	OrderID   uint64 `json:"order_id"`
+	Side      string `json:"side"`
+	AccountID string `json:"account_id"`
+	Price     string `json:"price"`    // New limit price
+	Quantity  int64  `json:"quantity"` // New total quantity, including any already filled
+}
+
+// OrderResponse is the engine's answer to an order submission.
+type OrderResponse struct {
+	Success      bool       `json:"success"`
+	OrderID      uint64     `json:"order_id,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	FilledQty    int64      `json:"filled_qty,omitempty"`
+	RemainingQty int64      `json:"remaining_qty,omitempty"`
+	CumQty       int64      `json:"cum_qty"`
+	AvgPrice     string     `json:"avg_price,omitempty"`
+	LeavesQty    int64      `json:"leaves_qty"`
+	Fills        []FillInfo `json:"fills,omitempty"`
+	RejectReason string     `json:"reject_reason,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// FillInfo is a single execution in an OrderResponse.
+type FillInfo struct {
+	TradeID  uint64 `json:"trade_id"`
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+}
+
+// PreviewResponse is what an order would do against the book now, from
+// PreviewOrder. Status is what the order would end as; nothing was placed.
+type PreviewResponse struct {
+	Success      bool          `json:"success"`
+	Symbol       string        `json:"symbol,omitempty"`
+	Side         string        `json:"side,omitempty"`
+	Quantity     int64         `json:"quantity,omitempty"`
+	Status       string        `json:"status,omitempty"`
+	FilledQty    int64         `json:"filled_qty"`
+	LeavesQty    int64         `json:"leaves_qty"` // Would rest in the book
+	AvgPrice     string        `json:"avg_price,omitempty"`
+	BestPrice    string        `json:"best_price,omitempty"` // Best opposite price now
+	Slippage     string        `json:"slippage,omitempty"`   // Per share, against the order
+	SlippageBps  float64       `json:"slippage_bps,omitempty"`
+	Notional     string        `json:"notional,omitempty"`
+	Fills        []PreviewFill `json:"fills,omitempty"`
+	RejectCode   string        `json:"reject_code,omitempty"`
+	RejectReason string        `json:"reject_reason,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// PreviewFill is one fill an order would get.
+type PreviewFill struct {
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+}
+
+// ReplaceResponse is the engine's answer to a replace request.
+type ReplaceResponse struct {
+	OrderResponse
+	PriorityKept bool `json:"priority_kept"` // False if the order was re-queued
+}
+
+// CancelResponse is the engine's answer to a cancel request.
+type CancelResponse struct {
+	Success      bool   `json:"success"`
+	OrderID      uint64 `json:"order_id,omitempty"`
+	CancelledQty int64  `json:"cancelled_qty,omitempty"`
+	CumQty       int64  `json:"cum_qty"`
+	AvgPrice     string `json:"avg_price,omitempty"`
+	LeavesQty    int64  `json:"leaves_qty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// OrderInfo is the state of one order: one of an account's resting orders,
+// or the result of a status lookup.
+type OrderInfo struct {
+	OrderID       uint64 `json:"order_id"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	Price         string `json:"price"`
+	Quantity      int64  `json:"quantity"`
+	CumQty        int64  `json:"cum_qty"`
+	AvgPrice      string `json:"avg_price,omitempty"`
+	LeavesQty     int64  `json:"leaves_qty"`
+	DisplayQty    int64  `json:"display_qty,omitempty"`
+	Peg           string `json:"peg,omitempty"`
+	PegLimit      string `json:"peg_limit,omitempty"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// OpenOrdersResponse is the engine's answer to an open-orders query.
+type OpenOrdersResponse struct {
+	Account string      `json:"account"`
+	Orders  []OrderInfo `json:"orders"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// BookLevel is one price level of a book snapshot.
+type BookLevel struct {
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+	Orders   int    `json:"orders"`
+}
+
+// BookResponse is a snapshot of the top levels of a symbol's book.
+type BookResponse struct {
+	Symbol string      `json:"symbol"`
+	Bids   []BookLevel `json:"bids"` // Best first
+	Asks   []BookLevel `json:"asks"` // Best first
+	Spread string      `json:"spread"`
+	Mid    string      `json:"mid"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Client talks to the order matching engine.
+// It is safe for concurrent use; the retry budget is shared across calls.
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	retry          RetryPolicy
+	budget         *RetryBudget
+	onBackpressure func(BackpressureEvent)
+	consecutive    int64 // Consecutive 503s across all calls (atomic)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetryPolicy sets the backoff policy for 503 responses.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithRetryBudget sets the retry budget. Pass nil to disable budgeting.
+func WithRetryBudget(b *RetryBudget) Option {
+	return func(c *Client) { c.budget = b }
+}
+
+// WithBackpressureHook registers a callback invoked on every 503 response.
+// The hook runs on the calling goroutine and must not block.
+func WithBackpressureHook(fn func(BackpressureEvent)) Option {
+	return func(c *Client) { c.onBackpressure = fn }
+}
+
+// New creates a client for the engine at baseURL (e.g. "http://localhost:8080").
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		retry:      DefaultRetryPolicy(),
+		budget:     NewRetryBudget(0.1, 10, 100),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SubmitOrder submits a new order.
+//
+// Rejections (risk, validation) are returned as a response with
+// Success=false and a nil error; errors are reserved for transport failures
+// and exhausted backpressure retries.
+func (c *Client) SubmitOrder(ctx context.Context, req OrderRequest) (*OrderResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp OrderResponse
+	if err := c.do(ctx, http.MethodPost, "/order", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PreviewOrder asks what an order would do against the book now -
+// fills, average price and slippage - without placing it. Rejections are
+// returned as a response with Success=false, as for SubmitOrder.
+func (c *Client) PreviewOrder(ctx context.Context, req OrderRequest) (*PreviewResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp PreviewResponse
+	if err := c.do(ctx, http.MethodPost, "/order/preview", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelOrder cancels a resting order.
+func (c *Client) CancelOrder(ctx context.Context, symbol string, orderID uint64) (*CancelResponse, error) {
+	q := url.Values{}
+	q.Set("symbol", symbol)
+	q.Set("order_id", strconv.FormatUint(orderID, 10))
+
+	var resp CancelResponse
+	if err := c.do(ctx, http.MethodDelete, "/cancel?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReplaceOrder changes a resting order's price and/or quantity.
+// A quantity reduction at the same price keeps time priority.
+func (c *Client) ReplaceOrder(ctx context.Context, req ReplaceRequest) (*ReplaceResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ReplaceResponse
+	if err := c.do(ctx, http.MethodPost, "/order/replace", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// OpenOrders lists an account's resting orders, e.g. to recover order IDs
+// after a reconnect. An empty symbol lists every symbol.
+func (c *Client) OpenOrders(ctx context.Context, account, symbol string) (*OpenOrdersResponse, error) {
+	q := url.Values{}
+	q.Set("account", account)
+	if symbol != "" {
+		q.Set("symbol", symbol)
+	}
+
+	var resp OpenOrdersResponse
+	if err := c.do(ctx, http.MethodGet, "/orders?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// OrderStatus looks up an order by ID, live or recently completed.
+// Orders the engine does not know (never placed, or completed and since
+// evicted from its history) are returned as an error.
+func (c *Client) OrderStatus(ctx context.Context, orderID uint64) (*OrderInfo, error) {
+	q := url.Values{}
+	q.Set("id", strconv.FormatUint(orderID, 10))
+	return c.orderStatus(ctx, q)
+}
+
+// ClientOrderStatus looks up the latest order an account placed with the
+// given client order ID, live or recently completed.
+func (c *Client) ClientOrderStatus(ctx context.Context, account, clientOrderID string) (*OrderInfo, error) {
+	q := url.Values{}
+	q.Set("account", account)
+	q.Set("client_order_id", clientOrderID)
+	return c.orderStatus(ctx, q)
+}
+
+func (c *Client) orderStatus(ctx context.Context, q url.Values) (*OrderInfo, error) {
+	var resp struct {
+		OrderInfo
+		Error string `json:"error,omitempty"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/order?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp.OrderInfo, nil
+}
+
+// Book returns the top levels of a symbol's book.
+func (c *Client) Book(ctx context.Context, symbol string, levels int) (*BookResponse, error) {
+	q := url.Values{}
+	q.Set("symbol", symbol)
+	q.Set("levels", strconv.Itoa(levels))
+
+	var resp BookResponse
+	if err := c.do(ctx, http.MethodGet, "/book?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// SetSymbolState changes a symbol's session state (an admin operation),
+// e.g. "HALTED" to halt trading and "OPEN" to resume it.
+func (c *Client) SetSymbolState(ctx context.Context, symbol, state string) error {
+	q := url.Values{}
+	q.Set("symbol", symbol)
+	q.Set("state", state)
+
+	var resp struct {
+		Error string `json:"error,omitempty"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/admin/symbol/state?"+q.Encode(), nil, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// ConsecutiveBusy returns the number of 503s seen since the last non-503
+// response. A steadily growing value indicates sustained backpressure.
+func (c *Client) ConsecutiveBusy() int {
+	return int(atomic.LoadInt64(&c.consecutive))
+}
+
+// do sends a request, retrying 503 responses according to the retry policy
+// and budget, and decodes the JSON body of the final response into out.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	if c.budget != nil {
+		c.budget.deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			atomic.StoreInt64(&c.consecutive, 0)
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+			}
+			return nil
+		}
+
+		// 503: the ring buffer was full and the request was never sequenced
+		consecutive := atomic.AddInt64(&c.consecutive, 1)
+		event := BackpressureEvent{
+			Path:        path,
+			Attempt:     attempt,
+			Consecutive: int(consecutive),
+		}
+
+		if attempt >= c.retry.MaxAttempts {
+			event.GaveUp = true
+			c.notify(event)
+			return fmt.Errorf("%w after %d attempts", ErrServerBusy, attempt)
+		}
+		if c.budget != nil && !c.budget.withdraw() {
+			event.GaveUp = true
+			event.BudgetExhausted = true
+			c.notify(event)
+			return ErrRetryBudgetExhausted
+		}
+
+		event.Delay = c.retry.backoff(attempt)
+		c.notify(event)
+
+		select {
+		case <-time.After(event.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notify invokes the backpressure hook if one is registered.
+func (c *Client) notify(event BackpressureEvent) {
+	if c.onBackpressure != nil {
+		c.onBackpressure(event)
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// busyServer answers 503 for the first `busy` requests, then accepts orders.
+func busyServer(busy int64) (*httptest.Server, *int64) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt64(&calls, 1) <= busy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"success":false,"error":"server busy, please retry"}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"order_id":7,"status":"NEW","leaves_qty":100}`))
+	}))
+	return srv, &calls
+}
+
+func fastPolicy(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+// TestClient_RetriesBusyThenSucceeds tests that 503s are retried transparently
+func TestClient_RetriesBusyThenSucceeds(t *testing.T) {
+	srv, calls := busyServer(2)
+	defer srv.Close()
+
+	var events []BackpressureEvent
+	c := New(srv.URL,
+		WithRetryPolicy(fastPolicy(5)),
+		WithBackpressureHook(func(ev BackpressureEvent) { events = append(events, ev) }))
+
+	resp, err := c.SubmitOrder(context.Background(), OrderRequest{Symbol: "AAPL", Side: "buy", Type: "limit", Price: "150.00", Quantity: 100})
+	if err != nil {
+		t.Fatalf("SubmitOrder failed: %v", err)
+	}
+	if !resp.Success || resp.OrderID != 7 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+	if len(events) != 2 || events[1].Consecutive != 2 || events[1].GaveUp {
+		t.Errorf("Unexpected backpressure events: %+v", events)
+	}
+	if c.ConsecutiveBusy() != 0 {
+		t.Errorf("Expected consecutive busy reset after success, got %d", c.ConsecutiveBusy())
+	}
+}
+
+// TestClient_GivesUpAfterMaxAttempts tests sustained backpressure surfaces as ErrServerBusy
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := busyServer(100)
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetryPolicy(fastPolicy(3)))
+	_, err := c.SubmitOrder(context.Background(), OrderRequest{Symbol: "AAPL"})
+	if !errors.Is(err, ErrServerBusy) {
+		t.Fatalf("Expected ErrServerBusy, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+}
+
+// TestClient_RetryBudgetBoundsRetries tests that retries stop once the budget is spent
+func TestClient_RetryBudgetBoundsRetries(t *testing.T) {
+	srv, calls := busyServer(100)
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetryPolicy(fastPolicy(10)), WithRetryBudget(NewRetryBudget(0, 2, 2)))
+	_, err := c.SubmitOrder(context.Background(), OrderRequest{Symbol: "AAPL"})
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("Expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	if *calls != 3 { // 1 initial + 2 budgeted retries
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+}
+
+// TestRetryBudget_DepositsUpToCap tests requests earn retries beyond the
+// initial allowance, up to the cap, even from an empty start
+func TestRetryBudget_DepositsUpToCap(t *testing.T) {
+	b := NewRetryBudget(0.5, 0, 3)
+	if b.withdraw() {
+		t.Fatal("Expected an empty budget to refuse a retry")
+	}
+	for i := 0; i < 2; i++ {
+		b.deposit()
+	}
+	if !b.withdraw() || b.withdraw() {
+		t.Fatal("Expected two requests to earn exactly one retry")
+	}
+
+	for i := 0; i < 100; i++ {
+		b.deposit()
+	}
+	if n := b.Available(); n != 3 {
+		t.Errorf("Expected the balance capped at 3, got %d", n)
+	}
+
+	// A cap below the initial allowance is raised to it
+	if n := NewRetryBudget(0.1, 5, 1).Available(); n != 5 {
+		t.Errorf("Expected 5 retries available, got %d", n)
+	}
+}
+
+// TestRetryPolicy_BackoffBounds tests the jittered backoff stays within its ceiling
+func TestRetryPolicy_BackoffBounds(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+	ceilings := map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 8: 40 * time.Millisecond}
+
+	for attempt, ceiling := range ceilings {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(attempt); d < 0 || d > ceiling {
+				t.Fatalf("Attempt %d: backoff %v outside [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls exponential backoff for 503 responses.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first (1 = no retries)
+	BaseDelay   time.Duration // Backoff ceiling for the first retry
+	MaxDelay    time.Duration // Upper bound on any single backoff
+}
+
+// DefaultRetryPolicy returns a policy tuned for the engine's ~100μs
+// sequencer spin: short first retry, capped well under the 5s handler timeout.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    500 * time.Millisecond,
+	}
+}
+
+// backoff returns the delay before the given retry using "full jitter":
+// a uniform random duration in [0, min(MaxDelay, BaseDelay * 2^(attempt-1))].
+//
+// Why full jitter?
+// Without jitter, every client that saw the same 503 retries at the same
+// instant and the buffer fills again. Randomizing over the whole window
+// spreads retries out and gives the consumer time to drain.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay
+	for i := 1; i < attempt && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// RetryBudget limits retries to a fraction of overall request volume.
+//
+// Design (token bucket, as in gRPC retry throttling):
+// - The budget starts with an initial allowance of retries
+// - Every request deposits `ratio` tokens
+// - Every retry withdraws one token
+// - The balance is capped so a long quiet period can't bank unlimited retries
+//
+// With ratio=0.1, retries can add at most ~10% extra load once the initial
+// allowance is spent, no matter how long the engine stays saturated.
+type RetryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	ratio  float64
+}
+
+// NewRetryBudget creates a budget allowing `ratio` retries per request,
+// starting with `minRetries` banked retries and banking at most
+// `maxRetries`. With minRetries = 0 the budget starts empty and retries are
+// earned by requests, one per 1/ratio of them.
+//
+// Negative arguments are taken as 0, and a maxRetries below minRetries as
+// minRetries.
+func NewRetryBudget(ratio float64, minRetries, maxRetries int) *RetryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minRetries < 0 {
+		minRetries = 0
+	}
+	if maxRetries < minRetries {
+		maxRetries = minRetries
+	}
+	return &RetryBudget{
+		tokens: float64(minRetries),
+		max:    float64(maxRetries),
+		ratio:  ratio,
+	}
+}
+
+// deposit credits the budget for a new request.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// withdraw spends one retry. Returns false if the budget is empty.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns the number of whole retries currently available.
+func (b *RetryBudget) Available() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.tokens)
+}
+
+// BackpressureEvent describes a 503 response seen by the client.
+type BackpressureEvent struct {
+	Path            string        // Request path that was rejected
+	Attempt         int           // Attempt number that got the 503 (1-based)
+	Consecutive     int           // 503s in a row across all calls on this client
+	Delay           time.Duration // Backoff before the next attempt (0 if giving up)
+	GaveUp          bool          // True if no further retry will be made
+	BudgetExhausted bool          // True if the retry budget caused the give-up
+}
@@ -0,0 +1,300 @@
+// Package main benchmarks the matching engine with a synthetic order flow.
+//
+// The workload is generated from a seed before the clock starts, so it
+// costs nothing during the run and the same flags always produce the same
+// ops (see Workload):
+//
+//   - Symbols are picked from a Zipf distribution, the first the most
+//     traded, as on a real exchange where a few names take most of the flow
+//   - A share of ops (-cancel-ratio) cancel an earlier resting order
+//   - A share of orders (-aggression) are IOCs priced through the mid and
+//     take liquidity; the rest are limit orders behind it that provide it
+//
+// and run against one of three targets:
+//
+//	engine  an in-process matching engine: matching alone, single-threaded
+//	http    a server's POST /order and DELETE /cancel
+//	binary  a server's binary order entry (-binary-addr) sessions
+//
+// Each op is timed from send to acknowledgement, and the report gives
+// throughput and latency percentiles for orders and cancels. Every worker
+// (-concurrency) runs every op of its symbols in order, so a cancel always
+// follows its order; ops are closed-loop, one in flight per worker.
+//
+// For regression comparisons, save a run with -out, then rerun the same
+// flags against the new build with -baseline. Runs of different workloads
+// refuse to compare, and the command exits 1 if throughput fell or order
+// p99 latency rose by more than -max-regression. Against a server, start
+// it fresh for both runs: the books it already holds change the matching.
+//
+// Usage:
+//
+//	go run ./cmd/bench -ops 2000000 -out base.json
+//	go run ./cmd/bench -ops 2000000 -baseline base.json
+//	go run ./cmd/bench -target http -addr http://localhost:8080 -ops 50000 -concurrency 4
+//	go run ./cmd/bench -target binary -addr localhost:9001 -ops 200000 -zipf 0 -aggression 0.5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Config holds benchmark configuration.
+type Config struct {
+	Target        string // engine, http or binary
+	Addr          string // Server URL (http) or host:port (binary)
+	Workload      Workload
+	Warmup        int // Ops run before the clock starts
+	Concurrency   int
+	Out           string  // Report file to write
+	Baseline      string  // Report file to compare against
+	MaxRegression float64 // Fraction throughput or p99 may worsen by before the run fails
+}
+
+// worker runs its ops on one session and records their latencies.
+type worker struct {
+	session session
+	ops     []int // Indexes into the workload, in order
+
+	orderLatency  []int64 // Nanoseconds
+	cancelLatency []int64
+	rejected      int
+	late          int
+	skipped       int
+	busy          int
+	fills         int
+	err           error
+}
+
+// run runs the worker's ops in [from, to).
+func (w *worker) run(ops []Op, from, to int, measure bool) {
+	for _, i := range w.ops {
+		if i < from || i >= to {
+			continue
+		}
+		op := &ops[i]
+		start := time.Now()
+		res, err := w.session.do(i, op)
+		elapsed := time.Since(start).Nanoseconds()
+		if err != nil {
+			w.err = fmt.Errorf("op %d: %w", i, err)
+			return
+		}
+		if !measure {
+			continue
+		}
+		switch res.outcome {
+		case skipped:
+			w.skipped++
+			continue
+		case rejected:
+			if op.Cancel {
+				w.late++
+			} else {
+				w.rejected++
+			}
+		case busy:
+			w.busy++
+		}
+		w.fills += res.fills
+		if op.Cancel {
+			w.cancelLatency = append(w.cancelLatency, elapsed)
+		} else {
+			w.orderLatency = append(w.orderLatency, elapsed)
+		}
+	}
+}
+
+// runAll runs ops [from, to) on every worker at once, and returns the
+// first error.
+func runAll(workers []*worker, ops []Op, from, to int, measure bool) error {
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.run(ops, from, to, measure)
+		}(w)
+	}
+	wg.Wait()
+	for _, w := range workers {
+		if w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+func newTarget(config Config) (target, error) {
+	switch config.Target {
+	case "engine":
+		return newEngineTarget(config.Workload.Symbols), nil
+	case "http":
+		return newHTTPTarget(config.Addr, config.Concurrency), nil
+	case "binary":
+		return &binaryTarget{addr: config.Addr}, nil
+	}
+	return nil, fmt.Errorf("unknown target %q (engine, http or binary)", config.Target)
+}
+
+func run(config Config) (*Report, error) {
+	ops := config.Workload.Generate()
+	t, err := newTarget(config)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	// A symbol's ops all go to one worker
+	workerOf := make(map[string]int, len(config.Workload.Symbols))
+	for i, symbol := range config.Workload.Symbols {
+		workerOf[symbol] = i % config.Concurrency
+	}
+	workers := make([]*worker, config.Concurrency)
+	for i := range workers {
+		s, err := t.session(i, ops)
+		if err != nil {
+			return nil, err
+		}
+		defer s.close()
+		workers[i] = &worker{session: s}
+	}
+	for i := range ops {
+		w := workers[workerOf[ops[i].Symbol]]
+		w.ops = append(w.ops, i)
+	}
+
+	if err := runAll(workers, ops, 0, config.Warmup, false); err != nil {
+		return nil, fmt.Errorf("warmup failed: %w", err)
+	}
+	report := &Report{
+		Target:      config.Target,
+		Workload:    config.Workload,
+		Warmup:      config.Warmup,
+		Concurrency: config.Concurrency,
+		Started:     time.Now().UTC(),
+	}
+	start := time.Now()
+	if err := runAll(workers, ops, config.Warmup, len(ops), true); err != nil {
+		return nil, err
+	}
+	report.Seconds = time.Since(start).Seconds()
+
+	var orderLatency, cancelLatency []int64
+	for _, w := range workers {
+		orderLatency = append(orderLatency, w.orderLatency...)
+		cancelLatency = append(cancelLatency, w.cancelLatency...)
+		report.Rejected += w.rejected
+		report.Late += w.late
+		report.Skipped += w.skipped
+		report.Busy += w.busy
+		report.Fills += w.fills
+	}
+	report.Orders = len(orderLatency)
+	report.Cancels = len(cancelLatency) + report.Skipped
+	report.Ops = report.Orders + report.Cancels
+	if report.Seconds > 0 {
+		report.Throughput = float64(len(orderLatency)+len(cancelLatency)) / report.Seconds
+	}
+	report.OrderLatency = percentiles(orderLatency)
+	report.CancelLatency = percentiles(cancelLatency)
+	return report, nil
+}
+
+func main() {
+	target := flag.String("target", "engine", "What to run against: engine (in-process), http or binary")
+	addr := flag.String("addr", "", "Server URL for http (default http://localhost:8080), host:port for binary (default localhost:9001)")
+	ops := flag.Int("ops", 1000000, "Ops to run after the warmup")
+	warmup := flag.Int("warmup", 10000, "Ops to run before the clock starts")
+	seed := flag.Int64("seed", 1, "Workload seed (0 = time-based)")
+	symbols := flag.String("symbols", "AAPL,GOOGL,MSFT,AMZN,TSLA", "Comma-separated symbols, most traded first")
+	zipf := flag.Float64("zipf", 1.2, "Zipf exponent of symbol popularity, > 1 (0 = uniform)")
+	cancelRatio := flag.Float64("cancel-ratio", 0.3, "Share of ops that cancel an earlier resting order")
+	aggression := flag.Float64("aggression", 0.2, "Share of orders that cross the spread (IOCs)")
+	levels := flag.Int("levels", 10, "Ticks either side of the mid orders are priced within")
+	maxQty := flag.Int64("max-qty", 100, "Largest order size")
+	price := flag.String("price", "100.00", "Every symbol's starting mid")
+	accounts := flag.String("accounts", "TRADER1,TRADER2,MM1,MM2", "Comma-separated accounts orders are entered for")
+	concurrency := flag.Int("concurrency", 1, "Workers, each with its own session and share of the symbols")
+	out := flag.String("out", "", "Write the report to this JSON file")
+	baseline := flag.String("baseline", "", "Compare against this report (from -out) and exit 1 on a regression")
+	maxRegression := flag.Float64("max-regression", 0.10, "Fraction throughput or order p99 may worsen by against -baseline")
+	flag.Parse()
+
+	startPrice, err := orders.ParsePrice(*price)
+	if err != nil {
+		log.Fatalf("Invalid -price: %v", err)
+	}
+	config := Config{
+		Target: *target,
+		Addr:   *addr,
+		Workload: Workload{
+			Seed:        *seed,
+			Ops:         *warmup + *ops,
+			Symbols:     strings.Split(*symbols, ","),
+			Zipf:        *zipf,
+			CancelRatio: *cancelRatio,
+			Aggression:  *aggression,
+			Levels:      *levels,
+			MaxQty:      *maxQty,
+			StartPrice:  startPrice,
+			Accounts:    strings.Split(*accounts, ","),
+		},
+		Warmup:        *warmup,
+		Concurrency:   *concurrency,
+		Out:           *out,
+		Baseline:      *baseline,
+		MaxRegression: *maxRegression,
+	}
+	if config.Workload.Seed == 0 {
+		config.Workload.Seed = time.Now().UnixNano()
+	}
+	if config.Addr == "" {
+		switch config.Target {
+		case "http":
+			config.Addr = "http://localhost:8080"
+		case "binary":
+			config.Addr = "localhost:9001"
+		}
+	}
+	switch {
+	case *ops < 1 || *warmup < 0:
+		log.Fatal("-ops must be positive and -warmup not negative")
+	case config.Concurrency < 1 || config.Workload.Levels < 1 || config.Workload.MaxQty < 1:
+		log.Fatal("-concurrency, -levels and -max-qty must be positive")
+	case config.Workload.Zipf != 0 && config.Workload.Zipf <= 1:
+		log.Fatal("-zipf must be greater than 1, or 0 for uniform")
+	}
+
+	var base *Report
+	if config.Baseline != "" {
+		if base, err = loadReport(config.Baseline); err != nil {
+			log.Fatalf("Failed to read the baseline: %v", err)
+		}
+	}
+
+	report, err := run(config)
+	if err != nil {
+		log.Fatalf("Benchmark failed (seed %d): %v", config.Workload.Seed, err)
+	}
+	report.print(os.Stdout)
+	if config.Out != "" {
+		if err := report.save(config.Out); err != nil {
+			log.Fatalf("Failed to write the report: %v", err)
+		}
+	}
+	if base != nil {
+		if err := report.compare(os.Stdout, base, config.MaxRegression); err != nil {
+			log.Printf("REGRESSION: %v", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Percentiles summarizes a set of latencies, in microseconds.
+type Percentiles struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_us"`
+	P50   float64 `json:"p50_us"`
+	P90   float64 `json:"p90_us"`
+	P99   float64 `json:"p99_us"`
+	P999  float64 `json:"p999_us"`
+	Max   float64 `json:"max_us"`
+}
+
+// percentiles summarizes latencies in nanoseconds, sorting them.
+func percentiles(latencies []int64) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum int64
+	for _, l := range latencies {
+		sum += l
+	}
+	// Nearest rank: the smallest latency at least p of them don't exceed
+	at := func(p float64) float64 {
+		i := int(p*float64(len(latencies))+0.999999) - 1
+		if i < 0 {
+			i = 0
+		}
+		return micros(latencies[i])
+	}
+	return Percentiles{
+		Count: len(latencies),
+		Mean:  micros(sum) / float64(len(latencies)),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		P999:  at(0.999),
+		Max:   micros(latencies[len(latencies)-1]),
+	}
+}
+
+func micros(ns int64) float64 {
+	return float64(ns) / 1e3
+}
+
+// Report is the result of a run, as -out writes it and -baseline reads it.
+type Report struct {
+	Target      string    `json:"target"`
+	Workload    Workload  `json:"workload"`
+	Warmup      int       `json:"warmup"`
+	Concurrency int       `json:"concurrency"`
+	Started     time.Time `json:"started"`
+
+	// Measured ops only, warmup excluded
+	Ops        int     `json:"ops"`
+	Orders     int     `json:"orders"`
+	Cancels    int     `json:"cancels"`
+	Rejected   int     `json:"rejected"` // Orders
+	Late       int     `json:"late"`     // Cancels refused: the order had filled
+	Skipped    int     `json:"skipped"`
+	Busy       int     `json:"busy"`
+	Fills      int     `json:"fills"`
+	Seconds    float64 `json:"seconds"`
+	Throughput float64 `json:"ops_per_sec"` // Ops sent (skipped cancels aren't) per second
+
+	OrderLatency  Percentiles `json:"order_latency"`
+	CancelLatency Percentiles `json:"cancel_latency"`
+}
+
+// print writes the report for a person to read.
+func (r *Report) print(w io.Writer) {
+	fmt.Fprintf(w, "Target %s, seed %d: %d ops (%d orders, %d cancels) after %d warmup, %d worker(s)\n",
+		r.Target, r.Workload.Seed, r.Ops, r.Orders, r.Cancels, r.Warmup, r.Concurrency)
+	fmt.Fprintf(w, "  %.2fs, %.0f ops/sec; %d fills, %d orders rejected, %d cancels too late, %d skipped, %d busy\n",
+		r.Seconds, r.Throughput, r.Fills, r.Rejected, r.Late, r.Skipped, r.Busy)
+	fmt.Fprintf(w, "  %-8s %10s %10s %10s %10s %10s %10s   (µs)\n", "", "mean", "p50", "p90", "p99", "p99.9", "max")
+	for _, row := range []struct {
+		name string
+		p    Percentiles
+	}{{"orders", r.OrderLatency}, {"cancels", r.CancelLatency}} {
+		fmt.Fprintf(w, "  %-8s %10.1f %10.1f %10.1f %10.1f %10.1f %10.1f\n",
+			row.name, row.p.Mean, row.p.P50, row.p.P90, row.p.P99, row.p.P999, row.p.Max)
+	}
+}
+
+func (r *Report) save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func loadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// compare prints how r differs from baseline, and returns an error if
+// throughput fell, or order p99 latency rose, by more than maxRegression
+// (a fraction). Only runs of the same workload against the same target
+// compare.
+func (r *Report) compare(w io.Writer, baseline *Report, maxRegression float64) error {
+	if r.Target != baseline.Target || r.Warmup != baseline.Warmup || r.Concurrency != baseline.Concurrency ||
+		!reflect.DeepEqual(r.Workload, baseline.Workload) {
+		return fmt.Errorf("the baseline ran a different workload or target; rerun it with its flags (seed %d)", baseline.Workload.Seed)
+	}
+
+	change := func(now, then float64) float64 {
+		if then == 0 {
+			return 0
+		}
+		return (now - then) / then
+	}
+	fmt.Fprintf(w, "\nAgainst the baseline of %s:\n", baseline.Started.Format(time.RFC3339))
+	rows := []struct {
+		name      string
+		now, then float64
+	}{
+		{"ops/sec", r.Throughput, baseline.Throughput},
+		{"order p50 µs", r.OrderLatency.P50, baseline.OrderLatency.P50},
+		{"order p99 µs", r.OrderLatency.P99, baseline.OrderLatency.P99},
+		{"order p99.9 µs", r.OrderLatency.P999, baseline.OrderLatency.P999},
+		{"cancel p99 µs", r.CancelLatency.P99, baseline.CancelLatency.P99},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "  %-15s %12.1f → %12.1f  %+6.1f%%\n", row.name, row.then, row.now, 100*change(row.now, row.then))
+	}
+	// Same workload, same matching: anything else is a behaviour change
+	if r.Fills != baseline.Fills || r.Rejected != baseline.Rejected || r.Late != baseline.Late || r.Skipped != baseline.Skipped {
+		fmt.Fprintf(w, "  outcomes differ: fills %d → %d, rejected %d → %d, too late %d → %d, skipped %d → %d\n",
+			baseline.Fills, r.Fills, baseline.Rejected, r.Rejected, baseline.Late, r.Late, baseline.Skipped, r.Skipped)
+	}
+
+	if c := change(r.Throughput, baseline.Throughput); -c > maxRegression {
+		return fmt.Errorf("throughput regressed %.1f%% (limit %.1f%%)", -100*c, 100*maxRegression)
+	}
+	if c := change(r.OrderLatency.P99, baseline.OrderLatency.P99); c > maxRegression {
+		return fmt.Errorf("order p99 latency regressed %.1f%% (limit %.1f%%)", 100*c, 100*maxRegression)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/client"
+	"github.com/rishav/order-matching-engine/internal/gateway"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// outcome is what became of one op.
+type outcome int
+
+const (
+	accepted outcome = iota // Orders accepted and cancels carried out
+	rejected                // Orders rejected, and cancels refused because the order had filled
+	skipped                 // Cancels of orders that were never accepted: not sent, not timed
+	busy                    // HTTP 503: the ring buffer was full and the op was never sequenced
+)
+
+// result is the outcome of one op, and the fills it reported.
+type result struct {
+	outcome outcome
+	fills   int
+}
+
+// session runs ops for one worker, one at a time. A worker is given every
+// op of its symbols, so a cancel always follows the order it cancels on
+// the same session.
+type session interface {
+	do(i int, op *Op) (result, error)
+	close() error
+}
+
+// target is what a workload is run against. ops is the whole workload, so
+// sessions can look up the order a cancel names.
+type target interface {
+	session(worker int, ops []Op) (session, error)
+	close() error
+}
+
+// engineTarget runs ops on an in-process matching engine: no network, no
+// ring buffer, no risk checks, just matching. It has one session.
+type engineTarget struct {
+	engine *matching.Engine
+}
+
+func newEngineTarget(symbols []string) *engineTarget {
+	engine := matching.NewEngine()
+	for _, symbol := range symbols {
+		engine.AddSymbol(symbol)
+	}
+	return &engineTarget{engine: engine}
+}
+
+func (t *engineTarget) session(worker int, ops []Op) (session, error) {
+	if worker > 0 {
+		return nil, errors.New("the engine target is single-threaded: run it with -concurrency 1")
+	}
+	return &engineSession{engine: t.engine, ids: make([]uint64, len(ops))}, nil
+}
+
+func (t *engineTarget) close() error { return nil }
+
+type engineSession struct {
+	engine *matching.Engine
+	ids    []uint64 // Order IDs by op, 0 if not accepted
+}
+
+func (s *engineSession) do(i int, op *Op) (result, error) {
+	if op.Cancel {
+		id := s.ids[op.Target]
+		if id == 0 {
+			return result{outcome: skipped}, nil
+		}
+		if _, err := s.engine.CancelOrder(op.Symbol, id); err != nil {
+			return result{outcome: rejected}, nil
+		}
+		return result{}, nil
+	}
+
+	order := &orders.Order{
+		Symbol:    op.Symbol,
+		Side:      op.Side,
+		Type:      op.Type,
+		Price:     op.Price,
+		Quantity:  op.Quantity,
+		AccountID: op.Account,
+		Timestamp: orders.Now(),
+	}
+	res := s.engine.ProcessOrder(order)
+	if !res.Accepted {
+		return result{outcome: rejected}, nil
+	}
+	s.ids[i] = order.ID
+	return result{fills: len(res.Fills)}, nil
+}
+
+func (s *engineSession) close() error { return nil }
+
+// httpTarget sends ops to a server's POST /order and DELETE /cancel.
+// Sessions share one client and its connection pool.
+type httpTarget struct {
+	client *client.Client
+	ids    []uint64
+}
+
+func newHTTPTarget(baseURL string, concurrency int) *httpTarget {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = concurrency // Keep every worker's connection open
+	return &httpTarget{
+		client: client.New(baseURL,
+			client.WithHTTPClient(&http.Client{Transport: transport, Timeout: 10 * time.Second}),
+			// A 503 is counted, not retried: retries would hide the queueing
+			client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1})),
+	}
+}
+
+func (t *httpTarget) session(worker int, ops []Op) (session, error) {
+	if t.ids == nil {
+		t.ids = make([]uint64, len(ops)) // Workers write only their own symbols' ops
+	}
+	return &httpSession{target: t}, nil
+}
+
+func (t *httpTarget) close() error { return nil }
+
+type httpSession struct {
+	target *httpTarget
+}
+
+func (s *httpSession) do(i int, op *Op) (result, error) {
+	ctx := context.Background()
+	if op.Cancel {
+		id := s.target.ids[op.Target]
+		if id == 0 {
+			return result{outcome: skipped}, nil
+		}
+		resp, err := s.target.client.CancelOrder(ctx, op.Symbol, id)
+		if err != nil {
+			return httpError(err)
+		}
+		if !resp.Success {
+			return result{outcome: rejected}, nil
+		}
+		return result{}, nil
+	}
+
+	resp, err := s.target.client.SubmitOrder(ctx, client.OrderRequest{
+		Symbol:    op.Symbol,
+		Side:      strings.ToLower(op.Side.String()),
+		Type:      strings.ToLower(op.Type.String()),
+		Price:     dollars(op.Price),
+		Quantity:  op.Quantity,
+		AccountID: op.Account,
+	})
+	if err != nil {
+		return httpError(err)
+	}
+	if !resp.Success {
+		return result{outcome: rejected}, nil
+	}
+	s.target.ids[i] = resp.OrderID
+	return result{fills: len(resp.Fills)}, nil
+}
+
+func (s *httpSession) close() error { return nil }
+
+// httpError turns a 503 into a result, and passes other errors on.
+func httpError(err error) (result, error) {
+	if errors.Is(err, client.ErrServerBusy) {
+		return result{outcome: busy}, nil
+	}
+	return result{}, err
+}
+
+// dollars formats cents as the string price POST /order takes.
+func dollars(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// binaryTarget sends ops over binary order entry sessions. Each worker
+// logs in one session per account it sends for, lazily, so every order is
+// entered for its op's account.
+type binaryTarget struct {
+	addr string
+}
+
+func (t *binaryTarget) session(worker int, ops []Op) (session, error) {
+	return &binarySession{
+		addr:    t.addr,
+		clients: make(map[string]*gateway.Client),
+		conns:   make(map[string]net.Conn),
+		entered: make(map[int]bool),
+	}, nil
+}
+
+func (t *binaryTarget) close() error { return nil }
+
+type binarySession struct {
+	addr    string
+	clients map[string]*gateway.Client // By account
+	conns   map[string]net.Conn
+	entered map[int]bool // Ops whose orders were accepted
+	fills   int          // Executions seen since the last op returned
+}
+
+// do sends an op and waits for its acknowledgement: accepted or rejected
+// for an order, canceled or cancel rejected for a cancel. The executions
+// of an order follow its acceptance, so they are counted as they are read,
+// and those of the last op may be missed.
+func (s *binarySession) do(i int, op *Op) (result, error) {
+	token := strconv.FormatUint(uint64(i), 36)
+	if op.Cancel {
+		if !s.entered[op.Target] {
+			return result{outcome: skipped}, nil
+		}
+		token = strconv.FormatUint(uint64(op.Target), 36) // Cancels name the order's token
+	}
+	c, err := s.client(op.Account)
+	if err != nil {
+		return result{}, err
+	}
+
+	m := gateway.Message{Type: gateway.CancelOrder, Token: token}
+	if !op.Cancel {
+		m = gateway.Message{
+			Type:      gateway.EnterOrder,
+			Token:     token,
+			Side:      op.Side,
+			Quantity:  op.Quantity,
+			Symbol:    op.Symbol,
+			Price:     op.Price,
+			OrderType: op.Type,
+		}
+	}
+	if err := c.Send(&m); err != nil {
+		return result{}, err
+	}
+
+	for {
+		reply, err := c.Receive()
+		if err != nil {
+			return result{}, err
+		}
+		if reply.Type == gateway.Executed {
+			s.fills++
+		}
+		if reply.Token != token {
+			continue
+		}
+		var res result
+		switch {
+		case !op.Cancel && reply.Type == gateway.Accepted:
+			s.entered[i] = true
+		case !op.Cancel && reply.Type == gateway.Rejected:
+			res.outcome = rejected
+		case op.Cancel && reply.Type == gateway.Canceled:
+		case op.Cancel && reply.Type == gateway.CancelReject:
+			res.outcome = rejected
+		default:
+			continue // An earlier op's executions or IOC cancel
+		}
+		res.fills, s.fills = s.fills, 0
+		return res, nil
+	}
+}
+
+// client returns the session's client for account, logging in if needed.
+func (s *binarySession) client(account string) (*gateway.Client, error) {
+	if c := s.clients[account]; c != nil {
+		return c, nil
+	}
+	conn, err := net.DialTimeout("tcp", s.addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c, err := gateway.Login(conn, account, "", 0, false)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to log in as %s: %w", account, err)
+	}
+	s.clients[account] = c
+	s.conns[account] = conn
+	return c, nil
+}
+
+func (s *binarySession) close() error {
+	for account, c := range s.clients {
+		c.Logout()
+		s.conns[account].Close()
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Workload describes a synthetic order flow. The flow is a pure function
+// of it: the same Workload always generates the same ops, whatever they
+// are run against, so two runs with one seed are comparable.
+type Workload struct {
+	Seed        int64    `json:"seed"`
+	Ops         int      `json:"ops"`          // Orders and cancels, warmup included
+	Symbols     []string `json:"symbols"`      // In popularity order when Zipf is set
+	Zipf        float64  `json:"zipf"`         // Exponent of the symbol distribution, > 1 (0 = uniform)
+	CancelRatio float64  `json:"cancel_ratio"` // Share of ops that cancel an earlier passive order
+	Aggression  float64  `json:"aggression"`   // Share of orders that cross the spread
+	Levels      int      `json:"levels"`       // Ticks either side of the mid orders are priced within
+	MaxQty      int64    `json:"max_qty"`      // Order sizes are uniform in 1..MaxQty
+	StartPrice  int64    `json:"start_price"`  // Every symbol's first mid, in cents
+	Accounts    []string `json:"accounts"`
+}
+
+// Op is one step of a workload: a new order, or a cancel of an earlier
+// one.
+type Op struct {
+	Cancel   bool
+	Target   int // Cancels: index of the op that entered the order
+	Symbol   string
+	Side     orders.Side
+	Type     orders.OrderType
+	Price    int64
+	Quantity int64
+	Account  string
+}
+
+// Generate generates the workload's ops.
+//
+// Each symbol's mid takes a random walk of a cent at a time. A passive
+// order is a limit order 1 to Levels ticks behind the mid on its own side;
+// an aggressive one is an IOC up to Levels ticks through it. Cancels pick
+// a random passive order not already cancelled. It may have filled since,
+// which the targets count as a cancel skipped.
+func (w Workload) Generate() []Op {
+	rng := rand.New(rand.NewSource(w.Seed))
+	var zipf *rand.Zipf
+	if w.Zipf > 1 && len(w.Symbols) > 1 {
+		zipf = rand.NewZipf(rng, w.Zipf, 1, uint64(len(w.Symbols)-1))
+	}
+	mids := make(map[string]int64, len(w.Symbols))
+	for _, symbol := range w.Symbols {
+		mids[symbol] = w.StartPrice
+	}
+	floor := int64(w.Levels) + 1
+
+	ops := make([]Op, 0, w.Ops)
+	var passive []int // Ops that entered passive orders, not yet cancelled
+	for len(ops) < w.Ops {
+		if len(passive) > 0 && rng.Float64() < w.CancelRatio {
+			i := rng.Intn(len(passive))
+			target := passive[i]
+			passive[i] = passive[len(passive)-1]
+			passive = passive[:len(passive)-1]
+			ops = append(ops, Op{Cancel: true, Target: target, Symbol: ops[target].Symbol, Account: ops[target].Account})
+			continue
+		}
+
+		var symbol string
+		if zipf != nil {
+			symbol = w.Symbols[zipf.Uint64()]
+		} else {
+			symbol = w.Symbols[rng.Intn(len(w.Symbols))]
+		}
+		mid := mids[symbol] + int64(rng.Intn(3)-1)
+		if mid < floor {
+			mid = floor
+		}
+		mids[symbol] = mid
+
+		op := Op{
+			Symbol:   symbol,
+			Side:     orders.Side(rng.Intn(2)),
+			Quantity: 1 + rng.Int63n(w.MaxQty),
+			Account:  w.Accounts[rng.Intn(len(w.Accounts))],
+		}
+		// Ticks away from the mid: behind it on the order's own side when
+		// passive, through it when aggressive
+		away := 1 + int64(rng.Intn(w.Levels))
+		if rng.Float64() < w.Aggression {
+			op.Type = orders.OrderTypeIOC
+			away = -int64(rng.Intn(w.Levels + 1))
+		} else {
+			op.Type = orders.OrderTypeLimit
+			passive = append(passive, len(ops))
+		}
+		if op.Side == orders.SideBuy {
+			op.Price = mid - away
+		} else {
+			op.Price = mid + away
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
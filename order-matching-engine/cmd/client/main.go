@@ -2,13 +2,16 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+
+	"github.com/rishav/order-matching-engine/client"
+	"github.com/rishav/order-matching-engine/internal/orders"
 )
 
 func main() {
@@ -24,6 +27,14 @@ func main() {
 	submitQty := submitCmd.Int64("qty", 100, "Order quantity")
 	submitAccount := submitCmd.String("account", "TRADER1", "Account ID")
 
+	previewCmd := flag.NewFlagSet("preview", flag.ExitOnError)
+	previewSymbol := previewCmd.String("symbol", "AAPL", "Stock symbol")
+	previewSide := previewCmd.String("side", "buy", "Order side (buy/sell)")
+	previewType := previewCmd.String("type", "market", "Order type (market/limit/ioc/fok)")
+	previewPrice := previewCmd.String("price", "", "Order price (limit orders)")
+	previewQty := previewCmd.Int64("qty", 100, "Order quantity")
+	previewAccount := previewCmd.String("account", "TRADER1", "Account ID")
+
 	cancelCmd := flag.NewFlagSet("cancel", flag.ExitOnError)
 	cancelSymbol := cancelCmd.String("symbol", "", "Stock symbol")
 	cancelOrderID := cancelCmd.Uint64("order-id", 0, "Order ID to cancel")
@@ -36,6 +47,7 @@ func main() {
 	accountID := accountCmd.String("id", "TRADER1", "Account ID")
 
 	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	statsSymbol := statsCmd.String("symbol", "", "Show per-symbol stats instead of system stats")
 
 	if len(os.Args) < 2 {
 		printUsage()
@@ -50,6 +62,10 @@ func main() {
 		submitCmd.Parse(os.Args[2:])
 		submitOrder(*serverURL, *submitSymbol, *submitSide, *submitType, *submitPrice, *submitQty, *submitAccount)
 
+	case "preview":
+		previewCmd.Parse(os.Args[2:])
+		previewOrder(*serverURL, *previewSymbol, *previewSide, *previewType, *previewPrice, *previewQty, *previewAccount)
+
 	case "cancel":
 		cancelCmd.Parse(os.Args[2:])
 		cancelOrder(*serverURL, *cancelSymbol, *cancelOrderID)
@@ -64,10 +80,25 @@ func main() {
 
 	case "stats":
 		statsCmd.Parse(os.Args[2:])
-		getStats(*serverURL)
+		if *statsSymbol != "" {
+			getSymbolStats(*serverURL, *statsSymbol)
+		} else {
+			getStats(*serverURL)
+		}
 
 	case "demo":
-		runDemo(*serverURL)
+		if !runScenarios(*serverURL, []string{"demo"}) {
+			os.Exit(1)
+		}
+
+	case "scenario":
+		if len(os.Args) < 3 {
+			printUsage()
+			os.Exit(1)
+		}
+		if !runScenarios(*serverURL, os.Args[2:]) {
+			os.Exit(1)
+		}
 
 	default:
 		printUsage()
@@ -83,32 +114,49 @@ Usage:
 
 Commands:
   submit    Submit a new order
+  preview   Show what an order would fill at now, without placing it
   cancel    Cancel an existing order
   book      View order book
   account   View account details
   stats     View system statistics
-  demo      Run a demonstration
+  demo      Run a demonstration (the built-in "demo" scenario)
+  scenario  Run YAML scenario scripts against the server (see scenario.go)
 
 Examples:
   client submit -symbol AAPL -side buy -type limit -price 150.00 -qty 100 -account TRADER1
+  client preview -symbol AAPL -side buy -qty 500
   client cancel -symbol AAPL -order-id 123
   client book -symbol AAPL -levels 10
   client account -id TRADER1
   client stats
-  client demo`)
+  client stats -symbol AAPL
+  client demo
+  client scenario cmd/client/scenarios/halt.yaml
+  client scenario halt                 # built-in scenarios by name`)
 }
 
 func submitOrder(serverURL, symbol, side, orderType, price string, qty int64, account string) {
-	req := map[string]interface{}{
-		"symbol":     symbol,
-		"side":       side,
-		"type":       orderType,
-		"price":      price,
-		"quantity":   qty,
-		"account_id": account,
+	// Catch a mistyped price here, exactly as the server parses it
+	if price != "" {
+		if _, err := orders.ParsePrice(price); err != nil {
+			fmt.Printf("Error: invalid price: %v\n", err)
+			return
+		}
 	}
 
-	resp, err := postJSON(serverURL+"/order", req)
+	// The SDK retries 503 (ring buffer full) with jittered backoff
+	c := client.New(serverURL, client.WithBackpressureHook(func(ev client.BackpressureEvent) {
+		fmt.Printf("Server busy (attempt %d), retrying in %v\n", ev.Attempt, ev.Delay)
+	}))
+
+	resp, err := c.SubmitOrder(context.Background(), client.OrderRequest{
+		Symbol:    symbol,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Quantity:  qty,
+		AccountID: account,
+	})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -118,26 +166,51 @@ func submitOrder(serverURL, symbol, side, orderType, price string, qty int64, ac
 	printJSON(resp)
 }
 
-func cancelOrder(serverURL, symbol string, orderID uint64) {
-	url := fmt.Sprintf("%s/cancel?symbol=%s&order_id=%d", serverURL, symbol, orderID)
-
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+func previewOrder(serverURL, symbol, side, orderType, price string, qty int64, account string) {
+	if price == "" {
+		price = "0" // Market orders
+	}
+	resp, err := client.New(serverURL).PreviewOrder(context.Background(), client.OrderRequest{
+		Symbol:    symbol,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Quantity:  qty,
+		AccountID: account,
+	})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
+	if !resp.Success && resp.Status == "" {
+		fmt.Printf("Preview rejected: %s\n", rejectReason(resp.RejectReason, resp.Error))
+		return
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	fmt.Printf("\n=== Preview: %s %d %s %s ===\n\n", side, qty, symbol, orderType)
+	for _, fill := range resp.Fills {
+		fmt.Printf("  fill %6d @ %s\n", fill.Quantity, fill.Price)
+	}
+	fmt.Printf("\nStatus:    %s", resp.Status)
+	if resp.RejectReason != "" {
+		fmt.Printf(" (%s)", resp.RejectReason)
+	}
+	fmt.Printf("\nFilled:    %d of %d, %d would rest\n", resp.FilledQty, qty, resp.LeavesQty)
+	if resp.FilledQty > 0 {
+		fmt.Printf("Avg price: %s (best %s, slippage %s/share, %.2f bps)\n", resp.AvgPrice, resp.BestPrice, resp.Slippage, resp.SlippageBps)
+		fmt.Printf("Notional:  %s\n", resp.Notional)
+	}
+}
+
+func cancelOrder(serverURL, symbol string, orderID uint64) {
+	resp, err := client.New(serverURL).CancelOrder(context.Background(), symbol, orderID)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
 	fmt.Printf("Cancel Response:\n")
-	printJSONBytes(body)
+	printJSON(resp)
 }
 
 func getBook(serverURL, symbol string, levels int) {
@@ -212,63 +285,17 @@ func getStats(serverURL string) {
 	printJSONBytes(body)
 }
 
-func runDemo(serverURL string) {
-	fmt.Println("=== Order Matching Engine Demo ===")
-
-	// Step 1: Show initial book
-	fmt.Println("1. Initial order book (empty):")
-	getBook(serverURL, "AAPL", 5)
-
-	// Step 2: Market maker posts liquidity
-	fmt.Println("\n2. Market maker (MM1) posts buy orders:")
-	submitOrder(serverURL, "AAPL", "buy", "limit", "149.00", 100, "MM1")
-	submitOrder(serverURL, "AAPL", "buy", "limit", "148.50", 200, "MM1")
-	submitOrder(serverURL, "AAPL", "buy", "limit", "148.00", 300, "MM1")
-
-	fmt.Println("\n3. Market maker (MM1) posts sell orders:")
-	submitOrder(serverURL, "AAPL", "sell", "limit", "151.00", 100, "MM1")
-	submitOrder(serverURL, "AAPL", "sell", "limit", "151.50", 200, "MM1")
-	submitOrder(serverURL, "AAPL", "sell", "limit", "152.00", 300, "MM1")
-
-	// Step 3: Show book with liquidity
-	fmt.Println("\n4. Order book with liquidity:")
-	getBook(serverURL, "AAPL", 5)
-
-	// Step 4: Trader executes against the book
-	fmt.Println("\n5. Trader (TRADER1) buys 150 shares with market order:")
-	submitOrder(serverURL, "AAPL", "buy", "market", "0", 150, "TRADER1")
-
-	// Step 5: Show updated book
-	fmt.Println("\n6. Order book after trade:")
-	getBook(serverURL, "AAPL", 5)
-
-	// Step 6: Show stats
-	fmt.Println("\n7. System statistics:")
-	getStats(serverURL)
-
-	fmt.Println("\n=== Demo Complete ===")
-}
-
-func postJSON(url string, data interface{}) (map[string]interface{}, error) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+func getSymbolStats(serverURL, symbol string) {
+	resp, err := http.Get(fmt.Sprintf("%s/stats/symbol?symbol=%s", serverURL, symbol))
 	if err != nil {
-		return nil, err
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var result map[string]interface{}
-	err = json.Unmarshal(body, &result)
-	return result, err
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("%s Statistics:\n", symbol)
+	printJSONBytes(body)
 }
 
 func printJSON(data interface{}) {
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rishav/order-matching-engine/client"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Scenario Runner
+//
+// A scenario is a YAML script run step by step against a live server, so a
+// teaching example or a regression demo plays out the same way every time:
+//
+//	name: Market order walks the book
+//	symbol: AAPL                     # Default for steps that omit one
+//	cleanup: true                    # Cancel leftover orders, reopen halted symbols
+//	steps:
+//	  - say: Market maker posts an offer
+//	  - order: {ref: ask1, account: MM1, side: sell, price: "151.00", qty: 100}
+//	    expect: {status: NEW, leaves: 100}
+//	  - order: {account: TRADER1, side: buy, type: market, qty: 60}
+//	    expect: {status: FILLED, filled: 60}
+//	  - expect_book:
+//	      asks: [{price: "151.00", qty: 40, orders: 1}]
+//	  - halt: AAPL
+//	  - order: {account: TRADER1, side: buy, price: "151.00", qty: 10}
+//	    expect: {rejected: HALTED}
+//	  - resume: AAPL
+//	  - wait: 500ms
+//	  - cancel: ask1
+//	  - expect_order: {ref: ask1, status: CANCELLED, filled: 60}
+//
+// Each step does exactly one thing:
+//
+//	say            print a line of narration
+//	order          submit an order; ref names it for later steps
+//	preview        show what an order would fill at now, without placing it
+//	cancel         cancel a referenced order
+//	halt / resume  set a symbol's session state to HALTED / OPEN
+//	wait           a time jump; the engine runs on the wall clock, so it's a real wait
+//	book / stats   print a symbol's book / the system statistics
+//	expect_book    assert the book's levels; a listed side must match exactly
+//	expect_order   assert a referenced order's current status
+//
+// An order, preview or cancel step may carry an expect block, checked
+// against its response; a preview's status is what the order would end as. Failed assertions are reported and the run continues; the
+// command exits non-zero if any failed. Book assertions see every order on
+// the server, so scenarios that assert on the book expect a fresh one.
+
+//go:embed scenarios/*.yaml
+var builtinScenarios embed.FS
+
+// scenario is one YAML script.
+type scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Symbol      string `yaml:"symbol"`
+	Cleanup     bool   `yaml:"cleanup"`
+	Steps       []step `yaml:"steps"`
+}
+
+// step is one scenario step. Exactly one action field is set.
+type step struct {
+	Say         string        `yaml:"say"`
+	Order       *orderStep    `yaml:"order"`
+	Preview     *orderStep    `yaml:"preview"`
+	Cancel      string        `yaml:"cancel"`
+	Halt        string        `yaml:"halt"`
+	Resume      string        `yaml:"resume"`
+	Wait        time.Duration `yaml:"wait"`
+	Book        string        `yaml:"book"`
+	Stats       bool          `yaml:"stats"`
+	ExpectBook  *bookExpect   `yaml:"expect_book"`
+	ExpectOrder *orderExpect  `yaml:"expect_order"`
+
+	Expect *resultExpect `yaml:"expect"` // Checks an order, preview or cancel step's response
+}
+
+type orderStep struct {
+	Ref        string `yaml:"ref"`
+	Symbol     string `yaml:"symbol"`
+	Account    string `yaml:"account"`
+	Side       string `yaml:"side"`
+	Type       string `yaml:"type"` // Default limit
+	Price      string `yaml:"price"`
+	Qty        int64  `yaml:"qty"`
+	DisplayQty int64  `yaml:"display_qty"`
+}
+
+// resultExpect checks an order or cancel response. Unset fields are not
+// checked.
+type resultExpect struct {
+	Status    string  `yaml:"status"`
+	Filled    *int64  `yaml:"filled"`    // Cumulative filled quantity
+	Leaves    *int64  `yaml:"leaves"`    // Quantity still working
+	Cancelled *int64  `yaml:"cancelled"` // Quantity removed by a cancel
+	Rejected  *string `yaml:"rejected"`  // Expect a rejection whose reason contains this
+	AvgPrice  string  `yaml:"avg_price"` // Average fill price
+}
+
+type bookExpect struct {
+	Symbol string        `yaml:"symbol"`
+	Bids   []levelExpect `yaml:"bids"` // Best first; omitted = not checked, [] = empty
+	Asks   []levelExpect `yaml:"asks"`
+}
+
+type levelExpect struct {
+	Price  string `yaml:"price"`
+	Qty    int64  `yaml:"qty"`
+	Orders int    `yaml:"orders"` // 0 = not checked
+}
+
+type orderExpect struct {
+	Ref    string `yaml:"ref"`
+	Status string `yaml:"status"`
+	Filled *int64 `yaml:"filled"`
+	Leaves *int64 `yaml:"leaves"`
+}
+
+// loadScenario reads a scenario from a file, or from the built-in
+// scenarios when the path names one (e.g. "demo").
+func loadScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if builtin, berr := builtinScenarios.ReadFile("scenarios/" + path + ".yaml"); berr == nil {
+			data, err = builtin, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sc scenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // A misspelt key should fail, not silently skip a check
+	if err := decoder.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, st := range sc.Steps {
+		if n := st.actions(); n != 1 {
+			return nil, fmt.Errorf("%s: step %d has %d actions, expected 1", path, i+1, n)
+		}
+		if st.Expect != nil && st.Order == nil && st.Preview == nil && st.Cancel == "" {
+			return nil, fmt.Errorf("%s: step %d: expect only applies to order, preview and cancel steps", path, i+1)
+		}
+	}
+	return &sc, nil
+}
+
+// actions counts the action fields set on a step.
+func (st *step) actions() int {
+	n := 0
+	for _, set := range []bool{
+		st.Say != "", st.Order != nil, st.Preview != nil, st.Cancel != "", st.Halt != "", st.Resume != "",
+		st.Wait != 0, st.Book != "", st.Stats, st.ExpectBook != nil, st.ExpectOrder != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// scenarioRun is the state of one scenario being run.
+type scenarioRun struct {
+	sc        *scenario
+	serverURL string
+	client    *client.Client
+	ctx       context.Context
+
+	refs     map[string]client.OrderResponse // Order ref -> submission response
+	symbols  map[string]string               // Order ref -> symbol
+	halted   map[string]bool                 // Symbols halted and not yet resumed
+	failures int
+}
+
+// runScenarios runs each scenario in turn. Returns false if any assertion
+// failed or a step could not be executed.
+func runScenarios(serverURL string, paths []string) bool {
+	ok := true
+	for _, path := range paths {
+		sc, err := loadScenario(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			ok = false
+			continue
+		}
+		if !runScenario(serverURL, sc) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// runScenario runs one scenario. Returns false if it failed.
+func runScenario(serverURL string, sc *scenario) bool {
+	run := &scenarioRun{
+		sc:        sc,
+		serverURL: serverURL,
+		client:    client.New(serverURL),
+		ctx:       context.Background(),
+		refs:      make(map[string]client.OrderResponse),
+		symbols:   make(map[string]string),
+		halted:    make(map[string]bool),
+	}
+
+	fmt.Printf("=== %s ===\n", sc.Name)
+	if sc.Description != "" {
+		fmt.Println(strings.TrimSpace(sc.Description))
+	}
+
+	var err error
+	for i := range sc.Steps {
+		if err = run.step(&sc.Steps[i]); err != nil {
+			fmt.Printf("Error in step %d: %v\n", i+1, err)
+			break
+		}
+	}
+	if sc.Cleanup {
+		run.cleanup()
+	}
+
+	switch {
+	case err != nil:
+		fmt.Printf("=== %s: ABORTED ===\n\n", sc.Name)
+	case run.failures > 0:
+		fmt.Printf("=== %s: FAILED (%d assertions) ===\n\n", sc.Name, run.failures)
+	default:
+		fmt.Printf("=== %s: PASSED ===\n\n", sc.Name)
+	}
+	return err == nil && run.failures == 0
+}
+
+// step executes one step. Errors abort the scenario; failed assertions
+// are counted and reported.
+func (run *scenarioRun) step(st *step) error {
+	switch {
+	case st.Say != "":
+		fmt.Printf("\n%s\n", st.Say)
+
+	case st.Order != nil:
+		return run.order(st.Order, st.Expect)
+
+	case st.Preview != nil:
+		return run.preview(st.Preview, st.Expect)
+
+	case st.Cancel != "":
+		return run.cancel(st.Cancel, st.Expect)
+
+	case st.Halt != "":
+		if err := run.client.SetSymbolState(run.ctx, st.Halt, "HALTED"); err != nil {
+			return err
+		}
+		run.halted[st.Halt] = true
+		fmt.Printf("  halted %s\n", st.Halt)
+
+	case st.Resume != "":
+		if err := run.client.SetSymbolState(run.ctx, st.Resume, "OPEN"); err != nil {
+			return err
+		}
+		delete(run.halted, st.Resume)
+		fmt.Printf("  resumed %s\n", st.Resume)
+
+	case st.Wait != 0:
+		fmt.Printf("  waiting %v\n", st.Wait)
+		time.Sleep(st.Wait)
+
+	case st.Book != "":
+		getBook(run.serverURL, st.Book, 5)
+
+	case st.Stats:
+		getStats(run.serverURL)
+
+	case st.ExpectBook != nil:
+		return run.expectBook(st.ExpectBook)
+
+	case st.ExpectOrder != nil:
+		return run.expectOrder(st.ExpectOrder)
+	}
+	return nil
+}
+
+// orderRequest builds the request for an order or preview step. price is
+// how to print its price.
+func (run *scenarioRun) orderRequest(o *orderStep) (req client.OrderRequest, price string) {
+	req = client.OrderRequest{
+		Symbol:     run.symbol(o.Symbol),
+		Side:       o.Side,
+		Type:       o.Type,
+		Price:      o.Price,
+		Quantity:   o.Qty,
+		AccountID:  o.Account,
+		DisplayQty: o.DisplayQty,
+	}
+	if req.Type == "" {
+		req.Type = "limit"
+	}
+	price = "@ " + req.Price
+	if req.Price == "" {
+		req.Price = "0" // Market orders
+		price = "at market"
+	}
+	return req, price
+}
+
+func (run *scenarioRun) order(o *orderStep, expect *resultExpect) error {
+	req, price := run.orderRequest(o)
+	resp, err := run.client.SubmitOrder(run.ctx, req)
+	if err != nil {
+		return err
+	}
+	if o.Ref != "" {
+		run.refs[o.Ref] = *resp
+		run.symbols[o.Ref] = req.Symbol
+	}
+
+	label := o.Ref
+	if label == "" {
+		label = "order"
+	}
+	if resp.Success {
+		fmt.Printf("  %s: %s %s %s %d %s %s -> #%d %s (filled %d, leaves %d)\n",
+			label, o.Account, req.Side, req.Type, req.Quantity, req.Symbol, price,
+			resp.OrderID, resp.Status, resp.CumQty, resp.LeavesQty)
+	} else {
+		fmt.Printf("  %s: %s %s %d %s rejected: %s\n",
+			label, o.Account, req.Side, req.Quantity, req.Symbol, rejectReason(resp.RejectReason, resp.Error))
+	}
+
+	if expect != nil {
+		run.check(label, expect.Status, resp.Status, "status")
+		run.checkQty(label, expect.Filled, resp.CumQty, "filled")
+		run.checkQty(label, expect.Leaves, resp.LeavesQty, "leaves")
+		run.checkPrice(label, expect.AvgPrice, resp.AvgPrice, "avg price")
+		run.checkRejected(label, expect.Rejected, resp.Success, rejectReason(resp.RejectReason, resp.Error))
+	}
+	return nil
+}
+
+func (run *scenarioRun) preview(o *orderStep, expect *resultExpect) error {
+	req, price := run.orderRequest(o)
+	resp, err := run.client.PreviewOrder(run.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	label := "preview"
+	if resp.Success {
+		fmt.Printf("  preview: %s %s %s %d %s %s -> %s (filled %d @ %s, best %s, slippage %s, leaves %d)\n",
+			o.Account, req.Side, req.Type, req.Quantity, req.Symbol, price,
+			resp.Status, resp.FilledQty, resp.AvgPrice, resp.BestPrice, resp.Slippage, resp.LeavesQty)
+	} else {
+		fmt.Printf("  preview: %s %s %d %s rejected: %s\n",
+			o.Account, req.Side, req.Quantity, req.Symbol, rejectReason(resp.RejectReason, resp.Error))
+	}
+
+	if expect != nil {
+		run.check(label, expect.Status, resp.Status, "status")
+		run.checkQty(label, expect.Filled, resp.FilledQty, "filled")
+		run.checkQty(label, expect.Leaves, resp.LeavesQty, "leaves")
+		run.checkPrice(label, expect.AvgPrice, resp.AvgPrice, "avg price")
+		run.checkRejected(label, expect.Rejected, resp.Success, rejectReason(resp.RejectReason, resp.Error))
+	}
+	return nil
+}
+
+func (run *scenarioRun) cancel(ref string, expect *resultExpect) error {
+	placed, ok := run.refs[ref]
+	if !ok || !placed.Success {
+		return fmt.Errorf("cancel: no accepted order %q", ref)
+	}
+
+	resp, err := run.client.CancelOrder(run.ctx, run.symbols[ref], placed.OrderID)
+	if err != nil {
+		return err
+	}
+	if resp.Success {
+		fmt.Printf("  cancelled %s (#%d): %d shares\n", ref, placed.OrderID, resp.CancelledQty)
+	} else {
+		fmt.Printf("  cancel %s (#%d) rejected: %s\n", ref, placed.OrderID, resp.Error)
+	}
+
+	if expect != nil {
+		label := "cancel " + ref
+		run.checkQty(label, expect.Cancelled, resp.CancelledQty, "cancelled")
+		run.checkQty(label, expect.Filled, resp.CumQty, "filled")
+		run.checkQty(label, expect.Leaves, resp.LeavesQty, "leaves")
+		run.checkRejected(label, expect.Rejected, resp.Success, resp.Error)
+	}
+	return nil
+}
+
+func (run *scenarioRun) expectBook(expect *bookExpect) error {
+	symbol := run.symbol(expect.Symbol)
+	// One level deeper than expected, so an extra level is caught
+	levels := len(expect.Bids)
+	if len(expect.Asks) > levels {
+		levels = len(expect.Asks)
+	}
+	book, err := run.client.Book(run.ctx, symbol, levels+1)
+	if err != nil {
+		return err
+	}
+
+	label := symbol + " book"
+	if expect.Bids != nil {
+		run.checkLevels(label+" bids", expect.Bids, book.Bids)
+	}
+	if expect.Asks != nil {
+		run.checkLevels(label+" asks", expect.Asks, book.Asks)
+	}
+	return nil
+}
+
+func (run *scenarioRun) expectOrder(expect *orderExpect) error {
+	placed, ok := run.refs[expect.Ref]
+	if !ok || !placed.Success {
+		return fmt.Errorf("expect_order: no accepted order %q", expect.Ref)
+	}
+
+	info, err := run.client.OrderStatus(run.ctx, placed.OrderID)
+	if err != nil {
+		return err
+	}
+	label := fmt.Sprintf("%s (#%d)", expect.Ref, placed.OrderID)
+	run.check(label, expect.Status, info.Status, "status")
+	run.checkQty(label, expect.Filled, info.CumQty, "filled")
+	run.checkQty(label, expect.Leaves, info.LeavesQty, "leaves")
+	return nil
+}
+
+// cleanup cancels the scenario's orders still resting and reopens the
+// symbols it left halted, so the next run starts from the same book.
+func (run *scenarioRun) cleanup() {
+	cancelled := 0
+	for ref, placed := range run.refs {
+		if !placed.Success || placed.LeavesQty == 0 {
+			continue
+		}
+		resp, err := run.client.CancelOrder(run.ctx, run.symbols[ref], placed.OrderID)
+		if err == nil && resp.Success {
+			cancelled++
+		}
+	}
+	for symbol := range run.halted {
+		if err := run.client.SetSymbolState(run.ctx, symbol, "OPEN"); err != nil {
+			fmt.Printf("  cleanup: failed to reopen %s: %v\n", symbol, err)
+		}
+	}
+	fmt.Printf("\nCleanup: cancelled %d resting orders, reopened %d symbols\n", cancelled, len(run.halted))
+}
+
+// symbol returns the step's symbol, or the scenario default.
+func (run *scenarioRun) symbol(symbol string) string {
+	if symbol == "" {
+		return run.sc.Symbol
+	}
+	return symbol
+}
+
+// check reports whether a string field matched. An empty expectation is
+// not checked.
+func (run *scenarioRun) check(label, expected, actual, field string) {
+	if expected == "" {
+		return
+	}
+	run.result(label, expected == actual, "%s %s (got %s)", field, expected, actual)
+}
+
+// checkPrice reports whether a price matched, however it is written. An
+// empty expectation is not checked.
+func (run *scenarioRun) checkPrice(label, expected, actual, field string) {
+	if expected == "" {
+		return
+	}
+	run.result(label, samePrice(expected, actual), "%s %s (got %s)", field, expected, actual)
+}
+
+func (run *scenarioRun) checkQty(label string, expected *int64, actual int64, field string) {
+	if expected == nil {
+		return
+	}
+	run.result(label, *expected == actual, "%s %d (got %d)", field, *expected, actual)
+}
+
+func (run *scenarioRun) checkRejected(label string, expected *string, success bool, reason string) {
+	if expected == nil {
+		if !success {
+			run.result(label, false, "accepted (got rejected: %s)", reason)
+		}
+		return
+	}
+	run.result(label, !success && strings.Contains(reason, *expected),
+		"rejected with %q (got success=%v, %q)", *expected, success, reason)
+}
+
+func (run *scenarioRun) checkLevels(label string, expected []levelExpect, actual []client.BookLevel) {
+	ok := len(expected) == len(actual)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = samePrice(expected[i].Price, actual[i].Price) &&
+			expected[i].Qty == actual[i].Quantity &&
+			(expected[i].Orders == 0 || expected[i].Orders == actual[i].Orders)
+	}
+	run.result(label, ok, "%s (got %s)", formatExpectedLevels(expected), formatLevels(actual))
+}
+
+// result prints an assertion's outcome and counts failures.
+func (run *scenarioRun) result(label string, ok bool, format string, args ...interface{}) {
+	mark := "ok  "
+	if !ok {
+		mark = "FAIL"
+		run.failures++
+	}
+	fmt.Printf("  [%s] %s: expected %s\n", mark, label, fmt.Sprintf(format, args...))
+}
+
+func rejectReason(reason, errMsg string) string {
+	if reason != "" {
+		return reason
+	}
+	return errMsg
+}
+
+// samePrice compares dollar prices written differently ("151", "$151.00").
+func samePrice(a, b string) bool {
+	x, errA := orders.ParsePrice(a)
+	y, errB := orders.ParsePrice(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return x == y
+}
+
+func formatExpectedLevels(levels []levelExpect) string {
+	parts := make([]string, len(levels))
+	for i, l := range levels {
+		parts[i] = fmt.Sprintf("%s x %d", l.Price, l.Qty)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatLevels(levels []client.BookLevel) string {
+	parts := make([]string, len(levels))
+	for i, l := range levels {
+		parts[i] = fmt.Sprintf("%s x %d", l.Price, l.Quantity)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// filter selects the events dump prints.
+type filter struct {
+	to      uint64 // Last sequence number (0 = the end of the log)
+	types   map[events.EventType]bool
+	symbol  string
+	orderID uint64
+	account string
+
+	// Orders placed by account, learned from its NewOrder events, so the
+	// events that name only the order (accepts, cancels, replaces) match
+	// too. Orders placed before the scan's start aren't known.
+	accountOrders map[uint64]bool
+}
+
+// match reports whether event is selected, learning account's orders.
+func (f *filter) match(event interface{}) bool {
+	if f.types != nil && !f.types[events.TypeOf(event)] {
+		return false
+	}
+	if f.symbol != "" && events.SymbolOf(event) != f.symbol {
+		return false
+	}
+	ids := orderIDsOf(event)
+	if f.orderID != 0 && !contains(ids, f.orderID) {
+		return false
+	}
+	if f.account != "" {
+		switch e := event.(type) {
+		case *events.NewOrderEvent:
+			if e.AccountID == f.account {
+				f.accountOrders[e.OrderID] = true
+			}
+		case *events.SymbolImportedEvent:
+			for i := range e.Orders {
+				if e.Orders[i].AccountID == f.account {
+					f.accountOrders[e.Orders[i].ID] = true
+				}
+			}
+		}
+		if !contains(accountsOf(event), f.account) && !f.anyAccountOrder(ids) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *filter) anyAccountOrder(ids []uint64) bool {
+	for _, id := range ids {
+		if f.accountOrders[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// orderIDsOf returns the orders an event is about.
+func orderIDsOf(event interface{}) []uint64 {
+	switch e := event.(type) {
+	case *events.NewOrderEvent:
+		return []uint64{e.OrderID}
+	case *events.CancelOrderEvent:
+		return []uint64{e.OrderID}
+	case *events.OrderAcceptedEvent:
+		return []uint64{e.OrderID}
+	case *events.OrderRejectedEvent:
+		return []uint64{e.OrderID}
+	case *events.FillEvent:
+		return []uint64{e.MakerOrderID, e.TakerOrderID}
+	case *events.OrderCancelledEvent:
+		return []uint64{e.OrderID}
+	case *events.OrderReplacedEvent:
+		return []uint64{e.OrderID}
+	case *events.SymbolImportedEvent:
+		ids := make([]uint64, len(e.Orders))
+		for i := range e.Orders {
+			ids[i] = e.Orders[i].ID
+		}
+		return ids
+	}
+	return nil
+}
+
+// accountsOf returns the accounts an event names.
+func accountsOf(event interface{}) []string {
+	switch e := event.(type) {
+	case *events.NewOrderEvent:
+		return []string{e.AccountID}
+	case *events.CancelOrderEvent:
+		return []string{e.AccountID}
+	case *events.FillEvent:
+		return []string{e.MakerAccountID, e.TakerAccountID}
+	case *events.RiskLimitsEvent:
+		return []string{e.AccountID}
+	case *events.RestrictionEvent:
+		return []string{e.AccountID}
+	case *events.AccountEvent:
+		return []string{e.AccountID}
+	}
+	return nil
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpedEvent is an event as dump -json prints it, one per line.
+type dumpedEvent struct {
+	Seq   uint64      `json:"seq"`
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+// dump prints the events of a log that pass the filter flags.
+func dump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	path := flags.String("log", "", "Event log to read (with its closed segments, if any)")
+	from := flags.Uint64("from", 1, "First sequence number")
+	to := flags.Uint64("to", 0, "Last sequence number (0 = the end of the log)")
+	types := flags.String("type", "", "Comma-separated event types, e.g. NEW_ORDER,FILL (empty = all)")
+	symbol := flags.String("symbol", "", "Only events of this symbol")
+	orderID := flags.Uint64("order", 0, "Only events of this order ID: its entry, fills, cancels and replaces")
+	account := flags.String("account", "", "Only events of this account, and of the orders it places from -from on")
+	limit := flags.Int("limit", 0, "Stop after this many events (0 = no limit)")
+	asJSON := flags.Bool("json", false, "Print JSON lines instead of text")
+	flags.Parse(args)
+
+	eventLog, err := openLog(flags, *path)
+	if err != nil {
+		return err
+	}
+	defer eventLog.Close()
+
+	f := &filter{to: *to, symbol: *symbol, orderID: *orderID, account: *account, accountOrders: make(map[uint64]bool)}
+	if *types != "" {
+		f.types = make(map[events.EventType]bool)
+		for _, name := range strings.Split(*types, ",") {
+			eventType, ok := events.ParseEventType(strings.TrimSpace(name))
+			if !ok {
+				return fmt.Errorf("unknown event type %q", name)
+			}
+			f.types[eventType] = true
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	printed := 0
+	after := uint64(0)
+	if *from > 0 {
+		after = *from - 1
+	}
+	err = eventLog.ScanFrom(after, func(seq uint64, event interface{}) error {
+		if f.to > 0 && seq > f.to {
+			return events.ErrStopScan
+		}
+		if !f.match(event) {
+			return nil
+		}
+		if *asJSON {
+			if err := encoder.Encode(dumpedEvent{Seq: seq, Type: events.TypeOf(event).String(), Event: event}); err != nil {
+				return err
+			}
+		} else {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-8d %-27s  %-18s %s\n", seq, formatTime(events.TimestampOf(event)), events.TypeOf(event), payload)
+		}
+		if printed++; *limit > 0 && printed >= *limit {
+			return events.ErrStopScan
+		}
+		return nil
+	})
+	if errors.Is(err, events.ErrTruncated) {
+		return fmt.Errorf("%w (the log now starts at %d)", err, eventLog.FirstSequence())
+	}
+	if err != nil {
+		return err
+	}
+	if !*asJSON {
+		fmt.Fprintf(os.Stderr, "%d events (log sequence %d-%d; damaged records skipped, see eventctl verify)\n",
+			printed, eventLog.FirstSequence(), eventLog.GetLastSequence())
+	}
+	return nil
+}
+
+// formatTime formats an event timestamp, in nanoseconds, in UTC.
+func formatTime(nanos int64) string {
+	if nanos == 0 {
+		return "-"
+	}
+	return time.Unix(0, nanos).UTC().Format("2006-01-02T15:04:05.000000Z")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+)
+
+// errReplayEnd ends a replay at -to.
+var errReplayEnd = errors.New("replay end")
+
+// replay re-executes a log with a fresh engine, the way the server
+// recovers (see matching.Replayer), and prints the books it ends with.
+func replay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	path := flags.String("log", "", "Event log to replay (with its closed segments, if any)")
+	to := flags.Uint64("to", 0, "Stop after this sequence number (0 = the end of the log)")
+	snapshotDir := flags.String("snapshot-dir", "", "Start from the latest snapshot here instead of an empty engine (needed once retention has deleted the log's start)")
+	shards := flags.Int("shards", 1, "Number of shards the engine ran, for a shard's log")
+	shardIndex := flags.Int("shard", 0, "Shard whose log this is (0 to -shards - 1)")
+	instrumentsFile := flags.String("instruments", "", "The server's -instruments file, for tick sizes and pro-rata allocation")
+	symbol := flags.String("symbol", "", "Print only this symbol's book")
+	depth := flags.Int("depth", 10, "Price levels per side to print (0 = all)")
+	showOrders := flags.Bool("orders", false, "Print each level's orders in queue order")
+	flags.Parse(args)
+
+	if *shards < 1 || *shardIndex < 0 || *shardIndex >= *shards {
+		return fmt.Errorf("-shard must be between 0 and %d", *shards-1)
+	}
+	eventLog, err := openLog(flags, *path)
+	if err != nil {
+		return err
+	}
+	defer eventLog.Close()
+
+	engine := matching.NewEngine()
+	engine.SetIDStride(*shards, *shardIndex) // Trade IDs are issued as the shard issued them
+	if *instrumentsFile != "" {
+		instruments, err := refdata.Load(*instrumentsFile)
+		if err != nil {
+			return fmt.Errorf("failed to load instruments: %w", err)
+		}
+		for _, inst := range instruments {
+			engine.SetTickSize(inst.Symbol, inst.TickSize)
+			engine.SetAllocation(inst.Symbol, inst.Allocation)
+		}
+	}
+
+	var after uint64
+	if *snapshotDir != "" {
+		if after, err = restoreSnapshot(engine, *snapshotDir, *to); err != nil {
+			return err
+		}
+	}
+
+	replayer := matching.NewReplayer(engine)
+	var last uint64
+	var fills int
+	err = eventLog.ReplayFrom(after, func(seq uint64, event interface{}) error {
+		if *to > 0 && seq > *to {
+			return errReplayEnd
+		}
+		if _, err := replayer.Apply(event); err != nil {
+			return err
+		}
+		if _, ok := event.(*events.FillEvent); ok {
+			fills++
+		}
+		last = seq
+		return nil
+	})
+	if errors.Is(err, errReplayEnd) || errors.Is(err, events.ErrTornWrite) {
+		err = nil // A torn record ends a log still being written
+	}
+	engine.RestoreIDCounters(replayer.Counters())
+
+	counters := engine.IDCounters()
+	fmt.Printf("Replayed events %d-%d: %d state changes, %d logged fills reproduced (order=%d trade=%d seq=%d)\n",
+		after+1, last, replayer.Events(), fills, counters.OrderID, counters.TradeID, counters.SequenceNum)
+	printBooks(engine, *symbol, *depth, *showOrders)
+	if err != nil {
+		return fmt.Errorf("replay stopped after event %d: %w", last, err)
+	}
+	return nil
+}
+
+// restoreSnapshot restores engine from the latest snapshot in dir, and
+// returns the sequence number of the last event it reflects.
+func restoreSnapshot(engine *matching.Engine, dir string, to uint64) (uint64, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return 0, err // Opening the store would create it
+	}
+	store, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+	if err != nil {
+		return 0, err
+	}
+	img := store.Latest()
+	switch {
+	case img == nil:
+		fmt.Fprintf(os.Stderr, "No snapshot in %s, replaying from an empty engine\n", dir)
+		return 0, nil
+	case to > 0 && img.EventSeq > to:
+		return 0, fmt.Errorf("the latest snapshot is at event %d, after -to %d", img.EventSeq, to)
+	}
+	if err := engine.RestoreOrders(img.Books); err != nil {
+		return 0, err
+	}
+	engine.RestoreIDCounters(img.Counters)
+	engine.RestoreMoved(img.Moved)
+	engine.RestoreAuctions(img.Auctions)
+	fmt.Printf("Restored %d resting orders from the snapshot at event %d\n", img.Orders(), img.EventSeq)
+	return img.EventSeq, nil
+}
+
+// printBooks prints the engine's books, by symbol, asks above bids.
+func printBooks(engine *matching.Engine, only string, depth int, showOrders bool) {
+	symbols := engine.Symbols()
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		if only != "" && symbol != only {
+			continue
+		}
+		book := engine.GetOrderBook(symbol)
+		fmt.Printf("\n=== %s: %d orders, %d bid and %d ask levels ===\n",
+			symbol, book.TotalOrders(), book.BidLevels(), book.AskLevels())
+
+		asks := levels(book, orders.SideSell, depth)
+		for i := len(asks) - 1; i >= 0; i-- {
+			printLevel("ASK", asks[i], showOrders)
+		}
+		if spread := book.GetSpread(); spread > 0 {
+			fmt.Printf("  --- spread %s ---\n", orders.FormatPrice(spread))
+		} else {
+			fmt.Println("  ---")
+		}
+		for _, level := range levels(book, orders.SideBuy, depth) {
+			printLevel("BID", level, showOrders)
+		}
+	}
+}
+
+// levels returns up to depth of a side's levels, best first (all for 0).
+func levels(book *orderbook.OrderBook, side orders.Side, depth int) []*orderbook.PriceLevel {
+	var out []*orderbook.PriceLevel
+	book.ForEachLevel(side, func(level *orderbook.PriceLevel) bool {
+		out = append(out, level)
+		return depth <= 0 || len(out) < depth
+	})
+	return out
+}
+
+func printLevel(side string, level *orderbook.PriceLevel, showOrders bool) {
+	fmt.Printf("  %s %10s %8d shares (%d orders", side, orders.FormatPrice(level.Price), level.TotalQty, level.Count())
+	if level.HiddenQty > 0 {
+		fmt.Printf(", %d hidden", level.HiddenQty)
+	}
+	fmt.Println(")")
+	if !showOrders {
+		return
+	}
+	for _, order := range level.Orders() {
+		fmt.Printf("        #%-8d %-12s %8d left of %d", order.ID, order.AccountID, order.RemainingQty(), order.Quantity)
+		if order.IsIceberg() {
+			fmt.Printf(" (%d shown)", order.VisibleQty())
+		}
+		if order.IsPegged() {
+			fmt.Printf(" (%s)", order.Peg)
+		}
+		fmt.Println()
+	}
+}
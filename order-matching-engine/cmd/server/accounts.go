@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// Accounts
+//
+// Accounts are opened, and cash and shares moved in and out of them, over
+// HTTP (see settlement/accounts.go):
+//
+//	POST /accounts                    {"id":"CLIENT9","cash":"25000.00"}
+//	POST /accounts/CLIENT9/deposit    {"cash":"1000.00"} or {"symbol":"AAPL","quantity":100}
+//	POST /accounts/CLIENT9/withdraw   the same
+//	GET  /account?id=CLIENT9          balances and buying power
+//
+// A withdrawal takes only what is free: cash not held for open buys or
+// owed for unsettled ones, shares not held for open sells or owed for
+// unsettled ones, and nothing while the account has a margin call.
+//
+// Changes are sequenced through the first shard's ring buffer, checked and
+// applied on its processor goroutine, and logged as AccountEvents (see
+// disruptor/accounts.go) with who asked for them, so the event log holds
+// every movement of money in order with the trading around it. They are
+// audited as account.open, account.deposit and account.withdraw. The
+// balances themselves are the clearing house's, kept by its journal.
+
+// accountsPath prefixes the deposit and withdrawal endpoints.
+const accountsPath = "/accounts/"
+
+// AccountRequest is the body of POST /accounts, /deposit and /withdraw.
+type AccountRequest struct {
+	ID       string `json:"id,omitempty"`   // POST /accounts only
+	Cash     string `json:"cash,omitempty"` // Dollars
+	Symbol   string `json:"symbol,omitempty"`
+	Quantity int64  `json:"quantity,omitempty"` // Shares of Symbol
+}
+
+// accountResponse is an account's balances in API responses.
+func (s *Server) accountResponse(account *settlement.Account) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       account.ID,
+		"cash":     orders.FormatPrice(account.Cash),
+		"fees":     orders.FormatPrice(account.Fees),
+		"holdings": account.Holdings,
+
+		"buying_power": orders.FormatPrice(s.clearingHouse.BuyingPower(account.ID)),
+		"collateral":   orders.FormatPrice(account.Collateral),
+	}
+}
+
+// applyAccount applies a logged account change to the clearing house.
+// Runs on the processor goroutine.
+func applyAccount(clearingHouse *settlement.ClearingHouse, change *events.AccountEvent) error {
+	switch change.Action {
+	case events.AccountOpened:
+		return clearingHouse.OpenAccount(change.AccountID, change.Cash)
+	case events.AccountDeposit:
+		return clearingHouse.Deposit(change.AccountID, change.Cash, change.Symbol, change.Shares)
+	case events.AccountWithdrawal:
+		return clearingHouse.Withdraw(change.AccountID, change.Cash, change.Symbol, change.Shares)
+	}
+	return fmt.Errorf("unknown account action %q", change.Action)
+}
+
+// parseAccountRequest converts a request body into an account change.
+func (s *Server) parseAccountRequest(r *http.Request, action, accountID string) (*events.AccountEvent, error) {
+	var req AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+	change := &events.AccountEvent{
+		AccountID: accountID,
+		Action:    action,
+		Symbol:    req.Symbol,
+		Shares:    req.Quantity,
+		Actor:     adminActor(r),
+	}
+	if action == events.AccountOpened {
+		change.AccountID = req.ID
+		if req.ID == "" || strings.Contains(req.ID, "/") {
+			return nil, errors.New("invalid id")
+		}
+		if req.Symbol != "" || req.Quantity != 0 {
+			return nil, errors.New("accounts open with cash only: deposit shares once open")
+		}
+	}
+	if req.Cash != "" {
+		cash, err := orders.ParsePrice(req.Cash)
+		if err != nil || cash < 0 {
+			return nil, fmt.Errorf("invalid cash %q", req.Cash)
+		}
+		change.Cash = cash
+	}
+	switch {
+	case req.Quantity < 0:
+		return nil, fmt.Errorf("invalid quantity %d", req.Quantity)
+	case req.Quantity > 0 && req.Symbol == "":
+		return nil, errors.New("symbol required with quantity")
+	case req.Symbol != "" && req.Quantity == 0:
+		return nil, errors.New("quantity required with symbol")
+	case action != events.AccountOpened && change.Cash == 0 && change.Shares == 0:
+		return nil, errors.New("cash or quantity required")
+	}
+	if req.Symbol != "" {
+		if _, ok := s.refData.Get(req.Symbol); !ok {
+			return nil, fmt.Errorf("unknown symbol: %s", req.Symbol)
+		}
+	}
+	return change, nil
+}
+
+// handleOpenAccount opens an account.
+func (s *Server) handleOpenAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.changeAccount(w, r, events.AccountOpened, "")
+}
+
+// handleAccountMovement deposits into or withdraws from an account, e.g.
+// POST /accounts/CLIENT9/deposit.
+func (s *Server) handleAccountMovement(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, accountsPath), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	var action string
+	switch parts[1] {
+	case "deposit":
+		action = events.AccountDeposit
+	case "withdraw":
+		action = events.AccountWithdrawal
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.changeAccount(w, r, action, parts[0])
+}
+
+// changeAccount sequences an account change and answers with the account
+// as it leaves it.
+func (s *Server) changeAccount(w http.ResponseWriter, r *http.Request, action, accountID string) {
+	auditAction := map[string]string{
+		events.AccountOpened:     "account.open",
+		events.AccountDeposit:    "account.deposit",
+		events.AccountWithdrawal: "account.withdraw",
+	}[action]
+
+	change, err := s.parseAccountRequest(r, action, accountID)
+	if err != nil {
+		s.audit(adminActor(r), auditAction, accountID, nil, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	params := map[string]string{"cash": orders.FormatPrice(change.Cash)}
+	if change.Shares > 0 {
+		params["symbol"] = change.Symbol
+		params["quantity"] = strconv.FormatInt(change.Shares, 10)
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:    disruptor.RequestTypeAccount,
+		Account: change,
+	})
+	if response == nil {
+		err = errors.New(submitErrorMessage(status))
+	} else if response.Error != nil {
+		err = response.Error
+		switch {
+		case errors.Is(err, settlement.ErrAccountNotFound):
+			status = http.StatusNotFound
+		default:
+			status = http.StatusConflict // Taken, or more than the account has free
+		}
+	}
+	s.audit(adminActor(r), auditAction, change.AccountID, params, err)
+	if err != nil {
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Account %s: %s %v", change.AccountID, strings.ToLower(action), params)
+	status = http.StatusOK
+	if action == events.AccountOpened {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, s.accountResponse(s.clearingHouse.GetAccount(change.AccountID)))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+)
+
+// Call Auctions
+//
+// An auction call (see matching/auction.go) is driven by the symbol's
+// session state:
+//
+//	POST /admin/symbol/state?symbol=AAPL&state=AUCTION   start the call
+//	POST /admin/symbol/state?symbol=AAPL&state=OPEN      uncross, then trade
+//
+// Entering AUCTION starts the call in the engine before the state changes,
+// so every limit order accepted for the call rests. Leaving it for any
+// other state uncrosses the book first; the fills go through risk and
+// market data like any trade, and the state change response carries them:
+//
+//	{"symbol":"AAPL","state":"OPEN","uncross":{"price":"$150.02","volume":1200,...}}
+//
+// The reference price is the last trade (the risk checker's mark). During
+// the call the indicative price is published after every book change and
+// served by GET /book/auction?symbol=AAPL.
+
+// auctionInfo is an auction state in API responses.
+type auctionInfo struct {
+	Symbol        string `json:"symbol"`
+	InCall        bool   `json:"in_call"`
+	RefPrice      string `json:"ref_price,omitempty"`
+	Price         string `json:"price,omitempty"` // Indicative, or the uncross price once the call ends
+	Volume        int64  `json:"volume"`
+	Imbalance     int64  `json:"imbalance"`
+	ImbalanceSide string `json:"imbalance_side,omitempty"`
+}
+
+func newAuctionInfo(state marketdata.AuctionState) auctionInfo {
+	info := auctionInfo{
+		Symbol:    state.Symbol,
+		InCall:    state.InCall,
+		Volume:    state.Volume,
+		Imbalance: state.Imbalance,
+	}
+	if state.RefPrice > 0 {
+		info.RefPrice = orders.FormatPrice(state.RefPrice)
+	}
+	if state.Price > 0 {
+		info.Price = orders.FormatPrice(state.Price)
+	}
+	if state.Imbalance > 0 {
+		info.ImbalanceSide = state.ImbalanceSide.String()
+	}
+	return info
+}
+
+func newAuctionState(info matching.AuctionInfo, inCall bool) marketdata.AuctionState {
+	return marketdata.AuctionState{
+		Symbol:        info.Symbol,
+		InCall:        inCall,
+		RefPrice:      info.RefPrice,
+		Price:         info.Price,
+		Volume:        info.Volume,
+		Imbalance:     info.Imbalance,
+		ImbalanceSide: info.ImbalanceSide,
+		Timestamp:     orders.Now(),
+	}
+}
+
+// publishAuction publishes a symbol's indicative auction. Called on the
+// processor goroutine.
+func (s *Server) publishAuction(info matching.AuctionInfo) {
+	s.publisher.PublishAuction(newAuctionState(info, true))
+}
+
+// setSymbolState changes a symbol's session state, starting or uncrossing
+// its auction call when the state enters or leaves AUCTION. Returns the
+// uncross if there was one, and the HTTP status of a failure.
+func (s *Server) setSymbolState(symbol string, state refdata.SessionState) (*matching.UncrossResult, int, error) {
+	inst, ok := s.refData.Get(symbol)
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	calling := inst.State == refdata.SessionAuction
+
+	var uncross *matching.UncrossResult
+	switch {
+	case state == refdata.SessionAuction && !calling:
+		if _, status, err := s.auctionRequest(&disruptor.OrderRequest{
+			Type:     disruptor.RequestTypeStartAuction,
+			Symbol:   symbol,
+			RefPrice: s.riskChecker.GetReferencePrice(symbol),
+		}); err != nil {
+			return nil, status, err
+		}
+
+	case calling && state != refdata.SessionAuction:
+		result, status, err := s.auctionRequest(&disruptor.OrderRequest{
+			Type:   disruptor.RequestTypeUncross,
+			Symbol: symbol,
+		})
+		if err != nil {
+			return nil, status, err
+		}
+		uncross = result
+	}
+
+	if err := s.refData.SetState(symbol, state); err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return uncross, http.StatusOK, nil
+}
+
+// auctionRequest sequences an auction start or uncross.
+func (s *Server) auctionRequest(request *disruptor.OrderRequest) (*matching.UncrossResult, int, error) {
+	response, status := s.submitRequest(request)
+	if response == nil {
+		return nil, status, errors.New(submitErrorMessage(status))
+	}
+	if !response.Success {
+		return nil, http.StatusConflict, response.Error
+	}
+	return response.Auction, http.StatusOK, nil
+}
+
+// postUncross records an uncross's fills for risk and market data, and
+// publishes the final auction state.
+func (s *Server) postUncross(result *matching.UncrossResult) marketdata.AuctionState {
+	for _, fill := range result.Fills {
+		s.recordFill(fill)
+	}
+	if len(result.Fills) > 0 {
+		s.riskChecker.CheckLossLimits(result.Symbol)
+		s.clearingHouse.CheckMargin(result.Symbol)
+	}
+	s.publishMarketData(result.Symbol, result.Fills)
+
+	state := newAuctionState(result.AuctionInfo, false)
+	s.publisher.PublishAuction(state)
+	return state
+}
+
+// handleAuction returns a symbol's latest auction state: the indicative
+// price during a call, or how the last call uncrossed.
+func (s *Server) handleAuction(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	state, published := s.publisher.LatestAuction(symbol)
+	if !published {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "no auction for symbol",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, newAuctionInfo(state))
+}
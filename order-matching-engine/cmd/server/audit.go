@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/audit"
+)
+
+// Admin Audit Log
+//
+// Every privileged operation is recorded in the audit log (see package
+// audit), whether it succeeded or was refused:
+//
+//	action             target    from
+//	symbol.state       symbol    POST /admin/symbol/state
+//	symbol.migrate     symbol    POST /admin/symbol/migrate
+//	symbol.import      symbol    POST /admin/symbol/import
+//	risk.profile       account   POST /admin/risk/profile
+//	risk.limits        account   PUT or DELETE /admin/risk/{account}
+//	risk.restrict      symbol    POST /admin/restrictions ("TRADER1/TSLA" for an account's list)
+//	risk.unrestrict    symbol    DELETE /admin/restrictions
+//	risk.reinstate     account   POST /admin/risk/reinstate
+//	risk.kill          account   POST /admin/kill
+//	risk.kill_switch   account   daily loss or order rate limit breached (actor "system")
+//	fees.tier          account   POST /admin/fees/tier
+//	account.collateral account   POST /admin/collateral
+//	tape.reveal        code      GET /admin/tape/counterparty
+//	symbol.circuit     symbol    price move paused or halted it (actor "system")
+//	symbol.journal     symbol    event log damage halted it (actor "system")
+//	journal.resume     symbol    POST /admin/journal/resume
+//	stress.run                   POST /admin/stress
+//	degrade.override             POST /admin/degrade
+//	replication.promote          standby took over (POST /admin/replication/promote, or "system" on -failover-after)
+//
+// The actor is the X-Admin-User header with the caller's address, e.g.
+// "alice@10.0.0.5:51234". The header is not authenticated: it names the
+// operator for the record, the network in front of /admin decides who may
+// call it. On a listener requiring client certificates (see listeners.go)
+// the verified certificate's common name is the operator instead, and the
+// header is ignored.
+
+// adminUserHeader names the operator performing an admin request.
+const adminUserHeader = "X-Admin-User"
+
+// adminActor identifies who made an admin request.
+func adminActor(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName + "@" + r.RemoteAddr
+	}
+	if user := r.Header.Get(adminUserHeader); user != "" {
+		return user + "@" + r.RemoteAddr
+	}
+	return r.RemoteAddr
+}
+
+// audit records an admin action. outcome is nil for success.
+func (s *Server) audit(actor, action, target string, params map[string]string, outcome error) {
+	recordAudit(s.auditLog, s.alerter, actor, action, target, params, outcome)
+}
+
+// recordAudit appends an entry to the audit log. A failed write is alerted
+// rather than failing the action, which has already happened.
+func recordAudit(auditLog *audit.Log, alerter *alerts.Alerter, actor, action, target string, params map[string]string, outcome error) {
+	entry := audit.Entry{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Params:  params,
+		Outcome: "ok",
+	}
+	if outcome != nil {
+		entry.Outcome = outcome.Error()
+	}
+	if _, err := auditLog.Record(entry); err != nil {
+		alerter.Raise(alerts.KindAuditWrite, action, alerts.SeverityCritical,
+			"failed to audit %s on %q by %s: %v", action, target, actor, err)
+	}
+}
+
+// handleAudit queries the audit log, e.g.
+// GET /admin/audit?action=risk.&target=TRADER1&since=2024-01-02T09:30:00Z&limit=50
+//
+// All parameters are optional: action matches exactly, or as a prefix when
+// it ends in "."; since and until are RFC 3339 times; limit keeps the most
+// recent matches. Entries are returned oldest first.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+		Actor:  query.Get("actor"),
+	}
+	for name, field := range map[string]*int64{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{
+					"error": "invalid " + name + ": must be an RFC 3339 time",
+				})
+				return
+			}
+			*field = parsed.UnixNano()
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid limit",
+			})
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	entries, err := s.auditLog.Query(filter)
+	if err != nil {
+		log.Printf("Audit log query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
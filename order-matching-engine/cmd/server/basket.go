@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/migration"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Basket Orders
+//
+// POST /basket submits legs across several symbols with all-or-none
+// acceptance: every leg must pass parsing, reference data validation, risk
+// checks and engine validation, or none is entered. Accepted legs then
+// execute independently.
+//
+//	→ {"account_id":"MM1","legs":[
+//	     {"symbol":"AAPL","side":"buy","type":"limit","price":"150.00","quantity":100},
+//	     {"symbol":"MSFT","side":"sell","type":"limit","price":"300.00","quantity":50}]}
+//	← {"success":true,"legs":[{...same as POST /order...},{...}]}
+
+// maxBasketLegs bounds the work a single ring buffer request can carry.
+const maxBasketLegs = 100
+
+// BasketRequest represents a basket submission request.
+type BasketRequest struct {
+	AccountID string         `json:"account_id"` // Default for legs without their own
+	Legs      []OrderRequest `json:"legs"`
+}
+
+// BasketResponse represents a basket response.
+type BasketResponse struct {
+	Success      bool            `json:"success"`
+	Legs         []OrderResponse `json:"legs,omitempty"`
+	RejectCode   string          `json:"reject_code,omitempty"`
+	RejectReason string          `json:"reject_reason,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+func (s *Server) handleBasket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BasketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BasketResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request: %v", err),
+		})
+		return
+	}
+
+	status, resp := s.executeBasket(req)
+	writeJSON(w, status, resp)
+}
+
+// executeBasket parses and risk-checks every leg, then sequences the basket
+// as a single ring buffer request.
+func (s *Server) executeBasket(req BasketRequest) (int, BasketResponse) {
+	if err := s.admitOrder(); err != nil {
+		return http.StatusServiceUnavailable, BasketResponse{Success: false, Error: err.Error()}
+	}
+	if len(req.Legs) == 0 || len(req.Legs) > maxBasketLegs {
+		return http.StatusBadRequest, BasketResponse{
+			Success: false,
+			Error:   fmt.Sprintf("basket must have between 1 and %d legs", maxBasketLegs),
+		}
+	}
+
+	legs := make([]*orders.Order, len(req.Legs))
+	for i, legReq := range req.Legs {
+		if legReq.AccountID == "" {
+			legReq.AccountID = req.AccountID
+		}
+		leg, err := parseOrderRequest(legReq)
+		if err != nil {
+			return http.StatusBadRequest, BasketResponse{
+				Success: false,
+				Error:   fmt.Sprintf("leg %d: %v", i, err),
+			}
+		}
+		if reject := s.refData.Validate(leg); reject != nil {
+			return http.StatusBadRequest, BasketResponse{
+				Success:      false,
+				RejectCode:   string(reject.Code),
+				RejectReason: fmt.Sprintf("leg %d (%s): %s", i, leg.Symbol, reject.Reason),
+			}
+		}
+		legs[i] = leg
+	}
+
+	// Legs are executed together on one engine, so a basket cannot include
+	// a symbol that moved to another instance, nor span this instance's
+	// shards (rejected when sequenced)
+	symbols := make([]string, len(legs))
+	for i, leg := range legs {
+		symbols[i] = leg.Symbol
+	}
+	leave, err := s.migrations.Enter(symbols...)
+	var moved *migration.MovedError
+	if errors.As(err, &moved) {
+		return http.StatusBadRequest, BasketResponse{
+			Success: false,
+			Error:   fmt.Sprintf("%v; a basket cannot span shards", moved),
+		}
+	}
+	if err != nil {
+		return http.StatusServiceUnavailable, BasketResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	defer leave()
+
+	// All-or-none at the risk layer: one failing leg rejects the basket
+	if i, riskResult := s.riskChecker.CheckBasket(legs); !riskResult.Passed {
+		return http.StatusBadRequest, BasketResponse{
+			Success:      false,
+			RejectCode:   riskResult.Code,
+			RejectReason: fmt.Sprintf("leg %d (%s): %s", i, legs[i].Symbol, riskResult.Reason),
+		}
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type: disruptor.RequestTypeBasket,
+		Legs: legs,
+	})
+	if response == nil {
+		return status, BasketResponse{
+			Success: false,
+			Error:   submitErrorMessage(status),
+		}
+	}
+
+	basket := response.Basket
+	if !basket.Accepted {
+		return http.StatusBadRequest, BasketResponse{
+			Success:      false,
+			RejectReason: basket.RejectReason,
+		}
+	}
+
+	resp := BasketResponse{
+		Success: true,
+		Legs:    make([]OrderResponse, len(legs)),
+	}
+	for i, leg := range legs {
+		result := basket.Legs[i]
+		if !result.Accepted {
+			resp.Legs[i] = OrderResponse{
+				Success:      false,
+				OrderID:      leg.ID,
+				RejectReason: result.RejectReason,
+			}
+			continue
+		}
+		resp.Legs[i] = s.postTrade(leg, result)
+	}
+	return http.StatusOK, resp
+}
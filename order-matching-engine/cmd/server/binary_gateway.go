@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/gateway"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Binary Order Entry
+//
+// -binary-addr serves the binary order entry protocol of internal/gateway
+// alongside HTTP. Its orders take the same path as POST /order: reference
+// data validation, risk checks, the ring buffer and post-trade processing.
+// Only the encoding differs, and that the client streams orders on one
+// session instead of making a request per order.
+//
+// As on WebSocket sessions, the session reports its own orders' outcomes:
+// acceptance, the fills of an incoming order and cancels. Fills of a
+// resting order against a later one are not sent; see GET /orders.
+
+// binaryHandler carries out binary gateway requests.
+type binaryHandler struct {
+	s *Server
+}
+
+// Authorize allows accounts the clearing house knows.
+func (h binaryHandler) Authorize(accountID string) bool {
+	return h.s.clearingHouse.GetAccount(accountID) != nil
+}
+
+// EnterOrder executes an order like POST /order.
+func (h binaryHandler) EnterOrder(order *orders.Order) gateway.Result {
+	status, resp := h.s.executeOrder(order)
+	if !resp.Success {
+		return gateway.Result{Reject: rejectText(status, resp.RejectCode, resp.RejectReason, resp.Error)}
+	}
+
+	result := gateway.Result{OrderID: resp.OrderID}
+	if status == http.StatusOK {
+		// Unfilled and no longer open: the rest of an IOC, FOK or market order
+		result.Cancelled = resp.RemainingQty - resp.LeavesQty
+	}
+	for _, fill := range resp.Fills {
+		price, err := parseFormattedPrice(fill.Price)
+		if err != nil {
+			continue
+		}
+		result.Fills = append(result.Fills, gateway.Fill{TradeID: fill.TradeID, Price: price, Quantity: fill.Quantity})
+	}
+	return result
+}
+
+// CancelOrder cancels an order like POST /cancel.
+func (h binaryHandler) CancelOrder(symbol string, orderID uint64) gateway.Result {
+	status, resp := h.s.cancelOrder(symbol, "", orderID)
+	cancelled, reason, ok := cancelOutcome(status, resp)
+	if !ok {
+		return gateway.Result{Reject: rejectText(status, "", "", reason)}
+	}
+	return gateway.Result{OrderID: orderID, Cancelled: cancelled}
+}
+
+// cancelOutcome reads the response body of cancelOrder: the cancelled
+// quantity, or the error if the cancel failed.
+func cancelOutcome(status int, resp interface{}) (cancelled int64, reason string, ok bool) {
+	body, _ := resp.(map[string]interface{})
+	if status != http.StatusOK || body == nil {
+		switch body := resp.(type) {
+		case map[string]string:
+			reason = body["error"]
+		case map[string]interface{}:
+			reason, _ = body["error"].(string)
+		}
+		return 0, reason, false
+	}
+
+	// cancelled_qty is an int64 here, or a float64 decoded from a shard the
+	// symbol migrated to
+	switch qty := body["cancelled_qty"].(type) {
+	case int64:
+		cancelled = qty
+	case float64:
+		cancelled = int64(qty)
+	}
+	return cancelled, "", true
+}
+
+// CancelSession cancels a session's orders, for cancel on disconnect.
+func (h binaryHandler) CancelSession(sessionID string) []gateway.Result {
+	cancelled := h.s.cancelSessionOrders(sessionID, "cancel on disconnect")
+	results := make([]gateway.Result, len(cancelled))
+	for i, order := range cancelled {
+		results[i] = gateway.Result{OrderID: order.ID, Cancelled: order.RemainingQty()}
+	}
+	return results
+}
+
+// rejectText is the reject message for a failed request.
+func rejectText(status int, code, reason, errText string) string {
+	text := reason
+	if text == "" {
+		text = errText
+	}
+	if text == "" {
+		text = http.StatusText(status)
+	}
+	if code != "" {
+		text = code + ": " + text
+	}
+	return text
+}
+
+// parseFormattedPrice parses a price formatted by orders.FormatPrice, such
+// as "$150.25" or the rebate "-$0.90", back into cents.
+func parseFormattedPrice(s string) (int64, error) {
+	if rest, negative := strings.CutPrefix(s, "-"); negative {
+		p, err := parseFormattedPrice(rest)
+		return -p, err
+	}
+	dollars, cents, ok := strings.Cut(strings.TrimPrefix(s, "$"), ".")
+	if !ok || len(cents) != 2 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	d, err := strconv.ParseInt(dollars, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	c, err := strconv.ParseInt(cents, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return d*100 + c, nil
+}
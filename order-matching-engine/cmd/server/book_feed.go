@@ -0,0 +1,211 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Book Feed
+//
+// The sequenced book feed (see marketdata/book_updates.go) is served two
+// ways:
+//
+//	GET /book/updates?symbol=AAPL&from=42   backfill only, e.g. after a gap
+//	GET /ws/book?symbol=AAPL&from=42        backfill, then live updates
+//
+// Both answer with the updates from seq 42 if they are still kept, or with
+// an image of the book if not (or if from is omitted):
+//
+//	{"type":"backfill","symbol":"AAPL","seq":57,
+//	 "image":{"bids":[{"price":"$150.00","quantity":300,"orders":3}],"asks":[...],"checksum":2871365208},
+//	 "updates":[{"seq":43,"side":"SELL","price":"$150.05","quantity":0,"orders":0,"checksum":913726561},...]}
+//	{"type":"update","symbol":"AAPL","seq":58,"side":"BUY",...,"checksum":1520648123}
+//
+// An image's checksum, and each update's, is the CRC-32 of the top 10
+// levels per side as they stand (see marketdata/checksum.go). A subscriber
+// whose own book doesn't hash to it has gone wrong, and backfills from 0.
+//
+// A WebSocket subscriber that falls too far behind has updates dropped
+// rather than slowing the server. It sees the gap in seq and fetches
+// /book/updates from the first seq it is missing, or reconnects with from.
+
+// bookLevelInfo is one price level in a book feed message.
+type bookLevelInfo struct {
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+	Orders   int    `json:"orders"`
+}
+
+// bookImageInfo is a full book in a backfill.
+type bookImageInfo struct {
+	Bids     []bookLevelInfo `json:"bids"`
+	Asks     []bookLevelInfo `json:"asks"`
+	Checksum uint32          `json:"checksum"`
+}
+
+// bookUpdateInfo is one level change.
+type bookUpdateInfo struct {
+	Type     string `json:"type,omitempty"` // "update" when sent live
+	Symbol   string `json:"symbol,omitempty"`
+	Seq      uint64 `json:"seq"`
+	Side     string `json:"side"`
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+	Orders   int    `json:"orders"`
+	Checksum uint32 `json:"checksum"`
+}
+
+// backfillInfo is a backfill response.
+type backfillInfo struct {
+	Type    string           `json:"type"`
+	Symbol  string           `json:"symbol"`
+	Seq     uint64           `json:"seq"`
+	Image   *bookImageInfo   `json:"image,omitempty"`
+	Updates []bookUpdateInfo `json:"updates"`
+}
+
+// publishBook sends a symbol's top levels to the book feed.
+func (s *Server) publishBook(book *orderbook.OrderBook) {
+	levels := func(depth []*orderbook.PriceLevel) []marketdata.PriceLevel {
+		out := make([]marketdata.PriceLevel, len(depth))
+		for i, level := range depth {
+			out[i] = marketdata.PriceLevel{Price: level.Price, Quantity: level.TotalQty, Count: level.Count()}
+		}
+		return out
+	}
+	s.publisher.PublishBook(marketdata.L2Depth{
+		Symbol:    book.Symbol(),
+		Bids:      levels(book.GetBidDepth(marketdata.DefaultBookDepth)),
+		Asks:      levels(book.GetAskDepth(marketdata.DefaultBookDepth)),
+		Timestamp: orders.Now(),
+	})
+}
+
+func newBookUpdateInfo(update marketdata.BookUpdate) bookUpdateInfo {
+	return bookUpdateInfo{
+		Seq:      update.Seq,
+		Side:     update.Side.String(),
+		Price:    orders.FormatPrice(update.Price),
+		Quantity: update.Quantity,
+		Orders:   update.Count,
+		Checksum: update.Checksum,
+	}
+}
+
+func newBackfillInfo(backfill marketdata.Backfill) backfillInfo {
+	info := backfillInfo{
+		Type:    "backfill",
+		Symbol:  backfill.Symbol,
+		Seq:     backfill.Seq,
+		Updates: make([]bookUpdateInfo, len(backfill.Updates)),
+	}
+	for i, update := range backfill.Updates {
+		info.Updates[i] = newBookUpdateInfo(update)
+	}
+	if backfill.Image != nil {
+		levels := func(depth []marketdata.PriceLevel) []bookLevelInfo {
+			out := make([]bookLevelInfo, len(depth))
+			for i, level := range depth {
+				out[i] = bookLevelInfo{Price: orders.FormatPrice(level.Price), Quantity: level.Quantity, Orders: level.Count}
+			}
+			return out
+		}
+		info.Image = &bookImageInfo{
+			Bids:     levels(backfill.Image.Bids),
+			Asks:     levels(backfill.Image.Asks),
+			Checksum: backfill.Image.Checksum,
+		}
+	}
+	return info
+}
+
+// bookFeedParams reads the symbol and from parameters of a book feed request.
+func (s *Server) bookFeedParams(w http.ResponseWriter, r *http.Request) (string, uint64, bool) {
+	symbol := r.URL.Query().Get("symbol")
+	if s.engineFor(symbol).GetOrderBook(symbol) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "symbol not found",
+		})
+		return "", 0, false
+	}
+
+	var from uint64
+	if v := r.URL.Query().Get("from"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid from: must be a sequence number",
+			})
+			return "", 0, false
+		}
+		from = parsed
+	}
+	return symbol, from, true
+}
+
+// handleBookUpdates returns a book feed backfill.
+func (s *Server) handleBookUpdates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbol, from, ok := s.bookFeedParams(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newBackfillInfo(s.publisher.BookBackfill(symbol, from)))
+}
+
+// handleBookFeed streams a symbol's book feed over a WebSocket: the
+// backfill, then every update after it.
+func (s *Server) handleBookFeed(w http.ResponseWriter, r *http.Request) {
+	symbol, from, ok := s.bookFeedParams(w, r)
+	if !ok {
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	backfill, sub := s.publisher.SubscribeBook(symbol, from)
+	defer sub.Close()
+
+	// The feed is one way; reading only notices the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(newBackfillInfo(backfill)); err != nil {
+		return
+	}
+	for {
+		select {
+		case update, ok := <-sub.C:
+			if !ok {
+				return // Publisher closed, or evicted as stalled
+			}
+			info := newBookUpdateInfo(update)
+			info.Type = "update"
+			info.Symbol = update.Symbol
+			if err := conn.WriteJSON(info); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Market Calendars
+//
+// Each symbol trades on a market (-market, default XNYS) whose holidays
+// and session hours come from -calendar (see package calendar). Settlement
+// dates count that market's business days, and the trading day follows its
+// sessions (see sessions.go). Symbol states are still the operator's:
+// whatever opens and closes symbols through /admin/symbol/state asks
+// GET /calendar whether the market is in session:
+//
+//	GET /calendar?symbol=AAPL
+//	{"market":"XNYS","date":"2026-11-26","business_day":false,"holiday":"Thanksgiving Day",
+//	 "in_session":false,"session_open":"2026-11-26T09:30:00-05:00","session_close":"2026-11-26T16:00:00-05:00",
+//	 "next_business_day":"2026-11-27","settle_date":"2026-12-01","settlement_cycle":"T+2","holidays":[...]}
+
+// calendarHoliday is a holiday in API responses.
+type calendarHoliday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// handleCalendar returns the business day calendar of a symbol's market,
+// or of ?market=, as of today.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	market := r.URL.Query().Get("market")
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		inst, ok := s.refData.Get(symbol)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": "unknown symbol: " + symbol,
+			})
+			return
+		}
+		market = inst.Market
+	}
+	if market == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "symbol or market required",
+		})
+		return
+	}
+
+	cal := s.calendars.Get(market)
+	now := time.Now()
+	holidays := []calendarHoliday{}
+	for _, h := range cal.Holidays(now) {
+		holidays = append(holidays, calendarHoliday{Date: h[0], Name: h[1]})
+	}
+	open, close := cal.Session(now)
+	response := map[string]interface{}{
+		"market":            market,
+		"date":              cal.Date(now),
+		"business_day":      cal.IsBusinessDay(now),
+		"in_session":        cal.IsOpen(now),
+		"session_open":      open,
+		"session_close":     close,
+		"next_business_day": cal.Date(cal.AddBusinessDays(now, 1)),
+		"holidays":          holidays,
+	}
+	if name, ok := cal.Holiday(now); ok {
+		response["holiday"] = name
+	}
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		response["settle_date"] = cal.Date(s.clearingHouse.SettleDate(symbol, now))
+		response["settlement_cycle"] = fmt.Sprintf("T+%d", s.clearingHouse.SettlementDays())
+	}
+	writeJSON(w, http.StatusOK, response)
+}
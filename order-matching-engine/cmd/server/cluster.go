@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/consensus"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Raft Cluster
+//
+// With -raft-peers the server is one node of a cluster of matching engines.
+// Requests that change engine state are committed through Raft (see
+// internal/consensus) before any node's ring buffer sees them:
+//
+//	handler ──propose──▶ leader ──AppendEntries──▶ followers
+//	                                  │ committed (majority on disk)
+//	                                  ▼
+//	        apply, on every node in commit order ──▶ ring buffer ──▶ engine
+//
+// Every engine starts empty and is fed the same requests in the same
+// order, stamped with the proposing node's time, so every node holds the
+// same books, order IDs and fills, timestamps included. Only the
+// leader takes orders, cancels, replaces and baskets; followers refuse
+// them with 503 and the leader's address, and serve reads from their own
+// copy of the books. When the leader fails the others elect a new one
+// within an election timeout, and clients move to it.
+//
+// The node that took a request answers it once its own engine has applied
+// it. The others run the post-trade step of new orders (risk positions,
+// the tape, market data) themselves, so a new leader's risk checks and
+// feeds carry on from the same fills. A request in flight when its leader
+// fails times out (504): it may still commit under the next leader, so
+// check with /orders before resending it.
+//
+// Reads (open orders, order status), stress probes and timer ticks stay
+// local. Ticks come from each node's own clock, so timers that change
+// state act only through the leader, as DAY order expiry does (see
+// sessions.go): when a dead man's switch expires or the settlement cycle
+// is due, the leader proposes the request that does it, and every node
+// applies it at the same point of the sequence. A follower's own timers
+// propose nothing:
+//
+//	leader timer fires ──TripDeadMan / Settle──▶ propose ──▶ committed ──▶ every node's ring buffer
+
+// proposalTTL is how long an unanswered proposal is remembered; its
+// handler has long since timed out.
+const proposalTTL = 30 * time.Second
+
+// clusterCommand is a request as committed to the Raft log.
+type clusterCommand struct {
+	Origin   uint64 // Random per process of the node that proposed it
+	Proposal uint64 // Counter within Origin
+	Request  *disruptor.OrderRequest
+}
+
+// cluster connects a server to its Raft node.
+type cluster struct {
+	s      *Server
+	node   *consensus.Node
+	ln     net.Listener
+	origin uint64
+
+	mu        sync.Mutex
+	next      uint64
+	pending   map[uint64]pendingProposal // Proposal -> handler waiting on it
+	lastSweep time.Time
+}
+
+// pendingProposal is a request this node proposed, waiting to commit.
+type pendingProposal struct {
+	request    *disruptor.OrderRequest
+	responseCh chan *disruptor.OrderResponse
+	proposed   time.Time
+}
+
+// newCluster opens the Raft node for config and listens for its peers.
+func newCluster(s *Server, config consensus.Config) (*cluster, error) {
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	c := &cluster{
+		s:       s,
+		origin:  binary.BigEndian.Uint64(nonce[:]),
+		pending: make(map[uint64]pendingProposal),
+	}
+
+	node, err := consensus.Open(config, c.apply)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", config.Peers[config.ID])
+	if err != nil {
+		node.Close()
+		return nil, fmt.Errorf("failed to listen for raft peers on %s: %w", config.Peers[config.ID], err)
+	}
+	c.node, c.ln = node, ln
+	return c, nil
+}
+
+// start joins the cluster. The processors must be running: committed
+// requests are applied from here on.
+func (c *cluster) start() {
+	go func() {
+		if err := c.node.Serve(c.ln); err != nil {
+			log.Printf("Raft peer listener stopped: %v", err)
+		}
+	}()
+	c.node.Start()
+}
+
+// close leaves the cluster; nothing more is applied.
+func (c *cluster) close() error {
+	return c.node.Close()
+}
+
+// replicated reports whether a request changes engine state, and so must
+// be committed through Raft.
+func replicated(t disruptor.RequestType) bool {
+	return t.ChangesState()
+}
+
+// propose commits a request through Raft. Once it commits, this node's
+// engine applies it and answers on responseCh. Fails straight away, with a
+// *consensus.NotLeaderError, if this node is not the leader.
+func (c *cluster) propose(request *disruptor.OrderRequest, responseCh chan *disruptor.OrderResponse) error {
+	c.mu.Lock()
+	c.next++
+	proposal := c.next
+	c.sweep()
+	c.mu.Unlock()
+
+	// Encoded before the engine can touch the request, with the time every
+	// node's processor stamps it with (see disruptor/clock.go)
+	if request.Time == 0 {
+		request.Time = orders.Now()
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(clusterCommand{Origin: c.origin, Proposal: proposal, Request: request})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	// Registered first: the command can commit before Propose returns
+	c.mu.Lock()
+	c.pending[proposal] = pendingProposal{request: request, responseCh: responseCh, proposed: time.Now()}
+	c.mu.Unlock()
+	if _, err := c.node.Propose(buf.Bytes()); err != nil {
+		c.mu.Lock()
+		delete(c.pending, proposal)
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// proposeTimer commits the request of a processor timer that fired (see
+// disruptor.OnTimerRequest), if this node is the leader. A follower drops
+// it: the leader's own timer asks for the same request, and until it is
+// applied this node's timer asks again, in case it leads by then.
+func (c *cluster) proposeTimer(request *disruptor.OrderRequest) {
+	if _, isLeader := c.node.Leader(); !isLeader {
+		return
+	}
+	if err := c.propose(request, make(chan *disruptor.OrderResponse, 1)); err != nil {
+		log.Printf("Warning: timer request type %d not proposed: %v", request.Type, err)
+	}
+}
+
+// sweep forgets proposals that never committed. Must hold c.mu.
+func (c *cluster) sweep() {
+	if time.Since(c.lastSweep) < proposalTTL {
+		return
+	}
+	c.lastSweep = time.Now()
+	for proposal, p := range c.pending {
+		if time.Since(p.proposed) > proposalTTL {
+			delete(c.pending, proposal)
+		}
+	}
+}
+
+// apply feeds a committed request to the engine. It runs on the Raft
+// node's apply goroutine, so requests reach the ring buffer in commit
+// order.
+func (c *cluster) apply(applied consensus.Applied) {
+	var cmd clusterCommand
+	if err := gob.NewDecoder(bytes.NewReader(applied.Command)).Decode(&cmd); err != nil || cmd.Request == nil {
+		c.s.alerter.Raise(alerts.KindClusterDiverged, "", alerts.SeverityCritical,
+			"raft entry %d can't be decoded, this node's engine no longer matches the cluster: %v", applied.Index, err)
+		return
+	}
+
+	request, responseCh := cmd.Request, make(chan *disruptor.OrderResponse, 1)
+	if cmd.Origin == c.origin {
+		c.mu.Lock()
+		if p, ok := c.pending[cmd.Proposal]; ok {
+			request, responseCh = p.request, p.responseCh
+			delete(c.pending, cmd.Proposal)
+		}
+		c.mu.Unlock()
+	}
+	if request == cmd.Request {
+		go c.s.followCommitted(request, responseCh)
+	}
+
+	// A committed request must be applied: wait out backpressure
+	sequencer := c.s.shards.All()[0].Sequencer
+	for {
+		seq, err := sequencer.Next()
+		if err == nil {
+			sequencer.Publish(seq, request, responseCh)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// followCommitted runs the post-trade step of a new order another node
+// took, once this node's engine has applied it.
+func (s *Server) followCommitted(request *disruptor.OrderRequest, responseCh chan *disruptor.OrderResponse) {
+	if request.Type != disruptor.RequestTypeNewOrder {
+		return
+	}
+	select {
+	case response := <-responseCh:
+		if response.Success && !response.Duplicate {
+			s.postTrade(request.Order, response.Result)
+		}
+	case <-time.After(5 * time.Second):
+		log.Printf("Warning: Order %d committed by the cluster was not applied in time", request.Order.ID)
+	}
+}
+
+// sequenceError describes why a request could not be sequenced.
+func sequenceError(err error) string {
+	var notLeader *consensus.NotLeaderError
+	if errors.As(err, &notLeader) {
+		return notLeader.Error()
+	}
+	return "server busy, please retry"
+}
+
+// leaderOnly refuses order entry on a follower, naming the leader, before
+// any work is done on it.
+func (s *Server) leaderOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isOrderEntry(r) {
+			if leader, isLeader := s.cluster.node.Leader(); !isLeader {
+				status := s.cluster.node.Status()
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+					"error":       "not the leader",
+					"leader":      leader,
+					"leader_addr": status.LeaderAddr,
+				})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isOrderEntry reports whether r enters, replaces or cancels orders.
+// Cancels are sent with POST or DELETE.
+func isOrderEntry(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/order", "/order/replace", "/basket":
+		return r.Method == http.MethodPost
+	case "/cancel":
+		return r.Method == http.MethodPost || r.Method == http.MethodDelete
+	}
+	return false
+}
+
+// handleCluster reports this node's view of the Raft cluster.
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.cluster == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"mode": "standalone"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cluster.node.Status())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/pkg/degrade"
+)
+
+// Graceful Degradation
+//
+// One degrade.Controller decides how the whole server behaves under load
+// (see pkg/degrade), and each component applies it:
+//
+//	gateway     shedHTTP refuses low priority requests (reads) with 503
+//	engine      admitOrder refuses new orders, replaces and baskets with 503;
+//	            cancels are always sequenced
+//	market data the publisher conflates quotes and depth
+//
+// The load is the fullest shard's ring buffer occupancy, sampled every
+// degradeSample. Operators can hold a level with POST /admin/degrade, and
+// shutdown enters drain. Every 503 is safe to retry: nothing was sequenced.
+
+// degradeSample is how often ring buffer occupancy is fed to the controller.
+const degradeSample = 50 * time.Millisecond
+
+// watchLoad feeds ring buffer occupancy to the controller until stop closes.
+func (s *Server) watchLoad(stop <-chan struct{}) {
+	ticker := time.NewTicker(degradeSample)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var occupancy float64
+			for _, sh := range s.shards.All() {
+				if o := sh.RingBuffer.Occupancy(); o > occupancy {
+					occupancy = o
+				}
+			}
+			s.degrade.Observe(occupancy)
+		}
+	}
+}
+
+// onDegrade applies a level change to the components that don't check the
+// level per request.
+func (s *Server) onDegrade(from, to degrade.Level) {
+	log.Printf("Degradation level %s -> %s", from, to)
+	s.publisher.SetConflation(to.Conflates())
+	if to >= degrade.RejectNew && to != degrade.Drain {
+		s.alerter.Raise(alerts.KindDegraded, to.String(), alerts.SeverityCritical,
+			"server degraded from %s to %s: new orders refused", from, to)
+	}
+}
+
+// admitOrder returns an error if the current level refuses new orders,
+// replaces and baskets. Callers answer it with 503.
+func (s *Server) admitOrder() error {
+	if level := s.degrade.Level(); !level.Admits(degrade.Order) {
+		return fmt.Errorf("server degraded (%s): new orders refused, cancels accepted; please retry", level)
+	}
+	return nil
+}
+
+// requestClass is the priority of an HTTP request.
+func requestClass(r *http.Request) degrade.Class {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/admin/"), path == "/health", path == "/metrics", path == "/cancel":
+		return degrade.Critical
+	case r.Method == http.MethodPost && (path == "/order" || path == "/order/replace" || path == "/basket"):
+		return degrade.Order // Refused by the engine, which knows cancels apart
+	case path == "/ws":
+		return degrade.Critical // Order entry sessions; their orders are checked one by one
+	case path == "/ws/dropcopy":
+		return degrade.Critical // Risk desks watch accounts most closely under load
+	}
+	return degrade.LowPriority
+}
+
+// shedHTTP refuses the requests the current level doesn't admit before any
+// work is done on them.
+func (s *Server) shedHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := requestClass(r)
+		if class == degrade.LowPriority && !s.degrade.Admits(class) {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+				"error": fmt.Sprintf("server degraded (%s): low priority requests shed, please retry", s.degrade.Level()),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleDegrade reports the degradation level, or sets an operator
+// override, e.g. POST /admin/degrade?level=shed-low-priority (level=normal
+// clears it; the load can still raise the level above an override).
+func (s *Server) handleDegrade(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		name := r.URL.Query().Get("level")
+		level, err := degrade.ParseLevel(name)
+		if err == nil {
+			err = s.degrade.Override(level)
+		}
+		s.audit(adminActor(r), "degrade.override", "", map[string]string{"level": name}, err)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"degrade":             s.degrade.Status(),
+		"conflated_updates":   s.publisher.ConflatedUpdates(),
+		"subscribers":         s.publisher.SubscriberStats(),
+		"evicted_subscribers": s.publisher.EvictedSubscribers(),
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// Drop Copy
+//
+// A risk desk or back office watches an account's orders without being the
+// one trading them:
+//
+//	GET /ws/dropcopy?account=MM1
+//
+// streams every execution report for the account's orders, whichever
+// session or request caused it (see disruptor/reports.go and
+// internal/dropcopy), and its risk events:
+//
+//	{"type":"execution_report","seq":1,"account_id":"MM1","execution":{"exec_type":"NEW",
+//	 "order_id":42,"symbol":"AAPL","side":"BUY","status":"NEW","price":"$150.00",
+//	 "quantity":100,"cum_qty":0,"leaves_qty":100,"timestamp":...}}
+//	{"type":"execution_report","seq":2,...,"execution":{"exec_type":"TRADE",...,
+//	 "trade_id":7,"last_qty":60,"last_px":"$150.00","cum_qty":60,"avg_px":"$150.00","leaves_qty":40}}
+//	{"type":"risk_event","seq":3,"account_id":"MM1","risk":{"type":"kill_switch_tripped",...}}
+//
+// The feed starts from the time of subscribing; GET /orders?account= gives
+// the open orders to start from. Every node of a cluster runs the same
+// processors, so a follower serves the feed as well as the leader.
+
+// handleDropCopy streams an account's drop-copy feed over a WebSocket.
+func (s *Server) handleDropCopy(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+	if account == "" || s.clearingHouse.GetAccount(account) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "unknown account",
+		})
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	messages := s.dropCopy.Subscribe(account)
+	defer s.dropCopy.Unsubscribe(account, messages)
+	log.Printf("Drop copy for %s subscribed from %s", account, r.RemoteAddr)
+
+	// The feed is one way; reading only notices the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return // Hub closed
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
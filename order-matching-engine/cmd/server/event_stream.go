@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Event Stream
+//
+// Downstream services (surveillance, analytics) follow a shard's event log
+// as server-sent events instead of re-reading its files:
+//
+//	GET /events/stream?from=1&types=FILL,ORDER_CANCELLED&symbol=AAPL&shard=0
+//
+// from is the first sequence number to send; without it only events logged
+// from now on are. types and symbol keep the matching events. Each event is
+// sent as
+//
+//	id: 42
+//	event: FILL
+//	data: {"SequenceNum":42,"Timestamp":...,"TradeID":7,...}
+//
+// A stream that falls too far behind the log is ended (see events/tail.go).
+// An EventSource reconnects on its own, sending the last id it got as
+// Last-Event-ID, and the stream resumes after it. A comment line is sent
+// every eventStreamKeepAlive so idle proxies keep the connection open.
+
+// eventStreamKeepAlive is the longest a stream goes without writing.
+const eventStreamKeepAlive = 15 * time.Second
+
+// handleEventStream streams a shard's event log as server-sent events.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+
+	index := 0
+	if sh := query.Get("shard"); sh != "" {
+		parsed, err := strconv.Atoi(sh)
+		if err != nil || parsed < 0 || parsed >= s.shards.Len() {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("invalid shard: must be between 0 and %d", s.shards.Len()-1),
+			})
+			return
+		}
+		index = parsed
+	}
+	eventLog := s.shards.All()[index].EventLog
+
+	from := eventLog.GetLastSequence() + 1
+	if v := query.Get("from"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid from: must be a sequence number",
+			})
+			return
+		}
+		from = parsed
+	} else if v := r.Header.Get("Last-Event-ID"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid Last-Event-ID: must be a sequence number",
+			})
+			return
+		}
+		from = parsed + 1
+	}
+
+	var types map[events.EventType]bool
+	if v := query.Get("types"); v != "" {
+		types = make(map[events.EventType]bool)
+		for _, name := range strings.Split(v, ",") {
+			eventType, ok := events.ParseEventType(strings.ToUpper(strings.TrimSpace(name)))
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("unknown event type %q", name),
+				})
+				return
+			}
+			types[eventType] = true
+		}
+	}
+	symbol := query.Get("symbol")
+
+	records, err := eventLog.Tail(from)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		switch {
+		case errors.Is(err, events.ErrTailAhead):
+			status = http.StatusBadRequest
+		case errors.Is(err, events.ErrTruncated):
+			status = http.StatusGone
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	defer eventLog.StopTail(records)
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Event stream: can't lift the write deadline: %v", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return // Fell behind, or the log closed: the client resumes from its last id
+			}
+			if types != nil && !types[record.Type] {
+				continue
+			}
+			if symbol != "" && events.SymbolOf(record.Event) != symbol {
+				continue
+			}
+			data, err := json.Marshal(record.Event)
+			if err != nil {
+				log.Printf("Event stream: failed to encode event %d: %v", record.Seq, err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", record.Seq, record.Type, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.eventStreams:
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
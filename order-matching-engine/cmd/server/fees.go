@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// handleFeeTier lists fee tiers, or reports or assigns an account's tier, e.g.
+// GET  /admin/fees/tier
+// GET  /admin/fees/tier?account=MM1
+// POST /admin/fees/tier?account=MM1&tier=market-maker
+//
+// Assigning "default" returns the account to the -maker-bps/-taker-bps
+// rates. A new tier applies from the account's next fill; fills already
+// logged keep the fees they were charged.
+func (s *Server) handleFeeTier(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+
+	switch r.Method {
+	case http.MethodGet:
+		if account == "" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"tiers": s.fees.TierNames(),
+			})
+			return
+		}
+
+	case http.MethodPost:
+		tier := r.URL.Query().Get("tier")
+		err := s.fees.AssignTier(account, tier)
+		s.audit(adminActor(r), "fees.tier", account, map[string]string{"tier": tier}, err)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		log.Printf("Account %s assigned fee tier %s", account, tier)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, schedule := s.fees.AccountTier(account)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account,
+		"tier":       name,
+		"maker_bps":  schedule.MakerBps,
+		"taker_bps":  schedule.TakerBps,
+	})
+}
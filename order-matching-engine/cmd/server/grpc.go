@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/grpcapi"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// gRPC Order Entry
+//
+// -grpc-addr serves the OrderEntry service of internal/grpcapi alongside
+// HTTP. Each RPC is a front-end to the HTTP handler it mirrors:
+//
+//	SubmitOrder   executeOrder, after the account's order rate limit
+//	CancelOrder   cancelOrder
+//	GetBook       the shard's book, as GET /book reads it
+//	Executions    the drop-copy feed of the account
+//
+// so gRPC orders are validated, risk checked and sequenced through the same
+// ring buffers as JSON ones. HTTP statuses become gRPC codes: a rejected
+// order is a reply with accepted = false, 429 is RESOURCE_EXHAUSTED, 503
+// UNAVAILABLE and 504 DEADLINE_EXCEEDED.
+//
+// Orders sent with the cancel-on-disconnect metadata are tagged with their
+// connection's session and mass-cancelled through the ring buffer when it
+// closes, as on WebSocket and binary sessions.
+
+// grpcHandler carries out OrderEntry requests.
+type grpcHandler struct {
+	s *Server
+}
+
+// SubmitOrder executes an order like POST /order.
+func (h grpcHandler) SubmitOrder(ctx context.Context, req *grpcapi.SubmitOrderRequest) (*grpcapi.OrderReply, error) {
+	order, err := req.Order()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if sessionID, cancelOnDisconnect := grpcapi.Session(ctx); cancelOnDisconnect {
+		order.SessionID = sessionID
+	}
+	if result := h.s.orderLimits.Allow(order.AccountID); !result.Allowed {
+		h.s.metrics.orders.With("throttled").Inc()
+		return nil, status.Errorf(codes.ResourceExhausted, "order rate limit exceeded for account %s, retry in %s",
+			order.AccountID, result.RetryAfter.Round(time.Millisecond))
+	}
+
+	code, resp := h.s.executeOrder(order)
+	switch code {
+	case http.StatusOK, http.StatusAccepted:
+	case http.StatusBadRequest:
+		reason := resp.RejectReason
+		if reason == "" {
+			reason = resp.Error
+		}
+		return &grpcapi.OrderReply{
+			Status:       grpcapi.OrderStatusRejected,
+			RejectCode:   resp.RejectCode,
+			RejectReason: reason,
+		}, nil
+	default:
+		return nil, status.Error(grpcCode(code), rejectText(code, resp.RejectCode, resp.RejectReason, resp.Error))
+	}
+
+	reply := &grpcapi.OrderReply{
+		Accepted:  true,
+		OrderID:   resp.OrderID,
+		Status:    grpcOrderStatus(resp.Status),
+		CumQty:    resp.CumQty,
+		LeavesQty: resp.LeavesQty,
+		Duplicate: resp.Duplicate,
+	}
+	if resp.AvgPrice != "" {
+		reply.AvgPrice, _ = parseFormattedPrice(resp.AvgPrice)
+	}
+	for _, fill := range resp.Fills {
+		price, err := parseFormattedPrice(fill.Price)
+		if err != nil {
+			continue
+		}
+		f := grpcapi.Fill{TradeID: fill.TradeID, Price: price, Quantity: fill.Quantity}
+		if fill.Fee != "" {
+			f.Fee, _ = parseFormattedPrice(fill.Fee)
+		}
+		reply.Fills = append(reply.Fills, f)
+	}
+	return reply, nil
+}
+
+// CancelOrder cancels an order like POST /cancel.
+func (h grpcHandler) CancelOrder(ctx context.Context, req *grpcapi.CancelOrderRequest) (*grpcapi.CancelReply, error) {
+	if req.Symbol == "" || req.OrderID == 0 {
+		return nil, status.Error(codes.InvalidArgument, "symbol and order_id required")
+	}
+	code, resp := h.s.cancelOrder(req.Symbol, "", req.OrderID)
+	cancelled, reason, ok := cancelOutcome(code, resp)
+	if !ok {
+		return nil, status.Error(grpcCode(code), rejectText(code, "", "", reason))
+	}
+	return &grpcapi.CancelReply{OrderID: req.OrderID, CancelledQty: cancelled}, nil
+}
+
+// GetBook returns the top of a symbol's book like GET /book.
+func (h grpcHandler) GetBook(ctx context.Context, req *grpcapi.GetBookRequest) (*grpcapi.Book, error) {
+	if req.Symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol required")
+	}
+	book := h.s.engineFor(req.Symbol).GetOrderBook(req.Symbol)
+	if book == nil {
+		return nil, status.Error(codes.NotFound, "symbol not found")
+	}
+
+	depth := 10
+	if req.Depth > 0 {
+		depth = int(req.Depth)
+	}
+	reply := &grpcapi.Book{Symbol: req.Symbol}
+	for _, level := range book.GetBidDepth(depth) {
+		reply.Bids = append(reply.Bids, grpcapi.Level{Price: level.Price, Quantity: level.TotalQty, Orders: int64(level.Count())})
+	}
+	for _, level := range book.GetAskDepth(depth) {
+		reply.Asks = append(reply.Asks, grpcapi.Level{Price: level.Price, Quantity: level.TotalQty, Orders: int64(level.Count())})
+	}
+	return reply, nil
+}
+
+// Executions streams an account's execution reports like GET /ws/dropcopy,
+// without its risk events.
+func (h grpcHandler) Executions(ctx context.Context, req *grpcapi.ExecutionsRequest, send func(*grpcapi.ExecutionReport) error) error {
+	if req.AccountID == "" || h.s.clearingHouse.GetAccount(req.AccountID) == nil {
+		return status.Error(codes.NotFound, "unknown account")
+	}
+
+	messages := h.s.dropCopy.Subscribe(req.AccountID)
+	defer h.s.dropCopy.Unsubscribe(req.AccountID, messages)
+	if p, ok := peer.FromContext(ctx); ok {
+		log.Printf("gRPC executions for %s subscribed from %s", req.AccountID, p.Addr)
+	}
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			if msg.Type != dropcopy.MessageExecution || msg.Report == nil {
+				continue
+			}
+			if err := send(grpcapi.NewExecutionReport(msg.Seq, *msg.Report)); err != nil {
+				return err
+			}
+		case <-h.s.grpcStop:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// CancelSession cancels a session's orders, for cancel on disconnect.
+func (h grpcHandler) CancelSession(sessionID string) {
+	h.s.cancelSessionOrders(sessionID, "cancel on disconnect")
+}
+
+// grpcCode is the gRPC code of a failed request's HTTP status.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// grpcOrderStatus converts an OrderResponse status. An order held while its
+// symbol is stopped ("QUEUED") is reported as NEW.
+func grpcOrderStatus(s string) grpcapi.OrderStatus {
+	for st := orders.OrderStatusNew; st <= orders.OrderStatusRejected; st++ {
+		if st.String() == s {
+			return grpcapi.NewOrderStatus(st)
+		}
+	}
+	return grpcapi.OrderStatusNew
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/circuit"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+)
+
+// Halts and Circuit Breakers
+//
+// A symbol is OPEN (trading), HALTED or PAUSED. An operator halts and
+// resumes it with POST /admin/symbol/state; the circuit breaker (see
+// package circuit) does it automatically after every fill:
+//
+//	move > -circuit-pause-bps within -circuit-window   PAUSED, reopens after -circuit-pause-for
+//	move > -circuit-halt-bps within -circuit-window    HALTED until an operator reopens it
+//
+// Trips are audited (symbol.circuit, actor "system"), alerted and shared
+// with other shards like a manual state change. A pause is timed on the
+// processor timers of the symbol's shard, from just after the fill that
+// tripped it. An operator can lift a pause early, or turn it into a halt,
+// through the admin endpoint.
+//
+// Orders for a halted or paused symbol are rejected (-halt-orders=reject),
+// or held here (-halt-orders=queue) and answered 202 QUEUED. Held orders
+// have no order ID yet: they are sequenced in arrival order when the
+// symbol reopens, and can be looked up by client order ID after that.
+
+// HaltOrders modes.
+const (
+	HaltOrdersReject = "reject"
+	HaltOrdersQueue  = "queue"
+)
+
+// maxHeldOrders caps the orders held per symbol while it is not trading.
+const maxHeldOrders = 10000
+
+// haltControl is the server's halt state: pending pause expiries and held
+// orders. Safe for concurrent use.
+type haltControl struct {
+	mu     sync.Mutex
+	queue  bool                       // Hold orders instead of rejecting them
+	pauses map[string]uint64          // Symbol -> current pause; stale timers find it changed
+	nextID uint64                     // Last pause ID issued
+	held   map[string][]*orders.Order // Symbol -> orders waiting to be sequenced
+}
+
+func newHaltControl(mode string) *haltControl {
+	return &haltControl{
+		queue:  mode == HaltOrdersQueue,
+		pauses: make(map[string]uint64),
+		held:   make(map[string][]*orders.Order),
+	}
+}
+
+// hold queues an order for a symbol that is not trading. Returns false if
+// orders are not held, or the symbol's queue is full.
+func (h *haltControl) hold(order *orders.Order) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.queue || len(h.held[order.Symbol]) >= maxHeldOrders {
+		return false
+	}
+	h.held[order.Symbol] = append(h.held[order.Symbol], order)
+	return true
+}
+
+// take removes and returns a symbol's held orders, oldest first.
+func (h *haltControl) take(symbol string) []*orders.Order {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	held := h.held[symbol]
+	delete(h.held, symbol)
+	return held
+}
+
+// heldCount returns the number of orders held for a symbol.
+func (h *haltControl) heldCount(symbol string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.held[symbol])
+}
+
+// startPause records a new pause of a symbol and returns its ID.
+func (h *haltControl) startPause(symbol string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	h.pauses[symbol] = h.nextID
+	return h.nextID
+}
+
+// endPause clears a symbol's pause if it is still the given one. Returns
+// false if it already ended, or a later pause replaced it.
+func (h *haltControl) endPause(symbol string, id uint64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pauses[symbol] != id {
+		return false
+	}
+	delete(h.pauses, symbol)
+	return true
+}
+
+// clearPause forgets any pause of a symbol, e.g. when an operator changes
+// its state.
+func (h *haltControl) clearPause(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.pauses, symbol)
+}
+
+// holdOrder holds an order rejected because its symbol is halted or paused,
+// if the server queues such orders.
+func (s *Server) holdOrder(order *orders.Order) (int, OrderResponse, bool) {
+	inst, ok := s.refData.Get(order.Symbol)
+	if !ok || (inst.State != refdata.SessionHalted && inst.State != refdata.SessionPaused) {
+		return 0, OrderResponse{}, false
+	}
+	if !s.halts.hold(order) {
+		return 0, OrderResponse{}, false
+	}
+	return http.StatusAccepted, OrderResponse{
+		Success:   true,
+		Status:    "QUEUED",
+		LeavesQty: order.Quantity,
+	}, true
+}
+
+// releaseHeld sequences the orders held for a symbol that reopened, in the
+// order they arrived. An order that finds the symbol stopped again is held
+// again, and so are the ones behind it.
+func (s *Server) releaseHeld(symbol string) {
+	held := s.halts.take(symbol)
+	if len(held) == 0 {
+		return
+	}
+	log.Printf("Symbol %s reopened, sequencing %d held orders", symbol, len(held))
+	for _, order := range held {
+		if status, resp := s.executeOrder(order); status != http.StatusOK && status != http.StatusAccepted {
+			log.Printf("Held order for %s (account %s, client order %q) rejected: %s%s",
+				symbol, order.AccountID, order.ClientOrderID, resp.RejectReason, resp.Error)
+		}
+	}
+}
+
+// checkCircuit feeds a fill to the circuit breaker, and pauses or halts its
+// symbol if the price move tripped it.
+func (s *Server) checkCircuit(fill orders.Fill) {
+	trip, move := s.breaker.Observe(fill.Symbol, fill.Price, fill.Timestamp)
+	if trip == circuit.TripNone {
+		return
+	}
+	inst, ok := s.refData.Get(fill.Symbol)
+	if !ok || inst.State != refdata.SessionOpen {
+		return // Already stopped, or in an auction call
+	}
+
+	state := refdata.SessionHalted
+	if trip == circuit.TripPause {
+		state = refdata.SessionPaused
+	}
+	params := map[string]string{
+		"state":    state.String(),
+		"price":    orders.FormatPrice(fill.Price),
+		"move_bps": strconv.FormatInt(move, 10),
+	}
+	if err := s.refData.SetState(fill.Symbol, state); err != nil {
+		s.audit("system", "symbol.circuit", fill.Symbol, params, err)
+		return
+	}
+	s.breaker.Reset(fill.Symbol)
+	s.audit("system", "symbol.circuit", fill.Symbol, params, nil)
+	s.alerter.Raise(alerts.KindCircuitBreaker, fill.Symbol, alerts.SeverityWarning,
+		"%s %s: trade at %s moved %d bps within %s", fill.Symbol, state, orders.FormatPrice(fill.Price),
+		move, s.breaker.Config().Window)
+	log.Printf("Symbol %s session state set to %s by circuit breaker (%d bps)", fill.Symbol, state, move)
+	s.refShare.PublishState(fill.Symbol, state)
+
+	if state == refdata.SessionPaused {
+		s.schedulePauseEnd(fill.Symbol, s.halts.startPause(fill.Symbol))
+	}
+}
+
+// schedulePauseEnd times a pause on the processor timers of the symbol's
+// shard (see disruptor/timers.go), so it ends -circuit-pause-for after
+// this point in the symbol's sequence.
+func (s *Server) schedulePauseEnd(symbol string, id uint64) {
+	request := &disruptor.OrderRequest{
+		Type:    disruptor.RequestTypeSchedule,
+		Symbol:  symbol,
+		Timeout: s.breaker.Config().PauseFor,
+		OnTimer: func() { go s.endPause(symbol, id) }, // Publishes and sequences held orders
+	}
+	var response *disruptor.OrderResponse
+	for attempt := 0; attempt < 10 && response == nil; attempt++ {
+		if attempt > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		response, _ = s.submitRequest(request)
+	}
+	if response == nil || !response.Success {
+		log.Printf("ERROR: Pause of %s could not be timed, it lasts until an operator reopens it", symbol)
+		s.alerter.Raise(alerts.KindCircuitBreaker, symbol, alerts.SeverityCritical,
+			"%s paused without a timer: reopen it with POST /admin/symbol/state", symbol)
+	}
+}
+
+// endPause reopens a paused symbol when its pause expires, unless an
+// operator or a later trip changed its state in the meantime.
+func (s *Server) endPause(symbol string, id uint64) {
+	if !s.halts.endPause(symbol, id) {
+		return
+	}
+	inst, ok := s.refData.Get(symbol)
+	if !ok || inst.State != refdata.SessionPaused {
+		return
+	}
+	params := map[string]string{"state": refdata.SessionOpen.String(), "reason": "pause expired"}
+	err := s.refData.SetState(symbol, refdata.SessionOpen)
+	s.audit("system", "symbol.circuit", symbol, params, err)
+	if err != nil {
+		return
+	}
+	log.Printf("Symbol %s pause expired, session state set to %s", symbol, refdata.SessionOpen)
+	s.refShare.PublishState(symbol, refdata.SessionOpen)
+	s.releaseHeld(symbol)
+}
+
+// parseHaltOrders validates a -halt-orders mode.
+func parseHaltOrders(mode string) (string, error) {
+	switch mode {
+	case HaltOrdersReject, HaltOrdersQueue:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid halt orders mode %q: must be %q or %q", mode, HaltOrdersReject, HaltOrdersQueue)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/shard"
+)
+
+// Journal Damage
+//
+// The event log is what recovery rebuilds the books from, so the engine
+// must not keep trading a symbol whose log it can no longer trust. Damage
+// is found two ways:
+//
+//	replay  a record failing its checksum, or a sequence gap
+//	live    an event the batcher dropped (queue full) or failed to append
+//
+// What happens then is set by -on-journal-damage:
+//
+//	exit  refuse to start on damage found at replay (default); live damage
+//	      is only alerted
+//	halt  start anyway and halt the affected symbols until an operator
+//	      acknowledges the damage and resumes them:
+//
+//	damage ──▶ HALTED (symbol.journal) ──▶ POST /admin/journal/resume ──▶ OPEN
+//
+// A bad record or a lost event affects its own symbol; a gap affects every
+// symbol, since nobody knows what the missing events were. A symbol halted
+// this way cannot be reopened with /admin/symbol/state. Replay skips the
+// bad record and, in the snapshot tail, every later event of its symbol
+// (they build on the one lost), so a resumed symbol trades from its last
+// good book.
+
+// Journal damage modes.
+const (
+	JournalDamageExit = "exit"
+	JournalDamageHalt = "halt"
+)
+
+// journalDamage is one problem found in the event log.
+type journalDamage struct {
+	Seq    uint64    `json:"seq,omitempty"` // 0 for live damage: the event was never logged
+	Source string    `json:"source"`        // replay, dropped or append
+	Error  string    `json:"error"`
+	Time   time.Time `json:"time"`
+}
+
+// journalGuard tracks symbols halted by journal damage. Safe for concurrent
+// use.
+type journalGuard struct {
+	halt    bool
+	symbols []string
+
+	mu     sync.Mutex
+	damage map[string][]journalDamage // Symbol -> unacknowledged damage
+}
+
+func newJournalGuard(mode string, symbols []string) *journalGuard {
+	return &journalGuard{
+		halt:    mode == JournalDamageHalt,
+		symbols: symbols,
+		damage:  make(map[string][]journalDamage),
+	}
+}
+
+// record notes damage to a symbol, or to every symbol if symbol is "".
+// Returns the symbols it newly affects. Damage already recorded, e.g. by
+// an earlier replay of the same log, is not recorded again.
+func (g *journalGuard) record(symbol string, d journalDamage) []string {
+	affected := []string{symbol}
+	if symbol == "" {
+		affected = g.symbols
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var halted []string
+	for _, sym := range affected {
+		if d.Seq > 0 && g.seen(sym, d.Seq) {
+			continue
+		}
+		if len(g.damage[sym]) == 0 {
+			halted = append(halted, sym)
+		}
+		g.damage[sym] = append(g.damage[sym], d)
+	}
+	return halted
+}
+
+// seen reports whether damage at seq was already recorded for a symbol.
+// Called with mu held.
+func (g *journalGuard) seen(symbol string, seq uint64) bool {
+	for _, d := range g.damage[symbol] {
+		if d.Seq == seq {
+			return true
+		}
+	}
+	return false
+}
+
+// damaged reports whether a symbol has unacknowledged damage.
+func (g *journalGuard) damaged(symbol string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.damage[symbol]) > 0
+}
+
+// acknowledge clears a symbol's damage and returns it.
+func (g *journalGuard) acknowledge(symbol string) []journalDamage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	damage := g.damage[symbol]
+	delete(g.damage, symbol)
+	return damage
+}
+
+// symbolsDamaged returns the symbols with unacknowledged damage, sorted.
+func (g *journalGuard) symbolsDamaged() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	symbols := make([]string, 0, len(g.damage))
+	for symbol := range g.damage {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// list returns the unacknowledged damage by symbol.
+func (g *journalGuard) list() map[string][]journalDamage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := make(map[string][]journalDamage, len(g.damage))
+	for symbol, damage := range g.damage {
+		list[symbol] = append([]journalDamage(nil), damage...)
+	}
+	return list
+}
+
+// onReplayDamage is the event log's damage hook: it stops the replay unless
+// the server halts on damage, in which case it records the damage and
+// carries on.
+func (g *journalGuard) onReplayDamage(d *events.Damage) error {
+	if !g.halt {
+		return d
+	}
+	symbol := d.Symbol
+	if errors.Is(d, events.ErrSequenceGap) {
+		symbol = "" // Missing events could be for any symbol
+	}
+	log.Printf("WARNING: event log damaged: %v", d)
+	g.record(symbol, journalDamage{Seq: d.Seq, Source: "replay", Error: d.Error(), Time: time.Now()})
+	return nil
+}
+
+// journalDamaged halts the symbols affected by an event that never made it
+// into the event log. An event without a symbol affects every symbol.
+func (s *Server) journalDamaged(event interface{}, source string, err error) {
+	halted := s.journal.record(events.SymbolOf(event), journalDamage{
+		Source: source,
+		Error:  fmt.Sprintf("%T not written: %v", event, err),
+		Time:   time.Now(),
+	})
+	s.haltDamaged(halted)
+}
+
+// haltDamaged halts symbols with journal damage.
+func (s *Server) haltDamaged(symbols []string) {
+	for _, symbol := range symbols {
+		damage := s.journal.list()[symbol]
+		params := map[string]string{
+			"state":  refdata.SessionHalted.String(),
+			"damage": strconv.Itoa(len(damage)),
+		}
+		if len(damage) > 0 {
+			params["error"] = damage[0].Error
+		}
+		err := s.refData.SetState(symbol, refdata.SessionHalted)
+		s.audit("system", "symbol.journal", symbol, params, err)
+		if err != nil {
+			continue
+		}
+		s.halts.clearPause(symbol)
+		s.alerter.Raise(alerts.KindJournalDamage, symbol, alerts.SeverityCritical,
+			"%s HALTED: event log damaged (%s); resume with POST /admin/journal/resume", symbol, params["error"])
+		log.Printf("Symbol %s session state set to %s: event log damaged", symbol, refdata.SessionHalted)
+		s.refShare.PublishState(symbol, refdata.SessionHalted)
+	}
+}
+
+// handleJournal lists the event log damage symbols are halted on, e.g.
+// GET /admin/journal
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	mode := JournalDamageExit
+	if s.journal.halt {
+		mode = JournalDamageHalt
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mode":   mode,
+		"halted": s.journal.symbolsDamaged(),
+		"damage": s.journal.list(),
+	})
+}
+
+// handleJournalResume acknowledges a symbol's journal damage and reopens
+// it, e.g.
+// POST /admin/journal/resume?symbol=AAPL
+func (s *Server) handleJournalResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if !s.journal.damaged(symbol) {
+		err := fmt.Errorf("symbol %q is not halted on journal damage", symbol)
+		s.audit(adminActor(r), "journal.resume", symbol, nil, err)
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	_, status, err := s.setSymbolState(symbol, refdata.SessionOpen)
+	if err != nil {
+		s.audit(adminActor(r), "journal.resume", symbol, nil, err)
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	damage := s.journal.acknowledge(symbol)
+	s.audit(adminActor(r), "journal.resume", symbol, map[string]string{"damage": strconv.Itoa(len(damage))}, nil)
+
+	log.Printf("Symbol %s journal damage acknowledged (%d), session state set to %s", symbol, len(damage), refdata.SessionOpen)
+	s.refShare.PublishState(symbol, refdata.SessionOpen)
+	response := map[string]interface{}{
+		"symbol":       symbol,
+		"state":        refdata.SessionOpen.String(),
+		"acknowledged": damage,
+	}
+	if held := s.halts.heldCount(symbol); held > 0 {
+		go s.releaseHeld(symbol)
+		response["held_orders"] = held
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseJournalDamage validates a -on-journal-damage mode.
+func parseJournalDamage(mode string) (string, error) {
+	switch mode {
+	case JournalDamageExit, JournalDamageHalt:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid journal damage mode %q: must be %q or %q", mode, JournalDamageExit, JournalDamageHalt)
+}
+
+// verifyEventLogs checks the event log of every shard end to end, as
+// -verify, printing what it finds. Returns whether every log is sound.
+func verifyEventLogs(path string, shards int) bool {
+	ok := true
+	for i := 0; i < shards; i++ {
+		logPath := shard.LogPath(path, i, shards)
+		report, err := events.Verify(logPath)
+		if err != nil {
+			fmt.Printf("%s: %v\n", logPath, err)
+			ok = false
+			continue
+		}
+		fmt.Printf("%s: %d records in %d files, sequence %d-%d\n",
+			logPath, report.Records, report.Files, report.FirstSeq, report.LastSeq)
+		for _, d := range report.Damage {
+			fmt.Printf("  DAMAGED  %v\n", d)
+		}
+		for _, err := range report.Invalid {
+			fmt.Printf("  INVALID  %v\n", err)
+		}
+		if report.TornBytes > 0 {
+			fmt.Printf("  TORN     %d-byte partial record at the end (dropped when the engine next opens the log)\n", report.TornBytes)
+		}
+		if !report.OK() {
+			ok = false
+		}
+	}
+	return ok
+}
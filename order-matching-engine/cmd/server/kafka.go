@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/kafka"
+	"github.com/rishav/order-matching-engine/internal/shard"
+)
+
+// Kafka Sink
+//
+// With -kafka-brokers set, every event each shard logs is also produced to
+// -kafka-topic, so external systems can build read models from the
+// engine's events (see internal/kafka):
+//
+//	./server -kafka-brokers kafka1:9092,kafka2:9092 -kafka-topic engine-events
+//
+// Messages are keyed by symbol and carry the event's sequence number, type
+// and shard in headers; the value is the event in -kafka-codec. Delivery
+// is at least once: a consumer skips a shard's seq at or below the last it
+// applied. Each shard's sink keeps the last sequence number it produced in
+// <event log>.kafka-<topic>, and resumes after it on restart.
+//
+// The sinks follow the event logs, never the processors, so a slow or
+// unreachable cluster costs matching nothing: the sinks fall behind and
+// catch up from disk (kafka_sink_lag on /metrics). At shutdown they get
+// until the shutdown deadline to catch up.
+
+// kafkaOffsetPath is where a shard's sink keeps its position in topic.
+func kafkaOffsetPath(logPath, topic string) string {
+	return fmt.Sprintf("%s.kafka-%s", logPath, topic)
+}
+
+// newKafkaSinks creates a sink per shard event log, producing to
+// config.KafkaTopic. The sinks are started by Start.
+func newKafkaSinks(config Config, eventLogs []*events.EventLog) (*kafka.Producer, []*kafka.Sink, error) {
+	producer, err := kafka.NewProducer(kafka.ProducerConfig{Brokers: config.KafkaBrokers})
+	if err != nil {
+		return nil, nil, err
+	}
+	sinks := make([]*kafka.Sink, len(eventLogs))
+	for i, eventLog := range eventLogs {
+		sinks[i], err = kafka.NewSink(eventLog, producer, kafka.SinkConfig{
+			Topic:      config.KafkaTopic,
+			Shard:      i,
+			Codec:      config.KafkaCodec,
+			OffsetPath: kafkaOffsetPath(shard.LogPath(config.EventLogPath, i, config.Shards), config.KafkaTopic),
+		})
+		if err != nil {
+			producer.Close()
+			return nil, nil, err
+		}
+	}
+	log.Printf("Producing event logs to Kafka topic %s on %s", config.KafkaTopic, strings.Join(config.KafkaBrokers, ","))
+	return producer, sinks, nil
+}
+
+// closeKafka lets the sinks produce what the processors logged, until ctx
+// is done, then stops them.
+func (s *Server) closeKafka(ctx context.Context) {
+	for i, sink := range s.kafkaSinks {
+		if err := sink.Close(ctx); err != nil {
+			log.Printf("Kafka sink of shard %d: %v", i, err)
+		}
+	}
+	s.kafkaProducer.Close()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Account Kill Switch
+//
+// An operator who sees an account misbehaving stops it outright:
+//
+//	POST /admin/kill?account=TRADER1&reason=runaway+algo
+//
+// trips the account's kill switch in the risk checker, so every new order
+// from it is rejected from then on, then sends a mass cancel for the
+// account through the ring buffer. Each processor cancels the account's
+// resting orders in every symbol it trades, found through the books'
+// per-account index, and logs and reports every cancel as usual. The
+// response lists what was cancelled.
+//
+// An order already past its risk checks when the switch trips can still
+// reach the book after the mass cancel. Killing the account again is safe:
+// the switch stays tripped with its first reason, and the mass cancel
+// sweeps up anything that slipped in. POST /admin/risk/reinstate lets the
+// account trade again; its cancelled orders stay cancelled.
+
+// killReasonDefault is the reason recorded when the operator gives none.
+const killReasonDefault = "killed by operator"
+
+// handleKill trips an account's kill switch and cancels its resting orders.
+func (s *Server) handleKill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = killReasonDefault
+	}
+	params := map[string]string{"reason": reason}
+	if account == "" || s.clearingHouse.GetAccount(account) == nil {
+		err := fmt.Errorf("unknown account %q", account)
+		s.audit(adminActor(r), "risk.kill", account, params, err)
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Block first, so no new order gets in behind the mass cancel
+	tripped := s.riskChecker.Kill(account, reason)
+	cancelled, err := s.cancelAccountOrders(account, "account killed: "+reason)
+	if err != nil {
+		s.audit(adminActor(r), "risk.kill", account, params, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	params["cancelled"] = fmt.Sprint(len(cancelled))
+	s.audit(adminActor(r), "risk.kill", account, params, nil)
+	log.Printf("Account %s killed (%s): %d orders cancelled", account, reason, len(cancelled))
+
+	list := make([]map[string]interface{}, len(cancelled))
+	for i, order := range cancelled {
+		list[i] = map[string]interface{}{
+			"order_id":      order.ID,
+			"symbol":        order.Symbol,
+			"side":          order.Side.String(),
+			"price":         orders.FormatPrice(order.Price),
+			"cancelled_qty": order.RemainingQty(),
+		}
+	}
+	_, killReason := s.riskChecker.IsKilled(account)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account,
+		"killed":     true,
+		"tripped":    tripped, // false if the switch was already tripped
+		"reason":     killReason,
+		"cancelled":  list,
+	})
+}
+
+// cancelAccountOrders mass-cancels an account's resting orders on every
+// shard through the ring buffers, retrying briefly on backpressure like
+// cancel on disconnect. Returns the orders cancelled.
+func (s *Server) cancelAccountOrders(accountID, reason string) ([]*orders.Order, error) {
+	request := &disruptor.OrderRequest{
+		Type:      disruptor.RequestTypeMassCancel,
+		AccountID: accountID,
+		Reason:    reason,
+	}
+
+	var response *disruptor.OrderResponse
+	for attempt := 0; attempt < 10 && response == nil; attempt++ {
+		response, _ = s.submitRequest(request)
+	}
+	if response == nil {
+		log.Printf("ERROR: mass cancel for account %s could not be sequenced", accountID)
+		s.alerter.Raise(alerts.KindMassCancelFailed, accountID, alerts.SeverityCritical,
+			"mass cancel for account %s could not be sequenced (%s)", accountID, reason)
+		return nil, fmt.Errorf("account %s is blocked but its orders could not be cancelled: server busy, please retry", accountID)
+	}
+
+	s.publishCancelled(response.Cancelled)
+	return response.Cancelled, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Latency Breakdown
+//
+// POST /order reports where its time went in the engine as a Server-Timing
+// header, so a proxy in front (the rate limiter gateway's /latency-report)
+// or a browser's developer tools can show it without parsing the body:
+//
+//	Server-Timing: engine-match;dur=0.412;desc="ingress to match",
+//	               engine-post;dur=0.051;desc="match to response"
+//
+//	engine-match  request read, parsing, risk checks, the ring buffer queue
+//	              and matching itself
+//	engine-post   event logging handoff, risk positions, market data and
+//	              building the response
+//
+// Durations are in milliseconds, measured on the engine's clock only, so
+// they add up with the proxy's own stages without the two clocks agreeing.
+// An order that never reached the engine (rejected by reference data or
+// risk, or a full ring buffer) reports its whole time as engine-match.
+
+// setServerTiming sets the Server-Timing header of an order response from
+// the times (nanoseconds since epoch) the request arrived, the engine
+// matched it (0 if it never did) and the response was ready.
+func setServerTiming(w http.ResponseWriter, ingress, matched, done int64) {
+	if matched == 0 {
+		matched = done
+	}
+	w.Header().Set("Server-Timing", fmt.Sprintf(
+		`engine-match;dur=%.3f;desc="ingress to match", engine-post;dur=%.3f;desc="match to response"`,
+		millis(matched-ingress), millis(done-matched)))
+}
+
+// millis converts nanoseconds to milliseconds.
+func millis(ns int64) float64 {
+	return float64(ns) / 1e6
+}
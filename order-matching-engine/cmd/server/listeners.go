@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/listener"
+)
+
+// Listeners
+//
+// The HTTP API is split by who calls it, and each part can have a listener
+// of its own, with its own address, TLS and timeouts:
+//
+//	trading       -port              orders, cancels, previews, baskets, the order entry and
+//	                                 drop-copy WebSockets, an account's own views and trades
+//	market data   -marketdata-addr   books, quotes, trades, symbols, calendars and their WebSockets
+//	admin         -admin-addr        /admin/*, opening and funding accounts, settlement events
+//	                                 and reports, /metrics, /stats, the event stream
+//
+// A part without an address of its own is served on the trading listener,
+// so by default the whole API is on -port as before. /health answers on
+// every listener. Splitting them lets the admin port be firewalled off or
+// bound to a private interface, and lets market data subscribers hold
+// connections open for hours without the short write timeout order entry
+// wants: the market data listener has no write timeout by default.
+//
+// A listener with a certificate and key (-tls-cert, -tls-key, or the
+// -marketdata- and -admin- prefixed flags) serves HTTPS only; with a
+// client CA as well it requires every client to present a certificate
+// issued by it. On the admin listener a verified certificate names the
+// operator in the audit log (see audit.go). Certificates are read at
+// startup; a bad one fails it. The listeners themselves are built by
+// internal/listener.
+//
+// Symbol migration sends the book to the target's admin listener and
+// forwards orders to its trading listener: name the admin URL with
+// target_admin when they differ (see migrate.go).
+
+// listenerFlags registers the flags of the listener whose flags start with
+// prefix, defaulting to config. The returned function reads them back.
+func listenerFlags(prefix, name string, config listener.Config) func() listener.Config {
+	var addr *string
+	if prefix != "" {
+		addr = flag.String(prefix+"addr", config.Addr, fmt.Sprintf("Address of a separate %s listener, e.g. :9090 (empty = served on -port)", name))
+	}
+	certFile := flag.String(prefix+"tls-cert", config.CertFile, fmt.Sprintf("PEM certificate chain the %s listener serves HTTPS with (empty = plain HTTP)", name))
+	keyFile := flag.String(prefix+"tls-key", config.KeyFile, fmt.Sprintf("PEM private key of -%stls-cert", prefix))
+	clientCA := flag.String(prefix+"tls-client-ca", config.ClientCAFile, fmt.Sprintf("PEM CAs whose client certificates the %s listener requires (empty = none)", name))
+	readTimeout := flag.Duration(prefix+"read-timeout", config.ReadTimeout, fmt.Sprintf("Longest the %s listener takes to read a request (0 = no limit)", name))
+	writeTimeout := flag.Duration(prefix+"write-timeout", config.WriteTimeout, fmt.Sprintf("Longest the %s listener takes to write a response (0 = no limit)", name))
+	idleTimeout := flag.Duration(prefix+"idle-timeout", config.IdleTimeout, fmt.Sprintf("Keep-alive connections to the %s listener idle this long are closed (0 = the read timeout)", name))
+	return func() listener.Config {
+		parsed := listener.Config{
+			CertFile:     *certFile,
+			KeyFile:      *keyFile,
+			ClientCAFile: *clientCA,
+			ReadTimeout:  *readTimeout,
+			WriteTimeout: *writeTimeout,
+			IdleTimeout:  *idleTimeout,
+		}
+		if addr != nil {
+			parsed.Addr = *addr
+		}
+		return parsed
+	}
+}
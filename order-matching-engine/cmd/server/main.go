@@ -22,24 +22,58 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/audit"
+	"github.com/rishav/order-matching-engine/internal/calendar"
+	"github.com/rishav/order-matching-engine/internal/circuit"
+	"github.com/rishav/order-matching-engine/internal/consensus"
 	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/enrichment"
 	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/fees"
+	"github.com/rishav/order-matching-engine/internal/gateway"
+	"github.com/rishav/order-matching-engine/internal/grpcapi"
+	"github.com/rishav/order-matching-engine/internal/itch"
+	"github.com/rishav/order-matching-engine/internal/kafka"
+	"github.com/rishav/order-matching-engine/internal/listener"
 	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/migration"
 	"github.com/rishav/order-matching-engine/internal/matching"
 	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/ratelimit"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/refshare"
+	"github.com/rishav/order-matching-engine/internal/replication"
 	"github.com/rishav/order-matching-engine/internal/risk"
 	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/shard"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+	"github.com/rishav/order-matching-engine/internal/wal"
+	"github.com/rishav/order-matching-engine/internal/wsjournal"
+	"github.com/rishav/order-matching-engine/pkg/degrade"
 )
 
 // Server is the main order matching engine server.
@@ -48,21 +82,60 @@ import (
 //   - HTTP handlers (multi-threaded) submit to ring buffer using CAS operations
 //   - Single event processor consumes from ring buffer and calls matching engine
 //   - This achieves 1.1M orders/sec with lock-free coordination
+//   - With -shards N, each symbol shard has its own engine, ring buffer and processor
 type Server struct {
 	// Core components
-	engine        *matching.Engine        // Single-threaded matching engine (deterministic)
+	refData       *refdata.Store         // Instrument reference data (pre-sequencer validation)
 	riskChecker   *risk.Checker          // Pre-trade risk validation
-	eventLog      *events.EventLog       // Append-only event log for recovery
 	publisher     *marketdata.Publisher  // Market data publisher (L1/L2 quotes, trades)
 	clearingHouse *settlement.ClearingHouse // Post-trade settlement
+	symbolStats   *marketdata.StatsTracker  // Per-symbol intraday stats (volume, VWAP, high/low)
+	nbbo          *marketdata.Consolidator  // Best bid/offer across venues (this engine plus any added)
+	alerter       *alerts.Alerter           // Throttled operator alerts (dropped events, failed settlements)
+	refShare      *refshare.Sharer          // Shares reference prices/halts across shards (nil = standalone)
+	dropCopy      *dropcopy.Hub             // Per-account drop-copy feed (executions, risk events)
+	wsJournals    *wsjournal.Store          // Messages of WebSocket order entry sessions, for resend
+	migrations    *migration.Gate           // Holds or forwards requests for symbols moving between shards
+	auditLog      *audit.Log                // Signed record of admin actions, separate from the event log
+	calendars     *calendar.Set             // Market holiday calendars (settlement dates)
+	fees          *fees.Engine              // Maker/taker fees charged on every fill
+	tape          *enrichment.Pipeline      // Builds public trade reports from fills
+	anonymizer    *enrichment.Anonymizer    // Counterparty codes on the public tape
+	breaker       *circuit.Breaker          // Pauses or halts symbols on fast price moves
+	halts         *haltControl              // Pause expiries and orders held while symbols are stopped
+	journal       *journalGuard             // Symbols halted on event log damage
+	shardID       string                    // This instance's ID
+	itchFeed      *itch.Feed                // Binary multicast market data (nil = off)
+	itchGaps      net.Listener              // ITCH retransmission requests (nil = off)
+	binary        *gateway.Server           // Binary order entry sessions (nil = off)
+	binaryLn      net.Listener              // Binary order entry connections (nil = off)
+	grpc          *grpc.Server              // gRPC order entry (nil = off)
+	grpcLn        net.Listener              // gRPC order entry connections (nil = off)
+	grpcStop      chan struct{}             // Closed at shutdown, ending execution streams
+	eventStreams  chan struct{}             // Closed at shutdown, ending /events/stream responses (see event_stream.go)
+	replication   *replication.Primary      // Streams the event log to standbys (nil = off)
+	replicationLn net.Listener              // Standby connections (nil = off)
+	degrade       *degrade.Controller       // Overload level every component follows (see degrade.go)
+	stopLoad      chan struct{}             // Stops the load watcher
+	settleEvery   time.Duration             // How often the first shard runs the settlement cycle (0 = by hand)
+	sessions      *calendar.Sessions        // Acts on market opens and closes (nil = off, see sessions.go)
+	sessionCheck  time.Duration             // How often sessions are advanced
+	cluster       *cluster                  // Raft node state changes are committed through (nil = standalone, see cluster.go)
+	metrics       *serverMetrics            // Counters and histograms served on /metrics (see metrics.go)
+	orderLimits   *ratelimit.Limiter        // Per-account order submission rate (see ratelimit.go)
+	trades        *marketdata.TradeStore    // Trade history for GET /trades, older trades from the event log (see tape.go)
+	requests      *wal.Log                  // Write-ahead journal of requests (nil = off, see request_journal.go)
+	requestsAfter uint64                    // Journaled requests after this one are replayed at Start
+	kafkaProducer *kafka.Producer           // Shared by the Kafka sinks (nil = off, see kafka.go)
+	kafkaSinks    []*kafka.Sink             // Produce each shard's event log to Kafka
 
 	// LMAX Disruptor components for lock-free, high-throughput processing
 	// See README "LMAX Disruptor Pattern (Ring Buffer)" for detailed explanation
-	ringBuffer     *disruptor.RingBuffer      // 8192-slot pre-allocated ring buffer (power-of-2)
-	sequencer      *disruptor.Sequencer       // Lock-free sequencer using atomic CAS operations
-	eventProcessor *disruptor.EventProcessor  // Single-threaded processor (maintains determinism)
+	// Each shard: matching engine, event log, 8192-slot ring buffer, CAS
+	// sequencer and single-threaded processor (maintains determinism)
+	shards *shard.Set
 
-	httpServer *http.Server
+	listeners []*listener.Listener // HTTP API: trading first, then market data and admin if apart (see listeners.go)
 }
 
 // Config holds server configuration.
@@ -71,6 +144,65 @@ type Config struct {
 	EventLogPath  string
 	SyncMode      bool
 	Symbols       []string
+	AlertWebhook  string        // Optional URL alerts are POSTed to (always logged)
+	AlertInterval time.Duration // Minimum time between repeated alerts of one kind
+	FairBatch     int           // Per-symbol round-robin drain batch (0 = strict FIFO)
+	WaitStrategy  disruptor.WaitStrategy // How processors wait for requests: latency against idle CPU
+	RefShareRedis string        // Redis address for sharing reference data across shards (empty = off)
+	ShardID       string        // This instance's ID when sharing reference data or migrating symbols
+	MaxDailyLoss  int64         // Per-account intraday loss that trips its kill switch (0 = off)
+	MaxOrderRate  int64         // Orders per second per account without a profile, checked as risk (0 = off)
+	RateBreachKill int          // Order rate breaches in a day that trip the kill switch (0 = never)
+	TimerTick     time.Duration // Resolution of engine timers such as dead man's switches
+	MigrateWait   time.Duration // Longest a request waits for a symbol being migrated
+	OrderHistory  int           // Completed orders remembered for status lookups
+	AuditLogPath  string        // Audit log of admin actions
+	ClearingLogPath string      // Journal of the clearing house's accounts and trades (empty = in memory only)
+	AuditKey      string        // HMAC key signing audit entries (empty = unkeyed hash chain)
+	Circuit       circuit.Config // Price moves that pause or halt a symbol
+	HaltOrders    string         // Orders for halted or paused symbols: "reject" or "queue"
+	Market        string         // Market the configured symbols trade on
+	CalendarFile  string         // YAML market holiday calendars (empty = weekdays only)
+	InstrumentsFile string       // YAML per-symbol tick and lot sizes (empty = cent ticks, single shares)
+	SettlementDays  int           // T+N settlement cycle
+	SettleEvery     time.Duration // How often trades due are cleared and settled (0 = by hand)
+	SessionCheck    time.Duration // How often market opens and closes are checked for (0 = off)
+	SettleFails     settlement.FailConfig // Retries and buy-ins of instructions that fail to settle
+	InitialMarginBps int64        // Initial margin on unsettled trades (0 = no margin)
+	Fees          fees.Schedule  // Rates for accounts without a fee tier
+	TapeKey       string         // Key counterparty codes are derived with (empty = random)
+	JournalDamage string         // On event log damage: "exit", or "halt" the affected symbols
+	BuyingPower   bool           // Reject orders accounts can't cover, holding cash and shares for resting ones
+	CheckBooks    bool           // Debug: check the books every request touched for consistency (slow)
+	Shards        int            // Engine shards symbols are hashed across, each with its own processor
+	ItchMulticast  string        // Multicast group the ITCH feed is sent to (empty = off)
+	ItchRetransmit string        // TCP address serving ITCH gap requests (empty = off)
+	BinaryAddr     string        // TCP address for binary order entry (empty = off)
+	GRPCAddr       string        // TCP address for gRPC order entry (empty = off)
+	ReplicationAddr string        // TCP address standbys replicate the event log from (empty = off)
+	StandbyOf       string        // Primary's replication address: replicate until promoted (empty = serve)
+	FailoverAfter   time.Duration // Standby: promote once the primary is silent this long (0 = manual only)
+	Degrade         degrade.Policy // Ring buffer occupancy that sheds reads and rejects new orders
+	Raft            consensus.Config // Cluster this server is a node of (no Peers = standalone)
+	OrderRate       ratelimit.Limit  // Orders per second per account without its own limit (0 = unlimited)
+	IdempotencyWindow time.Duration  // How long a client_order_id resent by its account returns the original order (0 = off)
+	TradeHistory    int              // Trades per symbol kept in memory for GET /trades; older ones are read from the event log
+	StallTimeout    time.Duration    // Market data subscribers leaving updates unread this long are evicted (0 = never)
+	RetransmitHistory int            // Market data messages per symbol kept for /marketdata/replay
+	Listeners       listener.Set     // Addresses, TLS and timeouts of the trading, market data and admin listeners
+
+	SnapshotDir      string        // Directory for snapshots (empty = off)
+	SnapshotInterval time.Duration // Time between snapshots
+	SnapshotEvery    uint64        // Also snapshot every N logged events (0 = off)
+	RequestJournal   string        // Write-ahead journal of requests, replayed after a crash (empty = off; needs SnapshotDir)
+
+	LogSegmentBytes int64            // Rotate the event log at this size (0 = one file)
+	LogRetention    events.Retention // Expiry of closed event log segments
+	LogCodec        events.Codec     // Encodes new event log records (nil = protobuf)
+
+	KafkaBrokers []string     // Kafka cluster every logged event is produced to (empty = off)
+	KafkaTopic   string       // Topic the events are produced to
+	KafkaCodec   events.Codec // Encodes message values (nil = protobuf)
 }
 
 // DefaultConfig returns reasonable defaults.
@@ -80,37 +212,435 @@ func DefaultConfig() Config {
 		EventLogPath: "events.log",
 		SyncMode:     false,
 		Symbols:      []string{"AAPL", "GOOGL", "MSFT", "AMZN", "TSLA"},
+		AlertInterval: time.Minute,
+		FairBatch:     256,
+		TimerTick:     100 * time.Millisecond,
+		MigrateWait:   10 * time.Second,
+		OrderHistory:  matching.DefaultOrderHistory,
+		AuditLogPath:  "audit.log",
+		ClearingLogPath: "clearing.log",
+		Circuit:       circuit.DefaultConfig(),
+		HaltOrders:    HaltOrdersReject,
+		JournalDamage: JournalDamageExit,
+		Market:        "XNYS",
+		SettlementDays: 2,
+		SettleEvery:    time.Minute,
+		SessionCheck:   time.Minute,
+		SettleFails:    settlement.FailConfig{Retries: 5, BuyInAfter: 4, BuyInPremiumBps: 100},
+		Fees:          fees.DefaultSchedule(),
+		Shards:        1,
+		SnapshotInterval: 30 * time.Second,
+		SnapshotEvery:    100000,
+		LogSegmentBytes:  64 << 20,
+		Degrade:          degrade.DefaultPolicy(),
+		Raft:             consensus.DefaultConfig(),
+		IdempotencyWindow: disruptor.DefaultIdempotencyWindow,
+		TradeHistory:     marketdata.DefaultTradeHistory,
+		StallTimeout:     marketdata.DefaultStallTimeout,
+		RetransmitHistory: marketdata.DefaultRetransmitHistory,
+		Listeners:        listener.Defaults(),
 	}
 }
 
 // NewServer creates a new server instance.
 func NewServer(config Config) (*Server, error) {
-	// Create event log for compliance and recovery
+	// Create the alerter first so startup failures (e.g. a corrupt event log)
+	// reach operators too. Alerts always go to the log; a webhook is optional.
+	alertSinks := []alerts.Sink{alerts.LogSink{}}
+	if config.AlertWebhook != "" {
+		alertSinks = append(alertSinks, alerts.NewWebhookSink(config.AlertWebhook))
+	}
+	alertConfig := alerts.DefaultConfig()
+	alertConfig.Interval = config.AlertInterval
+	alerter := alerts.New(alertConfig, alertSinks...)
+
+	// Symbols hash to shards by the shard count, so an event log can only be
+	// recovered with the count it was written with. Snapshots image one
+	// engine and the clearing house, so they need a single shard
+	if config.Shards < 1 {
+		config.Shards = 1
+	}
+	if config.Shards > 1 && config.SnapshotDir != "" {
+		alerter.Close()
+		return nil, errors.New("snapshots require a single shard")
+	}
+	if config.Shards > 1 && config.ReplicationAddr != "" {
+		alerter.Close()
+		return nil, errors.New("replication requires a single shard")
+	}
+	if config.RequestJournal != "" && config.SnapshotDir == "" {
+		alerter.Close()
+		return nil, errors.New("a request journal requires snapshots (-snapshot-dir)")
+	}
+
+	// A cluster node's engine is rebuilt from the Raft log, which holds
+	// every request in the one order all nodes apply it in
+	clustered := len(config.Raft.Peers) > 0
+	if clustered && (config.Shards > 1 || config.SnapshotDir != "" || config.ReplicationAddr != "") {
+		alerter.Close()
+		return nil, errors.New("a raft cluster needs a single shard, and no snapshots or standbys")
+	}
+	if clustered && len(config.KafkaBrokers) > 0 {
+		alerter.Close()
+		return nil, errors.New("a raft cluster node can't produce to Kafka: every node would produce the same events")
+	}
+	if err := config.OrderRate.Validate(); err != nil {
+		alerter.Close()
+		return nil, fmt.Errorf("invalid order rate limit: %w", err)
+	}
+	if err := shard.CheckLayout(config.EventLogPath, config.Shards); err != nil {
+		alerter.Close()
+		return nil, err
+	}
+	// Certificates are read before anything is opened
+	listeners, err := listener.New(config.Listeners, config.Port)
+	if err != nil {
+		alerter.Close()
+		return nil, err
+	}
+
+	// Symbols in the instruments file trade alongside the configured ones,
+	// on their own tick and lot sizes
+	var instruments []refdata.Instrument
+	if config.InstrumentsFile != "" {
+		var err error
+		instruments, err = refdata.Load(config.InstrumentsFile)
+		if err != nil {
+			alerter.Close()
+			return nil, fmt.Errorf("failed to load instruments: %w", err)
+		}
+		configured := make(map[string]bool, len(config.Symbols))
+		for _, symbol := range config.Symbols {
+			configured[symbol] = true
+		}
+		symbols := append([]string(nil), config.Symbols...)
+		for _, inst := range instruments {
+			if !configured[inst.Symbol] {
+				symbols = append(symbols, inst.Symbol)
+			}
+		}
+		config.Symbols = symbols
+	}
+
+	// Damage found on replay stops startup, or halts the symbols it affects
+	journal := newJournalGuard(config.JournalDamage, config.Symbols)
+
+	// Create an event log per shard for compliance and recovery
 	// All state changes (new orders, fills, cancels) are logged before being applied
 	// This enables crash recovery by replaying the event log
-	eventLog, err := events.NewEventLog(events.EventLogConfig{
-		Path:     config.EventLogPath,
-		SyncMode: config.SyncMode, // SyncMode=true uses O_SYNC for durability (slower)
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create event log: %w", err)
+	eventLogs := make([]*events.EventLog, config.Shards)
+	closeLogs := func() {
+		for _, eventLog := range eventLogs {
+			if eventLog != nil {
+				eventLog.Close()
+			}
+		}
+	}
+	for i := range eventLogs {
+		eventLogs[i], err = events.NewEventLog(events.EventLogConfig{
+			Path:            shard.LogPath(config.EventLogPath, i, config.Shards),
+			SyncMode:        config.SyncMode, // SyncMode=true uses O_SYNC for durability (slower)
+			SegmentMaxBytes: config.LogSegmentBytes,
+			Retention:       config.LogRetention,
+			Codec:           config.LogCodec,
+		})
+		if err != nil {
+			closeLogs()
+			alerter.Close()
+			return nil, fmt.Errorf("failed to create event log: %w", err)
+		}
+		if torn := eventLogs[i].TornBytes(); torn > 0 {
+			log.Printf("Event log %s ended in a torn %d-byte record from an interrupted write; dropped it",
+				shard.LogPath(config.EventLogPath, i, config.Shards), torn)
+		}
+		eventLogs[i].OnDamage(journal.onReplayDamage)
+	}
+
+	// Every logged event is produced to Kafka, if configured (see kafka.go)
+	var kafkaProducer *kafka.Producer
+	var kafkaSinks []*kafka.Sink
+	if len(config.KafkaBrokers) > 0 {
+		kafkaProducer, kafkaSinks, err = newKafkaSinks(config, eventLogs)
+		if err != nil {
+			closeLogs()
+			alerter.Close()
+			return nil, fmt.Errorf("failed to set up the Kafka sink: %w", err)
+		}
 	}
 
-	// Create matching engine (single-threaded, deterministic)
+	// Create a matching engine per shard (single-threaded, deterministic)
 	// Each symbol gets its own order book with red-black trees for price levels
-	engine := matching.NewEngine()
+	engines := make([]*matching.Engine, config.Shards)
+	for i := range engines {
+		engines[i] = matching.NewEngine()
+		engines[i].SetOrderHistory(config.OrderHistory)
+		engines[i].SetIDStride(config.Shards, i) // Order and trade IDs unique across shards
+	}
+	refData := refdata.NewStore()
 	for _, symbol := range config.Symbols {
-		engine.AddSymbol(symbol)
+		engines[shard.Index(symbol, config.Shards)].AddSymbol(symbol)
+		refData.Add(refdata.Instrument{Symbol: symbol, Market: config.Market}) // 1 cent tick, 1 share lot
+	}
+	for _, inst := range instruments {
+		if inst.Market == "" {
+			inst.Market = config.Market
+		}
+		refData.Add(inst) // Unset sizes default to a cent and a share
+		engines[shard.Index(inst.Symbol, config.Shards)].SetTickSize(inst.Symbol, inst.TickSize)
+		engines[shard.Index(inst.Symbol, config.Shards)].SetAllocation(inst.Symbol, inst.Allocation)
+	}
+
+	// Settlement dates skip the holidays of each symbol's market
+	calendars := calendar.NewSet()
+	if config.CalendarFile != "" {
+		calendars, err = calendar.Load(config.CalendarFile)
+		if err != nil {
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to load calendar: %w", err)
+		}
+	}
+
+	// Post-trade settlement. Created before recovery, which restores it
+	clearingHouse := settlement.NewClearingHouse()
+	clearingHouse.SetSettlementDays(config.SettlementDays)
+	clearingHouse.SetCalendars(calendars, func(symbol string) string {
+		inst, _ := refData.Get(symbol)
+		return inst.Market
+	})
+	clearingHouse.OnSettlementFail(func(instr settlement.SettlementInstruction, reason string) {
+		alerter.Raise(alerts.KindSettlementFailed, instr.Symbol, alerts.SeverityCritical,
+			"%s->%s %d %s failed to settle: %s", instr.FromAccount, instr.ToAccount, instr.Quantity, instr.Symbol, reason)
+	})
+	clearingHouse.SetFailConfig(config.SettleFails)
+	clearingHouse.OnSettlementEvent(func(event settlement.SettlementEvent) { onSettlementEvent(alerter, event) })
+
+	// Balances and unsettled trades survive restarts in the clearing
+	// journal. It is newer than any snapshot, which then only replays the
+	// trades logged after the journal's last
+	if config.ClearingLogPath != "" {
+		records, err := clearingHouse.OpenJournal(config.ClearingLogPath, config.SyncMode)
+		if err != nil {
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to recover clearing journal: %w", err)
+		}
+		if records > 0 {
+			stats := clearingHouse.GetSettlementStats()
+			log.Printf("Recovered clearing house from %s: %d trades, %d pending instructions",
+				config.ClearingLogPath, stats["total_trades"], stats["instructions"])
+		}
+		closeEventLogs := closeLogs
+		closeLogs = func() {
+			clearingHouse.CloseJournal()
+			closeEventLogs()
+		}
+	}
+
+	// Requests written ahead of the processor, applied again after a crash
+	// (see request_journal.go)
+	var requests *wal.Log
+	if config.RequestJournal != "" {
+		requests, err = openRequestJournal(config.RequestJournal)
+		if err != nil {
+			alerter.Close()
+			closeLogs()
+			return nil, err
+		}
+		closeOtherLogs := closeLogs
+		closeLogs = func() {
+			requests.Close()
+			closeOtherLogs()
+		}
+	}
+
+	var snapshots *snapshot.Store
+	var requestsAfter uint64
+	if config.SnapshotDir != "" {
+		// Rebuild the books, ID counters and clearing house from the latest
+		// snapshot plus the events logged after it
+		snapshots, requestsAfter, err = recoverFromSnapshot(config.SnapshotDir, engines[0], clearingHouse, eventLogs[0], journal, requests)
+		if err != nil {
+			switch {
+			case errors.Is(err, snapshot.ErrChecksumMismatch):
+				alerter.Raise(alerts.KindReplayChecksum, "", alerts.SeverityCritical,
+					"snapshot in %s failed verification: %v", config.SnapshotDir, err)
+			case errors.Is(err, events.ErrChecksumMismatch), errors.Is(err, events.ErrSequenceGap):
+				alerter.Raise(alerts.KindReplayChecksum, "", alerts.SeverityCritical,
+					"event log %s failed verification on replay: %v", config.EventLogPath, err)
+			}
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to recover from snapshot: %w", err)
+		}
+	} else if !clustered {
+		// Restore ID high-water marks from the event logs so a restarted engine
+		// never reissues an order or trade ID that downstream systems already saw
+		for i, eventLog := range eventLogs {
+			counters, err := matching.RecoverIDCounters(eventLog)
+			if err != nil {
+				if errors.Is(err, events.ErrChecksumMismatch) || errors.Is(err, events.ErrSequenceGap) {
+					alerter.Raise(alerts.KindReplayChecksum, "", alerts.SeverityCritical,
+						"event log %s failed verification on replay: %v", shard.LogPath(config.EventLogPath, i, config.Shards), err)
+				}
+				alerter.Close() // Flush the alert before the caller exits
+				closeLogs()
+				return nil, fmt.Errorf("failed to recover ID counters: %w", err)
+			}
+			engines[i].RestoreIDCounters(counters)
+			log.Printf("Restored ID counters (shard %d): order=%d trade=%d seq=%d",
+				i, counters.OrderID, counters.TradeID, counters.SequenceNum)
+		}
+	}
+
+	// Admin actions are audited in a log of their own, verified on open so
+	// a tampered trail is caught at startup rather than during an inquiry
+	var auditKey []byte
+	if config.AuditKey != "" {
+		auditKey = []byte(config.AuditKey)
+	}
+	auditLog, err := audit.Open(config.AuditLogPath, auditKey)
+	if err != nil {
+		if errors.Is(err, audit.ErrTampered) {
+			alerter.Raise(alerts.KindAuditWrite, "", alerts.SeverityCritical,
+				"audit log %s failed verification: %v", config.AuditLogPath, err)
+		}
+		alerter.Close()
+		closeLogs()
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
 	}
 
+	// Symbols migrated away before the restart keep forwarding to their shard
+	migrations := migration.NewGate(config.MigrateWait)
+	moved := make(map[string]string)
+	for _, engine := range engines {
+		for symbol, target := range engine.MovedSymbols() {
+			moved[symbol] = target
+		}
+	}
+	migrations.SetMoved(moved)
+
 	// Create supporting components
-	riskChecker := risk.NewChecker(risk.DefaultConfig())
+	riskConfig := risk.DefaultConfig()
+	riskConfig.MaxDailyLoss = config.MaxDailyLoss
+	riskConfig.MaxOrderRate = config.MaxOrderRate
+	riskConfig.RateBreachKill = config.RateBreachKill
+	riskChecker := risk.NewChecker(riskConfig)
+	for name, profile := range risk.DefaultProfiles() {
+		riskChecker.SetProfile(name, profile)
+	}
+	// Per-account overrides live in the event log (see risk_limits.go)
+	overridden, err := restoreRiskLimits(riskChecker, eventLogs[0])
+	if err != nil {
+		alerter.Close()
+		closeLogs()
+		return nil, fmt.Errorf("failed to restore risk limits: %w", err)
+	}
+	if overridden > 0 {
+		log.Printf("Restored risk limit overrides for %d accounts", overridden)
+	}
+	restricted, err := restoreRestrictions(riskChecker, eventLogs[0])
+	if err != nil {
+		alerter.Close()
+		closeLogs()
+		return nil, fmt.Errorf("failed to restore restricted lists: %w", err)
+	}
+	if restricted > 0 {
+		log.Printf("Restored %d symbol restrictions", restricted)
+	}
+	dropCopy := dropcopy.NewHub(1000)
+	riskChecker.OnEvent(func(event risk.Event) {
+		log.Printf("Risk event %s for %s: %s", event.Type, event.AccountID, event.Reason)
+		switch {
+		case event.Type == risk.EventOrderRateBreached:
+			alerter.Raise(alerts.KindOrderRate, event.AccountID, alerts.SeverityWarning,
+				"account %s throttled: %s", event.AccountID, event.Reason)
+		case event.Type == risk.EventKillSwitchTripped && event.Check == risk.CheckOrderRate:
+			alerter.Raise(alerts.KindOrderRate, event.AccountID, alerts.SeverityCritical,
+				"account %s kill switch tripped: %s", event.AccountID, event.Reason)
+			recordAudit(auditLog, alerter, "system", "risk.kill_switch", event.AccountID, map[string]string{
+				"reason": event.Reason,
+				"limit":  strconv.FormatInt(event.Limit, 10),
+			}, nil)
+		case event.Type == risk.EventKillSwitchTripped && !event.Operator:
+			alerter.Raise(alerts.KindDailyLossLimit, event.AccountID, alerts.SeverityCritical,
+				"account %s kill switch tripped: %s", event.AccountID, event.Reason)
+			recordAudit(auditLog, alerter, "system", "risk.kill_switch", event.AccountID, map[string]string{
+				"reason": event.Reason,
+				"pnl":    orders.FormatPrice(event.PnL),
+				"limit":  orders.FormatPrice(event.Limit),
+			}, nil)
+		}
+		dropCopy.PublishRiskEvent(event)
+	})
+
+	// Margin on unsettled trades, marked at the risk checker's reference
+	// prices (see margin.go)
+	clearingHouse.SetMargin(settlement.MarginConfig{InitialBps: config.InitialMarginBps}, riskChecker.GetReferencePrice)
+	clearingHouse.OnMarginCall(func(m settlement.Margin) { onMarginCall(riskChecker, alerter, m) })
 	publisher := marketdata.NewPublisher(1000)
-	clearingHouse := settlement.NewClearingHouse()
+	publisher.SetStallTimeout(config.StallTimeout)
+	publisher.SetRetransmitHistory(config.RetransmitHistory)
+	symbolStats := marketdata.NewStatsTracker(publisher)
+
+	// This engine is one venue of the NBBO; other instances' L1 feeds are
+	// added with AddVenue
+	nbbo := marketdata.NewConsolidator(1000)
+	nbbo.AddVenue(config.ShardID, publisher.SubscribeAllL1Conflated().C) // Only the latest quote counts
+
+	// Binary market data over multicast, if configured. The session ID
+	// changes every start, so receivers know sequence numbers restarted
+	var itchFeed *itch.Feed
+	var itchGaps net.Listener
+	if config.ItchMulticast != "" {
+		conn, err := itch.DialMulticast(config.ItchMulticast)
+		if err != nil {
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to open ITCH multicast %s: %w", config.ItchMulticast, err)
+		}
+		itchFeed = itch.NewFeed(fmt.Sprintf("%010d", time.Now().Unix()%1e10), conn, itch.DefaultHistory)
+		publisher.AddFeed(itchFeed)
+		if config.ItchRetransmit != "" {
+			itchGaps, err = net.Listen("tcp", config.ItchRetransmit)
+			if err != nil {
+				conn.Close()
+				alerter.Close()
+				closeLogs()
+				return nil, fmt.Errorf("failed to listen for ITCH retransmission on %s: %w", config.ItchRetransmit, err)
+			}
+		}
+	}
+
+	// Share reference prices and halts with other shards, if configured
+	var refShare *refshare.Sharer
+	if config.RefShareRedis != "" {
+		refShare = refshare.New(
+			redis.NewClient(&redis.Options{
+				Addr:         config.RefShareRedis,
+				DialTimeout:  2 * time.Second,
+				ReadTimeout:  time.Second,
+				WriteTimeout: time.Second,
+			}),
+			refshare.Config{ShardID: config.ShardID},
+			riskChecker, refData)
+	}
 
-	// Create some test accounts for demo purposes
+	// Create some test accounts for demo purposes. With buying power checks
+	// they need shares to sell as well as cash
 	for _, acct := range []string{"TRADER1", "TRADER2", "MM1", "MM2"} {
+		if clearingHouse.GetAccount(acct) != nil {
+			continue // Restored from the clearing journal or a snapshot
+		}
 		clearingHouse.GetOrCreateAccount(acct, 10000000) // $100,000 each
+		if config.InitialMarginBps > 0 {
+			clearingHouse.PostCollateral(acct, demoCollateral)
+		}
+		if config.BuyingPower {
+			for _, symbol := range config.Symbols {
+				clearingHouse.DepositShares(acct, symbol, 10000)
+			}
+		}
 	}
 
 	// CRITICAL: Initialize LMAX Disruptor components (see README for details)
@@ -127,35 +657,287 @@ func NewServer(config Config) (*Server, error) {
 	//   - Maintains determinism (same input = same output)
 	//   - Processes orders sequentially in sequence number order
 	//   - Calls matching engine and logs events
-	ringBuffer := disruptor.NewRingBuffer(disruptor.DefaultConfig()) // 8192 slots
-	sequencer := disruptor.NewSequencer(ringBuffer)
-	eventProcessor := disruptor.NewEventProcessor(ringBuffer, engine, eventLog)
+	//
+	// Each shard gets all three; the clearing house and fee engine are shared
+	feeEngine := fees.NewEngine(config.Fees)
+	for name, schedule := range fees.DefaultTiers() {
+		feeEngine.SetTier(name, schedule)
+	}
+	shards := make([]*shard.Shard, config.Shards)
+	for i := range shards {
+		ringConfig := disruptor.DefaultConfig() // 8192 slots
+		ringConfig.WaitStrategy = config.WaitStrategy
+		ringBuffer := disruptor.NewRingBuffer(ringConfig)
+		sequencer := disruptor.NewSequencer(ringBuffer)
+		eventProcessor := disruptor.NewEventProcessor(ringBuffer, engines[i], eventLogs[i])
+		eventProcessor.SetFairScheduling(config.FairBatch) // One hot symbol can't starve the rest
+		eventProcessor.EnableTimers(config.TimerTick)
+		eventProcessor.EnableClearing(clearingHouse) // Trades recorded in log order
+		eventProcessor.EnableFees(feeEngine)         // Fees logged with each fill
+		eventProcessor.SetIdempotencyWindow(config.IdempotencyWindow) // Resent client order IDs answered, not re-entered
+		if config.BuyingPower {
+			eventProcessor.EnableBuyingPower() // Holds taken for the recovered books
+		}
+		if snapshots != nil {
+			eventProcessor.EnableSnapshots(snapshots, config.SnapshotInterval)
+			eventProcessor.SetSnapshotEvery(config.SnapshotEvery)
+		}
+		if requests != nil {
+			eventProcessor.EnableJournal(requests, requestsAfter) // Batches journaled before they are applied
+		}
+		shards[i] = &shard.Shard{
+			Index:      i,
+			Engine:     engines[i],
+			EventLog:   eventLogs[i],
+			RingBuffer: ringBuffer,
+			Sequencer:  sequencer,
+			Processor:  eventProcessor,
+		}
+	}
+	// Public trade reports carry counterparty codes, never account IDs
+	tapeKey := []byte(config.TapeKey)
+	if len(tapeKey) == 0 {
+		tapeKey = make([]byte, 32)
+		if _, err := rand.Read(tapeKey); err != nil {
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to generate tape key: %w", err)
+		}
+	}
+	anonymizer := enrichment.NewAnonymizer(tapeKey)
+
+	// Trades clear and settle on their settle dates without operator calls,
+	// on the first shard's timers (see disruptor/settle.go)
+	shards[0].Processor.SettleEvery(config.SettleEvery)
 
 	server := &Server{
-		engine:         engine,
+		refData:        refData,
 		riskChecker:    riskChecker,
-		eventLog:       eventLog,
 		publisher:      publisher,
 		clearingHouse:  clearingHouse,
-		ringBuffer:     ringBuffer,
-		sequencer:      sequencer,
-		eventProcessor: eventProcessor,
+		settleEvery:    config.SettleEvery,
+		symbolStats:    symbolStats,
+		nbbo:           nbbo,
+		alerter:        alerter,
+		refShare:       refShare,
+		dropCopy:       dropCopy,
+		wsJournals:     wsjournal.NewStore(),
+		migrations:     migrations,
+		auditLog:       auditLog,
+		calendars:      calendars,
+		fees:           feeEngine,
+		tape:           enrichment.NewPipeline(enrichment.Counterparties(anonymizer)),
+		anonymizer:     anonymizer,
+		breaker:        circuit.New(config.Circuit),
+		halts:          newHaltControl(config.HaltOrders),
+		journal:        journal,
+		shardID:        config.ShardID,
+		itchFeed:       itchFeed,
+		itchGaps:       itchGaps,
+		shards:         shard.NewSet(shards...),
+		degrade:        degrade.NewController(config.Degrade),
+		stopLoad:       make(chan struct{}),
+		eventStreams:   make(chan struct{}),
+		orderLimits:    ratelimit.New(config.OrderRate),
+		requests:       requests,
+		requestsAfter:  requestsAfter,
+		kafkaProducer:  kafkaProducer,
+		kafkaSinks:     kafkaSinks,
+		listeners:      listeners,
+	}
+	server.degrade.OnChange(server.onDegrade)
+	server.metrics = newServerMetrics(server)
+	server.trades = marketdata.NewTradeStore(config.TradeHistory, server.tradesFromLog)
+	if config.SessionCheck > 0 {
+		server.sessions = server.newSessions(config.Market)
+		server.sessionCheck = config.SessionCheck
+	}
+
+	for _, sh := range shards {
+		eventProcessor := sh.Processor
+
+		// Runs on the processor goroutine, so the shard's books are safe to read here
+		eventProcessor.OnDeadManTrip(func(sessionID string, cancelled []*orders.Order) {
+			log.Printf("Session %s: dead man's switch expired, cancelled %d orders", sessionID, len(cancelled))
+			server.publishCancelled(cancelled)
+		})
+		eventProcessor.OnAuction(server.publishAuction)
+		eventProcessor.OnRiskLimits(func(change *events.RiskLimitsEvent) { applyRiskLimits(riskChecker, change) })
+		eventProcessor.OnRestriction(func(change *events.RestrictionEvent) { applyRestriction(riskChecker, change) })
+		eventProcessor.OnAccount(func(change *events.AccountEvent) error { return applyAccount(clearingHouse, change) })
+		eventProcessor.OnExecution(func(report orders.ExecutionReport) {
+			dropCopy.PublishExecution(report)
+			server.wsJournals.Report(report)
+		})
+		if config.CheckBooks {
+			eventProcessor.CheckBooks(func(symbol string, err error) {
+				alerter.Raise(alerts.KindBookCheck, symbol, alerts.SeverityCritical, "order book inconsistent: %v", err)
+			})
+		}
+
+		// An event missing from the log is journal damage too. The hooks must
+		// not block the processor or batcher, so halting happens elsewhere
+		eventProcessor.OnEventDrop(func(event interface{}) {
+			alerter.Raise(alerts.KindEventDropped, "", alerts.SeverityCritical,
+				"event queue full, dropped %T (%d dropped total)", event, eventProcessor.DroppedEvents())
+			if journal.halt {
+				go server.journalDamaged(event, "dropped", errors.New("event queue full"))
+			}
+		})
+		eventProcessor.OnEventLogError(func(event interface{}, err error) {
+			alerter.Raise(alerts.KindJournalDamage, events.SymbolOf(event), alerts.SeverityCritical,
+				"%T not written to the event log: %v", event, err)
+			if journal.halt {
+				go server.journalDamaged(event, "append", err)
+			}
+		})
+	}
+
+	// Symbols the replay found damage to start halted
+	server.haltDamaged(journal.symbolsDamaged())
+
+	// Binary order entry, if configured (see binary_gateway.go)
+	if config.BinaryAddr != "" {
+		server.binaryLn, err = net.Listen("tcp", config.BinaryAddr)
+		if err != nil {
+			if itchGaps != nil {
+				itchGaps.Close()
+			}
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to listen for binary order entry on %s: %w", config.BinaryAddr, err)
+		}
+		server.binary = gateway.NewServer(binaryHandler{server})
+	}
+
+	// gRPC order entry, if configured (see grpc.go)
+	if config.GRPCAddr != "" {
+		server.grpcLn, err = net.Listen("tcp", config.GRPCAddr)
+		if err != nil {
+			if server.binaryLn != nil {
+				server.binaryLn.Close()
+			}
+			if itchGaps != nil {
+				itchGaps.Close()
+			}
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to listen for gRPC order entry on %s: %w", config.GRPCAddr, err)
+		}
+		server.grpc = grpcapi.NewServer(grpcHandler{server})
+		server.grpcStop = make(chan struct{})
+	}
+
+	// Standbys replicate the event log, if configured (see replication.go)
+	if config.ReplicationAddr != "" {
+		server.replicationLn, err = net.Listen("tcp", config.ReplicationAddr)
+		if err != nil {
+			if server.binaryLn != nil {
+				server.binaryLn.Close()
+			}
+			if server.grpcLn != nil {
+				server.grpcLn.Close()
+			}
+			if itchGaps != nil {
+				itchGaps.Close()
+			}
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to listen for standbys on %s: %w", config.ReplicationAddr, err)
+		}
+		server.replication = replication.NewPrimary(eventLogs[0], replication.DefaultConfig())
 	}
 
-	// Setup HTTP handlers
-	mux := http.NewServeMux()
-	mux.HandleFunc("/order", server.handleOrder)
-	mux.HandleFunc("/cancel", server.handleCancel)
-	mux.HandleFunc("/book", server.handleBook)
-	mux.HandleFunc("/account", server.handleAccount)
-	mux.HandleFunc("/stats", server.handleStats)
-	mux.HandleFunc("/health", server.handleHealth)
+	// Join the Raft cluster, if configured (see cluster.go)
+	if clustered {
+		server.cluster, err = newCluster(server, config.Raft)
+		if err != nil {
+			if server.binaryLn != nil {
+				server.binaryLn.Close()
+			}
+			if server.grpcLn != nil {
+				server.grpcLn.Close()
+			}
+			if itchGaps != nil {
+				itchGaps.Close()
+			}
+			alerter.Close()
+			closeLogs()
+			return nil, fmt.Errorf("failed to join raft cluster: %w", err)
+		}
+		// Timers that change state act through the leader
+		shards[0].Processor.OnTimerRequest(func(request *disruptor.OrderRequest) {
+			go server.cluster.proposeTimer(request)
+		})
+	}
 
-	server.httpServer = &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.Port),
-		Handler:      mux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	// Setup HTTP handlers, each on the listener serving its part of the API
+	trading := listener.For(server.listeners, listener.Trading).Mux
+	trading.HandleFunc("/order", server.handleOrder)
+	trading.HandleFunc("/order/replace", server.handleReplace)
+	trading.HandleFunc("/order/preview", server.handleOrderPreview)
+	trading.HandleFunc("/basket", server.handleBasket)
+	trading.HandleFunc("/cancel", server.handleCancel)
+	trading.HandleFunc("/orders", server.handleOpenOrders)
+	trading.HandleFunc("/ws", server.handleWebSocket)
+	trading.HandleFunc("/ws/dropcopy", server.handleDropCopy)
+	trading.HandleFunc("/account", server.handleAccount)
+	trading.HandleFunc("/margin", server.handleMargin)
+	trading.HandleFunc("/pnl", server.handlePnL)
+	trading.HandleFunc("/reports/trades", server.handleTradeReport)
+
+	marketData := listener.For(server.listeners, listener.MarketData).Mux
+	marketData.HandleFunc("/book", server.handleBook)
+	marketData.HandleFunc("/book/bands", server.handleBands)
+	marketData.HandleFunc("/book/nbbo", server.handleNBBO)
+	marketData.HandleFunc("/book/updates", server.handleBookUpdates)
+	marketData.HandleFunc("/book/auction", server.handleAuction)
+	marketData.HandleFunc("/marketdata/replay", server.handleMarketDataReplay)
+	marketData.HandleFunc("/tape", server.handleTape)
+	marketData.HandleFunc("/trades", server.handleTrades)
+	marketData.HandleFunc("/ws/book", server.handleBookFeed)
+	marketData.HandleFunc("/ws/marketdata", server.handleMarketDataFeed)
+	marketData.HandleFunc("/stats/symbol", server.handleSymbolStats)
+	marketData.HandleFunc("/symbols", server.handleSymbols)
+	marketData.HandleFunc("/calendar", server.handleCalendar)
+
+	admin := listener.For(server.listeners, listener.Admin).Mux
+	admin.HandleFunc("/accounts", server.handleOpenAccount)
+	admin.HandleFunc(accountsPath, server.handleAccountMovement)
+	admin.HandleFunc("/events/stream", server.handleEventStream)
+	admin.HandleFunc("/settlement/events", server.handleSettlementEvents)
+	admin.HandleFunc("/reports/settlement", server.handleSettlementReport)
+	admin.HandleFunc("/stats", server.handleStats)
+	admin.HandleFunc("/metrics", server.handleMetrics)
+	admin.HandleFunc("/admin/stress", server.handleStress)
+	admin.HandleFunc("/admin/symbol/state", server.handleSymbolState)
+	admin.HandleFunc("/admin/journal", server.handleJournal)
+	admin.HandleFunc("/admin/journal/resume", server.handleJournalResume)
+	admin.HandleFunc("/admin/symbol/migrate", server.handleMigrate)
+	admin.HandleFunc(migration.ImportPath, server.handleImport)
+	admin.HandleFunc("/admin/risk/pnl", server.handleAccountPnL)
+	admin.HandleFunc("/admin/risk/reinstate", server.handleReinstate)
+	admin.HandleFunc("/admin/kill", server.handleKill)
+	admin.HandleFunc("/admin/collateral", server.handleCollateral)
+	admin.HandleFunc("/admin/risk/profile", server.handleRiskProfile)
+	admin.HandleFunc(riskLimitsPath, server.handleRiskLimits)
+	admin.HandleFunc("/admin/restrictions", server.handleRestrictions)
+	admin.HandleFunc("/admin/fees/tier", server.handleFeeTier)
+	admin.HandleFunc("/admin/tape/counterparty", server.handleRevealCounterparty)
+	admin.HandleFunc("/admin/audit", server.handleAudit)
+	admin.HandleFunc("/admin/replication", server.handleReplication)
+	admin.HandleFunc("/admin/degrade", server.handleDegrade)
+	admin.HandleFunc("/admin/cluster", server.handleCluster)
+	admin.HandleFunc("/admin/ratelimit", server.handleRateLimit)
+
+	for _, l := range server.listeners {
+		l.Mux.HandleFunc("/health", server.handleHealth)
+
+		var handler http.Handler = l.Mux
+		if server.cluster != nil {
+			handler = server.leaderOnly(handler)
+		}
+		l.ServeWith(server.metrics.instrument(l.Mux, server.shedHTTP(handler)))
 	}
 
 	return server, nil
@@ -163,16 +945,101 @@ func NewServer(config Config) (*Server, error) {
 
 // Start starts the server.
 func (s *Server) Start() error {
-	log.Printf("Starting Order Matching Engine on %s", s.httpServer.Addr)
-	log.Printf("Symbols: %v", s.engine.Symbols())
+	log.Printf("Starting Order Matching Engine on %s", s.listeners[0].Config.Addr)
+	log.Printf("Symbols: %v (%d shard(s))", s.shards.Symbols(), s.shards.Len())
+
+	// CRITICAL: Start the event processors first before accepting HTTP requests
+	// Each processor runs in its own goroutine, consuming from its shard's ring
+	// buffer and calling its matching engine in a single-threaded, deterministic manner
+	for _, sh := range s.shards.All() {
+		sh.Processor.Start()
+	}
+
+	// Requests journaled but not logged before a crash are applied before
+	// any new ones
+	if s.requests != nil {
+		if err := s.replayJournal(); err != nil {
+			return err
+		}
+	}
+	for _, sink := range s.kafkaSinks {
+		sink.Start()
+	}
+	s.symbolStats.Start()
+	go s.watchLoad(s.stopLoad)
+	if s.sessions != nil {
+		if err := s.startSessions(); err != nil {
+			return err
+		}
+	}
+
+	// Committed requests are applied from here on, so after the processors
+	if s.cluster != nil {
+		status := s.cluster.node.Status()
+		log.Printf("Raft node %d on %s", status.ID, status.Addr)
+		s.cluster.start()
+	}
+
+	if s.itchFeed != nil {
+		s.itchFeed.Start()
+		log.Printf("ITCH feed: session %s", s.itchFeed.Session())
+	}
+	if s.itchGaps != nil {
+		go func() {
+			if err := s.itchFeed.ServeRetransmit(s.itchGaps); err != nil {
+				log.Printf("ITCH retransmission stopped: %v", err)
+			}
+		}()
+	}
+	if s.binary != nil {
+		log.Printf("Binary order entry on %s", s.binaryLn.Addr())
+		go func() {
+			if err := s.binary.Serve(s.binaryLn); err != nil {
+				log.Printf("Binary order entry stopped: %v", err)
+			}
+		}()
+	}
+	if s.grpc != nil {
+		log.Printf("gRPC order entry on %s", s.grpcLn.Addr())
+		go func() {
+			if err := s.grpc.Serve(s.grpcLn); err != nil {
+				log.Printf("gRPC order entry stopped: %v", err)
+			}
+		}()
+	}
 
-	// CRITICAL: Start the event processor first before accepting HTTP requests
-	// The processor runs in its own goroutine, consuming from the ring buffer
-	// and calling the matching engine in a single-threaded, deterministic manner
-	s.eventProcessor.Start()
+	if s.replication != nil {
+		log.Printf("Replicating the event log to standbys on %s", s.replicationLn.Addr())
+		go func() {
+			if err := s.replication.Serve(s.replicationLn); err != nil {
+				log.Printf("Replication stopped: %v", err)
+			}
+		}()
+	}
+
+	// Reference data sharing is an accuracy aid, not a dependency: if Redis
+	// is down the shard trades on its local view
+	if err := s.refShare.Start(context.Background()); err != nil {
+		log.Printf("WARNING: Reference data sharing disabled: %v", err)
+		s.refShare = nil
+	}
 
-	// Start HTTP server (blocks until shutdown)
-	return s.httpServer.ListenAndServe()
+	// Start HTTP listeners (blocks until shutdown). Every one is bound
+	// before any serves, so an address in use fails startup
+	if err := listener.ListenAll(s.listeners); err != nil {
+		return err
+	}
+	for _, l := range s.listeners {
+		log.Printf("Serving %s", l)
+	}
+	for _, l := range s.listeners[1:] {
+		go func(l *listener.Listener) {
+			if err := l.Serve(); err != http.ErrServerClosed {
+				log.Printf("HTTP %s listener stopped: %v", l.Roles[0], err)
+			}
+		}(l)
+	}
+	return s.listeners[0].Serve()
 }
 
 // Shutdown gracefully shuts down the server.
@@ -185,24 +1052,100 @@ func (s *Server) Start() error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
 
+	// Refuse new orders from here on, from every front-end; cancels drain
+	s.degrade.Drain()
+
 	// Step 1: Stop accepting new HTTP requests
-	// Existing in-flight requests will complete
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		return err
+	// Existing in-flight requests will complete; event streams never
+	// would, so they are ended first
+	close(s.eventStreams)
+	var httpErr error
+	for _, l := range s.listeners {
+		if err := l.Server.Shutdown(ctx); err != nil && httpErr == nil {
+			httpErr = err
+		}
+	}
+	if httpErr != nil {
+		return httpErr
+	}
+	if s.binary != nil {
+		// Cancel on disconnect still needs the processors
+		s.binaryLn.Close()
+		s.binary.Close()
+	}
+	if s.grpc != nil {
+		// Lets in-flight RPCs finish; execution streams would never, so
+		// they are ended first
+		close(s.grpcStop)
+		stopped := make(chan struct{})
+		go func() {
+			s.grpc.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpc.Stop()
+		}
 	}
 
-	// Step 2: Shutdown event processor
-	// This drains the ring buffer (processes all pending orders)
-	// and flushes all batched events to the event log
-	s.eventProcessor.Shutdown()
+	// Stop applying committed requests; what this node misses it re-applies
+	// from its Raft log on restart
+	if s.cluster != nil {
+		if err := s.cluster.close(); err != nil {
+			log.Printf("Failed to close raft node: %v", err)
+		}
+	}
 
-	// Step 3: Close event log (final fsync to ensure durability)
-	if err := s.eventLog.Close(); err != nil {
-		return err
+	// Step 2: Shutdown event processors
+	// This drains the ring buffers (processes all pending orders)
+	// and flushes all batched events to the event logs
+	close(s.stopLoad)
+	for _, sh := range s.shards.All() {
+		sh.Processor.Shutdown()
+	}
+	if err := s.clearingHouse.CloseJournal(); err != nil {
+		log.Printf("Failed to close clearing journal: %v", err)
+	}
+	if s.requests != nil {
+		if err := s.requests.Close(); err != nil {
+			log.Printf("Failed to close request journal: %v", err)
+		}
 	}
 
-	// Step 4: Close market data publisher
+	// Standbys have had every event the processors logged streamed to them
+	if s.replication != nil {
+		s.replicationLn.Close()
+		s.replication.Close()
+	}
+	// and the Kafka sinks catch up on it, until the deadline
+	if s.kafkaProducer != nil {
+		s.closeKafka(ctx)
+	}
+
+	// Step 3: Close event logs (final fsync to ensure durability)
+	for _, sh := range s.shards.All() {
+		if err := sh.EventLog.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Step 4: Close market data publisher and drop-copy feed. The ITCH feed
+	// sends its end of session once everything published is out
 	s.publisher.Close()
+	if s.itchGaps != nil {
+		s.itchGaps.Close()
+	}
+	if s.itchFeed != nil {
+		s.itchFeed.Close()
+	}
+	s.nbbo.Close()
+	s.dropCopy.Close()
+
+	// Step 5: Stop reference data sharing and deliver any pending alerts
+	s.refShare.Stop()
+	s.auditLog.Close()
+	s.alerter.Close()
 	return nil
 }
 
@@ -215,6 +1158,9 @@ type OrderRequest struct {
 	Quantity      int64  `json:"quantity"`
 	AccountID     string `json:"account_id"`
 	ClientOrderID string `json:"client_order_id,omitempty"`
+	DisplayQty    int64  `json:"display_qty,omitempty"`   // Iceberg: shares shown at a time (limit orders only)
+	Peg           string `json:"peg,omitempty"`           // "midpoint" or "primary" (limit orders only); price is then the cap
+	TimeInForce   string `json:"time_in_force,omitempty"` // "gtc" (default) or "day": expires at the market's close
 }
 
 // OrderResponse represents an order response.
@@ -224,9 +1170,16 @@ type OrderResponse struct {
 	Status        string        `json:"status,omitempty"`
 	FilledQty     int64         `json:"filled_qty,omitempty"`
 	RemainingQty  int64         `json:"remaining_qty,omitempty"`
+	CumQty        int64         `json:"cum_qty"`              // Total filled across all fills (FIX CumQty)
+	AvgPrice      string        `json:"avg_price,omitempty"`  // Average fill price (FIX AvgPx)
+	LeavesQty     int64         `json:"leaves_qty"`           // Still open for execution (FIX LeavesQty)
 	Fills         []FillInfo    `json:"fills,omitempty"`
+	RejectCode    string        `json:"reject_code,omitempty"`  // Stable code, same across all front-ends
 	RejectReason  string        `json:"reject_reason,omitempty"`
 	Error         string        `json:"error,omitempty"`
+	Duplicate     bool          `json:"duplicate,omitempty"` // Resent client_order_id: the original order's response
+
+	matched int64 // When the engine matched the order (0 if it never got there), for Server-Timing
 }
 
 // FillInfo represents fill information in a response.
@@ -234,13 +1187,19 @@ type FillInfo struct {
 	TradeID  uint64 `json:"trade_id"`
 	Price    string `json:"price"`
 	Quantity int64  `json:"quantity"`
+	Fee      string `json:"fee,omitempty"` // Taker fee charged to the order (negative = rebate)
 }
 
 func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.handleOrderStatus(w, r) // See order_query.go
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	ingress := orders.Now()
 
 	var req OrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -251,6 +1210,27 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	order, err := parseOrderRequest(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, OrderResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if s.throttled(w, order.AccountID) {
+		return
+	}
+
+	status, resp := s.executeOrder(order)
+	setServerTiming(w, ingress, resp.matched, orders.Now())
+	writeJSON(w, status, resp)
+}
+
+// parseOrderRequest converts a wire-format order request into an engine order.
+// Shared by every order entry front-end so they validate identically.
+func parseOrderRequest(req OrderRequest) (*orders.Order, error) {
 	// Parse side
 	var side orders.Side
 	switch req.Side {
@@ -259,11 +1239,7 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 	case "sell", "SELL":
 		side = orders.SideSell
 	default:
-		writeJSON(w, http.StatusBadRequest, OrderResponse{
-			Success: false,
-			Error:   "invalid side: must be 'buy' or 'sell'",
-		})
-		return
+		return nil, fmt.Errorf("invalid side: must be 'buy' or 'sell'")
 	}
 
 	// Parse order type
@@ -278,39 +1254,56 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 	case "fok", "FOK":
 		orderType = orders.OrderTypeFOK
 	default:
-		writeJSON(w, http.StatusBadRequest, OrderResponse{
-			Success: false,
-			Error:   "invalid type: must be 'market', 'limit', 'ioc', or 'fok'",
-		})
-		return
+		return nil, fmt.Errorf("invalid type: must be 'market', 'limit', 'ioc', or 'fok'")
 	}
 
 	// Parse price: Convert from decimal string to fixed-point integer
-	// Example: "150.00" -> 150000 (stored as integer with 3 decimal places)
+	// Example: "150.25" -> 15025 (stored as integer cents)
 	//
 	// Why fixed-point? Floating-point arithmetic has precision issues:
 	//   0.1 + 0.2 = 0.30000000000000004 (IEEE 754 rounding error)
 	//
-	// Financial systems use fixed-point to ensure exact decimal arithmetic:
-	//   $150.00 is stored as 150000 (integer)
-	//   $0.01 minimum tick size (1 = $0.001)
+	// The string is parsed digit by digit, never through a float, so every
+	// price converts exactly; one finer than a cent is refused, and one off
+	// the symbol's tick is rejected with the reference data checks.
 	//
-	// See README "Core Concepts - Price Representation" for more details
+	// See README "Core Concepts - Fixed-Point Arithmetic" for more details
 	var price int64
 	if req.Price != "" {
-		priceFloat, err := strconv.ParseFloat(req.Price, 64)
+		var err error
+		price, err = orders.ParsePrice(req.Price)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, OrderResponse{
-				Success: false,
-				Error:   fmt.Sprintf("invalid price: %v", err),
-			})
-			return
+			return nil, fmt.Errorf("invalid price: %v", err)
 		}
-		price = orders.ParsePrice(priceFloat) // Multiply by 1000 to convert to fixed-point
 	}
 
-	// Create order
-	order := &orders.Order{
+	// Pegged orders are priced by the engine; a price given with a peg caps
+	// it. Price keeps the cap until then, so risk checks see the worst case
+	var peg orders.PegType
+	switch req.Peg {
+	case "":
+	case "midpoint", "MIDPOINT":
+		peg = orders.PegMidpoint
+	case "primary", "PRIMARY":
+		peg = orders.PegPrimary
+	default:
+		return nil, fmt.Errorf("invalid peg: must be 'midpoint' or 'primary'")
+	}
+	var pegLimit int64
+	if peg != orders.PegNone {
+		pegLimit = price
+	}
+
+	var tif orders.TimeInForce
+	switch req.TimeInForce {
+	case "", "gtc", "GTC":
+	case "day", "DAY":
+		tif = orders.TIFDay
+	default:
+		return nil, fmt.Errorf("invalid time_in_force: must be 'gtc' or 'day'")
+	}
+
+	return &orders.Order{
 		Symbol:        req.Symbol,
 		Side:          side,
 		Type:          orderType,
@@ -318,18 +1311,56 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 		Quantity:      req.Quantity,
 		AccountID:     req.AccountID,
 		ClientOrderID: req.ClientOrderID,
-		Timestamp:     orders.Now(),
+		DisplayQty:    req.DisplayQty,
+		Peg:           peg,
+		PegLimit:      pegLimit,
+		TimeInForce:   tif,
+	}, nil
+}
+
+// executeOrder runs risk checks, sequences the order through the ring buffer,
+// and performs post-trade processing. Returns the HTTP status and response.
+func (s *Server) executeOrder(order *orders.Order) (status int, _ OrderResponse) {
+	defer func() { s.metrics.countOrder(status) }()
+
+	// A symbol being migrated holds its orders here until it re-opens,
+	// wherever that is
+	leave, err := s.migrations.Enter(order.Symbol)
+	var moved *migration.MovedError
+	if errors.As(err, &moved) {
+		return forwardOrder(moved, order)
+	}
+	if err != nil {
+		return http.StatusServiceUnavailable, OrderResponse{Success: false, Error: err.Error()}
+	}
+	defer leave()
+
+	// Under overload the engine takes cancels only (see degrade.go)
+	if err := s.admitOrder(); err != nil {
+		return http.StatusServiceUnavailable, OrderResponse{Success: false, Error: err.Error()}
 	}
 
-	// Run pre-trade risk checks (e.g., position limits, buying power)
-	// This happens before submitting to the ring buffer to reject invalid orders early
+	// Validate against reference data before the order can claim a ring
+	// buffer slot (symbol, session state, lot and tick size)
+	if reject := s.refData.Validate(order); reject != nil {
+		if reject.Code == refdata.RejectNotTrading {
+			if status, response, held := s.holdOrder(order); held {
+				return status, response
+			}
+		}
+		return http.StatusBadRequest, rejectResponse(reject)
+	}
+
+	// Run pre-trade risk checks (e.g., position limits)
+	// This happens before submitting to the ring buffer to reject invalid orders early.
+	// Buying power is checked by the event processor, in sequence with fills
 	riskResult := s.riskChecker.Check(order)
 	if !riskResult.Passed {
-		writeJSON(w, http.StatusBadRequest, OrderResponse{
+		return http.StatusBadRequest, OrderResponse{
 			Success:      false,
+			RejectCode:   riskResult.Code,
 			RejectReason: riskResult.Reason,
-		})
-		return
+		}
 	}
 
 	// ========================================================================
@@ -356,24 +1387,35 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 		Order: order,
 	}
 
-	// Step 1: Claim a sequence number in the ring buffer (lock-free CAS operation)
-	// The sequencer uses atomic.CompareAndSwapUint64 to claim the next slot
-	// If buffer is full, it spins for ~100μs then returns ErrBufferFull
-	seq, err := s.sequencer.Next()
-	if err != nil {
-		// Ring buffer full (backpressure) - return 503 Service Unavailable
-		// Client should retry with exponential backoff
-		writeJSON(w, http.StatusServiceUnavailable, OrderResponse{
-			Success: false,
-			Error:   "server busy, please retry",
-		})
-		return
-	}
+	if s.cluster != nil {
+		// Steps 1-2 in a cluster: committed through Raft first, then
+		// published to every node's ring buffer in commit order (see cluster.go)
+		if err := s.cluster.propose(request, responseCh); err != nil {
+			return http.StatusServiceUnavailable, OrderResponse{
+				Success: false,
+				Error:   sequenceError(err),
+			}
+		}
+	} else {
+		// Step 1: Claim a sequence number in the ring buffer (lock-free CAS operation)
+		// The sequencer uses atomic.CompareAndSwapUint64 to claim the next slot
+		// If buffer is full, it spins for ~100μs then returns ErrBufferFull
+		sequencer := s.shards.For(order.Symbol).Sequencer
+		seq, err := sequencer.Next()
+		if err != nil {
+			// Ring buffer full (backpressure) - return 503 Service Unavailable
+			// Client should retry with exponential backoff
+			return http.StatusServiceUnavailable, OrderResponse{
+				Success: false,
+				Error:   "server busy, please retry",
+			}
+		}
 
-	// Step 2: Publish the request to the claimed slot
-	// This writes the order and response channel to the slot, then atomically
-	// updates the slot's sequence number to signal readiness to the consumer
-	s.sequencer.Publish(seq, request, responseCh)
+		// Step 2: Publish the request to the claimed slot
+		// This writes the order and response channel to the slot, then atomically
+		// updates the slot's sequence number to signal readiness to the consumer
+		sequencer.Publish(seq, request, responseCh)
+	}
 
 	// Step 3: Wait for the event processor to process the order and respond
 	// The processor will call engine.ProcessOrder() and send the result
@@ -383,109 +1425,147 @@ func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
 		// Got response from event processor
 	case <-time.After(5 * time.Second):
 		// Timeout waiting for processing (shouldn't happen unless system overloaded)
-		writeJSON(w, http.StatusGatewayTimeout, OrderResponse{
+		return http.StatusGatewayTimeout, OrderResponse{
 			Success: false,
 			Error:   "processing timeout",
-		})
-		return
+		}
 	}
 
 	// Check if order was accepted
 	if !response.Success {
-		writeJSON(w, http.StatusBadRequest, OrderResponse{
+		return http.StatusBadRequest, OrderResponse{
 			Success:      false,
 			OrderID:      order.ID,
 			RejectReason: response.Result.RejectReason,
 			Error:        fmt.Sprintf("%v", response.Error),
-		})
-		return
+			matched:      response.Matched,
+		}
+	}
+
+	// A resent client_order_id: the original order, already post-traded
+	if response.Duplicate {
+		resp := buildOrderResponse(response.Order, response.Result.Fills)
+		resp.Duplicate = true
+		resp.matched = response.Matched
+		return http.StatusOK, resp
 	}
 
-	result := response.Result
+	resp := s.postTrade(order, response.Result)
+	resp.matched = response.Matched
+	return http.StatusOK, resp
+}
 
+// postTrade performs post-trade processing for an accepted order and builds
+// its response.
+func (s *Server) postTrade(order *orders.Order, result *orders.ExecutionResult) OrderResponse {
 	// ========================================================================
 	// Post-processing: Handle fills and publish market data
 	// ========================================================================
 	//
-	// NOTE: Event logging (NewOrderEvent, FillEvent) is already handled by
-	// the event processor before sending the response. We only need to:
-	//   1. Record trades for settlement (T+2 clearing)
-	//   2. Update risk positions (for future risk checks)
-	//   3. Publish market data (trades and L1 quotes)
+	// NOTE: Event logging (NewOrderEvent, FillEvent) and recording trades
+	// for settlement (T+2 clearing) are already handled by the event
+	// processor before sending the response. We only need to:
+	//   1. Update risk positions (for future risk checks)
+	//   2. Publish market data (trades and L1 quotes)
 
 	// Process each fill (trade execution)
-	fills := make([]FillInfo, len(result.Fills))
-	for i, fill := range result.Fills {
-		// Convert to response format (price as decimal string)
-		fills[i] = FillInfo{
-			TradeID:  fill.TradeID,
-			Price:    orders.FormatPrice(fill.Price), // Convert fixed-point to decimal
-			Quantity: fill.Quantity,
-		}
-
-		// Record trade for settlement (T+2 clearing house)
-		// This updates account cash and holdings
-		s.clearingHouse.RecordTrade(fill)
-
-		// Update risk checker's position tracking
-		// Taker gets +quantity (buy) or -quantity (sell)
-		// Maker gets opposite position
-		s.riskChecker.UpdatePosition(fill.TakerAccountID, fill.Symbol, fill.TakerSide, fill.Quantity)
-		s.riskChecker.UpdatePosition(fill.MakerAccountID, fill.Symbol, fill.TakerSide.Opposite(), fill.Quantity)
-		s.riskChecker.SetReferencePrice(fill.Symbol, fill.Price) // For mark-to-market
-
-		// Publish trade to market data feed (for tape, charting, etc.)
-		s.publisher.PublishTrade(marketdata.TradeReport{
-			TradeID:       fill.TradeID,
-			Symbol:        fill.Symbol,
-			Price:         fill.Price,
-			Quantity:      fill.Quantity,
-			AggressorSide: fill.TakerSide,
-			Timestamp:     fill.Timestamp,
-		})
+	// Fills include trades between pegged orders the order re-priced; only
+	// its own go in the response, but every fill updates risk and the tape
+	for _, fill := range result.Fills {
+		s.recordFill(fill)
+	}
+
+	// New fills and a new reference price can both push an account past its
+	// daily loss limit
+	if len(result.Fills) > 0 {
+		s.riskChecker.CheckLossLimits(order.Symbol)
+		s.clearingHouse.CheckMargin(order.Symbol)
 	}
 
 	// Publish Level 1 (L1) market data update (best bid/ask, last trade)
 	// This is used by trading UIs to show real-time quotes
-	book := s.engine.GetOrderBook(order.Symbol)
-	if book != nil {
-		l1 := marketdata.L1Quote{
-			Symbol:    order.Symbol,
-			Timestamp: orders.Now(),
-		}
-		if bestBid := book.GetBestBid(); bestBid != nil {
-			l1.BidPrice = bestBid.Price
-			l1.BidSize = bestBid.TotalQty
-		}
-		if bestAsk := book.GetBestAsk(); bestAsk != nil {
-			l1.AskPrice = bestAsk.Price
-			l1.AskSize = bestAsk.TotalQty
-		}
-		if len(result.Fills) > 0 {
-			lastFill := result.Fills[len(result.Fills)-1]
-			l1.LastPrice = lastFill.Price
-			l1.LastSize = lastFill.Quantity
+	s.publishMarketData(order.Symbol, result.Fills)
+
+	return buildOrderResponse(order, result.Fills)
+}
+
+// buildOrderResponse builds the response for an accepted order from its
+// state and the fills it took part in.
+func buildOrderResponse(order *orders.Order, executed []orders.Fill) OrderResponse {
+	fills := make([]FillInfo, 0, len(executed))
+	for _, fill := range executed {
+		// Convert to response format (price as decimal string)
+		if fill.TakerOrderID == order.ID {
+			info := FillInfo{
+				TradeID:  fill.TradeID,
+				Price:    orders.FormatPrice(fill.Price), // Convert fixed-point to decimal
+				Quantity: fill.Quantity,
+			}
+			if fill.TakerFee != 0 {
+				info.Fee = orders.FormatPrice(fill.TakerFee)
+			}
+			fills = append(fills, info)
 		}
-		s.publisher.PublishL1(l1)
 	}
 
-	writeJSON(w, http.StatusOK, OrderResponse{
+	return OrderResponse{
 		Success:      true,
 		OrderID:      order.ID,
 		Status:       order.Status.String(),
 		FilledQty:    order.FilledQty,
 		RemainingQty: order.RemainingQty(),
+		CumQty:       order.FilledQty,
+		AvgPrice:     formatAvgPrice(order),
+		LeavesQty:    order.LeavesQty(),
 		Fills:        fills,
-	})
+	}
 }
 
-// handleCancel handles order cancellation requests.
-//
-// Uses the same lock-free ring buffer pattern as handleOrder.
-func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// recordFill updates risk positions and publishes the trade for one fill.
+func (s *Server) recordFill(fill orders.Fill) {
+	// Update risk checker's position tracking
+	// Taker gets +quantity (buy) or -quantity (sell)
+	// Maker gets opposite position
+	s.riskChecker.UpdatePosition(fill.TakerAccountID, fill.Symbol, fill.TakerSide, fill.Quantity)
+	s.riskChecker.UpdatePosition(fill.MakerAccountID, fill.Symbol, fill.TakerSide.Opposite(), fill.Quantity)
+	s.riskChecker.RecordFill(fill.TakerAccountID, fill.Symbol, fill.TakerSide, fill.Quantity, fill.Price)
+	s.riskChecker.RecordFill(fill.MakerAccountID, fill.Symbol, fill.TakerSide.Opposite(), fill.Quantity, fill.Price)
+	s.riskChecker.SetReferencePrice(fill.Symbol, fill.Price) // For mark-to-market
+	s.refShare.PublishPrice(fill.Symbol, fill.Price)          // Keep other shards' price bands in step
+	s.checkCircuit(fill)                                      // Pause or halt on a fast price move
+	s.metrics.countFill(fill.Quantity)
+
+	// Publish trade to market data feed (for tape, charting, etc.), with
+	// the accounts replaced by counterparty codes, and keep it for /trades
+	report := s.tape.Enrich(fill)
+	s.trades.Record(report)
+	s.publisher.PublishTrade(report)
+}
+
+// rejectResponse builds the response for an order failing validation.
+func rejectResponse(reject *refdata.Reject) OrderResponse {
+	return OrderResponse{
+		Success:      false,
+		RejectCode:   string(reject.Code),
+		RejectReason: reject.Reason,
+	}
+}
+
+// formatAvgPrice formats an order's average fill price, or "" if unfilled.
+func formatAvgPrice(order *orders.Order) string {
+	if order.FilledQty == 0 {
+		return ""
+	}
+	return orders.FormatPrice(order.AvgFillPrice())
+}
+
+// handleCancel handles order cancellation requests.
+//
+// Uses the same lock-free ring buffer pattern as handleOrder.
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
 	// Parse cancellation parameters from query string
@@ -507,55 +1587,219 @@ func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Submit cancellation to ring buffer (same pattern as new orders)
-	responseCh := make(chan *disruptor.OrderResponse, 1)
+	// Optional: only cancel the order if this account owns it
+	accountID := r.URL.Query().Get("account")
 
-	request := &disruptor.OrderRequest{
-		Type:    disruptor.RequestTypeCancelOrder,
-		Symbol:  symbol,
-		OrderID: orderID,
-	}
+	status, resp := s.cancelOrder(symbol, accountID, orderID)
+	writeJSON(w, status, resp)
+}
 
-	// Step 1: Claim sequence number (lock-free CAS)
-	seq, err := s.sequencer.Next()
+// cancelOrder sequences a cancellation through the ring buffer. With an
+// accountID, an order of another account is not cancelled but reported as
+// not found. Returns the HTTP status and response body.
+func (s *Server) cancelOrder(symbol, accountID string, orderID uint64) (int, interface{}) {
+	leave, err := s.migrations.Enter(symbol)
+	var moved *migration.MovedError
+	if errors.As(err, &moved) {
+		return forwardCancel(moved, accountID, orderID)
+	}
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "server busy, please retry",
-		})
-		return
+		return http.StatusServiceUnavailable, map[string]string{"error": err.Error()}
 	}
+	defer leave()
 
-	// Step 2: Publish to ring buffer
-	s.sequencer.Publish(seq, request, responseCh)
-
-	// Step 3: Wait for event processor to cancel the order
-	var response *disruptor.OrderResponse
-	select {
-	case response = <-responseCh:
-		// Got response
-	case <-time.After(5 * time.Second):
-		writeJSON(w, http.StatusGatewayTimeout, map[string]string{
-			"error": "processing timeout",
-		})
-		return
+	// Submit cancellation to ring buffer (same pattern as new orders)
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:      disruptor.RequestTypeCancelOrder,
+		Symbol:    symbol,
+		OrderID:   orderID,
+		AccountID: accountID,
+	})
+	if response == nil {
+		return status, map[string]string{
+			"error": submitErrorMessage(status),
+		}
 	}
 
 	if !response.Success || response.Error != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{
+		return http.StatusNotFound, map[string]string{
 			"error": response.Error.Error(),
-		})
-		return
+		}
 	}
 
 	order := response.Order
 
 	// Note: Cancel event logging is handled by the event processor
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	// A cancel can change the top of book, so refresh L1 subscribers
+	s.publishMarketData(order.Symbol, nil)
+
+	return http.StatusOK, map[string]interface{}{
 		"success":       true,
 		"order_id":      order.ID,
 		"cancelled_qty": order.RemainingQty(),
+		"cum_qty":       order.FilledQty,
+		"avg_price":     formatAvgPrice(order),
+		"leaves_qty":    order.LeavesQty(),
+	}
+}
+
+// submitRequest publishes a request to the ring buffer of the shard it
+// belongs to and waits for the event processor's response. Requests that
+// span symbols go to every shard (see fanOut).
+//
+// Returns the response and http.StatusOK, or a nil response with
+// 503 (ring buffer full, safe to retry) or 504 (processing timeout).
+func (s *Server) submitRequest(request *disruptor.OrderRequest) (*disruptor.OrderResponse, int) {
+	if sh := s.shards.Route(request); sh != nil {
+		return s.submitTo(sh, request)
+	}
+	if request.Type == disruptor.RequestTypeBasket {
+		// All-or-none needs every leg on one processor
+		return &disruptor.OrderResponse{
+			Basket: &matching.BasketResult{RejectReason: "basket legs span shards"},
+		}, http.StatusOK
+	}
+	return s.fanOut(request)
+}
+
+// submitTo publishes a request to one shard's ring buffer and waits for its
+// event processor's response.
+func (s *Server) submitTo(sh *shard.Shard, request *disruptor.OrderRequest) (*disruptor.OrderResponse, int) {
+	responseCh := make(chan *disruptor.OrderResponse, 1)
+
+	if s.cluster != nil && replicated(request.Type) {
+		// Steps 1-2 in a cluster: committed through Raft first (see cluster.go)
+		if err := s.cluster.propose(request, responseCh); err != nil {
+			return nil, http.StatusServiceUnavailable
+		}
+	} else if request.Type == disruptor.RequestTypeCancelOrder {
+		// Steps 1-2 for cancels: a duplicate of a cancel still in the ring
+		// buffer claims no slot and shares its response
+		if err := sh.Sequencer.PublishCancel(request, responseCh); err != nil {
+			return nil, http.StatusServiceUnavailable
+		}
+	} else {
+		// Step 1: Claim sequence number (lock-free CAS)
+		seq, err := sh.Sequencer.Next()
+		if err != nil {
+			return nil, http.StatusServiceUnavailable
+		}
+
+		// Step 2: Publish to ring buffer
+		sh.Sequencer.Publish(seq, request, responseCh)
+	}
+
+	// Step 3: Wait for event processor to handle the request
+	select {
+	case response := <-responseCh:
+		if errors.Is(response.Error, disruptor.ErrBufferFull) {
+			return nil, http.StatusServiceUnavailable // Joined a cancel that was never sequenced
+		}
+		if errors.Is(response.Error, disruptor.ErrNotJournaled) {
+			return nil, http.StatusServiceUnavailable // Not applied (see request_journal.go)
+		}
+		return response, http.StatusOK
+	case <-time.After(5 * time.Second):
+		return nil, http.StatusGatewayTimeout
+	}
+}
+
+// fanOut sends a request that spans symbols to every shard and merges the
+// responses: cancelled and open orders are combined, and a status lookup
+// succeeds if any shard knows the order. If a shard fails to respond the
+// request fails; each request fanned out is safe to resend to every shard.
+func (s *Server) fanOut(request *disruptor.OrderRequest) (*disruptor.OrderResponse, int) {
+	all := s.shards.All()
+	responses := make([]*disruptor.OrderResponse, len(all))
+	statuses := make([]int, len(all))
+	var wg sync.WaitGroup
+	for i, sh := range all {
+		wg.Add(1)
+		go func(i int, sh *shard.Shard) {
+			defer wg.Done()
+			req := *request // Each processor gets its own copy
+			responses[i], statuses[i] = s.submitTo(sh, &req)
+		}(i, sh)
+	}
+	wg.Wait()
+
+	merged := &disruptor.OrderResponse{Success: true}
+	for i, response := range responses {
+		if response == nil {
+			return nil, statuses[i]
+		}
+		if request.Type == disruptor.RequestTypeOrderStatus {
+			if response.Success {
+				return response, http.StatusOK // The order lives on one shard
+			}
+			merged = response
+			continue
+		}
+		merged.Cancelled = append(merged.Cancelled, response.Cancelled...)
+		merged.Open = append(merged.Open, response.Open...)
+		if !response.Success && merged.Success {
+			merged.Success = false
+			merged.Error = response.Error
+		}
+	}
+	// Each shard lists its symbols in order
+	sort.SliceStable(merged.Open, func(i, j int) bool {
+		return merged.Open[i].Symbol < merged.Open[j].Symbol
 	})
+	return merged, http.StatusOK
+}
+
+// engineFor returns the matching engine of the shard a symbol belongs to.
+// Reads of its books are safe for the same reasons as with a single engine.
+func (s *Server) engineFor(symbol string) *matching.Engine {
+	return s.shards.For(symbol).Engine
+}
+
+// submitErrorMessage returns the client-facing error for a failed submitRequest.
+func submitErrorMessage(status int) string {
+	if status == http.StatusServiceUnavailable {
+		return "server busy, please retry"
+	}
+	return "processing timeout"
+}
+
+// publishMarketData publishes a symbol's L1 quote, depth bands and book
+// feed updates after its book changed.
+func (s *Server) publishMarketData(symbol string, fills []orders.Fill) {
+	s.publishL1(symbol, fills)
+	if book := s.engineFor(symbol).GetOrderBook(symbol); book != nil {
+		s.publisher.PublishBands(marketdata.ComputeBands(book, marketdata.DefaultBandBps))
+		s.publishBook(book)
+	}
+}
+
+// publishL1 publishes the current top of book for a symbol.
+// If fills are given, the last one is reported as the last trade.
+func (s *Server) publishL1(symbol string, fills []orders.Fill) {
+	book := s.engineFor(symbol).GetOrderBook(symbol)
+	if book == nil {
+		return
+	}
+
+	l1 := marketdata.L1Quote{
+		Symbol:    symbol,
+		Timestamp: orders.Now(),
+	}
+	if bestBid := book.GetBestBid(); bestBid != nil {
+		l1.BidPrice = bestBid.Price
+		l1.BidSize = bestBid.TotalQty
+	}
+	if bestAsk := book.GetBestAsk(); bestAsk != nil {
+		l1.AskPrice = bestAsk.Price
+		l1.AskSize = bestAsk.TotalQty
+	}
+	if len(fills) > 0 {
+		lastFill := fills[len(fills)-1]
+		l1.LastPrice = lastFill.Price
+		l1.LastSize = lastFill.Quantity
+	}
+	s.publisher.PublishL1(l1)
 }
 
 func (s *Server) handleBook(w http.ResponseWriter, r *http.Request) {
@@ -567,7 +1811,7 @@ func (s *Server) handleBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book := s.engine.GetOrderBook(symbol)
+	book := s.engineFor(symbol).GetOrderBook(symbol)
 	if book == nil {
 		writeJSON(w, http.StatusNotFound, map[string]string{
 			"error": "symbol not found",
@@ -612,6 +1856,71 @@ func (s *Server) handleBook(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleBands returns a symbol's latest depth bands: cumulative displayed
+// size within 0.1%, 0.5% and 1% of mid.
+func (s *Server) handleBands(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	book := s.engineFor(symbol).GetOrderBook(symbol)
+	if book == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "symbol not found",
+		})
+		return
+	}
+
+	bands, published := s.publisher.LatestBands(symbol)
+	if !published {
+		bands = marketdata.ComputeBands(book, marketdata.DefaultBandBps)
+	}
+
+	bandData := make([]map[string]interface{}, len(bands.Bands))
+	for i, band := range bands.Bands {
+		bandData[i] = map[string]interface{}{
+			"bps":     band.Bps,
+			"bid_qty": band.BidQty,
+			"ask_qty": band.AskQty,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol": symbol,
+		"mid":    orders.FormatPrice(bands.Mid),
+		"bands":  bandData,
+	})
+}
+
+func (s *Server) handleNBBO(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	nbbo, exists := s.nbbo.Latest(symbol)
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "no venue quotes symbol",
+		})
+		return
+	}
+
+	venueData := func(venues []marketdata.VenueSize) []map[string]interface{} {
+		data := make([]map[string]interface{}, len(venues))
+		for i, v := range venues {
+			data[i] = map[string]interface{}{
+				"venue": v.Venue,
+				"size":  v.Size,
+			}
+		}
+		return data
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":     symbol,
+		"bid_price":  orders.FormatPrice(nbbo.BidPrice),
+		"bid_size":   nbbo.BidSize,
+		"bid_venues": venueData(nbbo.BidVenues),
+		"ask_price":  orders.FormatPrice(nbbo.AskPrice),
+		"ask_size":   nbbo.AskSize,
+		"ask_venues": venueData(nbbo.AskVenues),
+		"locked":     nbbo.Locked(),
+		"crossed":    nbbo.Crossed(),
+	})
+}
+
 func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
 	accountID := r.URL.Query().Get("id")
 	if accountID == "" {
@@ -629,11 +1938,7 @@ func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"id":       account.ID,
-		"cash":     orders.FormatPrice(account.Cash),
-		"holdings": account.Holdings,
-	})
+	writeJSON(w, http.StatusOK, s.accountResponse(account))
 }
 
 // handleStats returns system statistics.
@@ -645,17 +1950,307 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	//   1. Event processor is the only writer (single-threaded)
 	//   2. HTTP handlers only read (concurrent reads are safe)
 	//   3. Go memory model guarantees read visibility after write completes
-	var totalOrders int
-	for _, symbol := range s.engine.Symbols() {
-		if book := s.engine.GetOrderBook(symbol); book != nil {
-			totalOrders += book.TotalOrders()
+	//
+	// Counters are summed across shards; event log sequences are per shard
+	var totalOrders, segments int
+	var dropped, conflated uint64
+	logSeqs := make([]uint64, 0, s.shards.Len())
+	var queues []disruptor.SymbolQueueStats
+	ringBuffers := make([]disruptor.RingBufferStats, 0, s.shards.Len()) // One per shard
+	for _, sh := range s.shards.All() {
+		for _, symbol := range sh.Engine.Symbols() {
+			if book := sh.Engine.GetOrderBook(symbol); book != nil {
+				totalOrders += book.TotalOrders()
+			}
+		}
+		logSeqs = append(logSeqs, sh.EventLog.GetLastSequence())
+		segments += len(sh.EventLog.Segments())
+		dropped += sh.Processor.DroppedEvents()
+		queues = append(queues, sh.RingBuffer.SymbolQueueStats()...)
+		ringBuffers = append(ringBuffers, sh.RingBuffer.Stats())
+		conflated += sh.RingBuffer.ConflatedCancels()
+	}
+
+	response := map[string]interface{}{
+		"orders_in_book":     totalOrders,
+		"shards":             s.shards.Len(),
+		"event_log_segments": segments,
+		"dropped_events":     dropped,
+		"symbol_queues":      queues,
+		"ring_buffers":       ringBuffers,
+		"conflated_cancels":  conflated,
+		"settlement_stats":   stats,
+	}
+	if s.itchFeed != nil {
+		response["itch_dropped_packets"] = s.itchFeed.Dropped()
+	}
+	if s.binary != nil {
+		total, connected := s.binary.Sessions()
+		response["binary_sessions"] = map[string]int{"total": total, "connected": connected}
+	}
+	if s.shards.Len() == 1 {
+		response["event_log_seq"] = logSeqs[0]
+	} else {
+		response["event_log_seq"] = logSeqs // One per shard
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleStress runs the disruptor stress mode against the live ring buffer.
+//
+// Query parameters:
+//   - producers: number of concurrent publishers (default GOMAXPROCS)
+//   - duration: how long to run, as a Go duration (default 5s, max 8s so the
+//     report is written before the admin listener's default 10s write timeout)
+//   - shard: index of the shard whose ring buffer is exercised (default 0)
+//
+// Returns 200 if every probe was verified, 500 if any integrity check failed.
+func (s *Server) handleStress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config := disruptor.DefaultStressConfig()
+	if p := r.URL.Query().Get("producers"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid producers",
+			})
+			return
+		}
+		config.Producers = parsed
+	}
+	if d := r.URL.Query().Get("duration"); d != "" {
+		parsed, err := time.ParseDuration(d)
+		if err != nil || parsed <= 0 || parsed > 8*time.Second {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid duration: must be between 0 and 8s",
+			})
+			return
+		}
+		config.Duration = parsed
+	}
+	index := 0
+	if sh := r.URL.Query().Get("shard"); sh != "" {
+		parsed, err := strconv.Atoi(sh)
+		if err != nil || parsed < 0 || parsed >= s.shards.Len() {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("invalid shard: must be between 0 and %d", s.shards.Len()-1),
+			})
+			return
+		}
+		index = parsed
+	}
+
+	log.Printf("Starting disruptor stress run (producers=%d, duration=%s, shard=%d)", config.Producers, config.Duration, index)
+	report := disruptor.RunStress(s.shards.All()[index].Sequencer, config)
+	log.Printf("Stress run complete: verified=%d passed=%v", report.Verified, report.Passed())
+	s.audit(adminActor(r), "stress.run", "", map[string]string{
+		"producers": strconv.Itoa(config.Producers),
+		"duration":  config.Duration.String(),
+		"shard":     strconv.Itoa(index),
+		"passed":    strconv.FormatBool(report.Passed()),
+	}, nil)
+
+	status := http.StatusOK
+	if !report.Passed() {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"passed": report.Passed(),
+		"report": report,
+	})
+}
+
+// handleSymbolStats returns intraday statistics for a single symbol.
+//
+// Trade and quote fields come from the incrementally maintained stats
+// tracker; book depth totals are summed from the live book.
+func (s *Server) handleSymbolStats(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "symbol required",
+		})
+		return
+	}
+
+	book := s.engineFor(symbol).GetOrderBook(symbol)
+	if book == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "symbol not found",
+		})
+		return
+	}
+
+	stats, _ := s.symbolStats.Get(symbol)
+	writeJSON(w, http.StatusOK, marketdata.Summarize(stats, book))
+}
+
+// handleSymbols returns reference data for every tradable symbol.
+func (s *Server) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	instruments := s.refData.All()
+	symbols := make([]map[string]interface{}, len(instruments))
+	for i, inst := range instruments {
+		symbols[i] = map[string]interface{}{
+			"symbol":    inst.Symbol,
+			"tick_size":      orders.FormatPrice(inst.TickSize),
+			"lot_size":       inst.LotSize,
+			"price_decimals": inst.Decimals(),
+			"market":         inst.Market,
+			"state":          inst.State.String(),
+			"allocation":     inst.Allocation.Method.String(),
+		}
+		if inst.Allocation.TopOrder {
+			symbols[i]["top_order"] = true
+		}
+		if inst.Allocation.MinQty > 0 {
+			symbols[i]["min_allocation"] = inst.Allocation.MinQty
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbols": symbols,
+	})
+}
+
+// handleSymbolState changes a symbol's session state, e.g.
+// POST /admin/symbol/state?symbol=AAPL&state=HALTED
+//
+// Orders for a symbol that is not OPEN are rejected before sequencing, or
+// held until it reopens with -halt-orders=queue. Resting orders are left in
+// the book. AUCTION starts a call auction and leaving it uncrosses the book
+// first (see auction.go). Setting a state cancels any circuit breaker pause
+// timer (see halts.go).
+func (s *Server) handleSymbolState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	params := map[string]string{"state": r.URL.Query().Get("state")}
+	state, err := refdata.ParseSessionState(params["state"])
+	if err != nil {
+		s.audit(adminActor(r), "symbol.state", symbol, params, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if state != refdata.SessionHalted && s.journal.damaged(symbol) {
+		err := fmt.Errorf("symbol %s is halted on event log damage: acknowledge it with POST /admin/journal/resume", symbol)
+		s.audit(adminActor(r), "symbol.state", symbol, params, err)
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	uncross, status, err := s.setSymbolState(symbol, state)
+	s.audit(adminActor(r), "symbol.state", symbol, params, err)
+	if err != nil {
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Symbol %s session state set to %s", symbol, state)
+	s.halts.clearPause(symbol)
+	s.refShare.PublishState(symbol, state)
+	response := map[string]interface{}{
+		"symbol": symbol,
+		"state":  state.String(),
+	}
+	if uncross != nil {
+		response["uncross"] = newAuctionInfo(s.postUncross(uncross))
+	}
+	if held := s.halts.heldCount(symbol); held > 0 {
+		if state == refdata.SessionOpen || state == refdata.SessionAuction {
+			go s.releaseHeld(symbol)
+		}
+		response["held_orders"] = held
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleAccountPnL reports an account's intraday P&L and kill switch state, e.g.
+// GET /admin/risk/pnl?account=TRADER1
+func (s *Server) handleAccountPnL(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "account required",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.riskChecker.GetPnL(account))
+}
+
+// handleReinstate clears an account's kill switch, e.g.
+// POST /admin/risk/reinstate?account=TRADER1
+func (s *Server) handleReinstate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	if !s.riskChecker.Reinstate(account) {
+		err := fmt.Errorf("account %q is not disabled", account)
+		s.audit(adminActor(r), "risk.reinstate", account, nil, err)
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	s.audit(adminActor(r), "risk.reinstate", account, nil, nil)
+
+	log.Printf("Account %s reinstated", account)
+	writeJSON(w, http.StatusOK, s.riskChecker.GetPnL(account))
+}
+
+// handleRiskProfile shows or assigns an account's risk profile, e.g.
+// GET  /admin/risk/profile                  (list profiles)
+// GET  /admin/risk/profile?account=TRADER1
+// POST /admin/risk/profile?account=TRADER1&profile=sponsored
+//
+// Assigning profile=default returns the account to the default limits.
+func (s *Server) handleRiskProfile(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+
+	switch r.Method {
+	case http.MethodGet:
+		if account == "" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"profiles": s.riskChecker.ProfileNames(),
+			})
+			return
 		}
+
+	case http.MethodPost:
+		profile := r.URL.Query().Get("profile")
+		err := s.riskChecker.AssignProfile(account, profile)
+		s.audit(adminActor(r), "risk.profile", account, map[string]string{"profile": profile}, err)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		log.Printf("Account %s assigned risk profile %s", account, profile)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
+	name, limits := s.riskChecker.AccountProfile(account)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"orders_in_book":    totalOrders,
-		"event_log_seq":     s.eventLog.GetLastSequence(),
-		"settlement_stats":  stats,
+		"account_id": account,
+		"profile":    name,
+		"limits":     limits,
 	})
 }
 
@@ -676,6 +2271,78 @@ func main() {
 	port := flag.Int("port", 8080, "Server port")
 	eventLog := flag.String("event-log", "events.log", "Path to event log file")
 	syncMode := flag.Bool("sync", false, "Enable sync mode for event log (slower but durable)")
+	alertWebhook := flag.String("alert-webhook", "", "URL to POST operator alerts to (alerts are always logged)")
+	refShareRedis := flag.String("refshare-redis", "", "Redis address for sharing reference prices and halts across shards")
+	shardID := flag.String("shard-id", "", "Unique ID of this engine instance (default: hostname:port)")
+	fairBatch := flag.Int("fair-batch", 256, "Requests drained per round for per-symbol fair scheduling (0 = strict FIFO)")
+	waitStrategy := flag.String("wait-strategy", disruptor.WaitYielding.String(), "How processors wait for requests: busy-spin (lowest latency, a core each), yielding, sleeping or blocking (least idle CPU)")
+	auditLog := flag.String("audit-log", "audit.log", "Path to the audit log of admin actions")
+	clearingLog := flag.String("clearing-log", "clearing.log", "Path to the clearing house journal of accounts and trades (empty = in memory only)")
+	auditKey := flag.String("audit-key", "", "HMAC key signing audit log entries (default: unkeyed hash chain; or set AUDIT_KEY)")
+	maxDailyLoss := flag.String("max-daily-loss", "0", "Per-account intraday loss in dollars that trips its kill switch (0 = off)")
+	maxOrderRate := flag.Int64("max-order-rate", 0, "Orders per second, over a sliding window, an account without a risk profile may send before its orders are rejected as a risk breach (0 = off)")
+	rateBreachKill := flag.Int("rate-breach-kill", 0, "Order rate breaches in a trading day that trip an account's kill switch, for accounts without a risk profile (0 = never)")
+	alertInterval := flag.Duration("alert-interval", time.Minute, "Minimum interval between repeated alerts of the same kind")
+	snapshotDir := flag.String("snapshot-dir", "", "Directory for snapshots restored on restart, replaying only the log after them (empty = off)")
+	snapshotInterval := flag.Duration("snapshot-interval", 30*time.Second, "Time between snapshots")
+	snapshotEvery := flag.Uint64("snapshot-every", 100000, "Also snapshot every N logged events (0 = interval only)")
+	requestJournal := flag.String("request-journal", "", "Write-ahead journal of requests, replayed after a crash (empty = off; needs -snapshot-dir)")
+	logSegmentMB := flag.Int64("log-segment-mb", 64, "Rotate the event log into a new segment at this size in MB (0 = one unbounded file)")
+	logRetainSegments := flag.Int("log-retain-segments", 0, "Closed event log segments kept in place once covered by a snapshot (0 = all)")
+	logRetainAge := flag.Duration("log-retain-age", 0, "Maximum age of closed event log segments once covered by a snapshot (0 = no limit)")
+	logArchiveDir := flag.String("log-archive-dir", "", "Move expired event log segments here instead of deleting them")
+	kafkaBrokers := flag.String("kafka-brokers", "", "Comma-separated Kafka brokers every logged event is produced to, e.g. kafka1:9092,kafka2:9092 (empty = off)")
+	kafkaTopic := flag.String("kafka-topic", "engine-events", "Kafka topic logged events are produced to")
+	kafkaCodec := flag.String("kafka-codec", "protobuf", "Encoding of Kafka message values: protobuf or gob")
+	logCodec := flag.String("log-codec", "protobuf", "Encoding of new event log records: protobuf or gob (existing records are read either way)")
+	timerTick := flag.Duration("timer-tick", 100*time.Millisecond, "Resolution of engine timers such as dead man's switches")
+	migrateWait := flag.Duration("migrate-wait", 10*time.Second, "Longest a request waits for a symbol being migrated to another shard")
+	orderHistory := flag.Int("order-history", matching.DefaultOrderHistory, "Completed orders remembered for GET /order status lookups")
+	circuitWindow := flag.Duration("circuit-window", 5*time.Minute, "Rolling window price moves are measured within")
+	circuitPauseBps := flag.Int64("circuit-pause-bps", 500, "Price move in basis points within the window that pauses a symbol (0 = off)")
+	circuitHaltBps := flag.Int64("circuit-halt-bps", 1000, "Price move in basis points within the window that halts a symbol (0 = off)")
+	circuitPauseFor := flag.Duration("circuit-pause-for", 5*time.Minute, "How long a circuit breaker pause lasts")
+	market := flag.String("market", "XNYS", "Market the symbols trade on, naming their holiday calendar")
+	makerBps := flag.Int64("maker-bps", fees.DefaultSchedule().MakerBps, "Fee in basis points charged to resting orders on a fill, for accounts without a fee tier (negative = rebate)")
+	takerBps := flag.Int64("taker-bps", fees.DefaultSchedule().TakerBps, "Fee in basis points charged to incoming orders on a fill, for accounts without a fee tier")
+	tapeKey := flag.String("tape-key", "", "Key counterparty codes on the public tape are derived with (default: random per start; or set TAPE_KEY)")
+	settlementDays := flag.Int("settlement-days", 2, "Settlement cycle: trades settle this many business days after the trade date (T+N)")
+	initialMarginBps := flag.Int64("initial-margin-bps", 0, "Initial margin on unsettled trades in basis points of their value; margin calls block trading (0 = off)")
+	settleEvery := flag.Duration("settle-every", time.Minute, "How often trades due are cleared and settled (0 = never)")
+	sessionCheck := flag.Duration("session-check", time.Minute, "How often market opens and closes are checked for, to reset daily risk counters and expire DAY orders (0 = never)")
+	settleRetries := flag.Int("settle-retries", 5, "Business days an instruction that fails to settle is retried before it fails for good")
+	buyInAfter := flag.Int("buy-in-after", 4, "Failed settlement days before a deliverer short of shares is bought in (0 = never)")
+	buyInPremiumBps := flag.Int64("buy-in-premium-bps", 100, "Buy-in price over the mark in basis points, charged to the failing deliverer")
+	calendarFile := flag.String("calendar", "", "YAML file of market holiday calendars for settlement dates (default: weekdays only)")
+	instrumentsFile := flag.String("instruments", "", "YAML file of per-symbol tick and lot sizes (default: 1 cent tick, 1 share lot)")
+	journalDamage := flag.String("on-journal-damage", JournalDamageExit, "On event log checksum failures, sequence gaps or lost events: exit at startup, or halt the affected symbols until resumed")
+	buyingPower := flag.Bool("buying-power", false, "Reject orders accounts can't cover from cash and shares net of open orders and unsettled trades")
+	shards := flag.Int("shards", 1, "Engine shards symbols are hashed across, each with its own ring buffer and processor (changing it needs a fresh event log)")
+	itchMulticast := flag.String("itch-multicast", "", "UDP multicast group for the binary ITCH market data feed, e.g. 239.1.1.1:30001 (empty = off)")
+	itchRetransmit := flag.String("itch-retransmit", "", "TCP address serving ITCH feed gap requests, e.g. :30002 (empty = off)")
+	binaryAddr := flag.String("binary-addr", "", "TCP address for binary (OUCH-style) order entry sessions, e.g. :9001 (empty = off)")
+	grpcAddr := flag.String("grpc-addr", "", "TCP address for the gRPC OrderEntry service, e.g. :9002 (empty = off)")
+	replicationAddr := flag.String("replication-addr", "", "TCP address standbys replicate the event log from, e.g. :9100 (empty = off)")
+	standbyOf := flag.String("standby-of", "", "Run as a standby of the primary at this replication address until promoted (POST /admin/replication/promote)")
+	failoverAfter := flag.Duration("failover-after", 0, "Standby: take over once the primary has been silent this long (0 = only when promoted)")
+	degradeShedAt := flag.Float64("degrade-shed-at", degrade.DefaultPolicy().ShedAt, "Ring buffer occupancy (0-1) at which reads are shed and market data conflated (0 = never)")
+	raftID := flag.Int("raft-id", 0, "This node's position in -raft-peers")
+	raftPeers := flag.String("raft-peers", "", "Comma-separated Raft addresses of every node of a matching engine cluster, the same on every node (empty = standalone)")
+	raftDir := flag.String("raft-dir", "raft", "Directory for this node's Raft term, vote and log")
+	orderRate := flag.Float64("order-rate", 0, "Orders per second each account may submit over HTTP, unless given its own limit (0 = unlimited)")
+	stallTimeout := flag.Duration("subscriber-stall-timeout", marketdata.DefaultStallTimeout, "Evict market data subscribers that leave updates unread this long, closing their feed (0 = never)")
+	retransmitHistory := flag.Int("retransmit-history", marketdata.DefaultRetransmitHistory, "Market data messages (quotes, depth, trades) per symbol kept for replay to consumers that missed them")
+	tradeHistory := flag.Int("trade-history", marketdata.DefaultTradeHistory, "Trades per symbol kept in memory for GET /trades (older trades are read back from the event log)")
+	idempotencyWindow := flag.Duration("idempotency-window", disruptor.DefaultIdempotencyWindow, "How long an order resent with the same account and client_order_id gets the original's response instead of a new order (0 = off)")
+	orderBurst := flag.Int("order-burst", 0, "Orders an account may submit at once above -order-rate (default: one second's worth)")
+	degradeRejectAt := flag.Float64("degrade-reject-at", degrade.DefaultPolicy().RejectAt, "Ring buffer occupancy (0-1) at which new orders are refused, cancels still accepted (0 = never)")
+	haltOrders := flag.String("halt-orders", HaltOrdersReject, "Orders for halted or paused symbols: reject, or queue until the symbol reopens")
+	checkBooks := flag.Bool("check-books", false, "Debug: after every request, check the books it touched for internal consistency and alert on the first violation per book (slow)")
+	verify := flag.Bool("verify", false, "Check every record of the event log (-event-log, -shards) and exit: status 1 if any is damaged")
+	defaultListeners := listener.Defaults()
+	tradingListener := listenerFlags("", "trading", defaultListeners.Trading)
+	marketDataListener := listenerFlags("marketdata-", "market data", defaultListeners.MarketData)
+	adminListener := listenerFlags("admin-", "admin", defaultListeners.Admin)
 	flag.Parse()
 
 	// Build configuration
@@ -683,12 +2350,164 @@ func main() {
 	config.Port = *port
 	config.EventLogPath = *eventLog
 	config.SyncMode = *syncMode
+	config.AlertWebhook = *alertWebhook
+	config.AlertInterval = *alertInterval
+	config.FairBatch = *fairBatch
+	config.RefShareRedis = *refShareRedis
+	config.ShardID = *shardID
+	config.TimerTick = *timerTick
+	config.MigrateWait = *migrateWait
+	config.OrderHistory = *orderHistory
+	config.AuditLogPath = *auditLog
+	config.ClearingLogPath = *clearingLog
+	config.Circuit = circuit.Config{
+		Window:   *circuitWindow,
+		PauseBps: *circuitPauseBps,
+		HaltBps:  *circuitHaltBps,
+		PauseFor: *circuitPauseFor,
+	}
+	haltMode, err := parseHaltOrders(*haltOrders)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.WaitStrategy, err = disruptor.ParseWaitStrategy(*waitStrategy)
+	if err != nil {
+		log.Fatalf("Invalid -wait-strategy: %v", err)
+	}
+	if config.WaitStrategy == disruptor.WaitBusySpin && runtime.GOMAXPROCS(0) <= *shards {
+		log.Printf("Warning: -wait-strategy busy-spin keeps a core per shard busy; with %d for %d shard(s) the rest of the server will starve", runtime.GOMAXPROCS(0), *shards)
+	}
+	config.HaltOrders = haltMode
+	config.MaxDailyLoss, err = orders.ParsePrice(*maxDailyLoss)
+	if err != nil {
+		log.Fatalf("invalid -max-daily-loss: %v", err)
+	}
+	if *maxOrderRate < 0 || *rateBreachKill < 0 {
+		log.Fatal("-max-order-rate and -rate-breach-kill must not be negative")
+	}
+	config.MaxOrderRate = *maxOrderRate
+	config.RateBreachKill = *rateBreachKill
+	config.JournalDamage, err = parseJournalDamage(*journalDamage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.Market = *market
+	config.CalendarFile = *calendarFile
+	if *settlementDays < 0 {
+		log.Fatal("-settlement-days must not be negative")
+	}
+	config.SettlementDays = *settlementDays
+	config.SettleEvery = *settleEvery
+	config.SessionCheck = *sessionCheck
+	if *settleRetries < 0 || *buyInAfter < 0 || *buyInPremiumBps < 0 {
+		log.Fatal("-settle-retries, -buy-in-after and -buy-in-premium-bps must not be negative")
+	}
+	config.SettleFails = settlement.FailConfig{Retries: *settleRetries, BuyInAfter: *buyInAfter, BuyInPremiumBps: *buyInPremiumBps}
+	config.InitialMarginBps = *initialMarginBps
+	config.InstrumentsFile = *instrumentsFile
+	config.Fees = fees.Schedule{MakerBps: *makerBps, TakerBps: *takerBps}
+	config.TapeKey = *tapeKey
+	config.BuyingPower = *buyingPower
+	config.CheckBooks = *checkBooks
+	config.Shards = *shards
+	config.ItchMulticast = *itchMulticast
+	config.ItchRetransmit = *itchRetransmit
+	config.BinaryAddr = *binaryAddr
+	config.GRPCAddr = *grpcAddr
+	config.ReplicationAddr = *replicationAddr
+	config.StandbyOf = *standbyOf
+	config.FailoverAfter = *failoverAfter
+	config.Degrade.ShedAt = *degradeShedAt
+	config.Degrade.RejectAt = *degradeRejectAt
+	if *raftPeers != "" {
+		config.Raft.Peers = strings.Split(*raftPeers, ",")
+		config.Raft.ID = *raftID
+		config.Raft.Dir = *raftDir
+	}
+	config.IdempotencyWindow = *idempotencyWindow
+	config.TradeHistory = *tradeHistory
+	config.StallTimeout = *stallTimeout
+	config.RetransmitHistory = *retransmitHistory
+	config.Listeners = listener.Set{
+		Trading:    tradingListener(),
+		MarketData: marketDataListener(),
+		Admin:      adminListener(),
+	}
+	config.OrderRate = ratelimit.Limit{Rate: *orderRate, Burst: *orderBurst}
+	if config.OrderRate.Burst == 0 {
+		config.OrderRate.Burst = int(math.Ceil(*orderRate))
+	}
+	if config.FailoverAfter > 0 && config.StandbyOf == "" {
+		log.Fatal("-failover-after needs -standby-of")
+	}
+	if config.ItchRetransmit != "" && config.ItchMulticast == "" {
+		log.Fatal("-itch-retransmit needs -itch-multicast")
+	}
+	if config.TapeKey == "" {
+		config.TapeKey = os.Getenv("TAPE_KEY")
+	}
+	config.AuditKey = *auditKey
+	if config.AuditKey == "" {
+		config.AuditKey = os.Getenv("AUDIT_KEY") // Keeps the key out of the process list
+	}
+	config.SnapshotDir = *snapshotDir
+	config.SnapshotInterval = *snapshotInterval
+	config.SnapshotEvery = *snapshotEvery
+	config.RequestJournal = *requestJournal
+	config.LogSegmentBytes = *logSegmentMB << 20
+	config.LogRetention = events.Retention{
+		MaxSegments: *logRetainSegments,
+		MaxAge:      *logRetainAge,
+		ArchiveDir:  *logArchiveDir,
+	}
+	codec, err := events.CodecByName(*logCodec)
+	if err != nil {
+		log.Fatalf("Invalid -log-codec: %v", err)
+	}
+	config.LogCodec = codec
+	if *kafkaBrokers != "" {
+		config.KafkaBrokers = strings.Split(*kafkaBrokers, ",")
+		config.KafkaTopic = *kafkaTopic
+		if config.KafkaCodec, err = events.CodecByName(*kafkaCodec); err != nil {
+			log.Fatalf("Invalid -kafka-codec: %v", err)
+		}
+	}
+	if config.SnapshotDir == "" && (*logRetainSegments > 0 || *logRetainAge > 0) {
+		log.Println("Warning: event log retention only removes segments covered by a snapshot; without -snapshot-dir every segment is kept")
+	}
+	if *verify {
+		if !verifyEventLogs(config.EventLogPath, config.Shards) {
+			os.Exit(1)
+		}
+		return
+	}
+	if config.ShardID == "" {
+		hostname, _ := os.Hostname()
+		config.ShardID = fmt.Sprintf("%s:%d", hostname, config.Port)
+	}
+
+	// A standby replicates until it takes over, then starts like any
+	// restart from its log
+	var promoted *promotion
+	if config.StandbyOf != "" {
+		promoted, err = runStandby(config)
+		if errors.Is(err, errStandbyStopped) {
+			log.Println("Standby stopped")
+			return
+		}
+		if err != nil {
+			log.Fatalf("Standby failed: %v", err)
+		}
+	}
 
 	// Create server
 	server, err := NewServer(config)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
+	if promoted != nil {
+		server.recordPromotion(promoted)
+	}
 
 	// ========================================================================
 	// Graceful shutdown handling
@@ -712,7 +2531,9 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start shutdown goroutine
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-sigCh
 		log.Println("Received shutdown signal")
 
@@ -730,6 +2551,8 @@ func main() {
 	if err := server.Start(); err != http.ErrServerClosed {
 		log.Fatalf("Server error: %v", err)
 	}
+	// Start returns as soon as HTTP stops; the logs are flushed after that
+	<-shutdownDone
 
 	log.Println("Server stopped")
 }
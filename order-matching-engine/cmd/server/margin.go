@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// Margin and Collateral
+//
+// With -initial-margin-bps the clearing house margins every account's
+// unsettled trades (see settlement/margin.go), marked at the risk checker's
+// reference prices, after each trade and before each settlement run. An
+// account whose collateral and mark-to-market no longer cover its initial
+// margin gets a margin call: a margin_call alert, and a block in the risk
+// checker refusing its new orders. Its settlement instructions wait. The
+// block lifts once the call is met:
+//
+//	GET  /margin?account=TRADER1
+//	POST /admin/collateral?account=TRADER1&amount=5000.00    (negative withdraws)
+//
+// Collateral is posted from the account's cash.
+
+// demoCollateral is posted for each demo account when margin is on.
+const demoCollateral = 2000000 // $20,000
+
+// onMarginCall blocks an account while it has a margin call, and unblocks
+// it once the call is met. Runs with the clearing house locked.
+func onMarginCall(riskChecker *risk.Checker, alerter *alerts.Alerter, m settlement.Margin) {
+	if m.Call == 0 {
+		riskChecker.Unblock(m.AccountID)
+		log.Printf("Margin call for %s met (excess %s)", m.AccountID, orders.FormatPrice(m.Excess))
+		return
+	}
+	reason := fmt.Sprintf("margin call for %s", orders.FormatPrice(m.Call))
+	riskChecker.Block(m.AccountID, reason)
+	log.Printf("Margin call for %s: %s due (initial %s, variation %s, collateral %s)", m.AccountID,
+		orders.FormatPrice(m.Call), orders.FormatPrice(m.Initial), orders.FormatPrice(m.Variation), orders.FormatPrice(m.Collateral))
+	alerter.Raise(alerts.KindMarginCall, m.AccountID, alerts.SeverityWarning,
+		"account %s has a %s: new orders refused, settlement held", m.AccountID, reason)
+}
+
+// marginResponse is an account's margin position in API responses.
+func marginResponse(m settlement.Margin) map[string]interface{} {
+	return map[string]interface{}{
+		"account_id":  m.AccountID,
+		"initial":     orders.FormatPrice(m.Initial),
+		"variation":   orders.FormatPrice(m.Variation),
+		"collateral":  orders.FormatPrice(m.Collateral),
+		"excess":      orders.FormatPrice(m.Excess),
+		"margin_call": orders.FormatPrice(m.Call),
+	}
+}
+
+// handleMargin returns an account's margin position.
+func (s *Server) handleMargin(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+	if account == "" || s.clearingHouse.GetAccount(account) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": fmt.Sprintf("unknown account %q", account),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, marginResponse(s.clearingHouse.GetMargin(account)))
+}
+
+// handleCollateral posts or withdraws an account's collateral.
+func (s *Server) handleCollateral(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	params := map[string]string{"amount": r.URL.Query().Get("amount")}
+	amount, err := orders.ParsePrice(params["amount"])
+	if err != nil || amount == 0 {
+		err = fmt.Errorf("invalid amount %q", params["amount"])
+		s.audit(adminActor(r), "account.collateral", account, params, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	margin, err := s.clearingHouse.PostCollateral(account, amount)
+	s.audit(adminActor(r), "account.collateral", account, params, err)
+	if err != nil {
+		status := http.StatusConflict
+		if s.clearingHouse.GetAccount(account) == nil {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, marginResponse(margin))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Market Data Feed
+//
+// Quotes, depth and trades carry one message seq per symbol (see
+// marketdata/retransmit.go), and a consumer that missed some asks for them
+// again instead of starting over:
+//
+//	GET /marketdata/replay?symbol=AAPL&from=118   replay only, e.g. after a gap
+//	GET /ws/marketdata?symbol=AAPL&from=118       replay, then live messages
+//
+//	{"type":"replay","symbol":"AAPL","seq":121,"gone":false,"messages":[
+//	  {"type":"trade","seq":119,"trade":{"trade_id":88,"price":"$150.05",...}},
+//	  {"type":"quote","seq":120,"quote":{"bid_price":"$150.00","ask_price":"$150.06",...}},...]}
+//	{"type":"quote","symbol":"AAPL","seq":122,"quote":{...}}
+//
+// The last -retransmit-history messages per symbol are kept. "gone" says
+// from is older than that, or past seq because the server restarted: the
+// messages are then everything kept, and the consumer takes its state from
+// the latest quote rather than assume it saw every trade. Omitting from
+// replays everything kept.
+//
+// As with the book feed, a WebSocket consumer that falls too far behind
+// has messages dropped rather than slowing the server; it sees the gap in
+// seq and replays from the first seq it is missing.
+
+// quoteInfo is an L1 quote in a market data message.
+type quoteInfo struct {
+	BidPrice  string `json:"bid_price"`
+	BidSize   int64  `json:"bid_size"`
+	AskPrice  string `json:"ask_price"`
+	AskSize   int64  `json:"ask_size"`
+	LastPrice string `json:"last_price"`
+	LastSize  int64  `json:"last_size"`
+}
+
+// marketMessageInfo is one sequenced quote, depth update or trade.
+type marketMessageInfo struct {
+	Type   string         `json:"type"`
+	Symbol string         `json:"symbol,omitempty"` // Set when sent live
+	Seq    uint64         `json:"seq"`
+	Quote  *quoteInfo     `json:"quote,omitempty"`
+	Depth  *bookImageInfo `json:"depth,omitempty"`
+	Trade  *tradeInfo     `json:"trade,omitempty"`
+}
+
+// replayInfo is a replay response.
+type replayInfo struct {
+	Type     string              `json:"type"`
+	Symbol   string              `json:"symbol"`
+	Seq      uint64              `json:"seq"`
+	Gone     bool                `json:"gone"`
+	Messages []marketMessageInfo `json:"messages"`
+}
+
+func newMarketMessageInfo(msg marketdata.Message) marketMessageInfo {
+	info := marketMessageInfo{Type: string(msg.Type), Seq: msg.Seq}
+	switch {
+	case msg.Quote != nil:
+		info.Quote = &quoteInfo{
+			BidPrice:  orders.FormatPrice(msg.Quote.BidPrice),
+			BidSize:   msg.Quote.BidSize,
+			AskPrice:  orders.FormatPrice(msg.Quote.AskPrice),
+			AskSize:   msg.Quote.AskSize,
+			LastPrice: orders.FormatPrice(msg.Quote.LastPrice),
+			LastSize:  msg.Quote.LastSize,
+		}
+	case msg.Depth != nil:
+		levels := func(depth []marketdata.PriceLevel) []bookLevelInfo {
+			out := make([]bookLevelInfo, len(depth))
+			for i, level := range depth {
+				out[i] = bookLevelInfo{Price: orders.FormatPrice(level.Price), Quantity: level.Quantity, Orders: level.Count}
+			}
+			return out
+		}
+		info.Depth = &bookImageInfo{
+			Bids:     levels(msg.Depth.Bids),
+			Asks:     levels(msg.Depth.Asks),
+			Checksum: msg.Depth.Checksum,
+		}
+	case msg.Trade != nil:
+		trade := newTradeInfo(*msg.Trade)
+		info.Trade = &trade
+	}
+	return info
+}
+
+func newReplayInfo(replay marketdata.Replay) replayInfo {
+	info := replayInfo{
+		Type:     "replay",
+		Symbol:   replay.Symbol,
+		Seq:      replay.Seq,
+		Gone:     replay.Gone,
+		Messages: make([]marketMessageInfo, len(replay.Messages)),
+	}
+	for i, msg := range replay.Messages {
+		info.Messages[i] = newMarketMessageInfo(msg)
+	}
+	return info
+}
+
+// handleMarketDataReplay returns a symbol's market data messages from a
+// seq.
+func (s *Server) handleMarketDataReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbol, from, ok := s.bookFeedParams(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newReplayInfo(s.publisher.Replay(symbol, from)))
+}
+
+// handleMarketDataFeed streams a symbol's market data messages over a
+// WebSocket: the replay, then every message after it.
+func (s *Server) handleMarketDataFeed(w http.ResponseWriter, r *http.Request) {
+	symbol, from, ok := s.bookFeedParams(w, r)
+	if !ok {
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	replay, sub := s.publisher.SubscribeMessages(symbol, from)
+	defer sub.Close()
+
+	// The feed is one way; reading only notices the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(newReplayInfo(replay)); err != nil {
+		return
+	}
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				return // Publisher closed, or evicted as stalled
+			}
+			info := newMarketMessageInfo(msg)
+			info.Symbol = msg.Symbol
+			if err := conn.WriteJSON(info); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
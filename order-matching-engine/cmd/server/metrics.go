@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/metrics"
+	"github.com/rishav/order-matching-engine/internal/shard"
+)
+
+// Prometheus Metrics
+//
+// GET /metrics serves counters, gauges and histograms in the Prometheus
+// text format (see internal/metrics):
+//
+//	orders_total{outcome}                 new orders: accepted, rejected
+//	                                      (400), throttled (429) or error
+//	                                      (503, 504)
+//	fills_total, fill_quantity_total      fills this node's engine made
+//	ring_buffer_occupancy{shard}          claimed but unconsumed slots, 0-1
+//	ring_buffer_consumer_lag{shard}       claimed but unconsumed slots
+//	ring_buffer_consumer_max_lag{shard}   high-water mark of the lag
+//	ring_buffer_batches_total{shard}      consumer batches (slots per batch
+//	                                      = consumed / batches)
+//	ring_buffer_consumed_total{shard}     slots processed and released
+//	ring_buffer_claims_total{shard}       slots claimed by producers
+//	sequencer_spins_total{shard}          producer retries claiming a slot
+//	sequencer_backoff_seconds_total{shard}
+//	                                      time producers waited for room
+//	ring_buffer_full_total{shard}         claims refused with 503 (failure
+//	                                      rate = full / (claims + full))
+//	event_batcher_queue_depth{shard}      events waiting to be logged
+//	event_batcher_dropped_total{shard}    events the batcher had no room for
+//	kafka_sink_lag{shard}                 logged events not yet produced to
+//	                                      Kafka (with -kafka-brokers)
+//	kafka_sink_failures_total{shard}      produce requests that failed and
+//	                                      were retried
+//	http_request_duration_seconds{path,method,code}
+//
+// Engine gauges are read when scraped, so scraping costs the order path
+// nothing. Paths are the registered route, never the raw URL, so the
+// number of series stays fixed however clients call the server.
+
+// serverMetrics are the metrics the server records as it goes.
+type serverMetrics struct {
+	registry *metrics.Registry
+
+	orders       *metrics.CounterVec
+	fills        *metrics.Counter
+	fillQuantity *metrics.Counter
+	httpDuration *metrics.HistogramVec
+}
+
+// newServerMetrics registers the server's metrics, sampling the engine
+// gauges from s's shards.
+func newServerMetrics(s *Server) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry:     r,
+		orders:       r.NewCounterVec("orders_total", "New orders by outcome.", "outcome"),
+		fills:        r.NewCounter("fills_total", "Fills made by the matching engine."),
+		fillQuantity: r.NewCounter("fill_quantity_total", "Quantity filled by the matching engine."),
+		httpDuration: r.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by route.",
+			metrics.DefaultLatencyBuckets, "path", "method", "code"),
+	}
+
+	perShard := func(value func(sh *shard.Shard) float64) func(emit func(float64, ...string)) {
+		return func(emit func(float64, ...string)) {
+			for _, sh := range s.shards.All() {
+				emit(value(sh), strconv.Itoa(sh.Index))
+			}
+		}
+	}
+	r.NewGaugeVecFunc("ring_buffer_occupancy", "Fraction of ring buffer slots claimed but not yet consumed.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return sh.RingBuffer.Occupancy() }))
+	r.NewGaugeVecFunc("ring_buffer_consumer_lag", "Ring buffer slots claimed but not yet processed.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.Stats().Lag) }))
+	r.NewGaugeVecFunc("ring_buffer_consumer_max_lag", "Most ring buffer slots the processor has found waiting at once.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.Stats().MaxLag) }))
+	r.NewCounterVecFunc("ring_buffer_batches_total", "Batches of ring buffer slots the processor woke up for.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.Stats().Batches) }))
+	r.NewCounterVecFunc("ring_buffer_consumed_total", "Ring buffer slots processed and released.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.Stats().Consumed) }))
+	r.NewCounterVecFunc("ring_buffer_claims_total", "Ring buffer slots claimed by producers.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.Stats().Claims) }))
+	r.NewCounterVecFunc("sequencer_spins_total", "Times a producer retried claiming a ring buffer slot.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.SequencerSpins()) }))
+	r.NewCounterVecFunc("sequencer_backoff_seconds_total", "Time producers spent waiting for room in a full ring buffer.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return sh.RingBuffer.Stats().Backoff.Seconds() }))
+	r.NewCounterVecFunc("ring_buffer_full_total", "Claims refused because the ring buffer was full.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.RingBuffer.BufferFullRejections()) }))
+	r.NewGaugeVecFunc("event_batcher_queue_depth", "Events waiting for the event log batcher.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.Processor.EventQueueDepth()) }))
+	r.NewCounterVecFunc("event_batcher_dropped_total", "Events dropped because the batcher queue was full.",
+		[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(sh.Processor.DroppedEvents()) }))
+	if len(s.kafkaSinks) > 0 {
+		r.NewGaugeVecFunc("kafka_sink_lag", "Logged events not yet produced to Kafka.",
+			[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(s.kafkaSinks[sh.Index].Stats().Lag) }))
+		r.NewCounterVecFunc("kafka_sink_failures_total", "Kafka produce requests that failed and were retried.",
+			[]string{"shard"}, perShard(func(sh *shard.Shard) float64 { return float64(s.kafkaSinks[sh.Index].Stats().Failures) }))
+	}
+	return m
+}
+
+// countOrder records the outcome of a new order from its HTTP status.
+func (m *serverMetrics) countOrder(status int) {
+	switch {
+	case status == http.StatusOK:
+		m.orders.With("accepted").Inc()
+	case status == http.StatusBadRequest:
+		m.orders.With("rejected").Inc()
+	default:
+		m.orders.With("error").Inc()
+	}
+}
+
+// countFill records one fill.
+func (m *serverMetrics) countFill(quantity int64) {
+	m.fills.Inc()
+	m.fillQuantity.Add(uint64(quantity))
+}
+
+// instrument times every request through next by the mux route it matches.
+func (m *serverMetrics) instrument(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, path := mux.Handler(r)
+		if path == "" {
+			path = "unmatched"
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		m.httpDuration.With(path, r.Method, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// handleMetrics serves the metrics for Prometheus to scrape.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.metrics.registry.Handler().ServeHTTP(w, r)
+}
+
+// statusRecorder remembers the status a handler wrote. It passes Hijack
+// through so WebSocket upgrades still work.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/migration"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Symbol Migration Between Shards
+//
+// POST /admin/symbol/migrate moves a symbol's book to another shard while
+// this one keeps trading every other symbol (see internal/migration):
+//
+//	→ POST /admin/symbol/migrate?symbol=AAPL&target=http://shard-b:8080
+//	← {"symbol":"AAPL","target":"http://shard-b:8080","orders":1250,"paused_ms":38}
+//
+// Orders, replaces and cancels for the symbol that arrive mid-move wait for
+// up to -migrate-wait instead of being rejected. Afterwards, and for any
+// client that still sends the symbol here, they are forwarded to the
+// target and its response is returned as is. Forwarded WebSocket orders
+// are placed there without the session, so cancel-on-disconnect does not
+// cover them. Baskets cannot span shards, so a basket with a moved leg is
+// rejected.
+//
+// The target receives the book on POST /admin/symbol/import, rebuilds it
+// in queue order and logs it before acknowledging. If the target's admin
+// endpoints are on a listener of their own, name it with target_admin,
+// e.g. &target_admin=https://shard-b:9090; orders are still forwarded to
+// target. Only then does the
+// source release its copy, so a failed transfer leaves the symbol trading
+// here untouched.
+
+// shardClient talks to other shards, for transfers and forwarded requests.
+var shardClient = &http.Client{Timeout: 10 * time.Second}
+
+// handleMigrate moves a symbol to another shard, e.g.
+// POST /admin/symbol/migrate?symbol=AAPL&target=http://shard-b:8080
+func (s *Server) handleMigrate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	target := r.URL.Query().Get("target")
+	targetAdmin := r.URL.Query().Get("target_admin")
+	if targetAdmin == "" {
+		targetAdmin = target
+	}
+	if symbol == "" || target == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "symbol and target required",
+		})
+		return
+	}
+	if _, ok := s.refData.Get(symbol); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": fmt.Sprintf("unknown symbol: %s", symbol),
+		})
+		return
+	}
+
+	start := time.Now()
+	moved, err := s.migrateSymbol(r.Context(), symbol, target, targetAdmin)
+	s.audit(adminActor(r), "symbol.migrate", symbol, map[string]string{
+		"target":       target,
+		"target_admin": targetAdmin,
+		"orders":       strconv.Itoa(moved),
+	}, err)
+	if err != nil {
+		log.Printf("Migration of %s to %s failed: %v", symbol, target, err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	paused := time.Since(start)
+	log.Printf("Migrated %s to %s: %d resting orders, paused for %v", symbol, target, moved, paused)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":    symbol,
+		"target":    target,
+		"orders":    moved,
+		"paused_ms": paused.Milliseconds(),
+	})
+}
+
+// migrateSymbol runs the migration workflow for one symbol, sending the
+// book to targetAdmin and forwarding orders to target afterwards. Returns
+// the number of resting orders moved.
+func (s *Server) migrateSymbol(ctx context.Context, symbol, target, targetAdmin string) (int, error) {
+	// 1. Quiesce: from here on requests for the symbol wait at the gate
+	if err := s.migrations.Pause(symbol); err != nil {
+		return 0, err
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			s.migrations.Resume(symbol)
+		}
+	}()
+
+	// 2. Snapshot, sequenced after every request admitted before the pause
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:   disruptor.RequestTypeExportSymbol,
+		Symbol: symbol,
+	})
+	if response == nil {
+		return 0, errors.New(submitErrorMessage(status))
+	}
+	if !response.Success {
+		return 0, response.Error
+	}
+
+	// 3-4. Transfer; the target replays the book and logs it before replying
+	inst, _ := s.refData.Get(symbol)
+	payload := &migration.Payload{
+		Symbol:     symbol,
+		Source:     s.shardID,
+		Instrument: inst,
+		Orders:     response.Book,
+	}
+	if err := migration.Send(ctx, shardClient, targetAdmin, payload); err != nil {
+		return 0, fmt.Errorf("transfer to %s: %w", targetAdmin, err)
+	}
+
+	// 5. The target trades it now. Releasing must not be skipped, or both
+	// shards would hold the book
+	release := &disruptor.OrderRequest{
+		Type:   disruptor.RequestTypeReleaseSymbol,
+		Symbol: symbol,
+		Shard:  target,
+	}
+	response = nil
+	for attempt := 0; attempt < 10 && response == nil; attempt++ {
+		response, _ = s.submitRequest(release)
+	}
+	if response == nil {
+		// Keep the symbol paused rather than trade a book the target holds too
+		completed = true
+		return 0, fmt.Errorf("%s imported by %s but could not be released here; order entry stays paused", symbol, target)
+	}
+	s.migrations.Complete(symbol, target)
+	completed = true
+	return len(payload.Orders), nil
+}
+
+// handleImport receives a book migrated from another shard.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload migration.Payload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Symbol == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("invalid payload: %v", err),
+		})
+		return
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:     disruptor.RequestTypeImportSymbol,
+		Symbol:   payload.Symbol,
+		Book:     payload.Orders,
+		Shard:    payload.Source,
+		TickSize: payload.Instrument.TickSize,
+	})
+	if response == nil {
+		writeJSON(w, status, map[string]string{
+			"error": submitErrorMessage(status),
+		})
+		return
+	}
+	params := map[string]string{
+		"source": payload.Source,
+		"orders": strconv.Itoa(len(payload.Orders)),
+	}
+	if !response.Success {
+		s.audit(adminActor(r), "symbol.import", payload.Symbol, params, response.Error)
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error": response.Error.Error(),
+		})
+		return
+	}
+	s.audit(adminActor(r), "symbol.import", payload.Symbol, params, nil)
+
+	// Re-open: the source's instrument definition, session state included
+	payload.Instrument.Symbol = payload.Symbol
+	s.refData.Add(payload.Instrument)
+	s.migrations.Adopt(payload.Symbol)
+	s.publishMarketData(payload.Symbol, nil)
+
+	log.Printf("Imported %s from %s: %d resting orders", payload.Symbol, payload.Source, len(payload.Orders))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol": payload.Symbol,
+		"orders": len(payload.Orders),
+	})
+}
+
+// forward sends a request for a migrated symbol to the shard that trades it
+// now and decodes the response into out. Returns the target's HTTP status.
+func forward(target, path string, query url.Values, body, out interface{}) (int, error) {
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	u := strings.TrimSuffix(target, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodPost, u, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := shardClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("invalid response from %s: %w", target, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// forwardOrder places an order for a migrated symbol on its new shard.
+func forwardOrder(moved *migration.MovedError, order *orders.Order) (int, OrderResponse) {
+	req := OrderRequest{
+		Symbol:        order.Symbol,
+		Side:          order.Side.String(),
+		Type:          order.Type.String(),
+		Quantity:      order.Quantity,
+		AccountID:     order.AccountID,
+		ClientOrderID: order.ClientOrderID,
+		DisplayQty:    order.DisplayQty,
+		Peg:           order.Peg.String(),
+	}
+	if order.Price > 0 {
+		req.Price = orders.FormatPrice(order.Price)
+	}
+
+	var resp OrderResponse
+	status, err := forward(moved.Target, "/order", nil, req, &resp)
+	if err != nil {
+		return http.StatusBadGateway, OrderResponse{Error: fmt.Sprintf("%v: %v", moved, err)}
+	}
+	return status, resp
+}
+
+// forwardCancel cancels an order for a migrated symbol on its new shard.
+func forwardCancel(moved *migration.MovedError, accountID string, orderID uint64) (int, interface{}) {
+	query := url.Values{}
+	query.Set("symbol", moved.Symbol)
+	query.Set("order_id", fmt.Sprint(orderID))
+	if accountID != "" {
+		query.Set("account", accountID)
+	}
+
+	var resp map[string]interface{}
+	status, err := forward(moved.Target, "/cancel", query, nil, &resp)
+	if err != nil {
+		return http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("%v: %v", moved, err)}
+	}
+	return status, resp
+}
+
+// forwardReplace replaces an order for a migrated symbol on its new shard.
+func forwardReplace(moved *migration.MovedError, req ReplaceRequest) (int, ReplaceResponse) {
+	var resp ReplaceResponse
+	status, err := forward(moved.Target, "/order/replace", nil, req, &resp)
+	if err != nil {
+		return http.StatusBadGateway, ReplaceResponse{OrderResponse: OrderResponse{
+			Error: fmt.Sprintf("%v: %v", moved, err),
+		}}
+	}
+	return status, resp
+}
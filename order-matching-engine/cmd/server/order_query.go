@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Order Queries
+//
+// GET /orders lists an account's resting orders, so a trader who lost
+// their order IDs (a reconnect, a crashed client) can find them again and
+// cancel or replace them.
+//
+//	→ GET /orders?account=T1&symbol=AAPL     (symbol is optional)
+//	← {"account":"T1","orders":[{"order_id":42,"symbol":"AAPL","side":"BUY",...}]}
+//
+// GET /order looks up a single order by ID, or by the account's own client
+// order ID, including orders that have since filled or been cancelled (as
+// long as they are still in the engine's bounded history, -order-history).
+//
+//	→ GET /order?id=42
+//	→ GET /order?account=T1&client_order_id=abc-1
+//	← {"order_id":42,"status":"FILLED","cum_qty":100,"leaves_qty":0,...}
+//
+// Both are answered on the event processor, so they are consistent with
+// every order and cancel sequenced before them.
+
+// OrderInfo is the state of one order in a query response.
+type OrderInfo struct {
+	OrderID       uint64 `json:"order_id"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	Price         string `json:"price"`
+	Quantity      int64  `json:"quantity"`
+	CumQty        int64  `json:"cum_qty"`
+	AvgPrice      string `json:"avg_price,omitempty"`
+	LeavesQty     int64  `json:"leaves_qty"`
+	DisplayQty    int64  `json:"display_qty,omitempty"`
+	Peg           string `json:"peg,omitempty"`
+	PegLimit      string `json:"peg_limit,omitempty"`
+	TimeInForce   string `json:"time_in_force"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+func (s *Server) handleOpenOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	symbol := r.URL.Query().Get("symbol")
+	if account == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "account required",
+		})
+		return
+	}
+	if symbol != "" {
+		if target, moved := s.migrations.Moved()[symbol]; moved {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": fmt.Sprintf("%s has moved to %s", symbol, target),
+			})
+			return
+		}
+		if _, ok := s.refData.Get(symbol); !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": fmt.Sprintf("unknown symbol: %s", symbol),
+			})
+			return
+		}
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:      disruptor.RequestTypeOpenOrders,
+		AccountID: account,
+		Symbol:    symbol,
+	})
+	if response == nil {
+		writeJSON(w, status, map[string]string{
+			"error": submitErrorMessage(status),
+		})
+		return
+	}
+
+	open := make([]OrderInfo, len(response.Open))
+	for i := range response.Open {
+		open[i] = newOrderInfo(&response.Open[i])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account": account,
+		"orders":  open,
+	})
+}
+
+// handleOrderStatus looks up one order, e.g. GET /order?id=42 or
+// GET /order?account=T1&client_order_id=abc-1
+func (s *Server) handleOrderStatus(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := &disruptor.OrderRequest{
+		Type:          disruptor.RequestTypeOrderStatus,
+		AccountID:     query.Get("account"),
+		ClientOrderID: query.Get("client_order_id"),
+	}
+	switch {
+	case request.ClientOrderID != "":
+		if request.AccountID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "account required with client_order_id",
+			})
+			return
+		}
+	case query.Get("id") != "":
+		id, err := strconv.ParseUint(query.Get("id"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid id",
+			})
+			return
+		}
+		request.OrderID = id
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "id or client_order_id required",
+		})
+		return
+	}
+
+	response, status := s.submitRequest(request)
+	if response == nil {
+		writeJSON(w, status, map[string]string{
+			"error": submitErrorMessage(status),
+		})
+		return
+	}
+	if !response.Success {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": response.Error.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newOrderInfo(response.Order))
+}
+
+// newOrderInfo reports an order's current state.
+func newOrderInfo(order *orders.Order) OrderInfo {
+	info := OrderInfo{
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientOrderID,
+		Symbol:        order.Symbol,
+		Side:          order.Side.String(),
+		Type:          order.Type.String(),
+		Status:        order.Status.String(),
+		Price:         orders.FormatPrice(order.Price),
+		Quantity:      order.Quantity,
+		CumQty:        order.FilledQty,
+		AvgPrice:      formatAvgPrice(order),
+		LeavesQty:     order.LeavesQty(),
+		DisplayQty:    order.DisplayQty,
+		Peg:           order.Peg.String(),
+		TimeInForce:   order.TimeInForce.String(),
+		Timestamp:     order.Timestamp,
+	}
+	if order.PegLimit > 0 {
+		info.PegLimit = orders.FormatPrice(order.PegLimit)
+	}
+	return info
+}
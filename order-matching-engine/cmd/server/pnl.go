@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Account P&L
+//
+//	GET /pnl?account=TRADER1
+//
+// combines the account's books at the clearing house with the risk
+// checker's view of its trading:
+//
+//	position    net quantity traded, settled or not (risk checker)
+//	holdings    shares settled into the account (clearing house)
+//	avg_price   average cost of the open position
+//	realized    locked in by closing trades, against average cost
+//	unrealized  the open position marked to the symbol's last trade
+//
+// P&L runs from the account's first fill, unlike the daily loss limit's
+// intraday P&L (GET /admin/risk/pnl), which starts over each day. It
+// covers traded shares only: deposited shares have no cost, so they show
+// in holdings but not in P&L. Fees are reported apart, and "net" is the
+// total less them. Like the rest of the risk checker's state, it is kept
+// in memory and starts over when the server restarts.
+
+// positionInfo is one symbol of a P&L response.
+type positionInfo struct {
+	Symbol     string `json:"symbol"`
+	Position   int64  `json:"position"`
+	Holdings   int64  `json:"holdings"`
+	AvgPrice   string `json:"avg_price"`
+	MarkPrice  string `json:"mark_price"`
+	Realized   string `json:"realized"`
+	Unrealized string `json:"unrealized"`
+}
+
+// pnlInfo is a P&L response.
+type pnlInfo struct {
+	Account    string         `json:"account"`
+	Cash       string         `json:"cash"`
+	Fees       string         `json:"fees"`
+	Realized   string         `json:"realized"`
+	Unrealized string         `json:"unrealized"`
+	Total      string         `json:"total"`
+	Net        string         `json:"net"` // Total less fees
+	Positions  []positionInfo `json:"positions"`
+}
+
+// handlePnL reports an account's positions and P&L per symbol.
+func (s *Server) handlePnL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	accountID := r.URL.Query().Get("account")
+	if accountID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "account required",
+		})
+		return
+	}
+
+	account := s.clearingHouse.GetAccount(accountID)
+	positions := s.riskChecker.Positions(accountID)
+	if account == nil && len(positions) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "account not found",
+		})
+		return
+	}
+
+	var cash, fees, realized, unrealized int64
+	holdings := map[string]int64{}
+	if account != nil {
+		cash, fees = account.Cash, account.Fees
+		for symbol, qty := range account.Holdings {
+			holdings[symbol] = qty
+		}
+	}
+
+	info := pnlInfo{Account: accountID, Positions: make([]positionInfo, 0, len(positions))}
+	for _, p := range positions {
+		info.Positions = append(info.Positions, positionInfo{
+			Symbol:     p.Symbol,
+			Position:   p.Position,
+			Holdings:   holdings[p.Symbol],
+			AvgPrice:   orders.FormatPrice(p.AvgPrice),
+			MarkPrice:  orders.FormatPrice(p.MarkPrice),
+			Realized:   orders.FormatPrice(p.Realized),
+			Unrealized: orders.FormatPrice(p.Unrealized),
+		})
+		realized += p.Realized
+		unrealized += p.Unrealized
+		delete(holdings, p.Symbol)
+	}
+	// Held but never traded
+	for symbol, qty := range holdings {
+		price := s.riskChecker.GetReferencePrice(symbol)
+		info.Positions = append(info.Positions, positionInfo{
+			Symbol:     symbol,
+			Holdings:   qty,
+			AvgPrice:   orders.FormatPrice(0),
+			MarkPrice:  orders.FormatPrice(price),
+			Realized:   orders.FormatPrice(0),
+			Unrealized: orders.FormatPrice(0),
+		})
+	}
+	sort.Slice(info.Positions, func(i, j int) bool { return info.Positions[i].Symbol < info.Positions[j].Symbol })
+
+	info.Cash = orders.FormatPrice(cash)
+	info.Fees = orders.FormatPrice(fees)
+	info.Realized = orders.FormatPrice(realized)
+	info.Unrealized = orders.FormatPrice(unrealized)
+	info.Total = orders.FormatPrice(realized + unrealized)
+	info.Net = orders.FormatPrice(realized + unrealized - fees)
+	writeJSON(w, http.StatusOK, info)
+}
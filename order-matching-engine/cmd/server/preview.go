@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Order Preview
+//
+//	POST /order/preview   the body of POST /order
+//
+//	{"success":true,"symbol":"AAPL","side":"BUY","quantity":150,"status":"FILLED",
+//	 "filled_qty":150,"leaves_qty":0,"avg_price":"$151.16","best_price":"$151.00",
+//	 "slippage":"$0.16","slippage_bps":10.6,"notional":"$22675.00",
+//	 "fills":[{"price":"$151.00","quantity":100},{"price":"$151.50","quantity":50}]}
+//
+// answers what the order would do if it arrived now, without placing it:
+// the engine matches it against a copy of the book (see
+// matching/preview.go), on the processor goroutine in sequence with real
+// orders, and nothing is logged, held or published. status is what the
+// order would end as - CANCELLED with reject_reason for a market order
+// that runs out of book, say - and leaves_qty what would rest. Slippage is
+// the average price past the best opposite price, per share and in basis
+// points of it.
+//
+// Reference data is checked as for a real order, so a halted symbol or an
+// off-tick price is refused the same way. Risk limits and buying power
+// are not: the preview is about the book, not the account.
+
+// previewFillInfo is one fill of a preview. It has no trade ID: it never
+// traded.
+type previewFillInfo struct {
+	Price    string `json:"price"`
+	Quantity int64  `json:"quantity"`
+}
+
+// previewInfo is a preview response.
+type previewInfo struct {
+	Success      bool              `json:"success"`
+	Symbol       string            `json:"symbol,omitempty"`
+	Side         string            `json:"side,omitempty"`
+	Quantity     int64             `json:"quantity,omitempty"`
+	Status       string            `json:"status,omitempty"` // What the order would end as
+	FilledQty    int64             `json:"filled_qty"`
+	LeavesQty    int64             `json:"leaves_qty"` // Would rest in the book
+	AvgPrice     string            `json:"avg_price,omitempty"`
+	BestPrice    string            `json:"best_price,omitempty"` // Best opposite price now
+	Slippage     string            `json:"slippage,omitempty"`   // Per share, against the order
+	SlippageBps  float64           `json:"slippage_bps,omitempty"`
+	Notional     string            `json:"notional,omitempty"`
+	Fills        []previewFillInfo `json:"fills,omitempty"`
+	RejectCode   string            `json:"reject_code,omitempty"`
+	RejectReason string            `json:"reject_reason,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// handleOrderPreview simulates an order against the current book.
+func (s *Server) handleOrderPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, previewInfo{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	order, err := parseOrderRequest(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, previewInfo{Error: err.Error()})
+		return
+	}
+	if reject := s.refData.Validate(order); reject != nil {
+		writeJSON(w, http.StatusBadRequest, previewInfo{
+			RejectCode:   string(reject.Code),
+			RejectReason: reject.Reason,
+		})
+		return
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:  disruptor.RequestTypePreview,
+		Order: order,
+	})
+	if response == nil {
+		writeJSON(w, status, previewInfo{Error: submitErrorMessage(status)})
+		return
+	}
+	if response.Preview == nil {
+		writeJSON(w, http.StatusServiceUnavailable, previewInfo{Error: fmt.Sprintf("%v", response.Error)})
+		return
+	}
+
+	preview := response.Preview
+	result := preview.Result
+	info := previewInfo{
+		Success:      result.Accepted,
+		Symbol:       order.Symbol,
+		Side:         order.Side.String(),
+		Quantity:     order.Quantity,
+		Status:       result.Order.Status.String(),
+		FilledQty:    preview.FilledQty,
+		LeavesQty:    result.RestingQty,
+		RejectReason: result.RejectReason,
+	}
+	if preview.BestPrice > 0 {
+		info.BestPrice = orders.FormatPrice(preview.BestPrice)
+	}
+	if preview.FilledQty > 0 {
+		info.AvgPrice = orders.FormatPrice(preview.AvgPrice)
+		info.Slippage = orders.FormatPrice(preview.Slippage)
+		info.SlippageBps = math.Round(float64(preview.Slippage)*1e6/float64(preview.BestPrice)) / 100
+		info.Notional = orders.FormatPrice(preview.Notional)
+	}
+	for _, fill := range result.Fills {
+		info.Fills = append(info.Fills, previewFillInfo{Price: orders.FormatPrice(fill.Price), Quantity: fill.Quantity})
+	}
+	writeJSON(w, http.StatusOK, info)
+}
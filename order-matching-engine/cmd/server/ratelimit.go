@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/ratelimit"
+)
+
+// Order Rate Limits
+//
+// POST /order takes a token from the account's bucket (see
+// internal/ratelimit) once the request has parsed, before risk checks or
+// the ring buffer. An account out of tokens gets 429 with Retry-After and
+// X-RateLimit-* headers, so one runaway client can't fill the ring buffer
+// and push everyone else into degradation. Nothing was sequenced, so a 429
+// is always safe to retry.
+//
+// Every account gets -order-rate/-order-burst unless an operator gives it
+// its own limit:
+//
+//	GET    /admin/ratelimit                               accounts with their own limit
+//	GET    /admin/ratelimit?account=MM1                   the limit MM1 gets
+//	POST   /admin/ratelimit?account=MM1&rate=500&burst=1000
+//	DELETE /admin/ratelimit?account=MM1                   back to the default
+//
+// A rate of 0 exempts the account. Limits are held in memory, like fee
+// tiers, and are not replicated to other shards or cluster nodes.
+
+// throttled answers 429 if the account has used up its order rate.
+func (s *Server) throttled(w http.ResponseWriter, accountID string) bool {
+	result := s.orderLimits.Allow(accountID)
+	if result.Limit.Unlimited() {
+		return false
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit.Burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if result.Allowed {
+		return false
+	}
+
+	s.metrics.orders.With("throttled").Inc()
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	writeJSON(w, http.StatusTooManyRequests, OrderResponse{
+		Success: false,
+		Error:   fmt.Sprintf("order rate limit exceeded for account %s, retry in %s", accountID, result.RetryAfter.Round(time.Millisecond)),
+	})
+	return true
+}
+
+// handleRateLimit reports or sets an account's order rate limit.
+func (s *Server) handleRateLimit(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "account is required"})
+			return
+		}
+		defaults, _ := s.orderLimits.Limit("")
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"default":  defaults,
+			"accounts": s.orderLimits.Limits(),
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPost:
+		rate := r.URL.Query().Get("rate")
+		burst := r.URL.Query().Get("burst")
+		limit, err := parseLimit(rate, burst)
+		if err == nil {
+			err = s.orderLimits.SetLimit(account, limit)
+		}
+		s.audit(adminActor(r), "ratelimit.set", account, map[string]string{"rate": rate, "burst": burst}, err)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("Account %s order rate limit set to %g/s, burst %d", account, limit.Rate, limit.Burst)
+
+	case http.MethodDelete:
+		s.orderLimits.ClearLimit(account)
+		s.audit(adminActor(r), "ratelimit.clear", account, nil, nil)
+		log.Printf("Account %s order rate limit returned to the default", account)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, own := s.orderLimits.Limit(account)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account,
+		"rate":       limit.Rate,
+		"burst":      limit.Burst,
+		"default":    !own,
+	})
+}
+
+// parseLimit reads a rate and burst from query parameters. The burst
+// defaults to one second's worth of the rate.
+func parseLimit(rate, burst string) (ratelimit.Limit, error) {
+	var limit ratelimit.Limit
+	var err error
+	if limit.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+		return limit, fmt.Errorf("invalid rate %q", rate)
+	}
+	if burst == "" {
+		limit.Burst = int(math.Ceil(limit.Rate))
+		return limit, nil
+	}
+	if limit.Burst, err = strconv.Atoi(burst); err != nil {
+		return limit, fmt.Errorf("invalid burst %q", burst)
+	}
+	return limit, nil
+}
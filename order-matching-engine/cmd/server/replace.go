@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/migration"
+)
+
+// Cancel/Replace
+//
+// POST /order/replace changes the price and/or total quantity of a resting
+// limit order in one sequenced step. Side and account must match the
+// resting order. A quantity reduction at the same price keeps time
+// priority; anything else re-queues the order, which may trade on entry.
+//
+//	→ {"symbol":"AAPL","order_id":42,"side":"buy","account_id":"TRADER1","price":"150.10","quantity":200}
+//	← {"success":true,"order_id":42,...same as POST /order...,"priority_kept":false}
+
+// ReplaceRequest represents a cancel/replace request.
+type ReplaceRequest struct {
+	Symbol    string `json:"symbol"`
+	OrderID   uint64 `json:"order_id"`
+	Side      string `json:"side"`
+	AccountID string `json:"account_id"`
+	Price     string `json:"price"`    // New limit price
+	Quantity  int64  `json:"quantity"` // New total quantity, including any already filled
+}
+
+// ReplaceResponse is an order response for the replaced order.
+type ReplaceResponse struct {
+	OrderResponse
+	PriorityKept bool `json:"priority_kept"`
+}
+
+func (s *Server) handleReplace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReplaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, OrderResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request: %v", err),
+		})
+		return
+	}
+
+	status, resp := s.replaceOrder(req)
+	writeJSON(w, status, resp)
+}
+
+// replaceOrder validates and risk-checks the order as it will be after the
+// replace, then sequences the replace through the ring buffer.
+func (s *Server) replaceOrder(req ReplaceRequest) (int, ReplaceResponse) {
+	if err := s.admitOrder(); err != nil {
+		return http.StatusServiceUnavailable, ReplaceResponse{OrderResponse: OrderResponse{
+			Error: err.Error(),
+		}}
+	}
+	leave, err := s.migrations.Enter(req.Symbol)
+	var moved *migration.MovedError
+	if errors.As(err, &moved) {
+		return forwardReplace(moved, req)
+	}
+	if err != nil {
+		return http.StatusServiceUnavailable, ReplaceResponse{OrderResponse: OrderResponse{
+			Error: err.Error(),
+		}}
+	}
+	defer leave()
+
+	// Parse exactly as a new limit order would be
+	candidate, err := parseOrderRequest(OrderRequest{
+		Symbol:    req.Symbol,
+		Side:      req.Side,
+		Type:      "limit",
+		Price:     req.Price,
+		Quantity:  req.Quantity,
+		AccountID: req.AccountID,
+	})
+	if err != nil {
+		return http.StatusBadRequest, ReplaceResponse{OrderResponse: OrderResponse{
+			Error: err.Error(),
+		}}
+	}
+	if reject := s.refData.Validate(candidate); reject != nil {
+		return http.StatusBadRequest, ReplaceResponse{OrderResponse: rejectResponse(reject)}
+	}
+	if riskResult := s.riskChecker.Check(candidate); !riskResult.Passed {
+		return http.StatusBadRequest, ReplaceResponse{OrderResponse: OrderResponse{
+			RejectCode:   riskResult.Code,
+			RejectReason: riskResult.Reason,
+		}}
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type: disruptor.RequestTypeModifyOrder,
+		Replace: &matching.ReplaceRequest{
+			Symbol:    req.Symbol,
+			OrderID:   req.OrderID,
+			Side:      candidate.Side,
+			AccountID: req.AccountID,
+			Price:     candidate.Price,
+			Quantity:  req.Quantity,
+		},
+	})
+	if response == nil {
+		return status, ReplaceResponse{OrderResponse: OrderResponse{
+			Error: submitErrorMessage(status),
+		}}
+	}
+	if !response.Success {
+		return http.StatusBadRequest, ReplaceResponse{OrderResponse: OrderResponse{
+			OrderID: req.OrderID,
+			Error:   response.Error.Error(),
+		}}
+	}
+
+	// Note: OrderReplacedEvent logging is handled by the event processor
+	return http.StatusOK, ReplaceResponse{
+		OrderResponse: s.postTrade(response.Order, response.Result),
+		PriorityKept:  response.Replace.PriorityKept,
+	}
+}
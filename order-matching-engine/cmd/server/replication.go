@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/listener"
+	"github.com/rishav/order-matching-engine/internal/replication"
+)
+
+// Primary/Standby Replication
+//
+// A primary started with -replication-addr streams its event log to
+// standbys (see package replication). A server started with -standby-of
+// does not trade: it replicates the primary's log into its own -event-log
+// and serves only its replication status, until it is promoted:
+//
+//	standby ──POST /admin/replication/promote──┐
+//	        ──primary silent for -failover-after┴─▶ stop replicating ──▶ recover ──▶ serve
+//
+// Promotion recovers from the replicated log like any restart, so the
+// books are rebuilt only with -snapshot-dir (the log is replayed from
+// empty books if the directory holds no snapshot). The promotion is
+// audited as replication.promote once the server is up.
+//
+// Both ends need a single shard: replication follows one event log.
+
+// promotion records why a standby took over.
+type promotion struct {
+	actor  string
+	reason string
+	seq    uint64 // Last event replicated
+}
+
+// errStandbyStopped is returned by runStandby when told to exit instead of
+// taking over.
+var errStandbyStopped = errors.New("standby stopped")
+
+// runStandby replicates the primary at config.StandbyOf until promoted,
+// returning with the replicated log closed and ready to recover from.
+func runStandby(config Config) (*promotion, error) {
+	if config.Shards != 1 {
+		return nil, errors.New("a standby needs a single shard")
+	}
+	// Until it takes over, a standby serves only its admin listener
+	listeners, err := listener.New(config.Listeners, config.Port)
+	if err != nil {
+		return nil, err
+	}
+	admin := listener.For(listeners, listener.Admin)
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path:            config.EventLogPath,
+		SyncMode:        config.SyncMode,
+		SegmentMaxBytes: config.LogSegmentBytes,
+		Codec:           config.LogCodec,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	standby := replication.NewStandby(eventLog, config.StandbyOf, replication.DefaultConfig())
+	runErr := make(chan error, 1)
+	go func() { runErr <- standby.Run() }()
+
+	promote := make(chan *promotion, 1)
+	mux := admin.Mux
+	mux.HandleFunc("/admin/replication", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"role":    "standby",
+			"standby": standby.Status(),
+		})
+	})
+	mux.HandleFunc("/admin/replication/promote", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case promote <- &promotion{actor: adminActor(r), reason: "manual"}:
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"promoting": true})
+		default:
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "already promoting"})
+		}
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "standby"})
+	})
+	admin.ServeWith(mux)
+	httpServer := admin.Server
+	if err := admin.Listen(); err != nil {
+		standby.Stop()
+		eventLog.Close()
+		return nil, err
+	}
+	go func() {
+		if err := admin.Serve(); err != http.ErrServerClosed {
+			log.Printf("Standby HTTP server stopped: %v", err)
+		}
+	}()
+	log.Printf("Standby of %s, replicating into %s from sequence %d; admin endpoints on %s",
+		config.StandbyOf, config.EventLogPath, eventLog.GetLastSequence(), admin.Addr())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	// Automatic failover polls how long the primary has been silent
+	var failover <-chan time.Time
+	if config.FailoverAfter > 0 {
+		ticker := time.NewTicker(config.FailoverAfter / 4)
+		defer ticker.Stop()
+		failover = ticker.C
+	}
+
+	var promoted *promotion
+	for promoted == nil {
+		select {
+		case promoted = <-promote:
+		case <-failover:
+			if silence := standby.Silence(); silence >= config.FailoverAfter {
+				promoted = &promotion{actor: "system", reason: "primary silent for " + silence.Round(time.Millisecond).String()}
+			}
+		case err = <-runErr:
+			// A diverged log can't be caught up; serving from it would be worse
+			httpServer.Close()
+			eventLog.Close()
+			return nil, fmt.Errorf("replication failed: %w", err)
+		case <-sigCh:
+			standby.Stop()
+			httpServer.Close()
+			eventLog.Close()
+			return nil, errStandbyStopped
+		}
+	}
+
+	// The admin listener's address is the promoted server's now
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(ctx)
+	promoted.seq = standby.Stop()
+	status := standby.Status()
+	if err := eventLog.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close replicated event log: %w", err)
+	}
+	log.Printf("Promoting standby (%s): replicated through sequence %d, primary last reported %d",
+		promoted.reason, promoted.seq, status.PrimarySeq)
+	return promoted, nil
+}
+
+// recordPromotion audits a standby's takeover.
+func (s *Server) recordPromotion(p *promotion) {
+	s.audit(p.actor, "replication.promote", "", map[string]string{
+		"reason": p.reason,
+		"seq":    strconv.FormatUint(p.seq, 10),
+	}, nil)
+}
+
+// handleReplication reports the standbys streaming this server's event log.
+// GET /admin/replication
+func (s *Server) handleReplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.replication == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"role": "standalone"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"role":      "primary",
+		"last_seq":  s.shards.All()[0].EventLog.GetLastSequence(),
+		"acked_seq": s.replication.Acked(),
+		"standbys":  s.replication.Standbys(),
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// End-of-Day Reports
+//
+// The clearing house's books as reports (see settlement/report.go), in
+// JSON or, with format=csv, as a CSV download:
+//
+//	GET /reports/settlement?date=2026-11-17              netting, settlement
+//	                                                     steps and failures
+//	GET /reports/trades?account=TRADER1&from=2026-11-16&to=2026-11-17
+//
+// The settlement CSV is one table: each row's type is "net" for a netting
+// line, the step's kind (SETTLED, PARTIAL, ...) for a settlement step, or
+// "FAILING" for an instruction still failing. Columns a row type has no
+// value for are left empty. Trade reports leave out the counterparty.
+
+// settlementCSVHeader is the header row of the settlement report CSV.
+var settlementCSVHeader = []string{"type", "time", "account", "counterparty", "symbol", "trades",
+	"trade_ids", "bought", "sold", "quantity", "amount", "settle_date", "attempts", "reason"}
+
+// tradeCSVHeader is the header row of the trade report CSV.
+var tradeCSVHeader = []string{"trade_id", "trade_date", "settle_date", "symbol", "side",
+	"quantity", "price", "value", "fee", "status"}
+
+// handleSettlementReport returns the settlement report for a settle date.
+func (s *Server) handleSettlementReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "date required: YYYY-MM-DD",
+		})
+		return
+	}
+	format, ok := reportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	report := s.clearingHouse.SettlementReport(date)
+	if format == "csv" {
+		rows := [][]string{settlementCSVHeader}
+		for _, line := range report.Netting {
+			rows = append(rows, []string{"net", "", line.AccountID, "", line.Symbol, strconv.Itoa(line.Trades), "",
+				fmtInt(line.Bought), fmtInt(line.Sold), fmtInt(line.NetQty), orders.FormatPrice(line.NetValue), date, "", ""})
+		}
+		for _, step := range report.Steps {
+			instr := step.Instruction
+			var qty, amount string // Left empty for steps delivering nothing
+			if step.Quantity > 0 {
+				qty, amount = fmtInt(step.Quantity), orders.FormatPrice(step.CashAmount)
+			}
+			rows = append(rows, []string{string(step.Kind), step.Time.UTC().Format(time.RFC3339),
+				instr.FromAccount, instr.ToAccount, instr.Symbol, "", joinIDs(instr.TradeIDs), "", "",
+				qty, amount, s.marketDate(instr.Symbol, instr.SettleDate), strconv.Itoa(instr.Attempts), step.Reason})
+		}
+		for _, instr := range report.Failing {
+			rows = append(rows, []string{"FAILING", "", instr.FromAccount, instr.ToAccount, instr.Symbol, "",
+				joinIDs(instr.TradeIDs), "", "", fmtInt(instr.Quantity), orders.FormatPrice(instr.CashAmount),
+				s.marketDate(instr.Symbol, instr.SettleDate), strconv.Itoa(instr.Attempts), instr.Status.String()})
+		}
+		writeCSV(w, "settlement-"+date+".csv", rows)
+		return
+	}
+
+	netting := make([]map[string]interface{}, len(report.Netting))
+	for i, line := range report.Netting {
+		netting[i] = map[string]interface{}{
+			"account_id": line.AccountID,
+			"symbol":     line.Symbol,
+			"trades":     line.Trades,
+			"bought":     line.Bought,
+			"sold":       line.Sold,
+			"net_qty":    line.NetQty,
+			"net_value":  orders.FormatPrice(line.NetValue),
+		}
+	}
+	steps := make([]map[string]interface{}, len(report.Steps))
+	for i, step := range report.Steps {
+		steps[i] = settlementEventResponse(step)
+	}
+	failing := make([]map[string]interface{}, len(report.Failing))
+	for i, instr := range report.Failing {
+		failing[i] = map[string]interface{}{
+			"from_account": instr.FromAccount,
+			"to_account":   instr.ToAccount,
+			"symbol":       instr.Symbol,
+			"trade_ids":    instr.TradeIDs,
+			"quantity":     instr.Quantity,
+			"cash":         orders.FormatPrice(instr.CashAmount),
+			"settle_date":  s.marketDate(instr.Symbol, instr.SettleDate),
+			"attempts":     instr.Attempts,
+			"status":       instr.Status.String(),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"date":    date,
+		"netting": netting,
+		"steps":   steps,
+		"failing": failing,
+	})
+}
+
+// handleTradeReport returns an account's trades.
+func (s *Server) handleTradeReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	account := query.Get("account")
+	if account == "" || s.clearingHouse.GetAccount(account) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": fmt.Sprintf("unknown account %q", account),
+		})
+		return
+	}
+	from, to := query.Get("from"), query.Get("to")
+	for name, date := range map[string]string{"from": from, "to": to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid " + name + ": YYYY-MM-DD",
+			})
+			return
+		}
+	}
+	format, ok := reportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	trades := s.clearingHouse.AccountTrades(account, from, to)
+	if format == "csv" {
+		rows := [][]string{tradeCSVHeader}
+		for _, trade := range trades {
+			side, fee := tradeSide(trade, account)
+			rows = append(rows, []string{strconv.FormatUint(trade.ID, 10), s.marketDate(trade.Symbol, trade.TradeTime),
+				s.marketDate(trade.Symbol, trade.SettleDate), trade.Symbol, side, fmtInt(trade.Quantity),
+				orders.FormatPrice(trade.Price), orders.FormatPrice(trade.Price * trade.Quantity),
+				orders.FormatPrice(fee), trade.Status.String()})
+		}
+		writeCSV(w, "trades-"+account+".csv", rows)
+		return
+	}
+
+	response := make([]map[string]interface{}, len(trades))
+	for i, trade := range trades {
+		side, fee := tradeSide(trade, account)
+		response[i] = map[string]interface{}{
+			"trade_id":    trade.ID,
+			"trade_time":  trade.TradeTime,
+			"settle_date": s.marketDate(trade.Symbol, trade.SettleDate),
+			"symbol":      trade.Symbol,
+			"side":        side,
+			"quantity":    trade.Quantity,
+			"price":       orders.FormatPrice(trade.Price),
+			"value":       orders.FormatPrice(trade.Price * trade.Quantity),
+			"fee":         orders.FormatPrice(fee),
+			"status":      trade.Status.String(),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account,
+		"trades":     response,
+	})
+}
+
+// marketDate returns t's date in the symbol's market.
+func (s *Server) marketDate(symbol string, t time.Time) string {
+	inst, _ := s.refData.Get(symbol)
+	return s.calendars.Get(inst.Market).Date(t)
+}
+
+// tradeSide returns the account's side of a trade and the fee it paid.
+func tradeSide(trade settlement.Trade, account string) (string, int64) {
+	if trade.BuyerAccount == account {
+		return "BUY", trade.BuyerFee
+	}
+	return "SELL", trade.SellerFee
+}
+
+// reportFormat reads a report request's format: "json" (default) or
+// "csv".
+func reportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return "json", true
+	case "csv":
+		return "csv", true
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("invalid format %q: json or csv", format),
+		})
+		return "", false
+	}
+}
+
+// writeCSV writes rows as a CSV download named filename.
+func writeCSV(w http.ResponseWriter, filename string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+}
+
+func fmtInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// joinIDs joins trade IDs with spaces, for one CSV field.
+func joinIDs(ids []uint64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(id, 10)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+	"github.com/rishav/order-matching-engine/internal/wal"
+)
+
+// Request Journal
+//
+// The event log is written behind the processor, so a crash can lose the
+// events of requests already answered, and the requests still waiting in
+// the ring buffer. With -request-journal set, the processor writes each
+// batch of requests to a write-ahead journal, with one fsync, before it
+// applies any of them (see disruptor/journal.go), and marks in the event
+// log how far the journaled requests' events have got.
+//
+// On startup, after the snapshot is loaded:
+//
+//	events up to the last checkpoint ──▶ replayed as usual
+//	events after it                  ──▶ dropped (a batch partly logged)
+//	journaled requests after it      ──▶ applied again, before serving
+//
+// The requests are applied in the order the engine first saw them, to the
+// same books and ID counters, with the time each was stamped with, so they
+// produce the same orders and fills, timestamps included. Their post-trade
+// step (risk positions, the tape, market data) runs as for new orders.
+// Clients that were waiting on them when the server crashed never got a
+// response: check with /orders before resending.
+//
+// The journal needs -snapshot-dir, which restores the books it replays
+// onto, and so a single shard. After each snapshot it is compacted up to
+// the last request the snapshot reflects.
+
+// openRequestJournal opens the request journal at path.
+func openRequestJournal(path string) (*wal.Log, error) {
+	requests, err := wal.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if torn := requests.TornBytes(); torn > 0 {
+		log.Printf("Request journal %s ended in a torn %d-byte record from an interrupted write; dropped it", path, torn)
+	}
+	return requests, nil
+}
+
+// rewindToCheckpoint finds the last journal checkpoint logged after img,
+// drops the events after it if journaled requests will be applied again in
+// their place, and returns the journal sequence number the checkpoint (or
+// img, without one) reflects.
+func rewindToCheckpoint(eventLog *events.EventLog, img *snapshot.Image, requests *wal.Log) (uint64, error) {
+	at, through := img.EventSeq, img.JournalSeq
+	err := eventLog.ScanFrom(img.EventSeq, func(seqNum uint64, event interface{}) error {
+		if checkpoint, ok := event.(*events.JournalCheckpointEvent); ok {
+			at, through = seqNum, checkpoint.Through
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the last journal checkpoint: %w", err)
+	}
+
+	// Events after the checkpoint without journaled requests to replace
+	// them (timers, or a log from before the journal) are kept
+	last := eventLog.GetLastSequence()
+	if requests.LastSeq() <= through || last <= at {
+		return through, nil
+	}
+	if err := eventLog.TruncateAfter(at); err != nil {
+		return 0, err
+	}
+	log.Printf("Dropped events %d-%d, logged after the last journal checkpoint; their requests are replayed from the journal",
+		at+1, last)
+	return through, nil
+}
+
+// replayJournal applies the journaled requests after s.requestsAfter to
+// the first shard, each after the one before it, and runs the post-trade
+// step of new orders. The processors must be running and nothing else
+// publishing yet.
+func (s *Server) replayJournal() error {
+	if first := s.requests.FirstSeq(); first > s.requestsAfter+1 {
+		log.Printf("WARNING: journaled requests %d-%d were compacted away before their events were logged; not replayed",
+			s.requestsAfter+1, first-1)
+	}
+
+	sequencer := s.shards.All()[0].Sequencer
+	replayed := 0
+	err := s.requests.Replay(s.requestsAfter, func(seq uint64, record []byte) error {
+		request, err := disruptor.DecodeRequest(record)
+		if err != nil {
+			return fmt.Errorf("request %d can't be decoded: %w", seq, err)
+		}
+		request.JournalSeq = seq // Already journaled: not written again
+
+		responseCh := make(chan *disruptor.OrderResponse, 1)
+		for {
+			n, err := sequencer.Next()
+			if err == nil {
+				sequencer.Publish(n, request, responseCh)
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		select {
+		case response := <-responseCh:
+			if request.Type == disruptor.RequestTypeNewOrder && response.Success && !response.Duplicate {
+				s.postTrade(request.Order, response.Result)
+			}
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("request %d was not applied in time", seq)
+		}
+		replayed++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay the request journal: %w", err)
+	}
+	if replayed > 0 {
+		log.Printf("Replayed %d journaled requests after request %d", replayed, s.requestsAfter)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// Restricted Lists
+//
+// Compliance restrictions (see risk/restrictions.go), per account or for
+// everyone:
+//
+//	GET    /admin/restrictions                                the global list and every account's
+//	GET    /admin/restrictions?account=TRADER1                TRADER1's list and the global one
+//	POST   /admin/restrictions?symbol=TSLA&reason=insider+list&account=TRADER1
+//	POST   /admin/restrictions?symbol=GME&reason=pending+corporate+action
+//	DELETE /admin/restrictions?symbol=TSLA&account=TRADER1
+//
+// Without account= a change is to the global list. Orders in a restricted
+// symbol are rejected with reject_code RESTRICTED_SYMBOL (the account's
+// list) or RESTRICTED_LIST (the global one).
+//
+// Changes are sequenced and logged like risk limit changes (see
+// risk_limits.go and disruptor/restrictions.go), and re-applied from the
+// event log on startup, subject to the same retention caveat.
+
+// applyRestriction applies a logged restricted list change to the risk
+// checker. Runs on the processor goroutine.
+func applyRestriction(checker *risk.Checker, change *events.RestrictionEvent) {
+	if !change.Restricted {
+		checker.Unrestrict(change.AccountID, change.Symbol)
+		return
+	}
+	if err := checker.Restrict(change.AccountID, change.Symbol, change.Reason); err != nil {
+		log.Printf("Restriction of %s not applied: %v", change.Symbol, err)
+	}
+}
+
+// restoreRestrictions re-applies the restricted list changes in an event
+// log. Returns the number of restrictions left in place.
+func restoreRestrictions(checker *risk.Checker, eventLog *events.EventLog) (int, error) {
+	lists := make(map[string]bool) // Accounts ("" = global) whose lists changed
+	err := eventLog.Scan(func(seqNum uint64, event interface{}) error {
+		if change, ok := event.(*events.RestrictionEvent); ok {
+			applyRestriction(checker, change)
+			lists[change.AccountID] = true
+		}
+		return nil
+	})
+	restored := 0
+	for accountID := range lists {
+		restored += len(checker.Restrictions(accountID))
+	}
+	return restored, err
+}
+
+// handleRestrictions shows or changes the restricted lists.
+func (s *Server) handleRestrictions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	account, symbol := query.Get("account"), query.Get("symbol")
+	action := "risk.restrict"
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeRestrictions(w, account)
+		return
+
+	case http.MethodPost:
+		// Restricts
+
+	case http.MethodDelete:
+		action = "risk.unrestrict"
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := restrictionTarget(account, symbol)
+	params := map[string]string{"reason": query.Get("reason")}
+	if symbol == "" {
+		err := errors.New("symbol required")
+		s.audit(adminActor(r), action, target, params, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, ok := s.riskChecker.Restrictions(account)[symbol]; !ok && r.Method == http.MethodDelete {
+		err := errors.New(target + " is not restricted")
+		s.audit(adminActor(r), action, target, params, err)
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type: disruptor.RequestTypeRestriction,
+		Restriction: &events.RestrictionEvent{
+			AccountID:  account,
+			Symbol:     symbol,
+			Restricted: r.Method == http.MethodPost,
+			Reason:     query.Get("reason"),
+			Actor:      adminActor(r),
+		},
+	})
+	if response == nil {
+		err := errors.New(submitErrorMessage(status))
+		s.audit(adminActor(r), action, target, params, err)
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	s.audit(adminActor(r), action, target, params, nil)
+	if r.Method == http.MethodPost {
+		log.Printf("%s restricted", target)
+	} else {
+		log.Printf("%s no longer restricted", target)
+	}
+	s.writeRestrictions(w, account)
+}
+
+// writeRestrictions writes an account's restricted list and the global
+// one, or with no account the global list and every account's.
+func (s *Server) writeRestrictions(w http.ResponseWriter, account string) {
+	response := map[string]interface{}{
+		"global": s.riskChecker.Restrictions(""),
+	}
+	if account != "" {
+		response["account_id"] = account
+		response["restricted"] = s.riskChecker.Restrictions(account)
+	} else {
+		accounts := make(map[string]map[string]string)
+		for _, accountID := range s.riskChecker.RestrictedAccounts() {
+			accounts[accountID] = s.riskChecker.Restrictions(accountID)
+		}
+		response["accounts"] = accounts
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// restrictionTarget names a restriction in the audit log and server log:
+// "TRADER1/TSLA", or just the symbol for the global list.
+func restrictionTarget(account, symbol string) string {
+	if account == "" {
+		return symbol
+	}
+	return account + "/" + symbol
+}
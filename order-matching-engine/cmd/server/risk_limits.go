@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// Account Risk Limits
+//
+// An account's limits can be overridden one by one on top of its risk
+// profile (see risk/limits.go):
+//
+//	GET    /admin/risk/TRADER1   profile, overrides and the limits in effect
+//	PUT    /admin/risk/TRADER1   {"max_order_size":2000,"max_daily_volume":"250000.00"}
+//	DELETE /admin/risk/TRADER1   back to the profile's limits
+//
+// A PUT replaces all of the account's overrides: limits left out are not
+// overridden. Sizes are in shares, values in dollars as strings.
+//
+// Changes are sequenced through the first shard's ring buffer and logged
+// as RiskLimitsEvents (see disruptor/risk_limits.go), so the event log
+// shows every limit change, who made it, and which orders came before and
+// after it. On startup the overrides are re-applied from the log; changes
+// in segments expired by -log-retain-* are lost with them, so move those
+// to -log-archive-dir and re-apply any still needed.
+
+// riskLimitsPath prefixes the account risk limits endpoint.
+const riskLimitsPath = "/admin/risk/"
+
+// RiskLimitsRequest is the body of PUT /admin/risk/{account}.
+type RiskLimitsRequest struct {
+	MaxOrderSize    int64  `json:"max_order_size,omitempty"`
+	MaxOrderValue   string `json:"max_order_value,omitempty"` // Dollars
+	MaxPositionSize int64  `json:"max_position_size,omitempty"`
+	MaxDailyVolume  string `json:"max_daily_volume,omitempty"` // Dollars
+}
+
+// parseRiskLimits converts a request body into risk limits.
+func parseRiskLimits(req RiskLimitsRequest) (risk.Limits, error) {
+	limits := risk.Limits{
+		MaxOrderSize:    req.MaxOrderSize,
+		MaxPositionSize: req.MaxPositionSize,
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *int64
+	}{
+		{"max_order_value", req.MaxOrderValue, &limits.MaxOrderValue},
+		{"max_daily_volume", req.MaxDailyVolume, &limits.MaxDailyVolume},
+	} {
+		if field.value == "" {
+			continue
+		}
+		cents, err := orders.ParsePrice(field.value)
+		if err != nil {
+			return risk.Limits{}, fmt.Errorf("invalid %s: %v", field.name, err)
+		}
+		*field.dst = cents
+	}
+	return limits, limits.Validate()
+}
+
+// applyRiskLimits applies a logged risk limit change to the risk checker.
+// Runs on the processor goroutine.
+func applyRiskLimits(checker *risk.Checker, change *events.RiskLimitsEvent) {
+	err := checker.SetLimits(change.AccountID, risk.Limits{
+		MaxOrderSize:    change.MaxOrderSize,
+		MaxOrderValue:   change.MaxOrderValue,
+		MaxPositionSize: change.MaxPositionSize,
+		MaxDailyVolume:  change.MaxDailyVolume,
+	})
+	if err != nil {
+		log.Printf("Risk limits for %s not applied: %v", change.AccountID, err)
+	}
+}
+
+// restoreRiskLimits re-applies the risk limit changes in an event log.
+// Returns the number of accounts left with overrides.
+func restoreRiskLimits(checker *risk.Checker, eventLog *events.EventLog) (int, error) {
+	accounts := make(map[string]bool)
+	err := eventLog.Scan(func(seqNum uint64, event interface{}) error {
+		if change, ok := event.(*events.RiskLimitsEvent); ok {
+			applyRiskLimits(checker, change)
+			accounts[change.AccountID] = !checker.AccountLimits(change.AccountID).IsZero()
+		}
+		return nil
+	})
+	restored := 0
+	for _, overridden := range accounts {
+		if overridden {
+			restored++
+		}
+	}
+	return restored, err
+}
+
+// handleRiskLimits shows, replaces or clears an account's risk limit
+// overrides.
+func (s *Server) handleRiskLimits(w http.ResponseWriter, r *http.Request) {
+	account := strings.TrimPrefix(r.URL.Path, riskLimitsPath)
+	if account == "" || strings.Contains(account, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var limits risk.Limits
+	switch r.Method {
+	case http.MethodGet:
+		s.writeRiskLimits(w, account)
+		return
+
+	case http.MethodPut:
+		var req RiskLimitsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("invalid request: %v", err),
+			})
+			return
+		}
+		parsed, err := parseRiskLimits(req)
+		if err != nil {
+			s.audit(adminActor(r), "risk.limits", account, nil, err)
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		limits = parsed
+
+	case http.MethodDelete:
+		// Zero limits clear the overrides
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type: disruptor.RequestTypeRiskLimits,
+		RiskLimits: &events.RiskLimitsEvent{
+			AccountID:       account,
+			MaxOrderSize:    limits.MaxOrderSize,
+			MaxOrderValue:   limits.MaxOrderValue,
+			MaxPositionSize: limits.MaxPositionSize,
+			MaxDailyVolume:  limits.MaxDailyVolume,
+			Actor:           adminActor(r),
+		},
+	})
+	if response == nil {
+		err := errors.New(submitErrorMessage(status))
+		s.audit(adminActor(r), "risk.limits", account, riskLimitsParams(limits), err)
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	s.audit(adminActor(r), "risk.limits", account, riskLimitsParams(limits), nil)
+	if limits.IsZero() {
+		log.Printf("Account %s risk limit overrides cleared", account)
+	} else {
+		log.Printf("Account %s risk limits overridden: %v", account, riskLimitsResponse(limits))
+	}
+	s.writeRiskLimits(w, account)
+}
+
+// writeRiskLimits writes an account's profile, overrides and the limits in
+// effect.
+func (s *Server) writeRiskLimits(w http.ResponseWriter, account string) {
+	name, _ := s.riskChecker.AccountProfile(account)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account,
+		"profile":    name,
+		"overrides":  riskLimitsResponse(s.riskChecker.AccountLimits(account)),
+		"limits":     s.riskChecker.EffectiveConfig(account),
+	})
+}
+
+// riskLimitsResponse is risk limits in API responses, leaving out those
+// not overridden.
+func riskLimitsResponse(limits risk.Limits) map[string]interface{} {
+	response := make(map[string]interface{})
+	if limits.MaxOrderSize > 0 {
+		response["max_order_size"] = limits.MaxOrderSize
+	}
+	if limits.MaxOrderValue > 0 {
+		response["max_order_value"] = orders.FormatPrice(limits.MaxOrderValue)
+	}
+	if limits.MaxPositionSize > 0 {
+		response["max_position_size"] = limits.MaxPositionSize
+	}
+	if limits.MaxDailyVolume > 0 {
+		response["max_daily_volume"] = orders.FormatPrice(limits.MaxDailyVolume)
+	}
+	return response
+}
+
+// riskLimitsParams records risk limits in the audit log.
+func riskLimitsParams(limits risk.Limits) map[string]string {
+	params := make(map[string]string)
+	for name, v := range riskLimitsResponse(limits) {
+		params[name] = fmt.Sprint(v)
+	}
+	return params
+}
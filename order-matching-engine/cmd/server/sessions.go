@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/calendar"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+)
+
+// Trading Day
+//
+// Every -session-check the server looks at each market's calendar (see
+// calendar/sessions.go), on the first shard's processor timers, and acts
+// as sessions open and close:
+//
+//	open of -market    daily volume, daily P&L and order rate breaches in
+//	                   the risk checker start again (one set of counters
+//	                   for all markets, so only the primary market's day
+//	                   resets them)
+//	close of a market  a settlement cycle is sequenced at once, so trades
+//	                   move on as soon as the day is over; resting DAY
+//	                   orders in its symbols entered before the close
+//	                   expire, through the ring buffer like any cancel
+//
+// Sessions default to the whole day: without -calendar, counters reset and
+// DAY orders expire at midnight UTC. At startup, once journaled requests
+// are replayed, the last close is caught up, expiring DAY orders restored
+// from the log.
+//
+// In a cluster each node resets its own risk counters on its own clock;
+// only the leader settles and expires DAY orders, committed through Raft
+// like any other request.
+
+// sessionMarkets returns the markets symbols trade on, and the primary
+// market even if none do.
+func (s *Server) sessionMarkets(primary string) []string {
+	markets := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, inst := range s.refData.All() {
+		if !seen[inst.Market] {
+			seen[inst.Market] = true
+			markets = append(markets, inst.Market)
+		}
+	}
+	return markets
+}
+
+// newSessions creates the trading day watcher for the server's markets.
+// Its hooks run on the first shard's processor goroutine.
+func (s *Server) newSessions(primary string) *calendar.Sessions {
+	sessions := calendar.NewSessions(s.calendars, s.sessionMarkets(primary))
+	sessions.OnOpen(func(event calendar.SessionEvent) {
+		if event.Market != primary {
+			return
+		}
+		s.riskChecker.ResetDailyVolume()
+		s.riskChecker.ResetDailyPnL()
+		s.riskChecker.ResetRateBreaches()
+		log.Printf("Trading day %s opened on %s: daily risk counters reset", event.Date, event.Market)
+	})
+	sessions.OnClose(func(event calendar.SessionEvent) {
+		go func() { // Sequenced through the ring buffer this hook runs on
+			if s.settleEvery > 0 { // Otherwise settlement is run by hand
+				s.settleAtClose(event)
+			}
+			expired := s.expireDayOrders(event)
+			log.Printf("Trading day %s closed on %s: %d DAY orders expired", event.Date, event.Market, expired)
+		}()
+	})
+	return sessions
+}
+
+// startSessions advances sessions at the next tick, catching up, then
+// every -session-check, on the first shard's processor timers (see
+// disruptor/timers.go).
+func (s *Server) startSessions() error {
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:    disruptor.RequestTypeSchedule,
+		Repeat:  s.sessionCheck,
+		OnTimer: func() { s.sessions.Advance() },
+	})
+	if response == nil {
+		return fmt.Errorf("failed to schedule session checks: %s", submitErrorMessage(status))
+	}
+	if !response.Success {
+		return fmt.Errorf("failed to schedule session checks: %w", response.Error)
+	}
+	return nil
+}
+
+// settleAtClose runs the settlement cycle at a market's close, through the
+// first shard's ring buffer.
+func (s *Server) settleAtClose(event calendar.SessionEvent) {
+	if s.cluster != nil {
+		if _, isLeader := s.cluster.node.Leader(); !isLeader {
+			return // The leader's cycle is applied here when it commits
+		}
+	}
+	response, status := s.submitRequest(&disruptor.OrderRequest{Type: disruptor.RequestTypeSettle})
+	if response == nil {
+		log.Printf("Settlement at the close of %s on %s: %s", event.Date, event.Market, submitErrorMessage(status))
+	}
+}
+
+// expireDayOrders expires the resting DAY orders in a closing market's
+// symbols. Returns how many expired.
+func (s *Server) expireDayOrders(event calendar.SessionEvent) int {
+	if s.cluster != nil {
+		if _, isLeader := s.cluster.node.Leader(); !isLeader {
+			return 0 // The leader's expiry is applied here when it commits
+		}
+	}
+	var symbols []string
+	for _, inst := range s.refData.All() {
+		if inst.Market == event.Market {
+			symbols = append(symbols, inst.Symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return 0
+	}
+
+	request := &disruptor.OrderRequest{
+		Type:    disruptor.RequestTypeExpireOrders,
+		Symbols: symbols,
+		Now:     event.Time.UnixNano(), // Orders entered since stay
+	}
+	var response *disruptor.OrderResponse
+	for attempt := 0; attempt < 10 && response == nil; attempt++ {
+		if attempt > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		response, _ = s.submitRequest(request)
+	}
+	if response == nil {
+		log.Printf("ERROR: DAY order expiry for %s could not be sequenced", event.Market)
+		s.alerter.Raise(alerts.KindMassCancelFailed, event.Market, alerts.SeverityCritical,
+			"DAY orders on %s could not be expired at the close of %s", event.Market, event.Date)
+		return 0
+	}
+	s.publishCancelled(response.Cancelled)
+	return len(response.Cancelled)
+}
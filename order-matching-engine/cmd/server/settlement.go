@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// Settlement Failures
+//
+// An instruction that fails to settle (see settlement/fails.go) settles
+// what it can and is retried on each business day's cycle, up to
+// -settle-retries days. A deliverer still short of shares after
+// -buy-in-after days is bought in at the mark plus -buy-in-premium-bps.
+// Each failed attempt raises a settlement_failed alert, each buy-in a
+// buy_in alert. Every step of every instruction is recorded as a
+// settlement event:
+//
+//	GET /settlement/events?after=0&account=TRADER1
+//
+// after is the last sequence number already seen; account keeps the
+// events of instructions it is a party to.
+
+// onSettlementEvent logs partial settlements and buy-ins, and alerts the
+// buy-ins. Runs with the clearing house locked.
+func onSettlementEvent(alerter *alerts.Alerter, event settlement.SettlementEvent) {
+	instr := event.Instruction
+	switch event.Kind {
+	case settlement.SettlementPartial:
+		log.Printf("Settlement: %s->%s %s settled %d, %d still due", instr.FromAccount, instr.ToAccount,
+			instr.Symbol, event.Quantity, instr.Quantity)
+	case settlement.SettlementBoughtIn:
+		log.Printf("Settlement: %s bought in for %d %s delivered to %s, cost %s", instr.FromAccount,
+			event.Quantity, instr.Symbol, instr.ToAccount, orders.FormatPrice(event.CashAmount))
+		alerter.Raise(alerts.KindBuyIn, instr.FromAccount, alerts.SeverityWarning,
+			"%s bought in for %d %s after %d failed attempts, cost %s", instr.FromAccount,
+			event.Quantity, instr.Symbol, instr.Attempts, orders.FormatPrice(event.CashAmount))
+	}
+}
+
+// settlementEventResponse is a settlement event in API responses.
+func settlementEventResponse(event settlement.SettlementEvent) map[string]interface{} {
+	instr := event.Instruction
+	response := map[string]interface{}{
+		"seq":          event.Seq,
+		"time":         event.Time,
+		"kind":         event.Kind,
+		"from_account": instr.FromAccount,
+		"to_account":   instr.ToAccount,
+		"symbol":       instr.Symbol,
+		"trade_ids":    instr.TradeIDs,
+		"due_quantity": instr.Quantity,
+		"due_cash":     orders.FormatPrice(instr.CashAmount),
+		"settle_date":  instr.SettleDate.Format("2006-01-02"),
+		"attempts":     instr.Attempts,
+	}
+	if event.Quantity > 0 {
+		response["quantity"] = event.Quantity
+		response["cash"] = orders.FormatPrice(event.CashAmount)
+	}
+	if event.Reason != "" {
+		response["reason"] = event.Reason
+	}
+	return response
+}
+
+// handleSettlementEvents returns settlement events.
+func (s *Server) handleSettlementEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var after uint64
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid after: must be a sequence number",
+			})
+			return
+		}
+		after = parsed
+	}
+	account := r.URL.Query().Get("account")
+
+	events := make([]map[string]interface{}, 0)
+	for _, event := range s.clearingHouse.Events(after) {
+		if account != "" && event.Instruction.FromAccount != account && event.Instruction.ToAccount != account {
+			continue
+		}
+		events = append(events, settlementEventResponse(event))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+	"github.com/rishav/order-matching-engine/internal/wal"
+)
+
+// Snapshots and Tail Replay
+//
+// With -snapshot-dir set, the processor snapshots the books, ID counters,
+// migrated symbols and clearing house every -snapshot-interval, every
+// -snapshot-every logged events, and at shutdown (full image plus deltas,
+// see internal/snapshot).
+//
+// On startup the latest snapshot is loaded and only the events logged after
+// it are replayed, so recovery time is bounded by the snapshot frequency
+// rather than the size of the log:
+//
+//	snapshot @ event N ──▶ replay events N+1..end ──▶ serve
+//
+// After a graceful shutdown the tail is empty. With no snapshot yet, or one
+// ahead of the log (events lost from an unsynced log in a crash), the whole
+// log is replayed from empty books instead. With -on-journal-damage=halt,
+// the events of symbols with damage are skipped rather than replayed (see
+// journal.go). The clearing house is taken from its own journal when there
+// is one (-clearing-log), which is newer than the snapshot; replayed trades
+// it already has are not recorded twice. With a request journal, the tail
+// stops at the last journal checkpoint, and the requests journaled after
+// it are applied again once the processor runs (see request_journal.go).
+
+// recoverFromSnapshot opens the snapshot store, restores the engine and
+// clearing house from the latest snapshot and replays the event log after
+// it. With a request journal, also returns the journal sequence number of
+// the last request the recovered state reflects.
+func recoverFromSnapshot(dir string, engine *matching.Engine, clearing *settlement.ClearingHouse, eventLog *events.EventLog, journal *journalGuard, requests *wal.Log) (*snapshot.Store, uint64, error) {
+	store, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	img := store.Latest()
+	switch {
+	case img == nil:
+		log.Printf("No snapshot in %s, replaying the whole event log", dir)
+		img = &snapshot.Image{}
+	case img.EventSeq > eventLog.GetLastSequence():
+		log.Printf("WARNING: snapshot at event %d is ahead of the event log at %d, replaying the whole event log",
+			img.EventSeq, eventLog.GetLastSequence())
+		img = &snapshot.Image{}
+	}
+
+	var through uint64
+	if requests != nil {
+		if through, err = rewindToCheckpoint(eventLog, img, requests); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := engine.RestoreOrders(img.Books); err != nil {
+		return nil, 0, err
+	}
+	engine.RestoreIDCounters(img.Counters)
+	engine.RestoreMoved(img.Moved)
+	engine.RestoreAuctions(img.Auctions)
+	if img.Clearing != nil && !clearing.Journaled() {
+		clearing.Restore(img.Clearing) // The journal is more recent
+	}
+
+	replayer := matching.NewReplayer(engine)
+	eventLog.OnDamage(func(d *events.Damage) error {
+		if err := journal.onReplayDamage(d); err != nil {
+			return err
+		}
+		if d.Event != nil {
+			replayer.Skip(d.Event) // Suspect, but its IDs may have been issued
+		}
+		return nil
+	})
+	defer eventLog.OnDamage(journal.onReplayDamage)
+	err = eventLog.ReplayFrom(img.EventSeq, func(seqNum uint64, event interface{}) error {
+		if journal.damaged(events.SymbolOf(event)) {
+			replayer.Skip(event)
+			return nil
+		}
+		fills, err := replayer.Apply(event)
+		for _, fill := range fills {
+			clearing.RecordTrade(fill)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to replay event log after event %d: %w", img.EventSeq, err)
+	}
+	engine.RestoreIDCounters(replayer.Counters())
+
+	counters := engine.IDCounters()
+	log.Printf("Restored %d resting orders from snapshot at event %d, replayed %d events after it (order=%d trade=%d seq=%d)",
+		img.Orders(), img.EventSeq, replayer.Events(), counters.OrderID, counters.TradeID, counters.SequenceNum)
+	return store, through, nil
+}
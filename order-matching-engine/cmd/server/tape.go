@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Public Tape
+//
+// Trades are published through the enrichment pipeline (see package
+// enrichment), which replaces account IDs with counterparty codes that
+// change daily. The codes are keyed with -tape-key (or TAPE_KEY); without
+// one a random key is drawn at startup, so codes also change on restart.
+//
+//	GET /tape?symbol=AAPL&limit=20                 recent trades, newest first
+//	GET /trades?symbol=AAPL&from=2025-01-02T14:30:00Z&to=...&limit=500
+//	                                               trades in a time range,
+//	                                               oldest first, in pages
+//	GET /admin/tape/counterparty?code=7F3A9C21D0   the account behind a code
+//
+// Revealing a code is audited (tape.reveal): it is the one way from the
+// public tape back to an account.
+//
+// /trades pages through the trade store (see marketdata/trades.go): the
+// last -trade-history trades per symbol are in memory, older ones are read
+// back from the event log and anonymized the same way. A response with
+// "next_after" has more: repeat the query with after=<next_after>. A page
+// reaching back before memory scans the symbol's event log from the start,
+// so narrow such queries with from.
+
+// maxTradesPage is the most trades one /trades response holds.
+const maxTradesPage = 1000
+
+// tradeInfo is a public trade report in API responses.
+type tradeInfo struct {
+	TradeID       uint64 `json:"trade_id"`
+	Symbol        string `json:"symbol"`
+	Price         string `json:"price"`
+	Quantity      int64  `json:"quantity"`
+	AggressorSide string `json:"aggressor_side"`
+	Buyer         string `json:"buyer"`
+	Seller        string `json:"seller"`
+	Time          string `json:"time"`
+}
+
+func newTradeInfo(trade marketdata.TradeReport) tradeInfo {
+	return tradeInfo{
+		TradeID:       trade.TradeID,
+		Symbol:        trade.Symbol,
+		Price:         orders.FormatPrice(trade.Price),
+		Quantity:      trade.Quantity,
+		AggressorSide: trade.AggressorSide.String(),
+		Buyer:         trade.BuyerCode,
+		Seller:        trade.SellerCode,
+		Time:          time.Unix(0, trade.Timestamp).UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// handleTape returns a symbol's recent trades, newest first.
+func (s *Server) handleTape(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if _, ok := s.refData.Get(symbol); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "unknown symbol: " + symbol,
+		})
+		return
+	}
+	limit := marketdata.TapeSize
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid limit",
+			})
+			return
+		}
+		limit = n
+	}
+
+	recent := s.publisher.RecentTrades(symbol, limit)
+	trades := make([]tradeInfo, len(recent))
+	for i, trade := range recent {
+		trades[i] = newTradeInfo(trade)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol": symbol,
+		"trades": trades,
+	})
+}
+
+// handleTrades returns a page of a symbol's trades within a time range,
+// oldest first.
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	symbol := query.Get("symbol")
+	if _, ok := s.refData.Get(symbol); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "unknown symbol: " + symbol,
+		})
+		return
+	}
+
+	q := marketdata.TradeQuery{Symbol: symbol, Limit: 100}
+	var err error
+	if q.From, err = parseTradeTime(query.Get("from")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid from: " + err.Error()})
+		return
+	}
+	if q.To, err = parseTradeTime(query.Get("to")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid to: " + err.Error()})
+		return
+	}
+	if param := query.Get("after"); param != "" {
+		if q.After, err = strconv.ParseUint(param, 10, 64); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid after"})
+			return
+		}
+	}
+	if param := query.Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 || n > maxTradesPage {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("invalid limit: must be 1-%d", maxTradesPage),
+			})
+			return
+		}
+		q.Limit = n
+	}
+
+	page, err := s.trades.Query(q)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to read trade history: " + err.Error(),
+		})
+		return
+	}
+	trades := make([]tradeInfo, len(page.Trades))
+	for i, trade := range page.Trades {
+		trades[i] = newTradeInfo(trade)
+	}
+	response := map[string]interface{}{
+		"symbol": symbol,
+		"trades": trades,
+	}
+	if page.Next != 0 {
+		response["next_after"] = page.Next
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseTradeTime parses an RFC 3339 time as nanoseconds since epoch, or 0
+// if empty.
+func parseTradeTime(param string) (int64, error) {
+	if param == "" {
+		return 0, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, param)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
+// tradesFromLog reads a symbol's trades back from its shard's event log,
+// anonymized like the live tape. It is the trade store's source for trades
+// no longer (or never) in memory.
+func (s *Server) tradesFromLog(symbol string, before uint64, fn func(marketdata.TradeReport) bool) error {
+	return s.shards.For(symbol).EventLog.Scan(func(seqNum uint64, event interface{}) error {
+		fill, ok := event.(*events.FillEvent)
+		if !ok || fill.Symbol != symbol {
+			return nil
+		}
+		if before != 0 && fill.TradeID >= before {
+			return events.ErrStopScan
+		}
+		report := s.tape.Enrich(orders.Fill{
+			TradeID:        fill.TradeID,
+			Symbol:         fill.Symbol,
+			Price:          fill.Price,
+			Quantity:       fill.Quantity,
+			MakerOrderID:   fill.MakerOrderID,
+			TakerOrderID:   fill.TakerOrderID,
+			MakerAccountID: fill.MakerAccountID,
+			TakerAccountID: fill.TakerAccountID,
+			TakerSide:      fill.TakerSide,
+			Timestamp:      fill.Timestamp,
+		})
+		if !fn(report) {
+			return events.ErrStopScan
+		}
+		return nil
+	})
+}
+
+// handleRevealCounterparty returns the account behind a counterparty code
+// issued today or yesterday.
+func (s *Server) handleRevealCounterparty(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	account, ok := s.anonymizer.Reveal(code)
+	if !ok {
+		err := fmt.Errorf("unknown counterparty code %q", code)
+		s.audit(adminActor(r), "tape.reveal", code, nil, err)
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	s.audit(adminActor(r), "tape.reveal", code, map[string]string{"account": account}, nil)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"code":       code,
+		"account_id": account,
+	})
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rishav/order-matching-engine/internal/alerts"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/wsjournal"
+)
+
+// WebSocket Order Entry
+//
+// Unlike the stateless HTTP API, a WebSocket connection is a session: the
+// client logs in once and every order it places is tagged with the session
+// ID. This enables cancel-on-disconnect (COD), a standard exchange protection:
+// if the connection drops (crash, network partition), the session's resting
+// orders are cancelled instead of sitting in the book unmanaged. A session
+// can only cancel orders of the account it logged in as.
+//
+// Protocol (JSON text frames):
+//
+//	→ {"type":"login","account_id":"MM1","cancel_on_disconnect":true}
+//	← {"type":"login_ack","session_id":"WS-1","cancel_on_disconnect":true}
+//	→ {"type":"order","order":{"symbol":"AAPL","side":"buy",...}}
+//	← {"type":"order_ack","status":200,"body":{...same as POST /order...}}
+//	→ {"type":"cancel","symbol":"AAPL","order_id":42}
+//	← {"type":"cancel_ack","status":200,"body":{...same as /cancel...}}
+//
+// Dead man's switch: a session can also arm a heartbeat timeout. If no
+// heartbeat arrives within it, the engine cancels the session's orders even
+// though the connection is still open (see disruptor/deadman.go). The
+// switch is one-shot and stays armed after a disconnect.
+//
+//	→ {"type":"arm_dms","timeout_ms":5000}
+//	← {"type":"dms_ack","status":200,"body":{"success":true}}
+//	→ {"type":"heartbeat"}                  (at least every timeout_ms)
+//	← {"type":"heartbeat_ack","status":200,...}
+//	→ {"type":"disarm_dms"}
+//	← {"type":"dms_ack","status":200,...}
+//
+// Every message after login carries a seq, the session is also sent an
+// execution_report for every change to its orders, and messages lost to a
+// dropped connection can be resent on the next one (see internal/wsjournal).
+//
+//	← {"type":"execution_report","seq":7,"body":{...same as the drop copy...}}
+//	→ {"type":"resend","session_id":"WS-1","from_seq":17}
+
+// wsMessage is a client-to-server WebSocket message.
+type wsMessage struct {
+	Type               string       `json:"type"`
+	AccountID          string       `json:"account_id,omitempty"`
+	CancelOnDisconnect bool         `json:"cancel_on_disconnect,omitempty"`
+	Order              OrderRequest `json:"order,omitempty"`
+	Symbol             string       `json:"symbol,omitempty"`
+	OrderID            uint64       `json:"order_id,omitempty"`
+	TimeoutMs          int64        `json:"timeout_ms,omitempty"`
+	SessionID          string       `json:"session_id,omitempty"` // Session to resend (default: this one)
+	FromSeq            uint64       `json:"from_seq,omitempty"`
+}
+
+// wsReply is a server-to-client WebSocket message.
+type wsReply = wsjournal.Message
+
+// wsSession is the per-connection state of an order entry session.
+type wsSession struct {
+	id                 string
+	accountID          string
+	cancelOnDisconnect bool
+	journal            *wsjournal.Journal
+}
+
+// wsOutboxSize is how many messages can wait for a slow connection before
+// it is closed: a full resend, and as many again.
+const wsOutboxSize = 2 * wsjournal.Size
+
+var (
+	wsUpgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+	}
+	wsSessionCounter uint64
+)
+
+// handleWebSocket upgrades the connection and runs an order entry session.
+//
+// Messages are queued for a writer goroutine, the only writer to the
+// connection, since execution reports arrive from the event processor
+// while the read loop waits for the client. When the read fails (client
+// closed or connection lost) the session ends and, if requested at login,
+// its orders are mass-cancelled.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	// Closed by the session's journal once logged in, by the handler if not
+	out := make(chan wsReply, wsOutboxSize)
+	written := make(chan struct{})
+	go writeWebSocket(conn, out, written)
+
+	var session *wsSession
+	defer func() {
+		if session != nil {
+			session.journal.Close()
+		} else {
+			close(out)
+		}
+		<-written
+		conn.Close()
+		if session != nil && session.cancelOnDisconnect {
+			s.cancelSessionOrders(session.id, "cancel on disconnect")
+		}
+	}()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if session != nil {
+				log.Printf("WebSocket session %s (%s) disconnected: %v", session.id, session.accountID, err)
+			}
+			return
+		}
+
+		var reply wsReply
+		switch {
+		case msg.Type == "login":
+			if session != nil {
+				reply = wsReply{Type: "error", Error: "already logged in"}
+				break
+			}
+			if msg.AccountID == "" || s.clearingHouse.GetAccount(msg.AccountID) == nil {
+				reply = wsReply{Type: "error", Error: "unknown account"}
+				break
+			}
+			session = &wsSession{
+				id:                 fmt.Sprintf("WS-%d", atomic.AddUint64(&wsSessionCounter, 1)),
+				accountID:          msg.AccountID,
+				cancelOnDisconnect: msg.CancelOnDisconnect,
+			}
+			session.journal = s.wsJournals.Open(session.id, session.accountID, out)
+			log.Printf("WebSocket session %s logged in as %s (cancel_on_disconnect=%v)",
+				session.id, session.accountID, session.cancelOnDisconnect)
+			reply = wsReply{Type: "login_ack", SessionID: session.id, CancelOnDisconnect: session.cancelOnDisconnect}
+
+		case session == nil:
+			reply = wsReply{Type: "error", Error: "login required"}
+
+		case msg.Type == "order":
+			order, err := parseOrderRequest(msg.Order)
+			if err != nil {
+				reply = wsReply{Type: "order_ack", Status: http.StatusBadRequest, Body: OrderResponse{Error: err.Error()}}
+				break
+			}
+			// Orders are always placed on behalf of the logged-in account
+			order.AccountID = session.accountID
+			order.SessionID = session.id
+			status, resp := s.executeOrder(order)
+			reply = wsReply{Type: "order_ack", Status: status, Body: resp}
+
+		case msg.Type == "cancel":
+			// Only the logged-in account's orders can be cancelled
+			status, resp := s.cancelOrder(msg.Symbol, session.accountID, msg.OrderID)
+			reply = wsReply{Type: "cancel_ack", Status: status, Body: resp}
+
+		case msg.Type == "arm_dms":
+			if msg.TimeoutMs <= 0 {
+				reply = wsReply{Type: "error", Error: "timeout_ms must be positive"}
+				break
+			}
+			status, resp := s.heartbeat(session.id, time.Duration(msg.TimeoutMs)*time.Millisecond)
+			reply = wsReply{Type: "dms_ack", Status: status, Body: resp}
+
+		case msg.Type == "heartbeat":
+			status, resp := s.heartbeat(session.id, 0)
+			reply = wsReply{Type: "heartbeat_ack", Status: status, Body: resp}
+
+		case msg.Type == "disarm_dms":
+			status, resp := s.heartbeat(session.id, -1)
+			reply = wsReply{Type: "dms_ack", Status: status, Body: resp}
+
+		case msg.Type == "resend":
+			reply = s.resend(session, msg)
+
+		default:
+			reply = wsReply{Type: "error", Error: fmt.Sprintf("unknown message type: %q", msg.Type)}
+		}
+
+		if session != nil {
+			session.journal.Record(&reply)
+		} else {
+			out <- reply
+		}
+	}
+}
+
+// writeWebSocket writes the queued messages to the connection until out
+// is closed, then closes written. After a failed write the rest are
+// dropped, and closing the connection ends the read loop.
+func writeWebSocket(conn *websocket.Conn, out <-chan wsReply, written chan<- struct{}) {
+	defer close(written)
+	failed := false
+	for reply := range out {
+		if failed {
+			continue
+		}
+		if err := conn.WriteJSON(reply); err != nil {
+			failed = true
+			conn.Close()
+		}
+	}
+	// A slow connection's journal closes out while the session is live
+	conn.Close()
+}
+
+// resend queues the messages of a session's journal from msg.FromSeq on,
+// flagged as possible duplicates, and returns the reply that ends them.
+func (s *Server) resend(session *wsSession, msg wsMessage) wsReply {
+	sessionID := msg.SessionID
+	if sessionID == "" {
+		sessionID = session.id
+	}
+	journal := s.wsJournals.Get(sessionID)
+	if journal == nil || journal.AccountID() != session.accountID {
+		return wsReply{Type: "error", Error: fmt.Sprintf("unknown session: %s", sessionID)}
+	}
+
+	replies, first, ok := journal.Resend(sessionID, msg.FromSeq)
+	if !ok {
+		return wsReply{Type: "error", Error: fmt.Sprintf(
+			"messages of %s before seq %d are no longer kept; reconcile with GET /orders", sessionID, first)}
+	}
+
+	var toSeq uint64 // Last seq resent, 0 if none
+	for _, resent := range replies {
+		session.journal.Send(resent)
+		toSeq = resent.Seq
+	}
+	return wsReply{Type: "resend_ack", Status: http.StatusOK, Body: map[string]interface{}{
+		"session_id": sessionID,
+		"from_seq":   msg.FromSeq,
+		"to_seq":     toSeq,
+		"resent":     len(replies),
+	}}
+}
+
+// Retries of a mass cancel the ring buffer refused: backoff with full
+// jitter, as in client/retry.go, until the deadline.
+const (
+	massCancelBaseDelay = 5 * time.Millisecond
+	massCancelMaxDelay  = 250 * time.Millisecond
+	massCancelDeadline  = 5 * time.Second
+)
+
+// cancelSessionOrders mass-cancels a session's resting orders through the
+// ring buffer, retrying on backpressure until massCancelDeadline since this
+// is a protection that must not be silently skipped. Returns the orders
+// cancelled.
+func (s *Server) cancelSessionOrders(sessionID, reason string) []*orders.Order {
+	request := &disruptor.OrderRequest{
+		Type:      disruptor.RequestTypeMassCancel,
+		SessionID: sessionID,
+		Reason:    reason,
+	}
+
+	var response *disruptor.OrderResponse
+	deadline := time.Now().Add(massCancelDeadline)
+	for ceiling := massCancelBaseDelay; ; {
+		if response, _ = s.submitRequest(request); response != nil {
+			break
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		delay := time.Duration(rand.Int63n(int64(ceiling) + 1))
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+		if ceiling *= 2; ceiling > massCancelMaxDelay {
+			ceiling = massCancelMaxDelay
+		}
+	}
+	if response == nil {
+		log.Printf("ERROR: mass cancel for session %s could not be sequenced", sessionID)
+		s.alerter.Raise(alerts.KindMassCancelFailed, sessionID, alerts.SeverityCritical,
+			"cancel-on-disconnect for session %s could not be sequenced (%s)", sessionID, reason)
+		return nil
+	}
+
+	s.publishCancelled(response.Cancelled)
+
+	if len(response.Cancelled) > 0 {
+		log.Printf("Session %s: cancelled %d orders (%s)", sessionID, len(response.Cancelled), reason)
+	}
+	return response.Cancelled
+}
+
+// publishCancelled refreshes market data once per symbol touched by a mass
+// cancel.
+func (s *Server) publishCancelled(cancelled []*orders.Order) {
+	symbols := make(map[string]bool)
+	for _, order := range cancelled {
+		symbols[order.Symbol] = true
+	}
+	for symbol := range symbols {
+		s.publishMarketData(symbol, nil)
+	}
+}
+
+// heartbeat sequences a dead man's switch request for a session: timeout
+// > 0 arms it, 0 refreshes it, < 0 disarms it.
+func (s *Server) heartbeat(sessionID string, timeout time.Duration) (int, interface{}) {
+	response, status := s.submitRequest(&disruptor.OrderRequest{
+		Type:      disruptor.RequestTypeHeartbeat,
+		SessionID: sessionID,
+		Timeout:   timeout,
+	})
+	if response == nil {
+		return status, map[string]string{"error": submitErrorMessage(status)}
+	}
+	if response.Error != nil {
+		return http.StatusBadRequest, map[string]string{"error": response.Error.Error()}
+	}
+	return http.StatusOK, map[string]bool{"success": true}
+}
@@ -0,0 +1,300 @@
+// Package main provides a long-running soak test for the matching engine.
+//
+// The unit and integration tests run fixed scenarios for milliseconds. Bugs
+// that only show up after millions of orders - a level whose TotalQty drifts
+// by one share per partial fill, a book that slowly leaks cancelled orders,
+// a map that is never pruned - need the engine to run for hours.
+//
+// The soak drives the engine directly (no HTTP, no ring buffer) with a
+// seeded random workload and checks invariants as it goes:
+//
+//   - Share conservation: every accepted share is filled (counted once per
+//     side), resting, or cancelled - nothing is created or lost
+//   - Book consistency (orderbook.Check): each price level's TotalQty and
+//     HiddenQty equal the sum of its orders' visible and hidden (iceberg
+//     reserve) quantity, and the queues and indexes agree
+//   - No crossed book: best bid < best ask after every order
+//   - Trade IDs strictly increase
+//   - Bounded memory: heap in use stays within a limit of the post-warmup
+//     baseline (the workload keeps the book size bounded, so growth is a leak)
+//
+// Any violation is logged and the process exits with status 1, so the soak
+// can gate a nightly job. The seed is printed so a failure can be replayed.
+//
+// Usage:
+//
+//	go run ./cmd/soak -duration 4h
+//	go run ./cmd/soak -duration 30s -seed 42
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Config holds soak test configuration.
+type Config struct {
+	Duration       time.Duration
+	Seed           int64
+	Symbols        []string
+	MaxResting     int           // Resting orders above which the workload favours cancels
+	CheckEvery     int           // Orders between full book scans
+	ReportInterval time.Duration // How often progress and memory are checked
+	MaxHeapGrowth  uint64        // Allowed heap growth over the baseline, in bytes
+}
+
+// symbolState is the workload's view of one symbol.
+type symbolState struct {
+	mid int64 // Random-walk reference price in cents
+}
+
+// restingRef identifies an order that may still be resting.
+type restingRef struct {
+	symbol string
+	id     uint64
+}
+
+// soak runs the workload and tracks the totals the invariants are checked
+// against.
+type soak struct {
+	config  Config
+	rng     *rand.Rand
+	engine  *matching.Engine
+	symbols map[string]*symbolState
+	resting []restingRef
+
+	orderCount  uint64
+	fillCount   uint64
+	lastTradeID uint64
+
+	// Conservation totals, in shares
+	acceptedQty  int64
+	filledQty    int64 // Per fill, so each trade counts once (both sides: 2x)
+	cancelledQty int64
+}
+
+func newSoak(config Config) *soak {
+	s := &soak{
+		config:  config,
+		rng:     rand.New(rand.NewSource(config.Seed)),
+		engine:  matching.NewEngine(),
+		symbols: make(map[string]*symbolState),
+	}
+	for _, symbol := range config.Symbols {
+		s.engine.AddSymbol(symbol)
+		s.symbols[symbol] = &symbolState{mid: 10000} // $100.00
+	}
+	return s
+}
+
+// step submits one random order or cancel and checks per-order invariants.
+func (s *soak) step() error {
+	symbol := s.config.Symbols[s.rng.Intn(len(s.config.Symbols))]
+
+	// Favour cancels once the book is large, so memory should plateau
+	cancelProb := 0.2
+	if len(s.resting) > s.config.MaxResting {
+		cancelProb = 0.7
+	}
+	if len(s.resting) > 0 && s.rng.Float64() < cancelProb {
+		return s.cancelRandom()
+	}
+
+	state := s.symbols[symbol]
+	state.mid += int64(s.rng.Intn(5) - 2) // Drift by up to 2 cents
+	if state.mid < 1000 {
+		state.mid = 1000
+	}
+
+	order := &orders.Order{
+		Symbol:    symbol,
+		Side:      orders.Side(s.rng.Intn(2)),
+		Type:      s.randomType(),
+		Price:     state.mid + int64(s.rng.Intn(41)-20), // Within 20 cents of mid
+		Quantity:  int64(1 + s.rng.Intn(500)),
+		AccountID: fmt.Sprintf("SOAK%d", s.rng.Intn(20)),
+	}
+	if order.Type == orders.OrderTypeMarket {
+		order.Price = 0
+	}
+	if order.Type == orders.OrderTypeLimit && s.rng.Intn(10) == 0 {
+		order.DisplayQty = int64(1 + s.rng.Intn(50)) // Iceberg
+	}
+
+	result := s.engine.ProcessOrder(order)
+	s.orderCount++
+	if !result.Accepted {
+		return nil
+	}
+	s.acceptedQty += order.Quantity
+
+	for _, fill := range result.Fills {
+		if fill.TradeID <= s.lastTradeID {
+			return fmt.Errorf("trade ID went backwards: %d after %d", fill.TradeID, s.lastTradeID)
+		}
+		s.lastTradeID = fill.TradeID
+		s.filledQty += fill.Quantity
+		s.fillCount++
+	}
+
+	if result.RestingQty > 0 {
+		s.resting = append(s.resting, restingRef{symbol: symbol, id: order.ID})
+	} else {
+		// Market, IOC and FOK remainders are cancelled, not rested
+		s.cancelledQty += order.RemainingQty()
+	}
+
+	return s.checkNotCrossed(symbol)
+}
+
+// randomType picks an order type, mostly limits so the book stays populated.
+func (s *soak) randomType() orders.OrderType {
+	switch r := s.rng.Intn(100); {
+	case r < 80:
+		return orders.OrderTypeLimit
+	case r < 90:
+		return orders.OrderTypeIOC
+	case r < 95:
+		return orders.OrderTypeFOK
+	default:
+		return orders.OrderTypeMarket
+	}
+}
+
+// cancelRandom cancels a random tracked order. Orders that have since been
+// filled are simply dropped from tracking.
+func (s *soak) cancelRandom() error {
+	i := s.rng.Intn(len(s.resting))
+	ref := s.resting[i]
+	s.resting[i] = s.resting[len(s.resting)-1]
+	s.resting = s.resting[:len(s.resting)-1]
+
+	order, err := s.engine.CancelOrder(ref.symbol, ref.id)
+	if err != nil {
+		return nil // Already filled
+	}
+	s.cancelledQty += order.RemainingQty()
+	return s.checkNotCrossed(ref.symbol)
+}
+
+// checkNotCrossed verifies best bid < best ask.
+func (s *soak) checkNotCrossed(symbol string) error {
+	book := s.engine.GetOrderBook(symbol)
+	bid, ask := book.GetBestBid(), book.GetBestAsk()
+	if bid != nil && ask != nil && bid.Price >= ask.Price {
+		return fmt.Errorf("%s book crossed: bid %s >= ask %s",
+			symbol, orders.FormatPrice(bid.Price), orders.FormatPrice(ask.Price))
+	}
+	return nil
+}
+
+// checkBooks scans every book for consistency and share conservation.
+func (s *soak) checkBooks() error {
+	var restingQty int64
+	for _, symbol := range s.config.Symbols {
+		book := s.engine.GetOrderBook(symbol)
+		if err := book.Check(); err != nil {
+			return err
+		}
+		for _, level := range append(book.GetBidDepth(0), book.GetAskDepth(0)...) {
+			restingQty += level.TotalQty + level.HiddenQty
+		}
+	}
+
+	// Each fill removes its quantity from both the taker and the maker
+	if accounted := 2*s.filledQty + restingQty + s.cancelledQty; accounted != s.acceptedQty {
+		return fmt.Errorf("shares not conserved: accepted %d, but filled 2x%d + resting %d + cancelled %d = %d",
+			s.acceptedQty, s.filledQty, restingQty, s.cancelledQty, accounted)
+	}
+	return nil
+}
+
+// heapInUse returns live heap bytes after a full GC.
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+func run(config Config) error {
+	s := newSoak(config)
+	start := time.Now()
+	deadline := start.Add(config.Duration)
+	nextReport := start.Add(config.ReportInterval)
+
+	// The baseline is taken after the first report interval, once the book
+	// has grown to its steady-state size
+	var baseline uint64
+
+	for time.Now().Before(deadline) {
+		for i := 0; i < config.CheckEvery; i++ {
+			if err := s.step(); err != nil {
+				return err
+			}
+		}
+		if err := s.checkBooks(); err != nil {
+			return err
+		}
+
+		if now := time.Now(); now.After(nextReport) {
+			nextReport = now.Add(config.ReportInterval)
+			heap := heapInUse()
+			if baseline == 0 {
+				baseline = heap
+			} else if heap > baseline+config.MaxHeapGrowth {
+				return fmt.Errorf("heap grew from %d MB to %d MB (limit +%d MB)",
+					baseline>>20, heap>>20, config.MaxHeapGrowth>>20)
+			}
+			log.Printf("%s elapsed: orders=%d fills=%d tracked=%d heap=%dMB",
+				now.Sub(start).Round(time.Second), s.orderCount, s.fillCount, len(s.resting), heap>>20)
+		}
+	}
+
+	if err := s.checkBooks(); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	log.Printf("Soak passed: %d orders, %d fills in %s (%.0f orders/sec)",
+		s.orderCount, s.fillCount, elapsed.Round(time.Second), float64(s.orderCount)/elapsed.Seconds())
+	return nil
+}
+
+func main() {
+	duration := flag.Duration("duration", time.Hour, "How long to run")
+	seed := flag.Int64("seed", 0, "Random seed (0 = time-based)")
+	symbols := flag.String("symbols", "AAPL,GOOGL,MSFT,AMZN,TSLA", "Comma-separated symbols")
+	maxResting := flag.Int("max-resting", 50000, "Resting orders above which the workload favours cancels")
+	checkEvery := flag.Int("check-every", 10000, "Orders between full book scans")
+	reportInterval := flag.Duration("report-interval", 30*time.Second, "Progress and memory check interval")
+	maxHeapGrowthMB := flag.Uint64("max-heap-growth-mb", 256, "Allowed heap growth over the baseline in MB")
+	flag.Parse()
+
+	config := Config{
+		Duration:       *duration,
+		Seed:           *seed,
+		Symbols:        strings.Split(*symbols, ","),
+		MaxResting:     *maxResting,
+		CheckEvery:     *checkEvery,
+		ReportInterval: *reportInterval,
+		MaxHeapGrowth:  *maxHeapGrowthMB << 20,
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	log.Printf("Starting soak: duration=%s seed=%d symbols=%v", config.Duration, config.Seed, config.Symbols)
+	if err := run(config); err != nil {
+		log.Printf("INVARIANT VIOLATION (seed %d): %v", config.Seed, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,264 @@
+// Package alerts implements throttled self-monitoring alerts.
+//
+// The engine already logs when something goes wrong (dropped events, failed
+// settlements), but a log line scrolling past at 100k lines/sec is not an
+// alert. This package turns those conditions into operator notifications.
+//
+// Throttling:
+//
+// Failure conditions tend to repeat: once the event queue is full, every
+// subsequent event is dropped. Without throttling, one incident produces
+// thousands of identical pages. The Alerter emits at most one alert per
+// (kind, key) per interval and folds everything in between into a
+// "suppressed" count carried on the next alert, so operators see
+// "event queue full (+4,211 suppressed)" once a minute instead of a flood.
+//
+// Delivery:
+//
+// Raise never blocks. Alerts are handed to a background goroutine through a
+// bounded queue, so a slow webhook can never stall the matching thread.
+// If the queue itself overflows, the overflow is counted and reported.
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Kind identifies the condition that triggered an alert.
+type Kind string
+
+const (
+	KindEventDropped     Kind = "event_dropped"      // Event batcher queue full, event not logged
+	KindReplayChecksum   Kind = "replay_checksum"    // Event log failed checksum verification on replay
+	KindSettlementFailed Kind = "settlement_failed"  // Settlement instruction could not be settled
+	KindMassCancelFailed Kind = "mass_cancel_failed" // Protective mass cancel could not be sequenced
+	KindDailyLossLimit   Kind = "daily_loss_limit"   // Account kill switch tripped by its daily loss limit
+	KindAuditWrite       Kind = "audit_write"        // Admin action could not be written to the audit log
+	KindCircuitBreaker   Kind = "circuit_breaker"    // Symbol paused or halted by a price move
+	KindJournalDamage    Kind = "journal_damage"     // Event not written to the event log, or symbol halted on log damage
+	KindDegraded         Kind = "degraded"           // Server degraded far enough to refuse new orders
+	KindClusterDiverged  Kind = "cluster_diverged"   // Committed raft entry this node could not apply
+	KindMarginCall       Kind = "margin_call"        // Account's collateral no longer covers its margin
+	KindBuyIn            Kind = "buy_in"             // Deliverer bought in after failing to deliver shares
+	KindOrderRate        Kind = "order_rate"         // Account over its risk profile's order rate limit
+	KindBookCheck        Kind = "book_check"         // Order book failed its consistency check (-check-books)
+)
+
+// Severity indicates how urgently an alert needs attention.
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Alert is a single notification delivered to a sink.
+type Alert struct {
+	Kind       Kind      `json:"kind"`
+	Key        string    `json:"key,omitempty"` // Sub-key for throttling (e.g. account, symbol)
+	Severity   Severity  `json:"severity"`
+	Message    string    `json:"message"`
+	Suppressed int64     `json:"suppressed"` // Alerts of this kind/key dropped by throttling since the last one
+	Time       time.Time `json:"time"`
+}
+
+func (a Alert) String() string {
+	s := fmt.Sprintf("[%s] %s", a.Severity, a.Kind)
+	if a.Key != "" {
+		s += "/" + a.Key
+	}
+	s += ": " + a.Message
+	if a.Suppressed > 0 {
+		s += fmt.Sprintf(" (+%d suppressed)", a.Suppressed)
+	}
+	return s
+}
+
+// Sink delivers alerts to operators.
+type Sink interface {
+	Send(alert Alert) error
+}
+
+// LogSink writes alerts to the standard logger.
+type LogSink struct{}
+
+// Send logs the alert.
+func (LogSink) Send(alert Alert) error {
+	log.Printf("ALERT %s", alert)
+	return nil
+}
+
+// WebhookSink POSTs alerts as JSON to a URL (Slack/PagerDuty relays, etc).
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a webhook sink with a short timeout, so an
+// unreachable endpoint delays only the alert goroutine briefly.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Send POSTs the alert to the webhook URL.
+func (s *WebhookSink) Send(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Config holds alerter configuration.
+type Config struct {
+	Interval  time.Duration // Minimum time between alerts of the same kind/key
+	QueueSize int           // Alerts buffered for delivery before overflow
+}
+
+// DefaultConfig returns reasonable defaults.
+func DefaultConfig() Config {
+	return Config{
+		Interval:  time.Minute,
+		QueueSize: 256,
+	}
+}
+
+// throttleState tracks emission for one kind/key.
+type throttleState struct {
+	lastSent   time.Time
+	suppressed int64
+}
+
+// Alerter rate-limits alerts and delivers them to sinks asynchronously.
+// It is safe for concurrent use.
+type Alerter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	state    map[string]*throttleState
+	overflow int64 // Alerts lost because the delivery queue was full
+	closed   bool
+	now      func() time.Time
+
+	sinks []Sink
+	queue chan Alert
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New creates an alerter delivering to the given sinks and starts its
+// delivery goroutine.
+func New(config Config, sinks ...Sink) *Alerter {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 256
+	}
+
+	a := &Alerter{
+		interval: config.Interval,
+		state:    make(map[string]*throttleState),
+		now:      time.Now,
+		sinks:    sinks,
+		queue:    make(chan Alert, config.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go a.deliverLoop()
+	return a
+}
+
+// Raise reports a condition. The alert is emitted immediately if none of the
+// same kind/key was emitted within the interval; otherwise it is counted as
+// suppressed. Never blocks.
+func (a *Alerter) Raise(kind Kind, key string, severity Severity, format string, args ...interface{}) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	now := a.now()
+	stateKey := string(kind) + "/" + key
+	st, exists := a.state[stateKey]
+	if !exists {
+		st = &throttleState{}
+		a.state[stateKey] = st
+	}
+	if exists && now.Sub(st.lastSent) < a.interval {
+		st.suppressed++
+		a.mu.Unlock()
+		return
+	}
+
+	alert := Alert{
+		Kind:       kind,
+		Key:        key,
+		Severity:   severity,
+		Message:    fmt.Sprintf(format, args...),
+		Suppressed: st.suppressed,
+		Time:       now,
+	}
+	st.lastSent = now
+	st.suppressed = 0
+
+	select {
+	case a.queue <- alert:
+	default:
+		a.overflow++
+	}
+	a.mu.Unlock()
+}
+
+// Overflow returns the number of alerts lost because delivery fell behind.
+func (a *Alerter) Overflow() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.overflow
+}
+
+// deliverLoop sends queued alerts to every sink until Close.
+func (a *Alerter) deliverLoop() {
+	defer close(a.done)
+	for alert := range a.queue {
+		for _, sink := range a.sinks {
+			if err := sink.Send(alert); err != nil {
+				log.Printf("WARNING: Failed to deliver alert %s: %v", alert.Kind, err)
+			}
+		}
+	}
+}
+
+// Close delivers any queued alerts and stops the delivery goroutine.
+// Alerts raised after Close are discarded.
+func (a *Alerter) Close() {
+	if a == nil {
+		return
+	}
+	a.once.Do(func() {
+		a.mu.Lock()
+		a.closed = true
+		close(a.queue)
+		a.mu.Unlock()
+		<-a.done
+	})
+}
@@ -0,0 +1,80 @@
+package alerts
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink collects delivered alerts.
+type recordingSink struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (s *recordingSink) Send(alert Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+// TestAlerter_ThrottlesAndCountsSuppressed tests that repeats within the
+// interval are folded into the next alert's suppressed count
+func TestAlerter_ThrottlesAndCountsSuppressed(t *testing.T) {
+	sink := &recordingSink{}
+	a := New(Config{Interval: time.Minute, QueueSize: 16}, sink)
+
+	now := time.Unix(1700000000, 0)
+	a.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		a.Raise(KindEventDropped, "", SeverityCritical, "dropped %d", i)
+	}
+	now = now.Add(time.Minute)
+	a.Raise(KindEventDropped, "", SeverityCritical, "dropped again")
+	a.Close()
+
+	if len(sink.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(sink.alerts))
+	}
+	if sink.alerts[0].Message != "dropped 0" || sink.alerts[0].Suppressed != 0 {
+		t.Errorf("Unexpected first alert: %+v", sink.alerts[0])
+	}
+	if sink.alerts[1].Suppressed != 4 {
+		t.Errorf("Expected 4 suppressed, got %d", sink.alerts[1].Suppressed)
+	}
+}
+
+// TestAlerter_ThrottlesPerKey tests that distinct kinds and keys are
+// throttled independently
+func TestAlerter_ThrottlesPerKey(t *testing.T) {
+	sink := &recordingSink{}
+	a := New(Config{Interval: time.Hour, QueueSize: 16}, sink)
+
+	a.Raise(KindSettlementFailed, "AAPL", SeverityCritical, "fail")
+	a.Raise(KindSettlementFailed, "MSFT", SeverityCritical, "fail")
+	a.Raise(KindSettlementFailed, "AAPL", SeverityCritical, "fail")
+	a.Raise(KindEventDropped, "", SeverityCritical, "drop")
+	a.Close()
+
+	if len(sink.alerts) != 3 {
+		t.Fatalf("Expected 3 alerts, got %d", len(sink.alerts))
+	}
+}
+
+// TestAlerter_RaiseAfterCloseIsDiscarded tests that late alerts don't panic
+func TestAlerter_RaiseAfterCloseIsDiscarded(t *testing.T) {
+	sink := &recordingSink{}
+	a := New(DefaultConfig(), sink)
+	a.Close()
+	a.Raise(KindEventDropped, "", SeverityWarning, "late")
+	a.Close()
+
+	if len(sink.alerts) != 0 {
+		t.Fatalf("Expected no alerts after close, got %d", len(sink.alerts))
+	}
+
+	var nilAlerter *Alerter
+	nilAlerter.Raise(KindEventDropped, "", SeverityWarning, "nil is a no-op")
+}
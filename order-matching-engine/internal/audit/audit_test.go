@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// record appends entries with fixed times so queries are deterministic.
+func record(t *testing.T, l *Log, entries ...Entry) {
+	t.Helper()
+	for i, e := range entries {
+		e.Time = int64(i+1) * 1000
+		if _, err := l.Record(e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+}
+
+// TestLog_ReopenContinuesChain tests that entries survive a reopen and new
+// entries chain onto the last one written before it
+func TestLog_ReopenContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	l, err := Open(path, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	record(t, l,
+		Entry{Actor: "alice", Action: "symbol.state", Target: "AAPL", Params: map[string]string{"state": "HALTED"}, Outcome: "ok"},
+		Entry{Actor: "system", Action: "risk.kill_switch", Target: "TRADER1", Outcome: "ok"},
+	)
+	l.Close()
+
+	l, err = Open(path, key)
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer l.Close()
+	third, err := l.Record(Entry{Actor: "bob", Action: "risk.reinstate", Target: "TRADER1", Outcome: "ok"})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 3 || third.Seq != 3 || third.Prev != entries[1].Sig {
+		t.Fatalf("Expected 3 chained entries, got %+v", entries)
+	}
+	if err := Verify(path, key); err != nil {
+		t.Errorf("Expected the log to verify, got %v", err)
+	}
+	if err := Verify(path, []byte("wrong")); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected the wrong key to fail verification, got %v", err)
+	}
+}
+
+// TestLog_DetectsTampering tests that editing or deleting an entry fails
+// verification, and Open refuses the log
+func TestLog_DetectsTampering(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		tamper func(lines []string) []string
+	}{
+		{"edited", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"TRADER1"`, `"TRADER2"`, 1)
+			return lines
+		}},
+		{"deleted", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}},
+	} {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		l, err := Open(path, nil)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		record(t, l,
+			Entry{Actor: "alice", Action: "symbol.state", Target: "AAPL", Outcome: "ok"},
+			Entry{Actor: "bob", Action: "risk.reinstate", Target: "TRADER1", Outcome: "ok"},
+			Entry{Actor: "bob", Action: "risk.profile", Target: "TRADER1", Outcome: "ok"},
+		)
+		l.Close()
+
+		data, _ := os.ReadFile(path)
+		lines := c.tamper(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+		os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+
+		if err := Verify(path, nil); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: expected ErrTampered, got %v", c.name, err)
+		}
+		if _, err := Open(path, nil); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: expected Open to refuse the log, got %v", c.name, err)
+		}
+	}
+}
+
+// TestLog_QueryFilters tests action prefixes, target, time range and limit
+func TestLog_QueryFilters(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"), nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer l.Close()
+	record(t, l,
+		Entry{Actor: "alice", Action: "symbol.state", Target: "AAPL", Outcome: "ok"},           // t=1000
+		Entry{Actor: "system", Action: "risk.kill_switch", Target: "TRADER1", Outcome: "ok"},   // t=2000
+		Entry{Actor: "bob", Action: "risk.reinstate", Target: "TRADER1", Outcome: "ok"},        // t=3000
+		Entry{Actor: "bob", Action: "risk.profile", Target: "TRADER2", Outcome: "bad profile"}, // t=4000
+	)
+
+	for _, c := range []struct {
+		name   string
+		filter Filter
+		seqs   []uint64
+	}{
+		{"all", Filter{}, []uint64{1, 2, 3, 4}},
+		{"exact action", Filter{Action: "risk.reinstate"}, []uint64{3}},
+		{"action prefix", Filter{Action: "risk."}, []uint64{2, 3, 4}},
+		{"no partial word", Filter{Action: "risk"}, nil},
+		{"target", Filter{Target: "TRADER1"}, []uint64{2, 3}},
+		{"actor", Filter{Actor: "bob"}, []uint64{3, 4}},
+		{"time range", Filter{Since: 2000, Until: 4000}, []uint64{2, 3}},
+		{"limit keeps latest", Filter{Action: "risk.", Limit: 2}, []uint64{3, 4}},
+	} {
+		entries, err := l.Query(c.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var seqs []uint64
+		for _, e := range entries {
+			seqs = append(seqs, e.Seq)
+		}
+		if len(seqs) != len(c.seqs) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.seqs, seqs)
+			continue
+		}
+		for i := range seqs {
+			if seqs[i] != c.seqs[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.seqs, seqs)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package calendar holds per-market business day calendars: which days a
+// market trades and settles on.
+//
+// Why a calendar?
+// T+N settlement counts business days, not calendar days. A trade on the
+// Wednesday before Thanksgiving settles T+2 on Monday, not Friday, because
+// the market is closed on Thursday. Skipping weekends alone gets every
+// holiday week wrong.
+//
+// Calendars are loaded from a YAML file, one entry per market:
+//
+//	XNYS:
+//	  timezone: America/New_York    # Dates are taken in this zone (default UTC)
+//	  weekend: [Saturday, Sunday]   # Default
+//	  open: "09:30"                 # Trading session, local time (default
+//	  close: "16:00"                # the whole day, 00:00 to 24:00)
+//	  holidays:
+//	    2026-11-26: Thanksgiving Day
+//	    2026-12-25: Christmas Day
+//
+// A market with no entry trades every weekday, all day.
+package calendar
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dateLayout is the format holidays are keyed by.
+const dateLayout = "2006-01-02"
+
+// Calendar is one market's business days. Read-only once built, so safe
+// for concurrent use.
+type Calendar struct {
+	Market   string
+	location *time.Location
+	weekend  [7]bool           // Indexed by time.Weekday
+	holidays map[string]string // Date -> holiday name
+	open     int               // Session open, in minutes after midnight
+	close    int               // Session close, in minutes after midnight
+}
+
+// New creates a calendar for a market that trades every weekday, in UTC.
+func New(market string) *Calendar {
+	c := &Calendar{
+		Market:   market,
+		location: time.UTC,
+		holidays: make(map[string]string),
+		close:    24 * 60,
+	}
+	c.weekend[time.Saturday] = true
+	c.weekend[time.Sunday] = true
+	return c
+}
+
+// AddHoliday closes the market on a date (YYYY-MM-DD).
+func (c *Calendar) AddHoliday(date, name string) error {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("invalid holiday date %q: %w", date, err)
+	}
+	c.holidays[date] = name
+	return nil
+}
+
+// SetSession sets the trading session's open and close, local time
+// ("09:30", "16:00"; "24:00" is midnight at the end of the day).
+func (c *Calendar) SetSession(open, close string) error {
+	openMin, err := parseClock(open)
+	if err != nil {
+		return fmt.Errorf("invalid session open: %w", err)
+	}
+	closeMin, err := parseClock(close)
+	if err != nil {
+		return fmt.Errorf("invalid session close: %w", err)
+	}
+	if openMin >= closeMin {
+		return fmt.Errorf("session open %s is not before close %s", open, close)
+	}
+	c.open, c.close = openMin, closeMin
+	return nil
+}
+
+// Session returns when the trading session on t's date opens and closes.
+// On a day the market is closed it never opens.
+func (c *Calendar) Session(t time.Time) (open, close time.Time) {
+	y, m, d := t.In(c.location).Date()
+	return time.Date(y, m, d, 0, c.open, 0, 0, c.location), time.Date(y, m, d, 0, c.close, 0, 0, c.location)
+}
+
+// IsOpen reports whether the market is in its trading session at t.
+func (c *Calendar) IsOpen(t time.Time) bool {
+	open, close := c.Session(t)
+	return c.IsBusinessDay(t) && !t.Before(open) && t.Before(close)
+}
+
+// LastClose returns the most recent session close at or before t, within
+// the past year. Returns false if there was none.
+func (c *Calendar) LastClose(t time.Time) (time.Time, bool) {
+	d := t.In(c.location)
+	for i := 0; i <= 366; i++ {
+		if c.IsBusinessDay(d) {
+			if _, close := c.Session(d); !close.After(t) {
+				return close, true
+			}
+		}
+		d = d.AddDate(0, 0, -1)
+	}
+	return time.Time{}, false
+}
+
+// Date returns t's date in the market's time zone, as YYYY-MM-DD.
+func (c *Calendar) Date(t time.Time) string {
+	return t.In(c.location).Format(dateLayout)
+}
+
+// Holiday returns the name of the holiday t falls on, if any.
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[c.Date(t)]
+	return name, ok
+}
+
+// IsBusinessDay reports whether the market is open on t's date.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if c.weekend[t.In(c.location).Weekday()] {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// AddBusinessDays returns the date n business days after t, at the same
+// time of day. n = 0 returns t itself.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	d := t.In(c.location)
+	for n > 0 {
+		d = d.AddDate(0, 0, 1)
+		if c.IsBusinessDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// NextBusinessDay returns the first business day on or after t.
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	d := t.In(c.location)
+	for !c.IsBusinessDay(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// Holidays returns the holidays from date from (inclusive) onwards, in
+// date order, as YYYY-MM-DD -> name pairs.
+func (c *Calendar) Holidays(from time.Time) [][2]string {
+	start := c.Date(from)
+	var holidays [][2]string
+	for date, name := range c.holidays {
+		if date >= start {
+			holidays = append(holidays, [2]string{date, name})
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i][0] < holidays[j][0] })
+	return holidays
+}
+
+// Set holds the calendars of every market.
+type Set struct {
+	calendars map[string]*Calendar
+}
+
+// NewSet creates an empty set: every market trades every weekday.
+func NewSet() *Set {
+	return &Set{calendars: make(map[string]*Calendar)}
+}
+
+// Add adds or replaces a market's calendar.
+func (s *Set) Add(c *Calendar) {
+	s.calendars[c.Market] = c
+}
+
+// Get returns a market's calendar, or a weekdays-only one if it has none.
+// A nil set has none.
+func (s *Set) Get(market string) *Calendar {
+	if s != nil {
+		if c, ok := s.calendars[market]; ok {
+			return c
+		}
+	}
+	return New(market)
+}
+
+// fileEntry is one market in a calendar file.
+type fileEntry struct {
+	Timezone string            `yaml:"timezone"`
+	Weekend  []string          `yaml:"weekend"`
+	Open     string            `yaml:"open"`
+	Close    string            `yaml:"close"`
+	Holidays map[string]string `yaml:"holidays"`
+}
+
+// Load reads a calendar file.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses calendar file contents.
+func Parse(data []byte) (*Set, error) {
+	var entries map[string]fileEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid calendar file: %w", err)
+	}
+
+	set := NewSet()
+	for market, entry := range entries {
+		c := New(market)
+		if entry.Timezone != "" {
+			location, err := time.LoadLocation(entry.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("market %s: %w", market, err)
+			}
+			c.location = location
+		}
+		if entry.Weekend != nil {
+			c.weekend = [7]bool{}
+			for _, name := range entry.Weekend {
+				day, err := parseWeekday(name)
+				if err != nil {
+					return nil, fmt.Errorf("market %s: %w", market, err)
+				}
+				c.weekend[day] = true
+			}
+		}
+		if entry.Open != "" || entry.Close != "" {
+			open, close := entry.Open, entry.Close
+			if open == "" {
+				open = "00:00"
+			}
+			if close == "" {
+				close = "24:00"
+			}
+			if err := c.SetSession(open, close); err != nil {
+				return nil, fmt.Errorf("market %s: %w", market, err)
+			}
+		}
+		for date, name := range entry.Holidays {
+			if err := c.AddHoliday(date, name); err != nil {
+				return nil, fmt.Errorf("market %s: %w", market, err)
+			}
+		}
+		set.Add(c)
+	}
+	return set, nil
+}
+
+// parseClock parses a time of day, "HH:MM", as minutes after midnight.
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trading Sessions
+//
+// Sessions watches markets' calendars and calls hooks as their trading
+// sessions open and close:
+//
+//	open   a new trading day: daily counters start again
+//	close  the day is over: DAY orders expire, the settlement cycle runs
+//
+// Advance fires every hook due by the clock. The server calls it on an
+// interval from an event processor's timer wheel, so the hooks run between
+// two requests; a test sets a fake clock (SetClock) and calls Advance
+// itself.
+//
+// The first Advance catches up: it fires the close of each market's last
+// session, then its open if the market is in session now, so a server
+// started after hours still expires the DAY orders it restored. After
+// that, if the clock jumps over several sessions (a suspended host), only
+// the latest close fires, and the open after it if one is due.
+
+// SessionEvent is a market's session opening or closing.
+type SessionEvent struct {
+	Market string
+	Date   string    // Trading date, in the market's time zone
+	Open   bool      // Opening (true) or closing
+	Time   time.Time // When the session opened or closed (not when seen)
+}
+
+// Sessions fires session open and close hooks for a set of markets.
+type Sessions struct {
+	mu      sync.Mutex
+	set     *Set
+	markets []string
+	now     func() time.Time
+	last    map[string]time.Time // Market -> when last advanced
+	onOpen  func(SessionEvent)
+	onClose func(SessionEvent)
+}
+
+// NewSessions creates a session watcher for markets, with calendars from
+// set.
+func NewSessions(set *Set, markets []string) *Sessions {
+	markets = append([]string(nil), markets...)
+	sort.Strings(markets)
+	return &Sessions{
+		set:     set,
+		markets: markets,
+		now:     time.Now,
+		last:    make(map[string]time.Time),
+	}
+}
+
+// SetClock replaces the clock sessions are advanced by, for tests.
+func (s *Sessions) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// OnOpen registers a hook called as each market's session opens. Must be
+// called before the first Advance.
+func (s *Sessions) OnOpen(fn func(SessionEvent)) {
+	s.onOpen = fn
+}
+
+// OnClose registers a hook called as each market's session closes. Must
+// be called before the first Advance.
+func (s *Sessions) OnClose(fn func(SessionEvent)) {
+	s.onClose = fn
+}
+
+// Advance fires the hooks of every open and close due by the clock since
+// the last Advance, by market, closes before opens. Returns the events
+// fired.
+func (s *Sessions) Advance() []SessionEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var fired []SessionEvent
+	for _, market := range s.markets {
+		cal := s.set.Get(market)
+		last := s.last[market] // Zero on the first Advance: catch up
+		s.last[market] = now
+
+		closed, ok := cal.LastClose(now)
+		if ok && closed.After(last) {
+			event := SessionEvent{Market: market, Date: cal.Date(closed.Add(-time.Nanosecond)), Time: closed}
+			fired = append(fired, event)
+			if s.onClose != nil {
+				s.onClose(event)
+			}
+		}
+		if opened, _ := cal.Session(now); cal.IsOpen(now) && opened.After(last) {
+			event := SessionEvent{Market: market, Date: cal.Date(now), Open: true, Time: opened}
+			fired = append(fired, event)
+			if s.onOpen != nil {
+				s.onOpen(event)
+			}
+		}
+	}
+	return fired
+}
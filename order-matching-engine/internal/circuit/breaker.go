@@ -0,0 +1,155 @@
+// Package circuit implements per-symbol circuit breakers: trading pauses
+// and halts tripped by fast price moves.
+//
+// Limit Up-Limit Down:
+// Each symbol's trades within a rolling window define its recent range: the
+// lowest and highest price traded. A trade that moves more than PauseBps
+// away from that range - up from the low, or down from the high - pauses
+// the symbol; more than HaltBps halts it:
+//
+//	OPEN ──move > PauseBps──▶ PAUSED ──PauseFor elapses──▶ OPEN
+//	  │                         │
+//	  └───────move > HaltBps────┴──▶ HALTED ──operator──▶ OPEN
+//
+// A pause lifts itself; a halt waits for an operator. The breaker only
+// measures moves and reports trips - the caller changes the session state
+// (see refdata) and resets the symbol's window, so the move that tripped
+// it is not measured again when trading resumes.
+//
+// The window keeps monotonic queues of its lows and highs, so each trade
+// costs O(1) amortised however many trades the window holds.
+package circuit
+
+import (
+	"sync"
+	"time"
+)
+
+// Config holds circuit breaker thresholds.
+type Config struct {
+	Window   time.Duration // Rolling window prices are compared within
+	PauseBps int64         // Move that pauses trading (0 = never)
+	HaltBps  int64         // Move that halts trading (0 = never)
+	PauseFor time.Duration // How long a pause lasts
+}
+
+// DefaultConfig returns LULD-style defaults: a 5% move within five minutes
+// pauses trading for five minutes, a 10% move halts it.
+func DefaultConfig() Config {
+	return Config{
+		Window:   5 * time.Minute,
+		PauseBps: 500,
+		HaltBps:  1000,
+		PauseFor: 5 * time.Minute,
+	}
+}
+
+// Trip is what a trade tripped.
+type Trip uint8
+
+const (
+	TripNone  Trip = iota
+	TripPause      // Limit up-limit down pause
+	TripHalt       // Trading halt
+)
+
+func (t Trip) String() string {
+	switch t {
+	case TripPause:
+		return "PAUSE"
+	case TripHalt:
+		return "HALT"
+	default:
+		return "NONE"
+	}
+}
+
+// point is one trade in a window.
+type point struct {
+	at    int64 // Nanoseconds since epoch
+	price int64
+}
+
+// window is one symbol's recent trades: lows holds rising prices and highs
+// falling ones, oldest first, so the front of each is the window's extreme.
+type window struct {
+	lows  []point
+	highs []point
+}
+
+// Breaker measures price moves per symbol. Safe for concurrent use.
+type Breaker struct {
+	mu      sync.Mutex
+	config  Config
+	windows map[string]*window
+}
+
+// New creates a circuit breaker.
+func New(config Config) *Breaker {
+	return &Breaker{
+		config:  config,
+		windows: make(map[string]*window),
+	}
+}
+
+// Config returns the breaker's thresholds.
+func (b *Breaker) Config() Config {
+	return b.config
+}
+
+// Observe records a trade at price and time at, and returns what it
+// tripped along with the move in basis points it made from the window's
+// range.
+func (b *Breaker) Observe(symbol string, price, at int64) (Trip, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w := b.windows[symbol]
+	if w == nil {
+		w = &window{}
+		b.windows[symbol] = w
+	}
+
+	// Drop trades that left the window
+	cutoff := at - int64(b.config.Window)
+	for len(w.lows) > 0 && w.lows[0].at < cutoff {
+		w.lows = w.lows[1:]
+	}
+	for len(w.highs) > 0 && w.highs[0].at < cutoff {
+		w.highs = w.highs[1:]
+	}
+
+	var move int64
+	if len(w.lows) > 0 && price > w.lows[0].price {
+		move = (price - w.lows[0].price) * 10000 / w.lows[0].price
+	}
+	if len(w.highs) > 0 && price < w.highs[0].price {
+		move = max(move, (w.highs[0].price-price)*10000/w.highs[0].price)
+	}
+
+	// A new trade outlives every older one, so older trades that are no
+	// lower (higher) can never be the window's low (high) again
+	for len(w.lows) > 0 && w.lows[len(w.lows)-1].price >= price {
+		w.lows = w.lows[:len(w.lows)-1]
+	}
+	w.lows = append(w.lows, point{at: at, price: price})
+	for len(w.highs) > 0 && w.highs[len(w.highs)-1].price <= price {
+		w.highs = w.highs[:len(w.highs)-1]
+	}
+	w.highs = append(w.highs, point{at: at, price: price})
+
+	switch {
+	case b.config.HaltBps > 0 && move > b.config.HaltBps:
+		return TripHalt, move
+	case b.config.PauseBps > 0 && move > b.config.PauseBps:
+		return TripPause, move
+	}
+	return TripNone, move
+}
+
+// Reset forgets a symbol's window, so trading resumes with no range.
+func (b *Breaker) Reset(symbol string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.windows, symbol)
+}
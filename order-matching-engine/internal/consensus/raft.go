@@ -0,0 +1,892 @@
+// Package consensus commits commands through Raft, so every node of a
+// cluster applies the same commands in the same order and the cluster
+// keeps going, with a new leader, when a minority of nodes fail.
+//
+// It is algorithms/raft hardened for the matching engine:
+//
+//	algorithms/raft                     consensus
+//	in-process calls between nodes      net/rpc over TCP, with timeouts
+//	term, vote and log in memory        on disk, synced before replying
+//	leader decrements nextIndex by 1    followers return a conflict index
+//	whole log suffix per append         at most MaxAppendEntries per call
+//	apply polled every 10ms             applied as soon as it commits
+//	leader keeps leading when cut off   steps down without a quorum
+//
+// Election, the lease that keeps a follower from voting while its leader
+// may still be alive, per-follower flow control and the no-op each new
+// leader appends all work as in algorithms/raft; see its README.
+//
+// Raft:
+//
+//	Propose ──▶ leader log ──AppendEntries──▶ follower logs
+//	                │                              │
+//	                └──── majority durable ◀───────┘
+//	                           │
+//	                 commit ──▶ apply, in index order, on every node
+//
+// Commands are opaque bytes. A command is only committed once a majority
+// of nodes have it on disk, so it survives any minority failing; the
+// callback given to Open sees every committed command exactly once per
+// process, in order, starting from index 1 on every start.
+//
+// Production Considerations:
+//   - The log is never compacted or snapshotted: it grows with every
+//     command, and a restarted node re-applies it from the start
+//   - Membership is fixed: Peers must list the same nodes on every node
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is a node's role.
+type State int
+
+const (
+	Follower State = iota
+	Candidate
+	Leader
+)
+
+func (s State) String() string {
+	switch s {
+	case Follower:
+		return "follower"
+	case Candidate:
+		return "candidate"
+	case Leader:
+		return "leader"
+	default:
+		return "unknown"
+	}
+}
+
+// Entry is one command in the log. A nil Command is a new leader's no-op.
+type Entry struct {
+	Term    uint64
+	Index   uint64
+	Command []byte
+}
+
+// Applied is a committed command handed to the application.
+type Applied struct {
+	Index   uint64
+	Term    uint64
+	Command []byte
+}
+
+// MinTimeoutHeartbeatRatio is how many heartbeats must fit in the shortest
+// election timeout, so one late heartbeat doesn't start an election.
+const MinTimeoutHeartbeatRatio = 3
+
+// Config configures a node.
+type Config struct {
+	ID    int      // This node's index in Peers
+	Peers []string // Raft address of every node, in the same order on every node
+	Dir   string   // Holds the node's term, vote and log
+
+	HeartbeatInterval  time.Duration // Leader heartbeat (and replication) period
+	ElectionTimeoutMin time.Duration // Election timeouts are random in [Min, Max)
+	ElectionTimeoutMax time.Duration
+	RPCTimeout         time.Duration // Longest a vote or append waits for its reply
+	MaxAppendEntries   int           // Entries sent per AppendEntries
+}
+
+// DefaultConfig returns reasonable timings; ID, Peers and Dir must be set.
+func DefaultConfig() Config {
+	return Config{
+		HeartbeatInterval:  50 * time.Millisecond,
+		ElectionTimeoutMin: 300 * time.Millisecond,
+		ElectionTimeoutMax: 600 * time.Millisecond,
+		RPCTimeout:         200 * time.Millisecond,
+		MaxAppendEntries:   512,
+	}
+}
+
+// Validate checks the node is one of the peers and the timings are sane.
+func (c Config) Validate() error {
+	switch {
+	case len(c.Peers) == 0:
+		return errors.New("consensus: no peers")
+	case c.ID < 0 || c.ID >= len(c.Peers):
+		return fmt.Errorf("consensus: node %d is not one of the %d peers", c.ID, len(c.Peers))
+	case c.Dir == "":
+		return errors.New("consensus: no directory for the raft log")
+	case c.HeartbeatInterval <= 0, c.RPCTimeout <= 0, c.MaxAppendEntries <= 0:
+		return errors.New("consensus: heartbeat interval, rpc timeout and max append entries must be positive")
+	case c.ElectionTimeoutMax <= c.ElectionTimeoutMin:
+		return fmt.Errorf("consensus: election timeout max (%v) must exceed min (%v)", c.ElectionTimeoutMax, c.ElectionTimeoutMin)
+	case c.ElectionTimeoutMin < MinTimeoutHeartbeatRatio*c.HeartbeatInterval:
+		return fmt.Errorf("consensus: election timeout min (%v) must be at least %d heartbeat intervals",
+			c.ElectionTimeoutMin, MinTimeoutHeartbeatRatio)
+	}
+	return nil
+}
+
+// ErrStopped is returned by a node that was closed or failed to write its
+// state to disk.
+var ErrStopped = errors.New("consensus: node stopped")
+
+// NotLeaderError is returned for a proposal made to a node that is not the
+// leader.
+type NotLeaderError struct {
+	LeaderID   int    // -1 if unknown
+	LeaderAddr string // Raft address of the leader, if known
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.LeaderID == -1 {
+		return "not the leader: leader unknown"
+	}
+	return fmt.Sprintf("not the leader: leader is node %d (%s)", e.LeaderID, e.LeaderAddr)
+}
+
+// Node is one member of a Raft cluster.
+type Node struct {
+	config  Config
+	storage *storage
+	peers   []*peer // nil at config.ID
+	apply   func(Applied)
+
+	mu              sync.Mutex
+	currentTerm     uint64
+	votedFor        int
+	log             []Entry // log[i].Index == i; log[0] is a placeholder
+	durable         uint64  // Last index synced to this node's disk
+	state           State
+	leaderID        int // Leader of currentTerm as far as we know, -1 if unknown
+	commitIndex     uint64
+	nextIndex       []uint64
+	matchIndex      []uint64
+	progress        []followerProgress
+	electionTimeout time.Duration
+	lastHeartbeat   time.Time
+	leaseUntil      time.Time // No votes before this: a leader's lease may still hold
+	stopped         bool      // Closed, or failed
+	closed          bool
+	err             error // Why the node stopped, if it failed
+
+	lastApplied uint64 // Atomic; only written by applyLoop
+	applyCh     chan struct{}
+	replicateCh chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	rpcServer *rpc.Server
+	connMu    sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+}
+
+// followerProgress is the leader's flow control state for one follower.
+type followerProgress struct {
+	inflight    int           // AppendEntries calls awaiting a reply
+	backoff     time.Duration // 0 while the follower is reachable
+	retryAt     time.Time     // Nothing is sent before this while backing off
+	lastContact time.Time
+}
+
+// Flow control per follower; backoff starts at one heartbeat interval.
+const (
+	maxInflightAppends    = 2
+	replicationBackoffMax = 2 * time.Second
+)
+
+// Open loads the node's state from config.Dir. apply is called with every
+// committed command, in order, from a single goroutine; the node does
+// nothing until Start.
+func Open(config Config, apply func(Applied)) (*Node, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	store, term, vote, entries, err := openStorage(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		config:      config,
+		storage:     store,
+		peers:       make([]*peer, len(config.Peers)),
+		apply:       apply,
+		currentTerm: term,
+		votedFor:    vote,
+		log:         entries,
+		durable:     uint64(len(entries) - 1),
+		state:       Follower,
+		leaderID:    -1,
+		applyCh:     make(chan struct{}, 1),
+		replicateCh: make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		rpcServer:   rpc.NewServer(),
+		conns:       make(map[net.Conn]struct{}),
+	}
+	for i, addr := range config.Peers {
+		if i != config.ID {
+			n.peers[i] = &peer{addr: addr, timeout: config.RPCTimeout}
+		}
+	}
+	if err := n.rpcServer.RegisterName("Raft", &service{node: n}); err != nil {
+		store.close()
+		return nil, err
+	}
+	n.resetElectionTimeout()
+	return n, nil
+}
+
+// Serve answers other nodes' RPCs on ln until Close.
+func (n *Node) Serve(ln net.Listener) error {
+	n.connMu.Lock()
+	n.listeners = append(n.listeners, ln)
+	n.connMu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-n.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		n.connMu.Lock()
+		n.conns[conn] = struct{}{}
+		n.connMu.Unlock()
+		go func() {
+			n.rpcServer.ServeConn(conn)
+			n.connMu.Lock()
+			delete(n.conns, conn)
+			n.connMu.Unlock()
+		}()
+	}
+}
+
+// Start begins elections, replication and applying committed commands.
+func (n *Node) Start() {
+	n.wg.Add(3)
+	go n.electionLoop()
+	go n.leaderLoop()
+	go n.applyLoop()
+}
+
+// Close stops the node. Commands it proposed that had not committed may
+// still commit under another leader.
+func (n *Node) Close() error {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closed = true
+	n.stopped = true
+	n.mu.Unlock()
+
+	close(n.done)
+	n.connMu.Lock()
+	for _, ln := range n.listeners {
+		ln.Close()
+	}
+	for conn := range n.conns {
+		conn.Close()
+	}
+	n.connMu.Unlock()
+	for _, p := range n.peers {
+		if p != nil {
+			p.close()
+		}
+	}
+	n.wg.Wait()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.storage.close()
+}
+
+// fail stops the node after its state could not be written: carrying on
+// could break a promise it already made to another node. Must hold n.mu.
+func (n *Node) fail(err error) {
+	log.Printf("Raft node %d stopped: failed to write its state: %v", n.config.ID, err)
+	n.err = err
+	n.stopped = true
+	n.state = Follower
+	n.leaderID = -1
+}
+
+// Propose appends a command to the leader's log and returns its index. It
+// commits once a majority has it on disk, when the apply callback sees it
+// on every node. Returns a *NotLeaderError on any other node.
+func (n *Node) Propose(command []byte) (uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stopped {
+		return 0, ErrStopped
+	}
+	if n.state != Leader {
+		return 0, n.notLeader()
+	}
+	entry := Entry{Term: n.currentTerm, Index: uint64(len(n.log)), Command: command}
+	if err := n.storage.append(entry); err != nil {
+		n.fail(err)
+		return 0, ErrStopped
+	}
+	n.log = append(n.log, entry)
+
+	// Synced and sent by the leader loop, so proposals made together share
+	// one sync and one round of appends
+	signal(n.replicateCh)
+	return entry.Index, nil
+}
+
+// notLeader describes the leader to send proposals to. Must hold n.mu.
+func (n *Node) notLeader() *NotLeaderError {
+	err := &NotLeaderError{LeaderID: n.leaderID}
+	if n.leaderID >= 0 {
+		err.LeaderAddr = n.config.Peers[n.leaderID]
+	}
+	return err
+}
+
+// Leader returns the leader's ID, or -1 if unknown, and whether it is this
+// node.
+func (n *Node) Leader() (int, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.leaderID, n.state == Leader
+}
+
+// signal wakes the goroutine waiting on ch without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default: // Already pending
+	}
+}
+
+// resetElectionTimeout draws a new election timeout. Must hold n.mu.
+func (n *Node) resetElectionTimeout() {
+	min, max := n.config.ElectionTimeoutMin, n.config.ElectionTimeoutMax
+	n.electionTimeout = min + time.Duration(rand.Int63n(int64(max-min)))
+	n.lastHeartbeat = time.Now()
+}
+
+// setTerm moves to a later term as a follower, with no vote cast. Must
+// hold n.mu.
+func (n *Node) setTerm(term uint64) error {
+	n.currentTerm = term
+	n.votedFor = -1
+	n.state = Follower
+	n.leaderID = -1
+	return n.storage.saveState(n.currentTerm, n.votedFor)
+}
+
+func (n *Node) lastIndex() uint64 {
+	return uint64(len(n.log) - 1)
+}
+
+// electionLoop starts an election when no leader has been heard from for
+// an election timeout.
+func (n *Node) electionLoop() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(n.config.ElectionTimeoutMin / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+		}
+
+		n.mu.Lock()
+		if !n.stopped && n.state != Leader && time.Since(n.lastHeartbeat) > n.electionTimeout {
+			n.startElection()
+		}
+		n.mu.Unlock()
+	}
+}
+
+// startElection becomes a candidate and asks every peer for its vote. Must
+// hold n.mu.
+func (n *Node) startElection() {
+	n.state = Candidate
+	n.currentTerm++
+	n.votedFor = n.config.ID
+	n.leaderID = -1
+	n.resetElectionTimeout()
+	if err := n.storage.saveState(n.currentTerm, n.votedFor); err != nil {
+		n.fail(err)
+		return
+	}
+	log.Printf("Raft node %d starting election for term %d", n.config.ID, n.currentTerm)
+
+	args := RequestVoteArgs{
+		Term:         n.currentTerm,
+		CandidateID:  n.config.ID,
+		LastLogIndex: n.lastIndex(),
+		LastLogTerm:  n.log[n.lastIndex()].Term,
+	}
+	votes := 1
+	if votes > len(n.peers)/2 {
+		n.becomeLeader() // A cluster of one
+		return
+	}
+	for _, p := range n.peers {
+		if p == nil {
+			continue
+		}
+		go func(p *peer) {
+			var reply RequestVoteReply
+			if !p.call("RequestVote", &args, &reply) {
+				return
+			}
+
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			if n.stopped || n.currentTerm != args.Term || n.state != Candidate {
+				return
+			}
+			if reply.Term > n.currentTerm {
+				if err := n.setTerm(reply.Term); err != nil {
+					n.fail(err)
+				}
+				return
+			}
+			if reply.VoteGranted {
+				votes++
+				if votes > len(n.peers)/2 {
+					n.becomeLeader()
+				}
+			}
+		}(p)
+	}
+}
+
+// becomeLeader takes over as leader of the current term. Must hold n.mu.
+func (n *Node) becomeLeader() {
+	n.state = Leader
+	n.leaderID = n.config.ID
+	log.Printf("Raft node %d became leader for term %d", n.config.ID, n.currentTerm)
+
+	now := time.Now()
+	n.nextIndex = make([]uint64, len(n.peers))
+	n.matchIndex = make([]uint64, len(n.peers))
+	n.progress = make([]followerProgress, len(n.peers))
+	for i := range n.peers {
+		n.nextIndex[i] = uint64(len(n.log))
+		n.progress[i].lastContact = now
+	}
+
+	// A no-op commits entries from earlier terms without waiting for a
+	// proposal (a leader only counts replicas of its own term's entries)
+	entry := Entry{Term: n.currentTerm, Index: uint64(len(n.log))}
+	if err := n.storage.append(entry); err != nil {
+		n.fail(err)
+		return
+	}
+	n.log = append(n.log, entry)
+	signal(n.replicateCh)
+}
+
+// leaderLoop syncs the leader's log and sends appends every heartbeat, or
+// sooner when there is something new to send.
+func (n *Node) leaderLoop() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(n.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+			n.mu.Lock()
+			n.checkQuorum()
+			n.mu.Unlock()
+		case <-n.replicateCh:
+		}
+
+		n.mu.Lock()
+		if !n.stopped && n.state == Leader {
+			if err := n.storage.sync(); err != nil {
+				n.fail(err)
+			} else {
+				n.durable = n.lastIndex()
+				n.updateCommitIndex()
+				n.replicateToAll()
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+// checkQuorum steps a leader down once it has heard from no majority for
+// an election timeout: the others have probably elected a new leader, and
+// nothing it is proposed can commit. Must hold n.mu.
+func (n *Node) checkQuorum() {
+	if n.state != Leader {
+		return
+	}
+	now := time.Now()
+	reached := 1
+	for i, p := range n.peers {
+		if p != nil && now.Sub(n.progress[i].lastContact) < n.config.ElectionTimeoutMax {
+			reached++
+		}
+	}
+	if reached <= len(n.peers)/2 {
+		log.Printf("Raft node %d stepping down in term %d: lost contact with a majority", n.config.ID, n.currentTerm)
+		n.state = Follower
+		n.leaderID = -1
+		n.resetElectionTimeout()
+	}
+}
+
+// replicateToAll sends AppendEntries to every follower that isn't backing
+// off or already waiting on maxInflightAppends calls. Must hold n.mu.
+func (n *Node) replicateToAll() {
+	now := time.Now()
+	for i, p := range n.peers {
+		if p == nil {
+			continue
+		}
+		progress := &n.progress[i]
+		if progress.inflight >= maxInflightAppends || now.Before(progress.retryAt) {
+			continue
+		}
+		progress.inflight++
+		go n.replicateTo(i, n.appendArgs(i))
+	}
+}
+
+// appendArgs builds the next AppendEntries for follower i. Must hold n.mu.
+func (n *Node) appendArgs(i int) AppendEntriesArgs {
+	next := n.nextIndex[i]
+	end := uint64(len(n.log))
+	if end-next > uint64(n.config.MaxAppendEntries) {
+		end = next + uint64(n.config.MaxAppendEntries)
+	}
+	return AppendEntriesArgs{
+		Term:          n.currentTerm,
+		LeaderID:      n.config.ID,
+		PrevLogIndex:  next - 1,
+		PrevLogTerm:   n.log[next-1].Term,
+		Entries:       append([]Entry(nil), n.log[next:end]...),
+		LeaderCommit:  n.commitIndex,
+		LeaseDuration: n.config.ElectionTimeoutMin,
+	}
+}
+
+// replicateTo sends one AppendEntries to follower i and handles its reply.
+func (n *Node) replicateTo(i int, args AppendEntriesArgs) {
+	var reply AppendEntriesReply
+	ok := n.peers[i].call("AppendEntries", &args, &reply)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped || n.state != Leader || n.currentTerm != args.Term {
+		return
+	}
+
+	progress := &n.progress[i]
+	progress.inflight--
+	if !ok {
+		// Unreachable: back off exponentially instead of retrying every heartbeat
+		if progress.backoff == 0 {
+			progress.backoff = n.config.HeartbeatInterval
+		} else if progress.backoff *= 2; progress.backoff > replicationBackoffMax {
+			progress.backoff = replicationBackoffMax
+		}
+		progress.retryAt = time.Now().Add(progress.backoff)
+		return
+	}
+	progress.backoff = 0
+	progress.retryAt = time.Time{}
+	progress.lastContact = time.Now()
+
+	if reply.Term > n.currentTerm {
+		if err := n.setTerm(reply.Term); err != nil {
+			n.fail(err)
+		}
+		n.resetElectionTimeout()
+		return
+	}
+
+	if reply.Success {
+		// With several calls in flight a reply may be older than one already handled
+		if match := args.PrevLogIndex + uint64(len(args.Entries)); match > n.matchIndex[i] {
+			n.matchIndex[i] = match
+		}
+		n.nextIndex[i] = n.matchIndex[i] + 1
+		n.updateCommitIndex()
+		if n.nextIndex[i] < uint64(len(n.log)) {
+			signal(n.replicateCh) // More to send
+		}
+		return
+	}
+
+	next := reply.ConflictIndex
+	if next > args.PrevLogIndex {
+		next = args.PrevLogIndex
+	}
+	if next < 1 {
+		next = 1
+	}
+	if next < n.nextIndex[i] {
+		n.nextIndex[i] = next
+	}
+	signal(n.replicateCh)
+}
+
+// updateCommitIndex commits the latest entry of the current term a
+// majority holds on disk. Must hold n.mu.
+func (n *Node) updateCommitIndex() {
+	for index := n.lastIndex(); index > n.commitIndex; index-- {
+		if n.log[index].Term != n.currentTerm {
+			break // Earlier terms' entries commit with this term's first one
+		}
+		count := 0
+		if n.durable >= index {
+			count++
+		}
+		for i, p := range n.peers {
+			if p != nil && n.matchIndex[i] >= index {
+				count++
+			}
+		}
+		if count > len(n.peers)/2 {
+			n.commitIndex = index
+			signal(n.applyCh)
+			return
+		}
+	}
+}
+
+// applyLoop hands committed commands to the application in index order.
+func (n *Node) applyLoop() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-n.applyCh:
+		}
+
+		n.mu.Lock()
+		from := atomic.LoadUint64(&n.lastApplied) + 1
+		entries := append([]Entry(nil), n.log[from:n.commitIndex+1]...)
+		n.mu.Unlock()
+
+		for _, entry := range entries {
+			if entry.Command != nil {
+				n.apply(Applied{Index: entry.Index, Term: entry.Term, Command: entry.Command})
+			}
+			atomic.StoreUint64(&n.lastApplied, entry.Index)
+		}
+	}
+}
+
+// handleRequestVote answers a candidate.
+func (n *Node) handleRequestVote(args *RequestVoteArgs, reply *RequestVoteReply) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return ErrStopped
+	}
+
+	// A leader's lease may still hold: don't help elect another, or even
+	// adopt the candidate's term, which would depose it
+	if args.Term > n.currentTerm && time.Now().Before(n.leaseUntil) {
+		reply.Term = n.currentTerm
+		return nil
+	}
+
+	if args.Term > n.currentTerm {
+		if err := n.setTerm(args.Term); err != nil {
+			n.fail(err)
+			return err
+		}
+	}
+	reply.Term = n.currentTerm
+	if args.Term < n.currentTerm || (n.votedFor != -1 && n.votedFor != args.CandidateID) {
+		return nil
+	}
+
+	// Only vote for a candidate whose log is at least as up to date
+	lastIndex := n.lastIndex()
+	lastTerm := n.log[lastIndex].Term
+	if args.LastLogTerm < lastTerm || (args.LastLogTerm == lastTerm && args.LastLogIndex < lastIndex) {
+		return nil
+	}
+
+	n.votedFor = args.CandidateID
+	if err := n.storage.saveState(n.currentTerm, n.votedFor); err != nil {
+		n.fail(err)
+		return err
+	}
+	n.resetElectionTimeout()
+	reply.VoteGranted = true
+	return nil
+}
+
+// handleAppendEntries appends a leader's entries, syncing them before
+// replying.
+func (n *Node) handleAppendEntries(args *AppendEntriesArgs, reply *AppendEntriesReply) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return ErrStopped
+	}
+
+	if args.Term > n.currentTerm {
+		if err := n.setTerm(args.Term); err != nil {
+			n.fail(err)
+			return err
+		}
+	}
+	reply.Term = n.currentTerm
+	if args.Term < n.currentTerm {
+		return nil
+	}
+
+	// Heard from the leader of this term
+	n.state = Follower
+	n.leaderID = args.LeaderID
+	n.resetElectionTimeout()
+	n.leaseUntil = time.Now().Add(args.LeaseDuration)
+
+	if args.PrevLogIndex > n.lastIndex() {
+		reply.ConflictIndex = uint64(len(n.log))
+		return nil
+	}
+	if term := n.log[args.PrevLogIndex].Term; term != args.PrevLogTerm {
+		index := args.PrevLogIndex
+		for index > 1 && n.log[index-1].Term == term {
+			index--
+		}
+		reply.ConflictIndex = index
+		return nil
+	}
+
+	// Skip entries already held; truncate at the first conflict
+	entries := args.Entries
+	for len(entries) > 0 {
+		index := entries[0].Index
+		if index > n.lastIndex() {
+			break
+		}
+		if n.log[index].Term != entries[0].Term {
+			if index <= n.commitIndex {
+				return fmt.Errorf("consensus: leader %d conflicts with committed entry %d", args.LeaderID, index)
+			}
+			if err := n.storage.truncate(index); err != nil {
+				n.fail(err)
+				return err
+			}
+			n.log = n.log[:index]
+			break
+		}
+		entries = entries[1:]
+	}
+	if len(entries) > 0 {
+		if err := n.storage.append(entries...); err != nil {
+			n.fail(err)
+			return err
+		}
+		n.log = append(n.log, entries...)
+	}
+	if err := n.storage.sync(); err != nil {
+		n.fail(err)
+		return err
+	}
+	n.durable = n.lastIndex()
+
+	// Only entries known to match the leader's may commit
+	if last := args.PrevLogIndex + uint64(len(args.Entries)); args.LeaderCommit > n.commitIndex {
+		n.commitIndex = args.LeaderCommit
+		if last < n.commitIndex {
+			n.commitIndex = last
+		}
+		signal(n.applyCh)
+	}
+	reply.Success = true
+	return nil
+}
+
+// Status is a node's view of the cluster, for operators.
+type Status struct {
+	ID          int              `json:"id"`
+	Addr        string           `json:"addr"`
+	State       string           `json:"state"`
+	Term        uint64           `json:"term"`
+	Leader      int              `json:"leader"` // -1 if unknown
+	LeaderAddr  string           `json:"leader_addr,omitempty"`
+	LastIndex   uint64           `json:"last_index"`
+	CommitIndex uint64           `json:"commit_index"`
+	LastApplied uint64           `json:"last_applied"`
+	Followers   []FollowerStatus `json:"followers,omitempty"` // Leader only
+	Error       string           `json:"error,omitempty"`     // Why the node stopped
+}
+
+// FollowerStatus is the leader's view of one follower.
+type FollowerStatus struct {
+	ID          int       `json:"id"`
+	Addr        string    `json:"addr"`
+	MatchIndex  uint64    `json:"match_index"`
+	Lag         uint64    `json:"lag"` // Entries the follower is known to be missing
+	Inflight    int       `json:"inflight"`
+	Backoff     string    `json:"backoff,omitempty"` // Set while unreachable
+	LastContact time.Time `json:"last_contact"`
+}
+
+// Status returns the node's state.
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := Status{
+		ID:          n.config.ID,
+		Addr:        n.config.Peers[n.config.ID],
+		State:       n.state.String(),
+		Term:        n.currentTerm,
+		Leader:      n.leaderID,
+		LastIndex:   n.lastIndex(),
+		CommitIndex: n.commitIndex,
+		LastApplied: atomic.LoadUint64(&n.lastApplied),
+	}
+	if n.leaderID >= 0 {
+		status.LeaderAddr = n.config.Peers[n.leaderID]
+	}
+	if n.err != nil {
+		status.Error = n.err.Error()
+	}
+	if n.state == Leader {
+		for i, p := range n.peers {
+			if p == nil {
+				continue
+			}
+			follower := FollowerStatus{
+				ID:          i,
+				Addr:        p.addr,
+				MatchIndex:  n.matchIndex[i],
+				Lag:         n.lastIndex() - n.matchIndex[i],
+				Inflight:    n.progress[i].inflight,
+				LastContact: n.progress[i].lastContact,
+			}
+			if n.progress[i].backoff > 0 {
+				follower.Backoff = n.progress[i].backoff.String()
+			}
+			status.Followers = append(status.Followers, follower)
+		}
+	}
+	return status
+}
@@ -0,0 +1,214 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// storage keeps a node's Raft state on disk: the current term and vote in
+// one small file, replaced atomically, and the log in another, appended to.
+//
+// Log record layout (big-endian):
+//
+//	crc32(4) term(8) index(8) length(4) command(length)
+//
+// The checksum covers everything after it; a no-op has length 0. A torn
+// record at the end of the log (from a crash mid-write) is truncated away
+// on open. Entries are never compacted.
+type storage struct {
+	dir     string
+	file    *os.File
+	offsets []int64 // offsets[i] is where entry i starts; offsets[0] is unused
+	size    int64
+	synced  bool
+}
+
+const (
+	stateFile   = "raft-state"
+	logFile     = "raft-log"
+	recordHead  = 4 + 8 + 8 + 4
+	maxCommand  = 64 << 20
+	stateLength = 8 + 8
+)
+
+// errCorrupt is returned when a log record that isn't the last one fails
+// its checksum, or entries are out of order.
+var errCorrupt = errors.New("consensus: raft log corrupt")
+
+// openStorage opens or creates the state in dir, returning the term, vote
+// and log it holds. The log starts with a placeholder entry at index 0.
+func openStorage(dir string) (*storage, uint64, int, []Entry, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, 0, nil, err
+	}
+	term, vote, err := readState(filepath.Join(dir, stateFile))
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	s := &storage{dir: dir, file: file, offsets: []int64{0}, synced: true}
+	entries, err := s.load()
+	if err != nil {
+		file.Close()
+		return nil, 0, 0, nil, err
+	}
+	return s, term, vote, entries, nil
+}
+
+// readState reads the term and vote, or returns term 0 and no vote if the
+// node has never saved any.
+func readState(path string) (uint64, int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, -1, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) != stateLength {
+		return 0, 0, fmt.Errorf("consensus: %s is %d bytes, expected %d", path, len(data), stateLength)
+	}
+	return binary.BigEndian.Uint64(data[0:]), int(int64(binary.BigEndian.Uint64(data[8:]))), nil
+}
+
+// saveState durably replaces the term and vote.
+func (s *storage) saveState(term uint64, vote int) error {
+	var data [stateLength]byte
+	binary.BigEndian.PutUint64(data[0:], term)
+	binary.BigEndian.PutUint64(data[8:], uint64(int64(vote)))
+
+	path := filepath.Join(s.dir, stateFile)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data[:]); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads every entry, truncating a torn tail.
+func (s *storage) load() ([]Entry, error) {
+	entries := []Entry{{}}
+	head := make([]byte, recordHead)
+	var offset int64
+	for {
+		n, err := s.file.ReadAt(head, offset)
+		if err == io.EOF && n == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n < recordHead {
+			break // Torn header
+		}
+		length := binary.BigEndian.Uint32(head[20:])
+		if length > maxCommand {
+			break // Torn or garbage length
+		}
+		body := make([]byte, recordHead-4+int(length))
+		if _, err := s.file.ReadAt(body, offset+4); err != nil {
+			break // Torn command
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(head[0:]) {
+			next := offset + recordHead + int64(length)
+			if info, err := s.file.Stat(); err == nil && next < info.Size() {
+				return nil, fmt.Errorf("%w: bad checksum at offset %d", errCorrupt, offset)
+			}
+			break // Torn last record
+		}
+		entry := Entry{
+			Term:  binary.BigEndian.Uint64(body[0:]),
+			Index: binary.BigEndian.Uint64(body[8:]),
+		}
+		if length > 0 {
+			entry.Command = body[recordHead-4:]
+		}
+		if entry.Index != uint64(len(entries)) {
+			return nil, fmt.Errorf("%w: entry %d at position %d", errCorrupt, entry.Index, len(entries))
+		}
+		entries = append(entries, entry)
+		s.offsets = append(s.offsets, offset)
+		offset += recordHead + int64(length)
+	}
+
+	if err := s.file.Truncate(offset); err != nil {
+		return nil, err
+	}
+	s.size = offset
+	return entries, nil
+}
+
+// append writes entries after the last one. They are durable once sync
+// returns.
+func (s *storage) append(entries ...Entry) error {
+	var buf []byte
+	offset := s.size
+	for _, entry := range entries {
+		start := len(buf)
+		buf = append(buf, make([]byte, recordHead)...)
+		binary.BigEndian.PutUint64(buf[start+4:], entry.Term)
+		binary.BigEndian.PutUint64(buf[start+12:], entry.Index)
+		binary.BigEndian.PutUint32(buf[start+20:], uint32(len(entry.Command)))
+		buf = append(buf, entry.Command...)
+		binary.BigEndian.PutUint32(buf[start:], crc32.ChecksumIEEE(buf[start+4:]))
+		s.offsets = append(s.offsets, offset+int64(start))
+	}
+	if _, err := s.file.WriteAt(buf, offset); err != nil {
+		return err
+	}
+	s.size += int64(len(buf))
+	s.synced = false
+	return nil
+}
+
+// truncate drops the entries from index on.
+func (s *storage) truncate(index uint64) error {
+	if index >= uint64(len(s.offsets)) {
+		return nil
+	}
+	offset := s.offsets[index]
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	s.offsets = s.offsets[:index]
+	s.size = offset
+	s.synced = false
+	return nil
+}
+
+// sync makes everything appended durable.
+func (s *storage) sync() error {
+	if s.synced {
+		return nil
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.synced = true
+	return nil
+}
+
+func (s *storage) close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,132 @@
+package consensus
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// Nodes talk net/rpc (gob over TCP). Each node serves the "Raft" service
+// and keeps one client connection per peer, dialled on first use and
+// dropped on any error or timeout, so a peer that restarts is reached again
+// on the next heartbeat.
+
+// RequestVoteArgs is the RPC request for voting.
+type RequestVoteArgs struct {
+	Term         uint64
+	CandidateID  int
+	LastLogIndex uint64
+	LastLogTerm  uint64
+}
+
+// RequestVoteReply is the RPC response for voting.
+type RequestVoteReply struct {
+	Term        uint64
+	VoteGranted bool
+}
+
+// AppendEntriesArgs is the RPC request for log replication (and heartbeat).
+type AppendEntriesArgs struct {
+	Term         uint64
+	LeaderID     int
+	PrevLogIndex uint64
+	PrevLogTerm  uint64
+	Entries      []Entry
+	LeaderCommit uint64
+
+	// How long the follower must refuse votes after receiving this: the
+	// leader's ElectionTimeoutMin (see RequestVote)
+	LeaseDuration time.Duration
+}
+
+// AppendEntriesReply is the RPC response for log replication.
+type AppendEntriesReply struct {
+	Term    uint64
+	Success bool
+
+	// On failure, where the leader should retry from: the follower's log
+	// length if it is too short, else the first index of the conflicting
+	// term, so a lagging follower is found in one round trip per term
+	// rather than one per entry
+	ConflictIndex uint64
+}
+
+// service exposes a node's RPC handlers to net/rpc.
+type service struct {
+	node *Node
+}
+
+func (s *service) RequestVote(args *RequestVoteArgs, reply *RequestVoteReply) error {
+	return s.node.handleRequestVote(args, reply)
+}
+
+func (s *service) AppendEntries(args *AppendEntriesArgs, reply *AppendEntriesReply) error {
+	return s.node.handleAppendEntries(args, reply)
+}
+
+// errTimeout fails a call the peer did not answer within RPCTimeout.
+var errTimeout = errors.New("consensus: rpc timed out")
+
+// peer is the client side of one other node.
+type peer struct {
+	addr    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// call invokes method on the peer and reports whether it answered.
+func (p *peer) call(method string, args, reply interface{}) bool {
+	client, err := p.dial()
+	if err != nil {
+		return false
+	}
+	call := client.Go("Raft."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-time.After(p.timeout):
+		err = errTimeout
+	}
+	if err != nil {
+		p.drop(client)
+		return false
+	}
+	return true
+}
+
+func (p *peer) dial() (*rpc.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		return p.client, nil
+	}
+	conn, err := net.DialTimeout("tcp", p.addr, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	p.client = rpc.NewClient(conn)
+	return p.client, nil
+}
+
+// drop closes client if it is still the peer's connection.
+func (p *peer) drop(client *rpc.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == client {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+func (p *peer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
@@ -0,0 +1,49 @@
+package disruptor
+
+import (
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Account Changes
+//
+// Opening an account, and depositing or withdrawing cash or shares, is a
+// ring buffer request like a risk limit change (see risk_limits.go), so a
+// withdrawal is checked in order with the orders spending the same cash.
+// The OnAccount hook applies the change to the clearing house and may
+// refuse it, e.g. a withdrawal of more than the account has free; only a
+// change it applies is logged, as an AccountEvent with the actor who asked
+// for it. The books are untouched, so replay skips it.
+
+// processAccount applies and logs an account change.
+func (p *EventProcessor) processAccount(req *OrderRequest, responseCh chan *OrderResponse) {
+	change := *req.Account
+	change.Event = events.Event{
+		Timestamp: p.now,
+		Type:      events.EventTypeAccount,
+	}
+
+	response := &OrderResponse{Success: true}
+	if p.onAccount != nil {
+		if err := p.onAccount(&change); err != nil {
+			response = &OrderResponse{Error: err}
+		}
+	}
+	if response.Success {
+		p.eventBatcher.QueueEvent(&change)
+	}
+
+	select {
+	case responseCh <- response:
+	default:
+		log.Printf("Warning: Failed to send account response for %s", change.AccountID)
+	}
+}
+
+// OnAccount registers a hook invoked on the processor goroutine with each
+// account change, which applies it or returns why not. It must not block.
+// Must be called before Start.
+func (p *EventProcessor) OnAccount(fn func(change *events.AccountEvent) error) {
+	p.onAccount = fn
+}
@@ -0,0 +1,101 @@
+package disruptor
+
+import (
+	"log"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+)
+
+// Call Auctions
+//
+// Starting and uncrossing an auction call (see matching/auction.go) are
+// ring buffer requests, so the call begins and ends at a definite point in
+// each symbol's order sequence. Both are logged - the uncross followed by
+// its fills, like a new order - and replayed on recovery.
+//
+// While a call is open the processor reports the indicative auction after
+// every request that may have changed the book, through the OnAuction hook.
+
+// processStartAuction puts a symbol into an auction call.
+func (p *EventProcessor) processStartAuction(req *OrderRequest, responseCh chan *OrderResponse) {
+	err := p.engine.StartAuction(req.Symbol, req.RefPrice)
+	if err == nil {
+		p.eventBatcher.QueueEvent(&events.AuctionStartedEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeAuctionStarted,
+			},
+			Symbol:   req.Symbol,
+			RefPrice: req.RefPrice,
+		})
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send auction start response for %s", req.Symbol)
+	}
+}
+
+// processUncross crosses a symbol's auction call and resumes continuous
+// trading.
+func (p *EventProcessor) processUncross(req *OrderRequest, responseCh chan *OrderResponse) {
+	result, err := p.engine.Uncross(req.Symbol)
+	if err == nil {
+		p.eventBatcher.QueueEvent(&events.AuctionUncrossedEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeAuctionUncrossed,
+			},
+			Symbol: req.Symbol,
+			Price:  result.Price,
+			Volume: result.Volume,
+		})
+		p.logFills(result.Fills)
+		p.reportTrades(result.Reports, result.Fills)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Auction: result, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send uncross response for %s", req.Symbol)
+	}
+}
+
+// OnAuction registers a hook invoked on the processor goroutine with a
+// symbol's indicative auction whenever a request may have changed its book
+// during an auction call. It must not block. Must be called before Start.
+func (p *EventProcessor) OnAuction(fn func(info matching.AuctionInfo)) {
+	p.onAuction = fn
+}
+
+// reportAuctions calls the auction hook for the symbols in a call that a
+// request may have changed: its own symbol, or every symbol in a call for
+// a request that spans symbols.
+func (p *EventProcessor) reportAuctions(req *OrderRequest) {
+	if p.onAuction == nil {
+		return
+	}
+	switch req.Type {
+	case RequestTypeStressProbe, RequestTypeHeartbeat, RequestTypeExportSymbol,
+		RequestTypeOpenOrders, RequestTypeOrderStatus, RequestTypePreview, RequestTypeSchedule,
+		RequestTypeSettle:
+		return // Reads only
+	}
+
+	symbols := []string{requestSymbol(req)}
+	if symbols[0] == "" {
+		symbols = symbols[:0]
+		for symbol := range p.engine.AuctionSymbols() {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+	}
+	for _, symbol := range symbols {
+		if info, calling := p.engine.IndicativeAuction(symbol); calling {
+			p.onAuction(info)
+		}
+	}
+}
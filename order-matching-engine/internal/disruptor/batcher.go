@@ -2,6 +2,7 @@ package disruptor
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/rishav/order-matching-engine/internal/events"
@@ -25,6 +26,12 @@ type EventBatcher struct {
 	flushInterval time.Duration
 	shutdownCh    chan struct{}
 	shutdownDone  chan struct{}
+
+	queued  uint64                  // Events accepted into the queue (processor goroutine only)
+	dropped atomic.Uint64           // Events dropped because the queue was full
+	onDrop  func(event interface{}) // Optional hook invoked on each drop
+
+	onAppendError func(event interface{}, err error) // Optional hook invoked on each failed append
 }
 
 // NewEventBatcher creates a new event batcher.
@@ -90,7 +97,7 @@ func (b *EventBatcher) batchLoop() {
 			for {
 				select {
 				case event := <-b.queue:
-					b.eventLog.Append(event)
+					b.append(event)
 				default:
 					return
 				}
@@ -102,15 +109,23 @@ func (b *EventBatcher) batchLoop() {
 // flush writes a batch of events to the event log.
 func (b *EventBatcher) flush(batch []interface{}) {
 	for _, event := range batch {
-		if _, err := b.eventLog.Append(event); err != nil {
-			log.Printf("ERROR: Failed to append event: %v", err)
-		}
+		b.append(event)
 	}
 
 	// Note: EventLog.Append already handles fsync if syncMode is enabled
 	// Batching reduces the number of fsync calls from N to 1 per batch
 }
 
+// append writes one event to the event log.
+func (b *EventBatcher) append(event interface{}) {
+	if _, err := b.eventLog.Append(event); err != nil {
+		log.Printf("ERROR: Failed to append event: %v", err)
+		if b.onAppendError != nil {
+			b.onAppendError(event, err)
+		}
+	}
+}
+
 // QueueEvent queues an event for batched writing.
 //
 // This method is non-blocking. If the queue is full, the event is dropped
@@ -119,12 +134,40 @@ func (b *EventBatcher) QueueEvent(event interface{}) {
 	select {
 	case b.queue <- event:
 		// Successfully queued
+		b.queued++
 	default:
 		// Queue full, drop event
+		b.dropped.Add(1)
 		log.Printf("WARNING: Event queue full, dropping event: %T", event)
+		if b.onDrop != nil {
+			b.onDrop(event)
+		}
 	}
 }
 
+// OnDrop registers a hook invoked (on the processor goroutine) whenever an
+// event is dropped. The hook must not block. Must be set before Start.
+func (b *EventBatcher) OnDrop(fn func(event interface{})) {
+	b.onDrop = fn
+}
+
+// OnAppendError registers a hook invoked (on the batcher goroutine) whenever
+// an event fails to be written to the event log. Must be set before Start.
+func (b *EventBatcher) OnAppendError(fn func(event interface{}, err error)) {
+	b.onAppendError = fn
+}
+
+// QueueDepth returns the events queued but not yet taken by the batch
+// loop.
+func (b *EventBatcher) QueueDepth() int {
+	return len(b.queue)
+}
+
+// Dropped returns the number of events dropped since startup.
+func (b *EventBatcher) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
 // Shutdown gracefully shuts down the batcher.
 //
 // It flushes all remaining events and waits for completion.
@@ -0,0 +1,55 @@
+package disruptor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Book Integrity Checks
+//
+// In debug mode the processor runs orderbook.Check on the books a request
+// may have changed after every request: its own symbol's, or every book
+// for a request that spans symbols. Check walks every order of a book, so
+// this is for tests, soak runs and chasing a bug, not production.
+//
+// A violation is reported through the hook once, when the book first
+// fails, and again only if it recovers and fails anew; the processor
+// carries on either way, so the broken state can still be inspected.
+
+// CheckBooks turns on book checks after every request, reporting each
+// book that fails to fn on the processor goroutine. It must not block.
+// Must be called before Start.
+func (p *EventProcessor) CheckBooks(fn func(symbol string, err error)) {
+	p.onBookCheck = fn
+	p.brokenBooks = make(map[string]bool)
+}
+
+// checkBooks checks the books a request may have changed.
+func (p *EventProcessor) checkBooks(req *OrderRequest) {
+	if p.onBookCheck == nil {
+		return
+	}
+	switch req.Type {
+	case RequestTypeStressProbe, RequestTypeHeartbeat, RequestTypeExportSymbol,
+		RequestTypeOpenOrders, RequestTypeOrderStatus, RequestTypePreview, RequestTypeSchedule,
+		RequestTypeSettle:
+		return // Reads only
+	}
+
+	symbols := []string{requestSymbol(req)}
+	if symbols[0] == "" {
+		symbols = p.engine.Symbols()
+		sort.Strings(symbols)
+	}
+	for _, symbol := range symbols {
+		book := p.engine.GetOrderBook(symbol)
+		if book == nil {
+			continue // Unknown, or released
+		}
+		err := book.Check()
+		if err != nil && !p.brokenBooks[symbol] {
+			p.onBookCheck(symbol, fmt.Errorf("after request type %d: %w", req.Type, err))
+		}
+		p.brokenBooks[symbol] = err != nil
+	}
+}
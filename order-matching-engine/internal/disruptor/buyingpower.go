@@ -0,0 +1,138 @@
+package disruptor
+
+import (
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// Buying Power Checks
+//
+// With buying power enabled, the processor keeps the clearing house's holds
+// (see settlement/buyingpower.go) in step with the books:
+//
+//	new order / basket  rejected unless the account can cover it; held once it rests
+//	replace             rejected unless the account can cover the increase
+//	fill                hold shrinks to what still rests
+//	cancel / release    hold released
+//
+// The check runs on the processor goroutine, after risk and before
+// matching, so no other order of the account can spend the same cash
+// between the check and the hold. Rejected orders are never logged.
+
+// EnableBuyingPower rejects orders accounts cannot cover from their
+// clearing house balances, and holds cash or shares for resting orders.
+// Holds for orders already resting (e.g. after recovery) are taken here.
+// Requires EnableClearing; must be called before Start.
+func (p *EventProcessor) EnableBuyingPower() {
+	p.buyingPower = true
+	for _, resting := range p.engine.RestingOrders() {
+		for i := range resting {
+			p.clearing.Hold(resting[i].ID, p.need(&resting[i]))
+		}
+	}
+}
+
+// need returns what an order needs set aside for its remaining quantity.
+func (p *EventProcessor) need(order *orders.Order) settlement.Need {
+	need := settlement.Need{AccountID: order.AccountID, Symbol: order.Symbol}
+	qty := order.RemainingQty()
+	if order.Side == orders.SideSell {
+		need.Shares = qty
+		return need
+	}
+
+	price := order.Price
+	if order.IsPegged() && order.PegLimit > 0 {
+		price = order.PegLimit
+	}
+	if price > 0 {
+		need.Cash = price * qty
+	} else {
+		need.Cash = p.sweepCost(order.Symbol, qty)
+	}
+	return need
+}
+
+// sweepCost returns what buying qty at market would cost, walking the asks
+// including reserve. Any quantity the book cannot fill is priced at the
+// last level.
+func (p *EventProcessor) sweepCost(symbol string, qty int64) int64 {
+	book := p.engine.GetOrderBook(symbol)
+	if book == nil {
+		return 0
+	}
+	var cost, last int64
+	book.ForEachLevel(orders.SideSell, func(level *orderbook.PriceLevel) bool {
+		take := level.TotalQty + level.HiddenQty
+		if take > qty {
+			take = qty
+		}
+		cost += take * level.Price
+		qty -= take
+		last = level.Price
+		return qty > 0
+	})
+	return cost + qty*last
+}
+
+// checkOrder returns why an account cannot cover an order, or "".
+func (p *EventProcessor) checkOrder(legs ...*orders.Order) string {
+	if !p.buyingPower {
+		return ""
+	}
+	needs := make([]settlement.Need, len(legs))
+	for i, leg := range legs {
+		needs[i] = p.need(leg)
+	}
+	if err := p.clearing.CanAfford(needs...); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkReplace returns an error wrapping settlement.ErrInsufficientBuyingPower
+// if an account cannot cover a replace. Only an increase over what the order
+// already holds is checked.
+func (p *EventProcessor) checkReplace(req *matching.ReplaceRequest) error {
+	if !p.buyingPower {
+		return nil
+	}
+	current := p.engine.GetOrder(req.Symbol, req.OrderID)
+	if current == nil {
+		return nil // The engine reports the unknown order
+	}
+	replaced := *current
+	replaced.Price = req.Price
+	replaced.Quantity = req.Quantity
+	need := p.need(&replaced)
+	held := p.clearing.Held(req.OrderID)
+	need.Cash -= held.Cash
+	need.Shares -= held.Shares
+	if need.Cash <= 0 && need.Shares <= 0 {
+		return nil
+	}
+	return p.clearing.CanAfford(need)
+}
+
+// syncHold holds what an order still needs while it rests, and releases
+// it once it no longer does.
+func (p *EventProcessor) syncHold(symbol string, orderID uint64) {
+	if !p.buyingPower {
+		return
+	}
+	if order := p.engine.GetOrder(symbol, orderID); order != nil {
+		p.clearing.Hold(orderID, p.need(order))
+	} else {
+		p.clearing.Release(orderID)
+	}
+}
+
+// syncFills updates the holds of both sides of each fill.
+func (p *EventProcessor) syncFills(fills []orders.Fill) {
+	for _, fill := range fills {
+		p.syncHold(fill.Symbol, fill.MakerOrderID)
+		p.syncHold(fill.Symbol, fill.TakerOrderID)
+	}
+}
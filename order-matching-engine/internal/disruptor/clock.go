@@ -0,0 +1,50 @@
+package disruptor
+
+// Request Time
+//
+// The processor reads its clock once per request, when it takes the
+// request from the ring buffer, and records the time on it (Time). Every
+// event the request logs and every fill it makes carries that one time:
+// the engine is told it before the request runs (Engine.SetTime) rather
+// than reading the wall clock mid-match. Orders take it too, whatever time
+// the front end that built them gave them, since replay rebuilds each order
+// with the time of the event that logged it.
+//
+//	ring buffer ──▶ stamp Time ──▶ journal ──▶ engine.SetTime(Time) ──▶ events, fills
+//
+// Time is journaled with the request, and a request that already has one
+// keeps it, so a request replayed from the journal - or applied by every
+// node of a cluster, stamped by the node that proposed it - logs exactly
+// the events it logged the first time. Replay from the event log does the
+// same with each event's logged time (see matching/replay.go).
+//
+// The clock is the wall clock unless SetClock injects another, e.g. a
+// counter for tests that compare runs.
+
+// SetClock replaces the clock the processor stamps requests with; it
+// returns nanoseconds since epoch and is only called on the processor
+// goroutine. Must be called before Start.
+func (p *EventProcessor) SetClock(clock func() int64) {
+	p.clock = clock
+}
+
+// stamp records the time on a request that has none yet.
+func (p *EventProcessor) stamp(req *OrderRequest) {
+	if req.Time == 0 {
+		req.Time = p.clock()
+	}
+}
+
+// begin sets the time of the request about to be processed, and of the
+// orders it carries.
+func (p *EventProcessor) begin(req *OrderRequest) {
+	p.stamp(req)
+	p.now = req.Time
+	p.engine.SetTime(req.Time)
+	if req.Order != nil {
+		req.Order.Timestamp = req.Time
+	}
+	for _, leg := range req.Legs {
+		leg.Timestamp = req.Time
+	}
+}
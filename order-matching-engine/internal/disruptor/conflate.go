@@ -0,0 +1,119 @@
+package disruptor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Cancel Conflation
+//
+// In a cancel storm (clients retrying cancels that have not been answered
+// yet, algos re-sending them on every tick) the same order is often
+// cancelled several times before the first cancel reaches the processor.
+// Every duplicate would claim a sequencer slot just to be told the order
+// is gone.
+//
+// PublishCancel keeps a table of cancels that are in the ring buffer but not
+// yet processed. A cancel for an order already in the table claims no slot:
+// its response channel is attached to the first cancel, and the processor
+// answers both with the first cancel's result.
+//
+//	Ring:   N1  C7  N2  N3        C7 (again) ─┐
+//	             ▲                            │ joins, no slot claimed
+//	             └────────────────────────────┘
+//
+// Once the first cancel has been processed it leaves the table, so a later
+// cancel for the same order is sequenced (and rejected) as usual.
+
+// cancelKey identifies the order a cancel targets, and the account a cancel
+// on behalf of one is limited to: only the same account's duplicates share
+// its result.
+type cancelKey struct {
+	symbol    string
+	orderID   uint64
+	accountID string
+}
+
+// pendingCancel is a cancel in the ring buffer and the duplicates waiting
+// on its result.
+type pendingCancel struct {
+	req       *OrderRequest
+	followers []chan *OrderResponse
+}
+
+// cancelConflator tracks in-flight cancels. Producers and the processor use
+// it concurrently; the zero value is ready to use.
+type cancelConflator struct {
+	mu        sync.Mutex
+	pending   map[cancelKey]*pendingCancel
+	conflated atomic.Uint64
+}
+
+// join attaches responseCh to an in-flight cancel for the same order and
+// returns true. Otherwise it records req as in flight and returns false;
+// the caller must then publish req or resolve it.
+func (c *cancelConflator) join(req *OrderRequest, responseCh chan *OrderResponse) bool {
+	key := cancelKey{symbol: req.Symbol, orderID: req.OrderID, accountID: req.AccountID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if first := c.pending[key]; first != nil {
+		first.followers = append(first.followers, responseCh)
+		c.conflated.Add(1)
+		return true
+	}
+	if c.pending == nil {
+		c.pending = make(map[cancelKey]*pendingCancel)
+	}
+	c.pending[key] = &pendingCancel{req: req}
+	return false
+}
+
+// resolve removes req from the in-flight table and answers its duplicates
+// with response. Cancels that were published without PublishCancel are not
+// in the table and are ignored.
+func (c *cancelConflator) resolve(req *OrderRequest, response *OrderResponse) {
+	key := cancelKey{symbol: req.Symbol, orderID: req.OrderID, accountID: req.AccountID}
+
+	c.mu.Lock()
+	first := c.pending[key]
+	if first == nil || first.req != req {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	for _, ch := range first.followers {
+		select {
+		case ch <- response:
+		default:
+		}
+	}
+}
+
+// PublishCancel publishes a cancel request, unless a cancel for the same
+// order is already in the ring buffer, in which case responseCh receives
+// that cancel's response instead.
+//
+// Returns ErrBufferFull if a slot could not be claimed. Duplicates that had
+// joined the cancel receive a response with ErrBufferFull as its Error.
+func (s *Sequencer) PublishCancel(request *OrderRequest, responseCh chan *OrderResponse) error {
+	if s.rb.cancels.join(request, responseCh) {
+		return nil
+	}
+
+	seq, err := s.Next()
+	if err != nil {
+		s.rb.cancels.resolve(request, &OrderResponse{Success: false, Error: err})
+		return err
+	}
+	s.Publish(seq, request, responseCh)
+	return nil
+}
+
+// ConflatedCancels returns the number of cancels answered from an earlier
+// cancel for the same order instead of being sequenced.
+func (rb *RingBuffer) ConflatedCancels() uint64 {
+	return rb.cancels.conflated.Load()
+}
@@ -0,0 +1,139 @@
+package disruptor
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/timerwheel"
+)
+
+// Dead Man's Switch
+//
+// Cancel-on-disconnect only helps when the connection actually drops. A
+// client that hangs (deadlocked strategy, frozen VM) keeps its connection
+// open while its quotes go stale. With a dead man's switch the session arms
+// a timeout and must keep sending heartbeats; if none arrives in time the
+// engine mass-cancels the session's resting orders.
+//
+// The registry of armed sessions lives on the processor goroutine and each
+// switch is a processor timer (see timers.go), so arming, heartbeats and
+// expiry are sequenced with orders:
+//
+//	session ─Heartbeat──▶ ring buffer ──▶ processor ──▶ reschedule timer
+//	ticker ──TimerTick──▶ ring buffer ──▶ processor ──▶ timer fires ──▶ mass cancel
+//
+// An order sequenced before an expiring tick is always cancelled by it, and
+// replaying the same sequence expires the same switches.
+//
+// Ticks are not replicated, so in a cluster (see OnTimerRequest) an expired
+// switch asks for a TripDeadMan request instead, which the leader commits:
+//
+//	ticker ──TimerTick──▶ processor ──▶ timer fires ──▶ TripDeadMan ──▶ Raft ──▶ every node's ring buffer
+//
+// It names the heartbeat the switch expired after, so a heartbeat committed
+// while it was on its way keeps the switch armed on every node. Until the
+// trip is applied the switch asks again every timeout, so one a failed
+// leader asked for is tripped by the next.
+
+// ErrNotArmed is returned for a heartbeat from a session with no switch armed.
+var ErrNotArmed = errors.New("dead man's switch not armed")
+
+// deadMan is one session's armed switch.
+type deadMan struct {
+	timeout   time.Duration
+	timer     timerwheel.TimerID
+	heartbeat uint64 // Heartbeat that last armed or refreshed it
+}
+
+// OnDeadManTrip registers a hook invoked on the processor goroutine after a
+// session's switch expires and its orders are cancelled. It must not block.
+// Must be called before Start.
+func (p *EventProcessor) OnDeadManTrip(fn func(sessionID string, cancelled []*orders.Order)) {
+	p.onDeadManTrip = fn
+}
+
+// processHeartbeat arms, refreshes or disarms a session's dead man's switch.
+func (p *EventProcessor) processHeartbeat(req *OrderRequest, responseCh chan *OrderResponse) {
+	var err error
+	p.heartbeats++
+	switch {
+	case p.timers == nil:
+		err = errTimersDisabled
+	case req.Timeout < 0:
+		p.disarmDeadMan(req.SessionID)
+	case req.Timeout > 0:
+		p.disarmDeadMan(req.SessionID)
+		p.deadMen[req.SessionID] = &deadMan{timeout: req.Timeout, heartbeat: p.heartbeats}
+		p.scheduleDeadMan(req.SessionID)
+	default:
+		dm := p.deadMen[req.SessionID]
+		if dm == nil {
+			err = ErrNotArmed
+			break
+		}
+		p.cancelTimer(dm.timer)
+		dm.heartbeat = p.heartbeats
+		p.scheduleDeadMan(req.SessionID)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send heartbeat response for session %s", req.SessionID)
+	}
+}
+
+// scheduleDeadMan starts a fresh timeout for an armed session.
+func (p *EventProcessor) scheduleDeadMan(sessionID string) {
+	dm := p.deadMen[sessionID]
+	dm.timer = p.scheduleAfter(dm.timeout, func() {
+		if p.onTimerRequest == nil {
+			p.tripDeadMan(sessionID)
+			return
+		}
+		p.onTimerRequest(&OrderRequest{Type: RequestTypeTripDeadMan, SessionID: sessionID, Heartbeat: dm.heartbeat})
+		p.scheduleDeadMan(sessionID) // Asks again unless tripped or refreshed by then
+	})
+}
+
+// processTripDeadMan trips a session's switch for a TripDeadMan request,
+// unless it was disarmed, or refreshed by a heartbeat sequenced after the
+// one the switch expired after.
+func (p *EventProcessor) processTripDeadMan(req *OrderRequest, responseCh chan *OrderResponse) {
+	var cancelled []*orders.Order
+	if dm := p.deadMen[req.SessionID]; dm != nil && dm.heartbeat == req.Heartbeat {
+		p.cancelTimer(dm.timer)
+		cancelled = p.tripDeadMan(req.SessionID)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: true, Cancelled: cancelled}:
+	default:
+		log.Printf("Warning: Failed to send dead man's switch trip response for session %s", req.SessionID)
+	}
+}
+
+// disarmDeadMan removes a session's switch, if armed.
+func (p *EventProcessor) disarmDeadMan(sessionID string) {
+	if dm := p.deadMen[sessionID]; dm != nil {
+		p.cancelTimer(dm.timer)
+		delete(p.deadMen, sessionID)
+	}
+}
+
+// tripDeadMan mass-cancels an expired session's orders, and returns them.
+// The switch is one shot: the session must re-arm it to be protected again.
+func (p *EventProcessor) tripDeadMan(sessionID string) []*orders.Order {
+	delete(p.deadMen, sessionID)
+
+	cancelled := p.engine.CancelSessionOrders(sessionID)
+	p.logCancels(cancelled, "dead man's switch")
+	p.reportCancels(cancelled, "dead man's switch")
+
+	if p.onDeadManTrip != nil {
+		p.onDeadManTrip(sessionID, cancelled)
+	}
+	return cancelled
+}
@@ -1,11 +1,19 @@
 package disruptor
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
 	"github.com/rishav/order-matching-engine/internal/orders"
 )
 
@@ -233,3 +241,670 @@ func BenchmarkSequencer_MultiProducer(b *testing.B) {
 		}
 	})
 }
+
+// TestStressMode drives probes through a real processor and verifies integrity
+func TestStressMode(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	rb := NewRingBuffer(Config{BufferSize: 64}) // Small buffer forces heavy slot reuse
+	seq := NewSequencer(rb)
+	processor := NewEventProcessor(rb, matching.NewEngine(), eventLog)
+	processor.Start()
+	defer processor.Shutdown()
+
+	report := RunStress(seq, StressConfig{
+		Producers:       8,
+		Duration:        200 * time.Millisecond,
+		ResponseTimeout: time.Second,
+	})
+
+	if !report.Passed() {
+		t.Fatalf("Stress run failed: %+v", report)
+	}
+	if report.Verified == 0 {
+		t.Errorf("Expected verified probes, got none")
+	}
+	if report.Verified != report.Published {
+		t.Errorf("Expected all %d published probes verified, got %d", report.Published, report.Verified)
+	}
+}
+
+// TestFairScheduler_RoundRobin tests that symbols are interleaved, per-symbol
+// order is kept, and a barrier runs only after everything before it
+func TestFairScheduler_RoundRobin(t *testing.T) {
+	s := newFairScheduler()
+
+	order := func(symbol string, id uint64) pendingRequest {
+		return pendingRequest{req: &OrderRequest{
+			Type:  RequestTypeNewOrder,
+			Order: &orders.Order{ID: id, Symbol: symbol},
+		}}
+	}
+
+	// Ring order: A1 A2 A3 B4 A5 C6, then a mass cancel barrier
+	for _, p := range []pendingRequest{order("A", 1), order("A", 2), order("A", 3), order("B", 4), order("A", 5), order("C", 6)} {
+		if !s.push(p) {
+			t.Fatalf("Single-symbol request treated as barrier")
+		}
+	}
+	if s.push(pendingRequest{req: &OrderRequest{Type: RequestTypeMassCancel}}) {
+		t.Fatalf("Mass cancel should be a barrier")
+	}
+
+	var got []uint64
+	var sawBarrier bool
+	for p, ok := s.next(); ok; p, ok = s.next() {
+		if p.req.Type == RequestTypeMassCancel {
+			sawBarrier = true
+			continue
+		}
+		if sawBarrier {
+			t.Fatalf("Order %d processed after the barrier", p.req.Order.ID)
+		}
+		got = append(got, p.req.Order.ID)
+	}
+
+	want := []uint64{1, 4, 6, 2, 3, 5}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+	if !sawBarrier {
+		t.Errorf("Barrier was never returned")
+	}
+}
+
+// TestFairProcessor_StressAndDepths runs stress probes (barriers) and orders
+// through a fair-mode processor and checks the per-symbol depth metrics
+func TestFairProcessor_StressAndDepths(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+
+	rb := NewRingBuffer(Config{BufferSize: 64})
+	seq := NewSequencer(rb)
+	processor := NewEventProcessor(rb, engine, eventLog)
+	processor.SetFairScheduling(16)
+	processor.Start()
+	defer processor.Shutdown()
+
+	var wg sync.WaitGroup
+	for _, symbol := range []string{"AAPL", "AAPL", "AAPL", "MSFT"} {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			responseCh := make(chan *OrderResponse, 1)
+			for i := 0; i < 200; i++ {
+				s, err := seq.Next()
+				if err != nil {
+					i--
+					continue
+				}
+				seq.Publish(s, &OrderRequest{
+					Type: RequestTypeNewOrder,
+					Order: &orders.Order{Symbol: symbol, Side: orders.SideBuy, Type: orders.OrderTypeLimit,
+						Price: 10000, Quantity: 1, AccountID: "T1"},
+				}, responseCh)
+				<-responseCh
+			}
+		}(symbol)
+	}
+
+	report := RunStress(seq, StressConfig{Producers: 4, Duration: 100 * time.Millisecond, ResponseTimeout: time.Second})
+	wg.Wait()
+
+	if !report.Passed() || report.Verified != report.Published {
+		t.Fatalf("Stress run failed in fair mode: %+v", report)
+	}
+
+	stats := rb.SymbolQueueStats()
+	if len(stats) != 2 || stats[0].Symbol != "AAPL" || stats[1].Symbol != "MSFT" {
+		t.Fatalf("Expected AAPL and MSFT queue stats, got %+v", stats)
+	}
+	if stats[0].Processed != 600 || stats[1].Processed != 200 {
+		t.Errorf("Expected 600/200 processed, got %d/%d", stats[0].Processed, stats[1].Processed)
+	}
+	for _, st := range stats {
+		if st.Depth != 0 || st.MaxDepth < 1 {
+			t.Errorf("%s: expected drained queue with a recorded high-water mark, got %+v", st.Symbol, st)
+		}
+	}
+}
+
+// submit publishes a request and waits for its response
+func submit(t *testing.T, seq *Sequencer, req *OrderRequest) *OrderResponse {
+	t.Helper()
+	s, err := seq.Next()
+	if err != nil {
+		t.Fatalf("Failed to claim sequence: %v", err)
+	}
+	responseCh := make(chan *OrderResponse, 1)
+	seq.Publish(s, req, responseCh)
+
+	select {
+	case resp := <-responseCh:
+		return resp
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for response to request type %d", req.Type)
+		return nil
+	}
+}
+
+// TestDeadManSwitch tests that an armed session's orders are cancelled once
+// heartbeats stop, and not while they keep coming
+func TestDeadManSwitch(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	rb := NewRingBuffer(Config{BufferSize: 1024})
+	seq := NewSequencer(rb)
+	processor := NewEventProcessor(rb, engine, eventLog)
+	processor.EnableTimers(5 * time.Millisecond)
+
+	tripped := make(chan []*orders.Order, 1)
+	processor.OnDeadManTrip(func(sessionID string, cancelled []*orders.Order) {
+		if sessionID == "S1" {
+			tripped <- cancelled
+		}
+	})
+	processor.Start()
+	defer processor.Shutdown()
+
+	// A refresh before arming is rejected
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1"}); resp.Error != ErrNotArmed {
+		t.Fatalf("Expected ErrNotArmed, got %v", resp.Error)
+	}
+
+	resp := submit(t, seq, &OrderRequest{
+		Type: RequestTypeNewOrder,
+		Order: &orders.Order{
+			Symbol:    "AAPL",
+			Side:      orders.SideBuy,
+			Type:      orders.OrderTypeLimit,
+			Price:     15000,
+			Quantity:  100,
+			SessionID: "S1",
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("Order failed: %v", resp.Error)
+	}
+
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1", Timeout: 50 * time.Millisecond}); !resp.Success {
+		t.Fatalf("Arm failed: %v", resp.Error)
+	}
+
+	// Heartbeats well inside the timeout keep the switch from tripping
+	for i := 0; i < 6; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1"}); !resp.Success {
+			t.Fatalf("Heartbeat failed: %v", resp.Error)
+		}
+		select {
+		case <-tripped:
+			t.Fatal("Switch tripped while heartbeats were arriving")
+		default:
+		}
+	}
+
+	// Silence: the switch trips and cancels the resting order
+	select {
+	case cancelled := <-tripped:
+		if len(cancelled) != 1 || cancelled[0].SessionID != "S1" {
+			t.Errorf("Expected the session's one order cancelled, got %d", len(cancelled))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Switch did not trip after heartbeats stopped")
+	}
+
+	// The switch is one-shot
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1"}); resp.Error != ErrNotArmed {
+		t.Errorf("Expected ErrNotArmed after trip, got %v", resp.Error)
+	}
+}
+
+// TestDeadManSwitch_TimerRequest tests that with OnTimerRequest an expired
+// switch asks for a TripDeadMan request instead of cancelling, and that the
+// request trips it unless a heartbeat was sequenced since
+func TestDeadManSwitch_TimerRequest(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	rb := NewRingBuffer(Config{BufferSize: 1024})
+	seq := NewSequencer(rb)
+	processor := NewEventProcessor(rb, engine, eventLog)
+	processor.EnableTimers(5 * time.Millisecond)
+
+	asked := make(chan *OrderRequest, 16)
+	processor.OnTimerRequest(func(req *OrderRequest) {
+		select {
+		case asked <- req:
+		default: // Must not block
+		}
+	})
+	processor.Start()
+	defer processor.Shutdown()
+
+	resp := submit(t, seq, &OrderRequest{
+		Type: RequestTypeNewOrder,
+		Order: &orders.Order{
+			Symbol:    "AAPL",
+			Side:      orders.SideBuy,
+			Type:      orders.OrderTypeLimit,
+			Price:     15000,
+			Quantity:  100,
+			SessionID: "S1",
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("Order failed: %v", resp.Error)
+	}
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1", Timeout: 20 * time.Millisecond}); !resp.Success {
+		t.Fatalf("Arm failed: %v", resp.Error)
+	}
+
+	var trip *OrderRequest
+	select {
+	case trip = <-asked:
+	case <-time.After(time.Second):
+		t.Fatal("Expired switch asked for no request")
+	}
+	if trip.Type != RequestTypeTripDeadMan || trip.SessionID != "S1" {
+		t.Fatalf("Expected a TripDeadMan request for S1, got %+v", trip)
+	}
+	// A heartbeat sequenced since the expiry saves the switch
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1"}); !resp.Success {
+		t.Fatalf("Heartbeat failed: %v", resp.Error)
+	}
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeTripDeadMan, SessionID: "S1", Heartbeat: trip.Heartbeat}); !resp.Success || len(resp.Cancelled) != 0 {
+		t.Fatalf("Expected a stale trip to cancel nothing, got %+v", resp)
+	}
+
+	// Otherwise the trip cancels the session's orders, once
+	for stale := trip.Heartbeat; trip.Heartbeat == stale; {
+		select {
+		case trip = <-asked:
+		case <-time.After(time.Second):
+			t.Fatal("Refreshed switch asked for no request")
+		}
+	}
+	resp = submit(t, seq, &OrderRequest{Type: RequestTypeTripDeadMan, SessionID: "S1", Heartbeat: trip.Heartbeat})
+	if !resp.Success || len(resp.Cancelled) != 1 || resp.Cancelled[0].SessionID != "S1" {
+		t.Fatalf("Expected the session's one order cancelled, got %+v", resp)
+	}
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeHeartbeat, SessionID: "S1"}); resp.Error != ErrNotArmed {
+		t.Errorf("Expected ErrNotArmed after trip, got %v", resp.Error)
+	}
+}
+
+// TestScheduledCallback tests that a Schedule request runs its callback on
+// the processor's timers once its delay has elapsed, and again every Repeat
+func TestScheduledCallback(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	rb := NewRingBuffer(Config{BufferSize: 1024})
+	seq := NewSequencer(rb)
+	processor := NewEventProcessor(rb, matching.NewEngine(), eventLog)
+	processor.EnableTimers(5 * time.Millisecond)
+	processor.Start()
+	defer processor.Shutdown()
+
+	fired := make(chan time.Time, 1)
+	scheduled := time.Now()
+	resp := submit(t, seq, &OrderRequest{
+		Type:    RequestTypeSchedule,
+		Symbol:  "AAPL",
+		Timeout: 50 * time.Millisecond,
+		OnTimer: func() { fired <- time.Now() },
+	})
+	if !resp.Success {
+		t.Fatalf("Schedule failed: %v", resp.Error)
+	}
+	select {
+	case at := <-fired:
+		// Measured from the last tick, which may be a tick or so earlier
+		if at.Sub(scheduled) < 25*time.Millisecond {
+			t.Errorf("Callback ran after %v, well before its delay", at.Sub(scheduled))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Callback did not run")
+	}
+
+	// A repeating callback runs at the next tick, then every Repeat
+	repeats := make(chan struct{}, 1)
+	resp = submit(t, seq, &OrderRequest{
+		Type:   RequestTypeSchedule,
+		Repeat: 10 * time.Millisecond,
+		OnTimer: func() {
+			select {
+			case repeats <- struct{}{}:
+			default: // Callbacks must not block
+			}
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("Schedule failed: %v", resp.Error)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-repeats:
+		case <-time.After(time.Second):
+			t.Fatalf("Repeating callback ran %d times, expected at least 3", i)
+		}
+	}
+
+	if resp := submit(t, seq, &OrderRequest{Type: RequestTypeSchedule, Timeout: time.Millisecond}); resp.Success {
+		t.Error("Expected a Schedule request without a callback refused")
+	}
+}
+
+// TestCancelConflation tests that duplicate cancels in flight share one slot
+// and one result, unless on behalf of another account, and that a cancel
+// after the first is answered is sequenced
+func TestCancelConflation(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(t.TempDir(), "events.log"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	order := &orders.Order{
+		Symbol:   "AAPL",
+		Side:     orders.SideBuy,
+		Type:     orders.OrderTypeLimit,
+		Price:    15000,
+		Quantity: 100,
+	}
+	engine.ProcessOrder(order)
+
+	rb := NewRingBuffer(Config{BufferSize: 1024})
+	seq := NewSequencer(rb)
+
+	// Published before the processor starts, so all are in flight together
+	responseChs := make([]chan *OrderResponse, 3)
+	for i := range responseChs {
+		responseChs[i] = make(chan *OrderResponse, 1)
+		req := &OrderRequest{Type: RequestTypeCancelOrder, Symbol: "AAPL", OrderID: order.ID}
+		if err := seq.PublishCancel(req, responseChs[i]); err != nil {
+			t.Fatalf("PublishCancel failed: %v", err)
+		}
+	}
+	// Another account's cancel of the order does not share the result
+	otherCh := make(chan *OrderResponse, 1)
+	if err := seq.PublishCancel(&OrderRequest{Type: RequestTypeCancelOrder, Symbol: "AAPL", OrderID: order.ID, AccountID: "T2"}, otherCh); err != nil {
+		t.Fatalf("PublishCancel failed: %v", err)
+	}
+	if claimed := atomic.LoadUint64(&rb.cursor); claimed != 2 {
+		t.Errorf("Expected 2 slots claimed, got %d", claimed)
+	}
+	if n := rb.ConflatedCancels(); n != 2 {
+		t.Errorf("Expected 2 conflated cancels, got %d", n)
+	}
+
+	processor := NewEventProcessor(rb, engine, eventLog)
+	processor.Start()
+	defer processor.Shutdown()
+
+	for i, ch := range responseChs {
+		select {
+		case resp := <-ch:
+			if !resp.Success || resp.Order == nil || resp.Order.ID != order.ID {
+				t.Errorf("Cancel %d: expected the first cancel's result, got %+v", i, resp)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Cancel %d: timed out", i)
+		}
+	}
+	select {
+	case resp := <-otherCh:
+		if resp.Success {
+			t.Error("Expected another account's cancel to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Another account's cancel timed out")
+	}
+
+	// The first cancel has been answered: a new one is sequenced and rejected
+	responseCh := make(chan *OrderResponse, 1)
+	if err := seq.PublishCancel(&OrderRequest{Type: RequestTypeCancelOrder, Symbol: "AAPL", OrderID: order.ID}, responseCh); err != nil {
+		t.Fatalf("PublishCancel failed: %v", err)
+	}
+	select {
+	case resp := <-responseCh:
+		if resp.Success {
+			t.Error("Expected a cancel of a cancelled order to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the late cancel")
+	}
+	if claimed := atomic.LoadUint64(&rb.cursor); claimed != 3 {
+		t.Errorf("Expected the late cancel to claim a slot, got cursor %d", claimed)
+	}
+}
+
+// TestWaitStrategies tests that every wait strategy drains a backlog in
+// order, wakes for a request after idling, and shuts down while waiting
+func TestWaitStrategies(t *testing.T) {
+	for _, strategy := range WaitStrategies {
+		t.Run(strategy.String(), func(t *testing.T) {
+			eventLog, err := events.NewEventLog(events.EventLogConfig{
+				Path: filepath.Join(t.TempDir(), "events.log"),
+			})
+			if err != nil {
+				t.Fatalf("Failed to create event log: %v", err)
+			}
+			defer eventLog.Close()
+
+			engine := matching.NewEngine()
+			engine.AddSymbol("AAPL")
+			rb := NewRingBuffer(Config{BufferSize: 64, WaitStrategy: strategy})
+			seq := NewSequencer(rb)
+			processor := NewEventProcessor(rb, engine, eventLog)
+
+			// A backlog published before the processor starts is drained
+			// as one batch, in sequence
+			const backlog = 40
+			responseCh := make(chan *OrderResponse, backlog)
+			for i := 0; i < backlog; i++ {
+				s, err := seq.Next()
+				if err != nil {
+					t.Fatalf("Failed to claim sequence: %v", err)
+				}
+				seq.Publish(s, &OrderRequest{
+					Type: RequestTypeNewOrder,
+					Order: &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit,
+						Price: int64(10000 + i), Quantity: 1},
+				}, responseCh)
+			}
+			processor.Start()
+			for i := 0; i < backlog; i++ {
+				select {
+				case resp := <-responseCh:
+					if resp.Order == nil || resp.Order.Price != int64(10000+i) {
+						t.Fatalf("Response %d: expected the order priced %d, got %+v", i, 10000+i, resp)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("Timed out waiting for response %d", i)
+				}
+			}
+			if released := atomic.LoadUint64(&rb.gatingSequence); released != backlog {
+				t.Errorf("Expected all %d slots released, got %d", backlog, released)
+			}
+
+			// Long enough for the sleeping strategy to get to sleeping
+			time.Sleep(20 * time.Millisecond)
+			if resp := submit(t, seq, &OrderRequest{Type: RequestTypeOpenOrders, AccountID: "T1"}); !resp.Success {
+				t.Errorf("Expected the request after idling answered, got %+v", resp)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				processor.Shutdown()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out shutting down an idle processor")
+			}
+		})
+	}
+}
+
+// startWaitBenchmark starts a processor with a wait strategy for a
+// benchmark, returning its sequencer
+func startWaitBenchmark(b *testing.B, strategy WaitStrategy) *Sequencer {
+	if strategy == WaitBusySpin && runtime.GOMAXPROCS(0) < 2 {
+		b.Skip("busy-spin needs a core of its own")
+	}
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path: filepath.Join(b.TempDir(), "events.log"),
+	})
+	if err != nil {
+		b.Fatalf("Failed to create event log: %v", err)
+	}
+	rb := NewRingBuffer(Config{BufferSize: 8192, WaitStrategy: strategy})
+	processor := NewEventProcessor(rb, matching.NewEngine(), eventLog)
+	processor.Start()
+	b.Cleanup(func() {
+		processor.Shutdown()
+		eventLog.Close()
+	})
+	return NewSequencer(rb)
+}
+
+// processCPU returns the CPU time the process has used, user and system,
+// from /proc (Linux only; false elsewhere)
+func processCPU() (time.Duration, bool) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, false
+	}
+	// Fields after the command, which is in parentheses and may hold spaces
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	if len(fields) < 13 {
+		return 0, false
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return time.Duration(utime+stime) * 10 * time.Millisecond, true // USER_HZ = 100
+}
+
+// BenchmarkWaitStrategy_RoundTrip measures one producer's round trip
+// through the processor under each wait strategy: back to back, and paced
+// with an idle gap before each request, as a quiet market sees them.
+// latency-ns/op is the round trip alone; cpu-ns/op is the process's CPU
+// per request, which when paced is mostly the processor waiting.
+//
+//	go test ./internal/disruptor -run XXX -bench WaitStrategy -benchtime 20000x
+func BenchmarkWaitStrategy_RoundTrip(b *testing.B) {
+	const gap = 100 * time.Microsecond
+
+	for _, paced := range []bool{false, true} {
+		for _, strategy := range WaitStrategies {
+			name := strategy.String()
+			if paced {
+				name += "/paced"
+			}
+			b.Run(name, func(b *testing.B) {
+				seq := startWaitBenchmark(b, strategy)
+				request := &OrderRequest{Type: RequestTypeOpenOrders, AccountID: "T1"}
+				responseCh := make(chan *OrderResponse, 1)
+				var latency time.Duration
+
+				cpuStart, cpuOK := processCPU()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if paced {
+						time.Sleep(gap)
+					}
+					start := time.Now()
+					s, err := seq.Next()
+					if err != nil {
+						b.Fatalf("Failed to claim sequence: %v", err)
+					}
+					seq.Publish(s, request, responseCh)
+					<-responseCh
+					latency += time.Since(start)
+				}
+				b.StopTimer()
+
+				b.ReportMetric(float64(latency.Nanoseconds())/float64(b.N), "latency-ns/op")
+				if cpuEnd, ok := processCPU(); cpuOK && ok {
+					b.ReportMetric(float64((cpuEnd-cpuStart).Nanoseconds())/float64(b.N), "cpu-ns/op")
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkWaitStrategy_Throughput measures requests per second with many
+// producers, where batch draining lets one wake-up serve a burst
+func BenchmarkWaitStrategy_Throughput(b *testing.B) {
+	for _, strategy := range WaitStrategies {
+		b.Run(strategy.String(), func(b *testing.B) {
+			seq := startWaitBenchmark(b, strategy)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				request := &OrderRequest{Type: RequestTypeOpenOrders, AccountID: "T1"}
+				responseCh := make(chan *OrderResponse, 1)
+				for pb.Next() {
+					s, err := seq.Next()
+					if err != nil {
+						continue // Skip on backpressure
+					}
+					seq.Publish(s, request, responseCh)
+					<-responseCh
+				}
+			})
+		})
+	}
+}
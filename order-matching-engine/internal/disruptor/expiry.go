@@ -0,0 +1,36 @@
+package disruptor
+
+import (
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// DAY Order Expiry
+//
+// DAY orders expire when their market closes. The close is a ring buffer
+// request, naming the market's symbols and the time it closed, so the
+// orders expire at a definite point in each symbol's order sequence. Each
+// expired order is logged as a cancel, which is how replay removes it, and
+// reported as EXPIRED.
+
+// expiredReason is logged as the cancel reason of an expired DAY order.
+const expiredReason = "day order expired"
+
+// processExpireOrders expires the DAY orders entered before the close in
+// the symbols of a market that closed.
+func (p *EventProcessor) processExpireOrders(req *OrderRequest, responseCh chan *OrderResponse) {
+	expired := p.engine.ExpireDayOrders(req.Symbols, req.Now)
+	p.logCancels(expired, expiredReason)
+	if p.onExecution != nil {
+		for _, order := range expired {
+			p.report(orders.NewOrderReport(order, orders.ExecTypeExpired, expiredReason))
+		}
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: true, Cancelled: expired}:
+	default:
+		log.Printf("Warning: Failed to send DAY order expiry response")
+	}
+}
@@ -0,0 +1,181 @@
+package disruptor
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Per-Symbol Fair Scheduling
+//
+// The ring buffer is strictly FIFO across all symbols. When one symbol is
+// hyperactive (an opening burst, a runaway algo), every other symbol sharing
+// the processor waits behind its whole backlog.
+//
+// In fair mode the processor drains up to a batch of ready slots into
+// per-symbol queues and serves them round-robin, one request per symbol per
+// turn. Order within a symbol is preserved, so each book still sees its
+// requests in sequence; only the interleaving between symbols changes.
+//
+// Requests that span symbols (baskets, mass cancels, stress probes) are
+// barriers: draining stops at a barrier, and it is processed only after
+// everything drained before it, so it observes every earlier request.
+//
+//	Ring:   A1 A2 A3 A4 B1 A5 C1        Fair order:  A1 B1 C1 A2 A3 A4 A5
+
+// pendingRequest is a request copied out of its ring buffer slot.
+type pendingRequest struct {
+	req        *OrderRequest
+	responseCh chan *OrderResponse
+	seq        uint64
+}
+
+// requestSymbol returns the one symbol a request touches, or "" if it spans
+// symbols and must be scheduled as a barrier.
+func requestSymbol(req *OrderRequest) string {
+	switch req.Type {
+	case RequestTypeNewOrder, RequestTypePreview:
+		if req.Order != nil {
+			return req.Order.Symbol
+		}
+	case RequestTypeCancelOrder, RequestTypeStartAuction, RequestTypeUncross, RequestTypeSchedule:
+		return req.Symbol
+	case RequestTypeModifyOrder:
+		if req.Replace != nil {
+			return req.Replace.Symbol
+		}
+	case RequestTypeOpenOrders:
+		return req.Symbol // All symbols: a barrier
+	}
+	return ""
+}
+
+// fairScheduler holds drained requests in per-symbol FIFO queues.
+// Only used by the processor goroutine.
+type fairScheduler struct {
+	queues  map[string][]pendingRequest
+	ready   []string // Symbols with queued requests, in round-robin order
+	barrier *pendingRequest
+}
+
+func newFairScheduler() *fairScheduler {
+	return &fairScheduler{
+		queues: make(map[string][]pendingRequest),
+	}
+}
+
+// push queues a drained request. Returns false if it was a barrier, in which
+// case draining must stop until the scheduler is empty.
+func (f *fairScheduler) push(p pendingRequest) bool {
+	symbol := requestSymbol(p.req)
+	if symbol == "" {
+		f.barrier = &p
+		return false
+	}
+
+	if len(f.queues[symbol]) == 0 {
+		f.ready = append(f.ready, symbol)
+	}
+	f.queues[symbol] = append(f.queues[symbol], p)
+	return true
+}
+
+// next returns the next request to process: the head of the next symbol's
+// queue in round-robin order, then the barrier once all queues are empty.
+func (f *fairScheduler) next() (pendingRequest, bool) {
+	if len(f.ready) == 0 {
+		if f.barrier != nil {
+			p := *f.barrier
+			f.barrier = nil
+			return p, true
+		}
+		return pendingRequest{}, false
+	}
+
+	symbol := f.ready[0]
+	f.ready = f.ready[1:]
+
+	queue := f.queues[symbol]
+	p := queue[0]
+	queue[0] = pendingRequest{} // Drop references for GC
+	if len(queue) > 1 {
+		f.queues[symbol] = queue[1:]
+		f.ready = append(f.ready, symbol) // Back of the line
+	} else {
+		delete(f.queues, symbol)
+	}
+	return p, true
+}
+
+// SymbolQueueStats reports the request backlog of one symbol.
+type SymbolQueueStats struct {
+	Symbol    string `json:"symbol"`
+	Depth     int64  `json:"depth"`     // Published but not yet processed
+	MaxDepth  int64  `json:"max_depth"` // High-water mark of Depth
+	Processed uint64 `json:"processed"` // Requests processed since startup
+}
+
+// symbolCounter holds the live counters for one symbol.
+type symbolCounter struct {
+	depth     atomic.Int64
+	maxDepth  atomic.Int64
+	processed atomic.Uint64
+}
+
+// symbolDepths tracks per-symbol queue depth from publish to processing.
+// Producers and the processor update it concurrently without locks.
+type symbolDepths struct {
+	counters sync.Map // symbol -> *symbolCounter
+}
+
+func (d *symbolDepths) counter(symbol string) *symbolCounter {
+	if c, ok := d.counters.Load(symbol); ok {
+		return c.(*symbolCounter)
+	}
+	c, _ := d.counters.LoadOrStore(symbol, &symbolCounter{})
+	return c.(*symbolCounter)
+}
+
+// published records a request entering the ring buffer.
+func (d *symbolDepths) published(req *OrderRequest) {
+	symbol := requestSymbol(req)
+	if symbol == "" {
+		return
+	}
+	c := d.counter(symbol)
+	depth := c.depth.Add(1)
+	for {
+		high := c.maxDepth.Load()
+		if depth <= high || c.maxDepth.CompareAndSwap(high, depth) {
+			return
+		}
+	}
+}
+
+// processed records a request leaving the processor.
+func (d *symbolDepths) processed(req *OrderRequest) {
+	symbol := requestSymbol(req)
+	if symbol == "" {
+		return
+	}
+	c := d.counter(symbol)
+	c.depth.Add(-1)
+	c.processed.Add(1)
+}
+
+// snapshot returns the current counters, sorted by symbol.
+func (d *symbolDepths) snapshot() []SymbolQueueStats {
+	var stats []SymbolQueueStats
+	d.counters.Range(func(key, value interface{}) bool {
+		c := value.(*symbolCounter)
+		stats = append(stats, SymbolQueueStats{
+			Symbol:    key.(string),
+			Depth:     c.depth.Load(),
+			MaxDepth:  c.maxDepth.Load(),
+			Processed: c.processed.Load(),
+		})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Symbol < stats[j].Symbol })
+	return stats
+}
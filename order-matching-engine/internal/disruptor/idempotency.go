@@ -0,0 +1,127 @@
+package disruptor
+
+import (
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Idempotent Order Entry
+//
+// A client whose POST /order timed out can't tell whether the order was
+// sequenced. It can resend it with the same client order ID: the processor
+// remembers the response to each accepted order that had one, per account,
+// and answers a duplicate within the window with that response (flagged
+// Duplicate) instead of creating a second order.
+//
+//	first:     T1 "abc" ──▶ engine ──▶ order 42 ──▶ remembered[T1]["abc"]
+//	duplicate: T1 "abc" ──▶ remembered? ──▶ order 42's response, Duplicate
+//
+// Only accepted orders are remembered: a rejected one created nothing, so
+// resending it is checked afresh. Past the window the ID is free again and
+// a reused ID starts a new order, as before.
+//
+// The window is measured between the orders' own timestamps (taken when the
+// request arrived), not on the processor's clock, so every node of a
+// cluster gives the same answer. After a restart the remembered responses
+// are gone, but the engine still indexes client order IDs (see
+// matching/history.go): a duplicate of an order it knows is answered with
+// that order's current state, without the original fills.
+
+// DefaultIdempotencyWindow is how long a client order ID is remembered.
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+// seenKey identifies an order by the account's own ID for it.
+type seenKey struct {
+	accountID     string
+	clientOrderID string
+}
+
+// seenOrder is the response to an accepted order, kept for duplicates.
+type seenOrder struct {
+	key       seenKey
+	timestamp int64
+	order     orders.Order // As it was when first answered
+	result    orders.ExecutionResult
+	matched   int64
+}
+
+// idempotency remembers recent client order IDs. Only used by the
+// processor goroutine.
+type idempotency struct {
+	window int64 // Nanoseconds; 0 = off
+	seen   map[string]map[string]*seenOrder
+	fifo   []*seenOrder // Oldest first, for expiry
+}
+
+// SetIdempotencyWindow sets how long client order IDs are remembered for
+// duplicate detection (0 = off). Must be called before Start.
+func (p *EventProcessor) SetIdempotencyWindow(window time.Duration) {
+	p.idempotency = idempotency{window: int64(window), seen: make(map[string]map[string]*seenOrder)}
+}
+
+// duplicate returns the response to an earlier order with the same account
+// and client order ID, if there is one within the window.
+func (p *EventProcessor) duplicate(order *orders.Order) (*OrderResponse, bool) {
+	d := &p.idempotency
+	if d.window <= 0 || order.ClientOrderID == "" {
+		return nil, false
+	}
+	d.expire(order.Timestamp)
+
+	if seen, ok := d.seen[order.AccountID][order.ClientOrderID]; ok {
+		original, result := seen.order, seen.result
+		result.Order = &original
+		return &OrderResponse{Success: true, Result: &result, Order: &original, Matched: seen.matched, Duplicate: true}, true
+	}
+
+	// Not remembered, e.g. since a restart: the engine may still know it
+	known, ok := p.engine.LookupClientOrder(order.AccountID, order.ClientOrderID)
+	if !ok || order.Timestamp-known.Timestamp > d.window {
+		return nil, false
+	}
+	return &OrderResponse{
+		Success:   true,
+		Result:    &orders.ExecutionResult{Order: &known, Accepted: true, Fills: make([]orders.Fill, 0)},
+		Order:     &known,
+		Matched:   orders.Now(),
+		Duplicate: true,
+	}, true
+}
+
+// remember keeps the response to an accepted order for its duplicates.
+func (p *EventProcessor) remember(order *orders.Order, result *orders.ExecutionResult, matched int64) {
+	d := &p.idempotency
+	if d.window <= 0 || order.ClientOrderID == "" || !result.Accepted {
+		return
+	}
+	seen := &seenOrder{
+		key:       seenKey{order.AccountID, order.ClientOrderID},
+		timestamp: order.Timestamp,
+		order:     *order,
+		result:    *result,
+		matched:   matched,
+	}
+	account, ok := d.seen[order.AccountID]
+	if !ok {
+		account = make(map[string]*seenOrder)
+		d.seen[order.AccountID] = account
+	}
+	account[order.ClientOrderID] = seen
+	d.fifo = append(d.fifo, seen)
+}
+
+// expire forgets orders older than the window as of now.
+func (d *idempotency) expire(now int64) {
+	for len(d.fifo) > 0 && now-d.fifo[0].timestamp > d.window {
+		oldest := d.fifo[0]
+		if account := d.seen[oldest.key.accountID]; account[oldest.key.clientOrderID] == oldest {
+			delete(account, oldest.key.clientOrderID)
+			if len(account) == 0 {
+				delete(d.seen, oldest.key.accountID)
+			}
+		}
+		d.fifo[0] = nil
+		d.fifo = d.fifo[1:]
+	}
+}
@@ -0,0 +1,161 @@
+package disruptor
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/wal"
+)
+
+// Request Journal
+//
+// The event log records what matching did, written behind the processor by
+// the event batcher, so a request answered just before a crash may have no
+// events on disk, and a request still waiting in the ring buffer leaves no
+// trace at all. With a request journal (see internal/wal), each batch the
+// processor takes from the ring buffer is written to it, with one fsync,
+// before any request of the batch is applied:
+//
+//	ring buffer ──batch──▶ journal (append + fsync) ──▶ engine ──▶ event log
+//	                                                       checkpoint ──┘
+//
+// In fair mode a round is journaled in the order it will be processed, not
+// the order it was published in, so the journal is the exact sequence the
+// engine saw. After each batch the processor logs a JournalCheckpointEvent
+// with the journal sequence number of the last request it applied: every
+// event of that request and those before it precedes the checkpoint.
+//
+// Recovery drops the events after the last checkpoint (a batch whose events
+// were only partly written) and processes the journaled requests after it
+// again. Matching is deterministic given the same books and ID counters, so
+// they produce the same orders and fills. Snapshots record the journal
+// position too; once one is written, the journal is compacted up to it.
+//
+// Only requests that change state are journaled (see ChangesState). Timer
+// ticks are not: a dead man's switch or auction timer that fired in the
+// batch lost to a crash fires again from the clock after restart.
+//
+// A batch that fails to journal is not applied: its journaled requests are
+// answered with ErrNotJournaled.
+
+// ErrNotJournaled answers a request that could not be written to the
+// request journal, and so was not applied.
+var ErrNotJournaled = errors.New("request journal unavailable")
+
+// ChangesState reports whether requests of type t change engine state, and
+// so are journaled (and, in a cluster, committed through Raft). Reads,
+// previews, stress probes and timer ticks do not.
+func (t RequestType) ChangesState() bool {
+	switch t {
+	case RequestTypeOpenOrders, RequestTypeOrderStatus, RequestTypePreview,
+		RequestTypeStressProbe, RequestTypeTimerTick, RequestTypeSchedule:
+		return false
+	}
+	return true
+}
+
+// EnableJournal writes every batch of requests to journal before applying
+// it. applied is the journal sequence number of the last request the
+// engine's recovered state reflects. Must be called before Start.
+func (p *EventProcessor) EnableJournal(journal *wal.Log, applied uint64) {
+	p.journal = journal
+	p.journaled = applied
+	p.checkpointed = applied
+}
+
+// JournalSeq returns the journal sequence number of the last request the
+// processor applied. Processor goroutine only (e.g. from hooks), or after
+// Shutdown.
+func (p *EventProcessor) JournalSeq() uint64 {
+	return p.journaled
+}
+
+// journalBatch writes the batch's requests that change state to the
+// journal, in order, setting their JournalSeq. Requests replayed from the
+// journal already have one and are not written again. If the write fails,
+// those requests are marked so processRequest refuses them.
+func (p *EventProcessor) journalBatch(batch []pendingRequest) {
+	records := p.records[:0]
+	written := p.written[:0]
+	for _, pending := range batch {
+		req := pending.req
+		p.stamp(req)
+		if req.JournalSeq != 0 || !req.Type.ChangesState() {
+			continue
+		}
+		record, err := EncodeRequest(req)
+		if err != nil {
+			req.journalErr = fmt.Errorf("%w: %v", ErrNotJournaled, err)
+			continue
+		}
+		records = append(records, record)
+		written = append(written, req)
+	}
+	p.records, p.written = records[:0], written[:0]
+	if len(records) == 0 {
+		return
+	}
+
+	first, err := p.journal.Append(records)
+	if err != nil {
+		log.Printf("ERROR: Failed to journal %d requests: %v", len(records), err)
+	}
+	for i, req := range written {
+		if err != nil {
+			req.journalErr = fmt.Errorf("%w: %v", ErrNotJournaled, err)
+			continue
+		}
+		req.JournalSeq = first + uint64(i)
+	}
+}
+
+// refuseUnjournaled answers a request the journal could not take.
+func (p *EventProcessor) refuseUnjournaled(req *OrderRequest, responseCh chan *OrderResponse) {
+	response := &OrderResponse{Success: false, Error: req.journalErr}
+	if req.Type == RequestTypeCancelOrder {
+		p.rb.cancels.resolve(req, response)
+	}
+	select {
+	case responseCh <- response:
+	default:
+	}
+}
+
+// checkpoint logs a JournalCheckpointEvent once the processor has applied
+// requests since the last one. Runs on the processor goroutine after each
+// batch.
+func (p *EventProcessor) checkpoint() {
+	if p.journaled == p.checkpointed {
+		return
+	}
+	p.eventBatcher.QueueEvent(&events.JournalCheckpointEvent{
+		Event: events.Event{
+			Timestamp: p.clock(),
+			Type:      events.EventTypeJournalCheckpoint,
+		},
+		Through: p.journaled,
+	})
+	p.checkpointed = p.journaled
+}
+
+// EncodeRequest encodes a request as a journal record.
+func EncodeRequest(req *OrderRequest) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(req); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeRequest decodes a journal record written by EncodeRequest.
+func DecodeRequest(record []byte) (*OrderRequest, error) {
+	var req OrderRequest
+	if err := gob.NewDecoder(bytes.NewReader(record)).Decode(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -0,0 +1,78 @@
+package disruptor
+
+import (
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Symbol Migration
+//
+// Each step of a migration (see matching/migrate.go) is a ring buffer
+// request, so it is sequenced after every order for the symbol that was
+// published before it. Migration requests span no single symbol's queue,
+// so in fair mode they are barriers as well.
+//
+// Export only reads the book and logs nothing. Import and release change
+// which books this engine holds, so they are logged (SymbolImportedEvent,
+// SymbolMovedEvent) and replayed on recovery like any order.
+
+// processExportSymbol copies a symbol's book for transfer.
+func (p *EventProcessor) processExportSymbol(req *OrderRequest, responseCh chan *OrderResponse) {
+	book, err := p.engine.ExportSymbol(req.Symbol)
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Book: book, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send export response for %s", req.Symbol)
+	}
+}
+
+// processImportSymbol loads a book migrated from another shard.
+func (p *EventProcessor) processImportSymbol(req *OrderRequest, responseCh chan *OrderResponse) {
+	err := p.engine.ImportSymbol(req.Symbol, req.Book)
+	if err == nil {
+		p.engine.SetTickSize(req.Symbol, req.TickSize)
+		p.eventBatcher.QueueEvent(&events.SymbolImportedEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeSymbolImported,
+			},
+			Symbol: req.Symbol,
+			Source: req.Shard,
+			Orders: req.Book,
+		})
+		for i := range req.Book {
+			p.syncHold(req.Symbol, req.Book[i].ID)
+		}
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send import response for %s", req.Symbol)
+	}
+}
+
+// processReleaseSymbol drops a symbol's book once another shard holds it.
+func (p *EventProcessor) processReleaseSymbol(req *OrderRequest, responseCh chan *OrderResponse) {
+	released := p.engine.ReleaseSymbol(req.Symbol, req.Shard)
+	if p.buyingPower {
+		p.clearing.ReleaseSymbol(req.Symbol)
+	}
+	p.eventBatcher.QueueEvent(&events.SymbolMovedEvent{
+		Event: events.Event{
+			Timestamp: p.now,
+			Type:      events.EventTypeSymbolMoved,
+		},
+		Symbol: req.Symbol,
+		Target: req.Shard,
+		Orders: released,
+	})
+
+	select {
+	case responseCh <- &OrderResponse{Success: true}:
+	default:
+		log.Printf("Warning: Failed to send release response for %s", req.Symbol)
+	}
+}
@@ -3,19 +3,24 @@ package disruptor
 import (
 	"fmt"
 	"log"
-	"runtime"
 	"sync/atomic"
+	"time"
 
 	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/fees"
 	"github.com/rishav/order-matching-engine/internal/matching"
 	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+	"github.com/rishav/order-matching-engine/internal/timerwheel"
+	"github.com/rishav/order-matching-engine/internal/wal"
 )
 
 // EventProcessor processes orders from the ring buffer in a single thread.
 //
 // Design:
 // - Single goroutine for deterministic, sequential processing
-// - Reads from ring buffer using spin-wait
+// - Waits for requests with a configurable strategy, draining in batches (see wait.go)
 // - Calls matching engine (single-threaded, no locks needed)
 // - Queues events for batched async logging
 // - Sends responses back to HTTP handlers via channels
@@ -26,6 +31,79 @@ type EventProcessor struct {
 	running      atomic.Bool
 	shutdownCh   chan struct{}
 	shutdownDone chan struct{}
+
+	// Stamps each request's Time, and the time of the one being
+	// processed (see clock.go)
+	clock func() int64
+	now   int64
+
+	// fairBatch > 0 enables per-symbol round-robin scheduling, draining up
+	// to fairBatch ready slots per round (see fair.go)
+	fairBatch int
+
+	// Timers, driven by TimerTick requests when timerTick > 0 (see timers.go).
+	// Only touched by the processor goroutine.
+	timerTick     time.Duration
+	timers        *timerwheel.Wheel
+	deadMen       map[string]*deadMan // session ID -> armed switch
+	heartbeats    uint64              // Heartbeats processed, numbering each switch's last
+	onDeadManTrip func(sessionID string, cancelled []*orders.Order)
+
+	// Takes the requests of timers that change state, instead of them
+	// acting, if set (see OnTimerRequest)
+	onTimerRequest func(req *OrderRequest)
+
+	// Indicative auction hook (see auction.go)
+	onAuction func(info matching.AuctionInfo)
+
+	// Risk limit change hook (see risk_limits.go)
+	onRiskLimits func(change *events.RiskLimitsEvent)
+
+	// Restricted list change hook (see restrictions.go)
+	onRestriction func(change *events.RestrictionEvent)
+
+	// Account change hook (see accounts.go)
+	onAccount func(change *events.AccountEvent) error
+
+	// Book snapshots, when a store is set (see snapshots.go)
+	snapshots        *snapshot.Store
+	snapshotInterval time.Duration
+	snapshotCh       chan *snapshot.Image
+	snapshotDone     chan struct{}
+	snapshotEvery    uint64 // Also snapshot every N queued events (0 = interval only)
+	snapshotQueued   uint64 // Queued event count at the last snapshot
+	eventBase        uint64 // Event log sequence at startup
+
+	// Clearing house fed with every logged fill, if set (see snapshots.go),
+	// and how often its settlement cycle runs (see settle.go)
+	clearing       *settlement.ClearingHouse
+	settleInterval time.Duration
+
+	// Prices every logged fill, if set (see EnableFees)
+	fees *fees.Engine
+
+	// Checks and holds buying power in the clearing house (see buyingpower.go)
+	buyingPower bool
+
+	// Recent client order IDs, answering resent orders (see idempotency.go)
+	idempotency idempotency
+
+	// Execution report hook (see reports.go)
+	onExecution func(report orders.ExecutionReport)
+
+	// Book integrity checks, in debug mode (see book_check.go)
+	onBookCheck func(symbol string, err error)
+	brokenBooks map[string]bool
+
+	// Request journal, if enabled (see journal.go): the journal sequence
+	// numbers of the last request applied and of the last checkpoint
+	// logged, and buffers reused for each batch
+	journal      *wal.Log
+	journaled    uint64
+	checkpointed uint64
+	batch        []pendingRequest
+	records      [][]byte
+	written      []*OrderRequest
 }
 
 // NewEventProcessor creates a new event processor.
@@ -36,14 +114,54 @@ func NewEventProcessor(rb *RingBuffer, engine *matching.Engine, eventLog *events
 		eventBatcher: NewEventBatcher(eventLog, 1000, 10), // 1000 events or 10ms
 		shutdownCh:   make(chan struct{}),
 		shutdownDone: make(chan struct{}),
+		clock:        orders.Now,
 	}
 }
 
+// OnEventDrop registers a hook invoked whenever the event batcher drops an
+// event because its queue is full. Must be called before Start.
+func (p *EventProcessor) OnEventDrop(fn func(event interface{})) {
+	p.eventBatcher.OnDrop(fn)
+}
+
+// OnEventLogError registers a hook invoked whenever an event fails to be
+// written to the event log. The hook runs on the batcher goroutine and must
+// not block. Must be called before Start.
+func (p *EventProcessor) OnEventLogError(fn func(event interface{}, err error)) {
+	p.eventBatcher.OnAppendError(fn)
+}
+
+// DroppedEvents returns the number of events the batcher has dropped.
+func (p *EventProcessor) DroppedEvents() uint64 {
+	return p.eventBatcher.Dropped()
+}
+
+// EventQueueDepth returns the events waiting for the batcher.
+func (p *EventProcessor) EventQueueDepth() int {
+	return p.eventBatcher.QueueDepth()
+}
+
+// SetFairScheduling enables per-symbol round-robin scheduling so a
+// hyperactive symbol cannot starve others. batch bounds how many ready
+// requests are drained per round; 0 disables it. Must be called before Start.
+func (p *EventProcessor) SetFairScheduling(batch int) {
+	p.fairBatch = batch
+}
+
 // Start begins processing events from the ring buffer.
 func (p *EventProcessor) Start() {
 	p.running.Store(true)
+	if p.snapshots != nil {
+		p.startSnapshots()
+	}
+	if p.clearing != nil && p.settleInterval > 0 {
+		p.startSettlement()
+	}
 	go p.processLoop()
 	go p.eventBatcher.Start()
+	if p.timerTick > 0 {
+		go p.tickLoop()
+	}
 }
 
 // processLoop is the main event processing loop (single goroutine).
@@ -54,67 +172,185 @@ func (p *EventProcessor) Start() {
 func (p *EventProcessor) processLoop() {
 	defer close(p.shutdownDone)
 
+	if p.fairBatch > 0 {
+		p.fairProcessLoop()
+		return
+	}
+
 	nextSequence := uint64(1) // Start at 1 (0 is initial state)
 
 	for p.running.Load() {
-		// Calculate slot index
-		index := nextSequence & p.rb.indexMask
-		slot := &p.rb.slots[index]
-
-		// Spin-wait for publisher to finish writing
+		// Wait for the publisher to finish writing the next slot
 		// The slot is ready when its SequenceNum matches our expected sequence
-		for {
-			available := atomic.LoadUint64(&slot.SequenceNum)
-			if available == nextSequence {
+		slot := &p.rb.slots[nextSequence&p.rb.indexMask]
+		if !p.rb.waiter.waitFor(slot, nextSequence, p.shutdownCh) {
+			return
+		}
+
+		// Process everything published since, as one batch, journaled
+		// first if the journal is enabled (see journal.go)
+		p.rb.startBatch(nextSequence)
+		last := p.rb.available(nextSequence)
+		if p.journal != nil {
+			batch := p.batch[:0]
+			for seq := nextSequence; seq <= last; seq++ {
+				slot := &p.rb.slots[seq&p.rb.indexMask]
+				batch = append(batch, pendingRequest{req: slot.Request, responseCh: slot.ResponseCh, seq: seq})
+			}
+			p.journalBatch(batch)
+			p.batch = batch[:0]
+		}
+		for seq := nextSequence; seq <= last; seq++ {
+			slot := &p.rb.slots[seq&p.rb.indexMask]
+			p.processRequest(slot.Request, slot.ResponseCh, seq)
+		}
+
+		// Update gating sequence to allow the batch's slots to be reused
+		atomic.StoreUint64(&p.rb.gatingSequence, last)
+		if p.journal != nil {
+			p.checkpoint()
+		}
+
+		nextSequence = last + 1
+	}
+}
+
+// fairProcessLoop is processLoop with per-symbol round-robin scheduling.
+//
+// Each round drains ready slots (at least one, at most fairBatch, stopping
+// at a barrier) into the scheduler, releasing each slot as soon as it has
+// been copied out, then processes everything drained in fair order.
+func (p *EventProcessor) fairProcessLoop() {
+	scheduler := newFairScheduler()
+	nextSequence := uint64(1)
+
+	for p.running.Load() {
+		for drained := 0; drained < p.fairBatch; drained++ {
+			slot := &p.rb.slots[nextSequence&p.rb.indexMask]
+
+			// Wait for the first request of the round, then take only
+			// what is already published
+			if drained == 0 {
+				if !p.rb.waiter.waitFor(slot, nextSequence, p.shutdownCh) {
+					return
+				}
+				p.rb.startBatch(nextSequence)
+			}
+			if atomic.LoadUint64(&slot.SequenceNum) != nextSequence {
 				break
 			}
 
-			// Check for shutdown signal
-			select {
-			case <-p.shutdownCh:
-				return
-			default:
-				// Yield to other goroutines to avoid busy loop
-				runtime.Gosched()
+			pending := pendingRequest{req: slot.Request, responseCh: slot.ResponseCh, seq: nextSequence}
+			atomic.StoreUint64(&p.rb.gatingSequence, nextSequence)
+			nextSequence++
+
+			if !scheduler.push(pending) {
+				break // Barrier: process everything before it first
 			}
 		}
 
-		// Process the request
-		p.processRequest(slot)
-
-		// Update gating sequence to allow this slot to be reused
-		atomic.StoreUint64(&p.rb.gatingSequence, nextSequence)
+		if p.journal == nil {
+			for pending, ok := scheduler.next(); ok; pending, ok = scheduler.next() {
+				p.processRequest(pending.req, pending.responseCh, pending.seq)
+			}
+			continue
+		}
 
-		nextSequence++
+		// Journal the round in the order it will be processed
+		round := p.batch[:0]
+		for pending, ok := scheduler.next(); ok; pending, ok = scheduler.next() {
+			round = append(round, pending)
+		}
+		p.journalBatch(round)
+		for _, pending := range round {
+			p.processRequest(pending.req, pending.responseCh, pending.seq)
+		}
+		p.batch = round[:0]
+		p.checkpoint()
 	}
 }
 
 // processRequest processes a single request from the ring buffer.
-func (p *EventProcessor) processRequest(slot *RingBufferSlot) {
-	req := slot.Request
-	responseCh := slot.ResponseCh
+func (p *EventProcessor) processRequest(req *OrderRequest, responseCh chan *OrderResponse, seq uint64) {
+	defer p.rb.depths.processed(req)
 
 	// Panic recovery to prevent processor crash
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("ERROR: Event processor panic: %v", r)
 			// Send error response
-			select {
-			case responseCh <- &OrderResponse{
+			response := &OrderResponse{
 				Success: false,
 				Error:   fmt.Errorf("internal error: %v", r),
-			}:
+			}
+			if req.Type == RequestTypeCancelOrder {
+				p.rb.cancels.resolve(req, response)
+			}
+			select {
+			case responseCh <- response:
 			default:
 			}
 		}
 	}()
 
+	// Refuse what the journal couldn't take, and note what it did
+	if req.journalErr != nil {
+		p.refuseUnjournaled(req, responseCh)
+		return
+	}
+	if req.JournalSeq != 0 {
+		p.journaled = req.JournalSeq
+	}
+	p.begin(req)
+
 	// Route based on request type
 	switch req.Type {
 	case RequestTypeNewOrder:
 		p.processNewOrder(req, responseCh)
 	case RequestTypeCancelOrder:
 		p.processCancelOrder(req, responseCh)
+	case RequestTypeModifyOrder:
+		p.processModifyOrder(req, responseCh)
+	case RequestTypeBasket:
+		p.processBasket(req, responseCh)
+	case RequestTypeMassCancel:
+		p.processMassCancel(req, responseCh)
+	case RequestTypeStressProbe:
+		p.processStressProbe(req, responseCh, seq)
+	case RequestTypeTimerTick:
+		p.processTimerTick(req)
+	case RequestTypeHeartbeat:
+		p.processHeartbeat(req, responseCh)
+	case RequestTypeExportSymbol:
+		p.processExportSymbol(req, responseCh)
+	case RequestTypeImportSymbol:
+		p.processImportSymbol(req, responseCh)
+	case RequestTypeReleaseSymbol:
+		p.processReleaseSymbol(req, responseCh)
+	case RequestTypeOpenOrders:
+		p.processOpenOrders(req, responseCh)
+	case RequestTypeOrderStatus:
+		p.processOrderStatus(req, responseCh)
+	case RequestTypeStartAuction:
+		p.processStartAuction(req, responseCh)
+	case RequestTypeUncross:
+		p.processUncross(req, responseCh)
+	case RequestTypeRiskLimits:
+		p.processRiskLimits(req, responseCh)
+	case RequestTypeExpireOrders:
+		p.processExpireOrders(req, responseCh)
+	case RequestTypeRestriction:
+		p.processRestriction(req, responseCh)
+	case RequestTypeAccount:
+		p.processAccount(req, responseCh)
+	case RequestTypePreview:
+		p.processPreview(req, responseCh)
+	case RequestTypeSchedule:
+		p.processSchedule(req, responseCh)
+	case RequestTypeTripDeadMan:
+		p.processTripDeadMan(req, responseCh)
+	case RequestTypeSettle:
+		p.processSettle(req, responseCh)
 	default:
 		// Unknown request type
 		select {
@@ -125,58 +361,51 @@ func (p *EventProcessor) processRequest(slot *RingBufferSlot) {
 		default:
 		}
 	}
+	p.reportAuctions(req)
+	p.checkBooks(req)
+
+	if p.snapshotEvery > 0 && p.snapshots != nil {
+		p.maybeSnapshot()
+	}
 }
 
 // processNewOrder processes a new order submission.
 func (p *EventProcessor) processNewOrder(req *OrderRequest, responseCh chan *OrderResponse) {
 	order := req.Order
 
-	// Process order through matching engine (single-threaded, deterministic)
-	result := p.engine.ProcessOrder(order)
-
-	// Queue events for batched logging
-	if result.Accepted {
-		// Log new order event
-		p.eventBatcher.QueueEvent(&events.NewOrderEvent{
-			Event: events.Event{
-				Timestamp: orders.Now(),
-				Type:      events.EventTypeNewOrder,
-			},
-			OrderID:   order.ID,
-			Symbol:    order.Symbol,
-			Side:      order.Side,
-			OrderType: order.Type,
-			Price:     order.Price,
-			Quantity:  order.Quantity,
-			AccountID: order.AccountID,
-		})
-
-		// Log fill events
-		for _, fill := range result.Fills {
-			p.eventBatcher.QueueEvent(&events.FillEvent{
-				Event: events.Event{
-					Timestamp: orders.Now(),
-					Type:      events.EventTypeFill,
-				},
-				TradeID:        fill.TradeID,
-				Symbol:         fill.Symbol,
-				Price:          fill.Price,
-				Quantity:       fill.Quantity,
-				MakerOrderID:   fill.MakerOrderID,
-				TakerOrderID:   fill.TakerOrderID,
-				MakerAccountID: fill.MakerAccountID,
-				TakerAccountID: fill.TakerAccountID,
-				TakerSide:      fill.TakerSide,
-			})
+	// A resent order gets the original's response (see idempotency.go)
+	if response, ok := p.duplicate(order); ok {
+		select {
+		case responseCh <- response:
+		default:
+			log.Printf("Warning: Failed to send duplicate order response for order %d", response.Order.ID)
 		}
+		return
 	}
 
+	// Process order through matching engine (single-threaded, deterministic),
+	// unless its account cannot cover it
+	var result *orders.ExecutionResult
+	if reason := p.checkOrder(order); reason != "" {
+		order.Status = orders.OrderStatusRejected
+		result = &orders.ExecutionResult{Order: order, Fills: make([]orders.Fill, 0), RejectReason: reason}
+	} else {
+		result = p.engine.ProcessOrder(order)
+	}
+	matched := orders.Now()
+
+	// Queue events for batched logging
+	p.logExecution(order, result)
+	p.reportExecution(order, result)
+	p.remember(order, result, matched)
+
 	// Send response back to HTTP handler
 	select {
 	case responseCh <- &OrderResponse{
 		Success: result.Accepted,
 		Result:  result,
 		Order:   order,
+		Matched: matched,
 	}:
 	default:
 		// Handler timed out or channel closed, drop response
@@ -184,16 +413,135 @@ func (p *EventProcessor) processNewOrder(req *OrderRequest, responseCh chan *Ord
 	}
 }
 
+// processBasket processes every leg of a basket within this single request,
+// so validation and execution of all legs happen without interleaving.
+func (p *EventProcessor) processBasket(req *OrderRequest, responseCh chan *OrderResponse) {
+	var result *matching.BasketResult
+	if reason := p.checkOrder(req.Legs...); reason != "" {
+		result = rejectBasket(req.Legs, reason)
+	} else {
+		result = p.engine.ProcessBasket(req.Legs)
+	}
+
+	for i, leg := range req.Legs {
+		p.logExecution(leg, result.Legs[i])
+		p.reportExecution(leg, result.Legs[i])
+	}
+
+	select {
+	case responseCh <- &OrderResponse{
+		Success: result.Accepted,
+		Basket:  result,
+	}:
+	default:
+		log.Printf("Warning: Failed to send basket response (%d legs)", len(req.Legs))
+	}
+}
+
+// logExecution queues the events for a processed order: the new order
+// itself (if accepted) and each of its fills.
+func (p *EventProcessor) logExecution(order *orders.Order, result *orders.ExecutionResult) {
+	if result.Accepted {
+		// Log new order event
+		p.eventBatcher.QueueEvent(&events.NewOrderEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeNewOrder,
+			},
+			OrderID:       order.ID,
+			Symbol:        order.Symbol,
+			Side:          order.Side,
+			OrderType:     order.Type,
+			Price:         order.Price,
+			Quantity:      order.Quantity,
+			AccountID:     order.AccountID,
+			ClientOrderID: order.ClientOrderID,
+			SessionID:     order.SessionID,
+			DisplayQty:    order.DisplayQty,
+			Peg:           order.Peg,
+			PegLimit:      order.PegLimit,
+			TimeInForce:   order.TimeInForce,
+		})
+
+		p.logFills(result.Fills)
+		p.syncHold(order.Symbol, order.ID)
+	}
+}
+
+// rejectBasket rejects every leg of a basket the way the engine rejects an
+// invalid one.
+func rejectBasket(legs []*orders.Order, reason string) *matching.BasketResult {
+	result := &matching.BasketResult{
+		RejectReason: reason,
+		Legs:         make([]*orders.ExecutionResult, len(legs)),
+	}
+	for i, leg := range legs {
+		leg.Status = orders.OrderStatusRejected
+		result.Legs[i] = &orders.ExecutionResult{
+			Order:        leg,
+			Fills:        make([]orders.Fill, 0),
+			RejectReason: "basket rejected: " + reason,
+		}
+	}
+	return result
+}
+
+// EnableFees prices every fill with the fee engine before it is logged and
+// cleared. Must be called before Start.
+func (p *EventProcessor) EnableFees(engine *fees.Engine) {
+	p.fees = engine
+}
+
+// logFills prices each execution if fees are enabled, queues a fill event
+// for it, and records it for clearing if enabled. Fees are set on the fills
+// in place, so the response carries them too.
+func (p *EventProcessor) logFills(fills []orders.Fill) {
+	for i := range fills {
+		if p.fees != nil {
+			p.fees.Charge(&fills[i])
+		}
+		fill := fills[i]
+		if p.clearing != nil {
+			p.clearing.RecordTrade(fill)
+		}
+		p.eventBatcher.QueueEvent(&events.FillEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeFill,
+			},
+			TradeID:        fill.TradeID,
+			Symbol:         fill.Symbol,
+			Price:          fill.Price,
+			Quantity:       fill.Quantity,
+			MakerOrderID:   fill.MakerOrderID,
+			TakerOrderID:   fill.TakerOrderID,
+			MakerAccountID: fill.MakerAccountID,
+			TakerAccountID: fill.TakerAccountID,
+			TakerSide:      fill.TakerSide,
+			MakerFee:       fill.MakerFee,
+			TakerFee:       fill.TakerFee,
+		})
+	}
+	p.syncFills(fills)
+}
+
 // processCancelOrder processes an order cancellation.
 func (p *EventProcessor) processCancelOrder(req *OrderRequest, responseCh chan *OrderResponse) {
-	// Cancel the order
-	order, err := p.engine.CancelOrder(req.Symbol, req.OrderID)
+	// Cancel the order, unless it belongs to another account than the one
+	// asking, which is told it does not exist
+	var order *orders.Order
+	var err error
+	if owned := p.engine.GetOrder(req.Symbol, req.OrderID); req.AccountID != "" && owned != nil && owned.AccountID != req.AccountID {
+		err = fmt.Errorf("order %d not found", req.OrderID)
+	} else {
+		order, err = p.engine.CancelOrder(req.Symbol, req.OrderID)
+	}
 
 	// Queue cancellation event if successful
 	if err == nil && order != nil {
 		p.eventBatcher.QueueEvent(&events.OrderCancelledEvent{
 			Event: events.Event{
-				Timestamp: orders.Now(),
+				Timestamp: p.now,
 				Type:      events.EventTypeOrderCancelled,
 			},
 			OrderID:      order.ID,
@@ -201,20 +549,181 @@ func (p *EventProcessor) processCancelOrder(req *OrderRequest, responseCh chan *
 			CancelledQty: order.RemainingQty(),
 			Reason:       "user cancelled",
 		})
+		p.syncHold(order.Symbol, order.ID)
+		p.reportCancels([]*orders.Order{order}, "user cancelled")
 	}
 
-	// Send response
-	select {
-	case responseCh <- &OrderResponse{
+	// Send response, to duplicates of this cancel as well (see conflate.go)
+	response := &OrderResponse{
 		Success: err == nil,
 		Order:   order,
 		Error:   err,
-	}:
+	}
+	p.rb.cancels.resolve(req, response)
+	select {
+	case responseCh <- response:
 	default:
 		log.Printf("Warning: Failed to send cancel response for order %d", req.OrderID)
 	}
 }
 
+// processModifyOrder applies a cancel/replace to a resting order.
+func (p *EventProcessor) processModifyOrder(req *OrderRequest, responseCh chan *OrderResponse) {
+	var replaced *matching.ReplaceResult
+	var err error
+	var before orders.Order
+	if err = p.checkReplace(req.Replace); err == nil {
+		if order := p.engine.GetOrder(req.Replace.Symbol, req.Replace.OrderID); order != nil {
+			before = *order
+		}
+		replaced, err = p.engine.ReplaceOrder(*req.Replace)
+	}
+
+	response := &OrderResponse{Success: err == nil, Error: err}
+	if err == nil {
+		order := replaced.Result.Order
+		p.eventBatcher.QueueEvent(&events.OrderReplacedEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeOrderReplaced,
+			},
+			OrderID:      order.ID,
+			Symbol:       order.Symbol,
+			OldPrice:     replaced.OldPrice,
+			OldQuantity:  replaced.OldQuantity,
+			NewPrice:     order.Price,
+			NewQuantity:  order.Quantity,
+			PriorityKept: replaced.PriorityKept,
+		})
+		p.logFills(replaced.Result.Fills)
+		p.syncHold(order.Symbol, order.ID)
+		p.reportReplace(before, replaced)
+
+		response.Result = replaced.Result
+		response.Order = order
+		response.Replace = replaced
+	}
+
+	select {
+	case responseCh <- response:
+	default:
+		log.Printf("Warning: Failed to send replace response for order %d", req.Replace.OrderID)
+	}
+}
+
+// processMassCancel cancels every resting order of a session, or of an
+// account if AccountID is set.
+func (p *EventProcessor) processMassCancel(req *OrderRequest, responseCh chan *OrderResponse) {
+	var cancelled []*orders.Order
+	if req.AccountID != "" {
+		cancelled = p.engine.CancelAccountOrders(req.AccountID)
+	} else {
+		cancelled = p.engine.CancelSessionOrders(req.SessionID)
+	}
+	p.logCancels(cancelled, req.Reason)
+	p.reportCancels(cancelled, req.Reason)
+
+	select {
+	case responseCh <- &OrderResponse{
+		Success:   true,
+		Cancelled: cancelled,
+	}:
+	default:
+		log.Printf("Warning: Failed to send mass cancel response for session %s account %s", req.SessionID, req.AccountID)
+	}
+}
+
+// logCancels queues a cancellation event for each order.
+func (p *EventProcessor) logCancels(cancelled []*orders.Order, reason string) {
+	for _, order := range cancelled {
+		p.eventBatcher.QueueEvent(&events.OrderCancelledEvent{
+			Event: events.Event{
+				Timestamp: p.now,
+				Type:      events.EventTypeOrderCancelled,
+			},
+			OrderID:      order.ID,
+			Symbol:       order.Symbol,
+			CancelledQty: order.RemainingQty(),
+			Reason:       reason,
+		})
+		p.syncHold(order.Symbol, order.ID)
+	}
+}
+
+// processOpenOrders lists an account's resting orders. Running it as a
+// request sequences it with the orders it reports on, so the list is never
+// torn by a concurrent fill.
+func (p *EventProcessor) processOpenOrders(req *OrderRequest, responseCh chan *OrderResponse) {
+	select {
+	case responseCh <- &OrderResponse{
+		Success: true,
+		Open:    p.engine.OpenOrders(req.AccountID, req.Symbol),
+	}:
+	default:
+		log.Printf("Warning: Failed to send open orders response for account %s", req.AccountID)
+	}
+}
+
+// processOrderStatus looks up an order by ID, or by the account's client
+// order ID. The response carries a copy of the order.
+func (p *EventProcessor) processOrderStatus(req *OrderRequest, responseCh chan *OrderResponse) {
+	var order orders.Order
+	var found bool
+	if req.ClientOrderID != "" {
+		order, found = p.engine.LookupClientOrder(req.AccountID, req.ClientOrderID)
+	} else {
+		order, found = p.engine.LookupOrder(req.OrderID)
+	}
+
+	response := &OrderResponse{Success: found}
+	if found {
+		response.Order = &order
+	} else {
+		response.Error = ErrOrderUnknown
+	}
+
+	select {
+	case responseCh <- response:
+	default:
+		log.Printf("Warning: Failed to send order status response for order %d", req.OrderID)
+	}
+}
+
+// processPreview simulates a new order without placing it. Running it as a
+// request previews it against the book exactly as the next order would
+// find it.
+func (p *EventProcessor) processPreview(req *OrderRequest, responseCh chan *OrderResponse) {
+	select {
+	case responseCh <- &OrderResponse{
+		Success: true,
+		Preview: p.engine.PreviewOrder(*req.Order),
+	}:
+	default:
+		log.Printf("Warning: Failed to send preview response for %s", req.Order.Symbol)
+	}
+}
+
+// processStressProbe echoes a stress probe back to its producer.
+//
+// The echo is a copy of what the processor read from the slot, not the
+// producer's pointer, so a stale or torn slot read is visible to the verifier.
+func (p *EventProcessor) processStressProbe(req *OrderRequest, responseCh chan *OrderResponse, seq uint64) {
+	var echo *StressProbe
+	if req.Probe != nil {
+		probeCopy := *req.Probe
+		echo = &probeCopy
+	}
+
+	select {
+	case responseCh <- &OrderResponse{
+		Success:  true,
+		Probe:    echo,
+		Sequence: seq,
+	}:
+	default:
+	}
+}
+
 // Shutdown gracefully shuts down the event processor.
 //
 // It stops accepting new requests, drains remaining requests from the ring buffer,
@@ -229,6 +738,11 @@ func (p *EventProcessor) Shutdown() {
 	// Wait for processor loop to finish
 	<-p.shutdownDone
 
+	// Snapshot the drained books
+	if p.snapshots != nil {
+		p.stopSnapshots()
+	}
+
 	// Shutdown event batcher (flushes remaining events)
 	p.eventBatcher.Shutdown()
 
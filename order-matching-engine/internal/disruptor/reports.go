@@ -0,0 +1,108 @@
+package disruptor
+
+import (
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Execution Reports
+//
+// Every change the processor makes to an order is also reported, in
+// sequence, to the OnExecution hook as a FIX-style execution report:
+//
+//	accepted        NEW        (then a TRADE per fill, maker and taker alike)
+//	refused         REJECTED   with the reason
+//	IOC remainder   CANCELLED  after its fills
+//	cancel          CANCELLED  user, mass cancel, dead man's switch
+//	cancel/replace  REPLACED   (then a TRADE per fill if it re-queued and matched)
+//	auction uncross TRADE      per fill
+//
+// This is what the drop-copy feed streams per account, so it covers orders
+// changed by someone else's request too (a maker's fills, a session's mass
+// cancel), not just the requester's own response. The hook runs on the
+// processor goroutine and must not block.
+
+// OnExecution registers a hook invoked with every execution report. Must be
+// called before Start.
+func (p *EventProcessor) OnExecution(fn func(report orders.ExecutionReport)) {
+	p.onExecution = fn
+}
+
+// report passes a report to the hook, if any.
+func (p *EventProcessor) report(report orders.ExecutionReport) {
+	if p.onExecution != nil {
+		p.onExecution(report)
+	}
+}
+
+// reportExecution reports a processed new order: rejected, or accepted and
+// then each fill it caused, and the cancel of any remainder that could not
+// rest.
+func (p *EventProcessor) reportExecution(order *orders.Order, result *orders.ExecutionResult) {
+	if p.onExecution == nil {
+		return
+	}
+	if !result.Accepted {
+		p.report(orders.NewOrderReport(order, orders.ExecTypeRejected, result.RejectReason))
+		return
+	}
+
+	// The acknowledgement shows the order as it was accepted, before fills
+	accepted := orders.NewOrderReport(order, orders.ExecTypeNew, "")
+	accepted.Status = orders.OrderStatusNew
+	accepted.CumQty, accepted.AvgPx, accepted.LeavesQty = 0, 0, order.Quantity
+	accepted.Timestamp = order.Timestamp
+	p.report(accepted)
+
+	p.reportTrades(result.Reports, result.Fills)
+	if order.Status == orders.OrderStatusCancelled {
+		reason := result.RejectReason
+		if reason == "" {
+			reason = "unfilled quantity cancelled"
+		}
+		p.report(orders.NewOrderReport(order, orders.ExecTypeCancelled, reason))
+	}
+}
+
+// reportCancels reports each cancelled order.
+func (p *EventProcessor) reportCancels(cancelled []*orders.Order, reason string) {
+	if p.onExecution == nil {
+		return
+	}
+	for _, order := range cancelled {
+		p.report(orders.NewOrderReport(order, orders.ExecTypeCancelled, reason))
+	}
+}
+
+// reportReplace reports a replaced order, then any fills it took on
+// re-entering the book. The replace itself shows the new price and quantity
+// with the fills the order had before.
+func (p *EventProcessor) reportReplace(before orders.Order, replaced *matching.ReplaceResult) {
+	if p.onExecution == nil {
+		return
+	}
+	order := replaced.Result.Order
+	before.Price, before.Quantity = order.Price, order.Quantity
+	p.report(orders.NewOrderReport(&before, orders.ExecTypeReplaced, ""))
+	p.reportTrades(replaced.Result.Reports, replaced.Result.Fills)
+}
+
+// reportTrades reports fills, with the fees logFills charged each side.
+func (p *EventProcessor) reportTrades(reports []orders.ExecutionReport, fills []orders.Fill) {
+	if p.onExecution == nil {
+		return
+	}
+	byTrade := make(map[uint64]*orders.Fill, len(fills))
+	for i := range fills {
+		byTrade[fills[i].TradeID] = &fills[i]
+	}
+	for _, report := range reports {
+		if fill := byTrade[report.TradeID]; fill != nil {
+			report.Fee = fill.TakerFee
+			if report.IsMaker {
+				report.Fee = fill.MakerFee
+			}
+		}
+		p.onExecution(report)
+	}
+}
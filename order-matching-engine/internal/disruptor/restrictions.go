@@ -0,0 +1,41 @@
+package disruptor
+
+import (
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Restricted List Changes
+//
+// Like risk limit changes (see risk_limits.go), a symbol put on or taken
+// off a restricted list (see risk/restrictions.go) is a ring buffer
+// request: it is logged in order with the orders it stops, with the
+// operator who made it, and passed to the OnRestriction hook, which
+// applies it to the risk checker. Replay skips it.
+
+// processRestriction logs a change to a restricted list.
+func (p *EventProcessor) processRestriction(req *OrderRequest, responseCh chan *OrderResponse) {
+	change := *req.Restriction
+	change.Event = events.Event{
+		Timestamp: p.now,
+		Type:      events.EventTypeRestriction,
+	}
+	p.eventBatcher.QueueEvent(&change)
+	if p.onRestriction != nil {
+		p.onRestriction(&change)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: true}:
+	default:
+		log.Printf("Warning: Failed to send restriction response for %s", change.Symbol)
+	}
+}
+
+// OnRestriction registers a hook invoked on the processor goroutine with
+// each change to a restricted list. It must not block. Must be called
+// before Start.
+func (p *EventProcessor) OnRestriction(fn func(change *events.RestrictionEvent)) {
+	p.onRestriction = fn
+}
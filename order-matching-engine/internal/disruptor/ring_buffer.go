@@ -12,7 +12,11 @@ package disruptor
 
 import (
 	"errors"
+	"sync/atomic"
+	"time"
 
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
 	"github.com/rishav/order-matching-engine/internal/orders"
 )
 
@@ -22,18 +26,114 @@ type RequestType uint8
 const (
 	RequestTypeNewOrder RequestType = iota
 	RequestTypeCancelOrder
+	RequestTypeStressProbe   // Synthetic integrity probe, never reaches the engine
+	RequestTypeMassCancel    // Cancel all resting orders of a session or account
+	RequestTypeBasket        // All-or-none multi-symbol basket
+	RequestTypeModifyOrder   // Cancel/replace a resting order's price or quantity
+	RequestTypeTimerTick     // Advances the processor's timers (see deadman.go)
+	RequestTypeHeartbeat     // Arms, refreshes or disarms a session's dead man's switch
+	RequestTypeExportSymbol  // Copies a symbol's book for migration (see migrate.go)
+	RequestTypeImportSymbol  // Loads a symbol's book migrated from another shard
+	RequestTypeReleaseSymbol // Drops a symbol's book once migrated to another shard
+	RequestTypeOpenOrders    // Lists an account's resting orders
+	RequestTypeOrderStatus   // Looks up one order, live or recently completed
+	RequestTypeStartAuction  // Puts a symbol into an auction call (see auction.go)
+	RequestTypeUncross       // Ends a symbol's auction call
+	RequestTypeRiskLimits    // Changes an account's risk limit overrides (see risk_limits.go)
+	RequestTypeExpireOrders  // Expires DAY orders at a market's close (see expiry.go)
+	RequestTypeRestriction   // Puts a symbol on or takes it off a restricted list (see restrictions.go)
+	RequestTypeAccount       // Opens an account, or moves cash or shares in or out (see accounts.go)
+	RequestTypePreview       // Simulates a new order against a copy of its book
+	RequestTypeSchedule      // Runs a callback once a delay has elapsed on the processor's timers (see timers.go)
+	RequestTypeTripDeadMan   // Trips a dead man's switch a cluster leader's timer expired (see deadman.go)
+	RequestTypeSettle        // Runs the settlement cycle (see settle.go)
 )
 
 // OrderRequest encapsulates an order processing request.
 type OrderRequest struct {
 	Type RequestType
 
-	// For new orders
+	// For new orders and previews
 	Order *orders.Order
 
-	// For cancellations
+	// For cancellations (AccountID, if set, must own the order)
 	Symbol  string
 	OrderID uint64
+
+	// For cancel/replace
+	Replace *matching.ReplaceRequest
+
+	// For baskets (one order per leg)
+	Legs []*orders.Order
+
+	// For mass cancels and heartbeats
+	SessionID string
+	Reason    string
+
+	// For dead man's switch trips (SessionID is the session's): the
+	// heartbeat that last armed or refreshed the switch when it expired
+	Heartbeat uint64
+
+	// For open-order queries (Symbol optionally narrows it to one symbol),
+	// status lookups (by OrderID, or by AccountID and ClientOrderID) and
+	// mass cancels of an account (instead of a session)
+	AccountID     string
+	ClientOrderID string
+
+	// For heartbeats: > 0 arms (or re-arms) the session's dead man's switch
+	// with this timeout, 0 refreshes it, < 0 disarms it. For scheduled
+	// callbacks: the delay
+	Timeout time.Duration
+
+	// For scheduled callbacks: run on the processor goroutine once Timeout
+	// has elapsed, so it must not block, then every Repeat (0 = once).
+	// Never journaled or replicated
+	OnTimer func()
+	Repeat  time.Duration
+
+	// For timer ticks: wall-clock time the tick was published. For DAY
+	// order expiry: the close, before which the orders expiring were entered
+	Now int64
+
+	// For DAY order expiry: the symbols of the market that closed
+	Symbols []string
+
+	// For stress probes
+	Probe *StressProbe
+
+	// For symbol migrations (Symbol is the symbol moved): the book imported,
+	// the shard it came from or went to, and the symbol's tick size
+	Book     []orders.Order
+	Shard    string
+	TickSize int64
+
+	// For auction starts (Symbol is the symbol called): the reference price
+	// the equilibrium tie-break uses
+	RefPrice int64
+
+	// For risk limit changes: the change, logged as is
+	RiskLimits *events.RiskLimitsEvent
+
+	// For restricted list changes: the change, logged as is
+	Restriction *events.RestrictionEvent
+
+	// For account openings, deposits and withdrawals: the change, logged
+	// as is once applied
+	Account *events.AccountEvent
+
+	// Time is the time the processor recorded for the request, which its
+	// events and fills carry. Set when the processor takes the request, or
+	// by the cluster node that proposes it, and journaled with it (see
+	// clock.go)
+	Time int64
+
+	// JournalSeq is the request's request journal sequence number, set once
+	// it is written, or by recovery for a request replayed from the journal
+	// (see journal.go). 0 if it is not journaled.
+	JournalSeq uint64
+
+	// journalErr is set if the journal could not take the request
+	journalErr error
 }
 
 // OrderResponse contains the execution result.
@@ -42,6 +142,41 @@ type OrderResponse struct {
 	Result  *orders.ExecutionResult
 	Order   *orders.Order
 	Error   error
+
+	// Matched is when the engine finished matching a new order, in
+	// nanoseconds since epoch
+	Matched int64
+
+	// Duplicate is set when a new order repeated an earlier one's client
+	// order ID: Result and Order are the earlier order's, and nothing new
+	// was matched or logged (see idempotency.go)
+	Duplicate bool
+
+	// Cancelled lists the orders removed by a mass cancel or a dead man's
+	// switch trip
+	Cancelled []*orders.Order
+
+	// Replace is set for cancel/replace requests
+	Replace *matching.ReplaceResult
+
+	// Basket is set for basket requests
+	Basket *matching.BasketResult
+
+	// Book is set for symbol exports
+	Book []orders.Order
+
+	// Auction is set for uncross requests
+	Auction *matching.UncrossResult
+
+	// Open is set for open-order queries: copies of the resting orders
+	Open []orders.Order
+
+	// Preview is set for previews
+	Preview *matching.Preview
+
+	// Probe and Sequence are only set for stress probe echoes
+	Probe    *StressProbe
+	Sequence uint64
 }
 
 // RingBufferSlot represents a single slot in the ring buffer.
@@ -92,6 +227,31 @@ type RingBuffer struct {
 	// Prevents producers from overwriting unconsumed data
 	gatingSequence uint64
 
+	// depths tracks per-symbol backlog (published, not yet processed)
+	depths symbolDepths
+
+	// cancels tracks cancels in flight, for conflation (see conflate.go)
+	cancels cancelConflator
+
+	// waiter is how the consumer waits for requests (see wait.go)
+	waiter waiter
+
+	// spins counts producer retries in Sequencer.Next (buffer full or a
+	// lost CAS race); full counts claims that gave up with ErrBufferFull;
+	// backoff is the nanoseconds producers spent waiting for room
+	spins   uint64
+	full    uint64
+	backoff uint64
+
+	// batches counts the consumer's batches (see wait.go); maxLag is the
+	// most slots it has found claimed but unconsumed at the start of one.
+	// Written by the consumer only.
+	batches uint64
+	maxLag  uint64
+
+	// strategy is the consumer's wait strategy, for Stats
+	strategy WaitStrategy
+
 	// Padding to prevent false sharing with other data structures
 	_ [40]byte
 }
@@ -101,6 +261,10 @@ type Config struct {
 	// BufferSize is the number of slots in the ring buffer.
 	// Must be a power of 2 (e.g., 1024, 4096, 8192).
 	BufferSize uint64
+
+	// WaitStrategy is how the processor waits for requests when the
+	// buffer is empty (see wait.go). The zero value is WaitYielding.
+	WaitStrategy WaitStrategy
 }
 
 // DefaultConfig returns reasonable defaults for the ring buffer.
@@ -124,6 +288,8 @@ func NewRingBuffer(config Config) *RingBuffer {
 		cursor:         0,
 		consumerCursor: 1, // Start at 1 (will consume from sequence 1)
 		gatingSequence: 0, // Initially, nothing has been consumed
+		waiter:         newWaiter(config.WaitStrategy),
+		strategy:       config.WaitStrategy,
 	}
 
 	// Initialize all slots with sequence numbers (not yet published)
@@ -139,5 +305,111 @@ func (rb *RingBuffer) GetBufferSize() uint64 {
 	return rb.bufferSize
 }
 
+// Occupancy returns the fraction of slots claimed but not yet consumed,
+// from 0 (empty) to 1 (full): the load signal for degradation.
+func (rb *RingBuffer) Occupancy() float64 {
+	claimed := atomic.LoadUint64(&rb.cursor)
+	consumed := atomic.LoadUint64(&rb.gatingSequence)
+	if claimed <= consumed {
+		return 0
+	}
+	return float64(claimed-consumed) / float64(rb.bufferSize)
+}
+
+// available returns the last sequence published without a gap from seq
+// on, which must itself be published. Consumer only: slots from seq on
+// can't be reused until the consumer releases them.
+func (rb *RingBuffer) available(seq uint64) uint64 {
+	last := seq
+	for last-seq < rb.bufferSize-1 && atomic.LoadUint64(&rb.slots[(last+1)&rb.indexMask].SequenceNum) == last+1 {
+		last++
+	}
+	return last
+}
+
+// SequencerSpins returns how many times producers have had to retry a
+// claim: a measure of contention and backpressure.
+func (rb *RingBuffer) SequencerSpins() uint64 {
+	return atomic.LoadUint64(&rb.spins)
+}
+
+// BufferFullRejections returns how many claims failed with ErrBufferFull.
+func (rb *RingBuffer) BufferFullRejections() uint64 {
+	return atomic.LoadUint64(&rb.full)
+}
+
+// RingBufferStats is a snapshot of a ring buffer's producer and consumer
+// counters, for sizing the buffer and spotting backpressure before claims
+// start failing. Counts are totals since startup; Lag and Occupancy are
+// as of the snapshot.
+type RingBufferStats struct {
+	BufferSize   uint64 `json:"buffer_size"`
+	WaitStrategy string `json:"wait_strategy"`
+
+	// Producers
+	Claims           uint64        `json:"claims"`             // Slots claimed
+	FullRejections   uint64        `json:"full_rejections"`    // Claims refused with ErrBufferFull
+	ClaimFailureRate float64       `json:"claim_failure_rate"` // FullRejections over all claim attempts
+	Spins            uint64        `json:"spins"`              // Retries claiming a slot
+	Backoff          time.Duration `json:"backoff_ns"`         // Time producers waited for room
+
+	// Consumer
+	Consumed  uint64  `json:"consumed"`  // Slots processed and released
+	Lag       uint64  `json:"lag"`       // Slots claimed but not yet processed
+	MaxLag    uint64  `json:"max_lag"`   // High-water mark of Lag, seen by the consumer
+	Occupancy float64 `json:"occupancy"` // Lag as a fraction of BufferSize
+	Batches   uint64  `json:"batches"`   // Consumer wake-ups that processed slots
+	AvgBatch  float64 `json:"avg_batch"` // Consumed per batch
+}
+
+// Stats returns a snapshot of the ring buffer's counters. The counters are
+// read one by one while producers and the consumer run, so they may be
+// off from each other by the requests in flight.
+func (rb *RingBuffer) Stats() RingBufferStats {
+	consumed := atomic.LoadUint64(&rb.gatingSequence)
+	claims := atomic.LoadUint64(&rb.cursor)
+	stats := RingBufferStats{
+		BufferSize:     rb.bufferSize,
+		WaitStrategy:   rb.strategy.String(),
+		Claims:         claims,
+		FullRejections: atomic.LoadUint64(&rb.full),
+		Spins:          atomic.LoadUint64(&rb.spins),
+		Backoff:        time.Duration(atomic.LoadUint64(&rb.backoff)),
+		Consumed:       consumed,
+		MaxLag:         atomic.LoadUint64(&rb.maxLag),
+		Batches:        atomic.LoadUint64(&rb.batches),
+	}
+	if attempts := stats.Claims + stats.FullRejections; attempts > 0 {
+		stats.ClaimFailureRate = float64(stats.FullRejections) / float64(attempts)
+	}
+	if claims > consumed {
+		stats.Lag = claims - consumed
+		stats.Occupancy = float64(stats.Lag) / float64(rb.bufferSize)
+	}
+	if stats.Batches > 0 {
+		stats.AvgBatch = float64(consumed) / float64(stats.Batches)
+	}
+	return stats
+}
+
+// startBatch records the start of a consumer batch at sequence next,
+// measuring the lag behind producers. Consumer only.
+func (rb *RingBuffer) startBatch(next uint64) {
+	atomic.AddUint64(&rb.batches, 1)
+	if lag := atomic.LoadUint64(&rb.cursor) - (next - 1); lag > atomic.LoadUint64(&rb.maxLag) {
+		atomic.StoreUint64(&rb.maxLag, lag)
+	}
+}
+
+// SymbolQueueStats returns the per-symbol backlog, sorted by symbol.
+// Requests spanning symbols (baskets, mass cancels) are not included.
+func (rb *RingBuffer) SymbolQueueStats() []SymbolQueueStats {
+	return rb.depths.snapshot()
+}
+
 // ErrBufferFull is returned when the ring buffer is full.
 var ErrBufferFull = errors.New("ring buffer is full")
+
+// ErrOrderUnknown is returned by a status lookup for an order that is
+// neither live nor in the engine's completed order history.
+var ErrOrderUnknown = errors.New("order not found")
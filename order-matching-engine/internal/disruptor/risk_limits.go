@@ -0,0 +1,42 @@
+package disruptor
+
+import (
+	"log"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Risk Limit Changes
+//
+// A change to an account's risk limit overrides (see risk/limits.go) is a
+// ring buffer request, so it is logged in order with the orders it governs:
+// the log shows which limits every order was checked against, and who set
+// them. The processor logs the change and passes it to the OnRiskLimits
+// hook, which applies it to the risk checker. The books are untouched, so
+// replay skips it.
+
+// processRiskLimits logs a change to an account's risk limits.
+func (p *EventProcessor) processRiskLimits(req *OrderRequest, responseCh chan *OrderResponse) {
+	change := *req.RiskLimits
+	change.Event = events.Event{
+		Timestamp: p.now,
+		Type:      events.EventTypeRiskLimits,
+	}
+	p.eventBatcher.QueueEvent(&change)
+	if p.onRiskLimits != nil {
+		p.onRiskLimits(&change)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: true}:
+	default:
+		log.Printf("Warning: Failed to send risk limits response for %s", change.AccountID)
+	}
+}
+
+// OnRiskLimits registers a hook invoked on the processor goroutine with
+// each change to an account's risk limits. It must not block. Must be
+// called before Start.
+func (p *EventProcessor) OnRiskLimits(fn func(change *events.RiskLimitsEvent)) {
+	p.onRiskLimits = fn
+}
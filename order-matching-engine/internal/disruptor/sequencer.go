@@ -3,6 +3,7 @@ package disruptor
 import (
 	"runtime"
 	"sync/atomic"
+	"time"
 )
 
 // Sequencer coordinates access to the ring buffer using atomic CAS operations.
@@ -34,6 +35,9 @@ func NewSequencer(rb *RingBuffer) *Sequencer {
 func (s *Sequencer) Next() (uint64, error) {
 	const maxSpins = 10000 // ~100μs on modern CPU (10ns per iteration)
 
+	// Set once the buffer is first found full, timing the wait for room
+	var backoffStart time.Time
+
 	for spins := 0; spins < maxSpins; spins++ {
 		// Load current cursor
 		current := atomic.LoadUint64(&s.rb.cursor)
@@ -47,12 +51,21 @@ func (s *Sequencer) Next() (uint64, error) {
 		// If next would exceed available space, buffer is full
 		if next > availableSequence {
 			// Buffer is full, yield to consumer
+			if backoffStart.IsZero() {
+				backoffStart = time.Now()
+			}
 			runtime.Gosched()
 			continue
 		}
 
 		// Try to claim this sequence number using CAS
 		if atomic.CompareAndSwapUint64(&s.rb.cursor, current, next) {
+			if spins > 0 {
+				atomic.AddUint64(&s.rb.spins, uint64(spins))
+			}
+			if !backoffStart.IsZero() {
+				atomic.AddUint64(&s.rb.backoff, uint64(time.Since(backoffStart)))
+			}
 			return next, nil
 		}
 
@@ -60,6 +73,11 @@ func (s *Sequencer) Next() (uint64, error) {
 	}
 
 	// Exhausted spins, buffer is full
+	atomic.AddUint64(&s.rb.spins, maxSpins)
+	atomic.AddUint64(&s.rb.full, 1)
+	if !backoffStart.IsZero() {
+		atomic.AddUint64(&s.rb.backoff, uint64(time.Since(backoffStart)))
+	}
 	return 0, ErrBufferFull
 }
 
@@ -77,6 +95,9 @@ func (s *Sequencer) Publish(seq uint64, request *OrderRequest, responseCh chan *
 	index := seq & s.rb.indexMask
 	slot := &s.rb.slots[index]
 
+	// Count the request against its symbol before the consumer can see it
+	s.rb.depths.published(request)
+
 	// Write request data to slot
 	slot.Request = request
 	slot.ResponseCh = responseCh
@@ -84,4 +105,7 @@ func (s *Sequencer) Publish(seq uint64, request *OrderRequest, responseCh chan *
 	// Memory barrier: ensure all writes above are visible before sequence update
 	// The atomic store with sequential consistency guarantees this
 	atomic.StoreUint64(&slot.SequenceNum, seq)
+
+	// Wake the consumer if its wait strategy parks it
+	s.rb.waiter.signal()
 }
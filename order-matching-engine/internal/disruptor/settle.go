@@ -0,0 +1,84 @@
+package disruptor
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// Settlement Cycle
+//
+// With SettleEvery, the processor moves the clearing house's trades through
+// the settlement cycle (settlement.ClearingHouse.Advance) on its timers: at
+// the first tick, catching up on trades restored at startup, then every
+// interval. Like fills, which the processor records in the clearing house
+// as they are logged, the cycle runs between two requests:
+//
+//	ticker ──TimerTick──▶ ring buffer ──▶ processor ──▶ timer fires ──▶ Advance
+//
+// so the trades it nets are exactly those of the requests sequenced before
+// it. Shards share one clearing house, so only one shard's processor
+// should run the cycle.
+//
+// A Settle request runs the cycle too, at its point in the sequence. With
+// OnTimerRequest the timer asks for one instead of running the cycle, so
+// a cluster runs it where its leader commits the request, on every node.
+
+// errClearingDisabled is returned for a Settle request when EnableClearing
+// was not called.
+var errClearingDisabled = errors.New("clearing not enabled")
+
+// SettleEvery runs the settlement cycle of the clearing house set with
+// EnableClearing every interval (0 = never, the default). Needs
+// EnableTimers. Must be called before Start.
+func (p *EventProcessor) SettleEvery(interval time.Duration) {
+	p.settleInterval = interval
+}
+
+// startSettlement schedules the first settlement cycle. Called from Start,
+// before the processor goroutine runs.
+func (p *EventProcessor) startSettlement() {
+	if p.timers == nil {
+		log.Println("Warning: settlement cycle disabled (timers not enabled)")
+		return
+	}
+	p.scheduleAfter(0, p.settle)
+}
+
+// settle runs the settlement cycle, or asks for a Settle request, and
+// schedules the next one. Runs on the processor goroutine.
+func (p *EventProcessor) settle() {
+	if p.onTimerRequest != nil {
+		p.onTimerRequest(&OrderRequest{Type: RequestTypeSettle})
+	} else {
+		p.runSettlement()
+	}
+	p.scheduleAfter(p.settleInterval, p.settle)
+}
+
+// runSettlement moves the clearing house's trades through the settlement
+// cycle.
+func (p *EventProcessor) runSettlement() error {
+	settled, err := p.clearing.Advance()
+	if len(settled) > 0 {
+		log.Printf("Settlement: %d instructions settled", len(settled))
+	}
+	if err != nil {
+		log.Printf("Settlement: %v", err) // Each failure is alerted by the fail hook
+	}
+	return err
+}
+
+// processSettle runs the settlement cycle for a Settle request.
+func (p *EventProcessor) processSettle(req *OrderRequest, responseCh chan *OrderResponse) {
+	err := errClearingDisabled
+	if p.clearing != nil {
+		err = p.runSettlement()
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send settlement response")
+	}
+}
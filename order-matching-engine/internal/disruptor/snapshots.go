@@ -0,0 +1,159 @@
+package disruptor
+
+import (
+	"log"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+)
+
+// Book Snapshots
+//
+// The books can only be read consistently on the processor goroutine, but
+// encoding and writing them to disk is slow. So a processor timer captures
+// an image (a copy of every resting order) between two requests and hands
+// it to a writer goroutine, which diffs it against the previous snapshot
+// and writes the delta (see internal/snapshot):
+//
+//	timer ──▶ processor: capture image ──chan──▶ writer: diff + write
+//
+// If the writer is still busy with the previous snapshot, the capture is
+// skipped rather than queued; the next one covers the same changes.
+//
+// Snapshots are taken every interval and, with SetSnapshotEvery, after
+// every N logged events, so a burst of activity doesn't leave a long log
+// tail to replay.
+//
+// Each image records the event log sequence it reflects: the sequence the
+// log had at startup plus the events queued since. Along with the books it
+// carries the engine's ID counters and, with EnableClearing, the clearing
+// house state, so recovery can load it and replay only the events after
+// that sequence (see matching.Replayer). Once written, the event log may
+// expire segments up to it (see events.SetRetentionFloor), and the request
+// journal is compacted up to the last request it reflects.
+
+// EnableSnapshots snapshots the books to store every interval, and once
+// more at shutdown. Periodic snapshots need EnableTimers. Must be called
+// before Start.
+func (p *EventProcessor) EnableSnapshots(store *snapshot.Store, interval time.Duration) {
+	p.snapshots = store
+	p.snapshotInterval = interval
+	p.eventBase = p.eventBatcher.eventLog.GetLastSequence()
+}
+
+// SetSnapshotEvery also snapshots after every n events queued for the event
+// log; 0 (the default) snapshots on the interval only. Must be called
+// before Start.
+func (p *EventProcessor) SetSnapshotEvery(n uint64) {
+	p.snapshotEvery = n
+}
+
+// EnableClearing records every fill into ch as it is logged, on the
+// processor goroutine, so the clearing house always matches the event log
+// and snapshots capture it consistently with the books. Must be called
+// before Start.
+func (p *EventProcessor) EnableClearing(ch *settlement.ClearingHouse) {
+	p.clearing = ch
+}
+
+// startSnapshots launches the writer and schedules the first snapshot.
+// Called from Start, before the processor goroutine runs.
+func (p *EventProcessor) startSnapshots() {
+	p.snapshotCh = make(chan *snapshot.Image, 1)
+	p.snapshotDone = make(chan struct{})
+	go p.snapshotLoop()
+
+	if p.timers == nil || p.snapshotInterval <= 0 {
+		if p.snapshotEvery == 0 {
+			log.Println("Warning: periodic snapshots disabled (timers not enabled); snapshotting at shutdown only")
+		}
+		return
+	}
+	p.scheduleAfter(p.snapshotInterval, p.takeSnapshot)
+}
+
+// takeSnapshot hands a snapshot to the writer and schedules the next one.
+// Runs on the processor goroutine.
+func (p *EventProcessor) takeSnapshot() {
+	if !p.snapshotNow() {
+		log.Println("Warning: snapshot writer busy, skipping snapshot")
+	}
+	p.scheduleAfter(p.snapshotInterval, p.takeSnapshot)
+}
+
+// maybeSnapshot takes a snapshot once snapshotEvery events have been queued
+// since the last one. Runs on the processor goroutine after each request.
+// A busy writer just defers it to a later request.
+func (p *EventProcessor) maybeSnapshot() {
+	if p.eventBatcher.queued-p.snapshotQueued >= p.snapshotEvery {
+		p.snapshotNow()
+	}
+}
+
+// snapshotNow captures an image for the writer, unless the writer already
+// has one waiting. Returns false if it was skipped.
+func (p *EventProcessor) snapshotNow() bool {
+	if len(p.snapshotCh) == cap(p.snapshotCh) {
+		return false // Only this goroutine sends, so the send below can't block
+	}
+	p.snapshotCh <- p.captureImage()
+	p.snapshotQueued = p.eventBatcher.queued
+	return true
+}
+
+// captureImage copies the resting state of every book, the ID counters,
+// the migrated symbols, the auction calls and the clearing house.
+func (p *EventProcessor) captureImage() *snapshot.Image {
+	img := &snapshot.Image{
+		EventSeq: p.eventBase + p.eventBatcher.queued,
+		Books:    p.engine.RestingOrders(),
+		Counters: p.engine.IDCounters(),
+		Moved:    p.engine.MovedSymbols(),
+		Auctions: p.engine.AuctionSymbols(),
+
+		JournalSeq: p.journaled,
+	}
+	if p.clearing != nil {
+		img.Clearing = p.clearing.Export()
+	}
+	return img
+}
+
+// snapshotLoop writes captured images until the channel is closed.
+func (p *EventProcessor) snapshotLoop() {
+	defer close(p.snapshotDone)
+
+	for img := range p.snapshotCh {
+		wrote, err := p.snapshots.Write(img)
+		if err != nil {
+			log.Printf("ERROR: Failed to write snapshot at event %d: %v", img.EventSeq, err)
+			continue
+		}
+		if wrote && p.snapshots.Stats().DeltasSinceFull == 0 {
+			log.Printf("Wrote full snapshot at event %d (%d orders, %d bytes)",
+				img.EventSeq, img.Orders(), p.snapshots.Stats().LastFullBytes)
+		}
+
+		// Recovery no longer needs the log up to here
+		if err := p.eventBatcher.eventLog.SetRetentionFloor(img.EventSeq); err != nil {
+			log.Printf("ERROR: Event log retention failed: %v", err)
+		}
+
+		// Nor the journaled requests it reflects, once their events are
+		// written (see journal.go)
+		if p.journal != nil && p.eventBatcher.eventLog.GetLastSequence() >= img.EventSeq {
+			if err := p.journal.Compact(img.JournalSeq); err != nil {
+				log.Printf("ERROR: Request journal compaction failed: %v", err)
+			}
+		}
+	}
+}
+
+// stopSnapshots writes a final snapshot of the drained books and waits for
+// the writer. Called from Shutdown after the processor goroutine exits.
+func (p *EventProcessor) stopSnapshots() {
+	p.snapshotCh <- p.captureImage()
+	close(p.snapshotCh)
+	<-p.snapshotDone
+}
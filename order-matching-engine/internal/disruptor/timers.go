@@ -0,0 +1,135 @@
+package disruptor
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/timerwheel"
+)
+
+// Processor Timers
+//
+// Anything the engine must do at a point in time - expire a dead man's
+// switch, and later GTD orders, auction phase changes or settlement cycles -
+// is scheduled on one timer wheel owned by the processor goroutine, rather
+// than a goroutine with time.After per timer racing the order flow.
+//
+// Time reaches the wheel only through the ring buffer:
+//
+//	ticker ──TimerTick{Now}──▶ ring buffer ──▶ processor ──▶ wheel.Advance ──▶ callbacks
+//
+// so a timer callback runs between two requests, like any other request,
+// and never concurrently with matching. Callbacks must therefore not block.
+//
+// Code outside the processor schedules a callback with a Schedule request,
+// which starts the delay at its point in the sequence:
+//
+//	{Type: RequestTypeSchedule, Symbol: "AAPL", Timeout: 5 * time.Minute, OnTimer: fn}   once, in 5 minutes
+//	{Type: RequestTypeSchedule, Repeat: time.Minute, OnTimer: fn}                        at the next tick, then every minute
+//
+// The callback still runs on the processor goroutine: one with work that
+// blocks (publishing, sequencing more requests) hands it to a goroutine.
+//
+// Ticks come from each process's own clock and are never journaled or
+// replicated. Where every node of a cluster must apply a timer's work at
+// the same point of the sequence, OnTimerRequest turns the timers that
+// change state (dead man's switches, the settlement cycle) into requests
+// the leader commits; Schedule callbacks stay local.
+
+// TimerSlots is the number of slots per level of the processor's timer wheel
+const TimerSlots = 512
+
+// errTimersDisabled is returned for requests that need timers when
+// EnableTimers was not called.
+var errTimersDisabled = errors.New("timers not enabled")
+
+// EnableTimers starts publishing a TimerTick request every tick, which
+// drives the processor's timers. Without it, requests that need a timer
+// (heartbeats) are rejected. Must be called before Start.
+func (p *EventProcessor) EnableTimers(tick time.Duration) {
+	p.timerTick = tick
+	p.timers = timerwheel.New(tick, TimerSlots, orders.Now())
+	p.deadMen = make(map[string]*deadMan)
+}
+
+// OnTimerRequest makes the timers that change engine state hand fn the
+// request that does their work (TripDeadMan, Settle) instead of doing it,
+// so a cluster's leader can commit it to every node. fn runs on the
+// processor goroutine and must not block; a request it drops is asked for
+// again when the timer next fires. Must be called before Start.
+func (p *EventProcessor) OnTimerRequest(fn func(req *OrderRequest)) {
+	p.onTimerRequest = fn
+}
+
+// tickLoop publishes timer ticks until shutdown. A tick that can't claim a
+// slot (ring buffer full) is skipped; the next one catches the wheel up.
+func (p *EventProcessor) tickLoop() {
+	sequencer := NewSequencer(p.rb)
+	ticker := time.NewTicker(p.timerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.shutdownCh:
+			return
+		case <-ticker.C:
+			seq, err := sequencer.Next()
+			if err != nil {
+				continue
+			}
+			sequencer.Publish(seq, &OrderRequest{Type: RequestTypeTimerTick, Now: orders.Now()}, nil)
+		}
+	}
+}
+
+// processTimerTick advances the timer wheel, firing expired timers.
+func (p *EventProcessor) processTimerTick(req *OrderRequest) {
+	if p.timers != nil {
+		p.timers.Advance(req.Now)
+	}
+}
+
+// scheduleAfter runs fn on the processor goroutine once d has elapsed,
+// measured from the last tick rather than the wall clock, so deadlines
+// depend only on the sequence of requests. Processor goroutine only.
+func (p *EventProcessor) scheduleAfter(d time.Duration, fn func()) timerwheel.TimerID {
+	return p.timers.Schedule(p.timers.Now()+int64(d), fn)
+}
+
+// cancelTimer stops a timer scheduled with scheduleAfter.
+// Processor goroutine only.
+func (p *EventProcessor) cancelTimer(id timerwheel.TimerID) {
+	p.timers.Cancel(id)
+}
+
+// scheduleRepeat runs fn once d has elapsed, then every interval.
+// Processor goroutine only.
+func (p *EventProcessor) scheduleRepeat(d, interval time.Duration, fn func()) {
+	p.scheduleAfter(d, func() {
+		fn()
+		p.scheduleRepeat(interval, interval, fn)
+	})
+}
+
+// processSchedule schedules a Schedule request's callback.
+func (p *EventProcessor) processSchedule(req *OrderRequest, responseCh chan *OrderResponse) {
+	var err error
+	switch {
+	case p.timers == nil:
+		err = errTimersDisabled
+	case req.OnTimer == nil:
+		err = errors.New("no callback to schedule")
+	case req.Repeat > 0:
+		p.scheduleRepeat(req.Timeout, req.Repeat, req.OnTimer)
+	default:
+		p.scheduleAfter(req.Timeout, req.OnTimer)
+	}
+
+	select {
+	case responseCh <- &OrderResponse{Success: err == nil, Error: err}:
+	default:
+		log.Printf("Warning: Failed to send schedule response")
+	}
+}
@@ -0,0 +1,157 @@
+package disruptor
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Wait Strategies
+//
+// How the processor waits for the next request when the ring buffer is
+// empty trades wake-up latency against CPU:
+//
+//	busy-spin   re-reads the slot in a tight loop: lowest latency, burns a
+//	            core even when idle, and needs a core to itself
+//	yielding    spins briefly, then yields to other goroutines between
+//	            reads: low latency, still busy when idle (the default)
+//	sleeping    spins, yields, then sleeps between reads: near-idle CPU
+//	            when quiet, at the cost of up to a sleep's wake-up delay
+//	blocking    parks on a channel each producer signals on publish:
+//	            idle CPU is zero, wake-up costs a goroutine switch, and
+//	            every publish pays for the signal
+//
+// Whatever the strategy, once woken the processor drains every request
+// already published in one batch and releases their slots to producers
+// together, so a burst costs one wake-up rather than one per request.
+//
+// Benchmarks (disruptor_test.go) compare the four:
+//
+//	go test ./internal/disruptor -run XXX -bench WaitStrategy
+
+// WaitStrategy selects how the processor waits for requests (Config).
+type WaitStrategy uint8
+
+const (
+	WaitYielding WaitStrategy = iota // Spin, then yield between reads (default)
+	WaitBusySpin                     // Spin without yielding
+	WaitSleeping                     // Spin, yield, then sleep between reads
+	WaitBlocking                     // Park until a producer signals
+)
+
+// Spin and yield budgets, and the sleep, of the spinning strategies
+const (
+	waitSpins  = 100
+	waitYields = 100
+	waitSleep  = 100 * time.Microsecond
+)
+
+// WaitStrategies lists every wait strategy.
+var WaitStrategies = []WaitStrategy{WaitBusySpin, WaitYielding, WaitSleeping, WaitBlocking}
+
+func (w WaitStrategy) String() string {
+	switch w {
+	case WaitYielding:
+		return "yielding"
+	case WaitBusySpin:
+		return "busy-spin"
+	case WaitSleeping:
+		return "sleeping"
+	case WaitBlocking:
+		return "blocking"
+	default:
+		return fmt.Sprintf("WaitStrategy(%d)", uint8(w))
+	}
+}
+
+// ParseWaitStrategy parses a wait strategy name as printed by String.
+func ParseWaitStrategy(name string) (WaitStrategy, error) {
+	for _, w := range WaitStrategies {
+		if w.String() == name {
+			return w, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown wait strategy %q (busy-spin, yielding, sleeping or blocking)", name)
+}
+
+// waiter is a wait strategy's implementation, one per ring buffer.
+type waiter interface {
+	// waitFor returns once seq is published to slot, or false if done is
+	// closed first. Consumer goroutine only.
+	waitFor(slot *RingBufferSlot, seq uint64, done <-chan struct{}) bool
+
+	// signal wakes the consumer after a publish. Any goroutine.
+	signal()
+}
+
+// newWaiter returns the implementation of a wait strategy.
+func newWaiter(strategy WaitStrategy) waiter {
+	switch strategy {
+	case WaitYielding:
+		return spinWaiter{spins: waitSpins, yields: -1}
+	case WaitBusySpin:
+		return spinWaiter{spins: -1}
+	case WaitSleeping:
+		return spinWaiter{spins: waitSpins, yields: waitYields, sleep: waitSleep}
+	case WaitBlocking:
+		return &blockingWaiter{wake: make(chan struct{}, 1)}
+	default:
+		panic(fmt.Sprintf("unknown wait strategy %d", strategy))
+	}
+}
+
+// spinWaiter re-reads the slot, spinning for the first spins reads, then
+// yielding for the next yields, then sleeping. -1 never moves on.
+type spinWaiter struct {
+	spins  int
+	yields int
+	sleep  time.Duration
+}
+
+func (w spinWaiter) waitFor(slot *RingBufferSlot, seq uint64, done <-chan struct{}) bool {
+	for tries := 0; atomic.LoadUint64(&slot.SequenceNum) != seq; tries++ {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+
+		switch {
+		case w.spins < 0 || tries < w.spins:
+			// Spin
+		case w.yields < 0 || tries < w.spins+w.yields:
+			runtime.Gosched()
+		default:
+			time.Sleep(w.sleep)
+		}
+	}
+	return true
+}
+
+func (spinWaiter) signal() {}
+
+// blockingWaiter parks the consumer on a channel. The channel holds one
+// wake-up, so a publish between the consumer's last read and its park is
+// not lost; a stale wake-up only costs an extra read.
+type blockingWaiter struct {
+	wake chan struct{}
+}
+
+func (w *blockingWaiter) waitFor(slot *RingBufferSlot, seq uint64, done <-chan struct{}) bool {
+	for atomic.LoadUint64(&slot.SequenceNum) != seq {
+		select {
+		case <-w.wake:
+		case <-done:
+			return false
+		}
+	}
+	return true
+}
+
+func (w *blockingWaiter) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
@@ -0,0 +1,181 @@
+// Package dropcopy implements a per-account drop-copy feed.
+//
+// A drop copy is a second, read-only stream of everything that happens to
+// an account, delivered independently of the connection that submitted the
+// orders. Risk desks and back offices subscribe to it to watch accounts
+// they don't trade for.
+//
+// It carries an execution report for every change to the account's orders
+// (accepted, filled, cancelled, replaced, rejected), whoever caused it, and
+// the account's risk events.
+//
+// Like the market data publisher, delivery is non-blocking: a subscriber
+// that falls behind loses messages rather than stalling the engine. Each
+// account's messages are numbered, so a subscriber can see that it did.
+package dropcopy
+
+import (
+	"sync"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// MessageType identifies what a drop-copy message carries.
+type MessageType string
+
+const (
+	MessageRiskEvent MessageType = "risk_event"       // Kill switch tripped or account reinstated
+	MessageExecution MessageType = "execution_report" // An order's state changed
+)
+
+// Message is one drop-copy update for an account.
+type Message struct {
+	Type      MessageType `json:"type"`
+	Seq       uint64      `json:"seq"` // Per account, from 1; a gap means messages were dropped
+	AccountID string      `json:"account_id"`
+	Risk      *risk.Event `json:"risk,omitempty"`
+	Execution *Execution  `json:"execution,omitempty"`
+
+	// Report is the execution report Execution was made from, for feeds
+	// with their own encoding
+	Report *orders.ExecutionReport `json:"-"`
+}
+
+// Execution is an execution report as sent on the feed, with prices as
+// decimals.
+type Execution struct {
+	ExecType      orders.ExecType `json:"exec_type"`
+	OrderID       uint64          `json:"order_id"`
+	ClientOrderID string          `json:"client_order_id,omitempty"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Status        string          `json:"status"`
+	Price         string          `json:"price,omitempty"`
+	Quantity      int64           `json:"quantity"`
+	TradeID       uint64          `json:"trade_id,omitempty"`
+	LastQty       int64           `json:"last_qty,omitempty"`
+	LastPx        string          `json:"last_px,omitempty"`
+	CumQty        int64           `json:"cum_qty"`
+	AvgPx         string          `json:"avg_px,omitempty"`
+	LeavesQty     int64           `json:"leaves_qty"`
+	IsMaker       bool            `json:"is_maker,omitempty"`
+	Fee           string          `json:"fee,omitempty"` // Negative = rebate
+	Text          string          `json:"text,omitempty"`
+	Timestamp     int64           `json:"timestamp"`
+}
+
+// NewExecution converts an engine execution report for the feed.
+func NewExecution(report orders.ExecutionReport) *Execution {
+	decimal := func(p int64) string {
+		if p == 0 {
+			return ""
+		}
+		return orders.FormatPrice(p)
+	}
+	return &Execution{
+		ExecType:      report.ExecType,
+		OrderID:       report.OrderID,
+		ClientOrderID: report.ClientOrderID,
+		Symbol:        report.Symbol,
+		Side:          report.Side.String(),
+		Status:        report.Status.String(),
+		Price:         decimal(report.Price),
+		Quantity:      report.Quantity,
+		TradeID:       report.TradeID,
+		LastQty:       report.LastQty,
+		LastPx:        decimal(report.LastPx),
+		CumQty:        report.CumQty,
+		AvgPx:         decimal(report.AvgPx),
+		LeavesQty:     report.LeavesQty,
+		IsMaker:       report.IsMaker,
+		Fee:           decimal(report.Fee),
+		Text:          report.Text,
+		Timestamp:     report.Timestamp,
+	}
+}
+
+// Hub fans drop-copy messages out to per-account subscribers.
+type Hub struct {
+	mu         sync.Mutex
+	subs       map[string][]chan Message // account -> subscribers
+	seq        map[string]uint64         // account -> last message number
+	bufferSize int
+}
+
+// NewHub creates a drop-copy hub.
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 100
+	}
+	return &Hub{
+		subs:       make(map[string][]chan Message),
+		seq:        make(map[string]uint64),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe returns a channel receiving every message for an account.
+func (h *Hub) Subscribe(accountID string) <-chan Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Message, h.bufferSize)
+	h.subs[accountID] = append(h.subs[accountID], ch)
+	return ch
+}
+
+// Unsubscribe removes and closes a subscription channel.
+func (h *Hub) Unsubscribe(accountID string, ch <-chan Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[accountID]
+	for i, sub := range subs {
+		if sub == ch {
+			h.subs[accountID] = append(subs[:i], subs[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+// Publish numbers a message and sends it to the account's subscribers.
+// Non-blocking: drops the message for any subscriber whose channel is full.
+func (h *Hub) Publish(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq[msg.AccountID]++
+	msg.Seq = h.seq[msg.AccountID]
+	for _, ch := range h.subs[msg.AccountID] {
+		select {
+		case ch <- msg:
+		default:
+			// Channel full, drop message (subscriber is slow)
+		}
+	}
+}
+
+// PublishRiskEvent sends a risk event to the account's subscribers.
+func (h *Hub) PublishRiskEvent(event risk.Event) {
+	h.Publish(Message{Type: MessageRiskEvent, AccountID: event.AccountID, Risk: &event})
+}
+
+// PublishExecution sends an execution report to the account's subscribers.
+func (h *Hub) PublishExecution(report orders.ExecutionReport) {
+	h.Publish(Message{Type: MessageExecution, AccountID: report.AccountID, Execution: NewExecution(report), Report: &report})
+}
+
+// Close closes all subscription channels.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	h.subs = make(map[string][]chan Message)
+}
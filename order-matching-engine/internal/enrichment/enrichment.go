@@ -0,0 +1,135 @@
+// Package enrichment turns fills into public trade reports.
+//
+// Public Tape vs Private Clearing:
+// A fill names both accounts: clearing needs them to settle, and the audit
+// trail needs them to reconstruct who traded. The public tape must not: a
+// competitor who can see which account bought would read its strategy off
+// the tape. Venues that show counterparties at all show codes instead:
+//
+//	fill (private)                         trade report (public)
+//	trade 42  100 AAPL @ 150.00            trade 42  100 AAPL @ 150.00
+//	  buyer  TRADER1  (taker)       ──▶      buyer  7F3A9C21D0
+//	  seller MM1      (maker)                seller 0B44E81A9F
+//
+// Real account IDs stay on the private paths (clearing, risk, drop copy,
+// the event and audit logs). Everything published goes through a Pipeline
+// first, whose stages build the report from the fill.
+//
+// Counterparty codes:
+// A code is a keyed hash of the account and the trading day, so an account
+// keeps one code all day - the tape still shows that the same party is
+// buying all morning - but a new one the next day, and nobody without the
+// key can link codes to accounts or to each other across days. Operators
+// can reverse a code through the Anonymizer, which remembers the codes it
+// issued for the current and previous day.
+package enrichment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Stage adds to a trade report built from a fill.
+type Stage func(fill orders.Fill, report *marketdata.TradeReport)
+
+// Pipeline builds public trade reports from fills.
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline running stages in order.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Enrich builds the public trade report for a fill. Only the trade's
+// public fields are copied; stages add the rest.
+func (p *Pipeline) Enrich(fill orders.Fill) marketdata.TradeReport {
+	report := marketdata.TradeReport{
+		TradeID:       fill.TradeID,
+		Symbol:        fill.Symbol,
+		Price:         fill.Price,
+		Quantity:      fill.Quantity,
+		AggressorSide: fill.TakerSide,
+		Timestamp:     fill.Timestamp,
+	}
+	for _, stage := range p.stages {
+		stage(fill, &report)
+	}
+	return report
+}
+
+// codeLength is the length of a counterparty code in hex digits.
+const codeLength = 10
+
+// Anonymizer issues counterparty codes. Safe for concurrent use.
+type Anonymizer struct {
+	key []byte
+
+	mu       sync.Mutex
+	day      string            // Trading day of issued (UTC date)
+	issued   map[string]string // Code -> account, for day
+	previous map[string]string // Code -> account, for the day before
+}
+
+// NewAnonymizer creates an anonymizer keyed with key.
+func NewAnonymizer(key []byte) *Anonymizer {
+	return &Anonymizer{
+		key:    key,
+		issued: make(map[string]string),
+	}
+}
+
+// Code returns an account's counterparty code on the trading day of at
+// (nanoseconds since epoch).
+func (a *Anonymizer) Code(accountID string, at int64) string {
+	day := time.Unix(0, at).UTC().Format("2006-01-02")
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(day + "|" + accountID))
+	code := strings.ToUpper(hex.EncodeToString(mac.Sum(nil))[:codeLength])
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if day > a.day {
+		a.previous, a.issued = a.issued, make(map[string]string)
+		if a.day != "" && time.Unix(0, at).UTC().AddDate(0, 0, -1).Format("2006-01-02") != a.day {
+			a.previous = nil // More than a day since the last trade
+		}
+		a.day = day
+	}
+	if day == a.day {
+		a.issued[code] = accountID
+	}
+	return code
+}
+
+// Reveal returns the account behind a code issued today or yesterday.
+func (a *Anonymizer) Reveal(code string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	code = strings.ToUpper(code)
+	if accountID, ok := a.issued[code]; ok {
+		return accountID, true
+	}
+	accountID, ok := a.previous[code]
+	return accountID, ok
+}
+
+// Counterparties is the stage that sets a report's buyer and seller codes.
+func Counterparties(a *Anonymizer) Stage {
+	return func(fill orders.Fill, report *marketdata.TradeReport) {
+		buyer, seller := fill.TakerAccountID, fill.MakerAccountID
+		if fill.TakerSide == orders.SideSell {
+			buyer, seller = seller, buyer
+		}
+		report.BuyerCode = a.Code(buyer, fill.Timestamp)
+		report.SellerCode = a.Code(seller, fill.Timestamp)
+	}
+}
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Record Codecs:
+//
+// A record's frame is the same whatever encodes its event:
+//
+//	marker   1  0xEB, a byte no gob message starts with
+//	length   4  big-endian, bytes from codec through payload
+//	codec    1  ID of the Codec that encoded the payload
+//	version  4  schema version the event was written with
+//	seq      8  sequence number
+//	type     1  EventType
+//	payload  n  the event, as the codec encodes it
+//	crc32    4  IEEE, over codec through payload
+//
+// The checksum covers the bytes as written, so any change to a record is
+// caught, and a record can be verified without decoding it.
+//
+// Logs written before records were framed are a gob stream of eventRecords.
+// Replay still reads them, even with framed records appended after them: a
+// gob message never starts with the marker, so each record is told apart by
+// its first byte. Rewrite converts such a log to framed records.
+
+// Codec encodes events as record payloads.
+type Codec interface {
+	// ID identifies the codec in the records it writes. It must never be
+	// reused for another encoding.
+	ID() byte
+
+	// Name is how the codec is chosen in configuration.
+	Name() string
+
+	// Marshal appends the encoding of event to dst.
+	Marshal(dst []byte, event interface{}) ([]byte, error)
+
+	// Unmarshal decodes a payload Marshal produced for an event of the
+	// given type.
+	Unmarshal(eventType EventType, payload []byte) (interface{}, error)
+}
+
+// codecs are the registered codecs by ID.
+var codecs = map[byte]Codec{}
+
+// RegisterCodec makes a codec available for writing by name and for reading
+// the records it wrote. Panics if its ID is taken.
+func RegisterCodec(codec Codec) {
+	if existing, ok := codecs[codec.ID()]; ok {
+		panic(fmt.Sprintf("events: codec ID %d of %s is taken by %s", codec.ID(), codec.Name(), existing.Name()))
+	}
+	codecs[codec.ID()] = codec
+}
+
+// CodecByName returns the registered codec called name.
+func CodecByName(name string) (Codec, error) {
+	var names []string
+	for _, codec := range codecs {
+		if codec.Name() == name {
+			return codec, nil
+		}
+		names = append(names, codec.Name())
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown event codec %q (have %s)", name, strings.Join(names, ", "))
+}
+
+func init() {
+	RegisterCodec(ProtobufCodec{})
+	RegisterCodec(GobCodec{})
+}
+
+const (
+	frameMarker = 0xEB
+
+	// frameHeader is the bytes of a frame's length field covers before the
+	// payload: codec, version, seq and type.
+	frameHeader = 1 + 4 + 8 + 1
+
+	// maxFrame bounds the length read from a frame, so a damaged length
+	// can't demand an absurd allocation.
+	maxFrame = 64 << 20
+)
+
+// appendFrame appends the framed record of an event to dst.
+func appendFrame(dst []byte, codec Codec, seq uint64, eventType EventType, event interface{}) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, frameMarker, 0, 0, 0, 0, codec.ID())
+	dst = binary.BigEndian.AppendUint32(dst, SchemaVersion)
+	dst = binary.BigEndian.AppendUint64(dst, seq)
+	dst = append(dst, byte(eventType))
+	dst, err := codec.Marshal(dst, event)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	body := dst[start+5:]
+	binary.BigEndian.PutUint32(dst[start+1:], uint32(len(body)))
+	return binary.BigEndian.AppendUint32(dst, crc32.ChecksumIEEE(body)), nil
+}
+
+// readFrame reads the framed record r is positioned at, marker included.
+// The payload is decoded later, by record.event.
+func readFrame(r io.Reader, record *eventRecord) error {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(head[1:])
+	if n < frameHeader || n > maxFrame {
+		return fmt.Errorf("invalid record length %d", n)
+	}
+	body := make([]byte, n+4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	record.framed = true
+	record.codec = codecs[body[0]]
+	record.Version = binary.BigEndian.Uint32(body[1:])
+	record.SequenceNum = binary.BigEndian.Uint64(body[5:])
+	record.Type = EventType(body[13])
+	record.payload = body[frameHeader:n]
+	record.Checksum = binary.BigEndian.Uint32(body[n:])
+	record.sum = crc32.ChecksumIEEE(body[:n])
+	if record.codec == nil {
+		record.codecID = body[0]
+	}
+	return nil
+}
+
+// GobCodec encodes each event as a gob stream of its own, type definitions
+// included: several times the size of ProtobufCodec's encoding, and readable
+// only from Go.
+type GobCodec struct{}
+
+func (GobCodec) ID() byte     { return 2 }
+func (GobCodec) Name() string { return "gob" }
+
+func (GobCodec) Marshal(dst []byte, event interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	if err := gob.NewEncoder(buf).Encode(&event); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(eventType EventType, payload []byte) (interface{}, error) {
+	var event interface{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Rewrite appends every event of src to dst, which must be empty: upgraded
+// to the current schema, and encoded with dst's codec. This migrates a log,
+// e.g. one still holding gob records, without changing sequence numbers, so
+// src must hold every event from 1 on. Returns the events rewritten.
+func Rewrite(src, dst *EventLog) (int, error) {
+	if last := dst.GetLastSequence(); last != 0 {
+		return 0, fmt.Errorf("rewrite target already holds events up to %d", last)
+	}
+
+	var n int
+	err := src.Replay(func(seqNum uint64, event interface{}) error {
+		written, err := dst.Append(event)
+		if err != nil {
+			return err
+		}
+		if written != seqNum {
+			return fmt.Errorf("%w: event %d rewritten as %d", ErrSequenceGap, seqNum, written)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, dst.Sync()
+}
@@ -3,21 +3,45 @@ package events
 import (
 	"bufio"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"os"
+	"strings"
 	"sync"
 )
 
+// ErrChecksumMismatch is returned by Replay when a record's checksum does not
+// match its contents, indicating on-disk corruption.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrSequenceGap is returned by Replay when sequence numbers skip, i.e.
+// events are missing from the log.
+var ErrSequenceGap = errors.New("sequence gap")
+
+// ErrTruncated is returned by Replay when retention has already removed
+// events the replay needs.
+var ErrTruncated = errors.New("event log truncated")
+
+// ErrTornWrite is returned when a log file ends in a partly written record,
+// as a crash in the middle of an append leaves it. Opening the log drops
+// such a record from the active file (see TornBytes).
+var ErrTornWrite = errors.New("torn write")
+
+// ErrReadOnly is returned by the writes of a log opened with OpenReadOnly.
+var ErrReadOnly = errors.New("event log opened read-only")
+
 // EventLog is an append-only, durable event log.
 //
 // Design Decisions:
 //
-// 1. Binary Format: We use gob encoding for simplicity, but production systems
-//    would use a more compact format (protobuf, flatbuffers, or custom binary).
+// 1. Binary Format: Each record is a small binary frame around the event,
+//    encoded by a pluggable Codec: protobuf by default (see codec.go).
 //
-// 2. Checksums: Each event has a CRC32 checksum to detect corruption.
+// 2. Checksums: Each record has a CRC32 checksum of its bytes to detect
+//    corruption. A record cut short at the end of the active file is a torn
+//    write, never acknowledged: it is truncated away on open.
 //
 // 3. Sync Options: We support both synchronous (fsync per write) and asynchronous
 //    modes. Sync mode guarantees durability but is slower.
@@ -25,59 +49,206 @@ import (
 // 4. Sequence Numbers: Each event has a monotonically increasing sequence number
 //    for gap detection and ordering.
 //
+// 5. Segments: With SegmentMaxBytes set, the log rotates into closed segment
+//    files listed in a manifest, and old segments can be archived or deleted
+//    (see segments.go).
+//
+// 6. Tailing: Tail streams events to in-process subscribers as they are
+//    appended, after catching them up from disk (see tail.go).
+//
 // Production Considerations:
 // - Real systems use write-ahead logs (WAL) with battery-backed RAM
-// - Segment files (rotate when size limit reached) for easy cleanup
 // - Compression for storage efficiency
 // - Replication for fault tolerance
 type EventLog struct {
 	file        *os.File
 	writer      *bufio.Writer
-	encoder     *gob.Encoder
+	codec       Codec  // Encodes new records
+	buf         []byte // Reused to frame records
 	mu          sync.Mutex
 	sequenceNum uint64
 	syncMode    bool // If true, fsync after every write
 	path        string
+
+	// Segments (see segments.go)
+	size      int64     // Bytes in the active file
+	maxBytes  int64     // Rotate the active file at this size (0 = never)
+	retention Retention // What happens to old closed segments
+	segments  []Segment // Closed segments, oldest first
+	firstSeq  uint64    // First sequence number in the active file
+	floor     uint64    // Segments ending at or below this may be expired
+
+	onDamage func(d *Damage) error // Optional replay damage hook (see OnDamage)
+	torn     int64                 // Bytes of a torn record dropped on open
+
+	onAppend []func(seqNum uint64, event interface{}) // Notified of each record written (see OnAppend)
+
+	// Tail subscribers (see tail.go)
+	tails       map[<-chan Record]*tail
+	tailBacklog int
+	closed      bool
+}
+
+// Damage is a problem replay found in the log: a record failing its
+// checksum, or missing events. Its Err wraps ErrChecksumMismatch or
+// ErrSequenceGap.
+type Damage struct {
+	Seq    uint64 // Sequence number of the bad record, or the one after a gap
+	Symbol string      // Symbol of the bad record ("" for gaps: the missing events' symbols are unknown)
+	Event  interface{} // The bad record's event as decoded, suspect (nil for gaps)
+	Err    error
 }
 
+func (d *Damage) Error() string { return d.Err.Error() }
+func (d *Damage) Unwrap() error { return d.Err }
+
 // EventLogConfig configures the event log.
 type EventLogConfig struct {
 	Path     string
 	SyncMode bool // If true, fsync after every write (slower but durable)
+
+	// Codec encodes new records (default ProtobufCodec). Records already in
+	// the log are read with whichever codec wrote them.
+	Codec Codec
+
+	// SegmentMaxBytes rotates the active file into a closed segment once it
+	// reaches this size (0 = one unbounded file).
+	SegmentMaxBytes int64
+
+	// Retention decides which closed segments are archived or deleted.
+	Retention Retention
+
+	// TailBacklog is the number of appended events queued for a Tail
+	// subscriber before it is dropped (default DefaultTailBacklog).
+	TailBacklog int
 }
 
 // NewEventLog creates a new event log.
 func NewEventLog(config EventLogConfig) (*EventLog, error) {
-	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open event log: %w", err)
+	log := &EventLog{
+		syncMode:    config.SyncMode,
+		path:        config.Path,
+		maxBytes:    config.SegmentMaxBytes,
+		retention:   config.Retention,
+		codec:       config.Codec,
+		tailBacklog: config.TailBacklog,
+	}
+	if log.codec == nil {
+		log.codec = ProtobufCodec{}
+	}
+	if log.tailBacklog <= 0 {
+		log.tailBacklog = DefaultTailBacklog
 	}
 
-	writer := bufio.NewWriter(file)
-
-	log := &EventLog{
-		file:     file,
-		writer:   writer,
-		encoder:  gob.NewEncoder(writer),
-		syncMode: config.SyncMode,
-		path:     config.Path,
+	// Closed segments first: the active file continues where they end
+	if err := log.loadSegments(); err != nil {
+		return nil, fmt.Errorf("failed to load event log segments: %w", err)
+	}
+	if err := log.openActive(); err != nil {
+		return nil, err
 	}
 
 	// Read existing events to get last sequence number
 	if err := log.recover(); err != nil {
-		file.Close()
+		log.file.Close()
 		return nil, fmt.Errorf("failed to recover event log: %w", err)
 	}
 
 	return log, nil
 }
 
-// eventRecord is the on-disk format for events.
+// OpenReadOnly opens an existing log to be read, e.g. by a tool inspecting
+// a running engine's log: nothing is created, truncated or rewritten, and
+// Append, Sync, TruncateAfter and SetRetentionFloor return ErrReadOnly. A
+// torn final record is left where it is; Scan stops before it.
+func OpenReadOnly(path string) (*EventLog, error) {
+	log := &EventLog{path: path, codec: ProtobufCodec{}, tailBacklog: DefaultTailBacklog}
+	if _, err := log.readSegments(); err != nil {
+		return nil, fmt.Errorf("failed to load event log segments: %w", err)
+	}
+	if n := len(log.segments); n > 0 {
+		log.sequenceNum = log.segments[n-1].LastSeq
+	}
+
+	first, last, _, err := scanFile(path)
+	if os.IsNotExist(err) && len(log.segments) > 0 {
+		err = nil // Rotated, and nothing appended since
+	}
+	if err != nil && !errors.Is(err, ErrTornWrite) {
+		return nil, err
+	}
+	if last > 0 {
+		log.firstSeq, log.sequenceNum = first, last
+	}
+	return log, nil
+}
+
+// openActive opens (creating if needed) the active file for appending.
+func (l *EventLog) openActive() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	l.writer = bufio.NewWriter(&countingWriter{w: file, n: &l.size})
+	return nil
+}
+
+// eventRecord is a record as read from the log. Its exported fields are
+// also the gob-encoded format of records written before framing.
 type eventRecord struct {
 	SequenceNum uint64
+	Version     uint32 // Schema version Data was written with (0 = pre-versioning, v1)
 	Type        EventType
-	Data        interface{}
+	Data        interface{} // Gob records only; framed records decode payload
 	Checksum    uint32
+
+	// Framed records (see codec.go)
+	framed  bool
+	codec   Codec // nil if codecID isn't registered
+	codecID byte
+	payload []byte
+	sum     uint32 // Checksum of the record as read
+}
+
+// intact reports whether the record matches its checksum.
+func (r *eventRecord) intact() bool {
+	if r.framed {
+		return r.sum == r.Checksum
+	}
+	// Gob records checksum the printed event, in the shape it was written
+	// with (simplified)
+	return r.Checksum == crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v", r.Data)))
+}
+
+// damage describes the record failing its checksum.
+func (r *eventRecord) damage() *Damage {
+	damage := &Damage{Seq: r.SequenceNum}
+	if data, err := r.event(); err == nil {
+		if event, err := Upgrade(r.Version, data); err == nil {
+			damage.Event, damage.Symbol = event, SymbolOf(event) // As recorded: may be corrupt too
+		}
+	}
+	damage.Err = fmt.Errorf("%w at sequence %d", ErrChecksumMismatch, r.SequenceNum)
+	return damage
+}
+
+// event returns the record's event as written, before migration.
+func (r *eventRecord) event() (interface{}, error) {
+	if !r.framed {
+		return r.Data, nil
+	}
+	if r.codec == nil {
+		return nil, fmt.Errorf("unknown event codec %d", r.codecID)
+	}
+	return r.codec.Unmarshal(r.Type, r.payload)
 }
 
 // Append writes an event to the log.
@@ -86,8 +257,14 @@ func (l *EventLog) Append(event interface{}) (uint64, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	l.sequenceNum++
-	seqNum := l.sequenceNum
+	if l.file == nil {
+		return 0, ErrReadOnly
+	}
+	eventType := TypeOf(event)
+	if eventType == 0 {
+		return 0, fmt.Errorf("failed to encode event: unknown event type %T", event)
+	}
+	seqNum := l.sequenceNum + 1
 
 	// Set sequence number on the event
 	switch e := event.(type) {
@@ -103,22 +280,20 @@ func (l *EventLog) Append(event interface{}) (uint64, error) {
 		e.SequenceNum = seqNum
 	case *OrderCancelledEvent:
 		e.SequenceNum = seqNum
+	case *OrderReplacedEvent:
+		e.SequenceNum = seqNum
 	}
 
-	// Create record
-	record := eventRecord{
-		SequenceNum: seqNum,
-		Data:        event,
-	}
-
-	// Calculate checksum (simplified - real impl would checksum encoded bytes)
-	record.Checksum = crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v", event)))
-
-	// Write length prefix (for easier recovery)
-	// In production, we'd write: [length][type][data][checksum]
-	if err := l.encoder.Encode(record); err != nil {
+	// Frame the record: [marker][length][codec, version, seq, type][event][crc32]
+	record, err := appendFrame(l.buf[:0], l.codec, seqNum, eventType, event)
+	if err != nil {
 		return 0, fmt.Errorf("failed to encode event: %w", err)
 	}
+	l.buf = record
+	if _, err := l.writer.Write(record); err != nil {
+		return 0, fmt.Errorf("failed to write event: %w", err)
+	}
+	l.sequenceNum = seqNum
 
 	// Flush buffer
 	if err := l.writer.Flush(); err != nil {
@@ -132,14 +307,108 @@ func (l *EventLog) Append(event interface{}) (uint64, error) {
 		}
 	}
 
+	if l.firstSeq == 0 {
+		l.firstSeq = seqNum
+	}
+	for _, fn := range l.onAppend {
+		fn(seqNum, event)
+	}
+	if len(l.tails) > 0 {
+		l.notifyTails(Record{Seq: seqNum, Type: eventType, Event: event})
+	}
+	if l.maxBytes > 0 && l.size >= l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return seqNum, fmt.Errorf("event %d written, but rotation failed: %w", seqNum, err)
+		}
+	}
+
 	return seqNum, nil
 }
 
 // Replay reads all events and calls the handler for each.
 // Used to rebuild state after restart.
+//
+// Events written with an older schema version are migrated to the current
+// types (see schema.go), so the handler never sees legacy shapes.
 func (l *EventLog) Replay(handler func(seqNum uint64, event interface{}) error) error {
+	return l.ReplayFrom(0, handler)
+}
+
+// ReplayFrom is Replay for only the events after sequence number after,
+// e.g. the tail of the log past a snapshot. Closed segments that end at or
+// before it are skipped unread. Earlier records in the segment it falls in
+// are still read (a segment has no index to seek by) but are neither
+// verified, migrated nor handed to the handler.
+//
+// Returns ErrTruncated if retention has already deleted events after
+// after.
+func (l *EventLog) ReplayFrom(after uint64, handler func(seqNum uint64, event interface{}) error) error {
+	return l.replayFrom(after, nil, handler)
+}
+
+// Scan reads every event like Replay, for queries over a live log rather
+// than recovery: damaged records are skipped without calling the OnDamage
+// hook, a record still being appended ends the scan, and the handler can
+// stop the scan early by returning ErrStopScan.
+func (l *EventLog) Scan(handler func(seqNum uint64, event interface{}) error) error {
+	return l.ScanFrom(0, handler)
+}
+
+// ScanFrom is Scan for only the events after sequence number after, read
+// the way ReplayFrom reads them.
+func (l *EventLog) ScanFrom(after uint64, handler func(seqNum uint64, event interface{}) error) error {
+	err := l.replayFrom(after, func(d *Damage) error { return nil }, handler)
+	if errors.Is(err, ErrStopScan) || errors.Is(err, ErrTornWrite) {
+		return nil
+	}
+	return err
+}
+
+// ErrStopScan ends a Scan early without error.
+var ErrStopScan = errors.New("events: stop scan")
+
+// replayFrom is ReplayFrom with onDamage in place of the OnDamage hook
+// (nil = the hook).
+func (l *EventLog) replayFrom(after uint64, onDamage func(d *Damage) error, handler func(seqNum uint64, event interface{}) error) error {
+	l.mu.Lock()
+	paths := make([]string, 0, len(l.segments)+1)
+	first := l.firstSeq
+	if len(l.segments) > 0 {
+		first = l.segments[0].FirstSeq
+	}
+	for _, seg := range l.segments {
+		if seg.LastSeq > after {
+			paths = append(paths, seg.Path)
+		}
+	}
+	paths = append(paths, l.path)
+	if onDamage == nil {
+		onDamage = l.onDamage
+	}
+	l.mu.Unlock()
+	if onDamage == nil {
+		onDamage = func(d *Damage) error { return d }
+	}
+
+	if first > after+1 {
+		return fmt.Errorf("%w: events %d-%d are gone, replay needs them from %d",
+			ErrTruncated, 1, first-1, after+1)
+	}
+
+	var lastSeq uint64
+	for _, path := range paths {
+		if err := replayFile(path, after, &lastSeq, onDamage, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayFile replays one segment file, continuing the gap check from
+// *lastSeq.
+func replayFile(path string, after uint64, lastSeq *uint64, onDamage func(d *Damage) error, handler func(seqNum uint64, event interface{}) error) error {
 	// Open a separate file handle for reading
-	file, err := os.Open(l.path)
+	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // Empty log
@@ -148,8 +417,7 @@ func (l *EventLog) Replay(handler func(seqNum uint64, event interface{}) error)
 	}
 	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	var lastSeq uint64
+	decoder := newRecordDecoder(file)
 
 	for {
 		var record eventRecord
@@ -161,19 +429,38 @@ func (l *EventLog) Replay(handler func(seqNum uint64, event interface{}) error)
 		}
 
 		// Check for gaps
-		if lastSeq > 0 && record.SequenceNum != lastSeq+1 {
-			return fmt.Errorf("sequence gap detected: expected %d, got %d",
-				lastSeq+1, record.SequenceNum)
+		if *lastSeq > 0 && record.SequenceNum != *lastSeq+1 {
+			err := onDamage(&Damage{
+				Seq: record.SequenceNum,
+				Err: fmt.Errorf("%w detected: expected %d, got %d", ErrSequenceGap, *lastSeq+1, record.SequenceNum),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		*lastSeq = record.SequenceNum
+		if record.SequenceNum <= after {
+			continue
 		}
-		lastSeq = record.SequenceNum
 
-		// Verify checksum (simplified)
-		expectedChecksum := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v", record.Data)))
-		if record.Checksum != expectedChecksum {
-			return fmt.Errorf("checksum mismatch at sequence %d", record.SequenceNum)
+		// Verify the checksum before decoding or migrating the event
+		if !record.intact() {
+			if err := onDamage(record.damage()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := record.event()
+		if err != nil {
+			return fmt.Errorf("failed to decode event at sequence %d: %w", record.SequenceNum, err)
+		}
+		event, err := Upgrade(record.Version, data)
+		if err != nil {
+			return fmt.Errorf("failed to migrate event at sequence %d: %w", record.SequenceNum, err)
 		}
 
-		if err := handler(record.SequenceNum, record.Data); err != nil {
+		if err := handler(record.SequenceNum, event); err != nil {
 			return fmt.Errorf("handler error at sequence %d: %w", record.SequenceNum, err)
 		}
 	}
@@ -181,18 +468,74 @@ func (l *EventLog) Replay(handler func(seqNum uint64, event interface{}) error)
 	return nil
 }
 
-// recover reads the log to find the last sequence number.
+// OnDamage registers a hook that decides what replay does about damage.
+// Returning nil carries on: a record failing its checksum is skipped, and
+// replay continues after a gap. Returning an error stops the replay with
+// it. Without a hook, replay stops at the first damage, returning it.
+// Must be set before replaying.
+func (l *EventLog) OnDamage(fn func(d *Damage) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onDamage = fn
+}
+
+// OnAppend registers a hook called with each event once it is written and
+// flushed (synced too, in sync mode), in sequence order. It runs under the
+// log's lock, so it must not block or use the log; anything slow belongs
+// on another goroutine. A reader that opens the log after registering sees
+// every event the hook was not called with.
+func (l *EventLog) OnAppend(fn func(seqNum uint64, event interface{})) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onAppend = append(l.onAppend, fn)
+}
+
+// recover reads the active file to find the last sequence number. An empty
+// active file continues from the last closed segment.
+//
+// A torn record at the end of the active file is truncated: the append
+// that wrote it never returned, so nothing was acknowledged on the strength
+// of it, and new records must not follow its garbage.
 func (l *EventLog) recover() error {
-	file, err := os.Open(l.path)
+	if n := len(l.segments); n > 0 {
+		l.sequenceNum = l.segments[n-1].LastSeq
+	}
+
+	first, last, end, err := scanFile(l.path)
+	if errors.Is(err, ErrTornWrite) {
+		if err := l.file.Truncate(end); err != nil {
+			return fmt.Errorf("failed to truncate torn write: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to truncate torn write: %w", err)
+		}
+		l.torn, l.size = l.size-end, end
+		err = nil
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // New log
 		}
 		return err
 	}
+	if last > 0 {
+		l.firstSeq, l.sequenceNum = first, last
+	}
+	return nil
+}
+
+// scanFile returns the first and last sequence numbers in a log file, or
+// zeros if it holds no events, and the offset its last whole record ends
+// at. A torn final record is returned as an ErrTornWrite error, along with
+// the numbers of the records before it.
+func scanFile(path string) (first, last uint64, end int64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
 	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
+	decoder := newRecordDecoder(file)
 
 	for {
 		var record eventRecord
@@ -200,14 +543,201 @@ func (l *EventLog) recover() error {
 			if err == io.EOF {
 				break
 			}
+			if errors.Is(err, ErrTornWrite) {
+				return first, last, end, err
+			}
+			return 0, 0, 0, err
+		}
+		if first == 0 {
+			first = record.SequenceNum
+		}
+		last, end = record.SequenceNum, decoder.reader.n
+	}
+
+	return first, last, end, nil
+}
+
+// recordDecoder decodes records from a log of framed records, gob records
+// written before framing, or both (see codec.go).
+//
+// Gob records form several concatenated gob streams: each process that
+// opened the log appended with a fresh gob.Encoder, which re-sent its type
+// definitions. A single gob.Decoder rejects those as duplicates, so on that
+// error the decoder rewinds to the start of the failed record (the start of
+// the next stream) and starts over.
+type recordDecoder struct {
+	file    *os.File
+	reader  *countingReader
+	decoder *gob.Decoder
+	start   int64 // Offset of the record last decoded
+}
+
+func newRecordDecoder(file *os.File) *recordDecoder {
+	d := &recordDecoder{file: file}
+	d.reset(0)
+	return d
+}
+
+func (d *recordDecoder) reset(offset int64) {
+	d.reader = &countingReader{r: bufio.NewReader(d.file), n: offset}
+	d.decoder = gob.NewDecoder(d.reader)
+}
+
+// Decode reads the next record.
+//
+// A record running past the end of the file, or a framed record failing
+// its checksum with nothing after it, is what an append cut short by a
+// crash leaves behind: Decode returns it as ErrTornWrite. A bad record
+// with more after it is damage, left to the caller's checksum check.
+func (d *recordDecoder) Decode(record *eventRecord) error {
+	next, err := d.reader.r.Peek(1)
+	if err != nil {
+		return err
+	}
+	d.start = d.reader.n
+	if next[0] == frameMarker {
+		err := readFrame(d.reader, record)
+		if err == nil && !record.intact() && d.atEnd() {
+			err = io.ErrUnexpectedEOF
+		}
+		return d.torn(err)
+	}
+
+	err = d.decoder.Decode(record)
+	if err == nil || !strings.Contains(err.Error(), "duplicate type") {
+		return d.torn(err)
+	}
+
+	// A new stream starts at this record
+	if _, err := d.file.Seek(d.start, io.SeekStart); err != nil {
+		return err
+	}
+	d.reset(d.start)
+	return d.torn(d.decoder.Decode(record))
+}
+
+// atEnd reports whether the file has no more bytes.
+func (d *recordDecoder) atEnd() bool {
+	_, err := d.reader.r.Peek(1)
+	return err == io.EOF
+}
+
+// torn turns a record cut short by the end of the file into ErrTornWrite.
+func (d *recordDecoder) torn(err error) error {
+	if err != io.ErrUnexpectedEOF {
+		return err
+	}
+	return fmt.Errorf("%w: %d bytes from offset %d", ErrTornWrite, d.tail(), d.start)
+}
+
+// tail returns the bytes from the start of the record last decoded to the
+// end of the file.
+func (d *recordDecoder) tail() int64 {
+	info, err := d.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size() - d.start
+}
+
+// countingWriter tracks the bytes written to the active file.
+type countingWriter struct {
+	w *os.File
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// countingReader tracks the file offset consumed by the decoders.
+// It implements io.ByteReader so gob does not add its own read-ahead buffer.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// TruncateAfter drops the events after sequence number seq, which must be
+// in the active file (or end the last closed segment), so the next append
+// is seq+1. Recovery uses it to drop the events of requests it is about to
+// process again (see internal/wal).
+func (l *EventLog) TruncateAfter(seq uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrReadOnly
+	}
+	if seq >= l.sequenceNum {
+		return nil
+	}
+	if l.firstSeq == 0 || seq < l.firstSeq-1 {
+		return fmt.Errorf("cannot truncate the event log after %d: events from %d on are in closed segments", seq, seq+1)
+	}
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+
+	// Find where the record for seq ends
+	var end int64
+	if seq >= l.firstSeq {
+		file, err := os.Open(l.path)
+		if err != nil {
 			return err
 		}
-		l.sequenceNum = record.SequenceNum
+		decoder := newRecordDecoder(file)
+		for {
+			var record eventRecord
+			err := decoder.Decode(&record)
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("failed to find event %d: %w", seq, err)
+			}
+			if record.SequenceNum == seq {
+				end = decoder.reader.n
+				break
+			}
+		}
+		file.Close()
 	}
 
+	if err := l.file.Truncate(end); err != nil {
+		return fmt.Errorf("failed to truncate event log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to truncate event log: %w", err)
+	}
+	l.size, l.sequenceNum = end, seq
+	if end == 0 {
+		l.firstSeq = 0
+	}
 	return nil
 }
 
+// TornBytes returns the size of the torn record dropped from the end of the
+// active file when the log was opened, or 0 if it ended cleanly.
+func (l *EventLog) TornBytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.torn
+}
+
 // GetLastSequence returns the last sequence number.
 func (l *EventLog) GetLastSequence() uint64 {
 	l.mu.Lock()
@@ -220,29 +750,30 @@ func (l *EventLog) Sync() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.file == nil {
+		return ErrReadOnly
+	}
 	if err := l.writer.Flush(); err != nil {
 		return err
 	}
 	return l.file.Sync()
 }
 
-// Close closes the event log.
+// Close closes the event log, ending every tail.
 func (l *EventLog) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.closed = true
+	for _, t := range l.tails {
+		l.dropTail(t)
+	}
+
+	if l.file == nil {
+		return nil // Read-only
+	}
 	if err := l.writer.Flush(); err != nil {
 		return err
 	}
 	return l.file.Close()
 }
-
-// Register gob types for encoding/decoding
-func init() {
-	gob.Register(&NewOrderEvent{})
-	gob.Register(&CancelOrderEvent{})
-	gob.Register(&OrderAcceptedEvent{})
-	gob.Register(&OrderRejectedEvent{})
-	gob.Register(&FillEvent{})
-	gob.Register(&OrderCancelledEvent{})
-}
@@ -0,0 +1,430 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Protobuf Encoding:
+//
+// ProtobufCodec writes events in the protobuf wire format of the messages in
+// events.proto, so tools in any language can read the log with code
+// generated from it. The Go side is written out by hand in bind methods,
+// one per message, that name each field's number once for both directions.
+//
+// Protobuf carries schema evolution itself: a field added under a new number
+// is skipped by older readers and reads as zero from older records, which
+// is exactly what every migration in schema.go does. Adding a field needs no
+// version bump; changing what an existing field means still does.
+
+// ProtobufCodec encodes events as protobuf messages (see events.proto). It
+// is the default.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ID() byte     { return 1 }
+func (ProtobufCodec) Name() string { return "protobuf" }
+
+func (ProtobufCodec) Marshal(dst []byte, event interface{}) ([]byte, error) {
+	msg, ok := event.(protoMessage)
+	if !ok {
+		return dst, fmt.Errorf("protobuf: no message for %T", event)
+	}
+	enc := protoEncoder{b: dst}
+	msg.bind(&enc)
+	return enc.b, nil
+}
+
+func (ProtobufCodec) Unmarshal(eventType EventType, payload []byte) (interface{}, error) {
+	var msg protoMessage
+	switch eventType {
+	case EventTypeNewOrder:
+		msg = &NewOrderEvent{}
+	case EventTypeCancelOrder:
+		msg = &CancelOrderEvent{}
+	case EventTypeOrderAccepted:
+		msg = &OrderAcceptedEvent{}
+	case EventTypeOrderRejected:
+		msg = &OrderRejectedEvent{}
+	case EventTypeFill:
+		msg = &FillEvent{}
+	case EventTypeOrderCancelled:
+		msg = &OrderCancelledEvent{}
+	case EventTypeOrderReplaced:
+		msg = &OrderReplacedEvent{}
+	case EventTypeSymbolImported:
+		msg = &SymbolImportedEvent{}
+	case EventTypeSymbolMoved:
+		msg = &SymbolMovedEvent{}
+	case EventTypeAuctionStarted:
+		msg = &AuctionStartedEvent{}
+	case EventTypeAuctionUncrossed:
+		msg = &AuctionUncrossedEvent{}
+	case EventTypeRiskLimits:
+		msg = &RiskLimitsEvent{}
+	case EventTypeRestriction:
+		msg = &RestrictionEvent{}
+	case EventTypeJournalCheckpoint:
+		msg = &JournalCheckpointEvent{}
+	case EventTypeAccount:
+		msg = &AccountEvent{}
+	default:
+		return nil, fmt.Errorf("protobuf: unknown event type %d", eventType)
+	}
+
+	dec, err := newProtoDecoder(payload)
+	if err != nil {
+		return nil, err
+	}
+	msg.bind(dec)
+	return msg, dec.err
+}
+
+// protoMessage is an event with a protobuf message in events.proto.
+type protoMessage interface {
+	bind(b protoBinder)
+}
+
+// protoBinder binds fields to their numbers. The encoder writes each field
+// it is given; the decoder sets each from the message it was given.
+type protoBinder interface {
+	uint64(num protowire.Number, p *uint64)
+	int64(num protowire.Number, p *int64)
+	sint64(num protowire.Number, p *int64)
+	string(num protowire.Number, p *string)
+	bool(num protowire.Number, p *bool)
+	orders(num protowire.Number, p *[]orders.Order)
+}
+
+// bindEnum binds an int-based type, such as orders.Side, as an int64.
+func bindEnum[T ~int | ~uint8](b protoBinder, num protowire.Number, p *T) {
+	v := int64(*p)
+	b.int64(num, &v)
+	*p = T(v)
+}
+
+// bindEvent binds the fields every message starts with.
+func bindEvent(b protoBinder, e *Event) {
+	b.uint64(1, &e.SequenceNum)
+	b.int64(2, &e.Timestamp)
+	bindEnum(b, 3, &e.Type)
+}
+
+func (e *NewOrderEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	bindEnum(b, 6, &e.Side)
+	bindEnum(b, 7, &e.OrderType)
+	b.int64(8, &e.Price)
+	b.int64(9, &e.Quantity)
+	b.string(10, &e.AccountID)
+	b.string(11, &e.ClientOrderID)
+	b.string(12, &e.SessionID)
+	b.int64(13, &e.DisplayQty)
+	bindEnum(b, 14, &e.Peg)
+	b.int64(15, &e.PegLimit)
+	bindEnum(b, 16, &e.TimeInForce)
+}
+
+func (e *CancelOrderEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	b.string(6, &e.AccountID)
+}
+
+func (e *OrderAcceptedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	b.int64(6, &e.RestingQty)
+}
+
+func (e *OrderRejectedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	b.string(6, &e.RejectReason)
+}
+
+func (e *FillEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.TradeID)
+	b.string(5, &e.Symbol)
+	b.int64(6, &e.Price)
+	b.int64(7, &e.Quantity)
+	b.uint64(8, &e.MakerOrderID)
+	b.uint64(9, &e.TakerOrderID)
+	b.string(10, &e.MakerAccountID)
+	b.string(11, &e.TakerAccountID)
+	bindEnum(b, 12, &e.TakerSide)
+	b.sint64(13, &e.MakerFee)
+	b.sint64(14, &e.TakerFee)
+}
+
+func (e *OrderCancelledEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	b.int64(6, &e.CancelledQty)
+	b.string(7, &e.Reason)
+}
+
+func (e *OrderReplacedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.OrderID)
+	b.string(5, &e.Symbol)
+	b.int64(6, &e.OldPrice)
+	b.int64(7, &e.OldQuantity)
+	b.int64(8, &e.NewPrice)
+	b.int64(9, &e.NewQuantity)
+	b.bool(10, &e.PriorityKept)
+}
+
+func (e *SymbolImportedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.Symbol)
+	b.string(5, &e.Source)
+	b.orders(6, &e.Orders)
+}
+
+func (e *SymbolMovedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.Symbol)
+	b.string(5, &e.Target)
+	bindEnum(b, 6, &e.Orders)
+}
+
+func (e *AuctionStartedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.Symbol)
+	b.int64(5, &e.RefPrice)
+}
+
+func (e *AuctionUncrossedEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.Symbol)
+	b.int64(5, &e.Price)
+	b.int64(6, &e.Volume)
+}
+
+func (e *RiskLimitsEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.AccountID)
+	b.int64(5, &e.MaxOrderSize)
+	b.int64(6, &e.MaxOrderValue)
+	b.int64(7, &e.MaxPositionSize)
+	b.int64(8, &e.MaxDailyVolume)
+	b.string(9, &e.Actor)
+}
+
+func (e *RestrictionEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.AccountID)
+	b.string(5, &e.Symbol)
+	b.bool(6, &e.Restricted)
+	b.string(7, &e.Reason)
+	b.string(8, &e.Actor)
+}
+
+func (e *JournalCheckpointEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.uint64(4, &e.Through)
+}
+
+func (e *AccountEvent) bind(b protoBinder) {
+	bindEvent(b, &e.Event)
+	b.string(4, &e.AccountID)
+	b.string(5, &e.Action)
+	b.int64(6, &e.Cash)
+	b.string(7, &e.Symbol)
+	b.int64(8, &e.Shares)
+	b.string(9, &e.Actor)
+}
+
+// bindOrder binds an orders.Order as the Order message.
+func bindOrder(b protoBinder, o *orders.Order) {
+	b.uint64(1, &o.ID)
+	b.uint64(2, &o.SequenceNum)
+	b.int64(3, &o.Price)
+	b.int64(4, &o.Quantity)
+	b.int64(5, &o.FilledQty)
+	b.int64(6, &o.FilledNotional)
+	b.int64(7, &o.DisplayQty)
+	b.int64(8, &o.ShownQty)
+	b.int64(9, &o.PegLimit)
+	b.int64(10, &o.Timestamp)
+	b.string(11, &o.Symbol)
+	b.string(12, &o.AccountID)
+	b.string(13, &o.ClientOrderID)
+	b.string(14, &o.SessionID)
+	bindEnum(b, 15, &o.Side)
+	bindEnum(b, 16, &o.Type)
+	bindEnum(b, 17, &o.Peg)
+	bindEnum(b, 18, &o.Status)
+	bindEnum(b, 19, &o.TimeInForce)
+}
+
+// protoEncoder appends fields in proto3 style: zero values are left out.
+type protoEncoder struct {
+	b []byte
+}
+
+func (e *protoEncoder) uint64(num protowire.Number, p *uint64) {
+	if *p != 0 {
+		e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+		e.b = protowire.AppendVarint(e.b, *p)
+	}
+}
+
+func (e *protoEncoder) int64(num protowire.Number, p *int64) {
+	v := uint64(*p)
+	e.uint64(num, &v)
+}
+
+func (e *protoEncoder) sint64(num protowire.Number, p *int64) {
+	v := protowire.EncodeZigZag(*p)
+	e.uint64(num, &v)
+}
+
+func (e *protoEncoder) string(num protowire.Number, p *string) {
+	if *p != "" {
+		e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+		e.b = protowire.AppendString(e.b, *p)
+	}
+}
+
+func (e *protoEncoder) bool(num protowire.Number, p *bool) {
+	v := protowire.EncodeBool(*p)
+	e.uint64(num, &v)
+}
+
+func (e *protoEncoder) orders(num protowire.Number, p *[]orders.Order) {
+	for i := range *p {
+		nested := protoEncoder{}
+		bindOrder(&nested, &(*p)[i])
+		e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+		e.b = protowire.AppendBytes(e.b, nested.b)
+	}
+}
+
+// protoField is a field value as read: varints in v, bytes in b.
+type protoField struct {
+	typ protowire.Type
+	v   uint64
+	b   []byte
+}
+
+// protoDecoder decodes a message's fields up front, then hands them out by
+// number. Unknown fields, from a newer writer, are ignored. The first field
+// of the wrong wire type is kept in err.
+type protoDecoder struct {
+	fields map[protowire.Number][]protoField
+	err    error
+}
+
+var errProtoWireType = errors.New("protobuf: wrong wire type")
+
+func newProtoDecoder(b []byte) (*protoDecoder, error) {
+	d := &protoDecoder{fields: make(map[protowire.Number][]protoField)}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		field := protoField{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			field.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		d.fields[num] = append(d.fields[num], field)
+	}
+	return d, nil
+}
+
+// varint returns the last varint given for num, as protobuf has the last
+// value of a repeated scalar win.
+func (d *protoDecoder) varint(num protowire.Number) uint64 {
+	fields := d.fields[num]
+	if len(fields) == 0 {
+		return 0
+	}
+	field := fields[len(fields)-1]
+	if field.typ != protowire.VarintType {
+		d.fail(num)
+		return 0
+	}
+	return field.v
+}
+
+func (d *protoDecoder) fail(num protowire.Number) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w for field %d", errProtoWireType, num)
+	}
+}
+
+func (d *protoDecoder) uint64(num protowire.Number, p *uint64) {
+	*p = d.varint(num)
+}
+
+func (d *protoDecoder) int64(num protowire.Number, p *int64) {
+	*p = int64(d.varint(num))
+}
+
+func (d *protoDecoder) sint64(num protowire.Number, p *int64) {
+	*p = protowire.DecodeZigZag(d.varint(num))
+}
+
+func (d *protoDecoder) bool(num protowire.Number, p *bool) {
+	*p = protowire.DecodeBool(d.varint(num))
+}
+
+func (d *protoDecoder) string(num protowire.Number, p *string) {
+	fields := d.fields[num]
+	if len(fields) == 0 {
+		*p = ""
+		return
+	}
+	field := fields[len(fields)-1]
+	if field.typ != protowire.BytesType {
+		d.fail(num)
+		return
+	}
+	*p = string(field.b)
+}
+
+func (d *protoDecoder) orders(num protowire.Number, p *[]orders.Order) {
+	*p = nil
+	for _, field := range d.fields[num] {
+		if field.typ != protowire.BytesType {
+			d.fail(num)
+			return
+		}
+		nested, err := newProtoDecoder(field.b)
+		if err != nil {
+			d.err = err
+			return
+		}
+		var order orders.Order
+		bindOrder(nested, &order)
+		if nested.err != nil {
+			d.err = nested.err
+			return
+		}
+		*p = append(*p, order)
+	}
+}
@@ -0,0 +1,252 @@
+package events
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Schema Versioning:
+//
+// The event log is kept forever (it is the audit trail), but event structs
+// evolve. Every record carries the schema version it was written with, and
+// Replay upgrades older events to the current shape before handing them to
+// the caller, so consumers only ever see current types.
+//
+// Evolving an event type:
+//   - Adding a field: give it a new number in events.proto and its bind
+//     method (see proto.go). Protobuf records without it read as zero, so no
+//     version bump is needed unless zero is the wrong value for old events.
+//   - Anything else (or a field whose zero is wrong for old events):
+//     1. Bump SchemaVersion.
+//     2. Freeze the old shape as an unexported type (e.g. newOrderEventV1)
+//        and register it under the old gob name, so old gob records still
+//        decode into exactly the shape they were written (and checksummed)
+//        with.
+//     3. Register the current type under a new name ("<name>.v<N>").
+//     4. Add a migration from the previous version. Protobuf records of
+//        older versions decode into the current type, so the migration must
+//        handle it too.
+//
+// History:
+//   - v1: initial schema (records written before the envelope had a version
+//     decode as 0 and are treated as v1)
+//   - v2: NewOrderEvent gains SessionID
+//   - v3: NewOrderEvent gains DisplayQty
+//   - v4: NewOrderEvent gains Peg and PegLimit
+//   - v5: FillEvent gains MakerFee and TakerFee
+//
+// Adding a new event type (e.g. OrderReplacedEvent) changes no existing
+// shape, so it needs no version bump: only an EventType, a gob
+// registration, and a message in events.proto with its bind method and
+// ProtobufCodec.Unmarshal case.
+
+// SchemaVersion is the version of the event types in this package.
+const SchemaVersion uint32 = 5
+
+// ErrUnsupportedVersion is returned by Replay for records written by a newer
+// schema than this binary understands.
+var ErrUnsupportedVersion = errors.New("unsupported event schema version")
+
+// migration upgrades an event from one schema version to the next.
+// Events whose shape did not change are returned as is.
+type migration func(event interface{}) interface{}
+
+// migrations[v] upgrades an event from version v to v+1.
+var migrations = map[uint32]migration{
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+	3: migrateV3ToV4,
+	4: migrateV4ToV5,
+}
+
+// Upgrade migrates an event written with the given schema version to the
+// current SchemaVersion.
+func Upgrade(version uint32, event interface{}) (interface{}, error) {
+	if version == 0 {
+		version = 1 // Written before records were versioned
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d (current %d)", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", v)
+		}
+		event = migrate(event)
+	}
+	return event, nil
+}
+
+// newOrderEventV1 is the v1 shape of NewOrderEvent.
+type newOrderEventV1 struct {
+	Event
+	OrderID       uint64
+	Symbol        string
+	Side          orders.Side
+	OrderType     orders.OrderType
+	Price         int64
+	Quantity      int64
+	AccountID     string
+	ClientOrderID string
+}
+
+// newOrderEventV2 is the v2 shape of NewOrderEvent.
+type newOrderEventV2 struct {
+	Event
+	OrderID       uint64
+	Symbol        string
+	Side          orders.Side
+	OrderType     orders.OrderType
+	Price         int64
+	Quantity      int64
+	AccountID     string
+	ClientOrderID string
+	SessionID     string
+}
+
+// newOrderEventV3 is the v3 shape of NewOrderEvent.
+type newOrderEventV3 struct {
+	Event
+	OrderID       uint64
+	Symbol        string
+	Side          orders.Side
+	OrderType     orders.OrderType
+	Price         int64
+	Quantity      int64
+	AccountID     string
+	ClientOrderID string
+	SessionID     string
+	DisplayQty    int64
+}
+
+// fillEventV4 is the v1-v4 shape of FillEvent.
+type fillEventV4 struct {
+	Event
+	TradeID        uint64
+	Symbol         string
+	Price          int64
+	Quantity       int64
+	MakerOrderID   uint64
+	TakerOrderID   uint64
+	MakerAccountID string
+	TakerAccountID string
+	TakerSide      orders.Side
+}
+
+// migrateV1ToV2 converts v1 new orders; they predate sessions, so SessionID
+// is left empty.
+func migrateV1ToV2(event interface{}) interface{} {
+	e, ok := event.(*newOrderEventV1)
+	if !ok {
+		return event
+	}
+	return &newOrderEventV2{
+		Event:         e.Event,
+		OrderID:       e.OrderID,
+		Symbol:        e.Symbol,
+		Side:          e.Side,
+		OrderType:     e.OrderType,
+		Price:         e.Price,
+		Quantity:      e.Quantity,
+		AccountID:     e.AccountID,
+		ClientOrderID: e.ClientOrderID,
+	}
+}
+
+// migrateV2ToV3 converts v2 new orders; they predate iceberg orders, so
+// DisplayQty is left 0 (fully displayed).
+func migrateV2ToV3(event interface{}) interface{} {
+	e, ok := event.(*newOrderEventV2)
+	if !ok {
+		return event
+	}
+	return &newOrderEventV3{
+		Event:         e.Event,
+		OrderID:       e.OrderID,
+		Symbol:        e.Symbol,
+		Side:          e.Side,
+		OrderType:     e.OrderType,
+		Price:         e.Price,
+		Quantity:      e.Quantity,
+		AccountID:     e.AccountID,
+		ClientOrderID: e.ClientOrderID,
+		SessionID:     e.SessionID,
+	}
+}
+
+// migrateV3ToV4 converts v3 new orders; they predate pegged orders, so Peg
+// is left PegNone (priced by the order itself).
+func migrateV3ToV4(event interface{}) interface{} {
+	e, ok := event.(*newOrderEventV3)
+	if !ok {
+		return event
+	}
+	return &NewOrderEvent{
+		Event:         e.Event,
+		OrderID:       e.OrderID,
+		Symbol:        e.Symbol,
+		Side:          e.Side,
+		OrderType:     e.OrderType,
+		Price:         e.Price,
+		Quantity:      e.Quantity,
+		AccountID:     e.AccountID,
+		ClientOrderID: e.ClientOrderID,
+		SessionID:     e.SessionID,
+		DisplayQty:    e.DisplayQty,
+	}
+}
+
+// migrateV4ToV5 converts v4 fills; they predate trading fees, so MakerFee
+// and TakerFee are left 0 (no cost).
+func migrateV4ToV5(event interface{}) interface{} {
+	e, ok := event.(*fillEventV4)
+	if !ok {
+		return event
+	}
+	return &FillEvent{
+		Event:          e.Event,
+		TradeID:        e.TradeID,
+		Symbol:         e.Symbol,
+		Price:          e.Price,
+		Quantity:       e.Quantity,
+		MakerOrderID:   e.MakerOrderID,
+		TakerOrderID:   e.TakerOrderID,
+		MakerAccountID: e.MakerAccountID,
+		TakerAccountID: e.TakerAccountID,
+		TakerSide:      e.TakerSide,
+	}
+}
+
+// Register gob types for encoding/decoding.
+//
+// Names are pinned explicitly: the name is written into every record, so it
+// must not change when a type is renamed or re-versioned.
+func init() {
+	// Current types
+	gob.RegisterName("*events.NewOrderEvent.v4", &NewOrderEvent{})
+	gob.RegisterName("*events.CancelOrderEvent", &CancelOrderEvent{})
+	gob.RegisterName("*events.OrderAcceptedEvent", &OrderAcceptedEvent{})
+	gob.RegisterName("*events.OrderRejectedEvent", &OrderRejectedEvent{})
+	gob.RegisterName("*events.FillEvent.v5", &FillEvent{})
+	gob.RegisterName("*events.OrderCancelledEvent", &OrderCancelledEvent{})
+	gob.RegisterName("*events.OrderReplacedEvent", &OrderReplacedEvent{})
+	gob.RegisterName("*events.SymbolImportedEvent", &SymbolImportedEvent{})
+	gob.RegisterName("*events.SymbolMovedEvent", &SymbolMovedEvent{})
+	gob.RegisterName("*events.AuctionStartedEvent", &AuctionStartedEvent{})
+	gob.RegisterName("*events.AuctionUncrossedEvent", &AuctionUncrossedEvent{})
+	gob.RegisterName("*events.RiskLimitsEvent", &RiskLimitsEvent{})
+	gob.RegisterName("*events.RestrictionEvent", &RestrictionEvent{})
+	gob.RegisterName("*events.JournalCheckpointEvent", &JournalCheckpointEvent{})
+	gob.RegisterName("*events.AccountEvent", &AccountEvent{})
+
+	// Frozen shapes from earlier versions
+	gob.RegisterName("*events.NewOrderEvent", &newOrderEventV1{})
+	gob.RegisterName("*events.NewOrderEvent.v2", &newOrderEventV2{})
+	gob.RegisterName("*events.NewOrderEvent.v3", &newOrderEventV3{})
+	gob.RegisterName("*events.FillEvent", &fillEventV4{})
+}
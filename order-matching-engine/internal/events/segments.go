@@ -0,0 +1,316 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment Rotation and Retention
+//
+// A single log file grows without bound: it can never be cleaned up, and
+// every replay reads all of it. With SegmentMaxBytes set, the active file
+// is closed once it reaches that size and renamed after its first sequence
+// number, and a fresh active file is started:
+//
+//	events.log.00000000000000000001   closed, events 1-48211
+//	events.log.00000000000000048212   closed, events 48212-96530
+//	events.log                        active, appended to
+//	events.log.manifest               closed segments, in order
+//
+// Closed segments never change. The manifest (JSON, rewritten atomically)
+// lists them with their sequence ranges, so a replay from a snapshot skips
+// every segment that ends before it without opening it.
+//
+// Retention:
+// Closed segments expire once there are more than MaxSegments of them or
+// they are older than MaxAge. An expired segment is moved to ArchiveDir
+// (still listed, still replayable) or deleted. Deleting events that
+// recovery still needs would make the log useless, so a segment only
+// expires once its last event is at or below the retention floor - the
+// sequence the latest snapshot covers (see SetRetentionFloor). Without
+// snapshots the floor stays at zero and nothing is ever removed.
+//
+// Crash safety:
+// Rotation renames the active file before rewriting the manifest, and
+// retention removes a file before dropping it from the manifest. On open, a
+// segment file missing from the manifest is adopted, and listed files that
+// are gone from the head of the log are forgotten.
+
+// Segment is a closed segment file.
+type Segment struct {
+	Path     string    `json:"path"`
+	FirstSeq uint64    `json:"first_seq"`
+	LastSeq  uint64    `json:"last_seq"`
+	Bytes    int64     `json:"bytes"`
+	ClosedAt time.Time `json:"closed_at"`
+	Archived bool      `json:"archived,omitempty"` // Moved to Retention.ArchiveDir
+}
+
+// Retention decides when closed segments expire and what happens to them.
+// The zero value keeps every segment.
+type Retention struct {
+	MaxSegments int           // Closed segments kept in place (0 = no limit)
+	MaxAge      time.Duration // Closed segments kept at most this long (0 = no limit)
+	ArchiveDir  string        // Move expired segments here (empty = delete them)
+}
+
+// manifest is the on-disk list of closed segments.
+type manifest struct {
+	Segments []Segment `json:"segments"`
+}
+
+func (l *EventLog) manifestPath() string {
+	return l.path + ".manifest"
+}
+
+func (l *EventLog) segmentPath(firstSeq uint64) string {
+	return fmt.Sprintf("%s.%020d", l.path, firstSeq)
+}
+
+// Segments returns the closed segments, oldest first.
+func (l *EventLog) Segments() []Segment {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Segment(nil), l.segments...)
+}
+
+// SetRetentionFloor allows segments whose events all have sequence numbers
+// at or below seq to expire, and applies retention. Call it once state up
+// to seq is safely persisted elsewhere, e.g. in a snapshot.
+func (l *EventLog) SetRetentionFloor(seq uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrReadOnly
+	}
+	if seq <= l.floor {
+		return nil
+	}
+	l.floor = seq
+	return l.expire()
+}
+
+// loadSegments reads the manifest and reconciles it with the files on disk.
+func (l *EventLog) loadSegments() error {
+	changed, err := l.readSegments()
+	if err != nil || !changed {
+		return err
+	}
+	return l.saveManifest()
+}
+
+// readSegments reads the manifest and reconciles it with the files on disk,
+// reporting whether the manifest needs rewriting.
+func (l *EventLog) readSegments() (changed bool, err error) {
+	data, err := os.ReadFile(l.manifestPath())
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err == nil {
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return false, fmt.Errorf("invalid manifest: %w", err)
+		}
+		l.segments = m.Segments
+	}
+
+	// Files removed by retention just before a crash
+	for len(l.segments) > 0 {
+		if _, err := os.Stat(l.segments[0].Path); !os.IsNotExist(err) {
+			break
+		}
+		l.segments = l.segments[1:]
+		changed = true
+	}
+
+	// Segments rotated just before a crash
+	listed := make(map[string]bool, len(l.segments))
+	for _, seg := range l.segments {
+		listed[seg.Path] = true
+	}
+	matches, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return false, err
+	}
+	for _, path := range matches {
+		suffix := strings.TrimPrefix(path, l.path+".")
+		if listed[path] || len(suffix) != 20 {
+			continue
+		}
+		if _, err := strconv.ParseUint(suffix, 10, 64); err != nil {
+			continue
+		}
+		seg, err := closedSegment(path)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		l.segments = append(l.segments, seg)
+		changed = true
+	}
+
+	if changed {
+		sort.Slice(l.segments, func(i, j int) bool { return l.segments[i].FirstSeq < l.segments[j].FirstSeq })
+	}
+	return changed, nil
+}
+
+// closedSegment describes a segment file by reading it.
+func closedSegment(path string) (Segment, error) {
+	first, last, _, err := scanFile(path)
+	if err != nil {
+		return Segment{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Segment{}, err
+	}
+	return Segment{Path: path, FirstSeq: first, LastSeq: last, Bytes: info.Size(), ClosedAt: info.ModTime()}, nil
+}
+
+// saveManifest writes the manifest to a temporary file and renames it into
+// place, so a crash never leaves a torn manifest.
+func (l *EventLog) saveManifest() error {
+	data, err := json.MarshalIndent(manifest{Segments: l.segments}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.manifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, l.manifestPath()); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// rotate closes the active file as a segment and starts a new one.
+// Caller must hold the lock.
+func (l *EventLog) rotate() error {
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	seg := Segment{
+		Path:     l.segmentPath(l.firstSeq),
+		FirstSeq: l.firstSeq,
+		LastSeq:  l.sequenceNum,
+		Bytes:    l.size,
+		ClosedAt: time.Now(),
+	}
+	if err := os.Rename(l.path, seg.Path); err != nil {
+		return err
+	}
+	l.segments = append(l.segments, seg)
+	if err := l.saveManifest(); err != nil {
+		return err
+	}
+
+	if err := l.openActive(); err != nil {
+		return err
+	}
+	l.firstSeq = 0
+	return l.expire()
+}
+
+// expire archives or deletes expired segments, oldest first.
+// Caller must hold the lock.
+func (l *EventLog) expire() error {
+	r := l.retention
+	if r.MaxSegments <= 0 && r.MaxAge <= 0 {
+		return nil
+	}
+
+	inPlace := 0
+	for _, seg := range l.segments {
+		if !seg.Archived {
+			inPlace++
+		}
+	}
+
+	now := time.Now()
+	drop := 0 // Leading segments deleted
+	changed := false
+	for i := range l.segments {
+		seg := &l.segments[i]
+		if seg.Archived {
+			continue
+		}
+		if seg.LastSeq > l.floor {
+			break // Still needed for recovery
+		}
+		tooMany := r.MaxSegments > 0 && inPlace > r.MaxSegments
+		tooOld := r.MaxAge > 0 && now.Sub(seg.ClosedAt) > r.MaxAge
+		if !tooMany && !tooOld {
+			break
+		}
+
+		if r.ArchiveDir != "" {
+			dest := filepath.Join(r.ArchiveDir, filepath.Base(seg.Path))
+			if err := moveFile(seg.Path, dest); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", seg.Path, err)
+			}
+			seg.Path = dest
+			seg.Archived = true
+		} else {
+			if err := os.Remove(seg.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete %s: %w", seg.Path, err)
+			}
+			drop = i + 1 // The log now starts after it, archives included
+		}
+		inPlace--
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	l.segments = l.segments[drop:]
+	return l.saveManifest()
+}
+
+// moveFile renames src to dst, copying across filesystems if need be.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,196 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tailing:
+//
+// Tail streams the log to in-process subscribers (surveillance, analytics,
+// the /events/stream endpoint) without them re-reading its files on a
+// timer. A subscriber starts at any sequence number still on disk:
+//
+//	Tail(from) ──▶ catch up from disk (Scan) ──▶ follow appends ──▶ ...
+//
+// It is registered before the catch-up starts, so every event appended
+// from then on is queued for it, and every one before is on disk. Events
+// are delivered once each, in sequence order. Damaged records are skipped,
+// as Scan skips them.
+//
+// Appends never wait for a subscriber. One that falls TailBacklog events
+// behind is dropped: its channel is closed, and it can Tail again from the
+// sequence number after the last event it got (an error then says if that
+// is no longer possible). Closing the log closes every channel too.
+
+// DefaultTailBacklog is the number of appended events queued for a tail
+// subscriber before it is dropped, unless EventLogConfig says otherwise.
+const DefaultTailBacklog = 4096
+
+// ErrTailAhead is returned by Tail for a start past the end of the log.
+var ErrTailAhead = errors.New("events: tail starts past the end of the log")
+
+// ErrLogClosed is returned by Tail once the log is closed.
+var ErrLogClosed = errors.New("events: log closed")
+
+// Record is a logged event as Tail delivers it.
+type Record struct {
+	Seq   uint64
+	Type  EventType
+	Event interface{} // Shared with every other reader: must not be modified
+}
+
+// tail is one subscriber.
+type tail struct {
+	out  chan Record   // What the subscriber reads, closed when the tail ends
+	live chan Record   // Appends queued during catch-up or a slow read
+	stop chan struct{} // Closed to end the tail
+}
+
+// Tail streams the events from sequence number fromSeq on (0 is the same as
+// 1, the whole log): first those already logged, then each one as it is
+// appended. Tail from GetLastSequence()+1 for new events only. The channel
+// stays open until StopTail, until the subscriber falls too far behind, or
+// until the log is closed.
+//
+// Returns ErrTruncated if retention has already deleted events from
+// fromSeq on, and ErrTailAhead if fromSeq is past the next event.
+func (l *EventLog) Tail(fromSeq uint64) (<-chan Record, error) {
+	if fromSeq == 0 {
+		fromSeq = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, ErrLogClosed
+	}
+	last := l.sequenceNum
+	if fromSeq > last+1 {
+		return nil, fmt.Errorf("%w: tail from %d, log ends at %d", ErrTailAhead, fromSeq, last)
+	}
+	if first := l.firstOnDisk(); fromSeq < first {
+		return nil, fmt.Errorf("%w: events %d-%d are gone, tail needs them from %d",
+			ErrTruncated, 1, first-1, fromSeq)
+	}
+
+	t := &tail{
+		out:  make(chan Record),
+		live: make(chan Record, l.tailBacklog),
+		stop: make(chan struct{}),
+	}
+	if l.tails == nil {
+		l.tails = make(map[<-chan Record]*tail)
+	}
+	l.tails[t.out] = t
+	go l.runTail(t, fromSeq-1, last)
+	return t.out, nil
+}
+
+// FirstSequence returns the sequence number of the oldest event still in
+// the log, or of the next one if none is.
+func (l *EventLog) FirstSequence() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.firstOnDisk()
+}
+
+// firstOnDisk is FirstSequence. Must hold l.mu.
+func (l *EventLog) firstOnDisk() uint64 {
+	if len(l.segments) > 0 {
+		return l.segments[0].FirstSeq
+	}
+	if l.firstSeq == 0 {
+		return l.sequenceNum + 1
+	}
+	return l.firstSeq
+}
+
+// StopTail ends the tail ch was returned for, closing it. Events already
+// queued for it are discarded.
+func (l *EventLog) StopTail(ch <-chan Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.tails[ch]; ok {
+		l.dropTail(t)
+	}
+}
+
+// Tails returns the number of tail subscribers.
+func (l *EventLog) Tails() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.tails)
+}
+
+// dropTail ends a tail. Must hold l.mu.
+func (l *EventLog) dropTail(t *tail) {
+	if _, ok := l.tails[t.out]; !ok {
+		return
+	}
+	delete(l.tails, t.out)
+	close(t.stop)
+}
+
+// notifyTails queues an appended event for every tail, dropping those with
+// a full queue. Must hold l.mu.
+func (l *EventLog) notifyTails(record Record) {
+	for _, t := range l.tails {
+		select {
+		case t.live <- record:
+		default:
+			l.dropTail(t)
+		}
+	}
+}
+
+// runTail delivers the events after sent: up to last from disk, then from
+// the live queue. Runs on its own goroutine, one per tail.
+func (l *EventLog) runTail(t *tail, sent, last uint64) {
+	defer close(t.out)
+	deliver := func(record Record) bool {
+		select {
+		case t.out <- record:
+			return true
+		case <-t.stop:
+			return false
+		}
+	}
+
+	if sent < last {
+		stopped := false
+		err := l.ScanFrom(sent, func(seqNum uint64, event interface{}) error {
+			if seqNum > last {
+				return ErrStopScan // Queued live as well
+			}
+			if !deliver(Record{Seq: seqNum, Type: TypeOf(event), Event: event}) {
+				stopped = true
+				return ErrStopScan
+			}
+			return nil
+		})
+		if stopped {
+			return
+		}
+		if err != nil {
+			l.StopTail(t.out)
+			return
+		}
+		sent = last // Damaged records up to last were skipped, not missed
+	}
+
+	for {
+		select {
+		case record := <-t.live:
+			if record.Seq <= sent {
+				continue
+			}
+			if !deliver(record) {
+				return
+			}
+			sent = record.Seq
+		case <-t.stop:
+			return
+		}
+	}
+}
@@ -28,6 +28,15 @@ const (
 	EventTypeOrderRejected
 	EventTypeFill
 	EventTypeOrderCancelled
+	EventTypeOrderReplaced
+	EventTypeSymbolImported
+	EventTypeSymbolMoved
+	EventTypeAuctionStarted
+	EventTypeAuctionUncrossed
+	EventTypeRiskLimits
+	EventTypeRestriction
+	EventTypeJournalCheckpoint
+	EventTypeAccount
 )
 
 func (t EventType) String() string {
@@ -44,11 +53,39 @@ func (t EventType) String() string {
 		return "FILL"
 	case EventTypeOrderCancelled:
 		return "ORDER_CANCELLED"
+	case EventTypeOrderReplaced:
+		return "ORDER_REPLACED"
+	case EventTypeSymbolImported:
+		return "SYMBOL_IMPORTED"
+	case EventTypeSymbolMoved:
+		return "SYMBOL_MOVED"
+	case EventTypeAuctionStarted:
+		return "AUCTION_STARTED"
+	case EventTypeAuctionUncrossed:
+		return "AUCTION_UNCROSSED"
+	case EventTypeRiskLimits:
+		return "RISK_LIMITS"
+	case EventTypeRestriction:
+		return "RESTRICTION"
+	case EventTypeJournalCheckpoint:
+		return "JOURNAL_CHECKPOINT"
+	case EventTypeAccount:
+		return "ACCOUNT"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ParseEventType returns the EventType whose String is name.
+func ParseEventType(name string) (EventType, bool) {
+	for t := EventTypeNewOrder; t <= EventTypeAccount; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
 // Event is the base event structure.
 // All events share these common fields.
 type Event struct {
@@ -68,6 +105,11 @@ type NewOrderEvent struct {
 	Quantity      int64
 	AccountID     string
 	ClientOrderID string
+	SessionID     string // Order entry session (empty for stateless HTTP orders), since v2
+	DisplayQty    int64  // Iceberg visible slice size (0 = fully displayed), since v3
+	Peg           orders.PegType // Pegged order type (PegNone = own price), since v4
+	PegLimit      int64  // Pegged order price cap (0 = uncapped), since v4
+	TimeInForce   orders.TimeInForce // GTC or DAY (GTC before DAY orders)
 }
 
 // CancelOrderEvent represents an order cancellation request.
@@ -106,6 +148,8 @@ type FillEvent struct {
 	MakerAccountID string
 	TakerAccountID string
 	TakerSide      orders.Side
+	MakerFee       int64 // Cents charged to the maker, negative = rebate (0 before fees)
+	TakerFee       int64 // Cents charged to the taker
 }
 
 // OrderCancelledEvent indicates an order was cancelled.
@@ -116,3 +160,181 @@ type OrderCancelledEvent struct {
 	CancelledQty  int64 // Remaining quantity that was cancelled
 	Reason        string
 }
+
+// OrderReplacedEvent indicates a resting order's price or quantity changed.
+// Any fills from a re-queued order matching on entry follow as FillEvents.
+type OrderReplacedEvent struct {
+	Event
+	OrderID      uint64
+	Symbol       string
+	OldPrice     int64
+	OldQuantity  int64
+	NewPrice     int64
+	NewQuantity  int64 // Total quantity, including any already filled
+	PriorityKept bool  // Amended in place; false if re-queued with a new sequence number
+}
+
+// SymbolImportedEvent records a symbol's book received from another shard.
+// Orders are in queue order, as captured on the source shard.
+type SymbolImportedEvent struct {
+	Event
+	Symbol string
+	Source string // Shard the symbol moved from
+	Orders []orders.Order
+}
+
+// SymbolMovedEvent records a symbol handed over to another shard. Its
+// resting orders left this engine with it.
+type SymbolMovedEvent struct {
+	Event
+	Symbol string
+	Target string // Address of the shard that trades it now
+	Orders int    // Resting orders handed over
+}
+
+// AuctionStartedEvent records a symbol entering an auction call.
+type AuctionStartedEvent struct {
+	Event
+	Symbol   string
+	RefPrice int64
+}
+
+// AuctionUncrossedEvent records the end of a symbol's auction call. Its
+// fills follow it in the log.
+type AuctionUncrossedEvent struct {
+	Event
+	Symbol string
+	Price  int64 // Equilibrium price; 0 if nothing crossed
+	Volume int64
+}
+
+// RiskLimitsEvent records a change to an account's risk limit overrides
+// (see risk/limits.go). Zero limits are not overridden, so an event with
+// none clears the account's overrides.
+type RiskLimitsEvent struct {
+	Event
+	AccountID       string
+	MaxOrderSize    int64  // Shares
+	MaxOrderValue   int64  // Cents
+	MaxPositionSize int64  // Shares
+	MaxDailyVolume  int64  // Cents
+	Actor           string // Operator who made the change
+}
+
+// RestrictionEvent records a symbol put on or taken off a restricted list
+// (see risk/restrictions.go): an account's, or with no AccountID the
+// global one.
+type RestrictionEvent struct {
+	Event
+	AccountID  string // Empty for the global list
+	Symbol     string
+	Restricted bool   // Put on (true) or taken off the list
+	Reason     string // Why, e.g. "insider list", for the rejects it causes
+	Actor      string // Operator who made the change
+}
+
+// Account actions (see AccountEvent).
+const (
+	AccountOpened    = "OPEN"
+	AccountDeposit   = "DEPOSIT"
+	AccountWithdrawal = "WITHDRAW"
+)
+
+// AccountEvent records an account opened, or cash or shares deposited into
+// or withdrawn from it (see settlement/accounts.go). The clearing house
+// holds the balances; the event log holds the record of every movement.
+type AccountEvent struct {
+	Event
+	AccountID string
+	Action    string // AccountOpened, AccountDeposit or AccountWithdrawal
+	Cash      int64  // Cents
+	Symbol    string // Of the shares moved, if any
+	Shares    int64
+	Actor     string // Who asked for it
+}
+
+// JournalCheckpointEvent marks the events of every request journaled up to
+// Through (a request journal sequence number, see internal/wal) as logged.
+// Recovery replays the journaled requests after the last checkpoint.
+type JournalCheckpointEvent struct {
+	Event
+	Through uint64
+}
+
+// base returns the fields every event shares.
+func (e *Event) base() *Event { return e }
+
+// TimestampOf returns an event's timestamp (nanoseconds since epoch), or 0
+// if it is not an event.
+func TimestampOf(event interface{}) int64 {
+	if e, ok := event.(interface{ base() *Event }); ok {
+		return e.base().Timestamp
+	}
+	return 0
+}
+
+// SymbolOf returns the symbol an event is for, or "" if it has none.
+func SymbolOf(event interface{}) string {
+	switch e := event.(type) {
+	case *NewOrderEvent:
+		return e.Symbol
+	case *CancelOrderEvent:
+		return e.Symbol
+	case *OrderAcceptedEvent:
+		return e.Symbol
+	case *OrderRejectedEvent:
+		return e.Symbol
+	case *FillEvent:
+		return e.Symbol
+	case *OrderCancelledEvent:
+		return e.Symbol
+	case *OrderReplacedEvent:
+		return e.Symbol
+	case *SymbolImportedEvent:
+		return e.Symbol
+	case *SymbolMovedEvent:
+		return e.Symbol
+	case *AuctionStartedEvent:
+		return e.Symbol
+	case *AuctionUncrossedEvent:
+		return e.Symbol
+	}
+	return ""
+}
+
+// TypeOf returns the EventType of an event, or 0 if it is not one.
+func TypeOf(event interface{}) EventType {
+	switch event.(type) {
+	case *NewOrderEvent:
+		return EventTypeNewOrder
+	case *CancelOrderEvent:
+		return EventTypeCancelOrder
+	case *OrderAcceptedEvent:
+		return EventTypeOrderAccepted
+	case *OrderRejectedEvent:
+		return EventTypeOrderRejected
+	case *FillEvent:
+		return EventTypeFill
+	case *OrderCancelledEvent:
+		return EventTypeOrderCancelled
+	case *OrderReplacedEvent:
+		return EventTypeOrderReplaced
+	case *SymbolImportedEvent:
+		return EventTypeSymbolImported
+	case *SymbolMovedEvent:
+		return EventTypeSymbolMoved
+	case *AuctionStartedEvent:
+		return EventTypeAuctionStarted
+	case *AuctionUncrossedEvent:
+		return EventTypeAuctionUncrossed
+	case *RiskLimitsEvent:
+		return EventTypeRiskLimits
+	case *RestrictionEvent:
+		return EventTypeRestriction
+	case *JournalCheckpointEvent:
+		return EventTypeJournalCheckpoint
+	case *AccountEvent:
+		return EventTypeAccount
+	}
+	return 0
+}
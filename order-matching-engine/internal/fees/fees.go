@@ -0,0 +1,156 @@
+// Package fees computes exchange trading fees: maker rebates and taker fees
+// charged on every fill.
+//
+// Maker/Taker Pricing:
+// Resting orders make the market other traders trade against, so the
+// exchange pays them a rebate; incoming orders take that liquidity and pay
+// a fee. The difference is the exchange's revenue per trade:
+//
+//	Trade: 100 AAPL @ $150.00 (notional $15,000.00)
+//	  Taker fee     3 bps  →  pays     $4.50
+//	  Maker rebate  2 bps  →  receives $3.00
+//	  Exchange keeps                   $1.50
+//
+// Rates are in basis points of the fill's notional value. A negative rate
+// is a rebate. Fees are rounded to the nearest cent, halves away from zero.
+//
+// Tiers:
+// Accounts are priced by tier. A market maker committed to quoting both
+// sides earns a bigger rebate than a retail account; accounts without a
+// tier pay the default schedule. Like risk profiles, tiers are named and
+// assigned per account, and an assignment applies from the account's next
+// fill.
+//
+// Fees are computed on the processor goroutine when a fill is logged, so
+// the event log records what was charged (see events.FillEvent) and
+// recovery replays those amounts rather than today's schedule.
+package fees
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Built-in tier names.
+const (
+	TierRetail      = "retail"
+	TierMarketMaker = "market-maker"
+	DefaultTierName = "default" // Reported for accounts without a tier
+)
+
+// Schedule is the fee rates of one tier.
+type Schedule struct {
+	MakerBps int64 // Charged to the resting order (negative = rebate)
+	TakerBps int64 // Charged to the incoming order (negative = rebate)
+}
+
+// DefaultSchedule returns the rates for accounts without a tier: a 3 bps
+// taker fee and a 2 bps maker rebate.
+func DefaultSchedule() Schedule {
+	return Schedule{MakerBps: -2, TakerBps: 3}
+}
+
+// DefaultTiers returns the built-in tiers.
+func DefaultTiers() map[string]Schedule {
+	return map[string]Schedule{
+		TierRetail:      {MakerBps: 0, TakerBps: 3},  // No rebate
+		TierMarketMaker: {MakerBps: -3, TakerBps: 2}, // Quoting obligation earns more
+	}
+}
+
+// Fee returns the fee at bps on a notional value, in cents.
+func Fee(notional, bps int64) int64 {
+	fee := notional * bps
+	if fee < 0 {
+		return -((-fee + 5000) / 10000)
+	}
+	return (fee + 5000) / 10000
+}
+
+// Engine prices fills. Safe for concurrent use.
+type Engine struct {
+	mu          sync.RWMutex
+	schedule    Schedule            // For accounts without a tier
+	tiers       map[string]Schedule // Tier name -> rates
+	accountTier map[string]string   // Account -> tier name
+}
+
+// NewEngine creates a fee engine charging schedule to accounts without a
+// tier.
+func NewEngine(schedule Schedule) *Engine {
+	return &Engine{
+		schedule:    schedule,
+		tiers:       make(map[string]Schedule),
+		accountTier: make(map[string]string),
+	}
+}
+
+// SetTier defines or replaces a named tier. Accounts already assigned to it
+// pay the new rates from their next fill.
+func (e *Engine) SetTier(name string, schedule Schedule) error {
+	if name == "" || name == DefaultTierName {
+		return fmt.Errorf("invalid fee tier name %q", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tiers[name] = schedule
+	return nil
+}
+
+// AssignTier assigns an account to a named tier. Assigning DefaultTierName
+// returns the account to the default schedule.
+func (e *Engine) AssignTier(accountID, name string) error {
+	if accountID == "" {
+		return fmt.Errorf("account required")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if name == DefaultTierName {
+		delete(e.accountTier, accountID)
+		return nil
+	}
+	if _, exists := e.tiers[name]; !exists {
+		return fmt.Errorf("unknown fee tier %q", name)
+	}
+	e.accountTier[accountID] = name
+	return nil
+}
+
+// AccountTier returns the name of an account's tier and its rates.
+func (e *Engine) AccountTier(accountID string) (string, Schedule) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if name, assigned := e.accountTier[accountID]; assigned {
+		return name, e.tiers[name]
+	}
+	return DefaultTierName, e.schedule
+}
+
+// TierNames returns the defined tier names, sorted.
+func (e *Engine) TierNames() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := make([]string, 0, len(e.tiers))
+	for name := range e.tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Charge prices a fill, setting its MakerFee and TakerFee.
+func (e *Engine) Charge(fill *orders.Fill) {
+	_, maker := e.AccountTier(fill.MakerAccountID)
+	_, taker := e.AccountTier(fill.TakerAccountID)
+	notional := fill.Price * fill.Quantity
+	fill.MakerFee = Fee(notional, maker.MakerBps)
+	fill.TakerFee = Fee(notional, taker.TakerBps)
+}
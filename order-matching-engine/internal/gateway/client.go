@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Client is an order entry session on the client side, for tools and
+// tests.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex // Writes
+
+	// Session is the session logged in to.
+	Session string
+
+	// Seq is the seq of the next sequenced message Receive returns. Log in
+	// with it after a disconnect to continue where the last one stopped.
+	Seq uint64
+}
+
+// LoginError is the reason a login was rejected.
+type LoginError byte
+
+func (e LoginError) Error() string {
+	switch byte(e) {
+	case RejectNotAuthorized:
+		return "gateway: login rejected: unknown account"
+	case RejectSessionUnavailable:
+		return "gateway: login rejected: session unavailable"
+	}
+	return fmt.Sprintf("gateway: login rejected (%q)", byte(e))
+}
+
+// Login logs in on conn to session (blank for a new one), asking for the
+// sequenced messages from seq (0 for new messages only).
+func Login(conn net.Conn, accountID, session string, seq uint64, cancelOnDisconnect bool) (*Client, error) {
+	if len(accountID) > AccountLen || len(session) > SessionLen {
+		return nil, fmt.Errorf("gateway: account or session too long")
+	}
+	login := appendText(nil, accountID, AccountLen)
+	login = appendText(login, session, SessionLen)
+	login = binary.BigEndian.AppendUint64(login, seq)
+	var flags byte
+	if cancelOnDisconnect {
+		flags |= 1
+	}
+	login = append(login, flags)
+	if err := WritePacket(conn, PacketLogin, login); err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	packetType, payload, err := ReadPacket(c.r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case packetType == PacketLoginRejected && len(payload) == 1:
+		return nil, LoginError(payload[0])
+	case packetType != PacketLoginAccepted || len(payload) < SessionLen+8:
+		return nil, fmt.Errorf("gateway: unexpected login reply %q", packetType)
+	}
+	c.Session = text(payload[:SessionLen])
+	c.Seq = binary.BigEndian.Uint64(payload[SessionLen:])
+	return c, nil
+}
+
+// Send sends an enter or cancel order message.
+func (c *Client) Send(m *Message) error {
+	payload, err := AppendMessage(nil, m)
+	if err != nil {
+		return err
+	}
+	return c.write(PacketUnsequenced, payload)
+}
+
+// Heartbeat sends a heartbeat.
+func (c *Client) Heartbeat() error {
+	return c.write(PacketHeartbeat, nil)
+}
+
+// Logout ends the session's connection; the session can be logged in to
+// again.
+func (c *Client) Logout() error {
+	return c.write(PacketLogout, nil)
+}
+
+// Receive returns the next sequenced message, skipping heartbeats.
+func (c *Client) Receive() (Message, error) {
+	for {
+		packetType, payload, err := ReadPacket(c.r)
+		if err != nil {
+			return Message{}, err
+		}
+		switch packetType {
+		case PacketServerBeat:
+			continue
+		case PacketSequenced:
+			c.Seq++
+			return ParseMessage(payload)
+		default:
+			return Message{}, fmt.Errorf("gateway: unexpected packet type %q", packetType)
+		}
+	}
+}
+
+func (c *Client) write(packetType byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return WritePacket(c.conn, packetType, payload)
+}
@@ -0,0 +1,330 @@
+// Package gateway implements binary order entry over TCP.
+//
+// # Binary Order Entry
+//
+// POST /order spends most of an order's latency parsing HTTP and JSON, and
+// opens a request per order; the ring buffer behind it can sequence far
+// more. This gateway speaks a compact binary protocol modelled on NASDAQ
+// OUCH over SoupBinTCP: a client logs in once, then streams orders on one
+// connection without waiting for each ack.
+//
+// Every packet, in both directions, is a big-endian uint16 length followed
+// by that many bytes: a packet type and its payload.
+//
+//	Client → server
+//	'L' login          account(16) session(10) seq(8) flags(1)
+//	'U' unsequenced    one order entry message
+//	'R' heartbeat
+//	'O' logout
+//
+//	Server → client
+//	'A' login accepted session(10) seq(8)
+//	'J' login rejected reason(1): 'A' unknown account, 'S' session unavailable
+//	'S' sequenced      one order entry message
+//	'H' heartbeat
+//
+// Text fields are left-aligned and space padded. Order entry messages:
+//
+//	'O' enter order    type(1) token(14) side(1) qty(8) symbol(8) price(8) order_type(1)   41 bytes
+//	'X' cancel order   type(1) token(14)                                                      15 bytes
+//
+//	'A' accepted       type(1) timestamp(8) token(14) side(1) qty(8) symbol(8) price(8) order_type(1) order_id(8)  57 bytes
+//	'E' executed       type(1) timestamp(8) token(14) qty(8) price(8) trade_id(8)            47 bytes
+//	'C' canceled       type(1) timestamp(8) token(14) qty(8) reason(1)                       32 bytes
+//	'J' rejected       type(1) timestamp(8) token(14) text                                   23+ bytes
+//	'I' cancel reject  type(1) timestamp(8) token(14) text                                   23+ bytes
+//
+// The token is the client's ID for an order, unique within the session;
+// cancels name the order by its token. Sides are 'B' or 'S', order types
+// 'L' limit, 'M' market, 'I' IOC and 'F' FOK, and prices are fixed-point
+// cents. Cancel reasons are 'U' requested, 'I' the unfilled rest of an
+// IOC, FOK or market order, and 'D' cancel on disconnect.
+//
+// Sessions are persistent: every message the server sends in an 'S' packet
+// is numbered from 1 for the session, implicitly, and kept. A client that
+// lost its connection logs in again with its session and the seq of the
+// next message it wants, and is sent everything from there on. Blank
+// session starts a new one; seq 0 skips the replay. Flags bit 0 asks for
+// cancel on disconnect.
+//
+// Either side that has sent nothing for a second sends a heartbeat. A
+// client silent for Timeout is disconnected.
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Packet types.
+const (
+	PacketLogin         byte = 'L'
+	PacketUnsequenced   byte = 'U'
+	PacketHeartbeat     byte = 'R'
+	PacketLogout        byte = 'O'
+	PacketLoginAccepted byte = 'A'
+	PacketLoginRejected byte = 'J'
+	PacketSequenced     byte = 'S'
+	PacketServerBeat    byte = 'H'
+)
+
+// Login reject reasons.
+const (
+	RejectNotAuthorized      byte = 'A'
+	RejectSessionUnavailable byte = 'S'
+)
+
+// MessageType identifies an order entry message.
+type MessageType byte
+
+const (
+	EnterOrder   MessageType = 'O'
+	CancelOrder  MessageType = 'X'
+	Accepted     MessageType = 'A'
+	Executed     MessageType = 'E'
+	Canceled     MessageType = 'C'
+	Rejected     MessageType = 'J'
+	CancelReject MessageType = 'I'
+)
+
+// Cancel reasons.
+const (
+	CancelRequested    byte = 'U'
+	CancelImmediate    byte = 'I'
+	CancelOnDisconnect byte = 'D'
+)
+
+const (
+	// TokenLen is the length of an order token.
+	TokenLen = 14
+
+	// SymbolLen is the length of the symbol field.
+	SymbolLen = 8
+
+	// AccountLen is the length of the account field of a login.
+	AccountLen = 16
+
+	// SessionLen is the length of a session ID.
+	SessionLen = 10
+
+	// MaxPacket is the largest packet payload either side accepts.
+	MaxPacket = 1024
+
+	loginLen = AccountLen + SessionLen + 8 + 1
+)
+
+// Message is one order entry message. Fields a type doesn't carry are
+// zero.
+type Message struct {
+	Type      MessageType
+	Timestamp int64 // Server messages only
+	Token     string
+	Side      orders.Side
+	Quantity  int64 // Entered, executed or canceled quantity
+	Symbol    string
+	Price     int64
+	OrderType orders.OrderType
+	OrderID   uint64
+	TradeID   uint64
+	Reason    byte   // Cancel reason
+	Text      string // Reject reason
+}
+
+var (
+	ErrShortMessage = errors.New("gateway: message truncated")
+	ErrPacketSize   = errors.New("gateway: packet too large")
+)
+
+// AppendMessage appends m's encoding to dst.
+func AppendMessage(dst []byte, m *Message) ([]byte, error) {
+	if len(m.Token) > TokenLen {
+		return dst, fmt.Errorf("gateway: token %q longer than %d characters", m.Token, TokenLen)
+	}
+	if len(m.Symbol) > SymbolLen {
+		return dst, fmt.Errorf("gateway: symbol %q longer than %d characters", m.Symbol, SymbolLen)
+	}
+
+	dst = append(dst, byte(m.Type))
+	switch m.Type {
+	case EnterOrder, CancelOrder:
+	case Accepted, Executed, Canceled, Rejected, CancelReject:
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Timestamp))
+	default:
+		return dst[:len(dst)-1], fmt.Errorf("gateway: unknown message type %q", m.Type)
+	}
+	dst = appendText(dst, m.Token, TokenLen)
+
+	switch m.Type {
+	case EnterOrder, Accepted:
+		dst = append(dst, sideCode(m.Side))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Quantity))
+		dst = appendText(dst, m.Symbol, SymbolLen)
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Price))
+		dst = append(dst, typeCode(m.OrderType))
+		if m.Type == Accepted {
+			dst = binary.BigEndian.AppendUint64(dst, m.OrderID)
+		}
+	case Executed:
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Quantity))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Price))
+		dst = binary.BigEndian.AppendUint64(dst, m.TradeID)
+	case Canceled:
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Quantity))
+		dst = append(dst, m.Reason)
+	case Rejected, CancelReject:
+		dst = append(dst, m.Text...)
+	}
+	return dst, nil
+}
+
+// ParseMessage decodes one message.
+func ParseMessage(b []byte) (Message, error) {
+	if len(b) == 0 {
+		return Message{}, ErrShortMessage
+	}
+	m := Message{Type: MessageType(b[0])}
+	b = b[1:]
+
+	var size int
+	switch m.Type {
+	case EnterOrder:
+		size = TokenLen + 1 + 8 + SymbolLen + 8 + 1
+	case CancelOrder:
+		size = TokenLen
+	case Accepted:
+		size = 8 + TokenLen + 1 + 8 + SymbolLen + 8 + 1 + 8
+	case Executed:
+		size = 8 + TokenLen + 8 + 8 + 8
+	case Canceled:
+		size = 8 + TokenLen + 8 + 1
+	case Rejected, CancelReject:
+		size = 8 + TokenLen
+	default:
+		return Message{}, fmt.Errorf("gateway: unknown message type %q", m.Type)
+	}
+	if len(b) < size {
+		return Message{}, ErrShortMessage
+	}
+
+	if m.Type != EnterOrder && m.Type != CancelOrder {
+		m.Timestamp = int64(binary.BigEndian.Uint64(b))
+		b = b[8:]
+	}
+	m.Token = text(b[:TokenLen])
+	b = b[TokenLen:]
+
+	switch m.Type {
+	case EnterOrder, Accepted:
+		m.Side = sideOf(b[0])
+		m.Quantity = int64(binary.BigEndian.Uint64(b[1:]))
+		m.Symbol = text(b[9 : 9+SymbolLen])
+		m.Price = int64(binary.BigEndian.Uint64(b[9+SymbolLen:]))
+		orderType, err := typeOf(b[17+SymbolLen])
+		if err != nil {
+			return Message{}, err
+		}
+		m.OrderType = orderType
+		if m.Type == Accepted {
+			m.OrderID = binary.BigEndian.Uint64(b[18+SymbolLen:])
+		}
+	case Executed:
+		m.Quantity = int64(binary.BigEndian.Uint64(b))
+		m.Price = int64(binary.BigEndian.Uint64(b[8:]))
+		m.TradeID = binary.BigEndian.Uint64(b[16:])
+	case Canceled:
+		m.Quantity = int64(binary.BigEndian.Uint64(b))
+		m.Reason = b[8]
+	case Rejected, CancelReject:
+		m.Text = string(b)
+	}
+	return m, nil
+}
+
+// WritePacket writes one packet.
+func WritePacket(w io.Writer, packetType byte, payload []byte) error {
+	if len(payload) >= MaxPacket {
+		return ErrPacketSize
+	}
+	packet := make([]byte, 0, 3+len(payload))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(1+len(payload)))
+	packet = append(packet, packetType)
+	packet = append(packet, payload...)
+	_, err := w.Write(packet)
+	return err
+}
+
+// ReadPacket reads one packet.
+func ReadPacket(r *bufio.Reader) (packetType byte, payload []byte, err error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(length[:]))
+	if n == 0 {
+		return 0, nil, ErrShortMessage
+	}
+	if n > MaxPacket {
+		return 0, nil, ErrPacketSize
+	}
+	packet := make([]byte, n)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+// appendText appends s left-aligned in a field of n bytes.
+func appendText(dst []byte, s string, n int) []byte {
+	dst = append(dst, s...)
+	return append(dst, strings.Repeat(" ", n-len(s))...)
+}
+
+func text(b []byte) string {
+	return strings.TrimRight(string(b), " ")
+}
+
+func sideCode(side orders.Side) byte {
+	if side == orders.SideSell {
+		return 'S'
+	}
+	return 'B'
+}
+
+func sideOf(code byte) orders.Side {
+	if code == 'S' {
+		return orders.SideSell
+	}
+	return orders.SideBuy
+}
+
+func typeCode(orderType orders.OrderType) byte {
+	switch orderType {
+	case orders.OrderTypeMarket:
+		return 'M'
+	case orders.OrderTypeIOC:
+		return 'I'
+	case orders.OrderTypeFOK:
+		return 'F'
+	}
+	return 'L'
+}
+
+func typeOf(code byte) (orders.OrderType, error) {
+	switch code {
+	case 'L':
+		return orders.OrderTypeLimit, nil
+	case 'M':
+		return orders.OrderTypeMarket, nil
+	case 'I':
+		return orders.OrderTypeIOC, nil
+	case 'F':
+		return orders.OrderTypeFOK, nil
+	}
+	return 0, fmt.Errorf("gateway: unknown order type %q", code)
+}
@@ -0,0 +1,451 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+const (
+	// DefaultHeartbeat is how long the server stays silent before sending
+	// a heartbeat.
+	DefaultHeartbeat = time.Second
+
+	// DefaultTimeout is how long a client may stay silent before it is
+	// disconnected.
+	DefaultTimeout = 15 * time.Second
+
+	// JournalSize is the number of sequenced messages kept per session for
+	// replay.
+	JournalSize = 100000
+)
+
+// Handler carries out order entry for the gateway. The server implements
+// it with its HTTP order path, so binary orders are validated, risk
+// checked and sequenced exactly like JSON ones.
+type Handler interface {
+	// Authorize reports whether an account may log in.
+	Authorize(accountID string) bool
+
+	// EnterOrder validates, sequences and matches an order.
+	EnterOrder(order *orders.Order) Result
+
+	// CancelOrder cancels a resting order.
+	CancelOrder(symbol string, orderID uint64) Result
+
+	// CancelSession cancels every resting order of a session, returning a
+	// result per order cancelled.
+	CancelSession(sessionID string) []Result
+}
+
+// Result is the outcome of an order entry request.
+type Result struct {
+	OrderID   uint64
+	Fills     []Fill // Executions of the order itself
+	Cancelled int64  // Quantity cancelled: on request, or the unfilled rest of an IOC, FOK or market order
+	Reject    string // Why the request was refused; empty if it wasn't
+}
+
+// Fill is one execution of an order.
+type Fill struct {
+	TradeID  uint64
+	Price    int64
+	Quantity int64
+}
+
+// Server accepts order entry sessions.
+type Server struct {
+	handler   Handler
+	heartbeat time.Duration
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	count    uint64
+	conns    map[net.Conn]bool
+	running  sync.WaitGroup // Connection goroutines
+}
+
+// session is a persistent order entry session. It outlives its
+// connections, so a client can reconnect and pick up where it left off.
+type session struct {
+	id        string
+	accountID string
+
+	mu     sync.Mutex
+	first  uint64   // Seq of sent[0]
+	next   uint64   // Seq of the next message
+	sent   [][]byte // Last JournalSize sequenced messages, oldest first
+	tokens map[string]*tokenEntry
+	byID   map[uint64]string // Order ID to token
+	conn   *conn             // nil while disconnected
+}
+
+// tokenEntry is the order a token names.
+type tokenEntry struct {
+	symbol  string
+	orderID uint64 // 0 if the order was rejected or is held unsequenced
+}
+
+// conn is a client connection. Writes come from the session and the
+// heartbeat goroutine.
+type conn struct {
+	net.Conn
+	timeout time.Duration
+
+	mu        sync.Mutex
+	lastWrite time.Time
+}
+
+// NewServer creates a gateway carrying out orders with handler.
+func NewServer(handler Handler) *Server {
+	return &Server{
+		handler:   handler,
+		heartbeat: DefaultHeartbeat,
+		timeout:   DefaultTimeout,
+		sessions:  make(map[string]*session),
+		conns:     make(map[net.Conn]bool),
+	}
+}
+
+// SetTimeouts sets how long the server stays silent before a heartbeat
+// and how long a client may. Must be called before Serve.
+func (s *Server) SetTimeouts(heartbeat, timeout time.Duration) {
+	s.heartbeat = heartbeat
+	s.timeout = timeout
+}
+
+// Serve accepts connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.conns[nc] = true
+		s.running.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(nc)
+	}
+}
+
+// Close disconnects every client, and returns once sessions flagged for
+// cancel on disconnect have had their orders cancelled. Close the listener
+// first.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.mu.Unlock()
+	s.running.Wait()
+}
+
+// Sessions returns the number of sessions and how many are connected.
+func (s *Server) Sessions() (total, connected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		sess.mu.Lock()
+		if sess.conn != nil {
+			connected++
+		}
+		sess.mu.Unlock()
+	}
+	return len(s.sessions), connected
+}
+
+// serveConn runs one connection: a login, then order entry until the
+// client logs out, goes silent or the connection fails.
+func (s *Server) serveConn(nc net.Conn) {
+	defer s.running.Done()
+	defer func() {
+		nc.Close()
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+	}()
+	c := &conn{Conn: nc, timeout: s.timeout}
+	r := bufio.NewReader(nc)
+
+	nc.SetReadDeadline(time.Now().Add(s.timeout))
+	packetType, payload, err := ReadPacket(r)
+	if err != nil || packetType != PacketLogin || len(payload) < loginLen {
+		return
+	}
+	accountID := text(payload[:AccountLen])
+	sessionID := text(payload[AccountLen : AccountLen+SessionLen])
+	seq := binary.BigEndian.Uint64(payload[AccountLen+SessionLen:])
+	cancelOnDisconnect := payload[AccountLen+SessionLen+8]&1 != 0
+
+	sess, reject := s.login(accountID, sessionID)
+	if reject != 0 {
+		c.write(PacketLoginRejected, []byte{reject})
+		return
+	}
+	if !sess.attach(c, seq) {
+		c.write(PacketLoginRejected, []byte{RejectSessionUnavailable})
+		return
+	}
+	log.Printf("Binary session %s logged in as %s (cancel_on_disconnect=%v)", sess.id, accountID, cancelOnDisconnect)
+
+	done := make(chan struct{})
+	go c.heartbeats(s.heartbeat, done)
+	defer func() {
+		close(done)
+		sess.detach()
+		if cancelOnDisconnect {
+			s.cancelSession(sess)
+		}
+	}()
+
+	for {
+		nc.SetReadDeadline(time.Now().Add(s.timeout))
+		packetType, payload, err := ReadPacket(r)
+		if err != nil {
+			log.Printf("Binary session %s (%s) disconnected: %v", sess.id, accountID, err)
+			return
+		}
+
+		switch packetType {
+		case PacketHeartbeat:
+		case PacketLogout:
+			log.Printf("Binary session %s (%s) logged out", sess.id, accountID)
+			return
+		case PacketUnsequenced:
+			m, err := ParseMessage(payload)
+			if err == nil && m.Type != EnterOrder && m.Type != CancelOrder {
+				err = fmt.Errorf("unexpected message type %q", m.Type)
+			}
+			if err != nil {
+				log.Printf("Binary session %s (%s): %v", sess.id, accountID, err)
+				return
+			}
+			if m.Type == EnterOrder {
+				s.enter(sess, m)
+			} else {
+				s.cancel(sess, m)
+			}
+		default:
+			log.Printf("Binary session %s (%s): unexpected packet type %q", sess.id, accountID, packetType)
+			return
+		}
+	}
+}
+
+// login finds the session to log in to, or creates one if sessionID is
+// blank. Returns a login reject reason if there is none.
+func (s *Server) login(accountID, sessionID string) (*session, byte) {
+	if accountID == "" || !s.handler.Authorize(accountID) {
+		return nil, RejectNotAuthorized
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sessionID == "" {
+		s.count++
+		sess := &session{
+			id:        fmt.Sprintf("B%09d", s.count),
+			accountID: accountID,
+			first:     1,
+			next:      1,
+			tokens:    make(map[string]*tokenEntry),
+			byID:      make(map[uint64]string),
+		}
+		s.sessions[sess.id] = sess
+		return sess, 0
+	}
+	sess := s.sessions[sessionID]
+	if sess == nil || sess.accountID != accountID {
+		return nil, RejectSessionUnavailable
+	}
+	return sess, 0
+}
+
+// enter sequences an order and reports its outcome.
+func (s *Server) enter(sess *session, m Message) {
+	sess.mu.Lock()
+	_, used := sess.tokens[m.Token]
+	if !used && m.Token != "" {
+		sess.tokens[m.Token] = &tokenEntry{symbol: m.Symbol}
+	}
+	sess.mu.Unlock()
+	if m.Token == "" || used {
+		sess.send(&Message{Type: Rejected, Token: m.Token, Text: "duplicate or empty token"})
+		return
+	}
+
+	order := &orders.Order{
+		Symbol:        m.Symbol,
+		Side:          m.Side,
+		Type:          m.OrderType,
+		Price:         m.Price,
+		Quantity:      m.Quantity,
+		AccountID:     sess.accountID, // Always the logged-in account
+		SessionID:     sess.id,
+		ClientOrderID: m.Token,
+	}
+	result := s.handler.EnterOrder(order)
+	if result.Reject != "" {
+		sess.send(&Message{Type: Rejected, Token: m.Token, Text: result.Reject})
+		return
+	}
+
+	sess.mu.Lock()
+	sess.tokens[m.Token].orderID = result.OrderID
+	if result.OrderID != 0 {
+		sess.byID[result.OrderID] = m.Token
+	}
+	sess.mu.Unlock()
+
+	accepted := m
+	accepted.Type = Accepted
+	accepted.OrderID = result.OrderID
+	sess.send(&accepted)
+	for _, fill := range result.Fills {
+		sess.send(&Message{Type: Executed, Token: m.Token, Quantity: fill.Quantity, Price: fill.Price, TradeID: fill.TradeID})
+	}
+	if result.Cancelled > 0 {
+		sess.send(&Message{Type: Canceled, Token: m.Token, Quantity: result.Cancelled, Reason: CancelImmediate})
+	}
+}
+
+// cancel cancels the order a token names and reports the outcome.
+func (s *Server) cancel(sess *session, m Message) {
+	sess.mu.Lock()
+	entry := sess.tokens[m.Token]
+	sess.mu.Unlock()
+
+	var result Result
+	switch {
+	case entry == nil:
+		result.Reject = "unknown token"
+	case entry.orderID == 0:
+		result.Reject = "order is not in the book"
+	default:
+		result = s.handler.CancelOrder(entry.symbol, entry.orderID)
+	}
+	if result.Reject != "" {
+		sess.send(&Message{Type: CancelReject, Token: m.Token, Text: result.Reject})
+		return
+	}
+	sess.send(&Message{Type: Canceled, Token: m.Token, Quantity: result.Cancelled, Reason: CancelRequested})
+}
+
+// cancelSession cancels a disconnected session's orders. The cancels are
+// journaled, so the client sees them when it logs back in.
+func (s *Server) cancelSession(sess *session) {
+	for _, result := range s.handler.CancelSession(sess.id) {
+		sess.mu.Lock()
+		token := sess.byID[result.OrderID]
+		sess.mu.Unlock()
+		if token == "" {
+			continue // Not entered through this gateway
+		}
+		sess.send(&Message{Type: Canceled, Token: token, Quantity: result.Cancelled, Reason: CancelOnDisconnect})
+	}
+}
+
+// attach connects c to the session, sends the login accept and replays
+// the journal from seq. Returns false if the session is already connected.
+func (sess *session) attach(c *conn, seq uint64) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.conn != nil {
+		return false
+	}
+
+	if seq == 0 || seq > sess.next {
+		seq = sess.next
+	}
+	if seq < sess.first {
+		seq = sess.first // No longer kept: the client sees the seq jump
+	}
+	sess.conn = c // A failed write closes c, and the read loop detaches it
+	accept := appendText(nil, sess.id, SessionLen)
+	accept = binary.BigEndian.AppendUint64(accept, seq)
+	if c.write(PacketLoginAccepted, accept) != nil {
+		return true
+	}
+	for _, m := range sess.sent[seq-sess.first:] {
+		if c.write(PacketSequenced, m) != nil {
+			break
+		}
+	}
+	return true
+}
+
+// detach disconnects the session's connection.
+func (sess *session) detach() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.conn = nil
+}
+
+// send sequences and journals a message, and writes it if the session is
+// connected.
+func (sess *session) send(m *Message) {
+	m.Timestamp = orders.Now()
+	encoded, err := AppendMessage(nil, m)
+	if err != nil {
+		log.Printf("Binary session %s: %v", sess.id, err)
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.sent = append(sess.sent, encoded)
+	sess.next++
+	if len(sess.sent) > JournalSize {
+		sess.sent[0] = nil
+		sess.sent = sess.sent[1:]
+		sess.first++
+	}
+	if sess.conn != nil {
+		sess.conn.write(PacketSequenced, encoded)
+	}
+}
+
+// write writes a packet. A write that fails or times out closes the
+// connection, ending its read loop.
+func (c *conn) write(packetType byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(c.timeout))
+	if err := WritePacket(c.Conn, packetType, payload); err != nil {
+		c.Close()
+		return err
+	}
+	c.lastWrite = time.Now()
+	return nil
+}
+
+// heartbeats sends a heartbeat whenever nothing was written for interval.
+func (c *conn) heartbeats(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			idle := time.Since(c.lastWrite) >= interval
+			c.mu.Unlock()
+			if idle && c.write(PacketServerBeat, nil) != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
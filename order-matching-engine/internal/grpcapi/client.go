@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client is a Go client of the OrderEntry service.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// Dial connects to an OrderEntry server. The connection is plaintext, like
+// the rest of the engine's listeners; extra options can add credentials.
+func Dial(addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SubmitOrder enters an order.
+func (c *Client) SubmitOrder(ctx context.Context, req *SubmitOrderRequest) (*OrderReply, error) {
+	reply := &OrderReply{}
+	return reply, c.conn.Invoke(ctx, "/"+ServiceName+"/SubmitOrder", req, reply)
+}
+
+// CancelOrder cancels a resting order.
+func (c *Client) CancelOrder(ctx context.Context, req *CancelOrderRequest) (*CancelReply, error) {
+	reply := &CancelReply{}
+	return reply, c.conn.Invoke(ctx, "/"+ServiceName+"/CancelOrder", req, reply)
+}
+
+// GetBook returns the top of a symbol's book.
+func (c *Client) GetBook(ctx context.Context, req *GetBookRequest) (*Book, error) {
+	reply := &Book{}
+	return reply, c.conn.Invoke(ctx, "/"+ServiceName+"/GetBook", req, reply)
+}
+
+// ExecutionStream receives an account's execution reports.
+type ExecutionStream struct {
+	stream grpc.ClientStream
+}
+
+// Executions subscribes to an account's execution reports until ctx is
+// cancelled.
+func (c *Client) Executions(ctx context.Context, req *ExecutionsRequest) (*ExecutionStream, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Executions")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &ExecutionStream{stream: stream}, nil
+}
+
+// Recv returns the next execution report.
+func (s *ExecutionStream) Recv() (*ExecutionReport, error) {
+	report := &ExecutionReport{}
+	if err := s.stream.RecvMsg(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,548 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Messages
+//
+// The messages of orderentry.proto, written out by hand like the event log's
+// (see events/proto.go): each has a bind method naming its fields' numbers
+// once, for the encoder and the decoder alike. Zero values are left out on
+// the wire, as in proto3, and unknown fields from a newer client are
+// skipped.
+
+// Side is a proto Side.
+type Side int32
+
+const (
+	SideUnspecified Side = iota
+	SideBuy
+	SideSell
+)
+
+// OrderType is a proto OrderType.
+type OrderType int32
+
+const (
+	OrderTypeUnspecified OrderType = iota
+	OrderTypeLimit
+	OrderTypeMarket
+	OrderTypeIOC
+	OrderTypeFOK
+)
+
+// Peg is a proto Peg.
+type Peg int32
+
+const (
+	PegNone Peg = iota
+	PegMidpoint
+	PegPrimary
+)
+
+// OrderStatus is a proto OrderStatus.
+type OrderStatus int32
+
+const (
+	OrderStatusUnspecified OrderStatus = iota
+	OrderStatusNew
+	OrderStatusPartiallyFilled
+	OrderStatusFilled
+	OrderStatusCancelled
+	OrderStatusRejected
+)
+
+// ExecType is a proto ExecType.
+type ExecType int32
+
+const (
+	ExecTypeUnspecified ExecType = iota
+	ExecTypeNew
+	ExecTypeTrade
+	ExecTypeCancelled
+	ExecTypeReplaced
+	ExecTypeRejected
+)
+
+// SubmitOrderRequest enters an order. Prices are in cents.
+type SubmitOrderRequest struct {
+	Symbol        string
+	Side          Side
+	Type          OrderType
+	Price         int64 // Limit price; with a peg, its cap
+	Quantity      int64
+	AccountID     string
+	ClientOrderID string
+	DisplayQty    int64 // Iceberg: shares shown at a time
+	Peg           Peg
+}
+
+// Fill is one execution of a submitted order.
+type Fill struct {
+	TradeID  uint64
+	Price    int64
+	Quantity int64
+	Fee      int64 // Negative = rebate
+}
+
+// OrderReply is the outcome of a submitted order.
+type OrderReply struct {
+	Accepted     bool
+	OrderID      uint64
+	Status       OrderStatus
+	CumQty       int64
+	AvgPrice     int64
+	LeavesQty    int64
+	Fills        []Fill
+	RejectCode   string
+	RejectReason string
+	Duplicate    bool // Resent client order ID: the original order's reply
+}
+
+// CancelOrderRequest cancels a resting order.
+type CancelOrderRequest struct {
+	Symbol  string
+	OrderID uint64
+}
+
+// CancelReply is the outcome of a cancel.
+type CancelReply struct {
+	OrderID      uint64
+	CancelledQty int64
+}
+
+// GetBookRequest asks for the top of a symbol's book.
+type GetBookRequest struct {
+	Symbol string
+	Depth  int64 // Levels per side (0 = 10)
+}
+
+// Level is one price level of a book.
+type Level struct {
+	Price    int64
+	Quantity int64
+	Orders   int64
+}
+
+// Book is the top of a symbol's book, best prices first.
+type Book struct {
+	Symbol string
+	Bids   []Level
+	Asks   []Level
+}
+
+// ExecutionsRequest subscribes to an account's execution reports.
+type ExecutionsRequest struct {
+	AccountID string
+}
+
+// ExecutionReport is one change to an account's order (see
+// orders.ExecutionReport).
+type ExecutionReport struct {
+	Seq           uint64 // The account's drop-copy number, also taken by risk events
+	AccountID     string
+	ExecType      ExecType
+	OrderID       uint64
+	ClientOrderID string
+	Symbol        string
+	Side          Side
+	Status        OrderStatus
+	Price         int64
+	Quantity      int64
+	TradeID       uint64
+	LastQty       int64
+	LastPx        int64
+	CumQty        int64
+	AvgPx         int64
+	LeavesQty     int64
+	IsMaker       bool
+	Fee           int64
+	Text          string
+	Timestamp     int64
+}
+
+// Order converts the request to an engine order, refusing unset or unknown
+// enums.
+func (r *SubmitOrderRequest) Order() (*orders.Order, error) {
+	order := &orders.Order{
+		Symbol:        r.Symbol,
+		Price:         r.Price,
+		Quantity:      r.Quantity,
+		AccountID:     r.AccountID,
+		ClientOrderID: r.ClientOrderID,
+		DisplayQty:    r.DisplayQty,
+	}
+	switch r.Side {
+	case SideBuy:
+		order.Side = orders.SideBuy
+	case SideSell:
+		order.Side = orders.SideSell
+	default:
+		return nil, fmt.Errorf("invalid side: must be BUY or SELL")
+	}
+	switch r.Type {
+	case OrderTypeLimit:
+		order.Type = orders.OrderTypeLimit
+	case OrderTypeMarket:
+		order.Type = orders.OrderTypeMarket
+	case OrderTypeIOC:
+		order.Type = orders.OrderTypeIOC
+	case OrderTypeFOK:
+		order.Type = orders.OrderTypeFOK
+	default:
+		return nil, fmt.Errorf("invalid type: must be LIMIT, MARKET, IOC or FOK")
+	}
+	switch r.Peg {
+	case PegNone:
+	case PegMidpoint:
+		order.Peg, order.PegLimit = orders.PegMidpoint, r.Price
+	case PegPrimary:
+		order.Peg, order.PegLimit = orders.PegPrimary, r.Price
+	default:
+		return nil, fmt.Errorf("invalid peg: must be NONE, MIDPOINT or PRIMARY")
+	}
+	return order, nil
+}
+
+// NewOrderStatus converts an engine order status.
+func NewOrderStatus(status orders.OrderStatus) OrderStatus {
+	return OrderStatus(status) + 1
+}
+
+// NewExecutionReport converts an engine execution report.
+func NewExecutionReport(seq uint64, report orders.ExecutionReport) *ExecutionReport {
+	side := SideBuy
+	if report.Side == orders.SideSell {
+		side = SideSell
+	}
+	return &ExecutionReport{
+		Seq:           seq,
+		AccountID:     report.AccountID,
+		ExecType:      execTypes[report.ExecType],
+		OrderID:       report.OrderID,
+		ClientOrderID: report.ClientOrderID,
+		Symbol:        report.Symbol,
+		Side:          side,
+		Status:        NewOrderStatus(report.Status),
+		Price:         report.Price,
+		Quantity:      report.Quantity,
+		TradeID:       report.TradeID,
+		LastQty:       report.LastQty,
+		LastPx:        report.LastPx,
+		CumQty:        report.CumQty,
+		AvgPx:         report.AvgPx,
+		LeavesQty:     report.LeavesQty,
+		IsMaker:       report.IsMaker,
+		Fee:           report.Fee,
+		Text:          report.Text,
+		Timestamp:     report.Timestamp,
+	}
+}
+
+var execTypes = map[orders.ExecType]ExecType{
+	orders.ExecTypeNew:       ExecTypeNew,
+	orders.ExecTypeTrade:     ExecTypeTrade,
+	orders.ExecTypeCancelled: ExecTypeCancelled,
+	orders.ExecTypeReplaced:  ExecTypeReplaced,
+	orders.ExecTypeRejected:  ExecTypeRejected,
+}
+
+// message is a message of orderentry.proto.
+type message interface {
+	bind(b binder)
+}
+
+// binder binds fields to their numbers. The encoder writes each field it is
+// given; the decoder sets each from the message it was given.
+type binder interface {
+	uint64(num protowire.Number, p *uint64)
+	int64(num protowire.Number, p *int64)
+	sint64(num protowire.Number, p *int64)
+	string(num protowire.Number, p *string)
+	bool(num protowire.Number, p *bool)
+
+	// repeated binds a repeated message field: the encoder writes item(i)
+	// for each of the n items, the decoder binds one add() per item read.
+	repeated(num protowire.Number, n int, item func(i int) message, add func() message)
+}
+
+// bindEnum binds an enum as an int64.
+func bindEnum[T ~int32](b binder, num protowire.Number, p *T) {
+	v := int64(*p)
+	b.int64(num, &v)
+	*p = T(v)
+}
+
+// bindRepeated binds a slice of messages.
+func bindRepeated[T any, P interface {
+	*T
+	message
+}](b binder, num protowire.Number, list *[]T) {
+	items := *list
+	b.repeated(num, len(items),
+		func(i int) message { return P(&items[i]) },
+		func() message {
+			*list = append(*list, *new(T))
+			return P(&(*list)[len(*list)-1])
+		})
+}
+
+func (m *SubmitOrderRequest) bind(b binder) {
+	b.string(1, &m.Symbol)
+	bindEnum(b, 2, &m.Side)
+	bindEnum(b, 3, &m.Type)
+	b.int64(4, &m.Price)
+	b.int64(5, &m.Quantity)
+	b.string(6, &m.AccountID)
+	b.string(7, &m.ClientOrderID)
+	b.int64(8, &m.DisplayQty)
+	bindEnum(b, 9, &m.Peg)
+}
+
+func (m *Fill) bind(b binder) {
+	b.uint64(1, &m.TradeID)
+	b.int64(2, &m.Price)
+	b.int64(3, &m.Quantity)
+	b.sint64(4, &m.Fee)
+}
+
+func (m *OrderReply) bind(b binder) {
+	b.bool(1, &m.Accepted)
+	b.uint64(2, &m.OrderID)
+	bindEnum(b, 3, &m.Status)
+	b.int64(4, &m.CumQty)
+	b.int64(5, &m.AvgPrice)
+	b.int64(6, &m.LeavesQty)
+	bindRepeated(b, 7, &m.Fills)
+	b.string(8, &m.RejectCode)
+	b.string(9, &m.RejectReason)
+	b.bool(10, &m.Duplicate)
+}
+
+func (m *CancelOrderRequest) bind(b binder) {
+	b.string(1, &m.Symbol)
+	b.uint64(2, &m.OrderID)
+}
+
+func (m *CancelReply) bind(b binder) {
+	b.uint64(1, &m.OrderID)
+	b.int64(2, &m.CancelledQty)
+}
+
+func (m *GetBookRequest) bind(b binder) {
+	b.string(1, &m.Symbol)
+	b.int64(2, &m.Depth)
+}
+
+func (m *Level) bind(b binder) {
+	b.int64(1, &m.Price)
+	b.int64(2, &m.Quantity)
+	b.int64(3, &m.Orders)
+}
+
+func (m *Book) bind(b binder) {
+	b.string(1, &m.Symbol)
+	bindRepeated(b, 2, &m.Bids)
+	bindRepeated(b, 3, &m.Asks)
+}
+
+func (m *ExecutionsRequest) bind(b binder) {
+	b.string(1, &m.AccountID)
+}
+
+func (m *ExecutionReport) bind(b binder) {
+	b.uint64(1, &m.Seq)
+	b.string(2, &m.AccountID)
+	bindEnum(b, 3, &m.ExecType)
+	b.uint64(4, &m.OrderID)
+	b.string(5, &m.ClientOrderID)
+	b.string(6, &m.Symbol)
+	bindEnum(b, 7, &m.Side)
+	bindEnum(b, 8, &m.Status)
+	b.int64(9, &m.Price)
+	b.int64(10, &m.Quantity)
+	b.uint64(11, &m.TradeID)
+	b.int64(12, &m.LastQty)
+	b.int64(13, &m.LastPx)
+	b.int64(14, &m.CumQty)
+	b.int64(15, &m.AvgPx)
+	b.int64(16, &m.LeavesQty)
+	b.bool(17, &m.IsMaker)
+	b.sint64(18, &m.Fee)
+	b.string(19, &m.Text)
+	b.int64(20, &m.Timestamp)
+}
+
+// encoder appends fields in proto3 style: zero values are left out.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) uint64(num protowire.Number, p *uint64) {
+	if *p != 0 {
+		e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+		e.b = protowire.AppendVarint(e.b, *p)
+	}
+}
+
+func (e *encoder) int64(num protowire.Number, p *int64) {
+	v := uint64(*p)
+	e.uint64(num, &v)
+}
+
+func (e *encoder) sint64(num protowire.Number, p *int64) {
+	v := protowire.EncodeZigZag(*p)
+	e.uint64(num, &v)
+}
+
+func (e *encoder) string(num protowire.Number, p *string) {
+	if *p != "" {
+		e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+		e.b = protowire.AppendString(e.b, *p)
+	}
+}
+
+func (e *encoder) bool(num protowire.Number, p *bool) {
+	v := protowire.EncodeBool(*p)
+	e.uint64(num, &v)
+}
+
+func (e *encoder) repeated(num protowire.Number, n int, item func(i int) message, _ func() message) {
+	for i := 0; i < n; i++ {
+		nested := encoder{}
+		item(i).bind(&nested)
+		e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+		e.b = protowire.AppendBytes(e.b, nested.b)
+	}
+}
+
+// field is a field value as read: varints in v, bytes in b.
+type field struct {
+	typ protowire.Type
+	v   uint64
+	b   []byte
+}
+
+// decoder decodes a message's fields up front, then hands them out by
+// number. The first field of the wrong wire type is kept in err.
+type decoder struct {
+	fields map[protowire.Number][]field
+	err    error
+}
+
+var errWireType = errors.New("protobuf: wrong wire type")
+
+func newDecoder(b []byte) (*decoder, error) {
+	d := &decoder{fields: make(map[protowire.Number][]field)}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		d.fields[num] = append(d.fields[num], f)
+	}
+	return d, nil
+}
+
+// last returns the last value given for num, as protobuf has the last
+// value of a repeated scalar win.
+func (d *decoder) last(num protowire.Number, typ protowire.Type) (field, bool) {
+	fields := d.fields[num]
+	if len(fields) == 0 {
+		return field{}, false
+	}
+	f := fields[len(fields)-1]
+	if f.typ != typ {
+		d.fail(num)
+		return field{}, false
+	}
+	return f, true
+}
+
+func (d *decoder) fail(num protowire.Number) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w for field %d", errWireType, num)
+	}
+}
+
+func (d *decoder) uint64(num protowire.Number, p *uint64) {
+	f, _ := d.last(num, protowire.VarintType)
+	*p = f.v
+}
+
+func (d *decoder) int64(num protowire.Number, p *int64) {
+	f, _ := d.last(num, protowire.VarintType)
+	*p = int64(f.v)
+}
+
+func (d *decoder) sint64(num protowire.Number, p *int64) {
+	f, _ := d.last(num, protowire.VarintType)
+	*p = protowire.DecodeZigZag(f.v)
+}
+
+func (d *decoder) bool(num protowire.Number, p *bool) {
+	f, _ := d.last(num, protowire.VarintType)
+	*p = protowire.DecodeBool(f.v)
+}
+
+func (d *decoder) string(num protowire.Number, p *string) {
+	f, _ := d.last(num, protowire.BytesType)
+	*p = string(f.b)
+}
+
+func (d *decoder) repeated(num protowire.Number, _ int, _ func(i int) message, add func() message) {
+	for _, f := range d.fields[num] {
+		if f.typ != protowire.BytesType {
+			d.fail(num)
+			return
+		}
+		nested, err := newDecoder(f.b)
+		if err != nil {
+			d.err = err
+			return
+		}
+		add().bind(nested)
+		if nested.err != nil {
+			d.err = nested.err
+			return
+		}
+	}
+}
+
+// marshal encodes a message.
+func marshal(m message) []byte {
+	enc := encoder{}
+	m.bind(&enc)
+	return enc.b
+}
+
+// unmarshal decodes b into a message.
+func unmarshal(b []byte, m message) error {
+	dec, err := newDecoder(b)
+	if err != nil {
+		return err
+	}
+	m.bind(dec)
+	return dec.err
+}
@@ -0,0 +1,87 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// Sessions
+//
+// Each client connection is a session, like a WebSocket or binary gateway
+// connection, with an ID of its own ("GRPC-1"). A client asks for cancel on
+// disconnect by sending the cancel-on-disconnect: true metadata with its
+// orders (WithCancelOnDisconnect does so for every call). The handler tags
+// those orders with the session ID (see Session), and once the connection
+// is gone, for whatever reason, Handler.CancelSession cancels whichever of
+// them still rest. Orders sent without the metadata are left alone.
+
+// CancelOnDisconnectKey is the metadata key asking for an order to be
+// cancelled if its connection drops.
+const CancelOnDisconnectKey = "cancel-on-disconnect"
+
+// sessionCounter numbers sessions.
+var sessionCounter uint64
+
+// session is the per-connection state.
+type session struct {
+	id   string
+	used atomic.Bool // An order asked for cancel on disconnect
+}
+
+type sessionKey struct{}
+
+// Session returns the ID of the session an RPC arrived on, and whether the
+// RPC asked for cancel on disconnect. Asking arms the session's cancel on
+// disconnect.
+func Session(ctx context.Context) (id string, cancelOnDisconnect bool) {
+	sess, _ := ctx.Value(sessionKey{}).(*session)
+	if sess == nil {
+		return "", false
+	}
+	values := metadata.ValueFromIncomingContext(ctx, CancelOnDisconnectKey)
+	if len(values) == 0 || values[len(values)-1] != "true" {
+		return sess.id, false
+	}
+	sess.used.Store(true)
+	return sess.id, true
+}
+
+// sessions is a stats handler giving each connection a session and ending
+// it when the connection closes.
+type sessions struct {
+	handler Handler
+}
+
+func (s sessions) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	id := fmt.Sprintf("GRPC-%d", atomic.AddUint64(&sessionCounter, 1))
+	return context.WithValue(ctx, sessionKey{}, &session{id: id})
+}
+
+func (s sessions) HandleConn(ctx context.Context, st stats.ConnStats) {
+	if _, ended := st.(*stats.ConnEnd); !ended {
+		return
+	}
+	if sess, _ := ctx.Value(sessionKey{}).(*session); sess != nil && sess.used.Load() {
+		s.handler.CancelSession(sess.id)
+	}
+}
+
+func (s sessions) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s sessions) HandleRPC(context.Context, stats.RPCStats) {}
+
+// WithCancelOnDisconnect makes a client ask for cancel on disconnect on
+// every call.
+func WithCancelOnDisconnect() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, CancelOnDisconnectKey, "true")
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
@@ -0,0 +1,247 @@
+package itch
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// DefaultHistory is the number of messages kept for retransmission.
+const DefaultHistory = 100000
+
+// DefaultHeartbeat is how often an idle feed sends a heartbeat.
+const DefaultHeartbeat = time.Second
+
+// Feed turns the publisher's book updates and trades into sequenced
+// messages and sends them as packets. Add it to a marketdata.Publisher with
+// AddFeed; it never blocks the publisher: packets are queued for a sender
+// goroutine, and dropped if the queue is full, leaving a gap receivers fill
+// by retransmission.
+type Feed struct {
+	mu      sync.Mutex
+	session [SessionLen]byte
+	next    uint64   // Seq of the next message, from 1
+	first   uint64   // Seq of history[0]
+	history [][]byte // Last max encoded messages, oldest first
+	max     int
+	levels  map[levelKey]bool // Levels currently in the book feed's top levels
+
+	out     chan []byte
+	w       io.Writer
+	beat    time.Duration
+	done    chan struct{}
+	stopped chan struct{}
+	dropped atomic.Uint64
+}
+
+type levelKey struct {
+	symbol string
+	buy    bool
+	price  int64
+}
+
+// NewFeed creates a feed for session writing packets to w, such as a
+// connection from DialMulticast, and keeping history messages for
+// retransmission. Only the first SessionLen bytes of session are used.
+func NewFeed(session string, w io.Writer, history int) *Feed {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Feed{
+		session: sessionID(session),
+		next:    1,
+		first:   1,
+		max:     history,
+		levels:  make(map[levelKey]bool),
+		out:     make(chan []byte, 1024),
+		w:       w,
+		beat:    DefaultHeartbeat,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// DialMulticast returns a connection sending to a multicast group, such as
+// 239.1.1.1:30001. Packets go out with the system's default multicast TTL
+// of 1, so they stay on the local network.
+func DialMulticast(group string) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", group)
+	if err != nil {
+		return nil, err
+	}
+	return net.DialUDP("udp", nil, addr)
+}
+
+// SetHeartbeat sets how often an idle feed sends a heartbeat. Must be
+// called before Start.
+func (f *Feed) SetHeartbeat(interval time.Duration) {
+	f.beat = interval
+}
+
+// Session returns the feed's session ID.
+func (f *Feed) Session() string {
+	return string(f.session[:])
+}
+
+// Dropped returns the number of packets dropped because the send queue
+// was full.
+func (f *Feed) Dropped() uint64 {
+	return f.dropped.Load()
+}
+
+// Start launches the sender.
+func (f *Feed) Start() {
+	go f.send()
+}
+
+// Close sends the end of session packet and stops the sender.
+func (f *Feed) Close() {
+	close(f.done)
+	<-f.stopped
+}
+
+// send writes queued packets, and heartbeats while there are none.
+func (f *Feed) send() {
+	defer close(f.stopped)
+	ticker := time.NewTicker(f.beat)
+	defer ticker.Stop()
+
+	idle := true
+	for {
+		select {
+		case packet := <-f.out:
+			f.write(packet)
+			idle = false
+		case <-ticker.C:
+			if idle {
+				f.write(f.emptyPacket(0))
+			}
+			idle = true
+		case <-f.done:
+			for len(f.out) > 0 {
+				f.write(<-f.out)
+			}
+			f.write(f.emptyPacket(EndOfSession))
+			return
+		}
+	}
+}
+
+func (f *Feed) write(packet []byte) {
+	if _, err := f.w.Write(packet); err != nil {
+		log.Printf("ITCH feed: send failed: %v", err)
+	}
+}
+
+// emptyPacket returns a packet without messages: a heartbeat, or the end
+// of the session.
+func (f *Feed) emptyPacket(count uint16) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return appendPacketHeader(nil, f.session, f.next, count)
+}
+
+// PublishBook sends an add, modify or delete message per level change.
+func (f *Feed) PublishBook(updates []marketdata.BookUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := make([]Message, len(updates))
+	for i, u := range updates {
+		key := levelKey{symbol: u.Symbol, buy: u.Side != orders.SideSell, price: u.Price}
+		m := Message{
+			Type:      ModifyLevel,
+			Symbol:    u.Symbol,
+			Timestamp: u.Timestamp,
+			Side:      u.Side,
+			Price:     u.Price,
+			Quantity:  u.Quantity,
+			Count:     u.Count,
+			BookSeq:   u.Seq,
+		}
+		switch {
+		case u.Quantity == 0:
+			m.Type = DeleteLevel
+			delete(f.levels, key)
+		case !f.levels[key]:
+			m.Type = AddLevel
+			f.levels[key] = true
+		}
+		messages[i] = m
+	}
+	f.publish(messages)
+}
+
+// PublishTrade sends a trade message.
+func (f *Feed) PublishTrade(trade marketdata.TradeReport) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publish([]Message{{
+		Type:      Trade,
+		Symbol:    trade.Symbol,
+		Timestamp: trade.Timestamp,
+		Side:      trade.AggressorSide,
+		Price:     trade.Price,
+		Quantity:  trade.Quantity,
+		TradeID:   trade.TradeID,
+	}})
+}
+
+// publish sequences and records messages, then queues them as packets.
+// Called with mu held.
+func (f *Feed) publish(messages []Message) {
+	start := f.next
+	for i := range messages {
+		encoded, err := AppendMessage(nil, &messages[i])
+		if err != nil {
+			log.Printf("ITCH feed: %v", err) // Not sequenced, so no gap
+			continue
+		}
+		f.history = append(f.history, encoded)
+		f.next++
+	}
+	if over := len(f.history) - f.max; over > 0 {
+		f.history = append([][]byte(nil), f.history[over:]...)
+		f.first += uint64(over)
+	}
+	if start < f.first {
+		start = f.first // More messages than the history holds
+	}
+
+	for _, packet := range f.packets(start, f.next) {
+		select {
+		case f.out <- packet:
+		default:
+			f.dropped.Add(1) // Receivers see the gap and ask for a retransmission
+		}
+	}
+}
+
+// packets packs the recorded messages from seq up to end into as few
+// packets as fit MaxPacketSize. Called with mu held.
+func (f *Feed) packets(seq, end uint64) [][]byte {
+	var packets [][]byte
+	for seq < end {
+		packet := appendPacketHeader(make([]byte, 0, MaxPacketSize), f.session, seq, 0)
+		count := uint16(0)
+		for ; seq < end; seq++ {
+			msg := f.history[seq-f.first]
+			if count > 0 && len(packet)+2+len(msg) > MaxPacketSize {
+				break
+			}
+			packet = binary.BigEndian.AppendUint16(packet, uint16(len(msg)))
+			packet = append(packet, msg...)
+			count++
+		}
+		binary.BigEndian.PutUint16(packet[SessionLen+8:], count)
+		packets = append(packets, packet)
+	}
+	return packets
+}
@@ -0,0 +1,241 @@
+// Package itch encodes market data as compact binary messages and
+// distributes them over UDP multicast, the way exchanges do.
+//
+// # ITCH-Style Binary Feed
+//
+// The HTTP and WebSocket feeds send JSON to each subscriber separately, so
+// the server's cost grows with every subscriber. A multicast feed sends each
+// packet once and the network copies it to every member of the group;
+// adding subscribers costs the server nothing.
+//
+// Messages are fixed-size, big-endian, and carry no field names, modelled
+// on NASDAQ TotalView-ITCH. The book messages are per price level, taken
+// from the publisher's sequenced book feed (see marketdata.BookUpdate):
+//
+//	'A' add level     type(1) symbol(8) timestamp(8) side(1) price(8) qty(8) count(4) book_seq(8)  46 bytes
+//	'U' modify level  same layout as add                                                            46 bytes
+//	'D' delete level  type(1) symbol(8) timestamp(8) side(1) price(8) book_seq(8)                    34 bytes
+//	'P' trade         type(1) symbol(8) timestamp(8) trade_id(8) side(1) price(8) qty(8)            42 bytes
+//
+// Symbols are left-aligned and space padded, sides are 'B' or 'S' (the
+// aggressor for trades) and prices are fixed-point cents. The same trade as
+// JSON on /ws is ~150 bytes.
+//
+// Packets are framed like MoldUDP64: every message gets a sequence number
+// for the session, and a packet carries consecutive messages:
+//
+//	session(10) seq(8) count(2) [ length(2) message ]*count
+//
+// seq is the sequence number of the first message. A packet with count 0
+// is a heartbeat, telling receivers the next seq to expect while the feed
+// is idle; count 0xFFFF ends the session.
+//
+// UDP drops packets silently, so receivers track seq. On a gap they ask
+// the retransmission server over TCP for the missing messages (see
+// ServeRetransmit), while applying nothing past the gap. The feed keeps
+// the last history messages for that; a receiver too far behind rebuilds
+// its book from GET /book instead and resumes from book_seq.
+package itch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// MessageType identifies a message.
+type MessageType byte
+
+const (
+	AddLevel    MessageType = 'A'
+	ModifyLevel MessageType = 'U'
+	DeleteLevel MessageType = 'D'
+	Trade       MessageType = 'P'
+)
+
+const (
+	// SessionLen is the length of a session ID.
+	SessionLen = 10
+
+	// SymbolLen is the length of the symbol field.
+	SymbolLen = 8
+
+	// MaxPacketSize keeps a packet within an Ethernet MTU after IP and UDP
+	// headers.
+	MaxPacketSize = 1400
+
+	// EndOfSession is the message count of the last packet of a session.
+	EndOfSession = 0xFFFF
+
+	packetHeaderLen  = SessionLen + 8 + 2
+	messageHeaderLen = 1 + SymbolLen + 8
+)
+
+// Message is one decoded message. Fields a type doesn't carry are zero.
+type Message struct {
+	Type      MessageType
+	Symbol    string
+	Timestamp int64
+	Side      orders.Side // Level side, or a trade's aggressor
+	Price     int64
+	Quantity  int64  // Add, modify and trade
+	Count     int    // Orders at the level; add and modify
+	BookSeq   uint64 // Book feed seq of the level change; add, modify and delete
+	TradeID   uint64 // Trade only
+}
+
+// Packet is one decoded packet.
+type Packet struct {
+	Session  string
+	Seq      uint64 // Seq of the first message, or the next one for a heartbeat
+	Messages []Message
+	End      bool // Last packet of the session
+}
+
+var (
+	ErrShortMessage = errors.New("itch: message truncated")
+	ErrShortPacket  = errors.New("itch: packet truncated")
+)
+
+// sizes is the encoded size of each message type.
+var sizes = map[MessageType]int{
+	AddLevel:    messageHeaderLen + 1 + 8 + 8 + 4 + 8,
+	ModifyLevel: messageHeaderLen + 1 + 8 + 8 + 4 + 8,
+	DeleteLevel: messageHeaderLen + 1 + 8 + 8,
+	Trade:       messageHeaderLen + 8 + 1 + 8 + 8,
+}
+
+// AppendMessage appends m's encoding to dst.
+func AppendMessage(dst []byte, m *Message) ([]byte, error) {
+	if _, ok := sizes[m.Type]; !ok {
+		return dst, fmt.Errorf("itch: unknown message type %q", m.Type)
+	}
+	if len(m.Symbol) > SymbolLen {
+		return dst, fmt.Errorf("itch: symbol %q longer than %d characters", m.Symbol, SymbolLen)
+	}
+
+	dst = append(dst, byte(m.Type))
+	dst = append(dst, m.Symbol...)
+	dst = append(dst, strings.Repeat(" ", SymbolLen-len(m.Symbol))...)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(m.Timestamp))
+	switch m.Type {
+	case AddLevel, ModifyLevel:
+		dst = append(dst, sideCode(m.Side))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Price))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Quantity))
+		dst = binary.BigEndian.AppendUint32(dst, uint32(m.Count))
+		dst = binary.BigEndian.AppendUint64(dst, m.BookSeq)
+	case DeleteLevel:
+		dst = append(dst, sideCode(m.Side))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Price))
+		dst = binary.BigEndian.AppendUint64(dst, m.BookSeq)
+	case Trade:
+		dst = binary.BigEndian.AppendUint64(dst, m.TradeID)
+		dst = append(dst, sideCode(m.Side))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Price))
+		dst = binary.BigEndian.AppendUint64(dst, uint64(m.Quantity))
+	}
+	return dst, nil
+}
+
+// ParseMessage decodes one message.
+func ParseMessage(b []byte) (Message, error) {
+	if len(b) == 0 {
+		return Message{}, ErrShortMessage
+	}
+	m := Message{Type: MessageType(b[0])}
+	size, ok := sizes[m.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("itch: unknown message type %q", b[0])
+	}
+	if len(b) < size {
+		return Message{}, ErrShortMessage
+	}
+
+	m.Symbol = strings.TrimRight(string(b[1:1+SymbolLen]), " ")
+	m.Timestamp = int64(binary.BigEndian.Uint64(b[1+SymbolLen:]))
+	body := b[messageHeaderLen:]
+	switch m.Type {
+	case AddLevel, ModifyLevel:
+		m.Side = sideOf(body[0])
+		m.Price = int64(binary.BigEndian.Uint64(body[1:]))
+		m.Quantity = int64(binary.BigEndian.Uint64(body[9:]))
+		m.Count = int(binary.BigEndian.Uint32(body[17:]))
+		m.BookSeq = binary.BigEndian.Uint64(body[21:])
+	case DeleteLevel:
+		m.Side = sideOf(body[0])
+		m.Price = int64(binary.BigEndian.Uint64(body[1:]))
+		m.BookSeq = binary.BigEndian.Uint64(body[9:])
+	case Trade:
+		m.TradeID = binary.BigEndian.Uint64(body)
+		m.Side = sideOf(body[8])
+		m.Price = int64(binary.BigEndian.Uint64(body[9:]))
+		m.Quantity = int64(binary.BigEndian.Uint64(body[17:]))
+	}
+	return m, nil
+}
+
+// appendPacketHeader appends a packet header to dst.
+func appendPacketHeader(dst []byte, session [SessionLen]byte, seq uint64, count uint16) []byte {
+	dst = append(dst, session[:]...)
+	dst = binary.BigEndian.AppendUint64(dst, seq)
+	return binary.BigEndian.AppendUint16(dst, count)
+}
+
+// ParsePacket decodes a packet and its messages.
+func ParsePacket(b []byte) (Packet, error) {
+	if len(b) < packetHeaderLen {
+		return Packet{}, ErrShortPacket
+	}
+	p := Packet{
+		Session: string(b[:SessionLen]),
+		Seq:     binary.BigEndian.Uint64(b[SessionLen:]),
+	}
+	count := binary.BigEndian.Uint16(b[SessionLen+8:])
+	if count == EndOfSession {
+		p.End = true
+		return p, nil
+	}
+
+	b = b[packetHeaderLen:]
+	for i := 0; i < int(count); i++ {
+		if len(b) < 2 {
+			return Packet{}, ErrShortPacket
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			return Packet{}, ErrShortPacket
+		}
+		m, err := ParseMessage(b[2 : 2+n])
+		if err != nil {
+			return Packet{}, err
+		}
+		p.Messages = append(p.Messages, m)
+		b = b[2+n:]
+	}
+	return p, nil
+}
+
+// sessionID pads or truncates session to SessionLen bytes.
+func sessionID(session string) [SessionLen]byte {
+	var id [SessionLen]byte
+	copy(id[:], session+strings.Repeat(" ", SessionLen))
+	return id
+}
+
+func sideCode(side orders.Side) byte {
+	if side == orders.SideSell {
+		return 'S'
+	}
+	return 'B'
+}
+
+func sideOf(code byte) orders.Side {
+	if code == 'S' {
+		return orders.SideSell
+	}
+	return orders.SideBuy
+}
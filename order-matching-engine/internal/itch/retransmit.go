@@ -0,0 +1,142 @@
+package itch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+)
+
+// Gap Requests
+//
+// A receiver that sees seq jump asks for the missing messages over TCP. A
+// request is 20 bytes, as in MoldUDP64:
+//
+//	session(10) seq(8) count(2)
+//
+// The reply is the messages as packets, each prefixed with its length as a
+// uint16, and ended by a zero length. The packets start at the first
+// message still held: if seq is older than the history, the reply's first
+// seq tells the receiver what it can no longer recover. A request for
+// another session (the server restarted) gets just a heartbeat of the
+// current session, from which the receiver starts over.
+
+// MaxRetransmit is the most messages one request returns.
+const MaxRetransmit = 10000
+
+const requestLen = SessionLen + 8 + 2
+
+// ErrSessionChanged is returned by RequestRetransmit when the server is on
+// another session.
+var ErrSessionChanged = errors.New("itch: feed session changed")
+
+// Retransmit returns the packets holding up to count messages from seq,
+// starting at the oldest held if seq is no longer.
+func (f *Feed) Retransmit(seq uint64, count int) [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if count > MaxRetransmit {
+		count = MaxRetransmit
+	}
+	if seq < f.first {
+		seq = f.first
+	}
+	end := seq + uint64(count)
+	if end > f.next {
+		end = f.next
+	}
+	if seq >= end {
+		return [][]byte{appendPacketHeader(nil, f.session, f.next, 0)}
+	}
+	return f.packets(seq, end)
+}
+
+// ServeRetransmit answers gap requests on l until it is closed.
+func (f *Feed) ServeRetransmit(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f.serveConn(conn)
+	}
+}
+
+// serveConn answers requests on one connection until the client closes it.
+func (f *Feed) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	request := make([]byte, requestLen)
+	for {
+		if _, err := io.ReadFull(r, request); err != nil {
+			if err != io.EOF {
+				log.Printf("ITCH retransmit: %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		var packets [][]byte
+		if string(request[:SessionLen]) != f.Session() {
+			packets = [][]byte{f.emptyPacket(0)}
+		} else {
+			seq := binary.BigEndian.Uint64(request[SessionLen:])
+			count := binary.BigEndian.Uint16(request[SessionLen+8:])
+			packets = f.Retransmit(seq, int(count))
+		}
+
+		for _, packet := range packets {
+			w.Write(binary.BigEndian.AppendUint16(nil, uint16(len(packet))))
+			w.Write(packet)
+		}
+		w.Write([]byte{0, 0})
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// RequestRetransmit asks the retransmission server on conn for count
+// messages of session from seq, and returns the packets of its reply.
+func RequestRetransmit(conn io.ReadWriter, session string, seq uint64, count uint16) ([]Packet, error) {
+	id := sessionID(session)
+	request := make([]byte, 0, requestLen)
+	request = append(request, id[:]...)
+	request = binary.BigEndian.AppendUint64(request, seq)
+	request = binary.BigEndian.AppendUint16(request, count)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	var packets []Packet
+	length := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint16(length)
+		if n == 0 {
+			break
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return nil, err
+		}
+		packet, err := ParsePacket(data)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+	}
+	if len(packets) > 0 && packets[0].Session != string(id[:]) {
+		return packets, ErrSessionChanged
+	}
+	return packets, nil
+}
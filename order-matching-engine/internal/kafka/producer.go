@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ProducerConfig configures a producer.
+type ProducerConfig struct {
+	Brokers  []string      // Bootstrap brokers, host:port
+	ClientID string        // Sent with every request (default "order-matching-engine")
+	Timeout  time.Duration // Dial and request timeout, and how long brokers wait for replicas (default 10s)
+}
+
+// Producer sends batches of messages to a topic's partition leaders, with
+// acks from every in-sync replica. It keeps one connection per broker and
+// sends one request at a time. Safe for concurrent use.
+type Producer struct {
+	config ProducerConfig
+
+	mu          sync.Mutex
+	correlation int32
+	brokers     map[int32]string       // Node ID -> address, from the last metadata
+	leaders     map[string][]int32     // Topic -> leader of each partition
+	conns       map[string]*brokerConn // Address -> open connection
+}
+
+// brokerConn is a connection to one broker.
+type brokerConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewProducer creates a producer for the cluster config.Brokers belong
+// to. It connects on first use.
+func NewProducer(config ProducerConfig) (*Producer, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers")
+	}
+	if config.ClientID == "" {
+		config.ClientID = "order-matching-engine"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &Producer{
+		config:  config,
+		brokers: make(map[int32]string),
+		leaders: make(map[string][]int32),
+		conns:   make(map[string]*brokerConn),
+	}, nil
+}
+
+// Produce writes messages to topic, each to the partition its key hashes
+// to, in order within a partition, and returns once every leader has
+// acknowledged them. On error some partitions may have taken their
+// messages and others not; producing the same messages again then
+// duplicates the ones that were taken.
+func (p *Producer) Produce(topic string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	leaders, err := p.partitionLeaders(topic)
+	if err != nil {
+		return err
+	}
+
+	// Messages by leader, then by partition
+	byLeader := make(map[int32]map[int32][]Message)
+	for _, msg := range messages {
+		partition := Partition(msg.Key, len(leaders))
+		leader := leaders[partition]
+		if leader < 0 {
+			p.forget(topic)
+			return &BrokerError{Topic: topic, Partition: partition, Code: errLeaderNotAvailable}
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[int32][]Message)
+		}
+		byLeader[leader][partition] = append(byLeader[leader][partition], msg)
+	}
+
+	for leader, batches := range byLeader {
+		addr, ok := p.brokers[leader]
+		if !ok {
+			p.forget(topic)
+			return fmt.Errorf("kafka: leader %d of %s is not a known broker", leader, topic)
+		}
+		p.correlation++
+		body, err := p.roundTrip(addr, produceRequest(p.correlation, p.config.ClientID, topic, p.config.Timeout, batches))
+		if err == nil {
+			err = parseProduce(body)
+		}
+		if err != nil {
+			var brokerErr *BrokerError
+			if !errors.As(err, &brokerErr) || brokerErr.Retriable() {
+				p.forget(topic) // Leadership may have moved
+			}
+			return fmt.Errorf("kafka: produce to %s via %s: %w", topic, addr, err)
+		}
+	}
+	return nil
+}
+
+// partitionLeaders returns the leader of each of topic's partitions,
+// fetching metadata if it isn't known. Must hold p.mu.
+func (p *Producer) partitionLeaders(topic string) ([]int32, error) {
+	if leaders, ok := p.leaders[topic]; ok {
+		return leaders, nil
+	}
+
+	var lastErr error
+	for _, addr := range p.bootstrap() {
+		p.correlation++
+		body, err := p.roundTrip(addr, metadataRequest(p.correlation, p.config.ClientID, topic))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		brokers, topics, err := parseMetadata(body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, b := range brokers {
+			p.brokers[b.id] = b.addr
+		}
+		metadata, ok := topics[topic]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("kafka: topic %s missing from metadata", topic)
+		case metadata.err != errNone:
+			return nil, &BrokerError{Topic: topic, Partition: -1, Code: metadata.err}
+		case len(metadata.leaders) == 0:
+			return nil, fmt.Errorf("kafka: topic %s has no partitions", topic)
+		}
+		p.leaders[topic] = metadata.leaders
+		return metadata.leaders, nil
+	}
+	return nil, fmt.Errorf("kafka: no broker answered metadata for %s: %w", topic, lastErr)
+}
+
+// bootstrap returns the brokers to ask for metadata: the configured ones,
+// then any learned since. Must hold p.mu.
+func (p *Producer) bootstrap() []string {
+	addrs := append([]string(nil), p.config.Brokers...)
+	for _, addr := range p.brokers {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// forget drops what is known about topic's leaders, so the next Produce
+// fetches metadata again. Must hold p.mu.
+func (p *Producer) forget(topic string) {
+	delete(p.leaders, topic)
+}
+
+// roundTrip sends a request to the broker at addr and returns the body of
+// its response. A connection that fails is closed and redialed next time.
+// Must hold p.mu.
+func (p *Producer) roundTrip(addr string, request []byte) ([]byte, error) {
+	bc, ok := p.conns[addr]
+	if !ok {
+		conn, err := net.DialTimeout("tcp", addr, p.config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		bc = &brokerConn{conn: conn, reader: bufio.NewReader(conn)}
+		p.conns[addr] = bc
+	}
+
+	body, err := bc.roundTrip(request, p.config.Timeout)
+	if err != nil {
+		bc.conn.Close()
+		delete(p.conns, addr)
+		return nil, err
+	}
+	return body, nil
+}
+
+func (bc *brokerConn) roundTrip(request []byte, timeout time.Duration) ([]byte, error) {
+	// The broker may hold a produce for up to the timeout waiting on
+	// replicas, so the deadline allows for that and the round trip
+	bc.conn.SetDeadline(time.Now().Add(2 * timeout))
+	if _, err := bc.conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	var head [8]byte // size, correlation_id
+	if _, err := io.ReadFull(bc.reader, head[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(head[0:])
+	if size < 4 || size > maxResponse {
+		return nil, fmt.Errorf("kafka: invalid response size %d", size)
+	}
+	sent := binary.BigEndian.Uint32(request[8:])
+	if got := binary.BigEndian.Uint32(head[4:]); got != sent {
+		return nil, fmt.Errorf("kafka: response to request %d answers %d", sent, got)
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(bc.reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Close closes every broker connection.
+func (p *Producer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, bc := range p.conns {
+		bc.conn.Close()
+		delete(p.conns, addr)
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+// Package kafka mirrors the event log to a Kafka topic, so systems outside
+// the engine can build read models from its events.
+//
+// # Wire Protocol
+//
+// Only what a producer needs is implemented, written out by hand like the
+// ITCH feed and the event log's protobuf codec, so the engine takes on no
+// client library. Two requests are sent, each framed as
+//
+//	size(4) api_key(2) api_version(2) correlation_id(4) client_id(string) body
+//
+//	Metadata v1  topics            ──▶ brokers, and each partition's leader
+//	Produce  v3  acks=all, batches ──▶ an error code per partition
+//
+// Messages go out as v2 record batches (magic 2), one per partition per
+// request, uncompressed and checksummed with CRC-32C:
+//
+//	base_offset(8) length(4) leader_epoch(4) magic(1) crc(4) attributes(2)
+//	last_offset_delta(4) first_timestamp(8) max_timestamp(8) producer_id(8)
+//	producer_epoch(2) base_sequence(4) count(4) records...
+//
+// Each record is varint-framed: length, attributes, timestamp and offset
+// deltas, key, value and headers. A message's partition is the murmur2
+// hash of its key, as Kafka's own clients choose it, so every event of a
+// symbol lands in one partition, in order.
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// API keys and the versions spoken.
+const (
+	apiProduce  = 0
+	apiMetadata = 3
+
+	produceVersion  = 3
+	metadataVersion = 1
+)
+
+// Broker error codes the producer recovers from by refreshing metadata.
+const (
+	errNone                   = 0
+	errUnknownTopic           = 3
+	errLeaderNotAvailable     = 5
+	errNotLeader              = 6
+	errRequestTimedOut        = 7
+	errNetwork                = 13
+	errNotEnoughReplicas      = 19
+	errNotEnoughReplicasAfter = 20
+)
+
+// maxResponse bounds the size read from a response, so a garbled length
+// can't demand an absurd allocation.
+const maxResponse = 64 << 20
+
+// BrokerError is an error code a broker answered for a partition.
+type BrokerError struct {
+	Topic     string
+	Partition int32
+	Code      int16
+}
+
+func (e *BrokerError) Error() string {
+	return fmt.Sprintf("kafka: broker error %d on %s/%d", e.Code, e.Topic, e.Partition)
+}
+
+// Retriable reports whether the error clears once the producer finds the
+// partition's current leader, or the broker catches up.
+func (e *BrokerError) Retriable() bool {
+	switch e.Code {
+	case errUnknownTopic, errLeaderNotAvailable, errNotLeader, errRequestTimedOut,
+		errNetwork, errNotEnoughReplicas, errNotEnoughReplicasAfter:
+		return true
+	}
+	return false
+}
+
+// errShortResponse is a response that ended before its last field.
+var errShortResponse = errors.New("kafka: response too short")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Message is one record to produce.
+type Message struct {
+	Key     []byte
+	Value   []byte
+	Headers []Header
+	Time    time.Time
+}
+
+// Header is a record header.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Partition returns the partition of n a key is produced to: the murmur2
+// hash of the key, made positive, modulo n, as Kafka's default partitioner
+// computes it.
+func Partition(key []byte, n int) int32 {
+	return int32((murmur2(key) & 0x7fffffff) % uint32(n))
+}
+
+// murmur2 is Kafka's 32-bit murmur2 hash (seed 0x9747b28c).
+func murmur2(data []byte) uint32 {
+	const (
+		m = 0x5bd1e995
+		r = 24
+	)
+	length := len(data)
+	h := uint32(0x9747b28c) ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// ============================================================================
+// ENCODING
+// ============================================================================
+
+// encoder appends protocol fields to a buffer.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *encoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *encoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *encoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v) // Zigzag, as Kafka's varints are
+}
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) nullString() { e.int16(-1) }
+
+// varBytes writes a varint length and b; nil is written as null (-1).
+func (e *encoder) varBytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// reserve32 writes a placeholder int32 and returns its offset, to be filled
+// in by fill32 once what it measures is written.
+func (e *encoder) reserve32() int {
+	e.int32(0)
+	return len(e.buf) - 4
+}
+
+func (e *encoder) fill32(at int, v uint32) {
+	binary.BigEndian.PutUint32(e.buf[at:], v)
+}
+
+// header starts a request, returning the offset of its size field.
+func (e *encoder) header(apiKey, version int16, correlation int32, clientID string) int {
+	size := e.reserve32()
+	e.int16(apiKey)
+	e.int16(version)
+	e.int32(correlation)
+	e.string(clientID)
+	return size
+}
+
+// finish fills in a request's size.
+func (e *encoder) finish(size int) {
+	e.fill32(size, uint32(len(e.buf)-size-4))
+}
+
+// metadataRequest encodes a Metadata v1 request for one topic.
+func metadataRequest(correlation int32, clientID, topic string) []byte {
+	var e encoder
+	size := e.header(apiMetadata, metadataVersion, correlation, clientID)
+	e.int32(1)
+	e.string(topic)
+	e.finish(size)
+	return e.buf
+}
+
+// produceRequest encodes a Produce v3 request for one topic, with a record
+// batch per partition.
+func produceRequest(correlation int32, clientID, topic string, timeout time.Duration, batches map[int32][]Message) []byte {
+	var e encoder
+	size := e.header(apiProduce, produceVersion, correlation, clientID)
+	e.nullString() // transactional_id
+	e.int16(-1)    // acks: every in-sync replica
+	e.int32(int32(timeout / time.Millisecond))
+	e.int32(1)
+	e.string(topic)
+	e.int32(int32(len(batches)))
+	for partition, messages := range batches {
+		e.int32(partition)
+		length := e.reserve32()
+		appendRecordBatch(&e, messages)
+		e.fill32(length, uint32(len(e.buf)-length-4))
+	}
+	e.finish(size)
+	return e.buf
+}
+
+// appendRecordBatch appends messages as one v2 record batch.
+func appendRecordBatch(e *encoder, messages []Message) {
+	first, max := messages[0].Time.UnixMilli(), messages[0].Time.UnixMilli()
+	for _, msg := range messages {
+		if ts := msg.Time.UnixMilli(); ts > max {
+			max = ts
+		}
+	}
+
+	e.int64(0) // base_offset: the broker assigns offsets
+	length := e.reserve32()
+	e.int32(-1) // partition_leader_epoch
+	e.int8(2)   // magic
+	crc := e.reserve32()
+	e.int16(0) // attributes: uncompressed, create time
+	e.int32(int32(len(messages) - 1))
+	e.int64(first)
+	e.int64(max)
+	e.int64(-1) // producer_id: not idempotent
+	e.int16(-1) // producer_epoch
+	e.int32(-1) // base_sequence
+	e.int32(int32(len(messages)))
+
+	var record encoder
+	for i, msg := range messages {
+		record.buf = record.buf[:0]
+		record.int8(0) // attributes
+		record.varint(msg.Time.UnixMilli() - first)
+		record.varint(int64(i))
+		record.varBytes(msg.Key)
+		record.varBytes(msg.Value)
+		record.varint(int64(len(msg.Headers)))
+		for _, header := range msg.Headers {
+			record.varBytes([]byte(header.Key))
+			record.varBytes(header.Value)
+		}
+		e.varint(int64(len(record.buf)))
+		e.buf = append(e.buf, record.buf...)
+	}
+
+	e.fill32(length, uint32(len(e.buf)-length-4))
+	e.fill32(crc, crc32.Checksum(e.buf[crc+4:], castagnoli))
+}
+
+// ============================================================================
+// DECODING
+// ============================================================================
+
+// decoder reads protocol fields from a response body. The first read past
+// the end sets err, and every read after it returns zero.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errShortResponse
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+// count reads an array length.
+func (d *decoder) count() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	if int(n) > len(d.buf) {
+		d.err = errShortResponse // Every element is at least a byte
+		return 0
+	}
+	return int(n)
+}
+
+// broker is a broker as Metadata lists it.
+type broker struct {
+	id   int32
+	addr string
+}
+
+// topicMetadata is what Metadata says about one topic.
+type topicMetadata struct {
+	err     int16
+	leaders []int32 // Leader node of each partition, by index (-1 = none)
+}
+
+// parseMetadata decodes a Metadata v1 response body.
+func parseMetadata(body []byte) ([]broker, map[string]topicMetadata, error) {
+	d := &decoder{buf: body}
+	brokers := make([]broker, d.count())
+	for i := range brokers {
+		brokers[i].id = d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[i].addr = fmt.Sprintf("%s:%d", host, port)
+	}
+	d.int32() // controller_id
+
+	topics := make(map[string]topicMetadata)
+	for i, n := 0, d.count(); i < n; i++ {
+		code := d.int16()
+		name := d.string()
+		d.int8() // is_internal
+		partitions := d.count()
+		topic := topicMetadata{err: code, leaders: make([]int32, partitions)}
+		for j := range topic.leaders {
+			topic.leaders[j] = -1
+		}
+		for j := 0; j < partitions; j++ {
+			d.int16() // partition error: a missing leader says as much
+			index := d.int32()
+			leader := d.int32()
+			for k, replicas := 0, d.count(); k < replicas; k++ {
+				d.int32()
+			}
+			for k, isr := 0, d.count(); k < isr; k++ {
+				d.int32()
+			}
+			if index >= 0 && int(index) < partitions {
+				topic.leaders[index] = leader
+			}
+		}
+		topics[name] = topic
+	}
+	return brokers, topics, d.err
+}
+
+// parseProduce decodes a Produce v3 response body, returning the first
+// partition error it reports.
+func parseProduce(body []byte) error {
+	d := &decoder{buf: body}
+	var first error
+	for i, n := 0, d.count(); i < n; i++ {
+		topic := d.string()
+		for j, partitions := 0, d.count(); j < partitions; j++ {
+			partition := d.int32()
+			code := d.int16()
+			d.int64() // base_offset
+			d.int64() // log_append_time
+			if code != errNone && first == nil {
+				first = &BrokerError{Topic: topic, Partition: partition, Code: code}
+			}
+		}
+	}
+	d.int32() // throttle_time_ms
+	if d.err != nil {
+		return d.err
+	}
+	return first
+}
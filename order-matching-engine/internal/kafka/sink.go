@@ -0,0 +1,326 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// Event Log Sink
+//
+// A Sink follows one event log with Tail and produces every event to a
+// topic, in sequence order:
+//
+//	event log ──Tail──▶ Sink ──batch──▶ Producer ──▶ topic (partition by symbol)
+//	                      │
+//	                      └──▶ offset file (last sequence number produced)
+//
+// Each message is keyed by the event's symbol ("" for events without one),
+// so a symbol's events stay in order in one partition. Its value is the
+// event as the codec encodes it, and its headers carry what a consumer
+// needs to order, deduplicate and decode it:
+//
+//	seq      the event's sequence number in its shard's log
+//	type     the event type, e.g. FILL
+//	shard    the shard whose log it came from
+//	codec    protobuf or gob (see events/events.proto)
+//	version  the event schema version
+//
+// Delivery is at least once. A failed batch is retried, with backoff,
+// until it goes through, and may then repeat events some partitions had
+// already taken; a consumer skips any seq at or below the last it applied.
+// After each batch the sequence number produced is written to the offset
+// file, and a restarted sink resumes after it. Without one, it starts from
+// the oldest event still in the log.
+//
+// The sink never slows the engine down: Tail drops it if it falls too far
+// behind, and it tails again from the offset it has produced to, reading
+// the log from disk.
+
+// SinkConfig configures a sink.
+type SinkConfig struct {
+	Topic      string
+	Shard      int           // Sent in the shard header
+	Codec      events.Codec  // Encodes message values (default protobuf)
+	BatchSize  int           // Most events per produce request (default 500)
+	OffsetPath string        // Keeps the last sequence number produced (empty = start from the log on every run)
+	Backoff    time.Duration // First wait after a failed batch, doubling up to MaxBackoff (default 100ms)
+	MaxBackoff time.Duration // Longest wait between retries (default 10s)
+}
+
+func (c SinkConfig) withDefaults() SinkConfig {
+	if c.Codec == nil {
+		c.Codec = events.ProtobufCodec{}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	return c
+}
+
+// SinkStats is a sink's progress.
+type SinkStats struct {
+	Produced uint64 `json:"produced_seq"` // Last sequence number produced
+	Lag      uint64 `json:"lag"`          // Events logged but not yet produced
+	Failures uint64 `json:"failures"`     // Batches that failed and were retried
+}
+
+// Sink produces an event log to a topic. Start it with Start.
+type Sink struct {
+	log      *events.EventLog
+	producer *Producer
+	config   SinkConfig
+
+	produced atomic.Uint64
+	failures atomic.Uint64
+
+	mu      sync.Mutex
+	records <-chan events.Record // Current tail (nil between tails)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewSink creates a sink producing eventLog to config.Topic through
+// producer, resuming after the sequence number in config.OffsetPath.
+func NewSink(eventLog *events.EventLog, producer *Producer, config SinkConfig) (*Sink, error) {
+	if config.Topic == "" {
+		return nil, errors.New("kafka: sink needs a topic")
+	}
+	s := &Sink{
+		log:      eventLog,
+		producer: producer,
+		config:   config.withDefaults(),
+		done:     make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if config.OffsetPath != "" {
+		produced, err := readOffset(config.OffsetPath)
+		if err != nil {
+			return nil, err
+		}
+		s.produced.Store(produced)
+	}
+	return s, nil
+}
+
+// Start starts producing on the sink's own goroutine.
+func (s *Sink) Start() {
+	go s.run()
+}
+
+// Stats returns the sink's progress.
+func (s *Sink) Stats() SinkStats {
+	stats := SinkStats{Produced: s.produced.Load(), Failures: s.failures.Load()}
+	if last := s.log.GetLastSequence(); last > stats.Produced {
+		stats.Lag = last - stats.Produced
+	}
+	return stats
+}
+
+// Close waits until every event logged so far is produced, or ctx is done,
+// then stops the sink. Stop the log's writers first.
+func (s *Sink) Close(ctx context.Context) error {
+	target := s.log.GetLastSequence()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	var err error
+	for s.produced.Load() < target && err == nil {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			err = fmt.Errorf("kafka: sink stopped %d events short: %w", target-s.produced.Load(), ctx.Err())
+		}
+	}
+
+	s.cancel()
+	s.mu.Lock()
+	if s.records != nil {
+		s.log.StopTail(s.records)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return err
+}
+
+// run tails the log and produces it until the sink is closed.
+func (s *Sink) run() {
+	defer close(s.done)
+	for s.ctx.Err() == nil {
+		from := s.produced.Load() + 1
+		records, err := s.log.Tail(from)
+		if errors.Is(err, events.ErrTruncated) {
+			first := s.log.FirstSequence()
+			log.Printf("Kafka sink: events %d-%d of shard %d were deleted before they were produced; resuming at %d",
+				from, first-1, s.config.Shard, first)
+			s.produced.Store(first - 1)
+			continue
+		}
+		if errors.Is(err, events.ErrTailAhead) {
+			// The log was rewound past what was produced (see the request
+			// journal): the events logged again replace ones already sent
+			last := s.log.GetLastSequence()
+			log.Printf("Kafka sink: produced up to %d, past the end of shard %d's event log (%d); resuming after %d",
+				from-1, s.config.Shard, last, last)
+			s.produced.Store(last)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, events.ErrLogClosed) {
+				log.Printf("Kafka sink: can't tail shard %d's event log: %v", s.config.Shard, err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		if s.ctx.Err() != nil {
+			s.mu.Unlock()
+			s.log.StopTail(records)
+			return
+		}
+		s.records = records
+		s.mu.Unlock()
+
+		s.produceTail(records)
+
+		s.mu.Lock()
+		s.records = nil
+		s.mu.Unlock()
+	}
+}
+
+// produceTail produces a tail's events in batches until it closes: the sink
+// fell behind, or is being closed.
+func (s *Sink) produceTail(records <-chan events.Record) {
+	batch := make([]events.Record, 0, s.config.BatchSize)
+	for {
+		record, ok := <-records
+		if !ok {
+			return
+		}
+		batch = append(batch[:0], record)
+	gather:
+		for len(batch) < s.config.BatchSize {
+			select {
+			case record, ok := <-records:
+				if !ok {
+					break gather
+				}
+				batch = append(batch, record)
+			default:
+				break gather
+			}
+		}
+		if !s.produceBatch(batch) {
+			return
+		}
+	}
+}
+
+// produceBatch produces a batch, retrying until it goes through. Returns
+// false if the sink was closed first.
+func (s *Sink) produceBatch(batch []events.Record) bool {
+	messages := make([]Message, 0, len(batch))
+	for _, record := range batch {
+		msg, err := s.message(record)
+		if err != nil {
+			// Can't be produced, ever: logged and skipped, not retried
+			log.Printf("Kafka sink: skipping event %d of shard %d: %v", record.Seq, s.config.Shard, err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	backoff := s.config.Backoff
+	for {
+		err := s.producer.Produce(s.config.Topic, messages)
+		if err == nil {
+			break
+		}
+		s.failures.Add(1)
+		log.Printf("Kafka sink: failed to produce events %d-%d of shard %d, retrying in %s: %v",
+			batch[0].Seq, batch[len(batch)-1].Seq, s.config.Shard, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			return false
+		}
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+
+	last := batch[len(batch)-1].Seq
+	s.produced.Store(last)
+	if s.config.OffsetPath != "" {
+		if err := writeOffset(s.config.OffsetPath, last); err != nil {
+			log.Printf("Kafka sink: failed to save offset %d: %v", last, err)
+		}
+	}
+	return true
+}
+
+// message builds the message of an event.
+func (s *Sink) message(record events.Record) (Message, error) {
+	value, err := s.config.Codec.Marshal(nil, record.Event)
+	if err != nil {
+		return Message{}, err
+	}
+	ts := time.Now()
+	if nanos := events.TimestampOf(record.Event); nanos > 0 {
+		ts = time.Unix(0, nanos)
+	}
+	return Message{
+		Key:   []byte(events.SymbolOf(record.Event)),
+		Value: value,
+		Time:  ts,
+		Headers: []Header{
+			{Key: "seq", Value: []byte(strconv.FormatUint(record.Seq, 10))},
+			{Key: "type", Value: []byte(record.Type.String())},
+			{Key: "shard", Value: []byte(strconv.Itoa(s.config.Shard))},
+			{Key: "codec", Value: []byte(s.config.Codec.Name())},
+			{Key: "version", Value: []byte(strconv.FormatUint(uint64(events.SchemaVersion), 10))},
+		},
+	}, nil
+}
+
+// readOffset reads an offset file, 0 if there is none.
+func readOffset(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read kafka offset: %w", err)
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid kafka offset in %s: %w", path, err)
+	}
+	return seq, nil
+}
+
+// writeOffset replaces an offset file. It isn't synced: an offset lost to
+// a crash only means producing some events again.
+func writeOffset(path string, seq uint64) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(seq, 10)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
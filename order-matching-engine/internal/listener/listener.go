@@ -0,0 +1,218 @@
+// Package listener splits an HTTP API across up to three listeners, by who
+// calls it: trading, market data and admin, each with its own address, TLS
+// and timeouts.
+//
+// A part without an address of its own is served on the trading listener,
+// with the trading listener's TLS; giving it TLS settings of its own is an
+// error rather than silently ignored. Every listener gets its own ServeMux:
+// routes are registered on the mux of the listener serving their part (see
+// For), so a route is only answered on that listener.
+//
+// A listener with a certificate and key serves HTTPS only (TLS 1.2 and
+// up); with a client CA as well it requires every client to present a
+// certificate issued by it. Certificates are read by New, so a bad one
+// fails before anything is served.
+package listener
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Parts of the API, each served by one listener.
+const (
+	Trading    = "trading"
+	MarketData = "market data"
+	Admin      = "admin"
+)
+
+// Config configures one HTTP listener.
+type Config struct {
+	Addr         string        // Host and port, e.g. ":9090" (empty = served on the trading listener)
+	CertFile     string        // PEM certificate chain; with KeyFile, serves HTTPS (empty = plain HTTP)
+	KeyFile      string        // PEM private key of CertFile
+	ClientCAFile string        // PEM CAs client certificates must be issued by (empty = none asked for)
+	ReadTimeout  time.Duration // Reading a whole request, body included (0 = none)
+	WriteTimeout time.Duration // Writing a response, from the end of the request headers (0 = none)
+	IdleTimeout  time.Duration // Keep-alive connections idle this long are closed (0 = ReadTimeout)
+}
+
+// Set configures the trading, market data and admin listeners.
+type Set struct {
+	Trading    Config // Addr defaults to the port passed to New
+	MarketData Config
+	Admin      Config
+}
+
+// Defaults serves everything on the trading listener. The market data
+// listener has no write timeout, so subscribers can hold connections open.
+func Defaults() Set {
+	return Set{
+		Trading:    Config{ReadTimeout: 5 * time.Second, WriteTimeout: 10 * time.Second},
+		MarketData: Config{ReadTimeout: 5 * time.Second, IdleTimeout: 2 * time.Minute},
+		Admin:      Config{ReadTimeout: 5 * time.Second, WriteTimeout: 10 * time.Second},
+	}
+}
+
+// TLSConfig loads the listener's certificates. Returns nil for plain HTTP.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		if c.ClientCAFile != "" {
+			return nil, errors.New("a client CA needs a certificate and key")
+		}
+		return nil, nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("a certificate and key are needed together")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", c.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Listener is one listener of the HTTP API.
+type Listener struct {
+	Roles  []string // Parts of the API it serves, its own first
+	Config Config
+	TLS    *tls.Config    // nil = plain HTTP
+	Mux    *http.ServeMux // Routes of the parts it serves
+	Server *http.Server   // Set by ServeWith
+
+	ln net.Listener // Bound by Listen
+}
+
+// New builds the listeners set asks for, the trading one first, on port
+// unless it has an address.
+func New(set Set, port int) ([]*Listener, error) {
+	trading := set.Trading
+	if trading.Addr == "" {
+		trading.Addr = fmt.Sprintf(":%d", port)
+	}
+	listeners := []*Listener{{Roles: []string{Trading}, Config: trading}}
+	for _, part := range []struct {
+		role   string
+		config Config
+	}{
+		{MarketData, set.MarketData},
+		{Admin, set.Admin},
+	} {
+		if part.config.Addr == "" {
+			// Served with the trading listener's TLS, so its own would be ignored
+			if part.config.CertFile != "" || part.config.KeyFile != "" || part.config.ClientCAFile != "" {
+				return nil, fmt.Errorf("%s listener TLS needs an address of its own", part.role)
+			}
+			listeners[0].Roles = append(listeners[0].Roles, part.role)
+			continue
+		}
+		listeners = append(listeners, &Listener{Roles: []string{part.role}, Config: part.config})
+	}
+
+	for _, l := range listeners {
+		config, err := l.Config.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("%s listener TLS: %w", l.Roles[0], err)
+		}
+		l.TLS = config
+		l.Mux = http.NewServeMux()
+	}
+	return listeners, nil
+}
+
+// For returns the one of listeners serving role.
+func For(listeners []*Listener, role string) *Listener {
+	for _, l := range listeners {
+		for _, served := range l.Roles {
+			if served == role {
+				return l
+			}
+		}
+	}
+	return listeners[0]
+}
+
+// ServeWith sets the handler of the listener's HTTP server.
+func (l *Listener) ServeWith(handler http.Handler) {
+	l.Server = &http.Server{
+		Addr:         l.Config.Addr,
+		Handler:      handler,
+		TLSConfig:    l.TLS,
+		ReadTimeout:  l.Config.ReadTimeout,
+		WriteTimeout: l.Config.WriteTimeout,
+		IdleTimeout:  l.Config.IdleTimeout,
+	}
+}
+
+// Listen binds the listener's address.
+func (l *Listener) Listen() error {
+	ln, err := net.Listen("tcp", l.Config.Addr)
+	if err != nil {
+		return fmt.Errorf("%s listener: %w", l.Roles[0], err)
+	}
+	l.ln = ln
+	return nil
+}
+
+// ListenAll binds every listener before any serves, so an address in use
+// fails startup. If one fails, those already bound are closed.
+func ListenAll(listeners []*Listener) error {
+	for i, l := range listeners {
+		if err := l.Listen(); err != nil {
+			for _, bound := range listeners[:i] {
+				bound.ln.Close()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Addr returns the bound address, e.g. with the port ":0" was given.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// String describes the bound listener for logs, e.g.
+// "admin on [::]:9090 (https, client certificates required)".
+func (l *Listener) String() string {
+	scheme := "http"
+	if l.TLS != nil {
+		scheme = "https"
+		if l.TLS.ClientCAs != nil {
+			scheme = "https, client certificates required"
+		}
+	}
+	return fmt.Sprintf("%s on %s (%s)", strings.Join(l.Roles, ", "), l.ln.Addr(), scheme)
+}
+
+// Serve serves HTTP on the bound listener until it is shut down.
+func (l *Listener) Serve() error {
+	if l.TLS != nil {
+		return l.Server.ServeTLS(l.ln, "", "") // Certificates from TLSConfig
+	}
+	return l.Server.Serve(l.ln)
+}
@@ -0,0 +1,56 @@
+package marketdata
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Auction State
+//
+// During an auction call the book may be crossed, so L1 and depth say
+// little about where the symbol will open. The auction message does: the
+// indicative price the call would uncross at now, the volume that would
+// trade there, and the imbalance left over.
+//
+//	AUCTION AAPL  indicative 150.02  volume 1200  imbalance 300 BUY
+//
+// It is published after every change to the book during the call, and once
+// more with InCall false when the call uncrosses, carrying the final price
+// and volume.
+
+// AuctionState is the state of a symbol's auction call.
+type AuctionState struct {
+	Symbol        string
+	InCall        bool  // False once uncrossed: Price and Volume are final
+	RefPrice      int64 // Reference price ties are broken towards
+	Price         int64 // Indicative (or uncross) price; 0 if nothing crosses
+	Volume        int64
+	Imbalance     int64
+	ImbalanceSide orders.Side // Side the imbalance is on, if any
+	Timestamp     int64
+}
+
+// SubscribeAuction subscribes to auction state updates for a symbol.
+func (p *Publisher) SubscribeAuction(symbol string) *Subscription[AuctionState] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return subscribe(p, p.auction, symbol, false)
+}
+
+// PublishAuction sends an auction state update to subscribers.
+func (p *Publisher) PublishAuction(state AuctionState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastAuction[state.Symbol] = state
+	if p.auction.send(state, p.stallTimeout) {
+		p.evictDeadLocked()
+	}
+}
+
+// LatestAuction returns the last auction state published for a symbol.
+func (p *Publisher) LatestAuction(symbol string) (AuctionState, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	state, exists := p.lastAuction[symbol]
+	return state, exists
+}
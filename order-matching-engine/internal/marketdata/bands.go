@@ -0,0 +1,137 @@
+package marketdata
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Depth Bands (Aggregated Depth)
+//
+// Retail-style consumers rarely want every price level. What they ask is
+// "how much could I trade without moving the price much?" Depth bands
+// answer that directly: the cumulative displayed size within fixed
+// distances of the mid price.
+//
+//	mid = 150.00
+//	  band      bids (≥ price)        asks (≤ price)
+//	  10 bps    149.85:  1,200        150.15:    800
+//	  50 bps    149.25:  9,400        150.75:  7,100
+//	  100 bps   148.50: 22,000        151.50: 18,300
+//
+// A band message is a handful of numbers regardless of book depth, so it
+// is cheap to fan out to many subscribers.
+//
+// Incremental computation:
+// Bands are recomputed only for the symbol whose book changed, and only the
+// levels inside the widest band are visited (best price outward, stopping
+// at the first level past it). A message is published only when a value
+// actually changed, so a cancel deep in the book sends nothing.
+
+// DefaultBandBps are the default band widths in basis points of mid:
+// 0.1%, 0.5% and 1%.
+var DefaultBandBps = []int64{10, 50, 100}
+
+// DepthBand is the cumulative displayed quantity within Bps of mid.
+type DepthBand struct {
+	Bps    int64 `json:"bps"`
+	BidQty int64 `json:"bid_qty"`
+	AskQty int64 `json:"ask_qty"`
+}
+
+// DepthBands is an aggregated depth message for one symbol.
+// Bands are empty when either side of the book is empty (no mid).
+type DepthBands struct {
+	Symbol    string      `json:"symbol"`
+	Mid       int64       `json:"mid"`
+	Bands     []DepthBand `json:"bands"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// sameAs reports whether two messages carry the same values.
+func (d DepthBands) sameAs(other DepthBands) bool {
+	if d.Mid != other.Mid || len(d.Bands) != len(other.Bands) {
+		return false
+	}
+	for i := range d.Bands {
+		if d.Bands[i] != other.Bands[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ComputeBands aggregates a book's displayed depth into bands of the given
+// widths (basis points, ascending).
+// Time complexity: O(L) where L = levels within the widest band
+func ComputeBands(book *orderbook.OrderBook, bps []int64) DepthBands {
+	result := DepthBands{Symbol: book.Symbol(), Timestamp: orders.Now()}
+
+	mid := book.GetMidPrice()
+	if mid == 0 || len(bps) == 0 {
+		return result
+	}
+	result.Mid = mid
+	result.Bands = make([]DepthBand, len(bps))
+	for i, width := range bps {
+		result.Bands[i].Bps = width
+	}
+
+	widest := bps[len(bps)-1]
+	for _, side := range []orders.Side{orders.SideBuy, orders.SideSell} {
+		book.ForEachLevel(side, func(level *orderbook.PriceLevel) bool {
+			// Distance from mid in bps, compared without division:
+			// |price - mid| * 10000 <= width * mid
+			distance := (level.Price - mid) * 10000
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance > widest*mid {
+				return false // Sorted best first: everything after is further out
+			}
+			for i, width := range bps {
+				if distance <= width*mid {
+					if side == orders.SideBuy {
+						result.Bands[i].BidQty += level.TotalQty
+					} else {
+						result.Bands[i].AskQty += level.TotalQty
+					}
+				}
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// SubscribeBands subscribes to depth band updates for a symbol.
+func (p *Publisher) SubscribeBands(symbol string) *Subscription[DepthBands] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return subscribe(p, p.bands, symbol, false)
+}
+
+// PublishBands sends a depth band update to subscribers, unless it carries
+// the same values as the last one published for the symbol.
+// Returns true if the update was published.
+func (p *Publisher) PublishBands(bands DepthBands) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if last, exists := p.lastBands[bands.Symbol]; exists && last.sameAs(bands) {
+		return false
+	}
+	p.lastBands[bands.Symbol] = bands
+
+	if p.bands.send(bands, p.stallTimeout) {
+		p.evictDeadLocked()
+	}
+	return true
+}
+
+// LatestBands returns the last depth bands published for a symbol.
+func (p *Publisher) LatestBands(symbol string) (DepthBands, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	bands, exists := p.lastBands[symbol]
+	return bands, exists
+}
@@ -0,0 +1,218 @@
+package marketdata
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Sequenced Book Updates and Backfill
+//
+// L1 quotes and depth bands are snapshots: a subscriber that misses one
+// just waits for the next. A subscriber building its own copy of the book
+// can't do that - it applies level changes, and one lost change leaves its
+// book wrong until the level happens to change again.
+//
+// So the book feed is sequenced. Every change to a price level in a
+// symbol's top DefaultBookDepth levels is one BookUpdate, numbered from 1
+// per symbol with no gaps:
+//
+//	seq 41  BUY  150.00  qty 300  (3 orders)
+//	seq 42  SELL 150.05  qty 0                ← level removed
+//	seq 43  SELL 150.06  qty 200  (1 order)   ← entered the top levels
+//
+// The publisher keeps the last book history size updates per symbol. A
+// subscriber that connects, or sees a gap (seq jumps), asks for everything
+// from the first seq it is missing:
+//
+//   - still in the history: just the missing updates
+//   - too old, from 0 (a new subscriber) or past the current seq (the
+//     server restarted and numbering began again): a full image of the book
+//     at the current seq instead, which replaces its book
+//
+// Either way, applying the backfill and then live updates from Seq+1 gives
+// the correct book. SubscribeBook does both under one lock, so nothing is
+// missed or repeated between the backfill and the first live update.
+//
+// Updates are diffs between successive images passed to PublishBook, so
+// the feed is consistent with itself whatever order images arrive in.
+// Each carries a checksum of the top levels it leaves (see checksum.go),
+// so a subscriber can also tell that its copy went wrong.
+
+// DefaultBookDepth is the number of levels per side the book feed covers.
+const DefaultBookDepth = 10
+
+// defaultBookHistory is the number of updates kept per symbol for backfill.
+const defaultBookHistory = 10000
+
+// BookUpdate is the new state of one price level.
+type BookUpdate struct {
+	Symbol    string
+	Seq       uint64 // Per symbol, from 1, no gaps
+	Side      orders.Side
+	Price     int64
+	Quantity  int64  // Displayed quantity; 0 removes the level
+	Count     int    // Number of orders at the level
+	Checksum  uint32 // Of the top levels with this update applied (see checksum.go)
+	Timestamp int64
+}
+
+// Backfill brings a book feed subscriber up to date.
+type Backfill struct {
+	Symbol  string
+	Seq     uint64       // Latest seq covered; live updates continue at Seq+1
+	Image   *L2Depth     // Full book at Seq, when the history no longer covers from
+	Updates []BookUpdate // Updates from the requested seq to Seq otherwise
+}
+
+// bookHistory is the book feed state of one symbol.
+type bookHistory struct {
+	seq     uint64
+	bids    []PriceLevel // Last image, best first
+	asks    []PriceLevel
+	updates []BookUpdate // Last bookHistory updates, oldest first
+}
+
+// SetBookHistory sets how many updates per symbol are kept for backfill.
+func (p *Publisher) SetBookHistory(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bookHistory = n
+}
+
+// PublishBook diffs a symbol's depth against the last image published,
+// records and sends an update for each level that changed, and returns
+// them. depth should carry the top DefaultBookDepth levels per side.
+func (p *Publisher) PublishBook(depth L2Depth) []BookUpdate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.books[depth.Symbol]
+	if h == nil {
+		h = &bookHistory{}
+		p.books[depth.Symbol] = h
+	}
+
+	// The image as each update leaves it, for its checksum
+	bids := append([]PriceLevel(nil), h.bids...)
+	asks := append([]PriceLevel(nil), h.asks...)
+
+	var changed []BookUpdate
+	for _, side := range []struct {
+		side      orders.Side
+		prev, now []PriceLevel
+	}{
+		{orders.SideBuy, h.bids, depth.Bids},
+		{orders.SideSell, h.asks, depth.Asks},
+	} {
+		for _, level := range diffLevels(side.prev, side.now) {
+			h.seq++
+			if side.side == orders.SideBuy {
+				bids = applyLevel(bids, side.side, level)
+			} else {
+				asks = applyLevel(asks, side.side, level)
+			}
+			changed = append(changed, BookUpdate{
+				Symbol:    depth.Symbol,
+				Seq:       h.seq,
+				Side:      side.side,
+				Price:     level.Price,
+				Quantity:  level.Quantity,
+				Count:     level.Count,
+				Checksum:  Checksum(bids, asks),
+				Timestamp: depth.Timestamp,
+			})
+		}
+	}
+	h.bids = append([]PriceLevel(nil), depth.Bids...)
+	h.asks = append([]PriceLevel(nil), depth.Asks...)
+
+	h.updates = append(h.updates, changed...)
+	if over := len(h.updates) - p.bookHistory; over > 0 {
+		h.updates = append([]BookUpdate(nil), h.updates[over:]...)
+	}
+
+	dead := false
+	for _, update := range changed {
+		// A full queue drops the update: the subscriber will see a gap and backfill
+		dead = p.book.send(update, p.stallTimeout) || dead
+	}
+	if dead {
+		p.evictDeadLocked()
+	}
+	if len(changed) > 0 {
+		for _, feed := range p.feeds {
+			feed.PublishBook(changed)
+		}
+	}
+	return changed
+}
+
+// SubscribeBook subscribes to a symbol's book updates, returning the
+// backfill from seq from (0 for a full image) and the subscription live
+// updates after it arrive on.
+func (p *Publisher) SubscribeBook(symbol string, from uint64) (Backfill, *Subscription[BookUpdate]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.backfill(symbol, from), subscribe(p, p.book, symbol, false)
+}
+
+// BookBackfill returns the book updates of a symbol from seq from, or a full
+// image if they are no longer kept (or from is 0).
+func (p *Publisher) BookBackfill(symbol string, from uint64) Backfill {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.backfill(symbol, from)
+}
+
+// backfill builds a Backfill. Callers hold p.mu.
+func (p *Publisher) backfill(symbol string, from uint64) Backfill {
+	result := Backfill{Symbol: symbol}
+	h := p.books[symbol]
+	if h == nil {
+		result.Image = &L2Depth{Symbol: symbol, Timestamp: orders.Now()}
+		return result
+	}
+	result.Seq = h.seq
+
+	// Nothing missed
+	if from == h.seq+1 {
+		return result
+	}
+	if from > 0 && from <= h.seq && len(h.updates) > 0 && from >= h.updates[0].Seq {
+		first := from - h.updates[0].Seq
+		result.Updates = append([]BookUpdate(nil), h.updates[first:]...)
+		return result
+	}
+
+	result.Image = &L2Depth{
+		Symbol:    symbol,
+		Seq:       h.seq,
+		Bids:      append([]PriceLevel(nil), h.bids...),
+		Asks:      append([]PriceLevel(nil), h.asks...),
+		Checksum:  Checksum(h.bids, h.asks),
+		Timestamp: orders.Now(),
+	}
+	return result
+}
+
+// diffLevels returns the levels that differ between two images of one side:
+// changed or new levels at their new state, then removed ones with
+// quantity 0.
+func diffLevels(prev, now []PriceLevel) []PriceLevel {
+	var changed []PriceLevel
+	index := make(map[int64]PriceLevel, len(prev))
+	for _, level := range prev {
+		index[level.Price] = level
+	}
+	for _, level := range now {
+		if old, ok := index[level.Price]; !ok || old != level {
+			changed = append(changed, level)
+		}
+		delete(index, level.Price)
+	}
+	for _, level := range prev {
+		if _, removed := index[level.Price]; removed {
+			changed = append(changed, PriceLevel{Price: level.Price})
+		}
+	}
+	return changed
+}
@@ -0,0 +1,74 @@
+package marketdata
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Book Checksums
+//
+// Sequence numbers tell a book feed subscriber that it missed an update;
+// they can't tell it that it applied one wrongly - a level kept after its
+// removal, two updates at one price merged, a bug in its own book code.
+// So every update carries a checksum of the publisher's top levels after
+// it, as Kraken's book feed does, and the subscriber compares it with the
+// checksum of its own copy. A mismatch means its book is wrong: it throws
+// it away and backfills from 0 for a fresh image.
+//
+// The checksum is the CRC-32 (IEEE) of a string built from the best
+// ChecksumDepth asks, lowest first, then the best ChecksumDepth bids,
+// highest first, each level contributing its price in cents and then its
+// displayed quantity, in decimal without separators:
+//
+//	asks 150.05 x 200, 150.10 x 50   bids 150.00 x 300
+//	crc32("15005" + "200" + "15010" + "50" + "15000" + "300")
+//
+// Hidden iceberg reserve and order counts are not covered. Each update's
+// checksum covers the book with that update and the ones before it
+// applied, so updates published together can be checked one at a time.
+
+// ChecksumDepth is the number of levels per side a checksum covers.
+const ChecksumDepth = 10
+
+// Checksum returns the checksum of a book's levels, best first per side.
+func Checksum(bids, asks []PriceLevel) uint32 {
+	buf := make([]byte, 0, 2*ChecksumDepth*16)
+	for _, side := range [][]PriceLevel{asks, bids} {
+		for i, level := range side {
+			if i == ChecksumDepth {
+				break
+			}
+			buf = strconv.AppendInt(buf, level.Price, 10)
+			buf = strconv.AppendInt(buf, level.Quantity, 10)
+		}
+	}
+	return crc32.ChecksumIEEE(buf)
+}
+
+// applyLevel returns levels, best first for side, with level set to its new
+// state: inserted in price order, replaced, or removed if its quantity is 0.
+func applyLevel(levels []PriceLevel, side orders.Side, level PriceLevel) []PriceLevel {
+	i := sort.Search(len(levels), func(i int) bool {
+		if side == orders.SideBuy {
+			return levels[i].Price <= level.Price
+		}
+		return levels[i].Price >= level.Price
+	})
+	found := i < len(levels) && levels[i].Price == level.Price
+	switch {
+	case level.Quantity == 0 && found:
+		return append(levels[:i], levels[i+1:]...)
+	case level.Quantity == 0:
+		return levels
+	case found:
+		levels[i] = level
+		return levels
+	}
+	levels = append(levels, PriceLevel{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = level
+	return levels
+}
@@ -0,0 +1,142 @@
+package marketdata
+
+import (
+	"sync"
+	"time"
+)
+
+// Conflation
+//
+// Under load (see pkg/degrade) the publisher conflates quotes and depth:
+// instead of every L1 quote and L2 depth update, subscribers get the latest
+// per symbol once per interval. A busy symbol then costs a bounded number
+// of messages however fast its book moves:
+//
+//	PublishL1 ──▶ latest[symbol] ──every interval──▶ subscribers
+//
+// Trades and the sequenced book feed are never conflated. Every trade
+// matters, and the book feed's seq lets subscribers detect gaps and
+// backfill them, which dropping updates would defeat.
+
+// DefaultConflateInterval is how often conflated updates are sent.
+const DefaultConflateInterval = 100 * time.Millisecond
+
+// conflator holds the latest quote and depth per symbol while conflating.
+type conflator struct {
+	mu        sync.Mutex
+	on        bool
+	closed    bool
+	interval  time.Duration
+	l1        map[string]L1Quote
+	l2        map[string]L2Depth
+	timer     *time.Timer
+	conflated uint64 // Updates replaced by a later one before being sent
+}
+
+// SetConflation turns conflation on or off. Turning it off sends whatever
+// is held straight away.
+func (p *Publisher) SetConflation(on bool) {
+	c := &p.conflator
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.on == on {
+		return
+	}
+	c.on = on
+	if !on {
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+		p.flushLocked()
+	}
+}
+
+// Conflating reports whether updates are being conflated.
+func (p *Publisher) Conflating() bool {
+	p.conflator.mu.Lock()
+	defer p.conflator.mu.Unlock()
+	return p.conflator.on
+}
+
+// ConflatedUpdates returns the quote and depth updates superseded before
+// they were sent.
+func (p *Publisher) ConflatedUpdates() uint64 {
+	p.conflator.mu.Lock()
+	defer p.conflator.mu.Unlock()
+	return p.conflator.conflated
+}
+
+// holdL1 keeps a quote for the next flush if conflating. Reports whether it
+// did; if not, the caller sends it now.
+func (p *Publisher) holdL1(quote L1Quote) bool {
+	c := &p.conflator
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.on {
+		return false
+	}
+	if _, ok := c.l1[quote.Symbol]; ok {
+		c.conflated++
+	}
+	c.l1[quote.Symbol] = quote
+	p.scheduleLocked()
+	return true
+}
+
+// holdL2 is holdL1 for depth.
+func (p *Publisher) holdL2(depth L2Depth) bool {
+	c := &p.conflator
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.on {
+		return false
+	}
+	if _, ok := c.l2[depth.Symbol]; ok {
+		c.conflated++
+	}
+	c.l2[depth.Symbol] = depth
+	p.scheduleLocked()
+	return true
+}
+
+// scheduleLocked arms the flush timer if it isn't. Must hold conflator.mu.
+func (p *Publisher) scheduleLocked() {
+	c := &p.conflator
+	if c.timer != nil {
+		return
+	}
+	c.timer = time.AfterFunc(c.interval, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.timer = nil
+		if !c.closed {
+			p.flushLocked()
+		}
+	})
+}
+
+// flushLocked sends everything held. Must hold conflator.mu, which keeps
+// Close from closing the channels underneath it.
+func (p *Publisher) flushLocked() {
+	c := &p.conflator
+	for symbol, quote := range c.l1 {
+		p.sendL1(quote)
+		delete(c.l1, symbol)
+	}
+	for symbol, depth := range c.l2 {
+		p.sendL2(depth)
+		delete(c.l2, symbol)
+	}
+}
+
+// closeConflator stops flushing, dropping anything held.
+func (p *Publisher) closeConflator() {
+	c := &p.conflator
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
@@ -0,0 +1,319 @@
+package marketdata
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// NBBO Consolidation (Multi-Venue)
+//
+// With several engine instances trading the same symbols, no single book
+// shows the best price available. The consolidator subscribes to each
+// venue's L1 stream and maintains the national best bid and offer: the
+// highest bid and the lowest ask across venues, with the venues quoting
+// them.
+//
+//	venue   bid             ask
+//	NYC     150.10 x 300    150.14 x 200
+//	CHI     150.12 x 100    150.14 x 500
+//	LDN     150.12 x 400    150.16 x 100
+//	NBBO    150.12 x 500    150.14 x 700
+//	        LDN 400, CHI 100    CHI 500, NYC 200
+//
+// Size at the best price is summed across venues. The venue list is the
+// attribution, largest first: the smart order router takes it as the order
+// to route in, and best-execution stats measure fills against the NBBO at
+// the time (see Improvement).
+//
+// Stale venues:
+// A venue whose feed closes is dropped from every symbol, so a dead engine
+// never holds the NBBO with its last quote.
+//
+// Like depth bands, an NBBO is published only when it changes. Across
+// venues the market can be locked (bid == ask) or crossed (bid > ask); the
+// NBBO is published as is and flagged, since that is what is available.
+
+// VenueSize is one venue's displayed size at an NBBO price.
+type VenueSize struct {
+	Venue string `json:"venue"`
+	Size  int64  `json:"size"`
+}
+
+// NBBO is the consolidated best bid and offer for one symbol.
+// A side's price is 0 when no venue quotes it.
+type NBBO struct {
+	Symbol    string      `json:"symbol"`
+	BidPrice  int64       `json:"bid_price"`
+	BidSize   int64       `json:"bid_size"`
+	BidVenues []VenueSize `json:"bid_venues"` // Venues at BidPrice, largest first
+	AskPrice  int64       `json:"ask_price"`
+	AskSize   int64       `json:"ask_size"`
+	AskVenues []VenueSize `json:"ask_venues"` // Venues at AskPrice, largest first
+	Timestamp int64       `json:"timestamp"`
+}
+
+// Locked reports whether the best bid equals the best ask.
+func (n NBBO) Locked() bool {
+	return n.BidPrice > 0 && n.AskPrice > 0 && n.BidPrice == n.AskPrice
+}
+
+// Crossed reports whether the best bid is above the best ask.
+func (n NBBO) Crossed() bool {
+	return n.BidPrice > 0 && n.AskPrice > 0 && n.BidPrice > n.AskPrice
+}
+
+// BestVenue returns the venue to route an order on side to: the largest
+// venue at the best ask for a buy, at the best bid for a sell.
+// Returns "" when nothing is quoted on the opposite side.
+func (n NBBO) BestVenue(side orders.Side) string {
+	venues := n.AskVenues
+	if side == orders.SideSell {
+		venues = n.BidVenues
+	}
+	if len(venues) == 0 {
+		return ""
+	}
+	return venues[0].Venue
+}
+
+// Improvement returns how much better than the NBBO an execution at price
+// was for side, in cents per share: positive is price improvement,
+// negative is a trade through. ok is false when the opposite side was not
+// quoted, so there is nothing to measure against.
+func (n NBBO) Improvement(side orders.Side, price int64) (cents int64, ok bool) {
+	if side == orders.SideBuy {
+		if n.AskPrice == 0 {
+			return 0, false
+		}
+		return n.AskPrice - price, true
+	}
+	if n.BidPrice == 0 {
+		return 0, false
+	}
+	return price - n.BidPrice, true
+}
+
+// sameAs reports whether two NBBOs carry the same prices, sizes and venues.
+func (n NBBO) sameAs(other NBBO) bool {
+	return n.BidPrice == other.BidPrice && n.BidSize == other.BidSize &&
+		n.AskPrice == other.AskPrice && n.AskSize == other.AskSize &&
+		sameVenues(n.BidVenues, other.BidVenues) && sameVenues(n.AskVenues, other.AskVenues)
+}
+
+func sameVenues(a, b []VenueSize) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Consolidator maintains the NBBO across venues and publishes changes.
+//
+// Thread-safe: each venue feed is consumed on its own goroutine.
+type Consolidator struct {
+	mu         sync.RWMutex
+	quotes     map[string]map[string]L1Quote // symbol -> venue -> latest quote
+	nbbo       map[string]NBBO               // Last NBBO published per symbol
+	subs       map[string][]chan NBBO
+	allSubs    []chan NBBO
+	bufferSize int
+	closed     bool
+	feeds      sync.WaitGroup
+}
+
+// NewConsolidator creates a consolidator with no venues.
+func NewConsolidator(bufferSize int) *Consolidator {
+	if bufferSize <= 0 {
+		bufferSize = 100
+	}
+	return &Consolidator{
+		quotes:     make(map[string]map[string]L1Quote),
+		nbbo:       make(map[string]NBBO),
+		subs:       make(map[string][]chan NBBO),
+		bufferSize: bufferSize,
+	}
+}
+
+// AddVenue consumes a venue's L1 stream, typically an engine instance's
+// Publisher.SubscribeAllL1. When the stream closes the venue is removed.
+func (c *Consolidator) AddVenue(venue string, feed <-chan L1Quote) {
+	c.feeds.Add(1)
+	go func() {
+		defer c.feeds.Done()
+		for quote := range feed {
+			c.Update(venue, quote)
+		}
+		c.RemoveVenue(venue)
+	}()
+}
+
+// Update applies a venue's latest quote for a symbol.
+// Returns true if the NBBO changed and was published.
+func (c *Consolidator) Update(venue string, quote L1Quote) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	venues, exists := c.quotes[quote.Symbol]
+	if !exists {
+		venues = make(map[string]L1Quote)
+		c.quotes[quote.Symbol] = venues
+	}
+	venues[venue] = quote
+	return c.recompute(quote.Symbol, quote.Timestamp)
+}
+
+// RemoveVenue drops every quote from a venue, e.g. when its feed is lost.
+func (c *Consolidator) RemoveVenue(venue string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := orders.Now()
+	for symbol, venues := range c.quotes {
+		if _, exists := venues[venue]; !exists {
+			continue
+		}
+		delete(venues, venue)
+		c.recompute(symbol, now)
+	}
+}
+
+// Latest returns the current NBBO for a symbol.
+// Returns false if no venue has quoted it.
+func (c *Consolidator) Latest(symbol string) (NBBO, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nbbo, exists := c.nbbo[symbol]
+	return nbbo, exists
+}
+
+// VenueQuotes returns each venue's latest quote for a symbol, for routing
+// decisions that look past the best price.
+func (c *Consolidator) VenueQuotes(symbol string) map[string]L1Quote {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string]L1Quote, len(c.quotes[symbol]))
+	for venue, quote := range c.quotes[symbol] {
+		result[venue] = quote
+	}
+	return result
+}
+
+// Subscribe subscribes to NBBO updates for a symbol.
+func (c *Consolidator) Subscribe(symbol string) <-chan NBBO {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan NBBO, c.bufferSize)
+	c.subs[symbol] = append(c.subs[symbol], ch)
+	return ch
+}
+
+// SubscribeAll subscribes to NBBO updates for all symbols.
+func (c *Consolidator) SubscribeAll() <-chan NBBO {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan NBBO, c.bufferSize)
+	c.allSubs = append(c.allSubs, ch)
+	return ch
+}
+
+// Wait blocks until every venue feed has closed.
+func (c *Consolidator) Wait() {
+	c.feeds.Wait()
+}
+
+// Close closes all subscription channels. Later updates are still applied
+// but no longer published.
+func (c *Consolidator) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, subs := range c.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	for _, ch := range c.allSubs {
+		close(ch)
+	}
+}
+
+// recompute rebuilds a symbol's NBBO from its venue quotes and publishes
+// it if it changed. Caller must hold the lock.
+// Time complexity: O(V log V) where V = venues quoting the symbol
+func (c *Consolidator) recompute(symbol string, timestamp int64) bool {
+	nbbo := NBBO{Symbol: symbol, Timestamp: timestamp}
+	for venue, quote := range c.quotes[symbol] {
+		if quote.BidPrice > 0 && quote.BidSize > 0 {
+			if quote.BidPrice > nbbo.BidPrice {
+				nbbo.BidPrice, nbbo.BidSize, nbbo.BidVenues = quote.BidPrice, 0, nbbo.BidVenues[:0]
+			}
+			if quote.BidPrice == nbbo.BidPrice {
+				nbbo.BidSize += quote.BidSize
+				nbbo.BidVenues = append(nbbo.BidVenues, VenueSize{Venue: venue, Size: quote.BidSize})
+			}
+		}
+		if quote.AskPrice > 0 && quote.AskSize > 0 {
+			if nbbo.AskPrice == 0 || quote.AskPrice < nbbo.AskPrice {
+				nbbo.AskPrice, nbbo.AskSize, nbbo.AskVenues = quote.AskPrice, 0, nbbo.AskVenues[:0]
+			}
+			if quote.AskPrice == nbbo.AskPrice {
+				nbbo.AskSize += quote.AskSize
+				nbbo.AskVenues = append(nbbo.AskVenues, VenueSize{Venue: venue, Size: quote.AskSize})
+			}
+		}
+	}
+	sortVenues(nbbo.BidVenues)
+	sortVenues(nbbo.AskVenues)
+
+	if last, exists := c.nbbo[symbol]; exists && last.sameAs(nbbo) {
+		return false
+	}
+	c.nbbo[symbol] = nbbo
+	if !c.closed {
+		c.publish(nbbo)
+	}
+	return true
+}
+
+// publish sends an NBBO to subscribers, dropping it for slow ones.
+// Caller must hold the lock.
+func (c *Consolidator) publish(nbbo NBBO) {
+	for _, ch := range c.subs[nbbo.Symbol] {
+		select {
+		case ch <- nbbo:
+		default:
+		}
+	}
+	for _, ch := range c.allSubs {
+		select {
+		case ch <- nbbo:
+		default:
+		}
+	}
+}
+
+// sortVenues orders venues largest first, by name on ties so the
+// attribution is deterministic.
+func sortVenues(venues []VenueSize) {
+	sort.Slice(venues, func(i, j int) bool {
+		if venues[i].Size != venues[j].Size {
+			return venues[i].Size > venues[j].Size
+		}
+		return venues[i].Venue < venues[j].Venue
+	})
+}
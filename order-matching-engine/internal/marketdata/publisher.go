@@ -13,6 +13,18 @@
 //   - Total size at each level
 //   - Used by: Active traders, algorithms
 //
+// Depth Bands - Aggregated Depth (see bands.go):
+//   - Cumulative size within fixed distances of mid (e.g., 0.1%, 0.5%, 1%)
+//   - Used by: Retail displays that don't want full L2
+//
+// Tape - Recent trades (see tape.go):
+//   - Price, size, aggressor and anonymized counterparty codes
+//   - Used by: Time and sales displays
+//
+// Auction State - During an auction call (see auction.go):
+//   - Indicative price, matched volume and imbalance
+//   - Used by: Anyone deciding whether to join the open or close
+//
 // L3 (Level 3) - Full Order Book:
 //   - Every individual order
 //   - Rarely available to public
@@ -26,6 +38,7 @@ package marketdata
 
 import (
 	"sync"
+	"time"
 
 	"github.com/rishav/order-matching-engine/internal/orders"
 )
@@ -40,14 +53,18 @@ type L1Quote struct {
 	LastPrice int64
 	LastSize  int64
 	Timestamp int64
+	MsgSeq    uint64 // Per-symbol message seq across quotes, depth and trades (see retransmit.go)
 }
 
 // L2Depth represents Level 2 (depth) market data.
 type L2Depth struct {
 	Symbol    string
+	Seq       uint64 // Book feed seq this depth is current to (book images only)
 	Bids      []PriceLevel
 	Asks      []PriceLevel
+	Checksum  uint32 // Of Bids and Asks, set by the publisher (see checksum.go)
 	Timestamp int64
+	MsgSeq    uint64 // Per-symbol message seq across quotes, depth and trades (see retransmit.go)
 }
 
 // PriceLevel represents a single price level in depth data.
@@ -65,17 +82,55 @@ type TradeReport struct {
 	Quantity      int64
 	AggressorSide orders.Side // Which side initiated the trade
 	Timestamp     int64
+	BuyerCode     string // Anonymized counterparties, never account IDs (see enrichment)
+	SellerCode    string
+	MsgSeq        uint64 // Per-symbol message seq across quotes, depth and trades (see retransmit.go)
 }
 
 // Publisher distributes market data to subscribers.
 type Publisher struct {
 	mu          sync.RWMutex
-	l1Subs      map[string][]chan L1Quote
-	l2Subs      map[string][]chan L2Depth
-	tradeSubs   map[string][]chan TradeReport
-	allL1Subs   []chan L1Quote    // Subscribers to all symbols
-	allTradeSubs []chan TradeReport // Subscribers to all trades
+	l1          *subscriptions[L1Quote] // Subscribers per feed (see subscribers.go)
+	l2          *subscriptions[L2Depth]
+	trades      *subscriptions[TradeReport]
+	bands       *subscriptions[DepthBands]
+	book        *subscriptions[BookUpdate]
+	auction     *subscriptions[AuctionState]
+	messages    *subscriptions[Message] // Quotes, depth and trades in message seq order
+	nextSubID   uint64
+	stallTimeout time.Duration // Unread updates evict a subscriber after this long (0 = never)
+	evicted     uint64         // Subscribers evicted for stalling
+	closed      bool
+	lastBands   map[string]DepthBands // Last depth bands published per symbol
+	books       map[string]*bookHistory // Book feed state per symbol
+	bookHistory int                     // Book updates kept per symbol
+	lastAuction map[string]AuctionState // Last auction state published per symbol
+	feeds       []Feed                  // Binary feeds, e.g. ITCH multicast
+
+	streamsMu         sync.Mutex                // Guards streams; each stream has its own lock
+	streams           map[string]*messageStream // Message seq and history per symbol (see retransmit.go)
+	retransmitHistory int                       // Messages kept per symbol for replay
+
+	tapeMu sync.Mutex               // Guards tape apart from mu, which PublishTrade only reads
+	tape   map[string][]TradeReport // Recent trades per symbol, oldest first
 	bufferSize  int
+
+	conflator conflator // Latest quotes and depth while conflating (see conflate.go)
+}
+
+// Feed receives every book update and trade the publisher sends, such as
+// a binary multicast feed. It is called with the publisher's lock held, so
+// book updates arrive in sequence per symbol; it must not block.
+type Feed interface {
+	PublishBook(updates []BookUpdate)
+	PublishTrade(trade TradeReport)
+}
+
+// AddFeed adds a feed. Must be called before anything is published.
+func (p *Publisher) AddFeed(feed Feed) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.feeds = append(p.feeds, feed)
 }
 
 // NewPublisher creates a new market data publisher.
@@ -83,164 +138,142 @@ func NewPublisher(bufferSize int) *Publisher {
 	if bufferSize <= 0 {
 		bufferSize = 100
 	}
-	return &Publisher{
-		l1Subs:     make(map[string][]chan L1Quote),
-		l2Subs:     make(map[string][]chan L2Depth),
-		tradeSubs:  make(map[string][]chan TradeReport),
+	p := &Publisher{
+		l1:         newSubscriptions("l1", func(q L1Quote) string { return q.Symbol }),
+		l2:         newSubscriptions("l2", func(d L2Depth) string { return d.Symbol }),
+		trades:     newSubscriptions("trades", func(t TradeReport) string { return t.Symbol }),
+		bands:      newSubscriptions("bands", func(b DepthBands) string { return b.Symbol }),
+		book:       newSubscriptions("book", func(u BookUpdate) string { return u.Symbol }),
+		auction:    newSubscriptions("auction", func(a AuctionState) string { return a.Symbol }),
+		messages:   newSubscriptions("messages", func(m Message) string { return m.Symbol }),
+		stallTimeout: DefaultStallTimeout,
+		lastBands:  make(map[string]DepthBands),
+		books:      make(map[string]*bookHistory),
+		bookHistory: defaultBookHistory,
+		lastAuction: make(map[string]AuctionState),
+		tape:        make(map[string][]TradeReport),
+		streams:     make(map[string]*messageStream),
+		retransmitHistory: DefaultRetransmitHistory,
 		bufferSize: bufferSize,
 	}
+	p.conflator = conflator{
+		interval: DefaultConflateInterval,
+		l1:       make(map[string]L1Quote),
+		l2:       make(map[string]L2Depth),
+	}
+	return p
 }
 
 // SubscribeL1 subscribes to L1 quotes for a symbol.
-// Returns a channel that will receive updates.
-func (p *Publisher) SubscribeL1(symbol string) <-chan L1Quote {
+func (p *Publisher) SubscribeL1(symbol string) *Subscription[L1Quote] {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
-	ch := make(chan L1Quote, p.bufferSize)
-	p.l1Subs[symbol] = append(p.l1Subs[symbol], ch)
-	return ch
+	return subscribe(p, p.l1, symbol, false)
 }
 
 // SubscribeAllL1 subscribes to L1 quotes for all symbols.
-func (p *Publisher) SubscribeAllL1() <-chan L1Quote {
+func (p *Publisher) SubscribeAllL1() *Subscription[L1Quote] {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
-	ch := make(chan L1Quote, p.bufferSize)
-	p.allL1Subs = append(p.allL1Subs, ch)
-	return ch
+	return subscribe(p, p.l1, "", false)
 }
 
 // SubscribeL2 subscribes to L2 depth for a symbol.
-func (p *Publisher) SubscribeL2(symbol string) <-chan L2Depth {
+func (p *Publisher) SubscribeL2(symbol string) *Subscription[L2Depth] {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
-	ch := make(chan L2Depth, p.bufferSize)
-	p.l2Subs[symbol] = append(p.l2Subs[symbol], ch)
-	return ch
+	return subscribe(p, p.l2, symbol, false)
 }
 
 // SubscribeTrades subscribes to trade reports for a symbol.
-func (p *Publisher) SubscribeTrades(symbol string) <-chan TradeReport {
+func (p *Publisher) SubscribeTrades(symbol string) *Subscription[TradeReport] {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
-	ch := make(chan TradeReport, p.bufferSize)
-	p.tradeSubs[symbol] = append(p.tradeSubs[symbol], ch)
-	return ch
+	return subscribe(p, p.trades, symbol, false)
 }
 
 // SubscribeAllTrades subscribes to trade reports for all symbols.
-func (p *Publisher) SubscribeAllTrades() <-chan TradeReport {
+func (p *Publisher) SubscribeAllTrades() *Subscription[TradeReport] {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
-	ch := make(chan TradeReport, p.bufferSize)
-	p.allTradeSubs = append(p.allTradeSubs, ch)
-	return ch
+	return subscribe(p, p.trades, "", false)
 }
 
 // PublishL1 sends an L1 quote update to subscribers.
-// Non-blocking: drops updates if subscriber channel is full.
+// Non-blocking: drops updates if subscriber channel is full, or holds the
+// latest for conflating subscribers.
 func (p *Publisher) PublishL1(quote L1Quote) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	// Send to symbol-specific subscribers
-	for _, ch := range p.l1Subs[quote.Symbol] {
-		select {
-		case ch <- quote:
-		default:
-			// Channel full, drop update (subscriber is slow)
-		}
+	if p.holdL1(quote) {
+		return // Sent with the next conflated flush
 	}
+	p.sendL1(quote)
+}
 
-	// Send to all-symbols subscribers
-	for _, ch := range p.allL1Subs {
-		select {
-		case ch <- quote:
-		default:
-		}
+func (p *Publisher) sendL1(quote L1Quote) {
+	p.mu.RLock()
+	dead := p.sequence(Message{Type: MessageQuote, Symbol: quote.Symbol, Quote: &quote}, func(msg Message) bool {
+		quote.MsgSeq = msg.Seq
+		return p.l1.send(quote, p.stallTimeout)
+	})
+	p.mu.RUnlock()
+	if dead {
+		p.evictDead()
 	}
 }
 
 // PublishL2 sends an L2 depth update to subscribers.
 func (p *Publisher) PublishL2(depth L2Depth) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	for _, ch := range p.l2Subs[depth.Symbol] {
-		select {
-		case ch <- depth:
-		default:
-		}
+	depth.Checksum = Checksum(depth.Bids, depth.Asks)
+	if p.holdL2(depth) {
+		return
 	}
+	p.sendL2(depth)
 }
 
-// PublishTrade sends a trade report to subscribers.
-func (p *Publisher) PublishTrade(trade TradeReport) {
+func (p *Publisher) sendL2(depth L2Depth) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	// Send to symbol-specific subscribers
-	for _, ch := range p.tradeSubs[trade.Symbol] {
-		select {
-		case ch <- trade:
-		default:
-		}
-	}
-
-	// Send to all-trades subscribers
-	for _, ch := range p.allTradeSubs {
-		select {
-		case ch <- trade:
-		default:
-		}
+	dead := p.sequence(Message{Type: MessageDepth, Symbol: depth.Symbol, Depth: &depth}, func(msg Message) bool {
+		depth.MsgSeq = msg.Seq
+		return p.l2.send(depth, p.stallTimeout)
+	})
+	p.mu.RUnlock()
+	if dead {
+		p.evictDead()
 	}
 }
 
-// Unsubscribe removes a subscription channel.
-// Note: In production, we'd track subscription IDs for clean removal.
-func (p *Publisher) UnsubscribeL1(symbol string, ch <-chan L1Quote) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	subs := p.l1Subs[symbol]
-	for i, sub := range subs {
-		if sub == ch {
-			p.l1Subs[symbol] = append(subs[:i], subs[i+1:]...)
-			close(sub)
-			return
+// PublishTrade sends a trade report to subscribers.
+func (p *Publisher) PublishTrade(trade TradeReport) {
+	p.mu.RLock()
+	dead := p.sequence(Message{Type: MessageTrade, Symbol: trade.Symbol, Trade: &trade}, func(msg Message) bool {
+		trade.MsgSeq = msg.Seq
+		p.recordTape(trade)
+		dead := p.trades.send(trade, p.stallTimeout)
+		for _, feed := range p.feeds {
+			feed.PublishTrade(trade)
 		}
+		return dead
+	})
+	p.mu.RUnlock()
+	if dead {
+		p.evictDead()
 	}
 }
 
-// Close closes all subscription channels.
+// Close closes all subscription channels. Subscribing afterwards returns
+// a closed subscription.
 func (p *Publisher) Close() {
+	p.closeConflator()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for _, subs := range p.l1Subs {
-		for _, ch := range subs {
-			close(ch)
-		}
-	}
-	for _, subs := range p.l2Subs {
-		for _, ch := range subs {
-			close(ch)
-		}
-	}
-	for _, subs := range p.tradeSubs {
-		for _, ch := range subs {
-			close(ch)
-		}
-	}
-	for _, ch := range p.allL1Subs {
-		close(ch)
-	}
-	for _, ch := range p.allTradeSubs {
-		close(ch)
-	}
+	p.closed = true
+	p.l1.closeAll()
+	p.l2.closeAll()
+	p.trades.closeAll()
+	p.bands.closeAll()
+	p.book.closeAll()
+	p.auction.closeAll()
+	p.messages.closeAll()
 }
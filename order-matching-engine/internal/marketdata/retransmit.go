@@ -0,0 +1,161 @@
+package marketdata
+
+import (
+	"sync"
+)
+
+// Market Data Retransmission
+//
+// Quotes, depth and trades are sequenced together per symbol: every one
+// the publisher sends takes the symbol's next message seq, from 1 with no
+// gaps, in MsgSeq. A consumer that sees MsgSeq jump, or reconnects, knows
+// exactly what it missed and asks for it rather than resnapshotting:
+//
+//	seq 118  quote  150.00 x 150.05
+//	seq 119  trade  150.05 x 200       ← missed
+//	seq 120  quote  150.00 x 150.06    ← missed
+//	seq 121  quote  150.01 x 150.06
+//
+// The publisher keeps the last retransmit history size messages per
+// symbol. Replay from the first missing seq returns:
+//
+//   - still in the history: the messages from it on
+//   - too old, or past the current seq (the server restarted and numbering
+//     began again): everything kept, marked Gone, so the consumer knows
+//     the missed trades aren't all there and takes its state from the
+//     latest quote and depth instead
+//
+// SubscribeMessages replays and subscribes under one lock, so live
+// messages continue at Seq+1 with nothing missed or repeated.
+//
+// Seqs are taken when a message is sent, not published: quotes and depth
+// conflated away by the publisher (see conflate.go) never get one, so they
+// leave no gaps. A conflating subscriber does see gaps, from what it
+// skipped, and needn't replay them. The book feed has its own seq (see
+// book_updates.go).
+
+// DefaultRetransmitHistory is the number of messages kept per symbol for
+// replay.
+const DefaultRetransmitHistory = 10000
+
+// MessageType says what a Message carries.
+type MessageType string
+
+const (
+	MessageQuote MessageType = "quote"
+	MessageDepth MessageType = "depth"
+	MessageTrade MessageType = "trade"
+)
+
+// Message is one sequenced quote, depth update or trade. Exactly one of
+// Quote, Depth and Trade is set, per Type.
+type Message struct {
+	Type   MessageType
+	Seq    uint64 // Per symbol, from 1, no gaps
+	Symbol string
+	Quote  *L1Quote
+	Depth  *L2Depth
+	Trade  *TradeReport
+}
+
+// Replay is a symbol's messages from a requested seq.
+type Replay struct {
+	Symbol   string
+	Seq      uint64    // Latest seq sent; live messages continue at Seq+1
+	Messages []Message // From the requested seq to Seq, oldest first
+	Gone     bool      // The requested seq is no longer kept: Messages is all there is
+}
+
+// messageStream is the message state of one symbol.
+type messageStream struct {
+	mu      sync.Mutex // Held from taking a seq until the message is sent
+	seq     uint64
+	history []Message // Last retransmit history messages, oldest first
+}
+
+// SetRetransmitHistory sets how many messages per symbol are kept for
+// replay.
+func (p *Publisher) SetRetransmitHistory(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retransmitHistory = n
+}
+
+// stream returns a symbol's message stream, creating it if new.
+func (p *Publisher) stream(symbol string) *messageStream {
+	p.streamsMu.Lock()
+	defer p.streamsMu.Unlock()
+	s := p.streams[symbol]
+	if s == nil {
+		s = &messageStream{}
+		p.streams[symbol] = s
+	}
+	return s
+}
+
+// sequence numbers and records a message, then calls send with it before
+// the symbol's next message can be numbered, so subscribers see seqs in
+// order. Callers hold p.mu, for reading at least.
+func (p *Publisher) sequence(msg Message, send func(Message) bool) bool {
+	s := p.stream(msg.Symbol)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	msg.Seq = s.seq
+	s.history = append(s.history, msg)
+	if over := len(s.history) - p.retransmitHistory; over > 0 {
+		s.history = append([]Message(nil), s.history[over:]...)
+	}
+
+	dead := send(msg)
+	return p.messages.send(msg, p.stallTimeout) || dead
+}
+
+// Replay returns a symbol's messages from seq from on.
+func (p *Publisher) Replay(symbol string, from uint64) Replay {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.replay(symbol, from)
+}
+
+// SubscribeMessages subscribes to a symbol's quotes, depth and trades as
+// sequenced messages, returning the replay from seq from and the
+// subscription live messages after it arrive on.
+func (p *Publisher) SubscribeMessages(symbol string, from uint64) (Replay, *Subscription[Message]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.replay(symbol, from), subscribe(p, p.messages, symbol, false)
+}
+
+// replay builds a Replay. Callers hold p.mu; holding it for writing keeps
+// senders from numbering anything until the caller is done.
+func (p *Publisher) replay(symbol string, from uint64) Replay {
+	result := Replay{Symbol: symbol}
+	if from == 0 {
+		from = 1 // Everything kept
+	}
+	p.streamsMu.Lock()
+	s := p.streams[symbol]
+	p.streamsMu.Unlock()
+	if s == nil {
+		result.Gone = from > 1
+		return result
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.Seq = s.seq
+
+	// Nothing missed
+	if from == s.seq+1 {
+		return result
+	}
+	if from > s.seq || len(s.history) == 0 || from < s.history[0].Seq {
+		result.Gone = true
+		result.Messages = append([]Message(nil), s.history...)
+		return result
+	}
+	first := s.history[0].Seq
+	result.Messages = append([]Message(nil), s.history[from-first:]...)
+	return result
+}
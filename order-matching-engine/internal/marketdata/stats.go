@@ -0,0 +1,228 @@
+package marketdata
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// SymbolStats holds the intraday statistics for a single symbol.
+type SymbolStats struct {
+	Symbol     string
+	Day        string // Trading day (UTC, YYYY-MM-DD) these stats cover
+	Volume     int64  // Shares traded today
+	Notional   int64  // Sum of price*quantity today (in cents)
+	TradeCount int64
+	High       int64
+	Low        int64
+	Open       int64
+	Last       int64
+	BidPrice   int64 // From the latest L1 quote
+	AskPrice   int64
+	BidSize    int64
+	AskSize    int64
+	UpdatedAt  int64 // Timestamp of the last trade or quote applied
+}
+
+// VWAP returns the volume-weighted average price, or 0 if nothing traded.
+func (s SymbolStats) VWAP() int64 {
+	if s.Volume == 0 {
+		return 0
+	}
+	return s.Notional / s.Volume
+}
+
+// Spread returns ask minus bid, or 0 if either side is empty.
+func (s SymbolStats) Spread() int64 {
+	if s.BidPrice == 0 || s.AskPrice == 0 {
+		return 0
+	}
+	return s.AskPrice - s.BidPrice
+}
+
+// StatsSummary is the body of GET /stats/symbol: a symbol's statistics,
+// prices as decimals, with the live book's levels and depth.
+type StatsSummary struct {
+	Symbol     string `json:"symbol"`
+	Day        string `json:"day"`
+	Volume     int64  `json:"volume"`
+	VWAP       string `json:"vwap"`
+	Open       string `json:"open"`
+	High       string `json:"high"`
+	Low        string `json:"low"`
+	Last       string `json:"last"`
+	TradeCount int64  `json:"trade_count"`
+	Spread     string `json:"spread"`
+	BidLevels  int    `json:"bid_levels"`
+	AskLevels  int    `json:"ask_levels"`
+	BidDepth   int64  `json:"bid_depth"` // Shares bid at every level
+	AskDepth   int64  `json:"ask_depth"`
+}
+
+// Summarize combines a symbol's statistics with its live book, which the
+// caller must be allowed to read.
+func Summarize(stats SymbolStats, book *orderbook.OrderBook) StatsSummary {
+	summary := StatsSummary{
+		Symbol:     book.Symbol(),
+		Day:        stats.Day,
+		Volume:     stats.Volume,
+		VWAP:       orders.FormatPrice(stats.VWAP()),
+		Open:       orders.FormatPrice(stats.Open),
+		High:       orders.FormatPrice(stats.High),
+		Low:        orders.FormatPrice(stats.Low),
+		Last:       orders.FormatPrice(stats.Last),
+		TradeCount: stats.TradeCount,
+		Spread:     orders.FormatPrice(stats.Spread()),
+		BidLevels:  book.BidLevels(),
+		AskLevels:  book.AskLevels(),
+	}
+	for _, level := range book.GetBidDepth(0) {
+		summary.BidDepth += level.TotalQty
+	}
+	for _, level := range book.GetAskDepth(0) {
+		summary.AskDepth += level.TotalQty
+	}
+	return summary
+}
+
+// StatsTracker maintains per-symbol statistics incrementally.
+//
+// Design:
+// - Subscribes to the all-symbols trade and L1 streams like any other consumer
+// - Each update is O(1), so queries never scan trade history
+// - Day rollover happens lazily when a trade from a new UTC day arrives
+//
+// Because it consumes the same non-blocking streams as external subscribers,
+// a stalled tracker drops updates rather than slowing the publisher.
+type StatsTracker struct {
+	mu     sync.RWMutex
+	stats  map[string]*SymbolStats
+	trades *Subscription[TradeReport]
+	quotes *Subscription[L1Quote]
+	done   chan struct{}
+}
+
+// NewStatsTracker creates a tracker subscribed to the given publisher.
+func NewStatsTracker(publisher *Publisher) *StatsTracker {
+	return &StatsTracker{
+		stats:  make(map[string]*SymbolStats),
+		trades: publisher.SubscribeAllTrades(),
+		quotes: publisher.SubscribeAllL1(),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start begins consuming the trade and quote streams.
+// The tracker stops when the publisher closes its channels.
+func (t *StatsTracker) Start() {
+	go t.consumeLoop()
+}
+
+// consumeLoop applies updates until both streams are closed.
+func (t *StatsTracker) consumeLoop() {
+	defer close(t.done)
+
+	trades, quotes := t.trades.C, t.quotes.C
+	for trades != nil || quotes != nil {
+		select {
+		case trade, ok := <-trades:
+			if !ok {
+				trades = nil
+				continue
+			}
+			t.ApplyTrade(trade)
+		case quote, ok := <-quotes:
+			if !ok {
+				quotes = nil
+				continue
+			}
+			t.ApplyQuote(quote)
+		}
+	}
+}
+
+// Done returns a channel that is closed once the tracker has stopped.
+func (t *StatsTracker) Done() <-chan struct{} {
+	return t.done
+}
+
+// ApplyTrade folds a trade into the symbol's statistics.
+func (t *StatsTracker) ApplyTrade(trade TradeReport) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getOrCreate(trade.Symbol)
+
+	day := tradingDay(trade.Timestamp)
+	if s.Day != day {
+		// New trading day: reset intraday counters, keep the quote
+		*s = SymbolStats{
+			Symbol:   s.Symbol,
+			Day:      day,
+			BidPrice: s.BidPrice,
+			AskPrice: s.AskPrice,
+			BidSize:  s.BidSize,
+			AskSize:  s.AskSize,
+		}
+	}
+
+	if s.TradeCount == 0 {
+		s.Open = trade.Price
+		s.High = trade.Price
+		s.Low = trade.Price
+	}
+	if trade.Price > s.High {
+		s.High = trade.Price
+	}
+	if trade.Price < s.Low {
+		s.Low = trade.Price
+	}
+
+	s.Volume += trade.Quantity
+	s.Notional += trade.Price * trade.Quantity
+	s.TradeCount++
+	s.Last = trade.Price
+	s.UpdatedAt = trade.Timestamp
+}
+
+// ApplyQuote records the latest top of book for a symbol.
+func (t *StatsTracker) ApplyQuote(quote L1Quote) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getOrCreate(quote.Symbol)
+	s.BidPrice = quote.BidPrice
+	s.AskPrice = quote.AskPrice
+	s.BidSize = quote.BidSize
+	s.AskSize = quote.AskSize
+	s.UpdatedAt = quote.Timestamp
+}
+
+// Get returns a copy of the statistics for a symbol.
+func (t *StatsTracker) Get(symbol string) (SymbolStats, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s, exists := t.stats[symbol]
+	if !exists {
+		return SymbolStats{Symbol: symbol}, false
+	}
+	return *s, true
+}
+
+// getOrCreate returns the stats entry for a symbol. Caller must hold the lock.
+func (t *StatsTracker) getOrCreate(symbol string) *SymbolStats {
+	s, exists := t.stats[symbol]
+	if !exists {
+		s = &SymbolStats{Symbol: symbol}
+		t.stats[symbol] = s
+	}
+	return s
+}
+
+// tradingDay returns the UTC calendar day for a nanosecond timestamp.
+func tradingDay(ts int64) string {
+	return time.Unix(0, ts).UTC().Format("2006-01-02")
+}
@@ -0,0 +1,424 @@
+package marketdata
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscriptions
+//
+// Every Subscribe method returns a *Subscription: its ID, the channel C
+// updates arrive on, and Close, which removes it from the publisher and
+// closes C. Subscribers close what they subscribe; a subscription that
+// is never closed costs the publisher a send per update forever.
+//
+// The publisher never waits for a subscriber: it runs on the processor's
+// market data path, and one stalled WebSocket must not hold up the rest.
+// What a subscriber that falls behind loses depends on how it subscribed:
+//
+//   - Subscribe...: updates are queued up to the publisher's buffer size,
+//     and dropped once the queue is full. The subscriber never learns
+//     which, except from the book feed's seq.
+//   - SubscribeL1Conflated, SubscribeAllL1Conflated, SubscribeL2Conflated:
+//     nothing is queued. The subscriber holds the latest update per symbol,
+//     each newer one replacing the last, and receives them, oldest symbol
+//     first, as fast as it reads. However far it falls behind, what it gets
+//     next is the current state, or at worst the one update already on its
+//     way, followed by the current state.
+//
+//	PublishL1 ──▶ pending[symbol] ──▶ pump goroutine ──▶ C (unbuffered)
+//
+// Quotes and depth are state, so the latest supersedes the rest; trades
+// and the sequenced book feed are events and never conflated (see
+// conflate.go).
+//
+// A subscriber that has read nothing for the stall timeout, with updates
+// waiting, is taken for dead - a consumer that returned without closing
+// its subscription - and evicted: removed and its channel closed, as if it
+// had called Close. Stats counts, per subscription, updates sent, dropped
+// and conflated away.
+
+// DefaultStallTimeout is how long a subscriber may leave updates unread
+// before it is evicted.
+const DefaultStallTimeout = time.Minute
+
+// SubscriberStats describes one subscription.
+type SubscriberStats struct {
+	ID         uint64 `json:"id"`
+	Feed       string `json:"feed"`   // "l1", "l2", "trades", "bands", "book", "auction" or "messages"
+	Symbol     string `json:"symbol"` // Empty for all symbols
+	Conflating bool   `json:"conflating"`
+	Sent       uint64 `json:"sent"`      // Updates handed to the subscriber
+	Dropped    uint64 `json:"dropped"`   // Updates lost to a full queue
+	Conflated  uint64 `json:"conflated"` // Updates replaced by a later one before being sent
+	Pending    int    `json:"pending"`   // Symbols with an update held for the subscriber
+}
+
+// Subscription is one subscriber's subscription to a feed of T.
+type Subscription[T any] struct {
+	ID uint64
+	C  <-chan T // Closed by Close, eviction or the publisher closing
+
+	ch     chan T
+	p      *Publisher
+	subs   *subscriptions[T]
+	symbol string
+	closed bool // Guarded by p.mu
+
+	sent, dropped, conflated atomic.Uint64
+	stalledSince             atomic.Int64 // Unix nanos updates have waited since (0 = none waiting)
+	dead                     atomic.Bool  // Stalled past the timeout, to be evicted
+
+	// Conflating only
+	conflate bool
+	mu       sync.Mutex
+	pending  map[string]T
+	order    []string      // Pending symbols, oldest first
+	wake     chan struct{} // Something was added to pending
+	done     chan struct{} // Closed by close; the pump then closes ch
+}
+
+// Close removes the subscription and closes C. It may be called more than
+// once, and after the publisher closed.
+func (s *Subscription[T]) Close() {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	s.subs.remove(s)
+}
+
+// Stats returns the subscription's stats.
+func (s *Subscription[T]) Stats() SubscriberStats {
+	stats := SubscriberStats{
+		ID:         s.ID,
+		Feed:       s.subs.feed,
+		Symbol:     s.symbol,
+		Conflating: s.conflate,
+		Sent:       s.sent.Load(),
+		Dropped:    s.dropped.Load(),
+		Conflated:  s.conflated.Load(),
+	}
+	if s.conflate {
+		s.mu.Lock()
+		stats.Pending = len(s.pending)
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+// send hands the subscriber an update without blocking. Reports whether
+// the subscriber has now been stalled longer than timeout (0 = never).
+func (s *Subscription[T]) send(update T, timeout time.Duration) bool {
+	if !s.conflate {
+		select {
+		case s.ch <- update:
+			s.sent.Add(1)
+			s.stalledSince.Store(0)
+			return false
+		default:
+			s.dropped.Add(1) // Queue full: the subscriber is slow
+			if s.stalledSince.Load() == 0 {
+				s.stalledSince.Store(time.Now().UnixNano())
+				return false
+			}
+			return s.stalled(timeout)
+		}
+	}
+
+	key := s.subs.key(update)
+	s.mu.Lock()
+	if _, held := s.pending[key]; held {
+		s.conflated.Add(1)
+	} else {
+		s.order = append(s.order, key)
+	}
+	s.pending[key] = update
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default: // Already woken
+	}
+	return s.stalled(timeout)
+}
+
+// stalled marks the subscriber dead if updates have waited for it longer
+// than timeout.
+func (s *Subscription[T]) stalled(timeout time.Duration) bool {
+	since := s.stalledSince.Load()
+	if timeout <= 0 || since == 0 || time.Since(time.Unix(0, since)) < timeout {
+		return false
+	}
+	s.dead.Store(true)
+	return true
+}
+
+// take removes the pending update for key, or the oldest one if key is
+// empty.
+func (s *Subscription[T]) take(key string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == "" {
+		if len(s.order) == 0 {
+			var none T
+			return none, false
+		}
+		key = s.order[0]
+	}
+	update, ok := s.pending[key]
+	if ok {
+		delete(s.pending, key)
+		for i, k := range s.order {
+			if k == key {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+	return update, ok
+}
+
+// pump delivers a conflating subscriber's pending updates as it reads
+// them. While it waits for the subscriber, an update for the symbol it is
+// holding replaces the held one.
+func (s *Subscription[T]) pump() {
+	defer close(s.ch)
+	for {
+		update, ok := s.take("")
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		key := s.subs.key(update)
+		s.stalledSince.Store(time.Now().UnixNano())
+		for delivered := false; !delivered; {
+			select {
+			case s.ch <- update:
+				s.sent.Add(1)
+				s.stalledSince.Store(0)
+				delivered = true
+			case <-s.wake:
+				if newer, ok := s.take(key); ok {
+					update = newer
+					s.conflated.Add(1)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// close closes the subscriber's channel, once. Anything still pending is
+// dropped. Callers hold p.mu.
+func (s *Subscription[T]) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.conflate {
+		close(s.done)
+		return
+	}
+	close(s.ch)
+}
+
+// subscriptions are a feed's subscriptions, to one symbol or all of them.
+// Guarded by the publisher's mu.
+type subscriptions[T any] struct {
+	feed     string
+	key      func(T) string // The update's symbol
+	bySymbol map[string][]*Subscription[T]
+	all      []*Subscription[T]
+}
+
+func newSubscriptions[T any](feed string, key func(T) string) *subscriptions[T] {
+	return &subscriptions[T]{feed: feed, key: key, bySymbol: make(map[string][]*Subscription[T])}
+}
+
+// subscribe adds a subscription to symbol ("" for all symbols). Callers
+// hold p.mu.
+func subscribe[T any](p *Publisher, subs *subscriptions[T], symbol string, conflate bool) *Subscription[T] {
+	p.nextSubID++
+	s := &Subscription[T]{ID: p.nextSubID, p: p, subs: subs, symbol: symbol}
+	if conflate {
+		s.ch = make(chan T)
+		s.conflate = true
+		s.pending = make(map[string]T)
+		s.wake = make(chan struct{}, 1)
+		s.done = make(chan struct{})
+		go s.pump()
+	} else {
+		s.ch = make(chan T, p.bufferSize)
+	}
+	s.C = s.ch
+
+	if p.closed {
+		s.close() // Nothing more will be published
+	} else if symbol == "" {
+		subs.all = append(subs.all, s)
+	} else {
+		subs.bySymbol[symbol] = append(subs.bySymbol[symbol], s)
+	}
+	return s
+}
+
+// send sends an update to its symbol's subscribers and the all-symbols
+// ones. Reports whether any has stalled past timeout and should be
+// evicted. Callers hold p.mu, for reading at least.
+func (subs *subscriptions[T]) send(update T, timeout time.Duration) bool {
+	dead := false
+	for _, s := range subs.bySymbol[subs.key(update)] {
+		dead = s.send(update, timeout) || dead
+	}
+	for _, s := range subs.all {
+		dead = s.send(update, timeout) || dead
+	}
+	return dead
+}
+
+// remove removes and closes a subscription, if still there. Callers hold
+// p.mu.
+func (subs *subscriptions[T]) remove(s *Subscription[T]) {
+	if s.symbol == "" {
+		subs.all = without(subs.all, s)
+	} else if list := without(subs.bySymbol[s.symbol], s); len(list) > 0 {
+		subs.bySymbol[s.symbol] = list
+	} else {
+		delete(subs.bySymbol, s.symbol)
+	}
+	s.close()
+}
+
+func without[T any](list []*Subscription[T], s *Subscription[T]) []*Subscription[T] {
+	for i, sub := range list {
+		if sub == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// each calls fn with every subscription.
+func (subs *subscriptions[T]) each(fn func(s *Subscription[T])) {
+	for _, s := range subs.all {
+		fn(s)
+	}
+	for _, list := range subs.bySymbol {
+		for _, s := range list {
+			fn(s)
+		}
+	}
+}
+
+// evict removes the subscriptions marked dead, returning how many.
+func (subs *subscriptions[T]) evict() int {
+	var dead []*Subscription[T]
+	subs.each(func(s *Subscription[T]) {
+		if s.dead.Load() {
+			dead = append(dead, s)
+		}
+	})
+	for _, s := range dead {
+		subs.remove(s)
+	}
+	return len(dead)
+}
+
+// closeAll closes every subscription, leaving none.
+func (subs *subscriptions[T]) closeAll() {
+	subs.each(func(s *Subscription[T]) { s.close() })
+	subs.all = nil
+	subs.bySymbol = make(map[string][]*Subscription[T])
+}
+
+// stats appends every subscription's stats.
+func (subs *subscriptions[T]) stats(into []SubscriberStats) []SubscriberStats {
+	subs.each(func(s *Subscription[T]) { into = append(into, s.Stats()) })
+	return into
+}
+
+// evictDead evicts stalled subscribers after a send reported one.
+func (p *Publisher) evictDead() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evictDeadLocked()
+}
+
+// evictDeadLocked is evictDead for callers holding p.mu.
+func (p *Publisher) evictDeadLocked() {
+	n := p.l1.evict() + p.l2.evict() + p.trades.evict() + p.bands.evict() + p.book.evict() + p.auction.evict() + p.messages.evict()
+	p.evicted += uint64(n)
+}
+
+// SetStallTimeout sets how long a subscriber may leave updates unread
+// before it is evicted (0 = never).
+func (p *Publisher) SetStallTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stallTimeout = timeout
+}
+
+// EvictedSubscribers returns how many subscribers have been evicted for
+// stalling.
+func (p *Publisher) EvictedSubscribers() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.evicted
+}
+
+// SubscriberStats returns the stats of every subscription, by feed, then
+// symbol, then ID.
+func (p *Publisher) SubscriberStats() []SubscriberStats {
+	p.mu.RLock()
+	var stats []SubscriberStats
+	stats = p.l1.stats(stats)
+	stats = p.l2.stats(stats)
+	stats = p.trades.stats(stats)
+	stats = p.bands.stats(stats)
+	stats = p.book.stats(stats)
+	stats = p.auction.stats(stats)
+	stats = p.messages.stats(stats)
+	p.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Feed != b.Feed {
+			return a.Feed < b.Feed
+		}
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.ID < b.ID
+	})
+	return stats
+}
+
+// SubscribeL1Conflated subscribes to L1 quotes for a symbol, conflating
+// them when the subscriber falls behind instead of dropping them.
+func (p *Publisher) SubscribeL1Conflated(symbol string) *Subscription[L1Quote] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return subscribe(p, p.l1, symbol, true)
+}
+
+// SubscribeAllL1Conflated subscribes to L1 quotes for all symbols, holding
+// the latest per symbol when the subscriber falls behind.
+func (p *Publisher) SubscribeAllL1Conflated() *Subscription[L1Quote] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return subscribe(p, p.l1, "", true)
+}
+
+// SubscribeL2Conflated subscribes to L2 depth for a symbol, conflating it
+// when the subscriber falls behind instead of dropping it.
+func (p *Publisher) SubscribeL2Conflated(symbol string) *Subscription[L2Depth] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return subscribe(p, p.l2, symbol, true)
+}
@@ -0,0 +1,44 @@
+package marketdata
+
+// Tape
+//
+// The tape is the public record of trades, newest last: what a time and
+// sales window shows. The publisher keeps the last TapeSize trades of each
+// symbol so a client that connects mid-session sees recent prints without
+// having been subscribed when they happened.
+//
+//	14:30:01.204  AAPL  100 @ 150.02  BUY   7F3A9C21D0 / 0B44E81A9F
+//
+// Reports are stored as published: counterparties are codes, already
+// anonymized upstream (see enrichment).
+
+// TapeSize is the number of recent trades kept per symbol.
+const TapeSize = 100
+
+// recordTape appends a trade to its symbol's tape. The tape grows to twice
+// TapeSize before it is trimmed, so trimming costs amortised O(1) per trade.
+func (p *Publisher) recordTape(trade TradeReport) {
+	p.tapeMu.Lock()
+	defer p.tapeMu.Unlock()
+
+	tape := append(p.tape[trade.Symbol], trade)
+	if len(tape) > 2*TapeSize {
+		tape = append(tape[:0:0], tape[len(tape)-TapeSize:]...)
+	}
+	p.tape[trade.Symbol] = tape
+}
+
+// RecentTrades returns up to n of a symbol's most recent trades, newest
+// first.
+func (p *Publisher) RecentTrades(symbol string, n int) []TradeReport {
+	p.tapeMu.Lock()
+	defer p.tapeMu.Unlock()
+
+	tape := p.tape[symbol]
+	n = max(0, min(n, len(tape), TapeSize))
+	recent := make([]TradeReport, n)
+	for i := range recent {
+		recent[i] = tape[len(tape)-1-i]
+	}
+	return recent
+}
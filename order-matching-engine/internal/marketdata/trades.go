@@ -0,0 +1,136 @@
+package marketdata
+
+import (
+	"sort"
+	"sync"
+)
+
+// Trade History
+//
+// The tape (tape.go) is the last TapeSize prints; the trade store answers
+// time-range queries over the session with pagination:
+//
+//	GET /trades?symbol=AAPL&from=...&to=...&limit=500&after=<trade ID>
+//
+// Each symbol keeps its most recent trades in memory, in trade ID order
+// (one engine numbers a symbol's trades, so that is also time order).
+// Older trades, from before the server started or pushed out of memory,
+// come from the event log instead: the caller supplies a Source that scans
+// it. The two meet at the oldest trade in memory, so a page never repeats
+// or skips a trade whichever side it came from.
+
+// DefaultTradeHistory is the number of trades kept in memory per symbol.
+const DefaultTradeHistory = 10000
+
+// TradeSource returns a symbol's trades with IDs before `before` (0 = any),
+// oldest first, calling fn for each until it returns false.
+type TradeSource func(symbol string, before uint64, fn func(TradeReport) bool) error
+
+// TradeQuery selects a page of a symbol's trades.
+type TradeQuery struct {
+	Symbol string
+	From   int64  // Earliest trade time, nanoseconds since epoch (0 = any)
+	To     int64  // Trades before this time (0 = any)
+	After  uint64 // Only trades with a later ID: the previous page's Next
+	Limit  int
+}
+
+// TradePage is one page of trades, oldest first.
+type TradePage struct {
+	Trades []TradeReport
+	Next   uint64 // Pass as After for the next page; 0 if this was the last
+}
+
+// TradeStore keeps recent trades per symbol. Safe for concurrent use.
+type TradeStore struct {
+	size   int
+	source TradeSource
+
+	mu     sync.RWMutex
+	trades map[string][]TradeReport // By trade ID, oldest first
+}
+
+// NewTradeStore creates a store keeping size trades per symbol in memory,
+// reading older ones from source (nil = memory only).
+func NewTradeStore(size int, source TradeSource) *TradeStore {
+	if size <= 0 {
+		size = DefaultTradeHistory
+	}
+	return &TradeStore{size: size, source: source, trades: make(map[string][]TradeReport)}
+}
+
+// Record adds a trade. Like the tape, a symbol's trades grow to twice the
+// size before the oldest are dropped.
+func (s *TradeStore) Record(trade TradeReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trades := s.trades[trade.Symbol]
+	// Fills are recorded by concurrent handlers, so a trade can arrive
+	// just behind a later one
+	i := len(trades)
+	for i > 0 && trades[i-1].TradeID > trade.TradeID {
+		i--
+	}
+	trades = append(trades, TradeReport{})
+	copy(trades[i+1:], trades[i:])
+	trades[i] = trade
+
+	if len(trades) > 2*s.size {
+		trades = append(trades[:0:0], trades[len(trades)-s.size:]...)
+	}
+	s.trades[trade.Symbol] = trades
+}
+
+// Query returns a page of trades matching q, oldest first.
+func (s *TradeStore) Query(q TradeQuery) (TradePage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	matches := func(trade TradeReport) bool {
+		return trade.TradeID > q.After && trade.Timestamp >= q.From && (q.To == 0 || trade.Timestamp < q.To)
+	}
+
+	s.mu.RLock()
+	recent := s.trades[q.Symbol]
+	var oldest uint64
+	if len(recent) > 0 {
+		oldest = recent[0].TradeID
+	}
+	// Copy out what can match, so the log scan runs without the lock
+	start := sort.Search(len(recent), func(i int) bool { return recent[i].TradeID > q.After })
+	inMemory := make([]TradeReport, 0, min(len(recent)-start, q.Limit+1))
+	for _, trade := range recent[start:] {
+		if len(inMemory) > q.Limit {
+			break
+		}
+		if matches(trade) {
+			inMemory = append(inMemory, trade)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Earlier trades are in the log, if the page can start before memory
+	var page []TradeReport
+	if s.source != nil && (oldest == 0 || q.After+1 < oldest) && (len(recent) == 0 || q.From < recent[0].Timestamp) {
+		err := s.source(q.Symbol, oldest, func(trade TradeReport) bool {
+			if q.To != 0 && trade.Timestamp >= q.To {
+				return false
+			}
+			if matches(trade) {
+				page = append(page, trade)
+			}
+			return len(page) <= q.Limit
+		})
+		if err != nil {
+			return TradePage{}, err
+		}
+	}
+	page = append(page, inMemory...)
+
+	if len(page) <= q.Limit {
+		return TradePage{Trades: page}, nil
+	}
+	page = page[:q.Limit]
+	return TradePage{Trades: page, Next: page[len(page)-1].TradeID}, nil
+}
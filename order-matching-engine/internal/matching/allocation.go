@@ -0,0 +1,123 @@
+package matching
+
+import (
+	"math/bits"
+
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Pro-Rata Allocation
+//
+// FIFO hands a level to its queue in time order, so the first order in
+// takes everything it can. Futures markets often share it out in
+// proportion to size instead, so a large resting order is worth keeping
+// at a price even if others got there first:
+//
+//	level @ $150.00: A 100, B 300, C 600 (1,000 displayed); sell 200 arrives
+//
+//	FIFO       A 100  B 100  C   0
+//	pro-rata   A  20  B  60  C 120
+//
+// For an incoming quantity Q below the level's displayed size, allocation
+// runs in three passes over the queue:
+//
+//  1. Top order (optional): the order at the front of the queue fills
+//     first, up to its displayed size
+//  2. Pro-rata: every other order gets floor(Q' * size / level size) of
+//     what is left, Q', or nothing if that is under the minimum
+//  3. Remainder: what rounding and the minimum held back goes out in time
+//     priority, the front of the queue first
+//
+// A quantity at or above the level's size fills every order, as FIFO
+// would. Only displayed size counts, and trades: an iceberg's reserve is
+// shown again at the back of the queue once its slice is gone. Shares are
+// computed in 128 bits, so they are exact for any quantity, and depend on
+// nothing but the queue, so replay reproduces them. Auction uncrosses
+// still allocate in time priority.
+
+// SetAllocation sets how an incoming order is shared among the resting
+// orders at a symbol's levels. Must be called from the processor goroutine
+// (or before it starts).
+func (e *Engine) SetAllocation(symbol string, alloc orders.Allocation) {
+	if alloc.Method == orders.AllocationFIFO {
+		delete(e.allocations, symbol)
+		return
+	}
+	e.allocations[symbol] = alloc
+}
+
+// Allocation returns a symbol's allocation (FIFO unless set).
+func (e *Engine) Allocation(symbol string) orders.Allocation {
+	return e.allocations[symbol]
+}
+
+// share is what a resting order gets of an incoming one.
+type share struct {
+	order *orders.Order
+	qty   int64
+}
+
+// allocate shares qty among the orders at level by alloc. Returns each
+// order's share in queue order, leaving out orders that get nothing.
+func allocate(level *orderbook.PriceLevel, qty int64, alloc orders.Allocation) []share {
+	shares := make([]share, 0, level.Count())
+	var size int64
+	for node := level.Head(); node != nil; node = node.Next() {
+		shares = append(shares, share{order: node.Order})
+		size += node.Order.VisibleQty()
+	}
+	if qty >= size {
+		// Enough for everyone
+		for i := range shares {
+			shares[i].qty = shares[i].order.VisibleQty()
+		}
+		return filled(shares)
+	}
+
+	// 1. Top order
+	remaining, rest := qty, shares
+	if alloc.TopOrder && len(shares) > 0 {
+		top := &shares[0]
+		top.qty = min(remaining, top.order.VisibleQty())
+		remaining -= top.qty
+		size -= top.order.VisibleQty()
+		rest = shares[1:]
+	}
+
+	// 2. Pro-rata. remaining < size here, so each share is below the
+	// order's size and the 128-bit quotient fits in 64 bits
+	if pool := remaining; pool > 0 {
+		for i := range rest {
+			hi, lo := bits.Mul64(uint64(pool), uint64(rest[i].order.VisibleQty()))
+			q, _ := bits.Div64(hi, lo, uint64(size))
+			if int64(q) < alloc.MinQty {
+				continue
+			}
+			rest[i].qty = int64(q)
+			remaining -= int64(q)
+		}
+	}
+
+	// 3. Remainder, in time priority
+	for i := range shares {
+		if remaining == 0 {
+			break
+		}
+		more := min(remaining, shares[i].order.VisibleQty()-shares[i].qty)
+		shares[i].qty += more
+		remaining -= more
+	}
+	return filled(shares)
+}
+
+// filled drops the shares of orders that get nothing.
+func filled(shares []share) []share {
+	out := shares[:0]
+	for _, s := range shares {
+		if s.qty > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,308 @@
+package matching
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Call Auctions
+//
+// In continuous trading an order matches the moment it arrives. A call
+// auction instead collects orders for a while and crosses them all at once,
+// at a single price - the way exchanges open and close:
+//
+//	StartAuction   call phase: limit orders rest without matching, so the
+//	     │         book may cross; IndicativeAuction shows where it would
+//	     ▼         uncross now
+//	Uncross        every crossing order trades at the equilibrium price
+//	     │
+//	     ▼
+//	continuous trading (pegs re-price)
+//
+// The equilibrium price is chosen among the limit prices in the book:
+//
+//  1. maximum volume: min(bids at or above P, asks at or below P)
+//  2. then minimum imbalance: the quantity left over on the larger side
+//  3. then market pressure: the highest price if every candidate leaves
+//     buyers over, the lowest if every one leaves sellers over
+//  4. then the price closest to the reference price (the previous close or
+//     last trade), the lower one on a tie
+//
+// At that price the best bids pair off with the best asks in price-time
+// priority, iceberg reserve included. In each pair the later order is the
+// taker: it completed the cross. Executing the maximum volume always leaves
+// the book uncrossed.
+//
+// Only plain limit orders enter during the call - no market, IOC, FOK or
+// pegged orders, so there is no market-on-open. Cancels and replaces work
+// as usual. Pegs already resting keep their price until the uncross.
+
+// AuctionInfo is where a symbol in a call auction would uncross now.
+type AuctionInfo struct {
+	Symbol        string
+	RefPrice      int64
+	Price         int64       // Equilibrium price; 0 if nothing crosses
+	Volume        int64       // Quantity that trades at Price
+	Imbalance     int64       // Quantity left unmatched at Price
+	ImbalanceSide orders.Side // Side the imbalance is on, if any
+}
+
+// UncrossResult is the outcome of an auction uncross.
+type UncrossResult struct {
+	AuctionInfo
+	Fills   []orders.Fill
+	Reports []orders.ExecutionReport
+}
+
+// StartAuction puts a symbol into a call auction. refPrice breaks the last
+// tie when choosing the equilibrium price; 0 if there is none.
+func (e *Engine) StartAuction(symbol string, refPrice int64) error {
+	if e.orderBooks[symbol] == nil {
+		return fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	if _, calling := e.auctions[symbol]; calling {
+		return fmt.Errorf("%s is already in an auction call", symbol)
+	}
+	e.auctions[symbol] = refPrice
+	return nil
+}
+
+// InAuction returns true if a symbol is in an auction call.
+func (e *Engine) InAuction(symbol string) bool {
+	_, calling := e.auctions[symbol]
+	return calling
+}
+
+// IndicativeAuction returns where a symbol in an auction call would uncross
+// now. Returns false if it is not in a call.
+func (e *Engine) IndicativeAuction(symbol string) (AuctionInfo, bool) {
+	refPrice, calling := e.auctions[symbol]
+	if !calling {
+		return AuctionInfo{}, false
+	}
+	return equilibrium(e.orderBooks[symbol], refPrice), true
+}
+
+// Uncross ends a symbol's auction call: it crosses the book at the
+// equilibrium price and returns the symbol to continuous trading.
+func (e *Engine) Uncross(symbol string) (*UncrossResult, error) {
+	refPrice, calling := e.auctions[symbol]
+	if !calling {
+		return nil, fmt.Errorf("%s is not in an auction call", symbol)
+	}
+	book := e.orderBooks[symbol]
+	result := &UncrossResult{AuctionInfo: equilibrium(book, refPrice)}
+	delete(e.auctions, symbol)
+
+	if result.Volume > 0 {
+		buys := crossingOrders(book, orders.SideBuy, result.Price, result.Volume)
+		sells := crossingOrders(book, orders.SideSell, result.Price, result.Volume)
+		for left := result.Volume; left > 0; {
+			buy, sell := buys[0], sells[0]
+			qty := min(left, min(buy.RemainingQty(), sell.RemainingQty()))
+			e.crossPair(book, buy, sell, result.Price, qty, result)
+			if buy.IsFilled() {
+				buys = buys[1:]
+			}
+			if sell.IsFilled() {
+				sells = sells[1:]
+			}
+			left -= qty
+		}
+	}
+
+	// Continuous trading resumes: pegs catch up with the new book
+	pegs := &orders.ExecutionResult{}
+	e.repricePegs(book, pegs)
+	result.Fills = append(result.Fills, pegs.Fills...)
+	result.Reports = append(result.Reports, pegs.Reports...)
+	return result, nil
+}
+
+// crossPair trades qty between a bid and an ask at the auction price.
+func (e *Engine) crossPair(book *orderbook.OrderBook, buy, sell *orders.Order, price, qty int64, result *UncrossResult) {
+	maker, taker := buy, sell
+	if buy.SequenceNum > sell.SequenceNum {
+		maker, taker = sell, buy
+	}
+	fill := orders.Fill{
+		TradeID:        e.nextTradeID(),
+		MakerOrderID:   maker.ID,
+		TakerOrderID:   taker.ID,
+		Price:          price,
+		Quantity:       qty,
+		Timestamp:      e.time(),
+		Symbol:         book.Symbol(),
+		MakerAccountID: maker.AccountID,
+		TakerAccountID: taker.AccountID,
+		TakerSide:      taker.Side,
+	}
+	result.Fills = append(result.Fills, fill)
+
+	for _, order := range []*orders.Order{taker, maker} {
+		book.FillOrder(order.ID, qty, price)
+		if order.IsFilled() {
+			order.Status = orders.OrderStatusFilled
+			e.untrackSession(order)
+			e.history.complete(order)
+		} else {
+			order.Status = orders.OrderStatusPartiallyFilled
+		}
+		result.Reports = append(result.Reports, orders.NewExecutionReport(order, fill, order == maker))
+	}
+}
+
+// crossingOrders returns one side's orders that trade in an uncross at
+// price, in priority order, until they cover volume.
+func crossingOrders(book *orderbook.OrderBook, side orders.Side, price, volume int64) []*orders.Order {
+	var crossing []*orders.Order
+	book.ForEachLevel(side, func(level *orderbook.PriceLevel) bool {
+		if (side == orders.SideBuy && level.Price < price) || (side == orders.SideSell && level.Price > price) {
+			return false
+		}
+		for node := level.Head(); node != nil && volume > 0; node = node.Next() {
+			crossing = append(crossing, node.Order)
+			volume -= node.Order.RemainingQty()
+		}
+		return volume > 0
+	})
+	return crossing
+}
+
+// auctionCross is the volume a book would trade at one candidate price.
+type auctionCross struct {
+	price  int64
+	demand int64 // Bids at or above price
+	supply int64 // Asks at or below price
+}
+
+func (c auctionCross) volume() int64 {
+	return min(c.demand, c.supply)
+}
+
+func (c auctionCross) imbalance() int64 {
+	if c.demand > c.supply {
+		return c.demand - c.supply
+	}
+	return c.supply - c.demand
+}
+
+// equilibrium computes a book's auction price (see the rules above).
+func equilibrium(book *orderbook.OrderBook, refPrice int64) AuctionInfo {
+	info := AuctionInfo{Symbol: book.Symbol(), RefPrice: refPrice}
+	bestBid, bestAsk := book.GetBestBid(), book.GetBestAsk()
+	if bestBid == nil || bestAsk == nil || bestBid.Price < bestAsk.Price {
+		return info // Nothing crosses
+	}
+
+	// Only levels between the best ask and the best bid can trade
+	var bids, asks []*orderbook.PriceLevel
+	book.ForEachLevel(orders.SideBuy, func(level *orderbook.PriceLevel) bool {
+		if level.Price < bestAsk.Price {
+			return false
+		}
+		bids = append(bids, level)
+		return true
+	})
+	book.ForEachLevel(orders.SideSell, func(level *orderbook.PriceLevel) bool {
+		if level.Price > bestBid.Price {
+			return false
+		}
+		asks = append(asks, level)
+		return true
+	})
+
+	prices := make(map[int64]struct{}, len(bids)+len(asks))
+	for _, level := range append(append([]*orderbook.PriceLevel(nil), bids...), asks...) {
+		prices[level.Price] = struct{}{}
+	}
+	candidates := make([]auctionCross, 0, len(prices))
+	for price := range prices {
+		c := auctionCross{price: price}
+		for _, level := range bids {
+			if level.Price >= price {
+				c.demand += level.TotalQty + level.HiddenQty
+			}
+		}
+		for _, level := range asks {
+			if level.Price <= price {
+				c.supply += level.TotalQty + level.HiddenQty
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].price < candidates[j].price })
+
+	// 1 and 2: maximum volume, then minimum imbalance
+	var tied []auctionCross
+	for _, c := range candidates {
+		switch {
+		case len(tied) == 0 || c.volume() > tied[0].volume() ||
+			(c.volume() == tied[0].volume() && c.imbalance() < tied[0].imbalance()):
+			tied = []auctionCross{c}
+		case c.volume() == tied[0].volume() && c.imbalance() == tied[0].imbalance():
+			tied = append(tied, c)
+		}
+	}
+
+	// 3: market pressure
+	buyers, sellers := true, true
+	for _, c := range tied {
+		buyers = buyers && c.demand > c.supply
+		sellers = sellers && c.supply > c.demand
+	}
+	best := tied[0]
+	switch {
+	case buyers:
+		best = tied[len(tied)-1]
+	case sellers:
+		best = tied[0]
+	default:
+		// 4: closest to the reference price, the lower one on a tie
+		for _, c := range tied[1:] {
+			if distance(c.price, refPrice) < distance(best.price, refPrice) {
+				best = c
+			}
+		}
+	}
+
+	info.Price = best.price
+	info.Volume = best.volume()
+	info.Imbalance = best.imbalance()
+	if best.supply > best.demand {
+		info.ImbalanceSide = orders.SideSell
+	}
+	return info
+}
+
+func distance(a, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// AuctionSymbols returns the symbols in an auction call: symbol ->
+// reference price.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) AuctionSymbols() map[string]int64 {
+	auctions := make(map[string]int64, len(e.auctions))
+	for symbol, refPrice := range e.auctions {
+		auctions[symbol] = refPrice
+	}
+	return auctions
+}
+
+// RestoreAuctions re-opens the auction calls captured by AuctionSymbols.
+//
+// Must be called before the engine processes its first order.
+func (e *Engine) RestoreAuctions(auctions map[string]int64) {
+	for symbol, refPrice := range auctions {
+		e.AddSymbol(symbol)
+		e.auctions[symbol] = refPrice
+	}
+}
@@ -0,0 +1,62 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Basket Orders
+//
+// A basket submits legs across several symbols in one request (e.g. buying
+// every constituent of an index while selling the ETF). Acceptance is
+// all-or-none: if any leg fails validation, no leg enters any book, so the
+// client is never left holding half a hedge. Once accepted, each leg matches
+// independently with its normal order type semantics - a basket of limit
+// orders may partially fill on some legs and rest on others.
+//
+// The whole basket is one ring buffer request, so no other order can be
+// sequenced between validation and the last leg.
+
+// BasketResult is the outcome of a basket submission.
+type BasketResult struct {
+	Accepted     bool
+	RejectReason string                    // Set if the basket was rejected
+	Legs         []*orders.ExecutionResult // One per leg, in submission order
+}
+
+// ProcessBasket validates every leg, then processes them in order.
+//
+// If any leg is invalid the basket is rejected and every leg is marked
+// rejected without touching the books.
+func (e *Engine) ProcessBasket(legs []*orders.Order) *BasketResult {
+	result := &BasketResult{
+		Legs: make([]*orders.ExecutionResult, len(legs)),
+	}
+
+	if len(legs) == 0 {
+		result.RejectReason = "basket has no legs"
+		return result
+	}
+
+	for i, leg := range legs {
+		if reason := e.validateOrder(leg); reason != "" {
+			result.RejectReason = fmt.Sprintf("leg %d (%s): %s", i, leg.Symbol, reason)
+			for j, l := range legs {
+				l.Status = orders.OrderStatusRejected
+				result.Legs[j] = &orders.ExecutionResult{
+					Order:        l,
+					Fills:        make([]orders.Fill, 0),
+					RejectReason: "basket rejected: " + result.RejectReason,
+				}
+			}
+			return result
+		}
+	}
+
+	result.Accepted = true
+	for i, leg := range legs {
+		result.Legs[i] = e.ProcessOrder(leg)
+	}
+	return result
+}
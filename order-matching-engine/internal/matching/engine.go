@@ -19,6 +19,7 @@ package matching
 
 import (
 	"fmt"
+	"sort"
 	"sync/atomic"
 
 	"github.com/rishav/order-matching-engine/internal/orderbook"
@@ -35,15 +36,86 @@ type Engine struct {
 	sequenceNum uint64 // Global sequence number
 	tradeID     uint64 // Global trade ID counter
 	orderID     uint64 // Global order ID counter
+
+	// Order and trade IDs are issued from a stride when several engines
+	// share one ID space: only IDs congruent to idOffset modulo idStride
+	// (see SetIDStride)
+	idStride uint64
+	idOffset uint64
+
+	// sessions tracks resting orders per order entry session so a dropped
+	// session can be mass-cancelled: session ID -> order ID -> symbol
+	sessions map[string]map[uint64]string
+
+	// moved records symbols handed over to another shard: symbol -> target
+	// (see migrate.go)
+	moved map[string]string
+
+	// auctions holds the symbols in an auction call: symbol -> reference
+	// price (see auction.go)
+	auctions map[string]int64
+
+	// ticks holds the tick size of symbols not traded in cents, for the
+	// prices the engine sets itself (midpoint pegs): symbol -> tick
+	ticks map[string]int64
+
+	// allocations holds the symbols not allocated FIFO: symbol ->
+	// allocation (see allocation.go)
+	allocations map[string]orders.Allocation
+
+	// history remembers recently completed orders for status lookups
+	// (see history.go)
+	history *orderHistory
+
+	// now is the time stamped on fills and new orders (see SetTime); 0
+	// reads the wall clock
+	now int64
 }
 
 // NewEngine creates a new matching engine.
 func NewEngine() *Engine {
 	return &Engine{
-		orderBooks: make(map[string]*orderbook.OrderBook),
+		orderBooks:  make(map[string]*orderbook.OrderBook),
+		sessions:    make(map[string]map[uint64]string),
+		moved:       make(map[string]string),
+		auctions:    make(map[string]int64),
+		ticks:       make(map[string]int64),
+		allocations: make(map[string]orders.Allocation),
+		history:     newOrderHistory(DefaultOrderHistory),
+		idStride:    1,
 	}
 }
 
+// SetIDStride makes the engine issue order and trade IDs congruent to
+// index+1 modulo n, so n engines (one per shard) never issue the same ID:
+//
+//	n = 4   shard 0: 1, 5, 9 ...   shard 1: 2, 6, 10 ...   shard 3: 4, 8, 12 ...
+//
+// Replaying a shard's own log reproduces its IDs, as the stride only
+// depends on the last ID issued. Must be called before the engine processes
+// its first order.
+func (e *Engine) SetIDStride(n, index int) {
+	e.idStride = uint64(n)
+	e.idOffset = uint64(index+1) % uint64(n)
+}
+
+// SetTime sets the time the engine stamps on fills, and on orders that
+// arrive without a timestamp, until it is set again. The event processor
+// sets it to the time it recorded for each request, and replay to the time
+// each event was logged at, so a request processed again stamps what it
+// stamped the first time. 0 (the default) reads the wall clock instead.
+func (e *Engine) SetTime(now int64) {
+	e.now = now
+}
+
+// time returns the time to stamp (see SetTime).
+func (e *Engine) time() int64 {
+	if e.now != 0 {
+		return e.now
+	}
+	return orders.Now()
+}
+
 // AddSymbol adds a new tradable symbol to the engine.
 func (e *Engine) AddSymbol(symbol string) {
 	if _, exists := e.orderBooks[symbol]; !exists {
@@ -51,6 +123,26 @@ func (e *Engine) AddSymbol(symbol string) {
 	}
 }
 
+// SetTickSize sets a symbol's tick size in cents, from its reference data.
+// Orders are validated against it before they reach the engine; the engine
+// keeps the prices it computes on it too. Must be called from the processor
+// goroutine (or before it starts).
+func (e *Engine) SetTickSize(symbol string, tick int64) {
+	if tick <= 1 {
+		delete(e.ticks, symbol)
+		return
+	}
+	e.ticks[symbol] = tick
+}
+
+// tickSize returns a symbol's tick size in cents.
+func (e *Engine) tickSize(symbol string) int64 {
+	if tick, ok := e.ticks[symbol]; ok {
+		return tick
+	}
+	return 1
+}
+
 // GetOrderBook returns the order book for a symbol.
 func (e *Engine) GetOrderBook(symbol string) *orderbook.OrderBook {
 	return e.orderBooks[symbol]
@@ -58,12 +150,27 @@ func (e *Engine) GetOrderBook(symbol string) *orderbook.OrderBook {
 
 // NextOrderID generates the next order ID.
 func (e *Engine) NextOrderID() uint64 {
-	return atomic.AddUint64(&e.orderID, 1)
+	return e.nextID(&e.orderID)
 }
 
 // nextTradeID generates the next trade ID.
 func (e *Engine) nextTradeID() uint64 {
-	return atomic.AddUint64(&e.tradeID, 1)
+	return e.nextID(&e.tradeID)
+}
+
+// nextID advances an ID counter to the next ID in the engine's stride.
+func (e *Engine) nextID(counter *uint64) uint64 {
+	if e.idStride <= 1 {
+		return atomic.AddUint64(counter, 1)
+	}
+	for {
+		last := atomic.LoadUint64(counter)
+		next := last + 1
+		next += (e.idOffset + e.idStride - next%e.idStride) % e.idStride
+		if atomic.CompareAndSwapUint64(counter, last, next) {
+			return next
+		}
+	}
 }
 
 // nextSequence generates the next sequence number.
@@ -78,6 +185,9 @@ func (e *Engine) nextSequence() uint64 {
 // 2. Assigns sequence number and order ID
 // 3. Attempts to match against resting orders
 // 4. Places any remaining quantity in the book (for limit orders)
+// 5. Re-prices pegged orders in the book (see peg.go)
+//
+// During an auction call (see auction.go) the order rests without matching.
 //
 // Time complexity: O(M * log P) where M = number of fills, P = price levels
 func (e *Engine) ProcessOrder(order *orders.Order) *orders.ExecutionResult {
@@ -88,24 +198,12 @@ func (e *Engine) ProcessOrder(order *orders.Order) *orders.ExecutionResult {
 	}
 
 	// Validate
-	book := e.orderBooks[order.Symbol]
-	if book == nil {
-		result.RejectReason = fmt.Sprintf("unknown symbol: %s", order.Symbol)
-		order.Status = orders.OrderStatusRejected
-		return result
-	}
-
-	if order.Quantity <= 0 {
-		result.RejectReason = "quantity must be positive"
-		order.Status = orders.OrderStatusRejected
-		return result
-	}
-
-	if order.Type == orders.OrderTypeLimit && order.Price <= 0 {
-		result.RejectReason = "limit order must have positive price"
+	if reason := e.validateOrder(order); reason != "" {
+		result.RejectReason = reason
 		order.Status = orders.OrderStatusRejected
 		return result
 	}
+	book := e.orderBooks[order.Symbol]
 
 	// Assign IDs
 	if order.ID == 0 {
@@ -113,14 +211,26 @@ func (e *Engine) ProcessOrder(order *orders.Order) *orders.ExecutionResult {
 	}
 	order.SequenceNum = e.nextSequence()
 	if order.Timestamp == 0 {
-		order.Timestamp = orders.Now()
+		order.Timestamp = e.time()
 	}
 	order.Status = orders.OrderStatusNew
+	if order.IsPegged() {
+		order.Price, _ = pegPrice(order, book, e.tickSize(order.Symbol)) // Checked in validateOrder
+	}
 	result.Accepted = true
+	e.history.accepted(order)
+
+	if e.InAuction(order.Symbol) {
+		book.AddOrder(order)
+		e.trackSession(order)
+		result.RestingQty = order.RemainingQty()
+		return result
+	}
 
 	// Match the order
-	fills := e.matchOrder(order, book)
+	fills, reports := e.matchOrder(order, book)
 	result.Fills = fills
+	result.Reports = reports
 
 	// Update order status based on fills
 	if order.IsFilled() {
@@ -151,21 +261,68 @@ func (e *Engine) ProcessOrder(order *orders.Order) *orders.ExecutionResult {
 		case orders.OrderTypeLimit:
 			// Limit orders rest in the book
 			book.AddOrder(order)
+			e.trackSession(order)
 			result.RestingQty = remainingQty
 		}
 	}
+	if result.RestingQty == 0 {
+		e.history.complete(order)
+	}
+
+	// The order may have moved the prices pegs follow
+	e.repricePegs(book, result)
 
 	return result
 }
 
+// validateOrder checks an order against engine state without changing it.
+// Returns the reject reason, or "" if the order can be processed.
+func (e *Engine) validateOrder(order *orders.Order) string {
+	if e.orderBooks[order.Symbol] == nil {
+		return fmt.Sprintf("unknown symbol: %s", order.Symbol)
+	}
+	if order.Quantity <= 0 {
+		return "quantity must be positive"
+	}
+	if order.Type == orders.OrderTypeLimit && order.Price <= 0 && !order.IsPegged() {
+		return "limit order must have positive price"
+	}
+	if order.DisplayQty < 0 {
+		return "display quantity must not be negative"
+	}
+	if order.DisplayQty > 0 && order.Type != orders.OrderTypeLimit {
+		return "display quantity is only valid for limit orders"
+	}
+	if e.InAuction(order.Symbol) && (order.Type != orders.OrderTypeLimit || order.IsPegged()) {
+		return "only limit orders are accepted during an auction call"
+	}
+	if order.IsPegged() {
+		if order.Type != orders.OrderTypeLimit {
+			return "peg is only valid for limit orders"
+		}
+		if order.DisplayQty > 0 {
+			return "pegged orders cannot be icebergs"
+		}
+		if order.PegLimit < 0 {
+			return "peg limit must not be negative"
+		}
+		if _, ok := pegPrice(order, e.orderBooks[order.Symbol], e.tickSize(order.Symbol)); !ok {
+			return fmt.Sprintf("no %s reference price for pegged order", order.Peg)
+		}
+	}
+	return ""
+}
+
 // matchOrder attempts to match an incoming order against resting orders.
-func (e *Engine) matchOrder(order *orders.Order, book *orderbook.OrderBook) []orders.Fill {
+// Returns the fills and an execution report for each side of each fill.
+func (e *Engine) matchOrder(order *orders.Order, book *orderbook.OrderBook) ([]orders.Fill, []orders.ExecutionReport) {
 	var fills []orders.Fill
+	var reports []orders.ExecutionReport
 
 	// FOK orders need special handling - check if we can fill entirely first
 	if order.Type == orders.OrderTypeFOK {
 		if !e.canFillEntirely(order, book) {
-			return fills // Empty - order will be cancelled
+			return fills, reports // Empty - order will be cancelled
 		}
 	}
 
@@ -197,6 +354,7 @@ func (e *Engine) matchOrder(order *orders.Order, book *orderbook.OrderBook) []or
 	}
 
 	// Match against resting orders
+	alloc := e.allocations[order.Symbol]
 	for order.RemainingQty() > 0 {
 		level := getMatchLevel()
 		if level == nil {
@@ -207,61 +365,101 @@ func (e *Engine) matchOrder(order *orders.Order, book *orderbook.OrderBook) []or
 			break // Price doesn't match
 		}
 
+		if alloc.Method == orders.AllocationProRata {
+			// Shared across the level at once (see allocation.go)
+			shares := allocate(level, order.RemainingQty(), alloc)
+			if len(shares) == 0 {
+				break // Nothing displayed to trade with
+			}
+			for _, share := range shares {
+				fill, report := e.trade(order, share.order, share.qty, level, book)
+				fills = append(fills, fill)
+				reports = append(reports, report...)
+			}
+			continue
+		}
+
 		// Match against orders at this price level (FIFO)
 		for node := level.Head(); node != nil && order.RemainingQty() > 0; {
 			makerOrder := node.Order
-			nextNode := node // Save for iteration
-
-			// Calculate fill quantity
-			fillQty := min(order.RemainingQty(), makerOrder.RemainingQty())
-
-			// Create fill record
-			fill := orders.Fill{
-				TradeID:        e.nextTradeID(),
-				MakerOrderID:   makerOrder.ID,
-				TakerOrderID:   order.ID,
-				Price:          level.Price, // Execute at maker's price (price improvement for taker)
-				Quantity:       fillQty,
-				Timestamp:      orders.Now(),
-				Symbol:         order.Symbol,
-				MakerAccountID: makerOrder.AccountID,
-				TakerAccountID: order.AccountID,
-				TakerSide:      order.Side,
-			}
-			fills = append(fills, fill)
 
-			// Update quantities
-			order.FilledQty += fillQty
-			makerOrder.FilledQty += fillQty
+			// Move to next node before potentially removing current
+			nextNode := node.Next()
 
-			// Update maker order status
-			if makerOrder.IsFilled() {
-				makerOrder.Status = orders.OrderStatusFilled
-			} else {
-				makerOrder.Status = orders.OrderStatusPartiallyFilled
-			}
+			// Calculate fill quantity. Only the displayed slice of an iceberg
+			// trades; its reserve waits for the slice to be replenished
+			fillQty := min(order.RemainingQty(), makerOrder.VisibleQty())
 
-			// Move to next node before potentially removing current
-			nextNode = nextNode.Next()
-
-			// Remove filled maker order from book
-			if makerOrder.IsFilled() {
-				book.CancelOrder(makerOrder.ID)
-			} else {
-				// Update the level's total quantity
-				level.UpdateQuantity(-fillQty)
-			}
+			fill, report := e.trade(order, makerOrder, fillQty, level, book)
+			fills = append(fills, fill)
+			reports = append(reports, report...)
 
 			node = nextNode
 		}
 
-		// Check if level is now empty (shouldn't happen due to CancelOrder, but safety check)
-		if level.IsEmpty() {
-			break
-		}
+		// If the level was exhausted it has already been removed from the book,
+		// so the next iteration picks up the next best price level
 	}
 
-	return fills
+	return fills, reports
+}
+
+// trade fills qty of the incoming order against a resting one at level's
+// price. Returns the fill and an execution report for each side. A filled
+// maker leaves the book; an iceberg whose slice is gone shows its next one.
+func (e *Engine) trade(order, makerOrder *orders.Order, fillQty int64, level *orderbook.PriceLevel, book *orderbook.OrderBook) (orders.Fill, []orders.ExecutionReport) {
+	// Create fill record
+	fill := orders.Fill{
+		TradeID:        e.nextTradeID(),
+		MakerOrderID:   makerOrder.ID,
+		TakerOrderID:   order.ID,
+		Price:          level.Price, // Execute at maker's price (price improvement for taker)
+		Quantity:       fillQty,
+		Timestamp:      e.time(),
+		Symbol:         order.Symbol,
+		MakerAccountID: makerOrder.AccountID,
+		TakerAccountID: order.AccountID,
+		TakerSide:      order.Side,
+	}
+
+	// Update quantities
+	order.ApplyFill(fillQty, fill.Price)
+	makerOrder.ApplyFill(fillQty, fill.Price)
+
+	// Update order statuses
+	if makerOrder.IsFilled() {
+		makerOrder.Status = orders.OrderStatusFilled
+	} else {
+		makerOrder.Status = orders.OrderStatusPartiallyFilled
+	}
+	if order.IsFilled() {
+		order.Status = orders.OrderStatusFilled
+	} else {
+		order.Status = orders.OrderStatusPartiallyFilled
+	}
+
+	// Execution reports snapshot cumulative state at this fill
+	reports := []orders.ExecutionReport{
+		orders.NewExecutionReport(order, fill, false),
+		orders.NewExecutionReport(makerOrder, fill, true),
+	}
+
+	// Update the level's total quantity. This must happen for full
+	// fills too: removal only subtracts the (now zero) remaining qty
+	level.UpdateQuantity(-fillQty)
+
+	// Remove filled maker order from book
+	if makerOrder.IsFilled() {
+		book.CancelOrder(makerOrder.ID)
+		e.untrackSession(makerOrder)
+		e.history.complete(makerOrder)
+	} else if makerOrder.VisibleQty() == 0 {
+		// Iceberg slice exhausted: show the next one at the back of
+		// the queue. If it was the last order at this price, the
+		// outer loop comes back to this level for it.
+		book.ReplenishOrder(makerOrder.ID)
+	}
+	return fill, reports
 }
 
 // canFillEntirely checks if a FOK order can be completely filled.
@@ -324,7 +522,7 @@ func (e *Engine) canFillEntirely(order *orders.Order, book *orderbook.OrderBook)
 		if !priceOK(level.Price) {
 			return false
 		}
-		availableQty := level.TotalQty
+		availableQty := level.TotalQty + level.HiddenQty // Reserve is executable
 		if availableQty >= remainingQty {
 			remainingQty = 0
 			return false
@@ -349,9 +547,85 @@ func (e *Engine) CancelOrder(symbol string, orderID uint64) (*orders.Order, erro
 	}
 
 	order.Status = orders.OrderStatusCancelled
+	e.untrackSession(order)
+	e.history.complete(order)
 	return order, nil
 }
 
+// CancelSessionOrders cancels every resting order placed by a session.
+// Orders are cancelled in order ID sequence so replay is deterministic.
+func (e *Engine) CancelSessionOrders(sessionID string) []*orders.Order {
+	tracked := e.sessions[sessionID]
+	if len(tracked) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(tracked))
+	for id := range tracked {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	cancelled := make([]*orders.Order, 0, len(ids))
+	for _, id := range ids {
+		if order, err := e.CancelOrder(tracked[id], id); err == nil {
+			cancelled = append(cancelled, order)
+		}
+	}
+	delete(e.sessions, sessionID)
+	return cancelled
+}
+
+// CancelAccountOrders cancels every resting order of an account, in every
+// symbol. Orders are cancelled in order ID sequence so replay is
+// deterministic.
+func (e *Engine) CancelAccountOrders(accountID string) []*orders.Order {
+	var resting []*orders.Order
+	for _, book := range e.orderBooks {
+		resting = append(resting, book.AccountOrders(accountID)...)
+	}
+	sort.Slice(resting, func(i, j int) bool { return resting[i].ID < resting[j].ID })
+
+	cancelled := make([]*orders.Order, 0, len(resting))
+	for _, order := range resting {
+		if order, err := e.CancelOrder(order.Symbol, order.ID); err == nil {
+			cancelled = append(cancelled, order)
+		}
+	}
+	return cancelled
+}
+
+// SessionOrderCount returns the number of resting orders tracked for a session.
+func (e *Engine) SessionOrderCount(sessionID string) int {
+	return len(e.sessions[sessionID])
+}
+
+// trackSession records a resting order against its session.
+func (e *Engine) trackSession(order *orders.Order) {
+	if order.SessionID == "" {
+		return
+	}
+	tracked := e.sessions[order.SessionID]
+	if tracked == nil {
+		tracked = make(map[uint64]string)
+		e.sessions[order.SessionID] = tracked
+	}
+	tracked[order.ID] = order.Symbol
+}
+
+// untrackSession forgets an order that is no longer resting.
+func (e *Engine) untrackSession(order *orders.Order) {
+	if order.SessionID == "" {
+		return
+	}
+	if tracked := e.sessions[order.SessionID]; tracked != nil {
+		delete(tracked, order.ID)
+		if len(tracked) == 0 {
+			delete(e.sessions, order.SessionID)
+		}
+	}
+}
+
 // GetOrder retrieves an order by symbol and ID.
 func (e *Engine) GetOrder(symbol string, orderID uint64) *orders.Order {
 	book := e.orderBooks[symbol]
@@ -361,6 +635,30 @@ func (e *Engine) GetOrder(symbol string, orderID uint64) *orders.Order {
 	return book.GetOrder(orderID)
 }
 
+// OpenOrders returns copies of an account's resting orders, by symbol and
+// then oldest first. An empty symbol lists every symbol.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) OpenOrders(accountID, symbol string) []orders.Order {
+	symbols := []string{symbol}
+	if symbol == "" {
+		symbols = e.Symbols()
+		sort.Strings(symbols)
+	}
+
+	var open []orders.Order
+	for _, s := range symbols {
+		book := e.orderBooks[s]
+		if book == nil {
+			continue
+		}
+		for _, order := range book.AccountOrders(accountID) {
+			open = append(open, *order) // Copy: the engine keeps trading them
+		}
+	}
+	return open
+}
+
 // Symbols returns all tradable symbols.
 func (e *Engine) Symbols() []string {
 	symbols := make([]string, 0, len(e.orderBooks))
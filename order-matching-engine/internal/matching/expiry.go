@@ -0,0 +1,35 @@
+package matching
+
+import (
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ExpireDayOrders cancels the DAY orders resting in symbols that were
+// entered before cutoff (nanoseconds since epoch), e.g. at their market's
+// close. Symbols this engine doesn't trade are skipped. Orders are
+// cancelled in order ID sequence so replay is deterministic.
+func (e *Engine) ExpireDayOrders(symbols []string, cutoff int64) []*orders.Order {
+	var expiring []orders.Order
+	for _, symbol := range symbols {
+		book := e.orderBooks[symbol]
+		if book == nil {
+			continue
+		}
+		for _, order := range restingOrders(book) {
+			if order.TimeInForce == orders.TIFDay && order.Timestamp < cutoff {
+				expiring = append(expiring, order)
+			}
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool { return expiring[i].ID < expiring[j].ID })
+
+	expired := make([]*orders.Order, 0, len(expiring))
+	for _, order := range expiring {
+		if cancelled, err := e.CancelOrder(order.Symbol, order.ID); err == nil {
+			expired = append(expired, cancelled)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,115 @@
+package matching
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Order Status History
+//
+// The books only hold live orders: once an order fills or is cancelled it
+// is gone, and so is any way to ask what happened to it. The engine keeps
+// the most recent completed orders (filled, cancelled, killed) in a bounded
+// FIFO, so a status lookup can answer for them too:
+//
+//	live:       book.GetOrder                ──┐
+//	completed:  history (last N, oldest out) ──┴── LookupOrder / LookupClientOrder
+//
+// Client order IDs are indexed per account for live and remembered orders
+// alike. An ID reused by the same account points at its latest order.
+//
+// The history is rebuilt by replay like the books, so after a restart from a
+// snapshot it only covers orders completed in the replayed log tail.
+
+// DefaultOrderHistory is the number of completed orders remembered by default.
+const DefaultOrderHistory = 100000
+
+// clientOrderKey identifies an order by the account's own ID for it.
+type clientOrderKey struct {
+	accountID     string
+	clientOrderID string
+}
+
+// orderHistory remembers completed orders. Only used by the processor
+// goroutine.
+type orderHistory struct {
+	limit     int
+	completed map[uint64]orders.Order // Order ID -> final state
+	fifo      []uint64                // Completed order IDs, oldest first
+	clientIDs map[clientOrderKey]uint64
+}
+
+func newOrderHistory(limit int) *orderHistory {
+	return &orderHistory{
+		limit:     limit,
+		completed: make(map[uint64]orders.Order),
+		clientIDs: make(map[clientOrderKey]uint64),
+	}
+}
+
+// accepted indexes a newly accepted order's client order ID.
+func (h *orderHistory) accepted(order *orders.Order) {
+	if order.ClientOrderID != "" {
+		h.clientIDs[clientOrderKey{order.AccountID, order.ClientOrderID}] = order.ID
+	}
+}
+
+// complete remembers an order that has left the book for good, evicting
+// the oldest completed order once the history is full.
+func (h *orderHistory) complete(order *orders.Order) {
+	if h.limit <= 0 {
+		h.forget(order)
+		return
+	}
+	if _, seen := h.completed[order.ID]; !seen {
+		h.fifo = append(h.fifo, order.ID)
+	}
+	h.completed[order.ID] = *order
+
+	for len(h.fifo) > h.limit {
+		oldest := h.completed[h.fifo[0]]
+		delete(h.completed, oldest.ID)
+		h.forget(&oldest)
+		h.fifo[0] = 0
+		h.fifo = h.fifo[1:]
+	}
+}
+
+// forget drops an order's client order ID, unless it was reused since.
+func (h *orderHistory) forget(order *orders.Order) {
+	key := clientOrderKey{order.AccountID, order.ClientOrderID}
+	if order.ClientOrderID != "" && h.clientIDs[key] == order.ID {
+		delete(h.clientIDs, key)
+	}
+}
+
+// SetOrderHistory sets how many completed orders are remembered for status
+// lookups (0 = none). Must be called before the engine processes its first
+// order.
+func (e *Engine) SetOrderHistory(limit int) {
+	e.history = newOrderHistory(limit)
+}
+
+// LookupOrder returns a copy of an order, live or recently completed.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) LookupOrder(orderID uint64) (orders.Order, bool) {
+	for _, book := range e.orderBooks {
+		if order := book.GetOrder(orderID); order != nil {
+			return *order, true
+		}
+	}
+	order, ok := e.history.completed[orderID]
+	return order, ok
+}
+
+// LookupClientOrder returns a copy of the latest order an account placed
+// with the given client order ID, live or recently completed.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) LookupClientOrder(accountID, clientOrderID string) (orders.Order, bool) {
+	orderID, ok := e.history.clientIDs[clientOrderKey{accountID, clientOrderID}]
+	if !ok {
+		return orders.Order{}, false
+	}
+	return e.LookupOrder(orderID)
+}
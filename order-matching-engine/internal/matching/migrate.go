@@ -0,0 +1,103 @@
+package matching
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Symbol Migration
+//
+// A symbol moves between shards in three engine steps, each run on the
+// processor goroutine of its shard while order entry for the symbol is
+// paused:
+//
+//	source: ExportSymbol ──transfer──▶ target: ImportSymbol ──▶ source: ReleaseSymbol
+//
+// Export only copies the book, so if the transfer fails the source simply
+// carries on trading it. Import rebuilds the book on the target in queue
+// order, so every order keeps its time priority. Only once the target has
+// it does the source release its copy and remember where it went.
+
+// ExportSymbol returns a copy of a symbol's resting orders in queue order.
+// The book itself is left untouched.
+func (e *Engine) ExportSymbol(symbol string) ([]orders.Order, error) {
+	book := e.orderBooks[symbol]
+	if book == nil {
+		return nil, fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	return restingOrders(book), nil
+}
+
+// ImportSymbol loads a book exported by another shard. The symbol's book
+// must be empty (or not exist yet). The order ID counter is advanced past
+// the imported orders, so IDs issued here never collide with them.
+func (e *Engine) ImportSymbol(symbol string, resting []orders.Order) error {
+	e.AddSymbol(symbol)
+	book := e.orderBooks[symbol]
+	if n := book.TotalOrders(); n > 0 {
+		return fmt.Errorf("%s already has %d resting orders", symbol, n)
+	}
+
+	var maxID uint64
+	for i := range resting {
+		order := resting[i] // Copy: the engine owns its orders
+		if order.Symbol != symbol {
+			return fmt.Errorf("order %d is for %s, not %s", order.ID, order.Symbol, symbol)
+		}
+		if err := book.RestoreOrder(&order); err != nil {
+			return fmt.Errorf("import %s: %w", symbol, err)
+		}
+		e.trackSession(&order)
+		e.history.accepted(&order)
+		maxID = max64(maxID, order.ID)
+	}
+	advance(&e.orderID, maxID)
+	delete(e.moved, symbol) // Moving back to a shard it once left
+	return nil
+}
+
+// ReleaseSymbol drops a symbol whose book now lives on target and records
+// the move. Returns the number of resting orders released.
+func (e *Engine) ReleaseSymbol(symbol, target string) int {
+	released := 0
+	if book := e.orderBooks[symbol]; book != nil {
+		for _, order := range restingOrders(book) {
+			e.untrackSession(&order)
+			e.history.forget(&order)
+			released++
+		}
+		delete(e.orderBooks, symbol)
+	}
+	e.moved[symbol] = target
+	return released
+}
+
+// MovedSymbols returns the symbols released to other shards: symbol ->
+// target.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) MovedSymbols() map[string]string {
+	moved := make(map[string]string, len(e.moved))
+	for symbol, target := range e.moved {
+		moved[symbol] = target
+	}
+	return moved
+}
+
+// RestoreMoved re-applies releases captured by MovedSymbols, dropping the
+// books of symbols that live elsewhere now.
+//
+// Must be called before the engine processes its first order.
+func (e *Engine) RestoreMoved(moved map[string]string) {
+	symbols := make([]string, 0, len(moved))
+	for symbol := range moved {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		e.ReleaseSymbol(symbol, moved[symbol])
+	}
+}
@@ -0,0 +1,112 @@
+package matching
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Pegged Orders
+//
+// A pegged order has no price of its own. The engine prices it from the
+// book's reference prices - the best bid and ask set by unpegged orders -
+// and re-prices it whenever an order changes them:
+//
+//	MIDPOINT   buy at floor((bid+ask)/2), sell at ceil((bid+ask)/2),
+//	           rounded the same way to the symbol's tick (see SetTickSize)
+//	PRIMARY    buy at the best bid, sell at the best ask
+//
+// PegLimit, if set, caps the price: a buy never goes above it, a sell never
+// below it.
+//
+// Pegs ignore each other when finding the reference, otherwise a primary
+// peg that became the best bid would follow itself. Rounding the midpoint
+// away from the other side means a peg never crosses an unpegged order, so
+// pegs only trade on re-pricing against other pegs: two midpoint pegs meet
+// when the spread is an even number of ticks.
+//
+// Re-pricing runs at the end of every ProcessOrder on the order's book, in
+// order ID sequence, all inside the processor goroutine - so it replays
+// exactly. A re-priced peg moves to the back of its new level, like any
+// price change. Cancels do not re-price: removing an order can only widen
+// the spread, which leaves every peg inside it, and the next order on the
+// book brings them up to date. A peg whose reference side empties keeps its
+// last price.
+//
+// Pegs are displayed at their current price. A dark midpoint book, where
+// pegs are hidden from market data, would build on this by excluding them
+// from depth.
+
+// pegPrice computes a pegged order's price from the book's reference
+// prices, on a grid of tick. Returns false if the book has no reference
+// for it.
+func pegPrice(order *orders.Order, book *orderbook.OrderBook, tick int64) (int64, bool) {
+	var price int64
+	switch order.Peg {
+	case orders.PegPrimary:
+		best, ok := book.ReferencePrice(order.Side)
+		if !ok {
+			return 0, false
+		}
+		price = best
+
+	case orders.PegMidpoint:
+		bid, okBid := book.ReferencePrice(orders.SideBuy)
+		ask, okAsk := book.ReferencePrice(orders.SideSell)
+		if !okBid || !okAsk {
+			return 0, false
+		}
+		price = (bid + ask) / 2 // Buys round down
+		price -= price % tick
+		if order.Side == orders.SideSell {
+			price = (bid + ask + 1) / 2 // Sells round up
+			if rem := price % tick; rem != 0 {
+				price += tick - rem
+			}
+		}
+
+	default:
+		return 0, false
+	}
+
+	if order.PegLimit > 0 {
+		if order.Side == orders.SideBuy && price > order.PegLimit {
+			price = order.PegLimit
+		}
+		if order.Side == orders.SideSell && price < order.PegLimit {
+			price = order.PegLimit
+		}
+	}
+	return price, true
+}
+
+// repricePegs moves every pegged order in the book whose reference has
+// changed to its new price, matching it on the way in. Fills and reports
+// are appended to result.
+//
+// All moving pegs leave the book before any re-enters, so a peg never
+// trades with another at the stale price it is about to leave.
+func (e *Engine) repricePegs(book *orderbook.OrderBook, result *orders.ExecutionResult) {
+	var moving []*orders.Order
+	for _, order := range book.PeggedOrders() {
+		price, ok := pegPrice(order, book, e.tickSize(book.Symbol()))
+		if !ok || price == order.Price {
+			continue
+		}
+		book.CancelOrder(order.ID)
+		order.Price = price
+		moving = append(moving, order)
+	}
+
+	for _, order := range moving {
+		fills, reports := e.matchOrder(order, book)
+		result.Fills = append(result.Fills, fills...)
+		result.Reports = append(result.Reports, reports...)
+
+		if order.IsFilled() {
+			e.untrackSession(order)
+			e.history.complete(order)
+			continue
+		}
+		book.AddOrder(order)
+	}
+}
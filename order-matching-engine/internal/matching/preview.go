@@ -0,0 +1,82 @@
+package matching
+
+import (
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Execution Preview
+//
+// PreviewOrder answers "what would this order get if it arrived now?"
+// without placing it. The order runs through a scratch engine holding a
+// copy of its symbol's book, with the symbol's tick size, allocation and
+// auction call, so the preview takes the same path a real order would:
+// FIFO or pro-rata, iceberg reserve, FOK all-or-nothing, pegs priced off
+// the book. The live books, ID counters, sessions and order history are
+// never touched.
+//
+//	book ──copy──▶ scratch engine ──ProcessOrder──▶ fills, resting qty
+//
+// Slippage is measured against the touch: how far the average fill price
+// is past the best opposite price when the order arrived, per share. A
+// preview is only a picture of the book as it was; nothing holds the
+// liquidity for an order sent after it. Copying the book costs time in
+// proportion to its size, which is the price of matching exactly.
+
+// Preview is what an order would do against the book as it stands.
+type Preview struct {
+	// Result holds the fills, the quantity that would rest and the reject
+	// reason, if the engine would reject or cancel the order. Its order is
+	// a copy; the ID it was given means nothing.
+	Result *orders.ExecutionResult
+
+	BestPrice int64 // Best opposite price before the order (0 if that side is empty)
+	FilledQty int64
+	AvgPrice  int64 // Average fill price, as the order would report it (0 if nothing fills)
+	Notional  int64 // Sum of price * quantity over the fills
+	Slippage  int64 // AvgPrice past BestPrice per share, against the order (0 if nothing fills)
+}
+
+// PreviewOrder simulates order against a copy of its symbol's book. The
+// order itself is not modified. Must be called from the processor goroutine
+// (or before it starts).
+func (e *Engine) PreviewOrder(order orders.Order) *Preview {
+	preview := &Preview{}
+	if reason := e.validateOrder(&order); reason != "" {
+		order.Status = orders.OrderStatusRejected
+		preview.Result = &orders.ExecutionResult{Order: &order, Fills: make([]orders.Fill, 0), RejectReason: reason}
+		return preview
+	}
+
+	book := e.orderBooks[order.Symbol]
+	if order.Side == orders.SideBuy {
+		if level := book.GetBestAsk(); level != nil {
+			preview.BestPrice = level.Price
+		}
+	} else if level := book.GetBestBid(); level != nil {
+		preview.BestPrice = level.Price
+	}
+
+	scratch := NewEngine()
+	scratch.AddSymbol(order.Symbol)
+	scratch.SetTickSize(order.Symbol, e.tickSize(order.Symbol))
+	scratch.SetAllocation(order.Symbol, e.allocations[order.Symbol])
+	if refPrice, ok := e.auctions[order.Symbol]; ok {
+		scratch.auctions[order.Symbol] = refPrice
+	}
+	scratch.ImportSymbol(order.Symbol, restingOrders(book)) // Copied from a valid book
+	scratch.RestoreIDCounters(e.IDCounters())
+	scratch.SetTime(e.now)
+
+	order.ID = 0
+	preview.Result = scratch.ProcessOrder(&order)
+	preview.FilledQty = order.FilledQty
+	preview.Notional = order.FilledNotional
+	preview.AvgPrice = order.AvgFillPrice()
+	if preview.FilledQty > 0 {
+		preview.Slippage = preview.AvgPrice - preview.BestPrice
+		if order.Side == orders.SideSell {
+			preview.Slippage = -preview.Slippage
+		}
+	}
+	return preview
+}
@@ -0,0 +1,99 @@
+package matching
+
+import (
+	"sync/atomic"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// IDCounters holds the engine's ID high-water marks.
+//
+// Why persist these?
+// Order and trade IDs are the join keys for everything downstream (clearing,
+// drop copies, client order tracking). If a restarted engine starts counting
+// from zero again it reissues IDs that already exist, silently corrupting
+// every system keyed on them.
+type IDCounters struct {
+	OrderID     uint64 // Last issued order ID
+	TradeID     uint64 // Last issued trade ID
+	SequenceNum uint64 // Last issued engine sequence number
+}
+
+// IDCounters returns the current ID high-water marks.
+func (e *Engine) IDCounters() IDCounters {
+	return IDCounters{
+		OrderID:     atomic.LoadUint64(&e.orderID),
+		TradeID:     atomic.LoadUint64(&e.tradeID),
+		SequenceNum: atomic.LoadUint64(&e.sequenceNum),
+	}
+}
+
+// RestoreIDCounters advances the engine's counters to at least the given
+// high-water marks. Counters never move backwards, so restoring from a stale
+// source is harmless.
+//
+// Must be called before the engine processes its first order.
+func (e *Engine) RestoreIDCounters(c IDCounters) {
+	advance(&e.orderID, c.OrderID)
+	advance(&e.tradeID, c.TradeID)
+	advance(&e.sequenceNum, c.SequenceNum)
+}
+
+// advance raises *addr to v if v is larger.
+func advance(addr *uint64, v uint64) {
+	for {
+		current := atomic.LoadUint64(addr)
+		if v <= current || atomic.CompareAndSwapUint64(addr, current, v) {
+			return
+		}
+	}
+}
+
+// RecoverIDCounters derives ID high-water marks by replaying the event log.
+//
+// Derivation:
+//   - OrderID: max OrderID over NewOrderEvent, OrderCancelledEvent and the
+//     orders of SymbolImportedEvent (issued by another shard, but still
+//     never to be reissued here)
+//   - TradeID: max TradeID over FillEvent
+//   - SequenceNum: number of NewOrderEvents (one sequence per accepted order)
+//     plus re-queued OrderReplacedEvents (a re-queue takes a new sequence)
+func RecoverIDCounters(eventLog *events.EventLog) (IDCounters, error) {
+	var c IDCounters
+
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		c.observe(event)
+		return nil
+	})
+
+	return c, err
+}
+
+// observe advances the counters past one logged event.
+func (c *IDCounters) observe(event interface{}) {
+	switch e := event.(type) {
+	case *events.NewOrderEvent:
+		c.OrderID = max64(c.OrderID, e.OrderID)
+		c.SequenceNum++
+	case *events.OrderCancelledEvent:
+		c.OrderID = max64(c.OrderID, e.OrderID)
+	case *events.OrderReplacedEvent:
+		if !e.PriorityKept {
+			c.SequenceNum++
+		}
+	case *events.FillEvent:
+		c.TradeID = max64(c.TradeID, e.TradeID)
+		c.OrderID = max64(c.OrderID, max64(e.MakerOrderID, e.TakerOrderID))
+	case *events.SymbolImportedEvent:
+		for i := range e.Orders {
+			c.OrderID = max64(c.OrderID, e.Orders[i].ID)
+		}
+	}
+}
+
+func max64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Cancel/Replace (Order Modification)
+//
+// Replacing an order changes its price and/or total quantity atomically: the
+// client never has a window where the old order is gone and the new one is
+// not yet in the book, as it would with a cancel followed by a new order.
+//
+// Whether the order keeps its place in the queue follows the usual exchange
+// rule - priority is only kept if the change cannot hurt anyone behind it:
+//
+//	Quantity reduction, same price  → amended in place, keeps time priority
+//	Price change or size increase   → re-queued at the back, may match on entry
+//
+// The order keeps its ID, fills and average price across a replace.
+
+// ReplaceRequest describes a change to a resting order.
+type ReplaceRequest struct {
+	Symbol    string
+	OrderID   uint64
+	Side      orders.Side // Must match the resting order
+	AccountID string      // Must match the resting order
+	Price     int64       // New limit price
+	Quantity  int64       // New total quantity, including any already filled
+}
+
+// ReplaceResult is the outcome of a replace.
+type ReplaceResult struct {
+	Result       *orders.ExecutionResult // Fills if the re-queued order matched
+	OldPrice     int64
+	OldQuantity  int64
+	PriorityKept bool // True if amended in place
+}
+
+// ReplaceOrder changes the price and/or total quantity of a resting order.
+func (e *Engine) ReplaceOrder(req ReplaceRequest) (*ReplaceResult, error) {
+	book := e.orderBooks[req.Symbol]
+	if book == nil {
+		return nil, fmt.Errorf("unknown symbol: %s", req.Symbol)
+	}
+	order := book.GetOrder(req.OrderID)
+	if order == nil {
+		return nil, fmt.Errorf("order %d not found", req.OrderID)
+	}
+	if order.Side != req.Side || order.AccountID != req.AccountID {
+		return nil, fmt.Errorf("order %d does not match side and account", req.OrderID)
+	}
+	if order.IsPegged() {
+		return nil, fmt.Errorf("order %d is pegged: the engine sets its price, cancel and re-enter to change it", req.OrderID)
+	}
+	if req.Price <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+	if req.Quantity <= order.FilledQty {
+		return nil, fmt.Errorf("quantity %d must exceed filled quantity %d", req.Quantity, order.FilledQty)
+	}
+
+	replaced := &ReplaceResult{
+		OldPrice:    order.Price,
+		OldQuantity: order.Quantity,
+	}
+
+	if req.Price == order.Price && req.Quantity <= order.Quantity {
+		book.ReduceOrder(order.ID, req.Quantity)
+		replaced.PriorityKept = true
+		replaced.Result = &orders.ExecutionResult{
+			Order:      order,
+			Fills:      make([]orders.Fill, 0),
+			Accepted:   true,
+			RestingQty: order.RemainingQty(),
+		}
+		return replaced, nil
+	}
+
+	// Re-queue: take the order out and run it through the book again as if it
+	// had just arrived, so a new price that crosses the spread trades now
+	book.CancelOrder(order.ID)
+	e.untrackSession(order)
+	order.Price = req.Price
+	order.Quantity = req.Quantity
+	order.Timestamp = e.time()
+	replaced.Result = e.ProcessOrder(order)
+	return replaced, nil
+}
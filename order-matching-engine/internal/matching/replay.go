@@ -0,0 +1,187 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Tail Replay
+//
+// A snapshot restores the books and ID counters as of one event log
+// sequence. The events after it are re-executed rather than patched in:
+// matching is deterministic, so running each logged order, cancel and
+// replace through the engine again - with the restored counters, at the
+// time the event was logged - yields the same fills, the same trade IDs,
+// the same timestamps and the same queues.
+//
+//	snapshot (books + counters @ seq N) ──▶ replay events N+1.. ──▶ live
+//
+// The fills the log recorded are not applied; they are checked against the
+// fills the replay produces. A mismatch means the snapshot and the log
+// disagree (or an event was dropped), and replay stops rather than build a
+// book nobody traded against. Once checked, the logged fill is handed on
+// to post-trade consumers: it carries the fees charged at the time, which
+// the matching engine does not compute.
+
+// Replayer re-executes logged events against an engine.
+//
+// The engine must hold exactly the state as of the event before the first
+// one replayed, ID counters included.
+type Replayer struct {
+	engine   *Engine
+	expected []orders.Fill // Fills the last event produced, not yet matched against the log
+	events   int
+	counters IDCounters // High-water marks seen in the replayed events
+}
+
+// NewReplayer creates a replayer for engine.
+func NewReplayer(engine *Engine) *Replayer {
+	return &Replayer{engine: engine}
+}
+
+// Apply re-executes one logged event. Returns the fill a FillEvent recorded
+// once the replay has reproduced it, for post-trade consumers such as
+// clearing.
+func (r *Replayer) Apply(event interface{}) ([]orders.Fill, error) {
+	r.counters.observe(event)
+
+	if logged, ok := event.(*events.FillEvent); ok {
+		if err := r.checkFill(logged); err != nil {
+			return nil, err
+		}
+		return []orders.Fill{{
+			TradeID:        logged.TradeID,
+			MakerOrderID:   logged.MakerOrderID,
+			TakerOrderID:   logged.TakerOrderID,
+			Price:          logged.Price,
+			Quantity:       logged.Quantity,
+			Timestamp:      logged.Timestamp,
+			Symbol:         logged.Symbol,
+			MakerAccountID: logged.MakerAccountID,
+			TakerAccountID: logged.TakerAccountID,
+			TakerSide:      logged.TakerSide,
+			MakerFee:       logged.MakerFee,
+			TakerFee:       logged.TakerFee,
+		}}, nil
+	}
+	r.expected = r.expected[:0]
+	r.engine.SetTime(events.TimestampOf(event))
+
+	var fills []orders.Fill
+	switch e := event.(type) {
+	case *events.NewOrderEvent:
+		r.engine.AddSymbol(e.Symbol) // It was accepted, so it traded then
+		result := r.engine.ProcessOrder(&orders.Order{
+			ID:            e.OrderID,
+			Symbol:        e.Symbol,
+			Side:          e.Side,
+			Type:          e.OrderType,
+			Price:         e.Price,
+			Quantity:      e.Quantity,
+			DisplayQty:    e.DisplayQty,
+			Peg:           e.Peg,
+			PegLimit:      e.PegLimit,
+			TimeInForce:   e.TimeInForce,
+			AccountID:     e.AccountID,
+			ClientOrderID: e.ClientOrderID,
+			SessionID:     e.SessionID,
+			Timestamp:     e.Timestamp,
+		})
+		if !result.Accepted {
+			return nil, fmt.Errorf("order %d rejected on replay: %s", e.OrderID, result.RejectReason)
+		}
+		fills = result.Fills
+
+	case *events.OrderCancelledEvent:
+		if _, err := r.engine.CancelOrder(e.Symbol, e.OrderID); err != nil {
+			return nil, fmt.Errorf("cancel on replay: %w", err)
+		}
+
+	case *events.OrderReplacedEvent:
+		order := r.engine.GetOrder(e.Symbol, e.OrderID)
+		if order == nil {
+			return nil, fmt.Errorf("replace on replay: order %d not found", e.OrderID)
+		}
+		replaced, err := r.engine.ReplaceOrder(ReplaceRequest{
+			Symbol:    e.Symbol,
+			OrderID:   e.OrderID,
+			Side:      order.Side,
+			AccountID: order.AccountID,
+			Price:     e.NewPrice,
+			Quantity:  e.NewQuantity,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("replace on replay: %w", err)
+		}
+		fills = replaced.Result.Fills
+
+	case *events.SymbolImportedEvent:
+		if err := r.engine.ImportSymbol(e.Symbol, e.Orders); err != nil {
+			return nil, fmt.Errorf("import on replay: %w", err)
+		}
+
+	case *events.SymbolMovedEvent:
+		r.engine.ReleaseSymbol(e.Symbol, e.Target)
+
+	case *events.AuctionStartedEvent:
+		if err := r.engine.StartAuction(e.Symbol, e.RefPrice); err != nil {
+			return nil, fmt.Errorf("auction on replay: %w", err)
+		}
+
+	case *events.AuctionUncrossedEvent:
+		uncrossed, err := r.engine.Uncross(e.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("uncross on replay: %w", err)
+		}
+		fills = uncrossed.Fills
+
+	default:
+		return nil, nil // Not a state change
+	}
+
+	r.events++
+	r.expected = append(r.expected, fills...)
+	return nil, nil
+}
+
+// checkFill verifies a logged fill is the next one the replay produced.
+func (r *Replayer) checkFill(logged *events.FillEvent) error {
+	if len(r.expected) == 0 {
+		return fmt.Errorf("replay diverged: logged trade %d was not reproduced", logged.TradeID)
+	}
+	fill := r.expected[0]
+	r.expected = r.expected[1:]
+	if fill.TradeID != logged.TradeID || fill.MakerOrderID != logged.MakerOrderID ||
+		fill.TakerOrderID != logged.TakerOrderID || fill.Price != logged.Price || fill.Quantity != logged.Quantity {
+		return fmt.Errorf("replay diverged: logged trade %d (%d@%d, maker %d) reproduced as trade %d (%d@%d, maker %d)",
+			logged.TradeID, logged.Quantity, logged.Price, logged.MakerOrderID,
+			fill.TradeID, fill.Quantity, fill.Price, fill.MakerOrderID)
+	}
+	return nil
+}
+
+// Skip passes over a logged event without re-executing it, e.g. one for a
+// symbol whose log is damaged. The engine's ID counters still advance past
+// it, so the events replayed after it reproduce the IDs they were logged
+// with, and none of its IDs is reissued.
+func (r *Replayer) Skip(event interface{}) {
+	r.counters.observe(event)
+	counters := r.engine.IDCounters()
+	counters.observe(event)
+	r.engine.RestoreIDCounters(counters)
+	r.expected = r.expected[:0]
+}
+
+// Events returns the number of state-changing events replayed.
+func (r *Replayer) Events() int {
+	return r.events
+}
+
+// Counters returns the ID high-water marks seen in the replayed events.
+// Restoring them too guarantees no ID the log holds is reissued, even one
+// the replay did not reproduce.
+func (r *Replayer) Counters() IDCounters {
+	return r.counters
+}
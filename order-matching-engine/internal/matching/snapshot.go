@@ -0,0 +1,65 @@
+package matching
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orderbook"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// RestingOrders returns a copy of every resting order, by symbol, in queue
+// order: bids best price first, then asks best price first, FIFO within each
+// level. Restoring them in this order rebuilds identical books.
+//
+// Must be called from the processor goroutine (or before it starts).
+func (e *Engine) RestingOrders() map[string][]orders.Order {
+	books := make(map[string][]orders.Order, len(e.orderBooks))
+	for symbol, book := range e.orderBooks {
+		if resting := restingOrders(book); len(resting) > 0 {
+			books[symbol] = resting
+		}
+	}
+	return books
+}
+
+// restingOrders copies one book's resting orders in queue order.
+func restingOrders(book *orderbook.OrderBook) []orders.Order {
+	var resting []orders.Order
+	for _, levels := range [][]*orderbook.PriceLevel{book.GetBidDepth(0), book.GetAskDepth(0)} {
+		for _, level := range levels {
+			for node := level.Head(); node != nil; node = node.Next() {
+				resting = append(resting, *node.Order)
+			}
+		}
+	}
+	return resting
+}
+
+// RestoreOrders loads resting orders captured by RestingOrders into empty
+// books, adding any symbol the engine doesn't know yet. Session tracking and
+// client order IDs are restored too, so cancel-on-disconnect and status
+// lookups still cover restored orders.
+//
+// Must be called before the engine processes its first order.
+func (e *Engine) RestoreOrders(books map[string][]orders.Order) error {
+	symbols := make([]string, 0, len(books))
+	for symbol := range books {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		e.AddSymbol(symbol)
+		book := e.orderBooks[symbol]
+		for i := range books[symbol] {
+			order := books[symbol][i] // Copy: the engine owns its orders
+			if err := book.RestoreOrder(&order); err != nil {
+				return fmt.Errorf("restore %s: %w", symbol, err)
+			}
+			e.trackSession(&order)
+			e.history.accepted(&order)
+		}
+	}
+	return nil
+}
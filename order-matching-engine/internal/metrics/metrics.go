@@ -0,0 +1,358 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format, for GET /metrics.
+//
+// It is deliberately small: no client library, just what the server needs.
+//
+//	Counter      monotonically increasing, e.g. orders accepted
+//	Gauge        sampled when scraped from a function, e.g. occupancy
+//	Histogram    observations counted into fixed buckets, e.g. latency
+//
+// Each is registered once under a name, optionally with label names. The
+// children for label values are created on first use and then updated with
+// atomics only, so recording on the order path never takes a lock once a
+// label combination has been seen.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is anything a registry can write.
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	names   map[string]bool
+	metrics []metric
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: " + name + " registered twice")
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// family is the name, help and children by label values shared by every
+// metric type.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu       sync.RWMutex
+	children map[string]interface{}
+	values   map[string][]string
+}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	return &family{
+		name:     name,
+		help:     help,
+		kind:     kind,
+		labels:   labels,
+		children: make(map[string]interface{}),
+		values:   make(map[string][]string),
+	}
+}
+
+// child returns the child for values, creating it with create.
+func (f *family) child(values []string, create func() interface{}) interface{} {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c
+	}
+	c = create()
+	f.children[key] = c
+	f.values[key] = append([]string(nil), values...)
+	return c
+}
+
+// each calls fn with every child in label order.
+func (f *family) each(fn func(labels string, c interface{})) {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.children))
+	for key := range f.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]interface{}, len(keys))
+	values := make([][]string, len(keys))
+	for i, key := range keys {
+		children[i], values[i] = f.children[key], f.values[key]
+	}
+	f.mu.RUnlock()
+
+	for i := range keys {
+		fn(formatLabels(f.labels, values[i]), children[i])
+	}
+}
+
+func (f *family) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+// formatLabels renders name="value" pairs, without braces.
+func formatLabels(names, values []string) string {
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeValue(values[i]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// braces wraps labels for a sample line, or returns "" for none.
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ============================================================================
+// COUNTERS
+// ============================================================================
+
+// Counter is a value that only goes up.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// CounterVec is a counter per combination of label values.
+type CounterVec struct {
+	f *family
+}
+
+// NewCounter registers a counter with no labels.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{f: newFamily(name, help, "counter", labels)}
+	r.register(name, v)
+	return v
+}
+
+// With returns the counter for the label values, in label name order.
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.child(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.f.header(w)
+	v.f.each(func(labels string, c interface{}) {
+		fmt.Fprintf(w, "%s%s %d\n", v.f.name, braces(labels), c.(*Counter).Value())
+	})
+}
+
+// ============================================================================
+// GAUGES
+// ============================================================================
+
+// gaugeFunc is a gauge (or counter kept elsewhere) read from a function
+// when scraped.
+type gaugeFunc struct {
+	f      *family
+	sample func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc registers a gauge sampled from fn when scraped.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.NewGaugeVecFunc(name, help, nil, func(emit func(float64, ...string)) { emit(fn()) })
+}
+
+// NewGaugeVecFunc registers a gauge with labels, sampled when scraped: fn
+// calls emit once per label combination, e.g. once per shard.
+func (r *Registry) NewGaugeVecFunc(name, help string, labels []string, fn func(emit func(value float64, labelValues ...string))) {
+	r.register(name, &gaugeFunc{f: newFamily(name, help, "gauge", labels), sample: fn})
+}
+
+// NewCounterFunc registers a counter kept elsewhere, read when scraped.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.NewCounterVecFunc(name, help, nil, func(emit func(float64, ...string)) { emit(fn()) })
+}
+
+// NewCounterVecFunc registers counters kept elsewhere with labels, read
+// when scraped like NewGaugeVecFunc.
+func (r *Registry) NewCounterVecFunc(name, help string, labels []string, fn func(emit func(value float64, labelValues ...string))) {
+	r.register(name, &gaugeFunc{f: newFamily(name, help, "counter", labels), sample: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.f.header(w)
+	g.sample(func(value float64, labelValues ...string) {
+		fmt.Fprintf(w, "%s%s %s\n", g.f.name, braces(formatLabels(g.f.labels, labelValues)), formatFloat(value))
+	})
+}
+
+// ============================================================================
+// HISTOGRAMS
+// ============================================================================
+
+// DefaultLatencyBuckets are upper bounds in seconds, from 50µs to 5s.
+var DefaultLatencyBuckets = []float64{
+	0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5,
+}
+
+// Histogram counts observations into buckets.
+type Histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // One per bound, then +Inf; not cumulative
+	sum    atomic.Uint64   // float64 bits
+	count  atomic.Uint64
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// HistogramVec is a histogram per combination of label values.
+type HistogramVec struct {
+	f      *family
+	bounds []float64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds
+// (ascending) and label names.
+func (r *Registry) NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(bounds) {
+		panic("metrics: " + name + " buckets not ascending")
+	}
+	v := &HistogramVec{f: newFamily(name, help, "histogram", labels), bounds: bounds}
+	r.register(name, v)
+	return v
+}
+
+// With returns the histogram for the label values, in label name order.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.child(values, func() interface{} {
+		return &Histogram{bounds: v.bounds, counts: make([]atomic.Uint64, len(v.bounds)+1)}
+	}).(*Histogram)
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.f.header(w)
+	v.f.each(func(labels string, c interface{}) {
+		h := c.(*Histogram)
+		sep := ""
+		if labels != "" {
+			sep = ","
+		}
+		var cumulative uint64
+		for i := range h.counts {
+			cumulative += h.counts[i].Load()
+			le := "+Inf"
+			if i < len(h.bounds) {
+				le = formatFloat(h.bounds[i])
+			}
+			fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", v.f.name, labels, sep, le, cumulative)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.f.name, braces(labels), formatFloat(math.Float64frombits(h.sum.Load())))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.f.name, braces(labels), cumulative)
+	})
+}
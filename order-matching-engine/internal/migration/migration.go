@@ -0,0 +1,261 @@
+// Package migration moves a symbol from one engine shard to another while
+// both keep trading everything else.
+//
+// Workflow (driven by the source shard's admin endpoint):
+//
+//	quiesce    Gate.Pause: new requests for the symbol wait at the gate,
+//	           requests already admitted are answered
+//	snapshot   the source processor exports the book in queue order
+//	transfer   Send POSTs it, with the instrument, to the target
+//	replay     the target rebuilds the book order by order and logs it
+//	re-open    the source releases its copy; Gate.Complete lets the
+//	           waiting requests through, to be forwarded to the target
+//
+// If anything fails before the target has the book, Gate.Resume re-opens
+// the symbol on the source as if nothing happened.
+//
+// Acks are paused, not lost:
+// A request that arrives mid-migration is neither rejected nor sequenced
+// against a book that is about to leave. It waits (up to the gate's wait)
+// and then goes wherever the symbol ended up, so the client gets its ack
+// from the shard that actually traded it.
+package migration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+)
+
+// ImportPath is the target shard's endpoint for a transferred book.
+const ImportPath = "/admin/symbol/import"
+
+// ErrPaused is returned by Enter when a symbol stays paused for longer than
+// the gate's wait. The request was not sequenced and is safe to retry.
+var ErrPaused = errors.New("symbol migration in progress, please retry")
+
+// MovedError is returned by Enter for a symbol that now trades on another
+// shard. Requests for it should be forwarded to Target.
+type MovedError struct {
+	Symbol string
+	Target string
+}
+
+func (e *MovedError) Error() string {
+	return fmt.Sprintf("%s has moved to %s", e.Symbol, e.Target)
+}
+
+// Payload is a symbol's book in transit between shards.
+type Payload struct {
+	Symbol     string             `json:"symbol"`
+	Source     string             `json:"source"` // Shard ID of the source
+	Instrument refdata.Instrument `json:"instrument"`
+	Orders     []orders.Order     `json:"orders"` // Queue order
+}
+
+// Gate holds order entry for symbols being migrated and remembers where
+// migrated symbols went. It is safe for concurrent use.
+type Gate struct {
+	mu       sync.Mutex
+	wait     time.Duration
+	paused   map[string]chan struct{} // Closed when the symbol re-opens
+	inflight map[string]int           // Admitted requests not yet answered
+	drained  map[string]chan struct{} // Closed when inflight reaches 0 during Pause
+	moved    map[string]string        // Symbol -> target shard
+}
+
+// NewGate creates a gate where requests wait at most wait for a paused
+// symbol, and Pause waits at most wait for admitted requests to drain.
+func NewGate(wait time.Duration) *Gate {
+	return &Gate{
+		wait:     wait,
+		paused:   make(map[string]chan struct{}),
+		inflight: make(map[string]int),
+		drained:  make(map[string]chan struct{}),
+		moved:    make(map[string]string),
+	}
+}
+
+// Enter admits a request touching symbols, waiting while any of them is
+// paused. leave must be called once the request has been answered. Returns
+// a *MovedError if a symbol has moved, or ErrPaused if the wait ran out.
+func (g *Gate) Enter(symbols ...string) (leave func(), err error) {
+	timer := time.NewTimer(g.wait)
+	defer timer.Stop()
+
+	g.mu.Lock()
+	for {
+		pause := g.pausedChan(symbols)
+		if pause == nil {
+			break
+		}
+		g.mu.Unlock()
+		select {
+		case <-pause:
+		case <-timer.C:
+			return nil, ErrPaused
+		}
+		g.mu.Lock()
+	}
+	defer g.mu.Unlock()
+
+	for _, symbol := range symbols {
+		if target, moved := g.moved[symbol]; moved {
+			return nil, &MovedError{Symbol: symbol, Target: target}
+		}
+	}
+	for _, symbol := range symbols {
+		g.inflight[symbol]++
+	}
+	return func() { g.leave(symbols) }, nil
+}
+
+// pausedChan returns the re-open channel of the first paused symbol, or nil.
+// Caller must hold the lock.
+func (g *Gate) pausedChan(symbols []string) chan struct{} {
+	for _, symbol := range symbols {
+		if ch := g.paused[symbol]; ch != nil {
+			return ch
+		}
+	}
+	return nil
+}
+
+// leave marks an admitted request as answered.
+func (g *Gate) leave(symbols []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, symbol := range symbols {
+		g.inflight[symbol]--
+		if g.inflight[symbol] > 0 {
+			continue
+		}
+		delete(g.inflight, symbol)
+		if ch := g.drained[symbol]; ch != nil {
+			close(ch)
+			delete(g.drained, symbol)
+		}
+	}
+}
+
+// Pause stops admitting requests for symbol and waits until every request
+// already admitted has been answered. The symbol stays paused until Resume
+// or Complete.
+func (g *Gate) Pause(symbol string) error {
+	g.mu.Lock()
+	if g.paused[symbol] != nil {
+		g.mu.Unlock()
+		return fmt.Errorf("%s is already being migrated", symbol)
+	}
+	if target, moved := g.moved[symbol]; moved {
+		g.mu.Unlock()
+		return &MovedError{Symbol: symbol, Target: target}
+	}
+	g.paused[symbol] = make(chan struct{})
+	var drained chan struct{}
+	if g.inflight[symbol] > 0 {
+		drained = make(chan struct{})
+		g.drained[symbol] = drained
+	}
+	g.mu.Unlock()
+
+	if drained == nil {
+		return nil
+	}
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(g.wait):
+		g.Resume(symbol)
+		return fmt.Errorf("requests for %s still in flight after %v", symbol, g.wait)
+	}
+}
+
+// Resume re-opens a paused symbol on this shard.
+func (g *Gate) Resume(symbol string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reopen(symbol)
+}
+
+// Complete records that symbol now trades on target and re-opens it, so
+// waiting requests are redirected there.
+func (g *Gate) Complete(symbol, target string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.moved[symbol] = target
+	g.reopen(symbol)
+}
+
+// reopen releases a symbol's waiters. Caller must hold the lock.
+func (g *Gate) reopen(symbol string) {
+	if ch := g.paused[symbol]; ch != nil {
+		close(ch)
+		delete(g.paused, symbol)
+	}
+	delete(g.drained, symbol)
+}
+
+// Adopt forgets that symbol moved away, when it is migrated back here.
+func (g *Gate) Adopt(symbol string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.moved, symbol)
+}
+
+// SetMoved records symbols that moved before a restart: symbol -> target.
+func (g *Gate) SetMoved(moved map[string]string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for symbol, target := range moved {
+		g.moved[symbol] = target
+	}
+}
+
+// Moved returns the symbols that moved to other shards: symbol -> target.
+func (g *Gate) Moved() map[string]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	moved := make(map[string]string, len(g.moved))
+	for symbol, target := range g.moved {
+		moved[symbol] = target
+	}
+	return moved
+}
+
+// Send transfers a book to the target shard's ImportPath. target is the
+// shard's base URL (e.g. http://shard-b:8080). Returns once the target has
+// rebuilt the book and queued it for its event log.
+func Send(ctx context.Context, client *http.Client, target string, payload *Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(target, "/")+ImportPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("target returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package orderbook
+
+import (
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Check verifies the book's internal consistency, returning the first
+// inconsistency found:
+//
+//   - each price level's TotalQty and HiddenQty equal the sums of its
+//     orders' displayed quantity and iceberg reserve, and its order and peg
+//     counts match its queue
+//   - levels are non-empty and in price order, and their orders have the
+//     level's price and side, something left to fill and something shown
+//   - the queue's links agree in both directions and with the level
+//   - the order, account and peg indexes hold exactly the resting orders
+//
+// The matching engine maintains all of these incrementally, so a violation
+// means a bug, not bad input. Check is O(orders): for debugging, the soak
+// test and the processor's debug mode (see disruptor/book_check.go), not
+// the order path.
+func (ob *OrderBook) Check() error {
+	resting := 0
+	pegged := 0
+	for _, side := range []orders.Side{orders.SideBuy, orders.SideSell} {
+		var err error
+		var prev *PriceLevel
+		ob.ForEachLevel(side, func(level *PriceLevel) bool {
+			ordered := prev == nil || level.Price < prev.Price
+			if side == orders.SideSell {
+				ordered = prev == nil || level.Price > prev.Price
+			}
+			if !ordered {
+				err = fmt.Errorf("%s %s level %s follows %s", ob.symbol, side,
+					orders.FormatPrice(level.Price), orders.FormatPrice(prev.Price))
+				return false
+			}
+			prev = level
+			var n, p int
+			n, p, err = ob.checkLevel(side, level)
+			resting += n
+			pegged += p
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if resting != len(ob.orders) {
+		return fmt.Errorf("%s: %d orders in the order index, %d resting", ob.symbol, len(ob.orders), resting)
+	}
+	if pegged != len(ob.pegged) {
+		return fmt.Errorf("%s: %d orders in the peg index, %d pegs resting", ob.symbol, len(ob.pegged), pegged)
+	}
+	accounts := 0
+	for account, ids := range ob.accounts {
+		for id := range ids {
+			node := ob.orders[id]
+			if node == nil || node.Order.AccountID != account {
+				return fmt.Errorf("%s: order %d is indexed under account %s but isn't resting for it", ob.symbol, id, account)
+			}
+		}
+		accounts += len(ids)
+	}
+	if accounts != resting {
+		return fmt.Errorf("%s: %d orders in the account index, %d resting", ob.symbol, accounts, resting)
+	}
+	return nil
+}
+
+// checkLevel checks one price level and its queue, returning how many
+// orders and pegs it holds.
+func (ob *OrderBook) checkLevel(side orders.Side, level *PriceLevel) (int, int, error) {
+	where := fmt.Sprintf("%s %s level %s", ob.symbol, side, orders.FormatPrice(level.Price))
+	if level.IsEmpty() {
+		return 0, 0, fmt.Errorf("%s is empty but still in the book", where)
+	}
+
+	var count, pegged int
+	var visible, hidden int64
+	var prev *OrderNode
+	for node := level.head; node != nil; node = node.next {
+		order := node.Order
+		switch {
+		case node.prev != prev:
+			return 0, 0, fmt.Errorf("%s: order %d's back link is broken", where, order.ID)
+		case node.level != level:
+			return 0, 0, fmt.Errorf("%s: order %d points at another level", where, order.ID)
+		case ob.orders[order.ID] != node:
+			return 0, 0, fmt.Errorf("%s: order %d is not in the order index", where, order.ID)
+		case order.Side != side || order.Price != level.Price:
+			return 0, 0, fmt.Errorf("%s: order %d is a %s at %s", where, order.ID, order.Side, orders.FormatPrice(order.Price))
+		case order.RemainingQty() <= 0 || order.VisibleQty() <= 0:
+			return 0, 0, fmt.Errorf("%s: order %d rests with %d remaining, %d shown", where, order.ID, order.RemainingQty(), order.VisibleQty())
+		}
+		if order.IsPegged() {
+			if _, ok := ob.pegged[order.ID]; !ok {
+				return 0, 0, fmt.Errorf("%s: pegged order %d is not in the peg index", where, order.ID)
+			}
+			pegged++
+		}
+		count++
+		visible += order.VisibleQty()
+		hidden += order.HiddenQty()
+		prev = node
+	}
+
+	switch {
+	case level.tail != prev:
+		return 0, 0, fmt.Errorf("%s: tail is not the last order", where)
+	case count != level.count || pegged != level.pegged:
+		return 0, 0, fmt.Errorf("%s: counts %d orders, %d pegged, but holds %d, %d", where, level.count, level.pegged, count, pegged)
+	case visible != level.TotalQty || hidden != level.HiddenQty:
+		return 0, 0, fmt.Errorf("%s: TotalQty %d/HiddenQty %d but orders sum to %d/%d",
+			where, level.TotalQty, level.HiddenQty, visible, hidden)
+	}
+	return count, pegged, nil
+}
@@ -2,6 +2,7 @@ package orderbook
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/rishav/order-matching-engine/internal/orders"
@@ -37,11 +38,21 @@ import (
 // 3. Price-Time Priority: Implemented via:
 //    - Red-black tree for price priority (best price first)
 //    - FIFO queue at each price level for time priority (first order first)
+//
+// 4. Account Index: Account ID -> set of live order IDs
+//    - Lets a trader list their resting orders without scanning the book
+//    - Maintained on every add, restore, cancel and fill
+//
+// 5. Peg Index: IDs of resting pegged orders
+//    - Lets the engine re-price pegs without scanning the book, and skip
+//      the work entirely in books that have none
 type OrderBook struct {
 	symbol string
 	bids   *RBTree             // Buy orders, sorted by price descending
 	asks   *RBTree             // Sell orders, sorted by price ascending
 	orders map[uint64]*OrderNode // Order ID -> Node for O(1) cancel
+	accounts map[string]map[uint64]struct{} // Account ID -> live order IDs
+	pegged map[uint64]struct{} // Live pegged order IDs
 }
 
 // NewOrderBook creates a new order book for the given symbol.
@@ -51,6 +62,8 @@ func NewOrderBook(symbol string) *OrderBook {
 		bids:   NewRBTree(true),  // descending: true (highest price first)
 		asks:   NewRBTree(false), // descending: false (lowest price first)
 		orders: make(map[uint64]*OrderNode),
+		accounts: make(map[string]map[uint64]struct{}),
+		pegged: make(map[uint64]struct{}),
 	}
 }
 
@@ -77,12 +90,38 @@ func (ob *OrderBook) AddOrder(order *orders.Order) error {
 		tree.Insert(level)
 	}
 
+	// Icebergs rest with their first slice on display
+	if order.IsIceberg() {
+		order.Replenish()
+	}
+
 	// Add order to the price level's queue
 	node := level.Append(order)
 
 	// Track order for O(1) cancellation
 	ob.orders[order.ID] = node
+	ob.track(order)
+
+	return nil
+}
+
+// RestoreOrder appends an order recovered from a snapshot to the back of
+// its price level exactly as it was, without the iceberg replenish AddOrder
+// does. Orders must be restored in their original queue order.
+// Time complexity: O(log P) where P = number of price levels
+func (ob *OrderBook) RestoreOrder(order *orders.Order) error {
+	if _, exists := ob.orders[order.ID]; exists {
+		return fmt.Errorf("order %d already exists", order.ID)
+	}
 
+	tree := ob.getTree(order.Side)
+	level := tree.Get(order.Price)
+	if level == nil {
+		level = NewPriceLevel(order.Price)
+		tree.Insert(level)
+	}
+	ob.orders[order.ID] = level.Append(order)
+	ob.track(order)
 	return nil
 }
 
@@ -102,8 +141,9 @@ func (ob *OrderBook) CancelOrder(orderID uint64) *orders.Order {
 	// Remove order from the queue
 	level.Remove(node)
 
-	// Remove from tracking map
+	// Remove from tracking maps
 	delete(ob.orders, orderID)
+	ob.untrack(order)
 
 	// If price level is empty, remove it from the tree
 	if level.IsEmpty() {
@@ -113,6 +153,79 @@ func (ob *OrderBook) CancelOrder(orderID uint64) *orders.Order {
 	return order
 }
 
+// ReplenishOrder displays the next slice of an iceberg order whose visible
+// slice has filled. The order moves to the back of its price level: a new
+// slice gets new time priority, exactly as if it were a new order, so
+// hiding size never buys queue position.
+// Returns false if the order is not in the book.
+// Time complexity: O(1)
+func (ob *OrderBook) ReplenishOrder(orderID uint64) bool {
+	node, exists := ob.orders[orderID]
+	if !exists {
+		return false
+	}
+
+	order := node.Order
+	level := node.level
+	level.Remove(node)
+	order.Replenish()
+	ob.orders[orderID] = level.Append(order)
+	return true
+}
+
+// FillOrder executes qty of a resting order at price, iceberg reserve
+// included, as an auction uncross does. A filled order leaves the book; an
+// iceberg whose displayed slice is used up shows its next one at the back
+// of its level. Returns false if the order is not in the book.
+// Time complexity: O(1), O(log P) if the price level becomes empty
+func (ob *OrderBook) FillOrder(orderID uint64, qty, price int64) bool {
+	node, exists := ob.orders[orderID]
+	if !exists {
+		return false
+	}
+
+	order := node.Order
+	level := node.level
+	level.TotalQty -= order.VisibleQty()
+	level.HiddenQty -= order.HiddenQty()
+	order.ApplyFill(qty, price)
+	level.TotalQty += order.VisibleQty()
+	level.HiddenQty += order.HiddenQty()
+
+	if order.IsFilled() {
+		ob.CancelOrder(orderID)
+	} else if order.VisibleQty() == 0 {
+		ob.ReplenishOrder(orderID)
+	}
+	return true
+}
+
+// ReduceOrder lowers a resting order's total quantity in place. The order
+// keeps its position in the queue: a smaller order takes nothing away from
+// the orders behind it. newQty must be above the order's filled quantity.
+// Returns false if the order is not in the book.
+// Time complexity: O(1)
+func (ob *OrderBook) ReduceOrder(orderID uint64, newQty int64) bool {
+	node, exists := ob.orders[orderID]
+	if !exists {
+		return false
+	}
+
+	order := node.Order
+	level := node.level
+	level.TotalQty -= order.VisibleQty()
+	level.HiddenQty -= order.HiddenQty()
+
+	order.Quantity = newQty
+	if order.ShownQty > order.RemainingQty() {
+		order.ShownQty = order.RemainingQty()
+	}
+
+	level.TotalQty += order.VisibleQty()
+	level.HiddenQty += order.HiddenQty()
+	return true
+}
+
 // GetOrder retrieves an order by ID.
 // Time complexity: O(1)
 func (ob *OrderBook) GetOrder(orderID uint64) *orders.Order {
@@ -123,6 +236,82 @@ func (ob *OrderBook) GetOrder(orderID uint64) *orders.Order {
 	return node.Order
 }
 
+// AccountOrders returns an account's live orders in this book, oldest
+// (lowest order ID) first.
+// Time complexity: O(k log k) where k = the account's live orders
+func (ob *OrderBook) AccountOrders(accountID string) []*orders.Order {
+	ids := ob.accounts[accountID]
+	if len(ids) == 0 {
+		return nil
+	}
+
+	live := make([]*orders.Order, 0, len(ids))
+	for id := range ids {
+		live = append(live, ob.orders[id].Order)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+	return live
+}
+
+// PeggedOrders returns the pegged orders resting in this book, oldest
+// (lowest order ID) first.
+// Time complexity: O(k log k) where k = the pegged orders
+func (ob *OrderBook) PeggedOrders() []*orders.Order {
+	if len(ob.pegged) == 0 {
+		return nil
+	}
+
+	pegged := make([]*orders.Order, 0, len(ob.pegged))
+	for id := range ob.pegged {
+		pegged = append(pegged, ob.orders[id].Order)
+	}
+	sort.Slice(pegged, func(i, j int) bool { return pegged[i].ID < pegged[j].ID })
+	return pegged
+}
+
+// ReferencePrice returns the best price on one side set by an unpegged
+// order. Pegs price themselves from it: a level made only of pegs is not a
+// market for them to follow, or they would chase each other.
+// Returns false if the side has no unpegged orders.
+// Time complexity: O(1) unless the top levels hold only pegs
+func (ob *OrderBook) ReferencePrice(side orders.Side) (int64, bool) {
+	var price int64
+	found := false
+	ob.ForEachLevel(side, func(level *PriceLevel) bool {
+		if level.HasUnpegged() {
+			price, found = level.Price, true
+			return false
+		}
+		return true
+	})
+	return price, found
+}
+
+// track adds a resting order to the account and peg indexes.
+func (ob *OrderBook) track(order *orders.Order) {
+	ids := ob.accounts[order.AccountID]
+	if ids == nil {
+		ids = make(map[uint64]struct{})
+		ob.accounts[order.AccountID] = ids
+	}
+	ids[order.ID] = struct{}{}
+	if order.IsPegged() {
+		ob.pegged[order.ID] = struct{}{}
+	}
+}
+
+// untrack removes an order that left the book from the account and peg
+// indexes.
+func (ob *OrderBook) untrack(order *orders.Order) {
+	if ids := ob.accounts[order.AccountID]; ids != nil {
+		delete(ids, order.ID)
+		if len(ids) == 0 {
+			delete(ob.accounts, order.AccountID)
+		}
+	}
+	delete(ob.pegged, order.ID)
+}
+
 // GetBestBid returns the highest bid price level, or nil if no bids.
 // Time complexity: O(1)
 func (ob *OrderBook) GetBestBid() *PriceLevel {
@@ -184,6 +373,13 @@ func (ob *OrderBook) GetAskDepth(levels int) []*PriceLevel {
 	return ob.getDepth(ob.asks, levels)
 }
 
+// ForEachLevel visits one side's price levels best price first, until fn
+// returns false. Unlike GetBidDepth/GetAskDepth it allocates nothing, so
+// callers that only need the levels near the top can stop early.
+func (ob *OrderBook) ForEachLevel(side orders.Side, fn func(*PriceLevel) bool) {
+	ob.getTree(side).ForEach(fn)
+}
+
 // getDepth returns the top N levels from a tree.
 func (ob *OrderBook) getDepth(tree *RBTree, maxLevels int) []*PriceLevel {
 	result := make([]*PriceLevel, 0)
@@ -211,7 +407,7 @@ func (ob *OrderBook) UpdateOrderQuantity(orderID uint64, fillQty int64) error {
 	}
 
 	order := node.Order
-	order.FilledQty += fillQty
+	order.ApplyFill(fillQty, order.Price) // Resting orders execute at their own price
 
 	// Update the price level's total quantity
 	node.level.UpdateQuantity(-fillQty)
@@ -235,6 +431,7 @@ func (ob *OrderBook) RemoveFilledOrders(level *PriceLevel, side orders.Side) int
 		if node.Order.IsFilled() {
 			level.Remove(node)
 			delete(ob.orders, node.Order.ID)
+			ob.untrack(node.Order)
 			removed++
 		}
 		node = next
@@ -30,6 +30,8 @@ func (n *OrderNode) Next() *OrderNode {
 // - Orders at the same price are stored in arrival order (FIFO)
 // - Doubly-linked list allows O(1) insertion at tail and O(1) removal anywhere
 // - TotalQty is maintained for quick depth queries without iterating
+// - Iceberg orders count only their displayed slice; HiddenQty holds the reserve
+// - pegged counts pegged orders, so pegs can find levels other orders made
 //
 // Example:
 //
@@ -37,11 +39,13 @@ func (n *OrderNode) Next() *OrderNode {
 //	  Head -> [Order1: 100 shares] <-> [Order2: 50 shares] <-> [Order3: 75 shares] <- Tail
 //	  TotalQty: 225 shares
 type PriceLevel struct {
-	Price    int64      // Price in cents (e.g., 15025 = $150.25)
-	head     *OrderNode // First order (oldest, highest priority)
-	tail     *OrderNode // Last order (newest, lowest priority)
-	count    int        // Number of orders at this level
-	TotalQty int64      // Sum of all order quantities (for quick depth queries)
+	Price     int64      // Price in cents (e.g., 15025 = $150.25)
+	head      *OrderNode // First order (oldest, highest priority)
+	tail      *OrderNode // Last order (newest, lowest priority)
+	count     int        // Number of orders at this level
+	pegged    int        // Number of pegged orders at this level
+	TotalQty  int64      // Sum of displayed order quantities (for quick depth queries)
+	HiddenQty int64      // Sum of undisplayed iceberg reserve (executable, not published)
 }
 
 // NewPriceLevel creates a new empty price level.
@@ -56,6 +60,11 @@ func (pl *PriceLevel) Count() int {
 	return pl.count
 }
 
+// HasUnpegged returns true if any order at this level has a price of its own.
+func (pl *PriceLevel) HasUnpegged() bool {
+	return pl.count > pl.pegged
+}
+
 // IsEmpty returns true if there are no orders at this level.
 func (pl *PriceLevel) IsEmpty() bool {
 	return pl.count == 0
@@ -87,7 +96,11 @@ func (pl *PriceLevel) Append(order *orders.Order) *OrderNode {
 	}
 
 	pl.count++
-	pl.TotalQty += order.RemainingQty()
+	if order.IsPegged() {
+		pl.pegged++
+	}
+	pl.TotalQty += order.VisibleQty()
+	pl.HiddenQty += order.HiddenQty()
 	return node
 }
 
@@ -99,8 +112,12 @@ func (pl *PriceLevel) Remove(node *OrderNode) {
 	}
 
 	// Update quantity before removal
-	pl.TotalQty -= node.Order.RemainingQty()
+	pl.TotalQty -= node.Order.VisibleQty()
+	pl.HiddenQty -= node.Order.HiddenQty()
 	pl.count--
+	if node.Order.IsPegged() {
+		pl.pegged--
+	}
 
 	// Update links
 	if node.prev != nil {
@@ -134,8 +151,12 @@ func (pl *PriceLevel) PopFront() *orders.Order {
 	node := pl.head
 	order := node.Order
 
-	pl.TotalQty -= order.RemainingQty()
+	pl.TotalQty -= order.VisibleQty()
+	pl.HiddenQty -= order.HiddenQty()
 	pl.count--
+	if order.IsPegged() {
+		pl.pegged--
+	}
 
 	pl.head = node.next
 	if pl.head != nil {
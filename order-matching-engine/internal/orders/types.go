@@ -20,6 +20,8 @@ package orders
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -88,6 +90,114 @@ func (t OrderType) String() string {
 	}
 }
 
+// PegType makes a limit order pegged: instead of a fixed price, the engine
+// prices it from the book and re-prices it as the book moves.
+type PegType int
+
+const (
+	// PegNone is an ordinary order at its own price.
+	PegNone PegType = iota
+
+	// PegMidpoint prices the order at the midpoint of the best bid and ask,
+	// rounded away from the other side (buys down, sells up) so it never
+	// crosses the spread. Two midpoint orders meet when the spread is even.
+	PegMidpoint
+
+	// PegPrimary prices the order at its own side's best price: a buy joins
+	// the best bid, a sell joins the best ask.
+	PegPrimary
+)
+
+func (p PegType) String() string {
+	switch p {
+	case PegNone:
+		return ""
+	case PegMidpoint:
+		return "MIDPOINT"
+	case PegPrimary:
+		return "PRIMARY"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// TimeInForce is how long a resting order stays in the book. IOC and FOK,
+// which never rest, are order types.
+type TimeInForce int
+
+const (
+	// TIFGoodTillCancel rests until filled or cancelled.
+	TIFGoodTillCancel TimeInForce = iota
+
+	// TIFDay expires at the close of its market's trading session (see
+	// calendar.Sessions).
+	TIFDay
+)
+
+func (t TimeInForce) String() string {
+	switch t {
+	case TIFGoodTillCancel:
+		return "GTC"
+	case TIFDay:
+		return "DAY"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// AllocationMethod is how an incoming order's quantity is shared among the
+// resting orders at a price level it trades with.
+type AllocationMethod int
+
+const (
+	// AllocationFIFO fills the level's orders in time priority: each in
+	// full before the next gets anything (price-time priority).
+	AllocationFIFO AllocationMethod = iota
+
+	// AllocationProRata fills the level's orders in proportion to their
+	// displayed size, as futures markets do: a 30% share of the level gets
+	// 30% of the incoming quantity, whatever its place in the queue.
+	AllocationProRata
+)
+
+func (m AllocationMethod) String() string {
+	switch m {
+	case AllocationFIFO:
+		return "fifo"
+	case AllocationProRata:
+		return "pro-rata"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAllocationMethod parses an allocation method name, as String writes
+// it.
+func ParseAllocationMethod(name string) (AllocationMethod, bool) {
+	switch name {
+	case "fifo":
+		return AllocationFIFO, true
+	case "pro-rata":
+		return AllocationProRata, true
+	}
+	return 0, false
+}
+
+// Allocation is a symbol's allocation method and its options.
+type Allocation struct {
+	Method AllocationMethod
+
+	// TopOrder gives the order at the front of a level's queue its fill in
+	// full before the rest is shared pro-rata, rewarding whoever set the
+	// price first. Pro-rata only.
+	TopOrder bool
+
+	// MinQty is the smallest pro-rata share handed out: orders due less get
+	// nothing in the pro-rata pass, and the remainder goes out in time
+	// priority. Pro-rata only (0 = no minimum).
+	MinQty int64
+}
+
 // OrderStatus represents the current state of an order.
 type OrderStatus int
 
@@ -150,6 +260,23 @@ type Order struct {
 	// RemainingQty = Quantity - FilledQty
 	FilledQty int64
 
+	// FilledNotional is the sum of price*quantity over all fills, in cents.
+	// AvgFillPrice = FilledNotional / FilledQty
+	FilledNotional int64
+
+	// DisplayQty makes this an iceberg order: only DisplayQty shares are
+	// shown in the book at a time, and the rest is a hidden reserve.
+	// 0 (or >= Quantity) displays the whole order.
+	DisplayQty int64
+
+	// ShownQty is the unfilled part of the slice currently on display.
+	// Only meaningful for a resting iceberg order.
+	ShownQty int64
+
+	// PegLimit caps a pegged order's price: the highest a buy or the lowest
+	// a sell will go. 0 is uncapped.
+	PegLimit int64
+
 	// Timestamp is the time the order was received, in nanoseconds since epoch.
 	Timestamp int64
 
@@ -162,12 +289,24 @@ type Order struct {
 	// ClientOrderID is an optional client-provided identifier for the order.
 	ClientOrderID string
 
+	// SessionID identifies the order entry session that placed this order.
+	// Empty for stateless (HTTP) submissions. Used for cancel-on-disconnect.
+	SessionID string
+
 	// Side indicates whether this is a buy or sell order.
 	Side Side
 
 	// Type indicates the order type (Limit, Market, IOC, FOK).
 	Type OrderType
 
+	// Peg makes a limit order track the book (see PegType). Price is then
+	// set by the engine.
+	Peg PegType
+
+	// TimeInForce is how long the order rests: until cancelled, or until
+	// its market closes.
+	TimeInForce TimeInForce
+
 	// Status is the current state of the order.
 	Status OrderStatus
 }
@@ -177,6 +316,72 @@ func (o *Order) RemainingQty() int64 {
 	return o.Quantity - o.FilledQty
 }
 
+// LeavesQty returns the quantity still open for execution.
+// Unlike RemainingQty, this is 0 once the order is done (filled, cancelled,
+// or rejected), matching FIX LeavesQty semantics.
+func (o *Order) LeavesQty() int64 {
+	if !o.IsActive() {
+		return 0
+	}
+	return o.RemainingQty()
+}
+
+// AvgFillPrice returns the volume-weighted average execution price,
+// or 0 if the order has no fills.
+func (o *Order) AvgFillPrice() int64 {
+	if o.FilledQty == 0 {
+		return 0
+	}
+	return o.FilledNotional / o.FilledQty
+}
+
+// ApplyFill records an execution of qty shares at price against this order.
+func (o *Order) ApplyFill(qty, price int64) {
+	o.FilledQty += qty
+	o.FilledNotional += qty * price
+
+	// A resting iceberg only trades its displayed slice
+	if o.ShownQty > 0 {
+		o.ShownQty -= qty
+		if o.ShownQty < 0 {
+			o.ShownQty = 0
+		}
+	}
+}
+
+// IsIceberg returns true if only part of the order is displayed.
+func (o *Order) IsIceberg() bool {
+	return o.DisplayQty > 0 && o.DisplayQty < o.Quantity
+}
+
+// VisibleQty returns the quantity shown in the book: the current slice for
+// an iceberg order, the whole remaining quantity otherwise.
+func (o *Order) VisibleQty() int64 {
+	if !o.IsIceberg() {
+		return o.RemainingQty()
+	}
+	return o.ShownQty
+}
+
+// HiddenQty returns the iceberg reserve that is not currently displayed.
+func (o *Order) HiddenQty() int64 {
+	return o.RemainingQty() - o.VisibleQty()
+}
+
+// Replenish displays the next slice of an iceberg order: DisplayQty, or
+// whatever remains if that is less.
+func (o *Order) Replenish() {
+	o.ShownQty = o.RemainingQty()
+	if o.ShownQty > o.DisplayQty {
+		o.ShownQty = o.DisplayQty
+	}
+}
+
+// IsPegged returns true if the engine sets the order's price.
+func (o *Order) IsPegged() bool {
+	return o.Peg != PegNone
+}
+
 // IsFilled returns true if the order has been completely filled.
 func (o *Order) IsFilled() bool {
 	return o.FilledQty >= o.Quantity
@@ -233,6 +438,11 @@ type Fill struct {
 
 	// TakerSide indicates whether the taker was buying or selling.
 	TakerSide Side
+
+	// MakerFee and TakerFee are the trading fees charged to each side, in
+	// cents; negative is a rebate. Set when the fill is logged (see fees).
+	MakerFee int64
+	TakerFee int64
 }
 
 // String returns a human-readable representation of the fill.
@@ -241,6 +451,93 @@ func (f *Fill) String() string {
 		f.TradeID, f.Quantity, FormatPrice(f.Price), f.MakerOrderID, f.TakerOrderID)
 }
 
+// ExecType is what caused an execution report, as in FIX ExecType (150).
+type ExecType string
+
+const (
+	ExecTypeNew       ExecType = "NEW"       // Order accepted
+	ExecTypeTrade     ExecType = "TRADE"     // Order (partially) filled
+	ExecTypeCancelled ExecType = "CANCELLED" // Order, or what was left of it, cancelled
+	ExecTypeReplaced  ExecType = "REPLACED"  // Price or quantity amended
+	ExecTypeRejected  ExecType = "REJECTED"  // Order refused
+	ExecTypeExpired   ExecType = "EXPIRED"   // DAY order, or what was left of it, expired at the close
+)
+
+// ExecutionReport describes the state of one order after an execution,
+// modelled on the FIX ExecutionReport (35=8) message.
+//
+// CumQty, AvgPx and LeavesQty are cumulative across all fills of the order,
+// so clients never have to sum fills themselves.
+type ExecutionReport struct {
+	ExecType      ExecType
+	OrderID       uint64
+	ClientOrderID string
+	AccountID     string
+	SessionID     string // Order entry session the order was placed on, if any
+	Symbol        string
+	Side          Side
+	Status        OrderStatus
+	Price         int64  // Order's limit price (0 for market orders)
+	Quantity      int64  // Order's total quantity
+	TradeID       uint64 // Execution that triggered this report
+	LastQty       int64  // Quantity of this execution
+	LastPx        int64  // Price of this execution
+	CumQty        int64  // Total quantity filled so far
+	AvgPx         int64  // Average fill price so far
+	LeavesQty     int64  // Quantity still open
+	IsMaker       bool   // True if the order was resting when it traded
+	Fee           int64  // Charged for this execution, in cents; negative = rebate
+	Text          string // Reject or cancel reason
+	Timestamp     int64
+}
+
+// NewExecutionReport builds a report for an order immediately after a fill.
+func NewExecutionReport(order *Order, fill Fill, isMaker bool) ExecutionReport {
+	return ExecutionReport{
+		ExecType:      ExecTypeTrade,
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientOrderID,
+		AccountID:     order.AccountID,
+		SessionID:     order.SessionID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Status:        order.Status,
+		Price:         order.Price,
+		Quantity:      order.Quantity,
+		TradeID:       fill.TradeID,
+		LastQty:       fill.Quantity,
+		LastPx:        fill.Price,
+		CumQty:        order.FilledQty,
+		AvgPx:         order.AvgFillPrice(),
+		LeavesQty:     order.LeavesQty(),
+		IsMaker:       isMaker,
+		Timestamp:     fill.Timestamp,
+	}
+}
+
+// NewOrderReport builds a report of an order's current state that no fill
+// caused: accepted, cancelled, replaced or rejected. text gives the reason,
+// if any.
+func NewOrderReport(order *Order, execType ExecType, text string) ExecutionReport {
+	return ExecutionReport{
+		ExecType:      execType,
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientOrderID,
+		AccountID:     order.AccountID,
+		SessionID:     order.SessionID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Status:        order.Status,
+		Price:         order.Price,
+		Quantity:      order.Quantity,
+		CumQty:        order.FilledQty,
+		AvgPx:         order.AvgFillPrice(),
+		LeavesQty:     order.LeavesQty(),
+		Text:          text,
+		Timestamp:     Now(),
+	}
+}
+
 // Trade represents a completed trade from the perspective of reporting.
 // It combines information from both sides of the execution.
 type Trade struct {
@@ -273,22 +570,67 @@ type ExecutionResult struct {
 	// RestingQty is the quantity that was added to the order book
 	// (for limit orders that didn't fully match).
 	RestingQty int64
+
+	// Reports contains one execution report per side of every fill,
+	// in execution order. Taker reports carry running cumulative totals.
+	Reports []ExecutionReport
 }
 
 // FormatPrice converts a price in cents to a dollar string.
 func FormatPrice(cents int64) string {
-	dollars := cents / 100
-	remaining := cents % 100
-	if remaining < 0 {
-		remaining = -remaining
+	sign := ""
+	if cents < 0 {
+		sign = "-" // Losses and rebates; -50 is -$0.50, not $0.50
+		cents = -cents
 	}
-	return fmt.Sprintf("$%d.%02d", dollars, remaining)
+	return fmt.Sprintf("%s$%d.%02d", sign, cents/100, cents%100)
+}
+
+// PriceDecimals is the scale prices are held at: cents, for every symbol.
+// A symbol's own precision is its tick, a whole number of cents (see
+// refdata.Instrument.Decimals), so sub-cent instruments are not supported:
+// prices, cash, fees and notional all share this one scale, and a finer
+// price would need converting wherever it turns into money.
+const PriceDecimals = 2
+
+// ParsePrice converts a dollar amount such as "150.25" or "$150.25" to
+// cents (15025), exactly. Digits past the cent must be zeros: "150.0750" is
+// refused rather than rounded, since no symbol trades below a cent.
+func ParsePrice(dollars string) (int64, error) {
+	return ParseDecimal(strings.TrimPrefix(dollars, "$"), PriceDecimals)
 }
 
-// ParsePrice converts a dollar amount to cents.
-// For example, 150.25 becomes 15025.
-func ParsePrice(dollars float64) int64 {
-	return int64(dollars * 100)
+// ParseDecimal converts a decimal string to a fixed-point integer with
+// scale decimal places, exactly: "150.0750" at scale 4 is 1500750. No float
+// is involved, so every decimal that fits is represented exactly; one with
+// non-zero digits past the scale, or too large for an int64, is an error.
+func ParseDecimal(s string, scale int) (int64, error) {
+	digits, negative := strings.CutPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	if len(fraction) > scale {
+		if strings.Trim(fraction[scale:], "0") != "" {
+			return 0, fmt.Errorf("%q has more than %d decimal places", s, scale)
+		}
+		fraction = fraction[:scale]
+	}
+
+	var value int64
+	for _, c := range whole + fraction + strings.Repeat("0", scale-len(fraction)) {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid decimal %q", s)
+		}
+		if value > (math.MaxInt64-int64(c-'0'))/10 {
+			return 0, fmt.Errorf("%q is out of range", s)
+		}
+		value = value*10 + int64(c-'0')
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
 }
 
 // Now returns the current time in nanoseconds since epoch.
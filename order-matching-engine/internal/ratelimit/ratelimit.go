@@ -0,0 +1,160 @@
+// Package ratelimit throttles order submission per account with token
+// buckets, in process.
+//
+// It is the rate limiter gateway's token bucket (rate-limiter/gateway)
+// without Redis: each account's bucket holds up to Burst tokens, refills at
+// Rate per second, and each order takes one. The engine runs as one
+// process per shard, so there is nothing to share between instances.
+//
+// Accounts get the default limit unless given their own. Buckets are
+// refilled lazily when an account next submits, so idle accounts cost
+// nothing but their entry.
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a sustained rate and the burst allowed above it.
+type Limit struct {
+	Rate  float64 `json:"rate"`  // Orders per second; 0 = unlimited
+	Burst int     `json:"burst"` // Bucket size; at least 1 when Rate is set
+}
+
+// Unlimited reports whether the limit throttles nothing.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Validate checks a limit can be applied.
+func (l Limit) Validate() error {
+	if l.Rate < 0 || math.IsNaN(l.Rate) || math.IsInf(l.Rate, 0) {
+		return errors.New("rate must be a non-negative number")
+	}
+	if l.Rate > 0 && l.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	return nil
+}
+
+// Result is the decision on one order.
+type Result struct {
+	Allowed    bool
+	Remaining  int           // Whole tokens left after this order
+	Limit      Limit         // The account's limit
+	RetryAfter time.Duration // Until the next token, when not allowed
+}
+
+// bucket is one account's tokens as of last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter holds a token bucket per account.
+type Limiter struct {
+	mu       sync.Mutex
+	defaults Limit
+	limits   map[string]Limit // Accounts with their own limit
+	buckets  map[string]*bucket
+}
+
+// New creates a limiter applying defaults to every account without its own
+// limit.
+func New(defaults Limit) *Limiter {
+	return &Limiter{
+		defaults: defaults,
+		limits:   make(map[string]Limit),
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the account's bucket, if there is one.
+func (l *Limiter) Allow(accountID string) Result {
+	return l.AllowAt(accountID, time.Now())
+}
+
+// AllowAt is Allow at the given time.
+func (l *Limiter) AllowAt(accountID string, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitLocked(accountID)
+	if limit.Unlimited() {
+		return Result{Allowed: true, Limit: limit}
+	}
+
+	b, ok := l.buckets[accountID]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[accountID] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / limit.Rate
+		return Result{
+			Limit:      limit,
+			RetryAfter: time.Duration(math.Ceil(wait * float64(time.Second))),
+		}
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens), Limit: limit}
+}
+
+// SetLimit gives an account its own limit. The account's bucket starts
+// full under the new limit.
+func (l *Limiter) SetLimit(accountID string, limit Limit) error {
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[accountID] = limit
+	delete(l.buckets, accountID)
+	return nil
+}
+
+// ClearLimit returns an account to the default limit.
+func (l *Limiter) ClearLimit(accountID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.limits, accountID)
+	delete(l.buckets, accountID)
+}
+
+// Limit returns the limit that applies to an account, and whether it is
+// the account's own.
+func (l *Limiter) Limit(accountID string) (Limit, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, own := l.limits[accountID]
+	if !own {
+		limit = l.defaults
+	}
+	return limit, own
+}
+
+// Limits returns the accounts with their own limit.
+func (l *Limiter) Limits() map[string]Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := make(map[string]Limit, len(l.limits))
+	for account, limit := range l.limits {
+		limits[account] = limit
+	}
+	return limits
+}
+
+func (l *Limiter) limitLocked(accountID string) Limit {
+	if limit, ok := l.limits[accountID]; ok {
+		return limit
+	}
+	return l.defaults
+}
@@ -0,0 +1,109 @@
+package refdata
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Instrument Files
+//
+// Tick and lot sizes differ per symbol: a $700,000 share trades single
+// shares on a dollar tick, a $5 one in round lots of 100 on a cent.
+// Prices are held in cents, so the finest tick is one cent. Instruments
+// are loaded from a YAML file, one entry per symbol:
+//
+//	AAPL:
+//	  tick_size: "0.01"    # Dollars; a whole number of cents (default 0.01)
+//	  lot_size: 1          # Shares (default 1)
+//	BRK.A:
+//	  tick_size: "1.00"
+//	  market: XNYS         # Holiday calendar (default: the server's -market)
+//	ESZ6:
+//	  allocation: pro-rata # fifo (default) or pro-rata (see matching/allocation.go)
+//	  top_order: true      # Pro-rata: the front of the queue fills first
+//	  min_allocation: 2    # Pro-rata: smallest share handed out (default: none)
+//
+// A tick finer than a cent is refused when the file loads rather than
+// rounded, which would silently put every price on a coarser grid.
+
+// fileEntry is one symbol of an instruments file.
+type fileEntry struct {
+	TickSize      string `yaml:"tick_size"`
+	LotSize       int64  `yaml:"lot_size"`
+	Market        string `yaml:"market"`
+	Allocation    string `yaml:"allocation"`
+	TopOrder      bool   `yaml:"top_order"`
+	MinAllocation int64  `yaml:"min_allocation"`
+}
+
+// Load reads an instruments file.
+func Load(path string) ([]Instrument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses instruments file contents. Instruments are sorted by symbol;
+// zero tick and lot sizes are left for Store.Add to default.
+func Parse(data []byte) ([]Instrument, error) {
+	var entries map[string]fileEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid instruments file: %w", err)
+	}
+
+	instruments := make([]Instrument, 0, len(entries))
+	for symbol, entry := range entries {
+		inst := Instrument{Symbol: symbol, LotSize: entry.LotSize, Market: entry.Market}
+		if entry.TickSize != "" {
+			tick, err := orders.ParsePrice(entry.TickSize)
+			if err != nil {
+				return nil, fmt.Errorf("%s: tick size: %w", symbol, err)
+			}
+			if tick <= 0 {
+				return nil, fmt.Errorf("%s: tick size must be positive", symbol)
+			}
+			inst.TickSize = tick
+		}
+		if entry.LotSize < 0 {
+			return nil, fmt.Errorf("%s: lot size must be positive", symbol)
+		}
+		if entry.Allocation != "" {
+			method, ok := orders.ParseAllocationMethod(entry.Allocation)
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown allocation %q (fifo or pro-rata)", symbol, entry.Allocation)
+			}
+			inst.Allocation.Method = method
+		}
+		if (entry.TopOrder || entry.MinAllocation != 0) && inst.Allocation.Method != orders.AllocationProRata {
+			return nil, fmt.Errorf("%s: top_order and min_allocation need pro-rata allocation", symbol)
+		}
+		if entry.MinAllocation < 0 {
+			return nil, fmt.Errorf("%s: min allocation must not be negative", symbol)
+		}
+		inst.Allocation.TopOrder, inst.Allocation.MinQty = entry.TopOrder, entry.MinAllocation
+		instruments = append(instruments, inst)
+	}
+	sort.Slice(instruments, func(i, j int) bool { return instruments[i].Symbol < instruments[j].Symbol })
+	return instruments, nil
+}
+
+// Decimals is the price precision the instrument's tick implies: the
+// decimal places its prices need (0 for a dollar tick, 1 for a dime, 2
+// otherwise).
+func (inst Instrument) Decimals() int {
+	switch {
+	case inst.TickSize%100 == 0:
+		return 0
+	case inst.TickSize%10 == 0:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,227 @@
+// Package refdata holds instrument reference data and the pre-sequencer
+// validation stage every order entry front-end runs before an order is
+// allowed to claim a ring buffer slot.
+//
+// Why validate before sequencing?
+// The ring buffer is the scarcest resource in the system: every slot a
+// malformed order occupies is a slot a good order could have used, and the
+// single-threaded processor pays to reject it. Checks that need only static
+// reference data (does the symbol exist, is the price on a tick, is the
+// quantity a whole lot, is the symbol trading) run here, on the gateway
+// goroutine, in parallel.
+//
+// Reject codes:
+// Every front-end (HTTP, WebSocket, and any future binary protocol) returns
+// the same RejectCode for the same problem, so clients can handle rejects
+// programmatically instead of parsing protocol-specific messages.
+package refdata
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// RejectCode identifies why an order failed validation.
+type RejectCode string
+
+const (
+	RejectUnknownSymbol   RejectCode = "UNKNOWN_SYMBOL"
+	RejectNotTrading      RejectCode = "SYMBOL_NOT_TRADING"
+	RejectInvalidQuantity RejectCode = "INVALID_QUANTITY"
+	RejectInvalidLot      RejectCode = "INVALID_LOT_SIZE"
+	RejectInvalidPrice    RejectCode = "INVALID_PRICE"
+	RejectInvalidTick     RejectCode = "INVALID_TICK_SIZE"
+)
+
+// Reject is a validation failure.
+type Reject struct {
+	Code   RejectCode
+	Reason string
+}
+
+func (r *Reject) Error() string {
+	return fmt.Sprintf("%s: %s", r.Code, r.Reason)
+}
+
+// SessionState is a symbol's trading session state.
+type SessionState uint8
+
+const (
+	SessionOpen    SessionState = iota // Continuous trading
+	SessionPreOpen                     // Before the open, no order entry
+	SessionHalted                      // Trading halted
+	SessionClosed                      // After the close
+	SessionAuction                     // Auction call: limit orders rest until the uncross
+	SessionPaused                      // Limit up-limit down pause, lifts itself (see circuit)
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case SessionOpen:
+		return "OPEN"
+	case SessionPreOpen:
+		return "PRE_OPEN"
+	case SessionHalted:
+		return "HALTED"
+	case SessionClosed:
+		return "CLOSED"
+	case SessionAuction:
+		return "AUCTION"
+	case SessionPaused:
+		return "PAUSED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseSessionState parses a state name as returned by String.
+func ParseSessionState(s string) (SessionState, error) {
+	for _, state := range []SessionState{SessionOpen, SessionPreOpen, SessionHalted, SessionClosed, SessionAuction, SessionPaused} {
+		if state.String() == s {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown session state: %q", s)
+}
+
+// Instrument is the static reference data for one symbol.
+type Instrument struct {
+	Symbol   string
+	TickSize int64  // Minimum price increment, in cents
+	LotSize  int64  // Quantity must be a multiple of this
+	Market   string // Market whose holiday calendar applies (see calendar)
+	State    SessionState
+
+	Allocation orders.Allocation // How a level is shared among its orders (default FIFO)
+}
+
+// Store holds reference data for all tradable symbols.
+// It is safe for concurrent use; reads vastly outnumber writes.
+type Store struct {
+	mu          sync.RWMutex
+	instruments map[string]Instrument
+}
+
+// NewStore creates an empty reference data store.
+func NewStore() *Store {
+	return &Store{
+		instruments: make(map[string]Instrument),
+	}
+}
+
+// Add adds or replaces an instrument. Zero tick and lot sizes default to 1.
+func (s *Store) Add(inst Instrument) {
+	if inst.TickSize <= 0 {
+		inst.TickSize = 1
+	}
+	if inst.LotSize <= 0 {
+		inst.LotSize = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instruments[inst.Symbol] = inst
+}
+
+// Get returns the instrument for a symbol.
+func (s *Store) Get(symbol string) (Instrument, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	inst, ok := s.instruments[symbol]
+	return inst, ok
+}
+
+// All returns every instrument, sorted by symbol.
+func (s *Store) All() []Instrument {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]Instrument, 0, len(s.instruments))
+	for _, inst := range s.instruments {
+		all = append(all, inst)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Symbol < all[j].Symbol })
+	return all
+}
+
+// SetState changes a symbol's session state.
+func (s *Store) SetState(symbol string, state SessionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instruments[symbol]
+	if !ok {
+		return fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	inst.State = state
+	s.instruments[symbol] = inst
+	return nil
+}
+
+// Validate checks an order against reference data.
+// Returns nil if the order may be sequenced.
+func (s *Store) Validate(order *orders.Order) *Reject {
+	inst, ok := s.Get(order.Symbol)
+	if !ok {
+		return &Reject{RejectUnknownSymbol, fmt.Sprintf("unknown symbol: %s", order.Symbol)}
+	}
+
+	if inst.State != SessionOpen && inst.State != SessionAuction {
+		return &Reject{RejectNotTrading, fmt.Sprintf("%s is %s", order.Symbol, inst.State)}
+	}
+	if inst.State == SessionAuction && (order.Type != orders.OrderTypeLimit || order.IsPegged()) {
+		return &Reject{RejectNotTrading, fmt.Sprintf("%s is in an auction call: only limit orders are accepted", order.Symbol)}
+	}
+
+	if order.Quantity <= 0 {
+		return &Reject{RejectInvalidQuantity, "quantity must be positive"}
+	}
+	if order.Quantity%inst.LotSize != 0 {
+		return &Reject{RejectInvalidLot, fmt.Sprintf("quantity %d is not a multiple of lot size %d", order.Quantity, inst.LotSize)}
+	}
+
+	// Iceberg slices rest on their own, so they must be tradable lots too
+	if order.DisplayQty < 0 {
+		return &Reject{RejectInvalidQuantity, "display quantity must not be negative"}
+	}
+	if order.DisplayQty > 0 {
+		if order.Type != orders.OrderTypeLimit {
+			return &Reject{RejectInvalidQuantity, "display quantity is only valid for limit orders"}
+		}
+		if order.DisplayQty%inst.LotSize != 0 {
+			return &Reject{RejectInvalidLot, fmt.Sprintf("display quantity %d is not a multiple of lot size %d", order.DisplayQty, inst.LotSize)}
+		}
+	}
+
+	// Pegged orders are priced by the engine, so their price is optional:
+	// when given it is the cap (PegLimit), and must be on a tick
+	if order.IsPegged() {
+		if order.Type != orders.OrderTypeLimit {
+			return &Reject{RejectInvalidPrice, "peg is only valid for limit orders"}
+		}
+		if order.PegLimit < 0 {
+			return &Reject{RejectInvalidPrice, "peg limit must not be negative"}
+		}
+		if order.PegLimit%inst.TickSize != 0 {
+			return &Reject{RejectInvalidTick, fmt.Sprintf("peg limit %s is not a multiple of tick size %s",
+				orders.FormatPrice(order.PegLimit), orders.FormatPrice(inst.TickSize))}
+		}
+		return nil
+	}
+
+	// Market orders carry no price; every other type needs a positive one
+	if order.Type != orders.OrderTypeMarket {
+		if order.Price <= 0 {
+			return &Reject{RejectInvalidPrice, fmt.Sprintf("%s order must have positive price", order.Type)}
+		}
+		if order.Price%inst.TickSize != 0 {
+			return &Reject{RejectInvalidTick, fmt.Sprintf("price %s is not a multiple of tick size %s",
+				orders.FormatPrice(order.Price), orders.FormatPrice(inst.TickSize))}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+// Package refshare shares reference prices and session states between
+// engine shards through Redis.
+//
+// Why share?
+// When symbols are sharded across engines, each engine's risk checker only
+// sees the trades of its own books. A gateway that can route a symbol to
+// more than one instance (during a migration, or a standby taking over)
+// then checks price bands against a stale or missing reference price, and
+// a halt on one instance is invisible to the others.
+//
+// Design:
+//
+//	┌──────────┐  HSET + PUBLISH   ┌─────────┐  SUBSCRIBE   ┌──────────┐
+//	│ Shard A  │──────────────────▶│  Redis  │─────────────▶│ Shard B  │
+//	│ (trades) │                   │ hashes  │              │ risk/ref │
+//	└──────────┘                   └─────────┘              └──────────┘
+//
+// Behaviour:
+//   - Writes: after a fill or a state change the shard updates its local
+//     copy immediately, then hands the update to a background goroutine that
+//     writes the latest value to a Redis hash and publishes it. The trading
+//     path never waits on Redis.
+//   - Reads: every shard applies published updates to its own risk checker
+//     and reference data, so risk checks stay local memory reads.
+//   - Startup: a shard subscribes, then loads the hashes, so it starts with
+//     the current cluster view rather than an empty one.
+//   - Ordering: each update carries its origin timestamp and older updates
+//     for a symbol are ignored, so a delayed message cannot roll a price back
+//     (last writer wins by origin clock; shards are assumed NTP-synced).
+//
+// If Redis is unreachable, shards keep trading on their local view and
+// updates are dropped (logged); sharing is an accuracy aid, not a
+// dependency of matching.
+package refshare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// Redis keys
+const (
+	pricesKey = "ome:refprice" // Hash: symbol -> JSON update
+	statesKey = "ome:state"    // Hash: symbol -> JSON update
+	channel   = "ome:refdata"  // Pub/sub channel for live updates
+)
+
+// Update kinds
+const (
+	KindPrice = "price"
+	KindState = "state"
+)
+
+// Update is a single shared reference data change.
+type Update struct {
+	Kind      string `json:"kind"`
+	Shard     string `json:"shard"` // Origin shard, so a shard ignores its own echoes
+	Symbol    string `json:"symbol"`
+	Price     int64  `json:"price,omitempty"`
+	State     string `json:"state,omitempty"`
+	Timestamp int64  `json:"ts"` // Origin time (ns); older updates are ignored
+}
+
+// Config holds sharing configuration.
+type Config struct {
+	ShardID   string // Unique per engine instance
+	QueueSize int    // Pending outbound updates before new ones are dropped
+}
+
+// Sharer publishes local reference data changes and applies remote ones.
+// A nil *Sharer is valid and does nothing, so callers need no checks when
+// sharing is disabled.
+type Sharer struct {
+	client  redis.UniversalClient
+	shardID string
+	checker *risk.Checker
+	refData *refdata.Store
+
+	mu   sync.Mutex
+	seen map[string]int64 // kind/symbol -> newest applied timestamp
+
+	outbound chan Update
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New creates a sharer that applies updates to the given checker and
+// reference data store. Call Start to begin syncing.
+func New(client redis.UniversalClient, config Config, checker *risk.Checker, refData *refdata.Store) *Sharer {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 4096
+	}
+	return &Sharer{
+		client:   client,
+		shardID:  config.ShardID,
+		checker:  checker,
+		refData:  refData,
+		seen:     make(map[string]int64),
+		outbound: make(chan Update, config.QueueSize),
+	}
+}
+
+// Start loads the current shared state and starts the subscriber and
+// publisher goroutines.
+func (s *Sharer) Start(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	// Subscribe before loading so no update falls between the two; anything
+	// seen twice is discarded by the timestamp check
+	sub := s.client.Subscribe(ctx, channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", channel, err)
+	}
+
+	loaded, err := s.load(ctx)
+	if err != nil {
+		sub.Close()
+		return err
+	}
+	log.Printf("Loaded %d shared reference data entries from Redis", loaded)
+
+	s.wg.Add(2)
+	go s.subscribeLoop(ctx, sub)
+	go s.publishLoop(ctx)
+	return nil
+}
+
+// Stop stops syncing. Pending outbound updates are discarded.
+func (s *Sharer) Stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// PublishPrice shares a new reference price for a symbol. Never blocks.
+func (s *Sharer) PublishPrice(symbol string, price int64) {
+	if s == nil {
+		return
+	}
+	s.enqueue(Update{Kind: KindPrice, Symbol: symbol, Price: price})
+}
+
+// PublishState shares a new session state for a symbol. Never blocks.
+func (s *Sharer) PublishState(symbol string, state refdata.SessionState) {
+	if s == nil {
+		return
+	}
+	s.enqueue(Update{Kind: KindState, Symbol: symbol, State: state.String()})
+}
+
+func (s *Sharer) enqueue(u Update) {
+	u.Shard = s.shardID
+	u.Timestamp = time.Now().UnixNano()
+	s.markSeen(u) // Our own value is the newest we know of
+
+	select {
+	case s.outbound <- u:
+	default:
+		log.Printf("WARNING: Reference data share queue full, dropping %s update for %s", u.Kind, u.Symbol)
+	}
+}
+
+// load applies every entry of the shared hashes.
+func (s *Sharer) load(ctx context.Context) (int, error) {
+	loaded := 0
+	for _, key := range []string{pricesKey, statesKey} {
+		entries, err := s.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return loaded, fmt.Errorf("failed to load %s: %w", key, err)
+		}
+		for _, raw := range entries {
+			var u Update
+			if err := json.Unmarshal([]byte(raw), &u); err != nil {
+				log.Printf("WARNING: Skipping malformed shared entry in %s: %v", key, err)
+				continue
+			}
+			if s.Apply(u) {
+				loaded++
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// subscribeLoop applies updates published by other shards.
+func (s *Sharer) subscribeLoop(ctx context.Context, sub *redis.PubSub) {
+	defer s.wg.Done()
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var u Update
+			if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil {
+				log.Printf("WARNING: Ignoring malformed reference data update: %v", err)
+				continue
+			}
+			if u.Shard != s.shardID {
+				s.Apply(u)
+			}
+		}
+	}
+}
+
+// publishLoop writes local updates to Redis.
+func (s *Sharer) publishLoop(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-s.outbound:
+			if err := s.write(ctx, u); err != nil {
+				log.Printf("WARNING: Failed to share %s update for %s: %v", u.Kind, u.Symbol, err)
+			}
+		}
+	}
+}
+
+// write stores the latest value and notifies the other shards.
+func (s *Sharer) write(ctx context.Context, u Update) error {
+	payload, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	key := pricesKey
+	if u.Kind == KindState {
+		key = statesKey
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, key, u.Symbol, payload)
+	pipe.Publish(ctx, channel, payload)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Apply applies an update to the local risk checker or reference data,
+// unless a newer update for the same symbol has already been applied.
+// Returns true if the update was applied.
+func (s *Sharer) Apply(u Update) bool {
+	if !s.markSeen(u) {
+		return false
+	}
+
+	switch u.Kind {
+	case KindPrice:
+		s.checker.SetReferencePrice(u.Symbol, u.Price)
+	case KindState:
+		state, err := refdata.ParseSessionState(u.State)
+		if err != nil {
+			log.Printf("WARNING: Ignoring shared state for %s: %v", u.Symbol, err)
+			return false
+		}
+		if err := s.refData.SetState(u.Symbol, state); err != nil {
+			return false // Symbol not traded on this shard
+		}
+		log.Printf("Symbol %s session state set to %s by shard %s", u.Symbol, state, u.Shard)
+	default:
+		return false
+	}
+	return true
+}
+
+// markSeen records an update's timestamp. Returns false if a newer or
+// equal update for the same kind and symbol was already recorded.
+func (s *Sharer) markSeen(u Update) bool {
+	key := u.Kind + "/" + u.Symbol
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.Timestamp <= s.seen[key] {
+		return false
+	}
+	s.seen[key] = u.Timestamp
+	return true
+}
@@ -0,0 +1,64 @@
+package refshare
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// TestApply_IgnoresStaleUpdates tests that a delayed update cannot roll a
+// newer price or state back
+func TestApply_IgnoresStaleUpdates(t *testing.T) {
+	checker := risk.NewChecker(risk.DefaultConfig())
+	refData := refdata.NewStore()
+	refData.Add(refdata.Instrument{Symbol: "AAPL"})
+	s := New(nil, Config{ShardID: "A"}, checker, refData)
+
+	if !s.Apply(Update{Kind: KindPrice, Shard: "B", Symbol: "AAPL", Price: 15100, Timestamp: 200}) {
+		t.Fatal("Expected first price update to apply")
+	}
+	if s.Apply(Update{Kind: KindPrice, Shard: "C", Symbol: "AAPL", Price: 14000, Timestamp: 100}) {
+		t.Error("Stale price update was applied")
+	}
+	if got := checker.GetReferencePrice("AAPL"); got != 15100 {
+		t.Errorf("Expected reference price 15100, got %d", got)
+	}
+
+	// States are ordered independently of prices
+	if !s.Apply(Update{Kind: KindState, Shard: "B", Symbol: "AAPL", State: "HALTED", Timestamp: 150}) {
+		t.Fatal("Expected state update to apply")
+	}
+	if inst, _ := refData.Get("AAPL"); inst.State != refdata.SessionHalted {
+		t.Errorf("Expected AAPL halted, got %s", inst.State)
+	}
+}
+
+// TestApply_UnknownInputs tests that updates for symbols or states this
+// shard does not know are skipped
+func TestApply_UnknownInputs(t *testing.T) {
+	refData := refdata.NewStore()
+	refData.Add(refdata.Instrument{Symbol: "AAPL"})
+	s := New(nil, Config{ShardID: "A"}, risk.NewChecker(risk.DefaultConfig()), refData)
+
+	if s.Apply(Update{Kind: KindState, Symbol: "MSFT", State: "HALTED", Timestamp: 1}) {
+		t.Error("State for a symbol not on this shard was applied")
+	}
+	if s.Apply(Update{Kind: KindState, Symbol: "AAPL", State: "SLEEPING", Timestamp: 1}) {
+		t.Error("Unknown state was applied")
+	}
+	if s.Apply(Update{Kind: "volume", Symbol: "AAPL", Timestamp: 1}) {
+		t.Error("Unknown kind was applied")
+	}
+}
+
+// TestNilSharer tests that a disabled (nil) sharer is a no-op
+func TestNilSharer(t *testing.T) {
+	var s *Sharer
+	s.PublishPrice("AAPL", 15000)
+	s.PublishState("AAPL", refdata.SessionHalted)
+	if err := s.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+}
@@ -0,0 +1,696 @@
+// Package replication streams the event log from a primary engine to
+// standby servers, so a standby can take over with every event the primary
+// had acknowledged.
+//
+// Primary/Standby Replication:
+//
+//	primary log ──append──▶ Primary ──TCP──▶ Standby ──append──▶ standby log
+//	                          ▲                 │
+//	                          └────── ack ──────┘
+//
+// A standby connects, says which sequence number its own log ends at, and
+// the primary catches it up from its log (ReplayFrom) before streaming each
+// event as it is appended. The standby appends every event to its log with
+// the same sequence number, syncs, and acknowledges the last one it holds.
+// The primary tracks the acknowledged sequence of each standby: everything
+// up to it survives losing the primary.
+//
+// Failover is a restart from the standby's log. Stopping the standby leaves
+// a log ending at its last acknowledged sequence (plus, at most, events it
+// wrote but had not yet acknowledged), which the server then recovers from
+// like any other.
+//
+// Wire format (big-endian), after the standby's 8-byte hello (the last
+// sequence number in its log):
+//
+//	primary ▶ standby   seq(8) type(1) length(4) payload(length)
+//	standby ▶ primary   seq(8)   acknowledged: durable in the standby's log
+//
+// Type 0 is a heartbeat, sent when the stream is idle, whose seq is the
+// primary's last. Payloads are events as the codec encodes them, whatever
+// codec the primary's log itself is written with.
+//
+// Production Considerations:
+//   - Replication here is asynchronous: the primary never waits for acks, so
+//     events logged since a standby's last ack are lost if the primary dies
+//   - Nothing fences the old primary; promoting a standby while the primary
+//     still trades splits the brain (see algorithms/raft for leases)
+package replication
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// ErrDiverged is returned when the standby's log cannot continue the
+// primary's: it is ahead of it, or an event arrives out of sequence.
+var ErrDiverged = errors.New("replication: standby log diverged from primary")
+
+// ErrLagging closes the stream of a standby that fell a whole backlog
+// behind. It reconnects and catches up from the primary's log.
+var ErrLagging = errors.New("replication: standby too far behind")
+
+// heartbeatType marks a frame carrying no event.
+const heartbeatType events.EventType = 0
+
+const (
+	headLen    = 8 + 1 + 4 // seq, type, length
+	maxPayload = 64 << 20
+)
+
+// Config configures replication on both ends.
+type Config struct {
+	Heartbeat time.Duration // Primary: idle time before a heartbeat
+	Timeout   time.Duration // Standby: silence before the primary counts as lost; primary: write deadline
+	Backlog   int           // Primary: live events queued per standby before it is dropped as lagging
+	Retry     time.Duration // Standby: wait between connection attempts
+	Codec     events.Codec  // Encodes events on the wire (default protobuf)
+}
+
+// DefaultConfig returns reasonable defaults.
+func DefaultConfig() Config {
+	return Config{
+		Heartbeat: 500 * time.Millisecond,
+		Timeout:   3 * time.Second,
+		Backlog:   65536,
+		Retry:     time.Second,
+		Codec:     events.ProtobufCodec{},
+	}
+}
+
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.Heartbeat <= 0 {
+		c.Heartbeat = d.Heartbeat
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = d.Timeout
+	}
+	if c.Backlog <= 0 {
+		c.Backlog = d.Backlog
+	}
+	if c.Retry <= 0 {
+		c.Retry = d.Retry
+	}
+	if c.Codec == nil {
+		c.Codec = d.Codec
+	}
+	return c
+}
+
+// frame is one event on the wire.
+type frame struct {
+	seq       uint64
+	eventType events.EventType
+	payload   []byte
+}
+
+func writeFrame(w io.Writer, f frame) error {
+	var head [headLen]byte
+	binary.BigEndian.PutUint64(head[0:], f.seq)
+	head[8] = byte(f.eventType)
+	binary.BigEndian.PutUint32(head[9:], uint32(len(f.payload)))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.payload)
+	return err
+}
+
+func readFrame(r io.Reader) (frame, error) {
+	var head [headLen]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{
+		seq:       binary.BigEndian.Uint64(head[0:]),
+		eventType: events.EventType(head[8]),
+	}
+	n := binary.BigEndian.Uint32(head[9:])
+	if n > maxPayload {
+		return frame{}, fmt.Errorf("replication: invalid payload length %d", n)
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	return f, nil
+}
+
+func writeSeq(w io.Writer, seq uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], seq)
+	_, err := w.Write(b[:])
+	return err
+}
+
+func readSeq(r io.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// ============================================================================
+// PRIMARY
+// ============================================================================
+
+// StandbyStatus is a connected standby as the primary sees it.
+type StandbyStatus struct {
+	Addr      string    `json:"addr"`
+	Connected time.Time `json:"connected"`
+	SentSeq   uint64    `json:"sent_seq"`
+	AckedSeq  uint64    `json:"acked_seq"`
+	Lag       uint64    `json:"lag"` // Events logged but not yet acknowledged
+}
+
+// Primary serves its event log to standbys. Safe for concurrent use.
+type Primary struct {
+	log    *events.EventLog
+	config Config
+
+	mu       sync.Mutex
+	standbys map[*follower]struct{}
+	acked    uint64 // Highest sequence any standby acknowledged
+	closed   bool
+}
+
+// follower is one connected standby.
+type follower struct {
+	conn      net.Conn
+	connected time.Time
+	live      chan frame
+	sent      uint64 // Atomic
+	acked     uint64 // Atomic
+	dropped   error  // Why the primary dropped it (under Primary.mu)
+}
+
+// NewPrimary creates a primary serving eventLog, which it watches for
+// appends from now on.
+func NewPrimary(eventLog *events.EventLog, config Config) *Primary {
+	p := &Primary{
+		log:      eventLog,
+		config:   config.withDefaults(),
+		standbys: make(map[*follower]struct{}),
+	}
+	eventLog.OnAppend(p.onAppend)
+	return p
+}
+
+// onAppend queues an appended event for every standby. It runs under the
+// log's lock, so a standby that can't keep up is dropped, not waited for.
+func (p *Primary) onAppend(seqNum uint64, event interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.standbys) == 0 {
+		return
+	}
+
+	payload, err := p.config.Codec.Marshal(nil, event)
+	if err != nil {
+		for f := range p.standbys {
+			p.drop(f, fmt.Errorf("replication: failed to encode event %d: %w", seqNum, err))
+		}
+		return
+	}
+	f := frame{seq: seqNum, eventType: events.TypeOf(event), payload: payload}
+	for standby := range p.standbys {
+		select {
+		case standby.live <- f:
+		default:
+			p.drop(standby, ErrLagging)
+		}
+	}
+}
+
+// drop disconnects a standby. Must hold p.mu.
+func (p *Primary) drop(f *follower, err error) {
+	if _, ok := p.standbys[f]; !ok {
+		return
+	}
+	delete(p.standbys, f)
+	f.dropped = err
+	close(f.live)
+	f.conn.Close()
+}
+
+// Serve accepts standbys on l until it is closed.
+func (p *Primary) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			if err := p.serveConn(conn); err != nil {
+				log.Printf("Replication to standby %s stopped: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// serveConn catches one standby up from the log, then streams appends to
+// it until it disconnects or falls behind.
+func (p *Primary) serveConn(conn net.Conn) error {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(p.config.Timeout))
+	after, err := readSeq(conn)
+	if err != nil {
+		return fmt.Errorf("no hello: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	if last := p.log.GetLastSequence(); after > last {
+		return fmt.Errorf("%w: standby at %d, primary at %d", ErrDiverged, after, last)
+	}
+
+	// Register before reading the log: every event appended from here on
+	// is queued, and every one before is in the log for the catch-up
+	f := &follower{
+		conn:      conn,
+		connected: time.Now(),
+		live:      make(chan frame, p.config.Backlog),
+		sent:      after,
+		acked:     after,
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.standbys[f] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.drop(f, nil)
+		p.mu.Unlock()
+	}()
+	log.Printf("Standby %s connected at sequence %d", conn.RemoteAddr(), after)
+
+	go p.readAcks(f)
+
+	w := &deadlineWriter{conn: conn, timeout: p.config.Timeout}
+	err = p.log.ReplayFrom(after, func(seqNum uint64, event interface{}) error {
+		payload, err := p.config.Codec.Marshal(nil, event)
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(w, frame{seq: seqNum, eventType: events.TypeOf(event), payload: payload}); err != nil {
+			return err
+		}
+		atomic.StoreUint64(&f.sent, seqNum)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("catch-up from %d failed: %w", after, err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(p.config.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case next, ok := <-f.live:
+			if !ok {
+				return p.dropReason(f)
+			}
+			for {
+				if err := p.send(w, f, next); err != nil {
+					return err
+				}
+				if len(f.live) == 0 {
+					break
+				}
+				if next, ok = <-f.live; !ok {
+					return p.dropReason(f)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if len(f.live) > 0 {
+				continue
+			}
+			sent := atomic.LoadUint64(&f.sent)
+			if err := writeFrame(w, frame{seq: sent, eventType: heartbeatType}); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// send writes a live event, skipping those the catch-up already sent.
+func (p *Primary) send(w io.Writer, f *follower, next frame) error {
+	sent := atomic.LoadUint64(&f.sent)
+	if next.seq <= sent {
+		return nil
+	}
+	if next.seq != sent+1 {
+		return fmt.Errorf("%w: event %d queued after %d", events.ErrSequenceGap, next.seq, sent)
+	}
+	if err := writeFrame(w, next); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.sent, next.seq)
+	return nil
+}
+
+func (p *Primary) dropReason(f *follower) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return f.dropped
+}
+
+// readAcks records a standby's acknowledgements until its connection closes.
+func (p *Primary) readAcks(f *follower) {
+	for {
+		seq, err := readSeq(f.conn)
+		if err != nil {
+			f.conn.Close()
+			return
+		}
+		atomic.StoreUint64(&f.acked, seq)
+		p.mu.Lock()
+		if seq > p.acked {
+			p.acked = seq
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Standbys returns the connected standbys.
+func (p *Primary) Standbys() []StandbyStatus {
+	last := p.log.GetLastSequence()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]StandbyStatus, 0, len(p.standbys))
+	for f := range p.standbys {
+		acked := atomic.LoadUint64(&f.acked)
+		status := StandbyStatus{
+			Addr:      f.conn.RemoteAddr().String(),
+			Connected: f.connected,
+			SentSeq:   atomic.LoadUint64(&f.sent),
+			AckedSeq:  acked,
+		}
+		if last > acked {
+			status.Lag = last - acked
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Acked returns the highest sequence number any standby has acknowledged:
+// the last event a failover to the most up-to-date standby keeps.
+func (p *Primary) Acked() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.acked
+}
+
+// Close disconnects every standby. The listener passed to Serve is the
+// caller's to close.
+func (p *Primary) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for f := range p.standbys {
+		p.drop(f, nil)
+	}
+}
+
+// deadlineWriter buffers writes to a connection, each flush bounded by a
+// deadline so a hung standby can't stall its stream forever.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+	buf     []byte
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= 64<<10 {
+		return len(p), w.Flush()
+	}
+	return len(p), nil
+}
+
+func (w *deadlineWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	_, err := w.conn.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// ============================================================================
+// STANDBY
+// ============================================================================
+
+// Status is a standby's view of its replication.
+type Status struct {
+	Primary     string    `json:"primary"`
+	Connected   bool      `json:"connected"`
+	LastSeq     uint64    `json:"last_seq"`    // Last event in the standby's log
+	AckedSeq    uint64    `json:"acked_seq"`   // Last event acknowledged to the primary
+	PrimarySeq  uint64    `json:"primary_seq"` // Primary's last event, as of its last heartbeat
+	LastContact time.Time `json:"last_contact,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Standby follows a primary, appending its events to a local log. Safe for
+// concurrent use.
+type Standby struct {
+	log     *events.EventLog
+	primary string
+	config  Config
+	created time.Time
+
+	mu          sync.Mutex
+	conn        net.Conn
+	acked       uint64
+	primarySeq  uint64
+	lastContact time.Time
+	lastError   error
+	stopped     bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewStandby creates a standby replicating the primary at addr into
+// eventLog, which must hold a prefix of the primary's log (or nothing).
+func NewStandby(eventLog *events.EventLog, addr string, config Config) *Standby {
+	return &Standby{
+		log:     eventLog,
+		primary: addr,
+		config:  config.withDefaults(),
+		created: time.Now(),
+		acked:   eventLog.GetLastSequence(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run follows the primary, reconnecting whenever the connection drops,
+// until Stop. A diverged log stops it with ErrDiverged: it needs rebuilding
+// from the primary's.
+func (s *Standby) Run() error {
+	defer close(s.done)
+	for {
+		err := s.follow()
+		s.mu.Lock()
+		s.conn = nil
+		stopped := s.stopped
+		if err != nil && !stopped {
+			s.lastError = err
+		}
+		s.mu.Unlock()
+		if stopped {
+			return nil
+		}
+		if errors.Is(err, ErrDiverged) {
+			return err
+		}
+		log.Printf("Replication from %s: %v (retrying in %s)", s.primary, err, s.config.Retry)
+
+		select {
+		case <-s.stop:
+			return nil
+		case <-time.After(s.config.Retry):
+		}
+	}
+}
+
+// follow streams from the primary over one connection.
+func (s *Standby) follow() error {
+	conn, err := net.DialTimeout("tcp", s.primary, s.config.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.conn = conn
+	s.mu.Unlock()
+
+	last := s.log.GetLastSequence()
+	if err := writeSeq(conn, last); err != nil {
+		return err
+	}
+	s.contact(0)
+	log.Printf("Replicating from %s after sequence %d", s.primary, last)
+
+	r := &timeoutReader{conn: conn, timeout: s.config.Timeout}
+	reader := bufio.NewReader(r)
+	for {
+		f, err := readFrame(reader)
+		if err != nil {
+			return err
+		}
+		if f.eventType == heartbeatType {
+			s.contact(f.seq)
+		} else if err := s.apply(f); err != nil {
+			return err
+		}
+
+		// Acknowledge once everything received so far is durable
+		if reader.Buffered() == 0 {
+			if err := s.ack(conn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply appends one replicated event to the local log.
+func (s *Standby) apply(f frame) error {
+	event, err := s.config.Codec.Unmarshal(f.eventType, f.payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode event %d: %w", f.seq, err)
+	}
+	if expected := s.log.GetLastSequence() + 1; f.seq != expected {
+		return fmt.Errorf("%w: received event %d, expected %d", ErrDiverged, f.seq, expected)
+	}
+	if _, err := s.log.Append(event); err != nil {
+		return fmt.Errorf("failed to append event %d: %w", f.seq, err)
+	}
+	s.contact(f.seq)
+	return nil
+}
+
+// ack syncs the log and acknowledges its last event, if not already.
+func (s *Standby) ack(conn net.Conn) error {
+	last := s.log.GetLastSequence()
+	s.mu.Lock()
+	acked := s.acked
+	s.mu.Unlock()
+	if last == acked {
+		return nil
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync: %w", err)
+	}
+	conn.SetWriteDeadline(time.Now().Add(s.config.Timeout))
+	if err := writeSeq(conn, last); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.acked = last
+	s.mu.Unlock()
+	return nil
+}
+
+// contact notes a frame from the primary.
+func (s *Standby) contact(primarySeq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastContact = time.Now()
+	if primarySeq > s.primarySeq {
+		s.primarySeq = primarySeq
+	}
+}
+
+// Status returns the standby's replication state.
+func (s *Standby) Status() Status {
+	last := s.log.GetLastSequence()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Primary:     s.primary,
+		Connected:   s.conn != nil,
+		LastSeq:     last,
+		AckedSeq:    s.acked,
+		PrimarySeq:  s.primarySeq,
+		LastContact: s.lastContact,
+	}
+	if s.lastError != nil {
+		status.LastError = s.lastError.Error()
+	}
+	return status
+}
+
+// Silence returns how long it has been since the primary was last heard
+// from, counting from when the standby was created if it never was.
+func (s *Standby) Silence() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastContact.IsZero() {
+		return time.Since(s.created)
+	}
+	return time.Since(s.lastContact)
+}
+
+// Stop stops following the primary and waits for Run to return. The log
+// is left ending at the last event received; returns its sequence number.
+func (s *Standby) Stop() uint64 {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}
+	s.mu.Unlock()
+	<-s.done
+	return s.log.GetLastSequence()
+}
+
+// timeoutReader reads from a connection, failing when the primary is
+// silent (no events and no heartbeats) for longer than timeout.
+type timeoutReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(r.timeout))
+	return r.conn.Read(p)
+}
@@ -0,0 +1,31 @@
+package risk
+
+// Blocks
+//
+// Other components bar an account from new orders while a condition of
+// theirs holds, such as an open margin call in the clearing house. Unlike
+// the kill switch, a block is lifted by whatever placed it, not by an
+// operator, and is not reported as a risk event.
+
+// Block bars an account from new orders, for reason, until Unblock.
+// Blocking a blocked account replaces the reason.
+func (c *Checker) Block(accountID, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blocked[accountID] = reason
+}
+
+// Unblock lifts an account's block.
+func (c *Checker) Unblock(accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.blocked, accountID)
+}
+
+// IsBlocked reports whether an account is blocked, and why.
+func (c *Checker) IsBlocked(accountID string) (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	reason, blocked := c.blocked[accountID]
+	return blocked, reason
+}
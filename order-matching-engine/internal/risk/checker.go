@@ -15,12 +15,19 @@
 // - Price bands (reject orders too far from market)
 // - Position limits (max shares held)
 // - Daily volume limits (max traded per day)
-// - Rate limits (max orders per second)
+// - Rate limits (max orders per second, see rate.go)
+// - Daily loss limit (account kill switch, see pnl.go)
+// - Restricted symbols, per account and global (see restrictions.go)
+//
+// Limits come from the account's risk profile (see profiles.go), or the
+// checker's default Config if it has none, with any overrides of the
+// account's own applied (see limits.go).
 package risk
 
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rishav/order-matching-engine/internal/orders"
 )
@@ -29,6 +36,7 @@ import (
 type CheckResult struct {
 	Passed    bool
 	Reason    string   // If failed, why
+	Code      string   // If failed, a stable reject code, for checks with one (e.g. RejectRestrictedList)
 	ChecksRun []string // List of checks that were run
 }
 
@@ -40,6 +48,9 @@ type Config struct {
 	MaxDailyVolume   int64            // Maximum daily trading volume per account (in cents)
 	PriceBandPercent float64          // Max deviation from reference price (0.1 = 10%)
 	SymbolLimits     map[string]int64 // Per-symbol position limits
+	MaxDailyLoss     int64            // Intraday loss that trips an account's kill switch (0 = off)
+	MaxOrderRate     int64            // Orders per second per account, over a sliding window (0 = off)
+	RateBreachKill   int              // Order rate breaches in a day that trip the kill switch (0 = never)
 }
 
 // DefaultConfig returns a reasonable default configuration.
@@ -55,10 +66,21 @@ func DefaultConfig() Config {
 
 // Checker performs pre-trade risk checks.
 type Checker struct {
-	config         Config
+	config         Config                      // Default profile, for accounts without one
+	profiles       map[string]Config           // Named profiles (see profiles.go)
+	accountProfile map[string]string           // account -> profile name
+	limits         map[string]Limits           // account -> overrides of its profile (see limits.go)
 	positions      map[string]map[string]int64 // account -> symbol -> position
 	dailyVolume    map[string]int64            // account -> daily volume (in cents)
 	referencePrices map[string]int64           // symbol -> last known price
+	pnl            map[string]map[string]*symbolPnL // account -> symbol -> intraday P&L
+	positionPnL    map[string]map[string]*symbolPnL // account -> symbol -> P&L since its first fill (see pnl.go)
+	killed         map[string]string           // account -> why its kill switch tripped
+	blocked        map[string]string           // account -> why it is blocked (see blocks.go)
+	rates          map[string]*rateWindow      // account -> recently accepted orders (see rate.go)
+	restricted     map[string]map[string]string // account ("" = global) -> symbol -> reason (see restrictions.go)
+	now            func() time.Time            // Clock order rates are measured by
+	onEvent        func(Event)                 // Risk event callback (kill switch trips)
 	mu             sync.RWMutex
 }
 
@@ -69,6 +91,16 @@ func NewChecker(config Config) *Checker {
 		positions:       make(map[string]map[string]int64),
 		dailyVolume:     make(map[string]int64),
 		referencePrices: make(map[string]int64),
+		profiles:        make(map[string]Config),
+		accountProfile:  make(map[string]string),
+		limits:          make(map[string]Limits),
+		pnl:             make(map[string]map[string]*symbolPnL),
+		positionPnL:     make(map[string]map[string]*symbolPnL),
+		killed:          make(map[string]string),
+		blocked:         make(map[string]string),
+		rates:           make(map[string]*rateWindow),
+		restricted:      make(map[string]map[string]string),
+		now:             time.Now,
 	}
 }
 
@@ -79,13 +111,55 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 		Passed:    true,
 		ChecksRun: make([]string, 0),
 	}
+	cfg := c.configFor(order.AccountID)
+
+	// 0. Kill switch: a killed account can't trade at all
+	result.ChecksRun = append(result.ChecksRun, "kill_switch")
+	if killed, reason := c.IsKilled(order.AccountID); killed {
+		return CheckResult{
+			Passed:    false,
+			Reason:    fmt.Sprintf("account %s is disabled: %s", order.AccountID, reason),
+			ChecksRun: result.ChecksRun,
+		}
+	}
+
+	// Blocks placed by other components, e.g. for a margin call
+	result.ChecksRun = append(result.ChecksRun, "blocked")
+	if blocked, reason := c.IsBlocked(order.AccountID); blocked {
+		return CheckResult{
+			Passed:    false,
+			Reason:    fmt.Sprintf("account %s is blocked: %s", order.AccountID, reason),
+			ChecksRun: result.ChecksRun,
+		}
+	}
+
+	// Restricted lists: compliance, not limits, so a reject code of its own
+	result.ChecksRun = append(result.ChecksRun, "restricted")
+	if code, reason, restricted := c.checkRestricted(order.AccountID, order.Symbol); restricted {
+		return CheckResult{
+			Passed:    false,
+			Reason:    reason,
+			Code:      code,
+			ChecksRun: result.ChecksRun,
+		}
+	}
+
+	// Order rate: a runaway algo is stopped before it reaches the ring buffer
+	result.ChecksRun = append(result.ChecksRun, "order_rate")
+	if reason, ok := c.checkOrderRate(order.AccountID, cfg); !ok {
+		return CheckResult{
+			Passed:    false,
+			Reason:    fmt.Sprintf("account %s throttled: %s", order.AccountID, reason),
+			ChecksRun: result.ChecksRun,
+		}
+	}
 
 	// 1. Order size check
 	result.ChecksRun = append(result.ChecksRun, "order_size")
-	if order.Quantity > c.config.MaxOrderSize {
+	if order.Quantity > cfg.MaxOrderSize {
 		return CheckResult{
 			Passed:    false,
-			Reason:    fmt.Sprintf("order size %d exceeds max %d", order.Quantity, c.config.MaxOrderSize),
+			Reason:    fmt.Sprintf("order size %d exceeds max %d", order.Quantity, cfg.MaxOrderSize),
 			ChecksRun: result.ChecksRun,
 		}
 	}
@@ -94,10 +168,10 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 	if order.Price > 0 {
 		result.ChecksRun = append(result.ChecksRun, "order_value")
 		orderValue := order.Price * order.Quantity
-		if orderValue > c.config.MaxOrderValue {
+		if orderValue > cfg.MaxOrderValue {
 			return CheckResult{
 				Passed:    false,
-				Reason:    fmt.Sprintf("order value %s exceeds max %s", orders.FormatPrice(orderValue), orders.FormatPrice(c.config.MaxOrderValue)),
+				Reason:    fmt.Sprintf("order value %s exceeds max %s", orders.FormatPrice(orderValue), orders.FormatPrice(cfg.MaxOrderValue)),
 				ChecksRun: result.ChecksRun,
 			}
 		}
@@ -106,14 +180,14 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 	// 3. Price band check (for limit orders)
 	if order.Type == orders.OrderTypeLimit && order.Price > 0 {
 		result.ChecksRun = append(result.ChecksRun, "price_band")
-		if !c.checkPriceBand(order) {
+		if !c.checkPriceBand(order, cfg) {
 			refPrice := c.GetReferencePrice(order.Symbol)
 			return CheckResult{
 				Passed: false,
 				Reason: fmt.Sprintf("price %s outside band (ref: %s, band: %.0f%%)",
 					orders.FormatPrice(order.Price),
 					orders.FormatPrice(refPrice),
-					c.config.PriceBandPercent*100),
+					cfg.PriceBandPercent*100),
 				ChecksRun: result.ChecksRun,
 			}
 		}
@@ -121,11 +195,11 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 
 	// 4. Position limit check
 	result.ChecksRun = append(result.ChecksRun, "position_limit")
-	if !c.checkPositionLimit(order) {
+	if !c.checkPositionLimit(order, cfg) {
 		currentPos := c.GetPosition(order.AccountID, order.Symbol)
 		return CheckResult{
 			Passed:    false,
-			Reason:    fmt.Sprintf("would exceed position limit (current: %d, order: %d, max: %d)", currentPos, order.Quantity, c.config.MaxPositionSize),
+			Reason:    fmt.Sprintf("would exceed position limit (current: %d, order: %d, max: %d)", currentPos, order.Quantity, cfg.MaxPositionSize),
 			ChecksRun: result.ChecksRun,
 		}
 	}
@@ -134,11 +208,11 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 	if order.Price > 0 {
 		result.ChecksRun = append(result.ChecksRun, "daily_volume")
 		orderValue := order.Price * order.Quantity
-		if !c.checkDailyVolume(order.AccountID, orderValue) {
+		if !c.checkDailyVolume(order.AccountID, orderValue, cfg) {
 			currentVol := c.GetDailyVolume(order.AccountID)
 			return CheckResult{
 				Passed:    false,
-				Reason:    fmt.Sprintf("would exceed daily volume limit (current: %s, order: %s, max: %s)", orders.FormatPrice(currentVol), orders.FormatPrice(orderValue), orders.FormatPrice(c.config.MaxDailyVolume)),
+				Reason:    fmt.Sprintf("would exceed daily volume limit (current: %s, order: %s, max: %s)", orders.FormatPrice(currentVol), orders.FormatPrice(orderValue), orders.FormatPrice(cfg.MaxDailyVolume)),
 				ChecksRun: result.ChecksRun,
 			}
 		}
@@ -147,8 +221,38 @@ func (c *Checker) Check(order *orders.Order) CheckResult {
 	return result
 }
 
+// CheckBasket performs risk checks on every leg of a basket order.
+//
+// Acceptance is all-or-none: the basket fails if any leg fails on its own,
+// or if the legs together would breach an account's daily volume limit.
+// Returns the index of the failing leg (-1 if the basket passed).
+func (c *Checker) CheckBasket(legs []*orders.Order) (int, CheckResult) {
+	basketVolume := make(map[string]int64) // account -> value of legs checked so far
+
+	for i, leg := range legs {
+		result := c.Check(leg)
+		if !result.Passed {
+			return i, result
+		}
+
+		if leg.Price > 0 {
+			basketVolume[leg.AccountID] += leg.Price * leg.Quantity
+			cfg := c.configFor(leg.AccountID)
+			if !c.checkDailyVolume(leg.AccountID, basketVolume[leg.AccountID], cfg) {
+				return i, CheckResult{
+					Passed:    false,
+					Reason:    fmt.Sprintf("basket would exceed daily volume limit (basket: %s, max: %s)", orders.FormatPrice(basketVolume[leg.AccountID]), orders.FormatPrice(cfg.MaxDailyVolume)),
+					ChecksRun: append(result.ChecksRun, "basket_daily_volume"),
+				}
+			}
+		}
+	}
+
+	return -1, CheckResult{Passed: true}
+}
+
 // checkPriceBand verifies the order price is within acceptable range.
-func (c *Checker) checkPriceBand(order *orders.Order) bool {
+func (c *Checker) checkPriceBand(order *orders.Order, cfg Config) bool {
 	c.mu.RLock()
 	refPrice, exists := c.referencePrices[order.Symbol]
 	c.mu.RUnlock()
@@ -157,7 +261,7 @@ func (c *Checker) checkPriceBand(order *orders.Order) bool {
 		return true // No reference price, allow order
 	}
 
-	band := float64(refPrice) * c.config.PriceBandPercent
+	band := float64(refPrice) * cfg.PriceBandPercent
 	lowBound := refPrice - int64(band)
 	highBound := refPrice + int64(band)
 
@@ -165,7 +269,7 @@ func (c *Checker) checkPriceBand(order *orders.Order) bool {
 }
 
 // checkPositionLimit verifies the order won't exceed position limits.
-func (c *Checker) checkPositionLimit(order *orders.Order) bool {
+func (c *Checker) checkPositionLimit(order *orders.Order, cfg Config) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -183,8 +287,8 @@ func (c *Checker) checkPositionLimit(order *orders.Order) bool {
 	}
 
 	// Check against limit (absolute value)
-	limit := c.config.MaxPositionSize
-	if symLimit, exists := c.config.SymbolLimits[order.Symbol]; exists {
+	limit := cfg.MaxPositionSize
+	if symLimit, exists := cfg.SymbolLimits[order.Symbol]; exists {
 		limit = symLimit
 	}
 
@@ -195,12 +299,12 @@ func (c *Checker) checkPositionLimit(order *orders.Order) bool {
 }
 
 // checkDailyVolume verifies the order won't exceed daily volume limits.
-func (c *Checker) checkDailyVolume(accountID string, orderValue int64) bool {
+func (c *Checker) checkDailyVolume(accountID string, orderValue int64, cfg Config) bool {
 	c.mu.RLock()
 	currentVolume := c.dailyVolume[accountID]
 	c.mu.RUnlock()
 
-	return currentVolume+orderValue <= c.config.MaxDailyVolume
+	return currentVolume+orderValue <= cfg.MaxDailyVolume
 }
 
 // UpdatePosition updates the position for an account after a fill.
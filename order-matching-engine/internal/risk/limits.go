@@ -0,0 +1,101 @@
+package risk
+
+import (
+	"fmt"
+)
+
+// Account Limits
+//
+// A profile fits a class of accounts; now and then one account needs
+// something else - a market maker cleared for a larger position in one
+// name, a new client held to smaller orders until it has a track record.
+// Rather than defining a profile per exception, an account's limits can be
+// overridden one by one on top of its profile:
+//
+//	profile retail:  order size 10,000   position 50,000   volume $100,000
+//	overrides:       order size  2,000                     volume $250,000
+//	in effect:       order size  2,000   position 50,000   volume $250,000
+//
+// Zero fields of Limits are not overridden. An overridden position limit
+// also replaces the profile's per-symbol position limits for the account.
+// Overrides take effect on the account's next order.
+
+// Limits are an account's overrides of its profile's limits. Zero fields
+// are not overridden.
+type Limits struct {
+	MaxOrderSize    int64 // Maximum shares per order
+	MaxOrderValue   int64 // Maximum dollar value per order (in cents)
+	MaxPositionSize int64 // Maximum position size per symbol
+	MaxDailyVolume  int64 // Maximum daily trading volume (in cents)
+}
+
+// IsZero reports whether l overrides nothing.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Validate checks that no limit is negative.
+func (l Limits) Validate() error {
+	for name, v := range map[string]int64{
+		"max order size":    l.MaxOrderSize,
+		"max order value":   l.MaxOrderValue,
+		"max position size": l.MaxPositionSize,
+		"max daily volume":  l.MaxDailyVolume,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// apply returns config with l's overrides applied.
+func (l Limits) apply(config Config) Config {
+	if l.MaxOrderSize > 0 {
+		config.MaxOrderSize = l.MaxOrderSize
+	}
+	if l.MaxOrderValue > 0 {
+		config.MaxOrderValue = l.MaxOrderValue
+	}
+	if l.MaxPositionSize > 0 {
+		config.MaxPositionSize = l.MaxPositionSize
+		config.SymbolLimits = nil
+	}
+	if l.MaxDailyVolume > 0 {
+		config.MaxDailyVolume = l.MaxDailyVolume
+	}
+	return config
+}
+
+// SetLimits replaces an account's overrides. Zero Limits clear them.
+func (c *Checker) SetLimits(accountID string, limits Limits) error {
+	if accountID == "" {
+		return fmt.Errorf("account required")
+	}
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if limits.IsZero() {
+		delete(c.limits, accountID)
+		return nil
+	}
+	c.limits[accountID] = limits
+	return nil
+}
+
+// AccountLimits returns an account's overrides.
+func (c *Checker) AccountLimits(accountID string) Limits {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.limits[accountID]
+}
+
+// EffectiveConfig returns the limits in effect for an account: its
+// profile's, with its overrides applied.
+func (c *Checker) EffectiveConfig(accountID string) Config {
+	return c.configFor(accountID)
+}
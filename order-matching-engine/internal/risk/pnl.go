@@ -0,0 +1,352 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Daily Loss Limit (P&L Circuit Breaker)
+//
+// Order-level checks stop a single bad order; they don't stop an account
+// that is losing money one reasonable-looking order at a time. The checker
+// therefore tracks each account's intraday P&L:
+//
+//	Realized   = gains/losses locked in by closing trades (average cost)
+//	Unrealized = open position marked to the symbol's reference price
+//
+// When realized + unrealized falls below -MaxDailyLoss, the account's kill
+// switch trips: every new order is rejected until an operator reinstates
+// the account. Resting orders are left in the book.
+//
+// All values are in the same units as order value (price × quantity).
+//
+// The same fills are booked a second time, never re-based: Positions
+// reports each open position's average cost and the P&L since the
+// account's first fill, for an account asking whether it is up or down
+// overall rather than today.
+
+// symbolPnL is an account's P&L in one symbol.
+type symbolPnL struct {
+	position int64 // Signed: +long, -short
+	openCost int64 // Cost basis of the open position (always >= 0)
+	realized int64 // Realized since the start of the day
+}
+
+// apply books a fill of signed quantity qty at price.
+func (p *symbolPnL) apply(qty, price int64) {
+	// Closing (part of) the open position realizes P&L against average cost
+	if p.position != 0 && (p.position > 0) != (qty > 0) {
+		held := abs(p.position)
+		closed := min(abs(qty), held)
+		basis := p.openCost * closed / held
+
+		if p.position > 0 {
+			p.realized += price*closed - basis
+			p.position -= closed
+		} else {
+			p.realized += basis - price*closed
+			p.position += closed
+		}
+		p.openCost -= basis
+
+		if qty > 0 {
+			qty -= closed
+		} else {
+			qty += closed
+		}
+	}
+
+	// Whatever is left opens or adds to the position
+	p.position += qty
+	p.openCost += price * abs(qty)
+}
+
+// unrealized marks the open position to refPrice (0 if there is no price).
+func (p *symbolPnL) unrealized(refPrice int64) int64 {
+	if p.position == 0 || refPrice == 0 {
+		return 0
+	}
+	if p.position > 0 {
+		return refPrice*p.position - p.openCost
+	}
+	return p.openCost - refPrice*(-p.position)
+}
+
+// SymbolPnL is the reported P&L for one account and symbol.
+type SymbolPnL struct {
+	Symbol     string `json:"symbol"`
+	Position   int64  `json:"position"`
+	Realized   int64  `json:"realized"`
+	Unrealized int64  `json:"unrealized"`
+}
+
+// AccountPnL is an account's intraday P&L across all symbols.
+type AccountPnL struct {
+	AccountID  string      `json:"account_id"`
+	Realized   int64       `json:"realized"`
+	Unrealized int64       `json:"unrealized"`
+	Total      int64       `json:"total"`
+	Symbols    []SymbolPnL `json:"symbols"`
+	Killed     bool        `json:"killed"`
+	KillReason string      `json:"kill_reason,omitempty"`
+}
+
+// EventType identifies a risk event.
+type EventType string
+
+const (
+	EventKillSwitchTripped EventType = "kill_switch_tripped"
+	EventReinstated        EventType = "reinstated"
+	EventOrderRateBreached EventType = "order_rate_breached" // See rate.go
+)
+
+// Limit checks that fire risk events.
+const (
+	CheckDailyLoss = "daily_loss"
+	CheckOrderRate = "order_rate"
+)
+
+// Event reports an account-level risk action.
+type Event struct {
+	Type      EventType `json:"type"`
+	Check     string    `json:"check,omitempty"` // Limit check that fired it (empty for an operator's action)
+	AccountID string    `json:"account_id"`
+	Reason    string    `json:"reason"`
+	PnL       int64     `json:"pnl"`                // Total P&L when the event fired
+	Limit     int64     `json:"limit"`              // The check's limit: MaxDailyLoss, or MaxOrderRate
+	Operator  bool      `json:"operator,omitempty"` // Tripped or cleared by an operator, not a limit
+	Timestamp int64     `json:"timestamp"`
+}
+
+// OnEvent registers a callback for risk events. It is called outside the
+// checker's lock, from whichever goroutine caused the event.
+func (c *Checker) OnEvent(fn func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvent = fn
+}
+
+// RecordFill books a fill in the account's P&L.
+func (c *Checker) RecordFill(accountID, symbol string, side orders.Side, quantity, price int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if side == orders.SideSell {
+		quantity = -quantity
+	}
+	for _, books := range []map[string]map[string]*symbolPnL{c.pnl, c.positionPnL} {
+		if books[accountID] == nil {
+			books[accountID] = make(map[string]*symbolPnL)
+		}
+		p := books[accountID][symbol]
+		if p == nil {
+			p = &symbolPnL{}
+			books[accountID][symbol] = p
+		}
+		p.apply(quantity, price)
+	}
+}
+
+// CheckLossLimits trips the kill switch of every account holding symbol, or
+// with P&L booked in it, whose daily loss now exceeds its profile's
+// MaxDailyLoss. Call it after fills are recorded and the symbol's reference
+// price is updated.
+func (c *Checker) CheckLossLimits(symbol string) {
+	c.mu.Lock()
+	var tripped []Event
+	for accountID, symbols := range c.pnl {
+		if _, exists := symbols[symbol]; !exists {
+			continue
+		}
+		if _, killed := c.killed[accountID]; killed {
+			continue
+		}
+		limit := c.configForLocked(accountID).MaxDailyLoss
+		if limit <= 0 {
+			continue
+		}
+		total := c.totalPnLLocked(accountID)
+		if total >= -limit {
+			continue
+		}
+
+		reason := fmt.Sprintf("daily loss %s exceeds limit %s",
+			orders.FormatPrice(-total), orders.FormatPrice(limit))
+		c.killed[accountID] = reason
+		tripped = append(tripped, Event{
+			Type:      EventKillSwitchTripped,
+			Check:     CheckDailyLoss,
+			AccountID: accountID,
+			Reason:    reason,
+			PnL:       total,
+			Limit:     limit,
+			Timestamp: time.Now().UnixNano(),
+		})
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+
+	if onEvent != nil {
+		for _, event := range tripped {
+			onEvent(event)
+		}
+	}
+}
+
+// totalPnLLocked returns an account's realized + unrealized P&L.
+// Callers must hold c.mu.
+func (c *Checker) totalPnLLocked(accountID string) int64 {
+	var total int64
+	for symbol, p := range c.pnl[accountID] {
+		total += p.realized + p.unrealized(c.referencePrices[symbol])
+	}
+	return total
+}
+
+// GetPnL returns an account's intraday P&L, marked to reference prices.
+func (c *Checker) GetPnL(accountID string) AccountPnL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := AccountPnL{AccountID: accountID, Symbols: make([]SymbolPnL, 0)}
+	for symbol, p := range c.pnl[accountID] {
+		sym := SymbolPnL{
+			Symbol:     symbol,
+			Position:   p.position,
+			Realized:   p.realized,
+			Unrealized: p.unrealized(c.referencePrices[symbol]),
+		}
+		result.Realized += sym.Realized
+		result.Unrealized += sym.Unrealized
+		result.Symbols = append(result.Symbols, sym)
+	}
+	sort.Slice(result.Symbols, func(i, j int) bool { return result.Symbols[i].Symbol < result.Symbols[j].Symbol })
+	result.Total = result.Realized + result.Unrealized
+	result.KillReason, result.Killed = c.killed[accountID]
+	return result
+}
+
+// PositionPnL is an account's position and P&L in one symbol since its
+// first fill.
+type PositionPnL struct {
+	Symbol     string `json:"symbol"`
+	Position   int64  `json:"position"`   // Signed: +long, -short
+	AvgPrice   int64  `json:"avg_price"`  // Average cost of the open position (0 if flat)
+	MarkPrice  int64  `json:"mark_price"` // Reference price it is marked to (0 if none yet)
+	Realized   int64  `json:"realized"`
+	Unrealized int64  `json:"unrealized"`
+}
+
+// Positions returns an account's P&L per symbol since its first fill, by
+// symbol, marked to reference prices. Unlike GetPnL it is never re-based.
+func (c *Checker) Positions(accountID string) []PositionPnL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]PositionPnL, 0, len(c.positionPnL[accountID]))
+	for symbol, p := range c.positionPnL[accountID] {
+		pos := PositionPnL{
+			Symbol:     symbol,
+			Position:   p.position,
+			MarkPrice:  c.referencePrices[symbol],
+			Realized:   p.realized,
+			Unrealized: p.unrealized(c.referencePrices[symbol]),
+		}
+		if p.position != 0 {
+			pos.AvgPrice = p.openCost / abs(p.position)
+		}
+		result = append(result, pos)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+	return result
+}
+
+// IsKilled reports whether an account's kill switch has tripped, and why.
+func (c *Checker) IsKilled(accountID string) (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	reason, killed := c.killed[accountID]
+	return killed, reason
+}
+
+// Kill trips an account's kill switch on an operator's say-so, whatever its
+// P&L. Returns false if it was already tripped, keeping the first reason.
+func (c *Checker) Kill(accountID, reason string) bool {
+	c.mu.Lock()
+	if _, killed := c.killed[accountID]; killed {
+		c.mu.Unlock()
+		return false
+	}
+	c.killed[accountID] = reason
+	event := Event{
+		Type:      EventKillSwitchTripped,
+		AccountID: accountID,
+		Reason:    reason,
+		PnL:       c.totalPnLLocked(accountID),
+		Limit:     c.configForLocked(accountID).MaxDailyLoss,
+		Operator:  true,
+		Timestamp: time.Now().UnixNano(),
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+
+	if onEvent != nil {
+		onEvent(event)
+	}
+	return true
+}
+
+// Reinstate clears an account's kill switch. Its P&L is not reset, so an
+// account still over the limit trips again on its next fill or price move.
+// Returns false if the account was not killed.
+func (c *Checker) Reinstate(accountID string) bool {
+	c.mu.Lock()
+	if _, killed := c.killed[accountID]; !killed {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.killed, accountID)
+	event := Event{
+		Type:      EventReinstated,
+		AccountID: accountID,
+		Reason:    "reinstated by operator",
+		PnL:       c.totalPnLLocked(accountID),
+		Limit:     c.configForLocked(accountID).MaxDailyLoss,
+		Operator:  true,
+		Timestamp: time.Now().UnixNano(),
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+
+	if onEvent != nil {
+		onEvent(event)
+	}
+	return true
+}
+
+// ResetDailyPnL starts a new trading day: realized P&L is cleared and open
+// positions are re-based at the current reference price, so only today's
+// moves count toward the loss limit. Kill switches stay tripped.
+func (c *Checker) ResetDailyPnL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, symbols := range c.pnl {
+		for symbol, p := range symbols {
+			p.realized = 0
+			if refPrice := c.referencePrices[symbol]; refPrice > 0 {
+				p.openCost = refPrice * abs(p.position)
+			}
+		}
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Risk Profiles
+//
+// Accounts don't all deserve the same limits. A market maker quotes both
+// sides all day and needs large position and volume limits; a retail
+// account should be stopped long before that; a sponsored-access client
+// (a broker's customer trading on the broker's membership) gets the
+// tightest checks, because the sponsoring broker carries its risk.
+//
+// A profile is a named Config. Each account is assigned at most one
+// profile; accounts without one use the checker's default Config.
+// Assignments take effect on the account's next order.
+
+// Built-in profile names.
+const (
+	ProfileRetail      = "retail"
+	ProfileSponsored   = "sponsored"
+	ProfileMarketMaker = "market-maker"
+	DefaultProfileName = "default" // Reported for accounts without a profile
+)
+
+// DefaultProfiles returns the built-in profiles.
+func DefaultProfiles() map[string]Config {
+	return map[string]Config{
+		ProfileRetail: {
+			MaxOrderSize:     10000,    // 10,000 shares
+			MaxOrderValue:    1000000,  // $10,000
+			MaxPositionSize:  50000,    // 50,000 shares
+			MaxDailyVolume:   10000000, // $100,000 daily
+			PriceBandPercent: 0.05,     // 5% from reference price
+			MaxDailyLoss:     500000,   // $5,000
+			MaxOrderRate:     50,       // 50 orders per second
+		},
+		ProfileSponsored: {
+			MaxOrderSize:     5000,    // 5,000 shares
+			MaxOrderValue:    500000,  // $5,000
+			MaxPositionSize:  20000,   // 20,000 shares
+			MaxDailyVolume:   5000000, // $50,000 daily
+			PriceBandPercent: 0.03,    // 3% from reference price
+			MaxDailyLoss:     200000,  // $2,000
+			MaxOrderRate:     20,      // 20 orders per second
+			RateBreachKill:   3,       // Killed on the third breach in a day
+		},
+		ProfileMarketMaker: {
+			MaxOrderSize:     500000,     // 500,000 shares
+			MaxOrderValue:    50000000,   // $500,000
+			MaxPositionSize:  5000000,    // 5,000,000 shares
+			MaxDailyVolume:   1000000000, // $10,000,000 daily
+			PriceBandPercent: 0.20,       // 20% from reference price
+			MaxOrderRate:     5000,       // 5,000 orders per second
+		},
+	}
+}
+
+// SetProfile defines or replaces a named profile. Accounts already assigned
+// to it pick up the new limits on their next order.
+func (c *Checker) SetProfile(name string, config Config) error {
+	if name == "" || name == DefaultProfileName {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.profiles[name] = config
+	return nil
+}
+
+// AssignProfile assigns an account to a named profile. Assigning
+// DefaultProfileName returns the account to the default Config.
+func (c *Checker) AssignProfile(accountID, name string) error {
+	if accountID == "" {
+		return fmt.Errorf("account required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == DefaultProfileName {
+		delete(c.accountProfile, accountID)
+		return nil
+	}
+	if _, exists := c.profiles[name]; !exists {
+		return fmt.Errorf("unknown risk profile %q", name)
+	}
+	c.accountProfile[accountID] = name
+	return nil
+}
+
+// AccountProfile returns the name of an account's profile and its limits.
+func (c *Checker) AccountProfile(accountID string) (string, Config) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if name, assigned := c.accountProfile[accountID]; assigned {
+		return name, c.profiles[name]
+	}
+	return DefaultProfileName, c.config
+}
+
+// ProfileNames returns the defined profile names, sorted.
+func (c *Checker) ProfileNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configFor returns the limits that apply to an account, overrides
+// included.
+func (c *Checker) configFor(accountID string) Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.configForLocked(accountID)
+}
+
+// configForLocked is configFor for callers that hold c.mu.
+func (c *Checker) configForLocked(accountID string) Config {
+	config := c.config
+	if name, assigned := c.accountProfile[accountID]; assigned {
+		config = c.profiles[name]
+	}
+	return c.limits[accountID].apply(config) // Overrides, see limits.go
+}
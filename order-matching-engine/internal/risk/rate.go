@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"fmt"
+	"time"
+)
+
+// Order Rate Limit
+//
+// A runaway algo sends orders as fast as it can loop, each one passing
+// every order-level check. MaxOrderRate caps the orders an account may
+// send in any one second, over a sliding window rather than fixed
+// one-second buckets, so a burst straddling a second boundary can't get
+// through twice the limit:
+//
+//	limit 3/s   orders at  0.0  0.2  0.4 | 0.9 rejected | 1.0 ok  1.1 ok
+//	                                       (3 in the     (0.0 and 0.2
+//	                                        last second)  have aged out)
+//
+// Each account keeps the times of its last MaxOrderRate accepted orders;
+// an order is accepted if the oldest of them is a second old. Rejected
+// orders don't count, so a throttled account gets through again as soon
+// as it slows down.
+//
+// Going over the limit is a breach, reported once as an
+// EventOrderRateBreached until the account's orders are accepted again.
+// With RateBreachKill set, the account's kill switch trips on that many
+// breaches in a trading day (see ResetRateBreaches): an algo that keeps
+// hitting the limit is stopped rather than throttled forever.
+
+// rateWindow is an account's recently accepted orders.
+type rateWindow struct {
+	times     []int64 // Ring of acceptance times, nanoseconds; 0 = unused
+	next      int     // Slot of the oldest time, overwritten next
+	throttled bool    // Over the limit since the last accepted order
+	breaches  int     // Breaches since the start of the trading day
+}
+
+// SetClock replaces the clock order rates are measured by, for tests.
+func (c *Checker) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// checkOrderRate counts an order against its account's rate limit. Returns
+// false, and why, if the account is over it.
+func (c *Checker) checkOrderRate(accountID string, cfg Config) (string, bool) {
+	if cfg.MaxOrderRate <= 0 {
+		return "", true
+	}
+
+	c.mu.Lock()
+	now := c.now().UnixNano()
+	w := c.rates[accountID]
+	if w == nil || int64(len(w.times)) != cfg.MaxOrderRate {
+		// New account, or its limit changed: start a fresh window
+		breaches := 0
+		if w != nil {
+			breaches = w.breaches
+		}
+		w = &rateWindow{times: make([]int64, cfg.MaxOrderRate), breaches: breaches}
+		c.rates[accountID] = w
+	}
+
+	if oldest := w.times[w.next]; oldest == 0 || now-oldest >= int64(time.Second) {
+		w.times[w.next] = now
+		w.next = (w.next + 1) % len(w.times)
+		w.throttled = false
+		c.mu.Unlock()
+		return "", true
+	}
+
+	reason := fmt.Sprintf("order rate exceeds %d per second", cfg.MaxOrderRate)
+	var fired []Event
+	if !w.throttled {
+		w.throttled = true
+		w.breaches++
+		fired = append(fired, Event{
+			Type:      EventOrderRateBreached,
+			Check:     CheckOrderRate,
+			AccountID: accountID,
+			Reason:    reason,
+			PnL:       c.totalPnLLocked(accountID),
+			Limit:     cfg.MaxOrderRate,
+			Timestamp: now,
+		})
+		_, killed := c.killed[accountID]
+		if cfg.RateBreachKill > 0 && w.breaches >= cfg.RateBreachKill && !killed {
+			killReason := fmt.Sprintf("order rate limit of %d per second breached %d times today",
+				cfg.MaxOrderRate, w.breaches)
+			c.killed[accountID] = killReason
+			fired = append(fired, Event{
+				Type:      EventKillSwitchTripped,
+				Check:     CheckOrderRate,
+				AccountID: accountID,
+				Reason:    killReason,
+				PnL:       c.totalPnLLocked(accountID),
+				Limit:     cfg.MaxOrderRate,
+				Timestamp: now,
+			})
+		}
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+
+	if onEvent != nil {
+		for _, event := range fired {
+			onEvent(event)
+		}
+	}
+	return reason, false
+}
+
+// RateBreaches returns how many times an account has gone over its order
+// rate limit since the start of the trading day.
+func (c *Checker) RateBreaches(accountID string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if w := c.rates[accountID]; w != nil {
+		return w.breaches
+	}
+	return 0
+}
+
+// ResetRateBreaches starts a new trading day for order rate breaches
+// (called at start of trading day). Kill switches stay tripped.
+func (c *Checker) ResetRateBreaches() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.rates {
+		w.breaches = 0
+	}
+}
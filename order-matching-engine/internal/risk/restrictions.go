@@ -0,0 +1,116 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Restricted Lists
+//
+// Compliance keeps lists of symbols that may not be traded:
+//
+//	an account's list   symbols that account may not trade, e.g. its
+//	                    employer's stock, or names on an insider list
+//	the global list     symbols no account may trade, e.g. pending a
+//	                    corporate action or a regulator's order
+//
+// Orders in a restricted symbol are rejected with a stable reject code
+// (RejectRestrictedSymbol or RejectRestrictedList), so clients can tell a
+// compliance reject from a limit breach. Unlike a halt, a restriction
+// leaves the symbol's session alone: other accounts trade on, and resting
+// orders stay in the book. Restrictions take effect on the next order.
+
+// Reject codes of the restriction checks, in CheckResult.Code.
+const (
+	RejectRestrictedSymbol = "RESTRICTED_SYMBOL" // On the account's restricted list
+	RejectRestrictedList   = "RESTRICTED_LIST"   // On the global restricted list
+)
+
+// globalList keys the global restricted list among the accounts' lists.
+const globalList = ""
+
+// Restrict puts a symbol on an account's restricted list, or with an empty
+// accountID the global list. A symbol already on the list gets the new
+// reason.
+func (c *Checker) Restrict(accountID, symbol, reason string) error {
+	if symbol == "" {
+		return fmt.Errorf("symbol required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.restricted[accountID] == nil {
+		c.restricted[accountID] = make(map[string]string)
+	}
+	c.restricted[accountID][symbol] = reason
+	return nil
+}
+
+// Unrestrict takes a symbol off an account's restricted list, or with an
+// empty accountID the global list. Returns false if it was not on it.
+func (c *Checker) Unrestrict(accountID, symbol string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.restricted[accountID][symbol]; !ok {
+		return false
+	}
+	delete(c.restricted[accountID], symbol)
+	if len(c.restricted[accountID]) == 0 {
+		delete(c.restricted, accountID)
+	}
+	return true
+}
+
+// Restrictions returns an account's restricted list, or with an empty
+// accountID the global list: symbol -> reason.
+func (c *Checker) Restrictions(accountID string) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := make(map[string]string, len(c.restricted[accountID]))
+	for symbol, reason := range c.restricted[accountID] {
+		list[symbol] = reason
+	}
+	return list
+}
+
+// RestrictedAccounts returns the accounts with a restricted list of their
+// own, sorted.
+func (c *Checker) RestrictedAccounts() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	accounts := make([]string, 0, len(c.restricted))
+	for accountID := range c.restricted {
+		if accountID != globalList {
+			accounts = append(accounts, accountID)
+		}
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// checkRestricted returns the reject code and reason if an account may not
+// trade a symbol.
+func (c *Checker) checkRestricted(accountID, symbol string) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if reason, ok := c.restricted[globalList][symbol]; ok {
+		return RejectRestrictedList, restrictedReason(symbol+" is on the restricted list", reason), true
+	}
+	if reason, ok := c.restricted[accountID][symbol]; ok {
+		return RejectRestrictedSymbol, restrictedReason(fmt.Sprintf("account %s may not trade %s", accountID, symbol), reason), true
+	}
+	return "", "", false
+}
+
+// restrictedReason appends a restriction's reason, if it has one.
+func restrictedReason(what, why string) string {
+	if why == "" {
+		return what
+	}
+	return what + ": " + why
+}
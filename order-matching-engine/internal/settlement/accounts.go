@@ -0,0 +1,121 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Account Lifecycle
+//
+// Accounts are opened with some cash, and cash and shares move in and out
+// of them:
+//
+//	OpenAccount   a new account, refused if the ID is taken
+//	Deposit       cash and/or shares in
+//	Withdraw      cash and/or shares out, only what is free
+//
+// What is free is what buying power leaves (see buyingpower.go): cash less
+// what open buys hold and unsettled buys owe, shares less what open sells
+// hold and unsettled sells owe. Collateral is not cash and is withdrawn
+// with PostCollateral instead. An account under a margin call withdraws
+// nothing until the call is met.
+//
+// Every change is journaled like any other. The server sequences them
+// through the event processor, so a withdrawal is checked in order with
+// the orders that spend the same cash, and logs them as AccountEvents.
+
+// ErrAccountExists is returned when opening an account whose ID is taken.
+var ErrAccountExists = errors.New("account already exists")
+
+// ErrAccountNotFound is returned for a deposit or withdrawal to an account
+// that was never opened.
+var ErrAccountNotFound = errors.New("account not found")
+
+// OpenAccount opens an account with initial cash.
+func (ch *ClearingHouse) OpenAccount(accountID string, cash int64) error {
+	if accountID == "" {
+		return errors.New("account ID required")
+	}
+	if cash < 0 {
+		return fmt.Errorf("initial cash %s is negative", orders.FormatPrice(cash))
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.accounts[accountID] != nil {
+		return fmt.Errorf("%w: %s", ErrAccountExists, accountID)
+	}
+	ch.accounts[accountID] = &Account{ID: accountID, Cash: cash, Holdings: make(map[string]int64)}
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
+	return nil
+}
+
+// Deposit adds cash, and shares of symbol, to an account.
+func (ch *ClearingHouse) Deposit(accountID string, cash int64, symbol string, shares int64) error {
+	if err := checkMovement(cash, symbol, shares); err != nil {
+		return err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	acct := ch.accounts[accountID]
+	if acct == nil {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	acct.Cash += cash
+	if shares > 0 {
+		acct.Holdings[symbol] += shares
+	}
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
+	return nil
+}
+
+// Withdraw takes cash, and shares of symbol, out of an account. It is
+// refused, taking nothing, if either is more than the account has free or
+// the account has a margin call.
+func (ch *ClearingHouse) Withdraw(accountID string, cash int64, symbol string, shares int64) error {
+	if err := checkMovement(cash, symbol, shares); err != nil {
+		return err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	acct := ch.accounts[accountID]
+	switch {
+	case acct == nil:
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	case ch.calls[accountID] > 0:
+		return fmt.Errorf("%s has a margin call for %s", accountID, orders.FormatPrice(ch.calls[accountID]))
+	case cash > ch.buyingPower(accountID):
+		return fmt.Errorf("insufficient cash: %s has %s free", accountID, orders.FormatPrice(max(ch.buyingPower(accountID), 0)))
+	case shares > ch.availableShares(accountID, symbol):
+		return fmt.Errorf("insufficient shares: %s has %d %s free", accountID, max(ch.availableShares(accountID, symbol), 0), symbol)
+	}
+	acct.Cash -= cash
+	if shares > 0 {
+		acct.Holdings[symbol] -= shares
+		if acct.Holdings[symbol] == 0 {
+			delete(acct.Holdings, symbol)
+		}
+	}
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
+	return nil
+}
+
+// checkMovement checks the amounts of a deposit or withdrawal.
+func checkMovement(cash int64, symbol string, shares int64) error {
+	switch {
+	case cash < 0 || shares < 0:
+		return errors.New("amounts must not be negative")
+	case cash == 0 && shares == 0:
+		return errors.New("nothing to move: give cash or shares")
+	case shares > 0 && symbol == "":
+		return errors.New("symbol required for shares")
+	}
+	return nil
+}
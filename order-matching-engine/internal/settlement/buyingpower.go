@@ -0,0 +1,196 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Buying Power
+//
+// Cash and shares only move at settlement, T+N days after the trade, so an
+// account's balances alone say nothing about what it has already promised.
+// Buying power subtracts every commitment not yet settled:
+//
+//	buying power     = cash - held for open buys - owed for unsettled buys
+//	available shares = holdings - held for open sells - owed for unsettled sells
+//
+//	Cash $100,000
+//	  BUY 200 AAPL @ $150 rests        held     $30,000  → buying power $70,000
+//	  100 of it fills @ $149.50         held     $15,000
+//	                                    owed     $14,950  → buying power $70,050
+//	  T+2 settles                       cash     $85,050  → buying power $70,050
+//
+// An order holds what it could cost when it enters the book: a buy its
+// limit price (a pegged buy its peg limit, a market buy the cost of walking
+// the book) times its remaining quantity, a sell its remaining shares. A
+// fill turns the filled part of the hold into an unsettled obligation, at
+// the price actually traded; a cancel releases the rest. Proceeds of sales
+// are not spendable until they settle, and fees are charged to cash when
+// the trade is recorded rather than held in advance.
+//
+// Holds are not part of the exported State: they follow from the resting
+// orders, and are rebuilt from the books after recovery.
+
+// ErrInsufficientBuyingPower is returned when an account cannot cover an
+// order.
+var ErrInsufficientBuyingPower = errors.New("insufficient buying power")
+
+// Need is what an order needs set aside: cash for a buy, shares for a sell.
+type Need struct {
+	AccountID string
+	Symbol    string
+	Cash      int64
+	Shares    int64
+}
+
+// exposure is what an account has committed but not yet settled.
+type exposure struct {
+	heldCash        int64
+	heldShares      map[string]int64
+	unsettledCash   int64                // Owed for buys not yet settled
+	unsettledShares map[string]int64     // Owed for sells not yet settled
+	positions       map[string]*position // Unsettled trades netted per symbol (see margin.go)
+}
+
+// exposureOf returns an account's exposure, creating it if needed. Caller
+// must hold the lock.
+func (ch *ClearingHouse) exposureOf(accountID string) *exposure {
+	exp := ch.exposures[accountID]
+	if exp == nil {
+		exp = &exposure{heldShares: make(map[string]int64), unsettledShares: make(map[string]int64), positions: make(map[string]*position)}
+		ch.exposures[accountID] = exp
+	}
+	return exp
+}
+
+// BuyingPower returns the cash an account has free for new buy orders.
+func (ch *ClearingHouse) BuyingPower(accountID string) int64 {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.buyingPower(accountID)
+}
+
+func (ch *ClearingHouse) buyingPower(accountID string) int64 {
+	var cash int64
+	if acct := ch.accounts[accountID]; acct != nil {
+		cash = acct.Cash
+	}
+	if exp := ch.exposures[accountID]; exp != nil {
+		cash -= exp.heldCash + exp.unsettledCash
+	}
+	return cash
+}
+
+// AvailableShares returns the shares of a symbol an account has free for
+// new sell orders.
+func (ch *ClearingHouse) AvailableShares(accountID, symbol string) int64 {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.availableShares(accountID, symbol)
+}
+
+func (ch *ClearingHouse) availableShares(accountID, symbol string) int64 {
+	var shares int64
+	if acct := ch.accounts[accountID]; acct != nil {
+		shares = acct.Holdings[symbol]
+	}
+	if exp := ch.exposures[accountID]; exp != nil {
+		shares -= exp.heldShares[symbol] + exp.unsettledShares[symbol]
+	}
+	return shares
+}
+
+// Held returns what an open order holds.
+func (ch *ClearingHouse) Held(orderID uint64) Need {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.holds[orderID]
+}
+
+// CanAfford checks that accounts can cover needs on top of what they
+// already hold, all together, as for the legs of a basket. Returns an
+// error wrapping ErrInsufficientBuyingPower for the first one short.
+func (ch *ClearingHouse) CanAfford(needs ...Need) error {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	type key struct{ account, symbol string }
+	cash := make(map[string]int64)
+	shares := make(map[key]int64)
+	for _, need := range needs {
+		cash[need.AccountID] += need.Cash
+		shares[key{need.AccountID, need.Symbol}] += need.Shares
+	}
+	for _, need := range needs {
+		if total := cash[need.AccountID]; total > 0 {
+			if free := ch.buyingPower(need.AccountID); total > free {
+				return fmt.Errorf("%w: %s needs %s, has %s", ErrInsufficientBuyingPower,
+					need.AccountID, orders.FormatPrice(total), orders.FormatPrice(free))
+			}
+		}
+		if total := shares[key{need.AccountID, need.Symbol}]; total > 0 {
+			if free := ch.availableShares(need.AccountID, need.Symbol); total > free {
+				return fmt.Errorf("%w: %s needs %d %s, has %d", ErrInsufficientBuyingPower,
+					need.AccountID, total, need.Symbol, free)
+			}
+		}
+	}
+	return nil
+}
+
+// Hold sets what an open order holds, replacing what it held before. A
+// zero need releases it.
+func (ch *ClearingHouse) Hold(orderID uint64, need Need) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.release(orderID)
+	if need.Cash == 0 && need.Shares == 0 {
+		return
+	}
+	exp := ch.exposureOf(need.AccountID)
+	exp.heldCash += need.Cash
+	exp.heldShares[need.Symbol] += need.Shares
+	ch.holds[orderID] = need
+}
+
+// Release releases what an order holds, once it is filled or cancelled.
+func (ch *ClearingHouse) Release(orderID uint64) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.release(orderID)
+}
+
+// ReleaseSymbol releases every hold of a symbol, e.g. once its book moved
+// to another shard.
+func (ch *ClearingHouse) ReleaseSymbol(symbol string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for orderID, need := range ch.holds {
+		if need.Symbol == symbol {
+			ch.release(orderID)
+		}
+	}
+}
+
+// release drops an order's hold. Caller must hold the lock.
+func (ch *ClearingHouse) release(orderID uint64) {
+	need, held := ch.holds[orderID]
+	if !held {
+		return
+	}
+	exp := ch.exposureOf(need.AccountID)
+	exp.heldCash -= need.Cash
+	exp.heldShares[need.Symbol] -= need.Shares
+	delete(ch.holds, orderID)
+}
+
+// owe records a trade's obligations until it settles, or stops recording
+// them once it has (sign -1). Caller must hold the lock.
+func (ch *ClearingHouse) owe(trade *Trade, sign int64) {
+	ch.exposureOf(trade.BuyerAccount).unsettledCash += sign * trade.Price * trade.Quantity
+	ch.exposureOf(trade.SellerAccount).unsettledShares[trade.Symbol] += sign * trade.Quantity
+	ch.exposureOf(trade.BuyerAccount).position(trade.Symbol).add(sign*trade.Quantity, trade.Price)
+	ch.exposureOf(trade.SellerAccount).position(trade.Symbol).add(-sign*trade.Quantity, trade.Price)
+}
@@ -39,9 +39,11 @@ package settlement
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/rishav/order-matching-engine/internal/calendar"
 	"github.com/rishav/order-matching-engine/internal/orders"
 )
 
@@ -84,6 +86,8 @@ type Trade struct {
 	TradeTime     time.Time
 	SettleDate    time.Time
 	Status        TradeStatus
+	BuyerFee      int64 // Trading fee charged to the buyer in cents (negative = rebate)
+	SellerFee     int64 // Trading fee charged to the seller in cents (negative = rebate)
 }
 
 // NetPosition represents a netted position for an account/symbol pair.
@@ -101,16 +105,20 @@ type SettlementInstruction struct {
 	ToAccount    string
 	Symbol       string
 	Quantity     int64
-	CashAmount   int64 // In cents
+	CashAmount   int64 // Paid by ToAccount to FromAccount, in cents
 	SettleDate   time.Time
 	Status       TradeStatus
+	Attempts     int       // Failed settlement attempts (see fails.go)
+	NextAttempt  time.Time // When a failing instruction is retried (zero = SettleDate)
 }
 
 // Account represents an account's balances.
 type Account struct {
-	ID       string
-	Cash     int64            // Cash balance in cents
-	Holdings map[string]int64 // symbol -> quantity
+	ID         string
+	Cash       int64            // Cash balance in cents
+	Holdings   map[string]int64 // symbol -> quantity
+	Fees       int64            // Net trading fees paid in cents (negative = net rebates)
+	Collateral int64            // Cash posted as margin collateral in cents (see margin.go)
 }
 
 // ClearingHouse manages the clearing and settlement process.
@@ -120,6 +128,35 @@ type ClearingHouse struct {
 	instructions []SettlementInstruction
 	mu           sync.RWMutex
 	settlementDays int // T+N settlement (default 2)
+
+	// now is the clock trade and settlement dates are taken from
+	now func() time.Time
+
+	// Business days settlement counts, per symbol's market (nil = weekdays)
+	calendars *calendar.Set
+	marketOf  func(symbol string) string
+
+	// onFail is invoked for each instruction that fails to settle
+	onFail func(instr SettlementInstruction, reason string)
+
+	// Failure handling and settlement events (see fails.go)
+	fails    FailConfig
+	events   []SettlementEvent
+	eventSeq uint64
+	onEvent  func(event SettlementEvent)
+
+	// Durable copy of the books (see journal.go; nil = in memory only)
+	journal *clearingJournal
+
+	// Buying power (see buyingpower.go)
+	holds     map[uint64]Need      // Order ID -> what it holds
+	exposures map[string]*exposure // Account -> held and unsettled amounts
+
+	// Margin (see margin.go)
+	margin       MarginConfig
+	markOf       func(symbol string) int64
+	calls        map[string]int64 // Account -> open margin call
+	onMarginCall func(m Margin)
 }
 
 // NewClearingHouse creates a new clearing house.
@@ -128,9 +165,64 @@ func NewClearingHouse() *ClearingHouse {
 		trades:         make(map[uint64]*Trade),
 		accounts:       make(map[string]*Account),
 		settlementDays: 2,
+		now:            time.Now,
+		holds:          make(map[uint64]Need),
+		exposures:      make(map[string]*exposure),
+		calls:          make(map[string]int64),
 	}
 }
 
+// OnSettlementFail registers a hook invoked each time an instruction fails
+// to settle, retried or not (e.g. to alert operations). The hook runs with the clearing
+// house locked, so it must not call back into the clearing house.
+func (ch *ClearingHouse) OnSettlementFail(fn func(instr SettlementInstruction, reason string)) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.onFail = fn
+}
+
+// SetSettlementDays sets the settlement cycle: trades settle n business
+// days after the trade date (T+n; 0 settles on the trade date). Trades
+// already recorded keep their settle dates.
+func (ch *ClearingHouse) SetSettlementDays(n int) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.settlementDays = n
+}
+
+// SettlementDays returns the settlement cycle's N.
+func (ch *ClearingHouse) SettlementDays() int {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.settlementDays
+}
+
+// SetClock replaces the clock trade dates and the settlement cycle run on
+// (default time.Now), e.g. to drive the cycle from a test.
+func (ch *ClearingHouse) SetClock(now func() time.Time) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.now = now
+}
+
+// SetCalendars sets the market holiday calendars settlement dates are
+// counted in; marketOf maps a symbol to its market.
+func (ch *ClearingHouse) SetCalendars(calendars *calendar.Set, marketOf func(symbol string) string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.calendars = calendars
+	ch.marketOf = marketOf
+}
+
+// calendarFor returns the calendar of a symbol's market.
+func (ch *ClearingHouse) calendarFor(symbol string) *calendar.Calendar {
+	market := ""
+	if ch.marketOf != nil {
+		market = ch.marketOf(symbol)
+	}
+	return ch.calendars.Get(market)
+}
+
 // GetOrCreateAccount gets or creates an account.
 func (ch *ClearingHouse) GetOrCreateAccount(accountID string, initialCash int64) *Account {
 	ch.mu.Lock()
@@ -146,9 +238,27 @@ func (ch *ClearingHouse) GetOrCreateAccount(accountID string, initialCash int64)
 		Holdings: make(map[string]int64),
 	}
 	ch.accounts[accountID] = acct
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
 	return acct
 }
 
+// DepositShares adds shares of a symbol to an account's holdings, creating
+// the account with no cash if needed.
+func (ch *ClearingHouse) DepositShares(accountID, symbol string, quantity int64) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	acct := ch.accounts[accountID]
+	if acct == nil {
+		acct = &Account{ID: accountID, Holdings: make(map[string]int64)}
+		ch.accounts[accountID] = acct
+	}
+	acct.Holdings[symbol] += quantity
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
+}
+
 // GetAccount retrieves an account.
 func (ch *ClearingHouse) GetAccount(accountID string) *Account {
 	ch.mu.RLock()
@@ -156,21 +266,27 @@ func (ch *ClearingHouse) GetAccount(accountID string) *Account {
 	return ch.accounts[accountID]
 }
 
-// RecordTrade records a new trade for settlement.
+// RecordTrade records a new trade for settlement. A trade already
+// recorded (e.g. replayed after a restart) is returned as it is.
 func (ch *ClearingHouse) RecordTrade(fill orders.Fill) *Trade {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	now := time.Now()
-	settleDate := ch.calculateSettleDate(now)
+	if trade := ch.trades[fill.TradeID]; trade != nil {
+		return trade
+	}
+
+	now := ch.now()
+	settleDate := ch.calculateSettleDate(fill.Symbol, now)
 
 	var buyerAccount, sellerAccount string
+	var buyerFee, sellerFee int64
 	if fill.TakerSide == orders.SideBuy {
-		buyerAccount = fill.TakerAccountID
-		sellerAccount = fill.MakerAccountID
+		buyerAccount, buyerFee = fill.TakerAccountID, fill.TakerFee
+		sellerAccount, sellerFee = fill.MakerAccountID, fill.MakerFee
 	} else {
-		buyerAccount = fill.MakerAccountID
-		sellerAccount = fill.TakerAccountID
+		buyerAccount, buyerFee = fill.MakerAccountID, fill.MakerFee
+		sellerAccount, sellerFee = fill.TakerAccountID, fill.TakerFee
 	}
 
 	trade := &Trade{
@@ -183,26 +299,51 @@ func (ch *ClearingHouse) RecordTrade(fill orders.Fill) *Trade {
 		TradeTime:     now,
 		SettleDate:    settleDate,
 		Status:        TradeStatusExecuted,
+		BuyerFee:      buyerFee,
+		SellerFee:     sellerFee,
 	}
 
 	ch.trades[trade.ID] = trade
+	ch.owe(trade, 1)
+	ch.chargeFee(buyerAccount, buyerFee)
+	ch.chargeFee(sellerAccount, sellerFee)
+	ch.touchTrades(trade)
+	ch.commitLocked()
 	return trade
 }
 
-// calculateSettleDate calculates T+N settlement date.
-func (ch *ClearingHouse) calculateSettleDate(tradeDate time.Time) time.Time {
-	settleDate := tradeDate
-	daysAdded := 0
-
-	for daysAdded < ch.settlementDays {
-		settleDate = settleDate.AddDate(0, 0, 1)
-		// Skip weekends
-		if settleDate.Weekday() != time.Saturday && settleDate.Weekday() != time.Sunday {
-			daysAdded++
-		}
+// chargeFee debits a trading fee from an account's cash, or credits a
+// rebate. Fees are charged when the trade is recorded rather than at
+// settlement: they are owed to the exchange, not netted with the
+// counterparty. An account not yet known is opened with no cash. Caller
+// must hold the lock.
+func (ch *ClearingHouse) chargeFee(accountID string, fee int64) {
+	if fee == 0 {
+		return
+	}
+	acct := ch.accounts[accountID]
+	if acct == nil {
+		acct = &Account{ID: accountID, Holdings: make(map[string]int64)}
+		ch.accounts[accountID] = acct
 	}
+	acct.Cash -= fee
+	acct.Fees += fee
+	ch.touchAccounts(accountID)
+}
+
+// SettleDate returns the date a trade in symbol made at tradeDate settles.
+func (ch *ClearingHouse) SettleDate(symbol string, tradeDate time.Time) time.Time {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.calculateSettleDate(symbol, tradeDate)
+}
 
-	return settleDate
+// calculateSettleDate calculates the T+N settlement date in business days
+// of the symbol's market. A trade on a weekend or holiday counts from the
+// next business day.
+func (ch *ClearingHouse) calculateSettleDate(symbol string, tradeDate time.Time) time.Time {
+	cal := ch.calendarFor(symbol)
+	return cal.AddBusinessDays(cal.NextBusinessDay(tradeDate), ch.settlementDays)
 }
 
 // CalculateNetting calculates net positions for all pending trades.
@@ -210,19 +351,27 @@ func (ch *ClearingHouse) calculateSettleDate(tradeDate time.Time) time.Time {
 func (ch *ClearingHouse) CalculateNetting() map[string]map[string]NetPosition {
 	ch.mu.RLock()
 	defer ch.mu.RUnlock()
-	return ch.calculateNettingLocked()
+	return netTrades(ch.unnettedLocked())
 }
 
-// calculateNettingLocked is the internal version that assumes the caller holds a lock.
-func (ch *ClearingHouse) calculateNettingLocked() map[string]map[string]NetPosition {
-	// account -> symbol -> NetPosition
-	netPositions := make(map[string]map[string]NetPosition)
-
+// unnettedLocked returns the trades not yet netted into instructions, by
+// trade ID. Caller must hold the lock.
+func (ch *ClearingHouse) unnettedLocked() []*Trade {
+	var trades []*Trade
 	for _, trade := range ch.trades {
-		if trade.Status != TradeStatusExecuted && trade.Status != TradeStatusClearing {
-			continue
+		if trade.Status == TradeStatusExecuted || trade.Status == TradeStatusClearing {
+			trades = append(trades, trade)
 		}
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+	return trades
+}
 
+// netTrades nets trades into positions: account -> symbol -> NetPosition.
+func netTrades(trades []*Trade) map[string]map[string]NetPosition {
+	netPositions := make(map[string]map[string]NetPosition)
+
+	for _, trade := range trades {
 		tradeValue := trade.Price * trade.Quantity
 
 		// Buyer: receives shares, owes cash
@@ -232,8 +381,8 @@ func (ch *ClearingHouse) calculateNettingLocked() map[string]map[string]NetPosit
 		buyerPos := netPositions[trade.BuyerAccount][trade.Symbol]
 		buyerPos.AccountID = trade.BuyerAccount
 		buyerPos.Symbol = trade.Symbol
-		buyerPos.NetQty += trade.Quantity  // Will receive shares
-		buyerPos.NetValue += tradeValue    // Owes cash
+		buyerPos.NetQty += trade.Quantity // Will receive shares
+		buyerPos.NetValue += tradeValue   // Owes cash
 		netPositions[trade.BuyerAccount][trade.Symbol] = buyerPos
 
 		// Seller: delivers shares, receives cash
@@ -243,33 +392,45 @@ func (ch *ClearingHouse) calculateNettingLocked() map[string]map[string]NetPosit
 		sellerPos := netPositions[trade.SellerAccount][trade.Symbol]
 		sellerPos.AccountID = trade.SellerAccount
 		sellerPos.Symbol = trade.Symbol
-		sellerPos.NetQty -= trade.Quantity  // Will deliver shares
-		sellerPos.NetValue -= tradeValue    // Will receive cash
+		sellerPos.NetQty -= trade.Quantity // Will deliver shares
+		sellerPos.NetValue -= tradeValue   // Will receive cash
 		netPositions[trade.SellerAccount][trade.Symbol] = sellerPos
 	}
 
 	return netPositions
 }
 
-// GenerateSettlementInstructions creates settlement instructions from netted positions.
+// GenerateSettlementInstructions nets every trade not yet netted into
+// settlement instructions, moving the trades to READY_TO_SETTLE. The
+// settlement scheduler does this by itself on each trade's settle date
+// (see Advance).
 func (ch *ClearingHouse) GenerateSettlementInstructions() []SettlementInstruction {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	netPositions := ch.calculateNettingLocked()
+	trades := ch.unnettedLocked()
+	bySymbol := make(map[string][]*Trade)
+	for _, trade := range trades {
+		bySymbol[trade.Symbol] = append(bySymbol[trade.Symbol], trade)
+	}
 	var instructions []SettlementInstruction
-
-	// For each symbol, match buyers and sellers
-	symbolNets := make(map[string][]NetPosition)
-	for _, positions := range netPositions {
-		for _, pos := range positions {
-			symbolNets[pos.Symbol] = append(symbolNets[pos.Symbol], pos)
-		}
+	for _, symbol := range sortedSymbols(bySymbol) {
+		settleDate := ch.calculateSettleDate(symbol, ch.now())
+		instructions = append(instructions, ch.instructLocked(bySymbol[symbol], settleDate)...)
 	}
+	ch.commitLocked()
+	return instructions
+}
 
-	for symbol, positions := range symbolNets {
-		// Separate longs (receivers) and shorts (deliverers)
-		var receivers, deliverers []NetPosition
+// instructLocked nets one symbol's trades into settlement instructions due
+// on settleDate, queues them, and moves the trades to READY_TO_SETTLE. Each
+// instruction lists the trades of either of its accounts. Caller must hold
+// the lock.
+func (ch *ClearingHouse) instructLocked(trades []*Trade, settleDate time.Time) []SettlementInstruction {
+	// Separate longs (receivers) and shorts (deliverers), by account for a
+	// deterministic pairing
+	var receivers, deliverers []NetPosition
+	for _, positions := range netTrades(trades) {
 		for _, pos := range positions {
 			if pos.NetQty > 0 {
 				receivers = append(receivers, pos)
@@ -277,103 +438,167 @@ func (ch *ClearingHouse) GenerateSettlementInstructions() []SettlementInstructio
 				deliverers = append(deliverers, pos)
 			}
 		}
+	}
+	byAccount := func(p []NetPosition) func(i, j int) bool {
+		return func(i, j int) bool { return p[i].AccountID < p[j].AccountID }
+	}
+	sort.Slice(receivers, byAccount(receivers))
+	sort.Slice(deliverers, byAccount(deliverers))
 
-		// Match deliverers to receivers
-		for _, deliverer := range deliverers {
-			qtyToDeliver := -deliverer.NetQty
+	// Match deliverers to receivers
+	var instructions []SettlementInstruction
+	for _, deliverer := range deliverers {
+		qtyToDeliver := -deliverer.NetQty
 
-			for i := range receivers {
-				if qtyToDeliver <= 0 {
-					break
-				}
-				if receivers[i].NetQty <= 0 {
-					continue
-				}
+		for i := range receivers {
+			if qtyToDeliver <= 0 {
+				break
+			}
+			if receivers[i].NetQty <= 0 {
+				continue
+			}
 
-				matchQty := min64(qtyToDeliver, receivers[i].NetQty)
-				avgPrice := deliverer.NetValue / deliverer.NetQty
-				cashAmount := matchQty * avgPrice
-
-				instruction := SettlementInstruction{
-					FromAccount: deliverer.AccountID,
-					ToAccount:   receivers[i].AccountID,
-					Symbol:      symbol,
-					Quantity:    matchQty,
-					CashAmount:  -cashAmount, // Negative because deliverer receives cash
-					SettleDate:  time.Now().AddDate(0, 0, ch.settlementDays),
-					Status:      TradeStatusReadyToSettle,
+			matchQty := min64(qtyToDeliver, receivers[i].NetQty)
+			avgPrice := deliverer.NetValue / deliverer.NetQty
+			cashAmount := matchQty * avgPrice
+
+			instruction := SettlementInstruction{
+				FromAccount: deliverer.AccountID,
+				ToAccount:   receivers[i].AccountID,
+				Symbol:      deliverer.Symbol,
+				Quantity:    matchQty,
+				CashAmount:  cashAmount, // Paid by the receiver to the deliverer
+				SettleDate:  settleDate,
+				Status:      TradeStatusReadyToSettle,
+			}
+			for _, trade := range trades {
+				if involves(trade, instruction.FromAccount) || involves(trade, instruction.ToAccount) {
+					instruction.TradeIDs = append(instruction.TradeIDs, trade.ID)
 				}
-				instructions = append(instructions, instruction)
-
-				qtyToDeliver -= matchQty
-				receivers[i].NetQty -= matchQty
 			}
+			instructions = append(instructions, instruction)
+
+			qtyToDeliver -= matchQty
+			receivers[i].NetQty -= matchQty
 		}
 	}
 
-	ch.instructions = instructions
+	for _, trade := range trades {
+		trade.Status = TradeStatusReadyToSettle
+	}
+	ch.touchTrades(trades...)
+	ch.touchInstructions()
+	for i := range instructions {
+		ch.record(SettlementInstructed, &instructions[i], 0, 0, "")
+	}
+	ch.instructions = append(ch.instructions, instructions...)
 	return instructions
 }
 
-// Settle executes settlement for all ready instructions.
+// involves reports whether an account is a party to a trade.
+func involves(trade *Trade, accountID string) bool {
+	return trade.BuyerAccount == accountID || trade.SellerAccount == accountID
+}
+
+// sortedSymbols returns a map's symbols in order.
+func sortedSymbols(bySymbol map[string][]*Trade) []string {
+	symbols := make([]string, 0, len(bySymbol))
+	for symbol := range bySymbol {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Settle executes settlement for all ready instructions, failing ones
+// included: each call is a retry.
 func (ch *ClearingHouse) Settle() ([]SettlementInstruction, error) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
+	defer ch.commitLocked()
+	return ch.settleLocked(func(*SettlementInstruction) bool { return true })
+}
 
+// settleLocked settles the ready instructions due reports due, in part if
+// that is all that can be (see fails.go), then settles or fails their
+// trades. A trade settles once no instruction listing it is left to
+// settle, and fails if any of them failed. Settled instructions are
+// dropped; failed ones are kept. Returns the instructions and parts of
+// them settled. Caller must hold the lock.
+func (ch *ClearingHouse) settleLocked(due func(instr *SettlementInstruction) bool) ([]SettlementInstruction, error) {
 	var settled []SettlementInstruction
 	var errors []string
 
 	for i := range ch.instructions {
 		instr := &ch.instructions[i]
-		if instr.Status != TradeStatusReadyToSettle {
+		if instr.Status != TradeStatusReadyToSettle || !due(instr) {
 			continue
 		}
 
+		// Waits for a margin call on either side to be met
+		if ch.calls[instr.FromAccount] > 0 || ch.calls[instr.ToAccount] > 0 {
+			continue
+		}
+		ch.touchInstructions()
+
 		// Get accounts
 		fromAcct := ch.accounts[instr.FromAccount]
 		toAcct := ch.accounts[instr.ToAccount]
 
 		if fromAcct == nil || toAcct == nil {
-			instr.Status = TradeStatusFailed
-			errors = append(errors, fmt.Sprintf("account not found for instruction %s->%s",
-				instr.FromAccount, instr.ToAccount))
+			errors = append(errors, ch.fail(instr, fmt.Sprintf("account not found for instruction %s->%s",
+				instr.FromAccount, instr.ToAccount)))
 			continue
 		}
 
-		// Check deliverer has sufficient shares
-		if fromAcct.Holdings[instr.Symbol] < instr.Quantity {
-			instr.Status = TradeStatusFailed
-			errors = append(errors, fmt.Sprintf("insufficient shares: %s has %d, needs %d",
-				instr.FromAccount, fromAcct.Holdings[instr.Symbol], instr.Quantity))
-			continue
+		// Execute DVP (Delivery vs Payment) atomically, as far as the
+		// deliverer's shares and the receiver's cash go
+		part, shortfall := ch.deliverLocked(instr, fromAcct, toAcct)
+		if part.Quantity > 0 {
+			settled = append(settled, part)
 		}
-
-		// Check receiver has sufficient cash
-		if toAcct.Cash < instr.CashAmount {
-			instr.Status = TradeStatusFailed
-			errors = append(errors, fmt.Sprintf("insufficient cash: %s has %s, needs %s",
-				instr.ToAccount, orders.FormatPrice(toAcct.Cash), orders.FormatPrice(instr.CashAmount)))
+		if shortfall == "" {
 			continue
 		}
 
-		// Execute DVP (Delivery vs Payment) atomically
-		// Shares: From deliverer to receiver
-		fromAcct.Holdings[instr.Symbol] -= instr.Quantity
-		toAcct.Holdings[instr.Symbol] += instr.Quantity
-
-		// Cash: From receiver to deliverer
-		toAcct.Cash -= instr.CashAmount
-		fromAcct.Cash += instr.CashAmount
-
-		instr.Status = TradeStatusSettled
-		settled = append(settled, *instr)
+		// The rest is retried, bought in or failed
+		boughtIn, reason := ch.retryLocked(instr, fromAcct, toAcct, shortfall)
+		if boughtIn.Quantity > 0 {
+			settled = append(settled, boughtIn)
+		}
+		if reason != "" {
+			errors = append(errors, reason)
+		}
 	}
 
 	// Update trade statuses
+	waiting := make(map[uint64]bool)
+	failed := make(map[uint64]bool)
+	kept := ch.instructions[:0]
+	for _, instr := range ch.instructions {
+		for _, id := range instr.TradeIDs {
+			switch instr.Status {
+			case TradeStatusReadyToSettle:
+				waiting[id] = true
+			case TradeStatusFailed:
+				failed[id] = true
+			}
+		}
+		if instr.Status != TradeStatusSettled {
+			kept = append(kept, instr)
+		}
+	}
+	ch.instructions = kept
 	for _, trade := range ch.trades {
-		if trade.Status == TradeStatusClearing || trade.Status == TradeStatusReadyToSettle {
-			trade.Status = TradeStatusSettled
+		if trade.Status != TradeStatusReadyToSettle || waiting[trade.ID] {
+			continue
+		}
+		trade.Status = TradeStatusSettled
+		if failed[trade.ID] {
+			trade.Status = TradeStatusFailed
 		}
+		ch.owe(trade, -1)
+		ch.touchTrades(trade)
 	}
 
 	if len(errors) > 0 {
@@ -383,6 +608,17 @@ func (ch *ClearingHouse) Settle() ([]SettlementInstruction, error) {
 	return settled, nil
 }
 
+// fail marks an instruction as failed for good and notifies the fail hook.
+// Returns the reason for convenience. Caller must hold the lock.
+func (ch *ClearingHouse) fail(instr *SettlementInstruction, reason string) string {
+	instr.Status = TradeStatusFailed
+	ch.record(SettlementFailed, instr, 0, 0, reason)
+	if ch.onFail != nil {
+		ch.onFail(*instr, reason)
+	}
+	return reason
+}
+
 // GetPendingTrades returns all trades pending settlement.
 func (ch *ClearingHouse) GetPendingTrades() []*Trade {
 	ch.mu.RLock()
@@ -430,6 +666,84 @@ func (ch *ClearingHouse) GetSettlementStats() map[string]int {
 	return stats
 }
 
+// State is a copy of the clearing house's books, for snapshots.
+type State struct {
+	Accounts     []Account // By account ID
+	Trades       []Trade   // By trade ID
+	Instructions []SettlementInstruction
+}
+
+// Export returns a deep copy of the clearing house's accounts, trades and
+// settlement instructions.
+func (ch *ClearingHouse) Export() *State {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	state := &State{
+		Accounts:     make([]Account, 0, len(ch.accounts)),
+		Trades:       make([]Trade, 0, len(ch.trades)),
+		Instructions: append([]SettlementInstruction(nil), ch.instructions...),
+	}
+	for _, acct := range ch.accounts {
+		state.Accounts = append(state.Accounts, copyAccount(acct))
+	}
+	for _, trade := range ch.trades {
+		state.Trades = append(state.Trades, *trade)
+	}
+	sort.Slice(state.Accounts, func(i, j int) bool { return state.Accounts[i].ID < state.Accounts[j].ID })
+	sort.Slice(state.Trades, func(i, j int) bool { return state.Trades[i].ID < state.Trades[j].ID })
+	return state
+}
+
+// Restore replaces the clearing house's accounts, trades and settlement
+// instructions with a copy of state. Holds are released: they are rebuilt
+// from the restored books.
+func (ch *ClearingHouse) Restore(state *State) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.accounts = make(map[string]*Account, len(state.Accounts))
+	for i := range state.Accounts {
+		acct := copyAccount(&state.Accounts[i])
+		ch.accounts[acct.ID] = &acct
+	}
+	ch.trades = make(map[uint64]*Trade, len(state.Trades))
+	for i := range state.Trades {
+		trade := state.Trades[i]
+		ch.trades[trade.ID] = &trade
+	}
+	ch.instructions = append([]SettlementInstruction(nil), state.Instructions...)
+	ch.reexposeLocked()
+	if ch.journal != nil {
+		ch.touchAllLocked()
+		ch.commitLocked()
+	}
+}
+
+// reexposeLocked rebuilds what accounts owe from the unsettled trades,
+// after the books were replaced. Holds are released and margin calls
+// cleared, to be re-issued by the next margin check. Caller must hold the
+// lock.
+func (ch *ClearingHouse) reexposeLocked() {
+	ch.holds = make(map[uint64]Need)
+	ch.exposures = make(map[string]*exposure)
+	for _, trade := range ch.trades {
+		if trade.Status != TradeStatusSettled && trade.Status != TradeStatusFailed {
+			ch.owe(trade, 1)
+		}
+	}
+	ch.calls = make(map[string]int64)
+}
+
+// copyAccount copies an account, holdings included.
+func copyAccount(acct *Account) Account {
+	c := Account{ID: acct.ID, Cash: acct.Cash, Fees: acct.Fees, Collateral: acct.Collateral, Holdings: make(map[string]int64, len(acct.Holdings))}
+	for symbol, qty := range acct.Holdings {
+		c.Holdings[symbol] = qty
+	}
+	return c
+}
+
 func min64(a, b int64) int64 {
 	if a < b {
 		return a
@@ -0,0 +1,227 @@
+package settlement
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Settlement Failures
+//
+// A deliverer short of shares or a receiver short of cash fails its
+// instruction, but a fail is usually temporary: the shares are in transit
+// from another trade, the cash arrives tomorrow. So a failing instruction
+// is not given up at once:
+//
+//	partial   whatever the deliverer's shares and the receiver's cash
+//	          cover settles now, DVP; the rest stays due
+//	retry     the rest is retried on the next business day's cycle, up to
+//	          Retries failed cycles; then it fails for good and its trades
+//	          with it
+//	buy-in    a deliverer still short of shares after BuyInAfter failed
+//	          cycles is bought in: the receiver gets the shares from the
+//	          market at the mark plus BuyInPremiumBps and pays the contract
+//	          price as agreed; the deliverer receives the contract price
+//	          and pays for the buy-in, whatever it cost
+//
+//	B sells A 100 AAPL @ $150 but holds 60; buy-in after 2, mark $155, 1%
+//	  day 1  60 settle ($9,000)          40 retry on day 2
+//	  day 2  40 fail again               40 bought in @ $156.55 = $6,262
+//	         A pays $6,000 for 40 shares, B receives $6,000 and pays $6,262
+//
+// Every step of an instruction through settlement is recorded as a
+// SettlementEvent (see Events) and passed to the OnSettlementEvent hook.
+// The zero FailConfig fails instructions at their first attempt.
+
+// FailConfig configures how failing settlement instructions are handled.
+type FailConfig struct {
+	Retries         int   // Failed cycles an instruction is retried before it fails (0 = fails at once)
+	BuyInAfter      int   // Failed cycles before a deliverer short of shares is bought in (0 = never)
+	BuyInPremiumBps int64 // Buy-in price over the mark, in basis points
+}
+
+// SettlementEventKind identifies a step of an instruction through
+// settlement.
+type SettlementEventKind string
+
+const (
+	SettlementInstructed SettlementEventKind = "INSTRUCTED" // Trades netted into the instruction
+	SettlementSettled    SettlementEventKind = "SETTLED"    // Settled in full, DVP
+	SettlementPartial    SettlementEventKind = "PARTIAL"    // Part settled, the rest still due
+	SettlementRetry      SettlementEventKind = "RETRY"      // Failed, retried on the next business day
+	SettlementBoughtIn   SettlementEventKind = "BOUGHT_IN"  // Deliverer bought in
+	SettlementFailed     SettlementEventKind = "FAILED"     // Failed for good
+)
+
+// SettlementEvent records one step of an instruction through settlement.
+type SettlementEvent struct {
+	Seq         uint64
+	Time        time.Time
+	Kind        SettlementEventKind
+	Instruction SettlementInstruction // As it stands after the step
+	Quantity    int64                 // Shares delivered by the step
+	CashAmount  int64                 // Cash paid for them (bought in: the buy-in's cost)
+	Reason      string                // Why it failed (RETRY, FAILED)
+}
+
+// maxSettlementEvents is how many settlement events Events keeps.
+const maxSettlementEvents = 10000
+
+// SetFailConfig sets how failing settlement instructions are handled.
+func (ch *ClearingHouse) SetFailConfig(config FailConfig) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.fails = config
+}
+
+// OnSettlementEvent registers a hook invoked for each settlement event.
+// The hook runs with the clearing house locked, so it must not call back
+// into it.
+func (ch *ClearingHouse) OnSettlementEvent(fn func(event SettlementEvent)) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.onEvent = fn
+}
+
+// Events returns the settlement events after sequence number after, oldest
+// first. Only the latest maxSettlementEvents are kept.
+func (ch *ClearingHouse) Events(after uint64) []SettlementEvent {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	var events []SettlementEvent
+	for _, event := range ch.events {
+		if event.Seq > after {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// record records a settlement event. Caller must hold the lock.
+func (ch *ClearingHouse) record(kind SettlementEventKind, instr *SettlementInstruction, qty, cash int64, reason string) {
+	ch.eventSeq++
+	event := SettlementEvent{
+		Seq:         ch.eventSeq,
+		Time:        ch.now(),
+		Kind:        kind,
+		Instruction: *instr,
+		Quantity:    qty,
+		CashAmount:  cash,
+		Reason:      reason,
+	}
+	event.Instruction.TradeIDs = append([]uint64(nil), instr.TradeIDs...)
+	if len(ch.events) == maxSettlementEvents {
+		ch.events = append(ch.events[:0], ch.events[1:]...)
+	}
+	ch.events = append(ch.events, event)
+	if ch.journal != nil {
+		ch.journal.events++
+	}
+	if ch.onEvent != nil {
+		ch.onEvent(event)
+	}
+}
+
+// deliverLocked settles as much of a due instruction as the deliverer's
+// shares and the receiver's cash cover, DVP. Returns the part settled
+// (Quantity 0 = none) and why the rest could not be ("" = settled in
+// full). Caller must hold the lock.
+func (ch *ClearingHouse) deliverLocked(instr *SettlementInstruction, fromAcct, toAcct *Account) (SettlementInstruction, string) {
+	price := instr.CashAmount / instr.Quantity // Netted at one price per share
+	qty := min(instr.Quantity, max(fromAcct.Holdings[instr.Symbol], 0))
+	if price > 0 {
+		qty = min(qty, max(toAcct.Cash, 0)/price)
+	}
+
+	var shortfall string
+	switch {
+	case fromAcct.Holdings[instr.Symbol] < instr.Quantity:
+		shortfall = fmt.Sprintf("insufficient shares: %s has %d, needs %d",
+			instr.FromAccount, fromAcct.Holdings[instr.Symbol], instr.Quantity)
+	case qty < instr.Quantity:
+		shortfall = fmt.Sprintf("insufficient cash: %s has %s, needs %s",
+			instr.ToAccount, orders.FormatPrice(toAcct.Cash), orders.FormatPrice(instr.CashAmount))
+	}
+
+	part := *instr
+	part.Quantity = qty
+	part.CashAmount = qty * price
+	part.Status = TradeStatusSettled
+	if qty == 0 {
+		return part, shortfall
+	}
+
+	// Shares: From deliverer to receiver
+	fromAcct.Holdings[instr.Symbol] -= part.Quantity
+	toAcct.Holdings[instr.Symbol] += part.Quantity
+
+	// Cash: From receiver to deliverer
+	toAcct.Cash -= part.CashAmount
+	fromAcct.Cash += part.CashAmount
+	ch.touchAccounts(fromAcct.ID, toAcct.ID)
+
+	instr.Quantity -= part.Quantity
+	instr.CashAmount -= part.CashAmount
+	if instr.Quantity == 0 {
+		instr.Quantity, instr.CashAmount = part.Quantity, part.CashAmount
+		instr.Status = TradeStatusSettled
+		ch.record(SettlementSettled, instr, part.Quantity, part.CashAmount, "")
+	} else {
+		ch.record(SettlementPartial, instr, part.Quantity, part.CashAmount, "")
+	}
+	return part, shortfall
+}
+
+// retryLocked handles a due instruction that failed to settle in full: it
+// is bought in, retried on the next business day, or failed once out of
+// retries. Returns the buy-in if there was one (Quantity 0 = none) and the
+// failure reason, if it still failed. Caller must hold the lock.
+func (ch *ClearingHouse) retryLocked(instr *SettlementInstruction, fromAcct, toAcct *Account, reason string) (SettlementInstruction, string) {
+	instr.Attempts++
+	if ch.fails.BuyInAfter > 0 && instr.Attempts >= ch.fails.BuyInAfter &&
+		fromAcct.Holdings[instr.Symbol] < instr.Quantity && toAcct.Cash >= instr.CashAmount {
+		return ch.buyInLocked(instr, fromAcct, toAcct), ""
+	}
+
+	if instr.Attempts > ch.fails.Retries {
+		if instr.Attempts > 1 {
+			reason = fmt.Sprintf("%s (after %d attempts)", reason, instr.Attempts)
+		}
+		return SettlementInstruction{}, ch.fail(instr, reason)
+	}
+
+	cal := ch.calendarFor(instr.Symbol)
+	instr.NextAttempt = cal.AddBusinessDays(ch.now(), 1)
+	reason = fmt.Sprintf("%s (attempt %d, retrying %s)", reason, instr.Attempts, cal.Date(instr.NextAttempt))
+	ch.record(SettlementRetry, instr, 0, 0, reason)
+	if ch.onFail != nil {
+		ch.onFail(*instr, reason)
+	}
+	return SettlementInstruction{}, reason
+}
+
+// buyInLocked closes out an instruction the deliverer cannot deliver: the
+// shares it is short are bought for the receiver at the mark plus the
+// buy-in premium, at the deliverer's expense. The receiver pays the
+// contract price to the deliverer as if delivered. Returns the part bought
+// in. Caller must hold the lock.
+func (ch *ClearingHouse) buyInLocked(instr *SettlementInstruction, fromAcct, toAcct *Account) SettlementInstruction {
+	mark := instr.CashAmount / instr.Quantity
+	if ch.markOf != nil {
+		if m := ch.markOf(instr.Symbol); m > 0 {
+			mark = m
+		}
+	}
+	cost := instr.Quantity * (mark * (10000 + ch.fails.BuyInPremiumBps) / 10000)
+
+	toAcct.Holdings[instr.Symbol] += instr.Quantity
+	toAcct.Cash -= instr.CashAmount
+	fromAcct.Cash += instr.CashAmount - cost // May go negative: the deliverer owes it
+	ch.touchAccounts(fromAcct.ID, toAcct.ID)
+
+	instr.Status = TradeStatusSettled
+	ch.record(SettlementBoughtIn, instr, instr.Quantity, cost, "")
+	return *instr
+}
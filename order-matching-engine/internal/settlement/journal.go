@@ -0,0 +1,301 @@
+package settlement
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// Clearing Journal
+//
+// The clearing house is kept in memory. Its journal makes it durable. After
+// each change (a trade recorded, a settlement run, collateral posted) one
+// JSON line is appended holding the after-images of what changed:
+//
+//	{"Seq":41,"Accounts":[{"ID":"TRADER1","Cash":8500000,...}],
+//	 "Trades":[{"ID":17,"Status":3,...}],"Instructions":[...],"Events":[...]}
+//
+// Accounts and trades are written whole, by ID; the pending instructions
+// are written as a list when any of them changed. Recovery applies the
+// records in order, so the last image of each account and trade wins, then
+// compacts the journal into one record of the full state. A record torn by
+// a crash mid-write is dropped; damage anywhere else stops recovery.
+//
+// Trades are recorded once per trade ID, so fills the event log replays
+// after a snapshot skip the trades the journal already has. Holds and
+// margin calls are not journaled: holds follow from the resting orders and
+// calls are re-issued by the next margin check.
+
+// journalRecord is one line of the clearing journal.
+type journalRecord struct {
+	Seq          uint64
+	Accounts     []Account                `json:",omitempty"`
+	Trades       []Trade                  `json:",omitempty"`
+	Instructions *[]SettlementInstruction `json:",omitempty"` // nil = unchanged
+	Events       []SettlementEvent        `json:",omitempty"`
+}
+
+// clearingJournal is an open clearing journal and what changed since its
+// last record.
+type clearingJournal struct {
+	file      *os.File
+	sync      bool // Fsync each record
+	seq       uint64
+	recovered bool // Opened on an existing journal
+
+	accounts     map[string]bool
+	trades       map[uint64]bool
+	instructions bool
+	events       int // Settlement events not yet written
+}
+
+// OpenJournal recovers the clearing house from the journal at path, if
+// there is one, and journals every change from then on. Recovered state
+// replaces the clearing house's books. With sync, each record is fsynced
+// before the change returns. Returns the number of records recovered.
+func (ch *ClearingHouse) OpenJournal(path string, sync bool) (int, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	records, err := ch.recoverLocked(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// Compact: rewrite the journal as one record of the recovered state
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	j := &clearingJournal{
+		file:      file,
+		sync:      true,
+		recovered: records > 0,
+		accounts:  make(map[string]bool),
+		trades:    make(map[uint64]bool),
+	}
+	ch.journal = j
+	ch.touchAllLocked()
+	if err := ch.writeLocked(); err != nil {
+		ch.journal = nil
+		file.Close()
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ch.journal = nil
+		file.Close()
+		return 0, err
+	}
+	j.sync = sync
+	return records, nil
+}
+
+// Journaled reports whether the clearing house was recovered from a
+// journal, which is more recent than any snapshot of it.
+func (ch *ClearingHouse) Journaled() bool {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.journal != nil && ch.journal.recovered
+}
+
+// CloseJournal syncs and closes the journal.
+func (ch *ClearingHouse) CloseJournal() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.journal == nil {
+		return nil
+	}
+	j := ch.journal
+	ch.journal = nil
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// recoverLocked applies the journal at path to empty books. Caller must
+// hold the lock.
+func (ch *ClearingHouse) recoverLocked(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	accounts := make(map[string]*Account)
+	trades := make(map[uint64]*Trade)
+	var instructions []SettlementInstruction
+	var events []SettlementEvent
+	var eventSeq, seq uint64
+	records := 0
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Clearing journal: dropped a torn record after record %d", seq)
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			if _, err := reader.Peek(1); err == io.EOF {
+				log.Printf("Clearing journal: dropped a torn record after record %d", seq)
+				break
+			}
+			return 0, fmt.Errorf("clearing journal %s: record after %d: %w", path, seq, err)
+		}
+		if record.Seq != seq+1 {
+			return 0, fmt.Errorf("clearing journal %s: record %d follows %d", path, record.Seq, seq)
+		}
+		seq = record.Seq
+		records++
+
+		for i := range record.Accounts {
+			acct := record.Accounts[i]
+			if acct.Holdings == nil {
+				acct.Holdings = make(map[string]int64)
+			}
+			accounts[acct.ID] = &acct
+		}
+		for i := range record.Trades {
+			trade := record.Trades[i]
+			trades[trade.ID] = &trade
+		}
+		if record.Instructions != nil {
+			instructions = *record.Instructions
+		}
+		for _, event := range record.Events {
+			events = append(events, event)
+			eventSeq = event.Seq
+		}
+		if len(events) > maxSettlementEvents {
+			events = append([]SettlementEvent(nil), events[len(events)-maxSettlementEvents:]...)
+		}
+	}
+	if records == 0 {
+		return 0, nil
+	}
+
+	ch.accounts = accounts
+	ch.trades = trades
+	ch.instructions = instructions
+	ch.events = events
+	ch.eventSeq = eventSeq
+	ch.reexposeLocked()
+	return records, nil
+}
+
+// touchAccounts marks accounts as changed since the last journal record.
+// Caller must hold the lock.
+func (ch *ClearingHouse) touchAccounts(ids ...string) {
+	if ch.journal == nil {
+		return
+	}
+	for _, id := range ids {
+		ch.journal.accounts[id] = true
+	}
+}
+
+// touchTrades marks trades as changed. Caller must hold the lock.
+func (ch *ClearingHouse) touchTrades(trades ...*Trade) {
+	if ch.journal == nil {
+		return
+	}
+	for _, trade := range trades {
+		ch.journal.trades[trade.ID] = true
+	}
+}
+
+// touchInstructions marks the pending instructions as changed. Caller
+// must hold the lock.
+func (ch *ClearingHouse) touchInstructions() {
+	if ch.journal != nil {
+		ch.journal.instructions = true
+	}
+}
+
+// touchAllLocked marks everything as changed, for a record of the full
+// state. Caller must hold the lock.
+func (ch *ClearingHouse) touchAllLocked() {
+	for id := range ch.accounts {
+		ch.touchAccounts(id)
+	}
+	for _, trade := range ch.trades {
+		ch.touchTrades(trade)
+	}
+	ch.touchInstructions()
+	ch.journal.events = len(ch.events)
+}
+
+// commitLocked journals what changed, if anything. A journal that cannot
+// be written is logged, not returned: the change has already been made.
+// Caller must hold the lock.
+func (ch *ClearingHouse) commitLocked() {
+	if ch.journal == nil {
+		return
+	}
+	if err := ch.writeLocked(); err != nil {
+		log.Printf("Clearing journal: %v", err)
+	}
+}
+
+// writeLocked appends a record of what changed. Caller must hold the lock.
+func (ch *ClearingHouse) writeLocked() error {
+	j := ch.journal
+	if len(j.accounts) == 0 && len(j.trades) == 0 && !j.instructions && j.events == 0 {
+		return nil
+	}
+
+	record := journalRecord{Seq: j.seq + 1}
+	for id := range j.accounts {
+		if acct := ch.accounts[id]; acct != nil {
+			record.Accounts = append(record.Accounts, copyAccount(acct))
+		}
+	}
+	sort.Slice(record.Accounts, func(i, k int) bool { return record.Accounts[i].ID < record.Accounts[k].ID })
+	for id := range j.trades {
+		if trade := ch.trades[id]; trade != nil {
+			record.Trades = append(record.Trades, *trade)
+		}
+	}
+	sort.Slice(record.Trades, func(i, k int) bool { return record.Trades[i].ID < record.Trades[k].ID })
+	if j.instructions {
+		instructions := append([]SettlementInstruction{}, ch.instructions...)
+		record.Instructions = &instructions
+	}
+	record.Events = ch.events[len(ch.events)-min(j.events, len(ch.events)):]
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if j.sync {
+		if err := j.file.Sync(); err != nil {
+			return err
+		}
+	}
+	j.seq = record.Seq
+	j.accounts = make(map[string]bool)
+	j.trades = make(map[uint64]bool)
+	j.instructions = false
+	j.events = 0
+	return nil
+}
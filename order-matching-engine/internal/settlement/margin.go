@@ -0,0 +1,228 @@
+package settlement
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// Margin
+//
+// Between the trade and settlement the clearing house stands behind both
+// sides of every trade, so it margins what it is exposed to: each account's
+// unsettled trades, netted per symbol into a position.
+//
+//	initial margin    InitialBps of each position's value at the mark price
+//	variation margin  each position's mark-to-market gain (+) or loss (-)
+//	                  against the prices it traded at
+//	excess            collateral + variation margin - initial margin
+//
+//	Collateral $2,000, margin 10%
+//	  buy 100 AAPL @ $150          initial $1,500  variation $0     excess  $500
+//	  AAPL marks $140              initial $1,400  variation -$1,000 excess -$400
+//	                               → margin call for $400
+//
+// Sales of shares the account holds are covered by them and need no margin.
+// Marks come from markOf (the risk checker's reference prices); a symbol
+// with no mark is marked at its average trade price. Collateral is cash the
+// account posts (PostCollateral), set aside from its buying power. A
+// negative excess is a margin call: the account's new orders are refused
+// and its settlement instructions wait until the call is met, by posting
+// collateral or a price move. CheckMargin re-margins accounts after trades
+// and mark changes; the settlement scheduler re-margins everyone before it
+// settles. Margin is off until SetMargin gives a positive rate.
+
+// MarginConfig configures margin requirements.
+type MarginConfig struct {
+	InitialBps int64 // Initial margin in basis points of position value (0 = off)
+}
+
+// Margin is an account's margin position. Amounts are in cents.
+type Margin struct {
+	AccountID  string
+	Initial    int64 // Required against its unsettled positions
+	Variation  int64 // Mark-to-market gain (+) or loss (-) on them
+	Collateral int64
+	Excess     int64 // Collateral + Variation - Initial; negative is a call
+	Call       int64 // Open margin call (0 = none)
+}
+
+// position is an account's unsettled trades in one symbol, netted.
+type position struct {
+	qty  int64 // Bought (+) or sold (-)
+	cost int64 // Paid (+) or received (-)
+}
+
+// add adds a trade of qty (signed) at price.
+func (p *position) add(qty, price int64) {
+	p.qty += qty
+	p.cost += qty * price
+}
+
+// position returns the account's position in a symbol, creating it if
+// needed.
+func (exp *exposure) position(symbol string) *position {
+	p := exp.positions[symbol]
+	if p == nil {
+		p = &position{}
+		exp.positions[symbol] = p
+	}
+	return p
+}
+
+// SetMargin turns on margin requirements, marking positions with markOf.
+func (ch *ClearingHouse) SetMargin(config MarginConfig, markOf func(symbol string) int64) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.margin = config
+	ch.markOf = markOf
+}
+
+// OnMarginCall registers a hook invoked when a margin call is issued or
+// changes (Call > 0) and when one is met (Call == 0). The hook runs with
+// the clearing house locked, so it must not call back into it.
+func (ch *ClearingHouse) OnMarginCall(fn func(m Margin)) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.onMarginCall = fn
+}
+
+// PostCollateral moves cash into an account's collateral, or back out for
+// a negative amount. A withdrawal is refused if the account would not have
+// enough margin left.
+func (ch *ClearingHouse) PostCollateral(accountID string, amount int64) (Margin, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	acct := ch.accounts[accountID]
+	if acct == nil {
+		return Margin{}, fmt.Errorf("account %s not found", accountID)
+	}
+	switch {
+	case amount > 0 && acct.Cash < amount:
+		return ch.marginLocked(accountID), fmt.Errorf("insufficient cash: %s has %s",
+			accountID, orders.FormatPrice(acct.Cash))
+	case amount < 0 && acct.Collateral < -amount:
+		return ch.marginLocked(accountID), fmt.Errorf("insufficient collateral: %s has %s posted",
+			accountID, orders.FormatPrice(acct.Collateral))
+	case amount < 0 && ch.marginLocked(accountID).Excess < -amount:
+		return ch.marginLocked(accountID), fmt.Errorf("withdrawal would leave %s under-margined", accountID)
+	}
+	acct.Cash -= amount
+	acct.Collateral += amount
+	ch.touchAccounts(accountID)
+	ch.commitLocked()
+	ch.callLocked(accountID)
+	return ch.marginLocked(accountID), nil
+}
+
+// GetMargin returns an account's margin position.
+func (ch *ClearingHouse) GetMargin(accountID string) Margin {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.marginLocked(accountID)
+}
+
+// MarginCall returns an account's open margin call (0 = none).
+func (ch *ClearingHouse) MarginCall(accountID string) int64 {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.calls[accountID]
+}
+
+// CheckMargin re-margins the accounts with unsettled trades in symbol, or
+// every account for "", and those under a call, issuing and clearing calls.
+// Call it after trades are recorded and marks move.
+func (ch *ClearingHouse) CheckMargin(symbol string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.checkMarginLocked(symbol)
+}
+
+// checkMarginLocked is CheckMargin with the lock held.
+func (ch *ClearingHouse) checkMarginLocked(symbol string) {
+	if ch.margin.InitialBps <= 0 {
+		return
+	}
+	accounts := make([]string, 0, len(ch.calls))
+	for accountID := range ch.calls {
+		accounts = append(accounts, accountID)
+	}
+	for accountID, exp := range ch.exposures {
+		if _, called := ch.calls[accountID]; called {
+			continue
+		}
+		for sym, p := range exp.positions {
+			if (symbol == "" || sym == symbol) && (p.qty != 0 || p.cost != 0) {
+				accounts = append(accounts, accountID)
+				break
+			}
+		}
+	}
+	sort.Strings(accounts)
+	for _, accountID := range accounts {
+		ch.callLocked(accountID)
+	}
+}
+
+// callLocked issues, updates or clears an account's margin call. Caller
+// must hold the lock.
+func (ch *ClearingHouse) callLocked(accountID string) {
+	if ch.margin.InitialBps <= 0 {
+		return
+	}
+	m := ch.marginLocked(accountID)
+	call := max(-m.Excess, 0)
+	if call == ch.calls[accountID] {
+		return
+	}
+	if call > 0 {
+		ch.calls[accountID] = call
+	} else {
+		delete(ch.calls, accountID)
+	}
+	m.Call = call
+	if ch.onMarginCall != nil {
+		ch.onMarginCall(m)
+	}
+}
+
+// marginLocked computes an account's margin position. Caller must hold
+// the lock.
+func (ch *ClearingHouse) marginLocked(accountID string) Margin {
+	m := Margin{AccountID: accountID, Call: ch.calls[accountID]}
+	acct := ch.accounts[accountID]
+	if acct != nil {
+		m.Collateral = acct.Collateral
+	}
+	if exp := ch.exposures[accountID]; exp != nil {
+		for symbol, p := range exp.positions {
+			qty, cost := p.qty, p.cost
+			if qty < 0 && acct != nil {
+				// Shares held cover a sale: delivering them settles it
+				covered := min(-qty, max(acct.Holdings[symbol], 0))
+				cost -= cost * covered / -qty
+				qty += covered
+			}
+			var mark int64
+			if ch.markOf != nil {
+				mark = ch.markOf(symbol)
+			}
+			if mark <= 0 && qty != 0 {
+				mark = cost / qty // Average trade price
+			}
+			m.Variation += qty*mark - cost
+			m.Initial += abs64(qty) * mark * ch.margin.InitialBps / 10000
+		}
+	}
+	m.Excess = m.Collateral + m.Variation - m.Initial
+	return m
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+package settlement
+
+import (
+	"sort"
+)
+
+// Reports
+//
+// End-of-day reports are read from the clearing house's books:
+//
+//	settlement report  for a settle date: the trades settling on it netted
+//	                   per account and symbol, every settlement step taken
+//	                   that day (see SettlementEvent), and the instructions
+//	                   due by then that have failed, for good or for now
+//	trade report       an account's trades between two trade dates, with
+//	                   their fees and settlement status
+//
+// Dates are business dates in each symbol's market (YYYY-MM-DD). Steps are
+// read from the settlement events, so a report reaches back only as far as
+// the events kept (maxSettlementEvents).
+
+// NetLine is one account's trades in one symbol settling on a date,
+// netted.
+type NetLine struct {
+	AccountID string
+	Symbol    string
+	Trades    int
+	Bought    int64 // Shares
+	Sold      int64 // Shares
+	NetQty    int64 // Bought - Sold: received (+) or delivered (-)
+	NetValue  int64 // Paid (+) or received (-) for them, in cents
+}
+
+// SettlementReport is the settlement report for a settle date.
+type SettlementReport struct {
+	Date    string
+	Netting []NetLine               // By account and symbol
+	Steps   []SettlementEvent       // Oldest first
+	Failing []SettlementInstruction // Retrying or failed, by settle date
+}
+
+// SettlementReport reports on the settle date date (YYYY-MM-DD).
+func (ch *ClearingHouse) SettlementReport(date string) SettlementReport {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	report := SettlementReport{Date: date}
+	lines := make(map[[2]string]*NetLine)
+	for _, trade := range ch.trades {
+		if ch.calendarFor(trade.Symbol).Date(trade.SettleDate) != date {
+			continue
+		}
+		for _, side := range []struct {
+			account string
+			qty     int64
+		}{{trade.BuyerAccount, trade.Quantity}, {trade.SellerAccount, -trade.Quantity}} {
+			key := [2]string{side.account, trade.Symbol}
+			line := lines[key]
+			if line == nil {
+				line = &NetLine{AccountID: side.account, Symbol: trade.Symbol}
+				lines[key] = line
+			}
+			line.Trades++
+			if side.qty > 0 {
+				line.Bought += side.qty
+			} else {
+				line.Sold -= side.qty
+			}
+			line.NetQty += side.qty
+			line.NetValue += side.qty * trade.Price
+		}
+	}
+	for _, line := range lines {
+		report.Netting = append(report.Netting, *line)
+	}
+	sort.Slice(report.Netting, func(i, j int) bool {
+		a, b := report.Netting[i], report.Netting[j]
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	for _, event := range ch.events {
+		if ch.calendarFor(event.Instruction.Symbol).Date(event.Time) == date {
+			report.Steps = append(report.Steps, event)
+		}
+	}
+
+	for _, instr := range ch.instructions {
+		failing := instr.Attempts > 0 || instr.Status == TradeStatusFailed
+		if failing && ch.calendarFor(instr.Symbol).Date(instr.SettleDate) <= date {
+			report.Failing = append(report.Failing, instr)
+		}
+	}
+	sort.SliceStable(report.Failing, func(i, j int) bool {
+		return report.Failing[i].SettleDate.Before(report.Failing[j].SettleDate)
+	})
+	return report
+}
+
+// AccountTrades returns an account's trades made from one trade date to
+// another (YYYY-MM-DD, inclusive; "" = unbounded), by trade ID.
+func (ch *ClearingHouse) AccountTrades(accountID, from, to string) []Trade {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	var trades []Trade
+	for _, trade := range ch.trades {
+		if !involves(trade, accountID) {
+			continue
+		}
+		date := ch.calendarFor(trade.Symbol).Date(trade.TradeTime)
+		if (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		trades = append(trades, *trade)
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+	return trades
+}
@@ -0,0 +1,64 @@
+package settlement
+
+import "time"
+
+// Settlement Cycle
+//
+// Trades move through the settlement cycle by themselves, on the business
+// days of their symbol's market:
+//
+//	EXECUTED         on the trade date
+//	CLEARING         from the next business day (at once for T+0)
+//	READY_TO_SETTLE  on the settle date the symbol's trades due are netted
+//	                 into settlement instructions...
+//	SETTLED          ...which are settled DVP, or retried and bought in
+//	                 (see fails.go); a trade fails with any instruction it
+//	                 is part of
+//
+// Advance makes every move due by the clearing house's clock. An event
+// processor calls it on an interval, from its timer wheel (see
+// disruptor/settle.go); a test moves a fake clock (SetClock) and calls
+// Advance itself. GenerateSettlementInstructions and Settle still run the
+// cycle by hand, ignoring dates.
+
+// Advance moves trades through the settlement cycle as far as the clock
+// allows, and returns the instructions settled. The error lists the
+// instructions that failed.
+func (ch *ClearingHouse) Advance() ([]SettlementInstruction, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	now := ch.now()
+	due := make(map[string][]*Trade) // Symbol -> trades to net
+	settleDates := make(map[string]time.Time)
+	for _, trade := range ch.unnettedLocked() {
+		cal := ch.calendarFor(trade.Symbol)
+		today, settles := cal.Date(now), cal.Date(trade.SettleDate)
+		if trade.Status == TradeStatusExecuted && (cal.Date(trade.TradeTime) < today || settles <= today) {
+			trade.Status = TradeStatusClearing
+			ch.touchTrades(trade)
+		}
+		if trade.Status == TradeStatusClearing && settles <= today {
+			due[trade.Symbol] = append(due[trade.Symbol], trade)
+			if date, ok := settleDates[trade.Symbol]; !ok || trade.SettleDate.Before(date) {
+				settleDates[trade.Symbol] = trade.SettleDate
+			}
+		}
+	}
+	for _, symbol := range sortedSymbols(due) {
+		ch.instructLocked(due[symbol], settleDates[symbol])
+	}
+	ch.checkMarginLocked("") // Marks may have moved without a trade since
+
+	settled, err := ch.settleLocked(func(instr *SettlementInstruction) bool {
+		cal := ch.calendarFor(instr.Symbol)
+		date := instr.SettleDate
+		if !instr.NextAttempt.IsZero() {
+			date = instr.NextAttempt // Failed, retried from the next business day
+		}
+		return cal.Date(date) <= cal.Date(now)
+	})
+	ch.checkMarginLocked("") // Settled and failed trades need no margin
+	ch.commitLocked()
+	return settled, err
+}
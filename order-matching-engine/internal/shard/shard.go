@@ -0,0 +1,178 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+)
+
+// Symbol Sharding
+//
+// One event processor serializes every symbol, so a burst in AAPL queues
+// TSLA behind it. With N shards the engine core is split N ways: each shard
+// has its own matching engine, event log, ring buffer, sequencer and
+// processor, and each symbol hashes to exactly one shard. A symbol's
+// requests are still processed one at a time in sequence order, so its
+// book is as deterministic as before; only symbols on different shards run
+// in parallel.
+//
+//	             ┌─▶ shard 0: ring ─▶ processor ─▶ engine {AAPL, TSLA}  ─▶ events.shard-0.log
+//	request ─────┤
+//	 (symbol)    └─▶ shard 1: ring ─▶ processor ─▶ engine {GOOGL, MSFT} ─▶ events.shard-1.log
+//
+// Order and trade IDs stay unique across shards: shard i issues IDs
+// congruent to i+1 modulo N (see matching.Engine.SetIDStride). Requests that
+// span symbols - mass cancels, heartbeats, account-wide queries - go to
+// every shard; a basket must keep all its legs on one shard to stay
+// all-or-none.
+//
+// Which shard a symbol hashes to depends on N, so a log written with one
+// shard count cannot be recovered with another: CheckLayout records N next
+// to the event log and refuses a restart with a different count.
+
+// Shard is one independent slice of the engine core.
+type Shard struct {
+	Index      int
+	Engine     *matching.Engine
+	EventLog   *events.EventLog
+	RingBuffer *disruptor.RingBuffer
+	Sequencer  *disruptor.Sequencer
+	Processor  *disruptor.EventProcessor
+}
+
+// Set is a fixed number of shards, symbols hashed across them.
+type Set struct {
+	shards []*Shard
+}
+
+// NewSet creates a set of shards, indexed in order.
+func NewSet(shards ...*Shard) *Set {
+	return &Set{shards: shards}
+}
+
+// Index returns the shard a symbol belongs to out of n (FNV-1a).
+func Index(symbol string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Len returns the number of shards.
+func (s *Set) Len() int {
+	return len(s.shards)
+}
+
+// All returns every shard, in index order.
+func (s *Set) All() []*Shard {
+	return s.shards
+}
+
+// For returns the shard a symbol belongs to.
+func (s *Set) For(symbol string) *Shard {
+	return s.shards[Index(symbol, len(s.shards))]
+}
+
+// Route returns the one shard a request must be processed by, or nil if it
+// spans symbols and goes to every shard. A basket whose legs hash to
+// different shards returns nil too: it cannot be processed by any one.
+func (s *Set) Route(req *disruptor.OrderRequest) *Shard {
+	if len(s.shards) == 1 {
+		return s.shards[0]
+	}
+	switch req.Type {
+	case disruptor.RequestTypeNewOrder, disruptor.RequestTypePreview:
+		return s.For(req.Order.Symbol)
+	case disruptor.RequestTypeModifyOrder:
+		return s.For(req.Replace.Symbol)
+	case disruptor.RequestTypeCancelOrder, disruptor.RequestTypeStartAuction, disruptor.RequestTypeUncross,
+		disruptor.RequestTypeExportSymbol, disruptor.RequestTypeImportSymbol, disruptor.RequestTypeReleaseSymbol:
+		return s.For(req.Symbol)
+	case disruptor.RequestTypeSchedule:
+		if req.Symbol != "" {
+			return s.For(req.Symbol)
+		}
+		return s.shards[0] // With the settlement cycle (see disruptor/settle.go)
+	case disruptor.RequestTypeOpenOrders:
+		if req.Symbol != "" {
+			return s.For(req.Symbol)
+		}
+	case disruptor.RequestTypeRiskLimits, disruptor.RequestTypeRestriction, disruptor.RequestTypeAccount:
+		return s.shards[0] // Logged once, in the first shard's log
+	case disruptor.RequestTypeSettle:
+		return s.shards[0] // With the settlement cycle
+	case disruptor.RequestTypeBasket:
+		if len(req.Legs) == 0 {
+			return s.shards[0] // Rejected as empty
+		}
+		shard := s.For(req.Legs[0].Symbol)
+		for _, leg := range req.Legs[1:] {
+			if s.For(leg.Symbol) != shard {
+				return nil
+			}
+		}
+		return shard
+	}
+	return nil
+}
+
+// Symbols returns the symbols of every shard, sorted.
+func (s *Set) Symbols() []string {
+	var symbols []string
+	for _, shard := range s.shards {
+		symbols = append(symbols, shard.Engine.Symbols()...)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// LogPath returns the event log path of shard index out of n: the base path
+// itself for a single shard, otherwise e.g. events.shard-2.log.
+func LogPath(base string, index, n int) string {
+	if n <= 1 {
+		return base
+	}
+	ext := filepath.Ext(base)
+	return fmt.Sprintf("%s.shard-%d%s", strings.TrimSuffix(base, ext), index, ext)
+}
+
+// CheckLayout verifies the event logs at base were written with n shards,
+// and records n for the next start. A single shard writes no record, so
+// unsharded deployments are unchanged; their log at base, one file or
+// segmented, counts as one.
+func CheckLayout(base string, n int) error {
+	marker := base + ".shards"
+	written := 1
+	if data, err := os.ReadFile(marker); err == nil {
+		if written, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+			return fmt.Errorf("invalid shard count in %s: %w", marker, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	} else if !exists(base) && !exists(base+".manifest") {
+		written = n // No log yet
+	}
+
+	if written != n {
+		return fmt.Errorf("event log %s was written with %d shard(s); restart with -shards %d", base, written, written)
+	}
+	if n > 1 {
+		return os.WriteFile(marker, []byte(strconv.Itoa(n)+"\n"), 0644)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
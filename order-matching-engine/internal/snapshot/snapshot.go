@@ -0,0 +1,441 @@
+// Package snapshot persists order book images so a restarted engine can
+// rebuild its books without replaying the whole event log.
+//
+// Why deltas?
+// A full image rewrites every resting order on every snapshot. A large book
+// changes only at the edges between two snapshots - a few new orders, some
+// fills and cancels - while most orders sit untouched. Writing the full
+// image each time means write amplification proportional to book size, not
+// activity. Instead the store writes:
+//
+//	full ──▶ delta ──▶ delta ──▶ delta ──▶ full ──▶ delta ...
+//	 │        │ added/removed/updated orders since the previous snapshot
+//	 └─ every resting order, in queue order
+//
+// and recovery loads the latest full image and applies the deltas after it.
+// A compaction policy bounds the chain: once there are too many deltas, or
+// they add up to a large fraction of a full image, the next snapshot is
+// written in full and everything older is deleted.
+//
+// Queue order:
+//
+// Restoring must reproduce time priority, so a delta has to describe where
+// orders sit, not just which exist. Within a price level orders only ever
+// leave (fills, cancels) or join at the back (new orders, replenished
+// icebergs, re-queued replaces). So each level of the new image is:
+//
+//	[orders kept from the previous image, same relative order] + [appended]
+//
+// An order that moved to the back appears as removed and added again.
+//
+// Beyond the books:
+// An image also carries the engine's ID counters, the symbols migrated to
+// other shards and the clearing house state, so recovery can load the
+// latest image and re-execute only the events logged after it (see
+// matching.Replayer). All are small next to the books except the clearing
+// house's trades, which only ever appear or change status, so a delta
+// carries just the new and changed trades.
+package snapshot
+
+import (
+	"sort"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// Image is the resting state of every book at one point in the event log.
+type Image struct {
+	// EventSeq is the last event log sequence number reflected in the books.
+	// The image is only current for a log that ends at EventSeq.
+	EventSeq uint64
+
+	// Books holds each symbol's resting orders in queue order: bids best
+	// price first, then asks, FIFO within a level.
+	Books map[string][]orders.Order
+
+	// Counters are the engine's ID high-water marks as of EventSeq. Replay
+	// of the later events continues from them.
+	Counters matching.IDCounters
+
+	// Clearing is the clearing house state as of EventSeq, or nil if the
+	// processor does not record trades.
+	Clearing *settlement.State
+
+	// Moved lists symbols handed over to other shards: symbol -> target.
+	Moved map[string]string
+
+	// Auctions lists symbols in an auction call: symbol -> reference price.
+	// Their books may be crossed.
+	Auctions map[string]int64
+
+	// JournalSeq is the request journal sequence number of the last request
+	// reflected in the books, or 0 without a request journal (see
+	// disruptor/journal.go).
+	JournalSeq uint64
+}
+
+// Delta is the change between two images.
+type Delta struct {
+	EventSeq uint64 // EventSeq of the image the delta produces
+	Books    []BookDelta
+	Counters matching.IDCounters
+
+	// Clearing holds accounts and instructions in full but only the trades
+	// that are new or changed status. Nil if the image has no clearing state.
+	Clearing *settlement.State
+
+	Moved    map[string]string // In full: migrations are rare
+	Auctions map[string]int64  // In full: only during auction calls
+
+	JournalSeq uint64 // JournalSeq of the image the delta produces
+}
+
+// BookDelta is the change to one symbol's book.
+type BookDelta struct {
+	Symbol  string
+	Removed []uint64       // Orders gone (or moved to the back of a level)
+	Updated []orders.Order // Orders whose state changed in place (fills, reductions)
+	Added   []orders.Order // Orders appended to the back of their level, in order
+}
+
+// Orders returns the number of resting orders in the image.
+func (img *Image) Orders() int {
+	n := 0
+	for _, book := range img.Books {
+		n += len(book)
+	}
+	return n
+}
+
+// levelKey identifies a price level.
+type levelKey struct {
+	side  orders.Side
+	price int64
+}
+
+func keyOf(o *orders.Order) levelKey {
+	return levelKey{side: o.Side, price: o.Price}
+}
+
+// Diff returns the delta that turns prev into next.
+func Diff(prev, next *Image) *Delta {
+	delta := &Delta{
+		EventSeq: next.EventSeq,
+		Counters: next.Counters,
+		Clearing: diffClearing(prev.Clearing, next.Clearing),
+		Moved:    next.Moved,
+		Auctions: next.Auctions,
+
+		JournalSeq: next.JournalSeq,
+	}
+
+	symbols := make(map[string]bool)
+	for symbol := range prev.Books {
+		symbols[symbol] = true
+	}
+	for symbol := range next.Books {
+		symbols[symbol] = true
+	}
+
+	for _, symbol := range sortedKeys(symbols) {
+		if bd := diffBook(symbol, prev.Books[symbol], next.Books[symbol]); bd != nil {
+			delta.Books = append(delta.Books, *bd)
+		}
+	}
+	return delta
+}
+
+// diffBook diffs one symbol's book. Returns nil if nothing changed.
+func diffBook(symbol string, prev, next []orders.Order) *BookDelta {
+	// Position of each previous order within its level
+	type prevOrder struct {
+		pos   int
+		order *orders.Order
+	}
+	before := make(map[uint64]prevOrder, len(prev))
+	levelLen := make(map[levelKey]int)
+	for i := range prev {
+		k := keyOf(&prev[i])
+		before[prev[i].ID] = prevOrder{pos: levelLen[k], order: &prev[i]}
+		levelLen[k]++
+	}
+
+	bd := &BookDelta{Symbol: symbol}
+	kept := make(map[uint64]bool, len(next))
+
+	// Walk each level of next: the longest prefix that is a subsequence of
+	// the same level in prev is kept; everything after it was appended.
+	lastPos := make(map[levelKey]int)
+	appending := make(map[levelKey]bool)
+	for i := range next {
+		o := &next[i]
+		k := keyOf(o)
+		if _, seen := lastPos[k]; !seen {
+			lastPos[k] = -1
+		}
+
+		p, existed := before[o.ID]
+		if !appending[k] && existed && keyOf(p.order) == k && p.pos > lastPos[k] {
+			lastPos[k] = p.pos
+			kept[o.ID] = true
+			if *p.order != *o {
+				bd.Updated = append(bd.Updated, *o)
+			}
+			continue
+		}
+		appending[k] = true
+		bd.Added = append(bd.Added, *o)
+	}
+
+	for i := range prev {
+		if !kept[prev[i].ID] {
+			bd.Removed = append(bd.Removed, prev[i].ID)
+		}
+	}
+
+	if len(bd.Removed) == 0 && len(bd.Updated) == 0 && len(bd.Added) == 0 {
+		return nil
+	}
+	return bd
+}
+
+// diffClearing returns next with only the trades that are not in prev or
+// whose status changed.
+func diffClearing(prev, next *settlement.State) *settlement.State {
+	if next == nil {
+		return nil
+	}
+	status := make(map[uint64]settlement.TradeStatus)
+	if prev != nil {
+		for _, trade := range prev.Trades {
+			status[trade.ID] = trade.Status
+		}
+	}
+
+	diff := &settlement.State{Accounts: next.Accounts, Instructions: next.Instructions}
+	for _, trade := range next.Trades {
+		if s, exists := status[trade.ID]; !exists || s != trade.Status {
+			diff.Trades = append(diff.Trades, trade)
+		}
+	}
+	return diff
+}
+
+// Apply returns the image produced by applying delta to img. img is not
+// modified.
+func Apply(img *Image, delta *Delta) *Image {
+	w := newWorkingImage(img)
+	w.apply(delta)
+	return w.image()
+}
+
+// workingImage is an image split into price levels so a chain of deltas
+// can be applied in place, touching only the levels that changed.
+type workingImage struct {
+	eventSeq   uint64
+	journalSeq uint64
+	books      map[string]*workingBook
+	counters   matching.IDCounters
+	clearing   *settlement.State
+	trades     map[uint64]int // Trade ID -> index in clearing.Trades
+	moved      map[string]string
+	auctions   map[string]int64
+}
+
+type workingBook struct {
+	levels map[levelKey][]orders.Order
+	where  map[uint64]levelKey // Order ID -> level
+}
+
+func newWorkingImage(img *Image) *workingImage {
+	w := &workingImage{eventSeq: img.EventSeq, journalSeq: img.JournalSeq, books: make(map[string]*workingBook, len(img.Books)), counters: img.Counters, moved: img.Moved, auctions: img.Auctions}
+	w.setClearing(img.Clearing)
+	for symbol, book := range img.Books {
+		wb := w.book(symbol)
+		for _, o := range book {
+			k := keyOf(&o)
+			wb.levels[k] = append(wb.levels[k], o)
+			wb.where[o.ID] = k
+		}
+	}
+	return w
+}
+
+func (w *workingImage) book(symbol string) *workingBook {
+	wb := w.books[symbol]
+	if wb == nil {
+		wb = &workingBook{levels: make(map[levelKey][]orders.Order), where: make(map[uint64]levelKey)}
+		w.books[symbol] = wb
+	}
+	return wb
+}
+
+// setClearing takes a copy of a clearing state to merge deltas into.
+func (w *workingImage) setClearing(state *settlement.State) {
+	w.clearing, w.trades = nil, nil
+	if state == nil {
+		return
+	}
+	w.clearing = &settlement.State{
+		Accounts:     state.Accounts,
+		Trades:       append([]settlement.Trade(nil), state.Trades...),
+		Instructions: state.Instructions,
+	}
+	w.trades = make(map[uint64]int, len(state.Trades))
+	for i, trade := range w.clearing.Trades {
+		w.trades[trade.ID] = i
+	}
+}
+
+// applyClearing merges a clearing delta: accounts and instructions are
+// replaced, trades updated in place or appended.
+func (w *workingImage) applyClearing(delta *settlement.State) {
+	if delta == nil {
+		w.clearing, w.trades = nil, nil
+		return
+	}
+	if w.clearing == nil {
+		w.setClearing(&settlement.State{})
+	}
+	w.clearing.Accounts = delta.Accounts
+	w.clearing.Instructions = delta.Instructions
+
+	appended := false
+	for _, trade := range delta.Trades {
+		if i, exists := w.trades[trade.ID]; exists {
+			w.clearing.Trades[i] = trade
+			continue
+		}
+		w.trades[trade.ID] = len(w.clearing.Trades)
+		w.clearing.Trades = append(w.clearing.Trades, trade)
+		appended = true
+	}
+	if appended {
+		trades := w.clearing.Trades
+		sort.Slice(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+		for i, trade := range trades {
+			w.trades[trade.ID] = i
+		}
+	}
+}
+
+// apply applies a delta: removals, then in-place updates, then appends.
+func (w *workingImage) apply(delta *Delta) {
+	w.eventSeq = delta.EventSeq
+	w.journalSeq = delta.JournalSeq
+	w.counters = delta.Counters
+	w.moved = delta.Moved
+	w.auctions = delta.Auctions
+	w.applyClearing(delta.Clearing)
+	for i := range delta.Books {
+		bd := &delta.Books[i]
+		wb := w.book(bd.Symbol)
+
+		// Group removals by level so each level is filtered once
+		removed := make(map[levelKey]map[uint64]bool)
+		for _, id := range bd.Removed {
+			k, exists := wb.where[id]
+			if !exists {
+				continue
+			}
+			if removed[k] == nil {
+				removed[k] = make(map[uint64]bool)
+			}
+			removed[k][id] = true
+			delete(wb.where, id)
+		}
+		for k, ids := range removed {
+			kept := wb.levels[k][:0]
+			for _, o := range wb.levels[k] {
+				if !ids[o.ID] {
+					kept = append(kept, o)
+				}
+			}
+			if len(kept) == 0 {
+				delete(wb.levels, k)
+			} else {
+				wb.levels[k] = kept
+			}
+		}
+
+		for _, u := range bd.Updated {
+			level := wb.levels[wb.where[u.ID]]
+			for j := range level {
+				if level[j].ID == u.ID {
+					level[j] = u
+					break
+				}
+			}
+		}
+
+		for _, o := range bd.Added {
+			k := keyOf(&o)
+			wb.levels[k] = append(wb.levels[k], o)
+			wb.where[o.ID] = k
+		}
+	}
+}
+
+// image flattens the working image back into queue order.
+func (w *workingImage) image() *Image {
+	img := &Image{
+		EventSeq: w.eventSeq,
+		Books:    make(map[string][]orders.Order, len(w.books)),
+		Counters: w.counters,
+		Moved:    w.moved,
+		Auctions: w.auctions,
+
+		JournalSeq: w.journalSeq,
+	}
+	if w.clearing != nil {
+		img.Clearing = &settlement.State{
+			Accounts:     w.clearing.Accounts,
+			Trades:       append([]settlement.Trade(nil), w.clearing.Trades...),
+			Instructions: w.clearing.Instructions,
+		}
+	}
+	for symbol, wb := range w.books {
+		if len(wb.levels) > 0 {
+			img.Books[symbol] = flatten(wb.levels)
+		}
+	}
+	return img
+}
+
+// flatten lists levels in queue order: bids best (highest) price first,
+// then asks best (lowest) price first.
+func flatten(levels map[levelKey][]orders.Order) []orders.Order {
+	keys := make([]levelKey, 0, len(levels))
+	n := 0
+	for k, level := range levels {
+		keys = append(keys, k)
+		n += len(level)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.side != b.side {
+			return a.side == orders.SideBuy
+		}
+		if a.side == orders.SideBuy {
+			return a.price > b.price
+		}
+		return a.price < b.price
+	})
+
+	book := make([]orders.Order, 0, n)
+	for _, k := range keys {
+		book = append(book, levels[k]...)
+	}
+	return book
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
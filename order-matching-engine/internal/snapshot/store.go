@@ -0,0 +1,304 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a snapshot file's contents don't
+// match its checksum.
+var ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+// File names: <generation>.full or <generation>.delta, generation zero-padded
+// so lexical order is chain order.
+const (
+	fullSuffix  = ".full"
+	deltaSuffix = ".delta"
+)
+
+// Policy decides when the next snapshot is written in full.
+type Policy struct {
+	// MaxDeltas is the longest chain of deltas after a full image. Recovery
+	// applies every one of them, so this bounds recovery time.
+	MaxDeltas int
+
+	// MaxDeltaRatio compacts once the deltas since the last full image add
+	// up to this fraction of its size; past that, a full image is cheaper
+	// than the chain it replaces.
+	MaxDeltaRatio float64
+}
+
+// DefaultPolicy returns the default compaction policy.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxDeltas:     20,
+		MaxDeltaRatio: 0.5,
+	}
+}
+
+// Stats describes what a store has written.
+type Stats struct {
+	Fulls           uint64 `json:"fulls"`
+	Deltas          uint64 `json:"deltas"`
+	BytesWritten    uint64 `json:"bytes_written"`
+	LastFullBytes   int64  `json:"last_full_bytes"`
+	DeltasSinceFull int    `json:"deltas_since_full"`
+	EventSeq        uint64 `json:"event_seq"` // EventSeq of the latest snapshot
+}
+
+// file is the on-disk envelope. Payload is a gob-encoded Image or Delta.
+type file struct {
+	Checksum uint32 // CRC32 of Payload
+	Payload  []byte
+}
+
+// Store writes snapshots to a directory as a full image followed by deltas.
+//
+// It is not safe for concurrent use; the processor gives it one writer
+// goroutine.
+type Store struct {
+	dir    string
+	policy Policy
+
+	last       *Image // State as of the latest snapshot written
+	generation uint64 // Generation of the latest file
+	chain      int    // Deltas since the last full image
+	deltaBytes int64  // Delta bytes since the last full image
+	stats      Stats
+}
+
+// Open opens (creating if needed) a snapshot directory and loads its latest
+// state, so the first snapshot written can already be a delta.
+func Open(dir string, policy Policy) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	if policy.MaxDeltas <= 0 {
+		policy.MaxDeltas = DefaultPolicy().MaxDeltas
+	}
+	if policy.MaxDeltaRatio <= 0 {
+		policy.MaxDeltaRatio = DefaultPolicy().MaxDeltaRatio
+	}
+
+	s := &Store{dir: dir, policy: policy}
+	img, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.last = img
+	return s, nil
+}
+
+// Latest returns the state as of the latest snapshot, or nil if there is
+// none.
+func (s *Store) Latest() *Image {
+	return s.last
+}
+
+// Stats returns what the store has written since it was opened.
+func (s *Store) Stats() Stats {
+	stats := s.stats
+	stats.DeltasSinceFull = s.chain
+	if s.last != nil {
+		stats.EventSeq = s.last.EventSeq
+	}
+	return stats
+}
+
+// Write persists img, as a delta against the previous snapshot or in full
+// if the policy calls for compaction. Returns false (and writes nothing) if
+// img is unchanged since the last snapshot.
+func (s *Store) Write(img *Image) (bool, error) {
+	if s.last != nil && s.last.EventSeq == img.EventSeq {
+		return false, nil
+	}
+
+	if s.last == nil || s.compactionDue() {
+		return true, s.writeFull(img)
+	}
+
+	delta := Diff(s.last, img)
+	n, err := s.writeFile(s.generation+1, deltaSuffix, delta)
+	if err != nil {
+		return false, err
+	}
+	s.generation++
+	s.chain++
+	s.deltaBytes += n
+	s.stats.Deltas++
+	s.last = img
+	return true, nil
+}
+
+// compactionDue reports whether the next snapshot should be a full image.
+func (s *Store) compactionDue() bool {
+	if s.chain >= s.policy.MaxDeltas {
+		return true
+	}
+	return s.stats.LastFullBytes > 0 &&
+		float64(s.deltaBytes) >= s.policy.MaxDeltaRatio*float64(s.stats.LastFullBytes)
+}
+
+// writeFull writes a full image and deletes the chain before it.
+func (s *Store) writeFull(img *Image) error {
+	n, err := s.writeFile(s.generation+1, fullSuffix, img)
+	if err != nil {
+		return err
+	}
+	s.generation++
+	s.chain = 0
+	s.deltaBytes = 0
+	s.stats.Fulls++
+	s.stats.LastFullBytes = n
+	s.last = img
+
+	// Compaction: nothing before the new full image is needed any more
+	names, err := s.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if gen, _, ok := parseName(name); ok && gen < s.generation {
+			os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+	return nil
+}
+
+// writeFile encodes v into a checksummed file, written to a temporary name
+// and renamed so a crash never leaves a torn snapshot. Returns its size.
+func (s *Store) writeFile(gen uint64, suffix string, v interface{}) (int64, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(v); err != nil {
+		return 0, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(file{
+		Checksum: crc32.ChecksumIEEE(payload.Bytes()),
+		Payload:  payload.Bytes(),
+	}); err != nil {
+		return 0, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", gen, suffix))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	s.stats.BytesWritten += uint64(buf.Len())
+	return int64(buf.Len()), nil
+}
+
+// load reads the latest full image and applies the deltas after it.
+// Returns nil if the directory holds no full image.
+func (s *Store) load() (*Image, error) {
+	names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	// Latest full image
+	start := -1
+	for i, name := range names {
+		if _, suffix, ok := parseName(name); ok && suffix == fullSuffix {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, nil
+	}
+
+	var full Image
+	gen, _, _ := parseName(names[start])
+	if err := readFile(filepath.Join(s.dir, names[start]), &full); err != nil {
+		return nil, fmt.Errorf("%s: %w", names[start], err)
+	}
+	s.generation = gen
+	img := newWorkingImage(&full)
+
+	// Deltas must follow without gaps
+	for _, name := range names[start+1:] {
+		next, suffix, ok := parseName(name)
+		if !ok || suffix != deltaSuffix || next != s.generation+1 {
+			break
+		}
+		path := filepath.Join(s.dir, name)
+		var delta Delta
+		if err := readFile(path, &delta); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		img.apply(&delta)
+		s.generation = next
+		s.chain++
+		if info, err := os.Stat(path); err == nil {
+			s.deltaBytes += info.Size()
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(s.dir, names[start])); err == nil {
+		s.stats.LastFullBytes = info.Size()
+	}
+	if s.chain == 0 {
+		return &full, nil // Nothing to apply
+	}
+	return img.image(), nil
+}
+
+// files lists snapshot files in generation order.
+func (s *Store) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, _, ok := parseName(entry.Name()); ok {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parseName splits a snapshot file name into generation and suffix.
+func parseName(name string) (uint64, string, bool) {
+	for _, suffix := range []string{fullSuffix, deltaSuffix} {
+		if strings.HasSuffix(name, suffix) {
+			gen, err := strconv.ParseUint(strings.TrimSuffix(name, suffix), 10, 64)
+			return gen, suffix, err == nil
+		}
+	}
+	return 0, "", false
+}
+
+// readFile decodes and verifies a snapshot file into v.
+func readFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f file
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&f); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if crc32.ChecksumIEEE(f.Payload) != f.Checksum {
+		return ErrChecksumMismatch
+	}
+	if err := gob.NewDecoder(bytes.NewReader(f.Payload)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return nil
+}
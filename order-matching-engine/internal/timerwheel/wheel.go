@@ -0,0 +1,225 @@
+// Package timerwheel implements a hierarchical timing wheel for
+// engine-internal timers: dead man's switches, and anything else the
+// processor needs to do at a point in time (order expiry, auction phase
+// changes, settlement cycles).
+//
+// A timing wheel is a circular array of slots, each covering one tick of
+// time. A timer goes into the slot its deadline falls in; advancing the
+// clock visits only the slots passed since the last advance. Scheduling and
+// cancelling are O(1), independent of how many timers are pending - unlike
+// a goroutine and time.After per timer, which costs memory and scheduler
+// work for every armed session.
+//
+// One wheel only covers slots × tick of time. Longer timers go in coarser
+// wheels stacked on top, like the hands of a clock:
+//
+//	level 0: 512 slots × 100ms      = 51.2s
+//	level 1: 512 slots × 51.2s      ≈ 7.3h
+//	level 2: 512 slots × 7.3h       ≈ 155 days
+//	level 3: 512 slots × 155 days   (anything later waits here)
+//
+// Each time a finer wheel completes a revolution, the next slot of the
+// coarser wheel is cascaded: its timers are re-placed in the finer wheels,
+// now that their deadlines are near. A timer is touched at most once per
+// level, so a GTD order expiring next week costs no more than a heartbeat
+// timeout due in five seconds.
+//
+// Determinism:
+//
+// The wheel never reads the clock. Time only moves when Advance is called,
+// so when the single-threaded processor drives it from sequenced tick
+// events, the same input sequence fires the same timers in the same order.
+// Timers due at the same Advance fire in (deadline, scheduling order).
+//
+// Waits that must fire even when the processor is stalled - an HTTP
+// handler giving up on a response, for instance - don't belong here; they
+// stay on the caller's goroutine with time.After.
+package timerwheel
+
+import (
+	"sort"
+	"time"
+)
+
+// Levels is the number of stacked wheels.
+const Levels = 4
+
+// TimerID identifies a scheduled timer.
+type TimerID uint64
+
+// timer is a scheduled callback.
+type timer struct {
+	id        TimerID
+	deadline  int64 // Nanoseconds since epoch
+	fn        func()
+	cancelled bool
+}
+
+// Wheel is a hierarchical timing wheel. It is not safe for concurrent use:
+// all calls must come from one goroutine (the event processor).
+type Wheel struct {
+	tick   int64         // Nanoseconds per level-0 slot
+	size   int64         // Slots per level
+	levels [][][]*timer  // Timers by level and slot; cancelled timers are dropped lazily
+	spans  [Levels]int64 // Ticks covered by one slot at each level
+	now    int64         // Time of the last Advance
+	cur    int64         // now / tick
+	timers map[TimerID]*timer
+	nextID TimerID
+}
+
+// New creates a wheel with the given tick and slots per level, starting at
+// start (nanoseconds since epoch).
+func New(tick time.Duration, slots int, start int64) *Wheel {
+	if tick <= 0 {
+		tick = time.Millisecond
+	}
+	if slots <= 1 {
+		slots = 512
+	}
+
+	w := &Wheel{
+		tick:   int64(tick),
+		size:   int64(slots),
+		levels: make([][][]*timer, Levels),
+		now:    start,
+		cur:    start / int64(tick),
+		timers: make(map[TimerID]*timer),
+	}
+	span := int64(1)
+	for level := range w.levels {
+		w.levels[level] = make([][]*timer, slots)
+		w.spans[level] = span
+		span *= w.size
+	}
+	return w
+}
+
+// Now returns the wheel's current time, as of the last Advance.
+func (w *Wheel) Now() int64 {
+	return w.now
+}
+
+// Len returns the number of pending timers.
+func (w *Wheel) Len() int {
+	return len(w.timers)
+}
+
+// Schedule arranges for fn to run at the first Advance at or after
+// deadline. A deadline already in the past fires on the next Advance.
+func (w *Wheel) Schedule(deadline int64, fn func()) TimerID {
+	w.nextID++
+	t := &timer{id: w.nextID, deadline: deadline, fn: fn}
+	w.timers[t.id] = t
+	w.place(t)
+	return t.id
+}
+
+// Cancel stops a pending timer. Returns false if it already fired or was
+// cancelled.
+func (w *Wheel) Cancel(id TimerID) bool {
+	t, exists := w.timers[id]
+	if !exists {
+		return false
+	}
+	t.cancelled = true
+	delete(w.timers, id)
+	return true
+}
+
+// Advance moves the wheel's time to now and fires every timer due by then.
+// Time never moves backwards; an earlier now is ignored. Timers scheduled
+// by a callback fire no earlier than the next Advance.
+// Returns the number of timers fired.
+func (w *Wheel) Advance(now int64) int {
+	if now < w.now {
+		return 0
+	}
+
+	// The current slot may hold timers due later in this same tick
+	due := w.collect(nil, now)
+
+	target := now / w.tick
+	if len(w.timers) == 0 {
+		w.cur = target // Nothing pending: skip the walk
+	}
+	for w.cur < target {
+		w.cur++
+		w.cascade()
+		due = w.collect(due, now)
+	}
+	w.now = now
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].deadline != due[j].deadline {
+			return due[i].deadline < due[j].deadline
+		}
+		return due[i].id < due[j].id
+	})
+
+	fired := 0
+	for _, t := range due {
+		if t.cancelled { // Cancelled by an earlier callback in this batch
+			continue
+		}
+		delete(w.timers, t.id)
+		t.fn()
+		fired++
+	}
+	return fired
+}
+
+// collect removes the timers due by now from the current level-0 slot.
+func (w *Wheel) collect(due []*timer, now int64) []*timer {
+	slot := w.cur % w.size
+	keep := w.levels[0][slot][:0]
+	for _, t := range w.levels[0][slot] {
+		switch {
+		case t.cancelled:
+		case t.deadline <= now:
+			due = append(due, t)
+		default:
+			keep = append(keep, t)
+		}
+	}
+	w.levels[0][slot] = keep
+	return due
+}
+
+// cascade re-places the timers of every coarser slot that starts at the
+// current tick, coarsest first, so each lands in the finest wheel that can
+// now hold it.
+func (w *Wheel) cascade() {
+	top := 0
+	for top+1 < Levels && w.cur%w.spans[top+1] == 0 {
+		top++
+	}
+	for level := top; level >= 1; level-- {
+		slot := (w.cur / w.spans[level]) % w.size
+		pending := w.levels[level][slot]
+		w.levels[level][slot] = nil
+		for _, t := range pending {
+			if !t.cancelled {
+				w.place(t)
+			}
+		}
+	}
+}
+
+// place puts a timer in the finest level whose current revolution covers
+// its deadline. Past deadlines go in the current slot, which the next
+// Advance always visits. Timers beyond the top level wait in its slot and
+// are re-placed each time it comes round.
+func (w *Wheel) place(t *timer) {
+	at := t.deadline / w.tick
+	if at < w.cur {
+		at = w.cur
+	}
+
+	level := 0
+	for level < Levels-1 && at/w.spans[level]-w.cur/w.spans[level] >= w.size {
+		level++
+	}
+	slot := (at / w.spans[level]) % w.size
+	w.levels[level][slot] = append(w.levels[level][slot], t)
+}
@@ -0,0 +1,111 @@
+package timerwheel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const ms = int64(time.Millisecond)
+
+// TestWheel_FiresInDeadlineOrder tests that due timers fire in (deadline,
+// scheduling order) and only once their deadline is reached
+func TestWheel_FiresInDeadlineOrder(t *testing.T) {
+	w := New(10*time.Millisecond, 8, 0)
+
+	var fired []string
+	w.Schedule(30*ms, func() { fired = append(fired, "C") })
+	w.Schedule(15*ms, func() { fired = append(fired, "A") })
+	w.Schedule(30*ms, func() { fired = append(fired, "D") })
+	w.Schedule(25*ms, func() { fired = append(fired, "B") })
+
+	if n := w.Advance(20 * ms); n != 1 {
+		t.Errorf("Expected 1 timer fired at 20ms, got %d", n)
+	}
+	w.Advance(40 * ms)
+
+	if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("Expected firing order %v, got %v", want, fired)
+	}
+	if w.Len() != 0 {
+		t.Errorf("Expected no pending timers, got %d", w.Len())
+	}
+}
+
+// TestWheel_Cancel tests that cancelled timers never fire
+func TestWheel_Cancel(t *testing.T) {
+	w := New(10*time.Millisecond, 8, 0)
+
+	fired := false
+	id := w.Schedule(20*ms, func() { fired = true })
+	if !w.Cancel(id) {
+		t.Error("Expected Cancel to succeed")
+	}
+	if w.Cancel(id) {
+		t.Error("Expected a second Cancel to fail")
+	}
+
+	w.Advance(100 * ms)
+	if fired {
+		t.Error("Cancelled timer fired")
+	}
+}
+
+// TestWheel_PastDeadlineAndLongGap tests that a past deadline fires on the
+// next advance, and timers beyond one revolution fire only when due
+func TestWheel_PastDeadlineAndLongGap(t *testing.T) {
+	w := New(10*time.Millisecond, 8, 100*ms)
+
+	var fired []string
+	w.Schedule(50*ms, func() { fired = append(fired, "past") })
+	w.Schedule(100*ms+200*ms, func() { fired = append(fired, "far") }) // 2.5 revolutions out
+
+	w.Advance(105 * ms)
+	if !reflect.DeepEqual(fired, []string{"past"}) {
+		t.Fatalf("Expected only the past timer, got %v", fired)
+	}
+
+	w.Advance(250 * ms)
+	if len(fired) != 1 {
+		t.Fatalf("Far timer fired early: %v", fired)
+	}
+
+	// A gap longer than the wheel still visits every slot
+	w.Advance(10000 * ms)
+	if !reflect.DeepEqual(fired, []string{"past", "far"}) {
+		t.Errorf("Expected the far timer after the gap, got %v", fired)
+	}
+}
+
+// TestWheel_CascadesFromCoarserLevels tests that timers placed in coarser
+// wheels (and beyond the top one) fire at the first tick at or after their
+// deadline, in order, when the wheel is driven tick by tick
+func TestWheel_CascadesFromCoarserLevels(t *testing.T) {
+	// 8 slots per level: level 0 covers 80ms, level 3 about 41s
+	w := New(10*time.Millisecond, 8, 0)
+
+	deadlines := []int64{95 * ms, 700 * ms, 3001 * ms, 45000 * ms, 100005 * ms}
+	firedAt := make(map[int64]int64)
+	var order []int64
+	for i := len(deadlines) - 1; i >= 0; i-- {
+		deadline := deadlines[i]
+		w.Schedule(deadline, func() {
+			firedAt[deadline] = w.Now()
+			order = append(order, deadline)
+		})
+	}
+
+	for now := int64(0); now <= 101000*ms; now += 10 * ms {
+		w.Advance(now)
+	}
+
+	if !reflect.DeepEqual(order, deadlines) {
+		t.Errorf("Expected firing order %v, got %v", deadlines, order)
+	}
+	for _, deadline := range deadlines {
+		want := (deadline + 10*ms - 1) / (10 * ms) * (10 * ms) // First tick at or after
+		if firedAt[deadline] != want {
+			t.Errorf("Timer due at %dms fired at %dms, want %dms", deadline/ms, firedAt[deadline]/ms, want/ms)
+		}
+	}
+}
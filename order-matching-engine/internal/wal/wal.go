@@ -0,0 +1,375 @@
+// Package wal implements the request journal: a write-ahead log of the
+// requests a processor is about to apply, written before it applies them.
+//
+// Records are opaque payloads numbered from 1, appended a batch at a time
+// with one fsync per batch:
+//
+//	header  [magic "OMEJ"][base uint64]             records before the file
+//	record  [length uint32][seq uint64][crc32 uint32][payload]
+//
+// The checksum covers the sequence number and payload. A record cut short
+// by a crash, or failing its checksum at the very end of the file, is a
+// torn write: its batch never returned, so nothing was acknowledged on the
+// strength of it, and Open truncates it away. A bad record with more after
+// it is damage, and Open fails with ErrChecksumMismatch.
+//
+// Compact drops the records a snapshot has made redundant by rewriting the
+// file; the header's base keeps the numbering going when none are left.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrChecksumMismatch is returned by Open when a record other than the last
+// fails its checksum.
+var ErrChecksumMismatch = errors.New("wal: checksum mismatch")
+
+const (
+	magic      = "OMEJ"
+	headerSize = len(magic) + 8
+	frameSize  = 4 + 8 + 4 // length, seq, crc
+	maxRecord  = 64 << 20  // Larger lengths are garbage, not records
+)
+
+// Log is an open request journal. Safe for concurrent use, though the
+// processor is its only writer.
+type Log struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	base    uint64 // Sequence number before the first record in the file
+	lastSeq uint64
+	size    int64
+	torn    int64 // Bytes of a torn record dropped on open
+	buf     []byte
+}
+
+// Open opens the journal at path, creating it if needed, and recovers its
+// last sequence number.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request journal: %w", err)
+	}
+	l := &Log{path: path, file: file}
+	if err := l.recover(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to recover request journal %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// recover reads the header and every record, truncating a torn tail.
+func (l *Log) recover() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		l.size = int64(headerSize)
+		return writeHeader(l.file, 0)
+	}
+
+	var end int64
+	err = scan(l.file, 0, func(base uint64) {
+		l.base, l.lastSeq = base, base
+	}, func(seq uint64, payload []byte, offset int64) error {
+		l.lastSeq, end = seq, offset
+		return nil
+	})
+	var torn *tornError
+	if errors.As(err, &torn) {
+		if err := l.file.Truncate(torn.offset); err != nil {
+			return fmt.Errorf("failed to truncate torn write: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to truncate torn write: %w", err)
+		}
+		l.torn = info.Size() - torn.offset
+		if torn.offset == 0 {
+			l.size = int64(headerSize)
+			return writeHeader(l.file, 0) // Torn before the first record was written
+		}
+		end, err = torn.offset, nil
+	}
+	if err != nil {
+		return err
+	}
+	if end == 0 {
+		end = int64(headerSize)
+	}
+	l.size = end
+	_, err = l.file.Seek(end, io.SeekStart)
+	return err
+}
+
+// writeHeader writes a header at the start of file and syncs it.
+func writeHeader(file *os.File, base uint64) error {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint64(header[len(magic):], base)
+	if _, err := file.WriteAt(header, 0); err != nil {
+		return err
+	}
+	if _, err := file.Seek(int64(headerSize), io.SeekStart); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Append writes a batch of records and syncs them, returning the sequence
+// number of the first; the others follow in order. If any of it fails the
+// batch is cut back off the file, and no record of it is numbered.
+func (l *Log) Append(records [][]byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	first := l.lastSeq + 1
+	if len(records) == 0 {
+		return first, nil
+	}
+
+	buf := l.buf[:0]
+	for i, payload := range records {
+		buf = appendFrame(buf, first+uint64(i), payload)
+	}
+	l.buf = buf
+
+	if _, err := l.file.Write(buf); err != nil {
+		return 0, l.rollback(fmt.Errorf("failed to write requests: %w", err))
+	}
+	if err := l.file.Sync(); err != nil {
+		return 0, l.rollback(fmt.Errorf("failed to sync requests: %w", err))
+	}
+	l.size += int64(len(buf))
+	l.lastSeq += uint64(len(records))
+	return first, nil
+}
+
+// rollback cuts a failed batch off the end of the file. Caller must hold
+// the lock.
+func (l *Log) rollback(err error) error {
+	if terr := l.file.Truncate(l.size); terr != nil {
+		return fmt.Errorf("%w (and failed to cut it off: %v)", err, terr)
+	}
+	if _, serr := l.file.Seek(l.size, io.SeekStart); serr != nil {
+		return fmt.Errorf("%w (and failed to cut it off: %v)", err, serr)
+	}
+	return err
+}
+
+// appendFrame appends one framed record to buf.
+func appendFrame(buf []byte, seq uint64, payload []byte) []byte {
+	var frame [frameSize]byte
+	binary.BigEndian.PutUint32(frame[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint64(frame[4:], seq)
+	binary.BigEndian.PutUint32(frame[12:], checksum(frame[4:12], payload))
+	buf = append(buf, frame[:]...)
+	return append(buf, payload...)
+}
+
+// checksum covers a record's sequence number and payload.
+func checksum(seq, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(seq), crc32.IEEETable, payload)
+}
+
+// Replay calls fn with each record after sequence number after, in order.
+// Records up to after are read but skipped. The payload is only valid
+// until fn returns.
+func (l *Log) Replay(after uint64, fn func(seq uint64, payload []byte) error) error {
+	l.mu.Lock()
+	file, err := os.Open(l.path)
+	size := l.size
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return scan(file, size, nil, func(seq uint64, payload []byte, offset int64) error {
+		if seq <= after {
+			return nil
+		}
+		return fn(seq, payload)
+	})
+}
+
+// Compact rewrites the journal without the records up to sequence number
+// after. Numbering carries on from where it was.
+func (l *Log) Compact(after uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if after <= l.base {
+		return nil // Nothing to drop
+	}
+	if after > l.lastSeq {
+		after = l.lastSeq
+	}
+
+	tmp := l.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact request journal: %w", err)
+	}
+
+	if err := writeHeader(file, after); err != nil {
+		return fail(err)
+	}
+	writer := bufio.NewWriter(file)
+	var buf []byte
+	err = scan(l.file, l.size, nil, func(seq uint64, payload []byte, offset int64) error {
+		if seq <= after {
+			return nil
+		}
+		buf = appendFrame(buf[:0], seq, payload)
+		_, err := writer.Write(buf)
+		return err
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, l.path)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	l.file.Close()
+	l.file, l.base = file, after
+	l.size, err = file.Seek(0, io.SeekEnd)
+	return err
+}
+
+// LastSeq returns the sequence number of the last record, or of the
+// records compacted away if none are left.
+func (l *Log) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastSeq
+}
+
+// FirstSeq returns the sequence number of the first record in the journal.
+// With no records, it is the one the next append gets.
+func (l *Log) FirstSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.base + 1
+}
+
+// TornBytes returns the size of the torn record dropped from the end of the
+// journal when it was opened, or 0 if it ended cleanly.
+func (l *Log) TornBytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.torn
+}
+
+// Path returns the journal's file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Close closes the journal. Every appended batch is already synced.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// tornError is a record cut short at the end of the file.
+type tornError struct {
+	offset int64 // Where the torn record starts
+}
+
+func (e *tornError) Error() string {
+	return fmt.Sprintf("wal: torn record at offset %d", e.offset)
+}
+
+// scan reads the header and the records of file, up to limit bytes (0 =
+// the whole file), calling header with the base and record with each
+// record and the offset it ends at. A torn tail is returned as *tornError.
+func scan(file *os.File, limit int64, header func(base uint64), record func(seq uint64, payload []byte, offset int64) error) error {
+	var r io.Reader = io.NewSectionReader(file, 0, 1<<62)
+	if limit > 0 {
+		r = io.NewSectionReader(file, 0, limit)
+	}
+	reader := bufio.NewReader(r)
+
+	head := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return &tornError{offset: 0}
+		}
+		return err
+	}
+	if string(head[:len(magic)]) != magic {
+		return errors.New("wal: not a request journal")
+	}
+	if header != nil {
+		header(binary.BigEndian.Uint64(head[len(magic):]))
+	}
+
+	offset := int64(headerSize)
+	var frame [frameSize]byte
+	var payload []byte
+	for {
+		if _, err := io.ReadFull(reader, frame[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return &tornError{offset: offset}
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(frame[0:])
+		seq := binary.BigEndian.Uint64(frame[4:])
+		if length > maxRecord {
+			return damaged(reader, offset, seq)
+		}
+		if cap(payload) < int(length) {
+			payload = make([]byte, length)
+		}
+		payload = payload[:length]
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return &tornError{offset: offset}
+			}
+			return err
+		}
+		if checksum(frame[4:12], payload) != binary.BigEndian.Uint32(frame[12:]) {
+			return damaged(reader, offset, seq)
+		}
+
+		offset += int64(frameSize) + int64(length)
+		if err := record(seq, payload, offset); err != nil {
+			return err
+		}
+	}
+}
+
+// damaged reports a bad record at offset: torn if nothing follows it,
+// otherwise a checksum mismatch.
+func damaged(reader *bufio.Reader, offset int64, seq uint64) error {
+	if _, err := reader.Peek(1); err == io.EOF {
+		return &tornError{offset: offset}
+	}
+	return fmt.Errorf("%w at record %d (offset %d)", ErrChecksumMismatch, seq, offset)
+}
@@ -0,0 +1,198 @@
+// Package wsjournal numbers the messages of WebSocket order entry sessions
+// and keeps them for resend.
+//
+// Every message the server sends on a logged-in session carries a seq, 1
+// for the login_ack and increasing by one per message. Besides the replies
+// to the client's own requests, a session is sent an execution_report for
+// every change to the orders placed on it, whoever caused it: a resting
+// order's fills, a cancel by the dead man's switch. The reports a request
+// causes come before its reply.
+//
+// A client that sees its connection drop can't tell which messages were
+// lost on the way: after reconnecting and logging in again, it asks for
+// everything after the last seq it processed on the old session.
+//
+//	→ {"type":"resend","session_id":"WS-1","from_seq":17}
+//	← {"type":"execution_report","seq":17,"session_id":"WS-1","poss_dup":true,...}
+//	← {"type":"cancel_ack","seq":18,"session_id":"WS-1","poss_dup":true,...}
+//	← {"type":"resend_ack","seq":3,"body":{"session_id":"WS-1","from_seq":17,"to_seq":18}}
+//
+// Resent messages keep their original seq and are flagged poss_dup. Only
+// sessions of the same account can be resent. A session's journal goes on
+// recording the execution reports of its orders after it disconnects, so
+// fills while the client was away are resent too. The journal keeps the
+// last Size messages per session, for TTL after it ends; a resend from
+// before that is refused, and the client should reconcile with GET /orders
+// instead.
+package wsjournal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+const (
+	Size = 1000            // Messages kept per session
+	TTL  = 5 * time.Minute // How long a closed session can be resent
+)
+
+// Message is a server-to-client WebSocket message.
+type Message struct {
+	Type               string      `json:"type"`
+	Seq                uint64      `json:"seq,omitempty"`      // Per-session message number, from 1
+	PossDup            bool        `json:"poss_dup,omitempty"` // Resent: seen before, possibly
+	SessionID          string      `json:"session_id,omitempty"`
+	CancelOnDisconnect bool        `json:"cancel_on_disconnect,omitempty"`
+	Status             int         `json:"status,omitempty"`
+	Body               interface{} `json:"body,omitempty"`
+	Error              string      `json:"error,omitempty"`
+}
+
+// Journal records the messages sent on one session.
+type Journal struct {
+	accountID string
+
+	mu       sync.Mutex
+	next     uint64         // Seq of the next message
+	sent     []Message      // Last Size messages, oldest first
+	out      chan<- Message // The connection's outgoing messages; nil once closed
+	closedAt time.Time      // Zero while the session is connected
+}
+
+// AccountID returns the account the session logged in as.
+func (j *Journal) AccountID() string {
+	return j.accountID
+}
+
+// Record assigns the next seq to a message, keeps a copy of it and queues
+// it on the connection.
+func (j *Journal) Record(msg *Message) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.next++
+	msg.Seq = j.next
+	j.sent = append(j.sent, *msg)
+	if len(j.sent) > Size {
+		j.sent[0] = Message{}
+		j.sent = j.sent[1:]
+	}
+	j.queue(*msg)
+}
+
+// Send queues a message on the connection without recording it.
+func (j *Journal) Send(msg Message) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.queue(msg)
+}
+
+// queue sends a message to the connection. Never blocks, since reports are
+// recorded on the event processor: a connection too slow to keep up is
+// closed, and the client resends what it missed on the next one.
+func (j *Journal) queue(msg Message) {
+	if j.out == nil {
+		return
+	}
+	select {
+	case j.out <- msg:
+	default:
+		close(j.out)
+		j.out = nil
+	}
+}
+
+// Resend returns the recorded messages from seq from onwards, flagged as
+// possible duplicates of sessionID's. ok is false if some of them are no
+// longer kept; first is the oldest seq that still is.
+func (j *Journal) Resend(sessionID string, from uint64) (msgs []Message, first uint64, ok bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if from == 0 {
+		from = 1
+	}
+	first = j.next + 1
+	if len(j.sent) > 0 {
+		first = j.sent[0].Seq
+	}
+	if from < first && from <= j.next {
+		return nil, first, false
+	}
+	for _, msg := range j.sent {
+		if msg.Seq >= from {
+			msg.PossDup = true
+			msg.SessionID = sessionID
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, first, true
+}
+
+// Close marks a session's journal as closed, and closes its connection's
+// outgoing messages. It is kept for TTL.
+func (j *Journal) Close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.out != nil {
+		close(j.out)
+		j.out = nil
+	}
+	j.closedAt = time.Now()
+}
+
+// Store holds the journals of live and recently closed sessions.
+type Store struct {
+	mu       sync.Mutex
+	sessions map[string]*Journal
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{sessions: make(map[string]*Journal)}
+}
+
+// Open starts the journal of a new session, whose messages are queued on
+// out, dropping journals that closed more than TTL ago. The journal closes
+// out when it closes, or when out is full.
+func (s *Store) Open(sessionID, accountID string, out chan<- Message) *Journal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, j := range s.sessions {
+		j.mu.Lock()
+		expired := !j.closedAt.IsZero() && time.Since(j.closedAt) > TTL
+		j.mu.Unlock()
+		if expired {
+			delete(s.sessions, id)
+		}
+	}
+
+	j := &Journal{accountID: accountID, out: out}
+	s.sessions[sessionID] = j
+	return j
+}
+
+// Get returns a session's journal if it is still kept.
+func (s *Store) Get(sessionID string) *Journal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[sessionID]
+}
+
+// Report records an execution report on the journal of the session its
+// order was placed on, if that is still kept. Never blocks, so it can be
+// an event processor's execution hook.
+func (s *Store) Report(report orders.ExecutionReport) {
+	if report.SessionID == "" {
+		return
+	}
+	j := s.Get(report.SessionID)
+	if j == nil || j.accountID != report.AccountID {
+		return
+	}
+	j.Record(&Message{Type: "execution_report", Body: dropcopy.NewExecution(report)})
+}
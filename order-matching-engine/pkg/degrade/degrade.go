@@ -0,0 +1,241 @@
+// Package degrade is the overload policy every part of the server follows,
+// so that under load they give ground in the same order instead of each
+// improvising its own.
+//
+// Degradation Levels (each includes the ones before it):
+//
+//	normal             everything is served
+//	shed-low-priority  reads (book, stats, queries) are refused by the
+//	                   gateway; market data is conflated
+//	reject-new         new orders, replaces and baskets are refused by the
+//	                   engine; cancels still go through
+//	drain              as reject-new, entered for good while shutting down
+//
+// Cancels and admin requests are never refused: they reduce risk, and an
+// operator has to be able to act on an overloaded server.
+//
+// The level is the highest of three sources:
+//
+//	load      ring buffer occupancy against the Policy thresholds
+//	override  a level set by an operator, e.g. ahead of a known burst
+//	drain     set once, when shutdown starts
+//
+// Load levels rise as soon as a threshold is crossed, but only fall once
+// occupancy is back below Policy.Recover of it, so a server hovering at a
+// threshold doesn't flap.
+package degrade
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is how far the server has degraded.
+type Level int32
+
+const (
+	Normal Level = iota
+	ShedLowPriority
+	RejectNew
+	Drain
+)
+
+var levelNames = [...]string{"normal", "shed-low-priority", "reject-new", "drain"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level by name.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if n == name {
+			return Level(i), nil
+		}
+	}
+	return Normal, fmt.Errorf("unknown degradation level %q", name)
+}
+
+// Class is the priority of a piece of work.
+type Class int
+
+const (
+	// Critical work is always admitted: cancels, mass cancels, admin.
+	Critical Class = iota
+	// Order is new risk: orders, replaces, baskets.
+	Order
+	// LowPriority is everything that can wait: reads and queries.
+	LowPriority
+)
+
+// Admits reports whether work of class c is served at this level.
+func (l Level) Admits(c Class) bool {
+	switch c {
+	case Critical:
+		return true
+	case Order:
+		return l < RejectNew
+	default:
+		return l < ShedLowPriority
+	}
+}
+
+// Conflates reports whether market data is conflated at this level.
+func (l Level) Conflates() bool {
+	return l >= ShedLowPriority
+}
+
+// Policy maps load to a level.
+type Policy struct {
+	ShedAt   float64 // Ring buffer occupancy (0-1) that sheds low priority work (0 = never)
+	RejectAt float64 // Occupancy that rejects new orders (0 = never)
+	Recover  float64 // Fraction of a threshold occupancy must fall below to leave its level
+}
+
+// DefaultPolicy returns reasonable defaults.
+func DefaultPolicy() Policy {
+	return Policy{
+		ShedAt:   0.5,
+		RejectAt: 0.85,
+		Recover:  0.8,
+	}
+}
+
+// Status is the controller's state, for operators.
+type Status struct {
+	Level    string    `json:"level"`
+	Load     string    `json:"load"`               // Level from occupancy alone
+	Override string    `json:"override,omitempty"` // Operator-set floor
+	Draining bool      `json:"draining"`
+	Since    time.Time `json:"since"` // When the level last changed
+}
+
+// Controller tracks the current level and tells components when it
+// changes. Safe for concurrent use; Level is a single atomic load, cheap
+// enough for every request.
+type Controller struct {
+	policy Policy
+	level  int32 // Atomic Level
+
+	mu        sync.Mutex
+	load      Level
+	override  Level
+	draining  bool
+	since     time.Time
+	listeners []func(from, to Level)
+}
+
+// NewController creates a controller at Normal.
+func NewController(policy Policy) *Controller {
+	return &Controller{policy: policy, since: time.Now()}
+}
+
+// Level returns the current level.
+func (c *Controller) Level() Level {
+	return Level(atomic.LoadInt32(&c.level))
+}
+
+// Admits reports whether work of class cl is served now.
+func (c *Controller) Admits(cl Class) bool {
+	return c.Level().Admits(cl)
+}
+
+// OnChange registers a hook called with every level change. It runs on the
+// goroutine making the change, with no lock held.
+func (c *Controller) OnChange(fn func(from, to Level)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Observe feeds the current load: the fullest ring buffer's occupancy,
+// from 0 (empty) to 1 (full).
+func (c *Controller) Observe(occupancy float64) {
+	c.mu.Lock()
+	c.load = c.policy.levelFor(c.load, occupancy)
+	c.update()
+}
+
+// levelFor returns the load level for an occupancy, given the current one.
+func (p Policy) levelFor(current Level, occupancy float64) Level {
+	above := func(threshold float64, level Level) bool {
+		if threshold <= 0 {
+			return false
+		}
+		if current >= level {
+			return occupancy >= threshold*p.Recover // Hold until well below
+		}
+		return occupancy >= threshold
+	}
+	switch {
+	case above(p.RejectAt, RejectNew):
+		return RejectNew
+	case above(p.ShedAt, ShedLowPriority):
+		return ShedLowPriority
+	}
+	return Normal
+}
+
+// Override sets a floor the level won't drop below whatever the load;
+// Normal clears it. Drain can't be set this way.
+func (c *Controller) Override(level Level) error {
+	if level < Normal || level >= Drain {
+		return fmt.Errorf("can't override to %s", level)
+	}
+	c.mu.Lock()
+	c.override = level
+	c.update()
+	return nil
+}
+
+// Drain enters the drain level for good.
+func (c *Controller) Drain() {
+	c.mu.Lock()
+	c.draining = true
+	c.update()
+}
+
+// update recomputes the level and notifies listeners of a change. Called
+// with c.mu held; releases it.
+func (c *Controller) update() {
+	level := c.load
+	if c.override > level {
+		level = c.override
+	}
+	if c.draining {
+		level = Drain
+	}
+	from := Level(atomic.SwapInt32(&c.level, int32(level)))
+	if from == level {
+		c.mu.Unlock()
+		return
+	}
+	c.since = time.Now()
+	listeners := c.listeners
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(from, level)
+	}
+}
+
+// Status returns the controller's state.
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := Status{
+		Level:    c.Level().String(),
+		Load:     c.load.String(),
+		Draining: c.draining,
+		Since:    c.since,
+	}
+	if c.override != Normal {
+		status.Override = c.override.String()
+	}
+	return status
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// ACCOUNT LIFECYCLE
+// ============================================================================
+
+// TestAccounts_WithdrawOnlyWhatIsFree verifies a withdrawal can't take cash
+// an open buy holds or shares an open sell holds, and a refused one takes
+// nothing.
+func TestAccounts_WithdrawOnlyWhatIsFree(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run, clearing := buyingPowerRun(t, openLog(t), engine)
+	defer run.processor.Shutdown()
+	if err := clearing.Deposit("T1", 0, "AAPL", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	run.order(limit(orders.SideBuy, 15000, 600)) // Holds $90,000 of $100,000
+	run.order(limit(orders.SideSell, 16000, 70)) // Holds 70 of 100 shares
+
+	err := clearing.Withdraw("T1", 1000001, "AAPL", 10)
+	if err == nil || !strings.Contains(err.Error(), "insufficient cash") {
+		t.Errorf("Expected more than the free $10,000 refused, got %v", err)
+	}
+	if err := clearing.Withdraw("T1", 0, "AAPL", 31); err == nil || !strings.Contains(err.Error(), "insufficient shares") {
+		t.Errorf("Expected more than the 30 free shares refused, got %v", err)
+	}
+	if acct := clearing.GetAccount("T1"); acct.Cash != 10000000 || acct.Holdings["AAPL"] != 100 {
+		t.Fatalf("Expected refusals to take nothing, got %+v", acct)
+	}
+
+	if err := clearing.Withdraw("T1", 1000000, "AAPL", 30); err != nil {
+		t.Fatalf("Expected what is free withdrawn, got %v", err)
+	}
+	if clearing.BuyingPower("T1") != 0 || clearing.AvailableShares("T1", "AAPL") != 0 {
+		t.Errorf("Expected nothing left free, got %d cash, %d shares",
+			clearing.BuyingPower("T1"), clearing.AvailableShares("T1", "AAPL"))
+	}
+	if err := clearing.Withdraw("NOPE", 1, "", 0); !errors.Is(err, settlement.ErrAccountNotFound) {
+		t.Errorf("Expected an unknown account refused, got %v", err)
+	}
+}
+
+// TestAccounts_LoggedWhenApplied verifies account changes go through the
+// processor to the hook, and only those it applies are logged, with who
+// asked for them.
+func TestAccounts_LoggedWhenApplied(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	clearing := settlement.NewClearingHouse()
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.OnAccount(func(change *events.AccountEvent) error {
+		switch change.Action {
+		case events.AccountOpened:
+			return clearing.OpenAccount(change.AccountID, change.Cash)
+		case events.AccountDeposit:
+			return clearing.Deposit(change.AccountID, change.Cash, change.Symbol, change.Shares)
+		}
+		return clearing.Withdraw(change.AccountID, change.Cash, change.Symbol, change.Shares)
+	})
+	run.processor.Start()
+
+	for _, step := range []struct {
+		change  events.AccountEvent
+		applied bool
+	}{
+		{events.AccountEvent{AccountID: "C9", Action: events.AccountOpened, Cash: 500000}, true},
+		{events.AccountEvent{AccountID: "C9", Action: events.AccountOpened, Cash: 100}, false}, // Taken
+		{events.AccountEvent{AccountID: "C9", Action: events.AccountDeposit, Symbol: "AAPL", Shares: 50}, true},
+		{events.AccountEvent{AccountID: "C9", Action: events.AccountWithdrawal, Cash: 600000}, false}, // Overdrawn
+		{events.AccountEvent{AccountID: "C9", Action: events.AccountWithdrawal, Cash: 200000}, true},
+	} {
+		change := step.change
+		change.Actor = "alice@10.0.0.5:51234"
+		response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeAccount, Account: &change})
+		if response.Success != step.applied {
+			t.Errorf("%s %d: expected applied %v, got %v", change.Action, change.Cash, step.applied, response.Error)
+		}
+	}
+	run.processor.Shutdown()
+
+	if acct := clearing.GetAccount("C9"); acct.Cash != 300000 || acct.Holdings["AAPL"] != 50 {
+		t.Errorf("Expected $3,000 and 50 AAPL left, got %+v", acct)
+	}
+	var logged []string
+	for _, event := range replayAll(t, eventLog) {
+		if e, ok := event.(*events.AccountEvent); ok {
+			if e.Actor != "alice@10.0.0.5:51234" {
+				t.Errorf("Expected the actor logged, got %+v", e)
+			}
+			logged = append(logged, e.Action)
+		}
+	}
+	if strings.Join(logged, ",") != "OPEN,DEPOSIT,WITHDRAW" {
+		t.Errorf("Expected only the applied changes logged, got %v", logged)
+	}
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+)
+
+// ============================================================================
+// PRO-RATA ALLOCATION
+// ============================================================================
+
+// proRataEngine returns an engine allocating ES pro-rata, with asks of the
+// given sizes resting at $150.00 in that order.
+func proRataEngine(t *testing.T, alloc orders.Allocation, sizes ...int64) (*matching.Engine, []*orders.Order) {
+	t.Helper()
+	engine := matching.NewEngine()
+	engine.AddSymbol("ES")
+	alloc.Method = orders.AllocationProRata
+	engine.SetAllocation("ES", alloc)
+
+	makers := make([]*orders.Order, len(sizes))
+	for i, size := range sizes {
+		makers[i] = &orders.Order{Symbol: "ES", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: size, AccountID: "MM"}
+		if result := engine.ProcessOrder(makers[i]); !result.Accepted {
+			t.Fatalf("Maker %d rejected: %s", i, result.RejectReason)
+		}
+	}
+	return engine, makers
+}
+
+// checkShares verifies a result's fills went to makers in queue order, each
+// for the quantity in want (makers due nothing get no fill).
+func checkShares(t *testing.T, result *orders.ExecutionResult, makers []*orders.Order, want ...int64) {
+	t.Helper()
+	fills := result.Fills
+	i := 0
+	for m, qty := range want {
+		if qty == 0 {
+			continue
+		}
+		if i >= len(fills) {
+			t.Fatalf("Expected a fill of %d for maker %d, got only %d fills", qty, m, len(fills))
+		}
+		if fills[i].MakerOrderID != makers[m].ID || fills[i].Quantity != qty {
+			t.Errorf("Fill %d: maker %d qty %d, want maker %d qty %d", i, fills[i].MakerOrderID, fills[i].Quantity, makers[m].ID, qty)
+		}
+		if i > 0 && fills[i].TradeID <= fills[i-1].TradeID {
+			t.Errorf("Expected trade IDs in queue order, got %d after %d", fills[i].TradeID, fills[i-1].TradeID)
+		}
+		i++
+	}
+	if i != len(fills) {
+		t.Errorf("Expected %d fills, got %d: %v", i, len(fills), fills)
+	}
+}
+
+// logNewOrder logs an order as the processor would on its entry.
+func logNewOrder(t *testing.T, eventLog *events.EventLog, order *orders.Order) {
+	t.Helper()
+	if _, err := eventLog.Append(&events.NewOrderEvent{OrderID: order.ID, Symbol: order.Symbol, Side: order.Side,
+		OrderType: order.Type, Price: order.Price, Quantity: order.Quantity, AccountID: order.AccountID}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// logFill logs a fill as the processor would.
+func logFill(t *testing.T, eventLog *events.EventLog, fill orders.Fill) {
+	t.Helper()
+	if _, err := eventLog.Append(&events.FillEvent{TradeID: fill.TradeID, Symbol: fill.Symbol, Price: fill.Price,
+		Quantity: fill.Quantity, MakerOrderID: fill.MakerOrderID, TakerOrderID: fill.TakerOrderID,
+		MakerAccountID: fill.MakerAccountID, TakerAccountID: fill.TakerAccountID, TakerSide: fill.TakerSide}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func buyES(qty int64) *orders.Order {
+	return &orders.Order{Symbol: "ES", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: qty, AccountID: "B1"}
+}
+
+// TestProRata_SharesLevelBySize verifies an incoming order is shared in
+// proportion to resting size, not time priority.
+func TestProRata_SharesLevelBySize(t *testing.T) {
+	engine, makers := proRataEngine(t, orders.Allocation{}, 100, 300, 600)
+
+	result := engine.ProcessOrder(buyES(200))
+	checkShares(t, result, makers, 20, 60, 120)
+
+	level := engine.GetOrderBook("ES").GetBestAsk()
+	if level.TotalQty != 800 || level.Count() != 3 {
+		t.Errorf("Expected 800 left in 3 orders, got %d in %d", level.TotalQty, level.Count())
+	}
+	if makers[2].Status != orders.OrderStatusPartiallyFilled || result.Order.Status != orders.OrderStatusFilled {
+		t.Errorf("Expected maker partially and taker fully filled, got %s and %s", makers[2].Status, result.Order.Status)
+	}
+}
+
+// TestProRata_RemainderInTimePriority verifies what rounding leaves over
+// goes to the front of the queue.
+func TestProRata_RemainderInTimePriority(t *testing.T) {
+	engine, makers := proRataEngine(t, orders.Allocation{}, 100, 100, 100)
+
+	// 10 * 100/300 = 3 each, and the 1 left over to the first
+	checkShares(t, engine.ProcessOrder(buyES(10)), makers, 4, 3, 3)
+}
+
+// TestProRata_TopOrderAndMinimum verifies the top order fills first in
+// full, and shares under the minimum go out in time priority instead.
+func TestProRata_TopOrderAndMinimum(t *testing.T) {
+	engine, makers := proRataEngine(t, orders.Allocation{TopOrder: true}, 50, 100, 300)
+
+	// Top takes 50, the 150 left is shared 1:3 over the rest (37 and 112),
+	// and the 1 rounding leaves goes to the front of the queue with room
+	checkShares(t, engine.ProcessOrder(buyES(200)), makers, 50, 38, 112)
+	if makers[0].Status != orders.OrderStatusFilled || engine.GetOrderBook("ES").GetOrder(makers[0].ID) != nil {
+		t.Errorf("Expected the top order filled and gone, got %s", makers[0].Status)
+	}
+
+	engine, makers = proRataEngine(t, orders.Allocation{MinQty: 5}, 10, 990)
+	// 20 * 10/1000 = 0.2, under the minimum: the 10-lot gets only what
+	// the 990-lot's 19 leaves, in time priority
+	checkShares(t, engine.ProcessOrder(buyES(20)), makers, 1, 19)
+}
+
+// TestProRata_SweepsLevels verifies an order larger than a level fills
+// every order there and carries on to the next price.
+func TestProRata_SweepsLevels(t *testing.T) {
+	engine, makers := proRataEngine(t, orders.Allocation{}, 100, 200)
+	engine.ProcessOrder(&orders.Order{Symbol: "ES", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15001, Quantity: 500, AccountID: "MM"})
+
+	result := engine.ProcessOrder(&orders.Order{Symbol: "ES", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15001, Quantity: 400, AccountID: "B1"})
+	if len(result.Fills) != 3 || result.Fills[2].Price != 15001 || result.Fills[2].Quantity != 100 {
+		t.Fatalf("Expected both $150.00 orders filled then 100 at $150.01, got %v", result.Fills)
+	}
+	if makers[0].Status != orders.OrderStatusFilled || makers[1].Status != orders.OrderStatusFilled {
+		t.Errorf("Expected both makers filled, got %s and %s", makers[0].Status, makers[1].Status)
+	}
+}
+
+// TestProRata_IcebergSharesByDisplayedSize verifies only an iceberg's
+// displayed slice counts toward its share.
+func TestProRata_IcebergSharesByDisplayedSize(t *testing.T) {
+	engine, makers := proRataEngine(t, orders.Allocation{}, 100)
+	iceberg := &orders.Order{Symbol: "ES", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 1000, DisplayQty: 100, AccountID: "ICE"}
+	engine.ProcessOrder(iceberg)
+
+	checkShares(t, engine.ProcessOrder(buyES(100)), append(makers, iceberg), 50, 50)
+	if iceberg.VisibleQty() != 50 || iceberg.RemainingQty() != 950 {
+		t.Errorf("Expected the iceberg to show 50 of 950, got %d of %d", iceberg.VisibleQty(), iceberg.RemainingQty())
+	}
+}
+
+// TestProRata_ReplayReproducesShares verifies a replayed log reproduces
+// pro-rata fills, and would catch an engine allocating FIFO instead.
+func TestProRata_ReplayReproducesShares(t *testing.T) {
+	eventLog := openLog(t)
+	engine, _ := proRataEngine(t, orders.Allocation{TopOrder: true}, 100, 300, 600)
+	for _, maker := range engine.GetOrderBook("ES").GetBestAsk().Orders() {
+		logNewOrder(t, eventLog, maker)
+	}
+	taker := buyES(500)
+	result := engine.ProcessOrder(taker)
+	logNewOrder(t, eventLog, taker)
+	for _, fill := range result.Fills {
+		logFill(t, eventLog, fill)
+	}
+
+	for name, alloc := range map[string]orders.Allocation{
+		"pro-rata": {Method: orders.AllocationProRata, TopOrder: true},
+		"fifo":     {},
+	} {
+		fresh := matching.NewEngine()
+		fresh.SetAllocation("ES", alloc)
+		replayer := matching.NewReplayer(fresh)
+		err := eventLog.Replay(func(seq uint64, event interface{}) error {
+			_, err := replayer.Apply(event)
+			return err
+		})
+		if diverged := err != nil; diverged != (name == "fifo") {
+			t.Errorf("%s replay: diverged %v, got %v", name, name == "fifo", err)
+		}
+	}
+}
+
+// TestProRata_InstrumentsFile verifies the allocation options load from an
+// instruments file.
+func TestProRata_InstrumentsFile(t *testing.T) {
+	instruments, err := refdata.Parse([]byte(`
+ESZ6:
+  allocation: pro-rata
+  top_order: true
+  min_allocation: 2
+AAPL: {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := orders.Allocation{Method: orders.AllocationProRata, TopOrder: true, MinQty: 2}
+	if instruments[0].Allocation != (orders.Allocation{}) || instruments[1].Allocation != want {
+		t.Errorf("Expected AAPL FIFO and ESZ6 %+v, got %+v and %+v", want, instruments[0].Allocation, instruments[1].Allocation)
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// CALL AUCTIONS
+// ============================================================================
+
+// callBook starts an auction call on AAPL and enters a crossed book:
+//
+//	bids 100@150.10  200@150.05  300@150.00
+//	asks 150@149.95  100@150.00  250@150.10
+func callBook(t *testing.T) *matching.Engine {
+	t.Helper()
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	if err := engine.StartAuction("AAPL", 15000); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []*orders.Order{
+		limit(orders.SideBuy, 15010, 100), limit(orders.SideBuy, 15005, 200), limit(orders.SideBuy, 15000, 300),
+		limit(orders.SideSell, 14995, 150), limit(orders.SideSell, 15000, 100), limit(orders.SideSell, 15010, 250),
+	} {
+		if r := engine.ProcessOrder(o); !r.Accepted || len(r.Fills) != 0 {
+			t.Fatalf("Expected the order to rest during the call, got %+v", r)
+		}
+	}
+	return engine
+}
+
+// TestAuction_IndicativePrice verifies the equilibrium price maximises
+// volume, then minimises the imbalance.
+func TestAuction_IndicativePrice(t *testing.T) {
+	engine := callBook(t)
+
+	// 150.00 and 150.05 both trade 250; 150.05 leaves 50 over instead of 350
+	info, calling := engine.IndicativeAuction("AAPL")
+	want := matching.AuctionInfo{Symbol: "AAPL", RefPrice: 15000, Price: 15005, Volume: 250, Imbalance: 50, ImbalanceSide: orders.SideBuy}
+	if !calling || info != want {
+		t.Errorf("Expected %+v, got %+v", want, info)
+	}
+}
+
+// TestAuction_UncrossAtOnePrice verifies every crossing order trades at the
+// equilibrium price in priority order, leaving the book uncrossed and
+// trading continuously again.
+func TestAuction_UncrossAtOnePrice(t *testing.T) {
+	engine := callBook(t)
+
+	result, err := engine.Uncross("AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Fills) != 3 || result.Volume != 250 {
+		t.Fatalf("Expected 250 shares in 3 fills, got %+v", result)
+	}
+	for _, fill := range result.Fills {
+		if fill.Price != 15005 {
+			t.Errorf("Expected every fill at 150.05, got %v", fill)
+		}
+	}
+
+	book := engine.GetOrderBook("AAPL")
+	if bid, ask := book.GetBestBid(), book.GetBestAsk(); bid.Price != 15005 || bid.TotalQty != 50 || ask.Price != 15010 {
+		t.Errorf("Expected 50@150.05 / 150.10 left, got %d@%d / %d", bid.TotalQty, bid.Price, ask.Price)
+	}
+	if engine.InAuction("AAPL") {
+		t.Error("Expected the call over")
+	}
+	if r := engine.ProcessOrder(limit(orders.SideSell, 15005, 50)); len(r.Fills) != 1 {
+		t.Errorf("Expected continuous matching after the uncross, got %+v", r)
+	}
+}
+
+// TestAuction_TieBreaks verifies market pressure, then the reference price,
+// decide between prices with the same volume and imbalance.
+func TestAuction_TieBreaks(t *testing.T) {
+	price := func(refPrice int64, entered ...*orders.Order) int64 {
+		engine := matching.NewEngine()
+		engine.AddSymbol("AAPL")
+		engine.StartAuction("AAPL", refPrice)
+		for _, o := range entered {
+			engine.ProcessOrder(o)
+		}
+		info, _ := engine.IndicativeAuction("AAPL")
+		return info.Price
+	}
+
+	if p := price(0, limit(orders.SideBuy, 15010, 200), limit(orders.SideSell, 15000, 100)); p != 15010 {
+		t.Errorf("Expected buy pressure to take 150.10, got %d", p)
+	}
+	if p := price(0, limit(orders.SideBuy, 15010, 100), limit(orders.SideSell, 15000, 200)); p != 15000 {
+		t.Errorf("Expected sell pressure to take 150.00, got %d", p)
+	}
+	if p := price(15008, limit(orders.SideBuy, 15010, 100), limit(orders.SideSell, 15000, 100)); p != 15010 {
+		t.Errorf("Expected the price nearest the 150.08 reference, got %d", p)
+	}
+	if p := price(15005, limit(orders.SideBuy, 15010, 100), limit(orders.SideSell, 15000, 100)); p != 15000 {
+		t.Errorf("Expected the lower price on an equal distance, got %d", p)
+	}
+}
+
+// TestAuction_IcebergReserveCrosses verifies an iceberg's hidden reserve
+// trades in the uncross, and its next slice is shown afterwards.
+func TestAuction_IcebergReserveCrosses(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.StartAuction("AAPL", 0)
+	iceberg := limit(orders.SideSell, 15000, 500)
+	iceberg.DisplayQty = 100
+	engine.ProcessOrder(iceberg)
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 400))
+
+	if info, _ := engine.IndicativeAuction("AAPL"); info.Volume != 400 {
+		t.Fatalf("Expected the reserve counted, got volume %d", info.Volume)
+	}
+	engine.Uncross("AAPL")
+	ask := engine.GetOrderBook("AAPL").GetBestAsk()
+	if iceberg.FilledQty != 400 || ask.TotalQty != 100 || ask.HiddenQty != 0 {
+		t.Errorf("Expected 100 shown and nothing hidden, got filled %d, level %d+%d", iceberg.FilledQty, ask.TotalQty, ask.HiddenQty)
+	}
+}
+
+// TestAuction_OnlyLimitOrders verifies market, IOC and pegged orders are
+// rejected during the call.
+func TestAuction_OnlyLimitOrders(t *testing.T) {
+	engine := callBook(t)
+
+	market := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeMarket, Quantity: 10, AccountID: "T1"}
+	ioc := limit(orders.SideBuy, 15010, 10)
+	ioc.Type = orders.OrderTypeIOC
+	peg := limit(orders.SideBuy, 0, 10)
+	peg.Peg = orders.PegMidpoint
+	for _, o := range []*orders.Order{market, ioc, peg} {
+		if r := engine.ProcessOrder(o); r.Accepted {
+			t.Errorf("Expected %s %s rejected during the call", o.Type, o.Peg)
+		}
+	}
+	if _, err := engine.Uncross("MSFT"); err == nil {
+		t.Error("Expected uncrossing a symbol with no call to fail")
+	}
+}
+
+// TestAuction_ReplayRebuildsBook verifies a call and its uncross replay from
+// the event log to the same book and trades.
+func TestAuction_ReplayRebuildsBook(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	run := startRun(t, engine, eventLog, settlement.NewClearingHouse(), nil, 0)
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeStartAuction, Symbol: "AAPL", RefPrice: 15000})
+	run.order(limit(orders.SideBuy, 15010, 100))
+	run.order(limit(orders.SideSell, 14995, 150))
+	run.order(limit(orders.SideBuy, 15005, 200))
+	run.order(limit(orders.SideSell, 15000, 100))
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeUncross, Symbol: "AAPL"})
+	run.order(limit(orders.SideSell, 15005, 30))
+	run.processor.Shutdown()
+	if !response.Success || len(response.Auction.Fills) == 0 {
+		t.Fatalf("Expected the uncross to trade, got %+v", response)
+	}
+
+	replayed := matching.NewEngine()
+	replayed.AddSymbol("AAPL")
+	replayer := matching.NewReplayer(replayed)
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		_, err := replayer.Apply(event)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(withoutTimestamps(replayed.RestingOrders()), withoutTimestamps(engine.RestingOrders())) {
+		t.Error("Replay did not rebuild the book after the uncross")
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// BASKET ORDERS
+// ============================================================================
+
+// TestBasket_InvalidLegRejectsAll verifies that one invalid leg keeps every
+// leg out of the books.
+func TestBasket_InvalidLegRejectsAll(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+
+	legs := []*orders.Order{
+		{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "MM1"},
+		{Symbol: "NOPE", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 30000, Quantity: 50, AccountID: "MM1"},
+	}
+
+	result := engine.ProcessBasket(legs)
+	if result.Accepted {
+		t.Fatal("Expected basket with unknown symbol to be rejected")
+	}
+	if !strings.Contains(result.RejectReason, "leg 1") {
+		t.Errorf("Expected reject reason to name leg 1, got %q", result.RejectReason)
+	}
+	for i, leg := range result.Legs {
+		if leg.Accepted || leg.Order.Status != orders.OrderStatusRejected {
+			t.Errorf("Leg %d should be rejected, got accepted=%v status=%s", i, leg.Accepted, leg.Order.Status)
+		}
+	}
+	if engine.GetOrderBook("AAPL").TotalOrders() != 0 {
+		t.Errorf("Valid leg must not rest when the basket is rejected")
+	}
+}
+
+// TestBasket_LegsExecuteIndependently verifies that accepted legs match and
+// rest on their own books.
+func TestBasket_LegsExecuteIndependently(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 60, AccountID: "MM2"})
+
+	legs := []*orders.Order{
+		{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "MM1"},
+		{Symbol: "MSFT", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 30000, Quantity: 50, AccountID: "MM1"},
+	}
+
+	result := engine.ProcessBasket(legs)
+	if !result.Accepted {
+		t.Fatalf("Expected basket accepted, got %q", result.RejectReason)
+	}
+
+	aapl := result.Legs[0]
+	if len(aapl.Fills) != 1 || aapl.RestingQty != 40 || aapl.Order.Status != orders.OrderStatusPartiallyFilled {
+		t.Errorf("AAPL leg: expected 1 fill and 40 resting, got %d fills, %d resting (%s)",
+			len(aapl.Fills), aapl.RestingQty, aapl.Order.Status)
+	}
+	msft := result.Legs[1]
+	if len(msft.Fills) != 0 || msft.RestingQty != 50 {
+		t.Errorf("MSFT leg: expected 50 resting, got %d fills, %d resting", len(msft.Fills), msft.RestingQty)
+	}
+}
+
+// TestBasket_RiskIsAllOrNone verifies one leg over the limit fails the
+// basket, and that legs are checked against limits together.
+func TestBasket_RiskIsAllOrNone(t *testing.T) {
+	config := risk.DefaultConfig()
+	config.MaxOrderSize = 1000
+	config.MaxDailyVolume = 2000000 // $20,000
+	checker := risk.NewChecker(config)
+
+	tooBig := []*orders.Order{
+		{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 1000, Quantity: 10, AccountID: "MM1"},
+		{Symbol: "MSFT", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 1000, Quantity: 5000, AccountID: "MM1"},
+	}
+	if i, result := checker.CheckBasket(tooBig); result.Passed || i != 1 {
+		t.Errorf("Expected leg 1 to fail order size, got leg %d passed=%v", i, result.Passed)
+	}
+
+	// Each leg is $15,000 - fine alone, over the $20,000 limit together
+	together := []*orders.Order{
+		{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "MM1"},
+		{Symbol: "MSFT", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "MM1"},
+	}
+	if i, result := checker.CheckBasket(together); result.Passed || i != 1 {
+		t.Errorf("Expected combined daily volume to fail at leg 1, got leg %d passed=%v", i, result.Passed)
+	}
+
+	if i, result := checker.CheckBasket(together[:1]); !result.Passed || i != -1 {
+		t.Errorf("Expected single leg to pass, got leg %d: %s", i, result.Reason)
+	}
+}
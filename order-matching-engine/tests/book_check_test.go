@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// BOOK INTEGRITY CHECKS
+// ============================================================================
+
+// TestBookCheck_ConsistentAfterTrading verifies Check passes on books that
+// have seen fills, iceberg refills, replaces and cancels.
+func TestBookCheck_ConsistentAfterTrading(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	var failures []string
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.CheckBooks(func(symbol string, err error) { failures = append(failures, err.Error()) })
+	run.processor.Start()
+
+	run.order(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 500, DisplayQty: 100, AccountID: "ICE"})
+	run.order(limit(orders.SideSell, 15000, 50))
+	maker := run.order(limit(orders.SideSell, 15010, 80))
+	run.order(limit(orders.SideBuy, 14990, 70))
+	run.order(limit(orders.SideBuy, 15000, 250))
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: maker.ID, Side: orders.SideSell, AccountID: "T1", Price: 15005, Quantity: 60,
+	}})
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: maker.ID})
+	run.processor.Shutdown()
+
+	if len(failures) != 0 {
+		t.Errorf("Expected no failures, got %v", failures)
+	}
+	if err := engine.GetOrderBook("AAPL").Check(); err != nil {
+		t.Errorf("Expected a consistent book, got %v", err)
+	}
+}
+
+// TestBookCheck_ReportsCorruptionOnce verifies a book changed behind the
+// engine's back fails the check after the next request for its symbol, is
+// reported once however many requests follow, and requests for other
+// symbols don't check it.
+func TestBookCheck_ReportsCorruptionOnce(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+	resting := limit(orders.SideBuy, 15000, 100)
+	engine.ProcessOrder(resting)
+	engine.GetOrderBook("AAPL").GetOrder(resting.ID).Quantity = 40 // Level still counts 100
+
+	reports := map[string][]string{}
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.CheckBooks(func(symbol string, err error) { reports[symbol] = append(reports[symbol], err.Error()) })
+	run.processor.Start()
+
+	other := limit(orders.SideBuy, 30000, 10)
+	other.Symbol = "MSFT"
+	run.order(other)
+	if len(reports) != 0 {
+		t.Fatalf("Expected an MSFT order not to check AAPL, got %v", reports)
+	}
+	run.order(limit(orders.SideBuy, 14990, 10))
+	run.order(limit(orders.SideBuy, 14980, 10))
+	run.processor.Shutdown()
+
+	if len(reports) != 1 || len(reports["AAPL"]) != 1 {
+		t.Fatalf("Expected one AAPL report, got %v", reports)
+	}
+	if !strings.Contains(reports["AAPL"][0], "TotalQty 100") {
+		t.Errorf("Expected the level's quantity mismatch, got %q", reports["AAPL"][0])
+	}
+}
@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// SEQUENCED BOOK FEED AND BACKFILL
+// ============================================================================
+
+// bookImage builds depth from {price, quantity} pairs, one order per level.
+func bookImage(bids, asks [][2]int64) marketdata.L2Depth {
+	levels := func(pairs [][2]int64) []marketdata.PriceLevel {
+		out := make([]marketdata.PriceLevel, len(pairs))
+		for i, p := range pairs {
+			out[i] = marketdata.PriceLevel{Price: p[0], Quantity: p[1], Count: 1}
+		}
+		return out
+	}
+	return marketdata.L2Depth{Symbol: "AAPL", Bids: levels(bids), Asks: levels(asks)}
+}
+
+// subscriberBook is a book feed subscriber's copy of the book.
+type subscriberBook map[orders.Side]map[int64]int64
+
+func (b subscriberBook) reset(image *marketdata.L2Depth) {
+	b[orders.SideBuy] = map[int64]int64{}
+	b[orders.SideSell] = map[int64]int64{}
+	for _, level := range image.Bids {
+		b[orders.SideBuy][level.Price] = level.Quantity
+	}
+	for _, level := range image.Asks {
+		b[orders.SideSell][level.Price] = level.Quantity
+	}
+}
+
+func (b subscriberBook) apply(t *testing.T, next *uint64, update marketdata.BookUpdate) {
+	t.Helper()
+	if update.Seq != *next {
+		t.Fatalf("Expected seq %d, got %d", *next, update.Seq)
+	}
+	*next++
+	if update.Quantity == 0 {
+		delete(b[update.Side], update.Price)
+	} else {
+		b[update.Side][update.Price] = update.Quantity
+	}
+	if sum := b.checksum(); update.Checksum != sum {
+		t.Fatalf("Seq %d: expected checksum %d, the book after it has %d", update.Seq, update.Checksum, sum)
+	}
+}
+
+// checksum is the subscriber's checksum of its copy.
+func (b subscriberBook) checksum() uint32 {
+	levels := func(side orders.Side) []marketdata.PriceLevel {
+		var out []marketdata.PriceLevel
+		for price, qty := range b[side] {
+			out = append(out, marketdata.PriceLevel{Price: price, Quantity: qty})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if side == orders.SideBuy {
+				return out[i].Price > out[j].Price
+			}
+			return out[i].Price < out[j].Price
+		})
+		return out
+	}
+	return marketdata.Checksum(levels(orders.SideBuy), levels(orders.SideSell))
+}
+
+// checksumOf is the checksum of a book built by bookImage.
+func checksumOf(bids, asks [][2]int64) uint32 {
+	image := bookImage(bids, asks)
+	return marketdata.Checksum(image.Bids, image.Asks)
+}
+
+// TestBookFeed_UpdatesAreLevelDiffs verifies each changed, new or removed
+// level is one update, numbered without gaps.
+func TestBookFeed_UpdatesAreLevelDiffs(t *testing.T) {
+	publisher := marketdata.NewPublisher(10)
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}, {14990, 200}}, [][2]int64{{15010, 50}}))
+
+	updates := publisher.PublishBook(bookImage([][2]int64{{15000, 60}, {14990, 200}}, [][2]int64{{15020, 70}}))
+	want := []marketdata.BookUpdate{
+		{Symbol: "AAPL", Seq: 4, Side: orders.SideBuy, Price: 15000, Quantity: 60, Count: 1,
+			Checksum: checksumOf([][2]int64{{15000, 60}, {14990, 200}}, [][2]int64{{15010, 50}})},
+		{Symbol: "AAPL", Seq: 5, Side: orders.SideSell, Price: 15020, Quantity: 70, Count: 1,
+			Checksum: checksumOf([][2]int64{{15000, 60}, {14990, 200}}, [][2]int64{{15010, 50}, {15020, 70}})},
+		{Symbol: "AAPL", Seq: 6, Side: orders.SideSell, Price: 15010, Quantity: 0, Count: 0,
+			Checksum: checksumOf([][2]int64{{15000, 60}, {14990, 200}}, [][2]int64{{15020, 70}})},
+	}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("Expected %+v, got %+v", want, updates)
+	}
+	if again := publisher.PublishBook(bookImage([][2]int64{{15000, 60}, {14990, 200}}, [][2]int64{{15020, 70}})); len(again) != 0 {
+		t.Errorf("Expected an unchanged book to publish nothing, got %+v", again)
+	}
+}
+
+// TestBookFeed_BackfillFromHistory verifies a subscriber that missed updates
+// gets exactly the missing ones, and rebuilds the publisher's book.
+func TestBookFeed_BackfillFromHistory(t *testing.T) {
+	publisher := marketdata.NewPublisher(10)
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}}, [][2]int64{{15010, 50}}))
+
+	book := subscriberBook{}
+	first := publisher.BookBackfill("AAPL", 0)
+	if first.Image == nil || first.Seq != 2 {
+		t.Fatalf("Expected a new subscriber to get an image at seq 2, got %+v", first)
+	}
+	book.reset(first.Image)
+	if first.Image.Checksum != book.checksum() {
+		t.Errorf("Expected the image's checksum %d to match its levels' %d", first.Image.Checksum, book.checksum())
+	}
+	next := first.Seq + 1
+
+	// Missed while disconnected
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}, {14995, 40}}, [][2]int64{{15010, 20}}))
+	publisher.PublishBook(bookImage([][2]int64{{14995, 40}}, [][2]int64{{15010, 20}}))
+
+	backfill := publisher.BookBackfill("AAPL", next)
+	if backfill.Image != nil || len(backfill.Updates) != 3 || backfill.Seq != 5 {
+		t.Fatalf("Expected updates 3-5, got %+v", backfill)
+	}
+	for _, update := range backfill.Updates {
+		book.apply(t, &next, update)
+	}
+	want := subscriberBook{orders.SideBuy: {14995: 40}, orders.SideSell: {15010: 20}}
+	if !reflect.DeepEqual(book, want) {
+		t.Errorf("Expected %v, got %v", want, book)
+	}
+
+	if caughtUp := publisher.BookBackfill("AAPL", next); caughtUp.Image != nil || len(caughtUp.Updates) != 0 {
+		t.Errorf("Expected nothing for an up to date subscriber, got %+v", caughtUp)
+	}
+}
+
+// TestBookFeed_ImageWhenHistoryGone verifies a request older than the kept
+// history, or past the current seq, gets a full image instead.
+func TestBookFeed_ImageWhenHistoryGone(t *testing.T) {
+	publisher := marketdata.NewPublisher(10)
+	publisher.SetBookHistory(2)
+	for qty := int64(1); qty <= 5; qty++ {
+		publisher.PublishBook(bookImage([][2]int64{{15000, qty}}, nil))
+	}
+
+	for _, from := range []uint64{1, 3, 9} {
+		backfill := publisher.BookBackfill("AAPL", from)
+		if backfill.Image == nil || backfill.Image.Seq != 5 || len(backfill.Image.Bids) != 1 || backfill.Image.Bids[0].Quantity != 5 {
+			t.Errorf("from %d: expected an image at seq 5, got %+v", from, backfill)
+		}
+	}
+	if backfill := publisher.BookBackfill("AAPL", 4); backfill.Image != nil || len(backfill.Updates) != 2 {
+		t.Errorf("Expected updates 4-5 still kept, got %+v", backfill)
+	}
+}
+
+// TestBookFeed_SubscribeContinuesBackfill verifies live updates start right
+// after the backfill SubscribeBook returns.
+func TestBookFeed_SubscribeContinuesBackfill(t *testing.T) {
+	publisher := marketdata.NewPublisher(10)
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}}, [][2]int64{{15010, 50}}))
+
+	backfill, sub := publisher.SubscribeBook("AAPL", 0)
+	updates := sub.C
+	book := subscriberBook{}
+	book.reset(backfill.Image)
+	next := backfill.Seq + 1
+
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}}, [][2]int64{{15005, 10}, {15010, 50}}))
+	publisher.PublishBook(bookImage(nil, [][2]int64{{15005, 10}, {15010, 50}}))
+	for i := 0; i < 2; i++ {
+		book.apply(t, &next, <-updates)
+	}
+
+	want := subscriberBook{orders.SideBuy: {}, orders.SideSell: {15005: 10, 15010: 50}}
+	if !reflect.DeepEqual(book, want) {
+		t.Errorf("Expected %v, got %v", want, book)
+	}
+	sub.Close()
+	if _, open := <-updates; open {
+		t.Error("Expected the channel closed on Close")
+	}
+}
+
+// TestBookFeed_Checksum verifies the checksum covers the top levels, asks
+// first, as price and quantity digits.
+func TestBookFeed_Checksum(t *testing.T) {
+	got := checksumOf([][2]int64{{15000, 300}}, [][2]int64{{15005, 200}, {15010, 50}})
+	if want := crc32.ChecksumIEEE([]byte("15005200" + "1501050" + "15000300")); got != want {
+		t.Errorf("Expected %d, got %d", want, got)
+	}
+
+	// Levels past ChecksumDepth don't count
+	var bids [][2]int64
+	for i := int64(0); i < marketdata.ChecksumDepth; i++ {
+		bids = append(bids, [2]int64{15000 - i, 10})
+	}
+	deep := append(append([][2]int64{}, bids...), [2]int64{14000, 10})
+	if checksumOf(bids, nil) != checksumOf(deep, nil) {
+		t.Error("Expected a level below the checksum depth not to change the checksum")
+	}
+	if checksumOf(bids, nil) == checksumOf(bids[1:], nil) {
+		t.Error("Expected a level within the checksum depth to change the checksum")
+	}
+}
+
+// TestBookFeed_ChecksumsTrackDeepBooks verifies a subscriber's checksum
+// keeps matching as levels move into and out of the top ChecksumDepth.
+func TestBookFeed_ChecksumsTrackDeepBooks(t *testing.T) {
+	publisher := marketdata.NewPublisher(100)
+	backfill, sub := publisher.SubscribeBook("AAPL", 0)
+	updates := sub.C
+	book := subscriberBook{}
+	book.reset(backfill.Image)
+	next := backfill.Seq + 1
+
+	// Grow the book past the checksum depth, then take levels off the top
+	var bids, asks [][2]int64
+	for i := int64(0); i < 15; i++ {
+		bids = append(bids, [2]int64{15000 - i, 10 + i})
+		asks = append(asks, [2]int64{15001 + i, 20 + i})
+		publisher.PublishBook(bookImage(bids, asks))
+	}
+	for len(asks) > 2 {
+		bids, asks = bids[1:], asks[2:]
+		publisher.PublishBook(bookImage(bids, asks))
+	}
+	for last := publisher.BookBackfill("AAPL", 0).Seq; next <= last; {
+		book.apply(t, &next, <-updates)
+	}
+	sub.Close()
+}
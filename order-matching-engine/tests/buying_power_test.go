@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// BUYING POWER
+// ============================================================================
+
+// buyingPowerRun starts a processor checking buying power, with T1 holding
+// $100,000 and MM1 holding 1,000 AAPL.
+func buyingPowerRun(t *testing.T, eventLog *events.EventLog, engine *matching.Engine) (*tailRun, *settlement.ClearingHouse) {
+	clearing := settlement.NewClearingHouse()
+	clearing.GetOrCreateAccount("T1", 10000000)
+	clearing.DepositShares("MM1", "AAPL", 1000)
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.EnableClearing(clearing)
+	run.processor.EnableBuyingPower()
+	run.processor.Start()
+	return run, clearing
+}
+
+// TestBuyingPower_HoldFillCancel verifies a resting buy holds its limit
+// value, a fill turns its filled part into an unsettled obligation at the
+// traded price, and a cancel releases the rest.
+func TestBuyingPower_HoldFillCancel(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run, clearing := buyingPowerRun(t, openLog(t), engine)
+	defer run.processor.Shutdown()
+
+	ask := limit(orders.SideSell, 14950, 100)
+	ask.AccountID = "MM1"
+	run.order(ask)
+	if shares := clearing.AvailableShares("MM1", "AAPL"); shares != 900 {
+		t.Errorf("Expected 100 of MM1's shares held, got %d available", shares)
+	}
+
+	bid := run.order(limit(orders.SideBuy, 15000, 200)) // Fills 100 @ $149.50, rests 100 @ $150
+	if held := clearing.Held(bid.ID); held.Cash != 1500000 {
+		t.Errorf("Expected $15,000 held for the rest of the bid, got %+v", held)
+	}
+	if power := clearing.BuyingPower("T1"); power != 7005000 {
+		t.Errorf("Expected $70,050 buying power, got %s", orders.FormatPrice(power))
+	}
+	if held := clearing.Held(ask.ID); held.Shares != 0 {
+		t.Errorf("Expected the filled ask's hold released, got %+v", held)
+	}
+	if shares := clearing.AvailableShares("MM1", "AAPL"); shares != 900 {
+		t.Errorf("Expected MM1's sold shares owed, got %d available", shares)
+	}
+
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: bid.ID})
+	if power := clearing.BuyingPower("T1"); power != 8505000 {
+		t.Errorf("Expected the cancel to free $15,000, got %s", orders.FormatPrice(power))
+	}
+}
+
+// TestBuyingPower_RejectsUncovered verifies orders, baskets and replaces an
+// account cannot cover are rejected without being logged or held.
+func TestBuyingPower_RejectsUncovered(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+	run, clearing := buyingPowerRun(t, eventLog, engine)
+
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: limit(orders.SideBuy, 15000, 1000)})
+	if response.Success || !strings.Contains(response.Result.RejectReason, "insufficient buying power") {
+		t.Errorf("Expected a $150,000 buy rejected, got %+v", response.Result)
+	}
+	response = run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: limit(orders.SideSell, 15000, 10)})
+	if response.Success {
+		t.Error("Expected a sell without shares rejected")
+	}
+
+	aapl, msft := limit(orders.SideBuy, 15000, 400), limit(orders.SideBuy, 30000, 200)
+	msft.Symbol = "MSFT"
+	response = run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeBasket, Legs: []*orders.Order{aapl, msft}})
+	if response.Success || aapl.Status != orders.OrderStatusRejected {
+		t.Errorf("Expected a $120,000 basket of two affordable legs rejected, got %+v", response.Basket)
+	}
+
+	bid := run.order(limit(orders.SideBuy, 15000, 600))
+	response = run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: bid.ID, Side: orders.SideBuy, AccountID: "T1", Price: 15000, Quantity: 700}})
+	if !errors.Is(response.Error, settlement.ErrInsufficientBuyingPower) {
+		t.Errorf("Expected a replace to $105,000 rejected, got %v", response.Error)
+	}
+	response = run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: bid.ID, Side: orders.SideBuy, AccountID: "T1", Price: 15000, Quantity: 300}})
+	if !response.Success || clearing.BuyingPower("T1") != 5500000 {
+		t.Errorf("Expected a smaller replace accepted, freeing $45,000, got %v / %s",
+			response.Error, orders.FormatPrice(clearing.BuyingPower("T1")))
+	}
+	run.processor.Shutdown()
+
+	var logged int
+	for _, event := range replayAll(t, eventLog) {
+		if _, ok := event.(*events.NewOrderEvent); ok {
+			logged++
+		}
+	}
+	if logged != 1 {
+		t.Errorf("Expected only the covered order logged, got %d", logged)
+	}
+}
+
+// TestBuyingPower_HoldsRestingOrders verifies enabling the check holds what
+// orders already resting need, as after recovery.
+func TestBuyingPower_HoldsRestingOrders(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+
+	run, clearing := buyingPowerRun(t, openLog(t), engine)
+	defer run.processor.Shutdown()
+	if power := clearing.BuyingPower("T1"); power != 8500000 {
+		t.Errorf("Expected $15,000 held for the recovered bid, got %s", orders.FormatPrice(power))
+	}
+}
@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/calendar"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// MARKET CALENDARS
+// ============================================================================
+
+const calendarFile = `
+XNYS:
+  holidays:
+    2026-11-26: Thanksgiving Day
+    2026-12-25: Christmas Day
+XDUB:
+  weekend: [Friday, Saturday]
+`
+
+// day returns noon UTC on a date.
+func day(date string) time.Time {
+	t, _ := time.Parse("2006-01-02", date)
+	return t.Add(12 * time.Hour)
+}
+
+// TestCalendar_SettlementSkipsHolidays verifies T+2 counts only the business
+// days of the symbol's market.
+func TestCalendar_SettlementSkipsHolidays(t *testing.T) {
+	calendars, err := calendar.Parse([]byte(calendarFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markets := map[string]string{"AAPL": "XNYS", "EMAAR": "XDUB"}
+	clearing := settlement.NewClearingHouse()
+	clearing.SetCalendars(calendars, func(symbol string) string { return markets[symbol] })
+
+	for _, tc := range []struct {
+		symbol, traded, settles string
+	}{
+		{"AAPL", "2026-11-25", "2026-11-30"},  // Wednesday before Thanksgiving
+		{"AAPL", "2026-11-26", "2026-12-01"},  // On the holiday: counts from Friday
+		{"AAPL", "2026-11-28", "2026-12-02"},  // Saturday: counts from Monday
+		{"AAPL", "2026-12-23", "2026-12-28"},  // Over Christmas
+		{"EMAAR", "2026-11-26", "2026-11-30"}, // Friday and Saturday off
+		{"MSFT", "2026-11-25", "2026-11-27"},  // No market: weekdays only
+	} {
+		settles := clearing.SettleDate(tc.symbol, day(tc.traded)).Format("2006-01-02")
+		if settles != tc.settles {
+			t.Errorf("%s traded %s: expected to settle %s, got %s", tc.symbol, tc.traded, tc.settles, settles)
+		}
+	}
+}
+
+// TestCalendar_BusinessDays verifies holiday lookups and business day
+// arithmetic on a parsed calendar.
+func TestCalendar_BusinessDays(t *testing.T) {
+	calendars, err := calendar.Parse([]byte(calendarFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nyse := calendars.Get("XNYS")
+
+	if name, ok := nyse.Holiday(day("2026-12-25")); !ok || name != "Christmas Day" {
+		t.Errorf("Expected Christmas Day, got %q", name)
+	}
+	if nyse.IsBusinessDay(day("2026-11-26")) || !nyse.IsBusinessDay(day("2026-11-27")) {
+		t.Error("Expected Thanksgiving closed and the day after open")
+	}
+	if next := nyse.Date(nyse.NextBusinessDay(day("2026-12-25"))); next != "2026-12-28" {
+		t.Errorf("Expected Monday 2026-12-28 after Christmas, got %s", next)
+	}
+	if holidays := nyse.Holidays(day("2026-12-01")); len(holidays) != 1 || holidays[0][0] != "2026-12-25" {
+		t.Errorf("Expected only Christmas left in December, got %v", holidays)
+	}
+}
+
+// TestCalendar_InvalidFile verifies bad dates and weekdays are rejected.
+func TestCalendar_InvalidFile(t *testing.T) {
+	for _, file := range []string{
+		"XNYS:\n  holidays:\n    2026-13-01: Nonsense\n",
+		"XNYS:\n  weekend: [Caturday]\n",
+		"XNYS:\n  open: \"16:00\"\n  close: \"09:30\"\n",
+		"XNYS:\n  close: \"4pm\"\n",
+	} {
+		if _, err := calendar.Parse([]byte(file)); err == nil {
+			t.Errorf("Expected %q rejected", file)
+		}
+	}
+}
+
+const sessionFile = `
+XNYS:
+  timezone: America/New_York
+  open: "09:30"
+  close: "16:00"
+  holidays:
+    2026-11-26: Thanksgiving Day
+`
+
+// at returns a time on a date in New York.
+func at(t *testing.T, date, clock string) time.Time {
+	t.Helper()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// TestCalendar_SessionHours verifies session hours are taken in the
+// market's time zone and closed days have no session.
+func TestCalendar_SessionHours(t *testing.T) {
+	calendars, err := calendar.Parse([]byte(sessionFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nyse := calendars.Get("XNYS")
+
+	for _, tc := range []struct {
+		date, clock string
+		open        bool
+	}{
+		{"2026-11-25", "09:29", false},
+		{"2026-11-25", "09:30", true},
+		{"2026-11-25", "15:59", true},
+		{"2026-11-25", "16:00", false},
+		{"2026-11-26", "12:00", false}, // Thanksgiving
+		{"2026-11-28", "12:00", false}, // Saturday
+	} {
+		if open := nyse.IsOpen(at(t, tc.date, tc.clock)); open != tc.open {
+			t.Errorf("%s %s: expected open=%v", tc.date, tc.clock, tc.open)
+		}
+	}
+	closed, ok := nyse.LastClose(at(t, "2026-11-30", "10:00"))
+	if !ok || !closed.Equal(at(t, "2026-11-27", "16:00")) {
+		t.Errorf("Expected the last close Friday 16:00 over Thanksgiving weekend, got %v", closed)
+	}
+	if open := calendars.Get("XDUB").IsOpen(day("2026-11-30")); !open {
+		t.Error("Expected a market without hours to be in session all day")
+	}
+}
+
+// TestCalendar_SessionsFireOpenAndClose verifies the session watcher
+// catches up on the last close, then fires each open and close once as
+// the clock moves, skipping holidays.
+func TestCalendar_SessionsFireOpenAndClose(t *testing.T) {
+	calendars, err := calendar.Parse([]byte(sessionFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := at(t, "2026-11-24", "08:00")
+	sessions := calendar.NewSessions(calendars, []string{"XNYS"})
+	sessions.SetClock(func() time.Time { return now })
+	var fired []string
+	sessions.OnOpen(func(e calendar.SessionEvent) { fired = append(fired, "open "+e.Date) })
+	sessions.OnClose(func(e calendar.SessionEvent) { fired = append(fired, "close "+e.Date) })
+
+	expect := func(step string, want ...string) {
+		t.Helper()
+		sessions.Advance()
+		if len(fired) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", step, want, fired)
+		}
+		for i := range want {
+			if fired[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", step, want, fired)
+			}
+		}
+		fired = nil
+	}
+
+	expect("startup before the open", "close 2026-11-23")
+	expect("again")
+	now = at(t, "2026-11-24", "09:30")
+	expect("open", "open 2026-11-24")
+	now = at(t, "2026-11-24", "12:00")
+	expect("mid-session")
+	now = at(t, "2026-11-24", "16:05")
+	expect("close", "close 2026-11-24")
+	now = at(t, "2026-11-25", "10:00")
+	expect("next day", "open 2026-11-25")
+	now = at(t, "2026-11-26", "10:00")
+	expect("Thanksgiving", "close 2026-11-25")
+	now = at(t, "2026-11-30", "11:00")
+	expect("after the weekend", "close 2026-11-27", "open 2026-11-30")
+}
+
+// TestCalendar_DayOrdersExpireAtClose verifies the close expires resting
+// DAY orders entered before it, through the ring buffer, logged as
+// cancels and reported as EXPIRED, and leaves GTC orders and DAY orders
+// entered after the close.
+func TestCalendar_DayOrdersExpireAtClose(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+	eventLog := openLog(t)
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	var reports []orders.ExecutionReport
+	run.processor.OnExecution(func(r orders.ExecutionReport) { reports = append(reports, r) })
+	run.processor.Start()
+
+	closed := time.Now().Add(-time.Hour)
+	enter := func(o *orders.Order, entered time.Time) *orders.Order {
+		run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: o, Time: entered.UnixNano()})
+		return o
+	}
+	dayOrder := func(price int64, entered time.Time) *orders.Order {
+		o := limit(orders.SideBuy, price, 100)
+		o.TimeInForce = orders.TIFDay
+		return enter(o, entered)
+	}
+	expiring := dayOrder(15000, closed.Add(-time.Hour))
+	late := dayOrder(14900, closed.Add(time.Minute)) // After the close
+	run.order(limit(orders.SideBuy, 14800, 100))     // GTC
+	other := limit(orders.SideBuy, 30000, 100)
+	other.Symbol, other.TimeInForce = "MSFT", orders.TIFDay
+	enter(other, closed.Add(-time.Hour)) // Its market is still open
+
+	response := run.send(&disruptor.OrderRequest{
+		Type:    disruptor.RequestTypeExpireOrders,
+		Symbols: []string{"AAPL"},
+		Now:     closed.UnixNano(),
+	})
+	run.processor.Shutdown()
+
+	if !response.Success || len(response.Cancelled) != 1 || response.Cancelled[0].ID != expiring.ID {
+		t.Fatalf("Expected only the DAY order entered before the close expired, got %+v", response)
+	}
+	if open := engine.OpenOrders("T1", ""); len(open) != 3 {
+		t.Errorf("Expected the late DAY, GTC and MSFT orders to rest, got %d", len(open))
+	}
+	last := reports[len(reports)-1]
+	if last.ExecType != orders.ExecTypeExpired || last.OrderID != expiring.ID {
+		t.Errorf("Expected the expiry reported as EXPIRED, got %+v", last)
+	}
+
+	// The log rebuilds the same book
+	replayed := matching.NewEngine()
+	replayed.AddSymbol("AAPL")
+	replayed.AddSymbol("MSFT")
+	replayer := matching.NewReplayer(replayed)
+	for _, event := range replayAll(t, eventLog) {
+		if _, err := replayer.Apply(event); err != nil {
+			t.Fatal(err)
+		}
+		if o, ok := event.(*events.NewOrderEvent); ok && o.OrderID == late.ID && o.TimeInForce != orders.TIFDay {
+			t.Error("Expected the time in force logged")
+		}
+	}
+	var want, got []uint64
+	for _, o := range engine.OpenOrders("T1", "") {
+		want = append(want, o.ID)
+	}
+	for _, o := range replayed.OpenOrders("T1", "") {
+		got = append(got, o.ID)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected replay to rebuild %v, got %v", want, got)
+	}
+}
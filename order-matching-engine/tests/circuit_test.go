@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/circuit"
+)
+
+// ============================================================================
+// CIRCUIT BREAKERS
+// ============================================================================
+
+// breakerConfig pauses on a 5% move within a minute and halts on 10%.
+var breakerConfig = circuit.Config{Window: time.Minute, PauseBps: 500, HaltBps: 1000, PauseFor: time.Minute}
+
+// TestCircuit_PauseAndHalt verifies moves up from the window's low and down
+// from its high trip a pause, then a halt, above their thresholds.
+func TestCircuit_PauseAndHalt(t *testing.T) {
+	breaker := circuit.New(breakerConfig)
+	sec := int64(time.Second)
+
+	breaker.Observe("AAPL", 10000, 0)
+	if trip, move := breaker.Observe("AAPL", 10500, sec); trip != circuit.TripNone || move != 500 {
+		t.Errorf("Expected a 500 bps move not to trip, got %s at %d bps", trip, move)
+	}
+	if trip, move := breaker.Observe("AAPL", 10600, 2*sec); trip != circuit.TripPause || move != 600 {
+		t.Errorf("Expected a 600 bps move up to pause, got %s at %d bps", trip, move)
+	}
+	if trip, _ := breaker.Observe("AAPL", 9500, 3*sec); trip != circuit.TripHalt {
+		t.Errorf("Expected a fall of over 10%% from the high to halt, got %s", trip)
+	}
+	if trip, _ := breaker.Observe("MSFT", 20000, 3*sec); trip != circuit.TripNone {
+		t.Errorf("Expected symbols measured separately, got %s", trip)
+	}
+}
+
+// TestCircuit_WindowExpires verifies trades older than the window no longer
+// define the range a move is measured from.
+func TestCircuit_WindowExpires(t *testing.T) {
+	breaker := circuit.New(breakerConfig)
+	sec := int64(time.Second)
+
+	breaker.Observe("AAPL", 10000, 0)
+	breaker.Observe("AAPL", 10400, 30*sec)
+	if trip, move := breaker.Observe("AAPL", 10800, 61*sec); trip != circuit.TripNone || move != 384 {
+		t.Errorf("Expected the 100.00 trade expired and a 384 bps move from 104.00, got %s at %d bps", trip, move)
+	}
+}
+
+// TestCircuit_Reset verifies a reset symbol resumes with no range, so the
+// move that tripped it is not measured again.
+func TestCircuit_Reset(t *testing.T) {
+	breaker := circuit.New(breakerConfig)
+
+	breaker.Observe("AAPL", 10000, 0)
+	if trip, _ := breaker.Observe("AAPL", 11500, 1); trip != circuit.TripHalt {
+		t.Fatalf("Expected a halt, got %s", trip)
+	}
+	breaker.Reset("AAPL")
+	if trip, _ := breaker.Observe("AAPL", 11500, 2); trip != circuit.TripNone {
+		t.Errorf("Expected no trip after a reset, got %s", trip)
+	}
+}
+
+// TestCircuit_ZeroThresholdsDisable verifies a zero threshold never trips.
+func TestCircuit_ZeroThresholdsDisable(t *testing.T) {
+	breaker := circuit.New(circuit.Config{Window: time.Minute, HaltBps: 1000})
+
+	breaker.Observe("AAPL", 10000, 0)
+	if trip, _ := breaker.Observe("AAPL", 10800, 1); trip != circuit.TripNone {
+		t.Errorf("Expected no pause with PauseBps 0, got %s", trip)
+	}
+	if trip, _ := breaker.Observe("AAPL", 11200, 2); trip != circuit.TripHalt {
+		t.Errorf("Expected the halt threshold still active, got %s", trip)
+	}
+}
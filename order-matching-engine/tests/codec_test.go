@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// EVENT LOG CODECS
+// ============================================================================
+
+// sampleEvents returns one event of every type, with every field set.
+func sampleEvents() []interface{} {
+	return []interface{}{
+		&events.NewOrderEvent{Event: events.Event{Timestamp: 1700000000000000001, Type: events.EventTypeNewOrder},
+			OrderID: 1, Symbol: "AAPL", Side: orders.SideSell, OrderType: orders.OrderTypeLimit, Price: 15025,
+			Quantity: 500, AccountID: "MM1", ClientOrderID: "c-1", SessionID: "WS-1", DisplayQty: 100,
+			Peg: orders.PegPrimary, PegLimit: 15100, TimeInForce: orders.TIFDay},
+		&events.CancelOrderEvent{OrderID: 1, Symbol: "AAPL", AccountID: "MM1"},
+		&events.OrderAcceptedEvent{OrderID: 2, Symbol: "AAPL", RestingQty: 40},
+		&events.OrderRejectedEvent{OrderID: 3, Symbol: "MSFT", RejectReason: "price outside collar"},
+		&events.FillEvent{TradeID: 9, Symbol: "AAPL", Price: 15025, Quantity: 60, MakerOrderID: 1, TakerOrderID: 2,
+			MakerAccountID: "MM1", TakerAccountID: "TRADER1", TakerSide: orders.SideBuy, MakerFee: -12, TakerFee: 27},
+		&events.OrderCancelledEvent{OrderID: 2, Symbol: "AAPL", CancelledQty: 40, Reason: "cancel on disconnect"},
+		&events.OrderReplacedEvent{OrderID: 4, Symbol: "AAPL", OldPrice: 15000, OldQuantity: 100, NewPrice: 15010,
+			NewQuantity: 80, PriorityKept: true},
+		&events.SymbolImportedEvent{Symbol: "TSLA", Source: "shard-a:8080", Orders: []orders.Order{
+			{ID: 7, SequenceNum: 70, Price: 25000, Quantity: 300, FilledQty: 100, FilledNotional: 2500000,
+				DisplayQty: 50, ShownQty: 50, Timestamp: 1700000000000000002, Symbol: "TSLA", AccountID: "MM2",
+				ClientOrderID: "t-7", SessionID: "WS-2", Side: orders.SideSell, Type: orders.OrderTypeLimit,
+				Status: orders.OrderStatusPartiallyFilled, TimeInForce: orders.TIFDay},
+			{ID: 8, Price: 24900, Quantity: 10, Symbol: "TSLA", AccountID: "MM3", Peg: orders.PegMidpoint, PegLimit: 24950},
+		}},
+		&events.SymbolMovedEvent{Symbol: "NVDA", Target: "shard-b:8080", Orders: 12},
+		&events.AuctionStartedEvent{Symbol: "AAPL", RefPrice: 15000},
+		&events.AuctionUncrossedEvent{Symbol: "AAPL", Price: 15005, Volume: 1200},
+		&events.RiskLimitsEvent{AccountID: "TRADER1", MaxOrderSize: 2000, MaxOrderValue: 20000000,
+			MaxPositionSize: 10000, MaxDailyVolume: 25000000, Actor: "alice@10.0.0.5:51234"},
+		&events.RestrictionEvent{AccountID: "TRADER1", Symbol: "TSLA", Restricted: true,
+			Reason: "insider list", Actor: "alice@10.0.0.5:51234"},
+		&events.JournalCheckpointEvent{Through: 42},
+		&events.AccountEvent{AccountID: "CLIENT9", Action: events.AccountWithdrawal, Cash: 250000, Symbol: "AAPL",
+			Shares: 100, Actor: "alice@10.0.0.5:51234"},
+	}
+}
+
+// TestCodec_RoundTripsEveryEvent verifies each codec replays every event
+// type exactly as it was appended.
+func TestCodec_RoundTripsEveryEvent(t *testing.T) {
+	for _, name := range []string{"protobuf", "gob"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := events.CodecByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "events.log")
+			eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path, Codec: codec})
+			if err != nil {
+				t.Fatal(err)
+			}
+			appended := sampleEvents()
+			for _, event := range appended {
+				if _, err := eventLog.Append(event); err != nil {
+					t.Fatalf("Append %T: %v", event, err)
+				}
+			}
+			eventLog.Close()
+
+			eventLog = openLogAt(t, path)
+			defer eventLog.Close()
+			replayed := replayAll(t, eventLog)
+			if len(replayed) != len(appended) {
+				t.Fatalf("Expected %d events, got %d", len(appended), len(replayed))
+			}
+			for i := range appended {
+				if !reflect.DeepEqual(replayed[i], appended[i]) {
+					t.Errorf("Event %d replayed as\n%+v\nwant\n%+v", i+1, replayed[i], appended[i])
+				}
+			}
+		})
+	}
+}
+
+// TestCodec_MixedCodecsInOneLog verifies a log written by one codec can be
+// appended to with another, and replays both.
+func TestCodec_MixedCodecsInOneLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path, Codec: events.GobCodec{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendCross(t, eventLog, "AAPL", 1, 2, 1, "")
+	eventLog.Close()
+
+	eventLog = openLogAt(t, path)
+	appendCross(t, eventLog, "MSFT", 3, 4, 2, "")
+	eventLog.Close()
+
+	eventLog = openLogAt(t, path)
+	defer eventLog.Close()
+	replayed := replayAll(t, eventLog)
+	if len(replayed) != 6 {
+		t.Fatalf("Expected 6 events, got %d", len(replayed))
+	}
+	if fill, ok := replayed[5].(*events.FillEvent); !ok || fill.Symbol != "MSFT" || fill.TradeID != 2 {
+		t.Errorf("Expected the MSFT fill last, got %+v", replayed[5])
+	}
+}
+
+// TestCodec_RewriteMigratesGobLog verifies a log of pre-framing gob records
+// rewrites into protobuf records with the same events and sequence numbers.
+func TestCodec_RewriteMigratesGobLog(t *testing.T) {
+	src := openLogAt(t, copyFixture(t, "events_v1.log"))
+	defer src.Close()
+
+	path := filepath.Join(t.TempDir(), "events.pb.log")
+	dst := openLogAt(t, path)
+	n, err := events.Rewrite(src, dst)
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if n != 4 || dst.GetLastSequence() != 4 {
+		t.Errorf("Expected 4 events rewritten up to sequence 4, got %d up to %d", n, dst.GetLastSequence())
+	}
+	dst.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 || data[0] != 0xEB {
+		t.Fatalf("Expected framed records, got % x", data[:min(len(data), 8)])
+	}
+
+	rewritten := openLogAt(t, path)
+	defer rewritten.Close()
+	want := replayAll(t, src)
+	got := replayAll(t, rewritten)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rewritten log replays as\n%+v\nwant\n%+v", got, want)
+	}
+
+	// A second rewrite into the same log would renumber events
+	if _, err := events.Rewrite(src, rewritten); err == nil {
+		t.Error("Expected a rewrite into a non-empty log to fail")
+	}
+}
+
+// TestCodec_ByName verifies codecs are chosen by name and unknown names are
+// refused.
+func TestCodec_ByName(t *testing.T) {
+	if codec, err := events.CodecByName("protobuf"); err != nil || codec.ID() != (events.ProtobufCodec{}).ID() {
+		t.Errorf("Expected the protobuf codec, got %v, %v", codec, err)
+	}
+	if _, err := events.CodecByName("flatbuffers"); err == nil {
+		t.Error("Expected an unknown codec to be refused")
+	}
+}
@@ -0,0 +1,421 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/consensus"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// RAFT CLUSTER
+// ============================================================================
+
+// raftNode is one cluster member and the commands it has applied.
+type raftNode struct {
+	node *consensus.Node
+
+	mu      sync.Mutex
+	applied []string
+}
+
+func (r *raftNode) commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.applied...)
+}
+
+// raftCluster runs a cluster of n nodes on loopback.
+type raftCluster struct {
+	t     *testing.T
+	dir   string
+	peers []string
+	nodes []*raftNode
+	apply func(node int, command []byte) // Also called with each applied command, if set
+}
+
+func newRaftCluster(t *testing.T, n int) *raftCluster {
+	return newRaftClusterApplying(t, n, nil)
+}
+
+// newRaftClusterApplying runs a cluster whose nodes also pass each command
+// they apply to apply.
+func newRaftClusterApplying(t *testing.T, n int, apply func(node int, command []byte)) *raftCluster {
+	t.Helper()
+	c := &raftCluster{t: t, dir: t.TempDir(), nodes: make([]*raftNode, n), apply: apply}
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.peers = append(c.peers, ln.Addr().String())
+		ln.Close()
+	}
+	for i := range c.nodes {
+		c.start(i)
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			if node != nil {
+				node.node.Close()
+			}
+		}
+	})
+	return c
+}
+
+// start opens node i from its directory and joins it to the cluster.
+func (c *raftCluster) start(i int) {
+	c.t.Helper()
+	config := consensus.DefaultConfig()
+	config.ID = i
+	config.Peers = c.peers
+	config.Dir = filepath.Join(c.dir, fmt.Sprintf("node%d", i))
+	config.HeartbeatInterval = 20 * time.Millisecond
+	config.ElectionTimeoutMin = 100 * time.Millisecond
+	config.ElectionTimeoutMax = 200 * time.Millisecond
+	config.RPCTimeout = 50 * time.Millisecond
+
+	member := &raftNode{}
+	node, err := consensus.Open(config, func(applied consensus.Applied) {
+		member.mu.Lock()
+		member.applied = append(member.applied, string(applied.Command))
+		member.mu.Unlock()
+		if c.apply != nil {
+			c.apply(i, applied.Command)
+		}
+	})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", c.peers[i])
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	go node.Serve(ln)
+	node.Start()
+	member.node = node
+	c.nodes[i] = member
+}
+
+// stop closes node i.
+func (c *raftCluster) stop(i int) {
+	c.nodes[i].node.Close()
+	c.nodes[i] = nil
+}
+
+// leader waits for a running node to lead and returns its index.
+func (c *raftCluster) leader() int {
+	c.t.Helper()
+	var leader int
+	waitForWithin(c.t, "a leader", 3*time.Second, func() bool {
+		for i, member := range c.nodes {
+			if member == nil {
+				continue
+			}
+			if _, isLeader := member.node.Leader(); isLeader {
+				leader = i
+				return true
+			}
+		}
+		return false
+	})
+	return leader
+}
+
+// propose submits commands to the leader.
+func (c *raftCluster) propose(commands ...string) {
+	c.t.Helper()
+	for _, command := range commands {
+		if _, err := c.nodes[c.leader()].node.Propose([]byte(command)); err != nil {
+			c.t.Fatalf("Propose %q failed: %v", command, err)
+		}
+	}
+}
+
+// waitForWithin polls cond until it holds or timeout passes.
+func waitForWithin(t *testing.T, what string, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func sameCommands(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestConsensus_EveryNodeAppliesInOrder verifies commands proposed to the
+// leader are applied by every node in the same order, and that a follower
+// refuses proposals naming the leader.
+func TestConsensus_EveryNodeAppliesInOrder(t *testing.T) {
+	c := newRaftCluster(t, 3)
+	c.propose("buy 1", "sell 2", "cancel 1")
+	expected := []string{"buy 1", "sell 2", "cancel 1"}
+
+	for i, member := range c.nodes {
+		waitForWithin(t, fmt.Sprintf("node %d to apply", i), 2*time.Second, func() bool {
+			return sameCommands(member.commands(), expected)
+		})
+	}
+
+	leader := c.leader()
+	follower := (leader + 1) % 3
+	_, err := c.nodes[follower].node.Propose([]byte("buy 3"))
+	var notLeader *consensus.NotLeaderError
+	if !errors.As(err, &notLeader) || notLeader.LeaderID != leader {
+		t.Fatalf("Expected a follower to name leader %d, got %v", leader, err)
+	}
+}
+
+// TestConsensus_LeaderFailover verifies a new leader is elected when the
+// leader stops, that it keeps every committed command, and that the old
+// leader catches up from its own log when it restarts.
+func TestConsensus_LeaderFailover(t *testing.T) {
+	c := newRaftCluster(t, 3)
+	c.propose("a", "b")
+	for _, member := range c.nodes {
+		waitForWithin(t, "first commands", 2*time.Second, func() bool { return len(member.commands()) == 2 })
+	}
+
+	old := c.leader()
+	c.stop(old)
+	newLeader := c.leader()
+	if newLeader == old {
+		t.Fatal("Stopped node still leads")
+	}
+	c.propose("c")
+	for _, member := range c.nodes {
+		if member != nil {
+			waitForWithin(t, "commit without the old leader", 2*time.Second, func() bool {
+				return sameCommands(member.commands(), []string{"a", "b", "c"})
+			})
+		}
+	}
+
+	// Restarted, the old leader re-applies its log from the start, then
+	// what it missed
+	c.start(old)
+	waitForWithin(t, "old leader to catch up", 3*time.Second, func() bool {
+		return sameCommands(c.nodes[old].commands(), []string{"a", "b", "c"})
+	})
+	if status := c.nodes[old].node.Status(); status.Leader != newLeader && status.State != "leader" {
+		t.Errorf("Expected the restarted node to follow node %d, got %+v", newLeader, status)
+	}
+}
+
+// TestConsensus_NoCommitWithoutMajority verifies a leader cut off from the
+// majority commits nothing, and steps down.
+func TestConsensus_NoCommitWithoutMajority(t *testing.T) {
+	c := newRaftCluster(t, 3)
+	leader := c.leader()
+	for i := range c.nodes {
+		if i != leader {
+			c.stop(i)
+		}
+	}
+
+	index, err := c.nodes[leader].node.Propose([]byte("lonely"))
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	waitForWithin(t, "leader to step down", 2*time.Second, func() bool {
+		_, isLeader := c.nodes[leader].node.Leader()
+		return !isLeader
+	})
+	if status := c.nodes[leader].node.Status(); status.CommitIndex >= index {
+		t.Fatalf("Entry %d committed without a majority: %+v", index, status)
+	}
+	if applied := c.nodes[leader].commands(); len(applied) != 0 {
+		t.Errorf("Expected nothing applied, got %v", applied)
+	}
+}
+
+// engineMember is a cluster node's matching engine, fed the requests the
+// cluster commits as the server feeds its own (see cmd/server/cluster.go).
+type engineMember struct {
+	engine    *matching.Engine
+	seq       *disruptor.Sequencer
+	processor *disruptor.EventProcessor
+	stopOnce  sync.Once
+
+	mu    sync.Mutex
+	trips []map[string][]orders.Order // The books as each dead man's switch trip left them
+}
+
+// newEngineMember starts an engine whose timers, ticking every tick, hand
+// their requests to onTimer.
+func newEngineMember(t *testing.T, tick time.Duration, onTimer func(request *disruptor.OrderRequest)) *engineMember {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 1024})
+	m := &engineMember{engine: engine, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	m.processor.EnableTimers(tick)
+	m.processor.OnTimerRequest(onTimer)
+	m.processor.OnDeadManTrip(func(sessionID string, cancelled []*orders.Order) {
+		m.mu.Lock()
+		m.trips = append(m.trips, engine.RestingOrders())
+		m.mu.Unlock()
+	})
+	m.processor.Start()
+	t.Cleanup(m.stop)
+	return m
+}
+
+// apply publishes a committed request to the member's ring buffer.
+func (m *engineMember) apply(t *testing.T, command []byte) {
+	var request disruptor.OrderRequest
+	if err := gob.NewDecoder(bytes.NewReader(command)).Decode(&request); err != nil {
+		t.Errorf("Committed request can't be decoded: %v", err)
+		return
+	}
+	for {
+		seq, err := m.seq.Next()
+		if err == nil {
+			m.seq.Publish(seq, &request, make(chan *disruptor.OrderResponse, 1))
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// tripped returns the books as each trip left them.
+func (m *engineMember) tripped() []map[string][]orders.Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]map[string][]orders.Order(nil), m.trips...)
+}
+
+// stop waits for the requests published so far to be processed, then
+// stops the processor.
+func (m *engineMember) stop() {
+	m.stopOnce.Do(func() {
+		if seq, err := m.seq.Next(); err == nil {
+			responseCh := make(chan *disruptor.OrderResponse, 1)
+			m.seq.Publish(seq, &disruptor.OrderRequest{Type: disruptor.RequestTypeOpenOrders, AccountID: "MM1"}, responseCh)
+			<-responseCh
+		}
+		m.processor.Shutdown()
+	})
+}
+
+// proposeRequest commits a request through node i, stamped with the
+// proposer's time as the server does.
+func (c *raftCluster) proposeRequest(i int, request *disruptor.OrderRequest) error {
+	if request.Time == 0 {
+		request.Time = orders.Now()
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(request); err != nil {
+		return err
+	}
+	_, err := c.nodes[i].node.Propose(buf.Bytes())
+	return err
+}
+
+// TestConsensus_DeadManTripCommitted verifies a dead man's switch that
+// expires on the leader's timers is tripped through the log: every node
+// cancels the session's orders at the same point of its sequence, its own
+// timers proposing nothing, and the books still match afterwards.
+func TestConsensus_DeadManTripCommitted(t *testing.T) {
+	members := make([]*engineMember, 3)
+	var c *raftCluster
+	for i := range members {
+		i := i
+		// Each node's timers run on their own ticks, here far apart
+		members[i] = newEngineMember(t, time.Duration(5+20*i)*time.Millisecond, func(request *disruptor.OrderRequest) {
+			go func() { // Only the leader proposes (see cmd/server/cluster.go)
+				if _, isLeader := c.nodes[i].node.Leader(); isLeader {
+					c.proposeRequest(i, request)
+				}
+			}()
+		})
+	}
+	c = newRaftClusterApplying(t, 3, func(i int, command []byte) { members[i].apply(t, command) })
+
+	propose := func(request *disruptor.OrderRequest) {
+		t.Helper()
+		if err := c.proposeRequest(c.leader(), request); err != nil {
+			t.Fatalf("Propose request type %d failed: %v", request.Type, err)
+		}
+	}
+	quote := limit(orders.SideBuy, 14900, 100)
+	quote.AccountID, quote.SessionID = "T1", "S1"
+	propose(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: quote})
+	propose(&disruptor.OrderRequest{Type: disruptor.RequestTypeHeartbeat, SessionID: "S1", Timeout: 100 * time.Millisecond})
+
+	// Orders keep coming while the switch runs out, so a trip applied at
+	// a different point on each node would leave different books
+	offers := 0
+	waitForWithin(t, "the switch to trip on every node", 3*time.Second, func() bool {
+		offer := limit(orders.SideSell, 15000+int64(offers), 10)
+		offer.AccountID, offer.SessionID = "MM1", "S2"
+		propose(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: offer})
+		offers++
+		time.Sleep(5 * time.Millisecond)
+		for _, m := range members {
+			if len(m.tripped()) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	// Wait for every node to apply everything (the orders, the heartbeat
+	// and at least one trip), then stop the engines
+	leader := c.nodes[c.leader()]
+	for _, member := range c.nodes {
+		waitForWithin(t, "every node to catch up", 2*time.Second, func() bool {
+			applied := member.commands()
+			return len(applied) >= offers+3 && sameCommands(applied, leader.commands())
+		})
+	}
+	for _, m := range members {
+		m.stop()
+	}
+
+	first := members[0].tripped()
+	if len(first) != 1 || len(first[0]["AAPL"]) == 0 {
+		t.Fatalf("Expected one trip with the offers resting, got %v", first)
+	}
+	for _, order := range first[0]["AAPL"] {
+		if order.SessionID == "S1" {
+			t.Errorf("Expected S1's order cancelled by the trip, got %+v", order)
+		}
+	}
+	books := members[0].engine.RestingOrders()
+	if len(books["AAPL"]) != offers {
+		t.Errorf("Expected the %d offers resting, got %d orders", offers, len(books["AAPL"]))
+	}
+	for i, m := range members[1:] {
+		if trips := m.tripped(); !reflect.DeepEqual(trips, first) {
+			t.Errorf("Node %d tripped at a different point: %d orders resting, node 0 %d", i+1, len(trips[0]["AAPL"]), len(first[0]["AAPL"]))
+		}
+		if !reflect.DeepEqual(m.engine.RestingOrders(), books) {
+			t.Errorf("Node %d's books differ from node 0's", i+1)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// DAILY LOSS LIMIT (ACCOUNT KILL SWITCH)
+// ============================================================================
+
+// TestPnL_AverageCostAcrossFlip verifies realized P&L uses average cost and
+// a fill through zero opens the opposite position at the fill price.
+func TestPnL_AverageCostAcrossFlip(t *testing.T) {
+	checker := risk.NewChecker(risk.DefaultConfig())
+
+	checker.RecordFill("T1", "AAPL", orders.SideBuy, 10, 10000)
+	checker.RecordFill("T1", "AAPL", orders.SideBuy, 10, 12000) // Average 110.00
+	checker.RecordFill("T1", "AAPL", orders.SideSell, 25, 13000)
+	checker.SetReferencePrice("AAPL", 12000)
+
+	pnl := checker.GetPnL("T1")
+	// Closed 20 at 130 vs 110 average; short 5 from 130 marked at 120
+	if pnl.Realized != 40000 || pnl.Unrealized != 5000 || pnl.Total != 45000 {
+		t.Fatalf("Expected realized 40000, unrealized 5000, got %d/%d (total %d)",
+			pnl.Realized, pnl.Unrealized, pnl.Total)
+	}
+	if len(pnl.Symbols) != 1 || pnl.Symbols[0].Position != -5 {
+		t.Errorf("Expected a 5 share short, got %+v", pnl.Symbols)
+	}
+}
+
+// TestPnL_PositionsSurviveNewDay verifies Positions keeps P&L and average
+// cost from the first fill across a new day, while the intraday P&L is
+// re-based.
+func TestPnL_PositionsSurviveNewDay(t *testing.T) {
+	checker := risk.NewChecker(risk.DefaultConfig())
+	checker.RecordFill("T1", "AAPL", orders.SideBuy, 10, 10000)
+	checker.RecordFill("T1", "AAPL", orders.SideSell, 4, 10500) // Realize $20
+	checker.RecordFill("T1", "MSFT", orders.SideSell, 5, 30000)
+	checker.SetReferencePrice("AAPL", 11000)
+
+	checker.ResetDailyPnL()
+	if pnl := checker.GetPnL("T1"); pnl.Realized != 0 || pnl.Unrealized != 0 {
+		t.Fatalf("Expected the intraday P&L re-based, got %d/%d", pnl.Realized, pnl.Unrealized)
+	}
+
+	positions := checker.Positions("T1")
+	if len(positions) != 2 || positions[0].Symbol != "AAPL" || positions[1].Symbol != "MSFT" {
+		t.Fatalf("Expected AAPL then MSFT, got %+v", positions)
+	}
+	// Long 6 at 100 marked at 110
+	if aapl := positions[0]; aapl.Position != 6 || aapl.AvgPrice != 10000 || aapl.MarkPrice != 11000 ||
+		aapl.Realized != 2000 || aapl.Unrealized != 6000 {
+		t.Errorf("Expected 6 at $100.00, $20 realized and $60 unrealized, got %+v", aapl)
+	}
+	// No price to mark the short to yet
+	if msft := positions[1]; msft.Position != -5 || msft.AvgPrice != 30000 || msft.Unrealized != 0 {
+		t.Errorf("Expected 5 short at $300.00, unmarked, got %+v", msft)
+	}
+	if other := checker.Positions("T2"); len(other) != 0 {
+		t.Errorf("Expected no positions for an account that never traded, got %+v", other)
+	}
+}
+
+// TestDailyLoss_TripsKillSwitch verifies an account is disabled once
+// realized plus unrealized losses exceed the limit, and that the risk event
+// reaches the account's drop-copy subscribers.
+func TestDailyLoss_TripsKillSwitch(t *testing.T) {
+	config := risk.DefaultConfig()
+	config.MaxDailyLoss = 100000 // $1,000
+	checker := risk.NewChecker(config)
+
+	hub := dropcopy.NewHub(10)
+	feed := hub.Subscribe("T1")
+	checker.OnEvent(hub.PublishRiskEvent)
+
+	checker.RecordFill("T1", "AAPL", orders.SideBuy, 100, 10000)
+	checker.RecordFill("T1", "AAPL", orders.SideSell, 50, 9000) // Realize -$500
+	checker.SetReferencePrice("AAPL", 9000)                     // Mark -$500
+	checker.CheckLossLimits("AAPL")
+
+	if killed, _ := checker.IsKilled("T1"); killed {
+		t.Fatal("Expected a loss exactly at the limit not to trip the kill switch")
+	}
+
+	checker.SetReferencePrice("AAPL", 8000) // Mark -$1,000
+	checker.CheckLossLimits("AAPL")
+
+	killed, reason := checker.IsKilled("T1")
+	if !killed {
+		t.Fatal("Expected the kill switch to trip")
+	}
+	t.Logf("Kill reason: %s", reason)
+
+	result := checker.Check(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 8000, Quantity: 50, AccountID: "T1"})
+	if result.Passed || !strings.Contains(result.Reason, "disabled") {
+		t.Errorf("Expected orders from a killed account to be rejected, got %+v", result)
+	}
+
+	select {
+	case msg := <-feed:
+		if msg.Type != dropcopy.MessageRiskEvent || msg.Risk.Type != risk.EventKillSwitchTripped || msg.Risk.PnL != -150000 {
+			t.Errorf("Unexpected drop-copy message: %+v", msg)
+		}
+	default:
+		t.Fatal("Expected a risk event on the drop-copy feed")
+	}
+
+	// Other accounts keep trading
+	if result := checker.Check(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 8000, Quantity: 50, AccountID: "T2"}); !result.Passed {
+		t.Errorf("Expected another account to pass, got %s", result.Reason)
+	}
+}
+
+// TestDailyLoss_ReinstateAndNewDay verifies an operator can reinstate an
+// account and that a new day only counts losses from the new reference.
+func TestDailyLoss_ReinstateAndNewDay(t *testing.T) {
+	config := risk.DefaultConfig()
+	config.MaxDailyLoss = 100000
+	checker := risk.NewChecker(config)
+
+	checker.RecordFill("T1", "AAPL", orders.SideSell, 100, 10000)
+	checker.SetReferencePrice("AAPL", 12000) // Short marked -$2,000
+	checker.CheckLossLimits("AAPL")
+	if killed, _ := checker.IsKilled("T1"); !killed {
+		t.Fatal("Expected the kill switch to trip")
+	}
+
+	checker.ResetDailyPnL()
+	if !checker.Reinstate("T1") {
+		t.Fatal("Expected Reinstate to clear the kill switch")
+	}
+	if checker.Reinstate("T1") {
+		t.Error("Expected a second Reinstate to report nothing to clear")
+	}
+
+	checker.CheckLossLimits("AAPL")
+	if pnl := checker.GetPnL("T1"); pnl.Killed || pnl.Total != 0 {
+		t.Errorf("Expected a fresh day at zero P&L, got %+v", pnl)
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/pkg/degrade"
+)
+
+// ============================================================================
+// GRACEFUL DEGRADATION
+// ============================================================================
+
+// TestDegrade_LevelsFollowLoadWithHysteresis verifies occupancy raises the
+// level past each threshold, and that it only falls once occupancy is well
+// below the threshold it crossed.
+func TestDegrade_LevelsFollowLoadWithHysteresis(t *testing.T) {
+	c := degrade.NewController(degrade.Policy{ShedAt: 0.5, RejectAt: 0.8, Recover: 0.8})
+	var changes []degrade.Level
+	c.OnChange(func(from, to degrade.Level) { changes = append(changes, to) })
+
+	steps := []struct {
+		occupancy float64
+		expected  degrade.Level
+	}{
+		{0.1, degrade.Normal},
+		{0.5, degrade.ShedLowPriority},
+		{0.9, degrade.RejectNew},
+		{0.7, degrade.RejectNew}, // Above 0.8 * 0.8
+		{0.6, degrade.ShedLowPriority},
+		{0.41, degrade.ShedLowPriority}, // Above 0.5 * 0.8
+		{0.3, degrade.Normal},
+	}
+	for _, step := range steps {
+		c.Observe(step.occupancy)
+		if level := c.Level(); level != step.expected {
+			t.Fatalf("At occupancy %.2f expected %s, got %s", step.occupancy, step.expected, level)
+		}
+	}
+	if len(changes) != 4 {
+		t.Errorf("Expected 4 level changes, got %v", changes)
+	}
+}
+
+// TestDegrade_ClassesAdmittedPerLevel verifies what each level admits:
+// cancels always, new orders until reject-new, reads only when normal.
+func TestDegrade_ClassesAdmittedPerLevel(t *testing.T) {
+	cases := []struct {
+		level                   degrade.Level
+		critical, order, lowPri bool
+	}{
+		{degrade.Normal, true, true, true},
+		{degrade.ShedLowPriority, true, true, false},
+		{degrade.RejectNew, true, false, false},
+		{degrade.Drain, true, false, false},
+	}
+	for _, tc := range cases {
+		if tc.level.Admits(degrade.Critical) != tc.critical ||
+			tc.level.Admits(degrade.Order) != tc.order ||
+			tc.level.Admits(degrade.LowPriority) != tc.lowPri {
+			t.Errorf("%s admits the wrong classes", tc.level)
+		}
+	}
+}
+
+// TestDegrade_OverrideAndDrain verifies an operator override is a floor
+// the load can raise but not lower, and that drain beats both.
+func TestDegrade_OverrideAndDrain(t *testing.T) {
+	c := degrade.NewController(degrade.DefaultPolicy())
+	if err := c.Override(degrade.ShedLowPriority); err != nil {
+		t.Fatal(err)
+	}
+	c.Observe(0)
+	if c.Level() != degrade.ShedLowPriority {
+		t.Fatalf("Expected override to hold shed-low-priority, got %s", c.Level())
+	}
+	c.Observe(0.95)
+	if c.Level() != degrade.RejectNew {
+		t.Fatalf("Expected load to raise the level above the override, got %s", c.Level())
+	}
+	if err := c.Override(degrade.Drain); err == nil {
+		t.Error("Expected drain to be refused as an override")
+	}
+
+	c.Drain()
+	c.Observe(0)
+	c.Override(degrade.Normal)
+	if c.Level() != degrade.Drain {
+		t.Errorf("Expected drain to stick, got %s", c.Level())
+	}
+}
+
+// TestDegrade_PublisherConflatesQuotes verifies that while conflating, a
+// burst of quotes for a symbol reaches subscribers as its latest one, and
+// that trades are never conflated.
+func TestDegrade_PublisherConflatesQuotes(t *testing.T) {
+	pub := marketdata.NewPublisher(100)
+	defer pub.Close()
+	quotes := pub.SubscribeL1("AAPL").C
+	trades := pub.SubscribeTrades("AAPL").C
+
+	pub.SetConflation(true)
+	for i := int64(1); i <= 10; i++ {
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 150000 + i})
+		pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: uint64(i)})
+	}
+	if len(trades) != 10 {
+		t.Errorf("Expected all 10 trades, got %d", len(trades))
+	}
+
+	select {
+	case quote := <-quotes:
+		if quote.BidPrice != 150010 {
+			t.Errorf("Expected the latest quote, got bid %d", quote.BidPrice)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Conflated quote never sent")
+	}
+	if len(quotes) != 0 || pub.ConflatedUpdates() != 9 {
+		t.Errorf("Expected 9 quotes conflated away, got %d conflated, %d queued", pub.ConflatedUpdates(), len(quotes))
+	}
+
+	pub.SetConflation(false)
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 149000})
+	if len(quotes) != 1 {
+		t.Errorf("Expected quotes sent straight away after conflation ends, got %d queued", len(quotes))
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// DEPTH BANDS (AGGREGATED DEPTH)
+// ============================================================================
+
+func newBandEngine() *matching.Engine {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	// Mid = 100.00
+	place := func(side orders.Side, price, qty, display int64) {
+		engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: side, Type: orders.OrderTypeLimit, Price: price, Quantity: qty, DisplayQty: display, AccountID: "MM1"})
+	}
+	place(orders.SideBuy, 9995, 100, 0)     // 5 bps from mid
+	place(orders.SideBuy, 9990, 200, 0)     // 10 bps: on the boundary
+	place(orders.SideBuy, 9960, 300, 0)     // 40 bps
+	place(orders.SideBuy, 9800, 400, 0)     // 200 bps: outside every band
+	place(orders.SideSell, 10005, 1000, 50) // Iceberg: only 50 displayed
+	place(orders.SideSell, 10080, 500, 0)   // 80 bps
+	return engine
+}
+
+// TestDepthBands_CumulativeDisplayedSize verifies each band sums displayed
+// size within its distance of mid, inclusive of the boundary.
+func TestDepthBands_CumulativeDisplayedSize(t *testing.T) {
+	engine := newBandEngine()
+	bands := marketdata.ComputeBands(engine.GetOrderBook("AAPL"), marketdata.DefaultBandBps)
+
+	if bands.Mid != 10000 {
+		t.Fatalf("Expected mid 10000, got %d", bands.Mid)
+	}
+	want := []marketdata.DepthBand{
+		{Bps: 10, BidQty: 300, AskQty: 50},
+		{Bps: 50, BidQty: 600, AskQty: 50},
+		{Bps: 100, BidQty: 600, AskQty: 550},
+	}
+	for i, w := range want {
+		if bands.Bands[i] != w {
+			t.Errorf("Band %d: expected %+v, got %+v", i, w, bands.Bands[i])
+		}
+	}
+}
+
+// TestDepthBands_PublishOnlyOnChange verifies a book change outside every
+// band publishes nothing, and a change inside one does.
+func TestDepthBands_PublishOnlyOnChange(t *testing.T) {
+	engine := newBandEngine()
+	book := engine.GetOrderBook("AAPL")
+	publisher := marketdata.NewPublisher(10)
+	updates := publisher.SubscribeBands("AAPL").C
+
+	if !publisher.PublishBands(marketdata.ComputeBands(book, marketdata.DefaultBandBps)) {
+		t.Fatal("Expected the first bands to be published")
+	}
+
+	// Deep in the book: bands unchanged
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 9700, Quantity: 100, AccountID: "T1"})
+	if publisher.PublishBands(marketdata.ComputeBands(book, marketdata.DefaultBandBps)) {
+		t.Error("Expected no update for a change outside every band")
+	}
+
+	// Inside the 50 bps band
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 9970, Quantity: 100, AccountID: "T1"})
+	if !publisher.PublishBands(marketdata.ComputeBands(book, marketdata.DefaultBandBps)) {
+		t.Error("Expected an update for a change inside a band")
+	}
+
+	if len(updates) != 2 {
+		t.Errorf("Expected 2 updates delivered, got %d", len(updates))
+	}
+	if latest, _ := publisher.LatestBands("AAPL"); latest.Bands[1].BidQty != 700 {
+		t.Errorf("Expected 700 bid within 50 bps, got %d", latest.Bands[1].BidQty)
+	}
+}
+
+// TestDepthBands_OneSidedBook verifies a book without a mid reports no bands.
+func TestDepthBands_OneSidedBook(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 9990, Quantity: 100, AccountID: "T1"})
+
+	bands := marketdata.ComputeBands(engine.GetOrderBook("AAPL"), marketdata.DefaultBandBps)
+	if bands.Mid != 0 || len(bands.Bands) != 0 {
+		t.Errorf("Expected no bands for a one-sided book, got %+v", bands)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// DROP COPY
+// ============================================================================
+
+// drain returns the messages waiting on a drop-copy feed.
+func drain(feed <-chan dropcopy.Message) []dropcopy.Message {
+	var messages []dropcopy.Message
+	for {
+		select {
+		case msg := <-feed:
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+// TestDropCopy_StreamsAccountExecutions verifies an account's feed carries
+// every change to its orders, including fills and cancels caused by other
+// accounts' requests, in order and with cumulative quantities, and nothing
+// of other accounts.
+func TestDropCopy_StreamsAccountExecutions(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	hub := dropcopy.NewHub(100)
+	makerFeed := hub.Subscribe("MM1")
+	takerFeed := hub.Subscribe("T1")
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.OnExecution(hub.PublishExecution)
+	run.processor.Start()
+	defer run.processor.Shutdown()
+
+	maker := limit(orders.SideSell, 15000, 100)
+	maker.AccountID = "MM1"
+	run.order(maker)
+	run.order(limit(orders.SideBuy, 15000, 60))
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: maker.ID, Side: orders.SideSell, AccountID: "MM1", Price: 15000, Quantity: 80,
+	}})
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: maker.ID})
+
+	expected := []struct {
+		execType          orders.ExecType
+		status            string
+		cum, leaves, last int64
+	}{
+		{orders.ExecTypeNew, "NEW", 0, 100, 0},
+		{orders.ExecTypeTrade, "PARTIALLY_FILLED", 60, 40, 60},
+		{orders.ExecTypeReplaced, "PARTIALLY_FILLED", 60, 20, 0},
+		{orders.ExecTypeCancelled, "CANCELLED", 60, 0, 0},
+	}
+	messages := drain(makerFeed)
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d maker messages, got %d: %+v", len(expected), len(messages), messages)
+	}
+	for i, msg := range messages {
+		e, want := msg.Execution, expected[i]
+		if msg.Type != dropcopy.MessageExecution || msg.Seq != uint64(i+1) || e.OrderID != maker.ID {
+			t.Fatalf("Message %d: unexpected %+v", i, msg)
+		}
+		if e.ExecType != want.execType || e.Status != want.status || e.CumQty != want.cum || e.LeavesQty != want.leaves || e.LastQty != want.last {
+			t.Errorf("Message %d: expected %+v, got %+v", i, want, e)
+		}
+	}
+	if trade := messages[1].Execution; !trade.IsMaker || trade.LastPx != "$150.00" || trade.TradeID == 0 {
+		t.Errorf("Expected the maker's fill at $150.00, got %+v", trade)
+	}
+	if cancel := messages[3].Execution; cancel.Text != "user cancelled" {
+		t.Errorf("Expected the cancel reason, got %q", cancel.Text)
+	}
+
+	// The taker's own feed has its order alone: accepted, then filled
+	messages = drain(takerFeed)
+	if len(messages) != 2 || messages[0].Execution.ExecType != orders.ExecTypeNew ||
+		messages[1].Execution.ExecType != orders.ExecTypeTrade || messages[1].Execution.Status != "FILLED" {
+		t.Errorf("Expected NEW then a filling TRADE for the taker, got %+v", messages)
+	}
+}
+
+// TestDropCopy_RejectAndUnfilledRemainder verifies a rejected order and the
+// cancelled remainder of an IOC order are reported with their reasons.
+func TestDropCopy_RejectAndUnfilledRemainder(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	hub := dropcopy.NewHub(100)
+	feed := hub.Subscribe("T1")
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.OnExecution(hub.PublishExecution)
+	run.processor.Start()
+	defer run.processor.Shutdown()
+
+	rejected := limit(orders.SideBuy, 15000, 10)
+	rejected.Symbol = "MSFT"
+	run.order(rejected)
+
+	ioc := limit(orders.SideBuy, 15000, 10)
+	ioc.Type = orders.OrderTypeIOC
+	run.order(ioc)
+
+	messages := drain(feed)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %+v", messages)
+	}
+	if e := messages[0].Execution; e.ExecType != orders.ExecTypeRejected || e.Status != "REJECTED" || e.Text == "" {
+		t.Errorf("Expected a reject with its reason, got %+v", e)
+	}
+	if e := messages[2].Execution; e.ExecType != orders.ExecTypeCancelled || e.OrderID != ioc.ID || e.LeavesQty != 0 || e.Text == "" {
+		t.Errorf("Expected the IOC's remainder cancelled, got %+v", e)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/enrichment"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// TRADE ENRICHMENT
+// ============================================================================
+
+// TestEnrichment_CounterpartyCodes verifies an account keeps one code per
+// trading day, gets a new one the next day, and codes depend on the key.
+func TestEnrichment_CounterpartyCodes(t *testing.T) {
+	anonymizer := enrichment.NewAnonymizer([]byte("key"))
+	morning := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC).UnixNano()
+	afternoon := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC).UnixNano()
+	nextDay := time.Date(2026, 3, 3, 9, 30, 0, 0, time.UTC).UnixNano()
+
+	code := anonymizer.Code("TRADER1", morning)
+	if code == "" || code == "TRADER1" || len(code) != 10 {
+		t.Fatalf("Expected a 10 digit code, got %q", code)
+	}
+	if again := anonymizer.Code("TRADER1", afternoon); again != code {
+		t.Errorf("Expected the same code all day, got %q and %q", code, again)
+	}
+	if other := anonymizer.Code("TRADER2", morning); other == code {
+		t.Error("Expected accounts to get different codes")
+	}
+	if tomorrow := anonymizer.Code("TRADER1", nextDay); tomorrow == code {
+		t.Error("Expected a new code the next day")
+	}
+	if rekeyed := enrichment.NewAnonymizer([]byte("other")).Code("TRADER1", morning); rekeyed == code {
+		t.Error("Expected codes to depend on the key")
+	}
+}
+
+// TestEnrichment_Reveal verifies operators can reverse today's and
+// yesterday's codes, and older ones are forgotten.
+func TestEnrichment_Reveal(t *testing.T) {
+	anonymizer := enrichment.NewAnonymizer([]byte("key"))
+	day := func(d int) int64 { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC).UnixNano() }
+
+	monday := anonymizer.Code("MM1", day(2))
+	tuesday := anonymizer.Code("MM1", day(3))
+	if account, ok := anonymizer.Reveal(tuesday); !ok || account != "MM1" {
+		t.Errorf("Expected today's code revealed as MM1, got %q", account)
+	}
+	if account, ok := anonymizer.Reveal(monday); !ok || account != "MM1" {
+		t.Errorf("Expected yesterday's code revealed as MM1, got %q", account)
+	}
+	anonymizer.Code("MM1", day(4))
+	if _, ok := anonymizer.Reveal(monday); ok {
+		t.Error("Expected a code from two days ago forgotten")
+	}
+}
+
+// TestEnrichment_PipelineHidesAccounts verifies a published trade names
+// buyer and seller by code whichever side was the aggressor.
+func TestEnrichment_PipelineHidesAccounts(t *testing.T) {
+	anonymizer := enrichment.NewAnonymizer([]byte("key"))
+	pipeline := enrichment.NewPipeline(enrichment.Counterparties(anonymizer))
+	now := orders.Now()
+
+	fill := orders.Fill{TradeID: 1, Symbol: "AAPL", Price: 15000, Quantity: 100, Timestamp: now,
+		MakerAccountID: "MM1", TakerAccountID: "TRADER1", TakerSide: orders.SideSell}
+	report := pipeline.Enrich(fill)
+	if report.BuyerCode != anonymizer.Code("MM1", now) || report.SellerCode != anonymizer.Code("TRADER1", now) {
+		t.Errorf("Expected the maker as buyer when the taker sells, got %+v", report)
+	}
+	if report.TradeID != 1 || report.Price != 15000 || report.AggressorSide != orders.SideSell {
+		t.Errorf("Expected the trade's public fields copied, got %+v", report)
+	}
+}
+
+// TestEnrichment_Tape verifies the publisher keeps the most recent trades,
+// newest first.
+func TestEnrichment_Tape(t *testing.T) {
+	publisher := marketdata.NewPublisher(10)
+	for id := uint64(1); id <= marketdata.TapeSize+50; id++ {
+		publisher.PublishTrade(marketdata.TradeReport{TradeID: id, Symbol: "AAPL"})
+	}
+
+	recent := publisher.RecentTrades("AAPL", 3)
+	if len(recent) != 3 || recent[0].TradeID != marketdata.TapeSize+50 || recent[2].TradeID != marketdata.TapeSize+48 {
+		t.Errorf("Expected the last 3 trades newest first, got %+v", recent)
+	}
+	if all := publisher.RecentTrades("AAPL", 1000); len(all) != marketdata.TapeSize || all[len(all)-1].TradeID != 51 {
+		t.Errorf("Expected the last %d trades kept, got %d", marketdata.TapeSize, len(all))
+	}
+	if none := publisher.RecentTrades("MSFT", 5); len(none) != 0 {
+		t.Errorf("Expected no trades for MSFT, got %d", len(none))
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// EXECUTION REPORTS (CumQty / AvgPx / LeavesQty)
+// ============================================================================
+
+// TestExecutionReports_CumulativeFields verifies that a taker sweeping two
+// price levels gets running cumulative totals on every report.
+func TestExecutionReports_CumulativeFields(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "S1"})
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15100, Quantity: 100, AccountID: "S2"})
+
+	taker := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15100, Quantity: 250, AccountID: "B1"}
+	result := engine.ProcessOrder(taker)
+
+	if len(result.Fills) != 2 || len(result.Reports) != 4 {
+		t.Fatalf("Expected 2 fills and 4 reports, got %d fills and %d reports", len(result.Fills), len(result.Reports))
+	}
+
+	var takerReports []orders.ExecutionReport
+	for _, r := range result.Reports {
+		if !r.IsMaker {
+			takerReports = append(takerReports, r)
+		}
+	}
+
+	first, second := takerReports[0], takerReports[1]
+	if first.CumQty != 100 || first.AvgPx != 15000 || first.LeavesQty != 150 {
+		t.Errorf("First report: cum=%d avg=%d leaves=%d, want 100/15000/150", first.CumQty, first.AvgPx, first.LeavesQty)
+	}
+	if second.CumQty != 200 || second.AvgPx != 15050 || second.LeavesQty != 50 {
+		t.Errorf("Second report: cum=%d avg=%d leaves=%d, want 200/15050/50", second.CumQty, second.AvgPx, second.LeavesQty)
+	}
+
+	if taker.AvgFillPrice() != 15050 || taker.LeavesQty() != 50 {
+		t.Errorf("Order avg=%d leaves=%d, want 15050/50", taker.AvgFillPrice(), taker.LeavesQty())
+	}
+
+	// Once cancelled, nothing is left open even though quantity is unfilled
+	if _, err := engine.CancelOrder("AAPL", taker.ID); err != nil {
+		t.Fatal(err)
+	}
+	if taker.LeavesQty() != 0 || taker.RemainingQty() != 50 {
+		t.Errorf("After cancel: leaves=%d remaining=%d, want 0/50", taker.LeavesQty(), taker.RemainingQty())
+	}
+}
+
+// TestExecutionReports_LevelQuantityAfterFullFill verifies a level's total
+// quantity drops by the full fill when a maker is filled and removed.
+func TestExecutionReports_LevelQuantityAfterFullFill(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "S1"})
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 50, AccountID: "S2"})
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 120, AccountID: "B1"})
+
+	level := engine.GetOrderBook("AAPL").GetBestAsk()
+	if level == nil || level.TotalQty != 30 {
+		t.Fatalf("Expected 30 shares left at $150.00, got %+v", level)
+	}
+}
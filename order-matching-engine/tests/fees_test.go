@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/fees"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// MAKER/TAKER FEES
+// ============================================================================
+
+// TestFees_Rounding verifies fees round to the nearest cent, halves away
+// from zero, for fees and rebates alike.
+func TestFees_Rounding(t *testing.T) {
+	for _, tc := range []struct{ notional, bps, fee int64 }{
+		{1500000, 3, 450},   // $15,000 at 3 bps = $4.50
+		{1500000, -2, -300}, // Rebate
+		{5000, 1, 1},        // $0.50 rounds up
+		{4999, 1, 0},
+		{5000, -1, -1},
+		{1500000, 0, 0},
+	} {
+		if fee := fees.Fee(tc.notional, tc.bps); fee != tc.fee {
+			t.Errorf("Fee(%d, %d): expected %d, got %d", tc.notional, tc.bps, tc.fee, fee)
+		}
+	}
+}
+
+// TestFees_Tiers verifies an account pays its tier's rates, and the default
+// schedule without one.
+func TestFees_Tiers(t *testing.T) {
+	engine := fees.NewEngine(fees.DefaultSchedule())
+	for name, schedule := range fees.DefaultTiers() {
+		engine.SetTier(name, schedule)
+	}
+	if err := engine.AssignTier("MM1", fees.TierMarketMaker); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.AssignTier("MM1", "platinum"); err == nil {
+		t.Error("Expected an unknown tier rejected")
+	}
+
+	fill := orders.Fill{Price: 15000, Quantity: 100, MakerAccountID: "MM1", TakerAccountID: "T1"}
+	engine.Charge(&fill)
+	if fill.MakerFee != -450 || fill.TakerFee != 450 {
+		t.Errorf("Expected a $4.50 market maker rebate and a $4.50 default fee, got %d / %d", fill.MakerFee, fill.TakerFee)
+	}
+
+	engine.AssignTier("MM1", fees.DefaultTierName)
+	if name, schedule := engine.AccountTier("MM1"); name != fees.DefaultTierName || schedule != fees.DefaultSchedule() {
+		t.Errorf("Expected MM1 back on the default schedule, got %s %+v", name, schedule)
+	}
+}
+
+// TestFees_LoggedAndCharged verifies the processor prices fills before
+// logging them, the response carries the fees, and clearing moves them in
+// account cash.
+func TestFees_LoggedAndCharged(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	clearing := settlement.NewClearingHouse()
+	clearing.GetOrCreateAccount("MM1", 1000000)
+	clearing.GetOrCreateAccount("T1", 1000000)
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.EnableClearing(clearing)
+	run.processor.EnableFees(fees.NewEngine(fees.DefaultSchedule()))
+	run.processor.Start()
+
+	ask := limit(orders.SideSell, 15000, 100)
+	ask.AccountID = "MM1"
+	run.order(ask)
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: limit(orders.SideBuy, 15000, 100)})
+	run.processor.Shutdown()
+
+	if fills := response.Result.Fills; len(fills) != 1 || fills[0].MakerFee != -300 || fills[0].TakerFee != 450 {
+		t.Fatalf("Expected a $3.00 rebate and a $4.50 fee on the response, got %+v", response.Result.Fills)
+	}
+	if maker, taker := clearing.GetAccount("MM1"), clearing.GetAccount("T1"); maker.Cash != 1000300 || taker.Cash != 999550 || taker.Fees != 450 {
+		t.Errorf("Expected the rebate credited and the fee debited, got MM1 %+v, T1 %+v", maker, taker)
+	}
+
+	var logged *events.FillEvent
+	for _, event := range replayAll(t, eventLog) {
+		if fill, ok := event.(*events.FillEvent); ok {
+			logged = fill
+		}
+	}
+	if logged == nil || logged.MakerFee != -300 || logged.TakerFee != 450 {
+		t.Errorf("Expected the fees in the fill event, got %+v", logged)
+	}
+}
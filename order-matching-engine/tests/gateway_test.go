@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/gateway"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// BINARY ORDER ENTRY
+// ============================================================================
+
+// fakeVenue is a gateway handler: limit orders rest, IOC orders fill half
+// and cancel the rest, and symbol BAD is rejected.
+type fakeVenue struct {
+	mu      sync.Mutex
+	nextID  uint64
+	resting map[uint64]*orders.Order
+}
+
+func (v *fakeVenue) Authorize(accountID string) bool { return accountID != "NOBODY" }
+
+func (v *fakeVenue) EnterOrder(order *orders.Order) gateway.Result {
+	if order.Symbol == "BAD" {
+		return gateway.Result{Reject: "unknown symbol"}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.nextID++
+	order.ID = v.nextID
+	if order.Type == orders.OrderTypeIOC {
+		filled := order.Quantity / 2
+		return gateway.Result{
+			OrderID:   order.ID,
+			Fills:     []gateway.Fill{{TradeID: 100 + order.ID, Price: order.Price, Quantity: filled}},
+			Cancelled: order.Quantity - filled,
+		}
+	}
+	v.resting[order.ID] = order
+	return gateway.Result{OrderID: order.ID}
+}
+
+func (v *fakeVenue) CancelOrder(symbol string, orderID uint64) gateway.Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	order := v.resting[orderID]
+	if order == nil || order.Symbol != symbol {
+		return gateway.Result{Reject: "order not found"}
+	}
+	delete(v.resting, orderID)
+	return gateway.Result{OrderID: orderID, Cancelled: order.Quantity}
+}
+
+func (v *fakeVenue) CancelSession(sessionID string) []gateway.Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var results []gateway.Result
+	for id, order := range v.resting {
+		if order.SessionID == sessionID {
+			delete(v.resting, id)
+			results = append(results, gateway.Result{OrderID: id, Cancelled: order.Quantity})
+		}
+	}
+	return results
+}
+
+// startGateway serves a gateway over a fakeVenue on a local port.
+func startGateway(t *testing.T) (*gateway.Server, *fakeVenue, string) {
+	t.Helper()
+	venue := &fakeVenue{resting: make(map[uint64]*orders.Order)}
+	server := gateway.NewServer(venue)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() {
+		l.Close()
+		server.Close()
+	})
+	return server, venue, l.Addr().String()
+}
+
+// loginGateway connects and logs in.
+func loginGateway(t *testing.T, addr, account, session string, seq uint64, cancelOnDisconnect bool) (*gateway.Client, net.Conn) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client, err := gateway.Login(conn, account, session, seq, cancelOnDisconnect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, conn
+}
+
+// receive returns the next sequenced message, failing after a second.
+func receive(t *testing.T, client *gateway.Client, conn net.Conn) gateway.Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	m, err := client.Receive()
+	if err != nil {
+		t.Fatalf("Expected a message: %v", err)
+	}
+	return m
+}
+
+// TestGateway_MessageRoundTrip verifies every message type decodes to what
+// was encoded, at its documented size.
+func TestGateway_MessageRoundTrip(t *testing.T) {
+	messages := []struct {
+		msg  gateway.Message
+		size int
+	}{
+		{gateway.Message{Type: gateway.EnterOrder, Token: "T1", Side: orders.SideSell, Quantity: 100, Symbol: "AAPL", Price: 15025, OrderType: orders.OrderTypeFOK}, 41},
+		{gateway.Message{Type: gateway.CancelOrder, Token: "T1"}, 15},
+		{gateway.Message{Type: gateway.Accepted, Timestamp: 1, Token: "T1", Side: orders.SideBuy, Quantity: 100, Symbol: "AAPL", Price: 15025, OrderType: orders.OrderTypeIOC, OrderID: 7}, 57},
+		{gateway.Message{Type: gateway.Executed, Timestamp: 2, Token: "T1", Quantity: 40, Price: 15020, TradeID: 9}, 47},
+		{gateway.Message{Type: gateway.Canceled, Timestamp: 3, Token: "T1", Quantity: 60, Reason: gateway.CancelImmediate}, 32},
+		{gateway.Message{Type: gateway.Rejected, Timestamp: 4, Token: "T1", Text: "unknown symbol"}, 23 + len("unknown symbol")},
+	}
+	for _, tc := range messages {
+		encoded, err := gateway.AppendMessage(nil, &tc.msg)
+		if err != nil {
+			t.Fatalf("%c: %v", tc.msg.Type, err)
+		}
+		if len(encoded) != tc.size {
+			t.Errorf("%c: expected %d bytes, got %d", tc.msg.Type, tc.size, len(encoded))
+		}
+		decoded, err := gateway.ParseMessage(encoded)
+		if err != nil {
+			t.Fatalf("%c: %v", tc.msg.Type, err)
+		}
+		if decoded != tc.msg {
+			t.Errorf("%c: expected %+v, got %+v", tc.msg.Type, tc.msg, decoded)
+		}
+	}
+	if _, err := gateway.ParseMessage([]byte{byte(gateway.CancelOrder), 'T'}); !errors.Is(err, gateway.ErrShortMessage) {
+		t.Errorf("Expected ErrShortMessage, got %v", err)
+	}
+}
+
+// TestGateway_OrderEntry verifies orders and cancels get sequenced
+// accepts, executions, cancels and rejects, named by token.
+func TestGateway_OrderEntry(t *testing.T) {
+	_, _, addr := startGateway(t)
+	client, conn := loginGateway(t, addr, "T1", "", 0, false)
+	if client.Session == "" || client.Seq != 1 {
+		t.Fatalf("Expected a new session starting at seq 1, got %q seq %d", client.Session, client.Seq)
+	}
+
+	client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: "IOC1", Side: orders.SideBuy, Quantity: 100, Symbol: "AAPL", Price: 15000, OrderType: orders.OrderTypeIOC})
+	if m := receive(t, client, conn); m.Type != gateway.Accepted || m.Token != "IOC1" || m.OrderID != 1 || m.Quantity != 100 {
+		t.Errorf("Expected IOC1 accepted as order 1, got %+v", m)
+	}
+	if m := receive(t, client, conn); m.Type != gateway.Executed || m.Quantity != 50 || m.TradeID != 101 {
+		t.Errorf("Expected 50 executed, got %+v", m)
+	}
+	if m := receive(t, client, conn); m.Type != gateway.Canceled || m.Quantity != 50 || m.Reason != gateway.CancelImmediate {
+		t.Errorf("Expected the rest cancelled, got %+v", m)
+	}
+
+	client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: "LMT1", Side: orders.SideSell, Quantity: 30, Symbol: "AAPL", Price: 15100})
+	if m := receive(t, client, conn); m.Type != gateway.Accepted || m.OrderID != 2 {
+		t.Errorf("Expected LMT1 accepted as order 2, got %+v", m)
+	}
+	client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: "LMT1", Side: orders.SideSell, Quantity: 30, Symbol: "AAPL", Price: 15100})
+	if m := receive(t, client, conn); m.Type != gateway.Rejected || m.Token != "LMT1" {
+		t.Errorf("Expected a reused token to be rejected, got %+v", m)
+	}
+	client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: "BAD1", Side: orders.SideBuy, Quantity: 1, Symbol: "BAD", Price: 100})
+	if m := receive(t, client, conn); m.Type != gateway.Rejected || m.Text != "unknown symbol" {
+		t.Errorf("Expected the handler's reject, got %+v", m)
+	}
+
+	client.Send(&gateway.Message{Type: gateway.CancelOrder, Token: "LMT1"})
+	if m := receive(t, client, conn); m.Type != gateway.Canceled || m.Quantity != 30 || m.Reason != gateway.CancelRequested {
+		t.Errorf("Expected LMT1 cancelled, got %+v", m)
+	}
+	client.Send(&gateway.Message{Type: gateway.CancelOrder, Token: "LMT1"})
+	if m := receive(t, client, conn); m.Type != gateway.CancelReject {
+		t.Errorf("Expected a second cancel to be rejected, got %+v", m)
+	}
+	client.Send(&gateway.Message{Type: gateway.CancelOrder, Token: "NOPE"})
+	if m := receive(t, client, conn); m.Type != gateway.CancelReject || m.Text != "unknown token" {
+		t.Errorf("Expected an unknown token to be rejected, got %+v", m)
+	}
+	if client.Seq != 10 {
+		t.Errorf("Expected 9 sequenced messages, next seq 10, got %d", client.Seq)
+	}
+}
+
+// TestGateway_PersistentSession verifies a client logging back in to its
+// session is sent the messages from the seq it asks for, and that a
+// session can't be taken over or used twice at once.
+func TestGateway_PersistentSession(t *testing.T) {
+	server, _, addr := startGateway(t)
+	client, conn := loginGateway(t, addr, "T1", "", 0, false)
+	for _, token := range []string{"A", "B", "C"} {
+		client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: token, Side: orders.SideBuy, Quantity: 10, Symbol: "AAPL", Price: 15000})
+		receive(t, client, conn)
+	}
+
+	other, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := gateway.Login(other, "T1", client.Session, 1, false); err != gateway.LoginError(gateway.RejectSessionUnavailable) {
+		t.Errorf("Expected a connected session to be unavailable, got %v", err)
+	}
+
+	client.Logout()
+	deadline := time.Now().Add(time.Second)
+	for _, connected := server.Sessions(); connected > 0 && time.Now().Before(deadline); _, connected = server.Sessions() {
+		time.Sleep(time.Millisecond)
+	}
+
+	resumed, conn := loginGateway(t, addr, "T1", client.Session, 2, false)
+	if resumed.Session != client.Session || resumed.Seq != 2 {
+		t.Fatalf("Expected %s from seq 2, got %s seq %d", client.Session, resumed.Session, resumed.Seq)
+	}
+	for _, token := range []string{"B", "C"} {
+		if m := receive(t, resumed, conn); m.Type != gateway.Accepted || m.Token != token {
+			t.Errorf("Expected %s replayed, got %+v", token, m)
+		}
+	}
+	resumed.Send(&gateway.Message{Type: gateway.CancelOrder, Token: "A"})
+	if m := receive(t, resumed, conn); m.Type != gateway.Canceled || m.Token != "A" || resumed.Seq != 5 {
+		t.Errorf("Expected A cancelled as seq 4 on the resumed session, got %+v (next seq %d)", m, resumed.Seq)
+	}
+
+	stranger, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+	if _, err := gateway.Login(stranger, "T2", client.Session, 1, false); err != gateway.LoginError(gateway.RejectSessionUnavailable) {
+		t.Errorf("Expected another account's session to be unavailable, got %v", err)
+	}
+	unknown, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	if _, err := gateway.Login(unknown, "NOBODY", "", 0, false); err != gateway.LoginError(gateway.RejectNotAuthorized) {
+		t.Errorf("Expected an unknown account to be refused, got %v", err)
+	}
+}
+
+// TestGateway_CancelOnDisconnect verifies a dropped session flagged for it
+// has its resting orders cancelled, and sees the cancels on logging back in.
+func TestGateway_CancelOnDisconnect(t *testing.T) {
+	_, venue, addr := startGateway(t)
+	client, conn := loginGateway(t, addr, "T1", "", 0, true)
+	client.Send(&gateway.Message{Type: gateway.EnterOrder, Token: "REST", Side: orders.SideBuy, Quantity: 25, Symbol: "AAPL", Price: 15000})
+	receive(t, client, conn)
+	conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		venue.mu.Lock()
+		left := len(venue.resting)
+		venue.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the resting order to be cancelled on disconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	resumed, conn := loginGateway(t, addr, "T1", client.Session, client.Seq, false)
+	if m := receive(t, resumed, conn); m.Type != gateway.Canceled || m.Token != "REST" || m.Quantity != 25 || m.Reason != gateway.CancelOnDisconnect {
+		t.Errorf("Expected REST cancelled on disconnect, got %+v", m)
+	}
+}
+
+// TestGateway_Heartbeats verifies an idle server sends heartbeats, and
+// disconnects a client that sends nothing.
+func TestGateway_Heartbeats(t *testing.T) {
+	venue := &fakeVenue{resting: make(map[uint64]*orders.Order)}
+	server := gateway.NewServer(venue)
+	server.SetTimeouts(20*time.Millisecond, 200*time.Millisecond)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	defer func() {
+		l.Close()
+		server.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := gateway.Login(conn, "T1", "", 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Receive returns only when the connection ends; heartbeats come first
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	var heartbeats int
+	start := time.Now()
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		for i := 0; i+2 < n; i += 3 {
+			if buf[i+2] == gateway.PacketServerBeat {
+				heartbeats++
+			}
+		}
+	}
+	if heartbeats == 0 {
+		t.Error("Expected heartbeats while idle")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a silent client to be disconnected after 200ms, took %v", elapsed)
+	}
+}
@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rishav/order-matching-engine/internal/grpcapi"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// gRPC ORDER ENTRY
+// ============================================================================
+
+// fakeOrderEntry is a grpcapi handler: orders fill in full at their price
+// with a rebate, only order 7 of AAPL can be cancelled, and AAPL's book has
+// one level a side.
+type fakeOrderEntry struct {
+	submitted chan *grpcapi.SubmitOrderRequest
+	sessions  chan string // Session of each order asking for cancel on disconnect
+	cancelled chan string // Sessions cancelled on disconnect
+}
+
+func (f *fakeOrderEntry) SubmitOrder(ctx context.Context, req *grpcapi.SubmitOrderRequest) (*grpcapi.OrderReply, error) {
+	f.submitted <- req
+	if id, cancelOnDisconnect := grpcapi.Session(ctx); cancelOnDisconnect {
+		f.sessions <- id
+	}
+	if req.Symbol == "BAD" {
+		return &grpcapi.OrderReply{Status: grpcapi.OrderStatusRejected, RejectCode: "UNKNOWN_SYMBOL", RejectReason: "unknown symbol"}, nil
+	}
+	return &grpcapi.OrderReply{
+		Accepted: true,
+		OrderID:  42,
+		Status:   grpcapi.OrderStatusFilled,
+		CumQty:   req.Quantity,
+		AvgPrice: req.Price,
+		Fills: []grpcapi.Fill{
+			{TradeID: 1, Price: req.Price, Quantity: req.Quantity / 2, Fee: -90},
+			{TradeID: 2, Price: req.Price, Quantity: req.Quantity - req.Quantity/2, Fee: -90},
+		},
+	}, nil
+}
+
+func (f *fakeOrderEntry) CancelOrder(ctx context.Context, req *grpcapi.CancelOrderRequest) (*grpcapi.CancelReply, error) {
+	if req.Symbol != "AAPL" || req.OrderID != 7 {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &grpcapi.CancelReply{OrderID: 7, CancelledQty: 100}, nil
+}
+
+func (f *fakeOrderEntry) GetBook(ctx context.Context, req *grpcapi.GetBookRequest) (*grpcapi.Book, error) {
+	return &grpcapi.Book{
+		Symbol: req.Symbol,
+		Bids:   []grpcapi.Level{{Price: 14999, Quantity: 300, Orders: 2}},
+		Asks:   []grpcapi.Level{{Price: 15001, Quantity: 100, Orders: 1}},
+	}, nil
+}
+
+func (f *fakeOrderEntry) Executions(ctx context.Context, req *grpcapi.ExecutionsRequest, send func(*grpcapi.ExecutionReport) error) error {
+	if req.AccountID != "MM1" {
+		return status.Error(codes.NotFound, "unknown account")
+	}
+	for seq := uint64(1); seq <= 2; seq++ {
+		report := &grpcapi.ExecutionReport{Seq: seq, AccountID: "MM1", ExecType: grpcapi.ExecTypeNew, OrderID: 40 + seq, Side: grpcapi.SideSell, Text: "accepted"}
+		if seq == 2 {
+			report.ExecType, report.TradeID, report.LastQty, report.LastPx, report.Fee = grpcapi.ExecTypeTrade, 9, 60, 15000, -90
+		}
+		if err := send(report); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakeOrderEntry) CancelSession(sessionID string) {
+	f.cancelled <- sessionID
+}
+
+// serveOrderEntry serves a fakeOrderEntry on a local port.
+func serveOrderEntry(t *testing.T) (*fakeOrderEntry, string) {
+	t.Helper()
+	fake := &fakeOrderEntry{
+		submitted: make(chan *grpcapi.SubmitOrderRequest, 10),
+		sessions:  make(chan string, 10),
+		cancelled: make(chan string, 10),
+	}
+	server := grpcapi.NewServer(fake)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	t.Cleanup(server.Stop)
+	return fake, l.Addr().String()
+}
+
+// startOrderEntry serves a fakeOrderEntry and dials it.
+func startOrderEntry(t *testing.T) (*grpcapi.Client, *fakeOrderEntry) {
+	t.Helper()
+	fake, addr := serveOrderEntry(t)
+	return dialOrderEntry(t, addr), fake
+}
+
+// dialOrderEntry connects a client, closed at the end of the test.
+func dialOrderEntry(t *testing.T, addr string, opts ...grpc.DialOption) *grpcapi.Client {
+	t.Helper()
+	client, err := grpcapi.Dial(addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestGRPC_SubmitAndCancel verifies orders and cancels round-trip with
+// every field, including repeated fills and negative fees, and that errors
+// keep their codes.
+func TestGRPC_SubmitAndCancel(t *testing.T) {
+	client, fake := startOrderEntry(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &grpcapi.SubmitOrderRequest{
+		Symbol: "AAPL", Side: grpcapi.SideBuy, Type: grpcapi.OrderTypeLimit, Price: 15000, Quantity: 101,
+		AccountID: "T1", ClientOrderID: "c-1", DisplayQty: 10, Peg: grpcapi.PegMidpoint,
+	}
+	reply, err := client.SubmitOrder(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := <-fake.submitted; *got != *req {
+		t.Errorf("Expected the server to get %+v, got %+v", req, got)
+	}
+	if !reply.Accepted || reply.OrderID != 42 || reply.Status != grpcapi.OrderStatusFilled || reply.CumQty != 101 || reply.AvgPrice != 15000 {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+	if len(reply.Fills) != 2 || reply.Fills[0].Quantity != 50 || reply.Fills[1].Quantity != 51 || reply.Fills[1].TradeID != 2 || reply.Fills[0].Fee != -90 {
+		t.Errorf("Expected two fills with rebates, got %+v", reply.Fills)
+	}
+
+	req.Symbol = "BAD"
+	reply, err = client.SubmitOrder(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Accepted || reply.Status != grpcapi.OrderStatusRejected || reply.RejectCode != "UNKNOWN_SYMBOL" || reply.RejectReason == "" {
+		t.Errorf("Expected a reject reply, got %+v", reply)
+	}
+
+	cancelled, err := client.CancelOrder(ctx, &grpcapi.CancelOrderRequest{Symbol: "AAPL", OrderID: 7})
+	if err != nil || cancelled.OrderID != 7 || cancelled.CancelledQty != 100 {
+		t.Errorf("Expected order 7 cancelled, got %+v, %v", cancelled, err)
+	}
+	if _, err := client.CancelOrder(ctx, &grpcapi.CancelOrderRequest{Symbol: "AAPL", OrderID: 8}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NOT_FOUND, got %v", err)
+	}
+}
+
+// TestGRPC_BookAndExecutions verifies book queries and the execution
+// report stream.
+func TestGRPC_BookAndExecutions(t *testing.T) {
+	client, _ := startOrderEntry(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	book, err := client.GetBook(ctx, &grpcapi.GetBookRequest{Symbol: "AAPL", Depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Symbol != "AAPL" || len(book.Bids) != 1 || len(book.Asks) != 1 ||
+		book.Bids[0] != (grpcapi.Level{Price: 14999, Quantity: 300, Orders: 2}) || book.Asks[0].Price != 15001 {
+		t.Errorf("Unexpected book %+v", book)
+	}
+
+	stream, err := client.Executions(ctx, &grpcapi.ExecutionsRequest{AccountID: "MM1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Seq != 1 || first.ExecType != grpcapi.ExecTypeNew || first.OrderID != 41 || first.Side != grpcapi.SideSell || first.Text != "accepted" {
+		t.Errorf("Unexpected first report %+v", first)
+	}
+	if second.Seq != 2 || second.ExecType != grpcapi.ExecTypeTrade || second.TradeID != 9 || second.LastPx != 15000 || second.Fee != -90 {
+		t.Errorf("Unexpected second report %+v", second)
+	}
+
+	stream, err = client.Executions(ctx, &grpcapi.ExecutionsRequest{AccountID: "NOBODY"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NOT_FOUND for an unknown account, got %v", err)
+	}
+}
+
+// TestGRPC_OrderConversion verifies requests convert to engine orders and
+// unset enums are refused.
+func TestGRPC_OrderConversion(t *testing.T) {
+	req := &grpcapi.SubmitOrderRequest{Symbol: "AAPL", Side: grpcapi.SideSell, Type: grpcapi.OrderTypeIOC, Price: 15000, Quantity: 10, AccountID: "T1", Peg: grpcapi.PegPrimary}
+	order, err := req.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Side != orders.SideSell || order.Type != orders.OrderTypeIOC || order.Peg != orders.PegPrimary || order.PegLimit != 15000 {
+		t.Errorf("Unexpected order %+v", order)
+	}
+
+	for _, bad := range []grpcapi.SubmitOrderRequest{
+		{Symbol: "AAPL", Type: grpcapi.OrderTypeLimit, Quantity: 10},
+		{Symbol: "AAPL", Side: grpcapi.SideBuy, Quantity: 10},
+		{Symbol: "AAPL", Side: grpcapi.SideBuy, Type: grpcapi.OrderTypeLimit, Quantity: 10, Peg: 7},
+	} {
+		if _, err := bad.Order(); err == nil {
+			t.Errorf("Expected %+v to be refused", bad)
+		}
+	}
+
+	if grpcapi.NewOrderStatus(orders.OrderStatusPartiallyFilled) != grpcapi.OrderStatusPartiallyFilled {
+		t.Error("Expected engine statuses to map one to one")
+	}
+}
+
+// TestGRPC_CancelOnDisconnect verifies a connection whose orders asked for
+// cancel on disconnect has its session cancelled when it closes, each
+// connection being a session of its own, and that other connections'
+// closing cancels nothing.
+func TestGRPC_CancelOnDisconnect(t *testing.T) {
+	fake, addr := serveOrderEntry(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &grpcapi.SubmitOrderRequest{Symbol: "AAPL", Side: grpcapi.SideBuy, Type: grpcapi.OrderTypeLimit, Price: 15000, Quantity: 10, AccountID: "T1"}
+	var ids []string
+	for i := 0; i < 2; i++ {
+		protected := dialOrderEntry(t, addr, grpcapi.WithCancelOnDisconnect())
+		if _, err := protected.SubmitOrder(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, <-fake.sessions)
+		if i == 0 {
+			protected.Close()
+		}
+	}
+	if ids[0] == "" || ids[0] == ids[1] {
+		t.Fatalf("Expected a session per connection, got %v", ids)
+	}
+	select {
+	case id := <-fake.cancelled:
+		if id != ids[0] {
+			t.Errorf("Expected session %s cancelled, got %s", ids[0], id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the closed connection's session to be cancelled")
+	}
+
+	plain := dialOrderEntry(t, addr)
+	if _, err := plain.SubmitOrder(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	plain.Close()
+	select {
+	case id := <-fake.cancelled:
+		t.Errorf("Expected no cancel for a connection that didn't ask, got %s", id)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
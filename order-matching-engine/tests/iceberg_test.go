@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// ICEBERG (DISPLAY QUANTITY) ORDERS
+// ============================================================================
+
+// TestIceberg_DepthShowsOnlyDisplaySlice verifies a resting iceberg
+// contributes only its visible slice to the level's published quantity.
+func TestIceberg_DepthShowsOnlyDisplaySlice(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 1000, DisplayQty: 100, AccountID: "ICE"})
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 50, AccountID: "LIT"})
+
+	level := engine.GetOrderBook("AAPL").GetBestAsk()
+	if level.TotalQty != 150 || level.HiddenQty != 900 {
+		t.Fatalf("Expected 150 displayed and 900 hidden, got %d/%d", level.TotalQty, level.HiddenQty)
+	}
+}
+
+// TestIceberg_ReplenishLosesTimePriority verifies a slice that fills is
+// replenished behind orders that arrived after the iceberg.
+func TestIceberg_ReplenishLosesTimePriority(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	iceberg := &orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 300, DisplayQty: 100, AccountID: "ICE"}
+	engine.ProcessOrder(iceberg)
+	lit := &orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "LIT"}
+	engine.ProcessOrder(lit)
+
+	// Takes the iceberg's first slice, then the lit order, then a second slice
+	result := engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 250, AccountID: "B1"})
+
+	want := []struct {
+		maker uint64
+		qty   int64
+	}{{iceberg.ID, 100}, {lit.ID, 100}, {iceberg.ID, 50}}
+	if len(result.Fills) != len(want) {
+		t.Fatalf("Expected %d fills, got %d: %v", len(want), len(result.Fills), result.Fills)
+	}
+	for i, w := range want {
+		if f := result.Fills[i]; f.MakerOrderID != w.maker || f.Quantity != w.qty {
+			t.Errorf("Fill %d: maker %d qty %d, want maker %d qty %d", i, f.MakerOrderID, f.Quantity, w.maker, w.qty)
+		}
+	}
+
+	level := engine.GetOrderBook("AAPL").GetBestAsk()
+	if iceberg.VisibleQty() != 50 || level.TotalQty != 50 || level.HiddenQty != 100 {
+		t.Errorf("Expected 50 shown and 100 in reserve, got order %d, level %d/%d",
+			iceberg.VisibleQty(), level.TotalQty, level.HiddenQty)
+	}
+}
+
+// TestIceberg_SweepsReserve verifies an aggressive order can trade through
+// the whole reserve of a lone iceberg, and FOK counts the reserve as liquidity.
+func TestIceberg_SweepsReserve(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	iceberg := &orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 500, DisplayQty: 100, AccountID: "ICE"}
+	engine.ProcessOrder(iceberg)
+
+	result := engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeFOK, Price: 15000, Quantity: 500, AccountID: "B1"})
+	if result.Order.Status != orders.OrderStatusFilled || len(result.Fills) != 5 {
+		t.Fatalf("Expected FOK filled in 5 slices, got %s with %d fills", result.Order.Status, len(result.Fills))
+	}
+	if iceberg.Status != orders.OrderStatusFilled || engine.GetOrderBook("AAPL").GetBestAsk() != nil {
+		t.Errorf("Expected iceberg filled and the level removed")
+	}
+}
+
+// TestIceberg_RejectsNonLimit verifies display quantity is limit-only.
+func TestIceberg_RejectsNonLimit(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	result := engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeIOC, Price: 15000, Quantity: 500, DisplayQty: 100})
+	if result.Accepted {
+		t.Fatal("Expected IOC with display quantity to be rejected")
+	}
+}
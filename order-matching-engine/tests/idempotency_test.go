@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// IDEMPOTENT ORDER ENTRY
+// ============================================================================
+
+// startIdempotentRun starts a processor remembering client order IDs for
+// window.
+func startIdempotentRun(t *testing.T, engine *matching.Engine, window time.Duration) *tailRun {
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.SetIdempotencyWindow(window)
+	run.processor.Start()
+	return run
+}
+
+func newOrderRequest(o *orders.Order) *disruptor.OrderRequest {
+	return &disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: o}
+}
+
+// TestIdempotency_ResentOrderGetsOriginalResponse verifies an order resent
+// with the same account and client order ID is answered with the original
+// order and its fills, without a second order reaching the book.
+func TestIdempotency_ResentOrderGetsOriginalResponse(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run := startIdempotentRun(t, engine, time.Minute)
+	defer run.processor.Shutdown()
+
+	run.order(limit(orders.SideSell, 15000, 60))
+	first := run.send(newOrderRequest(withClientID(limit(orders.SideBuy, 15000, 100), "abc")))
+	if !first.Success || first.Duplicate || len(first.Result.Fills) != 1 {
+		t.Fatalf("Expected the first order to fill once, got %+v", first)
+	}
+
+	resent := run.send(newOrderRequest(withClientID(limit(orders.SideBuy, 15000, 100), "abc")))
+	if !resent.Success || !resent.Duplicate {
+		t.Fatalf("Expected a duplicate, got %+v", resent)
+	}
+	if resent.Order.ID != first.Order.ID || resent.Order.FilledQty != 60 || len(resent.Result.Fills) != 1 ||
+		resent.Result.Fills[0].TradeID != first.Result.Fills[0].TradeID {
+		t.Errorf("Expected order %d's original response, got %+v", first.Order.ID, resent.Order)
+	}
+
+	if book := engine.GetOrderBook("AAPL"); book.TotalOrders() != 1 || book.GetOrder(first.Order.ID).RemainingQty() != 40 {
+		t.Errorf("Expected only the original's 40 resting, got %s", book)
+	}
+
+	// The ID is per account
+	other := withClientID(limit(orders.SideBuy, 14900, 10), "abc")
+	other.AccountID = "T2"
+	if response := run.send(newOrderRequest(other)); response.Duplicate {
+		t.Error("Expected another account's same ID to be a new order")
+	}
+}
+
+// TestIdempotency_WindowAndRestart verifies a client order ID is free again
+// once the window has passed, and that after a restart a duplicate is still
+// recognised from the engine's own index.
+func TestIdempotency_WindowAndRestart(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run := startIdempotentRun(t, engine, time.Minute)
+
+	first := withClientID(limit(orders.SideBuy, 14900, 100), "abc")
+	start := time.Unix(1700000000, 0).UnixNano()
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: first, Time: start})
+
+	later := withClientID(limit(orders.SideBuy, 14900, 100), "abc")
+	laterReq := newOrderRequest(later)
+	laterReq.Time = start + int64(2*time.Minute)
+	if response := run.send(laterReq); response.Duplicate || response.Order.ID == first.ID {
+		t.Fatalf("Expected a new order past the window, got %+v", response.Order)
+	}
+	run.processor.Shutdown()
+
+	// A new processor remembers nothing, but the engine knows the latest "abc"
+	restarted := startIdempotentRun(t, engine, time.Minute)
+	defer restarted.processor.Shutdown()
+	resentReq := newOrderRequest(withClientID(limit(orders.SideBuy, 14900, 100), "abc"))
+	resentReq.Time = laterReq.Time + int64(time.Second)
+	response := restarted.send(resentReq)
+	if !response.Duplicate || response.Order.ID != later.ID {
+		t.Errorf("Expected a duplicate of order %d after restart, got %+v", later.ID, response.Order)
+	}
+}
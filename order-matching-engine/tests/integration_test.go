@@ -586,8 +586,8 @@ LEVELS:
 	var receivedTrades int32
 	var wg sync.WaitGroup
 
-	l1Ch := publisher.SubscribeL1("AAPL")
-	tradeCh := publisher.SubscribeTrades("AAPL")
+	l1Ch := publisher.SubscribeL1("AAPL").C
+	tradeCh := publisher.SubscribeTrades("AAPL").C
 
 	done := make(chan bool)
 
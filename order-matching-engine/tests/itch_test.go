@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/itch"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// ITCH BINARY FEED
+// ============================================================================
+
+// TestITCH_MessageRoundTrip verifies every message type decodes to what was
+// encoded, at its documented size.
+func TestITCH_MessageRoundTrip(t *testing.T) {
+	messages := []struct {
+		msg  itch.Message
+		size int
+	}{
+		{itch.Message{Type: itch.AddLevel, Symbol: "AAPL", Timestamp: 1700000000000000000, Side: orders.SideBuy, Price: 15000, Quantity: 100, Count: 2, BookSeq: 7}, 46},
+		{itch.Message{Type: itch.ModifyLevel, Symbol: "GOOGL", Timestamp: 1, Side: orders.SideSell, Price: 280050, Quantity: 30, Count: 1, BookSeq: 8}, 46},
+		{itch.Message{Type: itch.DeleteLevel, Symbol: "MSFT", Timestamp: 2, Side: orders.SideSell, Price: 41000, BookSeq: 9}, 34},
+		{itch.Message{Type: itch.Trade, Symbol: "BRK.B", Timestamp: 3, Side: orders.SideBuy, Price: 45000, Quantity: 10, TradeID: 42}, 42},
+	}
+	for _, tc := range messages {
+		encoded, err := itch.AppendMessage(nil, &tc.msg)
+		if err != nil {
+			t.Fatalf("%c: %v", tc.msg.Type, err)
+		}
+		if len(encoded) != tc.size {
+			t.Errorf("%c: expected %d bytes, got %d", tc.msg.Type, tc.size, len(encoded))
+		}
+		decoded, err := itch.ParseMessage(encoded)
+		if err != nil {
+			t.Fatalf("%c: %v", tc.msg.Type, err)
+		}
+		if decoded != tc.msg {
+			t.Errorf("%c: expected %+v, got %+v", tc.msg.Type, tc.msg, decoded)
+		}
+		if _, err := itch.ParseMessage(encoded[:len(encoded)-1]); !errors.Is(err, itch.ErrShortMessage) {
+			t.Errorf("%c: expected ErrShortMessage for a truncated message, got %v", tc.msg.Type, err)
+		}
+	}
+
+	if _, err := itch.AppendMessage(nil, &itch.Message{Type: itch.Trade, Symbol: "TOOLONGSYM"}); err == nil {
+		t.Error("Expected a symbol longer than 8 characters to be refused")
+	}
+}
+
+// receivePacket reads and decodes one packet from conn.
+func receivePacket(t *testing.T, conn *net.UDPConn) itch.Packet {
+	t.Helper()
+	buf := make([]byte, itch.MaxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Expected a packet: %v", err)
+	}
+	packet, err := itch.ParsePacket(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packet
+}
+
+// TestITCH_FeedFromPublisher verifies book feed diffs become add, modify and
+// delete messages and trades become trade messages, sequenced without gaps,
+// and that closing the feed ends the session.
+func TestITCH_FeedFromPublisher(t *testing.T) {
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+	conn, err := itch.DialMulticast(receiver.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	feed := itch.NewFeed("TEST000001", conn, 100)
+	feed.SetHeartbeat(time.Hour)
+	publisher := marketdata.NewPublisher(10)
+	publisher.AddFeed(feed)
+	feed.Start()
+
+	publisher.PublishBook(bookImage([][2]int64{{15000, 100}}, [][2]int64{{15010, 50}}))
+	first := receivePacket(t, receiver)
+	if first.Session != "TEST000001" || first.Seq != 1 || len(first.Messages) != 2 {
+		t.Fatalf("Expected session TEST000001 seq 1 with 2 messages, got %+v", first)
+	}
+	for _, m := range first.Messages {
+		if m.Type != itch.AddLevel {
+			t.Errorf("Expected new levels to be adds, got %c", m.Type)
+		}
+	}
+
+	publisher.PublishBook(bookImage([][2]int64{{15000, 60}}, [][2]int64{{15020, 70}}))
+	second := receivePacket(t, receiver)
+	if second.Seq != 3 {
+		t.Errorf("Expected seq 3 to follow 2 messages, got %d", second.Seq)
+	}
+	var types []itch.MessageType
+	for _, m := range second.Messages {
+		types = append(types, m.Type)
+	}
+	if want := []itch.MessageType{itch.ModifyLevel, itch.AddLevel, itch.DeleteLevel}; !reflect.DeepEqual(types, want) {
+		t.Errorf("Expected modify, add, delete, got %q", types)
+	}
+	if m := second.Messages[0]; m.Price != 15000 || m.Quantity != 60 || m.BookSeq != 3 {
+		t.Errorf("Expected the modify to carry the new quantity and book seq, got %+v", m)
+	}
+
+	publisher.PublishTrade(marketdata.TradeReport{TradeID: 9, Symbol: "AAPL", Price: 15010, Quantity: 50, AggressorSide: orders.SideBuy})
+	trade := receivePacket(t, receiver)
+	if trade.Seq != 6 || len(trade.Messages) != 1 || trade.Messages[0].Type != itch.Trade || trade.Messages[0].TradeID != 9 {
+		t.Errorf("Expected trade 9 as seq 6, got %+v", trade)
+	}
+
+	feed.Close()
+	end := receivePacket(t, receiver)
+	if !end.End || end.Seq != 7 {
+		t.Errorf("Expected the end of session at seq 7, got %+v", end)
+	}
+}
+
+// TestITCH_Retransmit verifies a receiver recovers missed messages over
+// TCP, learns which are too old to recover, and is told when the session
+// changed.
+func TestITCH_Retransmit(t *testing.T) {
+	feed := itch.NewFeed("TEST000002", io.Discard, 3) // Sender not started; queued packets count as lost
+	for i := 1; i <= 5; i++ {
+		feed.PublishTrade(marketdata.TradeReport{TradeID: uint64(i), Symbol: "AAPL", Price: 15000, Quantity: 1})
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go feed.ServeRetransmit(l)
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	packets, err := itch.RequestRetransmit(conn, "TEST000002", 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || packets[0].Seq != 4 || len(packets[0].Messages) != 2 || packets[0].Messages[1].TradeID != 5 {
+		t.Fatalf("Expected trades 4 and 5 from seq 4, got %+v", packets)
+	}
+
+	// Only the last 3 messages are held: the reply starts at seq 3
+	packets, err = itch.RequestRetransmit(conn, "TEST000002", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || packets[0].Seq != 3 || len(packets[0].Messages) != 3 {
+		t.Fatalf("Expected 3 messages from seq 3, got %+v", packets)
+	}
+
+	// Nothing past the end: a heartbeat with the next seq
+	packets, err = itch.RequestRetransmit(conn, "TEST000002", 6, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || packets[0].Seq != 6 || len(packets[0].Messages) != 0 {
+		t.Fatalf("Expected a heartbeat at seq 6, got %+v", packets)
+	}
+
+	packets, err = itch.RequestRetransmit(conn, "OLDSESSION", 1, 10)
+	if !errors.Is(err, itch.ErrSessionChanged) {
+		t.Fatalf("Expected ErrSessionChanged, got %v", err)
+	}
+	if len(packets) != 1 || packets[0].Session != "TEST000002" || packets[0].Seq != 6 {
+		t.Errorf("Expected a heartbeat of the current session, got %+v", packets)
+	}
+}
@@ -0,0 +1,380 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// JOURNAL DAMAGE
+// ============================================================================
+
+// appendCross logs a resting sell, a buy crossing it and their fill, the way
+// the processor would.
+func appendCross(t *testing.T, eventLog *events.EventLog, symbol string, sellID, buyID, tradeID uint64, clientOrderID string) {
+	t.Helper()
+	for _, event := range []interface{}{
+		&events.NewOrderEvent{OrderID: sellID, Symbol: symbol, Side: orders.SideSell, OrderType: orders.OrderTypeLimit,
+			Price: 15000, Quantity: 100, AccountID: "MM1"},
+		&events.NewOrderEvent{OrderID: buyID, Symbol: symbol, Side: orders.SideBuy, OrderType: orders.OrderTypeLimit,
+			Price: 15000, Quantity: 100, AccountID: "TRADER1", ClientOrderID: clientOrderID},
+		&events.FillEvent{TradeID: tradeID, Symbol: symbol, Price: 15000, Quantity: 100, MakerOrderID: sellID,
+			TakerOrderID: buyID, MakerAccountID: "MM1", TakerAccountID: "TRADER1", TakerSide: orders.SideBuy},
+	} {
+		if _, err := eventLog.Append(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tamperedLog writes an AAPL cross and an MSFT cross, then alters the
+// MSFT buy order on disk so its record fails its checksum.
+func tamperedLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openLogAt(t, path)
+	appendCross(t, eventLog, "AAPL", 1, 2, 1, "")
+	appendCross(t, eventLog, "MSFT", 3, 4, 2, "tamper-me")
+	eventLog.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("tamper-me"), []byte("tampered!"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// openLogAt opens the event log at path.
+func openLogAt(t *testing.T, path string) *events.EventLog {
+	t.Helper()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eventLog
+}
+
+// TestJournal_ChecksumStopsReplayByDefault verifies replay stops at a
+// tampered record, reporting its sequence number and symbol.
+func TestJournal_ChecksumStopsReplayByDefault(t *testing.T) {
+	eventLog := openLogAt(t, tamperedLog(t))
+	defer eventLog.Close()
+
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error { return nil })
+	var damage *events.Damage
+	if !errors.Is(err, events.ErrChecksumMismatch) || !errors.As(err, &damage) {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+	if damage.Seq != 5 || damage.Symbol != "MSFT" {
+		t.Errorf("Expected damage to MSFT at sequence 5, got %d %q", damage.Seq, damage.Symbol)
+	}
+}
+
+// TestJournal_OnDamageCarriesOn verifies a damage hook returning nil skips
+// the bad record and replays the rest.
+func TestJournal_OnDamageCarriesOn(t *testing.T) {
+	eventLog := openLogAt(t, tamperedLog(t))
+	defer eventLog.Close()
+
+	var damaged []*events.Damage
+	eventLog.OnDamage(func(d *events.Damage) error {
+		damaged = append(damaged, d)
+		return nil
+	})
+	var seqs []uint64
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		seqs = append(seqs, seqNum)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(damaged) != 1 || damaged[0].Seq != 5 || damaged[0].Event == nil {
+		t.Fatalf("Expected the record at sequence 5 reported, got %+v", damaged)
+	}
+	if len(seqs) != 5 || seqs[3] != 4 || seqs[4] != 6 {
+		t.Errorf("Expected every event but 5 replayed, got %v", seqs)
+	}
+}
+
+// TestJournal_DetectsGap verifies a missing segment is reported as a
+// sequence gap, and a damage hook can carry on past it.
+func TestJournal_DetectsGap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openSegmented(t, path, events.Retention{})
+	appendOrders(t, eventLog, 100)
+	segments := eventLog.Segments()
+	if len(segments) < 3 {
+		t.Fatalf("Expected several segments, got %d", len(segments))
+	}
+	if err := os.Remove(segments[1].Path); err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+
+	if _, err := replaySeqs(eventLog, 0); !errors.Is(err, events.ErrSequenceGap) {
+		t.Fatalf("Expected a sequence gap, got %v", err)
+	}
+
+	var gap *events.Damage
+	eventLog.OnDamage(func(d *events.Damage) error {
+		gap = d
+		return nil
+	})
+	seqs, err := replaySeqs(eventLog, 0)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if gap == nil || gap.Seq != segments[2].FirstSeq || gap.Symbol != "" {
+		t.Errorf("Expected a gap before sequence %d, got %+v", segments[2].FirstSeq, gap)
+	}
+	if missing := segments[1].LastSeq - segments[1].FirstSeq + 1; uint64(len(seqs)) != 100-missing {
+		t.Errorf("Expected %d events replayed, got %d", 100-missing, len(seqs))
+	}
+}
+
+// TestJournal_SkipKeepsReplayAligned verifies skipping one symbol's events
+// still reproduces the other symbol's trades with their logged IDs.
+func TestJournal_SkipKeepsReplayAligned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openLogAt(t, path)
+	defer eventLog.Close()
+	appendCross(t, eventLog, "AAPL", 1, 2, 1, "")
+	appendCross(t, eventLog, "MSFT", 3, 4, 2, "")
+
+	replay := func(skip bool) (*matching.Engine, error) {
+		engine := matching.NewEngine()
+		replayer := matching.NewReplayer(engine)
+		err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+			if events.SymbolOf(event) == "AAPL" {
+				if skip {
+					replayer.Skip(event)
+				}
+				return nil
+			}
+			_, err := replayer.Apply(event)
+			return err
+		})
+		engine.RestoreIDCounters(replayer.Counters())
+		return engine, err
+	}
+
+	if _, err := replay(false); err == nil || !strings.Contains(err.Error(), "diverged") {
+		t.Fatalf("Expected dropping AAPL's events to diverge, got %v", err)
+	}
+	engine, err := replay(true)
+	if err != nil {
+		t.Fatalf("Replay skipping AAPL failed: %v", err)
+	}
+	if counters := engine.IDCounters(); counters.OrderID != 4 || counters.TradeID != 2 || counters.SequenceNum != 4 {
+		t.Errorf("Expected counters past every logged ID, got %+v", counters)
+	}
+}
+
+// tornLog writes an AAPL cross and an MSFT buy order, then cuts the log
+// short with cut, given the offset the last record starts at, as a crash
+// in the middle of appending it would. Returns the log and that offset.
+func tornLog(t *testing.T, cut func(data []byte, last int) []byte) (string, int64) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openLogAt(t, path)
+	appendCross(t, eventLog, "AAPL", 1, 2, 1, "")
+	eventLog.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventLog = openLogAt(t, path)
+	if _, err := eventLog.Append(&events.NewOrderEvent{OrderID: 3, Symbol: "MSFT", Side: orders.SideBuy,
+		OrderType: orders.OrderTypeLimit, Price: 30000, Quantity: 100, AccountID: "TRADER1"}); err != nil {
+		t.Fatal(err)
+	}
+	eventLog.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, cut(data, int(info.Size())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, info.Size()
+}
+
+// TestJournal_TruncatesTornWrite verifies opening a log drops a final
+// record cut short or left half-written, and appends after the last whole
+// one.
+func TestJournal_TruncatesTornWrite(t *testing.T) {
+	cuts := map[string]func(data []byte, last int) []byte{
+		"short":  func(data []byte, last int) []byte { return data[:last+(len(data)-last)/2] },
+		"header": func(data []byte, last int) []byte { return data[:last+3] },
+		"garbled": func(data []byte, last int) []byte {
+			data[len(data)-6] ^= 0xFF // Full length, but a sector never made it
+			return data
+		},
+	}
+	for name, cut := range cuts {
+		t.Run(name, func(t *testing.T) {
+			path, last := tornLog(t, cut)
+			eventLog := openLogAt(t, path)
+			defer eventLog.Close()
+
+			if eventLog.GetLastSequence() != 3 {
+				t.Errorf("Expected the log to end at sequence 3, got %d", eventLog.GetLastSequence())
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != last || eventLog.TornBytes() == 0 {
+				t.Errorf("Expected the torn record truncated to %d bytes, got %d (%d torn)", last, info.Size(), eventLog.TornBytes())
+			}
+
+			appendCross(t, eventLog, "MSFT", 3, 4, 2, "")
+			replayed := replayAll(t, eventLog)
+			if len(replayed) != 6 {
+				t.Fatalf("Expected 6 events after appending past the tear, got %d", len(replayed))
+			}
+			if fill, ok := replayed[5].(*events.FillEvent); !ok || fill.SequenceNum != 6 || fill.Symbol != "MSFT" {
+				t.Errorf("Expected the MSFT fill at sequence 6, got %+v", replayed[5])
+			}
+		})
+	}
+}
+
+// TestJournal_DamageIsNotTorn verifies a bad record with records after it
+// is left for replay to report, not truncated away.
+func TestJournal_DamageIsNotTorn(t *testing.T) {
+	path := tamperedLog(t)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventLog := openLogAt(t, path)
+	defer eventLog.Close()
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eventLog.TornBytes() != 0 || !bytes.Equal(before, after) || eventLog.GetLastSequence() != 6 {
+		t.Errorf("Expected the damaged log opened unchanged up to 6, got %d torn, up to %d", eventLog.TornBytes(), eventLog.GetLastSequence())
+	}
+}
+
+// TestJournal_VerifyReportsEverything verifies Verify reports checksum
+// damage, undecodable records and a torn tail in one pass, without
+// changing the log.
+func TestJournal_VerifyReportsEverything(t *testing.T) {
+	path := tamperedLog(t)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torn := append(append([]byte(nil), data...), 0xEB, 0, 0, 1, 0, 1, 0, 0)
+	if err := os.WriteFile(path, torn, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := events.Verify(path)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.Records != 6 || report.FirstSeq != 1 || report.LastSeq != 6 || report.Files != 1 {
+		t.Errorf("Expected records 1-6 in one file, got %+v", report)
+	}
+	if len(report.Damage) != 1 || report.Damage[0].Seq != 5 || !errors.Is(report.Damage[0], events.ErrChecksumMismatch) {
+		t.Errorf("Expected checksum damage at sequence 5, got %v", report.Damage)
+	}
+	if report.TornBytes != 8 || report.OK() {
+		t.Errorf("Expected an 8-byte torn tail and a failed verification, got %d, ok %v", report.TornBytes, report.OK())
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, torn) {
+		t.Error("Expected Verify to leave the log unchanged")
+	}
+
+	clean, err := events.Verify(copyFixture(t, "events_v1.log"))
+	if err != nil || !clean.OK() || clean.Records != 4 {
+		t.Errorf("Expected the v1 fixture verified clean, got %+v, %v", clean, err)
+	}
+}
+
+// TestJournal_TruncatesTornGobRecord verifies a log from before framing
+// also recovers from a torn final record.
+func TestJournal_TruncatesTornGobRecord(t *testing.T) {
+	path := copyFixture(t, "events_v1.log")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-5], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	eventLog := openLogAt(t, path)
+	defer eventLog.Close()
+	if eventLog.GetLastSequence() != 3 || eventLog.TornBytes() == 0 {
+		t.Fatalf("Expected the log to end at sequence 3 after a tear, got %d (%d torn)", eventLog.GetLastSequence(), eventLog.TornBytes())
+	}
+	appendCross(t, eventLog, "MSFT", 10, 11, 5, "")
+	if replayed := replayAll(t, eventLog); len(replayed) != 6 {
+		t.Errorf("Expected 6 events, got %d", len(replayed))
+	}
+}
+
+// TestJournal_OpenReadOnlyLeavesTornTail verifies a log opened read-only
+// reads up to a torn final record without truncating it, and refuses
+// appends.
+func TestJournal_OpenReadOnlyLeavesTornTail(t *testing.T) {
+	path, last := tornLog(t, func(data []byte, last int) []byte { return data[:last+3] })
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventLog, err := events.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+	if eventLog.GetLastSequence() != 3 {
+		t.Errorf("Expected the log to end at sequence 3, got %d", eventLog.GetLastSequence())
+	}
+	var scanned int
+	if err := eventLog.Scan(func(seq uint64, event interface{}) error {
+		scanned++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 3 {
+		t.Errorf("Expected 3 events scanned before the tear, got %d", scanned)
+	}
+	if _, err := eventLog.Append(&events.OrderCancelledEvent{OrderID: 1, Symbol: "AAPL"}); !errors.Is(err, events.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Append, got %v", err)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) || int64(len(after)) == last {
+		t.Error("Expected the torn record left on disk")
+	}
+}
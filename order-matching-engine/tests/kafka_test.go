@@ -0,0 +1,433 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/kafka"
+)
+
+// ============================================================================
+// KAFKA SINK
+// ============================================================================
+
+// producedRecord is a record as the fake broker decoded it.
+type producedRecord struct {
+	key     string
+	value   []byte
+	headers map[string]string
+}
+
+// fakeBroker is a single-node Kafka cluster that answers Metadata v1 and
+// Produce v3, decoding and keeping every record batch.
+type fakeBroker struct {
+	t          *testing.T
+	ln         net.Listener
+	partitions int
+
+	mu         sync.Mutex
+	logs       [][]producedRecord // By partition
+	failNext   int                // Produce requests still to answer with NOT_LEADER
+	badBatches int                // Record batches failing their CRC
+}
+
+func startFakeBroker(t *testing.T, partitions int) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, ln: ln, partitions: partitions, logs: make([][]producedRecord, partitions)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) addr() string { return b.ln.Addr().String() }
+
+// seqs returns the seq headers of a partition's records, in order.
+func (b *fakeBroker) seqs(partition int) []uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var seqs []uint64
+	for _, record := range b.logs[partition] {
+		seq, _ := strconv.ParseUint(record.headers["seq"], 10, 64)
+		seqs = append(seqs, seq)
+	}
+	return seqs
+}
+
+// records returns every record, deduplicated by seq.
+func (b *fakeBroker) records() map[uint64]producedRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bySeq := make(map[uint64]producedRecord)
+	for _, log := range b.logs {
+		for _, record := range log {
+			seq, _ := strconv.ParseUint(record.headers["seq"], 10, 64)
+			bySeq[seq] = record
+		}
+	}
+	return bySeq
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var size int32
+		if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+			return
+		}
+		request := make([]byte, size)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return
+		}
+		r := bytes.NewReader(request)
+		apiKey, version, correlation := readInt16(r), readInt16(r), readInt32(r)
+		readString(r) // client_id
+
+		var body []byte
+		switch {
+		case apiKey == 3 && version == 1:
+			body = b.metadata(r)
+		case apiKey == 0 && version == 3:
+			body = b.produce(r)
+		default:
+			b.t.Errorf("Unexpected request: api %d v%d", apiKey, version)
+			return
+		}
+		response := binary.BigEndian.AppendUint32(nil, uint32(4+len(body)))
+		response = binary.BigEndian.AppendUint32(response, uint32(correlation))
+		if _, err := conn.Write(append(response, body...)); err != nil {
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) metadata(r *bytes.Reader) []byte {
+	readInt32(r)
+	topic := readString(r)
+	host, portStr, _ := net.SplitHostPort(b.addr())
+	port, _ := strconv.Atoi(portStr)
+
+	out := binary.BigEndian.AppendUint32(nil, 1) // brokers
+	out = binary.BigEndian.AppendUint32(out, 0)  // node_id
+	out = appendString(out, host)
+	out = binary.BigEndian.AppendUint32(out, uint32(port))
+	out = binary.BigEndian.AppendUint16(out, 0xFFFF) // rack: null
+	out = binary.BigEndian.AppendUint32(out, 0)      // controller_id
+	out = binary.BigEndian.AppendUint32(out, 1)      // topics
+	out = binary.BigEndian.AppendUint16(out, 0)
+	out = appendString(out, topic)
+	out = append(out, 0) // is_internal
+	out = binary.BigEndian.AppendUint32(out, uint32(b.partitions))
+	for i := 0; i < b.partitions; i++ {
+		out = binary.BigEndian.AppendUint16(out, 0)
+		out = binary.BigEndian.AppendUint32(out, uint32(i))
+		out = binary.BigEndian.AppendUint32(out, 0) // leader
+		out = binary.BigEndian.AppendUint32(out, 1) // replicas
+		out = binary.BigEndian.AppendUint32(out, 0)
+		out = binary.BigEndian.AppendUint32(out, 1) // isr
+		out = binary.BigEndian.AppendUint32(out, 0)
+	}
+	return out
+}
+
+func (b *fakeBroker) produce(r *bytes.Reader) []byte {
+	readString(r) // transactional_id
+	if acks := readInt16(r); acks != -1 {
+		b.t.Errorf("Expected acks=all, got %d", acks)
+	}
+	readInt32(r) // timeout
+	readInt32(r) // topics
+	topic := readString(r)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	code := uint16(0)
+	if b.failNext > 0 {
+		b.failNext--
+		code = 6 // NOT_LEADER_FOR_PARTITION
+	}
+
+	n := readInt32(r)
+	out := binary.BigEndian.AppendUint32(nil, 1)
+	out = appendString(out, topic)
+	out = binary.BigEndian.AppendUint32(out, uint32(n))
+	for i := int32(0); i < n; i++ {
+		partition := readInt32(r)
+		batch := make([]byte, readInt32(r))
+		io.ReadFull(r, batch)
+		records, ok := decodeRecordBatch(batch)
+		if !ok {
+			b.badBatches++
+		} else if code == 0 {
+			b.logs[partition] = append(b.logs[partition], records...)
+		}
+		out = binary.BigEndian.AppendUint32(out, uint32(partition))
+		out = binary.BigEndian.AppendUint16(out, code)
+		out = binary.BigEndian.AppendUint64(out, 0)
+		out = binary.BigEndian.AppendUint64(out, math.MaxUint64)
+	}
+	return binary.BigEndian.AppendUint32(out, 0) // throttle_time_ms
+}
+
+// decodeRecordBatch decodes a v2 record batch, checking its CRC-32C.
+func decodeRecordBatch(batch []byte) ([]producedRecord, bool) {
+	if len(batch) < 61 || batch[16] != 2 {
+		return nil, false
+	}
+	crc := binary.BigEndian.Uint32(batch[17:])
+	if crc32.Checksum(batch[21:], crc32.MakeTable(crc32.Castagnoli)) != crc {
+		return nil, false
+	}
+	count := int(binary.BigEndian.Uint32(batch[57:]))
+	r := bytes.NewReader(batch[61:])
+	records := make([]producedRecord, 0, count)
+	for i := 0; i < count; i++ {
+		binary.ReadVarint(r) // length
+		r.ReadByte()         // attributes
+		binary.ReadVarint(r) // timestamp delta
+		if delta, _ := binary.ReadVarint(r); delta != int64(i) {
+			return nil, false
+		}
+		record := producedRecord{key: string(readVarBytes(r)), value: readVarBytes(r), headers: make(map[string]string)}
+		headers, _ := binary.ReadVarint(r)
+		for h := int64(0); h < headers; h++ {
+			key := string(readVarBytes(r))
+			record.headers[key] = string(readVarBytes(r))
+		}
+		records = append(records, record)
+	}
+	return records, r.Len() == 0
+}
+
+func readInt16(r *bytes.Reader) int16 {
+	var v int16
+	binary.Read(r, binary.BigEndian, &v)
+	return v
+}
+
+func readInt32(r *bytes.Reader) int32 {
+	var v int32
+	binary.Read(r, binary.BigEndian, &v)
+	return v
+}
+
+func readString(r *bytes.Reader) string {
+	n := readInt16(r)
+	if n < 0 {
+		return ""
+	}
+	s := make([]byte, n)
+	io.ReadFull(r, s)
+	return string(s)
+}
+
+func readVarBytes(r *bytes.Reader) []byte {
+	n, _ := binary.ReadVarint(r)
+	if n < 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	io.ReadFull(r, b)
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// TestKafka_PartitionMatchesJavaClient verifies keys are partitioned with
+// the murmur2 hash of Kafka's own clients.
+func TestKafka_PartitionMatchesJavaClient(t *testing.T) {
+	// Utils.murmur2 results from Kafka's test suite
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, hash := range cases {
+		want := int32(hash&0x7fffffff) % math.MaxInt32
+		if got := kafka.Partition([]byte(key), math.MaxInt32); got != want {
+			t.Errorf("Partition(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+// appendSymbolEvents appends a new order for each symbol in turn, then a
+// risk limit change, which has no symbol.
+func appendSymbolEvents(t *testing.T, eventLog *events.EventLog, symbols ...string) {
+	t.Helper()
+	for _, symbol := range symbols {
+		_, err := eventLog.Append(&events.NewOrderEvent{
+			Event:    events.Event{Type: events.EventTypeNewOrder, Timestamp: time.Now().UnixNano()},
+			OrderID:  eventLog.GetLastSequence() + 1,
+			Symbol:   symbol,
+			Price:    15000,
+			Quantity: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := eventLog.Append(&events.RiskLimitsEvent{
+		Event:     events.Event{Type: events.EventTypeRiskLimits, Timestamp: time.Now().UnixNano()},
+		AccountID: "TRADER1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitProduced waits until the broker holds every event up to seq.
+func waitProduced(t *testing.T, broker *fakeBroker, seq uint64) map[uint64]producedRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records := broker.records()
+		missing := false
+		for s := uint64(1); s <= seq; s++ {
+			if _, ok := records[s]; !ok {
+				missing = true
+			}
+		}
+		if !missing {
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for events up to %d, broker has %d", seq, len(records))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestKafka_SinkMirrorsLogBySymbol verifies every logged event reaches the
+// topic keyed by symbol, a symbol's events in order in one partition, after
+// a failed batch is retried; and a restarted sink resumes from its offset.
+func TestKafka_SinkMirrorsLogBySymbol(t *testing.T) {
+	broker := startFakeBroker(t, 3)
+	broker.failNext = 1
+	eventLog := openLog(t)
+	offsets := filepath.Join(t.TempDir(), "events.log.kafka-offset")
+
+	producer, err := kafka.NewProducer(kafka.ProducerConfig{Brokers: []string{broker.addr()}, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	newSink := func() *kafka.Sink {
+		sink, err := kafka.NewSink(eventLog, producer, kafka.SinkConfig{
+			Topic:      "engine-events",
+			Shard:      0,
+			BatchSize:  4,
+			OffsetPath: offsets,
+			Backoff:    time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink.Start()
+		return sink
+	}
+
+	// Events logged before the sink starts, and after
+	appendSymbolEvents(t, eventLog, "AAPL", "MSFT", "AAPL", "GOOGL")
+	sink := newSink()
+	appendSymbolEvents(t, eventLog, "MSFT", "AAPL", "TSLA", "AAPL")
+	last := eventLog.GetLastSequence()
+	records := waitProduced(t, broker, last)
+
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := sink.Stats(); stats.Failures != 1 || stats.Lag != 0 || stats.Produced != last {
+		t.Errorf("Expected one retried batch and nothing left, got %+v", stats)
+	}
+	if data, _ := os.ReadFile(offsets); strings.TrimSpace(string(data)) != strconv.FormatUint(last, 10) {
+		t.Errorf("Expected offset %d saved, got %q", last, data)
+	}
+	broker.mu.Lock()
+	if broker.badBatches != 0 {
+		t.Errorf("Expected every batch to pass its CRC, %d failed", broker.badBatches)
+	}
+	broker.mu.Unlock()
+
+	err = eventLog.Replay(func(seq uint64, event interface{}) error {
+		record := records[seq]
+		if record.key != events.SymbolOf(event) || record.headers["type"] != events.TypeOf(event).String() ||
+			record.headers["codec"] != "protobuf" || record.headers["shard"] != "0" {
+			t.Errorf("Event %d produced as key %q headers %v", seq, record.key, record.headers)
+		}
+		decoded, err := events.ProtobufCodec{}.Unmarshal(events.TypeOf(event), record.value)
+		if err != nil {
+			return err
+		}
+		if events.SymbolOf(decoded) != events.SymbolOf(event) || events.TimestampOf(decoded) != events.TimestampOf(event) {
+			t.Errorf("Event %d decodes to %+v, logged %+v", seq, decoded, event)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A symbol's events share a partition, in sequence order
+	partitionOf := make(map[string]int32)
+	for _, record := range records {
+		p := kafka.Partition([]byte(record.key), 3)
+		if existing, ok := partitionOf[record.key]; ok && existing != p {
+			t.Errorf("Symbol %q split across partitions", record.key)
+		}
+		partitionOf[record.key] = p
+	}
+	for p := 0; p < 3; p++ {
+		seqs := broker.seqs(p)
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] <= seqs[i-1] {
+				t.Errorf("Partition %d out of order: %v", p, seqs)
+				break
+			}
+		}
+	}
+
+	// Restarted: only what was logged since
+	appendSymbolEvents(t, eventLog, "AAPL")
+	sink = newSink()
+	waitProduced(t, broker, eventLog.GetLastSequence())
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for p := 0; p < 3; p++ {
+		total += len(broker.seqs(p))
+	}
+	if total != int(eventLog.GetLastSequence()) {
+		t.Errorf("Expected each event produced once, got %d records for %d events", total, eventLog.GetLastSequence())
+	}
+}
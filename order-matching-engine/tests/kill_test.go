@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// ACCOUNT KILL SWITCH AND MASS CANCEL
+// ============================================================================
+
+// TestKill_BlocksNewOrders verifies an operator kill rejects the account's
+// orders, is reported as the operator's, and keeps its first reason.
+func TestKill_BlocksNewOrders(t *testing.T) {
+	checker := risk.NewChecker(risk.DefaultConfig())
+	var events []risk.Event
+	checker.OnEvent(func(e risk.Event) { events = append(events, e) })
+
+	if !checker.Kill("T1", "runaway algo") {
+		t.Fatal("Expected the kill switch to trip")
+	}
+	if checker.Kill("T1", "again") {
+		t.Error("Expected a second kill not to trip it again")
+	}
+	if result := checker.Check(limit(orders.SideBuy, 15000, 10)); result.Passed {
+		t.Error("Expected orders from a killed account to be rejected")
+	}
+	if killed, reason := checker.IsKilled("T1"); !killed || reason != "runaway algo" {
+		t.Errorf("Expected the first reason to stick, got %q", reason)
+	}
+	if len(events) != 1 || events[0].Type != risk.EventKillSwitchTripped || !events[0].Operator {
+		t.Errorf("Expected one operator kill event, got %+v", events)
+	}
+
+	other := limit(orders.SideBuy, 15000, 10)
+	other.AccountID = "T2"
+	if result := checker.Check(other); !result.Passed {
+		t.Errorf("Expected other accounts to trade, got %s", result.Reason)
+	}
+}
+
+// TestKill_MassCancelsAccountOrders verifies an account mass cancel takes
+// the account's resting orders in every symbol off the book, through the
+// ring buffer, and leaves other accounts' orders alone.
+func TestKill_MassCancelsAccountOrders(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+	hub := dropcopy.NewHub(100)
+	feed := hub.Subscribe("T1")
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.OnExecution(hub.PublishExecution)
+	run.processor.Start()
+	defer run.processor.Shutdown()
+
+	aapl := run.order(limit(orders.SideBuy, 15000, 100))
+	msft := limit(orders.SideSell, 30000, 50)
+	msft.Symbol = "MSFT"
+	run.order(msft)
+	other := limit(orders.SideBuy, 14900, 100)
+	other.AccountID = "T2"
+	run.order(other)
+	drain(feed)
+
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeMassCancel, AccountID: "T1", Reason: "account killed"})
+	if !response.Success || len(response.Cancelled) != 2 ||
+		response.Cancelled[0].ID != aapl.ID || response.Cancelled[1].ID != msft.ID {
+		t.Fatalf("Expected both T1 orders cancelled in ID order, got %+v", response)
+	}
+	if len(engine.OpenOrders("T1", "")) != 0 {
+		t.Error("Expected T1 to have no resting orders")
+	}
+	if open := engine.OpenOrders("T2", ""); len(open) != 1 || open[0].ID != other.ID {
+		t.Errorf("Expected T2's order to keep resting, got %+v", open)
+	}
+
+	messages := drain(feed)
+	if len(messages) != 2 {
+		t.Fatalf("Expected two cancel reports, got %+v", messages)
+	}
+	for _, msg := range messages {
+		if msg.Execution.ExecType != orders.ExecTypeCancelled || msg.Execution.Text != "account killed" {
+			t.Errorf("Expected a cancel with the kill reason, got %+v", msg.Execution)
+		}
+	}
+}
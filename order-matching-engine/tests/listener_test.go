@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/listener"
+)
+
+// ============================================================================
+// HTTP LISTENERS
+// ============================================================================
+
+// testCert is a certificate and its key, written as PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// issueCert creates a certificate for name, signed by ca (self-signed if
+// nil), and writes it to dir.
+func issueCert(t *testing.T, dir, name string, ca *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued := &testCert{cert: cert, key: key, certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+".key")}
+	if err := os.WriteFile(issued.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(issued.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return issued
+}
+
+// serveListeners registers a route per part, binds every listener on a
+// free local port and serves them until the test ends.
+func serveListeners(t *testing.T, listeners []*listener.Listener) {
+	t.Helper()
+	for path, role := range map[string]string{"/order": listener.Trading, "/book": listener.MarketData, "/admin/audit": listener.Admin} {
+		role := role
+		listener.For(listeners, role).Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, role)
+		})
+	}
+	for _, l := range listeners {
+		l.ServeWith(l.Mux)
+	}
+	if err := listener.ListenAll(listeners); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listeners {
+		server := l.Server
+		go l.Serve()
+		t.Cleanup(func() { server.Close() })
+	}
+}
+
+// get returns the status and body of a GET, or the error.
+func get(client *http.Client, url string) (int, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+// TestListener_RouteSplitting verifies each part's routes are answered only
+// on the listener serving it, and a part without an address is served on
+// the trading listener.
+func TestListener_RouteSplitting(t *testing.T) {
+	set := listener.Defaults()
+	set.Trading.Addr = "127.0.0.1:0"
+	set.Admin.Addr = "127.0.0.1:0"
+	listeners, err := listener.New(set, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("Expected a trading and an admin listener, got %d", len(listeners))
+	}
+	trading, admin := listeners[0], listeners[1]
+	if strings.Join(trading.Roles, ",") != "trading,market data" || strings.Join(admin.Roles, ",") != "admin" {
+		t.Fatalf("Expected market data on the trading listener, got %v and %v", trading.Roles, admin.Roles)
+	}
+	if listener.For(listeners, listener.MarketData) != trading || listener.For(listeners, listener.Admin) != admin {
+		t.Fatal("Expected For to find the listener serving each part")
+	}
+	if trading.Config.WriteTimeout != 10*time.Second {
+		t.Errorf("Expected the trading listener's default write timeout, got %v", trading.Config.WriteTimeout)
+	}
+	serveListeners(t, listeners)
+
+	for _, c := range []struct {
+		listener *listener.Listener
+		path     string
+		status   int
+	}{
+		{trading, "/order", http.StatusOK},
+		{trading, "/book", http.StatusOK},
+		{trading, "/admin/audit", http.StatusNotFound},
+		{admin, "/admin/audit", http.StatusOK},
+		{admin, "/order", http.StatusNotFound},
+		{admin, "/book", http.StatusNotFound},
+	} {
+		status, _, err := get(http.DefaultClient, "http://"+c.listener.Addr().String()+c.path)
+		if err != nil || status != c.status {
+			t.Errorf("%s on %s: expected %d, got %d (%v)", c.path, c.listener.Roles[0], c.status, status, err)
+		}
+	}
+
+	// Without addresses, everything is on the port
+	listeners, err = listener.New(listener.Defaults(), 8080)
+	if err != nil || len(listeners) != 1 || listeners[0].Config.Addr != ":8080" || len(listeners[0].Roles) != 3 {
+		t.Errorf("Expected one listener on :8080 serving every part, got %+v (%v)", listeners, err)
+	}
+}
+
+// TestListener_TLSConfig verifies certificates and keys are needed
+// together and must match, a client CA needs them, and TLS for a part
+// served on the trading listener is refused.
+func TestListener_TLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, dir, "ca", nil, true)
+	server := issueCert(t, dir, "server", ca, false)
+	other := issueCert(t, dir, "other", ca, false)
+
+	for _, c := range []struct {
+		name   string
+		config listener.Config
+		err    string // Empty if the config is valid
+	}{
+		{"plain HTTP", listener.Config{}, ""},
+		{"certificate and key", listener.Config{CertFile: server.certFile, KeyFile: server.keyFile}, ""},
+		{"client CA", listener.Config{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile}, ""},
+		{"certificate without key", listener.Config{CertFile: server.certFile}, "needed together"},
+		{"key without certificate", listener.Config{KeyFile: server.keyFile}, "needed together"},
+		{"another certificate's key", listener.Config{CertFile: server.certFile, KeyFile: other.keyFile}, "does not match"},
+		{"client CA without certificate", listener.Config{ClientCAFile: ca.certFile}, "needs a certificate"},
+		{"client CA not PEM", listener.Config{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: server.keyFile}, "no certificates"},
+	} {
+		config, err := c.config.TLSConfig()
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: expected no error, got %v", c.name, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.err, err)
+		case c.err == "" && (config == nil) != (c.config.CertFile == ""):
+			t.Errorf("%s: expected TLS only with a certificate, got %+v", c.name, config)
+		}
+	}
+
+	// A part without an address of its own can't have TLS of its own
+	for _, set := range []listener.Set{
+		{MarketData: listener.Config{CertFile: server.certFile, KeyFile: server.keyFile}},
+		{Admin: listener.Config{ClientCAFile: ca.certFile}},
+	} {
+		if _, err := listener.New(set, 8080); err == nil || !strings.Contains(err.Error(), "needs an address of its own") {
+			t.Errorf("Expected TLS without an address refused, got %v", err)
+		}
+	}
+	set := listener.Set{Admin: listener.Config{Addr: ":0", CertFile: server.certFile}}
+	if _, err := listener.New(set, 8080); err == nil || !strings.HasPrefix(err.Error(), "admin listener TLS") {
+		t.Errorf("Expected the admin listener's TLS error, got %v", err)
+	}
+}
+
+// TestListener_ClientCertificates verifies a listener with a client CA
+// refuses clients without a certificate issued by it, while the others
+// stay plain HTTP.
+func TestListener_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, dir, "ca", nil, true)
+	server := issueCert(t, dir, "server", ca, false)
+	alice := issueCert(t, dir, "alice", ca, false)
+	rogueCA := issueCert(t, dir, "rogue-ca", nil, true)
+	mallory := issueCert(t, dir, "mallory", rogueCA, false)
+
+	set := listener.Defaults()
+	set.Trading.Addr = "127.0.0.1:0"
+	set.Admin = listener.Config{Addr: "127.0.0.1:0", CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile}
+	listeners, err := listener.New(set, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trading, admin := listeners[0], listeners[1]
+	if trading.TLS != nil || admin.TLS == nil || admin.TLS.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatal("Expected plain HTTP for trading and mutual TLS for admin")
+	}
+	serveListeners(t, listeners)
+	if !strings.HasSuffix(admin.String(), "(https, client certificates required)") {
+		t.Errorf("Expected the admin listener described as mutual TLS, got %q", admin.String())
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientWith := func(cert *testCert) *http.Client {
+		config := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			config.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.cert.Raw}, PrivateKey: cert.key}}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	}
+	adminURL := "https://" + admin.Addr().String() + "/admin/audit"
+
+	if status, body, err := get(clientWith(alice), adminURL); err != nil || status != http.StatusOK || body != listener.Admin {
+		t.Errorf("Expected alice's certificate accepted, got %d %q (%v)", status, body, err)
+	}
+	for name, cert := range map[string]*testCert{"no certificate": nil, "another CA's certificate": mallory} {
+		if _, _, err := get(clientWith(cert), adminURL); err == nil {
+			t.Errorf("%s: expected the TLS handshake to fail", name)
+		}
+	}
+	if status, body, _ := get(http.DefaultClient, "http://"+admin.Addr().String()+"/admin/audit"); status != http.StatusBadRequest || body == listener.Admin {
+		t.Errorf("Expected plain HTTP refused on the TLS listener, got %d %q", status, body)
+	}
+	if status, _, err := get(http.DefaultClient, "http://"+trading.Addr().String()+"/order"); err != nil || status != http.StatusOK {
+		t.Errorf("Expected plain HTTP on the trading listener, got %d (%v)", status, err)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/metrics"
+)
+
+// ============================================================================
+// PROMETHEUS METRICS
+// ============================================================================
+
+func scrape(r *metrics.Registry) string {
+	var buf bytes.Buffer
+	r.Write(&buf)
+	return buf.String()
+}
+
+func expectLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, output)
+		}
+	}
+}
+
+// TestMetrics_CountersAndGauges verifies counters by label and sampled
+// gauges are written in the text exposition format.
+func TestMetrics_CountersAndGauges(t *testing.T) {
+	r := metrics.NewRegistry()
+	orders := r.NewCounterVec("orders_total", "New orders by outcome.", "outcome")
+	orders.With("accepted").Add(3)
+	orders.With("rejected").Inc()
+	r.NewGaugeVecFunc("occupancy", "Ring buffer occupancy.", []string{"shard"}, func(emit func(float64, ...string)) {
+		emit(0.25, "0")
+		emit(0, "1")
+	})
+
+	expectLines(t, scrape(r),
+		"# HELP orders_total New orders by outcome.",
+		"# TYPE orders_total counter",
+		`orders_total{outcome="accepted"} 3`,
+		`orders_total{outcome="rejected"} 1`,
+		"# TYPE occupancy gauge",
+		`occupancy{shard="0"} 0.25`,
+		`occupancy{shard="1"} 0`,
+	)
+}
+
+// TestMetrics_HistogramBucketsAreCumulative verifies each bucket counts the
+// observations at or below its bound, with the sum and count after.
+func TestMetrics_HistogramBucketsAreCumulative(t *testing.T) {
+	r := metrics.NewRegistry()
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "path")
+	h := latency.With("/order")
+	for _, v := range []float64{0.05, 0.1, 0.5, 2} {
+		h.Observe(v)
+	}
+
+	expectLines(t, scrape(r),
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{path="/order",le="0.1"} 2`,
+		`latency_seconds_bucket{path="/order",le="1"} 3`,
+		`latency_seconds_bucket{path="/order",le="+Inf"} 4`,
+		`latency_seconds_sum{path="/order"} 2.65`,
+		`latency_seconds_count{path="/order"} 4`,
+	)
+}
+
+// TestMetrics_SequencerCountsFullBuffer verifies a claim refused by a full
+// ring buffer is counted, along with the spins it took.
+func TestMetrics_SequencerCountsFullBuffer(t *testing.T) {
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	seq := disruptor.NewSequencer(rb)
+	for i := 0; i < 64; i++ {
+		if _, err := seq.Next(); err != nil {
+			t.Fatalf("Claim %d failed: %v", i, err)
+		}
+	}
+	if rb.SequencerSpins() != 0 || rb.BufferFullRejections() != 0 {
+		t.Fatalf("Expected no spins with room, got %d spins, %d full", rb.SequencerSpins(), rb.BufferFullRejections())
+	}
+
+	if _, err := seq.Next(); err != disruptor.ErrBufferFull {
+		t.Fatalf("Expected ErrBufferFull, got %v", err)
+	}
+	if rb.BufferFullRejections() != 1 || rb.SequencerSpins() == 0 {
+		t.Errorf("Expected one full rejection with spins, got %d spins, %d full", rb.SequencerSpins(), rb.BufferFullRejections())
+	}
+}
+
+// TestMetrics_RingBufferStats verifies the ring buffer's introspection
+// counters: producer claims, failures and backoff while full, then the
+// consumer's lag, batches and high-water mark as it catches up.
+func TestMetrics_RingBufferStats(t *testing.T) {
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64, WaitStrategy: disruptor.WaitBlocking})
+	seq := disruptor.NewSequencer(rb)
+	responseCh := make(chan *disruptor.OrderResponse, 64)
+	for i := 0; i < 64; i++ {
+		s, err := seq.Next()
+		if err != nil {
+			t.Fatalf("Claim %d failed: %v", i, err)
+		}
+		seq.Publish(s, &disruptor.OrderRequest{Type: disruptor.RequestTypeOpenOrders, AccountID: "T1"}, responseCh)
+	}
+	if _, err := seq.Next(); err != disruptor.ErrBufferFull {
+		t.Fatalf("Expected ErrBufferFull, got %v", err)
+	}
+
+	stats := rb.Stats()
+	if stats.Claims != 64 || stats.FullRejections != 1 || stats.Lag != 64 || stats.Occupancy != 1 {
+		t.Errorf("Expected a full buffer with one refused claim, got %+v", stats)
+	}
+	if stats.ClaimFailureRate != 1.0/65 || stats.Backoff <= 0 || stats.WaitStrategy != "blocking" {
+		t.Errorf("Expected the refused claim's failure rate and backoff, got %+v", stats)
+	}
+
+	processor := disruptor.NewEventProcessor(rb, matching.NewEngine(), openLog(t))
+	processor.Start()
+	for i := 0; i < 64; i++ {
+		select {
+		case <-responseCh:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for response %d", i)
+		}
+	}
+	processor.Shutdown()
+
+	stats = rb.Stats()
+	if stats.Consumed != 64 || stats.Lag != 0 || stats.Occupancy != 0 {
+		t.Errorf("Expected the backlog consumed, got %+v", stats)
+	}
+	if stats.Batches != 1 || stats.AvgBatch != 64 || stats.MaxLag != 64 {
+		t.Errorf("Expected the backlog drained in one batch of 64, got %+v", stats)
+	}
+}
@@ -0,0 +1,278 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/migration"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+)
+
+// ============================================================================
+// SYMBOL MIGRATION BETWEEN SHARDS
+// ============================================================================
+
+// openLog opens an event log in a temporary directory.
+func openLog(t *testing.T) *events.EventLog {
+	t.Helper()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: filepath.Join(t.TempDir(), "events.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eventLog.Close() })
+	return eventLog
+}
+
+// TestMigration_BookKeepsQueueOrder verifies a migrated book trades on the
+// target exactly as it would have on the source, and the target never
+// reissues an imported order ID.
+func TestMigration_BookKeepsQueueOrder(t *testing.T) {
+	source := matching.NewEngine()
+	source.AddSymbol("AAPL")
+	first := limit(orders.SideSell, 15000, 100)
+	source.ProcessOrder(first)
+	source.ProcessOrder(limit(orders.SideSell, 15010, 100))
+	second := limit(orders.SideSell, 15000, 100)
+	source.ProcessOrder(second)
+
+	book, err := source.ExportSymbol("AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := matching.NewEngine()
+	target.AddSymbol("AAPL") // Empty book, as every shard starts with
+	if err := target.ImportSymbol("AAPL", book); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !reflect.DeepEqual(target.RestingOrders(), source.RestingOrders()) {
+		t.Fatal("Expected the imported book to match the source")
+	}
+
+	taker := limit(orders.SideBuy, 15000, 150)
+	result := target.ProcessOrder(taker)
+	if len(result.Fills) != 2 || result.Fills[0].MakerOrderID != first.ID || result.Fills[1].MakerOrderID != second.ID {
+		t.Errorf("Expected fills against %d then %d, got %+v", first.ID, second.ID, result.Fills)
+	}
+	if taker.ID <= second.ID {
+		t.Errorf("Expected a new order ID above the imported ones, got %d", taker.ID)
+	}
+
+	if err := target.ImportSymbol("AAPL", book); err == nil {
+		t.Error("Expected an import into a non-empty book to fail")
+	}
+}
+
+// TestMigration_ReleaseAndReplay verifies the source drops a released
+// symbol, and both shards' logs replay to the same state.
+func TestMigration_ReleaseAndReplay(t *testing.T) {
+	sourceLog, targetLog := openLog(t), openLog(t)
+	source := matching.NewEngine()
+	source.AddSymbol("AAPL")
+	target := matching.NewEngine()
+
+	src := startRun(t, source, sourceLog, settlement.NewClearingHouse(), nil, 0)
+	src.order(limit(orders.SideBuy, 14990, 100))
+	src.order(limit(orders.SideSell, 15010, 200))
+
+	exported := src.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeExportSymbol, Symbol: "AAPL"})
+	if !exported.Success || len(exported.Book) != 2 {
+		t.Fatalf("Expected 2 orders exported, got %+v", exported)
+	}
+
+	dst := startRun(t, target, targetLog, settlement.NewClearingHouse(), nil, 0)
+	imported := dst.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeImportSymbol, Symbol: "AAPL", Book: exported.Book, Shard: "shard-a"})
+	if !imported.Success {
+		t.Fatalf("Import failed: %v", imported.Error)
+	}
+	dst.order(limit(orders.SideBuy, 15010, 50)) // Trades on the target
+	src.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeReleaseSymbol, Symbol: "AAPL", Shard: "http://shard-b"})
+	src.processor.Shutdown()
+	dst.processor.Shutdown()
+
+	if source.GetOrderBook("AAPL") != nil {
+		t.Error("Expected the source to drop the released book")
+	}
+	if moved := source.MovedSymbols(); moved["AAPL"] != "http://shard-b" {
+		t.Errorf("Expected AAPL recorded as moved, got %v", moved)
+	}
+
+	for name, c := range map[string]struct {
+		eventLog *events.EventLog
+		live     *matching.Engine
+	}{"source": {sourceLog, source}, "target": {targetLog, target}} {
+		replayed := matching.NewEngine()
+		replayed.AddSymbol("AAPL")
+		replayer := matching.NewReplayer(replayed)
+		err := c.eventLog.Replay(func(seqNum uint64, event interface{}) error {
+			_, err := replayer.Apply(event)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("%s: replay failed: %v", name, err)
+		}
+		if !reflect.DeepEqual(withoutTimestamps(replayed.RestingOrders()), withoutTimestamps(c.live.RestingOrders())) ||
+			!reflect.DeepEqual(replayed.MovedSymbols(), c.live.MovedSymbols()) {
+			t.Errorf("%s: replay did not rebuild the books and moves", name)
+		}
+		if replayer.Counters().OrderID < c.live.IDCounters().OrderID {
+			t.Errorf("%s: expected replayed order IDs to reach %d, got %d",
+				name, c.live.IDCounters().OrderID, replayer.Counters().OrderID)
+		}
+	}
+}
+
+// TestMigration_SnapshotKeepsMoves verifies migrated symbols survive a
+// snapshot round trip, full image and delta alike.
+func TestMigration_SnapshotKeepsMoves(t *testing.T) {
+	dir := t.TempDir()
+	store, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+	engine.ProcessOrder(limit(orders.SideBuy, 14990, 100))
+
+	store.Write(&snapshot.Image{EventSeq: 1, Books: engine.RestingOrders(), Moved: engine.MovedSymbols()})
+	engine.ReleaseSymbol("AAPL", "http://shard-b")
+	store.Write(&snapshot.Image{EventSeq: 2, Books: engine.RestingOrders(), Moved: engine.MovedSymbols()})
+
+	reopened, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := reopened.Latest()
+	if img.Orders() != 0 || img.Moved["AAPL"] != "http://shard-b" {
+		t.Fatalf("Expected no orders and AAPL moved, got %d orders and %v", img.Orders(), img.Moved)
+	}
+
+	restored := matching.NewEngine()
+	restored.AddSymbol("AAPL")
+	restored.RestoreMoved(img.Moved)
+	if restored.GetOrderBook("AAPL") != nil {
+		t.Error("Expected a restored engine not to trade a moved symbol")
+	}
+}
+
+// TestMigration_GateHoldsThenForwards verifies a request for a paused
+// symbol waits, rather than failing, and learns where the symbol went.
+func TestMigration_GateHoldsThenForwards(t *testing.T) {
+	gate := migration.NewGate(time.Second)
+
+	if err := gate.Pause("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := gate.Enter("AAPL")
+		result <- err
+	}()
+
+	// Other symbols keep trading
+	leave, err := gate.Enter("MSFT")
+	if err != nil {
+		t.Fatalf("Expected MSFT to be admitted, got %v", err)
+	}
+	leave()
+
+	select {
+	case err := <-result:
+		t.Fatalf("Expected AAPL to wait, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	gate.Complete("AAPL", "http://shard-b")
+	var moved *migration.MovedError
+	if err := <-result; !errors.As(err, &moved) || moved.Target != "http://shard-b" {
+		t.Fatalf("Expected the held request to be sent to shard-b, got %v", err)
+	}
+
+	// Migrated back: trades here again
+	gate.Adopt("AAPL")
+	if leave, err := gate.Enter("AAPL"); err != nil {
+		t.Errorf("Expected AAPL admitted after it came back, got %v", err)
+	} else {
+		leave()
+	}
+}
+
+// TestMigration_PauseDrainsInFlight verifies the quiesce waits for admitted
+// requests, an aborted migration re-opens the symbol, and a wait that runs
+// out is reported as retryable.
+func TestMigration_PauseDrainsInFlight(t *testing.T) {
+	gate := migration.NewGate(200 * time.Millisecond)
+	leave, _ := gate.Enter("AAPL")
+
+	paused := make(chan error, 1)
+	go func() { paused <- gate.Pause("AAPL") }()
+	select {
+	case <-paused:
+		t.Fatal("Expected Pause to wait for the admitted request")
+	case <-time.After(50 * time.Millisecond):
+	}
+	leave()
+	if err := <-paused; err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+
+	if _, err := gate.Enter("AAPL"); !errors.Is(err, migration.ErrPaused) {
+		t.Errorf("Expected ErrPaused once the wait runs out, got %v", err)
+	}
+
+	gate.Resume("AAPL") // Transfer failed
+	if leave, err := gate.Enter("AAPL"); err != nil {
+		t.Errorf("Expected AAPL admitted after an abort, got %v", err)
+	} else {
+		leave()
+	}
+}
+
+// TestMigration_SendTransfersBook verifies the payload reaches the target's
+// import endpoint intact, and a refusal is reported.
+func TestMigration_SendTransfersBook(t *testing.T) {
+	var received migration.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != migration.ImportPath {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		if received.Symbol == "MSFT" {
+			http.Error(w, `{"error":"MSFT already has 3 resting orders"}`, http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+
+	payload := &migration.Payload{
+		Symbol:     "AAPL",
+		Source:     "shard-a",
+		Instrument: refdata.Instrument{Symbol: "AAPL", TickSize: 5, LotSize: 100},
+		Orders:     []orders.Order{*limit(orders.SideBuy, 15000, 100)},
+	}
+	if err := migration.Send(context.Background(), srv.Client(), srv.URL+"/", payload); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if !reflect.DeepEqual(received, *payload) {
+		t.Errorf("Expected %+v received, got %+v", *payload, received)
+	}
+
+	payload.Symbol = "MSFT"
+	if err := migration.Send(context.Background(), srv.Client(), srv.URL, payload); err == nil {
+		t.Error("Expected a refused import to fail")
+	}
+}
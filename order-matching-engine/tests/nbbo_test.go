@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// NBBO CONSOLIDATION (MULTI-VENUE)
+// ============================================================================
+
+func venueQuote(bid, bidSize, ask, askSize int64) marketdata.L1Quote {
+	return marketdata.L1Quote{Symbol: "AAPL", BidPrice: bid, BidSize: bidSize, AskPrice: ask, AskSize: askSize}
+}
+
+// TestNBBO_BestAcrossVenues verifies the NBBO takes the best price on each
+// side, sums size at it and attributes it to venues largest first.
+func TestNBBO_BestAcrossVenues(t *testing.T) {
+	c := marketdata.NewConsolidator(10)
+	c.Update("NYC", venueQuote(15010, 300, 15014, 200))
+	c.Update("CHI", venueQuote(15012, 100, 15014, 500))
+	c.Update("LDN", venueQuote(15012, 400, 15016, 100))
+
+	nbbo, ok := c.Latest("AAPL")
+	if !ok {
+		t.Fatal("Expected an NBBO")
+	}
+	if nbbo.BidPrice != 15012 || nbbo.BidSize != 500 || nbbo.AskPrice != 15014 || nbbo.AskSize != 700 {
+		t.Fatalf("Expected 150.12 x 500 / 150.14 x 700, got %+v", nbbo)
+	}
+	wantBids := []marketdata.VenueSize{{Venue: "LDN", Size: 400}, {Venue: "CHI", Size: 100}}
+	wantAsks := []marketdata.VenueSize{{Venue: "CHI", Size: 500}, {Venue: "NYC", Size: 200}}
+	for i := range wantBids {
+		if nbbo.BidVenues[i] != wantBids[i] || nbbo.AskVenues[i] != wantAsks[i] {
+			t.Fatalf("Expected venues %v / %v, got %v / %v", wantBids, wantAsks, nbbo.BidVenues, nbbo.AskVenues)
+		}
+	}
+	if nbbo.BestVenue(orders.SideBuy) != "CHI" || nbbo.BestVenue(orders.SideSell) != "LDN" {
+		t.Errorf("Expected to buy on CHI and sell on LDN, got %s and %s",
+			nbbo.BestVenue(orders.SideBuy), nbbo.BestVenue(orders.SideSell))
+	}
+}
+
+// TestNBBO_PublishOnlyOnChange verifies a venue quote that does not move
+// the NBBO publishes nothing.
+func TestNBBO_PublishOnlyOnChange(t *testing.T) {
+	c := marketdata.NewConsolidator(10)
+	updates := c.Subscribe("AAPL")
+
+	if !c.Update("NYC", venueQuote(15010, 300, 15014, 200)) {
+		t.Fatal("Expected the first quote to publish")
+	}
+	if c.Update("CHI", venueQuote(15005, 100, 15020, 100)) {
+		t.Error("Expected no update for a quote behind the NBBO on both sides")
+	}
+	if !c.Update("CHI", venueQuote(15010, 100, 15020, 100)) {
+		t.Error("Expected an update for size joining the best bid")
+	}
+	if len(updates) != 2 {
+		t.Errorf("Expected 2 updates delivered, got %d", len(updates))
+	}
+}
+
+// TestNBBO_LockedAndCrossed verifies a locked or crossed market across
+// venues is published and flagged.
+func TestNBBO_LockedAndCrossed(t *testing.T) {
+	c := marketdata.NewConsolidator(10)
+	c.Update("NYC", venueQuote(15010, 100, 15014, 100))
+	c.Update("CHI", venueQuote(15014, 100, 15018, 100))
+
+	nbbo, _ := c.Latest("AAPL")
+	if !nbbo.Locked() || nbbo.Crossed() {
+		t.Errorf("Expected a locked market, got %+v", nbbo)
+	}
+
+	c.Update("CHI", venueQuote(15016, 100, 15018, 100))
+	nbbo, _ = c.Latest("AAPL")
+	if !nbbo.Crossed() || nbbo.Locked() {
+		t.Errorf("Expected a crossed market, got %+v", nbbo)
+	}
+}
+
+// TestNBBO_Improvement verifies executions are measured against the far
+// side of the NBBO.
+func TestNBBO_Improvement(t *testing.T) {
+	nbbo := marketdata.NBBO{BidPrice: 15010, AskPrice: 15014}
+
+	if cents, ok := nbbo.Improvement(orders.SideBuy, 15012); !ok || cents != 2 {
+		t.Errorf("Expected a buy at 150.12 to improve 2 cents, got %d (%v)", cents, ok)
+	}
+	if cents, _ := nbbo.Improvement(orders.SideSell, 15008); cents != -2 {
+		t.Errorf("Expected a sell at 150.08 to trade through by 2 cents, got %d", cents)
+	}
+	if _, ok := (marketdata.NBBO{BidPrice: 15010}).Improvement(orders.SideBuy, 15012); ok {
+		t.Error("Expected no measurement without an offer")
+	}
+}
+
+// TestNBBO_EngineFeedsAndVenueLoss verifies the consolidator follows live
+// engine publishers and drops a venue's quotes when its feed closes.
+func TestNBBO_EngineFeedsAndVenueLoss(t *testing.T) {
+	nyc := marketdata.NewPublisher(10)
+	chi := marketdata.NewPublisher(10)
+	c := marketdata.NewConsolidator(10)
+	updates := c.Subscribe("AAPL")
+	c.AddVenue("NYC", nyc.SubscribeAllL1().C)
+	c.AddVenue("CHI", chi.SubscribeAllL1().C)
+
+	next := func() marketdata.NBBO {
+		t.Helper()
+		select {
+		case nbbo := <-updates:
+			return nbbo
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for an NBBO update")
+			return marketdata.NBBO{}
+		}
+	}
+
+	nyc.PublishL1(venueQuote(15010, 300, 15014, 200))
+	next()
+	chi.PublishL1(venueQuote(15012, 100, 15016, 100))
+	if nbbo := next(); nbbo.BestVenue(orders.SideSell) != "CHI" {
+		t.Fatalf("Expected CHI on the best bid, got %+v", nbbo)
+	}
+
+	// CHI goes away: its bid must not linger
+	chi.Close()
+	nbbo := next()
+	if nbbo.BidPrice != 15010 || nbbo.BestVenue(orders.SideSell) != "NYC" {
+		t.Errorf("Expected the NBBO back on NYC at 150.10, got %+v", nbbo)
+	}
+	if _, exists := c.VenueQuotes("AAPL")["CHI"]; exists {
+		t.Error("Expected CHI's quotes dropped")
+	}
+
+	nyc.Close()
+	c.Wait()
+	if nbbo, _ := c.Latest("AAPL"); nbbo.BidPrice != 0 || nbbo.AskPrice != 0 {
+		t.Errorf("Expected an empty NBBO with no venues, got %+v", nbbo)
+	}
+	c.Close()
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// OPEN ORDERS PER ACCOUNT
+// ============================================================================
+
+// openIDs returns the order IDs of an account's open orders, in order.
+func openIDs(engine *matching.Engine, account, symbol string) []uint64 {
+	var ids []uint64
+	for _, order := range engine.OpenOrders(account, symbol) {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+func sameIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestOpenOrders_IndexFollowsTheBook verifies the account index tracks
+// orders as they rest, fill, replenish, get replaced and get cancelled.
+func TestOpenOrders_IndexFollowsTheBook(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+
+	bid := limit(orders.SideBuy, 14990, 100)
+	engine.ProcessOrder(bid)
+	ask := limit(orders.SideSell, 15010, 200)
+	engine.ProcessOrder(ask)
+	iceberg := limit(orders.SideSell, 15020, 300)
+	iceberg.DisplayQty = 100
+	engine.ProcessOrder(iceberg)
+	msft := &orders.Order{Symbol: "MSFT", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 30000, Quantity: 50, AccountID: "T1"}
+	engine.ProcessOrder(msft)
+	other := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 14980, Quantity: 100, AccountID: "T2"}
+	engine.ProcessOrder(other)
+
+	if ids := openIDs(engine, "T1", ""); !sameIDs(ids, []uint64{bid.ID, ask.ID, iceberg.ID, msft.ID}) {
+		t.Fatalf("Expected T1's four orders by symbol, got %v", ids)
+	}
+	if ids := openIDs(engine, "T1", "MSFT"); !sameIDs(ids, []uint64{msft.ID}) {
+		t.Errorf("Expected only the MSFT order, got %v", ids)
+	}
+	if ids := openIDs(engine, "T2", ""); !sameIDs(ids, []uint64{other.ID}) {
+		t.Errorf("Expected T2's one order, got %v", ids)
+	}
+
+	// A partial fill leaves the ask open, with the fill reported
+	buy := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15010, Quantity: 50, AccountID: "T2"}
+	engine.ProcessOrder(buy)
+	open := engine.OpenOrders("T1", "AAPL")
+	if len(open) != 3 || open[1].FilledQty != 50 {
+		t.Errorf("Expected the ask open with 50 filled, got %+v", open)
+	}
+
+	// Filling the rest, and the iceberg's first slice, removes only the ask
+	buy = &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15020, Quantity: 250, AccountID: "T2"}
+	engine.ProcessOrder(buy)
+	if ids := openIDs(engine, "T1", "AAPL"); !sameIDs(ids, []uint64{bid.ID, iceberg.ID}) {
+		t.Errorf("Expected the bid and replenished iceberg, got %v", ids)
+	}
+
+	// Replaced orders stay listed, cancelled ones do not
+	if _, err := engine.ReplaceOrder(matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: bid.ID, Side: orders.SideBuy, AccountID: "T1", Price: 14995, Quantity: 100,
+	}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	engine.CancelOrder("AAPL", iceberg.ID)
+	engine.CancelOrder("MSFT", msft.ID)
+	open = engine.OpenOrders("T1", "")
+	if len(open) != 1 || open[0].ID != bid.ID || open[0].Price != 14995 {
+		t.Errorf("Expected only the replaced bid, got %+v", open)
+	}
+
+	// The list is a copy
+	open[0].Quantity = 1
+	if bid.Quantity != 100 {
+		t.Error("Expected OpenOrders to return copies")
+	}
+}
+
+// TestOpenOrders_Request verifies the query is answered on the processor,
+// after the requests sequenced before it, for restored books too.
+func TestOpenOrders_Request(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	resting := []orders.Order{*limit(orders.SideBuy, 14990, 100)}
+	resting[0].ID = 7
+	if err := engine.RestoreOrders(map[string][]orders.Order{"AAPL": resting}); err != nil {
+		t.Fatal(err)
+	}
+	engine.RestoreIDCounters(matching.IDCounters{OrderID: 7})
+
+	run := startRun(t, engine, openLog(t), settlement.NewClearingHouse(), nil, 0)
+	placed := run.order(limit(orders.SideSell, 15010, 100))
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeOpenOrders, AccountID: "T1"})
+	run.processor.Shutdown()
+
+	if !response.Success || len(response.Open) != 2 || response.Open[0].ID != 7 || response.Open[1].ID != placed.ID {
+		t.Errorf("Expected the restored and placed orders, got %+v", response.Open)
+	}
+}
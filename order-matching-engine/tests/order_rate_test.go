@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// ORDER RATE RISK CHECK
+// ============================================================================
+
+// newRateChecker returns a checker allowing 3 orders per second, on a fake
+// clock the test moves.
+func newRateChecker(breachKill int) (*risk.Checker, *time.Time, *[]risk.Event) {
+	config := risk.DefaultConfig()
+	config.MaxOrderRate = 3
+	config.RateBreachKill = breachKill
+	checker := risk.NewChecker(config)
+	now := time.Unix(1800000000, 0)
+	checker.SetClock(func() time.Time { return now })
+	var fired []risk.Event
+	checker.OnEvent(func(e risk.Event) { fired = append(fired, e) })
+	return checker, &now, &fired
+}
+
+// TestOrderRate_SlidingWindow verifies the limit holds over any one-second
+// window, not per calendar second, and that rejected orders don't count.
+func TestOrderRate_SlidingWindow(t *testing.T) {
+	checker, now, fired := newRateChecker(0)
+	start := *now
+	send := func(offset time.Duration) bool {
+		*now = start.Add(offset)
+		return checker.Check(limit(orders.SideBuy, 15000, 10)).Passed
+	}
+
+	for _, tc := range []struct {
+		at   time.Duration
+		pass bool
+	}{
+		{0, true},
+		{200 * time.Millisecond, true},
+		{400 * time.Millisecond, true},
+		{900 * time.Millisecond, false}, // 3 in the last second
+		{950 * time.Millisecond, false},
+		{1000 * time.Millisecond, true}, // The first has aged out
+		{1100 * time.Millisecond, false},
+		{1200 * time.Millisecond, true},
+	} {
+		if passed := send(tc.at); passed != tc.pass {
+			t.Errorf("Order at %v: expected passed=%v", tc.at, tc.pass)
+		}
+	}
+
+	other := limit(orders.SideBuy, 15000, 10)
+	other.AccountID = "T2"
+	if !checker.Check(other).Passed {
+		t.Error("Expected other accounts to have their own window")
+	}
+
+	// One breach per run of rejections
+	if len(*fired) != 2 || (*fired)[0].Type != risk.EventOrderRateBreached || (*fired)[0].Limit != 3 {
+		t.Errorf("Expected two breach events, got %+v", *fired)
+	}
+	if checker.RateBreaches("T1") != 2 {
+		t.Errorf("Expected 2 breaches, got %d", checker.RateBreaches("T1"))
+	}
+	if killed, _ := checker.IsKilled("T1"); killed {
+		t.Error("Expected no kill without RateBreachKill")
+	}
+}
+
+// TestOrderRate_BreachesTripKillSwitch verifies repeated breaches in a day
+// trip the kill switch, and a new trading day forgives earlier breaches.
+func TestOrderRate_BreachesTripKillSwitch(t *testing.T) {
+	checker, now, fired := newRateChecker(2)
+	burst := func() {
+		for i := 0; i < 5; i++ {
+			checker.Check(limit(orders.SideBuy, 15000, 10))
+		}
+		*now = now.Add(2 * time.Second)
+	}
+
+	burst()
+	checker.ResetRateBreaches() // Next trading day
+	burst()
+	if killed, _ := checker.IsKilled("T1"); killed {
+		t.Fatal("Expected one breach a day not to kill the account")
+	}
+	burst()
+	killed, reason := checker.IsKilled("T1")
+	if !killed || !strings.Contains(reason, "breached 2 times") {
+		t.Fatalf("Expected the second breach of the day to kill the account, got %q", reason)
+	}
+	last := (*fired)[len(*fired)-1]
+	if last.Type != risk.EventKillSwitchTripped || last.Check != risk.CheckOrderRate || last.Operator {
+		t.Errorf("Expected an order rate kill event, got %+v", last)
+	}
+
+	*now = now.Add(time.Minute)
+	if result := checker.Check(limit(orders.SideBuy, 15000, 10)); result.Passed {
+		t.Error("Expected a killed account to stay blocked once it slows down")
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// ORDER STATUS LOOKUP
+// ============================================================================
+
+// withClientID sets an order's client order ID.
+func withClientID(o *orders.Order, clientOrderID string) *orders.Order {
+	o.ClientOrderID = clientOrderID
+	return o
+}
+
+// TestOrderStatus_LiveAndCompleted verifies lookups by order ID and client
+// order ID answer for resting, filled, cancelled and killed orders.
+func TestOrderStatus_LiveAndCompleted(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	maker := engine.ProcessOrder(withClientID(limit(orders.SideSell, 15000, 100), "m-1")).Order
+	resting := engine.ProcessOrder(withClientID(limit(orders.SideBuy, 14900, 100), "r-1")).Order
+	taker := engine.ProcessOrder(withClientID(limit(orders.SideBuy, 15000, 60), "t-1")).Order
+	cancelled := engine.ProcessOrder(withClientID(limit(orders.SideBuy, 14800, 100), "c-1")).Order
+	engine.CancelOrder("AAPL", cancelled.ID)
+	ioc := limit(orders.SideBuy, 14000, 100)
+	ioc.Type = orders.OrderTypeIOC
+	engine.ProcessOrder(ioc)
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 40)) // Fills the maker
+
+	for _, c := range []struct {
+		name     string
+		orderID  uint64
+		clientID string
+		status   orders.OrderStatus
+		filled   int64
+	}{
+		{"resting", resting.ID, "r-1", orders.OrderStatusNew, 0},
+		{"filled maker", maker.ID, "m-1", orders.OrderStatusFilled, 100},
+		{"filled taker", taker.ID, "t-1", orders.OrderStatusFilled, 60},
+		{"cancelled", cancelled.ID, "c-1", orders.OrderStatusCancelled, 0},
+		{"killed IOC", ioc.ID, "", orders.OrderStatusCancelled, 0},
+	} {
+		order, ok := engine.LookupOrder(c.orderID)
+		if !ok || order.Status != c.status || order.FilledQty != c.filled {
+			t.Errorf("%s: expected %v with %d filled, got %+v (found %v)", c.name, c.status, c.filled, order, ok)
+		}
+		if c.clientID == "" {
+			continue
+		}
+		if order, ok := engine.LookupClientOrder("T1", c.clientID); !ok || order.ID != c.orderID {
+			t.Errorf("%s: expected client order ID %s to find order %d, got %+v", c.name, c.clientID, c.orderID, order)
+		}
+	}
+
+	if _, ok := engine.LookupClientOrder("T2", "r-1"); ok {
+		t.Error("Expected client order IDs to be scoped to the account")
+	}
+	if _, ok := engine.LookupOrder(9999); ok {
+		t.Error("Expected an unknown order ID not to be found")
+	}
+}
+
+// TestOrderStatus_HistoryIsBounded verifies the oldest completed orders are
+// evicted, and a reused client order ID finds the latest order.
+func TestOrderStatus_HistoryIsBounded(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.SetOrderHistory(2)
+	engine.AddSymbol("AAPL")
+
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		order := engine.ProcessOrder(withClientID(limit(orders.SideBuy, 14900, 100), "same")).Order
+		engine.CancelOrder("AAPL", order.ID)
+		ids = append(ids, order.ID)
+	}
+
+	if _, ok := engine.LookupOrder(ids[0]); ok {
+		t.Error("Expected the oldest completed order to be evicted")
+	}
+	for _, id := range ids[1:] {
+		if _, ok := engine.LookupOrder(id); !ok {
+			t.Errorf("Expected order %d to be remembered", id)
+		}
+	}
+	if order, ok := engine.LookupClientOrder("T1", "same"); !ok || order.ID != ids[2] {
+		t.Errorf("Expected the reused client order ID to find order %d, got %+v", ids[2], order)
+	}
+}
+
+// TestOrderStatus_Request verifies lookups are answered on the processor
+// with a copy of the order, and unknown orders are reported as such.
+func TestOrderStatus_Request(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run := startRun(t, engine, openLog(t), settlement.NewClearingHouse(), nil, 0)
+	defer run.processor.Shutdown()
+
+	placed := run.order(withClientID(limit(orders.SideBuy, 14900, 100), "abc"))
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: placed.ID})
+
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeOrderStatus, AccountID: "T1", ClientOrderID: "abc"})
+	if !response.Success || response.Order.ID != placed.ID || response.Order.Status != orders.OrderStatusCancelled {
+		t.Fatalf("Expected the cancelled order, got %+v", response.Order)
+	}
+	if response.Order == placed {
+		t.Error("Expected a copy of the order")
+	}
+
+	response = run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeOrderStatus, OrderID: placed.ID + 1})
+	if response.Success || response.Error != disruptor.ErrOrderUnknown {
+		t.Errorf("Expected ErrOrderUnknown, got %+v", response)
+	}
+}
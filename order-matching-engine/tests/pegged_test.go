@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// PEGGED ORDERS (MIDPOINT AND PRIMARY)
+// ============================================================================
+
+func pegged(side orders.Side, peg orders.PegType, qty int64) *orders.Order {
+	return &orders.Order{Symbol: "AAPL", Side: side, Type: orders.OrderTypeLimit, Peg: peg, Quantity: qty, AccountID: "P1"}
+}
+
+// TestPegged_MidpointTracksSpread verifies midpoint pegs are priced inside
+// the spread, rounded away from the other side, and follow it as it moves.
+func TestPegged_MidpointTracksSpread(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15005, 100))
+
+	buy := pegged(orders.SideBuy, orders.PegMidpoint, 50)
+	sell := pegged(orders.SideSell, orders.PegMidpoint, 50)
+	if r := engine.ProcessOrder(buy); !r.Accepted || len(r.Fills) != 0 {
+		t.Fatalf("Expected the buy peg to rest, got %+v", r)
+	}
+	engine.ProcessOrder(sell)
+	if buy.Price != 15002 || sell.Price != 15003 {
+		t.Fatalf("Expected pegs at 150.02/150.03, got %d/%d", buy.Price, sell.Price)
+	}
+
+	// A better bid moves the midpoint up
+	engine.ProcessOrder(limit(orders.SideBuy, 15002, 100))
+	if buy.Price != 15003 || sell.Price != 15004 {
+		t.Errorf("Expected pegs re-priced to 150.03/150.04, got %d/%d", buy.Price, sell.Price)
+	}
+	if buy.FilledQty != 0 || sell.FilledQty != 0 {
+		t.Errorf("Expected an odd spread to keep the pegs apart")
+	}
+}
+
+// TestPegged_MidpointOnTick verifies midpoint pegs stay on the symbol's
+// tick, buys rounding down and sells up.
+func TestPegged_MidpointOnTick(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.SetTickSize("AAPL", 5)
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15015, 100))
+
+	buy := pegged(orders.SideBuy, orders.PegMidpoint, 50)
+	sell := pegged(orders.SideSell, orders.PegMidpoint, 50)
+	engine.ProcessOrder(buy)
+	engine.ProcessOrder(sell)
+	if buy.Price != 15005 || sell.Price != 15010 {
+		t.Errorf("Expected pegs at 150.05/150.10, got %d/%d", buy.Price, sell.Price)
+	}
+}
+
+// TestPegged_PrimaryJoinsOwnSide verifies primary pegs join the best price
+// set by other orders on their own side, never a level of pegs alone.
+func TestPegged_PrimaryJoinsOwnSide(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	bid := engine.ProcessOrder(limit(orders.SideBuy, 15000, 100)).Order
+	engine.ProcessOrder(limit(orders.SideBuy, 14990, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15010, 100))
+
+	peg := pegged(orders.SideBuy, orders.PegPrimary, 50)
+	engine.ProcessOrder(peg)
+	if peg.Price != 15000 {
+		t.Fatalf("Expected the peg to join the 150.00 bid, got %d", peg.Price)
+	}
+
+	// The bid leaves; the peg follows on the next order, to 149.90
+	if _, err := engine.CancelOrder("AAPL", bid.ID); err != nil {
+		t.Fatal(err)
+	}
+	engine.ProcessOrder(limit(orders.SideSell, 15020, 100))
+	if peg.Price != 14990 {
+		t.Errorf("Expected the peg at 149.90, got %d", peg.Price)
+	}
+}
+
+// TestPegged_MidpointPegsMeet verifies two midpoint pegs trade when an order
+// narrows the spread to an even width, with the fill reported on that
+// order's result.
+func TestPegged_MidpointPegsMeet(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15003, 100))
+
+	buy := pegged(orders.SideBuy, orders.PegMidpoint, 50)
+	sell := pegged(orders.SideSell, orders.PegMidpoint, 30)
+	engine.ProcessOrder(buy)
+	engine.ProcessOrder(sell)
+
+	result := engine.ProcessOrder(limit(orders.SideSell, 15002, 100))
+	if len(result.Fills) != 1 {
+		t.Fatalf("Expected the pegs to trade, got %v", result.Fills)
+	}
+	fill := result.Fills[0]
+	if fill.MakerOrderID != buy.ID || fill.TakerOrderID != sell.ID || fill.Price != 15001 || fill.Quantity != 30 {
+		t.Errorf("Unexpected fill: %v", fill)
+	}
+	if sell.Status != orders.OrderStatusFilled || buy.RemainingQty() != 20 {
+		t.Errorf("Expected the sell filled and 20 left on the buy, got %s and %d", sell.Status, buy.RemainingQty())
+	}
+}
+
+// TestPegged_LimitCapsPrice verifies a peg limit stops a peg following the
+// market past it.
+func TestPegged_LimitCapsPrice(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15010, 100))
+
+	peg := pegged(orders.SideBuy, orders.PegPrimary, 50)
+	peg.PegLimit = 15002
+	engine.ProcessOrder(peg)
+	engine.ProcessOrder(limit(orders.SideBuy, 15005, 100))
+	if peg.Price != 15002 {
+		t.Errorf("Expected the peg capped at 150.02, got %d", peg.Price)
+	}
+}
+
+// TestPegged_Rejects verifies pegs need a reference price, cannot be icebergs
+// and cannot be replaced.
+func TestPegged_Rejects(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 100))
+
+	if r := engine.ProcessOrder(pegged(orders.SideSell, orders.PegMidpoint, 50)); r.Accepted {
+		t.Error("Expected a midpoint peg with no asks rejected")
+	}
+	iceberg := pegged(orders.SideBuy, orders.PegPrimary, 500)
+	iceberg.DisplayQty = 100
+	if r := engine.ProcessOrder(iceberg); r.Accepted {
+		t.Error("Expected a pegged iceberg rejected")
+	}
+
+	peg := pegged(orders.SideBuy, orders.PegPrimary, 50)
+	engine.ProcessOrder(peg)
+	_, err := engine.ReplaceOrder(matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: peg.ID, Side: orders.SideBuy, AccountID: "P1", Price: 14990, Quantity: 50})
+	if err == nil {
+		t.Error("Expected replacing a peg to fail")
+	}
+}
+
+// TestPegged_ReplayRebuildsBook verifies re-pricing and peg-to-peg fills
+// replay from the event log to the same book.
+func TestPegged_ReplayRebuildsBook(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	run := startRun(t, engine, eventLog, settlement.NewClearingHouse(), nil, 0)
+	run.order(limit(orders.SideBuy, 15000, 100))
+	run.order(limit(orders.SideSell, 15005, 100))
+	run.order(pegged(orders.SideBuy, orders.PegMidpoint, 50))
+	run.order(pegged(orders.SideSell, orders.PegMidpoint, 80))
+	run.order(pegged(orders.SideBuy, orders.PegPrimary, 40))
+	ask := run.order(limit(orders.SideSell, 15004, 100)) // Midpoint pegs meet at 150.02
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: ask.ID})
+	run.order(limit(orders.SideBuy, 15001, 100)) // Re-prices the rest
+	run.processor.Shutdown()
+
+	replayed := matching.NewEngine()
+	replayed.AddSymbol("AAPL")
+	replayer := matching.NewReplayer(replayed)
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		_, err := replayer.Apply(event)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(withoutTimestamps(replayed.RestingOrders()), withoutTimestamps(engine.RestingOrders())) {
+		t.Error("Replay did not rebuild the pegged book")
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// ORDER PREVIEW
+// ============================================================================
+
+// TestPreview_MatchesRealOrderWithoutTouchingBook verifies a preview gets
+// the fills the same order then gets for real, and leaves the book, the
+// ID counters and the order previewed as they were.
+func TestPreview_MatchesRealOrderWithoutTouchingBook(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideSell, 15100, 100))
+	engine.ProcessOrder(limit(orders.SideSell, 15150, 200))
+	iceberg := limit(orders.SideSell, 15200, 300)
+	iceberg.DisplayQty = 50
+	engine.ProcessOrder(iceberg)
+
+	books, counters := engine.RestingOrders(), engine.IDCounters()
+	taker := limit(orders.SideBuy, 15200, 400)
+	preview := engine.PreviewOrder(*taker)
+
+	if !reflect.DeepEqual(engine.RestingOrders(), books) || engine.IDCounters() != counters {
+		t.Fatal("Expected the preview to leave the book and counters untouched")
+	}
+	if taker.ID != 0 || taker.FilledQty != 0 {
+		t.Fatalf("Expected the order previewed untouched, got %+v", taker)
+	}
+	// 100 @ 151.00 + 200 @ 151.50 + 100 @ 152.00 (two iceberg slices)
+	if preview.FilledQty != 400 || preview.BestPrice != 15100 || preview.AvgPrice != 15150 || preview.Slippage != 50 {
+		t.Errorf("Expected 400 @ $151.50, $0.50 past $151.00, got %+v", preview)
+	}
+
+	result := engine.ProcessOrder(taker)
+	if len(result.Fills) != len(preview.Result.Fills) {
+		t.Fatalf("Expected %d fills for real, got %d", len(preview.Result.Fills), len(result.Fills))
+	}
+	for i, fill := range result.Fills {
+		want := preview.Result.Fills[i]
+		if fill.Price != want.Price || fill.Quantity != want.Quantity || fill.MakerOrderID != want.MakerOrderID {
+			t.Errorf("Fill %d: previewed %+v, got %+v", i, want, fill)
+		}
+	}
+}
+
+// TestPreview_SequencedWithOrders verifies a preview request sees the
+// orders before it, reports a market order's shortfall, and is neither
+// journaled nor logged.
+func TestPreview_SequencedWithOrders(t *testing.T) {
+	eventLog := openLog(t)
+	defer eventLog.Close()
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.Start()
+
+	run.order(limit(orders.SideBuy, 15000, 100))
+	run.order(limit(orders.SideBuy, 14900, 100))
+	market := &orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeMarket, Quantity: 250, AccountID: "T2"}
+	response := run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypePreview, Order: market})
+	run.processor.Shutdown()
+
+	preview := response.Preview
+	if preview == nil || preview.FilledQty != 200 || preview.AvgPrice != 14950 || preview.Slippage != 50 {
+		t.Fatalf("Expected 200 @ $149.50, $0.50 under $150.00, got %+v", preview)
+	}
+	if preview.Result.Order.Status != orders.OrderStatusCancelled || preview.Result.RejectReason != "insufficient liquidity" {
+		t.Errorf("Expected the shortfall cancelled, got %+v", preview.Result)
+	}
+	if disruptor.RequestTypePreview.ChangesState() {
+		t.Error("Expected previews not to be journaled")
+	}
+	if logged := replayAll(t, eventLog); len(logged) != 2 {
+		t.Errorf("Expected only the 2 orders logged, got %d events", len(logged))
+	}
+}
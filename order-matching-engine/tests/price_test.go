@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// PRICE PARSING (Exact Decimal Strings)
+// ============================================================================
+
+// TestPrice_ParseExact verifies decimal strings convert to fixed point
+// exactly, including values a float multiply gets wrong.
+func TestPrice_ParseExact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"150.07", 15007},
+		{"0.29", 29}, // 0.29 * 100 = 28.999999999999996 as a float
+		{"1.15", 115},
+		{"$150.25", 15025},
+		{"150", 15000},
+		{"150.5", 15050},
+		{".05", 5},
+		{"150.0700", 15007},
+		{"-0.50", -50},
+	}
+	for _, tt := range tests {
+		if got, err := orders.ParsePrice(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParsePrice(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	if got, err := orders.ParseDecimal("150.0750", 4); err != nil || got != 1500750 {
+		t.Errorf("Expected 150.0750 at scale 4 to be 1500750, got %d, %v", got, err)
+	}
+
+	for _, bad := range []string{"150.075", "", ".", "abc", "1e3", "1.2.3", "+1", "92233720368547758.08"} {
+		if got, err := orders.ParsePrice(bad); err == nil {
+			t.Errorf("Expected ParsePrice(%q) to fail, got %d", bad, got)
+		}
+	}
+}
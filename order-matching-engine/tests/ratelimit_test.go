@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/ratelimit"
+)
+
+// ============================================================================
+// ORDER RATE LIMITS
+// ============================================================================
+
+// TestRateLimit_BurstThenRefill verifies an account can submit its burst at
+// once, is refused with the time until its next token, and is refilled at
+// its rate.
+func TestRateLimit_BurstThenRefill(t *testing.T) {
+	l := ratelimit.New(ratelimit.Limit{Rate: 10, Burst: 3})
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		if result := l.AllowAt("TRADER1", now); !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("Order %d: expected allowed with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+	result := l.AllowAt("TRADER1", now)
+	if result.Allowed || result.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("Expected refusal with 100ms retry, got %+v", result)
+	}
+
+	// Other accounts have their own bucket
+	if !l.AllowAt("TRADER2", now).Allowed {
+		t.Fatal("Expected TRADER2 unaffected by TRADER1")
+	}
+
+	if !l.AllowAt("TRADER1", now.Add(100*time.Millisecond)).Allowed {
+		t.Fatal("Expected one token after 100ms")
+	}
+	if l.AllowAt("TRADER1", now.Add(150*time.Millisecond)).Allowed {
+		t.Fatal("Expected no token 50ms later")
+	}
+}
+
+// TestRateLimit_PerAccountLimits verifies an account's own limit replaces
+// the default, that a zero rate exempts it, and that clearing it restores
+// the default.
+func TestRateLimit_PerAccountLimits(t *testing.T) {
+	l := ratelimit.New(ratelimit.Limit{Rate: 1, Burst: 1})
+	now := time.Unix(1700000000, 0)
+
+	if err := l.SetLimit("MM1", ratelimit.Limit{Rate: 100, Burst: 50}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if !l.AllowAt("MM1", now).Allowed {
+			t.Fatalf("Expected MM1 order %d within its burst", i)
+		}
+	}
+	if l.AllowAt("MM1", now).Allowed {
+		t.Fatal("Expected MM1 refused past its burst")
+	}
+
+	if err := l.SetLimit("MM1", ratelimit.Limit{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !l.AllowAt("MM1", now).Allowed {
+			t.Fatal("Expected a zero rate to exempt MM1")
+		}
+	}
+
+	l.ClearLimit("MM1")
+	if limit, own := l.Limit("MM1"); own || limit.Rate != 1 {
+		t.Fatalf("Expected the default limit back, got %+v (own %v)", limit, own)
+	}
+
+	if err := l.SetLimit("MM1", ratelimit.Limit{Rate: 5}); err == nil {
+		t.Error("Expected a rate without a burst to be refused")
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// ID COUNTER RECOVERY
+// ============================================================================
+
+// TestRecovery_IDCountersSurviveRestart verifies that an engine rebuilt from
+// the event log never reissues an order or trade ID.
+func TestRecovery_IDCountersSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	submit := func(e *matching.Engine, log *events.EventLog, side orders.Side, qty int64) *orders.ExecutionResult {
+		order := &orders.Order{
+			Symbol: "AAPL", Side: side, Type: orders.OrderTypeLimit,
+			Price: 15000, Quantity: qty, AccountID: "T1",
+		}
+		result := e.ProcessOrder(order)
+		log.Append(&events.NewOrderEvent{OrderID: order.ID, Symbol: "AAPL", Side: side, Quantity: qty})
+		for _, fill := range result.Fills {
+			log.Append(&events.FillEvent{
+				TradeID: fill.TradeID, Symbol: fill.Symbol,
+				MakerOrderID: fill.MakerOrderID, TakerOrderID: fill.TakerOrderID,
+			})
+		}
+		return result
+	}
+
+	submit(engine, eventLog, orders.SideSell, 100)
+	submit(engine, eventLog, orders.SideBuy, 40)
+	submit(engine, eventLog, orders.SideBuy, 40)
+	before := engine.IDCounters()
+	eventLog.Close()
+
+	// Restart: fresh engine, counters recovered from the log
+	eventLog, err = events.NewEventLog(events.EventLogConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+
+	counters, err := matching.RecoverIDCounters(eventLog)
+	if err != nil {
+		t.Fatalf("RecoverIDCounters failed: %v", err)
+	}
+	if counters != before {
+		t.Fatalf("Recovered counters %+v, want %+v", counters, before)
+	}
+
+	restarted := matching.NewEngine()
+	restarted.AddSymbol("AAPL")
+	restarted.RestoreIDCounters(counters)
+
+	submit(restarted, eventLog, orders.SideSell, 10)
+	result := submit(restarted, eventLog, orders.SideBuy, 10)
+
+	if result.Order.ID <= before.OrderID {
+		t.Errorf("Restarted engine reissued order ID %d (last before restart: %d)", result.Order.ID, before.OrderID)
+	}
+	if len(result.Fills) != 1 || result.Fills[0].TradeID <= before.TradeID {
+		t.Errorf("Restarted engine reissued trade ID (fills: %+v, last before restart: %d)", result.Fills, before.TradeID)
+	}
+}
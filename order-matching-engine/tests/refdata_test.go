@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/refdata"
+)
+
+// ============================================================================
+// PRE-SEQUENCER VALIDATION (Reference Data)
+// ============================================================================
+
+// TestRefData_RejectCodes verifies each validation failure maps to its
+// stable reject code.
+func TestRefData_RejectCodes(t *testing.T) {
+	store := refdata.NewStore()
+	store.Add(refdata.Instrument{Symbol: "AAPL", TickSize: 5, LotSize: 100})
+	store.Add(refdata.Instrument{Symbol: "MSFT"})
+	if err := store.SetState("MSFT", refdata.SessionHalted); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := func(symbol string, price, qty int64) *orders.Order {
+		return &orders.Order{Symbol: symbol, Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: price, Quantity: qty}
+	}
+
+	tests := []struct {
+		name  string
+		order *orders.Order
+		want  refdata.RejectCode
+	}{
+		{"unknown symbol", limit("NOPE", 15000, 100), refdata.RejectUnknownSymbol},
+		{"halted symbol", limit("MSFT", 30000, 100), refdata.RejectNotTrading},
+		{"zero quantity", limit("AAPL", 15000, 0), refdata.RejectInvalidQuantity},
+		{"odd lot", limit("AAPL", 15000, 150), refdata.RejectInvalidLot},
+		{"no price", limit("AAPL", 0, 100), refdata.RejectInvalidPrice},
+		{"off tick", limit("AAPL", 15003, 100), refdata.RejectInvalidTick},
+		{"ioc needs price", &orders.Order{Symbol: "AAPL", Type: orders.OrderTypeIOC, Quantity: 100}, refdata.RejectInvalidPrice},
+	}
+
+	for _, tt := range tests {
+		reject := store.Validate(tt.order)
+		if reject == nil || reject.Code != tt.want {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.want, reject)
+		}
+	}
+
+	if reject := store.Validate(limit("AAPL", 15005, 200)); reject != nil {
+		t.Errorf("Valid order rejected: %v", reject)
+	}
+	market := &orders.Order{Symbol: "AAPL", Type: orders.OrderTypeMarket, Quantity: 100}
+	if reject := store.Validate(market); reject != nil {
+		t.Errorf("Market order without price rejected: %v", reject)
+	}
+}
+
+// TestRefData_InstrumentsFile verifies tick and lot sizes load from an
+// instruments file, and that ticks finer than a cent are refused.
+func TestRefData_InstrumentsFile(t *testing.T) {
+	instruments, err := refdata.Parse([]byte(`
+BRK.A:
+  tick_size: "1.00"
+  market: XNYS
+PENNY:
+  tick_size: "0.05"
+  lot_size: 100
+AAPL: {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instruments) != 3 || instruments[0].Symbol != "AAPL" {
+		t.Fatalf("Expected three instruments sorted by symbol, got %+v", instruments)
+	}
+	brk, penny := instruments[1], instruments[2]
+	if brk.TickSize != 100 || brk.Market != "XNYS" || brk.Decimals() != 0 {
+		t.Errorf("Expected BRK.A on a dollar tick, got %+v", brk)
+	}
+	if penny.TickSize != 5 || penny.LotSize != 100 || penny.Decimals() != 2 {
+		t.Errorf("Expected PENNY on a nickel tick in round lots, got %+v", penny)
+	}
+
+	store := refdata.NewStore()
+	for _, inst := range instruments {
+		store.Add(inst)
+	}
+	if inst, _ := store.Get("AAPL"); inst.TickSize != 1 || inst.LotSize != 1 {
+		t.Errorf("Expected AAPL to default to a cent and a share, got %+v", inst)
+	}
+	order := &orders.Order{Symbol: "BRK.A", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 70000050, Quantity: 1}
+	if reject := store.Validate(order); reject == nil || reject.Code != refdata.RejectInvalidTick {
+		t.Errorf("Expected a price between dollars rejected, got %v", reject)
+	}
+
+	for _, bad := range []string{`X: {tick_size: "0.005"}`, `X: {tick_size: "0"}`, `X: {tick_size: "abc"}`, `X: {lot_size: -1}`,
+		`X: {allocation: lifo}`, `X: {top_order: true}`, `X: {allocation: pro-rata, min_allocation: -1}`} {
+		if _, err := refdata.Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected %s to be refused", bad)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// CANCEL/REPLACE (ORDER MODIFICATION)
+// ============================================================================
+
+// restTwoBids rests two AAPL bids at the same price and returns them in
+// queue order.
+func restTwoBids(engine *matching.Engine) (*orders.Order, *orders.Order) {
+	first := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "B1"}
+	second := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "B2"}
+	engine.ProcessOrder(first)
+	engine.ProcessOrder(second)
+	return first, second
+}
+
+// TestReplace_ReductionKeepsPriority verifies a smaller order at the same
+// price is amended in place and still trades first.
+func TestReplace_ReductionKeepsPriority(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	first, second := restTwoBids(engine)
+
+	replaced, err := engine.ReplaceOrder(matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: first.ID, Side: orders.SideBuy, AccountID: "B1", Price: 15000, Quantity: 40,
+	})
+	if err != nil {
+		t.Fatalf("ReplaceOrder failed: %v", err)
+	}
+	if !replaced.PriorityKept || replaced.OldQuantity != 100 {
+		t.Errorf("Expected priority kept from quantity 100, got %+v", replaced)
+	}
+	if level := engine.GetOrderBook("AAPL").GetBestBid(); level.TotalQty != 140 {
+		t.Errorf("Expected 140 at the level, got %d", level.TotalQty)
+	}
+
+	result := engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeIOC, Price: 15000, Quantity: 40, AccountID: "S1"})
+	if len(result.Fills) != 1 || result.Fills[0].MakerOrderID != first.ID {
+		t.Fatalf("Expected the reduced order to trade first, got %v", result.Fills)
+	}
+	if second.FilledQty != 0 {
+		t.Errorf("Expected the second order untouched, got %d filled", second.FilledQty)
+	}
+}
+
+// TestReplace_IncreaseLosesPriority verifies a size increase re-queues the
+// order behind orders that were already at its price.
+func TestReplace_IncreaseLosesPriority(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	first, second := restTwoBids(engine)
+
+	replaced, err := engine.ReplaceOrder(matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: first.ID, Side: orders.SideBuy, AccountID: "B1", Price: 15000, Quantity: 150,
+	})
+	if err != nil {
+		t.Fatalf("ReplaceOrder failed: %v", err)
+	}
+	if replaced.PriorityKept {
+		t.Error("Expected a size increase to re-queue")
+	}
+
+	result := engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeIOC, Price: 15000, Quantity: 100, AccountID: "S1"})
+	if len(result.Fills) != 1 || result.Fills[0].MakerOrderID != second.ID {
+		t.Fatalf("Expected the second order to trade first, got %v", result.Fills)
+	}
+	if first.ID == 0 || engine.GetOrder("AAPL", first.ID) == nil || first.RemainingQty() != 150 {
+		t.Errorf("Expected the replaced order to keep its ID and rest 150, got %v", first)
+	}
+}
+
+// TestReplace_RepriceMatchesOnEntry verifies a new price that crosses the
+// spread trades immediately and keeps earlier fills.
+func TestReplace_RepriceMatchesOnEntry(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 30, AccountID: "S1"})
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeLimit, Price: 15100, Quantity: 50, AccountID: "S2"})
+	bid := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 15000, Quantity: 100, AccountID: "B1"}
+	engine.ProcessOrder(bid) // Fills 30, rests 70
+
+	replaced, err := engine.ReplaceOrder(matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: bid.ID, Side: orders.SideBuy, AccountID: "B1", Price: 15100, Quantity: 100,
+	})
+	if err != nil {
+		t.Fatalf("ReplaceOrder failed: %v", err)
+	}
+	if len(replaced.Result.Fills) != 1 || replaced.Result.Fills[0].Quantity != 50 {
+		t.Fatalf("Expected 50 to trade on re-entry, got %v", replaced.Result.Fills)
+	}
+	if bid.FilledQty != 80 || bid.Status != orders.OrderStatusPartiallyFilled || replaced.Result.RestingQty != 20 {
+		t.Errorf("Expected 80 filled and 20 resting, got %v resting %d", bid, replaced.Result.RestingQty)
+	}
+}
+
+// TestReplace_Rejections verifies replaces that can't apply leave the order
+// untouched.
+func TestReplace_Rejections(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	first, _ := restTwoBids(engine)
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeIOC, Price: 15000, Quantity: 60, AccountID: "S1"})
+
+	cases := []struct {
+		name string
+		req  matching.ReplaceRequest
+	}{
+		{"unknown order", matching.ReplaceRequest{Symbol: "AAPL", OrderID: 999, Side: orders.SideBuy, AccountID: "B1", Price: 15000, Quantity: 50}},
+		{"wrong account", matching.ReplaceRequest{Symbol: "AAPL", OrderID: first.ID, Side: orders.SideBuy, AccountID: "B2", Price: 15000, Quantity: 80}},
+		{"wrong side", matching.ReplaceRequest{Symbol: "AAPL", OrderID: first.ID, Side: orders.SideSell, AccountID: "B1", Price: 15000, Quantity: 80}},
+		{"at filled quantity", matching.ReplaceRequest{Symbol: "AAPL", OrderID: first.ID, Side: orders.SideBuy, AccountID: "B1", Price: 15000, Quantity: 60}},
+	}
+	for _, tc := range cases {
+		if _, err := engine.ReplaceOrder(tc.req); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+	if first.Quantity != 100 || first.RemainingQty() != 40 {
+		t.Errorf("Expected the order unchanged, got %v", first)
+	}
+}
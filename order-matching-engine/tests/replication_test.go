@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/replication"
+)
+
+// ============================================================================
+// PRIMARY/STANDBY REPLICATION
+// ============================================================================
+
+// servePrimary starts a primary for eventLog on a free port.
+func servePrimary(t *testing.T, eventLog *events.EventLog, config replication.Config) (*replication.Primary, net.Listener) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := replication.NewPrimary(eventLog, config)
+	go primary.Serve(ln)
+	t.Cleanup(func() {
+		ln.Close()
+		primary.Close()
+	})
+	return primary, ln
+}
+
+func testReplicationConfig() replication.Config {
+	config := replication.DefaultConfig()
+	config.Heartbeat = 20 * time.Millisecond
+	config.Timeout = 500 * time.Millisecond
+	config.Retry = 20 * time.Millisecond
+	return config
+}
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestReplication_CatchUpThenStream verifies a standby is caught up from
+// the primary's log, then receives live appends with the same sequence
+// numbers, and that the primary sees them acknowledged.
+func TestReplication_CatchUpThenStream(t *testing.T) {
+	dir := t.TempDir()
+	primaryLog := openLogAt(t, filepath.Join(dir, "primary.log"))
+	defer primaryLog.Close()
+	appendOrders(t, primaryLog, 50)
+
+	config := testReplicationConfig()
+	primary, ln := servePrimary(t, primaryLog, config)
+
+	standbyLog := openLogAt(t, filepath.Join(dir, "standby.log"))
+	standby := replication.NewStandby(standbyLog, ln.Addr().String(), config)
+	go standby.Run()
+
+	waitFor(t, "catch-up", func() bool { return standbyLog.GetLastSequence() == 50 })
+	appendOrders(t, primaryLog, 25)
+	waitFor(t, "live events", func() bool { return standby.Status().AckedSeq == 75 })
+	waitFor(t, "primary to see the ack", func() bool { return primary.Acked() == 75 })
+
+	standbys := primary.Standbys()
+	if len(standbys) != 1 || standbys[0].Lag != 0 {
+		t.Fatalf("Expected one standby with no lag, got %+v", standbys)
+	}
+
+	if last := standby.Stop(); last != 75 {
+		t.Fatalf("Expected standby to stop at 75, got %d", last)
+	}
+	standbyLog.Close()
+
+	// The replicated log recovers like the primary's
+	standbyLog = openLogAt(t, filepath.Join(dir, "standby.log"))
+	defer standbyLog.Close()
+	var orderIDs []uint64
+	err := standbyLog.Replay(func(seqNum uint64, event interface{}) error {
+		orderIDs = append(orderIDs, event.(*events.NewOrderEvent).OrderID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay of replicated log failed: %v", err)
+	}
+	if len(orderIDs) != 75 || orderIDs[0] != 1 || orderIDs[74] != 25 {
+		t.Errorf("Expected the primary's 75 events, got %d", len(orderIDs))
+	}
+}
+
+// TestReplication_ResumesAfterReconnect verifies a standby restarted from
+// its own log picks up where it stopped.
+func TestReplication_ResumesAfterReconnect(t *testing.T) {
+	dir := t.TempDir()
+	primaryLog := openLogAt(t, filepath.Join(dir, "primary.log"))
+	defer primaryLog.Close()
+	config := testReplicationConfig()
+	_, ln := servePrimary(t, primaryLog, config)
+
+	standbyPath := filepath.Join(dir, "standby.log")
+	standbyLog := openLogAt(t, standbyPath)
+	standby := replication.NewStandby(standbyLog, ln.Addr().String(), config)
+	go standby.Run()
+	appendOrders(t, primaryLog, 10)
+	waitFor(t, "first events", func() bool { return standby.Status().AckedSeq == 10 })
+	standby.Stop()
+	standbyLog.Close()
+
+	// Logged while the standby was down
+	appendOrders(t, primaryLog, 10)
+
+	standbyLog = openLogAt(t, standbyPath)
+	defer standbyLog.Close()
+	standby = replication.NewStandby(standbyLog, ln.Addr().String(), config)
+	go standby.Run()
+	defer standby.Stop()
+	appendOrders(t, primaryLog, 5)
+	waitFor(t, "resume", func() bool { return standby.Status().AckedSeq == 25 })
+
+	seqs, err := replaySeqs(standbyLog, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, seq := range seqs {
+		if seq != uint64(i+1) {
+			t.Fatalf("Expected contiguous sequences, got %d at %d", seq, i)
+		}
+	}
+}
+
+// TestReplication_StandbyAheadDiverges verifies a standby whose log is
+// ahead of the primary's is refused rather than streamed into.
+func TestReplication_StandbyAheadDiverges(t *testing.T) {
+	dir := t.TempDir()
+	primaryLog := openLogAt(t, filepath.Join(dir, "primary.log"))
+	defer primaryLog.Close()
+	appendOrders(t, primaryLog, 3)
+	config := testReplicationConfig()
+	primary, ln := servePrimary(t, primaryLog, config)
+
+	standbyLog := openLogAt(t, filepath.Join(dir, "standby.log"))
+	defer standbyLog.Close()
+	appendOrders(t, standbyLog, 5)
+	standby := replication.NewStandby(standbyLog, ln.Addr().String(), config)
+	go standby.Run()
+	defer standby.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if n := len(primary.Standbys()); n != 0 {
+		t.Fatalf("Expected the diverged standby to be refused, got %d connected", n)
+	}
+	if standbyLog.GetLastSequence() != 5 {
+		t.Errorf("Expected the standby's log untouched, got seq %d", standbyLog.GetLastSequence())
+	}
+	if status := standby.Status(); status.AckedSeq != 5 {
+		t.Errorf("Expected the standby still at 5, got %+v", status)
+	}
+}
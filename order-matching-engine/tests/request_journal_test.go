@@ -0,0 +1,292 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/grpcapi"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/wal"
+)
+
+// ============================================================================
+// REQUEST JOURNAL (WRITE-AHEAD OF THE RING BUFFER)
+// ============================================================================
+
+func openJournal(t *testing.T, path string) *wal.Log {
+	t.Helper()
+	journal, err := wal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return journal
+}
+
+// journaledAfter returns the payloads of the records after seq.
+func journaledAfter(t *testing.T, journal *wal.Log, seq uint64) []string {
+	t.Helper()
+	var payloads []string
+	err := journal.Replay(seq, func(seq uint64, payload []byte) error {
+		payloads = append(payloads, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return payloads
+}
+
+// TestRequestJournal_TornTailAndCompaction verifies a torn batch is cut off
+// on open, and compaction drops records without restarting the numbering.
+func TestRequestJournal_TornTailAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.wal")
+	journal := openJournal(t, path)
+	if first, err := journal.Append([][]byte{[]byte("a"), []byte("b"), []byte("c")}); err != nil || first != 1 {
+		t.Fatalf("Expected the first batch to start at 1, got %d (%v)", first, err)
+	}
+	if first, err := journal.Append([][]byte{[]byte("d"), []byte("e")}); err != nil || first != 4 {
+		t.Fatalf("Expected the second batch to start at 4, got %d (%v)", first, err)
+	}
+	journal.Close()
+
+	// A crash in the middle of the next batch's write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{0, 0, 0, 9, 0, 0, 0, 0, 0, 0, 0, 6, 1, 2})
+	file.Close()
+
+	journal = openJournal(t, path)
+	if journal.TornBytes() != 14 || journal.LastSeq() != 5 {
+		t.Fatalf("Expected the torn record dropped and 5 records, got torn=%d last=%d", journal.TornBytes(), journal.LastSeq())
+	}
+	if got := journaledAfter(t, journal, 2); !reflect.DeepEqual(got, []string{"c", "d", "e"}) {
+		t.Errorf("Expected c d e after 2, got %v", got)
+	}
+
+	if err := journal.Compact(4); err != nil {
+		t.Fatal(err)
+	}
+	if journal.FirstSeq() != 5 || !reflect.DeepEqual(journaledAfter(t, journal, 0), []string{"e"}) {
+		t.Errorf("Expected only record 5 after compaction, got first=%d %v", journal.FirstSeq(), journaledAfter(t, journal, 0))
+	}
+	if err := journal.Compact(5); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	// Empty, but numbering carries on across a restart
+	journal = openJournal(t, path)
+	defer journal.Close()
+	if first, err := journal.Append([][]byte{[]byte("f")}); err != nil || first != 6 {
+		t.Errorf("Expected numbering to carry on at 6, got %d (%v)", first, err)
+	}
+}
+
+// startJournaledRun starts a processor journaling to journal, whose
+// requests up to applied the engine already reflects.
+func startJournaledRun(t *testing.T, engine *matching.Engine, eventLog *events.EventLog, journal *wal.Log, applied uint64) *tailRun {
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.EnableClearing(settlement.NewClearingHouse())
+	run.processor.EnableJournal(journal, applied)
+	run.processor.Start()
+	return run
+}
+
+// fillsOf returns the fill events in a log, timestamps included.
+func fillsOf(t *testing.T, eventLog *events.EventLog) []events.FillEvent {
+	var fills []events.FillEvent
+	for _, event := range replayAll(t, eventLog) {
+		if fill, ok := event.(*events.FillEvent); ok {
+			f := *fill
+			f.SequenceNum = 0
+			fills = append(fills, f)
+		}
+	}
+	return fills
+}
+
+// TestRequestJournal_ReplayRebuildsLostBatch verifies the requests of a
+// batch whose events never reached the log are journaled, checkpointed
+// and, replayed from the journal onto the state as of the last checkpoint,
+// produce the same orders and fills again.
+func TestRequestJournal_ReplayRebuildsLostBatch(t *testing.T) {
+	dir := t.TempDir()
+	journal := openJournal(t, filepath.Join(dir, "requests.wal"))
+	defer journal.Close()
+	eventLog := openLogAt(t, filepath.Join(dir, "events.log"))
+	defer eventLog.Close()
+
+	// Live run, every request in a batch of its own
+	live := matching.NewEngine()
+	live.AddSymbol("AAPL")
+	run := startJournaledRun(t, live, eventLog, journal, 0)
+	run.order(limit(orders.SideSell, 15000, 100))
+	run.order(limit(orders.SideSell, 15100, 100))
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeOrderStatus, OrderID: 1}) // Not journaled
+	taker := run.order(limit(orders.SideBuy, 15100, 150))
+	run.processor.Shutdown()
+
+	if journal.LastSeq() != 3 || run.processor.JournalSeq() != 3 || taker.FilledQty != 150 {
+		t.Fatalf("Expected 3 requests journaled and the buy filled, got last=%d applied=%d filled=%d",
+			journal.LastSeq(), run.processor.JournalSeq(), taker.FilledQty)
+	}
+	checkpoints := make(map[uint64]uint64) // Through -> event sequence number
+	eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		if checkpoint, ok := event.(*events.JournalCheckpointEvent); ok {
+			checkpoints[checkpoint.Through] = seqNum
+		}
+		return nil
+	})
+	if len(checkpoints) != 3 || checkpoints[3] != eventLog.GetLastSequence() {
+		t.Fatalf("Expected a checkpoint after each journaled request, the last one last, got %v", checkpoints)
+	}
+	want := fillsOf(t, eventLog)
+
+	// Crash: the last request's events never reached the log
+	if err := eventLog.TruncateAfter(checkpoints[2]); err != nil {
+		t.Fatal(err)
+	}
+	if len(fillsOf(t, eventLog)) != 0 {
+		t.Fatal("Expected no fills left in the log")
+	}
+
+	// Recovery: the log up to the checkpoint, then the journal after it
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	replayer := matching.NewReplayer(engine)
+	for _, event := range replayAll(t, eventLog) {
+		if _, err := replayer.Apply(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine.RestoreIDCounters(replayer.Counters())
+
+	run = startJournaledRun(t, engine, eventLog, journal, 2)
+	err := journal.Replay(2, func(seq uint64, record []byte) error {
+		request, err := disruptor.DecodeRequest(record)
+		if err != nil {
+			return err
+		}
+		request.JournalSeq = seq
+		run.send(request)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.processor.Shutdown()
+
+	if journal.LastSeq() != 3 {
+		t.Errorf("Expected replayed requests not to be journaled again, got %d records", journal.LastSeq())
+	}
+	if got := fillsOf(t, eventLog); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed fills differ:\n got %+v\nwant %+v", got, want)
+	}
+	if got, want := engine.RestingOrders(), live.RestingOrders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed books differ:\n got %+v\nwant %+v", got, want)
+	}
+	if engine.IDCounters() != live.IDCounters() {
+		t.Errorf("Expected counters %+v, got %+v", live.IDCounters(), engine.IDCounters())
+	}
+}
+
+// TestRequestJournal_OneTimePerRequest verifies a request's order, fills
+// and events carry the one time the processor's clock gave it, and that
+// replaying the log rebuilds the book with the same timestamps.
+func TestRequestJournal_OneTimePerRequest(t *testing.T) {
+	eventLog := openLog(t)
+	defer eventLog.Close()
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	var now int64
+	run.processor.SetClock(func() int64 { now += 1000; return now })
+	run.processor.Start()
+	run.order(limit(orders.SideSell, 15000, 100))
+	run.order(limit(orders.SideSell, 15100, 100))
+	taker := run.order(limit(orders.SideBuy, 15100, 150))
+	run.processor.Shutdown()
+
+	if taker.Timestamp != 3000 {
+		t.Errorf("Expected the third request stamped 3000, got %d", taker.Timestamp)
+	}
+	logged := replayAll(t, eventLog)
+	var fills int
+	for _, event := range logged {
+		if fill, ok := event.(*events.FillEvent); ok {
+			fills++
+			if fill.Timestamp != 3000 {
+				t.Errorf("Expected fill %d logged at 3000, got %d", fill.TradeID, fill.Timestamp)
+			}
+		}
+	}
+	if fills != 2 || events.TimestampOf(logged[2]) != 3000 {
+		t.Fatalf("Expected the taker and its 2 fills at 3000, got %d fills in %+v", fills, logged)
+	}
+
+	replayed := matching.NewEngine()
+	replayed.AddSymbol("AAPL")
+	replayer := matching.NewReplayer(replayed)
+	for _, event := range logged {
+		if _, err := replayer.Apply(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := replayed.RestingOrders(), engine.RestingOrders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed books differ:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+// TestRequestJournal_GatewayOrdersTakeRequestTime verifies orders built by
+// a front end rest with their request's time, not one the front end gave
+// them, so a book replayed from the log is the book that was traded.
+func TestRequestJournal_GatewayOrdersTakeRequestTime(t *testing.T) {
+	eventLog := openLog(t)
+	defer eventLog.Close()
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	var now int64
+	run.processor.SetClock(func() int64 { now += 1000; return now })
+	run.processor.Start()
+
+	viaGRPC, err := (&grpcapi.SubmitOrderRequest{
+		Symbol: "AAPL", Side: grpcapi.SideSell, Type: grpcapi.OrderTypeLimit, Price: 15100, Quantity: 100, AccountID: "MM1",
+	}).Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.order(viaGRPC)
+	stamped := limit(orders.SideSell, 15200, 100)
+	stamped.Timestamp = orders.Now() // As a front end reading its own clock would
+	run.order(stamped)
+	run.processor.Shutdown()
+
+	if viaGRPC.Timestamp != 1000 || stamped.Timestamp != 2000 {
+		t.Errorf("Expected the orders at their request times 1000 and 2000, got %d and %d", viaGRPC.Timestamp, stamped.Timestamp)
+	}
+	replayed := matching.NewEngine()
+	replayed.AddSymbol("AAPL")
+	replayer := matching.NewReplayer(replayed)
+	for _, event := range replayAll(t, eventLog) {
+		if _, err := replayer.Apply(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := replayed.RestingOrders(), engine.RestingOrders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed books differ:\n got %+v\nwant %+v", got, want)
+	}
+}
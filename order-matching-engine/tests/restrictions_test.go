@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// RESTRICTED LISTS
+// ============================================================================
+
+// TestRestrictions_RejectWithCode verifies an account's restricted list
+// stops only that account, the global list stops everyone, each with its
+// own reject code, and lifting a restriction lets orders through again.
+func TestRestrictions_RejectWithCode(t *testing.T) {
+	checker := risk.NewChecker(risk.DefaultConfig())
+	order := func(account, symbol string) *orders.Order {
+		o := limit(orders.SideBuy, 15000, 10)
+		o.AccountID, o.Symbol = account, symbol
+		return o
+	}
+
+	checker.Restrict("T1", "AAPL", "insider list")
+	checker.Restrict("", "GME", "")
+
+	for _, tc := range []struct {
+		account, symbol, code, reason string
+	}{
+		{"T1", "AAPL", risk.RejectRestrictedSymbol, "account T1 may not trade AAPL: insider list"},
+		{"T2", "AAPL", "", ""},
+		{"T1", "MSFT", "", ""},
+		{"T2", "GME", risk.RejectRestrictedList, "GME is on the restricted list"},
+	} {
+		result := checker.Check(order(tc.account, tc.symbol))
+		if result.Passed != (tc.code == "") || result.Code != tc.code || result.Reason != tc.reason {
+			t.Errorf("%s %s: expected code %q reason %q, got %+v", tc.account, tc.symbol, tc.code, tc.reason, result)
+		}
+	}
+	if accounts := checker.RestrictedAccounts(); len(accounts) != 1 || accounts[0] != "T1" {
+		t.Errorf("Expected only T1 with a list of its own, got %v", accounts)
+	}
+
+	if !checker.Unrestrict("T1", "AAPL") || checker.Unrestrict("T1", "AAPL") {
+		t.Error("Expected the restriction lifted once")
+	}
+	if result := checker.Check(order("T1", "AAPL")); !result.Passed {
+		t.Errorf("Expected T1 to trade AAPL again, got %s", result.Reason)
+	}
+}
+
+// TestRestrictions_Logged verifies restricted list changes are logged in
+// sequence with orders and handed to the hook that applies them.
+func TestRestrictions_Logged(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	checker := risk.NewChecker(risk.DefaultConfig())
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.OnRestriction(func(change *events.RestrictionEvent) {
+		if change.Restricted {
+			checker.Restrict(change.AccountID, change.Symbol, change.Reason)
+		} else {
+			checker.Unrestrict(change.AccountID, change.Symbol)
+		}
+	})
+	run.processor.Start()
+
+	run.order(limit(orders.SideBuy, 15000, 100))
+	for _, restricted := range []bool{true, false, true} {
+		response := run.send(&disruptor.OrderRequest{
+			Type: disruptor.RequestTypeRestriction,
+			Restriction: &events.RestrictionEvent{AccountID: "T1", Symbol: "AAPL", Restricted: restricted,
+				Reason: "insider list", Actor: "compliance@10.0.0.7:40112"},
+		})
+		if !response.Success {
+			t.Fatalf("Expected the change accepted, got %v", response.Error)
+		}
+	}
+	run.processor.Shutdown()
+
+	if reason, ok := checker.Restrictions("T1")["AAPL"]; !ok || reason != "insider list" {
+		t.Errorf("Expected the hook to leave AAPL restricted, got %v", checker.Restrictions("T1"))
+	}
+
+	var changes []*events.RestrictionEvent
+	for _, event := range replayAll(t, eventLog) {
+		if e, ok := event.(*events.RestrictionEvent); ok {
+			changes = append(changes, e)
+		}
+	}
+	if len(changes) != 3 || changes[1].Restricted || !changes[2].Restricted || changes[2].Actor != "compliance@10.0.0.7:40112" {
+		t.Errorf("Expected the three changes and their author logged, got %+v", changes)
+	}
+}
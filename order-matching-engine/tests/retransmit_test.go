@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+)
+
+// ============================================================================
+// MARKET DATA RETRANSMISSION
+// ============================================================================
+
+// TestRetransmit_SeqsSpanQuotesDepthAndTrades verifies quotes, depth and
+// trades share one gapless seq per symbol, and each symbol has its own.
+func TestRetransmit_SeqsSpanQuotesDepthAndTrades(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+	quotes := pub.SubscribeL1("AAPL")
+	trades := pub.SubscribeTrades("AAPL")
+	depth := pub.SubscribeL2("AAPL")
+
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000})
+	pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: 1})
+	pub.PublishL1(marketdata.L1Quote{Symbol: "MSFT", BidPrice: 30000})
+	pub.PublishL2(bookImage([][2]int64{{15000, 100}}, nil))
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15001})
+
+	if q := <-quotes.C; q.MsgSeq != 1 {
+		t.Errorf("Expected the first quote at seq 1, got %d", q.MsgSeq)
+	}
+	if tr := <-trades.C; tr.MsgSeq != 2 {
+		t.Errorf("Expected the trade at seq 2, got %d", tr.MsgSeq)
+	}
+	if d := <-depth.C; d.MsgSeq != 3 {
+		t.Errorf("Expected the depth at seq 3, got %d", d.MsgSeq)
+	}
+	if q := <-quotes.C; q.MsgSeq != 4 {
+		t.Errorf("Expected the second quote at seq 4, got %d", q.MsgSeq)
+	}
+	if replay := pub.Replay("MSFT", 0); replay.Seq != 1 || len(replay.Messages) != 1 || replay.Gone {
+		t.Errorf("Expected MSFT numbered on its own, got %+v", replay)
+	}
+}
+
+// TestRetransmit_ReplayFillsGap verifies a replay returns exactly the
+// messages from the requested seq, and nothing when nothing was missed.
+func TestRetransmit_ReplayFillsGap(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+	for i := int64(1); i <= 5; i++ {
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000 + i})
+		pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: uint64(i)})
+	}
+
+	replay := pub.Replay("AAPL", 7)
+	if replay.Gone || replay.Seq != 10 || len(replay.Messages) != 4 {
+		t.Fatalf("Expected seqs 7 to 10, got %+v", replay)
+	}
+	for i, msg := range replay.Messages {
+		if msg.Seq != uint64(7+i) || msg.Symbol != "AAPL" {
+			t.Errorf("Expected seq %d, got %+v", 7+i, msg)
+		}
+	}
+	if first := replay.Messages[0]; first.Type != marketdata.MessageQuote || first.Quote.BidPrice != 15004 || first.Quote.MsgSeq != 7 {
+		t.Errorf("Expected the fourth quote first, got %+v", first)
+	}
+	if last := replay.Messages[3]; last.Type != marketdata.MessageTrade || last.Trade.TradeID != 5 {
+		t.Errorf("Expected the fifth trade last, got %+v", last)
+	}
+
+	if caughtUp := pub.Replay("AAPL", 11); caughtUp.Gone || caughtUp.Seq != 10 || len(caughtUp.Messages) != 0 {
+		t.Errorf("Expected nothing missed, got %+v", caughtUp)
+	}
+	if unknown := pub.Replay("MSFT", 0); unknown.Gone || unknown.Seq != 0 || len(unknown.Messages) != 0 {
+		t.Errorf("Expected an empty replay for a quiet symbol, got %+v", unknown)
+	}
+}
+
+// TestRetransmit_GoneWhenNoLongerKept verifies a replay from before the
+// history, or past the current seq, is marked gone and returns everything
+// kept.
+func TestRetransmit_GoneWhenNoLongerKept(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+	pub.SetRetransmitHistory(3)
+	for id := uint64(1); id <= 6; id++ {
+		pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: id})
+	}
+
+	for _, from := range []uint64{2, 9} {
+		replay := pub.Replay("AAPL", from)
+		if !replay.Gone || replay.Seq != 6 || len(replay.Messages) != 3 || replay.Messages[0].Seq != 4 {
+			t.Errorf("Expected from %d gone with seqs 4 to 6, got %+v", from, replay)
+		}
+	}
+	if replay := pub.Replay("AAPL", 4); replay.Gone || len(replay.Messages) != 3 {
+		t.Errorf("Expected the oldest kept seq replayed in full, got %+v", replay)
+	}
+}
+
+// TestRetransmit_SubscribeContinuesAfterReplay verifies live messages pick
+// up right after the replay, with nothing missed or repeated.
+func TestRetransmit_SubscribeContinuesAfterReplay(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000})
+	pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: 1})
+
+	replay, sub := pub.SubscribeMessages("AAPL", 2)
+	defer sub.Close()
+	if replay.Seq != 2 || len(replay.Messages) != 1 || replay.Messages[0].Type != marketdata.MessageTrade {
+		t.Fatalf("Expected the trade replayed, got %+v", replay)
+	}
+
+	pub.PublishL1(marketdata.L1Quote{Symbol: "MSFT", BidPrice: 30000})
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15001})
+	select {
+	case msg := <-sub.C:
+		if msg.Seq != 3 || msg.Type != marketdata.MessageQuote || msg.Quote.BidPrice != 15001 {
+			t.Errorf("Expected the AAPL quote at seq 3, got %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the live quote")
+	}
+}
+
+// TestRetransmit_ConflatedQuotesLeaveNoGaps verifies quotes the publisher
+// conflates away take no seq.
+func TestRetransmit_ConflatedQuotesLeaveNoGaps(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+	quotes := pub.SubscribeL1("AAPL")
+
+	pub.SetConflation(true)
+	for i := int64(1); i <= 10; i++ {
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000 + i})
+	}
+	pub.SetConflation(false)
+	pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: 1})
+
+	if q := <-quotes.C; q.MsgSeq != 1 || q.BidPrice != 15010 {
+		t.Errorf("Expected only the latest quote, at seq 1, got %+v", q)
+	}
+	if replay := pub.Replay("AAPL", 1); replay.Gone || replay.Seq != 2 || len(replay.Messages) != 2 {
+		t.Errorf("Expected the quote and the trade, got %+v", replay)
+	}
+}
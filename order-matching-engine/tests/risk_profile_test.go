@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/risk"
+)
+
+// ============================================================================
+// RISK PROFILES
+// ============================================================================
+
+func newProfiledChecker(t *testing.T) *risk.Checker {
+	t.Helper()
+	checker := risk.NewChecker(risk.DefaultConfig())
+	for name, profile := range risk.DefaultProfiles() {
+		if err := checker.SetProfile(name, profile); err != nil {
+			t.Fatalf("SetProfile(%s) failed: %v", name, err)
+		}
+	}
+	return checker
+}
+
+// TestRiskProfile_LimitsPerAccount verifies the same order passes or fails
+// depending on the profile its account is assigned.
+func TestRiskProfile_LimitsPerAccount(t *testing.T) {
+	checker := newProfiledChecker(t)
+	if err := checker.AssignProfile("SPONSORED1", risk.ProfileSponsored); err != nil {
+		t.Fatalf("AssignProfile failed: %v", err)
+	}
+	if err := checker.AssignProfile("MM1", risk.ProfileMarketMaker); err != nil {
+		t.Fatalf("AssignProfile failed: %v", err)
+	}
+
+	order := func(account string, qty int64) *orders.Order {
+		return &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 100, Quantity: qty, AccountID: account}
+	}
+
+	// 8,000 shares: over sponsored's 5,000, within the default 100,000
+	if result := checker.Check(order("SPONSORED1", 8000)); result.Passed {
+		t.Error("Expected the sponsored account to be rejected")
+	}
+	if result := checker.Check(order("TRADER1", 8000)); !result.Passed {
+		t.Errorf("Expected the default account to pass, got %s", result.Reason)
+	}
+
+	// 200,000 shares: only the market maker may send it
+	if result := checker.Check(order("TRADER1", 200000)); result.Passed {
+		t.Error("Expected the default account to be rejected")
+	}
+	if result := checker.Check(order("MM1", 200000)); !result.Passed {
+		t.Errorf("Expected the market maker to pass, got %s", result.Reason)
+	}
+}
+
+// TestRiskProfile_PriceBandPerAccount verifies the price band comes from the
+// account's profile.
+func TestRiskProfile_PriceBandPerAccount(t *testing.T) {
+	checker := newProfiledChecker(t)
+	checker.AssignProfile("SPONSORED1", risk.ProfileSponsored)
+	checker.SetReferencePrice("AAPL", 10000)
+
+	// 5% above reference: inside the default 10% band, outside sponsored's 3%
+	order := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 10500, Quantity: 10, AccountID: "SPONSORED1"}
+	if result := checker.Check(order); result.Passed {
+		t.Error("Expected the sponsored account to be outside its band")
+	}
+	order.AccountID = "TRADER1"
+	if result := checker.Check(order); !result.Passed {
+		t.Errorf("Expected the default account to pass, got %s", result.Reason)
+	}
+}
+
+// TestRiskProfile_AssignAndReset verifies unknown profiles are rejected,
+// redefining a profile applies to assigned accounts, and "default" clears
+// an assignment.
+func TestRiskProfile_AssignAndReset(t *testing.T) {
+	checker := newProfiledChecker(t)
+
+	if err := checker.AssignProfile("TRADER1", "no-such-profile"); err == nil {
+		t.Error("Expected an unknown profile to be rejected")
+	}
+	if err := checker.SetProfile(risk.DefaultProfileName, risk.DefaultConfig()); err == nil {
+		t.Error("Expected the default profile name to be reserved")
+	}
+
+	checker.AssignProfile("TRADER1", risk.ProfileRetail)
+	tight := risk.DefaultProfiles()[risk.ProfileRetail]
+	tight.MaxOrderSize = 10
+	checker.SetProfile(risk.ProfileRetail, tight)
+
+	name, limits := checker.AccountProfile("TRADER1")
+	if name != risk.ProfileRetail || limits.MaxOrderSize != 10 {
+		t.Errorf("Expected the redefined retail profile, got %s %+v", name, limits)
+	}
+
+	checker.AssignProfile("TRADER1", risk.DefaultProfileName)
+	if name, limits := checker.AccountProfile("TRADER1"); name != risk.DefaultProfileName || limits.MaxOrderSize != risk.DefaultConfig().MaxOrderSize {
+		t.Errorf("Expected the default limits, got %s %+v", name, limits)
+	}
+}
+
+// TestRiskProfile_AccountOverrides verifies an account's overrides replace
+// only the limits they set, on top of its profile, and clearing them
+// restores the profile's.
+func TestRiskProfile_AccountOverrides(t *testing.T) {
+	checker := newProfiledChecker(t)
+	checker.AssignProfile("TRADER1", risk.ProfileRetail)
+	order := &orders.Order{Symbol: "AAPL", Side: orders.SideBuy, Type: orders.OrderTypeLimit, Price: 100, Quantity: 5000, AccountID: "TRADER1"}
+
+	if err := checker.SetLimits("TRADER1", risk.Limits{MaxOrderSize: -1}); err == nil {
+		t.Error("Expected a negative limit rejected")
+	}
+	if err := checker.SetLimits("TRADER1", risk.Limits{MaxOrderSize: 2000, MaxDailyVolume: 25000000}); err != nil {
+		t.Fatal(err)
+	}
+	if result := checker.Check(order); result.Passed {
+		t.Error("Expected 5,000 shares over the overridden 2,000 share limit")
+	}
+	cfg := checker.EffectiveConfig("TRADER1")
+	retail := risk.DefaultProfiles()[risk.ProfileRetail]
+	if cfg.MaxOrderSize != 2000 || cfg.MaxDailyVolume != 25000000 || cfg.MaxPositionSize != retail.MaxPositionSize {
+		t.Errorf("Expected the overrides on top of retail, got %+v", cfg)
+	}
+	if name, limits := checker.AccountProfile("TRADER1"); name != risk.ProfileRetail || limits.MaxOrderSize != retail.MaxOrderSize {
+		t.Errorf("Expected the profile itself unchanged, got %s %+v", name, limits)
+	}
+
+	checker.SetLimits("TRADER1", risk.Limits{})
+	if result := checker.Check(order); !result.Passed {
+		t.Errorf("Expected the retail limits back, got %s", result.Reason)
+	}
+	if limits := checker.AccountLimits("TRADER1"); !limits.IsZero() {
+		t.Errorf("Expected no overrides left, got %+v", limits)
+	}
+}
+
+// TestRiskProfile_OverridesLogged verifies a change to an account's limits
+// is logged in sequence with orders and handed to the hook that applies it.
+func TestRiskProfile_OverridesLogged(t *testing.T) {
+	eventLog := openLog(t)
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	checker := newProfiledChecker(t)
+
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.OnRiskLimits(func(change *events.RiskLimitsEvent) {
+		checker.SetLimits(change.AccountID, risk.Limits{MaxOrderSize: change.MaxOrderSize})
+	})
+	run.processor.Start()
+
+	run.order(limit(orders.SideBuy, 15000, 100))
+	response := run.send(&disruptor.OrderRequest{
+		Type:       disruptor.RequestTypeRiskLimits,
+		RiskLimits: &events.RiskLimitsEvent{AccountID: "TRADER1", MaxOrderSize: 50, Actor: "alice@10.0.0.5:51234"},
+	})
+	run.order(limit(orders.SideBuy, 15000, 100))
+	run.processor.Shutdown()
+
+	if !response.Success {
+		t.Fatalf("Expected the change accepted, got %v", response.Error)
+	}
+	if limits := checker.AccountLimits("TRADER1"); limits.MaxOrderSize != 50 {
+		t.Errorf("Expected the hook to apply the change, got %+v", limits)
+	}
+
+	var logged []events.EventType
+	var change *events.RiskLimitsEvent
+	for _, event := range replayAll(t, eventLog) {
+		if typ := events.TypeOf(event); typ == events.EventTypeNewOrder || typ == events.EventTypeRiskLimits {
+			logged = append(logged, typ)
+		}
+		if e, ok := event.(*events.RiskLimitsEvent); ok {
+			change = e
+		}
+	}
+	want := []events.EventType{events.EventTypeNewOrder, events.EventTypeRiskLimits, events.EventTypeNewOrder}
+	if len(logged) != len(want) || logged[0] != want[0] || logged[1] != want[1] || logged[2] != want[2] {
+		t.Errorf("Expected the change logged between the orders, got %v", logged)
+	}
+	if change == nil || change.AccountID != "TRADER1" || change.MaxOrderSize != 50 || change.Actor != "alice@10.0.0.5:51234" {
+		t.Errorf("Expected the change and its author logged, got %+v", change)
+	}
+}
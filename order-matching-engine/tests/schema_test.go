@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// EVENT SCHEMA MIGRATION
+// ============================================================================
+
+// copyFixture copies a fixture log into a temp dir so tests can append to it.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// replayAll collects every event in the log.
+func replayAll(t *testing.T, eventLog *events.EventLog) []interface{} {
+	t.Helper()
+	var replayed []interface{}
+	err := eventLog.Replay(func(seqNum uint64, event interface{}) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return replayed
+}
+
+// TestSchema_ReplaysV1Fixture verifies a log written before records were
+// versioned replays as current event types.
+func TestSchema_ReplaysV1Fixture(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: copyFixture(t, "events_v1.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+
+	replayed := replayAll(t, eventLog)
+	if len(replayed) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(replayed))
+	}
+
+	newOrder, ok := replayed[0].(*events.NewOrderEvent)
+	if !ok {
+		t.Fatalf("Expected *NewOrderEvent, got %T", replayed[0])
+	}
+	if newOrder.OrderID != 1 || newOrder.Symbol != "AAPL" || newOrder.Side != orders.SideSell ||
+		newOrder.Price != 150000 || newOrder.Quantity != 100 || newOrder.ClientOrderID != "mm-1" {
+		t.Errorf("Unexpected migrated order: %+v", newOrder)
+	}
+	if newOrder.SessionID != "" {
+		t.Errorf("v1 orders predate sessions, got SessionID %q", newOrder.SessionID)
+	}
+
+	fill, ok := replayed[2].(*events.FillEvent)
+	if !ok || fill.TradeID != 1 || fill.Quantity != 40 {
+		t.Errorf("Unexpected fill: %+v", replayed[2])
+	}
+	if _, ok := replayed[3].(*events.OrderCancelledEvent); !ok {
+		t.Errorf("Expected *OrderCancelledEvent, got %T", replayed[3])
+	}
+}
+
+// TestSchema_AppendToV1Log verifies a log can mix records from both
+// schema versions and still drives recovery.
+func TestSchema_AppendToV1Log(t *testing.T) {
+	path := copyFixture(t, "events_v1.log")
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := eventLog.Append(&events.NewOrderEvent{
+		OrderID: 3, Symbol: "AAPL", Side: orders.SideBuy, Quantity: 10, SessionID: "WS-7",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	eventLog.Close()
+
+	eventLog, err = events.NewEventLog(events.EventLogConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+
+	replayed := replayAll(t, eventLog)
+	if len(replayed) != 5 {
+		t.Fatalf("Expected 5 events, got %d", len(replayed))
+	}
+	if e, ok := replayed[4].(*events.NewOrderEvent); !ok || e.SessionID != "WS-7" {
+		t.Errorf("Expected v2 order with session WS-7, got %+v", replayed[4])
+	}
+
+	counters, err := matching.RecoverIDCounters(eventLog)
+	if err != nil {
+		t.Fatalf("RecoverIDCounters failed: %v", err)
+	}
+	want := matching.IDCounters{OrderID: 3, TradeID: 1, SequenceNum: 3}
+	if counters != want {
+		t.Errorf("Recovered counters %+v, want %+v", counters, want)
+	}
+}
+
+// TestSchema_RejectsFutureVersion verifies events from a newer schema are
+// refused rather than misread.
+func TestSchema_RejectsFutureVersion(t *testing.T) {
+	_, err := events.Upgrade(events.SchemaVersion+1, &events.FillEvent{})
+	if !errors.Is(err, events.ErrUnsupportedVersion) {
+		t.Fatalf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	event, err := events.Upgrade(events.SchemaVersion, &events.FillEvent{TradeID: 9})
+	if err != nil || event.(*events.FillEvent).TradeID != 9 {
+		t.Fatalf("Current-version event should pass through unchanged, got %+v, %v", event, err)
+	}
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// ============================================================================
+// EVENT LOG SEGMENTS (ROTATION + RETENTION)
+// ============================================================================
+
+// openSegmented opens a log that rotates every 1KB (a handful of events).
+func openSegmented(t *testing.T, path string, retention events.Retention) *events.EventLog {
+	t.Helper()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: path, SegmentMaxBytes: 1024, Retention: retention})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eventLog
+}
+
+func appendOrders(t *testing.T, eventLog *events.EventLog, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := eventLog.Append(&events.NewOrderEvent{OrderID: uint64(i + 1), Symbol: "AAPL", Quantity: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// replaySeqs collects the sequence numbers ReplayFrom hands out.
+func replaySeqs(eventLog *events.EventLog, after uint64) ([]uint64, error) {
+	var seqs []uint64
+	err := eventLog.ReplayFrom(after, func(seqNum uint64, event interface{}) error {
+		seqs = append(seqs, seqNum)
+		return nil
+	})
+	return seqs, err
+}
+
+// TestSegments_RotateAndReplayInOrder verifies the log rotates into
+// contiguous segments and replays across them, before and after a restart.
+func TestSegments_RotateAndReplayInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openSegmented(t, path, events.Retention{})
+	appendOrders(t, eventLog, 100)
+
+	segments := eventLog.Segments()
+	if len(segments) < 3 {
+		t.Fatalf("Expected several segments, got %d", len(segments))
+	}
+	next := uint64(1)
+	for _, seg := range segments {
+		if seg.FirstSeq != next || seg.LastSeq < seg.FirstSeq {
+			t.Fatalf("Segments not contiguous at %+v, expected first %d", seg, next)
+		}
+		next = seg.LastSeq + 1
+	}
+	eventLog.Close()
+
+	eventLog = openSegmented(t, path, events.Retention{})
+	defer eventLog.Close()
+	if eventLog.GetLastSequence() != 100 || len(eventLog.Segments()) != len(segments) {
+		t.Fatalf("Expected seq 100 and %d segments after reopen, got %d and %d",
+			len(segments), eventLog.GetLastSequence(), len(eventLog.Segments()))
+	}
+	appendOrders(t, eventLog, 1)
+
+	seqs, err := replaySeqs(eventLog, 0)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(seqs) != 101 || seqs[0] != 1 || seqs[100] != 101 {
+		t.Errorf("Expected events 1-101 in order, got %d events", len(seqs))
+	}
+}
+
+// TestSegments_ReplayFromSkipsClosedSegments verifies a replay from a
+// snapshot never opens segments that end before it.
+func TestSegments_ReplayFromSkipsClosedSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openSegmented(t, path, events.Retention{})
+	defer eventLog.Close()
+	appendOrders(t, eventLog, 100)
+
+	// Damage the first segment: only a full replay reads it
+	first := eventLog.Segments()[0]
+	if err := os.WriteFile(first.Path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	seqs, err := replaySeqs(eventLog, first.LastSeq)
+	if err != nil {
+		t.Fatalf("ReplayFrom failed: %v", err)
+	}
+	if len(seqs) != int(100-first.LastSeq) || seqs[0] != first.LastSeq+1 {
+		t.Errorf("Expected events %d-100, got %d events", first.LastSeq+1, len(seqs))
+	}
+	if _, err := replaySeqs(eventLog, 0); err == nil {
+		t.Error("Expected a full replay to hit the damaged segment")
+	}
+}
+
+// TestSegments_RetentionWaitsForFloor verifies segments are only deleted
+// once covered by the retention floor, and replay before them reports
+// truncation.
+func TestSegments_RetentionWaitsForFloor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openSegmented(t, path, events.Retention{MaxSegments: 1})
+	defer eventLog.Close()
+	appendOrders(t, eventLog, 100)
+
+	total := len(eventLog.Segments())
+	if total < 3 {
+		t.Fatalf("Expected several segments, got %d", total)
+	}
+
+	// Nothing is covered by a snapshot yet
+	if _, err := replaySeqs(eventLog, 0); err != nil {
+		t.Fatalf("Expected a full log before any floor, got %v", err)
+	}
+
+	floor := eventLog.Segments()[total-1].LastSeq
+	if err := eventLog.SetRetentionFloor(floor); err != nil {
+		t.Fatal(err)
+	}
+	segments := eventLog.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment kept, got %d", len(segments))
+	}
+	if matches, _ := filepath.Glob(path + ".0*"); len(matches) != 1 {
+		t.Errorf("Expected 1 segment file on disk, got %d", len(matches))
+	}
+
+	if _, err := replaySeqs(eventLog, 0); !errors.Is(err, events.ErrTruncated) {
+		t.Errorf("Expected ErrTruncated replaying deleted events, got %v", err)
+	}
+	seqs, err := replaySeqs(eventLog, floor)
+	if err != nil || len(seqs) != int(100-floor) {
+		t.Errorf("Expected the %d events after the floor, got %d (%v)", 100-floor, len(seqs), err)
+	}
+}
+
+// TestSegments_ArchiveKeepsReplayable verifies archived segments leave the
+// log directory but still replay.
+func TestSegments_ArchiveKeepsReplayable(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	path := filepath.Join(dir, "events.log")
+	eventLog := openSegmented(t, path, events.Retention{MaxSegments: 1, ArchiveDir: archive})
+	appendOrders(t, eventLog, 100)
+	eventLog.SetRetentionFloor(eventLog.GetLastSequence())
+	eventLog.Close()
+
+	eventLog = openSegmented(t, path, events.Retention{MaxSegments: 1, ArchiveDir: archive})
+	defer eventLog.Close()
+	archived := 0
+	for _, seg := range eventLog.Segments() {
+		if seg.Archived {
+			archived++
+			if filepath.Dir(seg.Path) != archive {
+				t.Errorf("Archived segment at %s, expected in %s", seg.Path, archive)
+			}
+		}
+	}
+	if archived == 0 || archived != len(eventLog.Segments())-1 {
+		t.Errorf("Expected all but 1 segment archived, got %d of %d", archived, len(eventLog.Segments()))
+	}
+
+	seqs, err := replaySeqs(eventLog, 0)
+	if err != nil || len(seqs) != 100 {
+		t.Errorf("Expected 100 events replayed through the archive, got %d (%v)", len(seqs), err)
+	}
+}
+
+// TestSegments_RecoversUnlistedSegment verifies a segment rotated just
+// before a crash, before the manifest was rewritten, is adopted on open.
+func TestSegments_RecoversUnlistedSegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog := openSegmented(t, path, events.Retention{})
+	appendOrders(t, eventLog, 100)
+	want := len(eventLog.Segments())
+	eventLog.Close()
+
+	if err := os.Remove(path + ".manifest"); err != nil {
+		t.Fatal(err)
+	}
+	eventLog = openSegmented(t, path, events.Retention{})
+	defer eventLog.Close()
+	if len(eventLog.Segments()) != want || eventLog.GetLastSequence() != 100 {
+		t.Fatalf("Expected %d segments to seq 100, got %d to seq %d",
+			want, len(eventLog.Segments()), eventLog.GetLastSequence())
+	}
+	if seqs, err := replaySeqs(eventLog, 0); err != nil || len(seqs) != 100 {
+		t.Errorf("Expected 100 events, got %d (%v)", len(seqs), err)
+	}
+}
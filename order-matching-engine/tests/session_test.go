@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// SESSION TRACKING (Cancel-on-Disconnect)
+// ============================================================================
+
+// TestSession_CancelOnDisconnect verifies that only the session's resting
+// orders are cancelled, and that filled orders are no longer tracked.
+func TestSession_CancelOnDisconnect(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.AddSymbol("MSFT")
+
+	place := func(symbol string, side orders.Side, price, qty int64, session string) *orders.Order {
+		o := &orders.Order{Symbol: symbol, Side: side, Type: orders.OrderTypeLimit, Price: price, Quantity: qty, AccountID: "MM1", SessionID: session}
+		engine.ProcessOrder(o)
+		return o
+	}
+
+	place("AAPL", orders.SideBuy, 14900, 100, "WS-1")
+	filled := place("AAPL", orders.SideSell, 15100, 50, "WS-1")
+	place("MSFT", orders.SideSell, 30000, 100, "WS-1")
+	other := place("AAPL", orders.SideBuy, 14800, 100, "WS-2")
+	stateless := place("AAPL", orders.SideBuy, 14700, 100, "")
+
+	// Fully fill one of WS-1's orders; it must drop out of session tracking
+	place("AAPL", orders.SideBuy, 15100, 50, "")
+	if filled.Status != orders.OrderStatusFilled {
+		t.Fatalf("Expected order %d filled, got %s", filled.ID, filled.Status)
+	}
+	if n := engine.SessionOrderCount("WS-1"); n != 2 {
+		t.Fatalf("Expected 2 tracked orders for WS-1, got %d", n)
+	}
+
+	cancelled := engine.CancelSessionOrders("WS-1")
+	if len(cancelled) != 2 {
+		t.Fatalf("Expected 2 cancelled orders, got %d", len(cancelled))
+	}
+	if cancelled[0].ID > cancelled[1].ID {
+		t.Errorf("Expected cancels in order ID sequence, got %d then %d", cancelled[0].ID, cancelled[1].ID)
+	}
+
+	if engine.GetOrder("AAPL", other.ID) == nil || engine.GetOrder("AAPL", stateless.ID) == nil {
+		t.Errorf("Orders from other sessions must not be cancelled")
+	}
+	if engine.SessionOrderCount("WS-1") != 0 || len(engine.CancelSessionOrders("WS-1")) != 0 {
+		t.Errorf("Session WS-1 should have nothing left to cancel")
+	}
+}
+
+// TestSession_CancelOnlyOwnOrders verifies a cancel on behalf of an account
+// leaves another account's order resting and reports it as not found.
+func TestSession_CancelOnlyOwnOrders(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run := startRun(t, engine, openLog(t), settlement.NewClearingHouse(), nil, 0)
+	defer run.processor.Shutdown()
+
+	placed := run.order(limit(orders.SideBuy, 14900, 100))
+	cancel := func(accountID string) *disruptor.OrderResponse {
+		return run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: placed.ID, AccountID: accountID})
+	}
+
+	if response := cancel("T2"); response.Success || response.Error == nil || response.Order != nil {
+		t.Fatalf("Expected T2's cancel of T1's order to fail, got %+v", response)
+	}
+	if engine.GetOrder("AAPL", placed.ID) == nil {
+		t.Fatal("Expected the order to still rest")
+	}
+	if response := cancel("T1"); !response.Success || response.Order.ID != placed.ID {
+		t.Fatalf("Expected T1's cancel to succeed, got %+v", response)
+	}
+}
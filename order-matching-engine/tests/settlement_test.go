@@ -0,0 +1,372 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/calendar"
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+)
+
+// ============================================================================
+// SETTLEMENT CYCLE (T+N Scheduler)
+// ============================================================================
+
+// fakeClock is a settable clock for the clearing house.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// buy records a trade of qty AAPL at price from seller to buyer.
+func buy(ch *settlement.ClearingHouse, id uint64, buyer, seller string, qty, price int64) {
+	ch.RecordTrade(orders.Fill{
+		TradeID: id, Symbol: "AAPL", Price: price, Quantity: qty,
+		TakerAccountID: buyer, MakerAccountID: seller, TakerSide: orders.SideBuy,
+	})
+}
+
+// TestSettlement_CycleFollowsClock verifies trades clear the business day
+// after the trade date and settle, netted, on the settle date, skipping
+// holidays, as the clock moves.
+func TestSettlement_CycleFollowsClock(t *testing.T) {
+	calendars, err := calendar.Parse([]byte(calendarFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: day("2026-11-25")} // Wednesday before Thanksgiving
+	ch := settlement.NewClearingHouse()
+	ch.SetCalendars(calendars, func(string) string { return "XNYS" })
+	ch.SetSettlementDays(1)
+	ch.SetClock(clock.Now)
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 1000)
+
+	buy(ch, 1, "A", "B", 100, 15000)
+	buy(ch, 2, "B", "A", 20, 15100)
+
+	step := func(date string, want string) []settlement.SettlementInstruction {
+		t.Helper()
+		clock.now = day(date)
+		settled, err := ch.Advance()
+		if err != nil {
+			t.Fatalf("%s: %v", date, err)
+		}
+		for _, trade := range ch.Export().Trades {
+			if trade.Status.String() != want {
+				t.Errorf("%s: expected trade %d %s, got %s", date, trade.ID, want, trade.Status)
+			}
+		}
+		return settled
+	}
+
+	step("2026-11-25", "EXECUTED")
+	step("2026-11-26", "CLEARING") // Thanksgiving: cleared, settles the next business day
+	settled := step("2026-11-27", "SETTLED")
+
+	if len(settled) != 1 || settled[0].FromAccount != "B" || settled[0].ToAccount != "A" ||
+		settled[0].Quantity != 80 || settled[0].CashAmount != 1198000 || len(settled[0].TradeIDs) != 2 {
+		t.Fatalf("Expected one netted instruction B->A 80 for $11980.00, got %+v", settled)
+	}
+	if a := ch.GetAccount("A"); a.Holdings["AAPL"] != 80 || a.Cash != 10000000-1198000 {
+		t.Errorf("Expected A to hold 80 shares and have paid $11980.00, got %+v", a)
+	}
+	if b := ch.GetAccount("B"); b.Holdings["AAPL"] != 920 || b.Cash != 1198000 {
+		t.Errorf("Expected B to hold 920 shares and have received $11980.00, got %+v", b)
+	}
+	if stats := ch.GetSettlementStats(); stats["instructions"] != 0 {
+		t.Errorf("Expected settled instructions dropped, got %d", stats["instructions"])
+	}
+}
+
+// TestSettlement_SameDayFailure verifies a T+0 trade settles on the trade
+// date, and that one whose seller cannot deliver fails and is reported.
+func TestSettlement_SameDayFailure(t *testing.T) {
+	clock := &fakeClock{now: day("2026-11-24")}
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(0)
+	ch.SetClock(clock.Now)
+	var failures []string
+	ch.OnSettlementFail(func(instr settlement.SettlementInstruction, reason string) {
+		failures = append(failures, reason)
+	})
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.GetOrCreateAccount("B", 0) // No shares to deliver
+
+	buy(ch, 1, "A", "B", 10, 15000)
+	if _, err := ch.Advance(); err == nil {
+		t.Fatal("Expected the settlement to fail")
+	}
+	trade := ch.Export().Trades[0]
+	if trade.Status != settlement.TradeStatusFailed || trade.SettleDate.Format("2006-01-02") != "2026-11-24" {
+		t.Errorf("Expected the trade to fail on its trade date, got %s settling %s", trade.Status, trade.SettleDate)
+	}
+	if len(failures) != 1 {
+		t.Errorf("Expected one failure reported, got %v", failures)
+	}
+	if a := ch.GetAccount("A"); a.Cash != 10000000 || a.Holdings["AAPL"] != 0 {
+		t.Errorf("Expected nothing to move, got %+v", a)
+	}
+}
+
+// TestSettlement_ProcessorTimers verifies an event processor runs the
+// settlement cycle on its timers, settling the trades of the fills it
+// recorded.
+func TestSettlement_ProcessorTimers(t *testing.T) {
+	clock := &fakeClock{now: day("2026-11-24")} // Set before the processor reads it
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(0)
+	ch.SetClock(clock.Now)
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 1000)
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, openLog(t))}
+	run.processor.EnableClearing(ch)
+	run.processor.EnableTimers(5 * time.Millisecond)
+	run.processor.SettleEvery(20 * time.Millisecond)
+	run.processor.Start()
+	defer run.processor.Shutdown()
+
+	sell := limit(orders.SideSell, 15000, 100)
+	sell.AccountID = "B"
+	run.order(sell)
+	for i := 0; i < 2; i++ {
+		buy := limit(orders.SideBuy, 15000, 50)
+		buy.AccountID = "A"
+		run.order(buy)
+
+		var trades []settlement.Trade
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if trades = ch.Export().Trades; len(trades) == i+1 && trades[i].Status == settlement.TradeStatusSettled {
+				break
+			}
+		}
+		if len(trades) != i+1 || trades[i].Status != settlement.TradeStatusSettled {
+			t.Fatalf("Fill %d: expected its trade settled by the processor, got %+v", i+1, trades)
+		}
+	}
+	if a := ch.GetAccount("A"); a.Holdings["AAPL"] != 100 || a.Cash != 10000000-1500000 {
+		t.Errorf("Expected A to hold 100 shares and have paid $15000.00, got %+v", a)
+	}
+}
+
+// TestSettlement_MarginCall verifies a price move against an account's
+// unsettled position issues a margin call that holds its settlement, and
+// that posting collateral meets it.
+func TestSettlement_MarginCall(t *testing.T) {
+	clock := &fakeClock{now: day("2026-11-23")}
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(1)
+	ch.SetClock(clock.Now)
+	marks := map[string]int64{"AAPL": 15000}
+	ch.SetMargin(settlement.MarginConfig{InitialBps: 1000}, func(symbol string) int64 { return marks[symbol] })
+	var calls []settlement.Margin
+	ch.OnMarginCall(func(m settlement.Margin) { calls = append(calls, m) })
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 1000)
+	if _, err := ch.PostCollateral("A", 200000); err != nil { // $2,000
+		t.Fatal(err)
+	}
+
+	buy(ch, 1, "A", "B", 100, 15000)
+	ch.CheckMargin("AAPL")
+	if m := ch.GetMargin("A"); m.Initial != 150000 || m.Variation != 0 || m.Excess != 50000 || len(calls) != 0 {
+		t.Fatalf("Expected $1,500 initial margin and $500 excess, got %+v (calls %v)", m, calls)
+	}
+	if _, err := ch.PostCollateral("A", -100000); err == nil {
+		t.Error("Expected a withdrawal leaving A under-margined to be refused")
+	}
+
+	marks["AAPL"] = 14000 // A has lost $1,000
+	ch.CheckMargin("AAPL")
+	if len(calls) != 1 || calls[0].AccountID != "A" || calls[0].Call != 40000 || ch.MarginCall("A") != 40000 {
+		t.Fatalf("Expected a $400 margin call on A, got %+v", calls)
+	}
+
+	clock.now = day("2026-11-24") // Settle date: held by the call
+	if settled, err := ch.Advance(); err != nil || len(settled) != 0 {
+		t.Fatalf("Expected settlement held for the margin call, got %+v, %v", settled, err)
+	}
+
+	if m, err := ch.PostCollateral("A", 40000); err != nil || m.Call != 0 || m.Excess != 0 {
+		t.Fatalf("Expected posting $400 to meet the call, got %+v, %v", m, err)
+	}
+	if len(calls) != 2 || calls[1].Call != 0 {
+		t.Errorf("Expected the call reported met, got %+v", calls)
+	}
+	if settled, err := ch.Advance(); err != nil || len(settled) != 1 {
+		t.Fatalf("Expected the trade to settle once the call was met, got %+v, %v", settled, err)
+	}
+	if m := ch.GetMargin("A"); m.Initial != 0 || m.Collateral != 240000 {
+		t.Errorf("Expected no margin on settled trades, got %+v", m)
+	}
+	if a := ch.GetAccount("A"); a.Cash != 10000000-240000-1500000 || a.Holdings["AAPL"] != 100 {
+		t.Errorf("Expected A to have paid for 100 shares out of cash, got %+v", a)
+	}
+}
+
+// TestSettlement_PartialRetryBuyIn verifies a deliverer short of shares
+// settles what it holds, is retried on the next business day, and is then
+// bought in at its own expense.
+func TestSettlement_PartialRetryBuyIn(t *testing.T) {
+	clock := &fakeClock{now: day("2026-11-16")}
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(1)
+	ch.SetClock(clock.Now)
+	ch.SetFailConfig(settlement.FailConfig{Retries: 5, BuyInAfter: 2, BuyInPremiumBps: 100})
+	ch.SetMargin(settlement.MarginConfig{}, func(string) int64 { return 15500 })
+	var kinds []settlement.SettlementEventKind
+	ch.OnSettlementEvent(func(event settlement.SettlementEvent) { kinds = append(kinds, event.Kind) })
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 60) // Sells 100
+
+	buy(ch, 1, "A", "B", 100, 15000)
+	clock.now = day("2026-11-17")
+	settled, err := ch.Advance()
+	if err == nil || len(settled) != 1 || settled[0].Quantity != 60 || settled[0].CashAmount != 900000 {
+		t.Fatalf("Expected 60 shares settled for $9,000 and the rest failed, got %+v, %v", settled, err)
+	}
+	if settled, err := ch.Advance(); err != nil || len(settled) != 0 {
+		t.Fatalf("Expected no retry before the next business day, got %+v, %v", settled, err)
+	}
+	if trade := ch.Export().Trades[0]; trade.Status != settlement.TradeStatusReadyToSettle {
+		t.Errorf("Expected the trade to wait for the retry, got %s", trade.Status)
+	}
+
+	clock.now = day("2026-11-18") // Second failed attempt: bought in @ $156.55
+	settled, err = ch.Advance()
+	if err != nil || len(settled) != 1 || settled[0].Quantity != 40 || settled[0].Attempts != 2 {
+		t.Fatalf("Expected the 40 shares short bought in, got %+v, %v", settled, err)
+	}
+	if a := ch.GetAccount("A"); a.Holdings["AAPL"] != 100 || a.Cash != 10000000-1500000 {
+		t.Errorf("Expected A to hold 100 shares paid for at $150, got %+v", a)
+	}
+	if b := ch.GetAccount("B"); b.Holdings["AAPL"] != 0 || b.Cash != 1500000-626200 {
+		t.Errorf("Expected B to have received $15,000 and paid $6,262 for the buy-in, got %+v", b)
+	}
+	if trade := ch.Export().Trades[0]; trade.Status != settlement.TradeStatusSettled {
+		t.Errorf("Expected the trade settled, got %s", trade.Status)
+	}
+	want := []settlement.SettlementEventKind{settlement.SettlementInstructed, settlement.SettlementPartial,
+		settlement.SettlementRetry, settlement.SettlementBoughtIn}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) || len(ch.Events(0)) != 4 {
+		t.Errorf("Expected events %v, got %v", want, kinds)
+	}
+}
+
+// TestSettlement_JournalRecovery verifies a clearing house reopened on its
+// journal has the balances, trades, pending instructions and events it had,
+// that a torn last record is dropped, and that a trade replayed after the
+// restart is not recorded twice.
+func TestSettlement_JournalRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clearing.log")
+	clock := &fakeClock{now: day("2026-11-16")}
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(1)
+	ch.SetClock(clock.Now)
+	ch.SetFailConfig(settlement.FailConfig{Retries: 5})
+	if _, err := ch.OpenJournal(path, false); err != nil {
+		t.Fatal(err)
+	}
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 60)
+	ch.RecordTrade(orders.Fill{
+		TradeID: 1, Symbol: "AAPL", Price: 15000, Quantity: 100, TakerFee: 450,
+		TakerAccountID: "A", MakerAccountID: "B", TakerSide: orders.SideBuy,
+	})
+	clock.now = day("2026-11-17")
+	buy(ch, 2, "A", "B", 10, 15100) // Settles the day after
+	ch.Advance()                    // 60 of trade 1 settle, 40 retried
+	if err := ch.CloseJournal(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"Seq":99,"Accounts":[{"ID":"A","Ca`) // Crash mid-write
+	file.Close()
+
+	recovered := settlement.NewClearingHouse()
+	records, err := recovered.OpenJournal(path, false)
+	if err != nil || records == 0 || !recovered.Journaled() {
+		t.Fatalf("Expected the journal recovered, got %d records, %v", records, err)
+	}
+	if want, got := ch.Export(), recovered.Export(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected the books recovered\nwant %+v\n got %+v", want, got)
+	}
+	if want, got := ch.Events(0), recovered.Events(0); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected the settlement events recovered\nwant %+v\n got %+v", want, got)
+	}
+	if stats := recovered.GetSettlementStats(); stats["ready"] != 1 || stats["executed"] != 1 || stats["instructions"] != 1 {
+		t.Errorf("Expected trade 1 waiting on its retry and trade 2 executed, got %v", stats)
+	}
+	if got := recovered.BuyingPower("A"); got != ch.BuyingPower("A") {
+		t.Errorf("Expected unsettled trades owed again after recovery, got buying power %d, want %d", got, ch.BuyingPower("A"))
+	}
+
+	recovered.RecordTrade(orders.Fill{ // Replayed from the event log
+		TradeID: 1, Symbol: "AAPL", Price: 15000, Quantity: 100, TakerFee: 450,
+		TakerAccountID: "A", MakerAccountID: "B", TakerSide: orders.SideBuy,
+	})
+	if a := recovered.GetAccount("A"); a.Fees != 450 || len(recovered.Export().Trades) != 2 {
+		t.Errorf("Expected the replayed trade ignored, got fees %d and %d trades", a.Fees, len(recovered.Export().Trades))
+	}
+	recovered.CloseJournal()
+}
+
+// TestSettlement_Report verifies the settlement report for a settle date
+// nets its trades, lists the day's settlement steps and the instructions
+// still failing, and that an account's trades are reported by trade date.
+func TestSettlement_Report(t *testing.T) {
+	clock := &fakeClock{now: day("2026-11-16")}
+	ch := settlement.NewClearingHouse()
+	ch.SetSettlementDays(1)
+	ch.SetClock(clock.Now)
+	ch.SetFailConfig(settlement.FailConfig{Retries: 5})
+	ch.GetOrCreateAccount("A", 10000000)
+	ch.DepositShares("B", "AAPL", 60)
+
+	buy(ch, 1, "A", "B", 100, 15000)
+	buy(ch, 2, "B", "A", 30, 15100) // Nets against trade 1
+	clock.now = day("2026-11-17")
+	ch.Advance() // 60 of the net 70 settle, 10 retried
+
+	report := ch.SettlementReport("2026-11-17")
+	if len(report.Netting) != 2 {
+		t.Fatalf("Expected A and B netted, got %+v", report.Netting)
+	}
+	if a := report.Netting[0]; a.AccountID != "A" || a.Trades != 2 || a.Bought != 100 || a.Sold != 30 ||
+		a.NetQty != 70 || a.NetValue != 1500000-453000 {
+		t.Errorf("Expected A net long 70 for $10,470, got %+v", a)
+	}
+	var kinds []settlement.SettlementEventKind
+	for _, step := range report.Steps {
+		kinds = append(kinds, step.Kind)
+	}
+	if fmt.Sprint(kinds) != "[INSTRUCTED PARTIAL RETRY]" {
+		t.Errorf("Expected the day's steps, got %v", kinds)
+	}
+	if len(report.Failing) != 1 || report.Failing[0].Quantity != 10 || report.Failing[0].Attempts != 1 {
+		t.Errorf("Expected the 10 shares short failing, got %+v", report.Failing)
+	}
+	if other := ch.SettlementReport("2026-11-18"); len(other.Netting) != 0 || len(other.Steps) != 0 || len(other.Failing) != 1 {
+		t.Errorf("Expected only the failing instruction reported the next day, got %+v", other)
+	}
+
+	if trades := ch.AccountTrades("A", "2026-11-16", "2026-11-16"); len(trades) != 2 || trades[0].ID != 1 {
+		t.Errorf("Expected A's two trades on the 16th, got %+v", trades)
+	}
+	if trades := ch.AccountTrades("A", "2026-11-17", ""); len(trades) != 0 {
+		t.Errorf("Expected no trades from the 17th, got %+v", trades)
+	}
+}
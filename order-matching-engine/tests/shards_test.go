@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/shard"
+)
+
+// ============================================================================
+// SYMBOL SHARDS
+// ============================================================================
+
+// symbolsBySharding returns two symbols that hash to different shards of two.
+func symbolsBySharding(t *testing.T) (string, string) {
+	t.Helper()
+	candidates := []string{"AAPL", "GOOGL", "MSFT", "AMZN", "TSLA", "NVDA", "META"}
+	for _, other := range candidates[1:] {
+		if shard.Index(other, 2) != shard.Index(candidates[0], 2) {
+			return candidates[0], other
+		}
+	}
+	t.Fatal("Every candidate symbol hashes to one shard")
+	return "", ""
+}
+
+// TestShards_IDsUniqueAcrossShards verifies engines striding IDs never issue
+// the same order or trade ID, and a shard's log replays without divergence.
+func TestShards_IDsUniqueAcrossShards(t *testing.T) {
+	seen := make(map[uint64]int)
+	for i := 0; i < 2; i++ {
+		engine := matching.NewEngine()
+		engine.AddSymbol("AAPL")
+		engine.SetIDStride(2, i)
+		eventLog := openLog(t)
+		run := startRun(t, engine, eventLog, settlement.NewClearingHouse(), nil, 0)
+		for n := 0; n < 5; n++ {
+			run.order(limit(orders.SideSell, 15000, 100))
+			bid := run.order(limit(orders.SideBuy, 15000, 100))
+			if bid.ID%2 != uint64(i+1)%2 {
+				t.Errorf("Shard %d issued order ID %d", i, bid.ID)
+			}
+		}
+		run.processor.Shutdown()
+
+		replayed := matching.NewEngine()
+		replayed.SetIDStride(2, i)
+		replayer := matching.NewReplayer(replayed)
+		for _, event := range replayAll(t, eventLog) {
+			fills, err := replayer.Apply(event)
+			if err != nil {
+				t.Fatalf("Shard %d replay failed: %v", i, err)
+			}
+			for _, fill := range fills {
+				if other, dup := seen[fill.TradeID]; dup && other != i {
+					t.Errorf("Trade ID %d issued by shards %d and %d", fill.TradeID, other, i)
+				}
+				seen[fill.TradeID] = i
+			}
+		}
+		if counters := engine.IDCounters(); counters.TradeID == 0 {
+			t.Errorf("Shard %d issued no trade IDs", i)
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected 10 distinct trade IDs, got %d", len(seen))
+	}
+}
+
+// TestShards_Route verifies single-symbol requests go to their symbol's
+// shard, requests spanning symbols to every shard, and a basket only to one
+// shard when all its legs are on it.
+func TestShards_Route(t *testing.T) {
+	a, b := symbolsBySharding(t)
+	set := shard.NewSet(&shard.Shard{Index: 0}, &shard.Shard{Index: 1})
+	shardA, shardB := set.For(a), set.For(b)
+
+	single := []*disruptor.OrderRequest{
+		{Type: disruptor.RequestTypeNewOrder, Order: &orders.Order{Symbol: b}},
+		{Type: disruptor.RequestTypeCancelOrder, Symbol: b, OrderID: 7},
+		{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{Symbol: b}},
+		{Type: disruptor.RequestTypeOpenOrders, AccountID: "T1", Symbol: b},
+		{Type: disruptor.RequestTypeBasket, Legs: []*orders.Order{{Symbol: b}, {Symbol: b}}},
+	}
+	for _, req := range single {
+		if got := set.Route(req); got != shardB {
+			t.Errorf("Request type %d routed to %v, expected shard %d", req.Type, got, shardB.Index)
+		}
+	}
+
+	spanning := []*disruptor.OrderRequest{
+		{Type: disruptor.RequestTypeMassCancel, SessionID: "s1"},
+		{Type: disruptor.RequestTypeHeartbeat, SessionID: "s1"},
+		{Type: disruptor.RequestTypeOpenOrders, AccountID: "T1"},
+		{Type: disruptor.RequestTypeOrderStatus, OrderID: 7},
+		{Type: disruptor.RequestTypeBasket, Legs: []*orders.Order{{Symbol: a}, {Symbol: b}}},
+	}
+	for _, req := range spanning {
+		if got := set.Route(req); got != nil {
+			t.Errorf("Request type %d routed to shard %d, expected every shard", req.Type, got.Index)
+		}
+	}
+	if shardA == shardB {
+		t.Errorf("%s and %s routed to the same shard", a, b)
+	}
+}
+
+// TestShards_CheckLayout verifies a log is only reopened with the shard
+// count it was written with, and an unsharded log counts as one shard.
+func TestShards_CheckLayout(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "events.log")
+	if err := shard.CheckLayout(base, 4); err != nil {
+		t.Fatalf("Fresh layout refused: %v", err)
+	}
+	if err := shard.CheckLayout(base, 4); err != nil {
+		t.Errorf("Same shard count refused: %v", err)
+	}
+	if err := shard.CheckLayout(base, 2); err == nil {
+		t.Error("Expected a different shard count refused")
+	}
+	if err := shard.CheckLayout(base, 1); err == nil {
+		t.Error("Expected a single shard refused for a sharded log")
+	}
+	if got := shard.LogPath(base, 3, 4); got != filepath.Join(filepath.Dir(base), "events.shard-3.log") {
+		t.Errorf("Unexpected shard log path %s", got)
+	}
+
+	unsharded := filepath.Join(t.TempDir(), "events.log")
+	if err := os.WriteFile(unsharded, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := shard.CheckLayout(unsharded, 1); err != nil {
+		t.Errorf("Unsharded log refused with one shard: %v", err)
+	}
+	if err := shard.CheckLayout(unsharded, 2); err == nil {
+		t.Error("Expected an unsharded log refused with two shards")
+	}
+	if got := shard.LogPath(unsharded, 0, 1); got != unsharded {
+		t.Errorf("Expected a single shard to keep the log path, got %s", got)
+	}
+}
@@ -0,0 +1,503 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/disruptor"
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/settlement"
+	"github.com/rishav/order-matching-engine/internal/snapshot"
+)
+
+// ============================================================================
+// BOOK SNAPSHOTS (FULL IMAGE + DELTAS)
+// ============================================================================
+
+func limit(side orders.Side, price, qty int64) *orders.Order {
+	return &orders.Order{Symbol: "AAPL", Side: side, Type: orders.OrderTypeLimit, Price: price, Quantity: qty, AccountID: "T1"}
+}
+
+func imageOf(engine *matching.Engine, seq uint64) *snapshot.Image {
+	return &snapshot.Image{EventSeq: seq, Books: engine.RestingOrders()}
+}
+
+// TestSnapshot_DeltaReproducesQueueOrder verifies a delta covering fills,
+// cancels, new orders, an iceberg replenish and a re-queued replace
+// rebuilds the exact book, queue order included.
+func TestSnapshot_DeltaReproducesQueueOrder(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+
+	iceberg := limit(orders.SideSell, 15000, 300)
+	iceberg.DisplayQty = 100
+	engine.ProcessOrder(iceberg)
+	a := limit(orders.SideSell, 15000, 100)
+	engine.ProcessOrder(a)
+	b := limit(orders.SideSell, 15010, 100)
+	engine.ProcessOrder(b)
+	c := limit(orders.SideBuy, 14990, 100)
+	engine.ProcessOrder(c)
+	before := imageOf(engine, 1)
+
+	engine.ProcessOrder(limit(orders.SideBuy, 15000, 150)) // Iceberg slice + half of a; iceberg replenishes behind a
+	engine.CancelOrder("AAPL", c.ID)
+	engine.ProcessOrder(limit(orders.SideBuy, 14980, 10))
+	engine.ReplaceOrder(matching.ReplaceRequest{Symbol: "AAPL", OrderID: b.ID, Side: orders.SideSell, AccountID: "T1", Price: 15000, Quantity: 100})
+	after := imageOf(engine, 2)
+
+	delta := snapshot.Diff(before, after)
+	if got := snapshot.Apply(before, delta); !reflect.DeepEqual(got, after) {
+		t.Fatalf("Applied delta does not reproduce the book:\n got %+v\nwant %+v", got.Books, after.Books)
+	}
+
+	// Only the changed orders travel: a partial fill, the moved iceberg, the
+	// cancel, the new bid and the replaced order
+	bd := delta.Books[0]
+	if len(bd.Updated) != 1 || len(bd.Added) != 3 || len(bd.Removed) != 3 {
+		t.Errorf("Expected 1 updated, 3 added, 3 removed, got %d/%d/%d", len(bd.Updated), len(bd.Added), len(bd.Removed))
+	}
+}
+
+// TestSnapshot_RestoreKeepsPriority verifies restored books match in the
+// same priority as the originals.
+func TestSnapshot_RestoreKeepsPriority(t *testing.T) {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	first := limit(orders.SideSell, 15000, 100)
+	engine.ProcessOrder(first)
+	second := limit(orders.SideSell, 15000, 100)
+	second.SessionID = "S1"
+	engine.ProcessOrder(second)
+
+	restored := matching.NewEngine()
+	if err := restored.RestoreOrders(engine.RestingOrders()); err != nil {
+		t.Fatalf("RestoreOrders failed: %v", err)
+	}
+
+	result := restored.ProcessOrder(limit(orders.SideBuy, 15000, 150))
+	if len(result.Fills) != 2 || result.Fills[0].MakerOrderID != first.ID || result.Fills[1].MakerOrderID != second.ID {
+		t.Errorf("Expected fills against %d then %d, got %v", first.ID, second.ID, result.Fills)
+	}
+	if restored.SessionOrderCount("S1") != 1 {
+		t.Errorf("Expected the restored order tracked for its session")
+	}
+}
+
+// TestSnapshot_StoreChainAndCompaction verifies the store writes deltas
+// after a full image, compacts per policy, and reopens to the latest state.
+func TestSnapshot_StoreChainAndCompaction(t *testing.T) {
+	dir := t.TempDir()
+	store, err := snapshot.Open(dir, snapshot.Policy{MaxDeltas: 3, MaxDeltaRatio: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	for i := 1; i <= 6; i++ {
+		engine.ProcessOrder(limit(orders.SideBuy, int64(14000+i), 100))
+		if _, err := store.Write(imageOf(engine, uint64(i))); err != nil {
+			t.Fatalf("Write %d failed: %v", i, err)
+		}
+	}
+	if wrote, _ := store.Write(imageOf(engine, 6)); wrote {
+		t.Error("Expected an unchanged image to be skipped")
+	}
+
+	// full, d, d, d, full (compacted), d
+	stats := store.Stats()
+	if stats.Fulls != 2 || stats.Deltas != 4 || stats.DeltasSinceFull != 1 {
+		t.Errorf("Expected 2 fulls and 4 deltas (1 since compaction), got %+v", stats)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected compaction to leave 2 files, got %d", len(files))
+	}
+
+	reopened, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if !reflect.DeepEqual(reopened.Latest(), imageOf(engine, 6)) {
+		t.Errorf("Reopened store does not hold the latest image")
+	}
+}
+
+// TestSnapshot_DetectsCorruption verifies a damaged snapshot fails its
+// checksum instead of restoring a wrong book.
+func TestSnapshot_DetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := snapshot.Open(dir, snapshot.DefaultPolicy())
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	engine.ProcessOrder(limit(orders.SideBuy, 14000, 100))
+	store.Write(imageOf(engine, 1))
+
+	files, _ := os.ReadDir(dir)
+	path := filepath.Join(dir, files[0].Name())
+	data, _ := os.ReadFile(path)
+	data[len(data)-10] ^= 0xFF // Flip bits inside the payload
+	os.WriteFile(path, data, 0644)
+
+	if _, err := snapshot.Open(dir, snapshot.DefaultPolicy()); err == nil {
+		t.Error("Expected a corrupt snapshot to be rejected")
+	}
+}
+
+// TestSnapshot_ShutdownSnapshotMatchesEventLog verifies the processor's
+// final snapshot is stamped with the event log's last sequence, which is
+// what makes it restorable on restart.
+func TestSnapshot_ShutdownSnapshotMatchesEventLog(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "events.log")
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: logPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	seq := disruptor.NewSequencer(rb)
+	processor := disruptor.NewEventProcessor(rb, engine, eventLog)
+	processor.EnableTimers(10 * time.Millisecond)
+	processor.EnableSnapshots(store, 20*time.Millisecond)
+	processor.Start()
+
+	for i := 0; i < 10; i++ {
+		s, _ := seq.Next()
+		responseCh := make(chan *disruptor.OrderResponse, 1)
+		seq.Publish(s, &disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: limit(orders.SideSell, int64(15000+i%3), 100)}, responseCh)
+		<-responseCh
+		time.Sleep(5 * time.Millisecond)
+	}
+	processor.Shutdown()
+	eventLog.Close()
+
+	eventLog, _ = events.NewEventLog(events.EventLogConfig{Path: logPath})
+	defer eventLog.Close()
+	reopened, err := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	img := reopened.Latest()
+	if img == nil || img.EventSeq != eventLog.GetLastSequence() {
+		t.Fatalf("Expected snapshot at event %d, got %+v", eventLog.GetLastSequence(), img)
+	}
+	if img.Orders() != 10 {
+		t.Errorf("Expected 10 resting orders, got %d", img.Orders())
+	}
+}
+
+// ============================================================================
+// TAIL REPLAY (SNAPSHOT + EVENTS AFTER IT)
+// ============================================================================
+
+// tailRun drives a processor over an engine, event log and clearing house.
+type tailRun struct {
+	t         *testing.T
+	seq       *disruptor.Sequencer
+	processor *disruptor.EventProcessor
+}
+
+// startRun starts a processor; with a store it snapshots every n events.
+func startRun(t *testing.T, engine *matching.Engine, eventLog *events.EventLog, clearing *settlement.ClearingHouse, store *snapshot.Store, every uint64) *tailRun {
+	rb := disruptor.NewRingBuffer(disruptor.Config{BufferSize: 64})
+	run := &tailRun{t: t, seq: disruptor.NewSequencer(rb), processor: disruptor.NewEventProcessor(rb, engine, eventLog)}
+	run.processor.EnableClearing(clearing)
+	if store != nil {
+		run.processor.EnableSnapshots(store, 0)
+		run.processor.SetSnapshotEvery(every)
+	}
+	run.processor.Start()
+	return run
+}
+
+func (r *tailRun) send(req *disruptor.OrderRequest) *disruptor.OrderResponse {
+	s, err := r.seq.Next()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	responseCh := make(chan *disruptor.OrderResponse, 1)
+	r.seq.Publish(s, req, responseCh)
+	return <-responseCh
+}
+
+func (r *tailRun) order(o *orders.Order) *orders.Order {
+	r.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeNewOrder, Order: o})
+	return o
+}
+
+// withoutTimestamps clears order timestamps, which replay takes from the
+// log rather than the clock.
+func withoutTimestamps(books map[string][]orders.Order) map[string][]orders.Order {
+	for _, book := range books {
+		for i := range book {
+			book[i].Timestamp = 0
+		}
+	}
+	return books
+}
+
+// buildTail snapshots a session at shutdown, then logs more activity
+// without snapshots. Returns the live engine and clearing house.
+func buildTail(t *testing.T, dir string, eventLog *events.EventLog) (*matching.Engine, *settlement.ClearingHouse) {
+	store, err := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	clearing := settlement.NewClearingHouse()
+
+	run := startRun(t, engine, eventLog, clearing, store, 0)
+	iceberg := limit(orders.SideSell, 15000, 300)
+	iceberg.DisplayQty = 100
+	run.order(iceberg)
+	run.order(limit(orders.SideSell, 15000, 100))
+	stale := run.order(limit(orders.SideSell, 15010, 100))
+	run.order(limit(orders.SideBuy, 15000, 150)) // Fills the slice and half the next order
+	run.order(limit(orders.SideBuy, 14990, 100))
+	run.processor.Shutdown() // Snapshot here
+
+	run = startRun(t, engine, eventLog, clearing, nil, 0)
+	run.order(limit(orders.SideBuy, 15000, 120)) // Trades into the replenished iceberg
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeCancelOrder, Symbol: "AAPL", OrderID: stale.ID})
+	mover := run.order(limit(orders.SideBuy, 14980, 50))
+	run.send(&disruptor.OrderRequest{Type: disruptor.RequestTypeModifyOrder, Replace: &matching.ReplaceRequest{
+		Symbol: "AAPL", OrderID: mover.ID, Side: orders.SideBuy, AccountID: "T1", Price: 15000, Quantity: 60}})
+	run.order(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeMarket, Quantity: 70, AccountID: "T2"})
+	run.processor.Shutdown()
+	return engine, clearing
+}
+
+// TestSnapshot_TailReplayRebuildsState verifies that loading a snapshot and
+// replaying only the events after it rebuilds the same books, ID counters
+// and clearing house as the engine that ran.
+func TestSnapshot_TailReplayRebuildsState(t *testing.T) {
+	dir := t.TempDir()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: filepath.Join(dir, "events.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+	live, liveClearing := buildTail(t, dir, eventLog)
+
+	store, err := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := store.Latest()
+	if img == nil || img.EventSeq >= eventLog.GetLastSequence() {
+		t.Fatalf("Expected a snapshot behind the log at %d, got %+v", eventLog.GetLastSequence(), img)
+	}
+	if img.Clearing == nil || len(img.Clearing.Trades) != 2 {
+		t.Fatalf("Expected 2 trades in the snapshot's clearing state, got %+v", img.Clearing)
+	}
+
+	engine := matching.NewEngine()
+	clearing := settlement.NewClearingHouse()
+	if err := engine.RestoreOrders(img.Books); err != nil {
+		t.Fatal(err)
+	}
+	engine.RestoreIDCounters(img.Counters)
+	clearing.Restore(img.Clearing)
+
+	replayer := matching.NewReplayer(engine)
+	err = eventLog.ReplayFrom(img.EventSeq, func(seqNum uint64, event interface{}) error {
+		fills, err := replayer.Apply(event)
+		for _, fill := range fills {
+			clearing.RecordTrade(fill)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Tail replay failed: %v", err)
+	}
+	engine.RestoreIDCounters(replayer.Counters())
+	if replayer.Events() != 5 {
+		t.Errorf("Expected 5 events replayed, got %d", replayer.Events())
+	}
+
+	if got, want := withoutTimestamps(engine.RestingOrders()), withoutTimestamps(live.RestingOrders()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed books differ:\n got %+v\nwant %+v", got, want)
+	}
+	if engine.IDCounters() != live.IDCounters() {
+		t.Errorf("Expected counters %+v, got %+v", live.IDCounters(), engine.IDCounters())
+	}
+	got, want := clearing.Export().Trades, liveClearing.Export().Trades
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d trades, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Quantity != want[i].Quantity || got[i].BuyerAccount != want[i].BuyerAccount {
+			t.Errorf("Trade %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+// TestSnapshot_TailReplayDetectsDivergence verifies replay onto books that
+// don't match the snapshot fails instead of inventing different trades.
+func TestSnapshot_TailReplayDetectsDivergence(t *testing.T) {
+	dir := t.TempDir()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: filepath.Join(dir, "events.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+	buildTail(t, dir, eventLog)
+
+	store, _ := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	img := store.Latest()
+
+	engine := matching.NewEngine() // Counters restored, books not
+	engine.RestoreIDCounters(img.Counters)
+	replayer := matching.NewReplayer(engine)
+	err = eventLog.ReplayFrom(img.EventSeq, func(seqNum uint64, event interface{}) error {
+		_, err := replayer.Apply(event)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "diverged") {
+		t.Errorf("Expected a divergence error, got %v", err)
+	}
+}
+
+// TestSnapshot_EveryNEvents verifies the processor snapshots after every N
+// logged events, each image carrying the counters and clearing state.
+func TestSnapshot_EveryNEvents(t *testing.T) {
+	dir := t.TempDir()
+	eventLog, err := events.NewEventLog(events.EventLogConfig{Path: filepath.Join(dir, "events.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+	store, err := snapshot.Open(filepath.Join(dir, "snapshots"), snapshot.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	run := startRun(t, engine, eventLog, settlement.NewClearingHouse(), store, 5)
+	for i := 0; i < 10; i++ {
+		run.order(limit(orders.SideSell, 15000, 10))
+		run.order(limit(orders.SideBuy, 15000, 10)) // 2 events: the order and its fill
+		time.Sleep(time.Millisecond)                // Let the writer keep up
+	}
+	run.processor.Shutdown()
+
+	// Requests queue 1 or 2 events, so at 6, 12, ... 30; the final snapshot
+	// adds nothing new
+	stats := store.Stats()
+	if stats.Fulls+stats.Deltas != 5 {
+		t.Errorf("Expected 5 snapshots, got %d full + %d delta", stats.Fulls, stats.Deltas)
+	}
+	img := store.Latest()
+	if img.EventSeq != 30 || img.Counters.TradeID != 10 || len(img.Clearing.Trades) != 10 {
+		t.Errorf("Expected event 30 with 10 trades, got event %d, trade counter %d, %d cleared trades",
+			img.EventSeq, img.Counters.TradeID, len(img.Clearing.Trades))
+	}
+}
+
+// ============================================================================
+// BENCHMARKS: full snapshots vs full + deltas
+// ============================================================================
+
+// benchBook builds a book of n resting orders across 200 levels per side.
+func benchBook(n int) *matching.Engine {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	for i := 0; i < n; i++ {
+		side, price := orders.SideBuy, int64(14999-i%200)
+		if i%2 == 1 {
+			side, price = orders.SideSell, int64(15001+i%200)
+		}
+		engine.ProcessOrder(limit(side, price, 100))
+	}
+	return engine
+}
+
+// churn applies light activity: a few new orders, a fill and a cancel.
+func churn(engine *matching.Engine, round int) {
+	for i := 0; i < 5; i++ {
+		engine.ProcessOrder(limit(orders.SideBuy, int64(14800+(round+i)%200), 100))
+	}
+	engine.ProcessOrder(&orders.Order{Symbol: "AAPL", Side: orders.SideSell, Type: orders.OrderTypeMarket, Quantity: 50, AccountID: "T1"})
+	if book := engine.RestingOrders()["AAPL"]; len(book) > 0 {
+		engine.CancelOrder("AAPL", book[len(book)-1].ID)
+	}
+}
+
+// benchmarkSnapshotWrites writes one snapshot per round of churn and
+// reports bytes written per snapshot.
+func benchmarkSnapshotWrites(b *testing.B, policy snapshot.Policy) {
+	engine := benchBook(50000)
+	store, err := snapshot.Open(b.TempDir(), policy)
+	if err != nil {
+		b.Fatal(err)
+	}
+	store.Write(imageOf(engine, 0)) // Initial full image, not measured
+	start := store.Stats().BytesWritten
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		churn(engine, i)
+		img := imageOf(engine, uint64(i+1))
+		b.StartTimer()
+		if _, err := store.Write(img); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(store.Stats().BytesWritten-start)/float64(b.N), "bytes/snapshot")
+}
+
+func BenchmarkSnapshotWrite_Full(b *testing.B) {
+	benchmarkSnapshotWrites(b, snapshot.Policy{MaxDeltas: 1, MaxDeltaRatio: 1e-9})
+}
+
+func BenchmarkSnapshotWrite_Delta(b *testing.B) {
+	benchmarkSnapshotWrites(b, snapshot.DefaultPolicy())
+}
+
+// benchmarkRecovery measures loading a store holding a full image followed
+// by deltas deltas, then rebuilding the books.
+func benchmarkRecovery(b *testing.B, deltas int) {
+	engine := benchBook(50000)
+	dir := b.TempDir()
+	store, _ := snapshot.Open(dir, snapshot.Policy{MaxDeltas: deltas + 1, MaxDeltaRatio: 100})
+	store.Write(imageOf(engine, 0))
+	for i := 0; i < deltas; i++ {
+		churn(engine, i)
+		store.Write(imageOf(engine, uint64(i+1)))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reopened, err := snapshot.Open(dir, snapshot.DefaultPolicy())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := matching.NewEngine().RestoreOrders(reopened.Latest().Books); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSnapshotRecovery(b *testing.B) {
+	for _, deltas := range []int{0, 5, 20} {
+		b.Run(fmt.Sprintf("deltas=%d", deltas), func(b *testing.B) { benchmarkRecovery(b, deltas) })
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/enrichment"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+	"github.com/rishav/order-matching-engine/internal/matching"
+	"github.com/rishav/order-matching-engine/internal/orders"
+)
+
+// ============================================================================
+// SYMBOL STATISTICS
+// ============================================================================
+
+// statsRun matches orders and publishes their trades and the top of book
+// after each, as the server does, to a stats tracker.
+type statsRun struct {
+	t         *testing.T
+	engine    *matching.Engine
+	publisher *marketdata.Publisher
+	tape      *enrichment.Pipeline
+	tracker   *marketdata.StatsTracker
+}
+
+func startStatsRun(t *testing.T) *statsRun {
+	engine := matching.NewEngine()
+	engine.AddSymbol("AAPL")
+	publisher := marketdata.NewPublisher(100)
+	run := &statsRun{t: t, engine: engine, publisher: publisher, tape: enrichment.NewPipeline(), tracker: marketdata.NewStatsTracker(publisher)}
+	run.tracker.Start()
+	t.Cleanup(func() {
+		publisher.Close()
+		<-run.tracker.Done()
+	})
+	return run
+}
+
+// order matches an order for account, then publishes its trades and the
+// book's new top.
+func (r *statsRun) order(account string, side orders.Side, price, qty int64) {
+	o := limit(side, price, qty)
+	o.AccountID = account
+	result := r.engine.ProcessOrder(o)
+	for _, fill := range result.Fills {
+		r.publisher.PublishTrade(r.tape.Enrich(fill))
+	}
+
+	book := r.engine.GetOrderBook("AAPL")
+	quote := marketdata.L1Quote{Symbol: "AAPL", Timestamp: o.Timestamp}
+	if bid := book.GetBestBid(); bid != nil {
+		quote.BidPrice, quote.BidSize = bid.Price, bid.TotalQty
+	}
+	if ask := book.GetBestAsk(); ask != nil {
+		quote.AskPrice, quote.AskSize = ask.Price, ask.TotalQty
+	}
+	r.publisher.PublishL1(quote)
+}
+
+// expect waits for the tracker to catch up with the published updates
+// and checks the /stats/symbol body.
+func (r *statsRun) expect(want marketdata.StatsSummary) {
+	r.t.Helper()
+	var got marketdata.StatsSummary
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		stats, _ := r.tracker.Get("AAPL")
+		if got = marketdata.Summarize(stats, r.engine.GetOrderBook("AAPL")); reflect.DeepEqual(got, want) {
+			return
+		}
+	}
+	r.t.Fatalf("Expected stats %+v, got %+v", want, got)
+}
+
+// TestStats_TradesAndBook verifies volume, VWAP, open/high/low/last and the
+// spread follow fills and book changes, and reset with the trading day.
+func TestStats_TradesAndBook(t *testing.T) {
+	run := startStatsRun(t)
+	day := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	run.engine.SetTime(day.UnixNano())
+
+	run.order("MM1", orders.SideSell, 15000, 100)
+	run.order("MM1", orders.SideSell, 15100, 100)
+	run.order("T1", orders.SideBuy, 15100, 150) // 100 at 150.00, 50 at 151.00
+	run.order("MM2", orders.SideBuy, 14900, 50)
+
+	run.expect(marketdata.StatsSummary{
+		Symbol: "AAPL", Day: "2024-03-01",
+		Volume:     150,
+		VWAP:       "$150.33", // (15000*100 + 15100*50) / 150, rounded down
+		Open:       "$150.00",
+		High:       "$151.00",
+		Low:        "$150.00",
+		Last:       "$151.00",
+		TradeCount: 2,
+		Spread:     "$2.00",
+		BidLevels:  1, AskLevels: 1,
+		BidDepth: 50, AskDepth: 50,
+	})
+
+	// A sell through the bid sets a new low and empties the bid side
+	run.order("T2", orders.SideSell, 14900, 50)
+	run.expect(marketdata.StatsSummary{
+		Symbol: "AAPL", Day: "2024-03-01",
+		Volume:     200,
+		VWAP:       "$150.00", // (2255000 + 14900*50) / 200
+		Open:       "$150.00",
+		High:       "$151.00",
+		Low:        "$149.00",
+		Last:       "$149.00",
+		TradeCount: 3,
+		Spread:     "$0.00", // No bid
+		BidLevels:  0, AskLevels: 1,
+		BidDepth: 0, AskDepth: 50,
+	})
+
+	// The next day's first trade starts the counters again
+	run.engine.SetTime(day.Add(24 * time.Hour).UnixNano())
+	run.order("MM2", orders.SideBuy, 14800, 20)
+	run.order("T1", orders.SideBuy, 15100, 10)
+	run.expect(marketdata.StatsSummary{
+		Symbol: "AAPL", Day: "2024-03-02",
+		Volume:     10,
+		VWAP:       "$151.00",
+		Open:       "$151.00",
+		High:       "$151.00",
+		Low:        "$151.00",
+		Last:       "$151.00",
+		TradeCount: 1,
+		Spread:     "$3.00",
+		BidLevels:  1, AskLevels: 1,
+		BidDepth: 20, AskDepth: 40,
+	})
+}
+
+// TestStats_Body verifies the /stats/symbol field names, and that a symbol
+// that never traded reports zeros.
+func TestStats_Body(t *testing.T) {
+	run := startStatsRun(t)
+	stats, ok := run.tracker.Get("AAPL")
+	if ok {
+		t.Fatalf("Expected no stats before any update, got %+v", stats)
+	}
+
+	body, err := json.Marshal(marketdata.Summarize(stats, run.engine.GetOrderBook("AAPL")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"symbol": "AAPL", "day": "", "volume": 0.0, "vwap": "$0.00",
+		"open": "$0.00", "high": "$0.00", "low": "$0.00", "last": "$0.00",
+		"trade_count": 0.0, "spread": "$0.00",
+		"bid_levels": 0.0, "ask_levels": 0.0, "bid_depth": 0.0, "ask_depth": 0.0,
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Expected %v, got %v", want, fields)
+	}
+}
@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+)
+
+// ============================================================================
+// SLOW MARKET DATA SUBSCRIBERS
+// ============================================================================
+
+// TestSubscribers_QueueingSubscriberCountsDrops verifies a subscriber that
+// doesn't read loses updates past its buffer, and its stats say how many.
+func TestSubscribers_QueueingSubscriberCountsDrops(t *testing.T) {
+	pub := marketdata.NewPublisher(3)
+	defer pub.Close()
+	quotes := pub.SubscribeL1("AAPL")
+
+	for i := int64(1); i <= 10; i++ {
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000 + i})
+	}
+	if quote := <-quotes.C; quote.BidPrice != 15001 {
+		t.Errorf("Expected the oldest quote first, got bid %d", quote.BidPrice)
+	}
+	stats := quotes.Stats()
+	if stats.Conflating || stats.Sent != 3 || stats.Dropped != 7 || stats.Conflated != 0 {
+		t.Errorf("Expected 3 sent and 7 dropped, got %+v", stats)
+	}
+}
+
+// TestSubscribers_ConflatingSubscriberGetsLatest verifies a conflating
+// subscriber that falls behind loses nothing but superseded updates: it
+// catches up with each symbol's latest, and every update is counted as
+// either sent or conflated.
+func TestSubscribers_ConflatingSubscriberGetsLatest(t *testing.T) {
+	pub := marketdata.NewPublisher(3)
+	defer pub.Close()
+	quotes := pub.SubscribeAllL1Conflated()
+
+	for i := int64(1); i <= 100; i++ {
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 15000 + i})
+		pub.PublishL1(marketdata.L1Quote{Symbol: "MSFT", BidPrice: 30000 + i})
+	}
+
+	latest := map[string]int64{}
+	received := 0
+	for done := false; !done; {
+		select {
+		case quote := <-quotes.C:
+			latest[quote.Symbol] = quote.BidPrice
+			received++
+		case <-time.After(100 * time.Millisecond):
+			done = true
+		}
+	}
+	if latest["AAPL"] != 15100 || latest["MSFT"] != 30100 {
+		t.Errorf("Expected each symbol's latest quote, got %v", latest)
+	}
+	// At most one superseded quote, already on its way when the rest came
+	if received > 3 {
+		t.Errorf("Expected superseded quotes conflated away, received %d", received)
+	}
+	stats := quotes.Stats()
+	if !stats.Conflating || stats.Dropped != 0 || stats.Pending != 0 ||
+		stats.Sent != uint64(received) || stats.Sent+stats.Conflated != 200 {
+		t.Errorf("Expected %d sent and the rest of 200 conflated, got %+v", received, stats)
+	}
+
+	// Caught up: updates flow one by one again
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: 14900})
+	select {
+	case quote := <-quotes.C:
+		if quote.BidPrice != 14900 {
+			t.Errorf("Expected the new quote, got bid %d", quote.BidPrice)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the new quote")
+	}
+}
+
+// TestSubscribers_ConflatedDepthAndClose verifies depth conflates per
+// symbol too, stats list every subscription, and closing the publisher
+// closes conflating channels.
+func TestSubscribers_ConflatedDepthAndClose(t *testing.T) {
+	pub := marketdata.NewPublisher(3)
+	depth := pub.SubscribeL2Conflated("AAPL")
+	pub.SubscribeL1("MSFT")
+
+	for qty := int64(1); qty <= 5; qty++ {
+		pub.PublishL2(bookImage([][2]int64{{15000, qty}}, nil))
+	}
+	var last marketdata.L2Depth
+	for done := false; !done; {
+		select {
+		case last = <-depth.C:
+		case <-time.After(100 * time.Millisecond):
+			done = true
+		}
+	}
+	if len(last.Bids) != 1 || last.Bids[0].Quantity != 5 || last.Checksum != checksumOf([][2]int64{{15000, 5}}, nil) {
+		t.Errorf("Expected the latest depth, got %+v", last)
+	}
+
+	stats := pub.SubscriberStats()
+	if len(stats) != 2 || stats[0].Feed != "l1" || stats[0].Symbol != "MSFT" || stats[0].Conflating ||
+		stats[1].Feed != "l2" || stats[1].Symbol != "AAPL" || !stats[1].Conflating {
+		t.Errorf("Expected the MSFT quote and AAPL depth subscriptions, got %+v", stats)
+	}
+
+	pub.Close()
+	select {
+	case _, open := <-depth.C:
+		if open {
+			t.Error("Expected nothing after the latest depth")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the channel closed with the publisher")
+	}
+}
+
+// TestSubscribers_CloseRemovesEveryFeed verifies every feed's subscriptions
+// get their own IDs, and Close removes them and closes their channels,
+// once, however often it is called.
+func TestSubscribers_CloseRemovesEveryFeed(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	defer pub.Close()
+
+	l1 := pub.SubscribeL1("AAPL")
+	allL1 := pub.SubscribeAllL1Conflated()
+	l2 := pub.SubscribeL2("AAPL")
+	trades := pub.SubscribeTrades("AAPL")
+	allTrades := pub.SubscribeAllTrades()
+	bands := pub.SubscribeBands("AAPL")
+	_, book := pub.SubscribeBook("AAPL", 0)
+	auction := pub.SubscribeAuction("AAPL")
+	closers := []interface{ Close() }{l1, allL1, l2, trades, allTrades, bands, book, auction}
+
+	stats := pub.SubscriberStats()
+	if len(stats) != len(closers) {
+		t.Fatalf("Expected %d subscriptions, got %+v", len(closers), stats)
+	}
+	ids := map[uint64]bool{}
+	for _, s := range stats {
+		ids[s.ID] = true
+	}
+	if len(ids) != len(closers) || !ids[l1.ID] || !ids[auction.ID] {
+		t.Errorf("Expected distinct IDs, got %+v", stats)
+	}
+
+	for _, c := range closers {
+		c.Close()
+		c.Close()
+	}
+	if stats := pub.SubscriberStats(); len(stats) != 0 {
+		t.Errorf("Expected no subscriptions left, got %+v", stats)
+	}
+	for name, closed := range map[string]bool{
+		"l1": isClosed(l1.C), "all l1": isClosed(allL1.C), "l2": isClosed(l2.C),
+		"trades": isClosed(trades.C), "all trades": isClosed(allTrades.C), "bands": isClosed(bands.C),
+		"book": isClosed(book.C), "auction": isClosed(auction.C),
+	} {
+		if !closed {
+			t.Errorf("Expected the %s channel closed", name)
+		}
+	}
+
+	// Publishing to nobody is fine
+	pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL"})
+	pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL"})
+	pub.PublishAuction(marketdata.AuctionState{Symbol: "AAPL"})
+}
+
+// isClosed reports whether ch closes once whatever is still on its way
+// has been read, waiting a little for a conflating subscription's pump.
+func isClosed[T any](ch <-chan T) bool {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, open := <-ch:
+			if !open {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+// TestSubscribers_CloseAfterPublisherClose verifies closing a subscription
+// after the publisher is safe, and subscribing afterwards gets a closed
+// channel rather than one nothing will ever send on.
+func TestSubscribers_CloseAfterPublisherClose(t *testing.T) {
+	pub := marketdata.NewPublisher(10)
+	quotes := pub.SubscribeL1Conflated("AAPL")
+	pub.Close()
+	quotes.Close()
+	if !isClosed(quotes.C) {
+		t.Error("Expected the channel closed with the publisher")
+	}
+
+	late := pub.SubscribeTrades("AAPL")
+	if !isClosed(late.C) {
+		t.Error("Expected a subscription after Close to be closed")
+	}
+	late.Close()
+}
+
+// TestSubscribers_StalledSubscribersEvicted verifies a subscriber that
+// leaves updates unread past the stall timeout is evicted, queueing or
+// conflating, while one that reads is kept.
+func TestSubscribers_StalledSubscribersEvicted(t *testing.T) {
+	pub := marketdata.NewPublisher(2)
+	defer pub.Close()
+	pub.SetStallTimeout(20 * time.Millisecond)
+	stalled := pub.SubscribeTrades("AAPL")
+	stalledQuotes := pub.SubscribeL1Conflated("AAPL")
+	reading := pub.SubscribeTrades("AAPL")
+
+	publish := func(id uint64) {
+		pub.PublishTrade(marketdata.TradeReport{Symbol: "AAPL", TradeID: id})
+		pub.PublishL1(marketdata.L1Quote{Symbol: "AAPL", BidPrice: int64(id)})
+		<-reading.C
+	}
+	for id := uint64(1); id <= 3; id++ {
+		publish(id) // The third fills the stalled queue
+	}
+	time.Sleep(30 * time.Millisecond)
+	publish(4)
+
+	if pub.EvictedSubscribers() != 2 {
+		t.Fatalf("Expected both stalled subscribers evicted, got %d", pub.EvictedSubscribers())
+	}
+	if stats := pub.SubscriberStats(); len(stats) != 1 || stats[0].ID != reading.ID {
+		t.Errorf("Expected only the reading subscriber left, got %+v", stats)
+	}
+	// What was queued is still there to read, then the channel ends
+	if trade := <-stalled.C; trade.TradeID != 1 {
+		t.Errorf("Expected the first trade still queued, got %+v", trade)
+	}
+	<-stalled.C
+	if !isClosed(stalled.C) {
+		t.Error("Expected the evicted queue closed")
+	}
+	if !isClosed(stalledQuotes.C) {
+		t.Error("Expected the evicted conflating subscription closed")
+	}
+	stalled.Close() // Already gone
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+)
+
+// ============================================================================
+// EVENT LOG TAILING
+// ============================================================================
+
+// appendCancels appends one cancel per order ID.
+func appendCancels(t *testing.T, eventLog *events.EventLog, orderIDs ...uint64) {
+	t.Helper()
+	for _, id := range orderIDs {
+		_, err := eventLog.Append(&events.CancelOrderEvent{
+			Event:   events.Event{Type: events.EventTypeCancelOrder},
+			OrderID: id,
+			Symbol:  "AAPL",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// nextRecords reads n records from a tail, failing if it closes or stalls.
+func nextRecords(t *testing.T, records <-chan events.Record, n int) []uint64 {
+	t.Helper()
+	var seqs []uint64
+	for len(seqs) < n {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				t.Fatalf("Tail closed after %v, expected %d records", seqs, n)
+			}
+			if cancel, ok := record.Event.(*events.CancelOrderEvent); !ok || cancel.OrderID != record.Seq {
+				t.Fatalf("Record %d carries the wrong event: %+v", record.Seq, record.Event)
+			}
+			seqs = append(seqs, record.Seq)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out after %v, expected %d records", seqs, n)
+		}
+	}
+	return seqs
+}
+
+// waitClosed drains a tail until it closes, returning the last sequence
+// number it delivered.
+func waitClosed(t *testing.T, records <-chan events.Record) uint64 {
+	t.Helper()
+	var last uint64
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return last
+			}
+			last = record.Seq
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the tail to close")
+		}
+	}
+}
+
+// TestTail_CatchesUpThenFollows verifies a tail gets the logged events from
+// where it starts, then each new one once, in order, until stopped.
+func TestTail_CatchesUpThenFollows(t *testing.T) {
+	eventLog := openLog(t)
+	appendCancels(t, eventLog, 1, 2, 3, 4, 5)
+
+	if _, err := eventLog.Tail(7); !errors.Is(err, events.ErrTailAhead) {
+		t.Errorf("Expected ErrTailAhead past the end of the log, got %v", err)
+	}
+
+	records, err := eventLog.Tail(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextRecords(t, records, 3); got[0] != 3 || got[2] != 5 {
+		t.Errorf("Expected the catch-up to be 3-5, got %v", got)
+	}
+	appendCancels(t, eventLog, 6, 7)
+	if got := nextRecords(t, records, 2); got[0] != 6 || got[1] != 7 {
+		t.Errorf("Expected 6 and 7 as they were appended, got %v", got)
+	}
+
+	// New events only, while another tail is open
+	fresh, err := eventLog.Tail(eventLog.GetLastSequence() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendCancels(t, eventLog, 8)
+	if got := nextRecords(t, fresh, 1); got[0] != 8 {
+		t.Errorf("Expected only 8 on the new-events tail, got %v", got)
+	}
+	if got := nextRecords(t, records, 1); got[0] != 8 {
+		t.Errorf("Expected 8 on the first tail too, got %v", got)
+	}
+
+	eventLog.StopTail(records)
+	waitClosed(t, records)
+	if eventLog.Tails() != 1 {
+		t.Errorf("Expected one tail left, got %d", eventLog.Tails())
+	}
+	eventLog.Close()
+	waitClosed(t, fresh)
+	if _, err := eventLog.Tail(1); !errors.Is(err, events.ErrLogClosed) {
+		t.Errorf("Expected ErrLogClosed, got %v", err)
+	}
+}
+
+// TestTail_SlowSubscriberResumes verifies appends never wait for a tail
+// that stopped reading: it is dropped, and resumes from the sequence number
+// after the last event it got.
+func TestTail_SlowSubscriberResumes(t *testing.T) {
+	eventLog, err := events.NewEventLog(events.EventLogConfig{
+		Path:        filepath.Join(t.TempDir(), "events.log"),
+		TailBacklog: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventLog.Close()
+
+	records, err := eventLog.Tail(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appended := make(chan struct{})
+	go func() {
+		defer close(appended)
+		for id := uint64(1); id <= 20; id++ {
+			appendCancels(t, eventLog, id)
+		}
+	}()
+	select {
+	case <-appended:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Appends waited for a tail that wasn't reading")
+	}
+
+	last := waitClosed(t, records)
+	if last >= 20 || eventLog.Tails() != 0 {
+		t.Fatalf("Expected the tail dropped short of the end, got through %d with %d tails", last, eventLog.Tails())
+	}
+
+	records, err = eventLog.Tail(last + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nextRecords(t, records, int(20-last))
+	if got[0] != last+1 || got[len(got)-1] != 20 {
+		t.Errorf("Expected to resume at %d through 20, got %v", last+1, got)
+	}
+}
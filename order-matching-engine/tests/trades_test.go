@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/events"
+	"github.com/rishav/order-matching-engine/internal/marketdata"
+)
+
+// ============================================================================
+// TRADE HISTORY
+// ============================================================================
+
+// tradeAt is an AAPL trade with the given ID at second id.
+func tradeAt(id uint64) marketdata.TradeReport {
+	return marketdata.TradeReport{TradeID: id, Symbol: "AAPL", Price: 15000, Quantity: 10, Timestamp: int64(id) * 1e9}
+}
+
+func tradeIDs(trades []marketdata.TradeReport) []uint64 {
+	ids := make([]uint64, len(trades))
+	for i, trade := range trades {
+		ids[i] = trade.TradeID
+	}
+	return ids
+}
+
+// TestTrades_PagesAcrossLogAndMemory verifies pages run oldest first from
+// the log source into memory without repeating or skipping a trade, and
+// that the source is not read once the pages have caught up with memory.
+func TestTrades_PagesAcrossLogAndMemory(t *testing.T) {
+	// Trades 1-10 are in the log; 6-12 were recorded since, but memory
+	// keeps 2 to 4 of them, so it ends up with 9-12
+	var scans int
+	source := func(symbol string, before uint64, fn func(marketdata.TradeReport) bool) error {
+		scans++
+		for id := uint64(1); id <= 10 && (before == 0 || id < before); id++ {
+			if !fn(tradeAt(id)) {
+				break
+			}
+		}
+		return nil
+	}
+	store := marketdata.NewTradeStore(2, source)
+	for _, id := range []uint64{6, 7, 9, 8, 10, 11, 12} { // 8 arrives late
+		store.Record(tradeAt(id))
+	}
+
+	var all []uint64
+	query := marketdata.TradeQuery{Symbol: "AAPL", Limit: 4}
+	for page := 0; ; page++ {
+		result, err := store.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, tradeIDs(result.Trades)...)
+		if result.Next == 0 {
+			break
+		}
+		if page > 5 {
+			t.Fatal("Pagination did not end")
+		}
+		query.After = result.Next
+	}
+	expected := []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	if !sameIDs(all, expected) {
+		t.Fatalf("Expected trades %v, got %v", expected, all)
+	}
+
+	scans = 0
+	result, _ := store.Query(marketdata.TradeQuery{Symbol: "AAPL", After: 10, Limit: 10})
+	if scans != 0 || !sameIDs(tradeIDs(result.Trades), []uint64{11, 12}) {
+		t.Errorf("Expected 11-12 from memory alone, got %v after %d scans", tradeIDs(result.Trades), scans)
+	}
+}
+
+// TestTrades_TimeRange verifies from is inclusive and to exclusive.
+func TestTrades_TimeRange(t *testing.T) {
+	store := marketdata.NewTradeStore(100, nil)
+	for id := uint64(1); id <= 10; id++ {
+		store.Record(tradeAt(id))
+	}
+	result, err := store.Query(marketdata.TradeQuery{Symbol: "AAPL", From: 3e9, To: 7e9, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []uint64{3, 4, 5, 6}; !sameIDs(tradeIDs(result.Trades), expected) || result.Next != 0 {
+		t.Errorf("Expected %v, got %v (next %d)", expected, tradeIDs(result.Trades), result.Next)
+	}
+}
+
+// TestTrades_ScanStopsEarly verifies a scan of the event log ends when the
+// handler asks it to.
+func TestTrades_ScanStopsEarly(t *testing.T) {
+	eventLog := openLog(t)
+	for id := uint64(1); id <= 5; id++ {
+		if _, err := eventLog.Append(&events.FillEvent{TradeID: id, Symbol: "AAPL", Price: 15000, Quantity: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := eventLog.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []uint64
+	err := eventLog.Scan(func(seqNum uint64, event interface{}) error {
+		fill := event.(*events.FillEvent)
+		if fill.TradeID == 3 {
+			return events.ErrStopScan
+		}
+		seen = append(seen, fill.TradeID)
+		return nil
+	})
+	if err != nil || !sameIDs(seen, []uint64{1, 2}) {
+		t.Errorf("Expected trades 1-2 and no error, got %v, %v", seen, err)
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rishav/order-matching-engine/internal/dropcopy"
+	"github.com/rishav/order-matching-engine/internal/orders"
+	"github.com/rishav/order-matching-engine/internal/wsjournal"
+)
+
+// ============================================================================
+// WEBSOCKET SEQUENCE NUMBERS AND RESEND
+// ============================================================================
+
+// received drains the messages queued on a connection.
+func received(out <-chan wsjournal.Message) []wsjournal.Message {
+	var msgs []wsjournal.Message
+	for {
+		select {
+		case msg, ok := <-out:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// TestWSJournal_SeqAndReports verifies messages are numbered from 1 and
+// queued in order, with execution reports for the session's own orders.
+func TestWSJournal_SeqAndReports(t *testing.T) {
+	store := wsjournal.NewStore()
+	out := make(chan wsjournal.Message, 10)
+	journal := store.Open("WS-1", "MM1", out)
+
+	journal.Record(&wsjournal.Message{Type: "login_ack"})
+	journal.Record(&wsjournal.Message{Type: "order_ack"})
+
+	fill := orders.ExecutionReport{ExecType: orders.ExecTypeTrade, OrderID: 7, AccountID: "MM1", SessionID: "WS-1", LastQty: 40}
+	store.Report(fill)
+	store.Report(orders.ExecutionReport{ExecType: orders.ExecTypeTrade, OrderID: 8, AccountID: "MM2", SessionID: "WS-1"}) // Another account's
+	store.Report(orders.ExecutionReport{ExecType: orders.ExecTypeTrade, OrderID: 9, AccountID: "MM1", SessionID: "WS-2"}) // Another session's
+	store.Report(orders.ExecutionReport{ExecType: orders.ExecTypeTrade, OrderID: 10, AccountID: "MM1"})                   // No session's
+
+	msgs := received(out)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %+v", msgs)
+	}
+	for i, want := range []string{"login_ack", "order_ack", "execution_report"} {
+		if msgs[i].Type != want || msgs[i].Seq != uint64(i+1) || msgs[i].PossDup {
+			t.Errorf("Message %d: expected %s with seq %d, got %+v", i, want, i+1, msgs[i])
+		}
+	}
+	if report, ok := msgs[2].Body.(*dropcopy.Execution); !ok || report.OrderID != 7 || report.LastQty != 40 {
+		t.Errorf("Expected the fill of order 7, got %+v", msgs[2].Body)
+	}
+
+	// Reports go on being recorded after the session ends, and out is closed
+	journal.Close()
+	store.Report(fill)
+	if msgs := received(out); len(msgs) != 0 {
+		t.Errorf("Expected nothing queued after close, got %+v", msgs)
+	}
+	if _, ok := <-out; ok {
+		t.Error("Expected out closed")
+	}
+	if resent, _, _ := journal.Resend("WS-1", 4); len(resent) != 1 || resent[0].Seq != 4 {
+		t.Errorf("Expected the fill after close recorded as seq 4, got %+v", resent)
+	}
+}
+
+// TestWSJournal_Resend verifies a resend returns the messages from the
+// seq asked for, flagged as possible duplicates, and is refused once some
+// of them are no longer kept.
+func TestWSJournal_Resend(t *testing.T) {
+	store := wsjournal.NewStore()
+	journal := store.Open("WS-1", "MM1", nil)
+	for i := 0; i < 5; i++ {
+		journal.Record(&wsjournal.Message{Type: "order_ack"})
+	}
+	if store.Get("WS-1") != journal || journal.AccountID() != "MM1" || store.Get("WS-2") != nil {
+		t.Fatal("Expected only WS-1's journal, of MM1")
+	}
+
+	resent, _, ok := journal.Resend("WS-1", 3)
+	if !ok || len(resent) != 3 {
+		t.Fatalf("Expected seqs 3 to 5, got %+v (ok %v)", resent, ok)
+	}
+	for i, msg := range resent {
+		if msg.Seq != uint64(3+i) || !msg.PossDup || msg.SessionID != "WS-1" {
+			t.Errorf("Resent %d: expected seq %d flagged poss_dup, got %+v", i, 3+i, msg)
+		}
+	}
+	if resent, _, ok := journal.Resend("WS-1", 0); !ok || len(resent) != 5 {
+		t.Errorf("Expected from_seq 0 to resend everything, got %d (ok %v)", len(resent), ok)
+	}
+	if resent, _, ok := journal.Resend("WS-1", 6); !ok || len(resent) != 0 {
+		t.Errorf("Expected nothing after the last seq, got %+v (ok %v)", resent, ok)
+	}
+
+	// Push the first messages out of the journal
+	for i := 0; i < wsjournal.Size; i++ {
+		journal.Record(&wsjournal.Message{Type: "order_ack"})
+	}
+	const first = 6 // The first 5 were dropped
+	if _, got, ok := journal.Resend("WS-1", first-1); ok || got != first {
+		t.Errorf("Expected a resend from seq %d refused with seq %d the oldest kept, got %d (ok %v)", first-1, first, got, ok)
+	}
+	if resent, _, ok := journal.Resend("WS-1", first); !ok || len(resent) != wsjournal.Size {
+		t.Errorf("Expected a resend from the oldest kept to return %d, got %d (ok %v)", wsjournal.Size, len(resent), ok)
+	}
+}
+
+// TestWSJournal_SlowConnection verifies a connection that cannot keep up
+// has its outgoing messages closed, while the journal keeps recording.
+func TestWSJournal_SlowConnection(t *testing.T) {
+	store := wsjournal.NewStore()
+	out := make(chan wsjournal.Message, 1)
+	journal := store.Open("WS-1", "MM1", out)
+
+	journal.Record(&wsjournal.Message{Type: "login_ack"})
+	journal.Record(&wsjournal.Message{Type: "order_ack"}) // out is full
+
+	if msgs := received(out); len(msgs) != 1 || msgs[0].Seq != 1 {
+		t.Fatalf("Expected only the login_ack queued, got %+v", msgs)
+	}
+	if _, ok := <-out; ok {
+		t.Fatal("Expected out closed")
+	}
+	journal.Record(&wsjournal.Message{Type: "order_ack"})
+	journal.Close()
+	if resent, _, ok := journal.Resend("WS-1", 2); !ok || len(resent) != 2 {
+		t.Errorf("Expected seqs 2 and 3 kept for resend, got %+v", resent)
+	}
+}
@@ -13,6 +13,7 @@ func main() {
 
 	mux.HandleFunc("/health", handleHealth)
 	mux.HandleFunc("/api/resource", handleResource)
+	mux.HandleFunc("/api/private", handlePrivate)
 
 	server := &http.Server{
 		Addr:         ":8081",
@@ -38,6 +39,17 @@ func handleResource(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handlePrivate requires a bearer token, so the gateway's auth-failure
+// penalties can be exercised
+func handlePrivate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer demo-token" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"secret": "42"})
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// TestGeneratedUpToDate fails when a type or endpoint changed without
+// running go generate ./api.
+func TestGeneratedUpToDate(t *testing.T) {
+	spec, err := Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(spec, OpenAPI) {
+		t.Error("openapi.json is stale: run go generate ./api")
+	}
+
+	client, err := ClientSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile("client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(client, current) {
+		t.Error("client_gen.go is stale: run go generate ./api")
+	}
+}
+
+// TestClient decodes a response and surfaces the gateway's errors.
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/profile":
+			w.Write([]byte(`{"active":"peak","bucket_size":5,"refill_rate":0.5,"read_only":false,"profiles":[{"name":"peak","schedule":"* 9-16 * * 1-5"}]}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"read-only maintenance window"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL + "/")
+
+	profile, err := client.GetProfile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Active != "peak" || profile.BucketSize != 5 || len(profile.Profiles) != 1 || profile.Profiles[0].Schedule != "* 9-16 * * 1-5" {
+		t.Errorf("Unexpected profile %+v", profile)
+	}
+
+	_, err = client.GetMetrics(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "read-only maintenance window" {
+		t.Errorf("Expected the gateway's 503 error, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+// Code generated by go generate ./api; DO NOT EDIT.
+
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetProfile calls GET /admin/profile: Active limit profile and schedule.
+func (c *Client) GetProfile(ctx context.Context) (*ProfileStatus, error) {
+	var out ProfileStatus
+	if err := c.do(ctx, http.MethodGet, "/admin/profile", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetConfig calls GET /admin/config: Effective gateway configuration.
+func (c *Client) GetConfig(ctx context.Context) (*Config, error) {
+	var out Config
+	if err := c.do(ctx, http.MethodGet, "/admin/config", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMetrics calls GET /admin/metrics: Bucket creation, key count and eviction metrics.
+func (c *Client) GetMetrics(ctx context.Context) (*Metrics, error) {
+	var out Metrics
+	if err := c.do(ctx, http.MethodGet, "/admin/metrics", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLatencyReport calls GET /latency-report: Latency of proxied requests by stage, across gateway and backend.
+func (c *Client) GetLatencyReport(ctx context.Context) (*LatencyReport, error) {
+	var out LatencyReport
+	if err := c.do(ctx, http.MethodGet, "/latency-report", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,26 @@
+// Command gen writes the admin API's OpenAPI document and client from
+// api.Endpoints. Run it with go generate ./api from the gateway module.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/rate-limiter/gateway/api"
+)
+
+func main() {
+	spec, err := api.Spec()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := api.ClientSource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"openapi.json": spec, "client_gen.go": client} {
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"bytes"
+	"go/format"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by go generate ./api; DO NOT EDIT.
+
+package api
+
+import (
+	"context"
+	"net/http"
+)
+{{range .}}
+// {{.Operation}} calls {{.Method}} {{.Path}}: {{.Summary}}.
+func (c *Client) {{.Operation}}(ctx context.Context) (*{{.Type}}, error) {
+	var out {{.Type}}
+	if err := c.do(ctx, http.Method{{.MethodName}}, "{{.Path}}", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+{{end}}`))
+
+// ClientSource renders client_gen.go: one Client method per endpoint.
+func ClientSource() ([]byte, error) {
+	type operation struct {
+		Endpoint
+		Type       string
+		MethodName string
+	}
+	operations := make([]operation, len(Endpoints))
+	for i, ep := range Endpoints {
+		method := ep.Method[:1] + strings.ToLower(ep.Method[1:]) // GET -> MethodGet
+		operations[i] = operation{Endpoint: ep, Type: reflect.TypeOf(ep.Response).Name(), MethodName: method}
+	}
+
+	var buf bytes.Buffer
+	if err := clientTemplate.Execute(&buf, operations); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//go:generate go run ./gen
+
+// Endpoint is one admin API operation. The OpenAPI document and the client
+// are both generated from Endpoints, so a handler, its documentation and its
+// client method cannot drift apart.
+type Endpoint struct {
+	Method    string
+	Path      string
+	Operation string // Client method name and OpenAPI operationId
+	Summary   string
+	Response  any // Zero value of the response type
+}
+
+// Endpoints is every admin API operation, in the order they are documented.
+// The admin API is not rate limited, so it stays usable during an incident.
+var Endpoints = []Endpoint{
+	{"GET", "/admin/profile", "GetProfile", "Active limit profile and schedule", ProfileStatus{}},
+	{"GET", "/admin/config", "GetConfig", "Effective gateway configuration", Config{}},
+	{"GET", "/admin/metrics", "GetMetrics", "Bucket creation, key count and eviction metrics", Metrics{}},
+	{"GET", "/latency-report", "GetLatencyReport", "Latency of proxied requests by stage, across gateway and backend", LatencyReport{}},
+}
+
+// SpecPath is where the gateway serves the OpenAPI document.
+const SpecPath = "/admin/openapi.json"
+
+// OpenAPI is the generated document, as served at SpecPath.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// Spec builds the OpenAPI 3.0 document for Endpoints. Schemas are derived
+// from the response types' fields and JSON tags: a field is required unless
+// it is omitempty.
+func Spec() ([]byte, error) {
+	schemas := make(map[string]any)
+	paths := make(map[string]any)
+	for _, ep := range Endpoints {
+		operation := map[string]any{
+			"operationId": ep.Operation,
+			"summary":     ep.Summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": ep.Summary,
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(ep.Response), schemas)},
+					},
+				},
+			},
+		}
+		item, _ := paths[ep.Path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[ep.Path] = item
+		}
+		item[strings.ToLower(ep.Method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Rate limiter gateway admin API",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema for t. Named structs are added to schemas
+// once and referenced.
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return schemaOf(t.Elem(), schemas)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, done := schemas[t.Name()]; !done {
+			schemas[t.Name()] = nil // Placeholder, in case the type refers to itself
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return ref
+	}
+	panic(fmt.Sprintf("api: no schema for %s", t))
+}
+
+// structSchema returns the object schema for a struct's exported fields.
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,77 @@
+// Package api defines the gateway's admin API: the response types, the
+// endpoints serving them, the OpenAPI document generated from both, and a
+// typed client generated from the same table. The gateway, rlctl and any
+// external tooling share these types instead of decoding maps by hand.
+//
+// After changing a type or an endpoint, regenerate openapi.json and
+// client_gen.go:
+//
+//	cd gateway && go generate ./api
+package api
+
+import "github.com/rate-limiter/gateway/ratelimiter"
+
+// ProfileStatus is the limit profile in effect and the configured schedule.
+type ProfileStatus struct {
+	Active     string                `json:"active"` // Profile name, or "default" when none matches
+	BucketSize int64                 `json:"bucket_size"`
+	RefillRate float64               `json:"refill_rate"`
+	ReadOnly   bool                  `json:"read_only"`
+	Timezone   string                `json:"timezone,omitempty"` // Rules file timezone; absent without one
+	Profiles   []ratelimiter.Profile `json:"profiles"`
+}
+
+// Config is the configuration a gateway is running with, after defaults and
+// environment overrides.
+type Config struct {
+	GatewayID  string        `json:"gateway_id"`
+	RedisMode  string        `json:"redis_mode"` // "standalone" or "cluster"
+	BucketSize int64         `json:"bucket_size"`
+	RefillRate float64       `json:"refill_rate"`
+	RulesFile  string        `json:"rules_file,omitempty"`
+	Penalty    PenaltyConfig `json:"penalty"`
+	TTL        TTLConfig     `json:"ttl"`
+}
+
+// PenaltyConfig is how backend responses shrink a client's bucket.
+type PenaltyConfig struct {
+	HalfLifeSeconds float64            `json:"half_life_seconds"`
+	MaxScore        float64            `json:"max_score"`
+	Weights         map[string]float64 `json:"weights"` // Status code -> score added
+}
+
+// TTLConfig is how long idle buckets are kept in Redis.
+type TTLConfig struct {
+	Jitter     float64 `json:"jitter"`
+	MinSeconds int64   `json:"min_seconds"`
+	MaxSeconds int64   `json:"max_seconds"`
+}
+
+// Metrics is bucket state for capacity planning.
+type Metrics struct {
+	BucketsCreated int64                 `json:"buckets_created"` // Fresh buckets started by this gateway
+	TTL            TTLConfig             `json:"ttl"`
+	Redis          *ratelimiter.KeyStats `json:"redis,omitempty"`
+	RedisError     string                `json:"redis_error,omitempty"` // Set instead of redis if Redis could not be read
+}
+
+// LatencyReport breaks the latency of requests proxied to the backend down
+// by stage, across the gateway and the backend, over the most recent
+// requests.
+type LatencyReport struct {
+	Requests int64          `json:"requests"` // Proxied requests timed since the gateway started
+	Window   int64          `json:"window"`   // Most recent requests the stages summarize
+	Stages   []LatencyStage `json:"stages"`   // In the order a request passes them, then the total
+}
+
+// LatencyStage summarizes one stage of a request, in milliseconds.
+type LatencyStage struct {
+	Name   string  `json:"name"`
+	Source string  `json:"source"` // "gateway", or "backend" for stages it reports in Server-Timing
+	Count  int64   `json:"count"`  // Requests in the window that reported the stage
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+	Share  float64 `json:"share"` // Mean as a fraction of the mean total
+}
@@ -0,0 +1,475 @@
+// Command rlctl is the rate limiter's operator tool.
+//
+// It answers the questions asked first during an incident - "why is this
+// client being limited?", "what does Redis actually hold for it?", "what is
+// the gateway deciding right now?" - without redis-cli archaeology.
+//
+// Usage:
+//
+//	rlctl inspect <client>...   Bucket state on the client's master and replicas
+//	rlctl explain <client>      Simulate a request: matched profile, penalty, decision
+//	rlctl tail                  Stream live decisions from every gateway
+//	rlctl status                A gateway's active profile, configuration and metrics
+//
+// rlctl reads the same environment as the gateway (REDIS_MODE, REDIS_ADDR,
+// REDIS_ADDRS, BUCKET_SIZE, REFILL_RATE, PENALTY_HALF_LIFE, RULES_FILE), so
+// run it with the gateway's environment to get the gateway's answers.
+// status asks a gateway itself, through the generated admin API client, at
+// GATEWAY_URL (default http://localhost:8080).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rate-limiter/gateway/api"
+	"github.com/rate-limiter/gateway/ratelimiter"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := newRedisClient()
+	defer client.Close()
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "inspect":
+		err = runInspect(ctx, client, args)
+	case "explain":
+		err = runExplain(ctx, client, args)
+	case "tail":
+		err = runTail(ctx, client, args)
+	case "status":
+		err = runStatus(ctx, args)
+	case "latency":
+		err = runLatency(ctx, args)
+	case "-h", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rlctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: rlctl <command> [flags] [args]
+
+Commands:
+  inspect <client>...  Show a client's bucket on its master and every replica
+  explain <client>     Explain the decision for a request from a client
+  tail                 Stream live decisions from all gateways
+  status               Show a gateway's active profile, configuration and metrics
+  latency              Show where proxied requests spend their time, by stage
+
+Run "rlctl <command> -h" for command flags.`)
+}
+
+// newRedisClient connects the way the gateway does, but always reads from
+// masters unless a command asks a replica directly.
+func newRedisClient() redis.UniversalClient {
+	if getEnv("REDIS_MODE", "standalone") == "cluster" {
+		addrs := strings.Split(getEnv("REDIS_ADDRS", "localhost:7000,localhost:7001,localhost:7002"), ",")
+		for i := range addrs {
+			addrs[i] = strings.TrimSpace(addrs[i])
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{Addrs: addrs, DialTimeout: 2 * time.Second})
+	}
+	return redis.NewClient(&redis.Options{Addr: getEnv("REDIS_ADDR", "localhost:6379"), DialTimeout: 2 * time.Second})
+}
+
+// bucketNode is one Redis node holding a copy of a bucket.
+type bucketNode struct {
+	addr string
+	role string
+	conn redis.Cmdable
+}
+
+// nodesForKey returns the master and replicas responsible for key. Replica
+// connections are put in READONLY mode so they answer instead of redirecting.
+func nodesForKey(ctx context.Context, client redis.UniversalClient, key string) (int64, []bucketNode, func(), error) {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok {
+		return -1, []bucketNode{{addr: client.(*redis.Client).Options().Addr, role: "master", conn: client}}, func() {}, nil
+	}
+
+	slot, err := cluster.ClusterKeySlot(ctx, key).Result()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	slots, err := cluster.ClusterSlots(ctx).Result()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var nodes []bucketNode
+	var opened []*redis.Client
+	for _, s := range slots {
+		if int64(s.Start) > slot || int64(s.End) < slot {
+			continue
+		}
+		for i, n := range s.Nodes {
+			role := "replica"
+			if i == 0 {
+				role = "master"
+			}
+			conn := redis.NewClient(&redis.Options{
+				Addr:        n.Addr,
+				DialTimeout: 2 * time.Second,
+				OnConnect: func(ctx context.Context, cn *redis.Conn) error {
+					return cn.ReadOnly(ctx).Err()
+				},
+			})
+			opened = append(opened, conn)
+			nodes = append(nodes, bucketNode{addr: n.Addr, role: role, conn: conn})
+		}
+	}
+	closeAll := func() {
+		for _, c := range opened {
+			c.Close()
+		}
+	}
+	if len(nodes) == 0 {
+		closeAll()
+		return 0, nil, nil, fmt.Errorf("no node serves slot %d", slot)
+	}
+	return slot, nodes, closeAll, nil
+}
+
+// runInspect prints a client's bucket as stored on each node, so replication
+// lag and divergent copies are visible side by side.
+func runInspect(ctx context.Context, client redis.UniversalClient, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, "Usage: rlctl inspect <client>...") }
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	now := time.Now()
+	for _, clientID := range fs.Args() {
+		key := "ratelimit:" + clientID
+		slot, nodes, closeNodes, err := nodesForKey(ctx, client, key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s", key)
+		if slot >= 0 {
+			fmt.Printf("  (slot %d)", slot)
+		}
+		fmt.Println()
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  NODE\tROLE\tTOKENS\tLAST REFILL\tPENALTY\tTTL")
+		for _, node := range nodes {
+			state, err := ratelimiter.ReadBucket(ctx, node.conn, key)
+			switch {
+			case err != nil:
+				fmt.Fprintf(tw, "  %s\t%s\terror: %v\n", node.addr, node.role, err)
+			case !state.Exists:
+				fmt.Fprintf(tw, "  %s\t%s\t-\t(no state: full bucket)\t-\t-\n", node.addr, node.role)
+			default:
+				penalty := "-"
+				if state.Penalty > 0 {
+					penalty = fmt.Sprintf("%.2f @ %s ago", state.Penalty, ago(now, state.PenaltyTS))
+				}
+				fmt.Fprintf(tw, "  %s\t%s\t%.2f\t%s ago\t%s\t%s\n",
+					node.addr, node.role, state.Tokens, ago(now, state.LastRefill), penalty, state.TTL)
+			}
+		}
+		tw.Flush()
+		closeNodes()
+	}
+	return nil
+}
+
+// runExplain walks through the decision the gateway would make for a
+// request, without consuming a token.
+func runExplain(ctx context.Context, client redis.UniversalClient, args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	method := fs.String("method", "GET", "Request method")
+	at := fs.String("at", "", "Evaluate at this RFC 3339 time instead of now (profiles only; bucket state is current)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: rlctl explain [flags] <client>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	clientID := fs.Arg(0)
+	key := "ratelimit:" + clientID
+
+	now := time.Now()
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at: %w", err)
+		}
+		now = t
+	}
+
+	bucketSize := int64(getEnvInt("BUCKET_SIZE", 10))
+	refillRate := getEnvFloat("REFILL_RATE", 1.0)
+	halfLife := time.Duration(getEnvFloat("PENALTY_HALF_LIFE", ratelimiter.DefaultPenaltyConfig().HalfLife.Seconds()) * float64(time.Second))
+
+	var rules *ratelimiter.Rules
+	if path := getEnv("RULES_FILE", ""); path != "" {
+		var err error
+		if rules, err = ratelimiter.LoadRules(path); err != nil {
+			return err
+		}
+	}
+
+	local := now.In(rules.Location())
+	fmt.Printf("client:    %s (key %s)\n", clientID, key)
+	fmt.Printf("request:   %s at %s\n", *method, local.Format("Mon 2006-01-02 15:04:05 MST"))
+	fmt.Printf("defaults:  bucket_size=%d refill_rate=%g\n", bucketSize, refillRate)
+
+	// Profiles: first match wins, exactly as in Rules.Active
+	active := rules.Active(now)
+	if rules == nil || len(rules.Profiles) == 0 {
+		fmt.Println("profiles:  none configured (RULES_FILE unset)")
+	} else {
+		fmt.Println("profiles:")
+		matched := false
+		for i := range rules.Profiles {
+			p := &rules.Profiles[i]
+			verdict := "no match: " + p.Explain(local)
+			if p == active {
+				verdict, matched = "MATCH", true
+			} else if matched {
+				verdict = "skipped: earlier profile matched"
+			}
+			fmt.Printf("  %-14s %-18q %s\n", p.Name, p.Schedule, verdict)
+		}
+	}
+
+	profileName := "default"
+	if active != nil {
+		profileName = active.Name
+		if active.BucketSize > 0 {
+			bucketSize = active.BucketSize
+		}
+		if active.RefillRate > 0 {
+			refillRate = active.RefillRate
+		}
+		if active.ReadOnly && *method != "GET" && *method != "HEAD" && *method != "OPTIONS" {
+			fmt.Printf("decision:  REJECT 503 - profile %s is read-only and %s is a write\n", active.Name, *method)
+			return nil
+		}
+	}
+	fmt.Printf("limits:    %s: bucket_size=%d refill_rate=%g\n", profileName, bucketSize, refillRate)
+
+	state, err := ratelimiter.ReadBucket(ctx, client, key)
+	if err != nil {
+		return fmt.Errorf("reading bucket: %w", err)
+	}
+	sim := ratelimiter.Simulate(*state, bucketSize, refillRate, halfLife, time.Now())
+
+	if state.Penalty > 0 {
+		fmt.Printf("penalty:   score %.2f (%.2f written %s ago, half-life %s) -> bucket %d\n",
+			sim.Penalty, state.Penalty, ago(time.Now(), state.PenaltyTS), halfLife, sim.BucketSize)
+	} else {
+		fmt.Println("penalty:   none")
+	}
+
+	if state.Exists {
+		fmt.Printf("tokens:    %.2f stored %s ago -> %.2f after refill (cap %d), key TTL %s\n",
+			state.Tokens, ago(time.Now(), state.LastRefill), sim.Tokens, sim.BucketSize, state.TTL)
+	} else {
+		fmt.Printf("tokens:    no state in Redis -> new full bucket of %d\n", sim.BucketSize)
+	}
+
+	if sim.Allowed {
+		fmt.Printf("decision:  ALLOW (remaining %d/%d)\n", sim.Remaining, sim.BucketSize)
+	} else {
+		fmt.Printf("decision:  REJECT 429 (retry after %s)\n", sim.RetryAfter)
+	}
+	return nil
+}
+
+// runTail subscribes to the decision channel and prints decisions as the
+// gateways make them. Gateways only publish while someone is subscribed,
+// so the first decisions appear after their next poll (~2s).
+func runTail(ctx context.Context, client redis.UniversalClient, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	clientFilter := fs.String("client", "", "Only show decisions for this client")
+	deniedOnly := fs.Bool("denied", false, "Only show requests that were not allowed")
+	raw := fs.Bool("json", false, "Print decisions as JSON lines")
+	fs.Parse(args)
+
+	sub := client.Subscribe(ctx, ratelimiter.DecisionChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribing: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Tailing %s (Ctrl+C to stop)...\n", ratelimiter.DecisionChannel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return nil
+			}
+			var d ratelimiter.Decision
+			if err := json.Unmarshal([]byte(msg.Payload), &d); err != nil {
+				continue
+			}
+			if (*clientFilter != "" && d.Client != *clientFilter) || (*deniedOnly && d.Allowed) {
+				continue
+			}
+			if *raw {
+				fmt.Println(msg.Payload)
+				continue
+			}
+
+			verdict := fmt.Sprintf("ALLOW %d/%d", d.Remaining, d.Limit)
+			switch d.Reason {
+			case ratelimiter.ReasonRateLimited:
+				verdict = fmt.Sprintf("DENY  retry %ds", d.RetryAfter)
+			case ratelimiter.ReasonReadOnly:
+				verdict = "DENY  read-only"
+			case ratelimiter.ReasonFailOpen:
+				verdict = "ALLOW fail-open"
+			}
+			profile := d.Profile
+			if profile == "" {
+				profile = "default"
+			}
+			fmt.Printf("%s  %-20s %-16s %-6s %-24s %-10s %s\n",
+				d.Time.Format("15:04:05.000"), d.Gateway, d.Client, d.Method, d.Path, profile, verdict)
+		}
+	}
+}
+
+// runStatus asks a gateway for its active profile, configuration and
+// metrics through the admin API.
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	gatewayURL := fs.String("gateway", getEnv("GATEWAY_URL", "http://localhost:8080"), "Gateway base URL")
+	raw := fs.Bool("json", false, "Print the responses as JSON")
+	fs.Parse(args)
+
+	gw := api.NewClient(*gatewayURL)
+	profile, err := gw.GetProfile(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := gw.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	metrics, err := gw.GetMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *raw {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"profile": profile, "config": config, "metrics": metrics})
+	}
+
+	fmt.Printf("gateway:   %s (%s, redis %s)\n", config.GatewayID, *gatewayURL, config.RedisMode)
+	fmt.Printf("defaults:  bucket_size=%d refill_rate=%g\n", config.BucketSize, config.RefillRate)
+	readOnly := ""
+	if profile.ReadOnly {
+		readOnly = " read-only"
+	}
+	fmt.Printf("profile:   %s: bucket_size=%d refill_rate=%g%s (%d configured)\n",
+		profile.Active, profile.BucketSize, profile.RefillRate, readOnly, len(profile.Profiles))
+	fmt.Printf("penalty:   half-life %gs, max score %g, weights %v\n",
+		config.Penalty.HalfLifeSeconds, config.Penalty.MaxScore, config.Penalty.Weights)
+	fmt.Printf("ttl:       %ds-%ds, jitter %g\n", metrics.TTL.MinSeconds, metrics.TTL.MaxSeconds, metrics.TTL.Jitter)
+	if metrics.Redis != nil {
+		fmt.Printf("buckets:   %d created here; %d keys on %d shards, %d expired, %d evicted\n", metrics.BucketsCreated,
+			metrics.Redis.Keys, metrics.Redis.Shards, metrics.Redis.ExpiredKeys, metrics.Redis.EvictedKeys)
+	} else {
+		fmt.Printf("buckets:   %d created here; redis: %s\n", metrics.BucketsCreated, metrics.RedisError)
+	}
+	return nil
+}
+
+// runLatency prints a gateway's latency breakdown of proxied requests.
+func runLatency(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("latency", flag.ExitOnError)
+	gatewayURL := fs.String("gateway", getEnv("GATEWAY_URL", "http://localhost:8080"), "Gateway base URL")
+	raw := fs.Bool("json", false, "Print the response as JSON")
+	fs.Parse(args)
+
+	report, err := api.NewClient(*gatewayURL).GetLatencyReport(ctx)
+	if err != nil {
+		return err
+	}
+	if *raw {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Printf("%d requests timed; stages over the last %d\n\n", report.Requests, min(report.Requests, report.Window))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tSOURCE\tMEAN\tP50\tP99\tMAX\tSHARE")
+	for _, stage := range report.Stages {
+		fmt.Fprintf(w, "%s\t%s\t%.3fms\t%.3fms\t%.3fms\t%.3fms\t%.0f%%\n", stage.Name, stage.Source,
+			stage.MeanMs, stage.P50Ms, stage.P99Ms, stage.MaxMs, stage.Share*100)
+	}
+	return w.Flush()
+}
+
+// ago formats the time since a Unix-seconds timestamp
+func ago(now time.Time, unixSeconds float64) time.Duration {
+	t := time.Unix(0, int64(unixSeconds*float64(time.Second)))
+	return now.Sub(t).Round(100 * time.Millisecond)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rate-limiter/gateway/api"
+)
+
+// LATENCY BREAKDOWN:
+// When the gateway fronts the matching engine, a slow order could be slow
+// anywhere: in Redis, on the wire, queued in the engine or in its
+// post-trade work. Every proxied request is stamped as it passes the
+// gateway, and the backend reports its own stages in a Server-Timing
+// header (the engine sends engine-match and engine-post for POST /order):
+//
+//	ingress ──limiter──▶ decision ──network──▶ [backend stages] ──▶ headers ──response──▶ done
+//
+//	limiter   gateway ingress to the rate limit decision (Redis round trip)
+//	network   decision to the backend's response headers, less the
+//	          backend's stages: proxying, connection reuse and the wire
+//	response  streaming the body back to the client
+//	total     gateway ingress to done
+//
+// Backend stages are durations on the backend's own clock, so no clock
+// agreement is needed; they must not overlap, since network is what is
+// left of the round trip after subtracting them all. A backend sending no
+// Server-Timing is all network.
+//
+// GET /latency-report summarizes the latest latencyWindow requests per
+// stage. The gateway also adds its limiter and network stages to the
+// Server-Timing header passed to the client, so browser developer tools
+// show the whole breakdown for a single request.
+
+// latencyWindow is how many recent requests the report summarizes.
+const latencyWindow = 1024
+
+// timingContextKey carries a request's timing from handleRequest to
+// timeResponse, which only sees the proxied request
+type timingContextKey struct{}
+
+// requestTiming is when a proxied request passed each stage.
+type requestTiming struct {
+	start    time.Time
+	decided  time.Time
+	received time.Time     // Backend response headers arrived (zero if none did)
+	backend  []stageTiming // From the backend's Server-Timing header
+}
+
+// stageTiming is one stage's duration.
+type stageTiming struct {
+	name string
+	dur  time.Duration
+}
+
+// latencyRecorder keeps the latest latencyWindow durations of each stage.
+// Safe for concurrent use.
+type latencyRecorder struct {
+	mu       sync.Mutex
+	requests int64
+	stages   map[string]*stageSamples
+	backend  []string // Backend stage names, in the order first reported
+}
+
+// stageSamples is a ring of a stage's recent durations in milliseconds.
+type stageSamples struct {
+	ms [latencyWindow]float64
+	n  int64 // Samples ever recorded
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{stages: make(map[string]*stageSamples)}
+}
+
+// gatewayStages are the stages the gateway measures itself, in request
+// order; backend stages go between network and response.
+var gatewayStages = []string{"limiter", "network", "response", "total"}
+
+// record adds a finished request's stages.
+func (l *latencyRecorder) record(timing *requestTiming, done time.Time) {
+	if timing.received.IsZero() {
+		return // The backend never answered: nothing to break down
+	}
+	stages := timing.stages(done)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests++
+	for _, stage := range stages {
+		samples := l.stages[stage.name]
+		if samples == nil {
+			samples = &stageSamples{}
+			l.stages[stage.name] = samples
+			if !isGatewayStage(stage.name) {
+				l.backend = append(l.backend, stage.name)
+			}
+		}
+		samples.ms[samples.n%latencyWindow] = milliseconds(stage.dur)
+		samples.n++
+	}
+}
+
+// stages returns every stage of a request that finished at done.
+func (t *requestTiming) stages(done time.Time) []stageTiming {
+	stages := []stageTiming{{"limiter", t.decided.Sub(t.start)}, {"network", t.network()}}
+	stages = append(stages, t.backend...)
+	return append(stages, stageTiming{"response", done.Sub(t.received)}, stageTiming{"total", done.Sub(t.start)})
+}
+
+// network returns the round trip to the backend less the backend's stages.
+func (t *requestTiming) network() time.Duration {
+	network := t.received.Sub(t.decided)
+	for _, stage := range t.backend {
+		network -= stage.dur
+	}
+	return max(network, 0) // The clocks tick at slightly different rates
+}
+
+func isGatewayStage(name string) bool {
+	for _, stage := range gatewayStages {
+		if stage == name {
+			return true
+		}
+	}
+	return false
+}
+
+// report summarizes the recorded stages.
+func (l *latencyRecorder) report() api.LatencyReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := append([]string{"limiter", "network"}, l.backend...)
+	names = append(names, "response", "total")
+	report := api.LatencyReport{Requests: l.requests, Window: latencyWindow, Stages: []api.LatencyStage{}}
+	var totalMean float64
+	if total := l.stages["total"]; total != nil {
+		totalMean = total.summary("total").MeanMs
+	}
+	for _, name := range names {
+		samples := l.stages[name]
+		if samples == nil {
+			continue
+		}
+		stage := samples.summary(name)
+		if totalMean > 0 {
+			stage.Share = stage.MeanMs / totalMean
+		}
+		report.Stages = append(report.Stages, stage)
+	}
+	return report
+}
+
+// summary returns the stage's statistics over the window.
+func (s *stageSamples) summary(name string) api.LatencyStage {
+	n := min(s.n, latencyWindow)
+	sorted := append([]float64(nil), s.ms[:n]...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, ms := range sorted {
+		sum += ms
+	}
+
+	stage := api.LatencyStage{Name: name, Source: "backend", Count: n}
+	if isGatewayStage(name) {
+		stage.Source = "gateway"
+	}
+	if n > 0 {
+		stage.MeanMs = sum / float64(n)
+		stage.P50Ms = percentile(sorted, 50)
+		stage.P99Ms = percentile(sorted, 99)
+		stage.MaxMs = sorted[n-1]
+	}
+	return stage
+}
+
+// percentile returns the nearest-rank pth percentile of sorted samples.
+func percentile(sorted []float64, p int) float64 {
+	rank := (len(sorted)*p + 99) / 100 // ceil(len * p/100)
+	return sorted[max(rank, 1)-1]
+}
+
+// timeResponse stamps the arrival of the backend's response headers, reads
+// the backend's stages from them and adds the gateway's.
+func (g *Gateway) timeResponse(resp *http.Response) {
+	timing, ok := resp.Request.Context().Value(timingContextKey{}).(*requestTiming)
+	if !ok {
+		return
+	}
+	timing.received = time.Now()
+	timing.backend = parseServerTiming(resp.Header.Values("Server-Timing"))
+	resp.Header.Add("Server-Timing", fmt.Sprintf("limiter;dur=%.3f, network;dur=%.3f",
+		milliseconds(timing.decided.Sub(timing.start)), milliseconds(timing.network())))
+}
+
+// handleLatencyReport serves the latency breakdown of recent proxied
+// requests.
+func (g *Gateway) handleLatencyReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, g.latency.report())
+}
+
+// parseServerTiming reads the metrics with a duration from Server-Timing
+// header values, e.g. `engine-match;dur=0.412;desc="ingress to match"`.
+// Metrics without one, or named like a gateway stage, are ignored.
+func parseServerTiming(values []string) []stageTiming {
+	var stages []stageTiming
+	for _, value := range values {
+		for _, metric := range splitUnquoted(value, ',') {
+			params := splitUnquoted(metric, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" || isGatewayStage(name) {
+				continue
+			}
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "dur") {
+					continue
+				}
+				ms, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(val), `"`), 64)
+				if err == nil && ms >= 0 {
+					stages = append(stages, stageTiming{name, time.Duration(ms * float64(time.Millisecond))})
+				}
+				break
+			}
+		}
+	}
+	return stages
+}
+
+// splitUnquoted splits s at each sep outside double quotes.
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
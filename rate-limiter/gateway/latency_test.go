@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestParseServerTiming reads durations, skips metrics without one and
+// keeps commas inside quoted descriptions.
+func TestParseServerTiming(t *testing.T) {
+	stages := parseServerTiming([]string{
+		`engine-match;dur=0.412;desc="ingress, then match", cache;desc=hit`,
+		`engine-post;dur=1.5, limiter;dur=9`,
+	})
+	want := []stageTiming{{"engine-match", 412 * time.Microsecond}, {"engine-post", 1500 * time.Microsecond}}
+	if len(stages) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, stages)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want[i], stages[i])
+		}
+	}
+}
+
+// TestLatencyReport proxies requests to a backend reporting Server-Timing
+// and checks the stages add up, with the backend's time taken out of
+// network.
+func TestLatencyReport(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Server-Timing", `engine-match;dur=15, engine-post;dur=5`)
+		io.WriteString(w, `{"success":true}`)
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	g := &Gateway{proxy: httputil.NewSingleHostReverseProxy(target), latency: newLatencyRecorder()}
+	g.proxy.ModifyResponse = func(resp *http.Response) error {
+		g.timeResponse(resp)
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		timing := &requestTiming{start: time.Now().Add(-2 * time.Millisecond), decided: time.Now()}
+		w := httptest.NewRecorder()
+		g.forward(w, httptest.NewRequest(http.MethodPost, "/order", nil), timing)
+		if got := w.Header().Values("Server-Timing"); len(got) != 2 {
+			t.Fatalf("Expected the backend's and the gateway's Server-Timing, got %q", got)
+		}
+	}
+
+	report := g.latency.report()
+	if report.Requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", report.Requests)
+	}
+	names := []string{"limiter", "network", "engine-match", "engine-post", "response", "total"}
+	if len(report.Stages) != len(names) {
+		t.Fatalf("Expected stages %v, got %+v", names, report.Stages)
+	}
+	var sum float64
+	for i, stage := range report.Stages {
+		if stage.Name != names[i] || stage.Count != 3 {
+			t.Errorf("Expected stage %s of 3 requests, got %+v", names[i], stage)
+		}
+		if stage.Name != "total" {
+			sum += stage.MeanMs
+		}
+	}
+	stages := map[string]float64{}
+	for _, stage := range report.Stages {
+		stages[stage.Name] = stage.MeanMs
+	}
+	if stages["engine-match"] != 15 || stages["limiter"] < 2 {
+		t.Errorf("Expected 15ms matching and at least 2ms limiting, got %v", stages)
+	}
+	if stages["network"] >= 20 {
+		t.Errorf("Expected the backend's 20ms taken out of network, got %.3fms", stages["network"])
+	}
+	if diff := sum - stages["total"]; diff < -0.01 || diff > 0.01 {
+		t.Errorf("Expected the stages to add up to the total %.3fms, got %.3fms", stages["total"], sum)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"log"
 	"net/http"
@@ -12,14 +13,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rate-limiter/gateway/api"
 	"github.com/rate-limiter/gateway/ratelimiter"
 	"github.com/redis/go-redis/v9"
 )
 
+// clientKeyContextKey carries the rate limit key from handleRequest to
+// penalizeResponse, which only sees the proxied request
+type clientKeyContextKey struct{}
+
 type Gateway struct {
 	limiter    *ratelimiter.TokenBucket
+	rules      *ratelimiter.Rules // Scheduled limit profiles; nil if RULES_FILE is unset
+	decisions  *ratelimiter.DecisionLog
 	proxy      *httputil.ReverseProxy
+	latency    *latencyRecorder // Stage timings of proxied requests (see latency.go)
 	redisAlive bool
+	config     api.Config // Reported by /admin/config
 }
 
 func main() {
@@ -28,6 +38,33 @@ func main() {
 	refillRate := getEnvFloat("REFILL_RATE", 1.0)
 	redisMode := getEnv("REDIS_MODE", "standalone")
 	backendURL := getEnv("BACKEND_URL", "http://localhost:8081")
+	rulesFile := getEnv("RULES_FILE", "")
+	hostname, _ := os.Hostname()
+	gatewayID := getEnv("GATEWAY_ID", hostname+":8080")
+
+	// Backend connection pool (see transport.go)
+	transport := defaultTransportConfig()
+	transport.MaxIdleConns = getEnvInt("BACKEND_MAX_IDLE_CONNS", transport.MaxIdleConns)
+	transport.MaxIdleConnsPerHost = getEnvInt("BACKEND_MAX_IDLE_CONNS_PER_HOST", transport.MaxIdleConnsPerHost)
+	transport.MaxConnsPerHost = getEnvInt("BACKEND_MAX_CONNS_PER_HOST", transport.MaxConnsPerHost)
+	transport.IdleConnTimeout = time.Duration(getEnvInt("BACKEND_IDLE_CONN_TIMEOUT", int(transport.IdleConnTimeout.Seconds()))) * time.Second
+	transport.TLSHandshakeTimeout = time.Duration(getEnvInt("BACKEND_TLS_HANDSHAKE_TIMEOUT", int(transport.TLSHandshakeTimeout.Seconds()))) * time.Second
+	transport.DisableCompression = getEnvBool("BACKEND_DISABLE_COMPRESSION", transport.DisableCompression)
+
+	// Idle bucket eviction: TTLs are derived from the refill time (see ratelimiter/ttl.go)
+	ttl := ratelimiter.DefaultTTLConfig()
+	ttl.Jitter = getEnvFloat("BUCKET_TTL_JITTER", ttl.Jitter)
+	ttl.MinTTL = time.Duration(getEnvInt("BUCKET_TTL_MIN", int(ttl.MinTTL.Seconds()))) * time.Second
+	ttl.MaxTTL = time.Duration(getEnvInt("BUCKET_TTL_MAX", int(ttl.MaxTTL.Seconds()))) * time.Second
+
+	// Penalty-based limits: weights of 0 disable penalties for that class
+	penalties := ratelimiter.DefaultPenaltyConfig()
+	penalties.HalfLife = time.Duration(getEnvFloat("PENALTY_HALF_LIFE", penalties.HalfLife.Seconds()) * float64(time.Second))
+	penalties.MaxScore = getEnvFloat("PENALTY_MAX", penalties.MaxScore)
+	authWeight := getEnvFloat("PENALTY_AUTH_WEIGHT", penalties.Weights[401])
+	penalties.Weights[401] = authWeight
+	penalties.Weights[403] = authWeight
+	penalties.Weights[404] = getEnvFloat("PENALTY_NOT_FOUND_WEIGHT", penalties.Weights[404])
 
 	// Initialize Redis client based on mode
 	var redisClient redis.Cmdable
@@ -93,6 +130,19 @@ func main() {
 
 	// Initialize rate limiter
 	limiter := ratelimiter.NewTokenBucket(redisClient, int64(bucketSize), refillRate)
+	limiter.SetPenalties(penalties)
+	limiter.SetTTL(ttl)
+
+	// Load scheduled limit profiles
+	var rules *ratelimiter.Rules
+	if rulesFile != "" {
+		loaded, err := ratelimiter.LoadRules(rulesFile)
+		if err != nil {
+			log.Fatal("Invalid rules file:", err)
+		}
+		rules = loaded
+		log.Printf("Loaded %d limit profiles from %s", len(rules.Profiles), rulesFile)
+	}
 
 	// Initialize reverse proxy
 	target, err := url.Parse(backendURL)
@@ -100,19 +150,44 @@ func main() {
 		log.Fatal("Invalid backend URL:", err)
 	}
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = newBackendTransport(transport)
 
 	gateway := &Gateway{
 		limiter:    limiter,
+		rules:      rules,
+		decisions:  ratelimiter.NewDecisionLog(redisClient, gatewayID),
 		proxy:      proxy,
+		latency:    newLatencyRecorder(),
 		redisAlive: true,
+		config: api.Config{
+			GatewayID:  gatewayID,
+			RedisMode:  redisMode,
+			BucketSize: int64(bucketSize),
+			RefillRate: refillRate,
+			RulesFile:  rulesFile,
+			Penalty:    penaltyConfig(penalties),
+			TTL:        ttlConfig(limiter.TTL()),
+		},
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		gateway.timeResponse(resp)
+		return gateway.penalizeResponse(resp)
 	}
 
 	// Start health check goroutine
 	go gateway.healthCheckLoop(context.Background())
 
+	// Stream decisions to `rlctl tail` while anyone is subscribed
+	go gateway.decisions.Run(context.Background())
+
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", gateway.handleRequest)
+	mux.HandleFunc("/admin/profile", gateway.handleProfile)
+	mux.HandleFunc("/admin/config", gateway.handleConfig)
+	mux.HandleFunc("/admin/metrics", gateway.handleMetrics)
+	mux.HandleFunc("/latency-report", gateway.handleLatencyReport)
+	mux.HandleFunc(api.SpecPath, handleSpec)
 
 	server := &http.Server{
 		Addr:         ":8080",
@@ -121,26 +196,49 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	log.Printf("Gateway starting on :8080 (bucket_size=%d, refill_rate=%.2f)", bucketSize, refillRate)
+	log.Printf("Gateway starting on :8080 (bucket_size=%d, refill_rate=%.2f, penalty_half_life=%s)",
+		bucketSize, refillRate, penalties.HalfLife)
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
+	timing := &requestTiming{start: time.Now()}
+
 	// Extract client identifier (use IP address)
-	clientKey := "ratelimit:" + getClientIP(r)
+	clientIP := getClientIP(r)
+	clientKey := "ratelimit:" + clientIP
+	decision := ratelimiter.Decision{Client: clientIP, Method: r.Method, Path: r.URL.Path}
+
+	// Resolve the scheduled profile for this request
+	profile, bucketSize, refillRate := g.activeLimits(time.Now())
+	if profile != nil {
+		w.Header().Set("X-RateLimit-Profile", profile.Name)
+		decision.Profile = profile.Name
+		if profile.ReadOnly && !isReadMethod(r.Method) {
+			decision.Reason = ratelimiter.ReasonReadOnly
+			g.decisions.Record(decision)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `{"error":"read-only maintenance window","profile":"`+profile.Name+`"}`)
+			return
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
 	// Check rate limit
-	result, err := g.limiter.Allow(ctx, clientKey)
+	result, err := g.limiter.AllowLimit(ctx, clientKey, bucketSize, refillRate)
+	timing.decided = time.Now()
 	if err != nil {
 		// Redis error - fail open (allow request) but log warning
 		log.Printf("Rate limiter error (failing open): %v", err)
 		w.Header().Set("X-RateLimit-Warning", "rate-limiter-unavailable")
-		g.proxy.ServeHTTP(w, r)
+		decision.Allowed, decision.Reason = true, ratelimiter.ReasonFailOpen
+		g.decisions.Record(decision)
+		g.forward(w, r, timing)
 		return
 	}
 
@@ -148,6 +246,14 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
 	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
 
+	decision.Allowed, decision.Reason = result.Allowed, ratelimiter.ReasonAllowed
+	decision.Limit, decision.Remaining = result.Limit, result.Remaining
+	if !result.Allowed {
+		decision.Reason = ratelimiter.ReasonRateLimited
+		decision.RetryAfter = int64(result.RetryAfter.Seconds())
+	}
+	g.decisions.Record(decision)
+
 	if !result.Allowed {
 		w.Header().Set("X-RateLimit-Retry-After", strconv.FormatInt(int64(result.RetryAfter.Seconds()), 10))
 		w.Header().Set("Content-Type", "application/json")
@@ -156,8 +262,152 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Forward to backend
+	// Forward to backend, remembering the key so the response can be scored
+	r = r.WithContext(context.WithValue(r.Context(), clientKeyContextKey{}, clientKey))
+	g.forward(w, r, timing)
+}
+
+// forward proxies a request to the backend and records its latency
+func (g *Gateway) forward(w http.ResponseWriter, r *http.Request, timing *requestTiming) {
+	r = r.WithContext(context.WithValue(r.Context(), timingContextKey{}, timing))
 	g.proxy.ServeHTTP(w, r)
+	g.latency.record(timing, time.Now())
+}
+
+// activeLimits returns the profile in effect at now (nil for the defaults)
+// and the bucket size and refill rate it resolves to
+func (g *Gateway) activeLimits(now time.Time) (*ratelimiter.Profile, int64, float64) {
+	bucketSize, refillRate := g.limiter.BucketSize(), g.limiter.RefillRate()
+	profile := g.rules.Active(now)
+	if profile != nil {
+		if profile.BucketSize > 0 {
+			bucketSize = profile.BucketSize
+		}
+		if profile.RefillRate > 0 {
+			refillRate = profile.RefillRate
+		}
+	}
+	return profile, bucketSize, refillRate
+}
+
+// handleProfile reports the limit profile in effect right now and the
+// configured schedule. Not rate limited, so it stays usable during an incident.
+func (g *Gateway) handleProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profile, bucketSize, refillRate := g.activeLimits(time.Now())
+	resp := api.ProfileStatus{
+		Active:     "default",
+		BucketSize: bucketSize,
+		RefillRate: refillRate,
+		Profiles:   []ratelimiter.Profile{},
+	}
+	if profile != nil {
+		resp.Active = profile.Name
+		resp.ReadOnly = profile.ReadOnly
+	}
+	if g.rules != nil {
+		resp.Timezone = g.rules.Timezone
+		resp.Profiles = g.rules.Profiles
+	}
+
+	writeJSON(w, resp)
+}
+
+// handleConfig reports the configuration this gateway is running with, so
+// operators can compare gateways without reading their environments.
+func (g *Gateway) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, g.config)
+}
+
+// handleMetrics reports bucket state metrics for capacity planning: how
+// many buckets this gateway has created, and key counts and expiry/eviction
+// counters from Redis.
+func (g *Gateway) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := api.Metrics{
+		BucketsCreated: g.limiter.BucketsCreated(),
+		TTL:            ttlConfig(g.limiter.TTL()),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if stats, err := g.limiter.KeyStats(ctx); err != nil {
+		resp.RedisError = err.Error()
+	} else {
+		resp.Redis = stats
+	}
+
+	writeJSON(w, resp)
+}
+
+// handleSpec serves the admin API's OpenAPI document (see api/openapi.go).
+func handleSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(api.OpenAPI)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ttlConfig converts eviction settings to their API form
+func ttlConfig(ttl ratelimiter.TTLConfig) api.TTLConfig {
+	return api.TTLConfig{
+		Jitter:     ttl.Jitter,
+		MinSeconds: int64(ttl.MinTTL.Seconds()),
+		MaxSeconds: int64(ttl.MaxTTL.Seconds()),
+	}
+}
+
+// penaltyConfig converts penalty settings to their API form
+func penaltyConfig(penalties ratelimiter.PenaltyConfig) api.PenaltyConfig {
+	weights := make(map[string]float64, len(penalties.Weights))
+	for status, weight := range penalties.Weights {
+		weights[strconv.Itoa(status)] = weight
+	}
+	return api.PenaltyConfig{
+		HalfLifeSeconds: penalties.HalfLife.Seconds(),
+		MaxScore:        penalties.MaxScore,
+		Weights:         weights,
+	}
+}
+
+// penalizeResponse feeds backend auth failures and not-founds back into the
+// client's penalty score (see ratelimiter/penalty.go). It runs inline, so a
+// penalized response pays one extra Redis round trip; successful responses
+// pay nothing. Errors are logged, never returned - penalties are best-effort
+// and must not turn a backend response into a 502.
+func (g *Gateway) penalizeResponse(resp *http.Response) error {
+	clientKey, ok := resp.Request.Context().Value(clientKeyContextKey{}).(string)
+	if !ok || !g.limiter.Penalizes(resp.StatusCode) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(resp.Request.Context(), time.Second)
+	defer cancel()
+
+	score, err := g.limiter.Penalize(ctx, clientKey, resp.StatusCode)
+	if err != nil {
+		log.Printf("Failed to record penalty for %s: %v", clientKey, err)
+		return nil
+	}
+	if score >= 1 {
+		log.Printf("Client %s penalized for %d (score %.2f)", clientKey, resp.StatusCode, score)
+	}
+	return nil
 }
 
 func (g *Gateway) healthCheckLoop(ctx context.Context) {
@@ -182,6 +432,11 @@ func (g *Gateway) healthCheckLoop(ctx context.Context) {
 	}
 }
 
+// isReadMethod reports whether a method is allowed during a read-only window
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header first
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
@@ -211,6 +466,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 func getEnvFloat(key string, defaultValue float64) float64 {
 	if value := os.Getenv(key); value != "" {
 		if f, err := strconv.ParseFloat(value, 64); err == nil {
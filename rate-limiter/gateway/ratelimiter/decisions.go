@@ -0,0 +1,135 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DecisionChannel is the Redis pub/sub channel gateways publish decisions to.
+const DecisionChannel = "ratelimit:decisions"
+
+// Decision reasons
+const (
+	ReasonAllowed     = "allowed"
+	ReasonRateLimited = "rate_limited"
+	ReasonReadOnly    = "read_only"
+	ReasonFailOpen    = "fail_open"
+)
+
+// Decision is one rate limit decision, as published for `rlctl tail`.
+type Decision struct {
+	Time       time.Time `json:"time"`
+	Gateway    string    `json:"gateway"`
+	Client     string    `json:"client"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Profile    string    `json:"profile,omitempty"`
+	Allowed    bool      `json:"allowed"`
+	Reason     string    `json:"reason"`
+	Limit      int64     `json:"limit"`
+	Remaining  int64     `json:"remaining"`
+	RetryAfter int64     `json:"retry_after,omitempty"` // Seconds
+}
+
+// DecisionLog streams decisions to Redis pub/sub so operators can watch
+// them live from any machine, across every gateway instance.
+//
+// COST WHEN NOBODY IS WATCHING:
+// Publishing every decision would double the Redis round trips per request.
+// Instead the log polls PUBSUB NUMSUB every couple of seconds and only
+// publishes while someone is subscribed; otherwise Record is an atomic load.
+// Publishing happens on a background goroutine through a bounded queue, so
+// a slow Redis drops decisions rather than slowing requests.
+//
+// A nil *DecisionLog is valid and records nothing.
+type DecisionLog struct {
+	client  redis.Cmdable
+	gateway string
+	active  atomic.Bool
+	queue   chan Decision
+}
+
+// NewDecisionLog creates a decision log for the gateway with the given ID.
+// Call Run to start it.
+func NewDecisionLog(client redis.Cmdable, gatewayID string) *DecisionLog {
+	return &DecisionLog{
+		client:  client,
+		gateway: gatewayID,
+		queue:   make(chan Decision, 1024),
+	}
+}
+
+// Record publishes a decision if anyone is subscribed. Never blocks.
+func (l *DecisionLog) Record(d Decision) {
+	if l == nil || !l.active.Load() {
+		return
+	}
+	d.Gateway = l.gateway
+	if d.Time.IsZero() {
+		d.Time = time.Now()
+	}
+	select {
+	case l.queue <- d:
+	default: // Queue full: drop rather than slow the request
+	}
+}
+
+// Run polls for subscribers and publishes queued decisions until ctx is done.
+func (l *DecisionLog) Run(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	l.active.Store(l.subscribers(ctx) > 0)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			active := l.subscribers(ctx) > 0
+			if active != l.active.Swap(active) {
+				log.Printf("Decision log streaming %s", map[bool]string{true: "started", false: "stopped"}[active])
+			}
+		case d := <-l.queue:
+			payload, err := json.Marshal(d)
+			if err != nil {
+				continue
+			}
+			if err := l.client.Publish(ctx, DecisionChannel, payload).Err(); err != nil {
+				log.Printf("Failed to publish decision: %v", err)
+			}
+		}
+	}
+}
+
+// subscribers counts subscribers to the decision channel. In cluster mode
+// subscriptions are per node while PUBLISH reaches every node, so the
+// counts of all nodes are summed.
+func (l *DecisionLog) subscribers(ctx context.Context) int64 {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	count := func(ctx context.Context, node redis.Cmdable) int64 {
+		counts, err := node.PubSubNumSub(ctx, DecisionChannel).Result()
+		if err != nil {
+			return 0
+		}
+		return counts[DecisionChannel]
+	}
+
+	cluster, ok := l.client.(*redis.ClusterClient)
+	if !ok {
+		return count(ctx, l.client)
+	}
+
+	var total atomic.Int64 // ForEachShard visits nodes concurrently
+	cluster.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+		total.Add(count(ctx, node))
+		return nil
+	})
+	return total.Load()
+}
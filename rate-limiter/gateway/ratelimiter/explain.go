@@ -0,0 +1,79 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BucketState is a client's bucket hash as stored in Redis.
+type BucketState struct {
+	Exists     bool
+	Tokens     float64
+	LastRefill float64 // Unix seconds
+	Penalty    float64 // Score as last written, before decay
+	PenaltyTS  float64 // Unix seconds
+	TTL        time.Duration
+}
+
+// ReadBucket reads a bucket's state from node without modifying it.
+func ReadBucket(ctx context.Context, node redis.Cmdable, key string) (*BucketState, error) {
+	fields, err := node.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	state := &BucketState{Exists: len(fields) > 0}
+	state.Tokens, _ = strconv.ParseFloat(fields["tokens"], 64)
+	state.LastRefill, _ = strconv.ParseFloat(fields["last_refill"], 64)
+	state.Penalty, _ = strconv.ParseFloat(fields["penalty"], 64)
+	state.PenaltyTS, _ = strconv.ParseFloat(fields["penalty_ts"], 64)
+
+	if state.Exists {
+		if state.TTL, err = node.TTL(ctx, key).Result(); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// Simulation is the decision tokenBucketScript would make for a request,
+// with the intermediate values that led to it.
+type Simulation struct {
+	Penalty    float64 // Decayed penalty score
+	BucketSize int64   // After penalty shrink
+	Tokens     float64 // After refill, before consuming
+	Allowed    bool
+	Remaining  int64
+	RetryAfter time.Duration
+}
+
+// Simulate replays tokenBucketScript's arithmetic in Go for a request at
+// now, without consuming a token. Keep in sync with the Lua script.
+func Simulate(state BucketState, bucketSize int64, refillRate float64, halfLife time.Duration, now time.Time) Simulation {
+	nowSec := float64(now.UnixNano()) / float64(time.Second)
+	sim := Simulation{BucketSize: bucketSize}
+
+	if state.Penalty > 0 && halfLife > 0 {
+		sim.Penalty = state.Penalty * math.Pow(0.5, (nowSec-state.PenaltyTS)/halfLife.Seconds())
+		sim.BucketSize = int64(math.Max(1, math.Floor(float64(bucketSize)/(1+sim.Penalty))))
+	}
+
+	tokens, lastRefill := float64(sim.BucketSize), nowSec
+	if state.Exists && state.LastRefill > 0 {
+		tokens, lastRefill = state.Tokens, state.LastRefill
+	}
+	sim.Tokens = math.Min(float64(sim.BucketSize), tokens+(nowSec-lastRefill)*refillRate)
+
+	left := sim.Tokens
+	if sim.Tokens >= 1 {
+		sim.Allowed = true
+		left--
+	} else {
+		sim.RetryAfter = time.Duration(math.Ceil((1-sim.Tokens)/refillRate)) * time.Second
+	}
+	sim.Remaining = int64(math.Floor(left))
+	return sim
+}
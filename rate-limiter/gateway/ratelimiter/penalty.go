@@ -0,0 +1,110 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PenaltyConfig controls how backend responses shrink a client's bucket.
+//
+// WHY PENALIZE:
+// A flat token bucket treats a client probing for valid credentials or
+// scanning for URLs the same as one making legitimate calls, as long as both
+// stay under the rate. The backend already knows which is which: it answers
+// the first with 401/403 and the second with a stream of 404s. Feeding those
+// codes back into the limiter lets a misbehaving client's limit drop while
+// it misbehaves, without any per-client configuration.
+//
+// HOW IT WORKS:
+// Each penalized response adds its weight to a score stored in the client's
+// bucket hash (fields "penalty" and "penalty_ts"), so it lives on the same
+// shard as the bucket, and the key is kept alive until the score decays
+// below 0.01. The score halves every HalfLife, and the bucket size used by
+// Allow is:
+//
+//	effective_size = max(1, floor(bucket_size / (1 + score)))
+//
+// With the defaults, ten 401s in quick succession push the score to
+// MaxScore (9) and cut a 10-token bucket to 1; a client that stops
+// misbehaving is back to full size after a few half-lives. Scores decay
+// lazily - they are recomputed from penalty_ts when read, so idle clients
+// cost nothing.
+type PenaltyConfig struct {
+	Weights  map[int]float64 // Score added per backend response, by status code
+	HalfLife time.Duration   // Time for a score to decay by half
+	MaxScore float64         // Cap, so a client can always earn its way back
+}
+
+// DefaultPenaltyConfig returns weights suited to credential stuffing and
+// URL scanning: auth failures count four times as much as a missing page,
+// since legitimate clients hit the occasional 404 but rarely a 401.
+func DefaultPenaltyConfig() PenaltyConfig {
+	return PenaltyConfig{
+		Weights: map[int]float64{
+			401: 1.0,
+			403: 1.0,
+			404: 0.25,
+		},
+		HalfLife: time.Minute,
+		MaxScore: 9,
+	}
+}
+
+// Lua script that decays the stored score and adds a new penalty atomically
+var penaltyScript = redis.NewScript(`
+local key = KEYS[1]
+local weight = tonumber(ARGV[1])
+local half_life = tonumber(ARGV[2])
+local max_score = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+local penalty = tonumber(redis.call('HGET', key, 'penalty')) or 0
+local penalty_ts = tonumber(redis.call('HGET', key, 'penalty_ts')) or now
+
+penalty = penalty * math.pow(0.5, (now - penalty_ts) / half_life)
+penalty = math.min(max_score, penalty + weight)
+
+redis.call('HSET', key, 'penalty', penalty, 'penalty_ts', now)
+
+-- Keep the key until the score has decayed away; Allow does the same
+local keep = math.ceil(half_life * math.log(penalty / 0.01) / math.log(2))
+if redis.call('TTL', key) < keep then
+    redis.call('EXPIRE', key, keep)
+end
+
+-- Numbers are truncated to integers on return, so send the score as a string
+return tostring(penalty)
+`)
+
+// SetPenalties enables penalty-based limits. Call before serving requests.
+func (tb *TokenBucket) SetPenalties(config PenaltyConfig) {
+	tb.penalties = config
+}
+
+// Penalizes reports whether a backend status code adds to a client's score.
+func (tb *TokenBucket) Penalizes(status int) bool {
+	return tb.penalties.HalfLife > 0 && tb.penalties.Weights[status] > 0
+}
+
+// Penalize records a backend response for the given key and returns the
+// client's new penalty score. Status codes without a weight are ignored.
+func (tb *TokenBucket) Penalize(ctx context.Context, key string, status int) (float64, error) {
+	if !tb.Penalizes(status) {
+		return 0, nil
+	}
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+
+	score, err := penaltyScript.Run(ctx, tb.client, []string{key},
+		tb.penalties.Weights[status],
+		tb.penalties.HalfLife.Seconds(),
+		tb.penalties.MaxScore,
+		now,
+	).Text()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(score, 64)
+}
@@ -0,0 +1,118 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Rules is the gateway's rules config: time-of-day limit profiles that
+// override the default bucket size and refill rate while their schedule
+// matches.
+//
+// WHY PROFILES:
+// Traffic is not flat. During peak hours a limit generous enough for quiet
+// periods lets a handful of clients crowd out everyone else, and during a
+// maintenance window the backend may only be able to serve reads. Profiles
+// express both without redeploying the gateway or running a cron job that
+// rewrites its config.
+//
+// EVALUATION:
+// The active profile is resolved on every request, from the request time:
+// the first profile (in file order) whose schedule matches wins, and if none
+// matches the defaults from the environment apply. Matching is a handful of
+// bit tests, so there is no background ticker and no state to go stale.
+// Bucket state in Redis is shared across profiles - when a stricter profile
+// starts, existing tokens are capped to the new size on the next request.
+//
+// Example rules file:
+//
+//	{
+//	  "timezone": "America/New_York",
+//	  "profiles": [
+//	    {"name": "maintenance", "schedule": "0-29 2 * * 0", "read_only": true},
+//	    {"name": "peak", "schedule": "* 9-16 * * 1-5", "bucket_size": 5, "refill_rate": 0.5}
+//	  ]
+//	}
+type Rules struct {
+	Timezone string    `json:"timezone"` // IANA name; empty means UTC
+	Profiles []Profile `json:"profiles"`
+
+	location *time.Location
+}
+
+// Profile is a set of limits that applies while its schedule matches.
+// Zero BucketSize or RefillRate keep the default.
+type Profile struct {
+	Name       string  `json:"name"`
+	Schedule   string  `json:"schedule"`
+	BucketSize int64   `json:"bucket_size,omitempty"`
+	RefillRate float64 `json:"refill_rate,omitempty"`
+	ReadOnly   bool    `json:"read_only,omitempty"` // Reject non-GET/HEAD/OPTIONS requests
+
+	schedule *Schedule
+}
+
+// LoadRules reads and validates a rules file.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	rules.location = time.UTC
+	if rules.Timezone != "" {
+		if rules.location, err = time.LoadLocation(rules.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", rules.Timezone, err)
+		}
+	}
+
+	for i := range rules.Profiles {
+		p := &rules.Profiles[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("profile %d has no name", i)
+		}
+		if p.BucketSize < 0 || p.RefillRate < 0 {
+			return nil, fmt.Errorf("profile %s: limits must not be negative", p.Name)
+		}
+		if p.schedule, err = ParseSchedule(p.Schedule); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	return &rules, nil
+}
+
+// Active returns the first profile whose schedule matches now, or nil if
+// the defaults apply. A nil *Rules has no profiles.
+func (r *Rules) Active(now time.Time) *Profile {
+	if r == nil {
+		return nil
+	}
+	now = now.In(r.location)
+	for i := range r.Profiles {
+		if r.Profiles[i].schedule.Matches(now) {
+			return &r.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone schedules are evaluated in.
+func (r *Rules) Location() *time.Location {
+	if r == nil {
+		return time.UTC
+	}
+	return r.location
+}
+
+// Explain returns why the profile's schedule does not match t, or "" if it
+// does.
+func (p *Profile) Explain(t time.Time) string {
+	return p.schedule.Explain(t)
+}
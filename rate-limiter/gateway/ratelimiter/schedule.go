@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like time matcher with five space-separated fields:
+//
+//	minute (0-59)  hour (0-23)  day-of-month (1-31)  month (1-12)  day-of-week (0-6, Sun=0)
+//
+// Each field accepts "*", a value "5", a range "9-17", a step "*/15" or
+// "0-30/10", or a comma-separated list of these. Examples:
+//
+//	"* 9-16 * * 1-5"   every minute from 09:00 to 16:59, Monday to Friday
+//	"0-29 2 * * 0"     02:00 to 02:29 on Sundays
+//
+// Unlike classic cron, day-of-month and day-of-week must BOTH match when
+// both are restricted; the schedule describes a window, not a trigger.
+type Schedule struct {
+	spec   string
+	fields [5]uint64 // Bitset of allowed values per field
+}
+
+// Field bounds, in spec order
+var scheduleBounds = [5]struct{ min, max int }{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week (7 is also Sunday)
+}
+
+// ParseSchedule parses a five-field schedule.
+func ParseSchedule(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("schedule %q: expected 5 fields, got %d", spec, len(parts))
+	}
+
+	s := &Schedule{spec: spec}
+	for i, part := range parts {
+		bits, err := parseScheduleField(part, scheduleBounds[i].min, scheduleBounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		s.fields[i] = bits
+	}
+
+	// Fold 7 onto 0 so both mean Sunday
+	if s.fields[4]&(1<<7) != 0 {
+		s.fields[4] |= 1
+	}
+	return s, nil
+}
+
+// parseScheduleField parses one comma-separated field into a bitset.
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", item)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value in %q", item)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches reports whether t falls inside the schedule.
+func (s *Schedule) Matches(t time.Time) bool {
+	values := [5]int{t.Minute(), t.Hour(), t.Day(), int(t.Month()), int(t.Weekday())}
+	for i, v := range values {
+		if s.fields[i]&(1<<uint(v)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Explain returns why t falls outside the schedule, or "" if it matches.
+func (s *Schedule) Explain(t time.Time) string {
+	names := [5]string{"minute", "hour", "day-of-month", "month", "day-of-week"}
+	values := [5]int{t.Minute(), t.Hour(), t.Day(), int(t.Month()), int(t.Weekday())}
+	for i, v := range values {
+		if s.fields[i]&(1<<uint(v)) == 0 {
+			return fmt.Sprintf("%s %d not in %q", names[i], v, strings.Fields(s.spec)[i])
+		}
+	}
+	return ""
+}
+
+// String returns the schedule as written.
+func (s *Schedule) String() string {
+	return s.spec
+}
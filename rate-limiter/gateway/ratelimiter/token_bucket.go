@@ -2,6 +2,8 @@ package ratelimiter
 
 import (
 	"context"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +14,10 @@ type TokenBucket struct {
 	client     redis.Cmdable
 	bucketSize int64
 	refillRate float64 // tokens per second
+	penalties  PenaltyConfig
+	ttl        TTLConfig
+
+	created atomic.Int64 // Buckets created because no state existed (see ttl.go)
 }
 
 // Result contains the rate limiting decision and metadata
@@ -29,15 +35,29 @@ local key = KEYS[1]
 local bucket_size = tonumber(ARGV[1])
 local refill_rate = tonumber(ARGV[2])
 local now = tonumber(ARGV[3])
+local half_life = tonumber(ARGV[4])
+local jitter = tonumber(ARGV[5])   -- Random stretch factor in [1, 1 + jitter ratio)
+local min_ttl = tonumber(ARGV[6])
+local max_ttl = tonumber(ARGV[7])
 
 -- Get current state
 local tokens = tonumber(redis.call('HGET', key, 'tokens'))
 local last_refill = tonumber(redis.call('HGET', key, 'last_refill'))
 
+-- Shrink the bucket by the client's decayed penalty score (see penalty.go)
+local penalty = tonumber(redis.call('HGET', key, 'penalty')) or 0
+if penalty > 0 and half_life > 0 then
+    local penalty_ts = tonumber(redis.call('HGET', key, 'penalty_ts')) or now
+    penalty = penalty * math.pow(0.5, (now - penalty_ts) / half_life)
+    bucket_size = math.max(1, math.floor(bucket_size / (1 + penalty)))
+end
+
 -- Initialize if first request
+local created = 0
 if tokens == nil then
     tokens = bucket_size
     last_refill = now
+    created = 1
 end
 
 -- Calculate tokens to add based on time elapsed
@@ -58,11 +78,20 @@ if allowed == 0 then
     retry_after = math.ceil((1 - tokens) / refill_rate)
 end
 
+-- Expire once the bucket would have refilled: a missing key means a full
+-- bucket, so idle state can go without changing any decision (see ttl.go)
+local ttl = (bucket_size - tokens) / refill_rate
+if penalty > 0.01 and half_life > 0 then
+    -- Keep a penalized client's score until it has decayed away
+    ttl = math.max(ttl, half_life * math.log(penalty / 0.01) / math.log(2))
+end
+ttl = math.min(max_ttl, math.max(min_ttl, math.ceil(ttl * jitter)))
+
 -- Save state
 redis.call('HSET', key, 'tokens', tokens, 'last_refill', now)
-redis.call('EXPIRE', key, 3600) -- Expire after 1 hour of inactivity
+redis.call('EXPIRE', key, ttl)
 
-return {allowed, math.floor(tokens), retry_after}
+return {allowed, math.floor(tokens), retry_after, bucket_size, created}
 `)
 
 // NewTokenBucket creates a new token bucket rate limiter
@@ -72,6 +101,7 @@ func NewTokenBucket(client redis.Cmdable, bucketSize int64, refillRate float64)
 		client:     client,
 		bucketSize: bucketSize,
 		refillRate: refillRate,
+		ttl:        DefaultTTLConfig(),
 	}
 }
 
@@ -101,26 +131,49 @@ func NewTokenBucket(client redis.Cmdable, bucketSize int64, refillRate float64)
 // ✗ Cons: Can't do global rate limits (e.g., "100 req/sec across ALL clients")
 //         Each client's limit is independent and sharded
 func (tb *TokenBucket) Allow(ctx context.Context, key string) (*Result, error) {
+	return tb.AllowLimit(ctx, key, tb.bucketSize, tb.refillRate)
+}
+
+// AllowLimit is Allow with an explicit bucket size and refill rate, used when
+// a scheduled profile overrides the defaults (see rules.go).
+func (tb *TokenBucket) AllowLimit(ctx context.Context, key string, bucketSize int64, refillRate float64) (*Result, error) {
 	now := float64(time.Now().UnixNano()) / float64(time.Second)
 
 	result, err := tokenBucketScript.Run(ctx, tb.client, []string{key},
-		tb.bucketSize,
-		tb.refillRate,
+		bucketSize,
+		refillRate,
 		now,
+		tb.penalties.HalfLife.Seconds(),
+		1+tb.ttl.Jitter*rand.Float64(),
+		int64(tb.ttl.MinTTL.Seconds()),
+		int64(tb.ttl.MaxTTL.Seconds()),
 	).Int64Slice()
 
 	if err != nil {
 		return nil, err
 	}
+	if result[4] == 1 {
+		tb.created.Add(1)
+	}
 
 	return &Result{
 		Allowed:    result[0] == 1,
 		Remaining:  result[1],
-		Limit:      tb.bucketSize,
+		Limit:      result[3], // Less than bucketSize while the client is penalized
 		RetryAfter: time.Duration(result[2]) * time.Second,
 	}, nil
 }
 
+// BucketSize returns the default bucket size
+func (tb *TokenBucket) BucketSize() int64 {
+	return tb.bucketSize
+}
+
+// RefillRate returns the default refill rate in tokens per second
+func (tb *TokenBucket) RefillRate() float64 {
+	return tb.refillRate
+}
+
 // IsHealthy checks if Redis connection is working
 func (tb *TokenBucket) IsHealthy(ctx context.Context) bool {
 	return tb.client.Ping(ctx).Err() == nil
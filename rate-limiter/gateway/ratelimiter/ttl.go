@@ -0,0 +1,146 @@
+package ratelimiter
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTLConfig controls when idle bucket state is evicted from Redis.
+//
+// WHY NOT A FIXED TTL:
+// A missing key means a full bucket. Once a bucket has had time to refill
+// completely, its key carries no information and is pure memory cost - so
+// the right TTL is the time to refill the deficit:
+//
+//	ttl = (bucket_size - tokens) / refill_rate
+//
+// With the defaults (10 tokens, 1/sec) that is at most 10 seconds, versus
+// the hour a fixed 3600s TTL would hold every one-off client. Evicting at
+// that point never changes a decision. Penalized clients are the exception:
+// their key is kept until the penalty score has decayed (see penalty.go).
+//
+// WHY JITTER:
+// Clients that burst together (a deploy, a retry storm, a cron job fleet)
+// create keys with identical TTLs, and Redis then expires them all in the
+// same instant. Each TTL is stretched by a random factor in
+// [1, 1 + Jitter) - never shortened, so jitter cannot evict early.
+//
+// CAPACITY PLANNING:
+// With derived TTLs, the live key count approximates the number of clients
+// active in the last refill window, and BucketsCreated (buckets started
+// from empty) tracks how often that window is exceeded. KeyStats reports
+// both sides from Redis: keys per shard, and expired vs evicted keys. Evicted
+// keys should stay at zero - a non-zero count means maxmemory is too small
+// and Redis is dropping live buckets, silently resetting clients' limits.
+type TTLConfig struct {
+	Jitter float64       // Maximum random stretch, as a fraction of the TTL
+	MinTTL time.Duration // Floor, at least one second
+	MaxTTL time.Duration // Cap for very slow refill rates; idle buckets reset after this
+}
+
+// DefaultTTLConfig returns a 10% jitter and a one hour cap.
+func DefaultTTLConfig() TTLConfig {
+	return TTLConfig{
+		Jitter: 0.1,
+		MinTTL: time.Second,
+		MaxTTL: time.Hour,
+	}
+}
+
+// SetTTL replaces the eviction settings. Call before serving requests.
+func (tb *TokenBucket) SetTTL(config TTLConfig) {
+	if config.MinTTL < time.Second {
+		config.MinTTL = time.Second // EXPIRE 0 would delete the key at once
+	}
+	if config.MaxTTL < config.MinTTL {
+		config.MaxTTL = config.MinTTL
+	}
+	if config.Jitter < 0 {
+		config.Jitter = 0
+	}
+	tb.ttl = config
+}
+
+// TTL returns the eviction settings in use.
+func (tb *TokenBucket) TTL() TTLConfig {
+	return tb.ttl
+}
+
+// BucketsCreated returns how many requests found no bucket state and started
+// a full bucket, since this gateway started.
+func (tb *TokenBucket) BucketsCreated() int64 {
+	return tb.created.Load()
+}
+
+// KeyStats describes rate limit state held in Redis.
+type KeyStats struct {
+	Shards      int   `json:"shards"`
+	Keys        int64 `json:"keys"`         // Live keys on all masters
+	ExpiredKeys int64 `json:"expired_keys"` // Removed by TTL since Redis started
+	EvictedKeys int64 `json:"evicted_keys"` // Removed by maxmemory policy; should be 0
+}
+
+// KeyStats collects key counts and expiry counters from every master.
+// Redis is assumed to be dedicated to the rate limiter, so DBSIZE counts
+// buckets.
+func (tb *TokenBucket) KeyStats(ctx context.Context) (*KeyStats, error) {
+	var (
+		mu    sync.Mutex
+		stats KeyStats
+	)
+	collect := func(ctx context.Context, node *redis.Client) error {
+		keys, err := node.DBSize(ctx).Result()
+		if err != nil {
+			return err
+		}
+		info, err := node.Info(ctx, "stats").Result()
+		if err != nil {
+			return err
+		}
+		expired, evicted := parseInfoStats(info)
+
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Shards++
+		stats.Keys += keys
+		stats.ExpiredKeys += expired
+		stats.EvictedKeys += evicted
+		return nil
+	}
+
+	var err error
+	switch client := tb.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachMaster(ctx, collect)
+	case *redis.Client:
+		err = collect(ctx, client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// parseInfoStats extracts expired_keys and evicted_keys from INFO stats.
+func parseInfoStats(info string) (expired, evicted int64) {
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "expired_keys":
+			expired, _ = strconv.ParseInt(value, 10, 64)
+		case "evicted_keys":
+			evicted, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return expired, evicted
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the gateway's connection pool to the backend.
+//
+// WHY TUNE:
+// httputil.NewSingleHostReverseProxy uses http.DefaultTransport, which keeps
+// at most 2 idle connections per host. A gateway talks to ONE host at high
+// concurrency, so with 100 requests in flight, 98 connections are closed as
+// soon as their response is done and the next request pays a new TCP (and
+// TLS) handshake. Under load the gateway spends its time connecting, and
+// the backend accumulates sockets in TIME_WAIT.
+//
+// Sizing MaxIdleConnsPerHost to the expected concurrency lets every request
+// reuse a warm keep-alive connection. See transport_test.go for a benchmark.
+type TransportConfig struct {
+	MaxIdleConns        int           // Idle connections across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept to the backend; size to peak concurrency
+	MaxConnsPerHost     int           // Hard cap on connections to the backend (0 = unlimited)
+	IdleConnTimeout     time.Duration // How long an idle connection is kept
+	TLSHandshakeTimeout time.Duration // For https:// backends
+
+	// DisableCompression stops the transport adding "Accept-Encoding: gzip"
+	// and transparently decompressing. A proxy should pass the client's
+	// encoding preference through untouched rather than decompress responses
+	// only to send them on uncompressed.
+	DisableCompression bool
+}
+
+// defaultTransportConfig returns settings for a single busy backend.
+func defaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        512,
+		MaxIdleConnsPerHost: 256,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		DisableCompression:  true,
+	}
+}
+
+// newBackendTransport builds the transport the reverse proxy reuses for
+// every request to the backend.
+func newBackendTransport(config TransportConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second, // TCP keep-alive probes on idle connections
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		DisableCompression:    config.DisableCompression,
+		ExpectContinueTimeout: time.Second,
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkProxyTransport compares the default transport with the tuned
+// backend transport at high concurrency. The "conns" metric is the number of
+// backend connections opened over the whole run: the default transport keeps
+// only 2 idle connections, so after every burst all but 2 are closed and the
+// next burst dials again.
+//
+//	go test -bench ProxyTransport -benchtime 20000x
+func BenchmarkProxyTransport(b *testing.B) {
+	const burst = 64
+
+	cases := []struct {
+		name      string
+		transport func() *http.Transport
+	}{
+		{"default", func() *http.Transport { return http.DefaultTransport.(*http.Transport).Clone() }},
+		{"tuned", func() *http.Transport { return newBackendTransport(defaultTransportConfig()) }},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			var conns atomic.Int64
+			backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"id":1,"name":"resource"}`)
+			}))
+			backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			backend.Start()
+			defer backend.Close()
+
+			target, _ := url.Parse(backend.URL)
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			transport := tc.transport()
+			defer transport.CloseIdleConnections()
+			proxy.Transport = transport
+
+			// Traffic arrives in bursts of concurrent requests, as it does from
+			// real clients; between bursts, connections go back to the idle pool
+			b.ResetTimer()
+			for sent := 0; sent < b.N; sent += burst {
+				var wg sync.WaitGroup
+				for i := 0; i < burst; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						req := httptest.NewRequest(http.MethodGet, "/api/resource", nil)
+						rec := httptest.NewRecorder()
+						proxy.ServeHTTP(rec, req)
+						if rec.Code != http.StatusOK {
+							b.Errorf("status %d", rec.Code)
+						}
+					}()
+				}
+				wg.Wait()
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(conns.Load()), "conns")
+		})
+	}
+}
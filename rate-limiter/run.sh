@@ -157,6 +157,7 @@ usage() {
     echo "  cluster-stop     - Stop Redis cluster"
     echo "  cluster-status   - Show Redis cluster status"
     echo "  cluster-demo     - Run failover demo (cluster must be running)"
+    echo "  cluster-test     - Run cluster failover tests (cluster must be running)"
     echo ""
     echo "Examples:"
     echo "  $0 demo                      # Standalone Redis demo"
@@ -186,6 +187,18 @@ case "${1:-}" in
     cluster-status)
         exec "$ROOT_DIR/scripts/cluster-setup.sh" status
         ;;
+    cluster-test)
+        # Failover tests kill and pause cluster nodes, so they only run in cluster mode
+        if ! redis-cli -p 7000 ping > /dev/null 2>&1; then
+            echo -e "${RED}Redis cluster is not running.${NC}"
+            echo "Start it with: $0 cluster-start"
+            exit 1
+        fi
+        export REDIS_MODE=cluster
+        start_services
+        echo -e "${YELLOW}Running cluster failover tests...${NC}"
+        cd "$ROOT_DIR/tests" && go test -v -count=1 -run 'TestCluster' ./...
+        ;;
     cluster-demo)
         # For cluster demo, set cluster mode and run failover demo
         if ! redis-cli -p 7000 ping > /dev/null 2>&1; then
@@ -0,0 +1,413 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Cluster failover tests
+//
+// These exercise the gateway's cluster-mode code paths against the 6-node
+// cluster from scripts/cluster-setup.sh (3 masters + 3 replicas,
+// cluster-node-timeout 5000). They kill and pause real Redis processes, so
+// they only run when the gateway is in cluster mode:
+//
+//	./run.sh cluster-start
+//	./run.sh cluster-test
+//
+// What they pin down:
+//   - Fail-open window: while a master is down the gateway forwards requests
+//     with X-RateLimit-Warning instead of erroring, and the window closes
+//     once the replica is promoted
+//   - Counter continuity: bucket state replicated before the failure is
+//     intact on the promoted replica
+//   - MOVED/ASK: requests keep being rate limited (no warning) while a
+//     bucket's slot is migrated between masters
+
+var clusterAddrs = []string{
+	"localhost:7000", "localhost:7001", "localhost:7002",
+	"localhost:7003", "localhost:7004", "localhost:7005",
+}
+
+const (
+	clusterConfigDir = "/tmp/redis-cluster" // Written by scripts/cluster-setup.sh
+
+	// Node timeout (5s) + election + gateway slot map refresh, with headroom
+	maxFailOpenWindow = 20 * time.Second
+	promotionTimeout  = 30 * time.Second
+)
+
+// Helper to skip unless the gateway under test is in cluster mode
+func requireCluster(t *testing.T) {
+	if os.Getenv("REDIS_MODE") != "cluster" {
+		t.Skip("cluster tests need REDIS_MODE=cluster (./run.sh cluster-test)")
+	}
+}
+
+// Helper to create a fresh cluster client with an up-to-date slot map
+func newClusterClient(t *testing.T) *redis.ClusterClient {
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:       clusterAddrs,
+		DialTimeout: time.Second,
+		ReadTimeout: time.Second,
+	})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// Helper to find a client IP whose bucket key is owned by the master at addr
+func clientIPOnMaster(t *testing.T, ctx context.Context, client *redis.ClusterClient, addr, prefix string) string {
+	for i := 0; i < 10000; i++ {
+		ip := fmt.Sprintf("%s.%d", prefix, i)
+		master, err := client.MasterForKey(ctx, "ratelimit:"+ip)
+		require.NoError(t, err)
+		if master.Options().Addr == addr {
+			return ip
+		}
+	}
+	t.Fatalf("No client IP with prefix %s maps to %s", prefix, addr)
+	return ""
+}
+
+// Helper to get the PID of the Redis server at addr
+func nodePID(t *testing.T, ctx context.Context, addr string) int {
+	node := redis.NewClient(&redis.Options{Addr: addr})
+	defer node.Close()
+
+	info, err := node.Info(ctx, "server").Result()
+	require.NoError(t, err)
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "process_id:"); ok {
+			pid, err := strconv.Atoi(value)
+			require.NoError(t, err)
+			return pid
+		}
+	}
+	t.Fatalf("No process_id in INFO from %s", addr)
+	return 0
+}
+
+// Helper to restart a killed node from its cluster-setup.sh config; it
+// rejoins as a replica of the promoted master
+func restartNode(t *testing.T, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	config := filepath.Join(clusterConfigDir, port, "redis.conf")
+	out, err := exec.Command("redis-server", config).CombinedOutput()
+	require.NoError(t, err, "Failed to restart %s: %s", addr, out)
+
+	waitForPing(t, addr)
+}
+
+// Helper to wait until the node at addr answers PING
+func waitForPing(t *testing.T, addr string) {
+	node := redis.NewClient(&redis.Options{Addr: addr})
+	defer node.Close()
+
+	deadline := time.Now().Add(promotionTimeout)
+	for time.Now().Before(deadline) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := node.Ping(ctx).Err()
+		cancel()
+		if err == nil {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("Node %s did not come back", addr)
+}
+
+// Helper to wait until the cluster is healthy and key's slot is served by a
+// master other than failedAddr. Returns the new master.
+func waitForPromotion(t *testing.T, key, failedAddr string) *redis.Client {
+	deadline := time.Now().Add(promotionTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+
+		client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: clusterAddrs, ReadTimeout: time.Second})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		master, err := client.MasterForKey(ctx, key)
+		if err == nil && master.Options().Addr != failedAddr {
+			info, err := master.ClusterInfo(ctx).Result()
+			if err == nil && strings.Contains(info, "cluster_state:ok") {
+				cancel()
+				t.Cleanup(func() { client.Close() })
+				return master
+			}
+		}
+		cancel()
+		client.Close()
+	}
+	t.Fatalf("No replica was promoted for %s within %s", failedAddr, promotionTimeout)
+	return nil
+}
+
+// loadSample is one gateway response observed during a failover
+type loadSample struct {
+	at      time.Time
+	status  int
+	warning bool
+}
+
+// Helper to send steady load for a client until stop is closed
+func runLoad(t *testing.T, clientIP string, stop <-chan struct{}) <-chan []loadSample {
+	done := make(chan []loadSample, 1)
+	go func() {
+		var samples []loadSample
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				done <- samples
+				return
+			case <-ticker.C:
+				resp, err := makeRequest(t, clientIP)
+				if err != nil {
+					samples = append(samples, loadSample{at: time.Now(), status: -1})
+					continue
+				}
+				resp.Body.Close()
+				samples = append(samples, loadSample{
+					at:      time.Now(),
+					status:  resp.StatusCode,
+					warning: resp.Header.Get("X-RateLimit-Warning") != "",
+				})
+			}
+		}
+	}()
+	return done
+}
+
+// failoverScenario is one way a master can fail
+type failoverScenario struct {
+	name    string
+	fail    func(t *testing.T, pid int)
+	recover func(t *testing.T, addr string, pid int)
+}
+
+// TestClusterFailover kills or pauses a master under load and checks the
+// fail-open window, that the gateway never errors, and counter continuity
+func TestClusterFailover(t *testing.T) {
+	requireCluster(t)
+
+	scenarios := []failoverScenario{
+		{
+			// Process crash: connections are refused immediately
+			name: "crash",
+			fail: func(t *testing.T, pid int) {
+				require.NoError(t, syscall.Kill(pid, syscall.SIGKILL))
+			},
+			recover: func(t *testing.T, addr string, pid int) {
+				restartNode(t, addr)
+			},
+		},
+		{
+			// Hung process (GC pause, swapped out, partitioned): connections
+			// are accepted but never answered, so every call hits its timeout
+			name: "hang",
+			fail: func(t *testing.T, pid int) {
+				require.NoError(t, syscall.Kill(pid, syscall.SIGSTOP))
+			},
+			recover: func(t *testing.T, addr string, pid int) {
+				require.NoError(t, syscall.Kill(pid, syscall.SIGCONT))
+				waitForPing(t, addr)
+			},
+		},
+	}
+
+	for i, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newClusterClient(t)
+
+			// Pick the current master of an arbitrary slot as the victim
+			victim, err := client.MasterForKey(ctx, "ratelimit:failover-victim")
+			require.NoError(t, err)
+			victimAddr := victim.Options().Addr
+			pid := nodePID(t, ctx, victimAddr)
+
+			loadIP := clientIPOnMaster(t, ctx, client, victimAddr, fmt.Sprintf("10.20.%d", i))
+			continuityIP := clientIPOnMaster(t, ctx, client, victimAddr, fmt.Sprintf("10.21.%d", i))
+			continuityKey := "ratelimit:" + continuityIP
+
+			// Exhaust a bucket, keep it alive past the failover, and make sure
+			// the replica has it before pulling the plug
+			require.NoError(t, client.Del(ctx, "ratelimit:"+loadIP, continuityKey).Err())
+			for j := 0; j < bucketSize; j++ {
+				resp, err := makeRequest(t, continuityIP)
+				require.NoError(t, err)
+				resp.Body.Close()
+			}
+			require.NoError(t, victim.Expire(ctx, continuityKey, 2*time.Minute).Err())
+			before, err := victim.HGetAll(ctx, continuityKey).Result()
+			require.NoError(t, err)
+			acked, err := victim.Wait(ctx, 1, 2*time.Second).Result()
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, acked, int64(1), "Replica did not acknowledge the bucket write")
+
+			stop := make(chan struct{})
+			samplesCh := runLoad(t, loadIP, stop)
+			time.Sleep(time.Second)
+
+			failedAt := time.Now()
+			sc.fail(t, pid)
+			recovered := false
+			t.Cleanup(func() {
+				if !recovered {
+					sc.recover(t, victimAddr, pid)
+				}
+			})
+
+			newMaster := waitForPromotion(t, continuityKey, victimAddr)
+			t.Logf("Replica %s promoted %s after failure", newMaster.Options().Addr,
+				time.Since(failedAt).Round(100*time.Millisecond))
+
+			// Let the gateway converge on the new slot map before stopping load
+			time.Sleep(3 * time.Second)
+			close(stop)
+			samples := <-samplesCh
+
+			sc.recover(t, victimAddr, pid)
+			recovered = true
+
+			// The gateway fails open - it never turns a Redis failure into an error
+			var firstWarning, lastWarning time.Time
+			for _, s := range samples {
+				assert.Contains(t, []int{http.StatusOK, http.StatusTooManyRequests}, s.status,
+					"Gateway returned %d at +%s", s.status, s.at.Sub(failedAt).Round(time.Millisecond))
+				if s.warning {
+					if firstWarning.IsZero() {
+						firstWarning = s.at
+					}
+					lastWarning = s.at
+				}
+			}
+
+			window := lastWarning.Sub(firstWarning)
+			t.Logf("Fail-open window: %s (%d samples)", window.Round(100*time.Millisecond), len(samples))
+			assert.LessOrEqual(t, window, maxFailOpenWindow, "Fail-open window too long")
+
+			// Rate limiting resumed after promotion
+			require.NotEmpty(t, samples)
+			assert.False(t, samples[len(samples)-1].warning, "Gateway still failing open after promotion")
+
+			// The promoted replica has the bucket exactly as it was
+			after, err := newMaster.HGetAll(ctx, continuityKey).Result()
+			require.NoError(t, err)
+			assert.Equal(t, before["tokens"], after["tokens"], "Token count lost in failover")
+			assert.Equal(t, before["last_refill"], after["last_refill"], "Refill timestamp lost in failover")
+		})
+	}
+}
+
+// Helper to look up a node's cluster ID
+func nodeID(t *testing.T, ctx context.Context, node *redis.Client) string {
+	id, err := node.Do(ctx, "CLUSTER", "MYID").Text()
+	require.NoError(t, err)
+	return id
+}
+
+// Helper to move every key in slot from src to dst and hand the slot over.
+// If pause is non-nil it is called after the first key has moved and before
+// the slot is reassigned - the window in which src answers with ASK.
+func migrateSlot(t *testing.T, ctx context.Context, client *redis.ClusterClient, slot int64, src, dst *redis.Client, pause func()) {
+	srcID, dstID := nodeID(t, ctx, src), nodeID(t, ctx, dst)
+	host, port, err := net.SplitHostPort(dst.Options().Addr)
+	require.NoError(t, err)
+
+	require.NoError(t, dst.Do(ctx, "CLUSTER", "SETSLOT", slot, "IMPORTING", srcID).Err())
+	require.NoError(t, src.Do(ctx, "CLUSTER", "SETSLOT", slot, "MIGRATING", dstID).Err())
+
+	keys, err := src.ClusterGetKeysInSlot(ctx, int(slot), 1000).Result()
+	require.NoError(t, err)
+	for _, key := range keys {
+		require.NoError(t, src.Migrate(ctx, host, port, key, 0, 5*time.Second).Err())
+	}
+
+	if pause != nil {
+		pause()
+	}
+
+	err = client.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		return master.Do(ctx, "CLUSTER", "SETSLOT", slot, "NODE", dstID).Err()
+	})
+	require.NoError(t, err)
+}
+
+// Helper to check a gateway response was rate limited normally, not failed open
+func requireRateLimited(t *testing.T, clientIP, phase string) int {
+	resp, err := makeRequest(t, clientIP)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Empty(t, resp.Header.Get("X-RateLimit-Warning"), "Gateway failed open during %s", phase)
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	require.NoError(t, err, "No rate limit headers during %s", phase)
+	return remaining
+}
+
+// TestClusterSlotMigration migrates a bucket's slot between masters and
+// checks the gateway follows ASK and MOVED redirects without losing state
+func TestClusterSlotMigration(t *testing.T) {
+	requireCluster(t)
+
+	ctx := context.Background()
+	client := newClusterClient(t)
+
+	clientIP := "10.22.0.1"
+	key := "ratelimit:" + clientIP
+	require.NoError(t, client.Del(ctx, key).Err())
+
+	slot, err := client.ClusterKeySlot(ctx, key).Result()
+	require.NoError(t, err)
+	src, err := client.MasterForKey(ctx, key)
+	require.NoError(t, err)
+
+	var dst *redis.Client
+	var mu sync.Mutex
+	err = client.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if dst == nil && master.Options().Addr != src.Options().Addr {
+			dst = master
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, dst, "Need at least two masters")
+
+	// Build up some bucket state on the source master
+	for i := 0; i < 3; i++ {
+		requireRateLimited(t, clientIP, "setup")
+	}
+
+	migrateSlot(t, ctx, client, slot, src, dst, func() {
+		// Key is on dst, slot still owned by src: src answers ASK
+		remaining := requireRateLimited(t, clientIP, "ASK redirect")
+		assert.Less(t, remaining, bucketSize-1, "Bucket state lost during migration")
+	})
+
+	// Slot now owned by dst: the gateway's stale slot map gets MOVED
+	requireRateLimited(t, clientIP, "MOVED redirect")
+
+	// Put the slot back so later tests see the original layout
+	migrateSlot(t, ctx, client, slot, dst, src, nil)
+	requireRateLimited(t, clientIP, "migration back")
+}
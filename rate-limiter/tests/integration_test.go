@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -333,6 +334,142 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+// Helper to make a GET request to an arbitrary path with a specific client IP
+func makePathRequest(t *testing.T, clientIP, path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", gatewayURL+path, nil)
+	require.NoError(t, err)
+
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	return client.Do(req)
+}
+
+// TestAuthFailurePenalty verifies repeated 401s shrink the client's bucket
+func TestAuthFailurePenalty(t *testing.T) {
+	clientIP := "10.0.0.12"
+	clearRateLimitState(t, clientIP)
+
+	// Probe a protected endpoint without credentials
+	for i := 0; i < 5; i++ {
+		resp, err := makePathRequest(t, clientIP, "/api/private")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Score ~5 shrinks the bucket to floor(10 / 6) = 1
+	resp, err := makeRequest(t, clientIP)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, limit, 2, "Bucket should shrink after repeated auth failures")
+
+	// The penalty is stored alongside the bucket
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer client.Close()
+	penalty, err := client.HGet(ctx, "ratelimit:"+clientIP, "penalty").Float64()
+	require.NoError(t, err)
+	assert.Greater(t, penalty, 4.0)
+}
+
+// TestNotFoundScanPenalty verifies 404 scanning shrinks the bucket, less
+// aggressively than auth failures
+func TestNotFoundScanPenalty(t *testing.T) {
+	clientIP := "10.0.0.13"
+	clearRateLimitState(t, clientIP)
+
+	for i := 0; i < 8; i++ {
+		resp, err := makePathRequest(t, clientIP, fmt.Sprintf("/api/scan-%d", i))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	// Score ~2 shrinks the bucket to floor(10 / 3) = 3
+	resp, err := makeRequest(t, clientIP)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	require.NoError(t, err)
+	assert.Less(t, limit, bucketSize, "Bucket should shrink after repeated 404s")
+	assert.Greater(t, limit, 1, "404s should be weighted below auth failures")
+}
+
+// TestAdminProfile verifies the admin API reports the active limit profile.
+// run.sh starts the gateway without a rules file, so the defaults apply.
+func TestAdminProfile(t *testing.T) {
+	resp, err := http.Get(gatewayURL + "/admin/profile")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var profile map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&profile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "default", profile["active"])
+	assert.Equal(t, float64(bucketSize), profile["bucket_size"])
+	assert.Equal(t, refillRate, profile["refill_rate"])
+	assert.Equal(t, false, profile["read_only"])
+}
+
+// TestBucketTTLDerived verifies a bucket key expires once it would have
+// refilled, rather than after a fixed hour
+func TestBucketTTLDerived(t *testing.T) {
+	clientIP := "10.0.0.14"
+	clearRateLimitState(t, clientIP)
+
+	for i := 0; i < 5; i++ {
+		resp, err := makeRequest(t, clientIP)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer client.Close()
+
+	// 5 tokens short at 1 token/sec refills in 5s; jitter stretches by < 10%
+	ttl, err := client.TTL(ctx, "ratelimit:"+clientIP).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0), "Bucket key should have a TTL")
+	assert.LessOrEqual(t, ttl, 6*time.Second, "TTL should track the refill time")
+}
+
+// TestAdminMetrics verifies the admin API reports bucket metrics
+func TestAdminMetrics(t *testing.T) {
+	clientIP := "10.0.0.15"
+	clearRateLimitState(t, clientIP)
+
+	resp, err := makeRequest(t, clientIP)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(gatewayURL + "/admin/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var metrics struct {
+		BucketsCreated int64 `json:"buckets_created"`
+		Redis          struct {
+			Shards int   `json:"shards"`
+			Keys   int64 `json:"keys"`
+		} `json:"redis"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&metrics)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, metrics.BucketsCreated, int64(1))
+	assert.GreaterOrEqual(t, metrics.Redis.Shards, 1)
+	assert.GreaterOrEqual(t, metrics.Redis.Keys, int64(1))
+}
+
 // TestMain runs setup before tests
 func TestMain(m *testing.M) {
 	// Wait for services to be ready
@@ -351,9 +488,13 @@ func TestMain(m *testing.M) {
 		time.Sleep(time.Second)
 	}
 
-	// Check Redis
+	// Check Redis (in cluster mode, the first cluster node)
 	ctx := context.Background()
-	client := redis.NewClient(&redis.Options{Addr: redisAddr})
+	addr := redisAddr
+	if os.Getenv("REDIS_MODE") == "cluster" {
+		addr = clusterAddrs[0]
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
 	for i := 0; i < 30; i++ {
 		if err := client.Ping(ctx).Err(); err == nil {
 			break